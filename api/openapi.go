@@ -0,0 +1,14 @@
+// Package api holds the contracts of the platform's API: the OpenAPI
+// document of the API the gateway serves, and the protocol buffers of the
+// gRPC services in proto.
+package api
+
+import _ "embed"
+
+//go:generate go run ../cmd/openapi-gen
+
+// Spec is the OpenAPI document of the API the gateway serves, generated
+// from the services by cmd/openapi-gen
+//
+//go:embed openapi.json
+var Spec []byte
@@ -1,15 +1,37 @@
 package main
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/libs/common"
 )
 
 func main() {
+	registry, err := adapters.NewRegistryFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize rail adapters: %v", err)
+	}
+
 	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("adapters service ok"))
 	})
+
+	// Rails with a registered adapter; the router executes payments through them
+	http.HandleFunc("/v1/adapters", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(common.NewSuccessResponse(map[string]interface{}{
+			"rails": registry.Rails(),
+		}))
+	})
+
+	// Mock ISO 20022 instant payment network; RTP_NETWORK_URL=http://<host>:8088/rtp
+	// points the router's RTP adapter at it
+	http.Handle("/rtp/", http.StripPrefix("/rtp", adapters.NewMockRTPNetwork(1000000)))
+
 	log.Println("Adapters service running on :8088")
 	log.Fatal(http.ListenAndServe(":8088", nil))
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// config holds the connection profiles and which one is current
+type config struct {
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+// profile is how to reach and authenticate to one environment
+type profile struct {
+	BaseURL string `json:"baseUrl"`
+	APIKey  string `json:"apiKey,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Output  string `json:"output,omitempty"` // Default output format
+}
+
+// configPath is $APCTL_CONFIG, or apctl/config.json in the user's config
+// directory
+func configPath() (string, error) {
+	if path := os.Getenv("APCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "apctl", "config.json"), nil
+}
+
+func loadConfig() (*config, error) {
+	cfg := &config{Profiles: map[string]profile{}}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	return cfg, nil
+}
+
+// save writes the config readable only by its owner, as it holds
+// credentials
+func (cfg *config) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+func newConfigCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage connection profiles",
+	}
+
+	var set profile
+	setCmd := &cobra.Command{
+		Use:   "set PROFILE",
+		Short: "Create or update a profile; the first one becomes current",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if set.Output != "" {
+				if _, err := newPrinter(nil, set.Output); err != nil {
+					return err
+				}
+			}
+			p := cfg.Profiles[args[0]]
+			flags := cmd.Flags()
+			if flags.Changed("base-url") {
+				p.BaseURL = set.BaseURL
+			}
+			if flags.Changed("api-key") {
+				p.APIKey = set.APIKey
+			}
+			if flags.Changed("token") {
+				p.Token = set.Token
+			}
+			if flags.Changed("output") {
+				p.Output = set.Output
+			}
+			cfg.Profiles[args[0]] = p
+			if cfg.Current == "" {
+				cfg.Current = args[0]
+			}
+			return cfg.save()
+		},
+	}
+	setCmd.Flags().StringVar(&set.BaseURL, "base-url", "", "gateway URL, such as https://api.example.com")
+	setCmd.Flags().StringVar(&set.APIKey, "api-key", "", "party API key")
+	setCmd.Flags().StringVar(&set.Token, "token", "", "access token, used instead of the API key")
+	setCmd.Flags().StringVar(&set.Output, "output", "", "default output format: table or json")
+
+	useCmd := &cobra.Command{
+		Use:   "use PROFILE",
+		Short: "Make a profile current",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("no profile %q", args[0])
+			}
+			cfg.Current = args[0]
+			return cfg.save()
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete PROFILE",
+		Short: "Delete a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("no profile %q", args[0])
+			}
+			delete(cfg.Profiles, args[0])
+			if cfg.Current == args[0] {
+				cfg.Current = ""
+			}
+			return cfg.save()
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles, with credentials masked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			out, err := newPrinter(cmd.OutOrStdout(), g.output)
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(cfg.Profiles))
+			for name := range cfg.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			type listed struct {
+				Name    string `json:"name"`
+				Current bool   `json:"current"`
+				BaseURL string `json:"baseUrl"`
+				Auth    string `json:"auth"`
+				Output  string `json:"output,omitempty"`
+			}
+			rows := make([]listed, 0, len(names))
+			for _, name := range names {
+				p := cfg.Profiles[name]
+				auth := "none"
+				switch {
+				case p.Token != "":
+					auth = "token " + mask(p.Token)
+				case p.APIKey != "":
+					auth = "api key " + mask(p.APIKey)
+				}
+				rows = append(rows, listed{Name: name, Current: name == cfg.Current, BaseURL: p.BaseURL, Auth: auth, Output: p.Output})
+			}
+			return out.print(rows, []string{"CURRENT", "NAME", "BASE URL", "AUTH", "OUTPUT"}, func(row int) []string {
+				r := rows[row]
+				current := ""
+				if r.Current {
+					current = "*"
+				}
+				return []string{current, r.Name, r.BaseURL, r.Auth, r.Output}
+			}, len(rows))
+		},
+	}
+
+	cmd.AddCommand(setCmd, useCmd, deleteCmd, listCmd)
+	return cmd
+}
+
+// mask shows only the last characters of a credential
+func mask(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
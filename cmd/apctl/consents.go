@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newConsentsCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "consents",
+		Aliases: []string{"consent"},
+		Short:   "Grant, list, show, update, renew and revoke consents",
+	}
+
+	var create client.CreateConsentRequest
+	var createExpires string
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Grant an agent consent to pay within limits",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			expiresAt, err := parseExpiry(createExpires)
+			if err != nil {
+				return err
+			}
+			create.ExpiresAt = expiresAt
+			consent, err := s.client.CreateConsent(cmd.Context(), &create)
+			if err != nil {
+				return err
+			}
+			return printConsent(s, consent)
+		}),
+	}
+	flags := createCmd.Flags()
+	flags.StringVar(&create.AgentID, "agent", "", "agent ID")
+	flags.StringVar(&create.OwnerPartyID, "owner", "", "owner party ID")
+	flags.Float64Var(&create.Limits.SingleTxnUSD, "single-limit", 0, "largest single payment, in USD")
+	flags.Float64Var(&create.Limits.DailyUSD, "daily-limit", 0, "most paid per day, in USD")
+	flags.IntVar(&create.Limits.Velocity.MaxTxnPerHour, "max-per-hour", 0, "most payments per hour")
+	flags.StringSliceVar(&create.Rails, "rails", nil, "rails allowed, such as ach,card; any when empty")
+	flags.StringSliceVar(&create.CounterpartiesAllow, "counterparties", nil, "counterparties allowed; any when empty")
+	flags.Float64Var(&create.CosignRule.ThresholdUSD, "cosign-threshold", 0, "USD amount from which payments need approval")
+	flags.StringVar(&create.CosignRule.ApproverGroup, "approver-group", "", "group that approves payments above the cosign threshold")
+	flags.StringVar(&create.PolicyBundleVersion, "policy-bundle", "", "policy bundle version")
+	flags.StringVar(&createExpires, "expires", "", "when the consent expires, as an RFC3339 time or a duration such as 720h; never when empty")
+	createCmd.MarkFlagRequired("agent")
+	createCmd.MarkFlagRequired("owner")
+
+	getCmd := &cobra.Command{
+		Use:   "get ID",
+		Short: "Show a consent",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			consent, err := s.client.GetConsent(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printConsent(s, consent)
+		}),
+	}
+
+	var list client.ConsentListOptions
+	var revoked string
+	var all bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the consents of an agent or owner party",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			if revoked != "" {
+				value, err := strconv.ParseBool(revoked)
+				if err != nil {
+					return fmt.Errorf("--revoked must be true or false")
+				}
+				list.Revoked = &value
+			}
+			consents, err := collect(all, s.client.Consents(cmd.Context(), list), func() ([]client.Consent, error) {
+				page, err := s.client.ListConsents(cmd.Context(), list)
+				if err != nil {
+					return nil, err
+				}
+				return page.Items, nil
+			})
+			if err != nil {
+				return err
+			}
+			return s.out.print(consents, []string{"ID", "AGENT", "OWNER", "SINGLE USD", "DAILY USD", "RAILS", "STATUS", "EXPIRES"}, func(i int) []string {
+				c := consents[i]
+				return []string{c.ID, c.AgentID, c.OwnerPartyID, usd(c.Limits.SingleTxnUSD), usd(c.Limits.DailyUSD), strings.Join(c.Rails, ","), c.Status, c.ExpiresAt}
+			}, len(consents))
+		}),
+	}
+	listCmd.Flags().StringVar(&list.AgentID, "agent", "", "agent ID")
+	listCmd.Flags().StringVar(&list.OwnerPartyID, "owner", "", "owner party ID")
+	listCmd.Flags().StringVar(&revoked, "revoked", "", "only revoked (true) or active (false) consents")
+	addListFlags(listCmd, &list.ListOptions, &all)
+
+	var reason string
+	revokeCmd := &cobra.Command{
+		Use:   "revoke ID",
+		Short: "Revoke a consent, stopping the payments in flight under it",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			revocation, err := s.client.RevokeConsent(cmd.Context(), args[0], reason)
+			if err != nil {
+				return err
+			}
+			if s.out.json {
+				return s.out.fields(revocation, nil)
+			}
+			if err := printConsent(s, &revocation.Consent); err != nil {
+				return err
+			}
+			outcomes := revocation.AffectedPayments
+			if len(outcomes) == 0 {
+				return nil
+			}
+			s.out.section("Payments in flight")
+			return s.out.print(outcomes, []string{"PAYMENT", "ACTION", "CONSENT", "REASON"}, func(i int) []string {
+				o := outcomes[i]
+				return []string{o.PaymentID, o.Action, o.ConsentID, o.Reason}
+			}, len(outcomes))
+		}),
+	}
+	revokeCmd.Flags().StringVar(&reason, "reason", "", "why the consent is revoked")
+
+	var terms client.Consent
+	var updateExpires string
+	updateCmd := &cobra.Command{
+		Use:   "update ID",
+		Short: "Change a consent's terms, creating its next version",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			current, err := s.client.GetConsent(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			// Limits and the cosign rule are replaced whole, so unchanged
+			// parts carry over from the current version
+			changed := cmd.Flags().Changed
+			var req client.UpdateConsentRequest
+			if changed("rails") {
+				req.Rails = &terms.Rails
+			}
+			if changed("counterparties") {
+				req.CounterpartiesAllow = &terms.CounterpartiesAllow
+			}
+			if changed("single-limit") || changed("daily-limit") || changed("max-per-hour") {
+				limits := current.Limits
+				if changed("single-limit") {
+					limits.SingleTxnUSD = terms.Limits.SingleTxnUSD
+				}
+				if changed("daily-limit") {
+					limits.DailyUSD = terms.Limits.DailyUSD
+				}
+				if changed("max-per-hour") {
+					limits.Velocity.MaxTxnPerHour = terms.Limits.Velocity.MaxTxnPerHour
+				}
+				req.Limits = &limits
+			}
+			if changed("cosign-threshold") || changed("approver-group") {
+				rule := current.CosignRule
+				if changed("cosign-threshold") {
+					rule.ThresholdUSD = terms.CosignRule.ThresholdUSD
+				}
+				if changed("approver-group") {
+					rule.ApproverGroup = terms.CosignRule.ApproverGroup
+				}
+				req.CosignRule = &rule
+			}
+			if changed("policy-bundle") {
+				req.PolicyBundleVersion = &terms.PolicyBundleVersion
+			}
+			if req.ExpiresAt, err = parseExpiry(updateExpires); err != nil {
+				return err
+			}
+			consent, err := s.client.UpdateConsent(cmd.Context(), current.ID, &req)
+			if err != nil {
+				return err
+			}
+			return printConsent(s, consent)
+		}),
+	}
+	flags = updateCmd.Flags()
+	flags.Float64Var(&terms.Limits.SingleTxnUSD, "single-limit", 0, "largest single payment, in USD")
+	flags.Float64Var(&terms.Limits.DailyUSD, "daily-limit", 0, "most paid per day, in USD")
+	flags.IntVar(&terms.Limits.Velocity.MaxTxnPerHour, "max-per-hour", 0, "most payments per hour")
+	flags.StringSliceVar(&terms.Rails, "rails", nil, "rails allowed, such as ach,card; any when empty")
+	flags.StringSliceVar(&terms.CounterpartiesAllow, "counterparties", nil, "counterparties allowed; any when empty")
+	flags.Float64Var(&terms.CosignRule.ThresholdUSD, "cosign-threshold", 0, "USD amount from which payments need approval")
+	flags.StringVar(&terms.CosignRule.ApproverGroup, "approver-group", "", "group that approves payments above the cosign threshold")
+	flags.StringVar(&terms.PolicyBundleVersion, "policy-bundle", "", "policy bundle version")
+	flags.StringVar(&updateExpires, "expires", "", "when the new version expires, as an RFC3339 time or a duration such as 720h; unchanged when empty")
+
+	versionsCmd := &cobra.Command{
+		Use:   "versions ID",
+		Short: "List every version of a consent, the first version first",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			versions, err := s.client.ConsentVersions(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return s.out.print(versions, []string{"VERSION", "ID", "STATUS", "SINGLE USD", "DAILY USD", "RAILS", "CREATED", "EXPIRES"}, func(i int) []string {
+				c := versions[i]
+				return []string{strconv.Itoa(c.Version), c.ID, c.Status, usd(c.Limits.SingleTxnUSD), usd(c.Limits.DailyUSD), strings.Join(c.Rails, ","), c.CreatedAt, c.ExpiresAt}
+			}, len(versions))
+		}),
+	}
+
+	var renewExpires string
+	renewCmd := &cobra.Command{
+		Use:   "renew ID",
+		Short: "Replace a consent with its next version, extending its expiry",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			expiresAt, err := parseExpiry(renewExpires)
+			if err != nil {
+				return err
+			}
+			consent, err := s.client.RenewConsent(cmd.Context(), args[0], expiresAt)
+			if err != nil {
+				return err
+			}
+			return printConsent(s, consent)
+		}),
+	}
+	renewCmd.Flags().StringVar(&renewExpires, "expires", "", "when the successor expires, as an RFC3339 time or a duration such as 720h; the original term from now when empty")
+
+	cmd.AddCommand(createCmd, getCmd, listCmd, updateCmd, versionsCmd, revokeCmd, renewCmd)
+	return cmd
+}
+
+func printConsent(s *session, consent *client.Consent) error {
+	fields := [][2]string{
+		{"ID", consent.ID},
+		{"Agent", consent.AgentID},
+		{"Owner", consent.OwnerPartyID},
+		{"Single payment", usd(consent.Limits.SingleTxnUSD)},
+		{"Daily", usd(consent.Limits.DailyUSD)},
+		{"Rails", strings.Join(consent.Rails, ", ")},
+		{"Counterparties", strings.Join(consent.CounterpartiesAllow, ", ")},
+		{"Policy bundle", consent.PolicyBundleVersion},
+		{"Status", consent.Status},
+		{"Version", strconv.Itoa(consent.Version)},
+		{"Created", consent.CreatedAt},
+		{"Expires", consent.ExpiresAt},
+		{"Revoked", consent.RevokedAt},
+		{"Revocation reason", consent.RevocationReason},
+		{"Renews", consent.PreviousConsentID},
+		{"Renewed as", consent.SupersededByID},
+	}
+	if consent.Limits.Velocity.MaxTxnPerHour > 0 {
+		fields = append(fields, [2]string{"Per hour", strconv.Itoa(consent.Limits.Velocity.MaxTxnPerHour)})
+	}
+	if consent.CosignRule.ThresholdUSD > 0 {
+		fields = append(fields, [2]string{"Cosign", usd(consent.CosignRule.ThresholdUSD) + " by " + consent.CosignRule.ApproverGroup})
+	}
+	return s.out.fields(consent, fields)
+}
+
+// parseExpiry parses an expiry given as an RFC3339 time or as a duration from
+// now; nil when empty
+func parseExpiry(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return &expiresAt, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("--expires must be an RFC3339 time or a duration, not %q", value)
+	}
+	expiresAt := time.Now().Add(duration)
+	return &expiresAt, nil
+}
+
+func usd(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return money(amount, "USD")
+}
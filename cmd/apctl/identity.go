@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+
+	"github.com/example/agent-payments/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newPartiesCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "parties",
+		Aliases: []string{"party"},
+		Short:   "Create, show and verify parties, the entities agents act for",
+	}
+
+	var create client.CreatePartyRequest
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Register a party",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			party, err := s.client.CreateParty(cmd.Context(), &create)
+			if err != nil {
+				return err
+			}
+			return printParty(s, party)
+		}),
+	}
+	createCmd.Flags().StringVar(&create.Name, "name", "", "legal name")
+	createCmd.Flags().StringVar(&create.Type, "type", "organization", "individual or organization")
+	createCmd.MarkFlagRequired("name")
+
+	getCmd := &cobra.Command{
+		Use:   "get ID",
+		Short: "Show a party",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			party, err := s.client.GetParty(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printParty(s, party)
+		}),
+	}
+
+	kycCmd := &cobra.Command{
+		Use:   "kyc ID",
+		Short: "Show a party's KYC/KYB verification and its submissions",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			status, err := s.client.GetKYCStatus(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if s.out.json {
+				return s.out.fields(status, nil)
+			}
+			if err := s.out.fields(status, [][2]string{
+				{"Party", status.PartyID},
+				{"Kind", status.Kind},
+				{"Status", status.Status},
+				{"Verified", status.VerifiedAt},
+			}); err != nil {
+				return err
+			}
+			submissions := status.Submissions
+			if len(submissions) == 0 {
+				return nil
+			}
+			s.out.section("Submissions")
+			return s.out.print(submissions, []string{"ID", "STATUS", "REASON", "PROVIDER", "CREATED"}, func(i int) []string {
+				sub := submissions[i]
+				return []string{sub.ID, sub.Status, sub.Reason, sub.Provider, sub.CreatedAt}
+			}, len(submissions))
+		}),
+	}
+
+	cmd.AddCommand(createCmd, getCmd, kycCmd)
+	return cmd
+}
+
+func printParty(s *session, party *client.Party) error {
+	return s.out.fields(party, [][2]string{
+		{"ID", party.ID},
+		{"Name", party.Name},
+		{"Type", party.Type},
+		{"KYC", party.KYCStatus},
+		{"Created", party.CreatedAt},
+	})
+}
+
+func newAgentsCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "agents",
+		Aliases: []string{"agent"},
+		Short:   "Create, list, show, suspend, activate and decommission agents",
+	}
+
+	var create client.CreateAgentRequest
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Register an agent for an owner party",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			agent, err := s.client.CreateAgent(cmd.Context(), &create)
+			if err != nil {
+				return err
+			}
+			return printAgent(s, agent)
+		}),
+	}
+	createCmd.Flags().StringVar(&create.DisplayName, "name", "", "display name")
+	createCmd.Flags().StringVar(&create.OwnerPartyID, "owner", "", "owner party ID")
+	createCmd.Flags().StringVar(&create.IdentityMode, "identity-mode", "oauth", "did or oauth")
+	createCmd.MarkFlagRequired("name")
+	createCmd.MarkFlagRequired("owner")
+
+	getCmd := &cobra.Command{
+		Use:   "get ID",
+		Short: "Show an agent",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			agent, err := s.client.GetAgent(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printAgent(s, agent)
+		}),
+	}
+
+	var owner string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List agents, optionally of one owner party",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			agents, err := s.client.ListAgents(cmd.Context(), owner)
+			if err != nil {
+				return err
+			}
+			return s.out.print(agents, []string{"ID", "NAME", "OWNER", "IDENTITY", "STATUS", "CREATED"}, func(i int) []string {
+				a := agents[i]
+				return []string{a.ID, a.DisplayName, a.OwnerPartyID, a.IdentityMode, a.Status, a.CreatedAt}
+			}, len(agents))
+		}),
+	}
+	listCmd.Flags().StringVar(&owner, "owner", "", "owner party ID")
+
+	suspendCmd := newAgentStatusCommand(g, "suspend ID", "Stop an agent's payments until it is activated", (*client.Client).SuspendAgent)
+	activateCmd := newAgentStatusCommand(g, "activate ID", "Let a suspended agent pay again", (*client.Client).ActivateAgent)
+	decommissionCmd := newAgentStatusCommand(g, "decommission ID", "Retire an agent for good and revoke its credentials", (*client.Client).DecommissionAgent)
+
+	cmd.AddCommand(createCmd, getCmd, listCmd, suspendCmd, activateCmd, decommissionCmd)
+	return cmd
+}
+
+// newAgentStatusCommand builds a command changing an agent's status with
+// change, recording the --reason given
+func newAgentStatusCommand(g *globals, use, short string, change func(*client.Client, context.Context, string, string, ...client.CallOption) (*client.Agent, error)) *cobra.Command {
+	var reason string
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			agent, err := change(s.client, cmd.Context(), args[0], reason)
+			if err != nil {
+				return err
+			}
+			return printAgent(s, agent)
+		}),
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "why the status changes")
+	return cmd
+}
+
+func printAgent(s *session, agent *client.Agent) error {
+	return s.out.fields(agent, [][2]string{
+		{"ID", agent.ID},
+		{"Name", agent.DisplayName},
+		{"Owner", agent.OwnerPartyID},
+		{"Identity", agent.IdentityMode},
+		{"Status", agent.Status},
+		{"Status reason", agent.StatusReason},
+		{"Status changed", agent.StatusChangedAt},
+		{"Created", agent.CreatedAt},
+	})
+}
@@ -0,0 +1,129 @@
+// Command apctl is the operators' command line client of the platform's
+// API. It creates parties, agents and consents, triggers test payments and
+// shows payments' workflow status, printing tables or JSON.
+//
+// Connection settings live in named profiles, one per environment, kept in
+// $APCTL_CONFIG or the user's config directory under apctl/config.json:
+//
+//	apctl config set staging --base-url https://staging.example.com --api-key ...
+//	apctl config use staging
+//	apctl payments create --agent $AGENT --amount 25 --counterparty vendor@example.com --wait
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/example/agent-payments/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// globals are the flags every command takes
+type globals struct {
+	profile string
+	output  string
+	baseURL string
+	apiKey  string
+	token   string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	g := &globals{}
+	root := &cobra.Command{
+		Use:           "apctl",
+		Short:         "Operate the agent payment platform",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVarP(&g.profile, "profile", "p", os.Getenv("APCTL_PROFILE"), "config profile to use, instead of the current one")
+	flags.StringVarP(&g.output, "output", "o", "", "output format: table or json (default from the profile, else table)")
+	flags.StringVar(&g.baseURL, "base-url", os.Getenv("APCTL_BASE_URL"), "gateway URL, overriding the profile's")
+	flags.StringVar(&g.apiKey, "api-key", os.Getenv("APCTL_API_KEY"), "API key, overriding the profile's")
+	flags.StringVar(&g.token, "token", os.Getenv("APCTL_TOKEN"), "access token, overriding the profile's API key")
+
+	root.AddCommand(
+		newConfigCommand(g),
+		newPartiesCommand(g),
+		newAgentsCommand(g),
+		newConsentsCommand(g),
+		newPaymentsCommand(g),
+		newTransactionsCommand(g),
+	)
+	return root
+}
+
+// session is what a command runs with: a client of the selected
+// environment and the output format
+type session struct {
+	client *client.Client
+	out    *printer
+}
+
+// connect resolves the profile and flags into a session
+func (g *globals) connect() (*session, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := g.profile
+	if name == "" {
+		name = cfg.Current
+	}
+	profile, ok := cfg.Profiles[name]
+	if name != "" && !ok {
+		return nil, fmt.Errorf("no profile %q; create it with apctl config set %s --base-url ...", name, name)
+	}
+
+	if g.baseURL != "" {
+		profile.BaseURL = g.baseURL
+	}
+	if g.apiKey != "" {
+		profile.APIKey = g.apiKey
+	}
+	if g.token != "" {
+		profile.Token = g.token
+	}
+	if g.output != "" {
+		profile.Output = g.output
+	}
+	if profile.BaseURL == "" {
+		profile.BaseURL = "http://localhost:8080"
+	}
+	out, err := newPrinter(os.Stdout, profile.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []client.Option{client.WithUserAgent("apctl")}
+	if profile.Token != "" {
+		options = append(options, client.WithToken(profile.Token))
+	} else if profile.APIKey != "" {
+		options = append(options, client.WithAPIKey(profile.APIKey))
+	}
+	return &session{client: client.New(profile.BaseURL, options...), out: out}, nil
+}
+
+// run wraps a command's action with its session
+func (g *globals) run(action func(cmd *cobra.Command, s *session, args []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		s, err := g.connect()
+		if err != nil {
+			return err
+		}
+		return action(cmd, s, args)
+	}
+}
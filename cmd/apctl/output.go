@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes command results as a table or as JSON
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "", "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	}
+	return nil, fmt.Errorf("output must be table or json, not %q", format)
+}
+
+// print writes value as JSON, or as a table with a row per item
+func (p *printer) print(value interface{}, header []string, row func(i int) []string, rows int) error {
+	if p.json {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for i := 0; i < rows; i++ {
+		fmt.Fprintln(tw, strings.Join(row(i), "\t"))
+	}
+	return tw.Flush()
+}
+
+// fields writes value as JSON, or as a table of a field per line
+func (p *printer) fields(value interface{}, fields [][2]string) error {
+	if p.json {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+		}
+	}
+	return tw.Flush()
+}
+
+// section writes a blank line and a heading between parts of a table
+// output; nothing for JSON
+func (p *printer) section(title string) {
+	if !p.json {
+		fmt.Fprintf(p.w, "\n%s\n", title)
+	}
+}
+
+func money(amount float64, currency string) string {
+	if currency == "" {
+		currency = "USD"
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64) + " " + currency
+}
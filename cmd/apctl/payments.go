@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/example/agent-payments/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newPaymentsCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "payments",
+		Aliases: []string{"payment"},
+		Short:   "Trigger payments and follow their workflows",
+	}
+
+	var create client.PaymentRequest
+	var idempotencyKey string
+	var wait bool
+	var timeout time.Duration
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Initiate a payment, such as a test payment in a sandbox",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			var options []client.CallOption
+			if idempotencyKey != "" {
+				options = append(options, client.IdempotencyKey(idempotencyKey))
+			}
+			payment, err := s.client.InitiatePayment(cmd.Context(), &create, options...)
+			if err != nil {
+				return err
+			}
+			if wait {
+				if payment, err = waitForPayment(cmd, s, payment, timeout); err != nil {
+					return err
+				}
+			}
+			return printPayment(s, payment)
+		}),
+	}
+	flags := createCmd.Flags()
+	flags.StringVar(&create.AgentID, "agent", "", "paying agent's ID")
+	flags.Float64Var(&create.Amount, "amount", 0, "amount in the currency")
+	flags.StringVar(&create.Currency, "currency", "USD", "ISO 4217 currency code")
+	flags.StringVar(&create.Counterparty, "counterparty", "", "payee")
+	flags.StringVar(&create.CounterpartyID, "counterparty-id", "", "counterparty entity paid, instead of --counterparty")
+	flags.StringVar(&create.Rail, "rail", "", "rail to pay on; chosen by the router when empty")
+	flags.StringVar(&create.Description, "description", "", "description")
+	flags.StringVar(&create.Category, "category", "", "spending category budgets apply to")
+	flags.StringVar(&create.CallbackURL, "callback-url", "", "URL the final outcome is POSTed to")
+	flags.StringToStringVar(&create.Metadata, "metadata", nil, "values for the description template, as key=value")
+	flags.StringVar(&idempotencyKey, "idempotency-key", "", "key that makes repeating the command safe; generated when empty")
+	flags.BoolVar(&wait, "wait", false, "wait until the payment is final")
+	flags.DurationVar(&timeout, "timeout", 2*time.Minute, "how long --wait waits")
+	createCmd.MarkFlagRequired("agent")
+	createCmd.MarkFlagRequired("amount")
+
+	getCmd := &cobra.Command{
+		Use:     "get ID",
+		Aliases: []string{"status"},
+		Short:   "Show a payment and its workflow steps",
+		Args:    cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			payment, err := s.client.GetPaymentStatus(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printPayment(s, payment)
+		}),
+	}
+
+	var watchTimeout time.Duration
+	watchCmd := &cobra.Command{
+		Use:   "watch ID",
+		Short: "Follow a payment's workflow until it is final",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			payment, err := s.client.GetPaymentStatus(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if payment, err = waitForPayment(cmd, s, payment, watchTimeout); err != nil {
+				return err
+			}
+			return printPayment(s, payment)
+		}),
+	}
+	watchCmd.Flags().DurationVar(&watchTimeout, "timeout", 10*time.Minute, "how long to wait")
+
+	var list client.PaymentListOptions
+	var all bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List payments",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			payments, err := collect(all, s.client.Payments(cmd.Context(), list), func() ([]client.Payment, error) {
+				page, err := s.client.ListPayments(cmd.Context(), list)
+				if err != nil {
+					return nil, err
+				}
+				return page.Items, nil
+			})
+			if err != nil {
+				return err
+			}
+			return s.out.print(payments, []string{"ID", "AGENT", "AMOUNT", "COUNTERPARTY", "RAIL", "STATUS", "CREATED"}, func(i int) []string {
+				p := payments[i]
+				return []string{p.ID, p.AgentID, money(p.Amount, p.Currency), p.Counterparty, p.Rail, p.Status, p.CreatedAt}
+			}, len(payments))
+		}),
+	}
+	listCmd.Flags().StringVar(&list.AgentID, "agent", "", "agent ID")
+	listCmd.Flags().StringVar(&list.Status, "status", "", "workflow status, such as processing")
+	listCmd.Flags().StringVar(&list.Rail, "rail", "", "rail")
+	listCmd.Flags().StringVar(&list.Counterparty, "counterparty", "", "counterparty")
+	listCmd.Flags().StringVar(&list.ConsentID, "consent", "", "consent the payments went ahead under")
+	listCmd.Flags().StringVar(&list.Category, "category", "", "spending category")
+	addListFlags(listCmd, &list.ListOptions, &all)
+
+	var reason string
+	cancelCmd := &cobra.Command{
+		Use:   "cancel ID",
+		Short: "Cancel a payment that is not final yet",
+		Args:  cobra.ExactArgs(1),
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			payment, err := s.client.CancelPayment(cmd.Context(), args[0], reason)
+			if err != nil {
+				return err
+			}
+			return printPayment(s, payment)
+		}),
+	}
+	cancelCmd.Flags().StringVar(&reason, "reason", "", "why the payment is cancelled")
+
+	cmd.AddCommand(createCmd, getCmd, watchCmd, listCmd, cancelCmd)
+	return cmd
+}
+
+// waitForPayment polls a payment until it is final, reporting its steps on
+// stderr as their status changes
+func waitForPayment(cmd *cobra.Command, s *session, payment *client.Payment, timeout time.Duration) (*client.Payment, error) {
+	deadline := time.Now().Add(timeout)
+	reported := map[string]string{} // Status last reported by step
+	for {
+		for _, step := range payment.Steps {
+			if reported[step.Name] != step.Status {
+				reported[step.Name] = step.Status
+				fmt.Fprintf(os.Stderr, "%-25s %-20s %s\n", step.Timestamp, step.Name, step.Status)
+			}
+		}
+		if payment.Final() {
+			return payment, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("payment %s is still %s after %s", payment.ID, payment.Status, timeout)
+		}
+		select {
+		case <-time.After(2 * time.Second):
+		case <-cmd.Context().Done():
+			return nil, errors.New("interrupted")
+		}
+		next, err := s.client.GetPaymentStatus(cmd.Context(), payment.ID)
+		if err != nil {
+			return nil, err
+		}
+		payment = next
+	}
+}
+
+func printPayment(s *session, payment *client.Payment) error {
+	consent := payment.ConsentID
+	if consent != "" && payment.ConsentVersion > 0 {
+		consent += fmt.Sprintf(" (version %d)", payment.ConsentVersion)
+	}
+	amountUSD := ""
+	if payment.Currency != "" && payment.Currency != "USD" {
+		amountUSD = money(payment.AmountUSD, "USD")
+	}
+	fields := [][2]string{
+		{"ID", payment.ID},
+		{"Agent", payment.AgentID},
+		{"Amount", money(payment.Amount, payment.Currency)},
+		{"Amount USD", amountUSD},
+		{"Counterparty", payment.Counterparty},
+		{"Rail", payment.Rail},
+		{"Status", payment.Status},
+		{"Description", payment.Description},
+		{"Category", payment.Category},
+		{"Consent", consent},
+		{"Risk decision", payment.RiskDecisionID},
+		{"Screening", payment.ComplianceScreeningID},
+		{"Created", payment.CreatedAt},
+		{"Updated", payment.UpdatedAt},
+	}
+	if decision := payment.RiskDecision; decision != nil {
+		fields = append(fields, [2]string{"Risk", fmt.Sprintf("%s (score %.2f, threshold %.2f) %s", decision.Decision, decision.Score, decision.Threshold, decision.Reason)})
+	}
+	if check := payment.ConsentCheck; check != nil && !check.Valid {
+		fields = append(fields, [2]string{"Consent check", check.Reason})
+	}
+	if err := s.out.fields(payment, fields); err != nil || s.out.json || len(payment.Steps) == 0 {
+		return err
+	}
+
+	s.out.section("Steps")
+	steps := payment.Steps
+	return s.out.print(steps, []string{"STEP", "STATUS", "AT", "MESSAGE"}, func(i int) []string {
+		step := steps[i]
+		return []string{step.Name, step.Status, step.Timestamp, step.Message}
+	}, len(steps))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"iter"
+
+	"github.com/example/agent-payments/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newTransactionsCommand(g *globals) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "transactions",
+		Aliases: []string{"txns"},
+		Short:   "Inspect ledger transactions",
+	}
+
+	var list client.TransactionListOptions
+	var all bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List ledger transactions",
+		Args:  cobra.NoArgs,
+		RunE: g.run(func(cmd *cobra.Command, s *session, args []string) error {
+			transactions, err := collect(all, s.client.Transactions(cmd.Context(), list), func() ([]client.Transaction, error) {
+				page, err := s.client.ListTransactions(cmd.Context(), list)
+				if err != nil {
+					return nil, err
+				}
+				return page.Items, nil
+			})
+			if err != nil {
+				return err
+			}
+			return s.out.print(transactions, []string{"ID", "AGENT", "REFERENCE", "BOOK", "STATUS", "DESCRIPTION", "CREATED"}, func(i int) []string {
+				t := transactions[i]
+				return []string{t.ID, t.AgentID, t.ReferenceID, t.Book, t.Status, t.Description, t.CreatedAt}
+			}, len(transactions))
+		}),
+	}
+	listCmd.Flags().StringVar(&list.AgentID, "agent", "", "agent ID")
+	listCmd.Flags().StringVar(&list.Status, "status", "", "pending, posted or failed")
+	listCmd.Flags().StringVar(&list.ReferenceID, "reference", "", "reference ID, such as a payment's")
+	listCmd.Flags().StringVar(&list.Book, "book", "", "ledger book")
+	addListFlags(listCmd, &list.ListOptions, &all)
+
+	cmd.AddCommand(listCmd)
+	return cmd
+}
+
+// addListFlags adds the paging flags of list commands
+func addListFlags(cmd *cobra.Command, opts *client.ListOptions, all *bool) {
+	flags := cmd.Flags()
+	flags.IntVar(&opts.Limit, "limit", 0, "items per page, up to 100 (default 20)")
+	flags.IntVar(&opts.Offset, "offset", 0, "items to skip")
+	flags.StringVar(&opts.From, "from", "", "only items created from this RFC3339 time")
+	flags.StringVar(&opts.To, "to", "", "only items created before this RFC3339 time")
+	flags.StringVar(&opts.Sort, "sort", "", "field to sort by")
+	flags.BoolVar(&opts.Ascending, "asc", false, "oldest first")
+	flags.BoolVar(all, "all", false, "fetch every page instead of one")
+}
+
+// collect returns every item of a listing when all is set, else the page
+// fetch returns
+func collect[T any](all bool, items iter.Seq2[T, error], fetch func() ([]T, error)) ([]T, error) {
+	if !all {
+		return fetch()
+	}
+	var collected []T
+	for item, err := range items {
+		if err != nil {
+			return nil, err
+		}
+		collected = append(collected, item)
+	}
+	return collected, nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/api"
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Backend is a downstream service the gateway proxies to
+type Backend struct {
+	Name  string
+	URL   *url.URL
+	proxy *httputil.ReverseProxy
+}
+
+// Route maps a path pattern to a backend. Pattern segments of "*" match any
+// single segment; Prefix routes also match every path below the pattern.
+type Route struct {
+	Pattern string
+	Prefix  bool
+	Backend string
+}
+
+// routes is evaluated in order, so more specific patterns must come first
+var routes = []Route{
+	// Consent service (party spending limits nested under /v1/parties)
+	{Pattern: "/v1/parties/*/spending-limits", Prefix: true, Backend: "consent"},
+	{Pattern: "/v1/parties/*/spending", Backend: "consent"},
+
+	// Identity service
+	{Pattern: "/v1/parties", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/kyc", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/agents", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/api-keys", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/auth", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/federation", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/reputation", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/dids", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/oauth", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/users", Prefix: true, Backend: "identity"},
+	{Pattern: "/v1/roles", Prefix: true, Backend: "identity"},
+
+	// Consent service, except the payments made under a consent
+	{Pattern: "/v1/consents/*/payments", Backend: "orchestration"},
+	{Pattern: "/v1/consents", Prefix: true, Backend: "consent"},
+	{Pattern: "/v1/approvals", Prefix: true, Backend: "consent"},
+
+	// Risk service
+	{Pattern: "/v1/risk", Prefix: true, Backend: "risk"},
+
+	// Router service (execution paths nested under /v1/payments)
+	{Pattern: "/v1/payments/execute", Backend: "router"},
+	{Pattern: "/v1/payments/*/status", Backend: "router"},
+	{Pattern: "/v1/payments/*/reverse", Backend: "router"},
+	{Pattern: "/v1/payments/*/void", Backend: "router"},
+	{Pattern: "/v1/payments/*/refunds", Backend: "router"},
+	{Pattern: "/v1/payments/*/adapter-calls", Backend: "router"},
+	{Pattern: "/v1/refunds", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/routing", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/rails/health", Backend: "router"},
+	{Pattern: "/v1/payment-batches", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/kill-switches", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/admin/eventing", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/admin/outbox", Prefix: true, Backend: "router"},
+	{Pattern: "/v1/admin/migrations", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/admin/dependencies", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/status", Backend: "router"},
+	{Pattern: "/v1/webhooks/rails", Prefix: true, Backend: "router"},
+
+	// Orchestration service
+	{Pattern: "/v1/payments", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/rails", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/payment-links", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/payment-callbacks", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/description-templates", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/workflow-templates", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/payment-schedules", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/budgets", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/statement-tokens", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/statements", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/fee-schedules", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/fee-experiments", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/fees", Prefix: true, Backend: "orchestration"},
+	{Pattern: "/v1/quotes", Prefix: true, Backend: "orchestration"},
+
+	// Ledger service
+	{Pattern: "/v1/accounts", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/transactions", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/balances", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/holds", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/posting-rules", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/books", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/reports", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/fx", Prefix: true, Backend: "ledger"},
+	{Pattern: "/v1/audit", Prefix: true, Backend: "ledger"},
+
+	// Funding service
+	{Pattern: "/v1/funding", Prefix: true, Backend: "funding"},
+	{Pattern: "/v1/funding-sources", Prefix: true, Backend: "funding"},
+	{Pattern: "/v1/counterparty-accounts", Prefix: true, Backend: "funding"},
+	{Pattern: "/v1/counterparties", Prefix: true, Backend: "funding"},
+
+	// GraphQL service
+	{Pattern: "/v1/graphql", Prefix: true, Backend: "graphql"},
+
+	// Compliance service
+	{Pattern: "/v1/compliance", Prefix: true, Backend: "compliance"},
+
+	// Event worker
+	{Pattern: "/v1/admin/dead-letters", Prefix: true, Backend: "worker"},
+}
+
+var backends = map[string]*Backend{}
+
+// publicPaths are proxied without credentials
+var publicPaths = map[string]bool{
+	"/v1/status":                 true,
+	"/v1/oauth/token":            true,
+	"/v1/reputation/issuer":      true,
+	"/v1/reputation/revocations": true,
+	"/v1/reputation/verify":      true,
+}
+
+// publicPrefixes are proxied without credentials; the backend authorizes them
+// by other means, such as payment link and statement tokens, or publishes
+// them, such as DID documents
+var publicPrefixes = []string{
+	"/v1/dids/",
+	"/v1/payment-links/",
+	"/v1/statements/",
+	"/v1/webhooks/",
+}
+
+func isPublicPath(path string) bool {
+	if publicPaths[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("gateway")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	registerBackend("identity", discovery.EnvURL(discovery.Identity))
+	registerBackend("consent", discovery.EnvURL(discovery.Consent))
+	registerBackend("risk", discovery.EnvURL(discovery.Risk))
+	registerBackend("orchestration", discovery.EnvURL(discovery.Orchestration))
+	registerBackend("router", discovery.EnvURL(discovery.Router))
+	registerBackend("ledger", discovery.EnvURL(discovery.Ledger))
+	registerBackend("compliance", discovery.EnvURL(discovery.Compliance))
+	registerBackend("funding", discovery.EnvURL(discovery.Funding))
+	registerBackend("graphql", discovery.EnvURL(discovery.GraphQL))
+	registerBackend("worker", discovery.EnvURL(discovery.Worker))
+
+	// The gateway has no credential store; API keys are validated by the
+	// backends, and tokens of OAuth clients by the identity service
+	authConfig := common.NewAuthConfigFromEnv(nil)
+	if err := authConfig.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	authConfig.Validator = common.NewIntrospectionValidator(backends["identity"].URL.String()+"/v1/oauth/introspect", "gateway", authConfig)
+	if !authConfig.Enabled {
+		common.Warn("AUTH_ENABLED is false - authentication is disabled")
+	}
+
+	r := gin.New()
+	r.Use(
+		common.LoggerMiddleware(),
+		common.CORSMiddleware(),
+		common.RequestIDMiddleware(),
+		common.CorrelationIDMiddleware(),
+		common.TracingMiddleware(),
+		common.MetricsMiddleware(),
+		common.RecoveryMiddleware(),
+	)
+
+	// The gateway's own metrics; each backend serves its own at /metrics
+	r.GET("/metrics", common.MetricsHandler())
+
+	// Merged health endpoint
+	r.GET("/healthz", mergedHealth)
+
+	// OpenAPI document of the whole API
+	r.GET("/openapi.json", openapi.Handler(api.Spec))
+
+	// Everything under /v1 is authenticated and proxied
+	v1 := r.Group("/v1", gatewayAuth(authConfig))
+	v1.Any("/*path", proxyRequest)
+
+	port := common.GetEnv("GATEWAY_PORT", "8080")
+	common.Info("Gateway running on :%s", port)
+	if err := common.NewServer(":"+port, r).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func registerBackend(name, rawURL string) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		log.Fatalf("Invalid URL for %s service: %v", name, err)
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		common.Error("Proxy error for %s service: %v", name, err)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{"success":false,"error":{"code":"BAD_GATEWAY","message":"` + name + ` service unavailable"}}`))
+	}
+
+	backends[name] = &Backend{Name: name, URL: target, proxy: proxy}
+}
+
+// resolveBackend returns the backend for a request path
+func resolveBackend(path string) (*Backend, bool) {
+	for _, route := range routes {
+		if matchRoute(route, path) {
+			backend, ok := backends[route.Backend]
+			return backend, ok
+		}
+	}
+	return nil, false
+}
+
+// matchRoute matches a path against a route pattern segment by segment
+func matchRoute(route Route, path string) bool {
+	patternSegments := strings.Split(strings.Trim(route.Pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(pathSegments) < len(patternSegments) {
+		return false
+	}
+	if !route.Prefix && len(pathSegments) != len(patternSegments) {
+		return false
+	}
+
+	for i, segment := range patternSegments {
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func proxyRequest(c *gin.Context) {
+	backend, ok := resolveBackend(c.Request.URL.Path)
+	if !ok {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "No service handles this path"))
+		return
+	}
+
+	// Forward tracing headers set by the middleware chain
+	c.Request.Header.Set("X-Request-ID", c.GetString("requestID"))
+	c.Request.Header.Set(common.CorrelationIDHeader, common.GetCorrelationID(c))
+	common.InjectTraceHeaders(c.Request.Context(), c.Request.Header)
+	c.Request.Header.Set("X-Forwarded-Host", c.Request.Host)
+
+	backend.proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// gatewayAuth rejects unauthenticated requests at the edge. JWTs are verified
+// locally; API keys are only checked for presence and forwarded so the owning
+// service can resolve them against its credential store.
+func gatewayAuth(cfg *common.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || isPublicPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		_, err := cfg.Authenticate(c.Request)
+		if err == nil || (errors.Is(err, common.ErrInvalidCredentials) && hasAPIKey(c.Request)) {
+			c.Next()
+			return
+		}
+
+		message := "Missing or invalid credentials"
+		if errors.Is(err, common.ErrTokenExpired) {
+			message = "Token expired"
+		}
+		c.JSON(http.StatusUnauthorized, common.NewErrorResponse("UNAUTHORIZED", message))
+		c.Abort()
+	}
+}
+
+func hasAPIKey(r *http.Request) bool {
+	if r.Header.Get("X-API-Key") != "" {
+		return true
+	}
+	authHeader := r.Header.Get("Authorization")
+	return strings.HasPrefix(authHeader, "ApiKey ") || strings.HasPrefix(authHeader, "Bearer ")
+}
+
+type backendHealth struct {
+	Status     string `json:"status"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+func mergedHealth(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	results := make(map[string]backendHealth)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, backend := range backends {
+		wg.Add(1)
+		go func(name string, backend *Backend) {
+			defer wg.Done()
+			health := checkBackend(ctx, backend)
+			mu.Lock()
+			results[name] = health
+			mu.Unlock()
+		}(name, backend)
+	}
+	wg.Wait()
+
+	status := "ok"
+	statusCode := http.StatusOK
+	for _, health := range results {
+		if health.Status != "ok" {
+			status = "degraded"
+			statusCode = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(statusCode, gin.H{
+		"status":   status,
+		"services": results,
+	})
+}
+
+func checkBackend(ctx context.Context, backend *Backend) backendHealth {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, common.BuildURL(backend.URL.String(), "healthz"), nil)
+	if err != nil {
+		return backendHealth{Status: "down", Error: err.Error()}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return backendHealth{Status: "down", LatencyMs: latency, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return backendHealth{Status: "unhealthy", StatusCode: resp.StatusCode, LatencyMs: latency}
+	}
+	return backendHealth{Status: "ok", StatusCode: resp.StatusCode, LatencyMs: latency}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// listedPackage is a package as reported by go list
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Export     string
+	GoFiles    []string
+}
+
+// pkg is a package of the module, type checked from source
+type pkg struct {
+	path  string
+	files []*ast.File
+	info  *types.Info
+	types *types.Package
+	funcs map[string]*ast.FuncDecl // Package-level functions by name
+}
+
+// loader type checks the module's packages from source, importing their
+// dependencies from the export data go list builds
+type loader struct {
+	fset     *token.FileSet
+	root     string
+	module   string
+	listed   map[string]*listedPackage
+	importer types.Importer
+	packages map[string]*pkg
+	comments map[string]map[string]string // Field comments by type, "path.Type"
+}
+
+func newLoader(root string, patterns ...string) (*loader, error) {
+	module, err := modulePath(root)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "-e", "-export", "-deps", "-json=ImportPath,Dir,Export,GoFiles"}, patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = root
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v", err)
+	}
+
+	l := &loader{
+		fset:     token.NewFileSet(),
+		root:     root,
+		module:   module,
+		listed:   map[string]*listedPackage{},
+		packages: map[string]*pkg{},
+		comments: map[string]map[string]string{},
+	}
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for decoder.More() {
+		var p listedPackage
+		if err := decoder.Decode(&p); err != nil {
+			return nil, fmt.Errorf("go list: %v", err)
+		}
+		l.listed[p.ImportPath] = &p
+	}
+	l.importer = importer.ForCompiler(l.fset, "gc", func(path string) (io.ReadCloser, error) {
+		p := l.listed[path]
+		if p == nil || p.Export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(p.Export)
+	})
+	return l, nil
+}
+
+// local reports whether an import path is a package of the module
+func (l *loader) local(path string) bool {
+	return path == l.module || strings.HasPrefix(path, l.module+"/")
+}
+
+// load type checks a package of the module; nil for other packages
+func (l *loader) load(path string) (*pkg, error) {
+	if p, ok := l.packages[path]; ok {
+		return p, nil
+	}
+	listed := l.listed[path]
+	if !l.local(path) || listed == nil {
+		return nil, nil
+	}
+
+	p := &pkg{
+		path: path,
+		info: &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+		},
+		funcs: map[string]*ast.FuncDecl{},
+	}
+	for _, name := range listed.GoFiles {
+		file, err := parser.ParseFile(l.fset, filepath.Join(listed.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		p.files = append(p.files, file)
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				p.funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+	config := types.Config{Importer: l.importer}
+	checked, err := config.Check(path, l.fset, p.files, p.info)
+	if err != nil {
+		return nil, fmt.Errorf("type checking %s: %v", path, err)
+	}
+	p.types = checked
+	l.packages[path] = p
+	return p, nil
+}
+
+// fieldComment returns the comment on a field of a struct type of the
+// module
+func (l *loader) fieldComment(typeName *types.TypeName, field string) string {
+	if typeName == nil || typeName.Pkg() == nil || !l.local(typeName.Pkg().Path()) {
+		return ""
+	}
+	key := typeName.Pkg().Path() + "." + typeName.Name()
+	fields, ok := l.comments[key]
+	if !ok {
+		fields = l.structComments(typeName.Pkg().Path(), typeName.Name())
+		l.comments[key] = fields
+	}
+	return fields[field]
+}
+
+func (l *loader) structComments(path, name string) map[string]string {
+	fields := map[string]string{}
+	p, err := l.load(path)
+	if err != nil || p == nil {
+		return fields
+	}
+	for _, file := range p.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok || spec.Name.Name != name {
+				return true
+			}
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				for _, field := range st.Fields.List {
+					text := field.Doc.Text()
+					if text == "" {
+						text = field.Comment.Text()
+					}
+					for _, ident := range field.Names {
+						fields[ident.Name] = strings.Join(strings.Fields(text), " ")
+					}
+				}
+			}
+			return false
+		})
+	}
+	return fields
+}
+
+// modulePath reads the module path from go.mod
+func modulePath(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+}
+
+// moduleRoot finds the directory of the main module's go.mod
+func moduleRoot() (string, error) {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return "", err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return "", fmt.Errorf("not in a Go module")
+	}
+	return filepath.Dir(gomod), nil
+}
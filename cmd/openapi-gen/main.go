@@ -0,0 +1,329 @@
+// Command openapi-gen generates the OpenAPI documents of the services from
+// their code: each service's routes, the types its handlers bind requests to
+// and respond with, and the binding rules on those types. It writes
+// services/<name>/openapi.json for each service, api/openapi.json, the
+// document of the API the gateway serves, docs/api_examples.md, a runnable
+// request for each of the gateway's endpoints, and docs/sdk_examples.md, a Go
+// SDK call for each, compiled as the examples of pkg/client.
+//
+// Run it from anywhere in the module with `make openapi` or `go generate
+// ./api`.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/example/agent-payments/internal/openapi"
+)
+
+const version = "1.0.0"
+
+// generator builds the documents of the services
+type generator struct {
+	loader  *loader
+	schemas *schemas // Components of the document being built
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("openapi-gen: ")
+
+	root, err := moduleRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	services, err := serviceNames(root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l, err := newLoader(root, "./services/...")
+	if err != nil {
+		log.Fatal(err)
+	}
+	gateway, err := gatewayRoutes(filepath.Join(root, "cmd", "gateway", "main.go"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := &generator{loader: l}
+	platform := newDocument("Agent Payment Platform API", "The platform's API as served by the gateway. Generated by cmd/openapi-gen; do not edit.")
+	for _, service := range services {
+		doc, err := g.service(service)
+		if err != nil {
+			log.Fatalf("%s: %v", service, err)
+		}
+		if err := write(filepath.Join(root, "services", service, "openapi.json"), doc); err != nil {
+			log.Fatal(err)
+		}
+		merge(platform, doc, service, gateway)
+	}
+	if err := write(filepath.Join(root, "api", "openapi.json"), platform); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "docs", "api_examples.md"), samples(platform), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	page, examples, err := sdkSamples(platform)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "docs", "sdk_examples.md"), page, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "client", "endpoint_examples_test.go"), examples, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serviceNames lists the services, the directories under services with a
+// main package
+func serviceNames(root string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "services"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, "services", entry.Name(), "main.go")); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func newDocument(title, description string) *openapi.Document {
+	return &openapi.Document{
+		OpenAPI: "3.0.3",
+		Info:    openapi.Info{Title: title, Description: description, Version: version},
+		Paths:   map[string]*openapi.PathItem{},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{},
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "An access token from POST /v1/auth/token, or an API key",
+				},
+				"apiKeyAuth": {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "X-API-Key",
+					Description: "A party's API key",
+				},
+			},
+		},
+	}
+}
+
+// service builds the document of a service
+func (g *generator) service(name string) (*openapi.Document, error) {
+	p, err := g.loader.load(g.loader.module + "/services/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("package not found")
+	}
+	g.schemas = newSchemas(g.loader)
+
+	title := strings.ToUpper(name[:1]) + name[1:] + " service API"
+	doc := newDocument(title, "Generated by cmd/openapi-gen; do not edit.")
+	doc.Tags = []openapi.Tag{{Name: name}}
+	operationIDs := map[string]bool{}
+	for _, r := range g.routes(p) {
+		path := openapi.Path(r.path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+		op := g.operation(name, r)
+		// A handler serving several methods has an operation for each
+		if operationIDs[op.OperationID] {
+			op.OperationID += r.method[:1] + strings.ToLower(r.method[1:])
+		}
+		operationIDs[op.OperationID] = true
+		(*item)[strings.ToLower(r.method)] = op
+	}
+	doc.Components.Schemas = g.schemas.components
+	return doc, nil
+}
+
+// merge adds the operations of a service the gateway routes to it to the
+// platform's document
+func merge(platform, doc *openapi.Document, service string, gateway []gatewayRoute) {
+	operationIDs := map[string]bool{}
+	for _, item := range platform.Paths {
+		for _, op := range *item {
+			operationIDs[op.OperationID] = true
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	merged := false
+	for _, path := range paths {
+		if backend(gateway, path) != service {
+			continue
+		}
+		target, ok := platform.Paths[path]
+		if !ok {
+			target = &openapi.PathItem{}
+			platform.Paths[path] = target
+		}
+		item := *doc.Paths[path]
+		methods := make([]string, 0, len(item))
+		for method := range item {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			copied := *item[method]
+			if operationIDs[copied.OperationID] {
+				copied.OperationID = service + strings.ToUpper(copied.OperationID[:1]) + copied.OperationID[1:]
+			}
+			operationIDs[copied.OperationID] = true
+			(*target)[method] = &copied
+			merged = true
+		}
+	}
+	if !merged {
+		return
+	}
+	platform.Tags = append(platform.Tags, openapi.Tag{Name: service})
+	for name, schema := range doc.Components.Schemas {
+		platform.Components.Schemas[name] = schema
+	}
+}
+
+// gatewayRoute is an entry of the gateway's route table
+type gatewayRoute struct {
+	pattern []string
+	prefix  bool
+	backend string
+}
+
+// gatewayRoutes reads the route table from the gateway's source
+func gatewayRoutes(file string) ([]gatewayRoute, error) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var routes []gatewayRoute
+	ast.Inspect(parsed, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "routes" || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			entry, ok := elt.(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+			var r gatewayRoute
+			for _, field := range entry.Elts {
+				kv, ok := field.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				switch key := kv.Key.(*ast.Ident).Name; key {
+				case "Pattern", "Backend":
+					value, err := strconv.Unquote(kv.Value.(*ast.BasicLit).Value)
+					if err != nil {
+						continue
+					}
+					if key == "Pattern" {
+						r.pattern = strings.Split(strings.Trim(value, "/"), "/")
+					} else {
+						r.backend = value
+					}
+				case "Prefix":
+					r.prefix = kv.Value.(*ast.Ident).Name == "true"
+				}
+			}
+			routes = append(routes, r)
+		}
+		return false
+	})
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no routes table in %s", file)
+	}
+	return routes, nil
+}
+
+// backend returns the service the gateway proxies a path to, matching path
+// parameters only to wildcard segments
+func backend(routes []gatewayRoute, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range routes {
+		if len(segments) < len(r.pattern) || !r.prefix && len(segments) != len(r.pattern) {
+			continue
+		}
+		matched := true
+		for i, segment := range r.pattern {
+			if segment != "*" && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r.backend
+		}
+	}
+	return ""
+}
+
+func write(file string, doc *openapi.Document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
+
+// commonPath is the import path of libs/common
+func (g *generator) commonPath() string {
+	return g.loader.module + "/libs/common"
+}
+
+// isCommon reports whether fn is the named function of libs/common
+func (g *generator) isCommon(fn *types.Func, name string) bool {
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == g.commonPath() && fn.Name() == name
+}
+
+// commonType returns a type of libs/common
+func (g *generator) commonType(name string) types.Type {
+	p, err := g.loader.load(g.commonPath())
+	if err != nil || p == nil {
+		log.Fatalf("loading %s: %v", g.commonPath(), err)
+	}
+	return p.types.Scope().Lookup(name).Type()
+}
+
+// errorSchema is the schema of error responses, a common.APIResponse
+func (g *generator) errorSchema() *openapi.Schema {
+	return g.schemas.of(g.commonType("APIResponse"))
+}
@@ -0,0 +1,869 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/example/agent-payments/internal/openapi"
+)
+
+const ginPath = "github.com/gin-gonic/gin"
+
+// route is a route registered in a service's main function
+type route struct {
+	method  string
+	path    string
+	auth    bool     // Behind common.AuthMiddleware
+	scopes  []string // From common.RequireScopes
+	pkg     *pkg
+	handler ast.Expr
+}
+
+// group is a router or route group with its prefix and middleware
+type group struct {
+	prefix string
+	auth   bool
+	scopes []string
+}
+
+var routeMethods = map[string]string{
+	"GET":    http.MethodGet,
+	"POST":   http.MethodPost,
+	"PUT":    http.MethodPut,
+	"PATCH":  http.MethodPatch,
+	"DELETE": http.MethodDelete,
+}
+
+// routes finds the routes a service's main function registers, in order
+func (g *generator) routes(p *pkg) []*route {
+	main := p.funcs["main"]
+	if main == nil {
+		return nil
+	}
+	groups := map[types.Object]*group{}
+	var routes []*route
+
+	groupOf := func(expr ast.Expr) *group {
+		if ident, ok := expr.(*ast.Ident); ok {
+			if grp, ok := groups[p.info.Uses[ident]]; ok {
+				return grp
+			}
+		}
+		if t := p.info.TypeOf(expr); t != nil && isGin(t, "Engine", "RouterGroup") {
+			return &group{}
+		}
+		return nil
+	}
+
+	ast.Inspect(main.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return true
+			}
+			call, ok := n.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Group" || len(call.Args) == 0 {
+				return true
+			}
+			parent := groupOf(sel.X)
+			ident, ok := n.Lhs[0].(*ast.Ident)
+			if parent == nil || !ok {
+				return true
+			}
+			grp := &group{prefix: parent.prefix + stringValue(p, call.Args[0]), auth: parent.auth, scopes: parent.scopes}
+			g.middleware(p, call.Args[1:], &grp.auth, &grp.scopes)
+			obj := p.info.Defs[ident]
+			if obj == nil {
+				obj = p.info.Uses[ident]
+			}
+			groups[obj] = grp
+			return false
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			method, ok := routeMethods[sel.Sel.Name]
+			if !ok || len(n.Args) < 2 {
+				return true
+			}
+			grp := groupOf(sel.X)
+			if grp == nil {
+				return true
+			}
+			r := &route{
+				method:  method,
+				path:    grp.prefix + stringValue(p, n.Args[0]),
+				auth:    grp.auth,
+				scopes:  grp.scopes,
+				pkg:     p,
+				handler: n.Args[len(n.Args)-1],
+			}
+			g.middleware(p, n.Args[1:len(n.Args)-1], &r.auth, &r.scopes)
+			routes = append(routes, r)
+			return false
+		}
+		return true
+	})
+	return routes
+}
+
+// middleware notes the authentication and scopes route middleware requires
+func (g *generator) middleware(p *pkg, args []ast.Expr, auth *bool, scopes *[]string) {
+	for _, arg := range args {
+		call, ok := arg.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		switch fn := calledFunc(p, call); {
+		case g.isCommon(fn, "AuthMiddleware"):
+			*auth = true
+		case g.isCommon(fn, "RequireScopes"):
+			list := append([]string{}, *scopes...)
+			for _, scope := range call.Args {
+				list = append(list, stringValue(p, scope))
+			}
+			*scopes = list
+		}
+	}
+}
+
+// operation describes a route from its handler's code
+func (g *generator) operation(service string, r *route) *openapi.Operation {
+	op := &openapi.Operation{
+		Tags:      []string{service},
+		Responses: map[string]*openapi.Response{},
+	}
+	b := &builder{
+		generator: g,
+		operation: op,
+		method:    r.method,
+		query:     map[string]*openapi.Parameter{},
+		visited:   map[*ast.FuncDecl]bool{},
+	}
+
+	switch handler := r.handler.(type) {
+	case *ast.Ident:
+		if fn, ok := r.pkg.info.Uses[handler].(*types.Func); ok {
+			if decl := r.pkg.funcs[fn.Name()]; decl != nil {
+				op.OperationID = decl.Name.Name
+				op.Description = docText(decl)
+				b.visited[decl] = true
+				b.function(r.pkg, decl.Type, decl.Body, nil)
+			}
+		}
+	case *ast.FuncLit:
+		b.function(r.pkg, handler.Type, handler.Body, nil)
+	case *ast.CallExpr:
+		// A constructor returning the handler, such as config.Handler(cfg)
+		if fn := calledFunc(r.pkg, handler); fn != nil && fn.Pkg() != nil {
+			if p, _ := g.loader.load(fn.Pkg().Path()); p != nil {
+				if decl := p.funcs[fn.Name()]; decl != nil {
+					op.Description = docText(decl)
+					for _, lit := range returnedFuncLits(decl) {
+						b.function(p, lit.Type, lit.Body, nil)
+					}
+				}
+			}
+		}
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationName(r.method, r.path)
+	}
+	op.Summary = summary(op.OperationID)
+
+	for _, segment := range strings.Split(r.path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			op.Parameters = append(op.Parameters, &openapi.Parameter{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+	for _, name := range b.queryOrder {
+		op.Parameters = append(op.Parameters, b.query[name])
+	}
+
+	if r.auth {
+		op.Security = []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}}
+		b.respond("401", g.errorSchema())
+	}
+	if len(r.scopes) > 0 {
+		op.Scopes = r.scopes
+		b.respond("403", g.errorSchema())
+		requirement := "Requires the " + strings.Join(r.scopes, " and ") + " scope."
+		if len(r.scopes) > 1 {
+			requirement = "Requires the " + strings.Join(r.scopes, " and ") + " scopes."
+		}
+		op.Description = strings.TrimSpace(op.Description + " " + requirement)
+	}
+	if op.RequestBody != nil {
+		b.respond("400", g.errorSchema())
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &openapi.Response{Description: "OK"}
+	}
+	return op
+}
+
+// builder collects what an operation's handler and the functions it passes
+// its context to read from the request and respond with
+type builder struct {
+	*generator
+	operation  *openapi.Operation
+	method     string
+	query      map[string]*openapi.Parameter
+	queryOrder []string
+	visited    map[*ast.FuncDecl]bool
+	depth      int
+}
+
+// frame is a function being read, with what is known of its variables
+type frame struct {
+	pkg    *pkg
+	ctx    types.Object                         // The *gin.Context
+	consts map[types.Object]string              // Parameters passed constant strings
+	keys   map[types.Object][]string            // Range keys over map literals
+	query  map[types.Object][]string            // Variables holding query parameters
+	values map[types.Object]ast.Expr            // Values assigned to variables
+	items  map[types.Object]ast.Expr            // Items added to lists
+	fields map[types.Object][]*ast.KeyValueExpr // Keys set on gin.H variables
+
+	readsBody bool // Reads the request body itself
+}
+
+func (b *builder) function(p *pkg, fn *ast.FuncType, body *ast.BlockStmt, consts map[types.Object]string) {
+	if body == nil {
+		return
+	}
+	f := &frame{
+		pkg:    p,
+		consts: consts,
+		keys:   map[types.Object][]string{},
+		query:  map[types.Object][]string{},
+		values: map[types.Object]ast.Expr{},
+		items:  map[types.Object]ast.Expr{},
+		fields: map[types.Object][]*ast.KeyValueExpr{},
+	}
+	for _, field := range fn.Params.List {
+		for _, name := range field.Names {
+			if obj := p.info.Defs[name]; obj != nil && isGinContext(obj.Type()) {
+				f.ctx = obj
+			}
+		}
+	}
+	if f.ctx == nil {
+		return
+	}
+
+	optional := map[*ast.CallExpr]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := n.X.(*ast.CallExpr); ok {
+				optional[call] = true
+			}
+		case *ast.AssignStmt:
+			f.assign(n, optional)
+		case *ast.RangeStmt:
+			if key, ok := n.Key.(*ast.Ident); ok {
+				if lit, ok := n.X.(*ast.CompositeLit); ok {
+					for _, elt := range lit.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok {
+							f.keys[p.info.Defs[key]] = append(f.keys[p.info.Defs[key]], stringValue(p, kv.Key))
+						}
+					}
+				}
+			}
+		case *ast.SwitchStmt:
+			if names := b.queryNames(f, n.Tag); len(names) > 0 {
+				var enum []interface{}
+				for _, stmt := range n.Body.List {
+					for _, value := range stmt.(*ast.CaseClause).List {
+						if s := stringValue(p, value); s != "" {
+							enum = append(enum, s)
+						}
+					}
+				}
+				for _, name := range names {
+					if param := b.param(name); len(enum) > 0 {
+						param.Schema.Enum = enum
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			// c.Request.Body
+			if request, ok := n.X.(*ast.SelectorExpr); ok && n.Sel.Name == "Body" && request.Sel.Name == "Request" && f.isCtx(request.X) {
+				f.readsBody = true
+			}
+		case *ast.CallExpr:
+			b.call(f, n, optional[n])
+		}
+		return true
+	})
+}
+
+// assign notes the values assigned to variables
+func (f *frame) assign(n *ast.AssignStmt, optional map[*ast.CallExpr]bool) {
+	p := f.pkg
+	if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+		if ident, ok := n.Lhs[0].(*ast.Ident); ok && ident.Name == "_" {
+			if call, ok := n.Rhs[0].(*ast.CallExpr); ok {
+				optional[call] = true
+			}
+		}
+	}
+	for i, lhs := range n.Lhs {
+		switch lhs := lhs.(type) {
+		case *ast.Ident:
+			obj := objectOf(p, lhs)
+			if obj == nil || i >= len(n.Rhs) && len(n.Rhs) != 1 {
+				continue
+			}
+			rhs := n.Rhs[0]
+			if len(n.Rhs) == len(n.Lhs) {
+				rhs = n.Rhs[i]
+			}
+			if call, ok := rhs.(*ast.CallExpr); ok {
+				if fun, ok := call.Fun.(*ast.Ident); ok && fun.Name == "append" && len(call.Args) == 2 && call.Ellipsis == token.NoPos {
+					if _, ok := f.items[obj]; !ok {
+						f.items[obj] = call.Args[1]
+					}
+					continue
+				}
+				if names := f.queryCall(call); len(names) > 0 {
+					f.query[obj] = names
+				}
+			}
+			if _, ok := f.values[obj]; !ok && len(n.Rhs) == len(n.Lhs) {
+				f.values[obj] = rhs
+			}
+		case *ast.IndexExpr:
+			if len(n.Rhs) != 1 {
+				continue
+			}
+			var obj types.Object
+			switch x := lhs.X.(type) {
+			case *ast.Ident:
+				obj = objectOf(p, x)
+			case *ast.SelectorExpr:
+				// response.Items[i] = item
+				if ident, ok := x.X.(*ast.Ident); ok && x.Sel.Name == "Items" {
+					obj = objectOf(p, ident)
+				}
+			}
+			if obj == nil {
+				continue
+			}
+			if key := stringValue(p, lhs.Index); key != "" && isMap(p.info.TypeOf(lhs.X)) {
+				f.fields[obj] = append(f.fields[obj], &ast.KeyValueExpr{Key: lhs.Index, Value: n.Rhs[0]})
+			} else if _, ok := f.items[obj]; !ok {
+				f.items[obj] = n.Rhs[0]
+			}
+		}
+	}
+}
+
+// queryCall returns the names of the query parameters a call to the
+// context reads
+func (f *frame) queryCall(call *ast.CallExpr) []string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !f.isCtx(sel.X) || len(call.Args) == 0 {
+		return nil
+	}
+	switch sel.Sel.Name {
+	case "Query", "DefaultQuery", "GetQuery", "QueryArray", "GetQueryArray":
+		return f.strings(call.Args[0])
+	}
+	return nil
+}
+
+// queryNames returns the query parameters an expression holds
+func (b *builder) queryNames(f *frame, expr ast.Expr) []string {
+	switch expr := expr.(type) {
+	case *ast.CallExpr:
+		return f.queryCall(expr)
+	case *ast.Ident:
+		return f.query[objectOf(f.pkg, expr)]
+	}
+	return nil
+}
+
+// call reads a call in a handler: what it binds, reads and responds with,
+// and the functions the context is passed to
+func (b *builder) call(f *frame, call *ast.CallExpr, optional bool) {
+	p := f.pkg
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && f.isCtx(sel.X) {
+		switch sel.Sel.Name {
+		case "ShouldBindJSON", "BindJSON", "ShouldBind", "Bind":
+			if len(call.Args) == 1 {
+				b.requestBody(f, call.Args[0], optional)
+			}
+		case "Query", "DefaultQuery", "GetQuery":
+			for _, name := range f.queryCall(call) {
+				b.param(name)
+			}
+		case "QueryArray", "GetQueryArray":
+			for _, name := range f.queryCall(call) {
+				b.param(name).Schema = &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}
+			}
+		case "JSON", "IndentedJSON", "PureJSON", "AbortWithStatusJSON":
+			if len(call.Args) == 2 {
+				b.respond(statusKey(p, call.Args[0]), b.body(f, call.Args[1]))
+			}
+		case "Data":
+			if len(call.Args) == 3 {
+				contentType := stringValue(p, call.Args[1])
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				b.respondContent(statusKey(p, call.Args[0]), contentType, &openapi.Schema{Type: "string", Format: "binary"})
+			}
+		case "String":
+			if len(call.Args) >= 2 {
+				b.respondContent(statusKey(p, call.Args[0]), "text/plain", &openapi.Schema{Type: "string"})
+			}
+		case "Status", "AbortWithStatus":
+			if len(call.Args) == 1 {
+				key := statusKey(p, call.Args[0])
+				if _, ok := b.operation.Responses[key]; !ok {
+					b.operation.Responses[key] = &openapi.Response{Description: description(key)}
+				}
+			}
+		}
+		return
+	}
+
+	// Parsed query parameters, and bodies read whole and decoded
+	if fn := calledFunc(p, call); fn != nil && fn.Pkg() != nil && len(call.Args) > 0 {
+		var schema *openapi.Schema
+		switch fn.Pkg().Path() + "." + fn.Name() {
+		case "encoding/json.Unmarshal":
+			if f.readsBody && len(call.Args) == 2 {
+				b.requestBody(f, call.Args[1], optional)
+			}
+			return
+		case "strconv.Atoi", "strconv.ParseInt":
+			schema = &openapi.Schema{Type: "integer"}
+		case "strconv.ParseUint":
+			zero := 0.0
+			schema = &openapi.Schema{Type: "integer", Minimum: &zero}
+		case "strconv.ParseFloat":
+			schema = &openapi.Schema{Type: "number"}
+		case "strconv.ParseBool":
+			schema = &openapi.Schema{Type: "boolean"}
+		case "time.Parse", b.commonPath() + ".ParseTime":
+			schema = &openapi.Schema{Type: "string", Format: "date-time"}
+		}
+		if schema != nil {
+			for _, arg := range call.Args {
+				for _, name := range b.queryNames(f, arg) {
+					if param := b.param(name); param.Schema.Enum == nil {
+						param.Schema = schema
+					}
+				}
+			}
+			return
+		}
+	}
+
+	// Functions the context is passed to
+	for i, arg := range call.Args {
+		if f.isCtx(arg) {
+			b.follow(f, call, i)
+		}
+	}
+}
+
+// requestBody notes the type a handler decodes the request body into
+func (b *builder) requestBody(f *frame, target ast.Expr, optional bool) {
+	if b.operation.RequestBody != nil || b.method == http.MethodGet {
+		return
+	}
+	t := f.pkg.info.TypeOf(target)
+	if t == nil {
+		return
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return
+	}
+	b.operation.RequestBody = &openapi.RequestBody{
+		Required: !optional,
+		Content:  map[string]*openapi.MediaType{"application/json": {Schema: b.schemas.of(t)}},
+	}
+}
+
+// follow reads a function of the module a handler passes its context to
+func (b *builder) follow(f *frame, call *ast.CallExpr, index int) {
+	fn := calledFunc(f.pkg, call)
+	if fn == nil || fn.Pkg() == nil || fn.Type().(*types.Signature).Recv() != nil || b.depth >= 6 {
+		return
+	}
+	p, _ := b.loader.load(fn.Pkg().Path())
+	if p == nil {
+		return
+	}
+	decl := p.funcs[fn.Name()]
+	if decl == nil || b.visited[decl] {
+		return
+	}
+	b.visited[decl] = true
+
+	// Constant strings passed to the function, such as query parameter names
+	consts := map[types.Object]string{}
+	i := 0
+	for _, field := range decl.Type.Params.List {
+		for _, name := range field.Names {
+			if i < len(call.Args) && i != index {
+				if values := f.strings(call.Args[i]); len(values) == 1 {
+					consts[p.info.Defs[name]] = values[0]
+				}
+			}
+			i++
+		}
+	}
+	b.depth++
+	b.function(p, decl.Type, decl.Body, consts)
+	b.depth--
+}
+
+// param returns a query parameter, adding it if new
+func (b *builder) param(name string) *openapi.Parameter {
+	param, ok := b.query[name]
+	if !ok {
+		param = &openapi.Parameter{Name: name, In: "query", Schema: &openapi.Schema{Type: "string"}}
+		b.query[name] = param
+		b.queryOrder = append(b.queryOrder, name)
+	}
+	return param
+}
+
+// respond adds a JSON response; the first response for a status is kept
+func (b *builder) respond(status string, schema *openapi.Schema) {
+	b.respondContent(status, "application/json", schema)
+}
+
+func (b *builder) respondContent(status, contentType string, schema *openapi.Schema) {
+	if response, ok := b.operation.Responses[status]; ok && response.Content != nil {
+		return
+	}
+	b.operation.Responses[status] = &openapi.Response{
+		Description: description(status),
+		Content:     map[string]*openapi.MediaType{contentType: {Schema: schema}},
+	}
+}
+
+// body is the schema of a response body
+func (b *builder) body(f *frame, expr ast.Expr) *openapi.Schema {
+	p := f.pkg
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.CallExpr:
+		fn := calledFunc(p, e)
+		if b.isCommon(fn, "NewSuccessResponse") && len(e.Args) == 1 {
+			return &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"success": {Type: "boolean"},
+					"data":    b.value(f, e.Args[0]),
+				},
+				Required: []string{"success"},
+			}
+		}
+	case *ast.Ident:
+		if value, ok := f.values[objectOf(p, e)]; ok && isAPIResponse(p.info.TypeOf(e)) {
+			return b.body(f, value)
+		}
+	}
+	if isAPIResponse(p.info.TypeOf(expr)) {
+		return b.errorSchema()
+	}
+	return b.value(f, expr)
+}
+
+// value is the schema of a value in a response
+func (b *builder) value(f *frame, expr ast.Expr) *openapi.Schema {
+	p := f.pkg
+	expr = ast.Unparen(expr)
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		if isMap(p.info.TypeOf(e)) {
+			return b.object(f, e.Elts, nil)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return b.value(f, e.X)
+		}
+	case *ast.CallExpr:
+		fn := calledFunc(p, e)
+		if (b.isCommon(fn, "NewListResponse") || b.isCommon(fn, "NewPageResponse")) && len(e.Args) > 0 {
+			var item ast.Expr
+			if ident, ok := e.Args[0].(*ast.Ident); ok {
+				item = f.items[objectOf(p, ident)]
+			}
+			return b.list(f, item)
+		}
+	case *ast.Ident:
+		obj := objectOf(p, e)
+		t := p.info.TypeOf(e)
+		if isListResponse(t) {
+			if item, ok := f.items[obj]; ok {
+				return b.list(f, item)
+			}
+			if value, ok := f.values[obj]; ok {
+				return b.value(f, value)
+			}
+		}
+		if isMap(t) {
+			if lit, ok := f.values[obj].(*ast.CompositeLit); ok {
+				return b.object(f, lit.Elts, f.fields[obj])
+			}
+		}
+		if t != nil {
+			if _, ok := t.Underlying().(*types.Interface); ok {
+				if value, ok := f.values[obj]; ok {
+					return b.value(f, value)
+				}
+			}
+		}
+	}
+	if t := p.info.TypeOf(expr); t != nil {
+		return b.schemas.of(t)
+	}
+	return &openapi.Schema{}
+}
+
+// object is the schema of a gin.H literal and the keys later set on it
+func (b *builder) object(f *frame, elts []ast.Expr, fields []*ast.KeyValueExpr) *openapi.Schema {
+	schema := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{}}
+	for _, elt := range elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key := stringValue(f.pkg, kv.Key); key != "" {
+				schema.Properties[key] = b.value(f, kv.Value)
+			}
+		}
+	}
+	for _, kv := range fields {
+		key := stringValue(f.pkg, kv.Key)
+		if _, ok := schema.Properties[key]; !ok {
+			schema.Properties[key] = b.value(f, kv.Value)
+		}
+	}
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+	return schema
+}
+
+// list is the schema of a common.ListResponse of items like item
+func (b *builder) list(f *frame, item ast.Expr) *openapi.Schema {
+	items := &openapi.Schema{}
+	if item != nil {
+		items = b.value(f, item)
+	}
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"items": {Type: "array", Items: items},
+			"meta":  b.schemas.of(b.commonType("Meta")),
+		},
+		Required: []string{"items", "meta"},
+	}
+}
+
+func (f *frame) isCtx(expr ast.Expr) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && f.ctx != nil && f.pkg.info.Uses[ident] == f.ctx
+}
+
+// strings returns the constant strings an expression may hold
+func (f *frame) strings(expr ast.Expr) []string {
+	if s := stringValue(f.pkg, expr); s != "" {
+		return []string{s}
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		obj := f.pkg.info.Uses[ident]
+		if s, ok := f.consts[obj]; ok {
+			return []string{s}
+		}
+		return f.keys[obj]
+	}
+	return nil
+}
+
+func objectOf(p *pkg, ident *ast.Ident) types.Object {
+	if obj := p.info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return p.info.Uses[ident]
+}
+
+// calledFunc returns the function a call calls; nil for builtins,
+// conversions and function values
+func calledFunc(p *pkg, call *ast.CallExpr) *types.Func {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := p.info.Uses[ident].(*types.Func)
+	return fn
+}
+
+// returnedFuncLits returns the function literals a function returns
+func returnedFuncLits(decl *ast.FuncDecl) []*ast.FuncLit {
+	var lits []*ast.FuncLit
+	if decl.Body == nil {
+		return nil
+	}
+	for _, stmt := range decl.Body.List {
+		if ret, ok := stmt.(*ast.ReturnStmt); ok {
+			for _, result := range ret.Results {
+				if lit, ok := result.(*ast.FuncLit); ok {
+					lits = append(lits, lit)
+				}
+			}
+		}
+	}
+	return lits
+}
+
+func stringValue(p *pkg, expr ast.Expr) string {
+	if tv, ok := p.info.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return constant.StringVal(tv.Value)
+	}
+	return ""
+}
+
+// statusKey is the response key of a status code: the code if constant,
+// otherwise "default"
+func statusKey(p *pkg, expr ast.Expr) string {
+	if tv, ok := p.info.Types[expr]; ok && tv.Value != nil {
+		if code, ok := constant.Int64Val(tv.Value); ok {
+			return strconv.FormatInt(code, 10)
+		}
+	}
+	return "default"
+}
+
+func description(status string) string {
+	code, err := strconv.Atoi(status)
+	if err != nil || http.StatusText(code) == "" {
+		return "Error"
+	}
+	return http.StatusText(code)
+}
+
+func isGin(t types.Type, names ...string) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != ginPath {
+		return false
+	}
+	for _, name := range names {
+		if named.Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isGinContext(t types.Type) bool {
+	_, ok := t.(*types.Pointer)
+	return ok && isGin(t, "Context")
+}
+
+func isMap(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Map)
+	return ok
+}
+
+func isCommonType(t types.Type, name string) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && strings.HasSuffix(named.Obj().Pkg().Path(), "/libs/common") && named.Obj().Name() == name
+}
+
+func isAPIResponse(t types.Type) bool {
+	return isCommonType(t, "APIResponse")
+}
+
+func isListResponse(t types.Type) bool {
+	return isCommonType(t, "ListResponse")
+}
+
+// docText is a function's doc comment as one paragraph
+func docText(decl *ast.FuncDecl) string {
+	text := strings.Join(strings.Fields(decl.Doc.Text()), " ")
+	if text != "" && !strings.HasSuffix(text, ".") {
+		text += "."
+	}
+	return text
+}
+
+// operationName names an operation without a named handler after its
+// method and path, such as getHealthz for GET /healthz
+func operationName(method, path string) string {
+	name := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "v1" || strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '.' || r == '_' }) {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return name
+}
+
+// summary turns an operation ID into a summary: createAPIKey becomes
+// "Create API key"
+func summary(operationID string) string {
+	runes := []rune(operationID)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+		acronymEnd := unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i, word := range words {
+		if strings.ToUpper(word) != word {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/agent-payments/internal/openapi"
+)
+
+// samples writes a curl command for each operation of the platform's
+// document, with the required parameters and a request body built from the
+// body's schema. They run as they are against the gateway at $BASE_URL with
+// the API key in $API_KEY and path parameters in upper case variables, such
+// as $ID for {id}.
+func samples(doc *openapi.Document) []byte {
+	var out bytes.Buffer
+	out.WriteString("# API Examples\n\n")
+	out.WriteString("A runnable request for each endpoint of the API the gateway serves.\n")
+	out.WriteString("Generated from `api/openapi.json` by cmd/openapi-gen; do not edit.\n\n")
+	out.WriteString("```bash\nexport BASE_URL=http://localhost:8080\nexport API_KEY=your-api-key\n```\n")
+
+	eachOperation(doc, func(tag string) {
+		fmt.Fprintf(&out, "\n## %s\n", strings.ToUpper(tag[:1])+tag[1:])
+	}, func(method, path string, op *openapi.Operation) {
+		writeSample(&out, doc, method, path, op)
+	})
+	return out.Bytes()
+}
+
+// eachOperation visits the document's operations by tag, in path order
+// within a tag, calling startTag before each tag's first operation
+func eachOperation(doc *openapi.Document, startTag func(tag string), visit func(method, path string, op *openapi.Operation)) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, tag := range doc.Tags {
+		if startTag != nil {
+			startTag(tag.Name)
+		}
+		for _, path := range paths {
+			item := *doc.Paths[path]
+			for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+				op := item[method]
+				if op == nil || len(op.Tags) == 0 || op.Tags[0] != tag.Name {
+					continue
+				}
+				visit(strings.ToUpper(method), path, op)
+			}
+		}
+	}
+}
+
+func writeSample(out *bytes.Buffer, doc *openapi.Document, method, path string, op *openapi.Operation) {
+	title := op.Summary
+	if title == "" {
+		title = op.OperationID
+	}
+	fmt.Fprintf(out, "\n### %s\n\n`%s %s`", title, method, path)
+	if op.Description != "" {
+		fmt.Fprintf(out, " · %s", op.Description)
+	}
+	out.WriteString("\n\n```bash\n")
+
+	target := path
+	var query []string
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			target = strings.ReplaceAll(target, "{"+p.Name+"}", "$"+shellName(p.Name))
+		case "query":
+			if p.Required {
+				query = append(query, p.Name+"="+fmt.Sprint(exampleScalar(doc.Resolve(p.Schema), p.Name)))
+			}
+		}
+	}
+	if len(query) > 0 {
+		target += "?" + strings.Join(query, "&")
+	}
+
+	lines := []string{fmt.Sprintf("curl -sS -X %s \"$BASE_URL%s\"", method, target)}
+	if len(op.Security) > 0 {
+		lines = append(lines, `-H "X-API-Key: $API_KEY"`)
+	}
+	if op.RequestBody != nil {
+		if media := op.RequestBody.Content["application/json"]; media != nil {
+			body, _ := json.MarshalIndent(example(doc, media.Schema, "", 0), "  ", "  ")
+			lines = append(lines, `-H "Content-Type: application/json"`, "-d '"+strings.ReplaceAll(string(body), "'", `'\''`)+"'")
+		}
+	}
+	out.WriteString(strings.Join(lines, " \\\n  "))
+	out.WriteString("\n```\n")
+}
+
+// example builds a value a schema accepts. Objects have their required
+// properties, or their scalar ones when none is required, so that a sample
+// shows what a request must carry without every option.
+func example(doc *openapi.Document, schema *openapi.Schema, name string, depth int) interface{} {
+	schema = doc.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if len(schema.AllOf) > 0 && schema.Type == "" {
+		return example(doc, schema.AllOf[0], name, depth)
+	}
+	switch schema.Type {
+	case "object":
+		value := map[string]interface{}{}
+		if depth > 4 {
+			return value
+		}
+		for property, propertySchema := range schema.Properties {
+			resolved := doc.Resolve(propertySchema)
+			if len(resolved.AllOf) > 0 {
+				resolved = doc.Resolve(resolved.AllOf[0])
+			}
+			scalar := resolved.Type != "object" && resolved.Type != "array" && resolved.Type != ""
+			if contains(schema.Required, property) || len(schema.Required) == 0 && scalar {
+				value[property] = example(doc, propertySchema, property, depth+1)
+			}
+		}
+		return value
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{example(doc, schema.Items, name, depth+1)}
+	}
+	return exampleScalar(schema, name)
+}
+
+func exampleScalar(schema *openapi.Schema, name string) interface{} {
+	if schema == nil {
+		return name
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if schema.Minimum != nil {
+			if schema.ExclusiveMinimum {
+				return *schema.Minimum + 1
+			}
+			return *schema.Minimum
+		}
+		if schema.Type == "number" {
+			return 100.0
+		}
+		return 1
+	case "boolean":
+		return false
+	}
+	switch schema.Format {
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "date-time":
+		return "2030-01-01T00:00:00Z"
+	case "email":
+		return "someone@example.com"
+	case "uri":
+		return "https://example.com/callback"
+	}
+	return name
+}
+
+// shellName is the variable a path parameter is read from, such as
+// PARTY_ID for partyId
+func shellName(param string) string {
+	var name strings.Builder
+	for i, r := range param {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			name.WriteByte('_')
+		}
+		name.WriteRune(r)
+	}
+	return strings.ToUpper(name.String())
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/example/agent-payments/internal/openapi"
+)
+
+// schemas converts Go types to the schemas encoding/json gives them. Named
+// struct types become components, named after their package: the service
+// for a service's own types, otherwise the package name.
+type schemas struct {
+	loader     *loader
+	components map[string]*openapi.Schema
+	names      map[string]string // Component names by qualified type name
+}
+
+func newSchemas(l *loader) *schemas {
+	return &schemas{loader: l, components: map[string]*openapi.Schema{}, names: map[string]string{}}
+}
+
+func (s *schemas) of(t types.Type) *openapi.Schema {
+	switch t := t.(type) {
+	case *types.Named:
+		return s.named(t)
+	case *types.Alias:
+		return s.of(types.Unalias(t))
+	case *types.Pointer:
+		schema := s.of(t.Elem())
+		if schema.Ref != "" {
+			return &openapi.Schema{AllOf: []*openapi.Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	case *types.Basic:
+		return basic(t)
+	case *types.Slice:
+		if b, ok := t.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return &openapi.Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &openapi.Schema{Type: "array", Items: s.of(t.Elem()), Nullable: true}
+	case *types.Array:
+		length := int(t.Len())
+		return &openapi.Schema{Type: "array", Items: s.of(t.Elem()), MinItems: &length, MaxItems: &length}
+	case *types.Map:
+		return &openapi.Schema{Type: "object", AdditionalProperties: &openapi.AdditionalSchema{Allowed: true, Schema: s.of(t.Elem())}, Nullable: true}
+	case *types.Struct:
+		return s.object(t, nil)
+	}
+	// Interfaces hold any value
+	return &openapi.Schema{}
+}
+
+func (s *schemas) named(t *types.Named) *openapi.Schema {
+	obj := t.Obj()
+	if obj.Pkg() != nil {
+		switch obj.Pkg().Path() + "." + obj.Name() {
+		case "time.Time":
+			return &openapi.Schema{Type: "string", Format: "date-time"}
+		case "encoding/json.RawMessage":
+			return &openapi.Schema{}
+		}
+	}
+	// Types that marshal themselves have no schema we can know
+	methods := types.NewMethodSet(types.NewPointer(t))
+	if methods.Lookup(nil, "MarshalJSON") != nil {
+		return &openapi.Schema{}
+	}
+	if methods.Lookup(nil, "MarshalText") != nil {
+		return &openapi.Schema{Type: "string"}
+	}
+
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || obj.Pkg() == nil {
+		return s.of(t.Underlying())
+	}
+	qualified := obj.Pkg().Path() + "." + obj.Name()
+	name, ok := s.names[qualified]
+	if !ok {
+		name = s.componentName(obj)
+		s.names[qualified] = name
+		s.components[name] = &openapi.Schema{} // Placeholder for recursive types
+		s.components[name] = s.object(st, obj)
+	}
+	return &openapi.Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *schemas) componentName(obj *types.TypeName) string {
+	path := strings.TrimPrefix(obj.Pkg().Path(), s.loader.module+"/")
+	prefix := obj.Pkg().Name()
+	if strings.HasPrefix(path, "services/") {
+		prefix = strings.TrimPrefix(path, "services/")
+	}
+	name := prefix + "." + obj.Name()
+	if _, taken := s.components[name]; taken {
+		name = strings.ReplaceAll(path, "/", ".") + "." + obj.Name()
+	}
+	return name
+}
+
+// object is the schema of a struct, with the fields of embedded structs
+// promoted. Unknown properties are not allowed.
+func (s *schemas) object(st *types.Struct, owner *types.TypeName) *openapi.Schema {
+	schema := &openapi.Schema{
+		Type:                 "object",
+		Properties:           map[string]*openapi.Schema{},
+		AdditionalProperties: &openapi.AdditionalSchema{},
+	}
+	s.fields(schema, st, owner, false)
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+	return schema
+}
+
+func (s *schemas) fields(schema *openapi.Schema, st *types.Struct, owner *types.TypeName, embedded bool) {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name, options, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		if field.Embedded() && name == "" {
+			t := field.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				if inner, ok := named.Underlying().(*types.Struct); ok {
+					s.fields(schema, inner, named.Obj(), true)
+					continue
+				}
+			}
+		}
+		if !field.Exported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name()
+		}
+		// Fields of the struct itself hide promoted fields
+		if _, exists := schema.Properties[name]; exists && embedded {
+			continue
+		}
+
+		property := s.of(field.Type())
+		if strings.Contains(","+options+",", ",string,") {
+			property = &openapi.Schema{Type: "string"}
+		}
+		if comment := s.loader.fieldComment(owner, field.Name()); comment != "" && property.Ref == "" {
+			property.Description = comment
+		}
+		if applyBinding(property, tag.Get("binding")) && !contains(schema.Required, name) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+func basic(t *types.Basic) *openapi.Schema {
+	info := t.Info()
+	switch {
+	case info&types.IsBoolean != 0:
+		return &openapi.Schema{Type: "boolean"}
+	case info&types.IsInteger != 0:
+		schema := &openapi.Schema{Type: "integer"}
+		switch t.Kind() {
+		case types.Int32, types.Uint32:
+			schema.Format = "int32"
+		case types.Int64, types.Uint64, types.Int, types.Uint:
+			schema.Format = "int64"
+		}
+		if info&types.IsUnsigned != 0 {
+			zero := 0.0
+			schema.Minimum = &zero
+		}
+		return schema
+	case info&types.IsFloat != 0:
+		if t.Kind() == types.Float32 {
+			return &openapi.Schema{Type: "number", Format: "float"}
+		}
+		return &openapi.Schema{Type: "number", Format: "double"}
+	case info&types.IsString != 0:
+		return &openapi.Schema{Type: "string"}
+	}
+	return &openapi.Schema{}
+}
+
+// applyBinding adds the constraints of a binding tag to a field's schema,
+// reporting whether the field is required. Constraints after dive apply to
+// the items of a list or the values of a map.
+func applyBinding(schema *openapi.Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	required := false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			if target == schema {
+				required = true
+			}
+		case "dive":
+			switch {
+			case target.Items != nil:
+				target = target.Items
+			case target.AdditionalProperties != nil && target.AdditionalProperties.Schema != nil:
+				target = target.AdditionalProperties.Schema
+			default:
+				return required
+			}
+		case "gt", "gte", "lt", "lte", "min", "max", "len":
+			bound(target, key, value)
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				option = strings.Trim(option, "'")
+				if target.Type == "integer" || target.Type == "number" {
+					if n, err := strconv.ParseFloat(option, 64); err == nil {
+						target.Enum = append(target.Enum, n)
+						continue
+					}
+				}
+				target.Enum = append(target.Enum, option)
+			}
+		case "email":
+			target.Format = "email"
+		case "url", "uri":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		}
+	}
+	return required
+}
+
+// bound applies a validator bound: a length for strings, a number of items
+// for lists and a value for numbers
+func bound(schema *openapi.Schema, key, value string) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string", "array":
+		minimum, maximum := &schema.MinLength, &schema.MaxLength
+		if schema.Type == "array" {
+			minimum, maximum = &schema.MinItems, &schema.MaxItems
+		}
+		length := int(n)
+		switch key {
+		case "gt":
+			length++
+			*minimum = &length
+		case "gte", "min":
+			*minimum = &length
+		case "lt":
+			length--
+			*maximum = &length
+		case "lte", "max":
+			*maximum = &length
+		case "len":
+			*minimum, *maximum = &length, &length
+		}
+	case "integer", "number":
+		switch key {
+		case "gt", "gte", "min":
+			schema.Minimum = &n
+			schema.ExclusiveMinimum = key == "gt"
+		case "lt", "lte", "max":
+			schema.Maximum = &n
+			schema.ExclusiveMaximum = key == "lt"
+		case "len":
+			schema.Minimum, schema.Maximum = &n, &n
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
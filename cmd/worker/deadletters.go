@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// maxReplayBatch is the most dead letters replayed in one bulk request
+const maxReplayBatch = 500
+
+// DeadLetterResponse is a dead-lettered message; the payload and headers are
+// only included when one message is fetched
+type DeadLetterResponse struct {
+	ID             string            `json:"id"`
+	Topic          string            `json:"topic"`
+	GroupID        string            `json:"groupId"`
+	Partition      int               `json:"partition"`
+	Offset         int64             `json:"offset"`
+	MessageKey     string            `json:"messageKey,omitempty"`
+	EventID        string            `json:"eventId,omitempty"`
+	EventType      string            `json:"eventType,omitempty"`
+	ErrorMessage   string            `json:"errorMessage"`
+	FailedHandlers []string          `json:"failedHandlers,omitempty"`
+	Attempts       int               `json:"attempts"`
+	DLQTopic       string            `json:"dlqTopic,omitempty"`
+	Status         string            `json:"status"`
+	ReplayCount    int               `json:"replayCount"`
+	LastReplayAt   string            `json:"lastReplayAt,omitempty"`
+	ReplayError    string            `json:"replayError,omitempty"`
+	CreatedAt      string            `json:"createdAt"`
+	Payload        string            `json:"payload,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+}
+
+// ReplayDeadLettersRequest selects dead letters to replay: the listed IDs,
+// or else the oldest pending ones, of a topic if given
+type ReplayDeadLettersRequest struct {
+	IDs   []string `json:"ids"`
+	Topic string   `json:"topic"`
+	Limit int      `json:"limit"` // Pending dead letters to replay without IDs, 100 by default
+}
+
+// ReplayResult is the outcome of replaying one dead letter
+type ReplayResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // replayed, failed or skipped
+	Error  string `json:"error,omitempty"`
+}
+
+// ReplaySummary is the outcome of a bulk replay
+type ReplaySummary struct {
+	Replayed int            `json:"replayed"`
+	Failed   int            `json:"failed"`
+	Skipped  int            `json:"skipped"`
+	Results  []ReplayResult `json:"results"`
+}
+
+func toDeadLetterResponse(record *database.DeadLetterEvent, full bool) *DeadLetterResponse {
+	response := &DeadLetterResponse{
+		ID:             record.ID,
+		Topic:          record.Topic,
+		GroupID:        record.GroupID,
+		Partition:      record.Partition,
+		Offset:         record.Offset,
+		MessageKey:     record.MessageKey,
+		EventID:        record.EventID,
+		EventType:      record.EventType,
+		ErrorMessage:   record.ErrorMessage,
+		FailedHandlers: splitList(record.FailedHandlers),
+		Attempts:       record.Attempts,
+		DLQTopic:       record.DLQTopic,
+		Status:         record.Status,
+		ReplayCount:    record.ReplayCount,
+		ReplayError:    record.ReplayError,
+		CreatedAt:      record.CreatedAt.Format(time.RFC3339),
+	}
+	if record.LastReplayAt != nil {
+		response.LastReplayAt = record.LastReplayAt.Format(time.RFC3339)
+	}
+	if full {
+		response.Payload = record.Payload
+		response.Headers = decodeHeaders(record.Headers)
+	}
+	return response
+}
+
+// listDeadLetters lists dead-lettered messages, newest first, filtered by
+// status and topic
+func listDeadLetters(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != database.DeadLetterPending && status != database.DeadLetterReplayed {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "status must be pending or replayed"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > maxReplayBatch {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "limit must be between 1 and 500"))
+		return
+	}
+
+	records, err := repo.DeadLetterEventRepository().List(status, c.Query("topic"), limit)
+	if err != nil {
+		common.Error("Failed to list dead letters: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list dead letters"))
+		return
+	}
+	items := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		items = append(items, toDeadLetterResponse(record, false))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getDeadLetter(c *gin.Context) {
+	record, err := repo.DeadLetterEventRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Dead letter not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDeadLetterResponse(record, true)))
+}
+
+// replayDeadLetter replays one dead letter through the handlers that failed
+// on it
+func replayDeadLetter(c *gin.Context) {
+	record, err := repo.DeadLetterEventRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Dead letter not found"))
+		return
+	}
+	if record.Status != database.DeadLetterPending {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Dead letter was already replayed"))
+		return
+	}
+	consumer := consumerFor(record)
+	if consumer == nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("NO_CONSUMER", "This worker does not consume "+record.Topic+" as group "+record.GroupID))
+		return
+	}
+
+	if err := consumer.Replay(c.Request.Context(), record); err != nil {
+		common.Warn("Replay of dead letter %s failed: %v", record.ID, err)
+		c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("REPLAY_FAILED", err.Error()))
+		return
+	}
+	common.Info("Dead letter %s (%s) replayed by %s", record.ID, record.EventType, actor(c))
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDeadLetterResponse(record, false)))
+}
+
+// replayDeadLetters replays dead letters in bulk, oldest first, reporting
+// each outcome. Dead letters already replayed, or of a topic this worker
+// does not consume, are skipped.
+func replayDeadLetters(c *gin.Context) {
+	var req ReplayDeadLettersRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+			return
+		}
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+	if req.Limit < 1 || req.Limit > maxReplayBatch || len(req.IDs) > maxReplayBatch {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "at most 500 dead letters can be replayed at once"))
+		return
+	}
+
+	var records []*database.DeadLetterEvent
+	var err error
+	if len(req.IDs) > 0 {
+		records, err = repo.DeadLetterEventRepository().ListByIDs(req.IDs)
+	} else {
+		records, err = repo.DeadLetterEventRepository().ListPending(req.Topic, req.Limit)
+	}
+	if err != nil {
+		common.Error("Failed to list dead letters to replay: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list dead letters"))
+		return
+	}
+
+	summary := &ReplaySummary{Results: []ReplayResult{}}
+	found := make(map[string]bool, len(records))
+	for _, record := range records {
+		found[record.ID] = true
+		result := ReplayResult{ID: record.ID, Status: "replayed"}
+		consumer := consumerFor(record)
+		switch {
+		case record.Status != database.DeadLetterPending:
+			result.Status, result.Error = "skipped", "already replayed"
+		case consumer == nil:
+			result.Status, result.Error = "skipped", "not consumed by this worker"
+		default:
+			if err := consumer.Replay(c.Request.Context(), record); err != nil {
+				result.Status, result.Error = "failed", err.Error()
+			}
+		}
+		summary.add(result)
+	}
+	for _, id := range req.IDs {
+		if !found[id] {
+			summary.add(ReplayResult{ID: id, Status: "skipped", Error: "not found"})
+		}
+	}
+
+	common.Info("%d dead letters replayed, %d failed, %d skipped by %s", summary.Replayed, summary.Failed, summary.Skipped, actor(c))
+	c.JSON(http.StatusOK, common.NewSuccessResponse(summary))
+}
+
+func (s *ReplaySummary) add(result ReplayResult) {
+	switch result.Status {
+	case "replayed":
+		s.Replayed++
+	case "failed":
+		s.Failed++
+	default:
+		s.Skipped++
+	}
+	s.Results = append(s.Results, result)
+}
+
+// consumerFor finds the worker's consumer of a dead letter's topic and group
+func consumerFor(record *database.DeadLetterEvent) *events.EventConsumer {
+	for _, consumer := range consumers {
+		if consumer.Topic() == record.Topic && consumer.GroupID() == record.GroupID {
+			return consumer
+		}
+	}
+	return nil
+}
+
+// decodeHeaders reads the message headers stored with a dead letter
+func decodeHeaders(encoded string) map[string]string {
+	var headers map[string]string
+	if encoded != "" {
+		json.Unmarshal([]byte(encoded), &headers)
+	}
+	return headers
+}
+
+func actor(c *gin.Context) string {
+	if principal := common.GetPrincipal(c); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// The worker consumes the platform's events from the event bus (EVENT_BUS,
+// kafka or nats) and runs their handlers: one consumer per topic in
+// WORKER_TOPICS (KAFKA_TOPIC by default), all in the consumer group
+// WORKER_GROUP_ID, each running the handlers named in WORKER_HANDLERS. Unless
+// WORKER_OUTBOX_RELAY is false it also relays the outbox to the bus every
+// OUTBOX_RELAY_INTERVAL_MS. On shutdown the consumers stop reading, handle
+// the events still held for reordering, and close.
+
+var repo database.Repository
+var authConfig *common.AuthConfig
+var consumers []*events.EventConsumer
+
+// handlerFactories build the handlers WORKER_HANDLERS can name
+var handlerFactories = map[string]func(database.Repository) events.EventHandler{
+	"payment": func(repo database.Repository) events.EventHandler { return events.NewPaymentEventHandler(repo) },
+	"ledger":  func(repo database.Repository) events.EventHandler { return events.NewLedgerEventHandler(repo) },
+}
+
+// ConsumerStatus is what one of the worker's consumers has done
+type ConsumerStatus struct {
+	Topic    string                  `json:"topic"`
+	GroupID  string                  `json:"groupId"`
+	Handlers []events.HandlerMetrics `json:"handlers"`
+	Ordering events.OrderingStats    `json:"ordering"`
+}
+
+func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing(discovery.Worker)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load(discovery.Worker)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.Connection())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Run migrations
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Initialize repository
+	repo = database.NewRepository(db)
+
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// The memory bus only reaches consumers in the publishing process
+	if cfg.EventBus.Backend == events.BusMemory {
+		log.Fatal("The worker cannot use EVENT_BUS=memory; the orchestration service consumes events itself on the memory bus")
+	}
+	bus, err := events.NewMessageBus(cfg.MessageBus())
+	if err != nil {
+		log.Fatalf("Failed to connect to the event bus: %v", err)
+	}
+
+	topics := splitList(common.GetEnv("WORKER_TOPICS", cfg.Kafka.Topic))
+	groupID := common.GetEnv("WORKER_GROUP_ID", "agent-payments")
+	handlerNames := splitList(common.GetEnv("WORKER_HANDLERS", "payment,ledger"))
+	if len(topics) == 0 {
+		log.Fatal("WORKER_TOPICS names no topics")
+	}
+	if len(handlerNames) == 0 {
+		log.Fatal("WORKER_HANDLERS names no handlers")
+	}
+	for _, name := range handlerNames {
+		if _, ok := handlerFactories[name]; !ok {
+			log.Fatalf("Unknown handler in WORKER_HANDLERS: %s", name)
+		}
+	}
+
+	r := gin.Default()
+	server := common.NewServer(cfg.Addr(), r)
+
+	// Start a consumer per topic, stopped once the HTTP server has shut down
+	for _, topic := range topics {
+		consumer := events.NewEventConsumer(repo, bus, topic, groupID)
+		for _, name := range handlerNames {
+			consumer.RegisterHandler(handlerFactories[name](repo))
+		}
+		if err := consumer.Start(server.Context()); err != nil {
+			log.Fatalf("Failed to start the consumer for %s: %v", topic, err)
+		}
+		consumers = append(consumers, consumer)
+		server.OnShutdown("consumer "+topic, func(ctx context.Context) error {
+			return consumer.Stop()
+		})
+	}
+
+	// Relay events saved to the outbox to the bus
+	if common.GetEnvAsBool("WORKER_OUTBOX_RELAY", true) {
+		publisher := events.NewEventPublisher(repo, bus, cfg.Kafka.Topic)
+		interval := time.Duration(common.GetEnvAsInt("OUTBOX_RELAY_INTERVAL_MS", 1000)) * time.Millisecond
+		go publisher.Run(server.Context(), interval)
+	}
+	server.OnShutdown("event bus", func(ctx context.Context) error {
+		return bus.Close()
+	})
+
+	// Setup common middleware
+	common.SetupCommonMiddleware(r, func() error {
+		return repo.HealthCheck()
+	})
+
+	// Health check endpoint
+	r.GET("/healthz", func(c *gin.Context) {
+		if err := repo.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unhealthy", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "worker service ok"})
+	})
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
+	// API v1 routes
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig))
+	{
+		v1.GET("/worker/status", common.RequireScopes(common.ScopeOperations), getWorkerStatus)
+
+		// Dead-lettered events
+		v1.GET("/admin/dead-letters", common.RequireScopes(common.ScopeOperations), listDeadLetters)
+		v1.GET("/admin/dead-letters/:id", common.RequireScopes(common.ScopeOperations), getDeadLetter)
+		v1.POST("/admin/dead-letters/:id/replay", common.RequireScopes(common.ScopeOperations), replayDeadLetter)
+		v1.POST("/admin/dead-letters/replay", common.RequireScopes(common.ScopeOperations), replayDeadLetters)
+	}
+
+	common.Info("Worker consuming %v on %s as group %s with handlers %v", topics, bus.Name(), groupID, handlerNames)
+	log.Printf("Worker service starting on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// getWorkerStatus reports each consumer's handler counters and the
+// out-of-order deliveries it has seen
+func getWorkerStatus(c *gin.Context) {
+	statuses := make([]ConsumerStatus, len(consumers))
+	for i, consumer := range consumers {
+		statuses[i] = ConsumerStatus{
+			Topic:    consumer.Topic(),
+			GroupID:  consumer.GroupID(),
+			Handlers: consumer.HandlerMetrics(),
+			Ordering: consumer.OrderingStats(),
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(statuses))
+}
+
+// splitList splits a comma-separated setting, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
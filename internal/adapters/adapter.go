@@ -0,0 +1,320 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Adapter payment statuses. Settled, failed, cancelled and refunded are terminal.
+const (
+	StatusAuthorized = "authorized"
+	StatusPending    = "pending" // Captured, awaiting settlement
+	StatusSettled    = "settled"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+	StatusRefunded   = "refunded"
+)
+
+// defaultRequiredConfirmations is how many blocks must include or follow a
+// crypto transfer before it is final
+const defaultRequiredConfirmations = 12
+
+var (
+	ErrUnknownRail      = errors.New("no adapter registered for rail")
+	ErrNotFound         = errors.New("payment not found at processor")
+	ErrInvalidState     = errors.New("operation not allowed in current payment state")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnavailable      = errors.New("processor unavailable")
+)
+
+// webhookTolerance rejects webhook signatures further than this from the
+// current time, so a captured webhook cannot be replayed later
+const webhookTolerance = 5 * time.Minute
+
+// Instruction is a payment to be sent over a rail
+type Instruction struct {
+	PaymentID     string // Platform execution ID, used as the idempotency key
+	AgentID       string
+	AmountUSD     float64
+	Counterparty  string
+	Description   string
+	PaymentMethod string // Processor payment method, for rails that charge a stored instrument
+
+	International *international.Details // Beneficiary's bank details, for payments abroad
+}
+
+// Result is a processor's view of a payment after an operation
+type Result struct {
+	ReferenceID string // Processor reference, stored on the execution
+	Status      string
+	Message     string
+	SettlesAt   *time.Time       // Expected settlement for pending payments
+	RefundID    string           // Processor reference of the refund, set by Refund
+	Chain       *ChainSettlement // On-chain progress, set by crypto rails
+}
+
+// WebhookEvent is a status change pushed by a processor
+type WebhookEvent struct {
+	ID          string
+	ReferenceID string
+	Status      string
+	Message     string
+}
+
+// RailAdapter executes payments over one payment rail. Authorize reserves
+// funds, Capture moves them, Cancel voids an uncaptured authorization and
+// Refund returns captured funds.
+type RailAdapter interface {
+	Rail() string
+	Authorize(ctx context.Context, instruction Instruction) (*Result, error)
+	Capture(ctx context.Context, referenceID string) (*Result, error)
+	Cancel(ctx context.Context, referenceID string) (*Result, error)
+	Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error)
+	GetStatus(ctx context.Context, referenceID string) (*Result, error)
+	// ParseWebhook verifies and decodes a callback from the processor
+	ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// Pinger is implemented by adapters that can check their processor is
+// reachable without moving funds
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks an adapter's processor is reachable. Adapters that cannot
+// check are taken to be reachable.
+func Ping(ctx context.Context, adapter RailAdapter) error {
+	if logged, ok := adapter.(*loggedAdapter); ok {
+		adapter = logged.RailAdapter
+	}
+	if pinger, ok := adapter.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// SettlesOnAcceptance reports whether payments on an adapter's rail are
+// settled with finality as soon as the processor accepts them, so their
+// status is final when Authorize returns
+func SettlesOnAcceptance(adapter RailAdapter) bool {
+	if logged, ok := adapter.(*loggedAdapter); ok {
+		adapter = logged.RailAdapter
+	}
+	final, ok := adapter.(interface{ SettlesOnAcceptance() bool })
+	return ok && final.SettlesOnAcceptance()
+}
+
+// IsRetryable reports whether an operation failed because the processor
+// could not be reached or refused to take requests, so that it certainly did
+// not act on the payment and the payment can be sent over another rail.
+// Timeouts are not retryable: the processor may have taken the payment
+// before the caller stopped waiting.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	return false
+}
+
+// IsTerminal reports whether no further status changes are expected
+func IsTerminal(status string) bool {
+	switch status {
+	case StatusSettled, StatusFailed, StatusCancelled, StatusRefunded:
+		return true
+	default:
+		return false
+	}
+}
+
+// Registry looks up the adapter for a rail
+type Registry struct {
+	adapters map[string]RailAdapter
+}
+
+// NewRegistry creates a registry of adapters keyed by rail
+func NewRegistry(adapters ...RailAdapter) *Registry {
+	registry := &Registry{adapters: make(map[string]RailAdapter)}
+	for _, adapter := range adapters {
+		registry.Register(adapter)
+	}
+	return registry
+}
+
+// Register adds an adapter, replacing any existing adapter for its rail
+func (r *Registry) Register(adapter RailAdapter) {
+	r.adapters[adapter.Rail()] = adapter
+}
+
+// Get returns the adapter for a rail
+func (r *Registry) Get(rail string) (RailAdapter, error) {
+	adapter, ok := r.adapters[rail]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRail, rail)
+	}
+	return adapter, nil
+}
+
+// Rails lists the rails with a registered adapter
+func (r *Registry) Rails() []string {
+	rails := make([]string, 0, len(r.adapters))
+	for rail := range r.adapters {
+		rails = append(rails, rail)
+	}
+	sort.Strings(rails)
+	return rails
+}
+
+// NewDefaultRegistry registers the mock adapters for every rail. The RTP
+// adapter exchanges its ISO 20022 messages with an in-process mock network,
+// and the USDC adapter pays on a mock chain.
+func NewDefaultRegistry(webhookSecret string) *Registry {
+	return NewRegistry(
+		NewMockACHAdapter(webhookSecret),
+		NewMockWireAdapter(webhookSecret),
+		NewMockCardAdapter(webhookSecret),
+		NewMockInstantAdapter(webhookSecret),
+		NewMockRTPAdapter(webhookSecret),
+		NewMockUSDCAdapter(defaultRequiredConfirmations),
+	)
+}
+
+// NewRegistryFromEnv starts from the mock adapters and replaces the card
+// adapter with Stripe when CARD_ADAPTER=stripe, the RTP adapter's mock
+// network with the network at RTP_NETWORK_URL when it is set, and the USDC
+// adapter's mock chain with the node at USDC_RPC_URL when it is set. The
+// mock adapters of the comma-separated MOCK_UNAVAILABLE_RAILS simulate a
+// processor outage.
+func NewRegistryFromEnv() (*Registry, error) {
+	webhookSecret := common.GetEnv("ADAPTER_WEBHOOK_SECRET", "")
+	if webhookSecret == "" {
+		common.Warn("ADAPTER_WEBHOOK_SECRET is not set: webhooks from the mock rails will be rejected")
+	}
+	registry := NewDefaultRegistry(webhookSecret)
+	for _, rail := range strings.Split(common.GetEnv("MOCK_UNAVAILABLE_RAILS", ""), ",") {
+		if mock, ok := registry.adapters[strings.TrimSpace(rail)].(*MockAdapter); ok {
+			mock.SetUnavailable(true)
+		}
+	}
+
+	switch cardAdapter := common.GetEnv("CARD_ADAPTER", "mock"); cardAdapter {
+	case "mock":
+	case "stripe":
+		secretKey := common.GetEnv("STRIPE_SECRET_KEY", "")
+		stripeWebhookSecret := common.GetEnv("STRIPE_WEBHOOK_SECRET", "")
+		if secretKey == "" || stripeWebhookSecret == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required for the Stripe card adapter")
+		}
+		registry.Register(NewStripeAdapter(secretKey, stripeWebhookSecret, common.GetEnv("STRIPE_DEFAULT_PAYMENT_METHOD", "")))
+	default:
+		return nil, fmt.Errorf("unknown card adapter: %s", cardAdapter)
+	}
+
+	if networkURL := common.GetEnv("RTP_NETWORK_URL", ""); networkURL != "" {
+		debtor := CreditTransferParties{
+			Network:  common.GetEnv("RTP_NETWORK", "RTP"),
+			MemberID: common.GetEnv("RTP_MEMBER_ID", ""),
+			Name:     common.GetEnv("RTP_DEBTOR_NAME", "Agent Payments Platform"),
+			Account:  common.GetEnv("RTP_DEBTOR_ACCOUNT", ""),
+		}
+		if debtor.MemberID == "" || debtor.Account == "" {
+			return nil, fmt.Errorf("RTP_MEMBER_ID and RTP_DEBTOR_ACCOUNT are required for the RTP network at %s", networkURL)
+		}
+		registry.Register(NewRTPAdapter(strings.TrimSuffix(networkURL, "/"), debtor, common.GetEnv("RTP_WEBHOOK_SECRET", webhookSecret)))
+	}
+
+	confirmations := common.GetEnvAsInt("USDC_REQUIRED_CONFIRMATIONS", defaultRequiredConfirmations)
+	if rpcURL := common.GetEnv("USDC_RPC_URL", ""); rpcURL != "" {
+		from := common.GetEnv("USDC_FROM_ADDRESS", "")
+		if !types.IsWalletAddress(from) {
+			return nil, fmt.Errorf("USDC_FROM_ADDRESS must be the wallet address USDC payments are sent from")
+		}
+		token := USDC
+		token.Contract = common.GetEnv("USDC_TOKEN_CONTRACT", USDC.Contract)
+		chain := NewRPCChain(common.GetEnv("USDC_NETWORK", "ethereum"), rpcURL)
+		registry.Register(NewStablecoinAdapter("usdc", chain, token, from, confirmations, common.GetEnvAsFloat("USDC_USD_RATE", 1)))
+	} else if confirmations != defaultRequiredConfirmations {
+		registry.Register(NewMockUSDCAdapter(confirmations))
+	}
+
+	return registry, nil
+}
+
+// SignWebhook signs body as at now, in the "t=<unix>,v1=<hex hmac>" form
+// that Stripe uses and the other adapters accept too: the HMAC-SHA256 under
+// secret of the timestamp, a dot and the body
+func SignWebhook(secret string, body []byte, now time.Time) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(secret, timestamp, body)
+}
+
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhookSignature checks a "t=<unix>,v1=<hex hmac>" header against
+// the body. Without a secret nothing verifies, so an adapter left without
+// one rejects every webhook instead of trusting them.
+func verifyWebhookSignature(header string, body []byte, secret string, now time.Time) error {
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := webhookMAC(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMockWebhookSignatures(t *testing.T) {
+	body := []byte(`{"id":"evt-1","referenceId":"ref-1","status":"settled"}`)
+	now := time.Now()
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		wantErr   bool
+	}{
+		{"signed now", "whsec", SignWebhook("whsec", body, now), false},
+		{"unsigned", "whsec", "", true},
+		{"other secret", "whsec", SignWebhook("other", body, now), true},
+		{"replayed later", "whsec", SignWebhook("whsec", body, now.Add(-10*time.Minute)), true},
+		{"dated ahead", "whsec", SignWebhook("whsec", body, now.Add(10*time.Minute)), true},
+		{"no secret configured", "", SignWebhook("", body, now), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(MockSignatureHeader, tt.signature)
+			event, err := NewMockACHAdapter(tt.secret).ParseWebhook(header, body)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Fatalf("want ErrInvalidSignature, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.ReferenceID != "ref-1" || event.Status != StatusSettled {
+				t.Fatalf("got %+v", event)
+			}
+		})
+	}
+}
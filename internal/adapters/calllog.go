@@ -0,0 +1,169 @@
+package adapters
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations logged for calls made through an adapter
+const (
+	OperationAuthorize = "authorize"
+	OperationCapture   = "capture"
+	OperationCancel    = "cancel"
+	OperationRefund    = "refund"
+	OperationStatus    = "status"
+)
+
+// Call is one operation performed with a processor through an adapter. Adapters
+// calling an HTTP API fill in the request they sent and the response status.
+type Call struct {
+	ExecutionID string // Set with WithExecution by the caller
+	Rail        string
+	Operation   string
+	Method      string
+	Endpoint    string
+	Request     map[string]string // Redacted request fields
+	StatusCode  int               // Zero if no response was received
+	Reference   string            // Processor reference returned
+	Outcome     string            // Adapter status returned, or "error"
+	Err         error
+	Latency     time.Duration
+}
+
+// CallRecorder stores a call made through a logged adapter. It must not fail
+// the payment, so it reports its own errors.
+type CallRecorder func(ctx context.Context, call *Call)
+
+type (
+	callKey      struct{}
+	executionKey struct{}
+)
+
+// WithExecution tags calls made with the context as being for a payment execution
+func WithExecution(ctx context.Context, executionID string) context.Context {
+	return context.WithValue(ctx, executionKey{}, executionID)
+}
+
+// loggedCall returns the call being logged for a context, or nil when the
+// adapter is not logged
+func loggedCall(ctx context.Context) *Call {
+	call, _ := ctx.Value(callKey{}).(*Call)
+	return call
+}
+
+// sensitiveFields are substrings of request field names whose values are
+// redacted: credentials and payment instruments
+var sensitiveFields = []string{
+	"secret", "password", "token", "authorization", "api_key", "apikey",
+	"payment_method", "paymentmethod", "card", "cvc", "account_number",
+	"accountnumber", "routing", "iban",
+}
+
+// Redact copies request fields, masking the values of sensitive ones. Values
+// long enough keep their last four characters so they can still be matched
+// against the processor's records.
+func Redact(fields map[string]string) map[string]string {
+	redacted := make(map[string]string, len(fields))
+	for name, value := range fields {
+		redacted[name] = value
+		lower := strings.ToLower(name)
+		for _, sensitive := range sensitiveFields {
+			if strings.Contains(lower, sensitive) {
+				redacted[name] = mask(value)
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) < 12 {
+		return "[REDACTED]"
+	}
+	return "[REDACTED]" + value[len(value)-4:]
+}
+
+// loggedAdapter passes every operation of an adapter to a recorder. Webhooks
+// are calls from the processor rather than to it and are not logged.
+type loggedAdapter struct {
+	RailAdapter
+	record CallRecorder
+}
+
+// LogCalls wraps every registered adapter so each call to a processor is
+// passed to record
+func (r *Registry) LogCalls(record CallRecorder) {
+	for rail, adapter := range r.adapters {
+		r.adapters[rail] = &loggedAdapter{RailAdapter: adapter, record: record}
+	}
+}
+
+func (a *loggedAdapter) Authorize(ctx context.Context, instruction Instruction) (*Result, error) {
+	request := map[string]string{
+		"paymentId":     instruction.PaymentID,
+		"agentId":       instruction.AgentID,
+		"amountUSD":     strconv.FormatFloat(instruction.AmountUSD, 'f', 2, 64),
+		"counterparty":  instruction.Counterparty,
+		"paymentMethod": instruction.PaymentMethod,
+	}
+	return a.do(ctx, OperationAuthorize, request, func(ctx context.Context) (*Result, error) {
+		return a.RailAdapter.Authorize(ctx, instruction)
+	})
+}
+
+func (a *loggedAdapter) Capture(ctx context.Context, referenceID string) (*Result, error) {
+	return a.do(ctx, OperationCapture, map[string]string{"referenceId": referenceID}, func(ctx context.Context) (*Result, error) {
+		return a.RailAdapter.Capture(ctx, referenceID)
+	})
+}
+
+func (a *loggedAdapter) Cancel(ctx context.Context, referenceID string) (*Result, error) {
+	return a.do(ctx, OperationCancel, map[string]string{"referenceId": referenceID}, func(ctx context.Context) (*Result, error) {
+		return a.RailAdapter.Cancel(ctx, referenceID)
+	})
+}
+
+func (a *loggedAdapter) Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error) {
+	request := map[string]string{
+		"referenceId": referenceID,
+		"amountUSD":   strconv.FormatFloat(amountUSD, 'f', 2, 64),
+	}
+	return a.do(ctx, OperationRefund, request, func(ctx context.Context) (*Result, error) {
+		return a.RailAdapter.Refund(ctx, referenceID, amountUSD)
+	})
+}
+
+func (a *loggedAdapter) GetStatus(ctx context.Context, referenceID string) (*Result, error) {
+	return a.do(ctx, OperationStatus, map[string]string{"referenceId": referenceID}, func(ctx context.Context) (*Result, error) {
+		return a.RailAdapter.GetStatus(ctx, referenceID)
+	})
+}
+
+// do runs an operation and records it with its outcome and latency
+func (a *loggedAdapter) do(ctx context.Context, operation string, request map[string]string, run func(ctx context.Context) (*Result, error)) (*Result, error) {
+	call := &Call{Rail: a.Rail(), Operation: operation, Request: Redact(request)}
+	call.ExecutionID, _ = ctx.Value(executionKey{}).(string)
+
+	started := time.Now()
+	result, err := run(context.WithValue(ctx, callKey{}, call))
+	call.Latency = time.Since(started)
+
+	switch {
+	case err != nil:
+		call.Outcome, call.Err = "error", err
+	case result != nil:
+		call.Outcome = result.Status
+		call.Reference = result.ReferenceID
+		if result.RefundID != "" {
+			call.Reference = result.RefundID
+		}
+	}
+	a.record(ctx, call)
+	return result, err
+}
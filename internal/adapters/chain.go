@@ -0,0 +1,283 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/types"
+)
+
+// erc20TransferSelector is the function selector of transfer(address,uint256)
+const erc20TransferSelector = "a9059cbb"
+
+// zeroAddress is the address the mock chain reverts transfers to
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// TokenTransfer moves tokens of a contract between two addresses
+type TokenTransfer struct {
+	Contract string   // Token contract address
+	From     string   // Sending address, controlled by the platform
+	To       string   // Receiving wallet address
+	Amount   *big.Int // Amount in the token's base units
+}
+
+// ChainReceipt is a mined transaction's outcome
+type ChainReceipt struct {
+	BlockNumber int64
+	Succeeded   bool // False if the transaction reverted
+}
+
+// ChainSettlement is the on-chain progress of a payment on a crypto rail
+type ChainSettlement struct {
+	Network               string
+	Token                 string
+	FromAddress           string
+	ToAddress             string
+	TokenAmount           float64
+	RateUSD               float64 // USD per token the amount was converted at
+	TxHash                string
+	BlockNumber           *int64 // Set once the transaction is mined
+	Confirmations         int
+	RequiredConfirmations int
+}
+
+// ChainClient submits token transfers to a blockchain and reports on them
+type ChainClient interface {
+	Network() string
+	// SubmitTransfer broadcasts a transfer and returns its transaction hash
+	SubmitTransfer(ctx context.Context, transfer TokenTransfer) (string, error)
+	// Receipt returns a transaction's receipt, or nil while it is not yet mined
+	Receipt(ctx context.Context, txHash string) (*ChainReceipt, error)
+	// BlockNumber returns the latest block
+	BlockNumber(ctx context.Context) (int64, error)
+}
+
+// MockChain simulates a blockchain in memory. A block is produced every
+// block time; transfers are included in the next block, and those to the
+// zero address revert.
+type MockChain struct {
+	network   string
+	blockTime time.Duration
+	genesis   time.Time
+
+	mu           sync.Mutex
+	transactions map[string]*mockChainTransaction
+	nonce        int
+}
+
+type mockChainTransaction struct {
+	block    int64
+	reverted bool
+}
+
+// mockGenesisBlock numbers the mock chain's first block
+const mockGenesisBlock = 1000000
+
+// NewMockChain creates a mock chain producing a block every blockTime
+func NewMockChain(network string, blockTime time.Duration) *MockChain {
+	return &MockChain{
+		network:      network,
+		blockTime:    blockTime,
+		genesis:      time.Now(),
+		transactions: make(map[string]*mockChainTransaction),
+	}
+}
+
+func (c *MockChain) Network() string {
+	return c.network
+}
+
+func (c *MockChain) SubmitTransfer(ctx context.Context, transfer TokenTransfer) (string, error) {
+	if !types.IsWalletAddress(transfer.To) || !types.IsWalletAddress(transfer.From) {
+		return "", fmt.Errorf("mock chain: invalid address")
+	}
+	if transfer.Amount == nil || transfer.Amount.Sign() <= 0 {
+		return "", fmt.Errorf("mock chain: amount must be positive")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonce++
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%s:%d", transfer.Contract, transfer.From, transfer.To, transfer.Amount, c.nonce)))
+	txHash := "0x" + hex.EncodeToString(hash[:])
+	c.transactions[txHash] = &mockChainTransaction{
+		block:    c.head() + 1,
+		reverted: strings.EqualFold(transfer.To, zeroAddress),
+	}
+	return txHash, nil
+}
+
+func (c *MockChain) Receipt(ctx context.Context, txHash string) (*ChainReceipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	transaction, ok := c.transactions[strings.ToLower(txHash)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if c.head() < transaction.block {
+		return nil, nil
+	}
+	return &ChainReceipt{BlockNumber: transaction.block, Succeeded: !transaction.reverted}, nil
+}
+
+func (c *MockChain) BlockNumber(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head(), nil
+}
+
+func (c *MockChain) head() int64 {
+	return mockGenesisBlock + int64(time.Since(c.genesis)/c.blockTime)
+}
+
+// RPCChain talks to an EVM node over JSON-RPC. Transfers are sent with
+// eth_sendTransaction, so the node, or a signer in front of it, must hold
+// the key of the sending address.
+type RPCChain struct {
+	network string
+	url     string
+	client  *http.Client
+
+	mu     sync.Mutex
+	nextID int
+}
+
+// NewRPCChain creates a client of the node at url
+func NewRPCChain(network, url string) *RPCChain {
+	return &RPCChain{network: network, url: url, client: &http.Client{Timeout: 20 * time.Second}}
+}
+
+func (c *RPCChain) Network() string {
+	return c.network
+}
+
+func (c *RPCChain) SubmitTransfer(ctx context.Context, transfer TokenTransfer) (string, error) {
+	data := "0x" + erc20TransferSelector + padWord(strings.TrimPrefix(strings.ToLower(transfer.To), "0x")) + padWord(transfer.Amount.Text(16))
+	var txHash string
+	err := c.call(ctx, "eth_sendTransaction", []interface{}{map[string]string{
+		"from": transfer.From,
+		"to":   transfer.Contract,
+		"data": data,
+	}}, &txHash)
+	if err != nil {
+		return "", err
+	}
+	return txHash, nil
+}
+
+func (c *RPCChain) Receipt(ctx context.Context, txHash string) (*ChainReceipt, error) {
+	var receipt *struct {
+		BlockNumber string `json:"blockNumber"`
+		Status      string `json:"status"`
+	}
+	if err := c.call(ctx, "eth_getTransactionReceipt", []interface{}{txHash}, &receipt); err != nil {
+		return nil, err
+	}
+	if receipt == nil || receipt.BlockNumber == "" {
+		return nil, nil
+	}
+	block, err := parseQuantity(receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainReceipt{BlockNumber: block, Succeeded: receipt.Status == "0x1"}, nil
+}
+
+func (c *RPCChain) BlockNumber(ctx context.Context) (int64, error) {
+	var block string
+	if err := c.call(ctx, "eth_blockNumber", []interface{}{}, &block); err != nil {
+		return 0, err
+	}
+	return parseQuantity(block)
+}
+
+// rpcError is an error returned by the node
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// call sends a JSON-RPC request. A node refusing requests while unavailable
+// is reported as ErrUnavailable, since it did not act on them.
+func (c *RPCChain) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	call := loggedCall(ctx)
+	if call != nil {
+		call.Method, call.Endpoint = "POST", c.url
+		call.Request = map[string]string{"rpc_method": method}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("rpc %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if call != nil {
+		call.StatusCode = resp.StatusCode
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: rpc %s returned %d", ErrUnavailable, method, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc %s: %d %s", method, resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("rpc %s: %w", method, err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	return json.Unmarshal(response.Result, out)
+}
+
+// padWord left-pads a hex value to a 32-byte ABI word
+func padWord(hexValue string) string {
+	if len(hexValue) >= 64 {
+		return hexValue
+	}
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+// parseQuantity decodes a hex-encoded JSON-RPC quantity
+func parseQuantity(quantity string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(quantity, "0x"), 16, 64)
+}
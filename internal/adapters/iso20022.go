@@ -0,0 +1,302 @@
+package adapters
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// ISO 20022 message namespaces the RTP adapter sends and accepts
+const (
+	Pacs008Namespace = "urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08"
+	Pacs002Namespace = "urn:iso:std:iso:20022:tech:xsd:pacs.002.001.10"
+	pacs008Name      = "pacs.008.001.08"
+)
+
+// ISO 20022 transaction statuses reported in pacs.002
+const (
+	TxStatusAcceptedSettlementCompleted = "ACSC"
+	TxStatusAcceptedSettlementInProcess = "ACSP"
+	TxStatusAcceptedTechnicalValidation = "ACTC"
+	TxStatusAcceptedWithoutPosting      = "ACWP"
+	TxStatusPending                     = "PDNG"
+	TxStatusRejected                    = "RJCT"
+)
+
+// ISO 20022 status reason codes the mock network rejects payments with
+const (
+	ReasonInvalidCreditorAccount = "AC03"
+	ReasonClosedAccount          = "AC04"
+	ReasonAmountNotAllowed       = "AM02"
+	ReasonInvalidCurrency        = "AM03"
+)
+
+// maxISOTextLength is the length of ISO 20022 Max35Text identifiers
+const maxISOTextLength = 35
+
+// maxRemittanceLength is the length of unstructured remittance information
+const maxRemittanceLength = 140
+
+// Pacs008 is an FI to FI customer credit transfer carrying one payment
+type Pacs008 struct {
+	XMLName  xml.Name                     `xml:"urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08 Document"`
+	Transfer FIToFICustomerCreditTransfer `xml:"FIToFICstmrCdtTrf"`
+}
+
+type FIToFICustomerCreditTransfer struct {
+	GroupHeader  Pacs008GroupHeader          `xml:"GrpHdr"`
+	Transactions []CreditTransferTransaction `xml:"CdtTrfTxInf"`
+}
+
+type Pacs008GroupHeader struct {
+	MessageID        string         `xml:"MsgId"`
+	CreationDateTime string         `xml:"CreDtTm"`
+	NumberOfTxs      string         `xml:"NbOfTxs"`
+	SettlementInfo   SettlementInfo `xml:"SttlmInf"`
+}
+
+type SettlementInfo struct {
+	Method         string `xml:"SttlmMtd"`     // CLRG: settled by the clearing system
+	ClearingSystem string `xml:"ClrSys>Prtry"` // RTP or FedNow
+}
+
+type CreditTransferTransaction struct {
+	PaymentID       PaymentIdentification `xml:"PmtId"`
+	Amount          ActiveAmount          `xml:"IntrBkSttlmAmt"`
+	SettlementDate  string                `xml:"IntrBkSttlmDt"`
+	ChargeBearer    string                `xml:"ChrgBr"`
+	Debtor          PartyName             `xml:"Dbtr"`
+	DebtorAccount   AccountIdentification `xml:"DbtrAcct"`
+	DebtorAgent     AgentIdentification   `xml:"DbtrAgt"`
+	Creditor        PartyName             `xml:"Cdtr"`
+	CreditorAccount AccountIdentification `xml:"CdtrAcct"`
+	Remittance      *RemittanceInfo       `xml:"RmtInf,omitempty"`
+}
+
+type PaymentIdentification struct {
+	InstructionID string `xml:"InstrId"`
+	EndToEndID    string `xml:"EndToEndId"`
+	TransactionID string `xml:"TxId"`
+}
+
+type ActiveAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type PartyName struct {
+	Name string `xml:"Nm"`
+}
+
+// AccountIdentification identifies an account by number or, for creditors
+// known only by an alias such as an email address, by proxy
+type AccountIdentification struct {
+	Other *GenericIdentification `xml:"Id>Othr,omitempty"`
+	Proxy *GenericIdentification `xml:"Prxy,omitempty"`
+}
+
+type GenericIdentification struct {
+	ID string `xml:"Id"`
+}
+
+// Identifier returns the account's number, or else its proxy
+func (a AccountIdentification) Identifier() string {
+	if a.Other != nil && a.Other.ID != "" {
+		return a.Other.ID
+	}
+	if a.Proxy != nil {
+		return a.Proxy.ID
+	}
+	return ""
+}
+
+type AgentIdentification struct {
+	MemberID string `xml:"FinInstnId>ClrSysMmbId>MmbId"`
+}
+
+type RemittanceInfo struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+// Pacs002 is an FI to FI payment status report answering a pacs.008
+type Pacs002 struct {
+	XMLName xml.Name           `xml:"urn:iso:std:iso:20022:tech:xsd:pacs.002.001.10 Document"`
+	Report  FIToFIStatusReport `xml:"FIToFIPmtStsRpt"`
+}
+
+type FIToFIStatusReport struct {
+	GroupHeader   Pacs002GroupHeader  `xml:"GrpHdr"`
+	OriginalGroup OriginalGroupInfo   `xml:"OrgnlGrpInfAndSts"`
+	Transactions  []TransactionStatus `xml:"TxInfAndSts"`
+}
+
+type Pacs002GroupHeader struct {
+	MessageID        string `xml:"MsgId"`
+	CreationDateTime string `xml:"CreDtTm"`
+}
+
+type OriginalGroupInfo struct {
+	MessageID   string `xml:"OrgnlMsgId"`
+	MessageName string `xml:"OrgnlMsgNmId"`
+}
+
+type TransactionStatus struct {
+	OriginalEndToEndID    string        `xml:"OrgnlEndToEndId"`
+	OriginalTransactionID string        `xml:"OrgnlTxId"`
+	Status                string        `xml:"TxSts"`
+	Reason                *StatusReason `xml:"StsRsnInf,omitempty"`
+	AcceptanceDateTime    string        `xml:"AccptncDtTm,omitempty"`
+	ClearingSystemRef     string        `xml:"ClrSysRef,omitempty"`
+}
+
+type StatusReason struct {
+	Code           string `xml:"Rsn>Cd"`
+	AdditionalInfo string `xml:"AddtlInf,omitempty"`
+}
+
+// CreditTransferParties are the debtor side of credit transfers: the
+// platform's participant in the network and its settlement account
+type CreditTransferParties struct {
+	Network  string // Clearing system, "RTP" or "FedNow"
+	MemberID string // Routing number of the platform's participant
+	Name     string
+	Account  string
+}
+
+// NewPacs008 builds the credit transfer of an instruction. The transaction
+// ID is derived from the payment ID, so a payment sent twice is detected as
+// a duplicate by the network. The creditor is identified by proxy with the
+// instruction's counterparty.
+func NewPacs008(instruction Instruction, debtor CreditTransferParties, now time.Time) *Pacs008 {
+	txID := ISOTransactionID(instruction.PaymentID)
+	transaction := CreditTransferTransaction{
+		PaymentID: PaymentIdentification{
+			InstructionID: txID,
+			EndToEndID:    txID,
+			TransactionID: txID,
+		},
+		Amount:          ActiveAmount{Currency: "USD", Value: strconv.FormatFloat(float64(toCents(instruction.AmountUSD))/100, 'f', 2, 64)},
+		SettlementDate:  now.UTC().Format("2006-01-02"),
+		ChargeBearer:    "SLEV",
+		Debtor:          PartyName{Name: truncateISOText(debtor.Name, 140)},
+		DebtorAccount:   AccountIdentification{Other: &GenericIdentification{ID: debtor.Account}},
+		DebtorAgent:     AgentIdentification{MemberID: debtor.MemberID},
+		Creditor:        PartyName{Name: truncateISOText(instruction.Counterparty, 140)},
+		CreditorAccount: AccountIdentification{Proxy: &GenericIdentification{ID: truncateISOText(instruction.Counterparty, 2048)}},
+	}
+	if instruction.Description != "" {
+		transaction.Remittance = &RemittanceInfo{Unstructured: truncateISOText(instruction.Description, maxRemittanceLength)}
+	}
+
+	return &Pacs008{Transfer: FIToFICustomerCreditTransfer{
+		GroupHeader: Pacs008GroupHeader{
+			MessageID:        "M" + txID,
+			CreationDateTime: now.UTC().Format(time.RFC3339),
+			NumberOfTxs:      "1",
+			SettlementInfo:   SettlementInfo{Method: "CLRG", ClearingSystem: debtor.Network},
+		},
+		Transactions: []CreditTransferTransaction{transaction},
+	}}
+}
+
+// ParsePacs008 decodes a credit transfer, which must carry one payment
+func ParsePacs008(body []byte) (*Pacs008, error) {
+	var message Pacs008
+	if err := xml.Unmarshal(body, &message); err != nil {
+		return nil, fmt.Errorf("invalid pacs.008: %w", err)
+	}
+	if len(message.Transfer.Transactions) != 1 || message.Transfer.GroupHeader.NumberOfTxs != "1" {
+		return nil, fmt.Errorf("invalid pacs.008: carries %d transactions, want 1", len(message.Transfer.Transactions))
+	}
+	return &message, nil
+}
+
+// NewPacs002 builds the status report of a credit transfer's payment.
+// reason is empty unless the payment was rejected.
+func NewPacs002(original *Pacs008, status, reason, info, clearingRef string, now time.Time) *Pacs002 {
+	transaction := original.Transfer.Transactions[0]
+	txStatus := TransactionStatus{
+		OriginalEndToEndID:    transaction.PaymentID.EndToEndID,
+		OriginalTransactionID: transaction.PaymentID.TransactionID,
+		Status:                status,
+		ClearingSystemRef:     clearingRef,
+	}
+	if reason != "" {
+		txStatus.Reason = &StatusReason{Code: reason, AdditionalInfo: truncateISOText(info, 105)}
+	}
+	if status != TxStatusRejected {
+		txStatus.AcceptanceDateTime = now.UTC().Format(time.RFC3339)
+	}
+
+	return &Pacs002{Report: FIToFIStatusReport{
+		GroupHeader: Pacs002GroupHeader{
+			MessageID:        ISOTransactionID(common.GenerateUUID()),
+			CreationDateTime: now.UTC().Format(time.RFC3339),
+		},
+		OriginalGroup: OriginalGroupInfo{
+			MessageID:   original.Transfer.GroupHeader.MessageID,
+			MessageName: pacs008Name,
+		},
+		Transactions: []TransactionStatus{txStatus},
+	}}
+}
+
+// ParsePacs002 decodes a status report, which must report on one payment
+func ParsePacs002(body []byte) (*Pacs002, error) {
+	var message Pacs002
+	if err := xml.Unmarshal(body, &message); err != nil {
+		return nil, fmt.Errorf("invalid pacs.002: %w", err)
+	}
+	if len(message.Report.Transactions) != 1 {
+		return nil, fmt.Errorf("invalid pacs.002: reports %d transactions, want 1", len(message.Report.Transactions))
+	}
+	return &message, nil
+}
+
+// MarshalISO encodes an ISO 20022 message with its XML declaration
+func MarshalISO(message interface{}) ([]byte, error) {
+	body, err := xml.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// StatusResult maps a status report onto an adapter result. Payments the
+// network accepted with settlement completed are settled with finality.
+func StatusResult(report *Pacs002) *Result {
+	transaction := report.Report.Transactions[0]
+	result := &Result{ReferenceID: transaction.OriginalTransactionID}
+	switch transaction.Status {
+	case TxStatusAcceptedSettlementCompleted:
+		result.Status = StatusSettled
+	case TxStatusRejected:
+		result.Status = StatusFailed
+		result.Message = "rejected by the network"
+	default: // ACSP, ACTC, ACWP, PDNG
+		result.Status = StatusPending
+	}
+	if reason := transaction.Reason; reason != nil {
+		result.Message = reason.Code
+		if reason.AdditionalInfo != "" {
+			result.Message += ": " + reason.AdditionalInfo
+		}
+	}
+	return result
+}
+
+// ISOTransactionID derives a Max35Text transaction ID from a payment ID
+func ISOTransactionID(paymentID string) string {
+	return truncateISOText(strings.ReplaceAll(paymentID, "-", ""), maxISOTextLength)
+}
+
+func truncateISOText(value string, length int) string {
+	if runes := []rune(value); len(runes) > length {
+		return string(runes[:length])
+	}
+	return value
+}
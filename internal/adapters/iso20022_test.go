@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/example/agent-payments/internal/golden"
+)
+
+// Run with GOLDEN_UPDATE=1 to rewrite testdata/*.golden after an intended
+// change to the messages
+
+var goldenNow = time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC)
+
+// normalizePacs002MessageID blanks the random message ID of status reports
+var normalizePacs002MessageID = golden.ReplaceAll(`<MsgId>[^<]*</MsgId>`, `<MsgId>MSGID</MsgId>`)
+
+func rtpDebtor() CreditTransferParties {
+	return CreditTransferParties{Network: "RTP", MemberID: "021000021", Name: "Agent Payments Platform", Account: "000123456789"}
+}
+
+func goldenInstruction() Instruction {
+	return Instruction{
+		PaymentID:    "5f2c8a9e-1b3d-4e6f-8a7b-9c0d1e2f3a4b",
+		AgentID:      "agent-1",
+		AmountUSD:    1250.5,
+		Counterparty: "payee@example.com",
+		Description:  "Invoice 2026-0042 & hosting <March>",
+	}
+}
+
+func marshalISO(t *testing.T, message interface{}) []byte {
+	t.Helper()
+	body, err := MarshalISO(message)
+	if err != nil {
+		t.Fatalf("MarshalISO: %v", err)
+	}
+	return body
+}
+
+func TestPacs008Golden(t *testing.T) {
+	tests := []struct {
+		name        string
+		instruction Instruction
+		debtor      CreditTransferParties
+	}{
+		{"pacs008_rtp", goldenInstruction(), rtpDebtor()},
+		{"pacs008_fednow_no_remittance", Instruction{
+			PaymentID:    "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d",
+			AmountUSD:    0.1 + 0.2, // Rounded to cents
+			Counterparty: "+1-555-0100",
+		}, CreditTransferParties{Network: "FedNow", MemberID: "011000015", Name: "Agent Payments Platform", Account: "000987654321"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := marshalISO(t, NewPacs008(tt.instruction, tt.debtor, goldenNow))
+			golden.Assert(t, tt.name, body, golden.ISO20022...)
+
+			// What is sent must be what the network parses
+			parsed, err := ParsePacs008(body)
+			if err != nil {
+				t.Fatalf("ParsePacs008: %v", err)
+			}
+			if got := marshalISO(t, parsed); string(got) != string(body) {
+				t.Fatalf("pacs.008 does not round-trip:\n%s\n%s", body, got)
+			}
+		})
+	}
+}
+
+func TestPacs002Golden(t *testing.T) {
+	original := NewPacs008(goldenInstruction(), rtpDebtor(), goldenNow)
+	tests := []struct {
+		name   string
+		report *Pacs002
+	}{
+		{"pacs002_accepted", NewPacs002(original, TxStatusAcceptedSettlementCompleted, "", "", "RTP20260314000001", goldenNow)},
+		{"pacs002_rejected", NewPacs002(original, TxStatusRejected, ReasonAmountNotAllowed, "Amount exceeds the network limit", "", goldenNow)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := marshalISO(t, tt.report)
+			normalizers := append([]golden.Normalizer{normalizePacs002MessageID}, golden.ISO20022...)
+			golden.Assert(t, tt.name, body, normalizers...)
+
+			if _, err := ParsePacs002(body); err != nil {
+				t.Fatalf("ParsePacs002: %v", err)
+			}
+		})
+	}
+}
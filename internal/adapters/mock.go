@@ -0,0 +1,250 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// MockSignatureHeader carries the SignWebhook signature of mock webhook bodies
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockAdapter simulates a processor in memory. Counterparties, and
+// beneficiaries abroad, containing "decline" are declined at authorization;
+// captures settle after the rail's settlement delay, or immediately when it
+// is zero. Settlement delays are scaled down so simulated payments finish
+// quickly in development.
+type MockAdapter struct {
+	rail            string
+	settlementDelay time.Duration
+	autoCapture     bool // Push rails (ACH, wire, instant) move funds at authorization
+	webhookSecret   string
+
+	mu          sync.Mutex
+	payments    map[string]*mockPayment
+	unavailable bool // Simulates a processor outage
+}
+
+type mockPayment struct {
+	instruction Instruction
+	status      string
+	capturedAt  time.Time
+	refunded    float64
+	refunds     int
+}
+
+// NewMockACHAdapter simulates batched ACH settlement
+func NewMockACHAdapter(webhookSecret string) *MockAdapter {
+	return newMockAdapter("ach", time.Second, true, webhookSecret)
+}
+
+// NewMockWireAdapter simulates wire settlement
+func NewMockWireAdapter(webhookSecret string) *MockAdapter {
+	return newMockAdapter("wire", 500*time.Millisecond, true, webhookSecret)
+}
+
+// NewMockCardAdapter simulates card authorization and capture
+func NewMockCardAdapter(webhookSecret string) *MockAdapter {
+	return newMockAdapter("card", 0, false, webhookSecret)
+}
+
+// NewMockInstantAdapter simulates real-time payments
+func NewMockInstantAdapter(webhookSecret string) *MockAdapter {
+	return newMockAdapter("instant", 0, true, webhookSecret)
+}
+
+func newMockAdapter(rail string, settlementDelay time.Duration, autoCapture bool, webhookSecret string) *MockAdapter {
+	return &MockAdapter{
+		rail:            rail,
+		settlementDelay: settlementDelay,
+		autoCapture:     autoCapture,
+		webhookSecret:   webhookSecret,
+		payments:        make(map[string]*mockPayment),
+	}
+}
+
+func (a *MockAdapter) Rail() string {
+	return a.rail
+}
+
+// SetUnavailable simulates a processor outage: while it lasts, pings and
+// authorizations fail with ErrUnavailable
+func (a *MockAdapter) SetUnavailable(unavailable bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.unavailable = unavailable
+}
+
+func (a *MockAdapter) Ping(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.unavailable {
+		return fmt.Errorf("%w: mock %s processor is down", ErrUnavailable, a.rail)
+	}
+	return nil
+}
+
+func (a *MockAdapter) Authorize(ctx context.Context, instruction Instruction) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.unavailable {
+		return nil, fmt.Errorf("%w: mock %s processor is down", ErrUnavailable, a.rail)
+	}
+
+	// Authorizing the same payment twice returns the original authorization
+	referenceID := "mock_" + a.rail + "_" + instruction.PaymentID
+	if payment, ok := a.payments[referenceID]; ok {
+		return a.result(referenceID, payment), nil
+	}
+
+	payment := &mockPayment{instruction: instruction, status: StatusAuthorized}
+	if strings.Contains(strings.ToLower(instruction.Counterparty), "decline") {
+		payment.status = StatusFailed
+	}
+	if beneficiary := instruction.International; beneficiary != nil && strings.Contains(strings.ToLower(beneficiary.BeneficiaryName), "decline") {
+		payment.status = StatusFailed
+	}
+	a.payments[referenceID] = payment
+
+	if a.autoCapture && payment.status == StatusAuthorized {
+		a.capture(payment)
+	}
+	return a.result(referenceID, payment), nil
+}
+
+func (a *MockAdapter) Capture(ctx context.Context, referenceID string) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	payment, ok := a.payments[referenceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if payment.status == StatusAuthorized {
+		a.capture(payment)
+	} else if payment.status != StatusPending && payment.status != StatusSettled {
+		return nil, ErrInvalidState
+	}
+	return a.result(referenceID, payment), nil
+}
+
+func (a *MockAdapter) Cancel(ctx context.Context, referenceID string) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	payment, ok := a.payments[referenceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if payment.status != StatusAuthorized {
+		return nil, ErrInvalidState
+	}
+	payment.status = StatusCancelled
+	return a.result(referenceID, payment), nil
+}
+
+func (a *MockAdapter) Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	payment, ok := a.payments[referenceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	a.settle(payment)
+	if payment.status != StatusSettled && payment.status != StatusRefunded {
+		return nil, ErrInvalidState
+	}
+	if amountUSD <= 0 || payment.refunded+amountUSD > payment.instruction.AmountUSD+0.005 {
+		return nil, ErrInvalidState
+	}
+
+	payment.refunded += amountUSD
+	payment.refunds++
+	if payment.refunded >= payment.instruction.AmountUSD-0.005 {
+		payment.status = StatusRefunded
+	}
+	result := a.result(referenceID, payment)
+	result.RefundID = fmt.Sprintf("%s_refund_%d", referenceID, payment.refunds)
+	return result, nil
+}
+
+func (a *MockAdapter) GetStatus(ctx context.Context, referenceID string) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	payment, ok := a.payments[referenceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	a.settle(payment)
+	return a.result(referenceID, payment), nil
+}
+
+// ParseWebhook accepts {"id", "referenceId", "status", "message"} bodies
+// signed with the webhook secret in the last five minutes. Without a secret
+// every webhook is rejected.
+func (a *MockAdapter) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifyWebhookSignature(header.Get(MockSignatureHeader), body, a.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		ID          string `json:"id"`
+		ReferenceID string `json:"referenceId"`
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ID == "" {
+		payload.ID = common.GenerateUUID()
+	}
+
+	// Keep the in-memory view consistent with what the processor reported
+	a.mu.Lock()
+	if payment, ok := a.payments[payload.ReferenceID]; ok {
+		payment.status = payload.Status
+	}
+	a.mu.Unlock()
+
+	return &WebhookEvent{
+		ID:          payload.ID,
+		ReferenceID: payload.ReferenceID,
+		Status:      payload.Status,
+		Message:     payload.Message,
+	}, nil
+}
+
+func (a *MockAdapter) capture(payment *mockPayment) {
+	payment.status = StatusPending
+	payment.capturedAt = time.Now()
+	a.settle(payment)
+}
+
+// settle moves pending payments to settled once the settlement delay has passed
+func (a *MockAdapter) settle(payment *mockPayment) {
+	if payment.status == StatusPending && time.Since(payment.capturedAt) >= a.settlementDelay {
+		payment.status = StatusSettled
+	}
+}
+
+func (a *MockAdapter) result(referenceID string, payment *mockPayment) *Result {
+	result := &Result{ReferenceID: referenceID, Status: payment.status}
+	switch payment.status {
+	case StatusFailed:
+		result.Message = "declined by counterparty bank"
+	case StatusPending:
+		settlesAt := payment.capturedAt.Add(a.settlementDelay)
+		result.SettlesAt = &settlesAt
+	}
+	return result
+}
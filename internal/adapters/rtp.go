@@ -0,0 +1,205 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+// RTPSignatureHeader carries the SignWebhook signature of status reports
+// the network pushes
+const RTPSignatureHeader = "X-RTP-Signature"
+
+// rtpNetworkLimit is the largest payment the mock network accepts, the RTP
+// network's limit
+const rtpNetworkLimit = 1000000.00
+
+// RTPAdapter sends real-time payments over an ISO 20022 instant payment
+// network such as RTP or FedNow. Each payment is one pacs.008 credit
+// transfer, answered by a pacs.002 status report; payments accepted with
+// settlement completed are final and cannot be cancelled or refunded, so
+// Authorize returns them settled and Capture has nothing left to do.
+type RTPAdapter struct {
+	networkURL    string
+	debtor        CreditTransferParties
+	webhookSecret string
+	client        *http.Client
+}
+
+// NewRTPAdapter creates an adapter submitting payments to the network at
+// networkURL on behalf of the platform's participant
+func NewRTPAdapter(networkURL string, debtor CreditTransferParties, webhookSecret string) *RTPAdapter {
+	return &RTPAdapter{
+		networkURL:    networkURL,
+		debtor:        debtor,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// NewMockRTPAdapter creates an adapter exchanging messages with an
+// in-process mock network
+func NewMockRTPAdapter(webhookSecret string) *RTPAdapter {
+	adapter := NewRTPAdapter("http://rtp.mock", CreditTransferParties{
+		Network:  "RTP",
+		MemberID: "990000001",
+		Name:     "Agent Payments Platform",
+		Account:  "000123456789",
+	}, webhookSecret)
+	adapter.client = &http.Client{Transport: handlerTransport{NewMockRTPNetwork(rtpNetworkLimit)}}
+	return adapter
+}
+
+func (a *RTPAdapter) Rail() string {
+	return "rtp"
+}
+
+// SettlesOnAcceptance reports that payments are final once the network
+// accepts them
+func (a *RTPAdapter) SettlesOnAcceptance() bool {
+	return true
+}
+
+func (a *RTPAdapter) Authorize(ctx context.Context, instruction Instruction) (*Result, error) {
+	message := NewPacs008(instruction, a.debtor, time.Now())
+	body, err := MarshalISO(message)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := message.Transfer.Transactions[0]
+	report, err := a.call(ctx, "POST", "/pacs.008", body, map[string]string{
+		"msg_id":                message.Transfer.GroupHeader.MessageID,
+		"tx_id":                 transaction.PaymentID.TransactionID,
+		"amount":                transaction.Amount.Value,
+		"creditor":              transaction.Creditor.Name,
+		"debtor_account_number": transaction.DebtorAccount.Identifier(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return StatusResult(report), nil
+}
+
+// Capture returns the payment's status; accepted payments are already settled
+func (a *RTPAdapter) Capture(ctx context.Context, referenceID string) (*Result, error) {
+	return a.GetStatus(ctx, referenceID)
+}
+
+// Cancel is refused: credit transfers cannot be recalled once sent
+func (a *RTPAdapter) Cancel(ctx context.Context, referenceID string) (*Result, error) {
+	return nil, fmt.Errorf("%w: rtp payments are irrevocable", ErrInvalidState)
+}
+
+// Refund is refused: only the creditor can return funds, with a payment of
+// its own
+func (a *RTPAdapter) Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error) {
+	return nil, fmt.Errorf("%w: rtp payments are final and are returned by the creditor", ErrInvalidState)
+}
+
+func (a *RTPAdapter) GetStatus(ctx context.Context, referenceID string) (*Result, error) {
+	report, err := a.call(ctx, "GET", "/payments/"+url.PathEscape(referenceID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return StatusResult(report), nil
+}
+
+// Ping sends the network an echo request
+func (a *RTPAdapter) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.networkURL+"/echo", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("rtp echo: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: rtp echo returned %d", ErrUnavailable, resp.StatusCode)
+	}
+	return nil
+}
+
+// ParseWebhook accepts pacs.002 status reports the network pushes for
+// payments it did not settle at once, signed with the webhook secret in the
+// last five minutes. Without a secret every report is rejected.
+func (a *RTPAdapter) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifyWebhookSignature(header.Get(RTPSignatureHeader), body, a.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	report, err := ParsePacs002(body)
+	if err != nil {
+		return nil, err
+	}
+	result := StatusResult(report)
+	return &WebhookEvent{
+		ID:          report.Report.GroupHeader.MessageID,
+		ReferenceID: result.ReferenceID,
+		Status:      result.Status,
+		Message:     result.Message,
+	}, nil
+}
+
+// call sends a message to the network and decodes the status report it
+// answers with. The network refusing messages while unavailable is
+// reported as ErrUnavailable, since it did not take the payment.
+func (a *RTPAdapter) call(ctx context.Context, method, path string, body []byte, fields map[string]string) (*Pacs002, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.networkURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+
+	call := loggedCall(ctx)
+	if call != nil {
+		call.Method, call.Endpoint = method, a.networkURL+path
+		if fields != nil {
+			call.Request = Redact(fields)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rtp %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if call != nil {
+		call.StatusCode = resp.StatusCode
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && method == "GET":
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: rtp %s returned %d", ErrUnavailable, path, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("rtp %s: %d %s", path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return ParsePacs002(data)
+}
+
+// handlerTransport serves requests with an in-process handler, standing in
+// for a network reached over HTTP
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
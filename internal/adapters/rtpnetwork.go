@@ -0,0 +1,107 @@
+package adapters
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockRTPNetwork simulates an instant payment network exchanging ISO 20022
+// messages over HTTP: POST /pacs.008 submits a credit transfer and is
+// answered with its pacs.002 status report, GET /payments/{txId} returns
+// the latest report of a payment and GET /echo checks the network is up.
+// Payments the network accepts settle at once, with finality. Creditors
+// containing "decline" are rejected as closed accounts.
+type MockRTPNetwork struct {
+	limit float64 // Largest payment the network accepts, in USD
+
+	mu       sync.Mutex
+	reports  map[string][]byte // pacs.002 by transaction ID
+	sequence int
+	mux      *http.ServeMux
+}
+
+// NewMockRTPNetwork creates a network accepting payments up to limit USD
+func NewMockRTPNetwork(limit float64) *MockRTPNetwork {
+	network := &MockRTPNetwork{limit: limit, reports: make(map[string][]byte), mux: http.NewServeMux()}
+	network.mux.HandleFunc("POST /pacs.008", network.submit)
+	network.mux.HandleFunc("GET /payments/{txId}", network.status)
+	network.mux.HandleFunc("GET /echo", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return network
+}
+
+func (n *MockRTPNetwork) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mux.ServeHTTP(w, r)
+}
+
+// submit settles or rejects a credit transfer. A transaction ID seen before
+// is a duplicate, answered with its original report without paying again.
+func (n *MockRTPNetwork) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "unreadable message", http.StatusBadRequest)
+		return
+	}
+	message, err := ParsePacs008(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	transaction := message.Transfer.Transactions[0]
+	txID := transaction.PaymentID.TransactionID
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if report, ok := n.reports[txID]; ok {
+		writeISO(w, report)
+		return
+	}
+
+	status, reason, info := TxStatusAcceptedSettlementCompleted, "", ""
+	amount, err := strconv.ParseFloat(transaction.Amount.Value, 64)
+	switch {
+	case transaction.Amount.Currency != "USD":
+		status, reason, info = TxStatusRejected, ReasonInvalidCurrency, "only USD is cleared"
+	case err != nil || amount <= 0 || amount > n.limit:
+		status, reason, info = TxStatusRejected, ReasonAmountNotAllowed, "amount outside the network limit"
+	case transaction.CreditorAccount.Identifier() == "":
+		status, reason, info = TxStatusRejected, ReasonInvalidCreditorAccount, "creditor account missing"
+	case strings.Contains(strings.ToLower(transaction.Creditor.Name), "decline"):
+		status, reason, info = TxStatusRejected, ReasonClosedAccount, "creditor account closed"
+	}
+
+	clearingRef := ""
+	if status != TxStatusRejected {
+		n.sequence++
+		clearingRef = "RTP" + time.Now().UTC().Format("20060102") + strconv.Itoa(100000+n.sequence)
+	}
+	report, err := MarshalISO(NewPacs002(message, status, reason, info, clearingRef, time.Now()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n.reports[txID] = report
+	writeISO(w, report)
+}
+
+func (n *MockRTPNetwork) status(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	report, ok := n.reports[r.PathValue("txId")]
+	n.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown transaction", http.StatusNotFound)
+		return
+	}
+	writeISO(w, report)
+}
+
+func writeISO(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
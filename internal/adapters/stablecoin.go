@@ -0,0 +1,195 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/types"
+)
+
+// Token is an ERC-20 token a crypto rail pays in
+type Token struct {
+	Symbol   string
+	Contract string
+	Decimals int
+}
+
+// USDC is Circle's USD stablecoin on Ethereum
+var USDC = Token{Symbol: "USDC", Contract: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Decimals: 6}
+
+// mockPlatformAddress is the address the mock chain sends payments from
+const mockPlatformAddress = "0x5aA1f0e3b1cD4e7E9f2a3C8b6D0e1F2a3B4c5D6e"
+
+// StablecoinAdapter pays wallet addresses in a stablecoin over a chain. Each
+// payment is one token transfer from the platform's address; it stays
+// pending until its transaction has the required number of confirmations,
+// and then is final. Transfers cannot be cancelled or refunded.
+type StablecoinAdapter struct {
+	rail                  string
+	chain                 ChainClient
+	token                 Token
+	from                  string
+	requiredConfirmations int
+	rateUSD               float64 // USD per token
+
+	mu        sync.Mutex
+	sent      map[string]string           // Transaction hash by payment ID
+	transfers map[string]*ChainSettlement // Transfers by transaction hash
+}
+
+// NewStablecoinAdapter creates an adapter for rail paying token from the
+// platform's address, converting USD amounts at rateUSD per token
+func NewStablecoinAdapter(rail string, chain ChainClient, token Token, from string, requiredConfirmations int, rateUSD float64) *StablecoinAdapter {
+	if requiredConfirmations < 1 {
+		requiredConfirmations = 1
+	}
+	return &StablecoinAdapter{
+		rail:                  rail,
+		chain:                 chain,
+		token:                 token,
+		from:                  from,
+		requiredConfirmations: requiredConfirmations,
+		rateUSD:               rateUSD,
+		sent:                  make(map[string]string),
+		transfers:             make(map[string]*ChainSettlement),
+	}
+}
+
+// NewMockUSDCAdapter pays USDC on a mock chain producing a block a second
+func NewMockUSDCAdapter(requiredConfirmations int) *StablecoinAdapter {
+	return NewStablecoinAdapter("usdc", NewMockChain("mock", time.Second), USDC, mockPlatformAddress, requiredConfirmations, 1)
+}
+
+func (a *StablecoinAdapter) Rail() string {
+	return a.rail
+}
+
+// Authorize submits the token transfer. Counterparties that are not wallet
+// addresses are declined. A payment already submitted returns its transfer
+// rather than paying twice.
+func (a *StablecoinAdapter) Authorize(ctx context.Context, instruction Instruction) (*Result, error) {
+	if !types.IsWalletAddress(instruction.Counterparty) {
+		return &Result{Status: StatusFailed, Message: "counterparty is not a wallet address"}, nil
+	}
+
+	a.mu.Lock()
+	txHash, sent := a.sent[instruction.PaymentID]
+	a.mu.Unlock()
+	if sent {
+		return a.GetStatus(ctx, txHash)
+	}
+
+	scale := math.Pow10(a.token.Decimals)
+	baseUnits := int64(math.Round(instruction.AmountUSD / a.rateUSD * scale))
+	settlement := &ChainSettlement{
+		Network:               a.chain.Network(),
+		Token:                 a.token.Symbol,
+		FromAddress:           a.from,
+		ToAddress:             instruction.Counterparty,
+		TokenAmount:           float64(baseUnits) / scale,
+		RateUSD:               a.rateUSD,
+		RequiredConfirmations: a.requiredConfirmations,
+	}
+	if call := loggedCall(ctx); call != nil {
+		call.Request = map[string]string{
+			"token":        a.token.Symbol,
+			"to":           instruction.Counterparty,
+			"token_amount": fmt.Sprintf("%.*f", a.token.Decimals, settlement.TokenAmount),
+		}
+	}
+
+	txHash, err := a.chain.SubmitTransfer(ctx, TokenTransfer{
+		Contract: a.token.Contract,
+		From:     a.from,
+		To:       instruction.Counterparty,
+		Amount:   big.NewInt(baseUnits),
+	})
+	if err != nil {
+		return nil, err
+	}
+	settlement.TxHash = txHash
+
+	a.mu.Lock()
+	a.sent[instruction.PaymentID] = txHash
+	a.transfers[txHash] = settlement
+	a.mu.Unlock()
+
+	copied := *settlement
+	return &Result{ReferenceID: txHash, Status: StatusPending, Message: "submitted", Chain: &copied}, nil
+}
+
+// Capture returns the transfer's status; submitted transfers need no capture
+func (a *StablecoinAdapter) Capture(ctx context.Context, referenceID string) (*Result, error) {
+	return a.GetStatus(ctx, referenceID)
+}
+
+// Cancel is refused: a broadcast transaction cannot be recalled
+func (a *StablecoinAdapter) Cancel(ctx context.Context, referenceID string) (*Result, error) {
+	return nil, fmt.Errorf("%w: %s transfers are irrevocable", ErrInvalidState, a.rail)
+}
+
+// Refund is refused: only the recipient can send tokens back
+func (a *StablecoinAdapter) Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error) {
+	return nil, fmt.Errorf("%w: %s transfers are final and are returned by the recipient", ErrInvalidState, a.rail)
+}
+
+// GetStatus counts the transfer's confirmations: the blocks from the one
+// including it to the latest. It is settled once it has the required
+// number, and failed if it reverted.
+func (a *StablecoinAdapter) GetStatus(ctx context.Context, referenceID string) (*Result, error) {
+	receipt, err := a.chain.Receipt(ctx, referenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &ChainSettlement{TxHash: referenceID, RequiredConfirmations: a.requiredConfirmations}
+	a.mu.Lock()
+	if known, ok := a.transfers[referenceID]; ok {
+		*settlement = *known
+	}
+	a.mu.Unlock()
+	result := &Result{ReferenceID: referenceID, Status: StatusPending, Message: "awaiting inclusion in a block", Chain: settlement}
+	if receipt == nil {
+		return result, nil
+	}
+
+	head, err := a.chain.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	block := receipt.BlockNumber
+	settlement.BlockNumber = &block
+	settlement.Confirmations = int(head - block + 1)
+	if settlement.Confirmations < 0 {
+		settlement.Confirmations = 0
+	}
+
+	switch {
+	case !receipt.Succeeded:
+		result.Status = StatusFailed
+		result.Message = "transaction reverted"
+	case settlement.Confirmations >= a.requiredConfirmations:
+		result.Status = StatusSettled
+		result.Message = fmt.Sprintf("%d confirmations", settlement.Confirmations)
+	default:
+		result.Message = fmt.Sprintf("%d of %d confirmations", settlement.Confirmations, a.requiredConfirmations)
+	}
+	return result, nil
+}
+
+// ParseWebhook is not supported: settlement is tracked by polling the chain
+func (a *StablecoinAdapter) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	return nil, errors.New(a.rail + ": settlement is tracked on chain, not by webhook")
+}
+
+// Ping reads the chain's latest block
+func (a *StablecoinAdapter) Ping(ctx context.Context) error {
+	_, err := a.chain.BlockNumber(ctx)
+	return err
+}
@@ -0,0 +1,309 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeSignatureHeader carries Stripe's webhook signature
+const StripeSignatureHeader = "Stripe-Signature"
+
+// StripeAdapter executes card payments as Stripe PaymentIntents. Intents are
+// created with manual capture so Authorize and Capture map onto Stripe's
+// confirm and capture steps.
+type StripeAdapter struct {
+	baseURL              string
+	secretKey            string
+	webhookSecret        string
+	defaultPaymentMethod string
+	client               *http.Client
+}
+
+// NewStripeAdapter creates a Stripe card adapter. defaultPaymentMethod is
+// charged when an instruction carries no payment method of its own.
+func NewStripeAdapter(secretKey, webhookSecret, defaultPaymentMethod string) *StripeAdapter {
+	return &StripeAdapter{
+		baseURL:              "https://api.stripe.com",
+		secretKey:            secretKey,
+		webhookSecret:        webhookSecret,
+		defaultPaymentMethod: defaultPaymentMethod,
+		client:               &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (a *StripeAdapter) Rail() string {
+	return "card"
+}
+
+// stripePaymentIntent is the subset of a PaymentIntent the adapter reads
+type stripePaymentIntent struct {
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	Amount           int64  `json:"amount"`
+	LastPaymentError *struct {
+		Message string `json:"message"`
+	} `json:"last_payment_error"`
+	CancellationReason string `json:"cancellation_reason"`
+}
+
+type stripeError struct {
+	Error struct {
+		Type          string               `json:"type"`
+		Code          string               `json:"code"`
+		Message       string               `json:"message"`
+		PaymentIntent *stripePaymentIntent `json:"payment_intent"`
+	} `json:"error"`
+}
+
+func (a *StripeAdapter) Authorize(ctx context.Context, instruction Instruction) (*Result, error) {
+	paymentMethod := instruction.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = a.defaultPaymentMethod
+	}
+	if paymentMethod == "" {
+		return nil, fmt.Errorf("stripe: no payment method for payment %s", instruction.PaymentID)
+	}
+
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(toCents(instruction.AmountUSD), 10))
+	form.Set("currency", "usd")
+	form.Set("capture_method", "manual")
+	form.Set("confirm", "true")
+	form.Set("payment_method", paymentMethod)
+	form.Set("description", instruction.Description)
+	form.Set("metadata[payment_id]", instruction.PaymentID)
+	form.Set("metadata[agent_id]", instruction.AgentID)
+	form.Set("metadata[counterparty]", instruction.Counterparty)
+	// Off-session: agents pay without a customer present to complete 3DS
+	form.Set("off_session", "true")
+
+	var intent stripePaymentIntent
+	if err := a.call(ctx, "POST", "/v1/payment_intents", form, instruction.PaymentID, &intent); err != nil {
+		return declinedResult(err)
+	}
+	return intentResult(&intent), nil
+}
+
+func (a *StripeAdapter) Capture(ctx context.Context, referenceID string) (*Result, error) {
+	var intent stripePaymentIntent
+	if err := a.call(ctx, "POST", "/v1/payment_intents/"+url.PathEscape(referenceID)+"/capture", url.Values{}, "capture-"+referenceID, &intent); err != nil {
+		return declinedResult(err)
+	}
+	return intentResult(&intent), nil
+}
+
+func (a *StripeAdapter) Cancel(ctx context.Context, referenceID string) (*Result, error) {
+	var intent stripePaymentIntent
+	if err := a.call(ctx, "POST", "/v1/payment_intents/"+url.PathEscape(referenceID)+"/cancel", url.Values{}, "cancel-"+referenceID, &intent); err != nil {
+		return nil, err
+	}
+	return intentResult(&intent), nil
+}
+
+func (a *StripeAdapter) Refund(ctx context.Context, referenceID string, amountUSD float64) (*Result, error) {
+	form := url.Values{}
+	form.Set("payment_intent", referenceID)
+	form.Set("amount", strconv.FormatInt(toCents(amountUSD), 10))
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"` // "pending", "succeeded", "failed", "canceled"
+	}
+	if err := a.call(ctx, "POST", "/v1/refunds", form, "", &refund); err != nil {
+		return nil, err
+	}
+
+	result := &Result{ReferenceID: referenceID, Status: StatusRefunded, Message: "refund " + refund.ID, RefundID: refund.ID}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return nil, fmt.Errorf("stripe: refund %s %s", refund.ID, refund.Status)
+	}
+	return result, nil
+}
+
+func (a *StripeAdapter) GetStatus(ctx context.Context, referenceID string) (*Result, error) {
+	var intent stripePaymentIntent
+	if err := a.call(ctx, "GET", "/v1/payment_intents/"+url.PathEscape(referenceID), nil, "", &intent); err != nil {
+		if stripeErr, ok := err.(*stripeAPIError); ok && stripeErr.status == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return intentResult(&intent), nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header and maps PaymentIntent
+// and refund events onto adapter statuses. Other event types return an event
+// without a reference so callers can acknowledge and ignore them.
+func (a *StripeAdapter) ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifyWebhookSignature(header.Get(StripeSignatureHeader), body, a.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+
+	webhookEvent := &WebhookEvent{ID: event.ID}
+	switch event.Type {
+	case "payment_intent.amount_capturable_updated", "payment_intent.succeeded",
+		"payment_intent.payment_failed", "payment_intent.canceled", "payment_intent.processing":
+		var intent stripePaymentIntent
+		if err := json.Unmarshal(event.Data.Object, &intent); err != nil {
+			return nil, err
+		}
+		result := intentResult(&intent)
+		webhookEvent.ReferenceID = intent.ID
+		webhookEvent.Status = result.Status
+		webhookEvent.Message = result.Message
+	case "charge.refunded":
+		var charge struct {
+			PaymentIntent  string `json:"payment_intent"`
+			Refunded       bool   `json:"refunded"` // Fully refunded
+			AmountRefunded int64  `json:"amount_refunded"`
+		}
+		if err := json.Unmarshal(event.Data.Object, &charge); err != nil {
+			return nil, err
+		}
+		if charge.Refunded {
+			webhookEvent.ReferenceID = charge.PaymentIntent
+			webhookEvent.Status = StatusRefunded
+			webhookEvent.Message = fmt.Sprintf("refunded %.2f", float64(charge.AmountRefunded)/100)
+		}
+	}
+	return webhookEvent, nil
+}
+
+// intentResult maps a PaymentIntent status onto an adapter status
+func intentResult(intent *stripePaymentIntent) *Result {
+	result := &Result{ReferenceID: intent.ID}
+	switch intent.Status {
+	case "requires_capture":
+		result.Status = StatusAuthorized
+	case "succeeded":
+		result.Status = StatusSettled
+	case "canceled":
+		result.Status = StatusCancelled
+		result.Message = intent.CancellationReason
+	case "requires_payment_method":
+		// A confirmed intent returns here when the card is declined
+		result.Status = StatusFailed
+		result.Message = "card declined"
+	default: // "processing", "requires_action", "requires_confirmation"
+		result.Status = StatusPending
+	}
+	if intent.LastPaymentError != nil && intent.LastPaymentError.Message != "" {
+		result.Message = intent.LastPaymentError.Message
+	}
+	return result
+}
+
+// declinedResult turns a card decline into a failed result; other errors pass through
+func declinedResult(err error) (*Result, error) {
+	stripeErr, ok := err.(*stripeAPIError)
+	if !ok || stripeErr.body.Error.Type != "card_error" {
+		return nil, err
+	}
+	result := &Result{Status: StatusFailed, Message: stripeErr.body.Error.Message}
+	if intent := stripeErr.body.Error.PaymentIntent; intent != nil {
+		result.ReferenceID = intent.ID
+	}
+	return result, nil
+}
+
+type stripeAPIError struct {
+	status int
+	body   stripeError
+}
+
+func (e *stripeAPIError) Error() string {
+	return fmt.Sprintf("stripe: %d %s: %s", e.status, e.body.Error.Code, e.body.Error.Message)
+}
+
+// Retryable reports whether Stripe refused the request without acting on
+// it: rate limited or temporarily unavailable
+func (e *stripeAPIError) Retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status == http.StatusServiceUnavailable
+}
+
+// Ping checks the Stripe API is reachable and accepts the key, by reading
+// the account balance
+func (a *StripeAdapter) Ping(ctx context.Context) error {
+	var balance struct {
+		Object string `json:"object"`
+	}
+	return a.call(ctx, "GET", "/v1/balance", nil, "", &balance)
+}
+
+// call sends a form-encoded request to the Stripe API
+func (a *StripeAdapter) call(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(a.secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	call := loggedCall(ctx)
+	if call != nil {
+		call.Method, call.Endpoint = method, a.baseURL+path
+		fields := map[string]string{}
+		for name := range form {
+			fields[name] = form.Get(name)
+		}
+		if idempotencyKey != "" {
+			fields["idempotency_key"] = idempotencyKey
+		}
+		call.Request = Redact(fields)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if call != nil {
+		call.StatusCode = resp.StatusCode
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &stripeAPIError{status: resp.StatusCode}
+		json.Unmarshal(data, &apiErr.body)
+		return apiErr
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func toCents(amountUSD float64) int64 {
+	return int64(math.Round(amountUSD * 100))
+}
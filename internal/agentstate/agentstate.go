@@ -0,0 +1,63 @@
+package agentstate
+
+import (
+	"fmt"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// State is an agent's lifecycle status. The values match the check
+// constraint on agents.status.
+type State string
+
+const (
+	Active         State = "active"         // May initiate and execute payments
+	Suspended      State = "suspended"      // Stopped until reactivated
+	Decommissioned State = "decommissioned" // Retired for good
+)
+
+// transitions lists the states each state may move to. Decommissioned agents
+// never come back.
+var transitions = map[State][]State{
+	Active:         {Suspended, Decommissioned},
+	Suspended:      {Active, Decommissioned},
+	Decommissioned: {},
+}
+
+// Valid reports whether the state exists
+func Valid(state State) bool {
+	_, ok := transitions[state]
+	return ok
+}
+
+// Allowed reports whether an agent may move from one state to another
+func Allowed(from, to State) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Of returns an agent's state. Agents stored before the lifecycle existed
+// are active.
+func Of(agent *database.Agent) State {
+	if agent.Status == "" {
+		return Active
+	}
+	return State(agent.Status)
+}
+
+// Refusal explains why an agent may not pay, or returns "" if it may
+func Refusal(agent *database.Agent) string {
+	state := Of(agent)
+	if state == Active {
+		return ""
+	}
+	refusal := fmt.Sprintf("Agent %s is %s", agent.ID, state)
+	if agent.StatusReason != "" {
+		refusal += ": " + agent.StatusReason
+	}
+	return refusal
+}
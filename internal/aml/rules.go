@@ -0,0 +1,235 @@
+package aml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Rule types
+const (
+	RuleStructuring               = "structuring"
+	RuleRapidFire                 = "rapid_fire"
+	RuleNearThreshold             = "near_threshold"
+	RuleNewCounterpartyHighAmount = "new_counterparty_high_amount"
+)
+
+// Rule actions, in increasing severity. A flag only adds the rule's score
+// impact; review and deny set the decision to at least that outcome.
+const (
+	ActionFlag   = "flag"
+	ActionReview = "review"
+	ActionDeny   = "deny"
+)
+
+// Parameters configures a rule. Each rule type reads only the fields it needs;
+// unset fields take the defaults of DefaultParameters.
+type Parameters struct {
+	ThresholdUSD  float64 `json:"thresholdUSD,omitempty"`  // Reporting threshold (structuring, near_threshold)
+	WindowMinutes int     `json:"windowMinutes,omitempty"` // Look-back window (structuring, rapid_fire)
+	MinCount      int     `json:"minCount,omitempty"`      // Payments in the window that trigger the rule
+	MarginPercent float64 `json:"marginPercent,omitempty"` // How far under the threshold counts as near it
+	MinAmountUSD  float64 `json:"minAmountUSD,omitempty"`  // Amount that is high for a new counterparty
+	LookbackDays  int     `json:"lookbackDays,omitempty"`  // History that makes a counterparty known
+}
+
+// DefaultParameters returns the parameters a rule type uses when unset
+func DefaultParameters(ruleType string) Parameters {
+	switch ruleType {
+	case RuleStructuring:
+		return Parameters{ThresholdUSD: 10000, WindowMinutes: 24 * 60, MinCount: 3}
+	case RuleRapidFire:
+		return Parameters{WindowMinutes: 10, MinCount: 5}
+	case RuleNearThreshold:
+		return Parameters{ThresholdUSD: 10000, MarginPercent: 10}
+	case RuleNewCounterpartyHighAmount:
+		return Parameters{MinAmountUSD: 5000, LookbackDays: 90}
+	}
+	return Parameters{}
+}
+
+// ValidRuleType reports whether a rule type is known
+func ValidRuleType(ruleType string) bool {
+	switch ruleType {
+	case RuleStructuring, RuleRapidFire, RuleNearThreshold, RuleNewCounterpartyHighAmount:
+		return true
+	}
+	return false
+}
+
+// ValidAction reports whether a rule action is known
+func ValidAction(action string) bool {
+	return action == ActionFlag || action == ActionReview || action == ActionDeny
+}
+
+// RuleParameters decodes a rule's parameters over its type's defaults
+func RuleParameters(rule *database.AMLRule) (Parameters, error) {
+	params := DefaultParameters(rule.Type)
+	if rule.Parameters != "" {
+		if err := json.Unmarshal([]byte(rule.Parameters), &params); err != nil {
+			return params, fmt.Errorf("invalid parameters for rule %s: %v", rule.ID, err)
+		}
+	}
+	return params, nil
+}
+
+// Payment is the payment being evaluated
+type Payment struct {
+	AgentID      string
+	Counterparty string
+	AmountUSD    float64
+	At           time.Time
+}
+
+// Trigger is a rule the payment triggered
+type Trigger struct {
+	RuleID      string  `json:"ruleId"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Action      string  `json:"action"`
+	ScoreImpact float64 `json:"scoreImpact"`
+	Detail      string  `json:"detail"`
+}
+
+// Evaluate runs the rules over a payment and the agent's earlier risk
+// decisions, newest first, returning the rules it triggers. A rule with
+// malformed parameters is skipped and reported in the error alongside the
+// triggers of the other rules.
+func Evaluate(rules []*database.AMLRule, payment Payment, history []*database.RiskDecision) ([]Trigger, error) {
+	var triggers []Trigger
+	var problems []string
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		params, err := RuleParameters(rule)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		var detail string
+		switch rule.Type {
+		case RuleStructuring:
+			detail = structuring(params, payment, history)
+		case RuleRapidFire:
+			detail = rapidFire(params, payment, history)
+		case RuleNearThreshold:
+			detail = nearThreshold(params, payment)
+		case RuleNewCounterpartyHighAmount:
+			detail = newCounterpartyHighAmount(params, payment, history)
+		}
+		if detail != "" {
+			triggers = append(triggers, Trigger{
+				RuleID:      rule.ID,
+				Name:        rule.Name,
+				Type:        rule.Type,
+				Action:      rule.Action,
+				ScoreImpact: rule.ScoreImpact,
+				Detail:      detail,
+			})
+		}
+	}
+
+	if len(problems) > 0 {
+		return triggers, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return triggers, nil
+}
+
+// within returns the decisions made in the window before the payment
+func within(history []*database.RiskDecision, payment Payment, window time.Duration) []*database.RiskDecision {
+	since := payment.At.Add(-window)
+	var recent []*database.RiskDecision
+	for _, decision := range history {
+		if decision.CreatedAt.After(since) && !decision.CreatedAt.After(payment.At) {
+			recent = append(recent, decision)
+		}
+	}
+	return recent
+}
+
+// structuring detects amounts split to stay under a reporting threshold:
+// several payments each under the threshold that together reach it
+func structuring(params Parameters, payment Payment, history []*database.RiskDecision) string {
+	if payment.AmountUSD >= params.ThresholdUSD {
+		return ""
+	}
+	count := 1
+	total := payment.AmountUSD
+	for _, decision := range within(history, payment, time.Duration(params.WindowMinutes)*time.Minute) {
+		if decision.AmountUSD < params.ThresholdUSD {
+			count++
+			total += decision.AmountUSD
+		}
+	}
+	if count >= params.MinCount && total >= params.ThresholdUSD {
+		return fmt.Sprintf("%d payments under %.2f USD total %.2f USD within %d minutes", count, params.ThresholdUSD, total, params.WindowMinutes)
+	}
+	return ""
+}
+
+// rapidFire detects bursts of payments from one agent
+func rapidFire(params Parameters, payment Payment, history []*database.RiskDecision) string {
+	count := 1 + len(within(history, payment, time.Duration(params.WindowMinutes)*time.Minute))
+	if count >= params.MinCount {
+		return fmt.Sprintf("%d payments within %d minutes", count, params.WindowMinutes)
+	}
+	return ""
+}
+
+// nearThreshold detects single amounts just under a reporting threshold
+func nearThreshold(params Parameters, payment Payment) string {
+	floor := params.ThresholdUSD * (1 - params.MarginPercent/100)
+	if payment.AmountUSD >= floor && payment.AmountUSD < params.ThresholdUSD {
+		return fmt.Sprintf("%.2f USD is within %.0f%% under the %.2f USD threshold", payment.AmountUSD, params.MarginPercent, params.ThresholdUSD)
+	}
+	return ""
+}
+
+// newCounterpartyHighAmount detects a high amount to a counterparty the agent
+// has not paid before. Denied attempts do not make a counterparty known.
+func newCounterpartyHighAmount(params Parameters, payment Payment, history []*database.RiskDecision) string {
+	if payment.AmountUSD < params.MinAmountUSD {
+		return ""
+	}
+	for _, decision := range within(history, payment, time.Duration(params.LookbackDays)*24*time.Hour) {
+		if decision.Decision != "deny" && strings.EqualFold(decision.Counterparty, payment.Counterparty) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%.2f USD to a counterparty not paid in the last %d days", payment.AmountUSD, params.LookbackDays)
+}
+
+// LookbackWindow is the history the rules need, so callers load it only once
+func LookbackWindow(rules []*database.AMLRule) time.Duration {
+	var longest time.Duration
+	for _, rule := range rules {
+		params, err := RuleParameters(rule)
+		if err != nil {
+			continue
+		}
+		window := time.Duration(params.WindowMinutes)*time.Minute + time.Duration(params.LookbackDays)*24*time.Hour
+		if window > longest {
+			longest = window
+		}
+	}
+	return longest
+}
+
+// DefaultRules are created when the risk service starts with no rules defined
+func DefaultRules() []*database.AMLRule {
+	return []*database.AMLRule{
+		{Name: "Structuring under reporting threshold", Type: RuleStructuring, Action: ActionReview, ScoreImpact: 0.3,
+			Description: "Several payments under 10,000 USD within 24 hours that together reach it"},
+		{Name: "Rapid-fire payments", Type: RuleRapidFire, Action: ActionReview, ScoreImpact: 0.2,
+			Description: "Five or more payments within 10 minutes"},
+		{Name: "Amount just under reporting threshold", Type: RuleNearThreshold, Action: ActionFlag, ScoreImpact: 0.15,
+			Description: "A payment within 10% under 10,000 USD"},
+		{Name: "High amount to new counterparty", Type: RuleNewCounterpartyHighAmount, Action: ActionReview, ScoreImpact: 0.2,
+			Description: "5,000 USD or more to a counterparty not paid in 90 days"},
+	}
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/hashchain"
+)
+
+// ErrNotAnchored is returned when a proof is asked for an entry no anchor
+// covers yet
+var ErrNotAnchored = errors.New("audit entry is not anchored yet")
+
+// EntryProof links an audit entry to the Merkle root of its anchor
+type EntryProof struct {
+	EntryID   string                `json:"entryId"`
+	AnchorID  string                `json:"anchorId"`
+	LeafIndex int                   `json:"leafIndex"`
+	LeafHash  string                `json:"leafHash"` // Hash of the entry as stored now
+	Proof     []hashchain.ProofStep `json:"proof"`
+	Root      string                `json:"root"`     // Root stored when the entry was anchored
+	Verified  bool                  `json:"verified"` // The entry still hashes up to the stored root
+}
+
+// EntryHash is the Merkle leaf of an audit entry: the SHA-256 of every field
+// recorded when the entry was logged. Archiving an entry does not change it.
+func EntryHash(entry *database.AuditEntry) string {
+	record := strings.Join([]string{
+		entry.ID,
+		entry.EventType,
+		entry.Severity,
+		entry.UserID,
+		entry.AgentID,
+		entry.ResourceID,
+		entry.ResourceType,
+		entry.Action,
+		entry.Description,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.OldValues,
+		entry.NewValues,
+		entry.Metadata,
+		entry.SessionID,
+		entry.CorrelationID,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+	}, "|")
+	sum := sha256.Sum256([]byte(record))
+	return hex.EncodeToString(sum[:])
+}
+
+// AnchorEntries builds a Merkle tree over up to limit entries logged before a
+// time that are not anchored yet, oldest first, and stores its root. It
+// returns nil when there is nothing to anchor.
+func (at *AuditTrail) AnchorEntries(ctx context.Context, before time.Time, limit int) (*database.AuditAnchor, error) {
+	entries, err := at.repo.AuditEntryRepository().ListUnanchored(before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unanchored audit entries: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	first, last := entries[0], entries[len(entries)-1]
+	anchor := &database.AuditAnchor{
+		Root:          hashchain.BuildMerkleTree(entryHashes(entries)).Root,
+		EntryCount:    len(entries),
+		FirstEntryID:  first.ID,
+		LastEntryID:   last.ID,
+		FromTimestamp: first.Timestamp,
+		ToTimestamp:   last.Timestamp,
+	}
+	if err := at.repo.AuditAnchorRepository().Create(anchor, entries); err != nil {
+		return nil, fmt.Errorf("failed to store audit anchor: %v", err)
+	}
+	return anchor, nil
+}
+
+// ProveEntry builds the Merkle proof of an anchored entry from the entries of
+// its anchor as they are stored now. Verified is false if any of them has
+// been altered or removed since the anchor was taken.
+func (at *AuditTrail) ProveEntry(ctx context.Context, entryID string) (*EntryProof, error) {
+	entry, err := at.repo.AuditEntryRepository().GetByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.AnchorID == nil {
+		return nil, ErrNotAnchored
+	}
+	anchor, err := at.repo.AuditAnchorRepository().GetByID(*entry.AnchorID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := at.repo.AuditAnchorRepository().ListEntries(anchor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Leaves missing from the anchor break the proof, so the entry is placed
+	// by its position among the stored entries rather than its AnchorIndex
+	index := -1
+	for i, anchored := range entries {
+		if anchored.ID == entry.ID {
+			index = i
+		}
+	}
+	tree := hashchain.BuildMerkleTree(entryHashes(entries))
+	proof, err := tree.Proof(index)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := EntryHash(entry)
+	return &EntryProof{
+		EntryID:   entry.ID,
+		AnchorID:  anchor.ID,
+		LeafIndex: entry.AnchorIndex,
+		LeafHash:  leaf,
+		Proof:     proof,
+		Root:      anchor.Root,
+		Verified:  len(entries) == anchor.EntryCount && index == entry.AnchorIndex && hashchain.VerifyProof(leaf, proof, anchor.Root),
+	}, nil
+}
+
+// VerifyProof checks a leaf hash and proof against the root stored for an anchor
+func (at *AuditTrail) VerifyProof(ctx context.Context, anchorID, leafHash string, proof []hashchain.ProofStep) (bool, *database.AuditAnchor, error) {
+	anchor, err := at.repo.AuditAnchorRepository().GetByID(anchorID)
+	if err != nil {
+		return false, nil, err
+	}
+	return hashchain.VerifyProof(leafHash, proof, anchor.Root), anchor, nil
+}
+
+func entryHashes(entries []*database.AuditEntry) []string {
+	hashes := make([]string, len(entries))
+	for i, entry := range entries {
+		hashes[i] = EntryHash(entry)
+	}
+	return hashes
+}
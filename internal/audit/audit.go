@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
 	"github.com/google/uuid"
 )
 
@@ -23,14 +24,19 @@ const (
 	AuditPermissionRevoke AuditEventType = "auth.permission.revoke"
 
 	// Payment Events
-	AuditPaymentInitiated   AuditEventType = "payment.initiated"
-	AuditPaymentAuthorized  AuditEventType = "payment.authorized"
-	AuditPaymentRiskChecked AuditEventType = "payment.risk_checked"
-	AuditPaymentRouted      AuditEventType = "payment.routed"
-	AuditPaymentExecuted    AuditEventType = "payment.executed"
-	AuditPaymentCompleted   AuditEventType = "payment.completed"
-	AuditPaymentFailed      AuditEventType = "payment.failed"
-	AuditPaymentCancelled   AuditEventType = "payment.cancelled"
+	AuditPaymentInitiated    AuditEventType = "payment.initiated"
+	AuditPaymentAuthorized   AuditEventType = "payment.authorized"
+	AuditPaymentRiskChecked  AuditEventType = "payment.risk_checked"
+	AuditPaymentRouted       AuditEventType = "payment.routed"
+	AuditPaymentExecuted     AuditEventType = "payment.executed"
+	AuditPaymentCompleted    AuditEventType = "payment.completed"
+	AuditPaymentFailed       AuditEventType = "payment.failed"
+	AuditPaymentCancelled    AuditEventType = "payment.cancelled"
+	AuditPaymentReviewed     AuditEventType = "payment.reviewed"
+	AuditPaymentApproved     AuditEventType = "payment.approved"
+	AuditPaymentRejected     AuditEventType = "payment.rejected"
+	AuditPaymentAutoDeclined AuditEventType = "payment.auto_declined"
+	AuditPaymentProcessing   AuditEventType = "payment.processing"
 
 	// Account Events
 	AuditAccountCreated    AuditEventType = "account.created"
@@ -44,22 +50,44 @@ const (
 	AuditTransactionVoided    AuditEventType = "transaction.voided"
 	AuditTransactionCorrected AuditEventType = "transaction.corrected"
 
+	// Party Events
+	AuditPartyKYCSubmitted AuditEventType = "party.kyc_submitted"
+	AuditPartyKYCDecided   AuditEventType = "party.kyc_decided"
+
+	// Tenant Events
+	AuditTenantAccessDenied AuditEventType = "tenant.access_denied"
+
+	// User Events
+	AuditUserCreated                AuditEventType = "user.created"
+	AuditUserUpdated                AuditEventType = "user.updated"
+	AuditUserRoleAssigned           AuditEventType = "user.role_assigned"
+	AuditUserRoleRevoked            AuditEventType = "user.role_revoked"
+	AuditApproverGroupMemberAdded   AuditEventType = "approver_group.member_added"
+	AuditApproverGroupMemberRemoved AuditEventType = "approver_group.member_removed"
+
 	// Agent Events
-	AuditAgentCreated   AuditEventType = "agent.created"
-	AuditAgentUpdated   AuditEventType = "agent.updated"
-	AuditAgentSuspended AuditEventType = "agent.suspended"
-	AuditAgentActivated AuditEventType = "agent.activated"
+	AuditAgentCreated        AuditEventType = "agent.created"
+	AuditAgentUpdated        AuditEventType = "agent.updated"
+	AuditAgentSuspended      AuditEventType = "agent.suspended"
+	AuditAgentActivated      AuditEventType = "agent.activated"
+	AuditAgentDecommissioned AuditEventType = "agent.decommissioned"
+	AuditAgentDIDVerified    AuditEventType = "agent.did_verified"
 
 	// Consent Events
 	AuditConsentCreated AuditEventType = "consent.created"
 	AuditConsentRevoked AuditEventType = "consent.revoked"
 	AuditConsentUpdated AuditEventType = "consent.updated"
+	AuditConsentRenewed AuditEventType = "consent.renewed"
 
 	// System Events
 	AuditSystemConfigChanged AuditEventType = "system.config.changed"
 	AuditDataExport          AuditEventType = "system.data.export"
 	AuditBackupCreated       AuditEventType = "system.backup.created"
 	AuditSecurityAlert       AuditEventType = "system.security.alert"
+	AuditOutboxFailureAlert  AuditEventType = "system.outbox.alert"
+
+	// API Events
+	AuditAPIRequest AuditEventType = "api.request" // A mutating request with no more specific event
 )
 
 // AuditSeverity represents the severity level of an audit event
@@ -91,6 +119,7 @@ type AuditEntry struct {
 	Timestamp     time.Time              `json:"timestamp"`
 	SessionID     string                 `json:"sessionId,omitempty"`
 	CorrelationID string                 `json:"correlationId,omitempty"`
+	TraceID       string                 `json:"traceId,omitempty"` // Trace the entry was recorded in
 }
 
 // AuditTrail manages audit logging and reporting
@@ -103,7 +132,8 @@ func NewAuditTrail(repo database.Repository) *AuditTrail {
 	return &AuditTrail{repo: repo}
 }
 
-// LogEvent logs an audit event
+// LogEvent logs an audit event, taking its correlation ID, if not set, and
+// trace ID from ctx
 func (at *AuditTrail) LogEvent(ctx context.Context, entry *AuditEntry) error {
 	// Set defaults
 	if entry.ID == "" {
@@ -112,6 +142,12 @@ func (at *AuditTrail) LogEvent(ctx context.Context, entry *AuditEntry) error {
 	if entry.Timestamp.IsZero() {
 		entry.Timestamp = time.Now().UTC()
 	}
+	if entry.CorrelationID == "" {
+		entry.CorrelationID = common.CorrelationIDFromContext(ctx)
+	}
+	if entry.TraceID == "" {
+		entry.TraceID = common.TraceID(ctx)
+	}
 
 	// Convert to database format
 	auditRecord := &database.AuditEntry{
@@ -128,6 +164,7 @@ func (at *AuditTrail) LogEvent(ctx context.Context, entry *AuditEntry) error {
 		UserAgent:     entry.UserAgent,
 		SessionID:     entry.SessionID,
 		CorrelationID: entry.CorrelationID,
+		TraceID:       entry.TraceID,
 		Timestamp:     entry.Timestamp,
 	}
 
@@ -145,7 +182,7 @@ func (at *AuditTrail) LogEvent(ctx context.Context, entry *AuditEntry) error {
 		auditRecord.Metadata = string(metadataJSON)
 	}
 
-	return at.repo.AuditEntryRepository().Create(auditRecord)
+	return at.repo.WithContext(ctx).AuditEntryRepository().Create(auditRecord)
 }
 
 // LogPaymentEvent logs a payment-related audit event
@@ -223,22 +260,7 @@ func (at *AuditTrail) LogSecurityEvent(ctx context.Context, eventType AuditEvent
 
 // QueryAuditTrail queries audit entries with filters
 func (at *AuditTrail) QueryAuditTrail(ctx context.Context, filters AuditQueryFilters) ([]*AuditEntry, error) {
-	// Convert filters to database query
-	dbFilters := database.AuditQueryFilters{
-		UserID:       filters.UserID,
-		AgentID:      filters.AgentID,
-		ResourceID:   filters.ResourceID,
-		ResourceType: filters.ResourceType,
-		EventType:    string(filters.EventType),
-		Severity:     string(filters.Severity),
-		StartDate:    filters.StartDate,
-		EndDate:      filters.EndDate,
-		IPAddress:    filters.IPAddress,
-		Limit:        filters.Limit,
-		Offset:       filters.Offset,
-	}
-
-	auditRecords, err := at.repo.AuditEntryRepository().Query(dbFilters)
+	auditRecords, err := at.repo.AuditEntryRepository().Query(filters.toDatabase())
 	if err != nil {
 		return nil, err
 	}
@@ -260,6 +282,7 @@ func (at *AuditTrail) QueryAuditTrail(ctx context.Context, filters AuditQueryFil
 			UserAgent:     record.UserAgent,
 			SessionID:     record.SessionID,
 			CorrelationID: record.CorrelationID,
+			TraceID:       record.TraceID,
 			Timestamp:     record.Timestamp,
 		}
 
@@ -291,15 +314,41 @@ type AuditQueryFilters struct {
 	StartDate    *time.Time     `json:"startDate,omitempty"`
 	EndDate      *time.Time     `json:"endDate,omitempty"`
 	IPAddress    string         `json:"ipAddress,omitempty"`
+	TenantID     string         `json:"tenantId,omitempty"` // Only entries about the tenant's agents
 	Limit        int            `json:"limit,omitempty"`
 	Offset       int            `json:"offset,omitempty"`
 }
 
-// GetAuditSummary generates an audit summary report
-func (at *AuditTrail) GetAuditSummary(ctx context.Context, startDate, endDate time.Time) (*AuditSummary, error) {
+// CountAuditTrail counts the audit entries matching filters, ignoring their
+// limit and offset
+func (at *AuditTrail) CountAuditTrail(ctx context.Context, filters AuditQueryFilters) (int64, error) {
+	return at.repo.AuditEntryRepository().CountMatching(filters.toDatabase())
+}
+
+func (filters AuditQueryFilters) toDatabase() database.AuditQueryFilters {
+	return database.AuditQueryFilters{
+		UserID:       filters.UserID,
+		AgentID:      filters.AgentID,
+		ResourceID:   filters.ResourceID,
+		ResourceType: filters.ResourceType,
+		EventType:    string(filters.EventType),
+		Severity:     string(filters.Severity),
+		StartDate:    filters.StartDate,
+		EndDate:      filters.EndDate,
+		IPAddress:    filters.IPAddress,
+		TenantID:     filters.TenantID,
+		Limit:        filters.Limit,
+		Offset:       filters.Offset,
+	}
+}
+
+// GetAuditSummary generates an audit summary report, of the entries about a
+// tenant's agents when tenantID is set
+func (at *AuditTrail) GetAuditSummary(ctx context.Context, tenantID string, startDate, endDate time.Time) (*AuditSummary, error) {
 	entries, err := at.QueryAuditTrail(ctx, AuditQueryFilters{
 		StartDate: &startDate,
 		EndDate:   &endDate,
+		TenantID:  tenantID,
 	})
 	if err != nil {
 		return nil, err
@@ -347,11 +396,13 @@ type AuditSummary struct {
 	ResourceActivity map[string]int         `json:"resourceActivity"`
 }
 
-// GetComplianceReport generates a compliance report
-func (at *AuditTrail) GetComplianceReport(ctx context.Context, startDate, endDate time.Time) (*ComplianceReport, error) {
+// GetComplianceReport generates a compliance report, of the entries about a
+// tenant's agents when tenantID is set
+func (at *AuditTrail) GetComplianceReport(ctx context.Context, tenantID string, startDate, endDate time.Time) (*ComplianceReport, error) {
 	entries, err := at.QueryAuditTrail(ctx, AuditQueryFilters{
 		StartDate: &startDate,
 		EndDate:   &endDate,
+		TenantID:  tenantID,
 	})
 	if err != nil {
 		return nil, err
@@ -409,11 +460,13 @@ func (at *AuditTrail) ArchiveOldEntries(ctx context.Context, beforeDate time.Tim
 	return at.repo.AuditEntryRepository().Archive(beforeDate)
 }
 
-// GetChangeHistory gets the change history for a specific resource
-func (at *AuditTrail) GetChangeHistory(ctx context.Context, resourceID, resourceType string, limit int) ([]*AuditEntry, error) {
+// GetChangeHistory gets the change history for a specific resource, limited
+// to the entries about a tenant's agents when tenantID is set
+func (at *AuditTrail) GetChangeHistory(ctx context.Context, tenantID, resourceID, resourceType string, limit int) ([]*AuditEntry, error) {
 	return at.QueryAuditTrail(ctx, AuditQueryFilters{
 		ResourceID:   resourceID,
 		ResourceType: resourceType,
+		TenantID:     tenantID,
 		Limit:        limit,
 	})
 }
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// recordedKey marks a request whose handler recorded its own audit entry
+const recordedKey = "auditRecorded"
+
+// Middleware records an audit entry for every mutating request that
+// succeeds. Handlers that record a richer entry through Record, with the
+// resource and its before and after values, replace the generic one. It is
+// installed after authentication so the caller is known.
+func Middleware(trail *AuditTrail) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return
+		}
+		if c.GetBool(recordedKey) || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		entry := &AuditEntry{
+			EventType:   AuditAPIRequest,
+			Severity:    SeverityLow,
+			ResourceID:  c.Param("id"),
+			Action:      c.Request.Method,
+			Description: c.Request.Method + " " + c.Request.URL.Path,
+			Metadata: map[string]interface{}{
+				"route":     route,
+				"status":    c.Writer.Status(),
+				"requestId": c.GetString("requestID"),
+			},
+		}
+		Record(c, trail, entry)
+	}
+}
+
+// Record logs an audit entry for a request, filling in the caller, IP
+// address, user agent and correlation ID the entry leaves empty. Failures are
+// logged as well as returned, so handlers that should not fail the request
+// when auditing fails can ignore the error.
+func Record(c *gin.Context, trail *AuditTrail, entry *AuditEntry) error {
+	if principal := common.GetPrincipal(c); principal != nil {
+		if entry.UserID == "" {
+			entry.UserID = principal.Subject
+		}
+		if entry.AgentID == "" {
+			entry.AgentID = principal.AgentID
+		}
+	}
+	if entry.IPAddress == "" {
+		entry.IPAddress = c.ClientIP()
+	}
+	if entry.UserAgent == "" {
+		entry.UserAgent = c.Request.UserAgent()
+	}
+	if len(entry.UserAgent) > 500 {
+		entry.UserAgent = entry.UserAgent[:500]
+	}
+	if entry.CorrelationID == "" {
+		entry.CorrelationID = common.GetCorrelationID(c)
+	}
+	if len(entry.Description) > 500 {
+		entry.Description = entry.Description[:500]
+	}
+
+	c.Set(recordedKey, true)
+	err := trail.LogEvent(c.Request.Context(), entry)
+	if err != nil {
+		common.Error("Failed to record audit entry %s for %s %s: %v", entry.EventType, c.Request.Method, c.Request.URL.Path, err)
+	}
+	return err
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Agent credential types, one per agent identity mode
+const (
+	CredentialTypeOAuthToken  = "oauth_token"
+	CredentialTypeDIDKey      = "did_key"
+	CredentialTypeOAuthClient = "oauth_client" // Client credentials of an OAuth agent
+)
+
+// Agent credential statuses
+const (
+	CredentialStatusActive  = "active"
+	CredentialStatusRotated = "rotated"
+	CredentialStatusRevoked = "revoked"
+)
+
+// DIDMethodPrefix is the DID method under which the platform publishes agent DIDs
+const DIDMethodPrefix = "did:agentpay:"
+
+// DefaultOAuthTokenTTL applies to OAuth agent tokens issued without a TTL
+const DefaultOAuthTokenTTL = 24 * time.Hour
+
+var (
+	ErrCredentialNotFound  = errors.New("credential not found")
+	ErrCredentialNotActive = errors.New("credential is not active")
+	ErrCredentialExpired   = errors.New("credential expired")
+	ErrCredentialMismatch  = errors.New("credential does not belong to agent")
+	ErrInvalidPublicKey    = errors.New("public key must be a base64url Ed25519 key")
+)
+
+// DIDDocument is a W3C DID document describing an agent's verification keys
+type DIDDocument struct {
+	Context            []string             `json:"@context"`
+	ID                 string               `json:"id"`
+	Controller         string               `json:"controller,omitempty"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+	Authentication     []string             `json:"authentication"`
+	AssertionMethod    []string             `json:"assertionMethod"`
+}
+
+// VerificationMethod is a public key entry in a DID document
+type VerificationMethod struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Controller   string `json:"controller"`
+	PublicKeyJwk JWK    `json:"publicKeyJwk"`
+}
+
+// JWK is an Ed25519 JSON Web Key. D is only set on private keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// IssuedAgentCredential is returned once at issuance. Secrets in it are never stored.
+type IssuedAgentCredential struct {
+	Credential   *database.AgentCredential
+	AccessToken  string       // Set for oauth_token credentials
+	ClientSecret string       // Set for oauth_client credentials
+	PrivateKey   *JWK         // Set for did_key credentials
+	DIDDocument  *DIDDocument // Set for did_key credentials
+}
+
+// AgentDID returns the DID of an agent
+func AgentDID(agentID string) string {
+	return DIDMethodPrefix + agentID
+}
+
+// IssueAgentCredential issues a credential matching the agent's identity mode.
+// A zero ttl uses DefaultOAuthTokenTTL for OAuth agents and no expiry for DID agents.
+func IssueAgentCredential(repo database.Repository, cfg *common.AuthConfig, agent *database.Agent, ttl time.Duration) (*IssuedAgentCredential, error) {
+	credential := &database.AgentCredential{
+		AgentID: agent.ID,
+		Status:  CredentialStatusActive,
+	}
+
+	var privateKey ed25519.PrivateKey
+	switch agent.IdentityMode {
+	case "oauth":
+		credential.Type = CredentialTypeOAuthToken
+		if ttl <= 0 {
+			ttl = DefaultOAuthTokenTTL
+		}
+	case "did":
+		credential.Type = CredentialTypeDIDKey
+		publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key pair: %v", err)
+		}
+		privateKey = key
+		credential.DID = AgentDID(agent.ID)
+		credential.PublicKey = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return nil, fmt.Errorf("unsupported identity mode: %s", agent.IdentityMode)
+	}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		credential.ExpiresAt = &expiresAt
+	}
+
+	if err := repo.AgentCredentialRepository().Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to store credential: %v", err)
+	}
+
+	issued := &IssuedAgentCredential{Credential: credential}
+
+	switch credential.Type {
+	case CredentialTypeOAuthToken:
+		token, err := common.SignJWT(common.Claims{
+			Issuer:    cfg.Issuer,
+			Subject:   agent.ID,
+			Type:      common.PrincipalAgent,
+			PartyID:   agent.OwnerPartyID,
+			AgentID:   agent.ID,
+			Scopes:    DefaultAgentScopes,
+			IssuedAt:  credential.CreatedAt.Unix(),
+			ExpiresAt: credential.ExpiresAt.Unix(),
+			ID:        credential.ID,
+		}, cfg.JWTSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign token: %v", err)
+		}
+		issued.AccessToken = token
+	case CredentialTypeDIDKey:
+		issued.PrivateKey = &JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   credential.PublicKey,
+			D:   base64.RawURLEncoding.EncodeToString(privateKey.Seed()),
+			Kid: verificationMethodID(credential),
+		}
+		issued.DIDDocument = BuildDIDDocument(agent, []*database.AgentCredential{credential})
+	}
+
+	return issued, nil
+}
+
+// RegisterDIDKey adds a base64url Ed25519 public key to a DID agent's DID
+// document as a did_key credential. The agent keeps the private key; the
+// platform never sees it. A zero ttl registers a key that does not expire.
+func RegisterDIDKey(repo database.Repository, agent *database.Agent, publicKey string, ttl time.Duration) (*database.AgentCredential, error) {
+	if agent.IdentityMode != "did" {
+		return nil, fmt.Errorf("agent %s does not use a DID", agent.ID)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	credential := &database.AgentCredential{
+		AgentID:   agent.ID,
+		Type:      CredentialTypeDIDKey,
+		Status:    CredentialStatusActive,
+		DID:       AgentDID(agent.ID),
+		PublicKey: publicKey,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		credential.ExpiresAt = &expiresAt
+	}
+	if err := repo.AgentCredentialRepository().Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to store credential: %v", err)
+	}
+	return credential, nil
+}
+
+// RotateAgentCredential issues a replacement credential and retires the old
+// one. OAuth clients are replaced by a client with the same scopes.
+func RotateAgentCredential(repo database.Repository, cfg *common.AuthConfig, agent *database.Agent, credentialID string, ttl time.Duration) (*IssuedAgentCredential, error) {
+	old, err := repo.AgentCredentialRepository().GetByID(credentialID)
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	if old.AgentID != agent.ID {
+		return nil, ErrCredentialMismatch
+	}
+	if old.Status != CredentialStatusActive {
+		return nil, ErrCredentialNotActive
+	}
+
+	var issued *IssuedAgentCredential
+	if old.Type == CredentialTypeOAuthClient {
+		issued, err = IssueOAuthClient(repo, agent, ClientScopes(old), ttl)
+	} else {
+		issued, err = IssueAgentCredential(repo, cfg, agent, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	old.Status = CredentialStatusRotated
+	old.RevokedAt = &now
+	old.ReplacedByID = issued.Credential.ID
+	if err := repo.AgentCredentialRepository().Update(old); err != nil {
+		return nil, fmt.Errorf("failed to retire credential %s: %v", old.ID, err)
+	}
+
+	return issued, nil
+}
+
+// RevokeAgentCredential revokes an agent credential
+func RevokeAgentCredential(repo database.Repository, agentID, credentialID string) (*database.AgentCredential, error) {
+	credential, err := repo.AgentCredentialRepository().GetByID(credentialID)
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	if credential.AgentID != agentID {
+		return nil, ErrCredentialMismatch
+	}
+
+	if credential.Status == CredentialStatusActive {
+		now := time.Now()
+		credential.Status = CredentialStatusRevoked
+		credential.RevokedAt = &now
+		if err := repo.AgentCredentialRepository().Update(credential); err != nil {
+			return nil, err
+		}
+	}
+	return credential, nil
+}
+
+// VerifyAgentCredential verifies a token presented by an agent. OAuth agents present
+// the HS256 access token they were issued; DID agents present a short-lived EdDSA
+// JWS signed with their private key, with the credential ID as "kid".
+func VerifyAgentCredential(repo database.Repository, cfg *common.AuthConfig, token string) (*database.AgentCredential, error) {
+	header, err := parseJWSHeader(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch header.Alg {
+	case "HS256":
+		return verifyOAuthToken(repo, cfg, token)
+	case "EdDSA":
+		return verifyDIDProof(repo, token, header.Kid)
+	default:
+		return nil, common.ErrInvalidToken
+	}
+}
+
+func verifyOAuthToken(repo database.Repository, cfg *common.AuthConfig, token string) (*database.AgentCredential, error) {
+	claims, err := common.ParseJWT(token, cfg.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != common.PrincipalAgent || claims.ID == "" {
+		return nil, common.ErrInvalidToken
+	}
+
+	credential, err := activeCredential(repo, claims.ID, CredentialTypeOAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	if credential.AgentID != claims.AgentID {
+		return nil, ErrCredentialMismatch
+	}
+	return credential, nil
+}
+
+func verifyDIDProof(repo database.Repository, token, kid string) (*database.AgentCredential, error) {
+	// kid is either the bare credential ID or a DID URL ending in "#<credential ID>"
+	credentialID := kid
+	if i := strings.LastIndex(kid, "#"); i >= 0 {
+		credentialID = kid[i+1:]
+	}
+
+	credential, err := activeCredential(repo, credentialID, CredentialTypeDIDKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString(credential.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("credential %s has an invalid public key", credential.ID)
+	}
+
+	parts := strings.Split(token, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, common.ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, common.ErrInvalidToken
+	}
+	var claims common.Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, common.ErrInvalidToken
+	}
+
+	// Proofs must be self-issued by the agent's DID and must expire
+	if claims.Issuer != credential.DID || claims.ExpiresAt == 0 {
+		return nil, common.ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, common.ErrTokenExpired
+	}
+
+	return credential, nil
+}
+
+func activeCredential(repo database.Repository, id, credentialType string) (*database.AgentCredential, error) {
+	credential, err := repo.AgentCredentialRepository().GetByID(id)
+	if err != nil || credential.Type != credentialType {
+		return nil, ErrCredentialNotFound
+	}
+	if credential.Status != CredentialStatusActive {
+		return nil, ErrCredentialNotActive
+	}
+	if credential.ExpiresAt != nil && time.Now().After(*credential.ExpiresAt) {
+		return nil, ErrCredentialExpired
+	}
+	return credential, nil
+}
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func parseJWSHeader(token string) (*jwsHeader, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, common.ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, common.ErrInvalidToken
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, common.ErrInvalidToken
+	}
+	return &header, nil
+}
+
+func verificationMethodID(credential *database.AgentCredential) string {
+	return credential.DID + "#" + credential.ID
+}
+
+// BuildDIDDocument builds the DID document for an agent from its active did_key credentials
+func BuildDIDDocument(agent *database.Agent, credentials []*database.AgentCredential) *DIDDocument {
+	did := AgentDID(agent.ID)
+	document := &DIDDocument{
+		Context:            []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/suites/jws-2020/v1"},
+		ID:                 did,
+		Controller:         DIDMethodPrefix + agent.OwnerPartyID,
+		VerificationMethod: []VerificationMethod{},
+		Authentication:     []string{},
+		AssertionMethod:    []string{},
+	}
+
+	for _, credential := range credentials {
+		if credential.Type != CredentialTypeDIDKey || credential.Status != CredentialStatusActive {
+			continue
+		}
+		if credential.ExpiresAt != nil && time.Now().After(*credential.ExpiresAt) {
+			continue
+		}
+
+		id := verificationMethodID(credential)
+		document.VerificationMethod = append(document.VerificationMethod, VerificationMethod{
+			ID:         id,
+			Type:       "JsonWebKey2020",
+			Controller: did,
+			PublicKeyJwk: JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   credential.PublicKey,
+			},
+		})
+		document.Authentication = append(document.Authentication, id)
+		document.AssertionMethod = append(document.AssertionMethod, id)
+	}
+
+	return document
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// CredentialStore resolves API keys against the api_credentials table
+type CredentialStore struct {
+	repo database.Repository
+}
+
+// NewCredentialStore creates a database-backed credential store
+func NewCredentialStore(repo database.Repository) *CredentialStore {
+	return &CredentialStore{repo: repo}
+}
+
+// LookupAPIKey implements common.CredentialStore
+func (s *CredentialStore) LookupAPIKey(keyHash string) (*common.Principal, error) {
+	credential, err := s.repo.APICredentialRepository().GetByKeyHash(keyHash)
+	if err != nil {
+		return nil, common.ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if credential.RevokedAt != nil {
+		return nil, fmt.Errorf("credential %s revoked", credential.ID)
+	}
+	if credential.ExpiresAt != nil && now.After(*credential.ExpiresAt) {
+		return nil, fmt.Errorf("credential %s expired", credential.ID)
+	}
+
+	// Keys of users carry the user's current roles, and stop working while
+	// the user is disabled
+	var roles []string
+	if credential.UserID != "" {
+		user, err := s.repo.UserRepository().GetByID(credential.UserID)
+		if err != nil || user.Status != UserStatusActive {
+			return nil, fmt.Errorf("user %s of credential %s is not active", credential.UserID, credential.ID)
+		}
+		if roles, err = s.repo.UserRepository().RoleNames(user.ID); err != nil {
+			return nil, fmt.Errorf("failed to load roles of user %s: %v", user.ID, err)
+		}
+	}
+
+	// Best effort usage tracking; authentication must not fail on it
+	credential.LastUsedAt = &now
+	if err := s.repo.APICredentialRepository().Update(credential); err != nil {
+		common.Warn("Failed to record credential usage for %s: %v", credential.ID, err)
+	}
+
+	principal := PrincipalFor(credential)
+	principal.Roles = roles
+	return principal, nil
+}
+
+// TenantOf implements common.TenantResolver
+func (s *CredentialStore) TenantOf(partyID string) (string, error) {
+	return s.repo.PartyRepository().TenantOf(partyID)
+}
+
+// PrincipalFor converts a stored credential into a principal
+func PrincipalFor(credential *database.APICredential) *common.Principal {
+	var scopes []string
+	if credential.Scopes != "" {
+		json.Unmarshal([]byte(credential.Scopes), &scopes)
+	}
+
+	principal := &common.Principal{
+		Subject: credential.PartyID,
+		Type:    common.PrincipalParty,
+		PartyID: credential.PartyID,
+		Scopes:  scopes,
+	}
+	if credential.AgentID != "" {
+		principal.Subject = credential.AgentID
+		principal.Type = common.PrincipalAgent
+		principal.AgentID = credential.AgentID
+	}
+	if credential.UserID != "" {
+		principal.Subject = credential.UserID
+		principal.UserID = credential.UserID
+	}
+	return principal
+}
+
+// IssueAPIKeyRequest describes a new API key
+type IssueAPIKeyRequest struct {
+	Name    string
+	PartyID string
+	AgentID string
+	UserID  string // Issue the key to one of the party's users
+	Scopes  []string
+	TTL     time.Duration // Zero means the key does not expire
+}
+
+// IssueAPIKey creates and stores a new API key, returning the plaintext key.
+// The plaintext is only available at issuance time.
+func IssueAPIKey(repo database.Repository, req IssueAPIKeyRequest) (string, *database.APICredential, error) {
+	key, err := common.GenerateAPIKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %v", err)
+	}
+
+	scopes, err := json.Marshal(req.Scopes)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal scopes: %v", err)
+	}
+
+	credential := &database.APICredential{
+		Name:      req.Name,
+		KeyPrefix: key[:11],
+		KeyHash:   common.HashAPIKey(key),
+		PartyID:   req.PartyID,
+		AgentID:   req.AgentID,
+		UserID:    req.UserID,
+		Scopes:    string(scopes),
+	}
+	if req.TTL > 0 {
+		expiresAt := time.Now().Add(req.TTL)
+		credential.ExpiresAt = &expiresAt
+	}
+
+	if err := repo.APICredentialRepository().Create(credential); err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %v", err)
+	}
+
+	return key, credential, nil
+}
+
+// RevokeAPIKey marks a credential as revoked
+func RevokeAPIKey(repo database.Repository, id string) (*database.APICredential, error) {
+	credential, err := repo.APICredentialRepository().GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.RevokedAt == nil {
+		now := time.Now()
+		credential.RevokedAt = &now
+		if err := repo.APICredentialRepository().Update(credential); err != nil {
+			return nil, err
+		}
+	}
+	return credential, nil
+}
+
+// DefaultPartyScopes are granted to party keys when none are requested
+var DefaultPartyScopes = []string{
+	common.ScopePartiesRead, common.ScopeAgentsRead, common.ScopeAgentsWrite,
+	common.ScopeConsentsRead, common.ScopeConsentsWrite,
+	common.ScopePaymentsRead, common.ScopePaymentsWrite,
+	common.ScopeLedgerRead, common.ScopeMandateKeys,
+	common.ScopeFundingRead, common.ScopeFundingWrite,
+}
+
+// DefaultAgentScopes are granted to agent keys when none are requested
+var DefaultAgentScopes = []string{
+	common.ScopeAgentsRead, common.ScopeConsentsRead,
+	common.ScopePaymentsRead, common.ScopePaymentsWrite,
+	common.ScopeLedgerRead, common.ScopeMandateKeys,
+}
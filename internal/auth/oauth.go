@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// OAuth 2.0 client credentials for agents
+//
+// An agent of identity mode "oauth" may be issued client credentials: a
+// client ID, the credential's ID, and a secret returned once. The agent
+// exchanges them at the token endpoint (client_credentials grant) for
+// short-lived access tokens scoped to the operations it asks for, within
+// those granted to the client. Tokens carry the agent and the client ID, and
+// stop working as soon as the client is revoked or rotated or the agent is
+// no longer active.
+
+// DefaultClientTokenTTL applies to client access tokens when the token
+// endpoint is not configured otherwise
+const DefaultClientTokenTTL = time.Hour
+
+var (
+	ErrInvalidClient = errors.New("invalid client credentials")
+	ErrInvalidScope  = errors.New("scope not granted to client")
+)
+
+// IssueOAuthClient issues client credentials to an OAuth agent, allowing it
+// to request tokens with the given scopes. A zero ttl issues a client that
+// does not expire.
+func IssueOAuthClient(repo database.Repository, agent *database.Agent, scopes []string, ttl time.Duration) (*IssuedAgentCredential, error) {
+	if agent.IdentityMode != "oauth" {
+		return nil, fmt.Errorf("agent %s does not use OAuth", agent.ID)
+	}
+	if len(scopes) == 0 {
+		scopes = DefaultAgentScopes
+	}
+	encoded, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scopes: %v", err)
+	}
+	random, err := common.GenerateRandomString(40)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client secret: %v", err)
+	}
+	secret := "cs_" + random
+
+	credential := &database.AgentCredential{
+		AgentID:    agent.ID,
+		Type:       CredentialTypeOAuthClient,
+		Status:     CredentialStatusActive,
+		SecretHash: common.HashAPIKey(secret),
+		Scopes:     string(encoded),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		credential.ExpiresAt = &expiresAt
+	}
+	if err := repo.AgentCredentialRepository().Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to store credential: %v", err)
+	}
+	return &IssuedAgentCredential{Credential: credential, ClientSecret: secret}, nil
+}
+
+// ClientScopes returns the scopes an OAuth client may request
+func ClientScopes(credential *database.AgentCredential) []string {
+	var scopes []string
+	if credential.Scopes != "" {
+		json.Unmarshal([]byte(credential.Scopes), &scopes)
+	}
+	return scopes
+}
+
+// AuthenticateClient checks an OAuth client's credentials, returning the
+// client and its agent. Clients of agents that are not active do not
+// authenticate.
+func AuthenticateClient(repo database.Repository, clientID, secret string) (*database.AgentCredential, *database.Agent, error) {
+	if clientID == "" || secret == "" {
+		return nil, nil, ErrInvalidClient
+	}
+	credential, err := activeCredential(repo, clientID, CredentialTypeOAuthClient)
+	if err != nil {
+		return nil, nil, ErrInvalidClient
+	}
+	if !hmac.Equal([]byte(credential.SecretHash), []byte(common.HashAPIKey(secret))) {
+		return nil, nil, ErrInvalidClient
+	}
+	agent, err := repo.AgentRepository().GetByID(credential.AgentID)
+	if err != nil || agentstate.Of(agent) != agentstate.Active {
+		return nil, nil, ErrInvalidClient
+	}
+	return credential, agent, nil
+}
+
+// IssueClientToken issues an access token to an authenticated client, valid
+// for ttl but not beyond the client's expiry. Requested scopes must be
+// granted to the client; none requested gets all of them.
+func IssueClientToken(cfg *common.AuthConfig, credential *database.AgentCredential, agent *database.Agent, requested []string, ttl time.Duration) (string, *common.Claims, error) {
+	granted := &common.Principal{Scopes: ClientScopes(credential)}
+	scopes := granted.Scopes
+	if len(requested) > 0 {
+		for _, scope := range requested {
+			if !granted.HasScope(scope) {
+				return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+			}
+		}
+		scopes = requested
+	}
+	if ttl <= 0 {
+		ttl = DefaultClientTokenTTL
+	}
+	if credential.ExpiresAt != nil {
+		if remaining := time.Until(*credential.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	return cfg.IssueToken(&common.Principal{
+		Subject:  agent.ID,
+		Type:     common.PrincipalAgent,
+		PartyID:  agent.OwnerPartyID,
+		AgentID:  agent.ID,
+		Scopes:   scopes,
+		ClientID: credential.ID,
+	}, ttl)
+}
+
+// CheckClientToken reports why a client token no longer holds: its client
+// was revoked, rotated or expired, or its agent is no longer active
+func CheckClientToken(repo database.Repository, claims *common.Claims) error {
+	credential, err := activeCredential(repo, claims.ClientID, CredentialTypeOAuthClient)
+	if err != nil {
+		return err
+	}
+	if credential.AgentID != claims.AgentID {
+		return ErrCredentialMismatch
+	}
+	agent, err := repo.AgentRepository().GetByID(credential.AgentID)
+	if err != nil {
+		return ErrCredentialNotFound
+	}
+	if refusal := agentstate.Refusal(agent); refusal != "" {
+		return errors.New(refusal)
+	}
+	return nil
+}
+
+// ValidateToken implements common.TokenValidator, so services check client
+// tokens against the database as they authenticate them
+func (s *CredentialStore) ValidateToken(ctx context.Context, token string, claims *common.Claims) error {
+	return CheckClientToken(s.repo, claims)
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// User statuses
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
+// Roles are the roles users may be assigned, which routes check with
+// common.RequireRoles
+var Roles = []*database.Role{
+	{Name: common.RoleAdmin, Description: "Manages the party's users, their roles and approver groups, and holds every other role"},
+	{Name: common.RoleApprover, Description: "Decides payment approvals of the approver groups the user is a member of"},
+	{Name: common.RoleReviewer, Description: "Decides compliance screening, risk case and KYC reviews"},
+}
+
+// SeedRoles stores the roles, so they can be listed and assigned
+func SeedRoles(repo database.Repository) error {
+	roles := make([]*database.Role, 0, len(Roles))
+	for _, role := range Roles {
+		roles = append(roles, &database.Role{Name: role.Name, Description: role.Description})
+	}
+	return repo.RoleRepository().Seed(roles)
+}
+
+// KnownRole reports whether a role exists
+func KnownRole(name string) bool {
+	for _, role := range Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -75,24 +75,71 @@ type OutstandingItem struct {
 	Type        string    `json:"type"` // "check", "deposit"
 }
 
-// GetAccountBalance gets detailed balance information for an account
-func (bc *BalanceCalculator) GetAccountBalance(accountID string) (*AccountBalance, error) {
-	account, err := bc.repo.AccountRepository().GetByID(accountID)
+// CurrentBalance is an account's balance derived from its postings
+func (bc *BalanceCalculator) CurrentBalance(account *database.Account) (float64, error) {
+	balance, err := bc.repo.AccountRepository().DerivedBalance(account.ID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("account not found: %s", accountID)
+		return 0, fmt.Errorf("failed to sum postings of account %s: %v", account.ID, err)
+	}
+	return balance, nil
+}
+
+// BalanceAsOf is an account's balance derived from the postings made up to a time
+func (bc *BalanceCalculator) BalanceAsOf(account *database.Account, asOf time.Time) (float64, error) {
+	balance, err := bc.repo.AccountRepository().DerivedBalance(account.ID, &asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum postings of account %s: %v", account.ID, err)
+	}
+	return balance, nil
+}
+
+// AvailableBalance is an account's balance less its active holds
+func (bc *BalanceCalculator) AvailableBalance(account *database.Account) (float64, error) {
+	balance, err := bc.CurrentBalance(account)
+	if err != nil {
+		return 0, err
+	}
+	held, err := bc.repo.HoldRepository().ActiveTotal(account.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get holds on account %s: %v", account.ID, err)
 	}
+	return balance - held, nil
+}
 
-	balance := &AccountBalance{
+// accountBalance reports an account's current and available balances
+func (bc *BalanceCalculator) accountBalance(account *database.Account) (AccountBalance, error) {
+	current, err := bc.CurrentBalance(account)
+	if err != nil {
+		return AccountBalance{}, err
+	}
+	available, err := bc.AvailableBalance(account)
+	if err != nil {
+		return AccountBalance{}, err
+	}
+	return AccountBalance{
 		AccountID:        account.ID,
 		AccountName:      account.Name,
 		AccountType:      account.Type,
-		CurrentBalance:   account.Balance,
-		AvailableBalance: account.Balance, // For now, same as current
+		CurrentBalance:   current,
+		AvailableBalance: available,
 		Currency:         account.Currency,
 		LastUpdated:      account.UpdatedAt,
+	}, nil
+}
+
+// GetAccountBalance gets detailed balance information for an account
+func (bc *BalanceCalculator) GetAccountBalance(accountID string) (*AccountBalance, error) {
+	account, err := bc.repo.AccountRepository().GetByID(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %s", accountID)
 	}
 
-	return balance, nil
+	balance, err := bc.accountBalance(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
 }
 
 // GetAgentBalances gets all balances for an agent
@@ -104,14 +151,9 @@ func (bc *BalanceCalculator) GetAgentBalances(agentID string) ([]AccountBalance,
 
 	var balances []AccountBalance
 	for _, account := range accounts {
-		balance := AccountBalance{
-			AccountID:        account.ID,
-			AccountName:      account.Name,
-			AccountType:      account.Type,
-			CurrentBalance:   account.Balance,
-			AvailableBalance: account.Balance,
-			Currency:         account.Currency,
-			LastUpdated:      account.UpdatedAt,
+		balance, err := bc.accountBalance(account)
+		if err != nil {
+			return nil, err
 		}
 		balances = append(balances, balance)
 	}
@@ -135,26 +177,24 @@ func (bc *BalanceCalculator) GenerateBalanceSheet(agentID string, asOfDate time.
 	}
 
 	for _, account := range accounts {
-		balance := AccountBalance{
-			AccountID:        account.ID,
-			AccountName:      account.Name,
-			AccountType:      account.Type,
-			CurrentBalance:   account.Balance,
-			AvailableBalance: account.Balance,
-			Currency:         account.Currency,
-			LastUpdated:      account.UpdatedAt,
+		balance, err := bc.accountBalance(account)
+		if err != nil {
+			return nil, err
+		}
+		if balance.CurrentBalance, err = bc.BalanceAsOf(account, asOfDate); err != nil {
+			return nil, err
 		}
 
 		switch account.Type {
 		case "asset":
 			bs.Assets = append(bs.Assets, balance)
-			bs.TotalAssets += account.Balance
+			bs.TotalAssets += balance.CurrentBalance
 		case "liability":
 			bs.Liabilities = append(bs.Liabilities, balance)
-			bs.TotalLiabilities += account.Balance
+			bs.TotalLiabilities += balance.CurrentBalance
 		case "equity":
 			bs.Equity = append(bs.Equity, balance)
-			bs.TotalEquity += account.Balance
+			bs.TotalEquity += balance.CurrentBalance
 		}
 	}
 
@@ -179,35 +219,34 @@ func (bc *BalanceCalculator) GenerateTrialBalance(agentID string, startDate, end
 	}
 
 	for _, account := range accounts {
-		balance := AccountBalance{
-			AccountID:        account.ID,
-			AccountName:      account.Name,
-			AccountType:      account.Type,
-			CurrentBalance:   account.Balance,
-			AvailableBalance: account.Balance,
-			Currency:         account.Currency,
-			LastUpdated:      account.UpdatedAt,
+		balance, err := bc.accountBalance(account)
+		if err != nil {
+			return nil, err
+		}
+		if balance.CurrentBalance, err = bc.BalanceAsOf(account, endDate); err != nil {
+			return nil, err
 		}
+		amount := balance.CurrentBalance
 
 		// Classify based on normal balance type
 		switch account.Type {
 		case "asset", "expense":
 			// Assets and expenses normally have debit balances
-			if account.Balance >= 0 {
+			if amount >= 0 {
 				tb.DebitBalances = append(tb.DebitBalances, balance)
-				tb.TotalDebits += account.Balance
+				tb.TotalDebits += amount
 			} else {
 				tb.CreditBalances = append(tb.CreditBalances, balance)
-				tb.TotalCredits += -account.Balance
+				tb.TotalCredits += -amount
 			}
 		case "liability", "equity", "revenue":
 			// Liabilities, equity, and revenue normally have credit balances
-			if account.Balance >= 0 {
+			if amount >= 0 {
 				tb.CreditBalances = append(tb.CreditBalances, balance)
-				tb.TotalCredits += account.Balance
+				tb.TotalCredits += amount
 			} else {
 				tb.DebitBalances = append(tb.DebitBalances, balance)
-				tb.TotalDebits += -account.Balance
+				tb.TotalDebits += -amount
 			}
 		}
 	}
@@ -237,8 +276,13 @@ func (bc *BalanceCalculator) ReconcileAccount(accountID string, bankBalance floa
 		return nil, fmt.Errorf("failed to get deposits in transit: %v", err)
 	}
 
+	bookBalance, err := bc.BalanceAsOf(account, reconciliationDate)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate reconciled balance
-	reconciledBalance := account.Balance
+	reconciledBalance := bookBalance
 	for _, check := range outstandingChecks {
 		reconciledBalance += check.Amount // Add back outstanding checks
 	}
@@ -248,7 +292,7 @@ func (bc *BalanceCalculator) ReconcileAccount(accountID string, bankBalance floa
 
 	reconciliation := &BalanceReconciliation{
 		AccountID:          accountID,
-		BookBalance:        account.Balance,
+		BookBalance:        bookBalance,
 		BankBalance:        bankBalance,
 		OutstandingChecks:  outstandingChecks,
 		DepositsInTransit:  depositsInTransit,
@@ -260,18 +304,34 @@ func (bc *BalanceCalculator) ReconcileAccount(accountID string, bankBalance floa
 	return reconciliation, nil
 }
 
-// GetBalanceHistory gets historical balance data for an account
+// GetBalanceHistory gets an account's end-of-day UTC balances from the start date
+// through the end date. Holds are not versioned, so past balances carry no
+// available balance.
 func (bc *BalanceCalculator) GetBalanceHistory(accountID string, startDate, endDate time.Time) ([]AccountBalance, error) {
-	// This would typically query transaction history to reconstruct balances
-	// For now, return current balance as a placeholder
-	currentBalance, err := bc.GetAccountBalance(accountID)
+	account, err := bc.repo.AccountRepository().GetByID(accountID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("account not found: %s", accountID)
 	}
 
-	// In a real implementation, this would reconstruct balance history
-	// by replaying transactions from the start date
-	history := []AccountBalance{*currentBalance}
+	history := []AccountBalance{}
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		asOf := day.Truncate(24 * time.Hour).Add(24*time.Hour - time.Nanosecond)
+		if asOf.After(endDate) {
+			asOf = endDate
+		}
+		balance, err := bc.BalanceAsOf(account, asOf)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, AccountBalance{
+			AccountID:      account.ID,
+			AccountName:    account.Name,
+			AccountType:    account.Type,
+			CurrentBalance: balance,
+			Currency:       account.Currency,
+			LastUpdated:    asOf,
+		})
+	}
 
 	return history, nil
 }
@@ -309,9 +369,13 @@ func (bc *BalanceCalculator) ValidateBalanceIntegrity(agentID string) (map[strin
 
 	// Check for accounts with invalid balances
 	for _, account := range accounts {
-		if account.Balance < -1000000 || account.Balance > 1000000 {
+		balance, err := bc.CurrentBalance(account)
+		if err != nil {
+			return nil, err
+		}
+		if balance < -1000000 || balance > 1000000 {
 			validation["issues"] = append(validation["issues"].([]string),
-				fmt.Sprintf("Account %s has suspicious balance: %.2f", account.ID, account.Balance))
+				fmt.Sprintf("Account %s has suspicious balance: %.2f", account.ID, balance))
 			validation["isValid"] = false
 		}
 	}
@@ -331,6 +395,16 @@ func (bc *BalanceCalculator) ValidateBalanceIntegrity(agentID string) (map[strin
 		}
 	}
 
+	// Check double-entry invariants
+	violations, err := bc.CheckLedgerInvariants(agentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, violation := range violations {
+		validation["issues"] = append(validation["issues"].([]string), violation.Error())
+		validation["isValid"] = false
+	}
+
 	return validation, nil
 }
 
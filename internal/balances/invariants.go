@@ -0,0 +1,163 @@
+package balances
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Ledger invariants
+const (
+	InvariantTransactionBalanced = "transaction_balanced"
+	InvariantAccountBalance      = "account_balance_matches_postings"
+	InvariantTrialBalance        = "trial_balance_balanced"
+	InvariantVoidNegates         = "void_negates_original"
+)
+
+// InvariantViolation describes a broken ledger invariant
+type InvariantViolation struct {
+	Invariant string `json:"invariant"`
+	SubjectID string `json:"subjectId"` // Transaction or account the violation applies to
+	Detail    string `json:"detail"`
+}
+
+func (v InvariantViolation) Error() string {
+	return fmt.Sprintf("%s violated for %s: %s", v.Invariant, v.SubjectID, v.Detail)
+}
+
+// toCents converts an amount to integer cents so comparisons are exact
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// CheckTransactionBalanced verifies that a transaction's postings sum to zero per currency
+func CheckTransactionBalanced(tx *database.Transaction, postings []*database.Posting) *InvariantViolation {
+	totals := make(map[string]int64)
+	for _, posting := range postings {
+		totals[posting.Currency] += toCents(posting.Amount)
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return &InvariantViolation{
+				Invariant: InvariantTransactionBalanced,
+				SubjectID: tx.ID,
+				Detail:    fmt.Sprintf("%s postings sum to %.2f", currency, float64(total)/100),
+			}
+		}
+	}
+	return nil
+}
+
+// CheckAccountBalance verifies that an account's derived balance, its latest
+// snapshot plus the postings since, equals the sum of all of its postings.
+// The stored Account.Balance is a cache and is not checked.
+func CheckAccountBalance(account *database.Account, derived float64, postings []*database.Posting) *InvariantViolation {
+	var total int64
+	for _, posting := range postings {
+		total += toCents(posting.Amount)
+	}
+
+	if total != toCents(derived) {
+		return &InvariantViolation{
+			Invariant: InvariantAccountBalance,
+			SubjectID: account.ID,
+			Detail:    fmt.Sprintf("derived balance %.2f but postings sum to %.2f", derived, float64(total)/100),
+		}
+	}
+	return nil
+}
+
+// CheckTrialBalance verifies that signed account balances, derived and keyed by
+// account ID, net to zero per currency. Balances are debit-positive, so a
+// balanced ledger always nets out.
+func CheckTrialBalance(agentID string, accounts []*database.Account, derived map[string]float64) *InvariantViolation {
+	totals := make(map[string]int64)
+	for _, account := range accounts {
+		totals[account.Currency] += toCents(derived[account.ID])
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return &InvariantViolation{
+				Invariant: InvariantTrialBalance,
+				SubjectID: agentID,
+				Detail:    fmt.Sprintf("%s debits exceed credits by %.2f", currency, float64(total)/100),
+			}
+		}
+	}
+	return nil
+}
+
+// CheckVoidNegates verifies that a void transaction exactly reverses the original,
+// leaving every account untouched once both are applied
+func CheckVoidNegates(original *database.Transaction, originalPostings []*database.Posting, void *database.Transaction, voidPostings []*database.Posting) *InvariantViolation {
+	net := make(map[string]int64)
+	for _, posting := range originalPostings {
+		net[posting.AccountID+"/"+posting.Currency] += toCents(posting.Amount)
+	}
+	for _, posting := range voidPostings {
+		net[posting.AccountID+"/"+posting.Currency] += toCents(posting.Amount)
+	}
+
+	for key, total := range net {
+		if total != 0 {
+			return &InvariantViolation{
+				Invariant: InvariantVoidNegates,
+				SubjectID: void.ID,
+				Detail:    fmt.Sprintf("leaves %.2f on %s after voiding %s", float64(total)/100, key, original.ID),
+			}
+		}
+	}
+	return nil
+}
+
+// CheckLedgerInvariants checks the transaction, account and trial balance invariants
+// for all of an agent's accounts
+func (bc *BalanceCalculator) CheckLedgerInvariants(agentID string) ([]InvariantViolation, error) {
+	accounts, err := bc.repo.AccountRepository().ListByAgentID(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %v", err)
+	}
+
+	violations := []InvariantViolation{}
+
+	derived := make(map[string]float64, len(accounts))
+	for _, account := range accounts {
+		if derived[account.ID], err = bc.CurrentBalance(account); err != nil {
+			return nil, err
+		}
+		postings, err := bc.repo.PostingRepository().ListByAccountID(account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get postings for account %s: %v", account.ID, err)
+		}
+		if v := CheckAccountBalance(account, derived[account.ID], postings); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+
+	transactions, err := bc.repo.TransactionRepository().ListByAgentID(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %v", err)
+	}
+
+	for _, tx := range transactions {
+		if tx.Status != "posted" {
+			continue
+		}
+		postings, err := bc.repo.PostingRepository().ListByTransactionID(tx.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get postings for transaction %s: %v", tx.ID, err)
+		}
+		if v := CheckTransactionBalanced(tx, postings); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+
+	if v := CheckTrialBalance(agentID, accounts, derived); v != nil {
+		violations = append(violations, *v)
+	}
+
+	return violations, nil
+}
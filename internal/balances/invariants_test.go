@@ -0,0 +1,249 @@
+package balances
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+)
+
+// Property tests: random sequences of postings, transfers and voids are run
+// through TransactionRepository().Post and every invariant must hold after
+// each sequence, including after postings the overdraft policies reject.
+
+// Accounts of the ledger under test, by index into ledgerAccounts
+const (
+	accountCash = iota
+	accountWallet
+	accountReserve
+	accountPayable
+	accountFunding
+	accountExpense
+)
+
+var ledgerAccounts = []struct {
+	name, accountType string
+}{
+	{"Cash", "asset"},
+	{"Wallet", "asset"},
+	{"Reserve", "asset"},
+	{"Payable", "liability"},
+	{"Funding", "equity"},
+	{"Expense", "expense"},
+}
+
+// Kinds of ledger operation
+const (
+	opPost     = iota // Debit one account and credit another
+	opTransfer        // Move funds between two asset accounts
+	opVoid            // Reverse an earlier posted transaction
+	opKinds
+)
+
+type ledgerOp struct {
+	Kind     int
+	From, To int   // Accounts credited and debited
+	Cents    int64 // Amount moved, from 0.01 to 500.00
+	Target   int   // Earlier transaction a void reverses, modulo those posted
+}
+
+// ledgerScript is a random sequence of ledger operations
+type ledgerScript []ledgerOp
+
+func (ledgerScript) Generate(r *rand.Rand, size int) reflect.Value {
+	assets := []int{accountCash, accountWallet, accountReserve}
+	script := make(ledgerScript, 1+r.Intn(size+1))
+	for i := range script {
+		op := ledgerOp{Kind: r.Intn(opKinds), Cents: 1 + r.Int63n(50000), Target: r.Intn(1 << 16)}
+		switch op.Kind {
+		case opTransfer:
+			op.From = assets[r.Intn(len(assets))]
+			op.To = assets[r.Intn(len(assets))]
+		default:
+			op.From = r.Intn(len(ledgerAccounts))
+			op.To = r.Intn(len(ledgerAccounts))
+		}
+		script[i] = op
+	}
+	return reflect.ValueOf(script)
+}
+
+// ledgerFixture is an agent with one account of each kind
+type ledgerFixture struct {
+	repo     database.Repository
+	agentID  string
+	accounts []*database.Account
+}
+
+func newLedgerFixture(t *testing.T, repo database.Repository) *ledgerFixture {
+	t.Helper()
+	party := &database.Party{Name: "Invariant Party", Type: "organization"}
+	if err := repo.PartyRepository().Create(party); err != nil {
+		t.Fatalf("create party: %v", err)
+	}
+	agent := &database.Agent{DisplayName: "Invariant Agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+	if err := repo.AgentRepository().Create(agent); err != nil {
+		t.Fatalf("create agent: %v", err)
+	}
+
+	fixture := &ledgerFixture{repo: repo, agentID: agent.ID}
+	for _, spec := range ledgerAccounts {
+		account := &database.Account{AgentID: agent.ID, Name: spec.name, Type: spec.accountType, Currency: "USD"}
+		if err := repo.AccountRepository().Create(account); err != nil {
+			t.Fatalf("create account %s: %v", spec.name, err)
+		}
+		fixture.accounts = append(fixture.accounts, account)
+	}
+	return fixture
+}
+
+// post posts a transaction of the postings. A posting the overdraft policies
+// reject is not an error; the transaction must then leave no trace.
+func (f *ledgerFixture) post(description, reference string, postings []*database.Posting) (*database.Transaction, error) {
+	tx := &database.Transaction{AgentID: f.agentID, Description: description, ReferenceID: reference, Status: "posted"}
+	err := f.repo.TransactionRepository().Post(tx, postings)
+	if errors.Is(err, database.ErrInsufficientFunds) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (f *ledgerFixture) leg(account int, cents int64) *database.Posting {
+	return &database.Posting{AccountID: f.accounts[account].ID, Amount: float64(cents) / 100, Currency: "USD"}
+}
+
+// voidPair is a transaction and the void reversing it
+type voidPair struct {
+	original, void *database.Transaction
+}
+
+// run applies the script, returning the voids it posted
+func (f *ledgerFixture) run(script ledgerScript) ([]voidPair, error) {
+	var posted []*database.Transaction
+	var voids []voidPair
+	for _, op := range script {
+		var tx *database.Transaction
+		var err error
+		switch op.Kind {
+		case opPost, opTransfer:
+			tx, err = f.post("Property test", "", []*database.Posting{f.leg(op.To, op.Cents), f.leg(op.From, -op.Cents)})
+		case opVoid:
+			if len(posted) == 0 {
+				continue
+			}
+			original := posted[op.Target%len(posted)]
+			originalPostings, err := f.repo.PostingRepository().ListByTransactionID(original.ID)
+			if err != nil {
+				return nil, err
+			}
+			reversing := make([]*database.Posting, len(originalPostings))
+			for i, posting := range originalPostings {
+				reversing[i] = &database.Posting{AccountID: posting.AccountID, Amount: -posting.Amount, Currency: posting.Currency}
+			}
+			if tx, err = f.post("Void of "+original.ID, original.ID+":void", reversing); err == nil && tx != nil {
+				voids = append(voids, voidPair{original, tx})
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tx != nil {
+			posted = append(posted, tx)
+		}
+	}
+	return voids, nil
+}
+
+func TestLedgerInvariantsHoldForRandomOperations(t *testing.T) {
+	repo, _ := dbtest.Open(t)
+	calculator := NewBalanceCalculator(repo)
+
+	property := func(script ledgerScript) bool {
+		fixture := newLedgerFixture(t, repo)
+		voids, err := fixture.run(script)
+		if err != nil {
+			t.Logf("posting failed: %v", err)
+			return false
+		}
+
+		// Transaction balanced, account balance and trial balance
+		violations, err := calculator.CheckLedgerInvariants(fixture.agentID)
+		if err != nil {
+			t.Logf("checking invariants failed: %v", err)
+			return false
+		}
+		for _, void := range voids {
+			originalPostings, err := repo.PostingRepository().ListByTransactionID(void.original.ID)
+			if err != nil {
+				t.Logf("listing postings failed: %v", err)
+				return false
+			}
+			voidPostings, err := repo.PostingRepository().ListByTransactionID(void.void.ID)
+			if err != nil {
+				t.Logf("listing postings failed: %v", err)
+				return false
+			}
+			if v := CheckVoidNegates(void.original, originalPostings, void.void, voidPostings); v != nil {
+				violations = append(violations, *v)
+			}
+		}
+		for _, violation := range violations {
+			t.Logf("%d operations: %v", len(script), violation)
+		}
+		return len(violations) == 0
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverdraftRejectionLeavesNoTrace(t *testing.T) {
+	repo, _ := dbtest.Open(t)
+	fixture := newLedgerFixture(t, repo)
+
+	// The wallet is an asset with nothing in it, so it may not be credited
+	tx, err := fixture.post("Overdraw", "", []*database.Posting{fixture.leg(accountExpense, 100), fixture.leg(accountWallet, -100)})
+	if err != nil || tx != nil {
+		t.Fatalf("overdraft: got %v, %v; want it rejected", tx, err)
+	}
+	transactions, err := repo.TransactionRepository().ListByAgentID(fixture.agentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Fatalf("a rejected transaction left %d transactions", len(transactions))
+	}
+}
+
+func TestCheckersReportViolations(t *testing.T) {
+	account := &database.Account{ID: "account-1", Currency: "USD"}
+	postings := []*database.Posting{{AccountID: "account-1", Amount: 10, Currency: "USD"}, {AccountID: "account-2", Amount: -9.99, Currency: "USD"}}
+
+	if v := CheckTransactionBalanced(&database.Transaction{ID: "tx-1"}, postings); v == nil || v.Invariant != InvariantTransactionBalanced {
+		t.Fatalf("unbalanced transaction: got %v", v)
+	}
+	// A stale cached balance is not a violation; a derived one that differs is
+	account.Balance = 99
+	if v := CheckAccountBalance(account, 10, postings[:1]); v != nil {
+		t.Fatalf("stale cached balance reported: %v", v)
+	}
+	if v := CheckAccountBalance(account, 9.99, postings[:1]); v == nil || v.Invariant != InvariantAccountBalance {
+		t.Fatalf("wrong derived balance: got %v", v)
+	}
+	accounts := []*database.Account{account, {ID: "account-2", Currency: "USD"}}
+	if v := CheckTrialBalance("agent-1", accounts, map[string]float64{"account-1": 10, "account-2": -9.99}); v == nil || v.Invariant != InvariantTrialBalance {
+		t.Fatalf("unbalanced trial balance: got %v", v)
+	}
+	void := []*database.Posting{{AccountID: "account-1", Amount: -10, Currency: "USD"}, {AccountID: "account-2", Amount: 9.98, Currency: "USD"}}
+	if v := CheckVoidNegates(&database.Transaction{ID: "tx-1"}, postings, &database.Transaction{ID: "tx-2"}, void); v == nil || v.Invariant != InvariantVoidNegates {
+		t.Fatalf("partial void: got %v", v)
+	}
+}
@@ -0,0 +1,84 @@
+package balances
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// BalanceDrift is a difference between an account's stored balance and the
+// balance derived from its postings
+type BalanceDrift struct {
+	AccountID      string  `json:"accountId"`
+	AgentID        string  `json:"agentId"`
+	Currency       string  `json:"currency"`
+	StoredBalance  float64 `json:"storedBalance"`
+	DerivedBalance float64 `json:"derivedBalance"`
+	Drift          float64 `json:"drift"` // Stored less derived
+}
+
+// SnapshotBalance records an account's balance derived from the postings made
+// up to a time. Postings must no longer be arriving with earlier creation
+// times, so callers snapshot a little in the past.
+func (bc *BalanceCalculator) SnapshotBalance(account *database.Account, asOf time.Time) (*database.BalanceSnapshot, error) {
+	balance, err := bc.BalanceAsOf(account, asOf)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &database.BalanceSnapshot{
+		AccountID: account.ID,
+		Balance:   balance,
+		AsOf:      asOf,
+	}
+	if err := bc.repo.BalanceSnapshotRepository().Create(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot balance of account %s: %v", account.ID, err)
+	}
+	return snapshot, nil
+}
+
+// FindDrift compares the stored and derived balances of accounts. A posting
+// can land between reading the two, so an account that differs is read again
+// and only reported if it still differs.
+func (bc *BalanceCalculator) FindDrift(accounts []*database.Account) ([]BalanceDrift, error) {
+	drifts := []BalanceDrift{}
+	for _, account := range accounts {
+		drift, err := bc.accountDrift(account)
+		if err != nil {
+			return nil, err
+		}
+		if drift == nil {
+			continue
+		}
+
+		reloaded, err := bc.repo.AccountRepository().GetByID(account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reload account %s: %v", account.ID, err)
+		}
+		if drift, err = bc.accountDrift(reloaded); err != nil {
+			return nil, err
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+	return drifts, nil
+}
+
+func (bc *BalanceCalculator) accountDrift(account *database.Account) (*BalanceDrift, error) {
+	derived, err := bc.CurrentBalance(account)
+	if err != nil {
+		return nil, err
+	}
+	if toCents(account.Balance) == toCents(derived) {
+		return nil, nil
+	}
+	return &BalanceDrift{
+		AccountID:      account.ID,
+		AgentID:        account.AgentID,
+		Currency:       account.Currency,
+		StoredBalance:  account.Balance,
+		DerivedBalance: derived,
+		Drift:          float64(toCents(account.Balance)-toCents(derived)) / 100,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package budgets
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/spending"
+)
+
+// What a budget does with what it has not spent when its period ends
+const (
+	CarryOverNone    = "none"
+	CarryOverUnspent = "unspent" // All of it
+	CarryOverCapped  = "capped"  // Up to the budget's MaxCarryOverUSD
+)
+
+// ValidCarryOver reports whether a budget can use a carry-over option
+func ValidCarryOver(option string) bool {
+	switch option {
+	case CarryOverNone, CarryOverUnspent, CarryOverCapped:
+		return true
+	}
+	return false
+}
+
+// Covers reports whether a payment's category falls under the budget. A
+// budget without a category covers all of its agent's payments.
+func Covers(budget *database.Budget, category string) bool {
+	return budget.Category == "" || strings.EqualFold(budget.Category, category)
+}
+
+// Available returns what the budget allows in its current period: its
+// amount plus what it carried over
+func Available(budget *database.Budget) float64 {
+	return round2(budget.AmountUSD + budget.CarriedOverUSD)
+}
+
+// Spent totals the agent's payments the budget covers in a period. As with
+// spending limits, payments that were cancelled or failed do not count and
+// those in flight do. The workflow being checked is left out when
+// excludeWorkflowID is set.
+func Spent(budget *database.Budget, workflows []*database.PaymentWorkflow, excludeWorkflowID string, start, end time.Time) float64 {
+	spent := 0.0
+	for _, workflow := range workflows {
+		if workflow.ID == excludeWorkflowID || workflow.AgentID != budget.AgentID || !Covers(budget, workflow.Category) {
+			continue
+		}
+		if workflow.Status == "cancelled" || workflow.Status == "failed" {
+			continue
+		}
+		if workflow.CreatedAt.Before(start) || !workflow.CreatedAt.Before(end) {
+			continue
+		}
+		spent += workflow.AmountUSD
+	}
+	return round2(spent)
+}
+
+// Exceeds reports whether a payment would take the spend over what the
+// budget has available
+func Exceeds(budget *database.Budget, spent, amountUSD float64) bool {
+	return round2(spent+amountUSD) > Available(budget)
+}
+
+// Remaining returns what the budget has left after the spend
+func Remaining(budget *database.Budget, spent float64) float64 {
+	return math.Max(0, round2(Available(budget)-spent))
+}
+
+// CarryOver returns what the budget carries into its next period after
+// spending the amount in its current one
+func CarryOver(budget *database.Budget, spent float64) float64 {
+	unspent := Remaining(budget, spent)
+	switch budget.CarryOver {
+	case CarryOverUnspent:
+		return unspent
+	case CarryOverCapped:
+		return math.Min(unspent, budget.MaxCarryOverUSD)
+	default:
+		return 0
+	}
+}
+
+// Start sets a new budget's current period to the one containing now
+func Start(budget *database.Budget, now time.Time) {
+	budget.PeriodStart, budget.PeriodEnd = spending.Window(budget.Period, now)
+	budget.CarriedOverUSD = 0
+}
+
+// Rollover closes each period of the budget that has ended by now, moving it
+// on to the period containing now with what it carries over. spentIn returns
+// the spend of a closed period. The closed periods are returned, oldest
+// first; none if the current period has not ended.
+func Rollover(budget *database.Budget, now time.Time, spentIn func(start, end time.Time) float64) []*database.BudgetPeriod {
+	var closed []*database.BudgetPeriod
+	for !budget.PeriodEnd.After(now) {
+		spent := spentIn(budget.PeriodStart, budget.PeriodEnd)
+		carried := CarryOver(budget, spent)
+		closed = append(closed, &database.BudgetPeriod{
+			BudgetID:      budget.ID,
+			PeriodStart:   budget.PeriodStart,
+			PeriodEnd:     budget.PeriodEnd,
+			AmountUSD:     budget.AmountUSD,
+			CarriedInUSD:  budget.CarriedOverUSD,
+			SpentUSD:      spent,
+			CarriedOutUSD: carried,
+		})
+		budget.PeriodStart, budget.PeriodEnd = spending.Window(budget.Period, budget.PeriodEnd)
+		budget.CarriedOverUSD = carried
+	}
+	return closed
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
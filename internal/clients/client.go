@@ -0,0 +1,178 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/libs/common"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+)
+
+// Service clients
+//
+// Services call each other through a typed client per downstream service.
+// Each client finds its service through a discovery.Resolver, authenticates
+// with a service token and passes on the caller's trace and correlation ID.
+// Calls go through a common.ResilientClient, which gives each service a
+// circuit breaker and retries calls that are safe to repeat.
+//
+// The calls payment processing makes to the risk, consent and router services
+// go over gRPC when the service's <NAME>_GRPC_ADDR is set, and over REST
+// while its gRPC server cannot be reached. Responses are returned the same
+// way either way.
+
+// ErrServiceUnavailable marks a call that failed because the service could
+// not be reached or failed itself, as opposed to rejecting the request
+var ErrServiceUnavailable = common.ErrServiceUnavailable
+
+// Config is shared by the clients of one calling service
+type Config struct {
+	Resolver discovery.Resolver
+	Token    func() (string, error) // Service token to call with
+	HTTP     *common.ResilientClient
+}
+
+// NewConfigFromEnv creates a config whose clients share a resilient HTTP
+// client configured from the environment
+func NewConfigFromEnv(resolver discovery.Resolver, token func() (string, error)) Config {
+	return Config{
+		Resolver: resolver,
+		Token:    token,
+		HTTP:     common.NewResilientClient(common.NewResilienceConfigFromEnv()),
+	}
+}
+
+// Client calls one service's API
+type Client struct {
+	service string
+	config  Config
+	conn    *grpc.ClientConn // Set when the service is called over gRPC
+}
+
+// New creates a client for the named service
+func New(service string, config Config) *Client {
+	client := &Client{service: service, config: config}
+	if addr := discovery.GRPCAddr(service); addr != "" {
+		conn, err := rpc.Dial(addr, config.Token)
+		if err != nil {
+			common.Warn("Calling %s over REST, invalid gRPC address %s: %v", service, addr, err)
+		} else {
+			client.conn = conn
+		}
+	}
+	return client
+}
+
+// viaGRPC makes a call over gRPC when the service is called over gRPC,
+// returning its result as the data of a response. It reports false when the
+// call is to be made over REST instead, as it is while the gRPC server
+// cannot be reached.
+func (c *Client) viaGRPC(call func(conn *grpc.ClientConn) (interface{}, error)) (*common.APIResponse, bool, error) {
+	if c.conn == nil {
+		return nil, false, nil
+	}
+	data, err := call(c.conn)
+	if err == nil {
+		return &common.APIResponse{Success: true, Data: data}, true, nil
+	}
+	if rpc.Unreachable(err) {
+		common.Warn("gRPC call to %s failed, falling back to REST: %v", c.service, err)
+		return nil, false, nil
+	}
+	return nil, true, rpc.ClientError(err)
+}
+
+// Service returns the name of the service the client calls
+func (c *Client) Service() string {
+	return c.service
+}
+
+// Breaker returns the circuit breaker of the client's service
+func (c *Client) Breaker() *common.CircuitBreaker {
+	return c.config.HTTP.Breaker(c.service)
+}
+
+// Get calls GET on a path of the service
+func (c *Client) Get(ctx context.Context, path string) (*common.APIResponse, error) {
+	return c.Do(ctx, http.MethodGet, path, nil)
+}
+
+// Post calls POST on a path of the service with a JSON payload
+func (c *Client) Post(ctx context.Context, path string, payload interface{}) (*common.APIResponse, error) {
+	return c.Do(ctx, http.MethodPost, path, payload)
+}
+
+// Do calls the service in a client span, passing on the trace and
+// correlation ID of ctx. Errors from calls that could not reach the service
+// wrap ErrServiceUnavailable.
+func (c *Client) Do(ctx context.Context, method, path string, payload interface{}) (response *common.APIResponse, err error) {
+	ctx, span := common.StartSpan(ctx, "call "+method,
+		attribute.String("http.request.method", method),
+		attribute.String("server.service", c.service),
+		attribute.String("url.path", path),
+	)
+	defer func() { common.EndSpan(span, err) }()
+
+	baseURL, err := c.config.Resolver.Resolve(ctx, c.service)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resolving %s: %v", ErrServiceUnavailable, c.service, err)
+	}
+	url := baseURL + path
+	span.SetAttributes(attribute.String("url.full", url))
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	common.InjectTraceHeaders(ctx, req.Header)
+
+	if c.config.Token != nil {
+		token, err := c.config.Token()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.config.HTTP.Do(c.service, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrServiceUnavailable, err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: %s returned %d", ErrServiceUnavailable, url, resp.StatusCode)
+	}
+
+	var apiResponse common.APIResponse
+	if err := json.Unmarshal(responseBody, &apiResponse); err != nil {
+		return nil, err
+	}
+	if !apiResponse.Success {
+		return nil, fmt.Errorf("service call failed: %v", apiResponse.Error)
+	}
+	return &apiResponse, nil
+}
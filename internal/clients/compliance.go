@@ -0,0 +1,37 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// ScreeningRequest asks the compliance service to screen a payment's counterparty
+type ScreeningRequest struct {
+	AgentID        string  `json:"agentId"`
+	Counterparty   string  `json:"counterparty"`
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, when the payment referenced one
+	AmountUSD      float64 `json:"amountUSD"`
+	WorkflowID     string  `json:"workflowId"`
+}
+
+// ComplianceClient calls the compliance service
+type ComplianceClient struct {
+	*Client
+}
+
+func NewComplianceClient(config Config) *ComplianceClient {
+	return &ComplianceClient{New(discovery.Compliance, config)}
+}
+
+// Screen screens a counterparty against sanctions lists and denylists
+func (c *ComplianceClient) Screen(ctx context.Context, req *ScreeningRequest) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/compliance/screen", req)
+}
+
+// GetScreening returns a screening
+func (c *ComplianceClient) GetScreening(ctx context.Context, screeningID string) (*common.APIResponse, error) {
+	return c.Get(ctx, "/v1/compliance/screenings/"+url.PathEscape(screeningID))
+}
@@ -0,0 +1,62 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/libs/common"
+	"google.golang.org/grpc"
+)
+
+// ConsentValidationRequest asks the consent service whether a payment is
+// covered by a consent
+type ConsentValidationRequest struct {
+	AgentID        string  `json:"agentId"`
+	OwnerPartyID   string  `json:"ownerPartyId"`
+	AmountUSD      float64 `json:"amountUSD"`
+	Counterparty   string  `json:"counterparty"`
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, when the payment referenced one
+	Rail           string  `json:"rail"`
+	WorkflowID     string  `json:"workflowId"`
+}
+
+// ConsentClient calls the consent service
+type ConsentClient struct {
+	*Client
+}
+
+func NewConsentClient(config Config) *ConsentClient {
+	return &ConsentClient{New(discovery.Consent, config)}
+}
+
+// Validate checks a payment against the owner's consents, requesting an
+// approval if the consent requires one
+func (c *ConsentClient) Validate(ctx context.Context, req *ConsentValidationRequest) (*common.APIResponse, error) {
+	response, called, err := c.viaGRPC(func(conn *grpc.ClientConn) (interface{}, error) {
+		check, err := agentpayv1.NewConsentServiceClient(conn).Validate(ctx, &agentpayv1.ValidateConsentRequest{
+			AgentId:        req.AgentID,
+			OwnerPartyId:   req.OwnerPartyID,
+			AmountUsd:      req.AmountUSD,
+			Counterparty:   req.Counterparty,
+			CounterpartyId: req.CounterpartyID,
+			Rail:           req.Rail,
+			WorkflowId:     req.WorkflowID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return rpc.ConsentCheckFromProto(check), nil
+	})
+	if called {
+		return response, err
+	}
+	return c.Post(ctx, "/v1/consents/validate", req)
+}
+
+// GetApproval returns an approval
+func (c *ConsentClient) GetApproval(ctx context.Context, approvalID string) (*common.APIResponse, error) {
+	return c.Get(ctx, "/v1/approvals/"+url.PathEscape(approvalID))
+}
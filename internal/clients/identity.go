@@ -0,0 +1,23 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// IdentityClient calls the identity service
+type IdentityClient struct {
+	*Client
+}
+
+func NewIdentityClient(config Config) *IdentityClient {
+	return &IdentityClient{New(discovery.Identity, config)}
+}
+
+// VerifyCredential checks a credential an agent presented
+func (c *IdentityClient) VerifyCredential(ctx context.Context, agentID, credential string) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/agents/"+url.PathEscape(agentID)+"/credentials/verify", map[string]interface{}{"token": credential})
+}
@@ -0,0 +1,52 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// HoldRequest asks the ledger service to hold funds on an account
+type HoldRequest struct {
+	AgentID     string  `json:"agentId"`
+	AccountID   string  `json:"accountId"`
+	Amount      float64 `json:"amount"`
+	ReferenceID string  `json:"referenceId"`
+	Description string  `json:"description"`
+}
+
+// CaptureRequest books held funds to a destination account
+type CaptureRequest struct {
+	DestinationAccountID string  `json:"destinationAccountId"`
+	Amount               float64 `json:"amount"`
+	Description          string  `json:"description"`
+	TokenAmount          float64 `json:"tokenAmount,omitempty"` // Tokens paid, for payments settled on chain
+	Token                string  `json:"token,omitempty"`
+}
+
+// LedgerClient calls the ledger service
+type LedgerClient struct {
+	*Client
+}
+
+func NewLedgerClient(config Config) *LedgerClient {
+	return &LedgerClient{New(discovery.Ledger, config)}
+}
+
+// PlaceHold holds funds, failing if the account's available balance does
+// not cover them
+func (c *LedgerClient) PlaceHold(ctx context.Context, req *HoldRequest) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/holds", req)
+}
+
+// ReleaseHold frees held funds
+func (c *LedgerClient) ReleaseHold(ctx context.Context, holdID string) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/holds/"+url.PathEscape(holdID)+"/release", map[string]interface{}{})
+}
+
+// CaptureHold books held funds
+func (c *LedgerClient) CaptureHold(ctx context.Context, holdID string, req *CaptureRequest) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/holds/"+url.PathEscape(holdID)+"/capture", req)
+}
@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/libs/common"
+	"google.golang.org/grpc"
+)
+
+// RiskEvaluationRequest asks the risk service to score a payment
+type RiskEvaluationRequest struct {
+	AgentID        string  `json:"agentId"`
+	AmountUSD      float64 `json:"amountUSD"`
+	Counterparty   string  `json:"counterparty"`
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, when the payment referenced one
+	Rail           string  `json:"rail"`
+	WorkflowID     string  `json:"workflowId"`
+}
+
+// RiskClient calls the risk service
+type RiskClient struct {
+	*Client
+}
+
+func NewRiskClient(config Config) *RiskClient {
+	return &RiskClient{New(discovery.Risk, config)}
+}
+
+// Evaluate scores a payment, opening a review case if it needs one
+func (c *RiskClient) Evaluate(ctx context.Context, req *RiskEvaluationRequest) (*common.APIResponse, error) {
+	response, called, err := c.viaGRPC(func(conn *grpc.ClientConn) (interface{}, error) {
+		decision, err := agentpayv1.NewRiskServiceClient(conn).Evaluate(ctx, &agentpayv1.EvaluateRiskRequest{
+			AgentId:        req.AgentID,
+			AmountUsd:      req.AmountUSD,
+			Counterparty:   req.Counterparty,
+			CounterpartyId: req.CounterpartyID,
+			Rail:           req.Rail,
+			WorkflowId:     req.WorkflowID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return rpc.RiskDecisionFromProto(decision), nil
+	})
+	if called {
+		return response, err
+	}
+	return c.Post(ctx, "/v1/risk/evaluate", req)
+}
+
+// GetCase returns a review case
+func (c *RiskClient) GetCase(ctx context.Context, caseID string) (*common.APIResponse, error) {
+	return c.Get(ctx, "/v1/risk/cases/"+url.PathEscape(caseID))
+}
@@ -0,0 +1,95 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/discovery"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/libs/common"
+	"google.golang.org/grpc"
+)
+
+// ExecutionRequest asks the router service to execute a payment on a rail
+type ExecutionRequest struct {
+	AgentID      string  `json:"agentId"`
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty"`
+	Rail         string  `json:"rail"`
+	Description  string  `json:"description"`
+
+	International *international.Details `json:"international,omitempty"` // Beneficiary's bank details, for payments abroad
+
+	// Identifies the attempt to execute the payment, the same whenever the
+	// attempt is resent; required
+	IdempotencyKey string `json:"idempotencyKey"`
+}
+
+// RouterClient calls the router service
+type RouterClient struct {
+	*Client
+}
+
+func NewRouterClient(config Config) *RouterClient {
+	return &RouterClient{New(discovery.Router, config)}
+}
+
+// Execute starts a payment's execution on its rail.
+//
+// The request carries the caller's idempotency key, so that sending it over
+// REST after its gRPC call was cut off, or again after the caller restarted,
+// when the router may have executed it already, returns that execution
+// rather than paying twice.
+func (c *RouterClient) Execute(ctx context.Context, req *ExecutionRequest) (*common.APIResponse, error) {
+	if req.IdempotencyKey == "" {
+		return nil, errors.New("execution request has no idempotency key")
+	}
+	response, called, err := c.viaGRPC(func(conn *grpc.ClientConn) (interface{}, error) {
+		execution, err := agentpayv1.NewRouterServiceClient(conn).Execute(ctx, &agentpayv1.ExecutePaymentRequest{
+			AgentId:      req.AgentID,
+			AmountUsd:    req.AmountUSD,
+			Counterparty: req.Counterparty,
+			Rail:         req.Rail,
+			Description:  req.Description,
+
+			International:  rpc.InternationalToProto(req.International),
+			IdempotencyKey: req.IdempotencyKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return rpc.PaymentExecutionFromProto(execution), nil
+	})
+	if called {
+		return response, err
+	}
+	return c.Post(ctx, "/v1/payments/execute", req)
+}
+
+// Status returns the status of an execution
+func (c *RouterClient) Status(ctx context.Context, executionID string) (*common.APIResponse, error) {
+	response, called, err := c.viaGRPC(func(conn *grpc.ClientConn) (interface{}, error) {
+		execution, err := agentpayv1.NewRouterServiceClient(conn).GetStatus(ctx, &agentpayv1.GetPaymentStatusRequest{Id: executionID})
+		if err != nil {
+			return nil, err
+		}
+		return rpc.PaymentExecutionFromProto(execution), nil
+	})
+	if called {
+		return response, err
+	}
+	return c.Get(ctx, "/v1/payments/"+url.PathEscape(executionID)+"/status")
+}
+
+// Reverse reverses a completed execution through its rail
+func (c *RouterClient) Reverse(ctx context.Context, executionID, reason string) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/payments/"+url.PathEscape(executionID)+"/reverse", map[string]interface{}{"reason": reason})
+}
+
+// Void voids an execution whose authorization is still open
+func (c *RouterClient) Void(ctx context.Context, executionID string) (*common.APIResponse, error) {
+	return c.Post(ctx, "/v1/payments/"+url.PathEscape(executionID)+"/void", map[string]interface{}{})
+}
@@ -0,0 +1,140 @@
+package compliance
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// noiseTokens are legal forms and filler words that say nothing about who a
+// party is, so "Acme Trading LLC" and "The Acme Trading Co." compare equal
+var noiseTokens = map[string]bool{
+	"the": true, "and": true, "of": true,
+	"inc": true, "incorporated": true, "llc": true, "ltd": true, "limited": true,
+	"corp": true, "corporation": true, "co": true, "company": true,
+	"plc": true, "sa": true, "ag": true, "gmbh": true, "bv": true, "nv": true,
+	"oao": true, "ooo": true, "pjsc": true, "jsc": true,
+}
+
+// NormalizeName lowercases a name, strips accents and punctuation and drops
+// noise tokens, returning the remaining tokens
+func NormalizeName(name string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent left by decomposition
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		case r == '\'' || r == '.':
+			// "O'Brien" and "S.A." read as one token
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	var tokens []string
+	for _, token := range strings.Fields(b.String()) {
+		if !noiseTokens[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// NameSimilarity scores how closely a screened name matches a listed name,
+// from 0 to 1. It takes the better of two measures: Jaro-Winkler similarity of
+// the whole names with their tokens sorted, which tolerates reordering and
+// misspelling, and how well every token of the listed name is found in the
+// screened name, which catches a listed name embedded in a longer one.
+func NameSimilarity(screened, listed string) float64 {
+	screenedTokens := NormalizeName(screened)
+	listedTokens := NormalizeName(listed)
+	if len(screenedTokens) == 0 || len(listedTokens) == 0 {
+		return 0
+	}
+
+	best := jaroWinkler(sortedJoin(screenedTokens), sortedJoin(listedTokens))
+
+	total := 0.0
+	for _, listedToken := range listedTokens {
+		tokenBest := 0.0
+		for _, screenedToken := range screenedTokens {
+			if score := jaroWinkler(screenedToken, listedToken); score > tokenBest {
+				tokenBest = score
+			}
+		}
+		total += tokenBest
+	}
+	if coverage := total / float64(len(listedTokens)); coverage > best {
+		best = coverage
+	}
+	return best
+}
+
+func sortedJoin(tokens []string) string {
+	sorted := append([]string(nil), tokens...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		start := max(0, i-window)
+		end := min(len(s2), i+window+1)
+		for j := start; j < end; j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	// Boost for a common prefix of up to four characters
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
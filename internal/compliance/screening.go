@@ -0,0 +1,112 @@
+package compliance
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Watchlist types
+const (
+	ListSanctions = "sanctions"
+	ListDenylist  = "denylist"
+)
+
+// Screening statuses. Clear, approved and blocked are final; a pending review
+// becomes approved or rejected by a compliance officer.
+const (
+	StatusClear         = "clear"
+	StatusPendingReview = "pending_review"
+	StatusApproved      = "approved"
+	StatusRejected      = "rejected"
+	StatusBlocked       = "blocked"
+)
+
+// Default thresholds on NameSimilarity. By default only a name identical to a
+// listed one after normalization is blocked; near matches go to review.
+const (
+	DefaultMatchThreshold = 0.90
+	DefaultBlockThreshold = 1.0
+)
+
+// Match is a watchlist entry a name was found similar to
+type Match struct {
+	EntryID     string  `json:"entryId"`
+	ListType    string  `json:"listType"`
+	ListName    string  `json:"listName"`
+	Name        string  `json:"name"`
+	MatchedName string  `json:"matchedName"` // The entry name or alias that matched
+	Program     string  `json:"program,omitempty"`
+	Score       float64 `json:"score"`
+}
+
+// Screener matches names against watchlist entries. Matches scoring at least
+// MatchThreshold are hits that need review; a hit at BlockThreshold or above
+// is treated as certain and blocks the payment outright.
+type Screener struct {
+	MatchThreshold float64
+	BlockThreshold float64
+}
+
+// NewScreenerFromEnv reads thresholds from COMPLIANCE_MATCH_THRESHOLD and
+// COMPLIANCE_BLOCK_THRESHOLD
+func NewScreenerFromEnv() *Screener {
+	return &Screener{
+		MatchThreshold: common.GetEnvAsFloat("COMPLIANCE_MATCH_THRESHOLD", DefaultMatchThreshold),
+		BlockThreshold: common.GetEnvAsFloat("COMPLIANCE_BLOCK_THRESHOLD", DefaultBlockThreshold),
+	}
+}
+
+// Screen returns the entries matching any of a party's names, best match
+// first. Each entry is scored on the best of its name and aliases.
+func (s *Screener) Screen(entries []*database.WatchlistEntry, names ...string) []Match {
+	var matches []Match
+	for _, entry := range entries {
+		best := Match{
+			EntryID:  entry.ID,
+			ListType: entry.ListType,
+			ListName: entry.ListName,
+			Name:     entry.Name,
+			Program:  entry.Program,
+		}
+		for _, candidate := range append([]string{entry.Name}, EntryAliases(entry)...) {
+			for _, name := range names {
+				if score := NameSimilarity(name, candidate); score > best.Score {
+					best.Score = score
+					best.MatchedName = candidate
+				}
+			}
+		}
+		if best.Score >= s.MatchThreshold {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Status decides a screening from its matches
+func (s *Screener) Status(matches []Match) string {
+	switch {
+	case len(matches) == 0:
+		return StatusClear
+	case matches[0].Score >= s.BlockThreshold:
+		return StatusBlocked
+	default:
+		return StatusPendingReview
+	}
+}
+
+// EntryAliases decodes an entry's aliases, ignoring malformed JSON
+func EntryAliases(entry *database.WatchlistEntry) []string {
+	var aliases []string
+	if entry.Aliases != "" {
+		json.Unmarshal([]byte(entry.Aliases), &aliases)
+	}
+	return aliases
+}
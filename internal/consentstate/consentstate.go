@@ -0,0 +1,70 @@
+package consentstate
+
+import (
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// State is where a consent is in its lifecycle, derived from its revocation,
+// supersession and expiry
+type State string
+
+const (
+	Active     State = "active"     // Authorizes payments
+	Expired    State = "expired"    // Past its expiry; can be renewed
+	Revoked    State = "revoked"    // Withdrawn by its owner
+	Superseded State = "superseded" // Replaced by a successor version
+)
+
+// Of returns the state of a consent at a time. Revocation and supersession
+// take precedence over expiry.
+func Of(consent *database.Consent, now time.Time) State {
+	switch {
+	case consent.Revoked:
+		return Revoked
+	case consent.SupersededByID != nil:
+		return Superseded
+	case consent.ExpiresAt != nil && !now.Before(*consent.ExpiresAt):
+		return Expired
+	}
+	return Active
+}
+
+// IsActive reports whether a consent authorizes payments at a time
+func IsActive(consent *database.Consent, now time.Time) bool {
+	return Of(consent, now) == Active
+}
+
+// Successor returns the next version of a consent with the same terms,
+// expiring at expiresAt, or never when nil
+func Successor(consent *database.Consent, expiresAt *time.Time) *database.Consent {
+	version := consent.Version
+	if version < 1 { // Consents stored before versioning are the first
+		version = 1
+	}
+	previousID := consent.ID
+	return &database.Consent{
+		AgentID:             consent.AgentID,
+		OwnerPartyID:        consent.OwnerPartyID,
+		Limits:              consent.Limits,
+		Rails:               consent.Rails,
+		CounterpartiesAllow: consent.CounterpartiesAllow,
+		CosignRule:          consent.CosignRule,
+		PolicyBundleVersion: consent.PolicyBundleVersion,
+		ExpiresAt:           expiresAt,
+		Version:             version + 1,
+		PreviousConsentID:   &previousID,
+	}
+}
+
+// RenewalExpiry returns when a renewed consent expires when no expiry is
+// given: after the predecessor's term counted from now, or never when the
+// predecessor did not expire
+func RenewalExpiry(consent *database.Consent, now time.Time) *time.Time {
+	if consent.ExpiresAt == nil {
+		return nil
+	}
+	expiresAt := now.Add(consent.ExpiresAt.Sub(consent.CreatedAt))
+	return &expiresAt
+}
@@ -0,0 +1,100 @@
+package counterparties
+
+import (
+	"encoding/json"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// KYC statuses, set by compliance officers
+const (
+	KYCUnverified = "unverified"
+	KYCPending    = "pending"
+	KYCVerified   = "verified"
+	KYCRejected   = "rejected" // Payments to the counterparty are blocked by screening
+)
+
+// Risk ratings, set by compliance officers
+const (
+	RatingUnrated = "unrated"
+	RatingLow     = "low"
+	RatingMedium  = "medium"
+	RatingHigh    = "high"
+)
+
+// List statuses, set by the party that pays the counterparty
+const (
+	ListNone    = "none"
+	ListAllowed = "allowed" // Known to the party: not scored as a new or unverified payee
+	ListDenied  = "denied"  // No agent of the party may pay it
+)
+
+// IdentifierTaxID is the identifier holding the counterparty's tax ID, which
+// workflow templates with a tax check require
+const IdentifierTaxID = "taxId"
+
+// ValidType reports whether a counterparty type is known
+func ValidType(counterpartyType string) bool {
+	return counterpartyType == "individual" || counterpartyType == "organization"
+}
+
+// ValidKYCStatus reports whether a KYC status is known
+func ValidKYCStatus(status string) bool {
+	switch status {
+	case KYCUnverified, KYCPending, KYCVerified, KYCRejected:
+		return true
+	}
+	return false
+}
+
+// ValidRiskRating reports whether a risk rating is known
+func ValidRiskRating(rating string) bool {
+	switch rating {
+	case RatingUnrated, RatingLow, RatingMedium, RatingHigh:
+		return true
+	}
+	return false
+}
+
+// ValidListStatus reports whether a list status is known
+func ValidListStatus(status string) bool {
+	switch status {
+	case ListNone, ListAllowed, ListDenied:
+		return true
+	}
+	return false
+}
+
+// Refusal returns why the agents of a party may not pay a counterparty, or ""
+// if they may
+func Refusal(counterparty *database.Counterparty, partyID string) string {
+	if counterparty.PartyID != partyID {
+		return "Counterparty belongs to another party"
+	}
+	if counterparty.ListStatus == ListDenied {
+		reason := "Counterparty is on the party's deny list"
+		if counterparty.ListReason != "" {
+			reason += ": " + counterparty.ListReason
+		}
+		return reason
+	}
+	return ""
+}
+
+// EncodeIdentifiers serializes identifiers for a JSON column
+func EncodeIdentifiers(identifiers map[string]string) string {
+	if len(identifiers) == 0 {
+		return "{}"
+	}
+	data, _ := json.Marshal(identifiers)
+	return string(data)
+}
+
+// DecodeIdentifiers reads identifiers from a JSON column
+func DecodeIdentifiers(value string) map[string]string {
+	identifiers := map[string]string{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &identifiers)
+	}
+	return identifiers
+}
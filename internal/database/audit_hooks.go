@@ -0,0 +1,157 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Updates to sensitive models are audited by a GORM hook rather than by the
+// handlers making them. Before each update the hook reads the stored row,
+// compares it with the model being saved and writes an audit entry with the
+// old and new values of the changed columns. Repositories update with Save,
+// so the model holds the complete new row. The entry is written in the
+// update's transaction, so it is rolled back if the update fails.
+
+// auditIgnoredColumns change on every write and carry no information
+var auditIgnoredColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+func (a *Agent) BeforeUpdate(tx *gorm.DB) error {
+	return auditUpdate(tx, &Agent{}, a, "agent", a.ID, a.ID)
+}
+
+func (c *Consent) BeforeUpdate(tx *gorm.DB) error {
+	if err := checkUnchanged(tx, &Consent{}, c, c.ID, consentTermColumns); err != nil {
+		return err
+	}
+	return auditUpdate(tx, &Consent{}, c, "consent", c.ID, c.AgentID)
+}
+
+func (a *Account) BeforeUpdate(tx *gorm.DB) error {
+	return auditUpdate(tx, &Account{}, a, "account", a.ID, a.AgentID)
+}
+
+func (r *RiskDecision) BeforeUpdate(tx *gorm.DB) error {
+	return auditUpdate(tx, &RiskDecision{}, r, "risk_decision", r.ID, r.AgentID)
+}
+
+// consentTermColumns hold what a consent authorized. They are fixed once the
+// consent is stored, so the consent past payments went ahead under stays as
+// it was; changing them creates a new version.
+var consentTermColumns = []string{
+	"agent_id", "owner_party_id", "rails", "counterparties_allow", "limits",
+	"policy_bundle_version", "cosign_rule", "expires_at", "version", "previous_consent_id",
+}
+
+// ErrImmutable is returned for updates changing columns fixed once stored
+var ErrImmutable = errors.New("column cannot be changed once stored")
+
+// checkUnchanged fails the update if it changes any of the columns
+func checkUnchanged(tx *gorm.DB, stored, updated interface{}, id string, columns []string) error {
+	if id == "" {
+		return nil
+	}
+
+	session := tx.Session(&gorm.Session{NewDB: true})
+	if err := session.Unscoped().Where("id = ?", id).Take(stored).Error; err != nil {
+		return nil
+	}
+
+	oldValues, _, err := changedColumns(session, stored, updated)
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if _, changed := oldValues[column]; changed {
+			return fmt.Errorf("%w: %s", ErrImmutable, column)
+		}
+	}
+	return nil
+}
+
+// auditUpdate records the columns of the stored row that the update changes
+func auditUpdate(tx *gorm.DB, stored, updated interface{}, resourceType, id, agentID string) error {
+	if id == "" {
+		return nil
+	}
+
+	session := tx.Session(&gorm.Session{NewDB: true})
+	if err := session.Unscoped().Where("id = ?", id).Take(stored).Error; err != nil {
+		// Nothing stored yet, e.g. Save inserting a new row
+		return nil
+	}
+
+	oldValues, newValues, err := changedColumns(session, stored, updated)
+	if err != nil {
+		return err
+	}
+	if len(oldValues) == 0 {
+		return nil
+	}
+
+	columns := make([]string, 0, len(oldValues))
+	for column := range oldValues {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	oldJSON, err := json.Marshal(oldValues)
+	if err != nil {
+		return err
+	}
+	newJSON, err := json.Marshal(newValues)
+	if err != nil {
+		return err
+	}
+
+	return session.Create(&AuditEntry{
+		EventType:    resourceType + ".updated",
+		Severity:     "medium",
+		AgentID:      agentID,
+		ResourceID:   id,
+		ResourceType: resourceType,
+		Action:       "update",
+		Description:  fmt.Sprintf("%s %s updated: %s", resourceType, id, strings.Join(columns, ", ")),
+		OldValues:    string(oldJSON),
+		NewValues:    string(newJSON),
+		Metadata:     `{"source":"model_hook"}`,
+		Timestamp:    time.Now().UTC(),
+	}).Error
+}
+
+// changedColumns returns the old and new values of the columns that differ,
+// keyed by column name
+func changedColumns(tx *gorm.DB, before, after interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	statement := &gorm.Statement{DB: tx}
+	if err := statement.Parse(after); err != nil {
+		return nil, nil, err
+	}
+
+	beforeValue := reflect.Indirect(reflect.ValueOf(before))
+	afterValue := reflect.Indirect(reflect.ValueOf(after))
+
+	oldValues := map[string]interface{}{}
+	newValues := map[string]interface{}{}
+	for _, field := range statement.Schema.Fields {
+		if field.DBName == "" || auditIgnoredColumns[field.DBName] {
+			continue
+		}
+		oldValue, _ := field.ValueOf(tx.Statement.Context, beforeValue)
+		newValue, _ := field.ValueOf(tx.Statement.Context, afterValue)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			oldValues[field.DBName] = oldValue
+			newValues[field.DBName] = newValue
+		}
+	}
+	return oldValues, newValues, nil
+}
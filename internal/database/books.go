@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Books are parallel sets of accounts kept under different accounting
+// treatments, such as management and statutory accounts. Every account
+// belongs to one book and a transaction posts within a single book. Posting
+// rules fan a transaction out into the other books, so one economic event is
+// recorded in each book under its own treatment.
+const (
+	BookManagement = "management" // Default book
+	BookStatutory  = "statutory"
+)
+
+var bookPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// ValidBook reports whether a name can be used for a book: lowercase letters,
+// digits, dashes and underscores, starting with a letter
+func ValidBook(book string) bool {
+	return bookPattern.MatchString(book)
+}
+
+// ErrMixedBooks is returned for a transaction posting to accounts in more
+// than one book
+var ErrMixedBooks = errors.New("postings span more than one book")
+
+// UnmappedPostingError is returned when posting rules fan a transaction out
+// into a book but do not map one of its accounts, so the mirror transaction
+// would not balance
+type UnmappedPostingError struct {
+	Book      string
+	AccountID string
+}
+
+func (e *UnmappedPostingError) Error() string {
+	return fmt.Sprintf("no posting rule maps account %s into the %s book", e.AccountID, e.Book)
+}
+
+// transactionBook returns the one book of the accounts posted to, checking
+// it against the transaction's book if set
+func transactionBook(tx *gorm.DB, transaction *Transaction, postings []*Posting) (string, error) {
+	ids := make([]string, len(postings))
+	for i, posting := range postings {
+		ids[i] = posting.AccountID
+	}
+	var books []string
+	if err := tx.Model(&Account{}).Where("id IN ?", ids).Distinct().Pluck("book", &books).Error; err != nil {
+		return "", err
+	}
+	if len(books) > 1 || (len(books) == 1 && transaction.Book != "" && transaction.Book != books[0]) {
+		return "", ErrMixedBooks
+	}
+	if len(books) == 0 {
+		return BookManagement, nil
+	}
+	return books[0], nil
+}
+
+// fanOut posts a mirror of the transaction into each book its accounts have
+// posting rules into. Mirrors are not fanned out again.
+func fanOut(tx *gorm.DB, transaction *Transaction, postings []*Posting) error {
+	ids := make([]string, len(postings))
+	for i, posting := range postings {
+		ids[i] = posting.AccountID
+	}
+	var rules []*PostingRule
+	if err := tx.Where("agent_id = ? AND source_account_id IN ?", transaction.AgentID, ids).Find(&rules).Error; err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	targets := make(map[string]map[string]string) // Book to source to target account
+	for _, rule := range rules {
+		if targets[rule.TargetBook] == nil {
+			targets[rule.TargetBook] = make(map[string]string)
+		}
+		targets[rule.TargetBook][rule.SourceAccountID] = rule.TargetAccountID
+	}
+	books := make([]string, 0, len(targets))
+	for book := range targets {
+		books = append(books, book)
+	}
+	sort.Strings(books)
+
+	for _, book := range books {
+		mirrorPostings := make([]*Posting, len(postings))
+		for i, posting := range postings {
+			target, ok := targets[book][posting.AccountID]
+			if !ok {
+				return &UnmappedPostingError{Book: book, AccountID: posting.AccountID}
+			}
+			mirrorPostings[i] = &Posting{AccountID: target, Amount: posting.Amount, Currency: posting.Currency}
+		}
+		mirror := &Transaction{
+			AgentID:             transaction.AgentID,
+			Description:         transaction.Description,
+			Status:              transaction.Status,
+			Book:                book,
+			SourceTransactionID: &transaction.ID,
+		}
+		if err := postTransaction(tx, mirror, mirrorPostings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
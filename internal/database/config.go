@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/example/agent-payments/libs/common"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -76,6 +77,9 @@ func Connect(config *Config) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	if err := registerTracing(db); err != nil {
+		return nil, fmt.Errorf("failed to register database tracing: %w", err)
+	}
 
 	// Configure connection pool (skip for SQLite)
 	if !config.UseSQLite {
@@ -90,6 +94,15 @@ func Connect(config *Config) (*gorm.DB, error) {
 		sqlDB.SetConnMaxLifetime(time.Hour)
 	}
 
+	// Expose connection pool statistics with the service's metrics
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
+	}
+	if err := common.RegisterDBStats(sqlDB, config.DBName); err != nil {
+		return nil, fmt.Errorf("failed to register database metrics: %w", err)
+	}
+
 	log.Println("Database connection established successfully")
 	return db, nil
 }
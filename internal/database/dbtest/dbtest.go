@@ -0,0 +1,83 @@
+// Package dbtest opens throwaway SQLite databases with the platform's schema,
+// so tests can run repositories without a PostgreSQL server.
+package dbtest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// postgresUUIDDefault is the column default PostgreSQL generates IDs with
+const postgresUUIDDefault = "gen_random_uuid()"
+
+// Open migrates a new database in the test's temporary directory and returns
+// a repository of it. SQLite has no gen_random_uuid, so the models' ID
+// defaults are dropped from the schema and IDs are assigned on create instead.
+func Open(t testing.TB) (database.Repository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open the test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to open the test database: %v", err)
+	}
+	// One connection, so transactions serialize as row locks would
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, model := range database.Models() {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(model); err != nil {
+			t.Fatalf("failed to parse %T: %v", model, err)
+		}
+		for _, field := range statement.Schema.Fields {
+			if field.DefaultValue == postgresUUIDDefault {
+				field.DefaultValue, field.HasDefaultValue, field.DefaultValueInterface = "", false, nil
+			}
+		}
+	}
+	err = db.Callback().Create().Before("gorm:create").Register("dbtest:uuid", assignIDs)
+	if err != nil {
+		t.Fatalf("failed to register the ID callback: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+	return database.NewRepository(db), db
+}
+
+// assignIDs sets a random UUID on each created row without an ID
+func assignIDs(db *gorm.DB) {
+	if db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field.DBName != "id" || field.FieldType.Kind() != reflect.String {
+		return
+	}
+	assign := func(value reflect.Value) {
+		if _, zero := field.ValueOf(db.Statement.Context, value); zero {
+			db.AddError(field.Set(db.Statement.Context, value, uuid.NewString()))
+		}
+	}
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			assign(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		assign(value)
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// An account's balance is the sum of its postings. The Balance column is a
+// cache that writers keep alongside their postings; reads derive the balance
+// from the postings, starting from the account's latest balance snapshot so
+// that only postings made since it are summed.
+
+// derivedBalance sums an account's postings up to asOf, or all of them when
+// asOf is nil
+func derivedBalance(db *gorm.DB, accountID string, asOf *time.Time) (float64, error) {
+	until := time.Now()
+	if asOf != nil {
+		until = *asOf
+	}
+	snapshot, err := latestBalanceSnapshot(db, accountID, until)
+	if err != nil {
+		return 0, err
+	}
+
+	balance := 0.0
+	query := db.Model(&Posting{}).Where("account_id = ?", accountID)
+	if snapshot != nil {
+		balance = snapshot.Balance
+		query = query.Where("created_at > ?", snapshot.AsOf)
+	}
+	if asOf != nil {
+		query = query.Where("created_at <= ?", *asOf)
+	}
+
+	var sum float64
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error; err != nil {
+		return 0, err
+	}
+	return balance + sum, nil
+}
+
+func latestBalanceSnapshot(db *gorm.DB, accountID string, asOf time.Time) (*BalanceSnapshot, error) {
+	var snapshots []*BalanceSnapshot
+	err := db.Where("account_id = ? AND as_of <= ?", accountID, asOf).
+		Order("as_of DESC").Limit(1).Find(&snapshots).Error
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return snapshots[0], nil
+}
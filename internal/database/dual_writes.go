@@ -0,0 +1,23 @@
+package database
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// Save hooks for online migrations (see internal/migrations). While a
+// migration's backfill runs, they write its new column alongside the old one
+// so rows saved meanwhile are already in the new shape.
+
+// minorUnits converts a ledger amount, stored to the cent, to cents
+func minorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// BeforeSave writes the postings_amount_minor migration's new column
+func (p *Posting) BeforeSave(tx *gorm.DB) error {
+	minor := minorUnits(p.Amount)
+	p.AmountMinor = &minor
+	return nil
+}
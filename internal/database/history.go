@@ -0,0 +1,70 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Models audited by the update hooks can be read as they were at an earlier
+// time. The current row is rewound by applying, newest first, the old values
+// of every update audited after that time. Updates made before the hooks were
+// installed left no audit entry, so history only reaches back to them.
+
+// rewind restores a model loaded in its current state to its state at asOf.
+// It returns gorm.ErrRecordNotFound if the row was created after asOf.
+func rewind(db *gorm.DB, model interface{}, resourceType, id string, createdAt, asOf time.Time) error {
+	if createdAt.After(asOf) {
+		return gorm.ErrRecordNotFound
+	}
+
+	var entries []*AuditEntry
+	err := db.Where("resource_type = ? AND resource_id = ? AND action = ? AND timestamp > ?", resourceType, id, "update", asOf).
+		Order("timestamp DESC").
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := restoreColumns(db, model, entry.OldValues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreColumns sets the columns of a model from a JSON object keyed by
+// column name, as written by auditUpdate
+func restoreColumns(db *gorm.DB, model interface{}, values string) error {
+	if values == "" {
+		return nil
+	}
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(values), &columns); err != nil {
+		return err
+	}
+
+	statement := &gorm.Statement{DB: db}
+	if err := statement.Parse(model); err != nil {
+		return err
+	}
+	modelValue := reflect.Indirect(reflect.ValueOf(model))
+
+	for column, raw := range columns {
+		field := statement.Schema.LookUpField(column)
+		if field == nil {
+			continue // Column since dropped from the model
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return err
+		}
+		if err := field.Set(db.Statement.Context, modelValue, value.Elem().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
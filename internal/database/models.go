@@ -15,6 +15,14 @@ type Party struct {
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 
+	// Verification of the party's identity (KYC) or business (KYB)
+	KYCStatus     string `gorm:"not null;size:20;default:'unverified';check:kyc_status IN ('unverified', 'pending', 'verified', 'rejected')"`
+	KYCVerifiedAt *time.Time
+
+	// Organization party the party belongs to, which is its tenant. Parties
+	// without one are tenants of their own.
+	OrganizationID *string `gorm:"type:uuid;index"`
+
 	// Relationships
 	Agents []Agent `gorm:"foreignKey:OwnerPartyID"`
 }
@@ -29,6 +37,11 @@ type Agent struct {
 	UpdatedAt    time.Time
 	DeletedAt    gorm.DeletedAt `gorm:"index"`
 
+	// Lifecycle: only active agents may pay
+	Status          string `gorm:"not null;size:20;default:'active';index;check:status IN ('active', 'suspended', 'decommissioned')"`
+	StatusReason    string `gorm:"size:500"` // Why the agent was last suspended, reactivated or decommissioned
+	StatusChangedAt *time.Time
+
 	// Relationships
 	OwnerParty Party `gorm:"foreignKey:OwnerPartyID;references:ID"`
 }
@@ -47,6 +60,19 @@ type Consent struct {
 	UpdatedAt           time.Time
 	DeletedAt           gorm.DeletedAt `gorm:"index"`
 	Revoked             bool           `gorm:"default:false"`
+	RevokedAt           *time.Time
+	RevocationReason    string `gorm:"size:500"`
+
+	// Consents with an expiry stop authorizing payments then, unless renewed
+	ExpiresAt          *time.Time `gorm:"index"`
+	ExpiringNotifiedAt *time.Time // When the agent was warned of the expiry
+
+	// Renewal replaces a consent with a successor, the next version, which
+	// the predecessor points to once superseded
+	Version           int     `gorm:"not null;default:1"`
+	PreviousConsentID *string `gorm:"type:uuid;index"`
+	SupersededByID    *string `gorm:"type:uuid;index"`
+	SupersededAt      *time.Time
 
 	// Relationships
 	Agent      Agent `gorm:"foreignKey:AgentID;references:ID"`
@@ -55,19 +81,24 @@ type Consent struct {
 
 // RiskDecision represents a risk evaluation decision in the database
 type RiskDecision struct {
-	ID           string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	AgentID      string  `gorm:"type:uuid;not null"`
-	AmountUSD    float64 `gorm:"type:decimal(15,2);not null"`
-	Counterparty string  `gorm:"not null;size:255"`
-	Rail         string  `gorm:"not null;size:50"`
-	Decision     string  `gorm:"not null;check:decision IN ('approve', 'deny', 'review')"`
-	Score        float64 `gorm:"type:decimal(3,2);not null;check:score >= 0 AND score <= 1"`
-	Reason       string  `gorm:"not null;size:500"`
-	Threshold    float64 `gorm:"type:decimal(3,2);not null"`
-	RiskFactors  string  `gorm:"type:jsonb"` // JSON array of strings
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	ID             string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID        string  `gorm:"type:uuid;not null"`
+	AmountUSD      float64 `gorm:"type:decimal(15,2);not null"`
+	Counterparty   string  `gorm:"not null;size:255"`
+	Rail           string  `gorm:"not null;size:50"`
+	Decision       string  `gorm:"not null;check:decision IN ('approve', 'deny', 'review')"`
+	Score          float64 `gorm:"type:decimal(3,2);not null;check:score >= 0 AND score <= 1"`
+	Reason         string  `gorm:"not null;size:500"`
+	Threshold      float64 `gorm:"type:decimal(3,2);not null"`
+	RiskFactors    string  `gorm:"type:jsonb"`               // JSON array of strings
+	TriggeredRules string  `gorm:"type:jsonb"`               // JSON array of triggered AML rule IDs
+	Features       string  `gorm:"type:jsonb"`               // JSON object of the behavioral features scored
+	DeclineTrace   string  `gorm:"type:jsonb"`               // JSON array of the owner's auto-decline rules evaluated
+	PolicyVersion  int     `gorm:"not null;default:0;index"` // Risk policy version scored under; 0 for the built-in default
+	TrustTier      string  `gorm:"size:20"`                  // Agent's trust tier when scored, which set the threshold
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
 	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
@@ -77,22 +108,64 @@ type RiskDecision struct {
 type PaymentWorkflow struct {
 	ID           string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	AgentID      string  `gorm:"type:uuid;not null"`
-	AmountUSD    float64 `gorm:"type:decimal(15,2);not null"`
+	Amount       float64 `gorm:"type:decimal(15,2)"` // Amount in the payment currency
+	Currency     string  `gorm:"not null;size:3;default:'USD'"`
+	AmountUSD    float64 `gorm:"type:decimal(15,2);not null"` // USD equivalent used for limits and routing
 	Counterparty string  `gorm:"not null;size:255"`
 	Rail         string  `gorm:"not null;size:50"`
 	Description  string  `gorm:"size:500"`
-	Status       string  `gorm:"not null;check:status IN ('pending', 'processing', 'completed', 'failed')"`
-	Steps        string  `gorm:"type:jsonb"`    // JSON array of workflow steps
-	RiskDecision string  `gorm:"type:jsonb"`    // JSON object for risk decision
-	ConsentCheck string  `gorm:"type:jsonb"`    // JSON object for consent check
-	Hash         string  `gorm:"size:64;index"` // SHA-256 hash of payment data
-	PreviousHash string  `gorm:"size:64;index"` // Previous payment hash for chain
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	Metadata     string  `gorm:"type:jsonb"` // JSON object of caller-supplied values for description templates
+	Status       string  `gorm:"not null;check:status IN ('pending', 'processing', 'awaiting_approval', 'completed', 'failed', 'cancelled')"`
+	Steps        string  `gorm:"type:jsonb"`     // JSON array of workflow steps
+	RiskDecision string  `gorm:"type:jsonb"`     // JSON object for risk decision
+	ConsentCheck string  `gorm:"type:jsonb"`     // JSON object for consent check
+	Hash         string  `gorm:"size:64;index"`  // SHA-256 hash of payment data
+	PreviousHash string  `gorm:"size:64;index"`  // Previous payment hash for chain
+	MandateID    string  `gorm:"size:36"`        // Signed mandate authorizing the payment
+	CallbackURL  string  `gorm:"size:2048"`      // Receives the signed summary once the payment is final
+	Category     string  `gorm:"size:100;index"` // Spending category, such as marketing, that budgets apply to
+
+	// Counterparty entity paid, when the payment referenced one by ID
+	CounterpartyID *string `gorm:"type:uuid;index"`
+
+	// Owner party's workflow template the steps were planned from, at the
+	// version active when the payment was created; none is the built-in one
+	WorkflowTemplateID      *string `gorm:"type:uuid;index"`
+	WorkflowTemplateVersion int     `gorm:"not null;default:0"`
+
+	// Payment schedule that created the payment, if any
+	ScheduleID *string `gorm:"type:uuid;index"`
+
+	// Evidence the payment went ahead on, set as each check passes
+	ConsentID             *string `gorm:"type:uuid;index"`    // Consent the payment was validated against
+	ConsentVersion        int     `gorm:"not null;default:0"` // Version of that consent, whose terms never change
+	RiskDecisionID        *string `gorm:"type:uuid;index"`    // Risk decision that allowed it
+	ComplianceScreeningID *string `gorm:"type:uuid;index"`    // Screening that cleared the counterparty
+
+	// Fee experiment variant the payment was quoted under, settled under the
+	// same variant even if the experiment has since ended
+	FeeExperimentID *string `gorm:"type:uuid;index"`
+	FeeVariantID    *string `gorm:"type:uuid;index"`
+
+	// Quote whose rail, fee and exchange rate the payment was made under
+	QuoteID *string `gorm:"type:uuid;index"`
+
+	// JSON object of the beneficiary's bank details, for payments abroad
+	International string `gorm:"type:jsonb"`
+
+	// JSON array of the degradation modes applied while a dependency was down
+	Degradations string `gorm:"type:jsonb"`
+	Degraded     bool   `gorm:"not null;default:false;index"` // Any check was skipped or deferred
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
-	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+	Agent          Agent                `gorm:"foreignKey:AgentID;references:ID"`
+	Consent        *Consent             `gorm:"foreignKey:ConsentID;references:ID"`
+	RiskEvaluation *RiskDecision        `gorm:"foreignKey:RiskDecisionID;references:ID"`
+	Screening      *ComplianceScreening `gorm:"foreignKey:ComplianceScreeningID;references:ID"`
 }
 
 // PaymentExecution represents a payment execution through a specific rail
@@ -103,13 +176,30 @@ type PaymentExecution struct {
 	Counterparty string  `gorm:"not null;size:255"`
 	Rail         string  `gorm:"not null;size:50"`
 	Description  string  `gorm:"size:500"`
-	Status       string  `gorm:"not null;check:status IN ('pending', 'processing', 'completed', 'failed')"`
-	Priority     string  `gorm:"size:50"`  // "fast", "cheap", "reliable"
-	ReferenceID  string  `gorm:"size:255"` // External reference from payment processor
+	Status       string  `gorm:"not null;check:status IN ('pending', 'processing', 'completed', 'failed', 'reversed')"`
+	Priority     string  `gorm:"size:50"`        // "fast", "cheap", "reliable"
+	ReferenceID  string  `gorm:"size:255;index"` // External reference from payment processor
 	ErrorMessage string  `gorm:"size:500"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	BatchID      *string `gorm:"type:uuid;index"` // Batch the execution is submitted in, for batched rails
+
+	// FailedOverFrom is the rail the execution was first sent on, when that
+	// rail's processor was unavailable and it failed over to Rail
+	FailedOverFrom string `gorm:"size:50"`
+
+	// JSON object of the beneficiary's bank details, for payments abroad
+	International string `gorm:"type:jsonb"`
+
+	// Caller's key of the execution; a request repeating it is answered with
+	// this execution instead of paying again
+	IdempotencyKey *string `gorm:"size:100;uniqueIndex"`
+
+	// Reversal of a completed payment
+	ReversedAt            *time.Time
+	ReversalReason        string `gorm:"size:500"`
+	ReversalTransactionID string `gorm:"size:36"` // Compensating ledger transaction, if the payment was booked
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
 	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
@@ -124,9 +214,13 @@ type Account struct {
 	Description string  `gorm:"size:500"`
 	Currency    string  `gorm:"not null;size:3;default:'USD'"`
 	Balance     float64 `gorm:"type:decimal(15,2);not null;default:0"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	// Overdraft policy: disallow, limit or unlimited; empty uses the type's default
+	OverdraftPolicy string  `gorm:"size:20"`
+	OverdraftLimit  float64 `gorm:"type:decimal(15,2);not null;default:0"`       // How far below zero the limit policy allows
+	Book            string  `gorm:"not null;size:50;default:'management';index"` // Set of books the account belongs to
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
 	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
@@ -135,16 +229,19 @@ type Account struct {
 // Transaction represents a financial transaction in the ledger
 type Transaction struct {
 	ID           string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	AgentID      string `gorm:"type:uuid;not null"`
+	AgentID      string `gorm:"type:uuid;not null;uniqueIndex:idx_transactions_agent_block,where:block_index > 0"`
 	Description  string `gorm:"not null;size:500"`
 	ReferenceID  string `gorm:"size:255"`
 	Status       string `gorm:"not null;check:status IN ('pending', 'posted', 'failed')"`
-	Hash         string `gorm:"size:64;index"` // SHA-256 hash of transaction data
-	PreviousHash string `gorm:"size:64;index"` // Previous transaction hash for chain
-	BlockIndex   int    `gorm:"default:0"`     // Block index in hash chain
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	Hash         string `gorm:"size:64;index"`                                                            // SHA-256 hash of transaction data
+	PreviousHash string `gorm:"size:64;index"`                                                            // Previous transaction hash for chain
+	BlockIndex   int    `gorm:"default:0;uniqueIndex:idx_transactions_agent_block,where:block_index > 0"` // Position in the agent's hash chain, from 1; 0 until chained
+	Book         string `gorm:"not null;size:50;default:'management';index"`                              // Book of every account posted to
+	// Transaction in another book this one was fanned out from by posting rules
+	SourceTransactionID *string `gorm:"type:uuid;index"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
 	Agent    Agent     `gorm:"foreignKey:AgentID;references:ID"`
@@ -153,11 +250,14 @@ type Transaction struct {
 
 // Posting represents an individual entry in a transaction (debit or credit)
 type Posting struct {
-	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TransactionID string  `gorm:"type:uuid;not null"`
-	AccountID     string  `gorm:"type:uuid;not null"`
-	Amount        float64 `gorm:"type:decimal(15,2);not null"` // Positive = debit, negative = credit
-	Currency      string  `gorm:"not null;size:3;default:'USD'"`
+	ID            string   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TransactionID string   `gorm:"type:uuid;not null"`
+	AccountID     string   `gorm:"type:uuid;not null"`
+	Amount        float64  `gorm:"type:decimal(15,2);not null"` // Positive = debit, negative = credit
+	AmountMinor   *int64   // Amount in cents; filled for older rows by the postings_amount_minor migration
+	Currency      string   `gorm:"not null;size:3;default:'USD'"`
+	TokenAmount   *float64 `gorm:"type:decimal(30,6)"` // Tokens moved by an on-chain payment, signed like Amount, which is their USD value
+	Token         string   `gorm:"size:20"`            // Token symbol, such as USDC
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `gorm:"index"`
@@ -173,6 +273,8 @@ type OutboxEvent struct {
 	EventType     string `gorm:"not null"`
 	AggregateID   string `gorm:"not null"`
 	AggregateType string `gorm:"not null"`
+	PartitionKey  string `gorm:"size:255;uniqueIndex:idx_outbox_events_key_sequence,where:sequence > 0"` // Kafka message key; the payment ref for payment events
+	Sequence      int64  `gorm:"uniqueIndex:idx_outbox_events_key_sequence,where:sequence > 0"`          // Position among events with the same key, from 1
 	Payload       string `gorm:"type:jsonb;not null"`
 	Metadata      string `gorm:"type:jsonb"`
 	Status        string `gorm:"not null;check:status IN ('pending', 'published', 'failed')"`
@@ -201,10 +303,1111 @@ type AuditEntry struct {
 	Metadata      string    `gorm:"type:jsonb"`
 	SessionID     string    `gorm:"index"`
 	CorrelationID string    `gorm:"index"`
+	TraceID       string    `gorm:"size:32;index"` // OpenTelemetry trace the entry was recorded in
 	Timestamp     time.Time `gorm:"not null;index"`
 	Archived      bool      `gorm:"default:false"`
+	AnchorID      *string   `gorm:"type:uuid;index"` // Merkle anchor covering the entry, once anchored
+	AnchorIndex   int       // Leaf position within the anchor
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// APICredential represents an API key issued to a party or one of its agents
+type APICredential struct {
+	ID         string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name       string `gorm:"not null;size:255"`
+	KeyPrefix  string `gorm:"not null;size:16"`             // First characters of the key, for identification
+	KeyHash    string `gorm:"not null;size:64;uniqueIndex"` // SHA-256 of the key; plaintext is never stored
+	PartyID    string `gorm:"type:uuid;not null;index"`
+	AgentID    string `gorm:"size:36;index"` // Empty for party-level keys
+	UserID     string `gorm:"size:36;index"` // Set for keys of a party's users
+	Scopes     string `gorm:"type:jsonb"`    // JSON array of scopes
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// AgentCredential represents a credential issued to an agent for its identity mode.
+// OAuth agents receive signed access tokens; DID agents receive an Ed25519 key
+// published through a DID document.
+type AgentCredential struct {
+	ID           string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID      string `gorm:"type:uuid;not null;index"`
+	Type         string `gorm:"not null;check:type IN ('oauth_token', 'did_key', 'oauth_client')"`
+	Status       string `gorm:"not null;default:'active';check:status IN ('active', 'rotated', 'revoked')"`
+	DID          string `gorm:"size:255"`  // DID of the agent, for did_key credentials
+	PublicKey    string `gorm:"size:255"`  // Base64url Ed25519 public key, for did_key credentials
+	SecretHash   string `gorm:"size:64"`   // SHA-256 of the client secret, for oauth_client credentials
+	Scopes       string `gorm:"type:text"` // JSON scopes the client may request, for oauth_client credentials
+	ReplacedByID string `gorm:"size:36"`   // Credential issued when this one was rotated
+	ExpiresAt    *time.Time
+	RevokedAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// DIDChallenge is a nonce a DID agent signs with one of its keys to prove it
+// controls its DID. Each challenge is answered once.
+type DIDChallenge struct {
+	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID     string    `gorm:"type:uuid;not null;index"`
+	DID         string    `gorm:"not null;size:255"`
+	Nonce       string    `gorm:"not null;size:64;uniqueIndex"`
+	Status      string    `gorm:"not null;size:20;default:'pending';check:status IN ('pending', 'verified', 'failed')"`
+	KeyID       string    `gorm:"size:300"` // Verification method the answer was signed with
+	Reason      string    `gorm:"size:255"` // Why a failed answer was refused
+	RequestedBy string    `gorm:"size:255"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	AnsweredAt  *time.Time
+	CreatedAt   time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// User is a person acting for a party, such as an approver or a compliance
+// reviewer. What a user may do beyond the scopes of its keys is decided by
+// its roles.
+type User struct {
+	ID        string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID   string `gorm:"type:uuid;not null;index"`
+	Email     string `gorm:"not null;size:255;uniqueIndex"`
+	Name      string `gorm:"not null;size:255"`
+	Status    string `gorm:"not null;default:'active';check:status IN ('active', 'disabled')"`
+	CreatedBy string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// Role is a named set of permissions routes check users for. Roles are
+// defined by the platform and seeded at startup.
+type Role struct {
+	ID          string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string `gorm:"not null;size:50;uniqueIndex"`
+	Description string `gorm:"size:500"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	ID         string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     string `gorm:"type:uuid;not null;uniqueIndex:idx_user_roles_user_role"`
+	RoleID     string `gorm:"type:uuid;not null;uniqueIndex:idx_user_roles_user_role"`
+	AssignedBy string `gorm:"size:255"`
+	CreatedAt  time.Time
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID"`
+	Role Role `gorm:"foreignKey:RoleID;references:ID"`
+}
+
+// ApproverGroupMember makes a user a member of one of its party's approver
+// groups, the groups consents name to approve payments above their cosign
+// threshold
+type ApproverGroupMember struct {
+	ID        string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID   string `gorm:"type:uuid;not null;uniqueIndex:idx_approver_group_members_member"`
+	GroupName string `gorm:"not null;size:100;uniqueIndex:idx_approver_group_members_member"`
+	UserID    string `gorm:"type:uuid;not null;uniqueIndex:idx_approver_group_members_member;index"`
+	AddedBy   string `gorm:"size:255"`
+	CreatedAt time.Time
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID"`
+}
+
+// Quote fixes the rail, fee and exchange rate of a payment until it expires.
+// The first payment referencing it in time is made under its terms.
+type Quote struct {
+	ID            string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID       string    `gorm:"type:uuid;not null;index"`
+	Amount        float64   `gorm:"type:decimal(15,2);not null"` // Amount in the payment currency
+	Currency      string    `gorm:"not null;size:3"`
+	AmountUSD     float64   `gorm:"type:decimal(15,2);not null"` // USD equivalent at FXRate
+	FXRate        float64   `gorm:"not null"`                    // USD per unit of Currency; 1 for USD
+	FXProvider    string    `gorm:"size:50"`
+	Counterparty  string    `gorm:"size:255"` // Payee the quote is for, if it was given
+	Rail          string    `gorm:"not null;size:50"`
+	RailFeeUSD    float64   `gorm:"type:decimal(15,2);not null"`
+	MarkupUSD     float64   `gorm:"type:decimal(15,2);not null"`
+	TotalFeeUSD   float64   `gorm:"type:decimal(15,2);not null"`
+	TotalDebitUSD float64   `gorm:"type:decimal(15,2);not null"` // AmountUSD plus TotalFeeUSD
+	ScheduleID    string    `gorm:"size:36"`                     // Fee schedule the markup came from
+	ExperimentID  string    `gorm:"size:36"`                     // Or the fee experiment variant
+	VariantID     string    `gorm:"size:36"`
+	ExpiresAt     time.Time `gorm:"not null;index"`
+	WorkflowID    *string   `gorm:"type:uuid;uniqueIndex"` // Payment made under the quote
+	CreatedAt     time.Time
+
+	// Beneficiary abroad the quote is for, as a JSON object, and the
+	// correspondent fees the payer pays, included in RailFeeUSD
+	International       string  `gorm:"type:jsonb"`
+	CorrespondentFeeUSD float64 `gorm:"type:decimal(15,2);not null;default:0"`
+}
+
+// PaymentRail is a rail of the rail catalog, which the orchestrator selects
+// and prices rails from and the router routes payments with. The built-in
+// rails are seeded at startup; operators add and change rails through the
+// API.
+type PaymentRail struct {
+	Rail                 string  `gorm:"primaryKey;size:50"`
+	Name                 string  `gorm:"not null;size:100"`
+	Description          string  `gorm:"size:500"`
+	MinAmount            float64 `gorm:"type:decimal(15,2);not null"`
+	MaxAmount            float64 `gorm:"type:decimal(15,2);not null"`
+	ProcessingSeconds    int     `gorm:"not null"`
+	SettlementSeconds    int     `gorm:"not null"`
+	FixedFee             float64 `gorm:"type:decimal(15,2);not null"`
+	PercentFee           float64 `gorm:"not null"` // Fraction of the amount, so 0.029 is 2.9%
+	MinFee               float64 `gorm:"type:decimal(15,2);not null"`
+	MaxFee               float64 `gorm:"type:decimal(15,2);not null"`
+	RiskLevel            string  `gorm:"not null;size:20"` // "low", "medium", "high"
+	Reversible           bool    `gorm:"not null"`
+	International        bool    `gorm:"not null"`
+	RequiresVerification bool    `gorm:"not null"`
+	MaxDescriptionLength int     `gorm:"not null"`
+	Reliability          float64 `gorm:"not null"`                           // Share of payments that complete
+	CounterpartyType     string  `gorm:"not null;size:20;default:'account'"` // "account", or "wallet" for crypto rails
+	Enabled              bool    `gorm:"not null"`                           // Disabled rails are kept but not offered
+	Builtin              bool    `gorm:"not null"`                           // Seeded from the built-in rails
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// ChainTransfer tracks the on-chain settlement of a payment execution on a
+// crypto rail, from submission until its transaction has the required
+// number of confirmations
+type ChainTransfer struct {
+	ID                    string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExecutionID           string     `gorm:"type:uuid;not null;uniqueIndex"`
+	Network               string     `gorm:"not null;size:50"` // Chain, such as ethereum or base
+	Token                 string     `gorm:"not null;size:20"`
+	FromAddress           string     `gorm:"not null;size:42"`
+	ToAddress             string     `gorm:"not null;size:42"`
+	TokenAmount           float64    `gorm:"type:decimal(30,6);not null"`
+	AmountUSD             float64    `gorm:"type:decimal(15,2);not null"`
+	RateUSD               float64    `gorm:"not null"` // USD per token the amount was converted at
+	TxHash                string     `gorm:"not null;size:66;index"`
+	BlockNumber           *int64     // Block the transaction was included in, once mined
+	Confirmations         int        `gorm:"not null;default:0"`
+	RequiredConfirmations int        `gorm:"not null"`
+	Status                string     `gorm:"not null;size:20;check:status IN ('submitted', 'confirming', 'confirmed', 'failed')"`
+	ConfirmedAt           *time.Time // When the required confirmations were reached
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// MandateKey is a public key an agent registers to sign payment mandates
+type MandateKey struct {
+	ID        string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID   string `gorm:"type:uuid;not null;index"`
+	Name      string `gorm:"size:255"`
+	Algorithm string `gorm:"not null;size:20;default:'Ed25519'"`
+	PublicKey string `gorm:"not null;size:64"` // Base64url encoded public key
+	Status    string `gorm:"not null;default:'active';check:status IN ('active', 'revoked')"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// Mandate is a verified, agent-signed authorization for one payment
+type Mandate struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID      string    `gorm:"type:uuid;not null;uniqueIndex:idx_mandate_agent_nonce"`
+	Nonce        string    `gorm:"not null;size:64;uniqueIndex:idx_mandate_agent_nonce"` // Unique per agent to prevent replay
+	KeyID        string    `gorm:"type:uuid;not null"`
+	WorkflowID   string    `gorm:"size:36;index"`
+	AmountUSD    float64   `gorm:"type:decimal(15,2);not null"`
+	Counterparty string    `gorm:"not null;size:255"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	Signature    string    `gorm:"not null;size:128"` // Base64url detached signature
+	VerifiedAt   time.Time
+	CreatedAt    time.Time
+
+	// Relationships
+	Key MandateKey `gorm:"foreignKey:KeyID;references:ID"`
+}
+
+// PaymentLink is a shareable link that lets a human fund a payment an agent
+// could not complete automatically
+type PaymentLink struct {
+	ID               string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID       string  `gorm:"type:uuid;not null;index"`
+	Token            string  `gorm:"not null;size:64;uniqueIndex"` // Unguessable token embedded in the link
+	AmountUSD        float64 `gorm:"type:decimal(15,2);not null"`
+	Counterparty     string  `gorm:"not null;size:255"`
+	Description      string  `gorm:"size:500"`
+	Reason           string  `gorm:"size:255"` // Why human funding is needed
+	Status           string  `gorm:"not null;default:'open';check:status IN ('open', 'completed', 'expired', 'cancelled')"`
+	FundingMethod    string  `gorm:"size:50"`
+	FundingReference string  `gorm:"size:255"`
+	ExpiresAt        time.Time
+	CompletedAt      *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	// Relationships
+	Workflow PaymentWorkflow `gorm:"foreignKey:WorkflowID;references:ID"`
+}
+
+// FundingSource is an external bank account linked through an open-banking provider
+type FundingSource struct {
+	ID                 string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID            string `gorm:"type:uuid;not null;index"`
+	Provider           string `gorm:"not null;size:50"`
+	ItemID             string `gorm:"size:255;index"`     // Provider item (institution login) ID
+	ExternalAccountID  string `gorm:"not null;size:255"`  // Provider account ID
+	AccessToken        string `gorm:"not null;size:1024"` // Encrypted provider access token
+	InstitutionName    string `gorm:"size:255"`
+	AccountName        string `gorm:"size:255"`
+	Mask               string `gorm:"size:10"`
+	AccountType        string `gorm:"size:50"`
+	AccountSubtype     string `gorm:"size:50"`
+	Status             string `gorm:"not null;default:'pending_verification';check:status IN ('pending_verification', 'verified', 'verification_failed', 'disabled')"`
+	VerificationMethod string `gorm:"size:50"`
+	VerifiedAt         *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// FundingTransfer is a debit from a funding source, either a wallet top-up or a direct ACH debit
+type FundingTransfer struct {
+	ID                  string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	FundingSourceID     string  `gorm:"type:uuid;not null;index"`
+	AgentID             string  `gorm:"type:uuid;not null;index"`
+	Type                string  `gorm:"not null;check:type IN ('top_up', 'ach_debit')"`
+	AmountUSD           float64 `gorm:"type:decimal(15,2);not null"`
+	Description         string  `gorm:"size:500"`
+	Status              string  `gorm:"not null;check:status IN ('pending', 'posted', 'failed')"`
+	ProviderTransferID  string  `gorm:"size:255"`
+	LedgerTransactionID string  `gorm:"size:36"`
+	ErrorMessage        string  `gorm:"size:500"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+
+	// Relationships
+	FundingSource FundingSource `gorm:"foreignKey:FundingSourceID;references:ID"`
+}
+
+// TrustedIssuer is an external agent platform whose credentials are accepted
+type TrustedIssuer struct {
+	ID                   string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                 string `gorm:"not null;size:255"`
+	Issuer               string `gorm:"not null;size:255;uniqueIndex"` // Expected "iss" claim
+	HostPartyID          string `gorm:"type:uuid;not null"`            // Party that owns the issuer's shadow agents
+	PublicKeys           string `gorm:"type:jsonb;not null"`           // JSON array of JWKs
+	RevocationListURL    string `gorm:"size:500"`
+	RevocationsSyncedAt  *time.Time
+	Status               string  `gorm:"not null;default:'active';check:status IN ('active', 'suspended')"`
+	DefaultSingleTxnUSD  float64 `gorm:"type:decimal(15,2);not null"` // Limits of shadow agent consents
+	DefaultDailyUSD      float64 `gorm:"type:decimal(15,2);not null"`
+	DefaultMaxTxnPerHour int     `gorm:"not null"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+
+	// Relationships
+	HostParty Party `gorm:"foreignKey:HostPartyID;references:ID"`
+}
+
+// FederatedIdentity maps an external agent to its local shadow agent
+type FederatedIdentity struct {
+	ID             string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	IssuerID       string `gorm:"type:uuid;not null;uniqueIndex:idx_federated_issuer_subject"`
+	Subject        string `gorm:"not null;size:255;uniqueIndex:idx_federated_issuer_subject"`
+	AgentID        string `gorm:"type:uuid;not null;index"`
+	LastVerifiedAt time.Time
+	CreatedAt      time.Time
+
+	// Relationships
+	Issuer TrustedIssuer `gorm:"foreignKey:IssuerID;references:ID"`
+	Agent  Agent         `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// FederatedRevocation is a revoked external credential, recorded locally or
+// synced from the issuer's revocation list
+type FederatedRevocation struct {
+	ID           string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	IssuerID     string `gorm:"type:uuid;not null;uniqueIndex:idx_federated_revocation"`
+	CredentialID string `gorm:"not null;size:255;uniqueIndex:idx_federated_revocation"` // "jti" of the revoked credential
+	Reason       string `gorm:"size:500"`
+	Source       string `gorm:"not null;size:20"` // "manual", "issuer_list"
+	CreatedAt    time.Time
+}
+
+// ReputationCredential is a signed payment reputation credential issued to an
+// agent. Claims are stored so a revoked credential's contents remain auditable.
+type ReputationCredential struct {
+	ID               string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID          string    `gorm:"type:uuid;not null;index"`
+	Claims           string    `gorm:"type:jsonb;not null"` // JSON object of disclosed reputation claims
+	Status           string    `gorm:"not null;default:'active';check:status IN ('active', 'revoked')"`
+	IssuedAt         time.Time `gorm:"not null"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+	RevocationReason string `gorm:"size:500"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// CounterpartyBankAccount holds the bank details of an ACH payee. ACH
+// executions to a counterparty are only sent once its account is verified.
+type CounterpartyBankAccount struct {
+	ID                   string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID              string `gorm:"type:uuid;not null;index:idx_counterparty_account"` // Party whose agents pay the account
+	Counterparty         string `gorm:"not null;size:255;index:idx_counterparty_account"`  // Matches PaymentExecution.Counterparty
+	HolderName           string `gorm:"not null;size:255"`
+	RoutingNumber        string `gorm:"not null;size:9"`
+	AccountNumber        string `gorm:"not null;size:1024"` // Encrypted account number
+	AccountMask          string `gorm:"size:4"`
+	AccountType          string `gorm:"not null;size:20;check:account_type IN ('checking', 'savings')"`
+	Status               string `gorm:"not null;default:'pending_verification';check:status IN ('pending_verification', 'verified', 'verification_failed', 'disabled')"`
+	VerificationMethod   string `gorm:"not null;size:50"` // "micro_deposits", "instant"
+	MicroDeposits        string `gorm:"size:1024"`        // Encrypted amounts awaiting confirmation
+	VerificationAttempts int    `gorm:"not null;default:0"`
+	ProviderReference    string `gorm:"size:255"`
+	VerifiedAt           *time.Time
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// Refund returns all or part of a completed payment execution to the payer
+type Refund struct {
+	ID                  string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExecutionID         string  `gorm:"type:uuid;not null;index"`
+	AgentID             string  `gorm:"type:uuid;not null;index"`
+	AmountUSD           float64 `gorm:"type:decimal(15,2);not null"`
+	Reason              string  `gorm:"size:500"`
+	Status              string  `gorm:"not null;check:status IN ('pending', 'processing', 'completed', 'failed')"`
+	ReferenceID         string  `gorm:"size:255"` // Processor reference of the refund
+	ErrorMessage        string  `gorm:"size:500"`
+	LedgerTransactionID string  `gorm:"size:36"` // Compensating ledger transaction, if the payment was booked
+	CompletedAt         *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	Execution PaymentExecution `gorm:"foreignKey:ExecutionID;references:ID"`
+}
+
+// Counterparty is a payee a party's agents pay, referenced by payments in
+// place of a free-form name. The party keeps it on its allow or deny list;
+// compliance officers set its KYC status and risk rating.
+type Counterparty struct {
+	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID       string  `gorm:"type:uuid;not null;uniqueIndex:idx_counterparties_party_name"` // Party whose agents pay it
+	Name          string  `gorm:"not null;size:255;uniqueIndex:idx_counterparties_party_name"`  // Name payments and screenings use
+	Type          string  `gorm:"not null;check:type IN ('individual', 'organization')"`
+	Email         string  `gorm:"size:255"`
+	Country       string  `gorm:"size:2"`
+	Identifiers   string  `gorm:"type:jsonb"`      // JSON object of identifiers such as taxId, lei or domain
+	BankAccountID *string `gorm:"type:uuid;index"` // Counterparty bank account payments are sent to
+	KYCStatus     string  `gorm:"not null;default:'unverified';index;check:kyc_status IN ('unverified', 'pending', 'verified', 'rejected')"`
+	RiskRating    string  `gorm:"not null;default:'unrated';check:risk_rating IN ('unrated', 'low', 'medium', 'high')"`
+	KYCNotes      string  `gorm:"size:1000"`
+	KYCReviewedBy string  `gorm:"size:255"`
+	KYCReviewedAt *time.Time
+	ListStatus    string `gorm:"not null;default:'none';index;check:list_status IN ('none', 'allowed', 'denied')"`
+	ListReason    string `gorm:"size:500"`
+	CreatedBy     string `gorm:"size:255"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	Party       Party                    `gorm:"foreignKey:PartyID;references:ID"`
+	BankAccount *CounterpartyBankAccount `gorm:"foreignKey:BankAccountID;references:ID"`
+}
+
+// WorkflowTemplate is a version of a party's workflow template: the ordered
+// steps, with their conditions and thresholds, its agents' payments go
+// through. Versions are never changed once stored.
+type WorkflowTemplate struct {
+	ID          string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID     string `gorm:"type:uuid;not null;uniqueIndex:idx_workflow_templates_party_version"`
+	Version     int    `gorm:"not null;uniqueIndex:idx_workflow_templates_party_version"`
+	Template    string `gorm:"type:jsonb;not null"` // JSON object of workflowtemplate.Template
+	Description string `gorm:"size:500"`
+	Active      bool   `gorm:"not null;default:false;index"`
+	CreatedBy   string `gorm:"size:255"`
+	ActivatedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// PaymentSchedule is a standing payment an agent makes on a cron schedule
+// or at a fixed interval until it ends. The scheduler creates a payment each
+// time it is due.
+type PaymentSchedule struct {
+	ID              string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID         string  `gorm:"type:uuid;not null;index"`
+	Amount          float64 `gorm:"type:decimal(15,2);not null"`
+	Currency        string  `gorm:"not null;size:3;default:'USD'"`
+	Counterparty    string  `gorm:"size:255"`
+	CounterpartyID  *string `gorm:"type:uuid;index"`
+	Rail            string  `gorm:"size:50"`    // Empty selects a rail for each payment
+	Preferences     string  `gorm:"type:jsonb"` // JSON object of rail preferences
+	Description     string  `gorm:"size:500"`
+	Metadata        string  `gorm:"type:jsonb"`
+	CallbackURL     string  `gorm:"size:2048"`
+	Category        string  `gorm:"size:100"`
+	Cron            string  `gorm:"size:100"` // Five-field cron expression, or empty with an interval
+	IntervalSeconds int64   `gorm:"not null;default:0"`
+	Timezone        string  `gorm:"size:64"` // Time zone the cron expression is evaluated in
+	StartAt         time.Time
+	EndAt           *time.Time
+	MaxRuns         int        `gorm:"not null;default:0"` // 0 for no limit
+	Status          string     `gorm:"not null;size:20;index;check:status IN ('active', 'paused', 'cancelled', 'completed')"`
+	NextRunAt       *time.Time `gorm:"index"`
+	LastRunAt       *time.Time
+	RunCount        int    `gorm:"not null;default:0"`
+	FailureCount    int    `gorm:"not null;default:0"` // Consecutive runs that failed to create a payment
+	StatusReason    string `gorm:"size:500"`
+	CreatedBy       string `gorm:"size:255"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// PaymentScheduleRun is one time a schedule was due: the payment it created,
+// or why it could not create one
+type PaymentScheduleRun struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ScheduleID   string    `gorm:"type:uuid;not null;index"`
+	ScheduledFor time.Time `gorm:"not null"`
+	WorkflowID   *string   `gorm:"type:uuid"`
+	Status       string    `gorm:"not null;size:20;check:status IN ('created', 'failed')"`
+	Error        string    `gorm:"size:500"`
+	CreatedAt    time.Time
+}
+
+// Budget caps what an agent may spend per period, in one category of
+// payments or across all of them, e.g. $500 a month on marketing. It rolls
+// over at the end of each period, carrying over unspent budget if set to.
+type Budget struct {
+	ID              string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID         string    `gorm:"type:uuid;not null;uniqueIndex:idx_budgets_agent_category_period"`
+	Category        string    `gorm:"not null;size:100;uniqueIndex:idx_budgets_agent_category_period"` // Empty covers all the agent's payments
+	Period          string    `gorm:"not null;size:20;uniqueIndex:idx_budgets_agent_category_period;check:period IN ('daily', 'weekly', 'monthly')"`
+	AmountUSD       float64   `gorm:"type:decimal(15,2);not null"`
+	CarryOver       string    `gorm:"not null;size:20;default:'none';check:carry_over IN ('none', 'unspent', 'capped')"`
+	MaxCarryOverUSD float64   `gorm:"type:decimal(15,2);not null;default:0"` // Cap of the capped option
+	Active          bool      `gorm:"not null;default:true"`
+	PeriodStart     time.Time `gorm:"not null"` // Current period
+	PeriodEnd       time.Time `gorm:"not null;index"`
+	CarriedOverUSD  float64   `gorm:"type:decimal(15,2);not null;default:0"` // Carried into the current period
+	CreatedBy       string    `gorm:"size:255"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// BudgetPeriod is a closed period of a budget: what it allowed, spent and
+// carried over
+type BudgetPeriod struct {
+	ID            string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BudgetID      string    `gorm:"type:uuid;not null;uniqueIndex:idx_budget_periods_budget_start"`
+	PeriodStart   time.Time `gorm:"not null;uniqueIndex:idx_budget_periods_budget_start"`
+	PeriodEnd     time.Time `gorm:"not null"`
+	AmountUSD     float64   `gorm:"type:decimal(15,2);not null"`
+	CarriedInUSD  float64   `gorm:"type:decimal(15,2);not null;default:0"`
+	SpentUSD      float64   `gorm:"type:decimal(15,2);not null;default:0"`
+	CarriedOutUSD float64   `gorm:"type:decimal(15,2);not null;default:0"`
+	CreatedAt     time.Time
+}
+
+// KYCSubmission is what a party submitted to verify its identity (KYC) or
+// business (KYB), and the decision on it, by the verification provider or by
+// a compliance reviewer when the provider left it pending
+type KYCSubmission struct {
+	ID                string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID           string `gorm:"type:uuid;not null;index"`
+	Kind              string `gorm:"not null;size:10;check:kind IN ('kyc', 'kyb')"`
+	Attributes        string `gorm:"type:jsonb"` // JSON object of the submitted attributes
+	Documents         string `gorm:"type:jsonb"` // JSON array of the submitted documents
+	Status            string `gorm:"not null;size:20;index;check:status IN ('pending', 'verified', 'rejected')"`
+	Reason            string `gorm:"size:500"`
+	Provider          string `gorm:"not null;size:50"`
+	ProviderReference string `gorm:"size:255"`
+	SubmittedBy       string `gorm:"size:255"`
+	ReviewedBy        string `gorm:"size:255"` // Reviewer of a submission the provider left pending
+	DecidedAt         *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// PaymentBatch is a batch of a rail's executions submitted to the processor
+// together, such as the ACH payments of one cutoff window. Items are added
+// while it is open; it is closed at its cutoff and then submitted.
+type PaymentBatch struct {
+	ID                   string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Rail                 string    `gorm:"not null;size:50;index:idx_payment_batches_rail_cutoff"`
+	Reference            string    `gorm:"not null;size:64;uniqueIndex"` // e.g. ACH-20261017-1430-1, the reference of the batch's ledger transactions
+	CutoffAt             time.Time `gorm:"not null;index:idx_payment_batches_rail_cutoff"`
+	Status               string    `gorm:"not null;size:20;index;check:status IN ('open', 'closed', 'submitted', 'completed', 'failed')"`
+	ItemCount            int       `gorm:"not null;default:0"`
+	TotalUSD             float64   `gorm:"type:decimal(15,2);not null;default:0"`
+	SettledCount         int       `gorm:"not null;default:0"`
+	SettledUSD           float64   `gorm:"type:decimal(15,2);not null;default:0"`
+	FailedCount          int       `gorm:"not null;default:0"`
+	ClosedBy             string    `gorm:"size:255"` // Operator who closed it early, empty at its cutoff
+	SubmittedBy          string    `gorm:"size:255"`
+	ClosedAt             *time.Time
+	SubmittedAt          *time.Time
+	CompletedAt          *time.Time
+	LedgerTransactionIDs string `gorm:"type:jsonb"` // JSON array of the clearing transactions booked for it
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// PaymentBatchItem is an execution in a batch and its result
+type PaymentBatchItem struct {
+	ID           string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	BatchID      string  `gorm:"type:uuid;not null;index"`
+	ExecutionID  string  `gorm:"type:uuid;not null;uniqueIndex"`
+	AgentID      string  `gorm:"type:uuid;not null"`
+	AmountUSD    float64 `gorm:"type:decimal(15,2);not null"`
+	Counterparty string  `gorm:"not null;size:255"`
+	Status       string  `gorm:"not null;size:20;check:status IN ('pending', 'submitted', 'completed', 'failed', 'removed')"`
+	ReferenceID  string  `gorm:"size:255"` // Processor reference of the item
+	ErrorMessage string  `gorm:"size:500"`
+	SubmittedAt  *time.Time
+	CompletedAt  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WatchlistEntry is a name on a sanctions list or the internal denylist that
+// payment counterparties are screened against
+type WatchlistEntry struct {
+	ID        string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ListType  string `gorm:"not null;size:20;index;check:list_type IN ('sanctions', 'denylist')"`
+	ListName  string `gorm:"not null;size:100"` // e.g. "OFAC SDN", "internal"
+	Name      string `gorm:"not null;size:255"`
+	Aliases   string `gorm:"type:jsonb"` // JSON array of alternative names
+	Country   string `gorm:"size:2"`
+	Program   string `gorm:"size:100"` // Sanctions program or denylist reason code
+	Reason    string `gorm:"size:500"`
+	Active    bool   `gorm:"not null;default:true;index"`
+	CreatedBy string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// ComplianceScreening is the result of screening a counterparty against the
+// watchlists. Hits below the block threshold wait in the review queue until a
+// compliance officer approves or rejects them.
+type ComplianceScreening struct {
+	ID             string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID     *string `gorm:"type:uuid;index"`
+	AgentID        string  `gorm:"type:uuid;not null;index"`
+	Counterparty   string  `gorm:"not null;size:255"`
+	CounterpartyID *string `gorm:"type:uuid;index"` // Counterparty entity screened, when the payment referenced one
+	AmountUSD      float64 `gorm:"type:decimal(15,2)"`
+	Status         string  `gorm:"not null;index;check:status IN ('clear', 'pending_review', 'approved', 'rejected', 'blocked')"`
+	MatchScore     float64 `gorm:"type:decimal(5,4)"` // Best match score, 0 when clear
+	Matches        string  `gorm:"type:jsonb"`        // JSON array of matched entries and scores
+	ReviewedBy     string  `gorm:"size:255"`
+	ReviewNotes    string  `gorm:"size:1000"`
+	ReviewedAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// AMLRule is a transaction-monitoring rule defined by compliance officers and
+// evaluated with every risk evaluation
+type AMLRule struct {
+	ID          string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string  `gorm:"not null;size:255"`
+	Description string  `gorm:"size:500"`
+	Type        string  `gorm:"not null;size:50;check:type IN ('structuring', 'rapid_fire', 'near_threshold', 'new_counterparty_high_amount')"`
+	Parameters  string  `gorm:"type:jsonb"` // JSON object of the type's parameters
+	Action      string  `gorm:"not null;check:action IN ('flag', 'review', 'deny')"`
+	ScoreImpact float64 `gorm:"type:decimal(3,2);not null;default:0"` // Added to the risk score when triggered
+	Enabled     bool    `gorm:"not null"`
+	CreatedBy   string  `gorm:"size:255"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// DescriptionTemplate is a party's template for the descriptions of its
+// agents' payments, optionally specific to one rail
+type DescriptionTemplate struct {
+	ID        string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID   string `gorm:"type:uuid;not null;index"`
+	Rail      string `gorm:"size:50"` // Empty applies to every rail without its own template
+	Template  string `gorm:"not null;size:500"`
+	Active    bool   `gorm:"not null"`
+	CreatedBy string `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// Dead-letter statuses
+const (
+	DeadLetterPending  = "pending"  // Waiting to be replayed
+	DeadLetterReplayed = "replayed" // Replayed through its handlers successfully
+)
+
+// DeadLetterEvent records a consumed message that its handlers failed to
+// process, so it can be inspected and replayed
+type DeadLetterEvent struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Topic        string    `gorm:"not null;size:255;index"`
+	GroupID      string    `gorm:"not null;size:255"`
+	Partition    int       `gorm:"not null"`
+	Offset       int64     `gorm:"not null"`
+	MessageKey   string    `gorm:"size:255"`
+	Payload      string    `gorm:"type:text"`
+	ErrorMessage string    `gorm:"size:500"`
+	CreatedAt    time.Time `gorm:"index"`
+
+	Headers        string `gorm:"type:text"`          // Message headers as a JSON object, kept for CloudEvents binary messages
+	EventID        string `gorm:"size:255;index"`     // Empty when the message could not be decoded
+	EventType      string `gorm:"size:100"`           // Empty when the message could not be decoded
+	FailedHandlers string `gorm:"size:500"`           // Comma-separated handlers that failed; empty when none ran
+	Attempts       int    `gorm:"not null;default:0"` // Times each failed handler was tried
+	DLQTopic       string `gorm:"size:255"`           // Topic the message was also published to; empty if it was not
+	Status         string `gorm:"not null;size:20;default:'pending';check:status IN ('pending', 'replayed');index"`
+	ReplayCount    int    `gorm:"not null;default:0"` // Replays attempted
+	ReplayError    string `gorm:"size:500"`           // Why the last replay failed
+	LastReplayAt   *time.Time
+	UpdatedAt      time.Time
+}
+
+// ReviewCase holds a payment the risk service sent to manual review until a
+// reviewer approves or denies it
+type ReviewCase struct {
+	ID             string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	RiskDecisionID string  `gorm:"type:uuid;not null;uniqueIndex"`
+	WorkflowID     *string `gorm:"type:uuid;index"` // Payment held by the case, when evaluated for one
+	AgentID        string  `gorm:"type:uuid;not null;index"`
+	AmountUSD      float64 `gorm:"type:decimal(15,2);not null"`
+	Counterparty   string  `gorm:"not null;size:255"`
+	Rail           string  `gorm:"not null;size:50"`
+	Score          float64 `gorm:"type:decimal(3,2);not null"`
+	Reason         string  `gorm:"size:500"`
+	RiskFactors    string  `gorm:"type:jsonb"` // JSON array of strings
+	Status         string  `gorm:"not null;default:'pending';check:status IN ('pending', 'approved', 'denied');index"`
+	ReviewedBy     string  `gorm:"size:255"`
+	ReviewNotes    string  `gorm:"size:1000"`
+	ReviewedAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	Agent        Agent        `gorm:"foreignKey:AgentID;references:ID"`
+	RiskDecision RiskDecision `gorm:"foreignKey:RiskDecisionID;references:ID"`
+}
+
+// Approval holds a payment above its consent's cosign threshold until an
+// approver of the owner party approves or rejects it
+type Approval struct {
+	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID    string  `gorm:"type:uuid;not null;uniqueIndex"`
+	ConsentID     string  `gorm:"type:uuid;not null;index"`
+	AgentID       string  `gorm:"type:uuid;not null;index"`
+	OwnerPartyID  string  `gorm:"type:uuid;not null;index"`
+	AmountUSD     float64 `gorm:"type:decimal(15,2);not null"`
+	Counterparty  string  `gorm:"not null;size:255"`
+	Rail          string  `gorm:"not null;size:50"`
+	ApproverGroup string  `gorm:"not null;size:100"`
+	Status        string  `gorm:"not null;default:'pending';check:status IN ('pending', 'approved', 'rejected');index"`
+	DecidedBy     string  `gorm:"size:255"`
+	DecisionNotes string  `gorm:"size:1000"`
+	DecidedAt     *time.Time
+	ExpiresAt     time.Time `gorm:"not null"`
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+
+	// Relationships
+	Consent    Consent         `gorm:"foreignKey:ConsentID;references:ID"`
+	Workflow   PaymentWorkflow `gorm:"foreignKey:WorkflowID;references:ID"`
+	OwnerParty Party           `gorm:"foreignKey:OwnerPartyID;references:ID"`
+}
+
+// WorkflowTransition records a payment workflow's change of status
+type WorkflowTransition struct {
+	ID         string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID string `gorm:"type:uuid;not null;index"`
+	FromStatus string `gorm:"size:50"` // Empty when the workflow was created
+	ToStatus   string `gorm:"not null;size:50"`
+	Reason     string `gorm:"size:500"`
+	Actor      string `gorm:"size:255"` // Principal or service that made the change
+	CreatedAt  time.Time
+
+	// Relationships
+	Workflow PaymentWorkflow `gorm:"foreignKey:WorkflowID;references:ID"`
+}
+
+// Hold reserves part of an account's balance, typically for a payment until
+// it executes. Active holds reduce the account's available balance.
+type Hold struct {
+	ID             string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID        string     `gorm:"type:uuid;not null;index"`
+	AccountID      string     `gorm:"type:uuid;not null;index"`
+	Amount         float64    `gorm:"type:decimal(15,2);not null"`
+	Currency       string     `gorm:"not null;size:3"`
+	ReferenceID    string     `gorm:"size:255;index"` // What the funds are reserved for, such as a payment workflow
+	Description    string     `gorm:"size:500"`
+	Status         string     `gorm:"not null;index;check:status IN ('active', 'captured', 'released', 'expired')"`
+	CapturedAmount float64    `gorm:"type:decimal(15,2)"`
+	TransactionID  string     `gorm:"size:36"` // Ledger transaction posted on capture
+	ExpiresAt      time.Time  `gorm:"not null;index"`
+	ResolvedAt     *time.Time // When it was captured, released or expired
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	// Relationships
+	Account Account `gorm:"foreignKey:AccountID;references:ID"`
+}
+
+// AutoDeclineRule is an owner party's hard limit on its agents' payments,
+// checked before risk scoring. A payment matching it is denied.
+type AutoDeclineRule struct {
+	ID           string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OwnerPartyID string  `gorm:"type:uuid;not null;index"`
+	AgentID      *string `gorm:"type:uuid;index"` // Applies to every agent of the party when nil
+	Name         string  `gorm:"not null;size:255"`
+	Type         string  `gorm:"not null;size:50;check:type IN ('blocked_counterparties', 'quiet_hours', 'new_counterparty_daily_limit')"`
+	Parameters   string  `gorm:"type:jsonb"` // JSON object of the type's parameters
+	Enabled      bool    `gorm:"not null"`
+	CreatedBy    string  `gorm:"size:255"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+// BalanceSnapshot records an account's balance as derived from its postings
+// up to a point in time, so later balances only sum the postings made since
+type BalanceSnapshot struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AccountID string    `gorm:"type:uuid;not null;index:idx_balance_snapshots_account_as_of"`
+	Balance   float64   `gorm:"type:decimal(15,2);not null"`
+	AsOf      time.Time `gorm:"not null;index:idx_balance_snapshots_account_as_of"` // Postings created up to this time are included
+	CreatedAt time.Time
+}
+
+// FeeSchedule is a tenant's markup on rail fees. A tenant is the party that
+// owns the paying agent. A schedule for a rail overrides one for all rails.
+type FeeSchedule struct {
+	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID       string  `gorm:"type:uuid;not null;index"`
+	Rail          string  `gorm:"size:50"`                    // Empty for all rails
+	MarkupPercent float64 `gorm:"type:decimal(7,4);not null"` // Fraction of the rail fee, 0.2 = 20%
+	MarkupFixed   float64 `gorm:"type:decimal(15,2);not null"`
+	MinMarkup     float64 `gorm:"type:decimal(15,2)"`
+	MaxMarkup     float64 `gorm:"type:decimal(15,2)"` // Zero for no maximum
+	Enabled       bool    `gorm:"not null;default:true"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+// FeeCharge is the fee booked for a payment: the rail's fee and the tenant's
+// markup on it, which is the platform's revenue
+type FeeCharge struct {
+	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID    string  `gorm:"type:uuid;not null;uniqueIndex"`
+	AgentID       string  `gorm:"type:uuid;not null;index"`
+	PartyID       string  `gorm:"type:uuid;not null;index"`
+	Rail          string  `gorm:"not null;size:50;index"`
+	AmountUSD     float64 `gorm:"type:decimal(15,2);not null"` // Payment amount
+	RailFeeUSD    float64 `gorm:"type:decimal(15,2);not null"`
+	MarkupUSD     float64 `gorm:"type:decimal(15,2);not null"`
+	TotalFeeUSD   float64 `gorm:"type:decimal(15,2);not null"`
+	ScheduleID    string  `gorm:"size:36"` // Fee schedule the markup came from
+	VariantID     string  `gorm:"size:36"` // Or the fee experiment variant
+	TransactionID string  `gorm:"size:36"` // Ledger transaction booking the fee
+	Status        string  `gorm:"not null;index;check:status IN ('charged', 'refunded')"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// FeeRevenue totals the fees charged to one tenant on one rail
+type FeeRevenue struct {
+	PartyID     string  `json:"partyId"`
+	Rail        string  `json:"rail"`
+	Payments    int     `json:"payments"`
+	AmountUSD   float64 `json:"amountUSD"`
+	RailFeeUSD  float64 `json:"railFeeUSD"`
+	MarkupUSD   float64 `json:"markupUSD"`
+	TotalFeeUSD float64 `json:"totalFeeUSD"`
+}
+
+// FeeExperiment tries markup variants against each other on a rail, or on
+// all rails. Tenants or agents are assigned a variant, which then overrides
+// their fee schedule until the experiment ends.
+type FeeExperiment struct {
+	ID         string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name       string `gorm:"not null;size:255"`
+	Rail       string `gorm:"size:50"`  // Empty for all rails
+	AutoAssign bool   `gorm:"not null"` // Assign tenants not yet in the experiment by weight
+	Status     string `gorm:"not null;index;check:status IN ('active', 'ended')"`
+	EndedAt    *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// Relationships
+	Variants []FeeExperimentVariant `gorm:"foreignKey:ExperimentID"`
+}
+
+// FeeExperimentVariant is one markup tried in an experiment. Weight sets the
+// share of automatically assigned tenants it gets.
+type FeeExperimentVariant struct {
+	ID            string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExperimentID  string  `gorm:"type:uuid;not null;index"`
+	Name          string  `gorm:"not null;size:100"`
+	Weight        int     `gorm:"not null;default:1"`
+	MarkupPercent float64 `gorm:"type:decimal(7,4);not null"` // Fraction of the rail fee, 0.2 = 20%
+	MarkupFixed   float64 `gorm:"type:decimal(15,2);not null"`
+	MinMarkup     float64 `gorm:"type:decimal(15,2)"`
+	MaxMarkup     float64 `gorm:"type:decimal(15,2)"` // Zero for no maximum
+	Quotes        int64   `gorm:"not null;default:0"` // Fees quoted under the variant
+	CreatedAt     time.Time
+}
+
+// FeeExperimentAssignment places a tenant (party) or an agent in a variant.
+// An agent's own assignment overrides its tenant's.
+type FeeExperimentAssignment struct {
+	ID           string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExperimentID string `gorm:"type:uuid;not null;uniqueIndex:idx_fee_experiment_subject"`
+	SubjectID    string `gorm:"type:uuid;not null;uniqueIndex:idx_fee_experiment_subject"`
+	SubjectType  string `gorm:"not null;size:10;check:subject_type IN ('party', 'agent')"`
+	VariantID    string `gorm:"type:uuid;not null;index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// FeeVariantResult totals the payments made under one experiment variant
+type FeeVariantResult struct {
+	VariantID         string  `json:"variantId"`
+	Payments          int     `json:"payments"`
+	CompletedPayments int     `json:"completedPayments"`
+	VolumeUSD         float64 `json:"volumeUSD"` // Of completed payments
+	MarkupUSD         float64 `json:"markupUSD"` // Markup charged and not refunded
+}
+
+// AuditAnchor is the Merkle root over a batch of audit entries. The entries
+// it covers point back to it with their place among its leaves.
+type AuditAnchor struct {
+	ID            string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Root          string    `gorm:"not null;size:64"`
+	EntryCount    int       `gorm:"not null"`
+	FirstEntryID  string    `gorm:"type:uuid;not null"` // Leaf 0
+	LastEntryID   string    `gorm:"type:uuid;not null"` // Last leaf
+	FromTimestamp time.Time `gorm:"not null"`           // Timestamp of the first entry
+	ToTimestamp   time.Time `gorm:"not null"`           // Timestamp of the last entry
+	CreatedAt     time.Time
+}
+
+// MigrationCheckpoint records how far the backfill of an online migration has
+// got, so it can be paused and resumed without starting over
+type MigrationCheckpoint struct {
+	ID          string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string `gorm:"not null;size:100;uniqueIndex"`
+	Status      string `gorm:"not null;check:status IN ('running', 'paused', 'completed', 'failed')"`
+	LastID      string `gorm:"size:36"` // Primary key of the last row backfilled; rows are visited in key order
+	Processed   int64  `gorm:"not null;default:0"`
+	Total       int64  `gorm:"not null;default:0"` // Rows in the table when the backfill started
+	Error       string `gorm:"size:500"`
+	StartedAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// AdapterCallLog records one call made to a processor through a rail
+// adapter, as evidence in disputes with the processor. Secrets and payment
+// instruments are redacted from the request summary before it is stored.
+type AdapterCallLog struct {
+	ID                string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ExecutionID       string    `gorm:"type:uuid;index"`
+	Rail              string    `gorm:"not null;size:50"`
+	Operation         string    `gorm:"not null;size:50"` // authorize, capture, cancel, refund or status
+	Method            string    `gorm:"size:10"`          // HTTP method, for adapters calling an HTTP API
+	Endpoint          string    `gorm:"size:500"`
+	RequestSummary    string    `gorm:"type:jsonb"` // JSON object of redacted request fields
+	StatusCode        int       // HTTP status of the processor's response; zero if none was received
+	Outcome           string    `gorm:"not null;size:50"` // Adapter status returned, or "error"
+	Error             string    `gorm:"size:500"`
+	ProviderReference string    `gorm:"size:255;index"`
+	LatencyMs         int64     `gorm:"not null"`
+	CreatedAt         time.Time `gorm:"index"`
+}
+
+// AdapterCallStats totals the calls made to one rail's processor
+type AdapterCallStats struct {
+	Rail         string
+	Calls        int
+	Errors       int     // Calls that failed without a processor status
+	AvgLatencyMs float64 // Mean latency of all calls
+	MaxLatencyMs int64
+}
+
+// PostingRule maps an account onto an account of the same agent in another
+// book. A transaction posted to accounts with rules into a book is fanned out
+// into a mirror transaction in that book, posting the same amounts to the
+// mapped accounts.
+type PostingRule struct {
+	ID              string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID         string `gorm:"type:uuid;not null;index"`
+	SourceAccountID string `gorm:"type:uuid;not null;uniqueIndex:idx_posting_rules_source_book"`
+	TargetBook      string `gorm:"not null;size:50;uniqueIndex:idx_posting_rules_source_book"` // Book of the target account
+	TargetAccountID string `gorm:"type:uuid;not null"`
+	Description     string `gorm:"size:500"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// StatementToken lets a counterparty view the payments an agent made to it
+// through a read-only statement. Only the token's hash is stored.
+type StatementToken struct {
+	ID           string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID      string `gorm:"type:uuid;not null;index"` // Owner of the agent
+	AgentID      string `gorm:"type:uuid;not null;index"`
+	Counterparty string `gorm:"not null;size:255"`
+	TokenPrefix  string `gorm:"not null;size:12"`             // Shown so owners can tell tokens apart
+	TokenHash    string `gorm:"not null;size:64;uniqueIndex"` // SHA-256 of the token
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	LastUsedAt   *time.Time
+	CreatedBy    string `gorm:"size:255"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// PaymentCallback is the delivery of a payment's final status to the
+// callback URL given when it was initiated
+type PaymentCallback struct {
+	ID             string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WorkflowID     string    `gorm:"type:uuid;not null;index"`
+	URL            string    `gorm:"not null;size:2048"`
+	Payload        string    `gorm:"type:jsonb;not null"` // Signed summary, identical on every attempt
+	Status         string    `gorm:"not null;default:'pending';check:status IN ('pending', 'delivered', 'failed')"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int
+	LastError      string `gorm:"size:500"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	// Relationships
+	Workflow PaymentWorkflow `gorm:"foreignKey:WorkflowID;references:ID"`
+}
+
+// RiskPolicy is a numbered version of the threshold and factor weights the
+// risk service scores payments with. At most one version is active.
+type RiskPolicy struct {
+	ID          string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Version     int    `gorm:"not null;uniqueIndex"`
+	Policy      string `gorm:"type:jsonb;not null"` // JSON object of riskpolicy.Policy
+	Description string `gorm:"size:500"`
+	Active      bool   `gorm:"not null;default:false;index"`
+	CreatedBy   string `gorm:"size:255"`
+	ActivatedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// AgentRiskProfile is how far the risk service trusts an agent. The tier is
+// assessed from the agent's payment history unless an operator pins it, and
+// sets the threshold the agent's payments are scored against.
+type AgentRiskProfile struct {
+	ID              string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	AgentID         string  `gorm:"type:uuid;not null;uniqueIndex"`
+	TrustTier       string  `gorm:"not null;size:20;index;check:trust_tier IN ('new', 'standard', 'trusted', 'restricted')"`
+	TierPinned      bool    `gorm:"not null;default:false"`               // Set by an operator rather than assessed
+	ScoreAdjustment float64 `gorm:"type:decimal(3,2);not null;default:0"` // Manual adjustment added to every score
+	Notes           string  `gorm:"size:1000"`
+	UpdatedBy       string  `gorm:"size:255"` // Operator who last pinned the tier or adjusted the score
+
+	// Payment history the tier was last assessed from
+	CompletedPayments int64   `gorm:"not null;default:0"`
+	FailedPayments    int64   `gorm:"not null;default:0"`
+	RefundedPayments  int64   `gorm:"not null;default:0"`
+	FailureRate       float64 `gorm:"type:decimal(5,4);not null;default:0"` // Failed share of finished payments
+	ChargebackRate    float64 `gorm:"type:decimal(5,4);not null;default:0"` // Refunded share of completed payments
+	AssessedAt        time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Relationships
+	Agent Agent `gorm:"foreignKey:AgentID;references:ID"`
+}
+
+// PartySpendingLimit caps the combined spend of all of a party's agents over
+// a calendar period, on top of each agent's own consent limits
+type PartySpendingLimit struct {
+	ID        string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PartyID   string  `gorm:"type:uuid;not null;uniqueIndex:idx_party_spending_limits_party_period"`
+	Period    string  `gorm:"not null;size:20;uniqueIndex:idx_party_spending_limits_party_period;check:period IN ('daily', 'weekly', 'monthly')"`
+	LimitUSD  float64 `gorm:"type:decimal(15,2);not null"`
+	CreatedBy string  `gorm:"size:255"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Relationships
+	Party Party `gorm:"foreignKey:PartyID;references:ID"`
+}
+
+// KillSwitchEvent records a kill switch being engaged or released for a component
+// such as a payment rail. The latest event per component is its current state.
+type KillSwitchEvent struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Component string    `gorm:"not null;size:50;index"`
+	Engaged   bool      `gorm:"not null"`
+	Reason    string    `gorm:"size:500"`
+	Actor     string    `gorm:"size:255"` // Principal that toggled the switch
+	CreatedAt time.Time `gorm:"index"`
 }
 
 // AuditQueryFilters represents filters for querying audit entries
@@ -218,6 +1421,7 @@ type AuditQueryFilters struct {
 	StartDate    *time.Time
 	EndDate      *time.Time
 	IPAddress    string
+	TenantID     string // Only entries about the agents of a tenant's parties
 	Limit        int
 	Offset       int
 }
@@ -257,7 +1461,193 @@ func (OutboxEvent) TableName() string {
 	return "outbox_events"
 }
 
+// TableName specifies the table name for APICredential
+func (APICredential) TableName() string {
+	return "api_credentials"
+}
+
+// TableName specifies the table name for AgentCredential
+func (AgentCredential) TableName() string {
+	return "agent_credentials"
+}
+
+// TableName specifies the table name for KillSwitchEvent
+func (KillSwitchEvent) TableName() string {
+	return "kill_switch_events"
+}
+
+// TableName specifies the table name for MandateKey
+func (MandateKey) TableName() string {
+	return "mandate_keys"
+}
+
+// TableName specifies the table name for Mandate
+func (Mandate) TableName() string {
+	return "mandates"
+}
+
+// TableName specifies the table name for PaymentLink
+func (PaymentLink) TableName() string {
+	return "payment_links"
+}
+
+// TableName specifies the table name for FundingSource
+func (FundingSource) TableName() string {
+	return "funding_sources"
+}
+
+// TableName specifies the table name for FundingTransfer
+func (FundingTransfer) TableName() string {
+	return "funding_transfers"
+}
+
+// TableName specifies the table name for TrustedIssuer
+func (TrustedIssuer) TableName() string {
+	return "trusted_issuers"
+}
+
+// TableName specifies the table name for FederatedIdentity
+func (FederatedIdentity) TableName() string {
+	return "federated_identities"
+}
+
+// TableName specifies the table name for FederatedRevocation
+func (FederatedRevocation) TableName() string {
+	return "federated_revocations"
+}
+
+func (ReputationCredential) TableName() string {
+	return "reputation_credentials"
+}
+
+func (CounterpartyBankAccount) TableName() string {
+	return "counterparty_bank_accounts"
+}
+
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+func (WatchlistEntry) TableName() string {
+	return "watchlist_entries"
+}
+
+func (ComplianceScreening) TableName() string {
+	return "compliance_screenings"
+}
+
+func (AMLRule) TableName() string {
+	return "aml_rules"
+}
+
+func (DescriptionTemplate) TableName() string {
+	return "description_templates"
+}
+
+func (DeadLetterEvent) TableName() string {
+	return "dead_letter_events"
+}
+
+func (ReviewCase) TableName() string {
+	return "review_cases"
+}
+
+func (Approval) TableName() string {
+	return "approvals"
+}
+
+func (WorkflowTransition) TableName() string {
+	return "workflow_transitions"
+}
+
+func (Hold) TableName() string {
+	return "holds"
+}
+
+func (AutoDeclineRule) TableName() string {
+	return "auto_decline_rules"
+}
+
+func (BalanceSnapshot) TableName() string {
+	return "balance_snapshots"
+}
+
+func (FeeSchedule) TableName() string {
+	return "fee_schedules"
+}
+
+func (FeeCharge) TableName() string {
+	return "fee_charges"
+}
+
+func (FeeExperiment) TableName() string {
+	return "fee_experiments"
+}
+
+func (FeeExperimentVariant) TableName() string {
+	return "fee_experiment_variants"
+}
+
+func (FeeExperimentAssignment) TableName() string {
+	return "fee_experiment_assignments"
+}
+
+func (AuditAnchor) TableName() string {
+	return "audit_anchors"
+}
+
+func (MigrationCheckpoint) TableName() string {
+	return "migration_checkpoints"
+}
+
+func (AdapterCallLog) TableName() string {
+	return "adapter_call_logs"
+}
+
+func (PostingRule) TableName() string {
+	return "posting_rules"
+}
+
+func (StatementToken) TableName() string {
+	return "statement_tokens"
+}
+
+func (PaymentCallback) TableName() string {
+	return "payment_callbacks"
+}
+
+func (PartySpendingLimit) TableName() string {
+	return "party_spending_limits"
+}
+
+func (RiskPolicy) TableName() string {
+	return "risk_policies"
+}
+
 // Migrate creates/updates database tables
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Party{}, &Agent{}, &Consent{}, &RiskDecision{}, &PaymentWorkflow{}, &PaymentExecution{}, &Account{}, &Transaction{}, &Posting{}, &OutboxEvent{}, &AuditEntry{})
+	// AutoMigrate only creates missing check constraints, so status and type
+	// checks widened after release are dropped here and recreated from the
+	// models
+	for _, check := range []struct {
+		model interface{}
+		name  string
+	}{
+		{&PaymentWorkflow{}, "chk_payment_workflows_status"},
+		{&PaymentExecution{}, "chk_payment_executions_status"},
+		{&AgentCredential{}, "chk_agent_credentials_type"},
+	} {
+		if db.Migrator().HasConstraint(check.model, check.name) {
+			if err := db.Migrator().DropConstraint(check.model, check.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return db.AutoMigrate(Models()...)
+}
+
+// Models returns a new instance of every model Migrate creates a table for
+func Models() []interface{} {
+	return []interface{}{&Party{}, &Agent{}, &Consent{}, &RiskDecision{}, &PaymentWorkflow{}, &PaymentExecution{}, &Account{}, &Transaction{}, &Posting{}, &OutboxEvent{}, &AuditEntry{}, &APICredential{}, &AgentCredential{}, &KillSwitchEvent{}, &MandateKey{}, &Mandate{}, &PaymentLink{}, &FundingSource{}, &FundingTransfer{}, &TrustedIssuer{}, &FederatedIdentity{}, &FederatedRevocation{}, &ReputationCredential{}, &CounterpartyBankAccount{}, &Refund{}, &WatchlistEntry{}, &ComplianceScreening{}, &AMLRule{}, &DescriptionTemplate{}, &DeadLetterEvent{}, &ReviewCase{}, &Approval{}, &WorkflowTransition{}, &Hold{}, &AutoDeclineRule{}, &BalanceSnapshot{}, &FeeSchedule{}, &FeeCharge{}, &FeeExperiment{}, &FeeExperimentVariant{}, &FeeExperimentAssignment{}, &AuditAnchor{}, &MigrationCheckpoint{}, &AdapterCallLog{}, &PostingRule{}, &StatementToken{}, &PaymentCallback{}, &PartySpendingLimit{}, &RiskPolicy{}, &AgentRiskProfile{}, &Counterparty{}, &WorkflowTemplate{}, &PaymentSchedule{}, &PaymentScheduleRun{}, &PaymentBatch{}, &PaymentBatchItem{}, &Budget{}, &BudgetPeriod{}, &KYCSubmission{}, &DIDChallenge{}, &User{}, &Role{}, &UserRole{}, &ApproverGroupMember{}, &Quote{}, &PaymentRail{}, &ChainTransfer{}}
 }
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Overdraft policies say how far below zero an account's available balance
+// (its balance less active holds) may go. Accounts without a policy use the
+// default for their type: assets may not be overdrawn, while the balances of
+// other types go negative in normal double-entry use.
+const (
+	OverdraftDisallow  = "disallow"  // Never below zero
+	OverdraftLimited   = "limit"     // Down to minus the account's OverdraftLimit
+	OverdraftUnlimited = "unlimited" // No floor
+)
+
+// ErrInsufficientFunds is returned when a posting would take an account
+// below the floor of its overdraft policy
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// InsufficientFundsError names the account a posting would overdraw
+type InsufficientFundsError struct {
+	AccountID string
+	Available float64 // Available balance after the posting
+	Floor     float64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("posting would take account %s to an available balance of %.2f, below %.2f", e.AccountID, e.Available, e.Floor)
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// ValidOverdraftPolicy reports whether a policy can be set on an account. The
+// empty policy restores the type's default.
+func ValidOverdraftPolicy(policy string) bool {
+	switch policy {
+	case "", OverdraftDisallow, OverdraftLimited, OverdraftUnlimited:
+		return true
+	}
+	return false
+}
+
+// EffectiveOverdraftPolicy returns the account's policy, or its type's
+// default when none is set
+func (a *Account) EffectiveOverdraftPolicy() string {
+	if a.OverdraftPolicy != "" {
+		return a.OverdraftPolicy
+	}
+	if a.Type == "asset" {
+		return OverdraftDisallow
+	}
+	return OverdraftUnlimited
+}
+
+// BalanceFloor returns the lowest available balance the account's policy
+// allows, and false if the policy sets no floor
+func (a *Account) BalanceFloor() (float64, bool) {
+	switch a.EffectiveOverdraftPolicy() {
+	case OverdraftUnlimited:
+		return 0, false
+	case OverdraftLimited:
+		return -math.Abs(a.OverdraftLimit), true
+	default:
+		return 0, true
+	}
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/example/agent-payments/libs/common"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidListParams is returned when a list is asked to sort by a field it
+// cannot sort by
+var ErrInvalidListParams = errors.New("invalid list parameters")
+
+// sortColumns maps the fields a list can be sorted by to their columns
+type sortColumns map[string]string
+
+// listPage counts the items matching a query and loads one page of them into
+// dest with their preloaded relations, sorted as asked, newest first by
+// default. Rows with equal sort values are ordered by ID so pages do not
+// overlap.
+func listPage(query *gorm.DB, params common.ListParams, columns sortColumns, dest interface{}, preloads ...string) (int, error) {
+	column := "created_at"
+	if params.Sort != "" {
+		var ok bool
+		if column, ok = columns[params.Sort]; !ok {
+			return 0, fmt.Errorf("%w: cannot sort by %s", ErrInvalidListParams, params.Sort)
+		}
+	}
+	direction := "ASC"
+	if params.Descending {
+		direction = "DESC"
+	}
+
+	if params.From != nil {
+		query = query.Where("created_at >= ?", *params.From)
+	}
+	if params.To != nil {
+		query = query.Where("created_at < ?", *params.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	for _, relation := range preloads {
+		query = query.Preload(relation)
+	}
+	err := query.Order(column + " " + direction).Order("id " + direction).
+		Limit(params.Limit).Offset(params.Offset).Find(dest).Error
+	return int(total), err
+}
+
+// PaymentWorkflowFilter selects payments by their fields. Empty fields match
+// any payment.
+type PaymentWorkflowFilter struct {
+	AgentID               string
+	Status                string
+	Rail                  string
+	Counterparty          string
+	ConsentID             string
+	RiskDecisionID        string
+	ComplianceScreeningID string
+	ScheduleID            string
+	Category              string
+	Degraded              *bool  // Whether a check was skipped or deferred while a dependency was down
+	TenantID              string // Only payments of agents of the tenant's parties
+}
+
+var paymentWorkflowSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"amountUSD": "amount_usd",
+	"status":    "status",
+}
+
+// PaymentExecutionFilter selects executions by their fields
+type PaymentExecutionFilter struct {
+	AgentID  string
+	Status   string
+	Rail     string
+	TenantID string // Only executions of agents of the tenant's parties
+}
+
+var paymentExecutionSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"amountUSD": "amount_usd",
+	"status":    "status",
+}
+
+// AgentFilter selects agents by their fields
+type AgentFilter struct {
+	OwnerPartyID string
+	Status       string
+	TenantID     string // Only agents of the tenant's parties
+}
+
+var agentSortColumns = sortColumns{
+	"createdAt":   "created_at",
+	"displayName": "display_name",
+}
+
+// TransactionFilter selects ledger transactions by their fields
+type TransactionFilter struct {
+	AgentID     string
+	Status      string
+	ReferenceID string
+	Book        string
+	TenantID    string // Only transactions of agents of the tenant's parties
+}
+
+var transactionSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"status":    "status",
+}
+
+var postingSortColumns = sortColumns{
+	"createdAt": "created_at",
+}
+
+// AccountFilter selects accounts by their fields
+type AccountFilter struct {
+	AgentID  string
+	Type     string
+	Currency string
+	Book     string
+	TenantID string // Only accounts of agents of the tenant's parties
+}
+
+var accountSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"name":      "name",
+	"type":      "type",
+}
+
+// RiskDecisionFilter selects risk decisions by their fields
+type RiskDecisionFilter struct {
+	AgentID      string
+	Decision     string
+	Rail         string
+	Counterparty string
+	TenantID     string // Only decisions of agents of the tenant's parties
+}
+
+var riskDecisionSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"amountUSD": "amount_usd",
+	"score":     "score",
+}
+
+// ConsentFilter selects consents by their fields
+type ConsentFilter struct {
+	AgentID      string
+	OwnerPartyID string
+	Revoked      *bool
+	TenantID     string // Only consents of the tenant's parties
+}
+
+var consentSortColumns = sortColumns{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+// tenantParties selects the IDs of a tenant's parties: the organization and
+// the parties belonging to it
+func tenantParties(db *gorm.DB, tenantID string) *gorm.DB {
+	return db.Model(&Party{}).Select("id").Where("id = ? OR organization_id = ?", tenantID, tenantID)
+}
+
+// tenantAgents selects the IDs of the agents of a tenant's parties
+func tenantAgents(db *gorm.DB, tenantID string) *gorm.DB {
+	return db.Model(&Agent{}).Select("id").Where("owner_party_id IN (?)", tenantParties(db, tenantID))
+}
+
+// whereSet adds an equality condition for each filter field that is set
+func whereSet(query *gorm.DB, conditions map[string]string) *gorm.DB {
+	for column, value := range conditions {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	return query
+}
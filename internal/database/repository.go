@@ -1,9 +1,16 @@
 package database
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 
+	"github.com/example/agent-payments/libs/common"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository defines the interface for data access
@@ -19,6 +26,63 @@ type Repository interface {
 	PostingRepository() PostingRepository
 	OutboxEventRepository() OutboxEventRepository
 	AuditEntryRepository() AuditEntryRepository
+	APICredentialRepository() APICredentialRepository
+	AgentCredentialRepository() AgentCredentialRepository
+	KillSwitchEventRepository() KillSwitchEventRepository
+	MandateKeyRepository() MandateKeyRepository
+	MandateRepository() MandateRepository
+	PaymentLinkRepository() PaymentLinkRepository
+	FundingSourceRepository() FundingSourceRepository
+	FundingTransferRepository() FundingTransferRepository
+	TrustedIssuerRepository() TrustedIssuerRepository
+	FederatedIdentityRepository() FederatedIdentityRepository
+	FederatedRevocationRepository() FederatedRevocationRepository
+	ReputationCredentialRepository() ReputationCredentialRepository
+	CounterpartyBankAccountRepository() CounterpartyBankAccountRepository
+	RefundRepository() RefundRepository
+	WatchlistEntryRepository() WatchlistEntryRepository
+	ComplianceScreeningRepository() ComplianceScreeningRepository
+	AMLRuleRepository() AMLRuleRepository
+	DescriptionTemplateRepository() DescriptionTemplateRepository
+	DeadLetterEventRepository() DeadLetterEventRepository
+	ReviewCaseRepository() ReviewCaseRepository
+	ApprovalRepository() ApprovalRepository
+	WorkflowTransitionRepository() WorkflowTransitionRepository
+	HoldRepository() HoldRepository
+	AutoDeclineRuleRepository() AutoDeclineRuleRepository
+	BalanceSnapshotRepository() BalanceSnapshotRepository
+	FeeScheduleRepository() FeeScheduleRepository
+	FeeChargeRepository() FeeChargeRepository
+	FeeExperimentRepository() FeeExperimentRepository
+	AuditAnchorRepository() AuditAnchorRepository
+	MigrationCheckpointRepository() MigrationCheckpointRepository
+	AdapterCallLogRepository() AdapterCallLogRepository
+	PostingRuleRepository() PostingRuleRepository
+	// WithContext returns the repository running its operations in ctx, so
+	// they are traced under the span of ctx
+	WithContext(ctx context.Context) Repository
+	// Transaction runs fn with a repository whose operations share one
+	// database transaction, committed if fn returns nil and rolled back
+	// otherwise
+	Transaction(fn func(tx Repository) error) error
+	StatementTokenRepository() StatementTokenRepository
+	PaymentCallbackRepository() PaymentCallbackRepository
+	PartySpendingLimitRepository() PartySpendingLimitRepository
+	RiskPolicyRepository() RiskPolicyRepository
+	AgentRiskProfileRepository() AgentRiskProfileRepository
+	CounterpartyRepository() CounterpartyRepository
+	WorkflowTemplateRepository() WorkflowTemplateRepository
+	PaymentScheduleRepository() PaymentScheduleRepository
+	PaymentBatchRepository() PaymentBatchRepository
+	BudgetRepository() BudgetRepository
+	KYCSubmissionRepository() KYCSubmissionRepository
+	DIDChallengeRepository() DIDChallengeRepository
+	UserRepository() UserRepository
+	RoleRepository() RoleRepository
+	ApproverGroupRepository() ApproverGroupRepository
+	QuoteRepository() QuoteRepository
+	PaymentRailRepository() PaymentRailRepository
+	ChainTransferRepository() ChainTransferRepository
 	HealthCheck() error
 	Migrate() error
 }
@@ -28,6 +92,9 @@ type PartyRepository interface {
 	Create(party *Party) error
 	GetByID(id string) (*Party, error)
 	List() ([]*Party, error)
+	// TenantOf returns the tenant a party belongs to: its organization, or
+	// the party itself
+	TenantOf(partyID string) (string, error)
 	Update(party *Party) error
 	Delete(id string) error
 }
@@ -38,6 +105,16 @@ type AgentRepository interface {
 	GetByID(id string) (*Agent, error)
 	List() ([]*Agent, error)
 	ListByOwnerPartyID(ownerPartyID string) ([]*Agent, error)
+	// ListByTenantID lists the agents of a tenant's parties
+	ListByTenantID(tenantID string) ([]*Agent, error)
+	// ListPage returns a page of the agents matching a filter and how many match
+	ListPage(filter AgentFilter, params common.ListParams) ([]*Agent, int, error)
+	ListByIDs(ids []string) ([]*Agent, error)
+	// ListByIDsInTenant lists those of the agents that belong to a tenant's parties
+	ListByIDsInTenant(ids []string, tenantID string) ([]*Agent, error)
+	// UpdateStatus moves an agent from its status to another, reporting false
+	// if its stored status changed since it was read
+	UpdateStatus(agent *Agent, status, reason string) (bool, error)
 	Update(agent *Agent) error
 	Delete(id string) error
 }
@@ -47,8 +124,27 @@ type ConsentRepository interface {
 	Create(consent *Consent) error
 	GetByID(id string) (*Consent, error)
 	List() ([]*Consent, error)
+	// ListPage returns a page of the consents matching a filter and how many match
+	ListPage(filter ConsentFilter, params common.ListParams) ([]*Consent, int, error)
 	ListByAgentID(agentID string) ([]*Consent, error)
+	ListByAgentIDs(agentIDs []string) ([]*Consent, error)
 	ListByOwnerPartyID(ownerPartyID string) ([]*Consent, error)
+	// GetAsOf and ListByAgentIDAsOf return consents as they were at a past time
+	GetAsOf(id string, asOf time.Time) (*Consent, error)
+	ListByAgentIDAsOf(agentID string, asOf time.Time) ([]*Consent, error)
+	// Supersede creates a successor of a consent and points the predecessor
+	// to it, reporting false if the predecessor was revoked or superseded
+	// meanwhile
+	Supersede(predecessor, successor *Consent) (bool, error)
+	// ListVersions lists every version of the consent a version belongs to,
+	// the first version first, including deleted ones
+	ListVersions(id string) ([]*Consent, error)
+	// ListExpiring lists current consents expiring before a time whose agent
+	// has not been warned yet
+	ListExpiring(before time.Time, limit int) ([]*Consent, error)
+	// MarkExpiringNotified records the expiry warning, reporting false if
+	// another instance recorded it first
+	MarkExpiringNotified(id string, at time.Time) (bool, error)
 	Update(consent *Consent) error
 	Delete(id string) error
 }
@@ -58,7 +154,11 @@ type RiskDecisionRepository interface {
 	Create(riskDecision *RiskDecision) error
 	GetByID(id string) (*RiskDecision, error)
 	List() ([]*RiskDecision, error)
+	// ListPage returns a page of the decisions matching a filter and how many match
+	ListPage(filter RiskDecisionFilter, params common.ListParams) ([]*RiskDecision, int, error)
 	ListByAgentID(agentID string) ([]*RiskDecision, error)
+	ListByAgentIDs(agentIDs []string) ([]*RiskDecision, error)
+	ListByAgentIDSince(agentID string, since time.Time) ([]*RiskDecision, error)
 	Update(riskDecision *RiskDecision) error
 	Delete(id string) error
 }
@@ -68,8 +168,23 @@ type PaymentWorkflowRepository interface {
 	Create(workflow *PaymentWorkflow) error
 	GetByID(id string) (*PaymentWorkflow, error)
 	List() ([]*PaymentWorkflow, error)
+	// ListPage returns a page of the workflows matching a filter and how many match
+	ListPage(filter PaymentWorkflowFilter, params common.ListParams) ([]*PaymentWorkflow, int, error)
 	ListByAgentID(agentID string) ([]*PaymentWorkflow, error)
+	ListByAgentIDs(agentIDs []string) ([]*PaymentWorkflow, error)
+	ListByAgentIDSince(agentID string, since time.Time) ([]*PaymentWorkflow, error)
+	ListByAgentIDsSince(agentIDs []string, since time.Time) ([]*PaymentWorkflow, error)
 	ListByStatus(status string) ([]*PaymentWorkflow, error)
+	// CountByAgentIDByStatus counts all of an agent's workflows, by status
+	CountByAgentIDByStatus(agentID string) (map[string]int64, error)
+	ListByConsentID(consentID string) ([]*PaymentWorkflow, error)
+	ListByRiskDecisionID(riskDecisionID string) ([]*PaymentWorkflow, error)
+	ListByComplianceScreeningID(screeningID string) ([]*PaymentWorkflow, error)
+	ListStale(statuses []string, before time.Time) ([]*PaymentWorkflow, error)
+	Touch(id string) error
+	Claim(id string, seenUpdatedAt time.Time) (bool, error)
+	UpdateStatus(id, from, to string) (bool, error)
+	LinkConsent(id, consentID string, consentVersion int) error
 	Update(workflow *PaymentWorkflow) error
 	Delete(id string) error
 }
@@ -78,9 +193,16 @@ type PaymentWorkflowRepository interface {
 type PaymentExecutionRepository interface {
 	Create(execution *PaymentExecution) error
 	GetByID(id string) (*PaymentExecution, error)
+	GetByReferenceID(referenceID string) (*PaymentExecution, error)
+	GetByIdempotencyKey(key string) (*PaymentExecution, error)
 	List() ([]*PaymentExecution, error)
+	// ListPage returns a page of the executions matching a filter and how many match
+	ListPage(filter PaymentExecutionFilter, params common.ListParams) ([]*PaymentExecution, int, error)
 	ListByAgentID(agentID string) ([]*PaymentExecution, error)
+	ListByAgentIDs(agentIDs []string) ([]*PaymentExecution, error)
+	ListByAgentIDSince(agentID string, since time.Time) ([]*PaymentExecution, error)
 	ListByStatus(status string) ([]*PaymentExecution, error)
+	ListSince(since time.Time) ([]*PaymentExecution, error)
 	Update(execution *PaymentExecution) error
 	Delete(id string) error
 }
@@ -90,9 +212,16 @@ type AccountRepository interface {
 	Create(account *Account) error
 	GetByID(id string) (*Account, error)
 	List() ([]*Account, error)
+	// ListPage returns a page of the accounts matching a filter and how many match
+	ListPage(filter AccountFilter, params common.ListParams) ([]*Account, int, error)
 	ListByAgentID(agentID string) ([]*Account, error)
+	ListByIDs(ids []string) ([]*Account, error)
 	ListByType(accountType string) ([]*Account, error)
 	ListByAgentIDAndType(agentID, accountType string) ([]*Account, error)
+	// GetAsOf returns an account, including its balance, as it was at a past time
+	GetAsOf(id string, asOf time.Time) (*Account, error)
+	// DerivedBalance sums the account's postings up to asOf, or all of them when asOf is nil
+	DerivedBalance(id string, asOf *time.Time) (float64, error)
 	Update(account *Account) error
 	Delete(id string) error
 }
@@ -102,7 +231,27 @@ type TransactionRepository interface {
 	Create(transaction *Transaction) error
 	GetByID(id string) (*Transaction, error)
 	List() ([]*Transaction, error)
+	// ListPage returns a page of the transactions matching a filter and how many match
+	ListPage(filter TransactionFilter, params common.ListParams) ([]*Transaction, int, error)
 	ListByAgentID(agentID string) ([]*Transaction, error)
+	ListByReferenceID(referenceID string) ([]*Transaction, error)
+	ListByReferenceIDs(referenceIDs []string) ([]*Transaction, error)
+	// ListPostedBetween returns an agent's posted transactions in a book
+	// created in [from, to), with their postings, oldest first
+	ListPostedBetween(agentID, book string, from, to time.Time) ([]*Transaction, error)
+	// Post creates a transaction with its postings and applies them to the
+	// account balances atomically. It fails with an *InsufficientFundsError,
+	// and writes nothing, if a posting would breach an overdraft policy. The
+	// transaction is fanned out into other books by the posting rules of its
+	// accounts; it fails with ErrMixedBooks if its accounts are in different
+	// books, or an *UnmappedPostingError if the rules map only some of them.
+	Post(transaction *Transaction, postings []*Posting) error
+	// Chain links a transaction whose postings were created separately into
+	// its agent's hash chain. Post chains the transactions it creates.
+	Chain(id string) error
+	// ListChain returns an agent's chained transactions, with their postings,
+	// in chain order
+	ListChain(agentID string) ([]*Transaction, error)
 	Update(transaction *Transaction) error
 	Delete(id string) error
 }
@@ -114,15 +263,39 @@ type PostingRepository interface {
 	List() ([]*Posting, error)
 	ListByTransactionID(transactionID string) ([]*Posting, error)
 	ListByAccountID(accountID string) ([]*Posting, error)
+	// ListPageByAccountID returns a page of an account's postings, sorted by
+	// creation time, and how many match
+	ListPageByAccountID(accountID string, params common.ListParams) ([]*Posting, int, error)
+	// BalanceThrough sums an account's postings up to and including the given
+	// one, in order of creation time then ID
+	BalanceThrough(posting *Posting) (float64, error)
+	// Totals sums an account's postings created in [from, to); nil bounds are open
+	Totals(accountID string, from, to *time.Time) (*PostingTotals, error)
 	Update(posting *Posting) error
 	Delete(id string) error
 }
 
+// PostingTotals sums the postings of an account over a range
+type PostingTotals struct {
+	Count   int64
+	Debits  float64 // Sum of the positive amounts
+	Credits float64 // Sum of the negative amounts, as a positive number
+}
+
 // OutboxEventRepository defines operations for OutboxEvent entity
 type OutboxEventRepository interface {
 	Create(outboxEvent *OutboxEvent) error
 	GetByID(id string) (*OutboxEvent, error)
 	ListPending(limit int) ([]*OutboxEvent, error)
+	CountByStatus(status string) (int64, error)
+	ListFailed(limit int) ([]*OutboxEvent, error)
+	ListRetryable(maxAttempts, limit int) ([]*OutboxEvent, error)
+	CountExhausted(maxAttempts int) (int64, error)
+	LastSequence(partitionKey string) (int64, error)
+	HasUnpublishedBefore(partitionKey string, sequence int64) (bool, error)
+	ListPrunable(publishedBefore time.Time, limit int) ([]*OutboxEvent, error)
+	DeleteByIDs(ids []string) (int64, error)
+	TableSize() (int64, error)
 	Update(outboxEvent *OutboxEvent) error
 	Delete(id string) error
 }
@@ -132,43 +305,694 @@ type AuditEntryRepository interface {
 	Create(auditEntry *AuditEntry) error
 	GetByID(id string) (*AuditEntry, error)
 	Query(filters AuditQueryFilters) ([]*AuditEntry, error)
+	// CountMatching counts the entries matching filters, ignoring their limit and offset
+	CountMatching(filters AuditQueryFilters) (int64, error)
 	Count() (int64, error)
 	CountMissingTimestamps() (int64, error)
 	CountDuplicates() (int64, error)
 	Archive(beforeDate time.Time) error
+	// ListUnanchored returns up to limit entries created before a time that
+	// no anchor covers yet, oldest first
+	ListUnanchored(before time.Time, limit int) ([]*AuditEntry, error)
+}
+
+// APICredentialRepository defines operations for APICredential entity
+type APICredentialRepository interface {
+	Create(credential *APICredential) error
+	GetByID(id string) (*APICredential, error)
+	GetByKeyHash(keyHash string) (*APICredential, error)
+	ListByPartyID(partyID string) ([]*APICredential, error)
+	ListByAgentID(agentID string) ([]*APICredential, error)
+	Update(credential *APICredential) error
+	Delete(id string) error
+}
+
+// AgentCredentialRepository defines operations for AgentCredential entity
+type AgentCredentialRepository interface {
+	Create(credential *AgentCredential) error
+	GetByID(id string) (*AgentCredential, error)
+	ListByAgentID(agentID string) ([]*AgentCredential, error)
+	Update(credential *AgentCredential) error
+	Delete(id string) error
+}
+
+// KillSwitchEventRepository defines operations for KillSwitchEvent entity
+type KillSwitchEventRepository interface {
+	Create(event *KillSwitchEvent) error
+	ListByComponent(component string) ([]*KillSwitchEvent, error)
+	ListSince(since time.Time) ([]*KillSwitchEvent, error)
+	ListCurrent() ([]*KillSwitchEvent, error)
+}
+
+// MandateKeyRepository defines operations for MandateKey entity
+type MandateKeyRepository interface {
+	Create(key *MandateKey) error
+	GetByID(id string) (*MandateKey, error)
+	ListByAgentID(agentID string) ([]*MandateKey, error)
+	Update(key *MandateKey) error
+}
+
+// MandateRepository defines operations for Mandate entity
+type MandateRepository interface {
+	Create(mandate *Mandate) error
+	GetByID(id string) (*Mandate, error)
+	GetByWorkflowID(workflowID string) (*Mandate, error)
+	Update(mandate *Mandate) error
+}
+
+// PaymentLinkRepository defines operations for PaymentLink entity
+type PaymentLinkRepository interface {
+	Create(link *PaymentLink) error
+	GetByID(id string) (*PaymentLink, error)
+	GetByToken(token string) (*PaymentLink, error)
+	ListByWorkflowID(workflowID string) ([]*PaymentLink, error)
+	Update(link *PaymentLink) error
+}
+
+// FundingSourceRepository defines operations for FundingSource entity
+type FundingSourceRepository interface {
+	Create(source *FundingSource) error
+	GetByID(id string) (*FundingSource, error)
+	ListByPartyID(partyID string) ([]*FundingSource, error)
+	Update(source *FundingSource) error
+}
+
+// FundingTransferRepository defines operations for FundingTransfer entity
+type FundingTransferRepository interface {
+	Create(transfer *FundingTransfer) error
+	GetByID(id string) (*FundingTransfer, error)
+	ListByFundingSourceID(fundingSourceID string) ([]*FundingTransfer, error)
+	Update(transfer *FundingTransfer) error
+}
+
+// TrustedIssuerRepository defines operations for TrustedIssuer entity
+type TrustedIssuerRepository interface {
+	Create(issuer *TrustedIssuer) error
+	GetByID(id string) (*TrustedIssuer, error)
+	GetByIssuer(issuer string) (*TrustedIssuer, error)
+	List() ([]*TrustedIssuer, error)
+	// ListByTenantID lists the issuers hosted by a tenant's parties
+	ListByTenantID(tenantID string) ([]*TrustedIssuer, error)
+	Update(issuer *TrustedIssuer) error
+}
+
+// FederatedIdentityRepository defines operations for FederatedIdentity entity
+type FederatedIdentityRepository interface {
+	Create(identity *FederatedIdentity) error
+	GetByIssuerAndSubject(issuerID, subject string) (*FederatedIdentity, error)
+	GetByAgentID(agentID string) (*FederatedIdentity, error)
+	ListByIssuerID(issuerID string) ([]*FederatedIdentity, error)
+	Update(identity *FederatedIdentity) error
+}
+
+// FederatedRevocationRepository defines operations for FederatedRevocation entity
+type FederatedRevocationRepository interface {
+	// Create records a revocation; revoking an already revoked credential is a no-op
+	Create(revocation *FederatedRevocation) error
+	IsRevoked(issuerID, credentialID string) (bool, error)
+	ListByIssuerID(issuerID string) ([]*FederatedRevocation, error)
+}
+
+// ReputationCredentialRepository defines operations for ReputationCredential entity
+type ReputationCredentialRepository interface {
+	Create(credential *ReputationCredential) error
+	GetByID(id string) (*ReputationCredential, error)
+	ListByAgentID(agentID string) ([]*ReputationCredential, error)
+	ListRevoked() ([]*ReputationCredential, error)
+	Update(credential *ReputationCredential) error
+}
+
+// CounterpartyBankAccountRepository defines operations for CounterpartyBankAccount entity
+type CounterpartyBankAccountRepository interface {
+	Create(account *CounterpartyBankAccount) error
+	GetByID(id string) (*CounterpartyBankAccount, error)
+	// GetVerified returns the verified account of a party's counterparty
+	GetVerified(partyID, counterparty string) (*CounterpartyBankAccount, error)
+	ListByPartyID(partyID string) ([]*CounterpartyBankAccount, error)
+	Update(account *CounterpartyBankAccount) error
+}
+
+// CounterpartyRepository defines operations for Counterparty entity
+type CounterpartyRepository interface {
+	Create(counterparty *Counterparty) error
+	GetByID(id string) (*Counterparty, error)
+	GetByName(partyID, name string) (*Counterparty, error)
+	ListByPartyID(partyID string, filter CounterpartyFilter) ([]*Counterparty, error)
+	Update(counterparty *Counterparty) error
+	Delete(id string) error
+}
+
+// CounterpartyFilter narrows a party's counterparties; empty fields match all
+type CounterpartyFilter struct {
+	KYCStatus  string
+	ListStatus string
+}
+
+// WorkflowTemplateRepository defines operations for WorkflowTemplate entity
+type WorkflowTemplateRepository interface {
+	// Create stores a template as the party's next version
+	Create(template *WorkflowTemplate) error
+	GetByID(id string) (*WorkflowTemplate, error)
+	// GetActive returns the party's active template, or nil if none is
+	GetActive(partyID string) (*WorkflowTemplate, error)
+	ListByPartyID(partyID string) ([]*WorkflowTemplate, error)
+	// Activate makes a template the party's only active one
+	Activate(id string) (*WorkflowTemplate, error)
+	Deactivate(id string) (*WorkflowTemplate, error)
+}
+
+// PaymentScheduleRepository defines operations for PaymentSchedule entity
+type PaymentScheduleRepository interface {
+	Create(schedule *PaymentSchedule) error
+	GetByID(id string) (*PaymentSchedule, error)
+	// List lists schedules newest first, of one agent and status when set
+	List(agentID, status string) ([]*PaymentSchedule, error)
+	ListByAgentIDs(agentIDs []string, status string) ([]*PaymentSchedule, error)
+	// ListByTenantID lists schedules newest first, of the agents of a
+	// tenant's parties and of a status when set
+	ListByTenantID(tenantID, status string) ([]*PaymentSchedule, error)
+	// ListDue lists active schedules due by a time
+	ListDue(now time.Time, limit int) ([]*PaymentSchedule, error)
+	// Claim takes a due run by moving the schedule's next run on, or clearing
+	// it, returning false if another instance took it first
+	Claim(id string, seenNextRunAt time.Time, nextRunAt *time.Time) (bool, error)
+	Update(schedule *PaymentSchedule) error
+	CreateRun(run *PaymentScheduleRun) error
+	ListRuns(scheduleID string, limit int) ([]*PaymentScheduleRun, error)
+}
+
+// BudgetRepository defines operations for Budget entity
+type BudgetRepository interface {
+	Create(budget *Budget) error
+	GetByID(id string) (*Budget, error)
+	ListByAgentID(agentID string) ([]*Budget, error)
+	ListByAgentIDs(agentIDs []string) ([]*Budget, error)
+	// ListDue lists active budgets whose period has ended by a time
+	ListDue(now time.Time, limit int) ([]*Budget, error)
+	Update(budget *Budget) error
+	Delete(id string) error
+	// Rollover records the budget's closed periods and saves its new
+	// period, returning false if its period was rolled over since
+	// previousStart was read
+	Rollover(budget *Budget, previousStart time.Time, closed []*BudgetPeriod) (bool, error)
+	// ListPeriods lists the budget's closed periods, newest first
+	ListPeriods(budgetID string, limit int) ([]*BudgetPeriod, error)
+}
+
+// KYCSubmissionRepository defines operations for KYCSubmission entity
+type KYCSubmissionRepository interface {
+	// Submit stores a submission and gives its status to its party,
+	// returning false if the party's status changed since partyStatus was
+	// read
+	Submit(submission *KYCSubmission, partyStatus string) (bool, error)
+	GetByID(id string) (*KYCSubmission, error)
+	// ListByPartyID lists a party's submissions, newest first
+	ListByPartyID(partyID string) ([]*KYCSubmission, error)
+	// ListByStatus lists submissions of a status, oldest first
+	ListByStatus(status string, limit int) ([]*KYCSubmission, error)
+	// ListByTenantID lists the submissions of a tenant's parties of a
+	// status, oldest first
+	ListByTenantID(tenantID, status string, limit int) ([]*KYCSubmission, error)
+	// Decide records the decision on a pending submission and gives it to
+	// its party, returning false if the submission was decided meanwhile
+	Decide(submission *KYCSubmission) (bool, error)
+}
+
+// DIDChallengeRepository defines operations for DIDChallenge entity
+type DIDChallengeRepository interface {
+	Create(challenge *DIDChallenge) error
+	GetByID(id string) (*DIDChallenge, error)
+	// Answer records the outcome of a pending challenge, returning false if
+	// it was answered meanwhile
+	Answer(challenge *DIDChallenge) (bool, error)
+}
+
+// UserRepository defines operations for User entity and its roles
+type UserRepository interface {
+	Create(user *User) error
+	GetByID(id string) (*User, error)
+	GetByEmail(email string) (*User, error)
+	ListByPartyID(partyID string) ([]*User, error)
+	Update(user *User) error
+	// AssignRole gives a user a role, returning false if it already held it
+	AssignRole(assignment *UserRole) (bool, error)
+	// RevokeRole takes a role from a user, returning false if it did not
+	// hold it
+	RevokeRole(userID, roleID string) (bool, error)
+	// RoleNames returns the names of a user's roles
+	RoleNames(userID string) ([]string, error)
+}
+
+// RoleRepository defines operations for Role entity
+type RoleRepository interface {
+	// Seed stores roles that do not exist yet and updates the descriptions
+	// of those that do
+	Seed(roles []*Role) error
+	GetByName(name string) (*Role, error)
+	List() ([]*Role, error)
+}
+
+// ApproverGroupRepository defines operations for ApproverGroupMember entity
+type ApproverGroupRepository interface {
+	// AddMember adds a user to a group, returning false if it was a member
+	AddMember(member *ApproverGroupMember) (bool, error)
+	// RemoveMember removes a user from a group, returning false if it was
+	// not a member
+	RemoveMember(partyID, group, userID string) (bool, error)
+	ListMembers(partyID, group string) ([]*ApproverGroupMember, error)
+	// ListByUserID lists the groups a user is a member of
+	ListByUserID(userID string) ([]*ApproverGroupMember, error)
+	IsMember(partyID, group, userID string) (bool, error)
+}
+
+// QuoteRepository defines operations for Quote entity
+type QuoteRepository interface {
+	Create(quote *Quote) error
+	GetByID(id string) (*Quote, error)
+	// Use binds an unused, unexpired quote to the payment made under it,
+	// returning false if it was used or expired meanwhile
+	Use(id, workflowID string, now time.Time) (bool, error)
+}
+
+// PaymentRailRepository defines operations for the rail catalog
+type PaymentRailRepository interface {
+	// Seed adds the rails missing from the catalog, leaving stored rails as
+	// operators changed them
+	Seed(rails []*PaymentRail) error
+	Create(rail *PaymentRail) error
+	GetByRail(rail string) (*PaymentRail, error)
+	List() ([]*PaymentRail, error)
+	Update(rail *PaymentRail) error
+	Delete(rail string) error
+}
+
+// ChainTransferRepository defines operations for ChainTransfer entity
+type ChainTransferRepository interface {
+	Create(transfer *ChainTransfer) error
+	GetByExecutionID(executionID string) (*ChainTransfer, error)
+	Update(transfer *ChainTransfer) error
+}
+
+// PaymentBatchRepository defines operations for PaymentBatch entity
+type PaymentBatchRepository interface {
+	// AddItem adds an item to the open batch of a rail for a cutoff, opening
+	// one referenced by the prefix and a sequence number if there is none
+	AddItem(rail string, cutoffAt time.Time, referencePrefix string, item *PaymentBatchItem) (*PaymentBatch, error)
+	GetByID(id string) (*PaymentBatch, error)
+	// List lists batches newest first, of one rail and status when set
+	List(rail, status string, limit int) ([]*PaymentBatch, error)
+	// ListDue lists open batches whose cutoff has passed
+	ListDue(now time.Time) ([]*PaymentBatch, error)
+	// Transition moves a batch on from a status, saving its other fields,
+	// and returns false if it was no longer in that status
+	Transition(batch *PaymentBatch, from, to string) (bool, error)
+	// AddLedgerTransactions appends to the batch's ledger transaction IDs
+	AddLedgerTransactions(id string, transactionIDs []string) error
+	ListItems(batchID string) ([]*PaymentBatchItem, error)
+	GetItemByExecutionID(executionID string) (*PaymentBatchItem, error)
+	UpdateItem(item *PaymentBatchItem) error
+	// RemoveItem takes an item out of its batch while the batch is open or
+	// closed, returning false once it has been submitted
+	RemoveItem(item *PaymentBatchItem) (bool, error)
+	// FinishItem records an item's final status, completed or failed, in it
+	// and its batch's counts, returning false if it was already final
+	FinishItem(item *PaymentBatchItem) (bool, error)
+}
+
+// RefundRepository defines operations for Refund entity
+type RefundRepository interface {
+	Create(refund *Refund) error
+	GetByID(id string) (*Refund, error)
+	ListByExecutionID(executionID string) ([]*Refund, error)
+	// CountRefundedExecutions counts the agent's executions with a completed refund
+	CountRefundedExecutions(agentID string) (int64, error)
+	Update(refund *Refund) error
+}
+
+// WatchlistEntryRepository defines operations for WatchlistEntry entity
+type WatchlistEntryRepository interface {
+	Create(entry *WatchlistEntry) error
+	GetByID(id string) (*WatchlistEntry, error)
+	List(listType string) ([]*WatchlistEntry, error)
+	ListActive() ([]*WatchlistEntry, error)
+	Update(entry *WatchlistEntry) error
+}
+
+// ComplianceScreeningRepository defines operations for ComplianceScreening entity
+type ComplianceScreeningRepository interface {
+	Create(screening *ComplianceScreening) error
+	GetByID(id string) (*ComplianceScreening, error)
+	ListByStatus(status string) ([]*ComplianceScreening, error)
+	// ListByTenantID lists the screenings of a status of the agents of a
+	// tenant's parties, oldest first
+	ListByTenantID(tenantID, status string) ([]*ComplianceScreening, error)
+	ListByAgentID(agentID string) ([]*ComplianceScreening, error)
+	Update(screening *ComplianceScreening) error
+}
+
+// AMLRuleRepository defines operations for AMLRule entity
+type AMLRuleRepository interface {
+	Create(rule *AMLRule) error
+	GetByID(id string) (*AMLRule, error)
+	List() ([]*AMLRule, error)
+	ListEnabled() ([]*AMLRule, error)
+	Update(rule *AMLRule) error
+	Delete(id string) error
+}
+
+// DescriptionTemplateRepository defines operations for DescriptionTemplate entity
+type DescriptionTemplateRepository interface {
+	Create(template *DescriptionTemplate) error
+	GetByID(id string) (*DescriptionTemplate, error)
+	ListByPartyID(partyID string) ([]*DescriptionTemplate, error)
+	GetActive(partyID, rail string) (*DescriptionTemplate, error)
+	Update(template *DescriptionTemplate) error
+	Delete(id string) error
+}
+
+// DeadLetterEventRepository defines operations for DeadLetterEvent entity
+type DeadLetterEventRepository interface {
+	Create(event *DeadLetterEvent) error
+	GetByID(id string) (*DeadLetterEvent, error)
+	// List lists dead-lettered messages, newest first; empty status and topic match all
+	List(status, topic string, limit int) ([]*DeadLetterEvent, error)
+	ListByIDs(ids []string) ([]*DeadLetterEvent, error)
+	// ListPending lists messages waiting to be replayed, oldest first; empty topic matches all
+	ListPending(topic string, limit int) ([]*DeadLetterEvent, error)
+	CountByTopicSince(since time.Time) (map[string]int64, error)
+	Update(event *DeadLetterEvent) error
+}
+
+// ReviewCaseRepository defines operations for ReviewCase entity
+type ReviewCaseRepository interface {
+	Create(reviewCase *ReviewCase) error
+	GetByID(id string) (*ReviewCase, error)
+	ListByStatus(status string) ([]*ReviewCase, error)
+	// ListByTenantID lists the cases of a status of the agents of a tenant's
+	// parties, oldest first
+	ListByTenantID(tenantID, status string) ([]*ReviewCase, error)
+	ListByAgentID(agentID string) ([]*ReviewCase, error)
+	Update(reviewCase *ReviewCase) error
+}
+
+// ApprovalRepository defines operations for Approval entity
+type ApprovalRepository interface {
+	Create(approval *Approval) error
+	GetByID(id string) (*Approval, error)
+	GetByWorkflowID(workflowID string) (*Approval, error)
+	ListByOwnerPartyID(ownerPartyID, status string) ([]*Approval, error)
+	ListByConsentID(consentID, status string) ([]*Approval, error)
+	Update(approval *Approval) error
+}
+
+// WorkflowTransitionRepository defines operations for WorkflowTransition entity
+type WorkflowTransitionRepository interface {
+	Create(transition *WorkflowTransition) error
+	ListByWorkflowID(workflowID string) ([]*WorkflowTransition, error)
+}
+
+// HoldRepository defines operations for Hold entity
+type HoldRepository interface {
+	Create(hold *Hold) error
+	GetByID(id string) (*Hold, error)
+	ListByAccountID(accountID, status string) ([]*Hold, error)
+	ListByReferenceID(referenceID string) ([]*Hold, error)
+	ListExpired(now time.Time) ([]*Hold, error)
+	ActiveTotal(accountID string) (float64, error)
+	Resolve(hold *Hold, status string) (bool, error)
+	Update(hold *Hold) error
+}
+
+// AutoDeclineRuleRepository defines operations for AutoDeclineRule entity
+type AutoDeclineRuleRepository interface {
+	Create(rule *AutoDeclineRule) error
+	GetByID(id string) (*AutoDeclineRule, error)
+	ListByOwnerPartyID(ownerPartyID string) ([]*AutoDeclineRule, error)
+	// ListEnabledForAgent returns the enabled rules of the party that apply to the agent
+	ListEnabledForAgent(ownerPartyID, agentID string) ([]*AutoDeclineRule, error)
+	Update(rule *AutoDeclineRule) error
+	Delete(id string) error
+}
+
+// BalanceSnapshotRepository defines operations for BalanceSnapshot entity
+type BalanceSnapshotRepository interface {
+	Create(snapshot *BalanceSnapshot) error
+	// Latest returns the account's last snapshot at or before a time, or nil if there is none
+	Latest(accountID string, asOf time.Time) (*BalanceSnapshot, error)
+}
+
+// FeeScheduleRepository defines operations for FeeSchedule entity
+type FeeScheduleRepository interface {
+	Create(schedule *FeeSchedule) error
+	GetByID(id string) (*FeeSchedule, error)
+	List() ([]*FeeSchedule, error)
+	ListByPartyID(partyID string) ([]*FeeSchedule, error)
+	Update(schedule *FeeSchedule) error
+	Delete(id string) error
+}
+
+// FeeChargeRepository defines operations for FeeCharge entity
+type FeeChargeRepository interface {
+	Create(charge *FeeCharge) error
+	GetByWorkflowID(workflowID string) (*FeeCharge, error)
+	Update(charge *FeeCharge) error
+	// Revenue totals the charged fees created in [since, until) by tenant and
+	// rail, for one tenant when partyID is set
+	Revenue(partyID string, since, until time.Time) ([]*FeeRevenue, error)
+}
+
+// FeeExperimentRepository defines operations for FeeExperiment entity and
+// its variants and assignments
+type FeeExperimentRepository interface {
+	Create(experiment *FeeExperiment) error
+	GetByID(id string) (*FeeExperiment, error)
+	List() ([]*FeeExperiment, error)
+	ListActive() ([]*FeeExperiment, error)
+	Update(experiment *FeeExperiment) error
+	GetVariant(id string) (*FeeExperimentVariant, error)
+	CountQuote(variantID string) error
+	GetAssignment(experimentID, subjectID string) (*FeeExperimentAssignment, error)
+	Assign(assignment *FeeExperimentAssignment) error
+	CountAssignments(experimentID string) (map[string]int, error)
+	Results(experimentID string) ([]*FeeVariantResult, error)
+}
+
+// AuditAnchorRepository defines operations for AuditAnchor entity
+type AuditAnchorRepository interface {
+	// Create stores an anchor and points each of its entries, in leaf order,
+	// to it. It fails, and writes nothing, if an entry is already anchored.
+	Create(anchor *AuditAnchor, entries []*AuditEntry) error
+	GetByID(id string) (*AuditAnchor, error)
+	List(limit int) ([]*AuditAnchor, error)
+	// ListEntries returns the entries an anchor covers in leaf order
+	ListEntries(anchorID string) ([]*AuditEntry, error)
+}
+
+// MigrationCheckpointRepository defines operations for MigrationCheckpoint entity
+type MigrationCheckpointRepository interface {
+	GetByName(name string) (*MigrationCheckpoint, error)
+	List() ([]*MigrationCheckpoint, error)
+	Save(checkpoint *MigrationCheckpoint) error
+}
+
+// AdapterCallLogRepository defines operations for AdapterCallLog entity
+type AdapterCallLogRepository interface {
+	Create(call *AdapterCallLog) error
+	ListByExecutionID(executionID string) ([]*AdapterCallLog, error)
+	// StatsSince totals the calls made to each rail's processor since a time
+	StatsSince(since time.Time) ([]*AdapterCallStats, error)
+}
+
+// PostingRuleRepository defines operations for PostingRule entity
+type PostingRuleRepository interface {
+	Create(rule *PostingRule) error
+	GetByID(id string) (*PostingRule, error)
+	ListByAgentID(agentID string) ([]*PostingRule, error)
+	Delete(id string) error
+}
+
+// StatementTokenRepository defines operations for StatementToken entity
+type StatementTokenRepository interface {
+	Create(token *StatementToken) error
+	GetByID(id string) (*StatementToken, error)
+	GetByTokenHash(tokenHash string) (*StatementToken, error)
+	ListByPartyID(partyID, agentID string) ([]*StatementToken, error)
+	Update(token *StatementToken) error
+}
+
+// PaymentCallbackRepository defines operations for PaymentCallback entity
+type PaymentCallbackRepository interface {
+	Create(callback *PaymentCallback) error
+	GetByID(id string) (*PaymentCallback, error)
+	ListByWorkflowID(workflowID string) ([]*PaymentCallback, error)
+	ListDue(now time.Time, limit int) ([]*PaymentCallback, error)
+	Update(callback *PaymentCallback) error
+}
+
+// PartySpendingLimitRepository defines operations for PartySpendingLimit entity
+type PartySpendingLimitRepository interface {
+	Create(limit *PartySpendingLimit) error
+	GetByPartyID(partyID, period string) (*PartySpendingLimit, error)
+	ListByPartyID(partyID string) ([]*PartySpendingLimit, error)
+	Update(limit *PartySpendingLimit) error
+	Delete(id string) error
+}
+
+// RiskPolicyRepository defines operations for RiskPolicy entity
+type RiskPolicyRepository interface {
+	// Create stores a policy as the next version
+	Create(policy *RiskPolicy) error
+	GetByVersion(version int) (*RiskPolicy, error)
+	// GetActive returns the active policy, or nil if none is
+	GetActive() (*RiskPolicy, error)
+	List() ([]*RiskPolicy, error)
+	// Activate makes a version the only active one
+	Activate(version int) (*RiskPolicy, error)
+}
+
+// AgentRiskProfileRepository defines operations for AgentRiskProfile entity
+type AgentRiskProfileRepository interface {
+	Create(profile *AgentRiskProfile) error
+	GetByAgentID(agentID string) (*AgentRiskProfile, error)
+	// List lists the profiles, of one trust tier if it is set
+	List(tier string) ([]*AgentRiskProfile, error)
+	// ListByTenantID lists the profiles of the agents of a tenant's parties,
+	// of one trust tier if it is set
+	ListByTenantID(tenantID, tier string) ([]*AgentRiskProfile, error)
+	Update(profile *AgentRiskProfile) error
 }
 
 // repository implements Repository interface
 type repository struct {
-	db                   *gorm.DB
-	partyRepo            PartyRepository
-	agentRepo            AgentRepository
-	consentRepo          ConsentRepository
-	riskDecisionRepo     RiskDecisionRepository
-	paymentWorkflowRepo  PaymentWorkflowRepository
-	paymentExecutionRepo PaymentExecutionRepository
-	accountRepo          AccountRepository
-	transactionRepo      TransactionRepository
-	postingRepo          PostingRepository
-	outboxEventRepo      OutboxEventRepository
-	auditEntryRepo       AuditEntryRepository
+	db                          *gorm.DB
+	partyRepo                   PartyRepository
+	agentRepo                   AgentRepository
+	consentRepo                 ConsentRepository
+	riskDecisionRepo            RiskDecisionRepository
+	paymentWorkflowRepo         PaymentWorkflowRepository
+	paymentExecutionRepo        PaymentExecutionRepository
+	accountRepo                 AccountRepository
+	transactionRepo             TransactionRepository
+	postingRepo                 PostingRepository
+	outboxEventRepo             OutboxEventRepository
+	auditEntryRepo              AuditEntryRepository
+	apiCredentialRepo           APICredentialRepository
+	agentCredentialRepo         AgentCredentialRepository
+	killSwitchEventRepo         KillSwitchEventRepository
+	mandateKeyRepo              MandateKeyRepository
+	mandateRepo                 MandateRepository
+	paymentLinkRepo             PaymentLinkRepository
+	fundingSourceRepo           FundingSourceRepository
+	fundingTransferRepo         FundingTransferRepository
+	trustedIssuerRepo           TrustedIssuerRepository
+	federatedIdentityRepo       FederatedIdentityRepository
+	federatedRevocationRepo     FederatedRevocationRepository
+	reputationCredentialRepo    ReputationCredentialRepository
+	counterpartyBankAccountRepo CounterpartyBankAccountRepository
+	refundRepo                  RefundRepository
+	watchlistEntryRepo          WatchlistEntryRepository
+	complianceScreeningRepo     ComplianceScreeningRepository
+	amlRuleRepo                 AMLRuleRepository
+	descriptionTemplateRepo     DescriptionTemplateRepository
+	deadLetterEventRepo         DeadLetterEventRepository
+	reviewCaseRepo              ReviewCaseRepository
+	approvalRepo                ApprovalRepository
+	workflowTransitionRepo      WorkflowTransitionRepository
+	holdRepo                    HoldRepository
+	autoDeclineRuleRepo         AutoDeclineRuleRepository
+	balanceSnapshotRepo         BalanceSnapshotRepository
+	feeScheduleRepo             FeeScheduleRepository
+	feeChargeRepo               FeeChargeRepository
+	feeExperimentRepo           FeeExperimentRepository
+	auditAnchorRepo             AuditAnchorRepository
+	migrationCheckpointRepo     MigrationCheckpointRepository
+	adapterCallLogRepo          AdapterCallLogRepository
+	postingRuleRepo             PostingRuleRepository
+	statementTokenRepo          StatementTokenRepository
+	paymentCallbackRepo         PaymentCallbackRepository
+	partySpendingLimitRepo      PartySpendingLimitRepository
+	riskPolicyRepo              RiskPolicyRepository
+	agentRiskProfileRepo        AgentRiskProfileRepository
+	counterpartyRepo            CounterpartyRepository
+	workflowTemplateRepo        WorkflowTemplateRepository
+	paymentScheduleRepo         PaymentScheduleRepository
+	paymentBatchRepo            PaymentBatchRepository
+	budgetRepo                  BudgetRepository
+	kycSubmissionRepo           KYCSubmissionRepository
+	didChallengeRepo            DIDChallengeRepository
+	userRepo                    UserRepository
+	roleRepo                    RoleRepository
+	approverGroupRepo           ApproverGroupRepository
+	quoteRepo                   QuoteRepository
+	paymentRailRepo             PaymentRailRepository
+	chainTransferRepo           ChainTransferRepository
 }
 
 // NewRepository creates a new repository instance
 func NewRepository(db *gorm.DB) Repository {
 	return &repository{
-		db:                   db,
-		partyRepo:            &partyRepository{db: db},
-		agentRepo:            &agentRepository{db: db},
-		consentRepo:          &consentRepository{db: db},
-		riskDecisionRepo:     &riskDecisionRepository{db: db},
-		paymentWorkflowRepo:  &paymentWorkflowRepository{db: db},
-		paymentExecutionRepo: &paymentExecutionRepository{db: db},
-		accountRepo:          &accountRepository{db: db},
-		transactionRepo:      &transactionRepository{db: db},
-		postingRepo:          &postingRepository{db: db},
-		outboxEventRepo:      &outboxEventRepository{db: db},
-		auditEntryRepo:       &auditEntryRepository{db: db},
+		db:                          db,
+		partyRepo:                   &partyRepository{db: db},
+		agentRepo:                   &agentRepository{db: db},
+		consentRepo:                 &consentRepository{db: db},
+		riskDecisionRepo:            &riskDecisionRepository{db: db},
+		paymentWorkflowRepo:         &paymentWorkflowRepository{db: db},
+		paymentExecutionRepo:        &paymentExecutionRepository{db: db},
+		accountRepo:                 &accountRepository{db: db},
+		transactionRepo:             &transactionRepository{db: db},
+		postingRepo:                 &postingRepository{db: db},
+		outboxEventRepo:             &outboxEventRepository{db: db},
+		auditEntryRepo:              &auditEntryRepository{db: db},
+		apiCredentialRepo:           &apiCredentialRepository{db: db},
+		agentCredentialRepo:         &agentCredentialRepository{db: db},
+		killSwitchEventRepo:         &killSwitchEventRepository{db: db},
+		mandateKeyRepo:              &mandateKeyRepository{db: db},
+		mandateRepo:                 &mandateRepository{db: db},
+		paymentLinkRepo:             &paymentLinkRepository{db: db},
+		fundingSourceRepo:           &fundingSourceRepository{db: db},
+		fundingTransferRepo:         &fundingTransferRepository{db: db},
+		trustedIssuerRepo:           &trustedIssuerRepository{db: db},
+		federatedIdentityRepo:       &federatedIdentityRepository{db: db},
+		federatedRevocationRepo:     &federatedRevocationRepository{db: db},
+		reputationCredentialRepo:    &reputationCredentialRepository{db: db},
+		counterpartyBankAccountRepo: &counterpartyBankAccountRepository{db: db},
+		refundRepo:                  &refundRepository{db: db},
+		watchlistEntryRepo:          &watchlistEntryRepository{db: db},
+		complianceScreeningRepo:     &complianceScreeningRepository{db: db},
+		amlRuleRepo:                 &amlRuleRepository{db: db},
+		descriptionTemplateRepo:     &descriptionTemplateRepository{db: db},
+		deadLetterEventRepo:         &deadLetterEventRepository{db: db},
+		reviewCaseRepo:              &reviewCaseRepository{db: db},
+		approvalRepo:                &approvalRepository{db: db},
+		workflowTransitionRepo:      &workflowTransitionRepository{db: db},
+		holdRepo:                    &holdRepository{db: db},
+		autoDeclineRuleRepo:         &autoDeclineRuleRepository{db: db},
+		balanceSnapshotRepo:         &balanceSnapshotRepository{db: db},
+		feeScheduleRepo:             &feeScheduleRepository{db: db},
+		feeChargeRepo:               &feeChargeRepository{db: db},
+		feeExperimentRepo:           &feeExperimentRepository{db: db},
+		auditAnchorRepo:             &auditAnchorRepository{db: db},
+		migrationCheckpointRepo:     &migrationCheckpointRepository{db: db},
+		adapterCallLogRepo:          &adapterCallLogRepository{db: db},
+		postingRuleRepo:             &postingRuleRepository{db: db},
+		statementTokenRepo:          &statementTokenRepository{db: db},
+		paymentCallbackRepo:         &paymentCallbackRepository{db: db},
+		partySpendingLimitRepo:      &partySpendingLimitRepository{db: db},
+		riskPolicyRepo:              &riskPolicyRepository{db: db},
+		agentRiskProfileRepo:        &agentRiskProfileRepository{db: db},
+		counterpartyRepo:            &counterpartyRepository{db: db},
+		workflowTemplateRepo:        &workflowTemplateRepository{db: db},
+		paymentScheduleRepo:         &paymentScheduleRepository{db: db},
+		paymentBatchRepo:            &paymentBatchRepository{db: db},
+		budgetRepo:                  &budgetRepository{db: db},
+		kycSubmissionRepo:           &kycSubmissionRepository{db: db},
+		didChallengeRepo:            &didChallengeRepository{db: db},
+		userRepo:                    &userRepository{db: db},
+		roleRepo:                    &roleRepository{db: db},
+		approverGroupRepo:           &approverGroupRepository{db: db},
+		quoteRepo:                   &quoteRepository{db: db},
+		paymentRailRepo:             &paymentRailRepository{db: db},
+		chainTransferRepo:           &chainTransferRepository{db: db},
 	}
 }
 
@@ -216,6 +1040,216 @@ func (r *repository) AuditEntryRepository() AuditEntryRepository {
 	return r.auditEntryRepo
 }
 
+func (r *repository) APICredentialRepository() APICredentialRepository {
+	return r.apiCredentialRepo
+}
+
+func (r *repository) AgentCredentialRepository() AgentCredentialRepository {
+	return r.agentCredentialRepo
+}
+
+func (r *repository) KillSwitchEventRepository() KillSwitchEventRepository {
+	return r.killSwitchEventRepo
+}
+
+func (r *repository) MandateKeyRepository() MandateKeyRepository {
+	return r.mandateKeyRepo
+}
+
+func (r *repository) MandateRepository() MandateRepository {
+	return r.mandateRepo
+}
+
+func (r *repository) PaymentLinkRepository() PaymentLinkRepository {
+	return r.paymentLinkRepo
+}
+
+func (r *repository) FundingSourceRepository() FundingSourceRepository {
+	return r.fundingSourceRepo
+}
+
+func (r *repository) FundingTransferRepository() FundingTransferRepository {
+	return r.fundingTransferRepo
+}
+
+func (r *repository) TrustedIssuerRepository() TrustedIssuerRepository {
+	return r.trustedIssuerRepo
+}
+
+func (r *repository) FederatedIdentityRepository() FederatedIdentityRepository {
+	return r.federatedIdentityRepo
+}
+
+func (r *repository) FederatedRevocationRepository() FederatedRevocationRepository {
+	return r.federatedRevocationRepo
+}
+
+func (r *repository) ReputationCredentialRepository() ReputationCredentialRepository {
+	return r.reputationCredentialRepo
+}
+
+func (r *repository) CounterpartyBankAccountRepository() CounterpartyBankAccountRepository {
+	return r.counterpartyBankAccountRepo
+}
+
+func (r *repository) RefundRepository() RefundRepository {
+	return r.refundRepo
+}
+
+func (r *repository) WatchlistEntryRepository() WatchlistEntryRepository {
+	return r.watchlistEntryRepo
+}
+
+func (r *repository) ComplianceScreeningRepository() ComplianceScreeningRepository {
+	return r.complianceScreeningRepo
+}
+
+func (r *repository) AMLRuleRepository() AMLRuleRepository {
+	return r.amlRuleRepo
+}
+
+func (r *repository) DescriptionTemplateRepository() DescriptionTemplateRepository {
+	return r.descriptionTemplateRepo
+}
+
+func (r *repository) DeadLetterEventRepository() DeadLetterEventRepository {
+	return r.deadLetterEventRepo
+}
+
+func (r *repository) ReviewCaseRepository() ReviewCaseRepository {
+	return r.reviewCaseRepo
+}
+
+func (r *repository) ApprovalRepository() ApprovalRepository {
+	return r.approvalRepo
+}
+
+func (r *repository) WorkflowTransitionRepository() WorkflowTransitionRepository {
+	return r.workflowTransitionRepo
+}
+
+func (r *repository) HoldRepository() HoldRepository {
+	return r.holdRepo
+}
+
+func (r *repository) AutoDeclineRuleRepository() AutoDeclineRuleRepository {
+	return r.autoDeclineRuleRepo
+}
+
+func (r *repository) BalanceSnapshotRepository() BalanceSnapshotRepository {
+	return r.balanceSnapshotRepo
+}
+
+func (r *repository) FeeScheduleRepository() FeeScheduleRepository {
+	return r.feeScheduleRepo
+}
+
+func (r *repository) FeeChargeRepository() FeeChargeRepository {
+	return r.feeChargeRepo
+}
+
+func (r *repository) FeeExperimentRepository() FeeExperimentRepository {
+	return r.feeExperimentRepo
+}
+
+func (r *repository) AuditAnchorRepository() AuditAnchorRepository {
+	return r.auditAnchorRepo
+}
+
+func (r *repository) MigrationCheckpointRepository() MigrationCheckpointRepository {
+	return r.migrationCheckpointRepo
+}
+
+func (r *repository) AdapterCallLogRepository() AdapterCallLogRepository {
+	return r.adapterCallLogRepo
+}
+
+func (r *repository) PostingRuleRepository() PostingRuleRepository {
+	return r.postingRuleRepo
+}
+
+func (r *repository) WithContext(ctx context.Context) Repository {
+	return NewRepository(r.db.WithContext(ctx))
+}
+
+func (r *repository) Transaction(fn func(tx Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepository(tx))
+	})
+}
+
+func (r *repository) StatementTokenRepository() StatementTokenRepository {
+	return r.statementTokenRepo
+}
+
+func (r *repository) PaymentCallbackRepository() PaymentCallbackRepository {
+	return r.paymentCallbackRepo
+}
+
+func (r *repository) PartySpendingLimitRepository() PartySpendingLimitRepository {
+	return r.partySpendingLimitRepo
+}
+
+func (r *repository) RiskPolicyRepository() RiskPolicyRepository {
+	return r.riskPolicyRepo
+}
+
+func (r *repository) AgentRiskProfileRepository() AgentRiskProfileRepository {
+	return r.agentRiskProfileRepo
+}
+
+func (r *repository) CounterpartyRepository() CounterpartyRepository {
+	return r.counterpartyRepo
+}
+
+func (r *repository) WorkflowTemplateRepository() WorkflowTemplateRepository {
+	return r.workflowTemplateRepo
+}
+
+func (r *repository) BudgetRepository() BudgetRepository {
+	return r.budgetRepo
+}
+
+func (r *repository) PaymentBatchRepository() PaymentBatchRepository {
+	return r.paymentBatchRepo
+}
+
+func (r *repository) PaymentScheduleRepository() PaymentScheduleRepository {
+	return r.paymentScheduleRepo
+}
+
+func (r *repository) KYCSubmissionRepository() KYCSubmissionRepository {
+	return r.kycSubmissionRepo
+}
+
+func (r *repository) DIDChallengeRepository() DIDChallengeRepository {
+	return r.didChallengeRepo
+}
+
+func (r *repository) UserRepository() UserRepository {
+	return r.userRepo
+}
+
+func (r *repository) RoleRepository() RoleRepository {
+	return r.roleRepo
+}
+
+func (r *repository) ApproverGroupRepository() ApproverGroupRepository {
+	return r.approverGroupRepo
+}
+
+func (r *repository) QuoteRepository() QuoteRepository {
+	return r.quoteRepo
+}
+
+func (r *repository) PaymentRailRepository() PaymentRailRepository {
+	return r.paymentRailRepo
+}
+
+func (r *repository) ChainTransferRepository() ChainTransferRepository {
+	return r.chainTransferRepo
+}
+
 func (r *repository) HealthCheck() error {
 	return HealthCheck(r.db)
 }
@@ -248,6 +1282,17 @@ func (r *partyRepository) List() ([]*Party, error) {
 	return parties, err
 }
 
+func (r *partyRepository) TenantOf(partyID string) (string, error) {
+	party, err := r.GetByID(partyID)
+	if err != nil {
+		return "", err
+	}
+	if party.OrganizationID != nil {
+		return *party.OrganizationID, nil
+	}
+	return party.ID, nil
+}
+
 func (r *partyRepository) Update(party *Party) error {
 	return r.db.Save(party).Error
 }
@@ -286,6 +1331,59 @@ func (r *agentRepository) ListByOwnerPartyID(ownerPartyID string) ([]*Agent, err
 	return agents, err
 }
 
+func (r *agentRepository) ListByTenantID(tenantID string) ([]*Agent, error) {
+	var agents []*Agent
+	err := r.db.Preload("OwnerParty").Where("owner_party_id IN (?)", tenantParties(r.db, tenantID)).Find(&agents).Error
+	return agents, err
+}
+
+func (r *agentRepository) ListPage(filter AgentFilter, params common.ListParams) ([]*Agent, int, error) {
+	var agents []*Agent
+	query := whereSet(r.db.Model(&Agent{}), map[string]string{
+		"owner_party_id": filter.OwnerPartyID,
+		"status":         filter.Status,
+	})
+	if filter.TenantID != "" {
+		query = query.Where("owner_party_id IN (?)", tenantParties(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, agentSortColumns, &agents)
+	return agents, total, err
+}
+
+func (r *agentRepository) ListByIDs(ids []string) ([]*Agent, error) {
+	var agents []*Agent
+	err := r.db.Where("id IN ?", ids).Find(&agents).Error
+	return agents, err
+}
+
+func (r *agentRepository) ListByIDsInTenant(ids []string, tenantID string) ([]*Agent, error) {
+	var agents []*Agent
+	err := r.db.Where("id IN ? AND owner_party_id IN (?)", ids, tenantParties(r.db, tenantID)).Find(&agents).Error
+	return agents, err
+}
+
+func (r *agentRepository) UpdateStatus(agent *Agent, status, reason string) (bool, error) {
+	// Updated through the model so the change is kept in its history
+	now := time.Now()
+	updated := *agent
+	updated.Status = status
+	updated.StatusReason = reason
+	updated.StatusChangedAt = &now
+	from := agent.Status
+	if from == "" {
+		from = "active"
+	}
+	result := r.db.Model(&updated).
+		Where("status = ?", from).
+		Select("Status", "StatusReason", "StatusChangedAt", "UpdatedAt").
+		Updates(&updated)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	*agent = updated
+	return true, nil
+}
+
 func (r *agentRepository) Update(agent *Agent) error {
 	return r.db.Save(agent).Error
 }
@@ -318,20 +1416,155 @@ func (r *consentRepository) List() ([]*Consent, error) {
 	return consents, err
 }
 
+func (r *consentRepository) ListPage(filter ConsentFilter, params common.ListParams) ([]*Consent, int, error) {
+	var consents []*Consent
+	query := whereSet(r.db.Model(&Consent{}), map[string]string{
+		"agent_id":       filter.AgentID,
+		"owner_party_id": filter.OwnerPartyID,
+	})
+	if filter.Revoked != nil {
+		query = query.Where("revoked = ?", *filter.Revoked)
+	}
+	if filter.TenantID != "" {
+		query = query.Where("owner_party_id IN (?)", tenantParties(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, consentSortColumns, &consents, "Agent", "OwnerParty")
+	return consents, total, err
+}
+
 func (r *consentRepository) ListByAgentID(agentID string) ([]*Consent, error) {
 	var consents []*Consent
 	err := r.db.Preload("Agent").Preload("OwnerParty").Where("agent_id = ?", agentID).Find(&consents).Error
 	return consents, err
 }
 
-func (r *consentRepository) ListByOwnerPartyID(ownerPartyID string) ([]*Consent, error) {
+func (r *consentRepository) ListByAgentIDs(agentIDs []string) ([]*Consent, error) {
 	var consents []*Consent
-	err := r.db.Preload("Agent").Preload("OwnerParty").Where("owner_party_id = ?", ownerPartyID).Find(&consents).Error
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("created_at DESC").Find(&consents).Error
 	return consents, err
 }
 
-func (r *consentRepository) Update(consent *Consent) error {
-	return r.db.Save(consent).Error
+func (r *consentRepository) ListByOwnerPartyID(ownerPartyID string) ([]*Consent, error) {
+	var consents []*Consent
+	err := r.db.Preload("Agent").Preload("OwnerParty").Where("owner_party_id = ?", ownerPartyID).Find(&consents).Error
+	return consents, err
+}
+
+func (r *consentRepository) GetAsOf(id string, asOf time.Time) (*Consent, error) {
+	var consent Consent
+	err := r.db.Unscoped().Preload("Agent").Preload("OwnerParty").
+		Where("deleted_at IS NULL OR deleted_at > ?", asOf).
+		First(&consent, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := rewind(r.db, &consent, "consent", consent.ID, consent.CreatedAt, asOf); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+func (r *consentRepository) ListByAgentIDAsOf(agentID string, asOf time.Time) ([]*Consent, error) {
+	var consents []*Consent
+	err := r.db.Unscoped().Preload("Agent").Preload("OwnerParty").
+		Where("agent_id = ? AND created_at <= ?", agentID, asOf).
+		Where("deleted_at IS NULL OR deleted_at > ?", asOf).
+		Order("created_at DESC").
+		Find(&consents).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, consent := range consents {
+		if err := rewind(r.db, consent, "consent", consent.ID, consent.CreatedAt, asOf); err != nil {
+			return nil, err
+		}
+	}
+	return consents, nil
+}
+
+func (r *consentRepository) Supersede(predecessor, successor *Consent) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Agent", "OwnerParty").Create(successor).Error; err != nil {
+			return err
+		}
+		// Updated through the model so the supersession is kept in its history
+		now := time.Now()
+		superseded := *predecessor
+		superseded.SupersededByID = &successor.ID
+		superseded.SupersededAt = &now
+		result := tx.Model(&superseded).
+			Where("revoked = ? AND superseded_by_id IS NULL", false).
+			Select("SupersededByID", "SupersededAt", "UpdatedAt").
+			Updates(&superseded)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNotSuperseded
+		}
+		*predecessor = superseded
+		return nil
+	})
+	if errors.Is(err, errNotSuperseded) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// errNotSuperseded rolls back a successor whose predecessor was no longer
+// current
+var errNotSuperseded = errors.New("consent not superseded")
+
+func (r *consentRepository) ListVersions(id string) ([]*Consent, error) {
+	var consent Consent
+	if err := r.db.Unscoped().First(&consent, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	// Back to the first version, then forward to the latest
+	first := &consent
+	for i := 0; first.PreviousConsentID != nil && i < maxConsentVersions; i++ {
+		var previous Consent
+		if err := r.db.Unscoped().First(&previous, "id = ?", *first.PreviousConsentID).Error; err != nil {
+			return nil, err
+		}
+		first = &previous
+	}
+	versions := []*Consent{first}
+	for latest := first; latest.SupersededByID != nil && len(versions) < maxConsentVersions; {
+		var next Consent
+		if err := r.db.Unscoped().First(&next, "id = ?", *latest.SupersededByID).Error; err != nil {
+			return nil, err
+		}
+		versions = append(versions, &next)
+		latest = &next
+	}
+	return versions, nil
+}
+
+// maxConsentVersions bounds the version chains followed
+const maxConsentVersions = 1000
+
+func (r *consentRepository) ListExpiring(before time.Time, limit int) ([]*Consent, error) {
+	var consents []*Consent
+	err := r.db.
+		Where("expires_at IS NOT NULL AND expires_at <= ?", before).
+		Where("revoked = ? AND superseded_by_id IS NULL AND expiring_notified_at IS NULL", false).
+		Order("expires_at").
+		Limit(limit).
+		Find(&consents).Error
+	return consents, err
+}
+
+func (r *consentRepository) MarkExpiringNotified(id string, at time.Time) (bool, error) {
+	result := r.db.Model(&Consent{}).
+		Where("id = ? AND expiring_notified_at IS NULL", id).
+		Update("expiring_notified_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *consentRepository) Update(consent *Consent) error {
+	return r.db.Save(consent).Error
 }
 
 func (r *consentRepository) Delete(id string) error {
@@ -362,12 +1595,40 @@ func (r *riskDecisionRepository) List() ([]*RiskDecision, error) {
 	return riskDecisions, err
 }
 
+func (r *riskDecisionRepository) ListPage(filter RiskDecisionFilter, params common.ListParams) ([]*RiskDecision, int, error) {
+	var riskDecisions []*RiskDecision
+	query := whereSet(r.db.Model(&RiskDecision{}), map[string]string{
+		"agent_id":     filter.AgentID,
+		"decision":     filter.Decision,
+		"rail":         filter.Rail,
+		"counterparty": filter.Counterparty,
+	})
+	if filter.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, riskDecisionSortColumns, &riskDecisions, "Agent")
+	return riskDecisions, total, err
+}
+
 func (r *riskDecisionRepository) ListByAgentID(agentID string) ([]*RiskDecision, error) {
 	var riskDecisions []*RiskDecision
 	err := r.db.Preload("Agent").Where("agent_id = ?", agentID).Find(&riskDecisions).Error
 	return riskDecisions, err
 }
 
+func (r *riskDecisionRepository) ListByAgentIDs(agentIDs []string) ([]*RiskDecision, error) {
+	var riskDecisions []*RiskDecision
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("created_at DESC").Find(&riskDecisions).Error
+	return riskDecisions, err
+}
+
+// ListByAgentIDSince lists an agent's decisions made after a time, newest first
+func (r *riskDecisionRepository) ListByAgentIDSince(agentID string, since time.Time) ([]*RiskDecision, error) {
+	var riskDecisions []*RiskDecision
+	err := r.db.Where("agent_id = ? AND created_at > ?", agentID, since).Order("created_at DESC").Find(&riskDecisions).Error
+	return riskDecisions, err
+}
+
 func (r *riskDecisionRepository) Update(riskDecision *RiskDecision) error {
 	return r.db.Save(riskDecision).Error
 }
@@ -400,20 +1661,141 @@ func (r *paymentWorkflowRepository) List() ([]*PaymentWorkflow, error) {
 	return workflows, err
 }
 
+func (r *paymentWorkflowRepository) ListPage(filter PaymentWorkflowFilter, params common.ListParams) ([]*PaymentWorkflow, int, error) {
+	var workflows []*PaymentWorkflow
+	query := whereSet(r.db.Model(&PaymentWorkflow{}), map[string]string{
+		"agent_id":                filter.AgentID,
+		"status":                  filter.Status,
+		"rail":                    filter.Rail,
+		"counterparty":            filter.Counterparty,
+		"consent_id":              filter.ConsentID,
+		"risk_decision_id":        filter.RiskDecisionID,
+		"compliance_screening_id": filter.ComplianceScreeningID,
+		"schedule_id":             filter.ScheduleID,
+		"category":                filter.Category,
+	})
+	if filter.Degraded != nil {
+		query = query.Where("degraded = ?", *filter.Degraded)
+	}
+	if filter.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, paymentWorkflowSortColumns, &workflows, "Agent")
+	return workflows, total, err
+}
+
 func (r *paymentWorkflowRepository) ListByAgentID(agentID string) ([]*PaymentWorkflow, error) {
 	var workflows []*PaymentWorkflow
 	err := r.db.Preload("Agent").Where("agent_id = ?", agentID).Find(&workflows).Error
 	return workflows, err
 }
 
+// ListByAgentIDSince lists an agent's workflows created after a time, newest first
+func (r *paymentWorkflowRepository) ListByAgentIDSince(agentID string, since time.Time) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Where("agent_id = ? AND created_at > ?", agentID, since).Order("created_at DESC").Find(&workflows).Error
+	return workflows, err
+}
+
+func (r *paymentWorkflowRepository) ListByAgentIDs(agentIDs []string) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("created_at DESC").Find(&workflows).Error
+	return workflows, err
+}
+
+func (r *paymentWorkflowRepository) ListByAgentIDsSince(agentIDs []string, since time.Time) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Where("agent_id IN ? AND created_at > ?", agentIDs, since).Order("created_at DESC").Find(&workflows).Error
+	return workflows, err
+}
+
 func (r *paymentWorkflowRepository) ListByStatus(status string) ([]*PaymentWorkflow, error) {
 	var workflows []*PaymentWorkflow
 	err := r.db.Preload("Agent").Where("status = ?", status).Find(&workflows).Error
 	return workflows, err
 }
 
+func (r *paymentWorkflowRepository) CountByAgentIDByStatus(agentID string) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&PaymentWorkflow{}).
+		Select("status, COUNT(*) AS count").
+		Where("agent_id = ?", agentID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ListByConsentID lists the payments validated against a consent, oldest first
+func (r *paymentWorkflowRepository) ListByConsentID(consentID string) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Preload("Agent").Where("consent_id = ?", consentID).Order("created_at ASC").Find(&workflows).Error
+	return workflows, err
+}
+
+// ListByRiskDecisionID lists the payments a risk decision allowed
+func (r *paymentWorkflowRepository) ListByRiskDecisionID(riskDecisionID string) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Preload("Agent").Where("risk_decision_id = ?", riskDecisionID).Order("created_at ASC").Find(&workflows).Error
+	return workflows, err
+}
+
+// ListByComplianceScreeningID lists the payments a screening cleared
+func (r *paymentWorkflowRepository) ListByComplianceScreeningID(screeningID string) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Preload("Agent").Where("compliance_screening_id = ?", screeningID).Order("created_at ASC").Find(&workflows).Error
+	return workflows, err
+}
+
+// Update saves a workflow except its status and consent, which only change
+// through UpdateStatus and LinkConsent
 func (r *paymentWorkflowRepository) Update(workflow *PaymentWorkflow) error {
-	return r.db.Save(workflow).Error
+	return r.db.Omit("Status", "ConsentID", "ConsentVersion").Save(workflow).Error
+}
+
+// LinkConsent records the consent version a workflow was validated against.
+// It is kept out of Update so a revocation can move a workflow to another
+// consent while the workflow is being processed.
+func (r *paymentWorkflowRepository) LinkConsent(id, consentID string, consentVersion int) error {
+	return r.db.Model(&PaymentWorkflow{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"consent_id": consentID, "consent_version": consentVersion}).Error
+}
+
+// UpdateStatus moves a workflow from one status to another, reporting false
+// if it was no longer in the from status
+func (r *paymentWorkflowRepository) UpdateStatus(id, from, to string) (bool, error) {
+	result := r.db.Model(&PaymentWorkflow{}).Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{"status": to, "updated_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ListStale lists workflows in the statuses not updated since before, oldest first
+func (r *paymentWorkflowRepository) ListStale(statuses []string, before time.Time) ([]*PaymentWorkflow, error) {
+	var workflows []*PaymentWorkflow
+	err := r.db.Where("status IN ? AND updated_at < ?", statuses, before).Order("updated_at ASC").Find(&workflows).Error
+	return workflows, err
+}
+
+// Touch marks a workflow as updated now without saving its other columns
+func (r *paymentWorkflowRepository) Touch(id string) error {
+	return r.db.Model(&PaymentWorkflow{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now()).Error
+}
+
+// Claim touches a workflow only if it has not been updated since it was read,
+// so a single caller wins when several race to take it over
+func (r *paymentWorkflowRepository) Claim(id string, seenUpdatedAt time.Time) (bool, error) {
+	result := r.db.Model(&PaymentWorkflow{}).Where("id = ? AND updated_at = ?", id, seenUpdatedAt).UpdateColumn("updated_at", time.Now())
+	return result.RowsAffected == 1, result.Error
 }
 
 func (r *paymentWorkflowRepository) Delete(id string) error {
@@ -438,24 +1820,75 @@ func (r *paymentExecutionRepository) GetByID(id string) (*PaymentExecution, erro
 	return &execution, nil
 }
 
+func (r *paymentExecutionRepository) GetByReferenceID(referenceID string) (*PaymentExecution, error) {
+	var execution PaymentExecution
+	err := r.db.First(&execution, "reference_id = ?", referenceID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func (r *paymentExecutionRepository) GetByIdempotencyKey(key string) (*PaymentExecution, error) {
+	var execution PaymentExecution
+	err := r.db.First(&execution, "idempotency_key = ?", key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
 func (r *paymentExecutionRepository) List() ([]*PaymentExecution, error) {
 	var executions []*PaymentExecution
 	err := r.db.Preload("Agent").Find(&executions).Error
 	return executions, err
 }
 
+func (r *paymentExecutionRepository) ListPage(filter PaymentExecutionFilter, params common.ListParams) ([]*PaymentExecution, int, error) {
+	var executions []*PaymentExecution
+	query := whereSet(r.db.Model(&PaymentExecution{}), map[string]string{
+		"agent_id": filter.AgentID,
+		"status":   filter.Status,
+		"rail":     filter.Rail,
+	})
+	if filter.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, paymentExecutionSortColumns, &executions)
+	return executions, total, err
+}
+
 func (r *paymentExecutionRepository) ListByAgentID(agentID string) ([]*PaymentExecution, error) {
 	var executions []*PaymentExecution
 	err := r.db.Preload("Agent").Where("agent_id = ?", agentID).Find(&executions).Error
 	return executions, err
 }
 
+// ListByAgentIDSince lists an agent's executions created after a time, newest first
+func (r *paymentExecutionRepository) ListByAgentIDSince(agentID string, since time.Time) ([]*PaymentExecution, error) {
+	var executions []*PaymentExecution
+	err := r.db.Where("agent_id = ? AND created_at > ?", agentID, since).Order("created_at DESC").Find(&executions).Error
+	return executions, err
+}
+
+func (r *paymentExecutionRepository) ListByAgentIDs(agentIDs []string) ([]*PaymentExecution, error) {
+	var executions []*PaymentExecution
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("created_at DESC").Find(&executions).Error
+	return executions, err
+}
+
 func (r *paymentExecutionRepository) ListByStatus(status string) ([]*PaymentExecution, error) {
 	var executions []*PaymentExecution
 	err := r.db.Preload("Agent").Where("status = ?", status).Find(&executions).Error
 	return executions, err
 }
 
+func (r *paymentExecutionRepository) ListSince(since time.Time) ([]*PaymentExecution, error) {
+	var executions []*PaymentExecution
+	err := r.db.Where("created_at >= ?", since).Order("created_at ASC").Find(&executions).Error
+	return executions, err
+}
+
 func (r *paymentExecutionRepository) Update(execution *PaymentExecution) error {
 	return r.db.Save(execution).Error
 }
@@ -488,12 +1921,33 @@ func (r *accountRepository) List() ([]*Account, error) {
 	return accounts, err
 }
 
+func (r *accountRepository) ListPage(filter AccountFilter, params common.ListParams) ([]*Account, int, error) {
+	var accounts []*Account
+	query := whereSet(r.db.Model(&Account{}), map[string]string{
+		"agent_id": filter.AgentID,
+		"type":     filter.Type,
+		"currency": filter.Currency,
+		"book":     filter.Book,
+	})
+	if filter.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, accountSortColumns, &accounts, "Agent")
+	return accounts, total, err
+}
+
 func (r *accountRepository) ListByAgentID(agentID string) ([]*Account, error) {
 	var accounts []*Account
 	err := r.db.Preload("Agent").Where("agent_id = ?", agentID).Find(&accounts).Error
 	return accounts, err
 }
 
+func (r *accountRepository) ListByIDs(ids []string) ([]*Account, error) {
+	var accounts []*Account
+	err := r.db.Where("id IN ?", ids).Find(&accounts).Error
+	return accounts, err
+}
+
 func (r *accountRepository) ListByType(accountType string) ([]*Account, error) {
 	var accounts []*Account
 	err := r.db.Preload("Agent").Where("type = ?", accountType).Find(&accounts).Error
@@ -506,6 +1960,27 @@ func (r *accountRepository) ListByAgentIDAndType(agentID, accountType string) ([
 	return accounts, err
 }
 
+func (r *accountRepository) GetAsOf(id string, asOf time.Time) (*Account, error) {
+	var account Account
+	err := r.db.Unscoped().Preload("Agent").
+		Where("deleted_at IS NULL OR deleted_at > ?", asOf).
+		First(&account, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := rewind(r.db, &account, "account", account.ID, account.CreatedAt, asOf); err != nil {
+		return nil, err
+	}
+	if account.Balance, err = derivedBalance(r.db, account.ID, &asOf); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *accountRepository) DerivedBalance(id string, asOf *time.Time) (float64, error) {
+	return derivedBalance(r.db, id, asOf)
+}
+
 func (r *accountRepository) Update(account *Account) error {
 	return r.db.Save(account).Error
 }
@@ -523,6 +1998,82 @@ func (r *transactionRepository) Create(transaction *Transaction) error {
 	return r.db.Create(transaction).Error
 }
 
+func (r *transactionRepository) Post(transaction *Transaction, postings []*Posting) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := postTransaction(tx, transaction, postings); err != nil {
+			return err
+		}
+		return fanOut(tx, transaction, postings)
+	})
+}
+
+// postTransaction creates a transaction in its accounts' book with its
+// postings, applies them to the balances and chains it
+func postTransaction(tx *gorm.DB, transaction *Transaction, postings []*Posting) error {
+	book, err := transactionBook(tx, transaction, postings)
+	if err != nil {
+		return err
+	}
+	transaction.Book = book
+	if err := tx.Omit("Postings").Create(transaction).Error; err != nil {
+		return err
+	}
+
+	for _, posting := range postings {
+		posting.TransactionID = transaction.ID
+		if err := tx.Create(posting).Error; err != nil {
+			return err
+		}
+
+		// Lock the account so concurrent postings see each other's balance
+		var account Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "id = ?", posting.AccountID).Error; err != nil {
+			return err
+		}
+		account.Balance += posting.Amount
+
+		// The floor is checked against the balance derived from the
+		// postings, which include this one; the stored balance is a cache
+		if floor, ok := account.BalanceFloor(); ok && posting.Amount < 0 {
+			balance, err := derivedBalance(tx, account.ID, nil)
+			if err != nil {
+				return err
+			}
+			var held float64
+			err = tx.Model(&Hold{}).Where("account_id = ? AND status = ?", account.ID, "active").
+				Select("COALESCE(SUM(amount), 0)").Scan(&held).Error
+			if err != nil {
+				return err
+			}
+			if available := balance - held; math.Round(available*100) < math.Round(floor*100) {
+				return &InsufficientFundsError{AccountID: account.ID, Available: available, Floor: floor}
+			}
+		}
+
+		if err := tx.Save(&account).Error; err != nil {
+			return err
+		}
+	}
+	return chainTransaction(tx, transaction)
+}
+
+func (r *transactionRepository) Chain(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var transaction Transaction
+		if err := tx.First(&transaction, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return chainTransaction(tx, &transaction)
+	})
+}
+
+func (r *transactionRepository) ListChain(agentID string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := r.db.Preload("Postings").Where("agent_id = ? AND block_index > 0", agentID).
+		Order("block_index ASC").Find(&transactions).Error
+	return transactions, err
+}
+
 func (r *transactionRepository) GetByID(id string) (*Transaction, error) {
 	var transaction Transaction
 	err := r.db.Preload("Agent").Preload("Postings").First(&transaction, "id = ?", id).Error
@@ -538,12 +2089,47 @@ func (r *transactionRepository) List() ([]*Transaction, error) {
 	return transactions, err
 }
 
+func (r *transactionRepository) ListPage(filter TransactionFilter, params common.ListParams) ([]*Transaction, int, error) {
+	var transactions []*Transaction
+	query := whereSet(r.db.Model(&Transaction{}), map[string]string{
+		"agent_id":     filter.AgentID,
+		"status":       filter.Status,
+		"reference_id": filter.ReferenceID,
+		"book":         filter.Book,
+	})
+	if filter.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filter.TenantID))
+	}
+	total, err := listPage(query, params, transactionSortColumns, &transactions, "Agent", "Postings")
+	return transactions, total, err
+}
+
 func (r *transactionRepository) ListByAgentID(agentID string) ([]*Transaction, error) {
 	var transactions []*Transaction
 	err := r.db.Preload("Agent").Preload("Postings").Where("agent_id = ?", agentID).Find(&transactions).Error
 	return transactions, err
 }
 
+func (r *transactionRepository) ListByReferenceID(referenceID string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := r.db.Preload("Postings").Where("reference_id = ?", referenceID).Order("created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *transactionRepository) ListByReferenceIDs(referenceIDs []string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := r.db.Preload("Postings").Where("reference_id IN ?", referenceIDs).Order("created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *transactionRepository) ListPostedBetween(agentID, book string, from, to time.Time) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := r.db.Preload("Postings").
+		Where("agent_id = ? AND book = ? AND status = ? AND created_at >= ? AND created_at < ?", agentID, book, "posted", from, to).
+		Order("created_at ASC").Find(&transactions).Error
+	return transactions, err
+}
+
 func (r *transactionRepository) Update(transaction *Transaction) error {
 	return r.db.Save(transaction).Error
 }
@@ -588,6 +2174,37 @@ func (r *postingRepository) ListByAccountID(accountID string) ([]*Posting, error
 	return postings, err
 }
 
+func (r *postingRepository) ListPageByAccountID(accountID string, params common.ListParams) ([]*Posting, int, error) {
+	var postings []*Posting
+	query := r.db.Model(&Posting{}).Where("account_id = ?", accountID)
+	total, err := listPage(query, params, postingSortColumns, &postings, "Transaction")
+	return postings, total, err
+}
+
+func (r *postingRepository) BalanceThrough(posting *Posting) (float64, error) {
+	var sum float64
+	err := r.db.Model(&Posting{}).
+		Where("account_id = ? AND (created_at < ? OR (created_at = ? AND id <= ?))", posting.AccountID, posting.CreatedAt, posting.CreatedAt, posting.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+	return sum, err
+}
+
+func (r *postingRepository) Totals(accountID string, from, to *time.Time) (*PostingTotals, error) {
+	query := r.db.Model(&Posting{}).Where("account_id = ?", accountID)
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+	var totals PostingTotals
+	err := query.Select("COUNT(*) AS count, " +
+		"COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS debits, " +
+		"COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0) AS credits").
+		Scan(&totals).Error
+	return &totals, err
+}
+
 func (r *postingRepository) Update(posting *Posting) error {
 	return r.db.Save(posting).Error
 }
@@ -616,10 +2233,82 @@ func (r *outboxEventRepository) GetByID(id string) (*OutboxEvent, error) {
 
 func (r *outboxEventRepository) ListPending(limit int) ([]*OutboxEvent, error) {
 	var outboxEvents []*OutboxEvent
-	err := r.db.Where("status = ?", "pending").Order("created_at ASC").Limit(limit).Find(&outboxEvents).Error
+	err := r.db.Where("status = ?", "pending").Order("created_at ASC, sequence ASC").Limit(limit).Find(&outboxEvents).Error
+	return outboxEvents, err
+}
+
+func (r *outboxEventRepository) CountByStatus(status string) (int64, error) {
+	var count int64
+	err := r.db.Model(&OutboxEvent{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+// ListFailed lists failed events, least recently attempted first
+func (r *outboxEventRepository) ListFailed(limit int) ([]*OutboxEvent, error) {
+	var outboxEvents []*OutboxEvent
+	err := r.db.Where("status = ?", "failed").Order("updated_at ASC").Limit(limit).Find(&outboxEvents).Error
+	return outboxEvents, err
+}
+
+// ListRetryable lists failed events with attempts left, least recently
+// attempted first
+func (r *outboxEventRepository) ListRetryable(maxAttempts, limit int) ([]*OutboxEvent, error) {
+	var outboxEvents []*OutboxEvent
+	err := r.db.Where("status = ? AND retry_count < ?", "failed", maxAttempts).Order("updated_at ASC").Limit(limit).Find(&outboxEvents).Error
+	return outboxEvents, err
+}
+
+// CountExhausted counts failed events that have used all their attempts
+func (r *outboxEventRepository) CountExhausted(maxAttempts int) (int64, error) {
+	var count int64
+	err := r.db.Model(&OutboxEvent{}).Where("status = ? AND retry_count >= ?", "failed", maxAttempts).Count(&count).Error
+	return count, err
+}
+
+// LastSequence returns the highest sequence assigned to a partition key, 0 if none
+func (r *outboxEventRepository) LastSequence(partitionKey string) (int64, error) {
+	var sequence int64
+	err := r.db.Model(&OutboxEvent{}).Where("partition_key = ?", partitionKey).Select("COALESCE(MAX(sequence), 0)").Scan(&sequence).Error
+	return sequence, err
+}
+
+// HasUnpublishedBefore reports whether an earlier event with the same
+// partition key is still pending or failed
+func (r *outboxEventRepository) HasUnpublishedBefore(partitionKey string, sequence int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&OutboxEvent{}).
+		Where("partition_key = ? AND sequence > 0 AND sequence < ? AND status <> ?", partitionKey, sequence, "published").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListPrunable lists published events published before a time, oldest
+// first. The last event of each partition key is kept back, so the key's
+// sequence carries on from it.
+func (r *outboxEventRepository) ListPrunable(publishedBefore time.Time, limit int) ([]*OutboxEvent, error) {
+	var outboxEvents []*OutboxEvent
+	err := r.db.Where("status = ? AND published_at < ?", "published", publishedBefore).
+		Where("NOT (sequence > 0 AND sequence = (SELECT MAX(latest.sequence) FROM outbox_events latest WHERE latest.partition_key = outbox_events.partition_key))").
+		Order("published_at ASC").Limit(limit).Find(&outboxEvents).Error
 	return outboxEvents, err
 }
 
+// DeleteByIDs deletes events, reporting how many were deleted
+func (r *outboxEventRepository) DeleteByIDs(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Where("id IN ?", ids).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// TableSize returns the bytes taken by the outbox table with its indexes
+func (r *outboxEventRepository) TableSize() (int64, error) {
+	var size int64
+	err := r.db.Raw("SELECT pg_total_relation_size(?)", OutboxEvent{}.TableName()).Scan(&size).Error
+	return size, err
+}
+
 func (r *outboxEventRepository) Update(outboxEvent *OutboxEvent) error {
 	return r.db.Save(outboxEvent).Error
 }
@@ -647,9 +2336,33 @@ func (r *auditEntryRepository) GetByID(id string) (*AuditEntry, error) {
 }
 
 func (r *auditEntryRepository) Query(filters AuditQueryFilters) ([]*AuditEntry, error) {
+	query := r.filtered(filters)
+
+	// Apply ordering and limits
+	// Ties are broken by ID so pages and export chunks do not overlap
+	query = query.Order("timestamp DESC, id DESC")
+	if filters.Limit > 0 {
+		query = query.Limit(filters.Limit)
+	}
+	if filters.Offset > 0 {
+		query = query.Offset(filters.Offset)
+	}
+
+	var auditEntries []*AuditEntry
+	err := query.Find(&auditEntries).Error
+	return auditEntries, err
+}
+
+func (r *auditEntryRepository) CountMatching(filters AuditQueryFilters) (int64, error) {
+	var count int64
+	err := r.filtered(filters).Count(&count).Error
+	return count, err
+}
+
+// filtered selects the entries matching every filter that is set
+func (r *auditEntryRepository) filtered(filters AuditQueryFilters) *gorm.DB {
 	query := r.db.Model(&AuditEntry{})
 
-	// Apply filters
 	if filters.UserID != "" {
 		query = query.Where("user_id = ?", filters.UserID)
 	}
@@ -677,19 +2390,10 @@ func (r *auditEntryRepository) Query(filters AuditQueryFilters) ([]*AuditEntry,
 	if filters.IPAddress != "" {
 		query = query.Where("ip_address = ?", filters.IPAddress)
 	}
-
-	// Apply ordering and limits
-	query = query.Order("timestamp DESC")
-	if filters.Limit > 0 {
-		query = query.Limit(filters.Limit)
-	}
-	if filters.Offset > 0 {
-		query = query.Offset(filters.Offset)
+	if filters.TenantID != "" {
+		query = query.Where("agent_id IN (?)", tenantAgents(r.db, filters.TenantID))
 	}
-
-	var auditEntries []*AuditEntry
-	err := query.Find(&auditEntries).Error
-	return auditEntries, err
+	return query
 }
 
 func (r *auditEntryRepository) Count() (int64, error) {
@@ -721,3 +2425,2205 @@ func (r *auditEntryRepository) Archive(beforeDate time.Time) error {
 	// Mark entries as archived instead of deleting them
 	return r.db.Model(&AuditEntry{}).Where("timestamp < ?", beforeDate).Update("archived", true).Error
 }
+
+func (r *auditEntryRepository) ListUnanchored(before time.Time, limit int) ([]*AuditEntry, error) {
+	var entries []*AuditEntry
+	err := r.db.Where("anchor_id IS NULL AND created_at < ?", before).
+		Order("timestamp ASC, id ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// apiCredentialRepository implements APICredentialRepository
+type apiCredentialRepository struct {
+	db *gorm.DB
+}
+
+func (r *apiCredentialRepository) Create(credential *APICredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *apiCredentialRepository) GetByID(id string) (*APICredential, error) {
+	var credential APICredential
+	err := r.db.First(&credential, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *apiCredentialRepository) GetByKeyHash(keyHash string) (*APICredential, error) {
+	var credential APICredential
+	err := r.db.First(&credential, "key_hash = ?", keyHash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *apiCredentialRepository) ListByPartyID(partyID string) ([]*APICredential, error) {
+	var credentials []*APICredential
+	err := r.db.Where("party_id = ?", partyID).Order("created_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *apiCredentialRepository) ListByAgentID(agentID string) ([]*APICredential, error) {
+	var credentials []*APICredential
+	err := r.db.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *apiCredentialRepository) Update(credential *APICredential) error {
+	return r.db.Save(credential).Error
+}
+
+func (r *apiCredentialRepository) Delete(id string) error {
+	return r.db.Delete(&APICredential{}, "id = ?", id).Error
+}
+
+// agentCredentialRepository implements AgentCredentialRepository
+type agentCredentialRepository struct {
+	db *gorm.DB
+}
+
+func (r *agentCredentialRepository) Create(credential *AgentCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *agentCredentialRepository) GetByID(id string) (*AgentCredential, error) {
+	var credential AgentCredential
+	err := r.db.First(&credential, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *agentCredentialRepository) ListByAgentID(agentID string) ([]*AgentCredential, error) {
+	var credentials []*AgentCredential
+	err := r.db.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *agentCredentialRepository) Update(credential *AgentCredential) error {
+	return r.db.Save(credential).Error
+}
+
+func (r *agentCredentialRepository) Delete(id string) error {
+	return r.db.Delete(&AgentCredential{}, "id = ?", id).Error
+}
+
+// killSwitchEventRepository implements KillSwitchEventRepository
+type killSwitchEventRepository struct {
+	db *gorm.DB
+}
+
+func (r *killSwitchEventRepository) Create(event *KillSwitchEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *killSwitchEventRepository) ListByComponent(component string) ([]*KillSwitchEvent, error) {
+	var events []*KillSwitchEvent
+	err := r.db.Where("component = ?", component).Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+func (r *killSwitchEventRepository) ListSince(since time.Time) ([]*KillSwitchEvent, error) {
+	var events []*KillSwitchEvent
+	err := r.db.Where("created_at >= ?", since).Order("created_at DESC").Find(&events).Error
+	return events, err
+}
+
+// ListCurrent returns the latest event for each component
+func (r *killSwitchEventRepository) ListCurrent() ([]*KillSwitchEvent, error) {
+	var events []*KillSwitchEvent
+	latest := r.db.Model(&KillSwitchEvent{}).Select("component, MAX(created_at) AS created_at").Group("component")
+	err := r.db.Joins("JOIN (?) AS latest ON latest.component = kill_switch_events.component AND latest.created_at = kill_switch_events.created_at", latest).
+		Order("kill_switch_events.component ASC").Find(&events).Error
+	return events, err
+}
+
+// mandateKeyRepository implements MandateKeyRepository
+type mandateKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *mandateKeyRepository) Create(key *MandateKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *mandateKeyRepository) GetByID(id string) (*MandateKey, error) {
+	var key MandateKey
+	err := r.db.First(&key, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *mandateKeyRepository) ListByAgentID(agentID string) ([]*MandateKey, error) {
+	var keys []*MandateKey
+	err := r.db.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *mandateKeyRepository) Update(key *MandateKey) error {
+	return r.db.Save(key).Error
+}
+
+// mandateRepository implements MandateRepository
+type mandateRepository struct {
+	db *gorm.DB
+}
+
+func (r *mandateRepository) Create(mandate *Mandate) error {
+	return r.db.Create(mandate).Error
+}
+
+func (r *mandateRepository) GetByID(id string) (*Mandate, error) {
+	var mandate Mandate
+	err := r.db.First(&mandate, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mandate, nil
+}
+
+func (r *mandateRepository) GetByWorkflowID(workflowID string) (*Mandate, error) {
+	var mandate Mandate
+	err := r.db.First(&mandate, "workflow_id = ?", workflowID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mandate, nil
+}
+
+func (r *mandateRepository) Update(mandate *Mandate) error {
+	return r.db.Save(mandate).Error
+}
+
+// paymentLinkRepository implements PaymentLinkRepository
+type paymentLinkRepository struct {
+	db *gorm.DB
+}
+
+func (r *paymentLinkRepository) Create(link *PaymentLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *paymentLinkRepository) GetByID(id string) (*PaymentLink, error) {
+	var link PaymentLink
+	err := r.db.First(&link, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *paymentLinkRepository) GetByToken(token string) (*PaymentLink, error) {
+	var link PaymentLink
+	err := r.db.First(&link, "token = ?", token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *paymentLinkRepository) ListByWorkflowID(workflowID string) ([]*PaymentLink, error) {
+	var links []*PaymentLink
+	err := r.db.Where("workflow_id = ?", workflowID).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+func (r *paymentLinkRepository) Update(link *PaymentLink) error {
+	return r.db.Save(link).Error
+}
+
+// fundingSourceRepository implements FundingSourceRepository
+type fundingSourceRepository struct {
+	db *gorm.DB
+}
+
+func (r *fundingSourceRepository) Create(source *FundingSource) error {
+	return r.db.Create(source).Error
+}
+
+func (r *fundingSourceRepository) GetByID(id string) (*FundingSource, error) {
+	var source FundingSource
+	err := r.db.First(&source, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (r *fundingSourceRepository) ListByPartyID(partyID string) ([]*FundingSource, error) {
+	var sources []*FundingSource
+	err := r.db.Where("party_id = ?", partyID).Order("created_at DESC").Find(&sources).Error
+	return sources, err
+}
+
+func (r *fundingSourceRepository) Update(source *FundingSource) error {
+	return r.db.Save(source).Error
+}
+
+// fundingTransferRepository implements FundingTransferRepository
+type fundingTransferRepository struct {
+	db *gorm.DB
+}
+
+func (r *fundingTransferRepository) Create(transfer *FundingTransfer) error {
+	return r.db.Create(transfer).Error
+}
+
+func (r *fundingTransferRepository) GetByID(id string) (*FundingTransfer, error) {
+	var transfer FundingTransfer
+	err := r.db.First(&transfer, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *fundingTransferRepository) ListByFundingSourceID(fundingSourceID string) ([]*FundingTransfer, error) {
+	var transfers []*FundingTransfer
+	err := r.db.Where("funding_source_id = ?", fundingSourceID).Order("created_at DESC").Find(&transfers).Error
+	return transfers, err
+}
+
+func (r *fundingTransferRepository) Update(transfer *FundingTransfer) error {
+	return r.db.Save(transfer).Error
+}
+
+// trustedIssuerRepository implements TrustedIssuerRepository
+type trustedIssuerRepository struct {
+	db *gorm.DB
+}
+
+func (r *trustedIssuerRepository) Create(issuer *TrustedIssuer) error {
+	return r.db.Create(issuer).Error
+}
+
+func (r *trustedIssuerRepository) GetByID(id string) (*TrustedIssuer, error) {
+	var issuer TrustedIssuer
+	err := r.db.First(&issuer, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func (r *trustedIssuerRepository) GetByIssuer(issuerName string) (*TrustedIssuer, error) {
+	var issuer TrustedIssuer
+	err := r.db.First(&issuer, "issuer = ?", issuerName).Error
+	if err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func (r *trustedIssuerRepository) List() ([]*TrustedIssuer, error) {
+	var issuers []*TrustedIssuer
+	err := r.db.Order("created_at ASC").Find(&issuers).Error
+	return issuers, err
+}
+
+func (r *trustedIssuerRepository) ListByTenantID(tenantID string) ([]*TrustedIssuer, error) {
+	var issuers []*TrustedIssuer
+	err := r.db.Where("host_party_id IN (?)", tenantParties(r.db, tenantID)).Order("created_at ASC").Find(&issuers).Error
+	return issuers, err
+}
+
+func (r *trustedIssuerRepository) Update(issuer *TrustedIssuer) error {
+	return r.db.Save(issuer).Error
+}
+
+// federatedIdentityRepository implements FederatedIdentityRepository
+type federatedIdentityRepository struct {
+	db *gorm.DB
+}
+
+func (r *federatedIdentityRepository) Create(identity *FederatedIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *federatedIdentityRepository) GetByIssuerAndSubject(issuerID, subject string) (*FederatedIdentity, error) {
+	var identity FederatedIdentity
+	err := r.db.First(&identity, "issuer_id = ? AND subject = ?", issuerID, subject).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *federatedIdentityRepository) GetByAgentID(agentID string) (*FederatedIdentity, error) {
+	var identity FederatedIdentity
+	err := r.db.First(&identity, "agent_id = ?", agentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *federatedIdentityRepository) ListByIssuerID(issuerID string) ([]*FederatedIdentity, error) {
+	var identities []*FederatedIdentity
+	err := r.db.Where("issuer_id = ?", issuerID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *federatedIdentityRepository) Update(identity *FederatedIdentity) error {
+	return r.db.Save(identity).Error
+}
+
+// federatedRevocationRepository implements FederatedRevocationRepository
+type federatedRevocationRepository struct {
+	db *gorm.DB
+}
+
+func (r *federatedRevocationRepository) Create(revocation *FederatedRevocation) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(revocation).Error
+}
+
+func (r *federatedRevocationRepository) IsRevoked(issuerID, credentialID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&FederatedRevocation{}).Where("issuer_id = ? AND credential_id = ?", issuerID, credentialID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *federatedRevocationRepository) ListByIssuerID(issuerID string) ([]*FederatedRevocation, error) {
+	var revocations []*FederatedRevocation
+	err := r.db.Where("issuer_id = ?", issuerID).Order("created_at DESC").Find(&revocations).Error
+	return revocations, err
+}
+
+// reputationCredentialRepository implements ReputationCredentialRepository
+type reputationCredentialRepository struct {
+	db *gorm.DB
+}
+
+func (r *reputationCredentialRepository) Create(credential *ReputationCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *reputationCredentialRepository) GetByID(id string) (*ReputationCredential, error) {
+	var credential ReputationCredential
+	err := r.db.First(&credential, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *reputationCredentialRepository) ListByAgentID(agentID string) ([]*ReputationCredential, error) {
+	var credentials []*ReputationCredential
+	err := r.db.Where("agent_id = ?", agentID).Order("issued_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *reputationCredentialRepository) ListRevoked() ([]*ReputationCredential, error) {
+	var credentials []*ReputationCredential
+	err := r.db.Where("status = ?", "revoked").Order("revoked_at ASC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *reputationCredentialRepository) Update(credential *ReputationCredential) error {
+	return r.db.Save(credential).Error
+}
+
+// counterpartyBankAccountRepository implements CounterpartyBankAccountRepository
+type counterpartyBankAccountRepository struct {
+	db *gorm.DB
+}
+
+func (r *counterpartyBankAccountRepository) Create(account *CounterpartyBankAccount) error {
+	return r.db.Create(account).Error
+}
+
+func (r *counterpartyBankAccountRepository) GetByID(id string) (*CounterpartyBankAccount, error) {
+	var account CounterpartyBankAccount
+	err := r.db.First(&account, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *counterpartyBankAccountRepository) GetVerified(partyID, counterparty string) (*CounterpartyBankAccount, error) {
+	var account CounterpartyBankAccount
+	err := r.db.Where("party_id = ? AND counterparty = ? AND status = ?", partyID, counterparty, "verified").
+		Order("verified_at DESC").First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *counterpartyBankAccountRepository) ListByPartyID(partyID string) ([]*CounterpartyBankAccount, error) {
+	var accounts []*CounterpartyBankAccount
+	err := r.db.Where("party_id = ?", partyID).Order("created_at DESC").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *counterpartyBankAccountRepository) Update(account *CounterpartyBankAccount) error {
+	return r.db.Save(account).Error
+}
+
+// refundRepository implements RefundRepository
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func (r *refundRepository) Create(refund *Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *refundRepository) GetByID(id string) (*Refund, error) {
+	var refund Refund
+	err := r.db.First(&refund, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) ListByExecutionID(executionID string) ([]*Refund, error) {
+	var refunds []*Refund
+	err := r.db.Where("execution_id = ?", executionID).Order("created_at ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *refundRepository) CountRefundedExecutions(agentID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&Refund{}).
+		Where("agent_id = ? AND status = ?", agentID, "completed").
+		Distinct("execution_id").
+		Count(&count).Error
+	return count, err
+}
+
+func (r *refundRepository) Update(refund *Refund) error {
+	return r.db.Save(refund).Error
+}
+
+// watchlistEntryRepository implements WatchlistEntryRepository
+type watchlistEntryRepository struct {
+	db *gorm.DB
+}
+
+func (r *watchlistEntryRepository) Create(entry *WatchlistEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *watchlistEntryRepository) GetByID(id string) (*WatchlistEntry, error) {
+	var entry WatchlistEntry
+	err := r.db.First(&entry, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *watchlistEntryRepository) List(listType string) ([]*WatchlistEntry, error) {
+	var entries []*WatchlistEntry
+	query := r.db.Order("name ASC")
+	if listType != "" {
+		query = query.Where("list_type = ?", listType)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+func (r *watchlistEntryRepository) ListActive() ([]*WatchlistEntry, error) {
+	var entries []*WatchlistEntry
+	err := r.db.Where("active = ?", true).Find(&entries).Error
+	return entries, err
+}
+
+func (r *watchlistEntryRepository) Update(entry *WatchlistEntry) error {
+	return r.db.Save(entry).Error
+}
+
+// complianceScreeningRepository implements ComplianceScreeningRepository
+type complianceScreeningRepository struct {
+	db *gorm.DB
+}
+
+func (r *complianceScreeningRepository) Create(screening *ComplianceScreening) error {
+	return r.db.Create(screening).Error
+}
+
+func (r *complianceScreeningRepository) GetByID(id string) (*ComplianceScreening, error) {
+	var screening ComplianceScreening
+	err := r.db.First(&screening, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &screening, nil
+}
+
+// ListByStatus lists screenings oldest first, so the review queue is worked in order
+func (r *complianceScreeningRepository) ListByStatus(status string) ([]*ComplianceScreening, error) {
+	var screenings []*ComplianceScreening
+	err := r.db.Where("status = ?", status).Order("created_at ASC").Find(&screenings).Error
+	return screenings, err
+}
+
+func (r *complianceScreeningRepository) ListByTenantID(tenantID, status string) ([]*ComplianceScreening, error) {
+	var screenings []*ComplianceScreening
+	err := r.db.Where("status = ? AND agent_id IN (?)", status, tenantAgents(r.db, tenantID)).Order("created_at ASC").Find(&screenings).Error
+	return screenings, err
+}
+
+func (r *complianceScreeningRepository) ListByAgentID(agentID string) ([]*ComplianceScreening, error) {
+	var screenings []*ComplianceScreening
+	err := r.db.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&screenings).Error
+	return screenings, err
+}
+
+func (r *complianceScreeningRepository) Update(screening *ComplianceScreening) error {
+	return r.db.Save(screening).Error
+}
+
+// amlRuleRepository implements AMLRuleRepository
+type amlRuleRepository struct {
+	db *gorm.DB
+}
+
+func (r *amlRuleRepository) Create(rule *AMLRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *amlRuleRepository) GetByID(id string) (*AMLRule, error) {
+	var rule AMLRule
+	err := r.db.First(&rule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *amlRuleRepository) List() ([]*AMLRule, error) {
+	var rules []*AMLRule
+	err := r.db.Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *amlRuleRepository) ListEnabled() ([]*AMLRule, error) {
+	var rules []*AMLRule
+	err := r.db.Where("enabled = ?", true).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *amlRuleRepository) Update(rule *AMLRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *amlRuleRepository) Delete(id string) error {
+	return r.db.Delete(&AMLRule{}, "id = ?", id).Error
+}
+
+// descriptionTemplateRepository implements DescriptionTemplateRepository
+type descriptionTemplateRepository struct {
+	db *gorm.DB
+}
+
+func (r *descriptionTemplateRepository) Create(template *DescriptionTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *descriptionTemplateRepository) GetByID(id string) (*DescriptionTemplate, error) {
+	var template DescriptionTemplate
+	err := r.db.First(&template, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *descriptionTemplateRepository) ListByPartyID(partyID string) ([]*DescriptionTemplate, error) {
+	var templates []*DescriptionTemplate
+	err := r.db.Where("party_id = ?", partyID).Order("created_at DESC").Find(&templates).Error
+	return templates, err
+}
+
+// GetActive returns the party's active template for a rail, falling back to
+// its template for all rails
+func (r *descriptionTemplateRepository) GetActive(partyID, rail string) (*DescriptionTemplate, error) {
+	var template DescriptionTemplate
+	err := r.db.Where("party_id = ? AND active = ? AND (rail = ? OR rail = '')", partyID, true, rail).
+		Order("rail DESC").
+		First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *descriptionTemplateRepository) Update(template *DescriptionTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *descriptionTemplateRepository) Delete(id string) error {
+	return r.db.Delete(&DescriptionTemplate{}, "id = ?", id).Error
+}
+
+// deadLetterEventRepository implements DeadLetterEventRepository
+type deadLetterEventRepository struct {
+	db *gorm.DB
+}
+
+func (r *deadLetterEventRepository) Create(event *DeadLetterEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *deadLetterEventRepository) GetByID(id string) (*DeadLetterEvent, error) {
+	var event DeadLetterEvent
+	err := r.db.First(&event, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *deadLetterEventRepository) List(status, topic string, limit int) ([]*DeadLetterEvent, error) {
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+	var events []*DeadLetterEvent
+	err := query.Find(&events).Error
+	return events, err
+}
+
+// ListByIDs lists the dead-lettered messages with the IDs, oldest first
+func (r *deadLetterEventRepository) ListByIDs(ids []string) ([]*DeadLetterEvent, error) {
+	var events []*DeadLetterEvent
+	err := r.db.Where("id IN ?", ids).Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+func (r *deadLetterEventRepository) ListPending(topic string, limit int) ([]*DeadLetterEvent, error) {
+	query := r.db.Where("status = ?", DeadLetterPending).Order("created_at ASC").Limit(limit)
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+	var events []*DeadLetterEvent
+	err := query.Find(&events).Error
+	return events, err
+}
+
+// CountByTopicSince counts the messages dead-lettered after a time and not
+// yet replayed, by topic
+func (r *deadLetterEventRepository) CountByTopicSince(since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Topic string
+		Count int64
+	}
+	err := r.db.Model(&DeadLetterEvent{}).
+		Select("topic, COUNT(*) AS count").
+		Where("created_at > ? AND status = ?", since, DeadLetterPending).
+		Group("topic").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Topic] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *deadLetterEventRepository) Update(event *DeadLetterEvent) error {
+	return r.db.Save(event).Error
+}
+
+// reviewCaseRepository implements ReviewCaseRepository
+type reviewCaseRepository struct {
+	db *gorm.DB
+}
+
+func (r *reviewCaseRepository) Create(reviewCase *ReviewCase) error {
+	return r.db.Create(reviewCase).Error
+}
+
+func (r *reviewCaseRepository) GetByID(id string) (*ReviewCase, error) {
+	var reviewCase ReviewCase
+	err := r.db.First(&reviewCase, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reviewCase, nil
+}
+
+// ListByStatus lists cases in a status, oldest first so the queue is worked in order
+func (r *reviewCaseRepository) ListByStatus(status string) ([]*ReviewCase, error) {
+	var cases []*ReviewCase
+	err := r.db.Where("status = ?", status).Order("created_at ASC").Find(&cases).Error
+	return cases, err
+}
+
+func (r *reviewCaseRepository) ListByTenantID(tenantID, status string) ([]*ReviewCase, error) {
+	var cases []*ReviewCase
+	err := r.db.Where("status = ? AND agent_id IN (?)", status, tenantAgents(r.db, tenantID)).Order("created_at ASC").Find(&cases).Error
+	return cases, err
+}
+
+func (r *reviewCaseRepository) ListByAgentID(agentID string) ([]*ReviewCase, error) {
+	var cases []*ReviewCase
+	err := r.db.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&cases).Error
+	return cases, err
+}
+
+func (r *reviewCaseRepository) Update(reviewCase *ReviewCase) error {
+	return r.db.Save(reviewCase).Error
+}
+
+// approvalRepository implements ApprovalRepository
+type approvalRepository struct {
+	db *gorm.DB
+}
+
+func (r *approvalRepository) Create(approval *Approval) error {
+	return r.db.Create(approval).Error
+}
+
+func (r *approvalRepository) GetByID(id string) (*Approval, error) {
+	var approval Approval
+	err := r.db.First(&approval, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *approvalRepository) GetByWorkflowID(workflowID string) (*Approval, error) {
+	var approval Approval
+	err := r.db.First(&approval, "workflow_id = ?", workflowID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// ListByOwnerPartyID lists a party's approvals, oldest first; an empty status lists all
+// ListByConsentID lists a consent's approvals, optionally in one status, oldest first
+func (r *approvalRepository) ListByConsentID(consentID, status string) ([]*Approval, error) {
+	var approvals []*Approval
+	query := r.db.Where("consent_id = ?", consentID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at ASC").Find(&approvals).Error
+	return approvals, err
+}
+
+func (r *approvalRepository) ListByOwnerPartyID(ownerPartyID, status string) ([]*Approval, error) {
+	var approvals []*Approval
+	query := r.db.Where("owner_party_id = ?", ownerPartyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at ASC").Find(&approvals).Error
+	return approvals, err
+}
+
+func (r *approvalRepository) Update(approval *Approval) error {
+	return r.db.Save(approval).Error
+}
+
+// workflowTransitionRepository implements WorkflowTransitionRepository
+type workflowTransitionRepository struct {
+	db *gorm.DB
+}
+
+func (r *workflowTransitionRepository) Create(transition *WorkflowTransition) error {
+	return r.db.Create(transition).Error
+}
+
+// ListByWorkflowID lists a workflow's transitions in the order they happened
+func (r *workflowTransitionRepository) ListByWorkflowID(workflowID string) ([]*WorkflowTransition, error) {
+	var transitions []*WorkflowTransition
+	err := r.db.Where("workflow_id = ?", workflowID).Order("created_at ASC").Find(&transitions).Error
+	return transitions, err
+}
+
+// holdRepository implements HoldRepository
+type holdRepository struct {
+	db *gorm.DB
+}
+
+func (r *holdRepository) Create(hold *Hold) error {
+	return r.db.Create(hold).Error
+}
+
+func (r *holdRepository) GetByID(id string) (*Hold, error) {
+	var hold Hold
+	err := r.db.First(&hold, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ListByAccountID lists an account's holds, optionally in one status, newest first
+func (r *holdRepository) ListByAccountID(accountID, status string) ([]*Hold, error) {
+	var holds []*Hold
+	query := r.db.Where("account_id = ?", accountID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&holds).Error
+	return holds, err
+}
+
+func (r *holdRepository) ListByReferenceID(referenceID string) ([]*Hold, error) {
+	var holds []*Hold
+	err := r.db.Where("reference_id = ?", referenceID).Order("created_at ASC").Find(&holds).Error
+	return holds, err
+}
+
+// ListExpired lists active holds past their expiry
+func (r *holdRepository) ListExpired(now time.Time) ([]*Hold, error) {
+	var holds []*Hold
+	err := r.db.Where("status = ? AND expires_at < ?", "active", now).Find(&holds).Error
+	return holds, err
+}
+
+// ActiveTotal sums the account's active holds
+func (r *holdRepository) ActiveTotal(accountID string) (float64, error) {
+	var total float64
+	err := r.db.Model(&Hold{}).Where("account_id = ? AND status = ?", accountID, "active").
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *holdRepository) Update(hold *Hold) error {
+	return r.db.Save(hold).Error
+}
+
+// Resolve moves an active hold to a final status, saving its other changes,
+// and reports false if the hold was no longer active
+func (r *holdRepository) Resolve(hold *Hold, status string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&Hold{}).Where("id = ? AND status = ?", hold.ID, "active").Updates(map[string]interface{}{
+		"status":          status,
+		"captured_amount": hold.CapturedAmount,
+		"transaction_id":  hold.TransactionID,
+		"resolved_at":     now,
+		"updated_at":      now,
+	})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false, result.Error
+	}
+	hold.Status = status
+	hold.ResolvedAt = &now
+	return true, nil
+}
+
+// autoDeclineRuleRepository implements AutoDeclineRuleRepository
+type autoDeclineRuleRepository struct {
+	db *gorm.DB
+}
+
+func (r *autoDeclineRuleRepository) Create(rule *AutoDeclineRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *autoDeclineRuleRepository) GetByID(id string) (*AutoDeclineRule, error) {
+	var rule AutoDeclineRule
+	err := r.db.First(&rule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *autoDeclineRuleRepository) ListByOwnerPartyID(ownerPartyID string) ([]*AutoDeclineRule, error) {
+	var rules []*AutoDeclineRule
+	err := r.db.Where("owner_party_id = ?", ownerPartyID).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *autoDeclineRuleRepository) ListEnabledForAgent(ownerPartyID, agentID string) ([]*AutoDeclineRule, error) {
+	var rules []*AutoDeclineRule
+	err := r.db.Where("owner_party_id = ? AND enabled = ? AND (agent_id IS NULL OR agent_id = ?)", ownerPartyID, true, agentID).
+		Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *autoDeclineRuleRepository) Update(rule *AutoDeclineRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *autoDeclineRuleRepository) Delete(id string) error {
+	return r.db.Delete(&AutoDeclineRule{}, "id = ?", id).Error
+}
+
+// balanceSnapshotRepository implements BalanceSnapshotRepository
+type balanceSnapshotRepository struct {
+	db *gorm.DB
+}
+
+func (r *balanceSnapshotRepository) Create(snapshot *BalanceSnapshot) error {
+	return r.db.Create(snapshot).Error
+}
+
+func (r *balanceSnapshotRepository) Latest(accountID string, asOf time.Time) (*BalanceSnapshot, error) {
+	return latestBalanceSnapshot(r.db, accountID, asOf)
+}
+
+// feeScheduleRepository implements FeeScheduleRepository
+type feeScheduleRepository struct {
+	db *gorm.DB
+}
+
+func (r *feeScheduleRepository) Create(schedule *FeeSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *feeScheduleRepository) GetByID(id string) (*FeeSchedule, error) {
+	var schedule FeeSchedule
+	err := r.db.First(&schedule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *feeScheduleRepository) List() ([]*FeeSchedule, error) {
+	var schedules []*FeeSchedule
+	err := r.db.Order("created_at ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *feeScheduleRepository) ListByPartyID(partyID string) ([]*FeeSchedule, error) {
+	var schedules []*FeeSchedule
+	err := r.db.Where("party_id = ?", partyID).Order("created_at ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *feeScheduleRepository) Update(schedule *FeeSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+func (r *feeScheduleRepository) Delete(id string) error {
+	return r.db.Delete(&FeeSchedule{}, "id = ?", id).Error
+}
+
+// feeChargeRepository implements FeeChargeRepository
+type feeChargeRepository struct {
+	db *gorm.DB
+}
+
+func (r *feeChargeRepository) Create(charge *FeeCharge) error {
+	return r.db.Create(charge).Error
+}
+
+func (r *feeChargeRepository) GetByWorkflowID(workflowID string) (*FeeCharge, error) {
+	var charge FeeCharge
+	err := r.db.First(&charge, "workflow_id = ?", workflowID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (r *feeChargeRepository) Update(charge *FeeCharge) error {
+	return r.db.Save(charge).Error
+}
+
+func (r *feeChargeRepository) Revenue(partyID string, since, until time.Time) ([]*FeeRevenue, error) {
+	var revenue []*FeeRevenue
+	query := r.db.Model(&FeeCharge{}).
+		Where("status = ? AND created_at >= ? AND created_at < ?", "charged", since, until)
+	if partyID != "" {
+		query = query.Where("party_id = ?", partyID)
+	}
+	err := query.Select("party_id, rail, COUNT(*) AS payments, SUM(amount_usd) AS amount_usd, " +
+		"SUM(rail_fee_usd) AS rail_fee_usd, SUM(markup_usd) AS markup_usd, SUM(total_fee_usd) AS total_fee_usd").
+		Group("party_id, rail").Order("party_id, rail").Scan(&revenue).Error
+	return revenue, err
+}
+
+// feeExperimentRepository implements FeeExperimentRepository
+type feeExperimentRepository struct {
+	db *gorm.DB
+}
+
+func (r *feeExperimentRepository) Create(experiment *FeeExperiment) error {
+	return r.db.Create(experiment).Error
+}
+
+func (r *feeExperimentRepository) GetByID(id string) (*FeeExperiment, error) {
+	var experiment FeeExperiment
+	err := r.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC, name ASC")
+	}).First(&experiment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+func (r *feeExperimentRepository) List() ([]*FeeExperiment, error) {
+	var experiments []*FeeExperiment
+	err := r.db.Preload("Variants").Order("created_at DESC").Find(&experiments).Error
+	return experiments, err
+}
+
+func (r *feeExperimentRepository) ListActive() ([]*FeeExperiment, error) {
+	var experiments []*FeeExperiment
+	err := r.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC, name ASC")
+	}).Where("status = ?", "active").Order("created_at ASC").Find(&experiments).Error
+	return experiments, err
+}
+
+func (r *feeExperimentRepository) Update(experiment *FeeExperiment) error {
+	return r.db.Omit("Variants").Save(experiment).Error
+}
+
+func (r *feeExperimentRepository) GetVariant(id string) (*FeeExperimentVariant, error) {
+	var variant FeeExperimentVariant
+	err := r.db.First(&variant, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *feeExperimentRepository) CountQuote(variantID string) error {
+	return r.db.Model(&FeeExperimentVariant{}).Where("id = ?", variantID).
+		UpdateColumn("quotes", gorm.Expr("quotes + 1")).Error
+}
+
+func (r *feeExperimentRepository) GetAssignment(experimentID, subjectID string) (*FeeExperimentAssignment, error) {
+	var assignment FeeExperimentAssignment
+	err := r.db.First(&assignment, "experiment_id = ? AND subject_id = ?", experimentID, subjectID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *feeExperimentRepository) Assign(assignment *FeeExperimentAssignment) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "experiment_id"}, {Name: "subject_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject_type", "variant_id", "updated_at"}),
+	}).Create(assignment).Error
+}
+
+func (r *feeExperimentRepository) CountAssignments(experimentID string) (map[string]int, error) {
+	var rows []struct {
+		VariantID string
+		Subjects  int
+	}
+	err := r.db.Model(&FeeExperimentAssignment{}).Where("experiment_id = ?", experimentID).
+		Select("variant_id, COUNT(*) AS subjects").Group("variant_id").Scan(&rows).Error
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.VariantID] = row.Subjects
+	}
+	return counts, err
+}
+
+func (r *feeExperimentRepository) Results(experimentID string) ([]*FeeVariantResult, error) {
+	var results []*FeeVariantResult
+	err := r.db.Model(&PaymentWorkflow{}).
+		Joins("LEFT JOIN fee_charges ON fee_charges.workflow_id = payment_workflows.id AND fee_charges.status = ?", "charged").
+		Where("payment_workflows.fee_experiment_id = ?", experimentID).
+		Select("payment_workflows.fee_variant_id AS variant_id, COUNT(*) AS payments, " +
+			"SUM(CASE WHEN payment_workflows.status = 'completed' THEN 1 ELSE 0 END) AS completed_payments, " +
+			"COALESCE(SUM(CASE WHEN payment_workflows.status = 'completed' THEN payment_workflows.amount_usd ELSE 0 END), 0) AS volume_usd, " +
+			"COALESCE(SUM(fee_charges.markup_usd), 0) AS markup_usd").
+		Group("payment_workflows.fee_variant_id").Scan(&results).Error
+	return results, err
+}
+
+// auditAnchorRepository implements AuditAnchorRepository
+type auditAnchorRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditAnchorRepository) Create(anchor *AuditAnchor, entries []*AuditEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(anchor).Error; err != nil {
+			return err
+		}
+		for i, entry := range entries {
+			result := tx.Model(&AuditEntry{}).Where("id = ? AND anchor_id IS NULL", entry.ID).
+				UpdateColumns(map[string]interface{}{"anchor_id": anchor.ID, "anchor_index": i})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return fmt.Errorf("audit entry %s is already anchored", entry.ID)
+			}
+			entry.AnchorID, entry.AnchorIndex = &anchor.ID, i
+		}
+		return nil
+	})
+}
+
+func (r *auditAnchorRepository) GetByID(id string) (*AuditAnchor, error) {
+	var anchor AuditAnchor
+	err := r.db.First(&anchor, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
+
+func (r *auditAnchorRepository) List(limit int) ([]*AuditAnchor, error) {
+	var anchors []*AuditAnchor
+	err := r.db.Order("created_at DESC").Limit(limit).Find(&anchors).Error
+	return anchors, err
+}
+
+func (r *auditAnchorRepository) ListEntries(anchorID string) ([]*AuditEntry, error) {
+	var entries []*AuditEntry
+	err := r.db.Where("anchor_id = ?", anchorID).Order("anchor_index ASC").Find(&entries).Error
+	return entries, err
+}
+
+// migrationCheckpointRepository implements MigrationCheckpointRepository
+type migrationCheckpointRepository struct {
+	db *gorm.DB
+}
+
+func (r *migrationCheckpointRepository) GetByName(name string) (*MigrationCheckpoint, error) {
+	var checkpoint MigrationCheckpoint
+	err := r.db.First(&checkpoint, "name = ?", name).Error
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (r *migrationCheckpointRepository) List() ([]*MigrationCheckpoint, error) {
+	var checkpoints []*MigrationCheckpoint
+	err := r.db.Order("name ASC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+func (r *migrationCheckpointRepository) Save(checkpoint *MigrationCheckpoint) error {
+	return r.db.Save(checkpoint).Error
+}
+
+// adapterCallLogRepository implements AdapterCallLogRepository
+type adapterCallLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *adapterCallLogRepository) Create(call *AdapterCallLog) error {
+	return r.db.Create(call).Error
+}
+
+func (r *adapterCallLogRepository) ListByExecutionID(executionID string) ([]*AdapterCallLog, error) {
+	var calls []*AdapterCallLog
+	err := r.db.Where("execution_id = ?", executionID).Order("created_at ASC").Find(&calls).Error
+	return calls, err
+}
+
+func (r *adapterCallLogRepository) StatsSince(since time.Time) ([]*AdapterCallStats, error) {
+	var stats []*AdapterCallStats
+	err := r.db.Model(&AdapterCallLog{}).
+		Where("created_at >= ?", since).
+		Select("rail, COUNT(*) AS calls, SUM(CASE WHEN outcome = 'error' THEN 1 ELSE 0 END) AS errors, " +
+			"AVG(latency_ms) AS avg_latency_ms, MAX(latency_ms) AS max_latency_ms").
+		Group("rail").Order("rail").Scan(&stats).Error
+	return stats, err
+}
+
+// postingRuleRepository implements PostingRuleRepository
+type postingRuleRepository struct {
+	db *gorm.DB
+}
+
+func (r *postingRuleRepository) Create(rule *PostingRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *postingRuleRepository) GetByID(id string) (*PostingRule, error) {
+	var rule PostingRule
+	err := r.db.First(&rule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *postingRuleRepository) ListByAgentID(agentID string) ([]*PostingRule, error) {
+	var rules []*PostingRule
+	err := r.db.Where("agent_id = ?", agentID).Order("target_book ASC, created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *postingRuleRepository) Delete(id string) error {
+	return r.db.Delete(&PostingRule{}, "id = ?", id).Error
+}
+
+// statementTokenRepository implements StatementTokenRepository
+type statementTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *statementTokenRepository) Create(token *StatementToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *statementTokenRepository) GetByID(id string) (*StatementToken, error) {
+	var token StatementToken
+	err := r.db.First(&token, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *statementTokenRepository) GetByTokenHash(tokenHash string) (*StatementToken, error) {
+	var token StatementToken
+	err := r.db.First(&token, "token_hash = ?", tokenHash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByPartyID lists a party's statement tokens, newest first, optionally
+// only those of one agent
+func (r *statementTokenRepository) ListByPartyID(partyID, agentID string) ([]*StatementToken, error) {
+	var tokens []*StatementToken
+	query := r.db.Where("party_id = ?", partyID)
+	if agentID != "" {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	err := query.Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *statementTokenRepository) Update(token *StatementToken) error {
+	return r.db.Save(token).Error
+}
+
+// paymentCallbackRepository implements PaymentCallbackRepository
+type paymentCallbackRepository struct {
+	db *gorm.DB
+}
+
+func (r *paymentCallbackRepository) Create(callback *PaymentCallback) error {
+	return r.db.Create(callback).Error
+}
+
+func (r *paymentCallbackRepository) GetByID(id string) (*PaymentCallback, error) {
+	var callback PaymentCallback
+	err := r.db.First(&callback, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &callback, nil
+}
+
+func (r *paymentCallbackRepository) ListByWorkflowID(workflowID string) ([]*PaymentCallback, error) {
+	var callbacks []*PaymentCallback
+	err := r.db.Where("workflow_id = ?", workflowID).Order("created_at ASC").Find(&callbacks).Error
+	return callbacks, err
+}
+
+// ListDue lists pending callbacks whose next attempt is due, oldest first
+func (r *paymentCallbackRepository) ListDue(now time.Time, limit int) ([]*PaymentCallback, error) {
+	var callbacks []*PaymentCallback
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&callbacks).Error
+	return callbacks, err
+}
+
+func (r *paymentCallbackRepository) Update(callback *PaymentCallback) error {
+	return r.db.Save(callback).Error
+}
+
+// partySpendingLimitRepository implements PartySpendingLimitRepository
+type partySpendingLimitRepository struct {
+	db *gorm.DB
+}
+
+func (r *partySpendingLimitRepository) Create(limit *PartySpendingLimit) error {
+	return r.db.Create(limit).Error
+}
+
+func (r *partySpendingLimitRepository) GetByPartyID(partyID, period string) (*PartySpendingLimit, error) {
+	var limit PartySpendingLimit
+	err := r.db.First(&limit, "party_id = ? AND period = ?", partyID, period).Error
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+func (r *partySpendingLimitRepository) ListByPartyID(partyID string) ([]*PartySpendingLimit, error) {
+	var limits []*PartySpendingLimit
+	err := r.db.Where("party_id = ?", partyID).Order("created_at ASC").Find(&limits).Error
+	return limits, err
+}
+
+func (r *partySpendingLimitRepository) Update(limit *PartySpendingLimit) error {
+	return r.db.Save(limit).Error
+}
+
+func (r *partySpendingLimitRepository) Delete(id string) error {
+	return r.db.Delete(&PartySpendingLimit{}, "id = ?", id).Error
+}
+
+// riskPolicyRepository implements RiskPolicyRepository
+type riskPolicyRepository struct {
+	db *gorm.DB
+}
+
+func (r *riskPolicyRepository) Create(policy *RiskPolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&RiskPolicy{}).Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		policy.Version = last + 1
+		return tx.Create(policy).Error
+	})
+}
+
+func (r *riskPolicyRepository) GetByVersion(version int) (*RiskPolicy, error) {
+	var policy RiskPolicy
+	err := r.db.First(&policy, "version = ?", version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *riskPolicyRepository) GetActive() (*RiskPolicy, error) {
+	var policies []*RiskPolicy
+	err := r.db.Where("active = ?", true).Order("version DESC").Limit(1).Find(&policies).Error
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+	return policies[0], nil
+}
+
+func (r *riskPolicyRepository) List() ([]*RiskPolicy, error) {
+	var policies []*RiskPolicy
+	err := r.db.Order("version DESC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *riskPolicyRepository) Activate(version int) (*RiskPolicy, error) {
+	var policy RiskPolicy
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&policy, "version = ?", version).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&RiskPolicy{}).Where("active = ? AND version <> ?", true, version).Update("active", false).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		policy.Active = true
+		policy.ActivatedAt = &now
+		return tx.Save(&policy).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// agentRiskProfileRepository implements AgentRiskProfileRepository
+type agentRiskProfileRepository struct {
+	db *gorm.DB
+}
+
+func (r *agentRiskProfileRepository) Create(profile *AgentRiskProfile) error {
+	return r.db.Create(profile).Error
+}
+
+func (r *agentRiskProfileRepository) GetByAgentID(agentID string) (*AgentRiskProfile, error) {
+	var profile AgentRiskProfile
+	err := r.db.First(&profile, "agent_id = ?", agentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *agentRiskProfileRepository) List(tier string) ([]*AgentRiskProfile, error) {
+	query := r.db.Order("agent_id")
+	if tier != "" {
+		query = query.Where("trust_tier = ?", tier)
+	}
+	var profiles []*AgentRiskProfile
+	err := query.Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *agentRiskProfileRepository) ListByTenantID(tenantID, tier string) ([]*AgentRiskProfile, error) {
+	query := r.db.Where("agent_id IN (?)", tenantAgents(r.db, tenantID)).Order("agent_id")
+	if tier != "" {
+		query = query.Where("trust_tier = ?", tier)
+	}
+	var profiles []*AgentRiskProfile
+	err := query.Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *agentRiskProfileRepository) Update(profile *AgentRiskProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// counterpartyRepository implements CounterpartyRepository
+type counterpartyRepository struct {
+	db *gorm.DB
+}
+
+func (r *counterpartyRepository) Create(counterparty *Counterparty) error {
+	return r.db.Create(counterparty).Error
+}
+
+func (r *counterpartyRepository) GetByID(id string) (*Counterparty, error) {
+	var counterparty Counterparty
+	err := r.db.First(&counterparty, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &counterparty, nil
+}
+
+func (r *counterpartyRepository) GetByName(partyID, name string) (*Counterparty, error) {
+	var counterparty Counterparty
+	err := r.db.First(&counterparty, "party_id = ? AND LOWER(name) = LOWER(?)", partyID, name).Error
+	if err != nil {
+		return nil, err
+	}
+	return &counterparty, nil
+}
+
+func (r *counterpartyRepository) ListByPartyID(partyID string, filter CounterpartyFilter) ([]*Counterparty, error) {
+	query := r.db.Where("party_id = ?", partyID)
+	if filter.KYCStatus != "" {
+		query = query.Where("kyc_status = ?", filter.KYCStatus)
+	}
+	if filter.ListStatus != "" {
+		query = query.Where("list_status = ?", filter.ListStatus)
+	}
+	var counterparties []*Counterparty
+	err := query.Order("name").Find(&counterparties).Error
+	return counterparties, err
+}
+
+func (r *counterpartyRepository) Update(counterparty *Counterparty) error {
+	return r.db.Save(counterparty).Error
+}
+
+func (r *counterpartyRepository) Delete(id string) error {
+	return r.db.Delete(&Counterparty{}, "id = ?", id).Error
+}
+
+// workflowTemplateRepository implements WorkflowTemplateRepository
+type workflowTemplateRepository struct {
+	db *gorm.DB
+}
+
+func (r *workflowTemplateRepository) Create(template *WorkflowTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&WorkflowTemplate{}).Where("party_id = ?", template.PartyID).Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		template.Version = last + 1
+		return tx.Create(template).Error
+	})
+}
+
+func (r *workflowTemplateRepository) GetByID(id string) (*WorkflowTemplate, error) {
+	var template WorkflowTemplate
+	err := r.db.First(&template, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *workflowTemplateRepository) GetActive(partyID string) (*WorkflowTemplate, error) {
+	var templates []*WorkflowTemplate
+	err := r.db.Where("party_id = ? AND active = ?", partyID, true).Order("version DESC").Limit(1).Find(&templates).Error
+	if err != nil || len(templates) == 0 {
+		return nil, err
+	}
+	return templates[0], nil
+}
+
+func (r *workflowTemplateRepository) ListByPartyID(partyID string) ([]*WorkflowTemplate, error) {
+	var templates []*WorkflowTemplate
+	err := r.db.Where("party_id = ?", partyID).Order("version DESC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *workflowTemplateRepository) Activate(id string) (*WorkflowTemplate, error) {
+	var template WorkflowTemplate
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&template, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&WorkflowTemplate{}).Where("party_id = ? AND active = ? AND id <> ?", template.PartyID, true, id).Update("active", false).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		template.Active = true
+		template.ActivatedAt = &now
+		return tx.Save(&template).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *workflowTemplateRepository) Deactivate(id string) (*WorkflowTemplate, error) {
+	var template WorkflowTemplate
+	if err := r.db.First(&template, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	template.Active = false
+	return &template, r.db.Save(&template).Error
+}
+
+// paymentScheduleRepository implements PaymentScheduleRepository
+type paymentScheduleRepository struct {
+	db *gorm.DB
+}
+
+func (r *paymentScheduleRepository) Create(schedule *PaymentSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *paymentScheduleRepository) GetByID(id string) (*PaymentSchedule, error) {
+	var schedule PaymentSchedule
+	err := r.db.First(&schedule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *paymentScheduleRepository) List(agentID, status string) ([]*PaymentSchedule, error) {
+	var schedules []*PaymentSchedule
+	query := whereSet(r.db.Model(&PaymentSchedule{}), map[string]string{"agent_id": agentID, "status": status})
+	err := query.Order("created_at DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *paymentScheduleRepository) ListByAgentIDs(agentIDs []string, status string) ([]*PaymentSchedule, error) {
+	var schedules []*PaymentSchedule
+	query := whereSet(r.db.Where("agent_id IN ?", agentIDs), map[string]string{"status": status})
+	err := query.Order("created_at DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *paymentScheduleRepository) ListByTenantID(tenantID, status string) ([]*PaymentSchedule, error) {
+	var schedules []*PaymentSchedule
+	query := whereSet(r.db.Where("agent_id IN (?)", tenantAgents(r.db, tenantID)), map[string]string{"status": status})
+	err := query.Order("created_at DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *paymentScheduleRepository) ListDue(now time.Time, limit int) ([]*PaymentSchedule, error) {
+	var schedules []*PaymentSchedule
+	err := r.db.Where("status = ? AND next_run_at <= ?", "active", now).Order("next_run_at").Limit(limit).Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *paymentScheduleRepository) Claim(id string, seenNextRunAt time.Time, nextRunAt *time.Time) (bool, error) {
+	result := r.db.Model(&PaymentSchedule{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", id, "active", seenNextRunAt).
+		UpdateColumn("next_run_at", nextRunAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *paymentScheduleRepository) Update(schedule *PaymentSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+func (r *paymentScheduleRepository) CreateRun(run *PaymentScheduleRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *paymentScheduleRepository) ListRuns(scheduleID string, limit int) ([]*PaymentScheduleRun, error) {
+	var runs []*PaymentScheduleRun
+	err := r.db.Where("schedule_id = ?", scheduleID).Order("scheduled_for DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// paymentBatchRepository implements PaymentBatchRepository
+type paymentBatchRepository struct {
+	db *gorm.DB
+}
+
+// errItemNotPending rolls back the removal of an item already submitted
+var errItemNotPending = errors.New("batch item is not pending")
+
+func (r *paymentBatchRepository) AddItem(rail string, cutoffAt time.Time, referencePrefix string, item *PaymentBatchItem) (*PaymentBatch, error) {
+	var batch PaymentBatch
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("rail = ? AND cutoff_at = ? AND status = ?", rail, cutoffAt, "open").First(&batch).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Batches of the window closed early are followed by another
+			var count int64
+			if err := tx.Model(&PaymentBatch{}).Where("rail = ? AND cutoff_at = ?", rail, cutoffAt).Count(&count).Error; err != nil {
+				return err
+			}
+			batch = PaymentBatch{
+				Rail:      rail,
+				Reference: fmt.Sprintf("%s-%d", referencePrefix, count+1),
+				CutoffAt:  cutoffAt,
+				Status:    "open",
+			}
+			err = tx.Create(&batch).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// The batch may have been closed since it was read
+		result := tx.Model(&PaymentBatch{}).Where("id = ? AND status = ?", batch.ID, "open").Updates(map[string]interface{}{
+			"item_count": gorm.Expr("item_count + 1"),
+			"total_usd":  gorm.Expr("total_usd + ?", item.AmountUSD),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("batch %s was closed", batch.Reference)
+		}
+		item.BatchID = batch.ID
+		item.Status = "pending"
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return tx.First(&batch, "id = ?", batch.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *paymentBatchRepository) GetByID(id string) (*PaymentBatch, error) {
+	var batch PaymentBatch
+	err := r.db.First(&batch, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *paymentBatchRepository) List(rail, status string, limit int) ([]*PaymentBatch, error) {
+	var batches []*PaymentBatch
+	query := whereSet(r.db.Model(&PaymentBatch{}), map[string]string{"rail": rail, "status": status})
+	err := query.Order("cutoff_at DESC, created_at DESC").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+func (r *paymentBatchRepository) ListDue(now time.Time) ([]*PaymentBatch, error) {
+	var batches []*PaymentBatch
+	err := r.db.Where("status = ? AND cutoff_at <= ?", "open", now).Order("cutoff_at").Find(&batches).Error
+	return batches, err
+}
+
+func (r *paymentBatchRepository) Transition(batch *PaymentBatch, from, to string) (bool, error) {
+	batch.Status = to
+	result := r.db.Model(&PaymentBatch{}).Where("id = ? AND status = ?", batch.ID, from).Updates(map[string]interface{}{
+		"status":       to,
+		"closed_by":    batch.ClosedBy,
+		"submitted_by": batch.SubmittedBy,
+		"closed_at":    batch.ClosedAt,
+		"submitted_at": batch.SubmittedAt,
+		"completed_at": batch.CompletedAt,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		batch.Status = from
+		return false, result.Error
+	}
+	return true, r.db.First(batch, "id = ?", batch.ID).Error
+}
+
+func (r *paymentBatchRepository) AddLedgerTransactions(id string, transactionIDs []string) error {
+	data, err := json.Marshal(transactionIDs)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&PaymentBatch{}).Where("id = ?", id).
+		UpdateColumn("ledger_transaction_ids", gorm.Expr("COALESCE(ledger_transaction_ids, '[]'::jsonb) || ?::jsonb", string(data))).Error
+}
+
+func (r *paymentBatchRepository) ListItems(batchID string) ([]*PaymentBatchItem, error) {
+	var items []*PaymentBatchItem
+	err := r.db.Where("batch_id = ?", batchID).Order("created_at").Find(&items).Error
+	return items, err
+}
+
+func (r *paymentBatchRepository) GetItemByExecutionID(executionID string) (*PaymentBatchItem, error) {
+	var item PaymentBatchItem
+	err := r.db.First(&item, "execution_id = ?", executionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *paymentBatchRepository) UpdateItem(item *PaymentBatchItem) error {
+	return r.db.Save(item).Error
+}
+
+func (r *paymentBatchRepository) RemoveItem(item *PaymentBatchItem) (bool, error) {
+	removed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PaymentBatch{}).Where("id = ? AND status IN ?", item.BatchID, []string{"open", "closed"}).Updates(map[string]interface{}{
+			"item_count": gorm.Expr("item_count - 1"),
+			"total_usd":  gorm.Expr("total_usd - ?", item.AmountUSD),
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		result = tx.Model(&PaymentBatchItem{}).Where("id = ? AND status = ?", item.ID, "pending").Update("status", "removed")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errItemNotPending
+		}
+		removed = true
+		item.Status = "removed"
+		return nil
+	})
+	if errors.Is(err, errItemNotPending) {
+		return false, nil
+	}
+	return removed, err
+}
+
+func (r *paymentBatchRepository) FinishItem(item *PaymentBatchItem) (bool, error) {
+	finished := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PaymentBatchItem{}).Where("id = ? AND status IN ?", item.ID, []string{"pending", "submitted"}).Updates(map[string]interface{}{
+			"status":        item.Status,
+			"reference_id":  item.ReferenceID,
+			"error_message": item.ErrorMessage,
+			"submitted_at":  item.SubmittedAt,
+			"completed_at":  item.CompletedAt,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		counts := map[string]interface{}{"failed_count": gorm.Expr("failed_count + 1")}
+		if item.Status == "completed" {
+			counts = map[string]interface{}{
+				"settled_count": gorm.Expr("settled_count + 1"),
+				"settled_usd":   gorm.Expr("settled_usd + ?", item.AmountUSD),
+			}
+		}
+		finished = true
+		return tx.Model(&PaymentBatch{}).Where("id = ?", item.BatchID).Updates(counts).Error
+	})
+	return finished, err
+}
+
+// budgetRepository implements BudgetRepository
+type budgetRepository struct {
+	db *gorm.DB
+}
+
+func (r *budgetRepository) Create(budget *Budget) error {
+	return r.db.Create(budget).Error
+}
+
+func (r *budgetRepository) GetByID(id string) (*Budget, error) {
+	var budget Budget
+	err := r.db.First(&budget, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *budgetRepository) ListByAgentID(agentID string) ([]*Budget, error) {
+	var budgets []*Budget
+	err := r.db.Where("agent_id = ?", agentID).Order("category, period").Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) ListByAgentIDs(agentIDs []string) ([]*Budget, error) {
+	var budgets []*Budget
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("agent_id, category, period").Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) ListDue(now time.Time, limit int) ([]*Budget, error) {
+	var budgets []*Budget
+	err := r.db.Where("active = ? AND period_end <= ?", true, now).Order("period_end").Limit(limit).Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) Update(budget *Budget) error {
+	return r.db.Save(budget).Error
+}
+
+func (r *budgetRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("budget_id = ?", id).Delete(&BudgetPeriod{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Budget{}, "id = ?", id).Error
+	})
+}
+
+func (r *budgetRepository) Rollover(budget *Budget, previousStart time.Time, closed []*BudgetPeriod) (bool, error) {
+	rolled := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Budget{}).Where("id = ? AND period_start = ?", budget.ID, previousStart).Updates(map[string]interface{}{
+			"period_start":     budget.PeriodStart,
+			"period_end":       budget.PeriodEnd,
+			"carried_over_usd": budget.CarriedOverUSD,
+			"updated_at":       time.Now(),
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		for _, period := range closed {
+			if err := tx.Create(period).Error; err != nil {
+				return err
+			}
+		}
+		rolled = true
+		return nil
+	})
+	return rolled, err
+}
+
+func (r *budgetRepository) ListPeriods(budgetID string, limit int) ([]*BudgetPeriod, error) {
+	var periods []*BudgetPeriod
+	err := r.db.Where("budget_id = ?", budgetID).Order("period_start DESC").Limit(limit).Find(&periods).Error
+	return periods, err
+}
+
+// kycSubmissionRepository implements KYCSubmissionRepository
+type kycSubmissionRepository struct {
+	db *gorm.DB
+}
+
+func (r *kycSubmissionRepository) Submit(submission *KYCSubmission, partyStatus string) (bool, error) {
+	submitted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if updated, err := setPartyKYCStatus(tx, submission.PartyID, partyStatus, submission); err != nil || !updated {
+			return err
+		}
+		if err := tx.Create(submission).Error; err != nil {
+			return err
+		}
+		submitted = true
+		return nil
+	})
+	return submitted, err
+}
+
+func (r *kycSubmissionRepository) GetByID(id string) (*KYCSubmission, error) {
+	var submission KYCSubmission
+	err := r.db.First(&submission, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *kycSubmissionRepository) ListByPartyID(partyID string) ([]*KYCSubmission, error) {
+	var submissions []*KYCSubmission
+	err := r.db.Where("party_id = ?", partyID).Order("created_at DESC").Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *kycSubmissionRepository) ListByStatus(status string, limit int) ([]*KYCSubmission, error) {
+	var submissions []*KYCSubmission
+	err := r.db.Where("status = ?", status).Order("created_at").Limit(limit).Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *kycSubmissionRepository) ListByTenantID(tenantID, status string, limit int) ([]*KYCSubmission, error) {
+	var submissions []*KYCSubmission
+	err := r.db.Where("status = ? AND party_id IN (?)", status, tenantParties(r.db, tenantID)).Order("created_at").Limit(limit).Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *kycSubmissionRepository) Decide(submission *KYCSubmission) (bool, error) {
+	decided := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&KYCSubmission{}).Where("id = ? AND status = ?", submission.ID, "pending").Updates(map[string]interface{}{
+			"status":      submission.Status,
+			"reason":      submission.Reason,
+			"reviewed_by": submission.ReviewedBy,
+			"decided_at":  submission.DecidedAt,
+			"updated_at":  time.Now(),
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if updated, err := setPartyKYCStatus(tx, submission.PartyID, "pending", submission); err != nil || !updated {
+			if err == nil {
+				err = fmt.Errorf("party %s is no longer pending verification", submission.PartyID)
+			}
+			return err
+		}
+		decided = true
+		return nil
+	})
+	return decided, err
+}
+
+// setPartyKYCStatus gives a party the status of its submission, if the
+// party's status is still from
+func setPartyKYCStatus(tx *gorm.DB, partyID, from string, submission *KYCSubmission) (bool, error) {
+	updates := map[string]interface{}{"kyc_status": submission.Status, "updated_at": time.Now()}
+	if submission.Status == "verified" {
+		updates["kyc_verified_at"] = submission.DecidedAt
+	}
+	result := tx.Model(&Party{}).Where("id = ? AND kyc_status = ?", partyID, from).Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// didChallengeRepository implements DIDChallengeRepository
+type didChallengeRepository struct {
+	db *gorm.DB
+}
+
+func (r *didChallengeRepository) Create(challenge *DIDChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+func (r *didChallengeRepository) GetByID(id string) (*DIDChallenge, error) {
+	var challenge DIDChallenge
+	err := r.db.First(&challenge, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *didChallengeRepository) Answer(challenge *DIDChallenge) (bool, error) {
+	result := r.db.Model(&DIDChallenge{}).Where("id = ? AND status = ?", challenge.ID, "pending").Updates(map[string]interface{}{
+		"status":      challenge.Status,
+		"key_id":      challenge.KeyID,
+		"reason":      challenge.Reason,
+		"answered_at": challenge.AnsweredAt,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// userRepository implements UserRepository
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) Create(user *User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *userRepository) GetByID(id string) (*User, error) {
+	var user User
+	err := r.db.First(&user, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmail(email string) (*User, error) {
+	var user User
+	err := r.db.First(&user, "email = ?", email).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) ListByPartyID(partyID string) ([]*User, error) {
+	var users []*User
+	err := r.db.Where("party_id = ?", partyID).Order("created_at").Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) Update(user *User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *userRepository) AssignRole(assignment *UserRole) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(assignment)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) RevokeRole(userID, roleID string) (bool, error) {
+	result := r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&UserRole{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) RoleNames(userID string) ([]string, error) {
+	var names []string
+	err := r.db.Model(&UserRole{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &names).Error
+	return names, err
+}
+
+// roleRepository implements RoleRepository
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func (r *roleRepository) Seed(roles []*Role) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "updated_at"}),
+	}).Create(roles).Error
+}
+
+func (r *roleRepository) GetByName(name string) (*Role, error) {
+	var role Role
+	err := r.db.First(&role, "name = ?", name).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) List() ([]*Role, error) {
+	var roles []*Role
+	err := r.db.Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// approverGroupRepository implements ApproverGroupRepository
+type approverGroupRepository struct {
+	db *gorm.DB
+}
+
+func (r *approverGroupRepository) AddMember(member *ApproverGroupMember) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *approverGroupRepository) RemoveMember(partyID, group, userID string) (bool, error) {
+	result := r.db.Where("party_id = ? AND group_name = ? AND user_id = ?", partyID, group, userID).Delete(&ApproverGroupMember{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *approverGroupRepository) ListMembers(partyID, group string) ([]*ApproverGroupMember, error) {
+	var members []*ApproverGroupMember
+	err := r.db.Preload("User").Where("party_id = ? AND group_name = ?", partyID, group).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+func (r *approverGroupRepository) ListByUserID(userID string) ([]*ApproverGroupMember, error) {
+	var members []*ApproverGroupMember
+	err := r.db.Where("user_id = ?", userID).Order("group_name").Find(&members).Error
+	return members, err
+}
+
+func (r *approverGroupRepository) IsMember(partyID, group, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&ApproverGroupMember{}).Where("party_id = ? AND group_name = ? AND user_id = ?", partyID, group, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// quoteRepository implements QuoteRepository
+type quoteRepository struct {
+	db *gorm.DB
+}
+
+func (r *quoteRepository) Create(quote *Quote) error {
+	return r.db.Create(quote).Error
+}
+
+func (r *quoteRepository) GetByID(id string) (*Quote, error) {
+	var quote Quote
+	err := r.db.First(&quote, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *quoteRepository) Use(id, workflowID string, now time.Time) (bool, error) {
+	result := r.db.Model(&Quote{}).
+		Where("id = ? AND workflow_id IS NULL AND expires_at > ?", id, now).
+		Update("workflow_id", workflowID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// paymentRailRepository implements PaymentRailRepository
+type paymentRailRepository struct {
+	db *gorm.DB
+}
+
+func (r *paymentRailRepository) Seed(rails []*PaymentRail) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(rails).Error
+}
+
+func (r *paymentRailRepository) Create(rail *PaymentRail) error {
+	return r.db.Create(rail).Error
+}
+
+func (r *paymentRailRepository) GetByRail(rail string) (*PaymentRail, error) {
+	var paymentRail PaymentRail
+	err := r.db.First(&paymentRail, "rail = ?", rail).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentRail, nil
+}
+
+func (r *paymentRailRepository) List() ([]*PaymentRail, error) {
+	var rails []*PaymentRail
+	err := r.db.Order("rail").Find(&rails).Error
+	return rails, err
+}
+
+func (r *paymentRailRepository) Update(rail *PaymentRail) error {
+	return r.db.Save(rail).Error
+}
+
+func (r *paymentRailRepository) Delete(rail string) error {
+	return r.db.Delete(&PaymentRail{}, "rail = ?", rail).Error
+}
+
+// chainTransferRepository implements ChainTransferRepository
+type chainTransferRepository struct {
+	db *gorm.DB
+}
+
+func (r *chainTransferRepository) Create(transfer *ChainTransfer) error {
+	return r.db.Create(transfer).Error
+}
+
+func (r *chainTransferRepository) GetByExecutionID(executionID string) (*ChainTransfer, error) {
+	var transfer ChainTransfer
+	err := r.db.First(&transfer, "execution_id = ?", executionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *chainTransferRepository) Update(transfer *ChainTransfer) error {
+	return r.db.Save(transfer).Error
+}
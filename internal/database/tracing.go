@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Database operations are traced by GORM callbacks. An operation gets a span
+// only when its context is part of a trace, so a repository taken with
+// WithContext traces under the caller's span, while background work on the
+// shared repository does not start traces of its own.
+
+const tracingSpanKey = "tracing:span"
+
+var tracer = otel.Tracer("github.com/example/agent-payments/internal/database")
+
+// registerTracing adds the span callbacks before and after each kind of
+// operation
+func registerTracing(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("tracing:before_create", startOperationSpan("create")),
+		callbacks.Create().After("*").Register("tracing:after_create", endOperationSpan),
+		callbacks.Query().Before("*").Register("tracing:before_query", startOperationSpan("query")),
+		callbacks.Query().After("*").Register("tracing:after_query", endOperationSpan),
+		callbacks.Update().Before("*").Register("tracing:before_update", startOperationSpan("update")),
+		callbacks.Update().After("*").Register("tracing:after_update", endOperationSpan),
+		callbacks.Delete().Before("*").Register("tracing:before_delete", startOperationSpan("delete")),
+		callbacks.Delete().After("*").Register("tracing:after_delete", endOperationSpan),
+		callbacks.Row().Before("*").Register("tracing:before_row", startOperationSpan("row")),
+		callbacks.Row().After("*").Register("tracing:after_row", endOperationSpan),
+		callbacks.Raw().Before("*").Register("tracing:before_raw", startOperationSpan("raw")),
+		callbacks.Raw().After("*").Register("tracing:after_raw", endOperationSpan),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startOperationSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+		ctx, span := tracer.Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", db.Dialector.Name()),
+				attribute.String("db.operation", operation),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func endOperationSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()), // Values are bound, not inlined
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"github.com/example/agent-payments/internal/hashchain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Each agent's posted transactions form a hash chain: a transaction's hash
+// covers its postings and the hash of the agent's previous transaction, so
+// altering or removing one breaks every link after it.
+
+// TransactionHashData is the data a transaction's hash is computed from. The
+// transaction's postings must be loaded.
+func TransactionHashData(transaction *Transaction) hashchain.TransactionHashData {
+	data := hashchain.TransactionHashData{
+		TransactionID: transaction.ID,
+		AgentID:       transaction.AgentID,
+		Description:   transaction.Description,
+		Timestamp:     transaction.CreatedAt,
+		PreviousHash:  transaction.PreviousHash,
+		BlockIndex:    transaction.BlockIndex,
+	}
+	for _, posting := range transaction.Postings {
+		data.Postings = append(data.Postings, hashchain.PostingHashData{
+			AccountID: posting.AccountID,
+			Amount:    posting.Amount,
+			Currency:  posting.Currency,
+		})
+		if posting.Amount > 0 {
+			data.Amount += posting.Amount
+		}
+		switch data.Currency {
+		case "":
+			data.Currency = posting.Currency
+		case posting.Currency:
+		default:
+			data.Currency = "MIXED"
+		}
+	}
+	return data
+}
+
+// chainTransaction links a transaction to the end of its agent's chain. The
+// agent's row is locked so concurrent postings take consecutive places. A
+// transaction already in the chain is left as it is.
+func chainTransaction(tx *gorm.DB, transaction *Transaction) error {
+	if transaction.BlockIndex > 0 {
+		return nil
+	}
+
+	var agent Agent
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&agent, "id = ?", transaction.AgentID).Error; err != nil {
+		return err
+	}
+
+	var previous Transaction
+	result := tx.Where("agent_id = ? AND block_index > 0", transaction.AgentID).
+		Order("block_index DESC").Limit(1).Find(&previous)
+	if result.Error != nil {
+		return result.Error
+	}
+	transaction.PreviousHash, transaction.BlockIndex = hashchain.GenesisHash, 1
+	if result.RowsAffected > 0 {
+		transaction.PreviousHash, transaction.BlockIndex = previous.Hash, previous.BlockIndex+1
+	}
+
+	if err := tx.Where("transaction_id = ?", transaction.ID).Find(&transaction.Postings).Error; err != nil {
+		return err
+	}
+	transaction.Hash = hashchain.GenerateTransactionHash(TransactionHashData(transaction))
+
+	return tx.Model(transaction).UpdateColumns(map[string]interface{}{
+		"hash":          transaction.Hash,
+		"previous_hash": transaction.PreviousHash,
+		"block_index":   transaction.BlockIndex,
+	}).Error
+}
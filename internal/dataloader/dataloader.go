@@ -0,0 +1,90 @@
+// Package dataloader batches the reads of GraphQL resolvers. A resolver
+// returning a list primes the loaders its items' fields will read from with
+// the items' keys; the first of those fields to load a value then fetches
+// every primed key in one batch, and the others read the cache. A query
+// level is thus resolved with one repository call per loader, however many
+// items it has, without waiting for resolvers to line up.
+package dataloader
+
+import "sync"
+
+// BatchFunc fetches the values of a set of keys in one round trip. Keys
+// without a value are left out of the result.
+type BatchFunc[V any] func(keys []string) (map[string]V, error)
+
+// Loader deduplicates keys, fetches the uncached ones in a single batch and
+// caches the results. Loaders live for one request, so cached values never
+// outlive the query that read them.
+type Loader[V any] struct {
+	batch BatchFunc[V]
+
+	mu      sync.Mutex
+	cache   map[string]V
+	fetched map[string]bool // Keys fetched, including those without a value
+	primed  []string
+}
+
+// New creates a loader fetching with the batch function
+func New[V any](batch BatchFunc[V]) *Loader[V] {
+	return &Loader[V]{batch: batch, cache: map[string]V{}, fetched: map[string]bool{}}
+}
+
+// Prime adds keys to the next batch, without fetching them yet
+func (l *Loader[V]) Prime(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if key != "" && !l.fetched[key] {
+			l.primed = append(l.primed, key)
+		}
+	}
+}
+
+// Load returns the value of a key, the zero value where there is none
+func (l *Loader[V]) Load(key string) (V, error) {
+	values, err := l.LoadMany([]string{key})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return values[0], nil
+}
+
+// LoadMany returns the value of each key, fetching those not cached together
+// with the primed keys
+func (l *Loader[V]) LoadMany(keys []string) ([]V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key != "" && !l.fetched[key] && !seen[key] {
+			seen[key] = true
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		for _, key := range l.primed {
+			if !l.fetched[key] && !seen[key] {
+				seen[key] = true
+				missing = append(missing, key)
+			}
+		}
+		values, err := l.batch(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range missing {
+			l.cache[key] = values[key]
+			l.fetched[key] = true // Misses are cached too, so they are not refetched
+		}
+		l.primed = nil
+	}
+
+	result := make([]V, len(keys))
+	for i, key := range keys {
+		result[i] = l.cache[key]
+	}
+	return result, nil
+}
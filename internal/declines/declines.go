@@ -0,0 +1,204 @@
+package declines
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Auto-decline rules are hard limits an owner party sets on its agents'
+// payments. They are evaluated before risk scoring, and a payment matching
+// any of them is denied whatever its score.
+
+// Rule types
+const (
+	RuleBlockedCounterparties     = "blocked_counterparties"
+	RuleQuietHours                = "quiet_hours"
+	RuleNewCounterpartyDailyLimit = "new_counterparty_daily_limit"
+)
+
+// Parameters configures a rule. Each rule type reads only the fields it needs;
+// unset fields take the defaults of DefaultParameters.
+type Parameters struct {
+	Counterparties []string `json:"counterparties,omitempty"` // Never paid (blocked_counterparties)
+	StartHour      *int     `json:"startHour,omitempty"`      // First hour of the quiet period, 0-23
+	EndHour        *int     `json:"endHour,omitempty"`        // Hour the quiet period ends, 0-23
+	Timezone       string   `json:"timezone,omitempty"`       // IANA zone of the hours, default UTC
+	MaxPayments    int      `json:"maxPayments,omitempty"`    // Payments per day to a new counterparty
+	LookbackDays   int      `json:"lookbackDays,omitempty"`   // History that makes a counterparty known
+}
+
+// DefaultParameters returns the parameters a rule type uses when unset
+func DefaultParameters(ruleType string) Parameters {
+	switch ruleType {
+	case RuleQuietHours:
+		start, end := 0, 6
+		return Parameters{StartHour: &start, EndHour: &end, Timezone: "UTC"}
+	case RuleNewCounterpartyDailyLimit:
+		return Parameters{MaxPayments: 1, LookbackDays: 90}
+	}
+	return Parameters{}
+}
+
+// ValidRuleType reports whether a rule type is known
+func ValidRuleType(ruleType string) bool {
+	switch ruleType {
+	case RuleBlockedCounterparties, RuleQuietHours, RuleNewCounterpartyDailyLimit:
+		return true
+	}
+	return false
+}
+
+// Validate checks a rule's parameters
+func Validate(ruleType string, params Parameters) error {
+	switch ruleType {
+	case RuleBlockedCounterparties:
+		if len(params.Counterparties) == 0 {
+			return fmt.Errorf("counterparties is required")
+		}
+	case RuleQuietHours:
+		if params.StartHour == nil || params.EndHour == nil ||
+			*params.StartHour < 0 || *params.StartHour > 23 || *params.EndHour < 0 || *params.EndHour > 23 ||
+			*params.StartHour == *params.EndHour {
+			return fmt.Errorf("startHour and endHour must be different hours from 0 to 23")
+		}
+		if _, err := time.LoadLocation(params.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", params.Timezone)
+		}
+	case RuleNewCounterpartyDailyLimit:
+		if params.MaxPayments < 1 || params.LookbackDays < 1 {
+			return fmt.Errorf("maxPayments and lookbackDays must be positive")
+		}
+	}
+	return nil
+}
+
+// RuleParameters decodes a rule's parameters over its type's defaults
+func RuleParameters(rule *database.AutoDeclineRule) (Parameters, error) {
+	params := DefaultParameters(rule.Type)
+	if rule.Parameters != "" {
+		if err := json.Unmarshal([]byte(rule.Parameters), &params); err != nil {
+			return params, fmt.Errorf("invalid parameters for rule %s: %v", rule.ID, err)
+		}
+	}
+	return params, nil
+}
+
+// Payment is the payment being evaluated
+type Payment struct {
+	AgentID      string
+	Counterparty string
+	AmountUSD    float64
+	At           time.Time
+}
+
+// Trace is the outcome of one rule for a payment
+type Trace struct {
+	RuleID  string `json:"ruleId"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Matched bool   `json:"matched"`
+	Detail  string `json:"detail"`
+}
+
+// Evaluate runs the enabled rules over a payment and the agent's earlier
+// risk decisions, returning a trace of every rule evaluated. A rule with
+// malformed parameters cannot be checked and is traced as a match, since the
+// owner asked for the payment to be declined in some case it describes.
+func Evaluate(rules []*database.AutoDeclineRule, payment Payment, history []*database.RiskDecision) []Trace {
+	traces := []Trace{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		trace := Trace{RuleID: rule.ID, Name: rule.Name, Type: rule.Type}
+
+		params, err := RuleParameters(rule)
+		if err == nil {
+			err = Validate(rule.Type, params)
+		}
+		if err != nil {
+			trace.Matched = true
+			trace.Detail = "Rule cannot be evaluated: " + err.Error()
+			traces = append(traces, trace)
+			continue
+		}
+
+		switch rule.Type {
+		case RuleBlockedCounterparties:
+			trace.Matched, trace.Detail = blockedCounterparty(params, payment)
+		case RuleQuietHours:
+			trace.Matched, trace.Detail = quietHours(params, payment)
+		case RuleNewCounterpartyDailyLimit:
+			trace.Matched, trace.Detail = newCounterpartyDailyLimit(params, payment, history)
+		}
+		traces = append(traces, trace)
+	}
+	return traces
+}
+
+// Matched returns the traces of the rules that matched
+func Matched(traces []Trace) []Trace {
+	var matched []Trace
+	for _, trace := range traces {
+		if trace.Matched {
+			matched = append(matched, trace)
+		}
+	}
+	return matched
+}
+
+func blockedCounterparty(params Parameters, payment Payment) (bool, string) {
+	for _, counterparty := range params.Counterparties {
+		if strings.EqualFold(strings.TrimSpace(counterparty), payment.Counterparty) {
+			return true, fmt.Sprintf("%s is a blocked counterparty", payment.Counterparty)
+		}
+	}
+	return false, fmt.Sprintf("%s is not among %d blocked counterparties", payment.Counterparty, len(params.Counterparties))
+}
+
+// quietHours matches payments made from the start hour up to the end hour in
+// the rule's timezone. A period whose end is before its start spans midnight.
+func quietHours(params Parameters, payment Payment) (bool, string) {
+	location, _ := time.LoadLocation(params.Timezone)
+	local := payment.At.In(location)
+	start, end, hour := *params.StartHour, *params.EndHour, local.Hour()
+
+	quiet := hour >= start && hour < end
+	if start > end {
+		quiet = hour >= start || hour < end
+	}
+	period := fmt.Sprintf("%02d:00-%02d:00 %s", start, end, params.Timezone)
+	if quiet {
+		return true, fmt.Sprintf("%s falls within the quiet hours %s", local.Format("15:04"), period)
+	}
+	return false, fmt.Sprintf("%s is outside the quiet hours %s", local.Format("15:04"), period)
+}
+
+// newCounterpartyDailyLimit caps the payments in a day to a counterparty the
+// agent had not paid in the lookback before that day. Denied attempts count
+// toward neither.
+func newCounterpartyDailyLimit(params Parameters, payment Payment, history []*database.RiskDecision) (bool, string) {
+	dayStart := payment.At.Add(-24 * time.Hour)
+	since := dayStart.Add(-time.Duration(params.LookbackDays) * 24 * time.Hour)
+
+	today := 0
+	for _, decision := range history {
+		if decision.Decision == "deny" || !strings.EqualFold(decision.Counterparty, payment.Counterparty) ||
+			decision.CreatedAt.After(payment.At) || decision.CreatedAt.Before(since) {
+			continue
+		}
+		if decision.CreatedAt.Before(dayStart) {
+			return false, fmt.Sprintf("%s was paid before the last day and is not new", payment.Counterparty)
+		}
+		today++
+	}
+
+	if today+1 > params.MaxPayments {
+		return true, fmt.Sprintf("%d payments within a day to new counterparty %s exceed %d", today+1, payment.Counterparty, params.MaxPayments)
+	}
+	return false, fmt.Sprintf("%d payments within a day to new counterparty %s, at most %d allowed", today+1, payment.Counterparty, params.MaxPayments)
+}
@@ -0,0 +1,130 @@
+package descriptions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/example/agent-payments/internal/types"
+)
+
+// MaxTemplateLength is the longest template a party can define
+const MaxTemplateLength = 500
+
+// Built-in template variables, filled from the payment itself. Any other
+// variable is looked up in the payment's metadata.
+const (
+	VarAgentName    = "agentName"
+	VarAgentID      = "agentId"
+	VarCounterparty = "counterparty"
+	VarAmount       = "amount"
+	VarCurrency     = "currency"
+	VarRail         = "rail"
+	VarDescription  = "description"
+	VarDate         = "date"
+)
+
+// separators are the characters templates put between variables. A run of
+// them left by empty variables collapses to one.
+const separators = "-–—|/:,;"
+
+var (
+	variableName  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]*$`)
+	separatorRuns = regexp.MustCompile(`\s*([` + separators + `])(?:\s*[` + separators + `])+\s*`)
+)
+
+// Validate checks that a template is well formed: every { opens a variable
+// with a valid name closed by }, and {{ and }} escape literal braces
+func Validate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("template cannot be empty")
+	}
+	if len([]rune(template)) > MaxTemplateLength {
+		return fmt.Errorf("template cannot exceed %d characters", MaxTemplateLength)
+	}
+	_, err := render(template, nil)
+	return err
+}
+
+// Render substitutes the variables of a template and cleans the result.
+// Variables without a value render empty, and the separators around them are
+// dropped. Built-in variables take precedence over metadata of the same name.
+func Render(template string, builtins, metadata map[string]string) (string, error) {
+	return render(template, func(name string) string {
+		if value, ok := builtins[name]; ok {
+			return value
+		}
+		return metadata[name]
+	})
+}
+
+func render(template string, lookup func(string) string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(template); i++ {
+		ch := template[i]
+		switch {
+		case ch == '{' && strings.HasPrefix(template[i:], "{{"):
+			out.WriteByte('{')
+			i++
+		case ch == '}' && strings.HasPrefix(template[i:], "}}"):
+			out.WriteByte('}')
+			i++
+		case ch == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed variable at position %d", i)
+			}
+			name := strings.TrimSpace(template[i+1 : i+end])
+			if !variableName.MatchString(name) {
+				return "", fmt.Errorf("invalid variable name %q", name)
+			}
+			if lookup != nil {
+				out.WriteString(lookup(name))
+			}
+			i += end
+		case ch == '}':
+			return "", fmt.Errorf("unmatched } at position %d", i)
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	return Clean(out.String()), nil
+}
+
+// Clean removes control characters, collapses whitespace and runs of
+// separators, and trims separators from both ends
+func Clean(description string) string {
+	description = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, description)
+	description = strings.Join(strings.Fields(description), " ")
+	description = separatorRuns.ReplaceAllString(description, " $1 ")
+	return strings.Trim(description, " "+separators)
+}
+
+// Truncate shortens a description to at most max characters, cutting at a
+// character boundary. A max of zero or less leaves it unchanged.
+func Truncate(description string, max int) string {
+	runes := []rune(description)
+	if max <= 0 || len(runes) <= max {
+		return description
+	}
+	return strings.TrimRight(string(runes[:max]), " "+separators)
+}
+
+// ForRail cleans a description and truncates it to the rail's limit, so every
+// record of a payment carries the description the rail will. An unknown rail,
+// nil, leaves the length unlimited.
+func ForRail(description string, rail *types.RailCharacteristics) string {
+	if rail == nil {
+		return Clean(description)
+	}
+	return Truncate(Clean(description), rail.MaxDescriptionLength)
+}
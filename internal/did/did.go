@@ -0,0 +1,267 @@
+// Package did resolves agents' decentralized identifiers to their DID
+// documents and checks what agents sign with the keys listed in them: the
+// nonces of challenges proving an agent controls its DID, and the payment
+// requests it sends.
+//
+// Agents of identity mode "did" are published as did:agentpay:<agent ID>.
+// Their DID document lists the agent's active did_key credentials, keys the
+// platform generated or public keys the agent registered while keeping the
+// private key to itself.
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+)
+
+// RequestPayloadVersion prefixes every canonical request payload
+const RequestPayloadVersion = "agentpay-request-v1"
+
+// SignatureHeader carries an agent's signature of a request
+const SignatureHeader = "X-Agent-Signature"
+
+// MaxSignatureAge bounds how long a signed request may take to arrive, and
+// how far ahead of the platform's clock it may be signed
+const MaxSignatureAge = 5 * time.Minute
+
+// ChallengeTTL is how long a challenge may be answered
+const ChallengeTTL = 5 * time.Minute
+
+// Verification relationships a key must be listed under
+const (
+	Authentication  = "authentication"  // Answering challenges
+	AssertionMethod = "assertionMethod" // Signing requests
+)
+
+var (
+	ErrUnsupportedMethod = errors.New("unsupported DID method")
+	ErrNotFound          = errors.New("DID not found")
+	ErrKeyNotFound       = errors.New("verification method not found in DID document")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrSignatureExpired  = errors.New("signature timestamp outside the allowed window")
+	ErrMalformed         = errors.New("malformed signature header")
+)
+
+// Resolver resolves DIDs to their documents
+type Resolver struct {
+	repo database.Repository
+}
+
+// NewResolver creates a resolver reading agents and their keys from repo
+func NewResolver(repo database.Repository) *Resolver {
+	return &Resolver{repo: repo}
+}
+
+// Resolve returns the document of a DID and the agent it identifies
+func (r *Resolver) Resolve(id string) (*auth.DIDDocument, *database.Agent, error) {
+	if !strings.HasPrefix(id, auth.DIDMethodPrefix) {
+		return nil, nil, ErrUnsupportedMethod
+	}
+	agent, err := r.repo.AgentRepository().GetByID(strings.TrimPrefix(id, auth.DIDMethodPrefix))
+	if err != nil || agent.IdentityMode != "did" {
+		return nil, nil, ErrNotFound
+	}
+	document, err := r.ResolveAgent(agent)
+	return document, agent, err
+}
+
+// ResolveAgent returns the document of an agent's DID
+func (r *Resolver) ResolveAgent(agent *database.Agent) (*auth.DIDDocument, error) {
+	if agent.IdentityMode != "did" {
+		return nil, ErrNotFound
+	}
+	credentials, err := r.repo.AgentCredentialRepository().ListByAgentID(agent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keys of %s: %v", auth.AgentDID(agent.ID), err)
+	}
+	return auth.BuildDIDDocument(agent, credentials), nil
+}
+
+// VerificationKey returns the Ed25519 key of a document's verification
+// method listed under a relationship, found by its DID URL or its fragment,
+// and the method's full ID
+func VerificationKey(document *auth.DIDDocument, keyID, relationship string) (ed25519.PublicKey, string, error) {
+	methodID := keyID
+	if !strings.Contains(keyID, "#") {
+		methodID = document.ID + "#" + keyID
+	}
+
+	listed := document.Authentication
+	if relationship == AssertionMethod {
+		listed = document.AssertionMethod
+	}
+	if !contains(listed, methodID) {
+		return nil, "", ErrKeyNotFound
+	}
+	for _, method := range document.VerificationMethod {
+		if method.ID != methodID {
+			continue
+		}
+		key, err := base64.RawURLEncoding.DecodeString(method.PublicKeyJwk.X)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, "", fmt.Errorf("verification method %s has an invalid key", methodID)
+		}
+		return ed25519.PublicKey(key), methodID, nil
+	}
+	return nil, "", ErrKeyNotFound
+}
+
+// NewNonce returns a random challenge nonce
+func NewNonce() (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// VerifyChallenge checks an agent's base64url Ed25519 signature of a
+// challenge nonce with a key listed under authentication, returning the
+// key's DID URL
+func VerifyChallenge(document *auth.DIDDocument, keyID, nonce, signature string) (string, error) {
+	key, methodID, err := VerificationKey(document, keyID, Authentication)
+	if err != nil {
+		return "", err
+	}
+	if !verify(key, []byte(nonce), signature) {
+		return methodID, ErrInvalidSignature
+	}
+	return methodID, nil
+}
+
+// RequestSignature is the parsed X-Agent-Signature header:
+// keyId="<DID URL>",created=<unix seconds>,signature="<base64url>"
+type RequestSignature struct {
+	KeyID     string
+	Created   time.Time
+	Signature string
+}
+
+// ParseRequestSignature parses an X-Agent-Signature header
+func ParseRequestSignature(header string) (*RequestSignature, error) {
+	parsed := &RequestSignature{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, ErrMalformed
+		}
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "keyId":
+			parsed.KeyID = value
+		case "created":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrMalformed
+			}
+			parsed.Created = time.Unix(seconds, 0)
+		case "signature":
+			parsed.Signature = value
+		}
+	}
+	if parsed.KeyID == "" || parsed.Created.IsZero() || parsed.Signature == "" {
+		return nil, ErrMalformed
+	}
+	return parsed, nil
+}
+
+// CanonicalRequest returns the exact bytes an agent signs for a request:
+// its method, path, signing time and the SHA-256 of its body
+func CanonicalRequest(method, path string, created time.Time, body []byte) []byte {
+	digest := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		RequestPayloadVersion,
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(created.Unix(), 10),
+		hex.EncodeToString(digest[:]),
+	}, "\n"))
+}
+
+// SignRequest returns the X-Agent-Signature header of a request signed with
+// the private key of a verification method
+func SignRequest(privateKey ed25519.PrivateKey, keyID, method, path string, created time.Time, body []byte) string {
+	signature := ed25519.Sign(privateKey, CanonicalRequest(method, path, created, body))
+	return fmt.Sprintf(`keyId="%s",created=%d,signature="%s"`, keyID, created.Unix(), base64.RawURLEncoding.EncodeToString(signature))
+}
+
+// Verification is the outcome of checking an agent's signature, kept with
+// the payment it authorized
+type Verification struct {
+	DID        string    `json:"did"`
+	KeyID      string    `json:"keyId"`
+	Method     string    `json:"method"` // "request_signature" or "challenge"
+	Verified   bool      `json:"verified"`
+	Reason     string    `json:"reason,omitempty"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// Map returns the verification as audit values
+func (v *Verification) Map() map[string]interface{} {
+	values := map[string]interface{}{
+		"did":        v.DID,
+		"keyId":      v.KeyID,
+		"method":     v.Method,
+		"verified":   v.Verified,
+		"verifiedAt": v.VerifiedAt.Format(time.RFC3339),
+	}
+	if v.Reason != "" {
+		values["reason"] = v.Reason
+	}
+	return values
+}
+
+// VerifyRequest checks an agent's signature of a request with a key listed
+// under assertionMethod. The verification is returned whether or not the
+// signature holds, with the reason it does not in the error.
+func VerifyRequest(document *auth.DIDDocument, header, method, path string, body []byte, now time.Time) (*Verification, error) {
+	verification := &Verification{DID: document.ID, Method: "request_signature", VerifiedAt: now}
+	fail := func(err error) (*Verification, error) {
+		verification.Reason = err.Error()
+		return verification, err
+	}
+
+	signature, err := ParseRequestSignature(header)
+	if err != nil {
+		return fail(err)
+	}
+	verification.KeyID = signature.KeyID
+	if age := now.Sub(signature.Created); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return fail(ErrSignatureExpired)
+	}
+	key, methodID, err := VerificationKey(document, signature.KeyID, AssertionMethod)
+	if err != nil {
+		return fail(err)
+	}
+	verification.KeyID = methodID
+	if !verify(key, CanonicalRequest(method, path, signature.Created, body), signature.Signature) {
+		return fail(ErrInvalidSignature)
+	}
+	verification.Verified = true
+	return verification, nil
+}
+
+func verify(key ed25519.PublicKey, message []byte, encoded string) bool {
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	return err == nil && ed25519.Verify(key, message, signature)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
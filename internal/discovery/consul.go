@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// ConsulResolver resolves services to a healthy instance registered with
+// Consul, asking its health API for instances passing their checks. Answers
+// are cached for a TTL.
+type ConsulResolver struct {
+	Address string // Consul HTTP API, such as http://localhost:8500
+	Token   string // ACL token, if Consul requires one
+	Scheme  string // Scheme of the resolved URLs, "http" by default
+	TTL     time.Duration
+	Client  *http.Client
+
+	cacheOnce sync.Once
+	cache     *resolveCache
+}
+
+// NewConsulResolverFromEnv creates a Consul resolver from CONSUL_HTTP_ADDR
+// (http://localhost:8500), CONSUL_HTTP_TOKEN, SERVICE_URL_SCHEME (http) and
+// SERVICE_DISCOVERY_TTL_SECONDS (30)
+func NewConsulResolverFromEnv() *ConsulResolver {
+	return &ConsulResolver{
+		Address: strings.TrimSuffix(common.GetEnv("CONSUL_HTTP_ADDR", "http://localhost:8500"), "/"),
+		Token:   common.GetEnv("CONSUL_HTTP_TOKEN", ""),
+		Scheme:  common.GetEnv("SERVICE_URL_SCHEME", "http"),
+		TTL:     time.Duration(common.GetEnvAsInt("SERVICE_DISCOVERY_TTL_SECONDS", 30)) * time.Second,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (r *ConsulResolver) Name() string {
+	return "consul"
+}
+
+// consulEntry is the part of a Consul health API entry used to reach an instance
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+func (r *ConsulResolver) Resolve(ctx context.Context, service string) (string, error) {
+	r.cacheOnce.Do(func() { r.cache = newResolveCache() })
+	return r.cache.get(service, r.TTL, func() (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.Address+"/v1/health/service/"+url.PathEscape(service)+"?passing=true", nil)
+		if err != nil {
+			return "", err
+		}
+		if r.Token != "" {
+			req.Header.Set("X-Consul-Token", r.Token)
+		}
+		client := r.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("consul lookup for %s failed: %v", service, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("consul lookup for %s returned %d", service, resp.StatusCode)
+		}
+
+		var entries []consulEntry
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			return "", fmt.Errorf("invalid consul response for %s: %v", service, err)
+		}
+		if len(entries) == 0 {
+			return "", fmt.Errorf("%w: no healthy %s instances in consul", ErrUnknownService, service)
+		}
+
+		// Spread instances of the service across callers
+		entry := entries[rand.Intn(len(entries))]
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		return r.Scheme + "://" + net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)), nil
+	})
+}
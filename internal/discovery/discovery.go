@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Service discovery
+//
+// Services find each other through a Resolver, which turns a service name
+// into the base URL to call. The env resolver, the default, reads
+// <NAME>_SERVICE_URL, falling back to the service's port on localhost for
+// development. Deployments with a service registry can resolve through DNS
+// SRV records or Consul instead, chosen with SERVICE_DISCOVERY.
+
+// Service names
+const (
+	Identity      = "identity"
+	Consent       = "consent"
+	Risk          = "risk"
+	Orchestration = "orchestration"
+	Router        = "router"
+	Ledger        = "ledger"
+	Compliance    = "compliance"
+	Funding       = "funding"
+	GraphQL       = "graphql"
+	Worker        = "worker"
+)
+
+// DefaultPorts are the ports each service listens on by default
+var DefaultPorts = map[string]int{
+	Identity:      8081,
+	Consent:       8082,
+	Risk:          8083,
+	Orchestration: 8084,
+	Router:        8085,
+	Ledger:        8086,
+	Compliance:    8089,
+	Funding:       8092,
+	GraphQL:       8093,
+	Worker:        8094,
+}
+
+// ErrUnknownService is returned for names no resolver knows
+var ErrUnknownService = errors.New("unknown service")
+
+// Resolver finds the base URL of a service, such as http://risk:8083
+type Resolver interface {
+	Name() string
+	Resolve(ctx context.Context, service string) (string, error)
+}
+
+// NewResolverFromEnv returns the resolver named by SERVICE_DISCOVERY: env
+// (the default), dns or consul
+func NewResolverFromEnv() (Resolver, error) {
+	switch mode := common.GetEnv("SERVICE_DISCOVERY", "env"); mode {
+	case "env":
+		return NewEnvResolver(), nil
+	case "dns":
+		return NewDNSResolverFromEnv(), nil
+	case "consul":
+		return NewConsulResolverFromEnv(), nil
+	default:
+		return nil, fmt.Errorf("unknown service discovery mode: %s", mode)
+	}
+}
+
+// EnvURL returns a service's base URL from <NAME>_SERVICE_URL, or its
+// default port on localhost
+func EnvURL(service string) string {
+	fallback := ""
+	if port, ok := DefaultPorts[service]; ok {
+		fallback = fmt.Sprintf("http://localhost:%d", port)
+	}
+	return strings.TrimSuffix(common.GetEnv(envName(service), fallback), "/")
+}
+
+// GRPCAddr returns the address of a service's gRPC server from
+// <NAME>_GRPC_ADDR, such as risk:9083, or "" when it is called over REST
+func GRPCAddr(service string) string {
+	return common.GetEnv(strings.ToUpper(strings.ReplaceAll(service, "-", "_"))+"_GRPC_ADDR", "")
+}
+
+func envName(service string) string {
+	return strings.ToUpper(strings.ReplaceAll(service, "-", "_")) + "_SERVICE_URL"
+}
+
+// EnvResolver resolves services from <NAME>_SERVICE_URL. URLs are read on
+// each call, so they can be changed without a restart in tests.
+type EnvResolver struct{}
+
+// NewEnvResolver creates a resolver reading service URLs from the environment
+func NewEnvResolver() *EnvResolver {
+	return &EnvResolver{}
+}
+
+func (r *EnvResolver) Name() string {
+	return "env"
+}
+
+func (r *EnvResolver) Resolve(ctx context.Context, service string) (string, error) {
+	if url := EnvURL(service); url != "" {
+		return url, nil
+	}
+	return "", fmt.Errorf("%w: %s (set %s)", ErrUnknownService, service, envName(service))
+}
@@ -0,0 +1,102 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// DNSResolver resolves services from SRV records named
+// _<port name>._tcp.<service>.<domain>, as published for named ports by
+// Kubernetes and most service registries. Answers are cached for a TTL.
+type DNSResolver struct {
+	Domain   string
+	PortName string // SRV service label, "http" by default
+	Scheme   string // Scheme of the resolved URLs, "http" by default
+	TTL      time.Duration
+
+	cacheOnce sync.Once
+	cache     *resolveCache
+}
+
+// NewDNSResolverFromEnv creates a DNS resolver from SERVICE_DNS_DOMAIN,
+// SERVICE_DNS_PORT_NAME (http), SERVICE_URL_SCHEME (http) and
+// SERVICE_DISCOVERY_TTL_SECONDS (30)
+func NewDNSResolverFromEnv() *DNSResolver {
+	return &DNSResolver{
+		Domain:   common.GetEnv("SERVICE_DNS_DOMAIN", ""),
+		PortName: common.GetEnv("SERVICE_DNS_PORT_NAME", "http"),
+		Scheme:   common.GetEnv("SERVICE_URL_SCHEME", "http"),
+		TTL:      time.Duration(common.GetEnvAsInt("SERVICE_DISCOVERY_TTL_SECONDS", 30)) * time.Second,
+	}
+}
+
+func (r *DNSResolver) Name() string {
+	return "dns"
+}
+
+func (r *DNSResolver) Resolve(ctx context.Context, service string) (string, error) {
+	r.cacheOnce.Do(func() { r.cache = newResolveCache() })
+	return r.cache.get(service, r.TTL, func() (string, error) {
+		name := service
+		if r.Domain != "" {
+			name += "." + strings.TrimPrefix(r.Domain, ".")
+		}
+		// Records come back ordered by priority and shuffled by weight
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, r.PortName, "tcp", name)
+		if err != nil {
+			return "", fmt.Errorf("SRV lookup for %s failed: %v", service, err)
+		}
+		if len(records) == 0 {
+			return "", fmt.Errorf("%w: no SRV records for %s", ErrUnknownService, name)
+		}
+		host := strings.TrimSuffix(records[0].Target, ".")
+		return r.Scheme + "://" + net.JoinHostPort(host, strconv.Itoa(int(records[0].Port))), nil
+	})
+}
+
+// resolveCache keeps resolved URLs for a TTL, so registries are not asked on
+// every call
+type resolveCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedURL
+}
+
+type cachedURL struct {
+	url     string
+	expires time.Time
+}
+
+func newResolveCache() *resolveCache {
+	return &resolveCache{entries: map[string]cachedURL{}}
+}
+
+func (c *resolveCache) get(service string, ttl time.Duration, resolve func() (string, error)) (string, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[service]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.url, nil
+	}
+
+	url, err := resolve()
+	if err != nil {
+		// A stale answer beats none while the registry is unreachable
+		if ok {
+			common.Warn("Using stale address of the %s service: %v", service, err)
+			return entry.url, nil
+		}
+		return "", err
+	}
+	c.mu.Lock()
+	c.entries[service] = cachedURL{url: url, expires: now.Add(ttl)}
+	c.mu.Unlock()
+	return url, nil
+}
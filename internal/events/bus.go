@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Message bus
+//
+// Publishers and consumers exchange messages through a MessageBus, selected
+// with EVENT_BUS:
+//
+//	kafka   Kafka, on KAFKA_BROKERS (the default)
+//	nats    NATS JetStream, on NATS_URL, over TLS for tls:// URLs or with
+//	        NATS_CA_FILE or NATS_CERT_FILE set; each topic is a stream
+//	memory  an in-process bus, for development without a broker; it only
+//	        carries messages between publishers and consumers of one process
+//
+// Each consumer group receives every message of a topic once, and within a
+// group each message goes to one consumer. A message whose key matches an
+// earlier one is delivered after it.
+
+// Event bus backends
+const (
+	BusKafka  = "kafka"
+	BusNATS   = "nats"
+	BusMemory = "memory"
+)
+
+// ErrBusClosed is returned by subscriptions of a closed bus
+var ErrBusClosed = errors.New("message bus is closed")
+
+// Header is a message header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a message on the bus. Partition and Offset locate it in its
+// topic as the bus numbers them; buses without partitions use partition 0.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Time      time.Time
+
+	ack jetstream.Msg // The delivered message, acknowledged on commit, on NATS
+}
+
+// MessageBus publishes messages to topics and subscribes consumer groups to them
+type MessageBus interface {
+	Name() string
+	// Publish publishes messages to a topic, returning once the bus has them
+	Publish(ctx context.Context, topic string, messages ...Message) error
+	// Subscribe joins a consumer group reading a topic
+	Subscribe(topic, groupID string) (Subscription, error)
+	Close() error
+}
+
+// Subscription is a consumer group member reading a topic. Messages are
+// committed once handled; one left uncommitted is delivered again, at the
+// latest when the member leaves the group.
+type Subscription interface {
+	// Fetch blocks until a message arrives or ctx ends
+	Fetch(ctx context.Context) (Message, error)
+	// Commit acknowledges a fetched message once it has been handled
+	Commit(ctx context.Context, message Message) error
+	Close() error
+}
+
+// BusConfig selects and configures the message bus
+type BusConfig struct {
+	Backend      string   // kafka, nats or memory
+	KafkaBrokers []string // Brokers of the kafka backend
+	NATSURL      string   // Server of the nats backend, such as nats://localhost:4222
+	NATSCAFile   string   // CA verifying the NATS server, instead of the system's
+	NATSCertFile string   // Client certificate presented to the NATS server
+	NATSKeyFile  string   // Key of the client certificate
+}
+
+// NewMessageBus connects to the configured bus. The memory bus is shared by
+// everything in the process that opens one.
+func NewMessageBus(cfg BusConfig) (MessageBus, error) {
+	switch cfg.Backend {
+	case BusKafka, "":
+		return newKafkaBus(cfg.KafkaBrokers), nil
+	case BusNATS:
+		return newNATSBus(cfg)
+	case BusMemory:
+		return sharedMemoryBus, nil
+	default:
+		return nil, fmt.Errorf("unknown event bus: %s", cfg.Backend)
+	}
+}
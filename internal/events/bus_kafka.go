@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaBus is the Kafka backend. Messages of a key go to the same
+// partition, as Java producers place them, so their order is kept.
+type kafkaBus struct {
+	brokers []string
+	mu      sync.Mutex
+	writers map[string]*kafka.Writer // By topic
+}
+
+func newKafkaBus(brokers []string) *kafkaBus {
+	return &kafkaBus{brokers: brokers, writers: make(map[string]*kafka.Writer)}
+}
+
+func (b *kafkaBus) Name() string {
+	return BusKafka
+}
+
+func (b *kafkaBus) writer(topic string) *kafka.Writer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	writer, ok := b.writers[topic]
+	if !ok {
+		writer = &kafka.Writer{
+			Addr:         kafka.TCP(b.brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Murmur2Balancer{}, // Same key, same partition, as Java producers place it
+			RequiredAcks: kafka.RequireOne,
+			Async:        false,
+		}
+		b.writers[topic] = writer
+	}
+	return writer
+}
+
+func (b *kafkaBus) Publish(ctx context.Context, topic string, messages ...Message) error {
+	kafkaMessages := make([]kafka.Message, len(messages))
+	for i, message := range messages {
+		kafkaMessages[i] = kafka.Message{Key: message.Key, Value: message.Value, Headers: toKafkaHeaders(message.Headers), Time: message.Time}
+	}
+	return b.writer(topic).WriteMessages(ctx, kafkaMessages...)
+}
+
+func (b *kafkaBus) Subscribe(topic, groupID string) (Subscription, error) {
+	return &kafkaSubscription{reader: kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  b.brokers,
+		Topic:    topic,
+		GroupID:  groupID,
+		MinBytes: 10e3, // 10KB
+		MaxBytes: 10e6, // 10MB
+	})}, nil
+}
+
+func (b *kafkaBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var firstErr error
+	for topic, writer := range b.writers {
+		if err := writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(b.writers, topic)
+	}
+	return firstErr
+}
+
+// kafkaSubscription fetches without committing, so a message is only
+// committed once it has been handled or dead-lettered
+type kafkaSubscription struct {
+	reader *kafka.Reader
+}
+
+func (s *kafkaSubscription) Fetch(ctx context.Context) (Message, error) {
+	message, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	headers := make([]Header, len(message.Headers))
+	for i, header := range message.Headers {
+		headers[i] = Header{Key: header.Key, Value: header.Value}
+	}
+	return Message{
+		Topic:     message.Topic,
+		Partition: message.Partition,
+		Offset:    message.Offset,
+		Key:       message.Key,
+		Value:     message.Value,
+		Headers:   headers,
+		Time:      message.Time,
+	}, nil
+}
+
+func (s *kafkaSubscription) Commit(ctx context.Context, message Message) error {
+	return s.reader.CommitMessages(ctx, kafka.Message{Topic: message.Topic, Partition: message.Partition, Offset: message.Offset})
+}
+
+func (s *kafkaSubscription) Close() error {
+	return s.reader.Close()
+}
+
+func toKafkaHeaders(headers []Header) []kafka.Header {
+	kafkaHeaders := make([]kafka.Header, len(headers))
+	for i, header := range headers {
+		kafkaHeaders[i] = kafka.Header{Key: header.Key, Value: header.Value}
+	}
+	return kafkaHeaders
+}
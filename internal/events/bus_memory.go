@@ -0,0 +1,178 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxMemoryMessages bounds the messages the memory bus keeps per topic; the
+// oldest are dropped beyond it, even if a group has not read them
+const maxMemoryMessages = 10000
+
+// sharedMemoryBus is the process's memory bus
+var sharedMemoryBus = newMemoryBus()
+
+// memoryBus is the in-process backend. A topic keeps its messages until
+// every group subscribed to it has committed them. A group joining a topic
+// starts from its oldest kept message.
+type memoryBus struct {
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	base     int64 // Offset of messages[0]
+	messages []Message
+	groups   map[string]*memoryGroup
+	notify   chan struct{} // Closed, and replaced, when messages are published
+}
+
+type memoryGroup struct {
+	next      int64 // Offset delivered next
+	committed int64 // Offsets before this are committed
+}
+
+func newMemoryBus() *memoryBus {
+	return &memoryBus{topics: make(map[string]*memoryTopic)}
+}
+
+func (b *memoryBus) Name() string {
+	return BusMemory
+}
+
+// topic returns a topic, creating it if needed. The caller holds mu.
+func (b *memoryBus) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{groups: make(map[string]*memoryGroup), notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *memoryBus) Publish(ctx context.Context, topic string, messages ...Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	for _, message := range messages {
+		message.Topic = topic
+		message.Partition = 0
+		message.Offset = t.base + int64(len(t.messages))
+		if message.Time.IsZero() {
+			message.Time = time.Now()
+		}
+		t.messages = append(t.messages, message)
+	}
+	if dropped := len(t.messages) - maxMemoryMessages; dropped > 0 {
+		log.Printf("Memory bus topic %s is full, dropping its %d oldest messages", topic, dropped)
+		t.drop(dropped)
+	}
+	close(t.notify)
+	t.notify = make(chan struct{})
+	return nil
+}
+
+// drop removes the oldest n messages
+func (t *memoryTopic) drop(n int) {
+	t.messages = append([]Message(nil), t.messages[n:]...)
+	t.base += int64(n)
+}
+
+// trim drops the messages every group has committed
+func (t *memoryTopic) trim() {
+	if len(t.groups) == 0 {
+		return
+	}
+	low := t.base + int64(len(t.messages))
+	for _, group := range t.groups {
+		if group.committed < low {
+			low = group.committed
+		}
+	}
+	if n := int(low - t.base); n > 0 {
+		t.drop(n)
+	}
+}
+
+func (b *memoryBus) Subscribe(topic, groupID string) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	group, ok := t.groups[groupID]
+	if !ok {
+		group = &memoryGroup{next: t.base, committed: t.base}
+		t.groups[groupID] = group
+	}
+	return &memorySubscription{bus: b, topic: t, group: group, done: make(chan struct{})}, nil
+}
+
+// Close does nothing, since the bus is shared by the whole process
+func (b *memoryBus) Close() error {
+	return nil
+}
+
+type memorySubscription struct {
+	bus    *memoryBus
+	topic  *memoryTopic
+	group  *memoryGroup
+	done   chan struct{}
+	closed bool
+}
+
+func (s *memorySubscription) Fetch(ctx context.Context) (Message, error) {
+	for {
+		s.bus.mu.Lock()
+		if s.closed {
+			s.bus.mu.Unlock()
+			return Message{}, ErrBusClosed
+		}
+		t, group := s.topic, s.group
+		if group.next < t.base {
+			group.next = t.base // Dropped while the topic was full
+		}
+		if index := group.next - t.base; index < int64(len(t.messages)) {
+			message := t.messages[index]
+			group.next++
+			s.bus.mu.Unlock()
+			return message, nil
+		}
+		notify := t.notify
+		s.bus.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-s.done:
+			return Message{}, ErrBusClosed
+		case <-notify:
+		}
+	}
+}
+
+func (s *memorySubscription) Commit(ctx context.Context, message Message) error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if message.Offset+1 > s.group.committed {
+		s.group.committed = message.Offset + 1
+	}
+	s.topic.trim()
+	return nil
+}
+
+// Close leaves the group; messages it fetched without committing are
+// delivered again
+func (s *memorySubscription) Close() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.group.next = s.group.committed
+	return nil
+}
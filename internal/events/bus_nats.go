@@ -0,0 +1,245 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsBus is the NATS JetStream backend. Each topic is a stream of the same
+// subject, and each consumer group a durable pull consumer of it with one
+// unacknowledged message at a time, so a group handles a topic in order.
+// The client reconnects by itself when the connection breaks.
+type natsBus struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+
+	mu      sync.Mutex
+	streams map[string]bool // Streams known to exist
+}
+
+const (
+	natsRequestTimeout = 5 * time.Second
+	natsFetchExpiry    = 5 * time.Second
+	natsAckWait        = 30 * time.Second
+)
+
+// newNATSBus connects to the server. A tls:// URL, a CA or a client
+// certificate makes the connection use TLS, as does a server requiring it;
+// without a CA the system's roots verify the server.
+func newNATSBus(cfg BusConfig) (*natsBus, error) {
+	options := []nats.Option{
+		nats.Name("agent-payments"),
+		nats.Timeout(natsRequestTimeout),
+		nats.MaxReconnects(-1),
+	}
+	if cfg.NATSCAFile != "" {
+		options = append(options, nats.RootCAs(cfg.NATSCAFile))
+	}
+	if cfg.NATSCertFile != "" {
+		options = append(options, nats.ClientCert(cfg.NATSCertFile, cfg.NATSKeyFile))
+	}
+	conn, err := nats.Connect(cfg.NATSURL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	return &natsBus{conn: conn, js: js, streams: make(map[string]bool)}, nil
+}
+
+func (b *natsBus) Name() string {
+	return BusNATS
+}
+
+// streamName names a topic's stream; stream names cannot contain dots
+func streamName(topic string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, topic)
+}
+
+// ensureStream creates a topic's stream unless it exists
+func (b *natsBus) ensureStream(ctx context.Context, topic string) (string, error) {
+	stream := streamName(topic)
+	b.mu.Lock()
+	known := b.streams[stream]
+	b.mu.Unlock()
+	if known {
+		return stream, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+	defer cancel()
+	_, err := b.js.Stream(ctx, stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = b.js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:      stream,
+			Subjects:  []string{topic},
+			Retention: jetstream.LimitsPolicy,
+			Storage:   jetstream.FileStorage,
+		})
+		// Another client may have created it meanwhile
+		if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set up stream %s: %w", stream, err)
+	}
+
+	b.mu.Lock()
+	b.streams[stream] = true
+	b.mu.Unlock()
+	return stream, nil
+}
+
+// Publish publishes each message and waits for JetStream to store it. A
+// message carrying an outbox ID is deduplicated by it, so an outbox entry
+// relayed twice is only stored once.
+func (b *natsBus) Publish(ctx context.Context, topic string, messages ...Message) error {
+	if _, err := b.ensureStream(ctx, topic); err != nil {
+		return err
+	}
+	for _, message := range messages {
+		msg := nats.NewMsg(topic)
+		msg.Data = message.Value
+		var options []jetstream.PublishOpt
+		for _, header := range message.Headers {
+			msg.Header.Add(header.Key, string(header.Value))
+			if header.Key == "outbox-id" {
+				options = append(options, jetstream.WithMsgID(string(header.Value)))
+			}
+		}
+		if len(message.Key) > 0 {
+			msg.Header.Set("message-key", string(message.Key))
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+		_, err := b.js.PublishMsg(publishCtx, msg, options...)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// Subscribe creates, or joins, the group's durable consumer of the topic
+func (b *natsBus) Subscribe(topic, groupID string) (Subscription, error) {
+	ctx := context.Background()
+	stream, err := b.ensureStream(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	durable := streamName(groupID)
+
+	ctx, cancel := context.WithTimeout(ctx, natsRequestTimeout)
+	defer cancel()
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		FilterSubject: topic,
+		AckWait:       natsAckWait,
+		MaxAckPending: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer %s of stream %s: %w", durable, stream, err)
+	}
+	return &natsSubscription{topic: topic, consumer: consumer, done: make(chan struct{})}, nil
+}
+
+func (b *natsBus) Close() error {
+	b.conn.Close()
+	return nil
+}
+
+type natsSubscription struct {
+	topic    string
+	consumer jetstream.Consumer
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (s *natsSubscription) Fetch(ctx context.Context) (Message, error) {
+	for {
+		select {
+		case <-s.done:
+			return Message{}, ErrBusClosed
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		default:
+		}
+
+		batch, err := s.consumer.Fetch(1, jetstream.FetchMaxWait(natsFetchExpiry))
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return Message{}, ErrBusClosed
+		}
+		if err != nil {
+			return Message{}, fmt.Errorf("JetStream fetch from %s failed: %w", s.topic, err)
+		}
+		select {
+		case msg, ok := <-batch.Messages():
+			if ok {
+				return s.toMessage(msg), nil
+			}
+			// No message yet, or the request expired
+			if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+				return Message{}, fmt.Errorf("JetStream fetch from %s failed: %w", s.topic, err)
+			}
+		case <-s.done:
+			return Message{}, ErrBusClosed
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+// toMessage converts a delivered message. Its stream sequence is its offset.
+func (s *natsSubscription) toMessage(msg jetstream.Msg) Message {
+	message := Message{Topic: s.topic, Value: msg.Data(), Time: time.Now(), ack: msg}
+	for key, values := range msg.Headers() {
+		switch key {
+		case "message-key":
+			message.Key = []byte(values[0])
+		case jetstream.MsgIDHeader:
+		default:
+			for _, value := range values {
+				message.Headers = append(message.Headers, Header{Key: key, Value: []byte(value)})
+			}
+		}
+	}
+	if metadata, err := msg.Metadata(); err == nil {
+		message.Offset = int64(metadata.Sequence.Stream)
+		message.Time = metadata.Timestamp
+	}
+	return message
+}
+
+func (s *natsSubscription) Commit(ctx context.Context, message Message) error {
+	if message.ack == nil {
+		return nil
+	}
+	return message.ack.Ack()
+}
+
+// Close stops fetching; a message fetched but not committed is delivered
+// again once its acknowledgement wait has passed
+func (s *natsSubscription) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
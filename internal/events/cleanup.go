@@ -0,0 +1,240 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// OutboxArchive keeps published outbox events somewhere cheaper than the
+// outbox table before the cleaner removes them
+type OutboxArchive interface {
+	Archive(ctx context.Context, events []*database.OutboxEvent) error
+}
+
+// DirArchive archives events as JSON lines, one file per batch, under a
+// directory on cold storage such as a mounted bucket
+type DirArchive struct {
+	Dir string
+}
+
+// archivedEvent is the line written for each archived event
+type archivedEvent struct {
+	ID            string          `json:"id"`
+	EventType     string          `json:"eventType"`
+	AggregateID   string          `json:"aggregateId"`
+	AggregateType string          `json:"aggregateType"`
+	PartitionKey  string          `json:"partitionKey,omitempty"`
+	Sequence      int64           `json:"sequence,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	PublishedAt   *time.Time      `json:"publishedAt,omitempty"`
+}
+
+// Archive writes the events to a new file named after the first event's
+// publish date and ID, so batches never overwrite each other
+func (a *DirArchive) Archive(ctx context.Context, events []*database.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	first := events[0]
+	day := first.CreatedAt
+	if first.PublishedAt != nil {
+		day = *first.PublishedAt
+	}
+	dir := filepath.Join(a.Dir, day.UTC().Format("2006/01/02"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("outbox-%s.jsonl", first.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %v", err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, event := range events {
+		line := archivedEvent{
+			ID:            event.ID,
+			EventType:     event.EventType,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			PartitionKey:  event.PartitionKey,
+			Sequence:      event.Sequence,
+			Payload:       json.RawMessage(event.Payload),
+			CreatedAt:     event.CreatedAt,
+			PublishedAt:   event.PublishedAt,
+		}
+		if event.Metadata != "" {
+			line.Metadata = json.RawMessage(event.Metadata)
+		}
+		if err := encoder.Encode(line); err != nil {
+			file.Close()
+			os.Remove(path)
+			return fmt.Errorf("failed to archive event %s: %v", event.ID, err)
+		}
+	}
+	// Sync so a crash after deleting the rows cannot lose the archive
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to sync archive file: %v", err)
+	}
+	return file.Close()
+}
+
+// Reasons the cleaner removes events, used as the metric label
+const (
+	OutboxCleanupAge  = "age"
+	OutboxCleanupRows = "rows"
+)
+
+// OutboxCleaner removes published outbox events so the table stays small
+// enough not to slow the writes that add to it. Events published more than
+// MaxAge ago are removed, then the oldest published events beyond MaxRows.
+// Either limit is off when zero. With an archive, each batch is archived
+// before it is deleted, and a batch that fails to archive is kept. The last
+// event of each partition key is always kept, since the publisher numbers
+// the key's next event from it.
+type OutboxCleaner struct {
+	repo      database.Repository
+	archive   OutboxArchive
+	MaxAge    time.Duration
+	MaxRows   int64
+	BatchSize int
+}
+
+// OutboxCleanupResult reports what a cleanup pass removed
+type OutboxCleanupResult struct {
+	RemovedByAge  int `json:"removedByAge"`
+	RemovedByRows int `json:"removedByRows"`
+	Archived      int `json:"archived"`
+}
+
+// NewOutboxCleanerFromEnv creates a cleaner configured by the
+// OUTBOX_RETENTION_* variables, archiving under OUTBOX_ARCHIVE_DIR when set
+func NewOutboxCleanerFromEnv(repo database.Repository) *OutboxCleaner {
+	cleaner := &OutboxCleaner{
+		repo:      repo,
+		MaxAge:    time.Duration(common.GetEnvAsInt("OUTBOX_RETENTION_HOURS", 168)) * time.Hour,
+		MaxRows:   int64(common.GetEnvAsInt("OUTBOX_RETENTION_MAX_ROWS", 1000000)),
+		BatchSize: common.GetEnvAsInt("OUTBOX_CLEANUP_BATCH_SIZE", 500),
+	}
+	if dir := common.GetEnv("OUTBOX_ARCHIVE_DIR", ""); dir != "" {
+		cleaner.archive = &DirArchive{Dir: dir}
+	}
+	return cleaner
+}
+
+// Run cleans up every interval until the context is cancelled
+func (c *OutboxCleaner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := c.Cleanup(ctx, time.Now())
+			if err != nil {
+				log.Printf("Outbox cleanup failed: %v", err)
+			}
+			if removed := result.RemovedByAge + result.RemovedByRows; removed > 0 {
+				common.Info("Outbox cleanup removed %d events (%d by age, %d by row limit), archived %d",
+					removed, result.RemovedByAge, result.RemovedByRows, result.Archived)
+			}
+		}
+	}
+}
+
+// Cleanup removes events past either retention limit as of now. What was
+// removed before an error is still reported.
+func (c *OutboxCleaner) Cleanup(ctx context.Context, now time.Time) (OutboxCleanupResult, error) {
+	var result OutboxCleanupResult
+	defer func() {
+		common.RecordOutboxCleanup(result.Archived, map[string]int{
+			OutboxCleanupAge:  result.RemovedByAge,
+			OutboxCleanupRows: result.RemovedByRows,
+		})
+	}()
+
+	if c.MaxAge > 0 {
+		removed, archived, err := c.prune(ctx, now.Add(-c.MaxAge), -1)
+		result.RemovedByAge, result.Archived = removed, archived
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if c.MaxRows > 0 {
+		published, err := c.repo.OutboxEventRepository().CountByStatus("published")
+		if err != nil {
+			return result, fmt.Errorf("failed to count published events: %v", err)
+		}
+		if excess := published - c.MaxRows; excess > 0 {
+			removed, archived, err := c.prune(ctx, now, int(excess))
+			result.RemovedByRows = removed
+			result.Archived += archived
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// prune removes prunable events published before the cutoff in batches,
+// oldest first, stopping after limit events unless limit is negative
+func (c *OutboxCleaner) prune(ctx context.Context, publishedBefore time.Time, limit int) (int, int, error) {
+	removed, archived := 0, 0
+	for limit < 0 || removed < limit {
+		if err := ctx.Err(); err != nil {
+			return removed, archived, err
+		}
+		batch := c.BatchSize
+		if batch <= 0 {
+			batch = 500
+		}
+		if limit >= 0 && limit-removed < batch {
+			batch = limit - removed
+		}
+
+		events, err := c.repo.OutboxEventRepository().ListPrunable(publishedBefore, batch)
+		if err != nil {
+			return removed, archived, fmt.Errorf("failed to list prunable events: %v", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		if c.archive != nil {
+			if err := c.archive.Archive(ctx, events); err != nil {
+				return removed, archived, fmt.Errorf("failed to archive events: %v", err)
+			}
+			archived += len(events)
+		}
+
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		deleted, err := c.repo.OutboxEventRepository().DeleteByIDs(ids)
+		if err != nil {
+			return removed, archived, fmt.Errorf("failed to delete events: %v", err)
+		}
+		removed += int(deleted)
+		if len(events) < batch {
+			break
+		}
+	}
+	return removed, archived, nil
+}
@@ -0,0 +1,222 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message formats the publisher can emit
+const (
+	FormatNative                 = "native"                 // Event JSON with platform headers
+	FormatCloudEventsStructured  = "cloudevents-structured" // Whole CloudEvent as the message value
+	FormatCloudEventsBinary      = "cloudevents-binary"     // Event data as the value, attributes as ce_ headers
+	CloudEventsSpecVersion       = "1.0"
+	CloudEventsContentType       = "application/cloudevents+json; charset=UTF-8"
+	cloudEventsHeaderPrefix      = "ce_"
+	defaultCloudEventsTypePrefix = "io.agentpayments."
+)
+
+// CloudEvent is a CloudEvents 1.0 envelope in the JSON event format. The
+// platform's event metadata travels as extension attributes.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+
+	// Extension attributes
+	PartitionKey  string `json:"partitionkey,omitempty"` // Kafka partitioning extension
+	AggregateType string `json:"aggregatetype,omitempty"`
+	CorrelationID string `json:"correlationid,omitempty"`
+	CausationID   string `json:"causationid,omitempty"`
+	TraceParent   string `json:"traceparent,omitempty"` // Distributed tracing extension
+	UserID        string `json:"userid,omitempty"`
+	EventVersion  string `json:"eventversion,omitempty"`
+	Sequence      string `json:"sequence,omitempty"` // Sequence extension, the decimal event sequence
+}
+
+// ToCloudEvent wraps a platform event. Event types are prefixed, e.g.
+// "payment.completed" becomes "io.agentpayments.payment.completed", and the
+// source is the emitting service as a URI reference.
+func ToCloudEvent(event *Event, typePrefix string) (*CloudEvent, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event data: %v", err)
+	}
+
+	source := event.Metadata.Source
+	if source == "" {
+		source = "unknown"
+	}
+
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          "/agent-payments/" + source,
+		Type:            typePrefix + string(event.Type),
+		Subject:         event.AggregateID,
+		Time:            event.Timestamp.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            data,
+		PartitionKey:    PartitionKey(event),
+		AggregateType:   event.AggregateType,
+		CorrelationID:   event.Metadata.CorrelationID,
+		CausationID:     event.Metadata.CausationID,
+		TraceParent:     event.Metadata.TraceParent,
+		UserID:          event.Metadata.UserID,
+		EventVersion:    strconv.Itoa(event.Version),
+		Sequence:        sequenceAttribute(event.Sequence),
+	}, nil
+}
+
+// ToEvent converts a CloudEvent back to a platform event
+func (ce *CloudEvent) ToEvent(typePrefix string) (*Event, error) {
+	event := &Event{
+		ID:            ce.ID,
+		Type:          EventType(strings.TrimPrefix(ce.Type, typePrefix)),
+		AggregateID:   ce.Subject,
+		AggregateType: ce.AggregateType,
+		Metadata: EventMetadata{
+			Source:        strings.TrimPrefix(ce.Source, "/agent-payments/"),
+			UserID:        ce.UserID,
+			CorrelationID: ce.CorrelationID,
+			CausationID:   ce.CausationID,
+			TraceParent:   ce.TraceParent,
+		},
+		Version: 1,
+	}
+	if ce.Time != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, ce.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time attribute: %v", err)
+		}
+		event.Timestamp = timestamp
+	}
+	if ce.EventVersion != "" {
+		if version, err := strconv.Atoi(ce.EventVersion); err == nil {
+			event.Version = version
+		}
+	}
+	if ce.Sequence != "" {
+		sequence, err := strconv.ParseInt(ce.Sequence, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sequence attribute: %v", err)
+		}
+		event.Sequence = sequence
+	}
+	if len(ce.Data) > 0 {
+		if err := json.Unmarshal(ce.Data, &event.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event data: %v", err)
+		}
+	}
+	return event, nil
+}
+
+// structuredMessage encodes the CloudEvent as the message value
+func (ce *CloudEvent) structuredMessage() (Message, error) {
+	value, err := json.Marshal(ce)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Key:     []byte(ce.PartitionKey),
+		Value:   value,
+		Headers: []Header{{Key: "content-type", Value: []byte(CloudEventsContentType)}},
+	}, nil
+}
+
+// binaryMessage carries the data as the value and each attribute as a ce_ header
+func (ce *CloudEvent) binaryMessage() Message {
+	headers := []Header{{Key: "content-type", Value: []byte(ce.DataContentType)}}
+	for _, attribute := range ce.attributes() {
+		if attribute[1] != "" {
+			headers = append(headers, Header{Key: cloudEventsHeaderPrefix + attribute[0], Value: []byte(attribute[1])})
+		}
+	}
+	return Message{
+		Key:     []byte(ce.PartitionKey),
+		Value:   ce.Data,
+		Headers: headers,
+	}
+}
+
+// attributes lists context attributes by name, except datacontenttype which
+// binary mode carries in the content-type header
+func (ce *CloudEvent) attributes() [][2]string {
+	return [][2]string{
+		{"specversion", ce.SpecVersion},
+		{"id", ce.ID},
+		{"source", ce.Source},
+		{"type", ce.Type},
+		{"subject", ce.Subject},
+		{"time", ce.Time},
+		{"partitionkey", ce.PartitionKey},
+		{"aggregatetype", ce.AggregateType},
+		{"correlationid", ce.CorrelationID},
+		{"causationid", ce.CausationID},
+		{"traceparent", ce.TraceParent},
+		{"userid", ce.UserID},
+		{"eventversion", ce.EventVersion},
+		{"sequence", ce.Sequence},
+	}
+}
+
+func sequenceAttribute(sequence int64) string {
+	if sequence <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(sequence, 10)
+}
+
+// DecodeMessage reads a platform event from a message in any format the
+// publisher emits
+func DecodeMessage(message *Message, typePrefix string) (*Event, error) {
+	headers := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		headers[strings.ToLower(header.Key)] = string(header.Value)
+	}
+
+	switch {
+	case headers[cloudEventsHeaderPrefix+"specversion"] != "":
+		ce := &CloudEvent{DataContentType: headers["content-type"], Data: message.Value}
+		values := map[string]*string{
+			"specversion":   &ce.SpecVersion,
+			"id":            &ce.ID,
+			"source":        &ce.Source,
+			"type":          &ce.Type,
+			"subject":       &ce.Subject,
+			"time":          &ce.Time,
+			"partitionkey":  &ce.PartitionKey,
+			"aggregatetype": &ce.AggregateType,
+			"correlationid": &ce.CorrelationID,
+			"causationid":   &ce.CausationID,
+			"traceparent":   &ce.TraceParent,
+			"userid":        &ce.UserID,
+			"eventversion":  &ce.EventVersion,
+			"sequence":      &ce.Sequence,
+		}
+		for name, value := range values {
+			*value = headers[cloudEventsHeaderPrefix+name]
+		}
+		return ce.ToEvent(typePrefix)
+	case strings.HasPrefix(headers["content-type"], "application/cloudevents+json"):
+		var ce CloudEvent
+		if err := json.Unmarshal(message.Value, &ce); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cloud event: %v", err)
+		}
+		return ce.ToEvent(typePrefix)
+	default:
+		var event Event
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %v", err)
+		}
+		return &event, nil
+	}
+}
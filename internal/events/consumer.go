@@ -6,69 +6,150 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/example/agent-payments/internal/database"
-	"github.com/segmentio/kafka-go"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // EventHandler defines the interface for handling events
 type EventHandler interface {
 	HandleEvent(ctx context.Context, event *Event) error
-	CanHandle(eventType EventType) bool
+	EventTypes() []EventType // Event types the handler is registered for
 }
 
-// EventConsumer handles consuming and processing events from Kafka
+// EventConsumer handles consuming and processing events from the message bus
 type EventConsumer struct {
-	reader       *kafka.Reader
-	handlers     []EventHandler
+	bus          MessageBus
+	subscription Subscription
+	dispatcher   *Dispatcher
 	repo         database.Repository
 	topic        string
 	groupID      string
+	typePrefix   string
+	sequencer    *SequenceChecker
+	handleMu     sync.Mutex         // Serializes handling, so released events keep their order
+	heldMessages map[string]Message // Messages of held events, by event ID
+	dlqTopic     string             // Topic dead-lettered messages are published to, if any
 	wg           sync.WaitGroup
 	shutdownChan chan struct{}
+	cancel       context.CancelFunc
 }
 
-// NewEventConsumer creates a new event consumer
-func NewEventConsumer(repo database.Repository, kafkaBrokers []string, topic, groupID string) *EventConsumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  kafkaBrokers,
-		Topic:    topic,
-		GroupID:  groupID,
-		MinBytes: 10e3, // 10KB
-		MaxBytes: 10e6, // 10MB
-	})
+// NewEventConsumer creates a new event consumer. Events that arrive ahead of
+// their sequence wait up to EVENT_REORDER_WINDOW_SECONDS for the events
+// before them. Failing handlers are tried EVENT_HANDLER_MAX_ATTEMPTS (3)
+// times, EVENT_HANDLER_RETRY_DELAY_MS (200) apart and doubling, before the
+// message is dead-lettered and published to the topic named with
+// EVENT_DLQ_TOPIC_SUFFIX (".dlq"). The consumer joins its group on bus when
+// started.
+func NewEventConsumer(repo database.Repository, bus MessageBus, topic, groupID string) *EventConsumer {
+	dispatcher := NewDispatcher()
+	dispatcher.MaxAttempts = common.GetEnvAsInt("EVENT_HANDLER_MAX_ATTEMPTS", 3)
+	dispatcher.RetryDelay = time.Duration(common.GetEnvAsInt("EVENT_HANDLER_RETRY_DELAY_MS", 200)) * time.Millisecond
+
+	dlqTopic := ""
+	if suffix := common.GetEnv("EVENT_DLQ_TOPIC_SUFFIX", ".dlq"); suffix != "" {
+		dlqTopic = topic + suffix
+	}
 
 	return &EventConsumer{
-		reader:       reader,
-		handlers:     []EventHandler{},
+		bus:          bus,
+		dispatcher:   dispatcher,
 		repo:         repo,
 		topic:        topic,
 		groupID:      groupID,
+		typePrefix:   cloudEventsTypePrefix(),
+		sequencer:    NewSequenceChecker(time.Duration(common.GetEnvAsInt("EVENT_REORDER_WINDOW_SECONDS", 30)) * time.Second),
+		heldMessages: make(map[string]Message),
+		dlqTopic:     dlqTopic,
 		shutdownChan: make(chan struct{}),
 	}
 }
 
-// RegisterHandler registers an event handler
+// Topic is the topic the consumer reads
+func (c *EventConsumer) Topic() string {
+	return c.topic
+}
+
+// GroupID is the consumer group the consumer commits offsets under
+func (c *EventConsumer) GroupID() string {
+	return c.groupID
+}
+
+// OrderingStats reports the out-of-order deliveries the consumer has seen
+func (c *EventConsumer) OrderingStats() OrderingStats {
+	return c.sequencer.Stats()
+}
+
+// RegisterHandler registers an event handler for the event types it declares
 func (c *EventConsumer) RegisterHandler(handler EventHandler) {
-	c.handlers = append(c.handlers, handler)
+	c.dispatcher.Register(handler)
+}
+
+// HandlerMetrics reports each handler's successes, failures and latency
+func (c *EventConsumer) HandlerMetrics() []HandlerMetrics {
+	return c.dispatcher.Metrics()
 }
 
-// Start starts consuming events
+// Start joins the consumer group and starts consuming events
 func (c *EventConsumer) Start(ctx context.Context) error {
-	c.wg.Add(1)
+	subscription, err := c.bus.Subscribe(c.topic, c.groupID)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %v", c.topic, err)
+	}
+	c.subscription = subscription
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.wg.Add(2)
 	go c.consumeEvents(ctx)
-	log.Printf("Event consumer started for topic: %s, group: %s", c.topic, c.groupID)
+	go c.expireHeld(ctx)
+	log.Printf("Event consumer started for topic: %s, group: %s, bus: %s", c.topic, c.groupID, c.bus.Name())
 	return nil
 }
 
-// Stop stops the event consumer
+// Stop stops the event consumer, first handling any events still held for
+// reordering since their offsets are already committed
 func (c *EventConsumer) Stop() error {
 	close(c.shutdownChan)
+	if c.cancel != nil {
+		c.cancel()
+	}
 	c.wg.Wait()
-	return c.reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), heldFlushTimeout)
+	defer cancel()
+	c.handleReleased(ctx, c.sequencer.Flush())
+
+	if c.subscription == nil {
+		return nil
+	}
+	return c.subscription.Close()
+}
+
+// expireHeld handles held events whose gap was not filled within the window
+func (c *EventConsumer) expireHeld(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.shutdownChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if released := c.sequencer.Expire(time.Now()); len(released) > 0 {
+				log.Printf("Reorder window expired, handling %d events past a sequence gap", len(released))
+				c.handleReleased(ctx, released)
+			}
+		}
+	}
 }
 
-// consumeEvents continuously consumes events from Kafka
+// consumeEvents continuously consumes events from the bus
 func (c *EventConsumer) consumeEvents(ctx context.Context) {
 	defer c.wg.Done()
 
@@ -81,73 +162,129 @@ func (c *EventConsumer) consumeEvents(ctx context.Context) {
 			log.Println("Event consumer context cancelled")
 			return
 		default:
-			message, err := c.reader.ReadMessage(ctx)
+			message, err := c.subscription.Fetch(ctx)
+			if err == ErrBusClosed {
+				return
+			}
 			if err != nil {
-				log.Printf("Error reading message: %v", err)
+				if ctx.Err() == nil {
+					log.Printf("Error reading message: %v", err)
+					time.Sleep(fetchRetryDelay)
+				}
 				continue
 			}
 
-			if err := c.processMessage(ctx, &message); err != nil {
+			if event, err := c.processMessage(ctx, &message); err != nil {
 				log.Printf("Error processing message: %v", err)
-				// In a production system, you might want to send failed messages to a dead letter queue
+				if err := c.deadLetter(ctx, &message, event, err); err != nil {
+					// Leave the offset uncommitted so the message is read again
+					log.Printf("Stopping before committing %s/%d@%d: %v", message.Topic, message.Partition, message.Offset, err)
+					return
+				}
 			}
 
-			// Commit the message offset
-			if err := c.reader.CommitMessages(ctx, message); err != nil {
+			// Commit the message offset, the message handled or dead-lettered
+			if err := c.subscription.Commit(ctx, message); err != nil {
 				log.Printf("Error committing message: %v", err)
 			}
 		}
 	}
 }
 
-// processMessage processes a single Kafka message
-func (c *EventConsumer) processMessage(ctx context.Context, message *kafka.Message) error {
-	// Parse the event from the message
-	var event Event
-	if err := json.Unmarshal(message.Value, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal event: %v", err)
+// processMessage processes a single message, along with any held
+// events it puts back in sequence. Late and repeated events are returned as
+// errors, so they are dead-lettered instead of handled. The decoded event is
+// returned with the error, or nil if the message could not be decoded.
+func (c *EventConsumer) processMessage(ctx context.Context, message *Message) (*Event, error) {
+	// Parse the event from the message, in native or CloudEvents format
+	event, err := DecodeMessage(message, c.typePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	c.handleMu.Lock()
+	defer c.handleMu.Unlock()
+
+	ready, outcome := c.sequencer.Accept(event, time.Now())
+	switch outcome {
+	case SequenceHeld:
+		log.Printf("Holding event %s: sequence %d of %s arrived early", event.ID, event.Sequence, PartitionKey(event))
+		c.heldMessages[event.ID] = *message
+		return event, nil
+	case SequenceDuplicate:
+		return event, fmt.Errorf("out-of-order event %s: sequence %d of %s was already handled", event.ID, event.Sequence, PartitionKey(event))
+	case SequenceOverflow:
+		return event, fmt.Errorf("out-of-order event %s: too many events of %s held before sequence %d", event.ID, PartitionKey(event), event.Sequence)
 	}
 
-	log.Printf("Processing event: %s (%s)", event.Type, event.ID)
+	if err := c.handleEvent(ctx, ready[0], nil); err != nil {
+		return event, err
+	}
+	c.handleHeld(ctx, ready[1:])
+	return event, nil
+}
+
+// handleReleased handles events released from the sequence checker
+func (c *EventConsumer) handleReleased(ctx context.Context, events []*Event) {
+	c.handleMu.Lock()
+	defer c.handleMu.Unlock()
+	c.handleHeld(ctx, events)
+}
 
-	// Find and execute appropriate handlers
-	handled := false
-	for _, handler := range c.handlers {
-		if handler.CanHandle(event.Type) {
-			if err := handler.HandleEvent(ctx, &event); err != nil {
-				log.Printf("Handler error for event %s: %v", event.Type, err)
-				return err
+// handleHeld handles previously held events, dead-lettering their original
+// messages on failure. The caller holds handleMu.
+func (c *EventConsumer) handleHeld(ctx context.Context, events []*Event) {
+	for _, event := range events {
+		message := c.heldMessages[event.ID]
+		delete(c.heldMessages, event.ID)
+		if err := c.handleEvent(ctx, event, nil); err != nil {
+			log.Printf("Error processing held event %s: %v", event.ID, err)
+			if err := c.deadLetter(ctx, &message, event, err); err != nil {
+				// The offset is already committed, so the event is lost
+				log.Printf("Lost held event %s (%s/%d@%d): %v", event.ID, message.Topic, message.Partition, message.Offset, err)
 			}
-			handled = true
 		}
 	}
+}
 
-	if !handled {
-		log.Printf("No handler found for event type: %s", event.Type)
+// handleEvent runs the named handlers of the event's type, or all of them
+// when handlers is empty, in a span continuing the trace the event was
+// published in
+func (c *EventConsumer) handleEvent(ctx context.Context, event *Event, handlers []string) (err error) {
+	log.Printf("Processing event: %s (%s)", event.Type, event.ID)
+	ctx = common.ContextWithTraceParent(ctx, event.Metadata.TraceParent)
+	if event.Metadata.CorrelationID != "" {
+		ctx = common.WithCorrelationID(ctx, event.Metadata.CorrelationID)
 	}
-
-	return nil
+	ctx, span := common.StartSpan(ctx, "event."+string(event.Type),
+		attribute.String("event.id", event.ID),
+		attribute.String("event.aggregate_id", event.AggregateID),
+	)
+	defer func() { common.EndSpan(span, err) }()
+	return c.dispatcher.DispatchTo(ctx, event, handlers)
 }
 
+// fetchRetryDelay is the pause after failing to read from the bus
+const fetchRetryDelay = time.Second
+
 // PaymentEventHandler handles payment-related events
 type PaymentEventHandler struct {
-	repo database.Repository
+	repo   database.Repository
+	states *workflowstate.Machine
 }
 
+// eventsActor is the actor recorded for transitions made by payment events
+const eventsActor = "events"
+
 // NewPaymentEventHandler creates a new payment event handler
 func NewPaymentEventHandler(repo database.Repository) *PaymentEventHandler {
-	return &PaymentEventHandler{repo: repo}
+	return &PaymentEventHandler{repo: repo, states: workflowstate.New(repo)}
 }
 
-// CanHandle returns true if this handler can handle the given event type
-func (h *PaymentEventHandler) CanHandle(eventType EventType) bool {
-	switch eventType {
-	case EventPaymentInitiated, EventPaymentAuthorized, EventPaymentRiskEvaluated,
-		EventPaymentRouted, EventPaymentExecuted, EventPaymentCompleted, EventPaymentFailed:
-		return true
-	default:
-		return false
-	}
+// EventTypes returns the payment events this handler handles
+func (h *PaymentEventHandler) EventTypes() []EventType {
+	return []EventType{EventPaymentInitiated, EventPaymentRiskEvaluated, EventPaymentRouted,
+		EventPaymentExecuted, EventPaymentCompleted, EventPaymentFailed}
 }
 
 // HandleEvent handles payment events
@@ -188,10 +325,13 @@ func (h *PaymentEventHandler) handlePaymentInitiated(ctx context.Context, event
 		Counterparty: data.Counterparty,
 		Rail:         data.Rail,
 		Description:  data.Description,
-		Status:       "initiated",
+		Status:       string(workflowstate.Pending),
 	}
 
-	return h.repo.PaymentWorkflowRepository().Create(workflow)
+	if err := h.repo.PaymentWorkflowRepository().Create(workflow); err != nil {
+		return err
+	}
+	return h.states.Created(workflow, eventsActor)
 }
 
 // handlePaymentRiskEvaluated handles risk evaluation events
@@ -239,8 +379,8 @@ func (h *PaymentEventHandler) handlePaymentRouted(ctx context.Context, event *Ev
 		return fmt.Errorf("failed to get payment workflow: %v", err)
 	}
 
+	// Routing does not change the workflow's state
 	workflow.Rail = data.SelectedRail
-	workflow.Status = "routed"
 
 	return h.repo.PaymentWorkflowRepository().Update(workflow)
 }
@@ -280,8 +420,7 @@ func (h *PaymentEventHandler) handlePaymentCompleted(ctx context.Context, event
 		return fmt.Errorf("failed to get payment workflow: %v", err)
 	}
 
-	workflow.Status = "completed"
-	return h.repo.PaymentWorkflowRepository().Update(workflow)
+	return h.states.Transition(workflow, workflowstate.Completed, "Payment completed event", eventsActor)
 }
 
 // handlePaymentFailed handles payment failure events
@@ -294,6 +433,5 @@ func (h *PaymentEventHandler) handlePaymentFailed(ctx context.Context, event *Ev
 		return fmt.Errorf("failed to get payment workflow: %v", err)
 	}
 
-	workflow.Status = "failed"
-	return h.repo.PaymentWorkflowRepository().Update(workflow)
+	return h.states.Transition(workflow, workflowstate.Failed, "Payment failed event", eventsActor)
 }
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Dead letters
+//
+// A message is dead-lettered when it cannot be decoded, arrives out of
+// order, or a handler still fails on it after its retries. It is stored in
+// dead_letter_events before its offset is committed: if the store cannot be
+// written the consumer keeps retrying, and stops without committing on
+// shutdown, so the message is read again rather than lost. It is also
+// published, with headers describing the failure, to the consumer's DLQ
+// topic for tools that watch it. Replay runs a stored message through the
+// handlers that failed on it.
+
+const (
+	// maxDeadLetterDelay caps the wait between attempts to store a dead letter
+	maxDeadLetterDelay = 30 * time.Second
+	// heldFlushTimeout bounds handling held events, and dead-lettering those
+	// that fail, when a consumer stops
+	heldFlushTimeout = 10 * time.Second
+	// maxErrorLength is the longest error kept with a dead letter
+	maxErrorLength = 500
+)
+
+// Headers added to messages published to a DLQ topic
+const (
+	dlqHeaderError     = "x-dead-letter-error"
+	dlqHeaderTopic     = "x-dead-letter-topic"
+	dlqHeaderPartition = "x-dead-letter-partition"
+	dlqHeaderOffset    = "x-dead-letter-offset"
+	dlqHeaderHandlers  = "x-dead-letter-handlers"
+)
+
+// deadLetter stores a message that could not be processed, and publishes it
+// to the DLQ topic. Storing is retried until it succeeds; the error is
+// returned only if ctx ends first. event is nil if the message could not
+// be decoded.
+func (c *EventConsumer) deadLetter(ctx context.Context, message *Message, event *Event, cause error) error {
+	record := &database.DeadLetterEvent{
+		Topic:        message.Topic,
+		GroupID:      c.groupID,
+		Partition:    message.Partition,
+		Offset:       message.Offset,
+		MessageKey:   string(message.Key),
+		Payload:      string(message.Value),
+		Headers:      encodeHeaders(message.Headers),
+		ErrorMessage: truncateError(cause.Error()),
+		Status:       database.DeadLetterPending,
+	}
+	if event != nil {
+		record.EventID = event.ID
+		record.EventType = string(event.Type)
+	}
+	var dispatchErr *DispatchError
+	if errors.As(cause, &dispatchErr) {
+		record.FailedHandlers = strings.Join(dispatchErr.Handlers(), ",")
+		for _, failure := range dispatchErr.Failures {
+			if failure.Attempts > record.Attempts {
+				record.Attempts = failure.Attempts
+			}
+		}
+	}
+
+	if c.publishDeadLetter(ctx, message, record) {
+		record.DLQTopic = c.dlqTopic
+	}
+
+	delay := time.Second
+	for {
+		err := c.repo.DeadLetterEventRepository().Create(record)
+		if err == nil {
+			common.RecordEventDeadLettered(message.Topic)
+			return nil
+		}
+		log.Printf("Failed to dead-letter message %s/%d@%d, retrying in %s: %v", message.Topic, message.Partition, message.Offset, delay, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("message was not dead-lettered: %v", err)
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxDeadLetterDelay {
+			delay = maxDeadLetterDelay
+		}
+	}
+}
+
+// publishDeadLetter publishes a dead-lettered message to the DLQ topic,
+// reporting whether it was published
+func (c *EventConsumer) publishDeadLetter(ctx context.Context, message *Message, record *database.DeadLetterEvent) bool {
+	if c.dlqTopic == "" {
+		return false
+	}
+	headers := append([]Header{}, message.Headers...)
+	headers = append(headers,
+		Header{Key: dlqHeaderError, Value: []byte(record.ErrorMessage)},
+		Header{Key: dlqHeaderTopic, Value: []byte(message.Topic)},
+		Header{Key: dlqHeaderPartition, Value: []byte(strconv.Itoa(message.Partition))},
+		Header{Key: dlqHeaderOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+	)
+	if record.FailedHandlers != "" {
+		headers = append(headers, Header{Key: dlqHeaderHandlers, Value: []byte(record.FailedHandlers)})
+	}
+
+	err := c.bus.Publish(ctx, c.dlqTopic, Message{Key: message.Key, Value: message.Value, Headers: headers})
+	if err != nil {
+		log.Printf("Failed to publish message %s/%d@%d to %s: %v", message.Topic, message.Partition, message.Offset, c.dlqTopic, err)
+		return false
+	}
+	return true
+}
+
+// Replay runs a dead-lettered message through the handlers that failed on
+// it, or every handler of its type when none ran, and records the outcome.
+// The sequence check is skipped, since the events after it have already
+// been handled. Handlers that succeed are dropped from the record's failed
+// handlers, so a later replay does not run them twice.
+func (c *EventConsumer) Replay(ctx context.Context, record *database.DeadLetterEvent) error {
+	message := Message{
+		Topic:     record.Topic,
+		Partition: record.Partition,
+		Offset:    record.Offset,
+		Key:       []byte(record.MessageKey),
+		Value:     []byte(record.Payload),
+		Headers:   decodeHeaders(record.Headers),
+	}
+	event, err := DecodeMessage(&message, c.typePrefix)
+	if err == nil {
+		c.handleMu.Lock()
+		err = c.handleEvent(ctx, event, splitHandlers(record.FailedHandlers))
+		c.handleMu.Unlock()
+	}
+
+	now := time.Now()
+	record.ReplayCount++
+	record.LastReplayAt = &now
+	if err != nil {
+		record.ReplayError = truncateError(err.Error())
+		var dispatchErr *DispatchError
+		if errors.As(err, &dispatchErr) {
+			record.FailedHandlers = strings.Join(dispatchErr.Handlers(), ",")
+		}
+	} else {
+		record.Status = database.DeadLetterReplayed
+		record.ReplayError = ""
+	}
+	if updateErr := c.repo.DeadLetterEventRepository().Update(record); updateErr != nil {
+		return fmt.Errorf("failed to record the replay: %v", updateErr)
+	}
+	return err
+}
+
+// encodeHeaders stores message headers as a JSON object
+func encodeHeaders(headers []Header) string {
+	if len(headers) == 0 {
+		return ""
+	}
+	values := make(map[string]string, len(headers))
+	for _, header := range headers {
+		values[header.Key] = string(header.Value)
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// decodeHeaders reads headers stored by encodeHeaders
+func decodeHeaders(encoded string) []Header {
+	if encoded == "" {
+		return nil
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(encoded), &values); err != nil {
+		return nil
+	}
+	headers := make([]Header, 0, len(values))
+	for key, value := range values {
+		headers = append(headers, Header{Key: key, Value: []byte(value)})
+	}
+	return headers
+}
+
+// splitHandlers splits a comma-separated list of handler names
+func splitHandlers(names string) []string {
+	if names == "" {
+		return nil
+	}
+	return strings.Split(names, ",")
+}
+
+func truncateError(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,280 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// HandlerMetrics are a handler's counters for one event type
+type HandlerMetrics struct {
+	Handler       string  `json:"handler"`
+	EventType     string  `json:"eventType"`
+	Handled       int64   `json:"handled"`
+	Failed        int64   `json:"failed"`
+	Panics        int64   `json:"panics"`
+	Retries       int64   `json:"retries"` // Attempts repeated after a failure
+	AverageMillis float64 `json:"averageMillis"`
+	LastError     string  `json:"lastError,omitempty"`
+	LastErrorAt   string  `json:"lastErrorAt,omitempty"`
+	LastHandledAt string  `json:"lastHandledAt,omitempty"`
+}
+
+type handlerEntry struct {
+	name    string
+	handler EventHandler
+	metrics map[EventType]*handlerStats
+}
+
+type handlerStats struct {
+	handled       int64
+	failed        int64
+	panics        int64
+	retries       int64
+	totalDuration time.Duration
+	lastError     string
+	lastErrorAt   time.Time
+	lastHandledAt time.Time
+}
+
+// Dispatcher routes events to the handlers registered for their type. Every
+// handler of a type runs even when another fails, so one broken handler does
+// not hold back the rest. A failing handler is tried up to MaxAttempts
+// times, waiting RetryDelay before the first retry and doubling it after.
+type Dispatcher struct {
+	mu          sync.RWMutex
+	byType      map[EventType][]*handlerEntry
+	handlers    []*handlerEntry
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
+// NewDispatcher creates an empty dispatcher trying each handler once
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{byType: make(map[EventType][]*handlerEntry), MaxAttempts: 1}
+}
+
+// HandlerFailure is a handler that failed on an event on every attempt
+type HandlerFailure struct {
+	Handler  string
+	Attempts int
+	Err      error
+}
+
+// DispatchError reports the handlers that failed on an event
+type DispatchError struct {
+	Failures []HandlerFailure
+}
+
+func (e *DispatchError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = fmt.Sprintf("%s: %v", failure.Handler, failure.Err)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *DispatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// Handlers names the handlers that failed
+func (e *DispatchError) Handlers() []string {
+	names := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		names[i] = failure.Handler
+	}
+	return names
+}
+
+// Register adds a handler for the event types it declares, named after its
+// Go type in metrics
+func (d *Dispatcher) Register(handler EventHandler) {
+	d.RegisterNamed(fmt.Sprintf("%T", handler), handler, handler.EventTypes()...)
+}
+
+// RegisterNamed adds a handler under a name for the given event types
+func (d *Dispatcher) RegisterNamed(name string, handler EventHandler, eventTypes ...EventType) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry := &handlerEntry{name: name, handler: handler, metrics: make(map[EventType]*handlerStats)}
+	for _, eventType := range eventTypes {
+		entry.metrics[eventType] = &handlerStats{}
+		d.byType[eventType] = append(d.byType[eventType], entry)
+	}
+	d.handlers = append(d.handlers, entry)
+}
+
+// Dispatch runs each handler registered for the event's type in registration
+// order. A handler that fails or panics on every attempt is recorded and the
+// others still run; the error is a *DispatchError naming every handler that
+// failed.
+func (d *Dispatcher) Dispatch(ctx context.Context, event *Event) error {
+	return d.DispatchTo(ctx, event, nil)
+}
+
+// DispatchTo runs the named handlers of the event's type, or all of them when
+// names is empty, such as to replay an event to the handlers that failed on
+// it. Naming a handler not registered for the type is a failure.
+func (d *Dispatcher) DispatchTo(ctx context.Context, event *Event, names []string) error {
+	d.mu.RLock()
+	entries := d.byType[event.Type]
+	d.mu.RUnlock()
+
+	if len(entries) == 0 && len(names) == 0 {
+		log.Printf("No handler found for event type: %s", event.Type)
+		return nil
+	}
+
+	var failures []HandlerFailure
+	for _, entry := range entries {
+		if len(names) > 0 && !containsName(names, entry.name) {
+			continue
+		}
+		if attempts, err := d.runWithRetries(ctx, entry, event); err != nil {
+			log.Printf("Handler %s failed on event %s (%s) after %d attempts: %v", entry.name, event.ID, event.Type, attempts, err)
+			failures = append(failures, HandlerFailure{Handler: entry.name, Attempts: attempts, Err: err})
+		}
+	}
+	for _, name := range names {
+		if !containsEntry(entries, name) {
+			failures = append(failures, HandlerFailure{Handler: name, Err: fmt.Errorf("handler is not registered for %s", event.Type)})
+		}
+	}
+	if len(failures) > 0 {
+		return &DispatchError{Failures: failures}
+	}
+	return nil
+}
+
+// runWithRetries runs a handler until it succeeds or MaxAttempts is reached,
+// returning the attempts made and the last error
+func (d *Dispatcher) runWithRetries(ctx context.Context, entry *handlerEntry, event *Event) (int, error) {
+	delay := d.RetryDelay
+	attempt := 1
+	for {
+		err := d.run(ctx, entry, event, attempt > 1)
+		if err == nil || attempt >= d.MaxAttempts {
+			return attempt, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		attempt++
+	}
+}
+
+// run calls one handler, turning a panic into an error, and records the result
+func (d *Dispatcher) run(ctx context.Context, entry *handlerEntry, event *Event, retry bool) (err error) {
+	started := time.Now()
+	panicked := false
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicked = true
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+		d.record(entry, event.Type, time.Since(started), err, panicked, retry)
+	}()
+	return entry.handler.HandleEvent(ctx, event)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsEntry(entries []*handlerEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) record(entry *handlerEntry, eventType EventType, duration time.Duration, err error, panicked, retry bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	outcome := "handled"
+	if panicked {
+		outcome = "panicked"
+	} else if err != nil {
+		outcome = "failed"
+	}
+	common.RecordEventHandled(entry.name, string(eventType), outcome, duration)
+
+	stats := entry.metrics[eventType]
+	now := time.Now()
+	if retry {
+		stats.retries++
+	}
+	stats.totalDuration += duration
+	stats.lastHandledAt = now
+	if err == nil {
+		stats.handled++
+		return
+	}
+	stats.failed++
+	if panicked {
+		stats.panics++
+	}
+	stats.lastError = err.Error()
+	stats.lastErrorAt = now
+}
+
+// Metrics returns each handler's counters per event type, by handler then type
+func (d *Dispatcher) Metrics() []HandlerMetrics {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var metrics []HandlerMetrics
+	for _, entry := range d.handlers {
+		for eventType, stats := range entry.metrics {
+			m := HandlerMetrics{
+				Handler:   entry.name,
+				EventType: string(eventType),
+				Handled:   stats.handled,
+				Failed:    stats.failed,
+				Panics:    stats.panics,
+				Retries:   stats.retries,
+				LastError: stats.lastError,
+			}
+			if calls := stats.handled + stats.failed; calls > 0 {
+				m.AverageMillis = float64(stats.totalDuration.Microseconds()) / 1000 / float64(calls)
+			}
+			if !stats.lastErrorAt.IsZero() {
+				m.LastErrorAt = stats.lastErrorAt.Format(time.RFC3339)
+			}
+			if !stats.lastHandledAt.IsZero() {
+				m.LastHandledAt = stats.lastHandledAt.Format(time.RFC3339)
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	sort.SliceStable(metrics, func(i, j int) bool {
+		if metrics[i].Handler != metrics[j].Handler {
+			return metrics[i].Handler < metrics[j].Handler
+		}
+		return metrics[i].EventType < metrics[j].EventType
+	})
+	return metrics
+}
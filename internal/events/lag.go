@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PartitionLag is how far a consumer group is behind on one partition
+type PartitionLag struct {
+	Partition       int   `json:"partition"`
+	CommittedOffset int64 `json:"committedOffset"` // -1 if the group has committed nothing
+	LatestOffset    int64 `json:"latestOffset"`
+	Lag             int64 `json:"lag"`
+}
+
+// TopicLag is a consumer group's lag on a topic
+type TopicLag struct {
+	GroupID    string         `json:"groupId"`
+	Topic      string         `json:"topic"`
+	Lag        int64          `json:"lag"`
+	Partitions []PartitionLag `json:"partitions"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// ConsumerLag reports the lag of each consumer group on each topic: the
+// messages between the group's committed offset and the end of each
+// partition. A group that has committed nothing lags by the whole partition.
+// Failures for one group and topic are reported on its entry, so the others
+// are still returned.
+func ConsumerLag(ctx context.Context, kafkaBrokers []string, groupIDs, topics []string) ([]TopicLag, error) {
+	client := &kafka.Client{Addr: kafka.TCP(kafkaBrokers...), Timeout: 5 * time.Second}
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic metadata: %v", err)
+	}
+
+	partitions := make(map[string][]int)
+	latestRequest := make(map[string][]kafka.OffsetRequest)
+	for _, topic := range metadata.Topics {
+		if topic.Error != nil {
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			partitions[topic.Name] = append(partitions[topic.Name], partition.ID)
+			latestRequest[topic.Name] = append(latestRequest[topic.Name], kafka.LastOffsetOf(partition.ID))
+		}
+	}
+
+	latest := make(map[string]map[int]int64)
+	if len(latestRequest) > 0 {
+		offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: latestRequest})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list topic offsets: %v", err)
+		}
+		for topic, partitionOffsets := range offsets.Topics {
+			latest[topic] = make(map[int]int64)
+			for _, offset := range partitionOffsets {
+				latest[topic][offset.Partition] = offset.LastOffset
+			}
+		}
+	}
+
+	var lags []TopicLag
+	for _, groupID := range groupIDs {
+		for _, topic := range topics {
+			lag := TopicLag{GroupID: groupID, Topic: topic, Partitions: []PartitionLag{}}
+			if len(partitions[topic]) == 0 {
+				lag.Error = "topic not found"
+				lags = append(lags, lag)
+				continue
+			}
+
+			committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+				GroupID: groupID,
+				Topics:  map[string][]int{topic: partitions[topic]},
+			})
+			if err == nil && committed.Error != nil {
+				err = committed.Error
+			}
+			if err != nil {
+				lag.Error = err.Error()
+				lags = append(lags, lag)
+				continue
+			}
+
+			for _, partition := range committed.Topics[topic] {
+				end := latest[topic][partition.Partition]
+				behind := end - partition.CommittedOffset
+				if partition.CommittedOffset < 0 {
+					behind = end
+				}
+				if behind < 0 {
+					behind = 0
+				}
+				lag.Partitions = append(lag.Partitions, PartitionLag{
+					Partition:       partition.Partition,
+					CommittedOffset: partition.CommittedOffset,
+					LatestOffset:    end,
+					Lag:             behind,
+				})
+				lag.Lag += behind
+			}
+			lags = append(lags, lag)
+		}
+	}
+	return lags, nil
+}
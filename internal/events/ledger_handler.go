@@ -19,14 +19,9 @@ func NewLedgerEventHandler(repo database.Repository) *LedgerEventHandler {
 	return &LedgerEventHandler{repo: repo}
 }
 
-// CanHandle returns true if this handler can handle the given event type
-func (h *LedgerEventHandler) CanHandle(eventType EventType) bool {
-	switch eventType {
-	case EventTransactionPosted, EventAccountCreated, EventBalanceUpdated:
-		return true
-	default:
-		return false
-	}
+// EventTypes returns the ledger events this handler handles
+func (h *LedgerEventHandler) EventTypes() []EventType {
+	return []EventType{EventTransactionPosted, EventAccountCreated, EventBalanceUpdated}
 }
 
 // HandleEvent handles ledger events
@@ -84,6 +79,9 @@ func (h *LedgerEventHandler) handleTransactionPosted(ctx context.Context, event
 		}
 	}
 
+	if err := h.repo.TransactionRepository().Chain(data.TransactionID); err != nil {
+		return fmt.Errorf("failed to chain transaction: %v", err)
+	}
 	return nil
 }
 
@@ -24,8 +24,11 @@ const (
 	EventAgentUpdated EventType = "agent.updated"
 
 	// Consent Events
-	EventConsentCreated EventType = "consent.created"
-	EventConsentRevoked EventType = "consent.revoked"
+	EventConsentCreated  EventType = "consent.created"
+	EventConsentRevoked  EventType = "consent.revoked"
+	EventConsentExpiring EventType = "consent.expiring" // Warns ahead of a consent's expiry
+	EventConsentRenewed  EventType = "consent.renewed"
+	EventConsentUpdated  EventType = "consent.updated" // A new version with changed terms
 
 	// Ledger Events
 	EventTransactionPosted EventType = "transaction.posted"
@@ -43,6 +46,7 @@ type Event struct {
 	Metadata      EventMetadata          `json:"metadata"`
 	Timestamp     time.Time              `json:"timestamp"`
 	Version       int                    `json:"version"`
+	Sequence      int64                  `json:"sequence,omitempty"` // Position among events with the same partition key, from 1
 }
 
 // EventMetadata contains event metadata
@@ -52,6 +56,7 @@ type EventMetadata struct {
 	SessionID     string            `json:"sessionId,omitempty"`
 	CorrelationID string            `json:"correlationId"`
 	CausationID   string            `json:"causationId,omitempty"`
+	TraceParent   string            `json:"traceparent,omitempty"` // W3C traceparent of the span that published the event
 	Headers       map[string]string `json:"headers,omitempty"`
 }
 
@@ -0,0 +1,197 @@
+package events
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ordering guarantees
+//
+// Every event has a partition key, and the publisher writes events with the
+// same key to the same Kafka partition, in sequence order. Kafka preserves
+// order within a partition, so consumers see each key's events in the order
+// they were published. Payment events are keyed by their payment reference
+// (the workflow ID), so every event of a payment shares one partition. Other
+// events are keyed by their aggregate ID.
+//
+// The publisher numbers each key's events from 1. It does not publish an
+// event while an earlier event with the same key is unpublished, so a failed
+// event holds back the later events of its payment until it is retried or
+// requeued. Consumers check the sequence with a SequenceChecker: events that
+// arrive early are held until the gap is filled, and events that arrive late
+// or twice are flagged rather than handled.
+//
+// Adding partitions to a topic moves keys to other partitions, which breaks
+// ordering for events in flight. Drain the outbox and consumers first.
+
+// PartitionKey returns the key an event is published under: the payment
+// reference for payment events, otherwise the aggregate ID
+func PartitionKey(event *Event) string {
+	if strings.HasPrefix(string(event.Type), "payment.") {
+		if paymentID, ok := event.Data["paymentId"].(string); ok && paymentID != "" {
+			return paymentID
+		}
+	}
+	return event.AggregateID
+}
+
+// Sequence check outcomes
+const (
+	SequenceInOrder   = "in_order"  // Next in sequence, or unsequenced
+	SequenceHeld      = "held"      // Early; held until the gap is filled
+	SequenceDuplicate = "duplicate" // At or before the last handled sequence
+	SequenceOverflow  = "overflow"  // Early, but too many are already held
+)
+
+// OrderingStats counts what a SequenceChecker has seen
+type OrderingStats struct {
+	Reordered  int64 `json:"reordered"`  // Held events released once the gap filled
+	Gaps       int64 `json:"gaps"`       // Gaps skipped when the hold window expired
+	Duplicates int64 `json:"duplicates"` // Late or repeated events not handled
+	Held       int   `json:"held"`       // Events held now
+}
+
+type heldEvent struct {
+	event    *Event
+	received time.Time
+}
+
+type keySequence struct {
+	last     int64
+	held     map[int64]heldEvent
+	lastSeen time.Time
+}
+
+// SequenceChecker restores the per-key order of sequenced events. The first
+// event seen for a key sets its starting point, since a consumer may join
+// mid-stream. Held events are kept in memory only.
+type SequenceChecker struct {
+	mu      sync.Mutex
+	keys    map[string]*keySequence
+	stats   OrderingStats
+	Window  time.Duration // How long an early event waits for the gap to fill
+	MaxHeld int           // Early events held per key
+	IdleTTL time.Duration // Keys unseen this long are forgotten
+}
+
+// NewSequenceChecker creates a checker holding early events for the window
+func NewSequenceChecker(window time.Duration) *SequenceChecker {
+	return &SequenceChecker{
+		keys:    make(map[string]*keySequence),
+		Window:  window,
+		MaxHeld: 100,
+		IdleTTL: 24 * time.Hour,
+	}
+}
+
+// Accept checks an event's sequence and returns the events that are ready to
+// handle, in order: the event itself and any held events it releases. Events
+// held, duplicated or rejected for overflow return none.
+func (s *SequenceChecker) Accept(event *Event, now time.Time) ([]*Event, string) {
+	if event.Sequence <= 0 {
+		return []*Event{event}, SequenceInOrder
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := PartitionKey(event)
+	state, ok := s.keys[key]
+	if !ok {
+		s.keys[key] = &keySequence{last: event.Sequence, held: make(map[int64]heldEvent), lastSeen: now}
+		return []*Event{event}, SequenceInOrder
+	}
+	state.lastSeen = now
+
+	switch {
+	case event.Sequence <= state.last:
+		s.stats.Duplicates++
+		return nil, SequenceDuplicate
+	case event.Sequence > state.last+1:
+		if _, exists := state.held[event.Sequence]; exists {
+			s.stats.Duplicates++
+			return nil, SequenceDuplicate
+		}
+		if len(state.held) >= s.MaxHeld {
+			return nil, SequenceOverflow
+		}
+		state.held[event.Sequence] = heldEvent{event: event, received: now}
+		s.stats.Held++
+		return nil, SequenceHeld
+	}
+
+	state.last = event.Sequence
+	released := s.release(state)
+	s.stats.Reordered += int64(len(released))
+	return append([]*Event{event}, released...), SequenceInOrder
+}
+
+// release returns the held events that now follow in sequence
+func (s *SequenceChecker) release(state *keySequence) []*Event {
+	var ready []*Event
+	for {
+		held, ok := state.held[state.last+1]
+		if !ok {
+			return ready
+		}
+		delete(state.held, state.last+1)
+		state.last++
+		s.stats.Held--
+		ready = append(ready, held.event)
+	}
+}
+
+// Expire gives up on gaps whose earliest held event has waited longer than
+// the window, skipping to it, and returns the events that become ready. It
+// also forgets idle keys.
+func (s *SequenceChecker) Expire(now time.Time) []*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ready []*Event
+	for key, state := range s.keys {
+		if len(state.held) == 0 {
+			if now.Sub(state.lastSeen) > s.IdleTTL {
+				delete(s.keys, key)
+			}
+			continue
+		}
+
+		sequences := make([]int64, 0, len(state.held))
+		for sequence := range state.held {
+			sequences = append(sequences, sequence)
+		}
+		sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+
+		first := state.held[sequences[0]]
+		if now.Sub(first.received) <= s.Window {
+			continue
+		}
+		s.stats.Gaps++
+		state.last = sequences[0] - 1
+		ready = append(ready, s.release(state)...)
+	}
+	return ready
+}
+
+// Flush gives up on every gap and returns all held events in order, for a
+// consumer that is stopping
+func (s *SequenceChecker) Flush() []*Event {
+	var ready []*Event
+	for {
+		released := s.Expire(time.Now().Add(s.Window + time.Second))
+		if len(released) == 0 {
+			return ready
+		}
+		ready = append(ready, released...)
+	}
+}
+
+// Stats returns the checker's counters
+func (s *SequenceChecker) Stats() OrderingStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
@@ -8,39 +8,63 @@ import (
 	"time"
 
 	"github.com/example/agent-payments/internal/database"
-	"github.com/segmentio/kafka-go"
+	"github.com/example/agent-payments/libs/common"
 )
 
 // EventPublisher handles publishing events using the outbox pattern
 type EventPublisher struct {
-	repo        database.Repository
-	kafkaWriter *kafka.Writer
-	topic       string
+	repo       database.Repository
+	bus        MessageBus
+	topic      string
+	format     string // FormatNative or a CloudEvents mode
+	typePrefix string // Prefix of CloudEvents types
 }
 
-// NewEventPublisher creates a new event publisher
-func NewEventPublisher(repo database.Repository, kafkaBrokers []string, topic string) *EventPublisher {
-	kafkaWriter := &kafka.Writer{
-		Addr:         kafka.TCP(kafkaBrokers...),
-		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireOne,
-		Async:        false,
+// NewEventPublisher creates a new event publisher. EVENT_FORMAT selects the
+// message format ("native", "cloudevents-structured" or "cloudevents-binary")
+// and CLOUDEVENTS_TYPE_PREFIX the prefix of CloudEvents types. Events are
+// relayed from the outbox to the topic on bus.
+func NewEventPublisher(repo database.Repository, bus MessageBus, topic string) *EventPublisher {
+	format := common.GetEnv("EVENT_FORMAT", FormatNative)
+	switch format {
+	case FormatNative, FormatCloudEventsStructured, FormatCloudEventsBinary:
+	default:
+		log.Printf("Unknown EVENT_FORMAT %q, publishing native events", format)
+		format = FormatNative
 	}
 
 	return &EventPublisher{
-		repo:        repo,
-		kafkaWriter: kafkaWriter,
-		topic:       topic,
+		repo:       repo,
+		bus:        bus,
+		topic:      topic,
+		format:     format,
+		typePrefix: cloudEventsTypePrefix(),
 	}
 }
 
-// PublishEvent publishes an event using the outbox pattern
+// cloudEventsTypePrefix is shared by publishers and consumers so types round-trip
+func cloudEventsTypePrefix() string {
+	return common.GetEnv("CLOUDEVENTS_TYPE_PREFIX", defaultCloudEventsTypePrefix)
+}
+
+// PublishEvent publishes an event using the outbox pattern. The event is
+// given the next sequence number of its partition key, and the correlation
+// ID and traceparent of ctx, so consumers continue the publisher's trace.
 func (p *EventPublisher) PublishEvent(ctx context.Context, event *Event) error {
-	// Convert event to JSON
-	payload, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %v", err)
+	return p.PublishEventTx(ctx, p.repo, event)
+}
+
+// PublishEventTx saves an event to the outbox through tx, the repository of
+// the transaction making the change the event reports, so the event is
+// published only if the change commits. A sequence taken concurrently fails
+// the transaction rather than being retried, since the failed insert has
+// already aborted it.
+func (p *EventPublisher) PublishEventTx(ctx context.Context, tx database.Repository, event *Event) error {
+	if correlationID := common.CorrelationIDFromContext(ctx); correlationID != "" {
+		event.Metadata.CorrelationID = correlationID
+	}
+	if event.Metadata.TraceParent == "" {
+		event.Metadata.TraceParent = common.TraceParent(ctx)
 	}
 
 	metadata, err := json.Marshal(event.Metadata)
@@ -48,28 +72,59 @@ func (p *EventPublisher) PublishEvent(ctx context.Context, event *Event) error {
 		return fmt.Errorf("failed to marshal event metadata: %v", err)
 	}
 
-	// Create outbox event
-	outboxEvent := &database.OutboxEvent{
-		EventType:     string(event.Type),
-		AggregateID:   event.AggregateID,
-		AggregateType: event.AggregateType,
-		Payload:       string(payload),
-		Metadata:      string(metadata),
-		Status:        "pending",
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+	partitionKey := PartitionKey(event)
+
+	// Concurrent publishers can pick the same sequence; the unique index on
+	// key and sequence rejects all but one, and the others take the next
+	attempts := maxSequenceAttempts
+	if tx != p.repo {
+		attempts = 1
 	}
+	for attempt := 1; ; attempt++ {
+		last, err := tx.OutboxEventRepository().LastSequence(partitionKey)
+		if err != nil {
+			return fmt.Errorf("failed to read event sequence: %v", err)
+		}
+		event.Sequence = last + 1
 
-	// Save to outbox (this should be in the same transaction as the business logic)
-	if err := p.repo.OutboxEventRepository().Create(outboxEvent); err != nil {
-		return fmt.Errorf("failed to save event to outbox: %v", err)
+		// Convert event to JSON
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %v", err)
+		}
+
+		// Create outbox event
+		outboxEvent := &database.OutboxEvent{
+			EventType:     string(event.Type),
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			PartitionKey:  partitionKey,
+			Sequence:      event.Sequence,
+			Payload:       string(payload),
+			Metadata:      string(metadata),
+			Status:        "pending",
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}
+
+		err = tx.OutboxEventRepository().Create(outboxEvent)
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("failed to save event to outbox: %v", err)
+		}
 	}
 
-	log.Printf("Event saved to outbox: %s (%s)", event.Type, event.ID)
+	log.Printf("Event saved to outbox: %s (%s), %s #%d", event.Type, event.ID, partitionKey, event.Sequence)
 	return nil
 }
 
-// ProcessOutbox processes pending events from the outbox and publishes them to Kafka
+// maxSequenceAttempts bounds the retries when concurrent publishers take the
+// same sequence
+const maxSequenceAttempts = 3
+
+// ProcessOutbox processes pending events from the outbox and publishes them to the bus
 func (p *EventPublisher) ProcessOutbox(ctx context.Context) error {
 	// Get pending events
 	pendingEvents, err := p.repo.OutboxEventRepository().ListPending(10) // Process up to 10 events at a time
@@ -78,8 +133,19 @@ func (p *EventPublisher) ProcessOutbox(ctx context.Context) error {
 	}
 
 	for _, outboxEvent := range pendingEvents {
-		if err := p.publishToKafka(ctx, outboxEvent); err != nil {
-			log.Printf("Failed to publish event %s to Kafka: %v", outboxEvent.ID, err)
+		// An event waits until the earlier events of its key are published
+		if outboxEvent.Sequence > 0 {
+			blocked, err := p.repo.OutboxEventRepository().HasUnpublishedBefore(outboxEvent.PartitionKey, outboxEvent.Sequence)
+			if err != nil {
+				return fmt.Errorf("failed to check event order: %v", err)
+			}
+			if blocked {
+				continue
+			}
+		}
+
+		if err := p.publish(ctx, outboxEvent); err != nil {
+			log.Printf("Failed to publish event %s to %s: %v", outboxEvent.ID, p.bus.Name(), err)
 			p.markEventFailed(outboxEvent, err.Error())
 			continue
 		}
@@ -98,28 +164,64 @@ func (p *EventPublisher) ProcessOutbox(ctx context.Context) error {
 	return nil
 }
 
-// publishToKafka publishes an event to Kafka
-func (p *EventPublisher) publishToKafka(ctx context.Context, outboxEvent *database.OutboxEvent) error {
-	message := kafka.Message{
-		Key:   []byte(outboxEvent.AggregateID),
-		Value: []byte(outboxEvent.Payload),
-		Headers: []kafka.Header{
-			{Key: "event-type", Value: []byte(outboxEvent.EventType)},
-			{Key: "aggregate-type", Value: []byte(outboxEvent.AggregateType)},
-			{Key: "outbox-id", Value: []byte(outboxEvent.ID)},
-		},
-		Time: time.Now(),
+// publish publishes an event to the bus
+func (p *EventPublisher) publish(ctx context.Context, outboxEvent *database.OutboxEvent) error {
+	message, err := p.buildMessage(outboxEvent)
+	if err != nil {
+		return err
 	}
+	message.Time = time.Now()
 
-	err := p.kafkaWriter.WriteMessages(ctx, message)
+	err = p.bus.Publish(ctx, p.topic, message)
 	if err != nil {
-		return fmt.Errorf("failed to write message to Kafka: %v", err)
+		return fmt.Errorf("failed to publish message: %v", err)
 	}
 
-	log.Printf("Event published to Kafka: %s (%s)", outboxEvent.EventType, outboxEvent.ID)
+	log.Printf("Event published to %s: %s (%s)", p.bus.Name(), outboxEvent.EventType, outboxEvent.ID)
 	return nil
 }
 
+// buildMessage encodes an outbox event in the publisher's format
+func (p *EventPublisher) buildMessage(outboxEvent *database.OutboxEvent) (Message, error) {
+	outboxHeader := Header{Key: "outbox-id", Value: []byte(outboxEvent.ID)}
+
+	// Events saved before partition keys were recorded are keyed by aggregate
+	key := outboxEvent.PartitionKey
+	if key == "" {
+		key = outboxEvent.AggregateID
+	}
+
+	if p.format == FormatNative {
+		return Message{
+			Key:   []byte(key),
+			Value: []byte(outboxEvent.Payload),
+			Headers: []Header{
+				{Key: "event-type", Value: []byte(outboxEvent.EventType)},
+				{Key: "aggregate-type", Value: []byte(outboxEvent.AggregateType)},
+				outboxHeader,
+			},
+		}, nil
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(outboxEvent.Payload), &event); err != nil {
+		return Message{}, fmt.Errorf("failed to unmarshal outbox payload: %v", err)
+	}
+	ce, err := ToCloudEvent(&event, p.typePrefix)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var message Message
+	if p.format == FormatCloudEventsBinary {
+		message = ce.binaryMessage()
+	} else if message, err = ce.structuredMessage(); err != nil {
+		return Message{}, err
+	}
+	message.Headers = append(message.Headers, outboxHeader)
+	return message, nil
+}
+
 // markEventFailed marks an event as failed and increments retry count
 func (p *EventPublisher) markEventFailed(outboxEvent *database.OutboxEvent, errorMsg string) {
 	outboxEvent.Status = "failed"
@@ -132,14 +234,31 @@ func (p *EventPublisher) markEventFailed(outboxEvent *database.OutboxEvent, erro
 	}
 }
 
-// Close closes the Kafka writer
+// Run relays the outbox to the bus every interval until ctx ends
+func (p *EventPublisher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.ProcessOutbox(ctx); err != nil {
+				log.Printf("Failed to relay the outbox: %v", err)
+			}
+		}
+	}
+}
+
+// Close closes the publisher's bus
 func (p *EventPublisher) Close() error {
-	return p.kafkaWriter.Close()
+	return p.bus.Close()
 }
 
 // EventPublisherInterface defines the interface for event publishing
 type EventPublisherInterface interface {
 	PublishEvent(ctx context.Context, event *Event) error
+	PublishEventTx(ctx context.Context, tx database.Repository, event *Event) error
 	ProcessOutbox(ctx context.Context) error
 	Close() error
 }
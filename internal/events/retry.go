@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// OutboxRetrier returns failed outbox events to pending so the publisher
+// tries them again. Each event waits BaseDelay after its first failure,
+// doubling per failure up to MaxDelay, and is left failed once it has
+// failed MaxAttempts times. An alert is raised when the failed events reach
+// AlertThreshold.
+type OutboxRetrier struct {
+	repo           database.Repository
+	auditTrail     *audit.AuditTrail
+	MaxAttempts    int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	AlertThreshold int64
+	alerting       bool
+}
+
+// NewOutboxRetrierFromEnv creates a retrier configured by the OUTBOX_RETRY_*
+// and OUTBOX_FAILURE_ALERT_THRESHOLD variables
+func NewOutboxRetrierFromEnv(repo database.Repository) *OutboxRetrier {
+	return &OutboxRetrier{
+		repo:           repo,
+		auditTrail:     audit.NewAuditTrail(repo),
+		MaxAttempts:    common.GetEnvAsInt("OUTBOX_RETRY_MAX_ATTEMPTS", 5),
+		BaseDelay:      time.Duration(common.GetEnvAsInt("OUTBOX_RETRY_BASE_DELAY_SECONDS", 30)) * time.Second,
+		MaxDelay:       time.Duration(common.GetEnvAsInt("OUTBOX_RETRY_MAX_DELAY_SECONDS", 3600)) * time.Second,
+		AlertThreshold: int64(common.GetEnvAsInt("OUTBOX_FAILURE_ALERT_THRESHOLD", 10)),
+	}
+}
+
+// Backoff is how long an event that has failed the given number of times
+// waits before its next attempt
+func (r *OutboxRetrier) Backoff(failures int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < failures && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	return delay
+}
+
+// NextAttempt is when a failed event becomes due for retry, or nil if it has
+// no attempts left
+func (r *OutboxRetrier) NextAttempt(event *database.OutboxEvent) *time.Time {
+	if event.RetryCount >= r.MaxAttempts {
+		return nil
+	}
+	next := event.UpdatedAt.Add(r.Backoff(event.RetryCount))
+	return &next
+}
+
+// Run retries due events every interval until the context is cancelled
+func (r *OutboxRetrier) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.RetryDue(ctx, time.Now()); err != nil {
+				log.Printf("Outbox retry failed: %v", err)
+			}
+			r.checkFailures(ctx)
+		}
+	}
+}
+
+// RetryDue returns the failed events whose backoff has elapsed to pending,
+// keeping their retry counts, and reports how many it requeued
+func (r *OutboxRetrier) RetryDue(ctx context.Context, now time.Time) (int, error) {
+	failed, err := r.repo.OutboxEventRepository().ListRetryable(r.MaxAttempts, 100)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list failed events: %v", err)
+	}
+
+	requeued := 0
+	for _, event := range failed {
+		if next := r.NextAttempt(event); next == nil || next.After(now) {
+			continue
+		}
+		event.Status = "pending"
+		event.UpdatedAt = now
+		if err := r.repo.OutboxEventRepository().Update(event); err != nil {
+			return requeued, fmt.Errorf("failed to requeue event %s: %v", event.ID, err)
+		}
+		requeued++
+		log.Printf("Requeued outbox event %s (%s), attempt %d of %d", event.ID, event.EventType, event.RetryCount+1, r.MaxAttempts)
+	}
+	return requeued, nil
+}
+
+// Requeue returns a failed event to pending with its attempts reset, for
+// operators replaying an event after fixing its cause
+func (r *OutboxRetrier) Requeue(event *database.OutboxEvent) error {
+	if event.Status != "failed" {
+		return fmt.Errorf("only failed events can be requeued")
+	}
+	event.Status = "pending"
+	event.RetryCount = 0
+	event.ErrorMessage = ""
+	event.UpdatedAt = time.Now()
+	return r.repo.OutboxEventRepository().Update(event)
+}
+
+// checkFailures raises an alert when the failed events reach the threshold,
+// once per breach
+func (r *OutboxRetrier) checkFailures(ctx context.Context) {
+	failed, err := r.repo.OutboxEventRepository().CountByStatus("failed")
+	if err != nil {
+		log.Printf("Failed to count failed outbox events: %v", err)
+		return
+	}
+
+	if failed < r.AlertThreshold || r.AlertThreshold <= 0 {
+		if r.alerting {
+			common.Info("Failed outbox events back under threshold: %d", failed)
+		}
+		r.alerting = false
+		return
+	}
+	if r.alerting {
+		return
+	}
+	r.alerting = true
+
+	exhausted, _ := r.repo.OutboxEventRepository().CountExhausted(r.MaxAttempts)
+	common.Error("ALERT: %d failed outbox events (threshold %d), %d with no attempts left", failed, r.AlertThreshold, exhausted)
+	err = r.auditTrail.LogEvent(ctx, &audit.AuditEntry{
+		EventType:    audit.AuditOutboxFailureAlert,
+		Severity:     audit.SeverityHigh,
+		ResourceType: "outbox",
+		Action:       string(audit.AuditOutboxFailureAlert),
+		Description:  fmt.Sprintf("%d failed outbox events exceed the alert threshold of %d", failed, r.AlertThreshold),
+		Metadata: map[string]interface{}{
+			"failed":      failed,
+			"exhausted":   exhausted,
+			"threshold":   r.AlertThreshold,
+			"maxAttempts": r.MaxAttempts,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to record outbox alert: %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package federation
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Issuer statuses
+const (
+	IssuerStatusActive    = "active"
+	IssuerStatusSuspended = "suspended"
+)
+
+// Credential formats accepted from external issuers
+const (
+	FormatJWT = "jwt"
+	FormatVC  = "vc" // W3C Verifiable Credential encoded as a JWT
+)
+
+// RevocationSyncInterval is how often an issuer's revocation list is refetched
+const RevocationSyncInterval = 5 * time.Minute
+
+// MaxRevocationStaleness is how long verification continues on a cached
+// revocation list while the issuer's list cannot be fetched
+const MaxRevocationStaleness = time.Hour
+
+var (
+	ErrUntrustedIssuer           = errors.New("credential issuer is not trusted")
+	ErrIssuerSuspended           = errors.New("credential issuer is suspended")
+	ErrUnknownKey                = errors.New("credential signing key is not registered for issuer")
+	ErrInvalidSignature          = errors.New("invalid credential signature")
+	ErrExpired                   = errors.New("credential expired")
+	ErrNotYetValid               = errors.New("credential is not yet valid")
+	ErrMissingSubject            = errors.New("credential has no subject")
+	ErrRevoked                   = errors.New("credential revoked by issuer")
+	ErrRevocationListUnavailable = errors.New("issuer revocation list unavailable")
+)
+
+// JWK is a public JSON Web Key registered for an issuer. Ed25519 (OKP) and
+// P-256 (EC) keys are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Claims are the fields read from an external credential
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	ID        string `json:"jti"`
+	Name      string `json:"name"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+	IssuedAt  int64  `json:"iat"`
+	VC        *struct {
+		ID                string `json:"id"`
+		CredentialSubject struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"credentialSubject"`
+	} `json:"vc,omitempty"`
+}
+
+// Result is a verified external credential
+type Result struct {
+	Issuer       *database.TrustedIssuer
+	Subject      string
+	CredentialID string
+	Name         string
+	Format       string
+	ExpiresAt    time.Time
+}
+
+// RevocationList is the document an issuer publishes at its revocation list URL
+type RevocationList struct {
+	Revoked []string `json:"revoked"` // Revoked credential IDs ("jti") or subjects
+}
+
+// Verifier checks credentials issued by trusted external platforms
+type Verifier struct {
+	repo   database.Repository
+	client *common.ResilientClient
+}
+
+// NewVerifier creates a federation verifier
+func NewVerifier(repo database.Repository) *Verifier {
+	return &Verifier{
+		repo:   repo,
+		client: common.NewResilientClient(common.NewResilienceConfigFromEnv()),
+	}
+}
+
+// PeekIssuer returns the unverified "iss" claim of a JWT, so callers can
+// route a token to the right verifier
+func PeekIssuer(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Issuer
+}
+
+// ParsePublicKeys decodes and validates an issuer's JWK set
+func ParsePublicKeys(raw string) ([]JWK, error) {
+	var keys []JWK
+	if err := json.Unmarshal([]byte(raw), &keys); err != nil {
+		return nil, fmt.Errorf("public keys must be a JSON array of JWKs: %v", err)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+	for i, key := range keys {
+		if _, err := key.publicKey(); err != nil {
+			return nil, fmt.Errorf("public key %d: %v", i, err)
+		}
+	}
+	return keys, nil
+}
+
+// Verify checks an external credential's issuer, signature, validity window
+// and revocation status
+func (v *Verifier) Verify(ctx context.Context, token string) (*Result, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, common.ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, common.ErrInvalidToken
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, common.ErrInvalidToken
+	}
+
+	issuer, err := v.repo.TrustedIssuerRepository().GetByIssuer(claims.Issuer)
+	if err != nil {
+		return nil, ErrUntrustedIssuer
+	}
+	if issuer.Status != IssuerStatusActive {
+		return nil, ErrIssuerSuspended
+	}
+
+	if err := verifySignature(issuer, header.Alg, header.Kid, parts); err != nil {
+		return nil, err
+	}
+
+	// External credentials must expire; a clock skew of one minute is tolerated
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt+60 {
+		return nil, ErrExpired
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore-60 {
+		return nil, ErrNotYetValid
+	}
+
+	result := &Result{
+		Issuer:       issuer,
+		Subject:      claims.Subject,
+		CredentialID: claims.ID,
+		Name:         claims.Name,
+		Format:       FormatJWT,
+		ExpiresAt:    time.Unix(claims.ExpiresAt, 0),
+	}
+	if claims.VC != nil {
+		result.Format = FormatVC
+		if result.Subject == "" {
+			result.Subject = claims.VC.CredentialSubject.ID
+		}
+		if result.CredentialID == "" {
+			result.CredentialID = claims.VC.ID
+		}
+		if result.Name == "" {
+			result.Name = claims.VC.CredentialSubject.Name
+		}
+	}
+	if result.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+	// Credentials without an ID can only be revoked by subject
+	if result.CredentialID == "" {
+		result.CredentialID = result.Subject
+	}
+
+	if err := v.checkRevocation(ctx, issuer, result.CredentialID, result.Subject); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkRevocation consults local revocations, refreshing them from the
+// issuer's published list when it is due. Revoking a subject revokes every
+// credential issued to it.
+func (v *Verifier) checkRevocation(ctx context.Context, issuer *database.TrustedIssuer, ids ...string) error {
+	if issuer.RevocationListURL != "" {
+		if issuer.RevocationsSyncedAt == nil || time.Since(*issuer.RevocationsSyncedAt) > RevocationSyncInterval {
+			if err := v.SyncRevocations(ctx, issuer); err != nil {
+				common.Warn("Failed to sync revocation list for issuer %s: %v", issuer.Issuer, err)
+				// Fail closed once the cached list is too old to trust
+				if issuer.RevocationsSyncedAt == nil || time.Since(*issuer.RevocationsSyncedAt) > MaxRevocationStaleness {
+					return ErrRevocationListUnavailable
+				}
+			}
+		}
+	}
+
+	for _, id := range ids {
+		revoked, err := v.repo.FederatedRevocationRepository().IsRevoked(issuer.ID, id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrRevoked
+		}
+	}
+	return nil
+}
+
+// SyncRevocations fetches the issuer's revocation list and records its entries
+func (v *Verifier) SyncRevocations(ctx context.Context, issuer *database.TrustedIssuer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", issuer.RevocationListURL, nil)
+	if err != nil {
+		return err
+	}
+	// Each issuer's list sits behind its own breaker
+	resp, err := v.client.Do(req.URL.Host, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("revocation list returned status %d", resp.StatusCode)
+	}
+
+	var list RevocationList
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&list); err != nil {
+		return fmt.Errorf("invalid revocation list: %v", err)
+	}
+
+	for _, credentialID := range list.Revoked {
+		if err := v.repo.FederatedRevocationRepository().Create(&database.FederatedRevocation{
+			IssuerID:     issuer.ID,
+			CredentialID: credentialID,
+			Source:       "issuer_list",
+		}); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	issuer.RevocationsSyncedAt = &now
+	return v.repo.TrustedIssuerRepository().Update(issuer)
+}
+
+func verifySignature(issuer *database.TrustedIssuer, alg, kid string, parts []string) error {
+	keys, err := ParsePublicKeys(issuer.PublicKeys)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	matched := false
+	for _, key := range keys {
+		if kid != "" && key.Kid != kid {
+			continue
+		}
+		if key.alg() != alg {
+			continue
+		}
+		matched = true
+		publicKey, _ := key.publicKey()
+		if verifyWithKey(publicKey, signingInput, signature) {
+			return nil
+		}
+	}
+	if !matched {
+		return ErrUnknownKey
+	}
+	return ErrInvalidSignature
+}
+
+func verifyWithKey(publicKey interface{}, signingInput, signature []byte) bool {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signingInput, signature)
+	case *ecdsa.PublicKey:
+		// JWS ES256 signatures are the fixed-width concatenation r || s
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(signingInput)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+// alg is the JWS algorithm the key verifies
+func (k JWK) alg() string {
+	switch {
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		return "EdDSA"
+	case k.Kty == "EC" && k.Crv == "P-256":
+		return "ES256"
+	default:
+		return ""
+	}
+}
+
+func (k JWK) publicKey() (interface{}, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, errors.New("x is not base64url")
+	}
+
+	switch k.alg() {
+	case "EdDSA":
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 keys must be %d bytes", ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(x), nil
+	case "ES256":
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, errors.New("y is not base64url")
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("P-256 coordinates must be 32 bytes")
+		}
+		// Reject points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, errors.New("point is not on P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s/%s", k.Kty, k.Crv)
+	}
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,74 @@
+package federation
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// ShadowAgent resolves the local agent for a verified external credential.
+// On first contact a shadow agent is created under the issuer's host party
+// with a consent restricted to the issuer's default limits.
+func (v *Verifier) ShadowAgent(result *Result) (*database.Agent, bool, error) {
+	identity, err := v.repo.FederatedIdentityRepository().GetByIssuerAndSubject(result.Issuer.ID, result.Subject)
+	if err == nil {
+		identity.LastVerifiedAt = time.Now()
+		if err := v.repo.FederatedIdentityRepository().Update(identity); err != nil {
+			return nil, false, err
+		}
+		agent, err := v.repo.AgentRepository().GetByID(identity.AgentID)
+		return agent, false, err
+	}
+
+	// VCs identify agents by DID; plain JWTs come from OAuth-style platforms
+	identityMode := "oauth"
+	if result.Format == FormatVC {
+		identityMode = "did"
+	}
+	displayName := result.Name
+	if displayName == "" {
+		displayName = result.Subject
+	}
+
+	agent := &database.Agent{
+		DisplayName:  displayName + " (" + result.Issuer.Name + ")",
+		OwnerPartyID: result.Issuer.HostPartyID,
+		IdentityMode: identityMode,
+	}
+	if err := v.repo.AgentRepository().Create(agent); err != nil {
+		return nil, false, err
+	}
+
+	limits, err := json.Marshal(map[string]interface{}{
+		"singleTxnUSD": result.Issuer.DefaultSingleTxnUSD,
+		"dailyUSD":     result.Issuer.DefaultDailyUSD,
+		"velocity":     map[string]int{"maxTxnPerHour": result.Issuer.DefaultMaxTxnPerHour},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	consent := &database.Consent{
+		AgentID:             agent.ID,
+		OwnerPartyID:        result.Issuer.HostPartyID,
+		Rails:               "[]",
+		CounterpartiesAllow: "[]",
+		Limits:              string(limits),
+		PolicyBundleVersion: "federated-default",
+	}
+	if err := v.repo.ConsentRepository().Create(consent); err != nil {
+		return nil, false, err
+	}
+
+	identity = &database.FederatedIdentity{
+		IssuerID:       result.Issuer.ID,
+		Subject:        result.Subject,
+		AgentID:        agent.ID,
+		LastVerifiedAt: time.Now(),
+	}
+	if err := v.repo.FederatedIdentityRepository().Create(identity); err != nil {
+		return nil, false, err
+	}
+
+	return agent, true, nil
+}
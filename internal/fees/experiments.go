@@ -0,0 +1,87 @@
+package fees
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Pricing experiments try markup variants against each other. A tenant or
+// agent is assigned one variant of an experiment and keeps it, so each
+// payment is quoted and settled under the same markup.
+
+// ValidateExperiment checks an experiment's variants
+func ValidateExperiment(experiment *database.FeeExperiment) error {
+	if len(experiment.Variants) < 2 {
+		return fmt.Errorf("an experiment needs at least two variants")
+	}
+	names := make(map[string]bool, len(experiment.Variants))
+	for i := range experiment.Variants {
+		variant := &experiment.Variants[i]
+		if variant.Name == "" {
+			return fmt.Errorf("variants must be named")
+		}
+		if names[strings.ToLower(variant.Name)] {
+			return fmt.Errorf("variant %s is named twice", variant.Name)
+		}
+		names[strings.ToLower(variant.Name)] = true
+		if variant.Weight < 0 {
+			return fmt.Errorf("variant %s: weight must not be negative", variant.Name)
+		}
+		if err := Validate(VariantSchedule(variant)); err != nil {
+			return fmt.Errorf("variant %s: %v", variant.Name, err)
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether an experiment prices payments on a rail
+func AppliesTo(experiment *database.FeeExperiment, rail string) bool {
+	return experiment.Rail == "" || strings.EqualFold(experiment.Rail, rail)
+}
+
+// AssignVariant picks a subject's variant by weight. The pick depends only
+// on the experiment and subject, so it is the same every time it is made.
+// It returns nil if no variant has weight.
+func AssignVariant(experiment *database.FeeExperiment, subjectID string) *database.FeeExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(experiment.ID + ":" + subjectID))
+	point := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for i := range experiment.Variants {
+		variant := &experiment.Variants[i]
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return nil
+}
+
+// VariantSchedule is the markup of a variant as a fee schedule
+func VariantSchedule(variant *database.FeeExperimentVariant) *database.FeeSchedule {
+	return &database.FeeSchedule{
+		MarkupPercent: variant.MarkupPercent,
+		MarkupFixed:   variant.MarkupFixed,
+		MinMarkup:     variant.MinMarkup,
+		MaxMarkup:     variant.MaxMarkup,
+		Enabled:       true,
+	}
+}
+
+// NewVariantQuote quotes a payment's fee under an experiment variant
+func NewVariantQuote(rail string, amountUSD, railFee float64, variant *database.FeeExperimentVariant) Quote {
+	quote := NewQuote(rail, amountUSD, railFee, VariantSchedule(variant))
+	quote.ExperimentID = variant.ExperimentID
+	quote.VariantID = variant.ID
+	return quote
+}
@@ -0,0 +1,92 @@
+package fees
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rounding"
+)
+
+// Platform operators mark up the fees of the rails payments go over. Each
+// tenant, the party owning the paying agent, can have its own schedule per
+// rail; the markup is the platform's revenue on the payment.
+
+// Quote is the fee for a payment: the rail's fee plus the tenant's markup
+type Quote struct {
+	Rail        string  `json:"rail"`
+	AmountUSD   float64 `json:"amountUSD"`
+	RailFeeUSD  float64 `json:"railFeeUSD"`
+	MarkupUSD   float64 `json:"markupUSD"`
+	TotalFeeUSD float64 `json:"totalFeeUSD"`
+	ScheduleID  string  `json:"scheduleId,omitempty"` // Schedule the markup came from
+
+	// Experiment variant the markup came from instead of a schedule
+	ExperimentID string `json:"experimentId,omitempty"`
+	VariantID    string `json:"variantId,omitempty"`
+
+	// Correspondent bank fees the payer pays for a wire abroad under the OUR
+	// charge bearer, included in RailFeeUSD
+	CorrespondentFeeUSD float64 `json:"correspondentFeeUSD,omitempty"`
+}
+
+// Validate checks a schedule's markup
+func Validate(schedule *database.FeeSchedule) error {
+	if schedule.MarkupPercent < 0 || schedule.MarkupFixed < 0 || schedule.MinMarkup < 0 || schedule.MaxMarkup < 0 {
+		return fmt.Errorf("markups must not be negative")
+	}
+	if schedule.MaxMarkup > 0 && schedule.MinMarkup > schedule.MaxMarkup {
+		return fmt.Errorf("minMarkup must not exceed maxMarkup")
+	}
+	return nil
+}
+
+// SelectSchedule picks the tenant's enabled schedule for a rail, preferring
+// one for the rail over one for all rails. It returns nil if none applies.
+func SelectSchedule(schedules []*database.FeeSchedule, rail string) *database.FeeSchedule {
+	var fallback *database.FeeSchedule
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		if strings.EqualFold(schedule.Rail, rail) {
+			return schedule
+		}
+		if schedule.Rail == "" && fallback == nil {
+			fallback = schedule
+		}
+	}
+	return fallback
+}
+
+// Markup applies a schedule to a rail fee: a share of the fee plus a fixed
+// amount, kept within the schedule's minimum and maximum
+func Markup(schedule *database.FeeSchedule, railFee float64) float64 {
+	if schedule == nil {
+		return 0
+	}
+	markup := railFee*schedule.MarkupPercent + schedule.MarkupFixed
+	if markup < schedule.MinMarkup {
+		markup = schedule.MinMarkup
+	}
+	if schedule.MaxMarkup > 0 && markup > schedule.MaxMarkup {
+		markup = schedule.MaxMarkup
+	}
+	return rounding.Round(markup)
+}
+
+// NewQuote quotes a payment's fee from its rail fee and the tenant's schedule,
+// which may be nil
+func NewQuote(rail string, amountUSD, railFee float64, schedule *database.FeeSchedule) Quote {
+	quote := Quote{
+		Rail:       rail,
+		AmountUSD:  amountUSD,
+		RailFeeUSD: rounding.Round(railFee),
+		MarkupUSD:  Markup(schedule, railFee),
+	}
+	if schedule != nil {
+		quote.ScheduleID = schedule.ID
+	}
+	quote.TotalFeeUSD = rounding.Round(quote.RailFeeUSD + quote.MarkupUSD)
+	return quote
+}
@@ -0,0 +1,180 @@
+package forecast
+
+import (
+	"math"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Lookback is the history the forecast learns from
+const Lookback = 28 * 24 * time.Hour
+
+// Smoothing is the weight of the most recent day in the moving average of
+// daily spend
+const Smoothing = 0.3
+
+// Budget is a daily spending limit the agent's payments count against
+type Budget struct {
+	Type     string // "consent" or "risk_daily_volume"
+	ID       string // Consent ID for consent budgets
+	LimitUSD float64
+}
+
+// Projection is the agent's expected spend for the current day (UTC)
+type Projection struct {
+	PeriodStart        time.Time
+	PeriodEnd          time.Time
+	SpentUSD           float64 // Spent so far in the period
+	ExpectedDailyUSD   float64 // Moving average of daily spend
+	ProjectedRemainUSD float64 // Expected spend for the rest of the period
+	ProjectedTotalUSD  float64
+	HistoryDays        int       // Days of history behind the average
+	HourlyProfile      []float64 // Share of a day's spend falling in each hour
+}
+
+// BudgetForecast says whether a budget is expected to run out before the
+// period ends
+type BudgetForecast struct {
+	Budget
+	RemainingUSD      float64
+	ProjectedUSD      float64
+	UtilizationRatio  float64    // Projected spend over the limit
+	ExhaustedEarly    bool       // Projected to run out before the period ends
+	ProjectedExhausts *time.Time // When the projected spend reaches the limit
+}
+
+// Project forecasts the rest of the current day from the agent's workflows.
+// Daily totals are smoothed with an exponentially weighted moving average,
+// and the average is spread over the remaining hours by the share of spend
+// each hour of the day has seen. Cancelled and failed workflows are ignored.
+func Project(workflows []*database.PaymentWorkflow, now time.Time) Projection {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	projection := Projection{
+		PeriodStart: start,
+		PeriodEnd:   start.Add(24 * time.Hour),
+	}
+
+	daily := map[time.Time]float64{}
+	hourly := make([]float64, 24)
+	var historyTotal float64
+	var first time.Time
+	for _, workflow := range workflows {
+		if workflow.Status == "cancelled" || workflow.Status == "failed" {
+			continue
+		}
+		at := workflow.CreatedAt.UTC()
+		if at.After(now) || now.Sub(at) > Lookback {
+			continue
+		}
+		if !at.Before(start) {
+			projection.SpentUSD += workflow.AmountUSD
+			continue
+		}
+
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		daily[day] += workflow.AmountUSD
+		hourly[at.Hour()] += workflow.AmountUSD
+		historyTotal += workflow.AmountUSD
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+	}
+
+	// Average every day since the first payment, counting days without spend
+	if !first.IsZero() {
+		var days []time.Time
+		for day := first; day.Before(start); day = day.Add(24 * time.Hour) {
+			days = append(days, day)
+		}
+
+		average := daily[days[0]]
+		for _, day := range days[1:] {
+			average = Smoothing*daily[day] + (1-Smoothing)*average
+		}
+		projection.ExpectedDailyUSD = round2(average)
+		projection.HistoryDays = len(days)
+	}
+
+	// Without history, spend is assumed to be spread evenly over the day
+	projection.HourlyProfile = make([]float64, 24)
+	for hour := range hourly {
+		if historyTotal > 0 {
+			projection.HourlyProfile[hour] = hourly[hour] / historyTotal
+		} else {
+			projection.HourlyProfile[hour] = 1.0 / 24
+		}
+	}
+
+	projection.ProjectedRemainUSD = round2(projection.ExpectedDailyUSD * remainingShare(projection.HourlyProfile, now))
+	projection.SpentUSD = round2(projection.SpentUSD)
+	projection.ProjectedTotalUSD = round2(projection.SpentUSD + projection.ProjectedRemainUSD)
+	for hour, share := range projection.HourlyProfile {
+		projection.HourlyProfile[hour] = math.Round(share*1000) / 1000
+	}
+	return projection
+}
+
+// remainingShare is the share of a day's spend expected after now, counting
+// the part of the current hour still to come
+func remainingShare(profile []float64, now time.Time) float64 {
+	elapsed := float64(now.Minute()*60+now.Second()) / 3600
+	share := profile[now.Hour()] * (1 - elapsed)
+	for hour := now.Hour() + 1; hour < 24; hour++ {
+		share += profile[hour]
+	}
+	return share
+}
+
+// Check compares the projection with each budget
+func Check(projection Projection, budgets []Budget, now time.Time) []BudgetForecast {
+	forecasts := make([]BudgetForecast, 0, len(budgets))
+	for _, budget := range budgets {
+		forecast := BudgetForecast{
+			Budget:       budget,
+			RemainingUSD: round2(math.Max(budget.LimitUSD-projection.SpentUSD, 0)),
+			ProjectedUSD: projection.ProjectedTotalUSD,
+		}
+		if budget.LimitUSD > 0 {
+			forecast.UtilizationRatio = round2(projection.ProjectedTotalUSD / budget.LimitUSD)
+		}
+		if projection.ProjectedTotalUSD >= budget.LimitUSD {
+			forecast.ExhaustedEarly = true
+			at := exhaustionTime(projection, budget.LimitUSD, now)
+			forecast.ProjectedExhausts = &at
+		}
+		forecasts = append(forecasts, forecast)
+	}
+	return forecasts
+}
+
+// exhaustionTime walks the remaining hours of the period until the projected
+// spend reaches the limit
+func exhaustionTime(projection Projection, limit float64, now time.Time) time.Time {
+	now = now.UTC()
+	spent := projection.SpentUSD
+	if spent >= limit {
+		return now
+	}
+
+	at := now
+	for at.Before(projection.PeriodEnd) {
+		next := at.Truncate(time.Hour).Add(time.Hour)
+		if next.After(projection.PeriodEnd) {
+			next = projection.PeriodEnd
+		}
+		rate := projection.ExpectedDailyUSD * projection.HourlyProfile[at.Hour()] // Per hour
+		expected := rate * next.Sub(at).Hours()
+		if rate > 0 && spent+expected >= limit {
+			return at.Add(time.Duration((limit - spent) / rate * float64(time.Hour)))
+		}
+		spent += expected
+		at = next
+	}
+	return projection.PeriodEnd
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
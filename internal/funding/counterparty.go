@@ -0,0 +1,146 @@
+package funding
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Counterparty bank account verification methods
+const (
+	VerificationMicroDeposits = "micro_deposits"
+	VerificationInstant       = "instant" // Plaid-style token exchange
+)
+
+// MaxMicroDepositAttempts is how many wrong confirmations fail a verification
+const MaxMicroDepositAttempts = 3
+
+var (
+	ErrCounterpartyNotVerified   = errors.New("counterparty bank account is not verified")
+	ErrInvalidRoutingNumber      = errors.New("invalid ABA routing number")
+	ErrMicroDepositsUnsupported  = errors.New("provider does not send micro-deposits")
+	ErrMicroDepositMismatch      = errors.New("micro-deposit amounts do not match")
+	ErrVerificationAttemptsSpent = errors.New("too many verification attempts")
+)
+
+// BankAccountDetails identifies a bank account for ACH
+type BankAccountDetails struct {
+	HolderName    string
+	RoutingNumber string
+	AccountNumber string
+	AccountType   string // "checking", "savings"
+}
+
+// AccountVerifier proves a payee controls a bank account before ACH payments
+// are sent to it, either by instant verification through an open-banking
+// token exchange or by micro-deposits the payee reports back
+type AccountVerifier interface {
+	Name() string
+	// ResolveAccount exchanges a public token for the numbers of one of the linked accounts
+	ResolveAccount(ctx context.Context, publicToken, accountID string) (*BankAccountDetails, error)
+	// SendMicroDeposits credits the amounts (in cents) and returns a provider reference
+	SendMicroDeposits(ctx context.Context, account BankAccountDetails, amountsCents []int64) (string, error)
+}
+
+// NewAccountVerifierFromEnv returns the verifier for FUNDING_PROVIDER
+func NewAccountVerifierFromEnv() (AccountVerifier, error) {
+	switch provider := common.GetEnv("FUNDING_PROVIDER", "sandbox"); provider {
+	case "sandbox":
+		return NewSandboxProvider(), nil
+	case "plaid":
+		return NewPlaidProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown funding provider: %s", provider)
+	}
+}
+
+// ValidRoutingNumber checks the length and ABA checksum of a routing number
+func ValidRoutingNumber(routingNumber string) bool {
+	if len(routingNumber) != 9 {
+		return false
+	}
+	weights := []int{3, 7, 1, 3, 7, 1, 3, 7, 1}
+	sum := 0
+	for i, r := range routingNumber {
+		if r < '0' || r > '9' {
+			return false
+		}
+		sum += int(r-'0') * weights[i]
+	}
+	return sum%10 == 0
+}
+
+// NewMicroDepositAmounts picks two distinct amounts between 1 and 99 cents
+func NewMicroDepositAmounts() ([]int64, error) {
+	amounts := make([]int64, 0, 2)
+	for len(amounts) < 2 {
+		n, err := rand.Int(rand.Reader, big.NewInt(99))
+		if err != nil {
+			return nil, err
+		}
+		amount := n.Int64() + 1
+		if len(amounts) == 1 && amounts[0] == amount {
+			continue
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts, nil
+}
+
+// EncodeMicroDeposits formats amounts for sealing
+func EncodeMicroDeposits(amountsCents []int64) string {
+	parts := make([]string, len(amountsCents))
+	for i, amount := range amountsCents {
+		parts[i] = strconv.FormatInt(amount, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// MicroDepositsMatch compares reported USD amounts with the encoded amounts
+// in any order
+func MicroDepositsMatch(encoded string, reportedUSD []float64) bool {
+	expected := map[int64]int{}
+	for _, part := range strings.Split(encoded, ",") {
+		amount, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return false
+		}
+		expected[amount]++
+	}
+	if len(reportedUSD) != len(strings.Split(encoded, ",")) {
+		return false
+	}
+	for _, reported := range reportedUSD {
+		cents := int64(reported*100 + 0.5)
+		if expected[cents] == 0 {
+			return false
+		}
+		expected[cents]--
+	}
+	return true
+}
+
+// Mask returns the last four digits of an account number
+func Mask(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return accountNumber[len(accountNumber)-4:]
+}
+
+// CheckCounterpartyAccount returns the party's verified bank account for the
+// counterparty, or ErrCounterpartyNotVerified
+func CheckCounterpartyAccount(repo database.Repository, partyID, counterparty string) (*database.CounterpartyBankAccount, error) {
+	account, err := repo.CounterpartyBankAccountRepository().GetVerified(partyID, counterparty)
+	if err != nil {
+		return nil, ErrCounterpartyNotVerified
+	}
+	return account, nil
+}
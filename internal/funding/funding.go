@@ -0,0 +1,160 @@
+package funding
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Funding source statuses
+const (
+	StatusPendingVerification = "pending_verification"
+	StatusVerified            = "verified"
+	StatusVerificationFailed  = "verification_failed"
+	StatusDisabled            = "disabled"
+)
+
+// Funding transfer types
+const (
+	TransferTopUp    = "top_up"
+	TransferACHDebit = "ach_debit"
+)
+
+var ErrNotVerified = errors.New("funding source is not verified")
+
+// ExternalAccount is a bank account reported by the open-banking provider
+type ExternalAccount struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Mask    string `json:"mask"` // Last digits of the account number
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+}
+
+// LinkedItem is the result of exchanging a public token for long-lived access
+type LinkedItem struct {
+	AccessToken     string
+	ItemID          string
+	InstitutionName string
+	Accounts        []ExternalAccount
+}
+
+// DebitRequest describes an ACH debit from a linked account
+type DebitRequest struct {
+	AccessToken    string
+	AccountID      string
+	AmountUSD      float64
+	Description    string
+	LegalName      string // Account holder name, required by some providers
+	IdempotencyKey string
+}
+
+// Transfer is a debit initiated with the provider
+type Transfer struct {
+	ID     string
+	Status string // "pending", "posted", "failed"
+}
+
+// Provider is an open-banking provider following the Plaid-style link flow:
+// a client-side link token yields a public token, which the server exchanges
+// for an access token used for all later calls
+type Provider interface {
+	Name() string
+	CreateLinkToken(ctx context.Context, userID, clientName string) (string, error)
+	ExchangePublicToken(ctx context.Context, publicToken string) (*LinkedItem, error)
+	// VerifyOwnership reports whether ownerName holds the account according to the provider
+	VerifyOwnership(ctx context.Context, accessToken, accountID, ownerName string) (bool, error)
+	InitiateDebit(ctx context.Context, req DebitRequest) (*Transfer, error)
+}
+
+// NewProviderFromEnv returns the provider selected by FUNDING_PROVIDER ("sandbox" or "plaid")
+func NewProviderFromEnv() (Provider, error) {
+	switch provider := common.GetEnv("FUNDING_PROVIDER", "sandbox"); provider {
+	case "sandbox":
+		return NewSandboxProvider(), nil
+	case "plaid":
+		return NewPlaidProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown funding provider: %s", provider)
+	}
+}
+
+// Sealer encrypts provider access tokens at rest with AES-GCM
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer derives an AES-256 key from the secret
+func NewSealer(secret string) (*Sealer, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// NewSealerFromEnv builds a sealer from FUNDING_ENCRYPTION_KEY
+func NewSealerFromEnv() (*Sealer, error) {
+	secret := common.GetEnv("FUNDING_ENCRYPTION_KEY", "")
+	if secret == "" {
+		secret = "dev-only-insecure-funding-key"
+		common.Warn("FUNDING_ENCRYPTION_KEY is not set - using an insecure development key")
+	}
+	return NewSealer(secret)
+}
+
+// Seal encrypts plaintext, returning base64(nonce || ciphertext)
+func (s *Sealer) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func (s *Sealer) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < s.aead.NonceSize() {
+		return "", errors.New("sealed value too short")
+	}
+	plaintext, err := s.aead.Open(nil, data[:s.aead.NonceSize()], data[s.aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NamesMatch compares account holder names loosely: case, punctuation and
+// word order are ignored
+func NamesMatch(a, b string) bool {
+	return normalizeName(a) != "" && normalizeName(a) == normalizeName(b)
+}
+
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	// Sort words so "Doe, Jane" matches "Jane Doe"
+	sort.Strings(fields)
+	return strings.Join(fields, " ")
+}
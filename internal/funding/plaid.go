@@ -0,0 +1,310 @@
+package funding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+var plaidHosts = map[string]string{
+	"sandbox":     "https://sandbox.plaid.com",
+	"development": "https://development.plaid.com",
+	"production":  "https://production.plaid.com",
+}
+
+// PlaidProvider talks to the Plaid API
+type PlaidProvider struct {
+	baseURL  string
+	clientID string
+	secret   string
+	client   *http.Client
+}
+
+// NewPlaidProviderFromEnv configures Plaid from PLAID_CLIENT_ID, PLAID_SECRET and PLAID_ENV
+func NewPlaidProviderFromEnv() (*PlaidProvider, error) {
+	clientID := common.GetEnv("PLAID_CLIENT_ID", "")
+	secret := common.GetEnv("PLAID_SECRET", "")
+	if clientID == "" || secret == "" {
+		return nil, fmt.Errorf("PLAID_CLIENT_ID and PLAID_SECRET are required")
+	}
+
+	env := common.GetEnv("PLAID_ENV", "sandbox")
+	baseURL, ok := plaidHosts[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown PLAID_ENV: %s", env)
+	}
+
+	return &PlaidProvider{
+		baseURL:  baseURL,
+		clientID: clientID,
+		secret:   secret,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (p *PlaidProvider) Name() string {
+	return "plaid"
+}
+
+func (p *PlaidProvider) CreateLinkToken(ctx context.Context, userID, clientName string) (string, error) {
+	var response struct {
+		LinkToken string `json:"link_token"`
+	}
+	err := p.call(ctx, "/link/token/create", map[string]interface{}{
+		"client_name":   clientName,
+		"language":      "en",
+		"country_codes": []string{"US"},
+		"user":          map[string]string{"client_user_id": userID},
+		"products":      []string{"auth", "identity", "transfer"},
+	}, &response)
+	return response.LinkToken, err
+}
+
+func (p *PlaidProvider) ExchangePublicToken(ctx context.Context, publicToken string) (*LinkedItem, error) {
+	var exchange struct {
+		AccessToken string `json:"access_token"`
+		ItemID      string `json:"item_id"`
+	}
+	if err := p.call(ctx, "/item/public_token/exchange", map[string]interface{}{
+		"public_token": publicToken,
+	}, &exchange); err != nil {
+		return nil, err
+	}
+
+	var accounts struct {
+		Accounts []struct {
+			AccountID string `json:"account_id"`
+			Name      string `json:"name"`
+			Mask      string `json:"mask"`
+			Type      string `json:"type"`
+			Subtype   string `json:"subtype"`
+		} `json:"accounts"`
+		Item struct {
+			InstitutionID string `json:"institution_id"`
+		} `json:"item"`
+	}
+	if err := p.call(ctx, "/accounts/get", map[string]interface{}{
+		"access_token": exchange.AccessToken,
+	}, &accounts); err != nil {
+		return nil, err
+	}
+
+	item := &LinkedItem{
+		AccessToken:     exchange.AccessToken,
+		ItemID:          exchange.ItemID,
+		InstitutionName: accounts.Item.InstitutionID,
+	}
+	for _, account := range accounts.Accounts {
+		// Only depository accounts can be debited over ACH
+		if account.Type != "depository" {
+			continue
+		}
+		item.Accounts = append(item.Accounts, ExternalAccount{
+			ID:      account.AccountID,
+			Name:    account.Name,
+			Mask:    account.Mask,
+			Type:    account.Type,
+			Subtype: account.Subtype,
+		})
+	}
+	return item, nil
+}
+
+func (p *PlaidProvider) VerifyOwnership(ctx context.Context, accessToken, accountID, ownerName string) (bool, error) {
+	var identity struct {
+		Accounts []struct {
+			AccountID string `json:"account_id"`
+			Owners    []struct {
+				Names []string `json:"names"`
+			} `json:"owners"`
+		} `json:"accounts"`
+	}
+	if err := p.call(ctx, "/identity/get", map[string]interface{}{
+		"access_token": accessToken,
+		"options":      map[string]interface{}{"account_ids": []string{accountID}},
+	}, &identity); err != nil {
+		return false, err
+	}
+
+	for _, account := range identity.Accounts {
+		if account.AccountID != accountID {
+			continue
+		}
+		for _, owner := range account.Owners {
+			for _, name := range owner.Names {
+				if NamesMatch(name, ownerName) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func (p *PlaidProvider) InitiateDebit(ctx context.Context, req DebitRequest) (*Transfer, error) {
+	amount := fmt.Sprintf("%.2f", req.AmountUSD)
+
+	var authorization struct {
+		Authorization struct {
+			ID       string `json:"id"`
+			Decision string `json:"decision"`
+		} `json:"authorization"`
+	}
+	if err := p.call(ctx, "/transfer/authorization/create", map[string]interface{}{
+		"access_token":    req.AccessToken,
+		"account_id":      req.AccountID,
+		"type":            "debit",
+		"network":         "ach",
+		"amount":          amount,
+		"ach_class":       "web",
+		"user":            map[string]string{"legal_name": req.LegalName},
+		"idempotency_key": req.IdempotencyKey,
+	}, &authorization); err != nil {
+		return nil, err
+	}
+	if authorization.Authorization.Decision != "approved" {
+		return nil, fmt.Errorf("transfer authorization %s", authorization.Authorization.Decision)
+	}
+
+	var transfer struct {
+		Transfer struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"transfer"`
+	}
+	if err := p.call(ctx, "/transfer/create", map[string]interface{}{
+		"access_token":     req.AccessToken,
+		"account_id":       req.AccountID,
+		"authorization_id": authorization.Authorization.ID,
+		"description":      truncate(req.Description, 15), // Plaid limits descriptions to 15 characters
+	}, &transfer); err != nil {
+		return nil, err
+	}
+
+	return &Transfer{ID: transfer.Transfer.ID, Status: transfer.Transfer.Status}, nil
+}
+
+// ResolveAccount exchanges the public token and reads the account's ACH
+// numbers and owner from /auth/get and /identity/get
+func (p *PlaidProvider) ResolveAccount(ctx context.Context, publicToken, accountID string) (*BankAccountDetails, error) {
+	item, err := p.ExchangePublicToken(ctx, publicToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var authResponse struct {
+		Accounts []struct {
+			AccountID string `json:"account_id"`
+			Subtype   string `json:"subtype"`
+		} `json:"accounts"`
+		Numbers struct {
+			ACH []struct {
+				AccountID string `json:"account_id"`
+				Account   string `json:"account"`
+				Routing   string `json:"routing"`
+			} `json:"ach"`
+		} `json:"numbers"`
+	}
+	if err := p.call(ctx, "/auth/get", map[string]interface{}{
+		"access_token": item.AccessToken,
+		"options":      map[string]interface{}{"account_ids": []string{accountID}},
+	}, &authResponse); err != nil {
+		return nil, err
+	}
+
+	details := &BankAccountDetails{}
+	for _, numbers := range authResponse.Numbers.ACH {
+		if numbers.AccountID == accountID {
+			details.RoutingNumber = numbers.Routing
+			details.AccountNumber = numbers.Account
+		}
+	}
+	if details.AccountNumber == "" {
+		return nil, fmt.Errorf("plaid returned no ACH numbers for account %s", accountID)
+	}
+	for _, account := range authResponse.Accounts {
+		if account.AccountID == accountID {
+			details.AccountType = account.Subtype
+		}
+	}
+
+	var identity struct {
+		Accounts []struct {
+			AccountID string `json:"account_id"`
+			Owners    []struct {
+				Names []string `json:"names"`
+			} `json:"owners"`
+		} `json:"accounts"`
+	}
+	if err := p.call(ctx, "/identity/get", map[string]interface{}{
+		"access_token": item.AccessToken,
+		"options":      map[string]interface{}{"account_ids": []string{accountID}},
+	}, &identity); err != nil {
+		return nil, err
+	}
+	for _, account := range identity.Accounts {
+		if account.AccountID == accountID && len(account.Owners) > 0 && len(account.Owners[0].Names) > 0 {
+			details.HolderName = account.Owners[0].Names[0]
+		}
+	}
+
+	return details, nil
+}
+
+// SendMicroDeposits is not offered by Plaid for accounts entered manually;
+// payees verify through Link instead
+func (p *PlaidProvider) SendMicroDeposits(ctx context.Context, account BankAccountDetails, amountsCents []int64) (string, error) {
+	return "", ErrMicroDepositsUnsupported
+}
+
+// call posts a request to the Plaid API and decodes the response into out
+func (p *PlaidProvider) call(ctx context.Context, path string, payload map[string]interface{}, out interface{}) error {
+	payload["client_id"] = p.clientID
+	payload["secret"] = p.secret
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("plaid %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			ErrorCode    string `json:"error_code"`
+			ErrorMessage string `json:"error_message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("plaid %s: %s: %s", path, apiErr.ErrorCode, apiErr.ErrorMessage)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,92 @@
+package funding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// SandboxProvider is an in-process provider for development. Public tokens
+// starting with "public-sandbox" link a fixed checking and savings account;
+// the owner name is whatever follows "public-sandbox-" (if anything).
+type SandboxProvider struct{}
+
+// NewSandboxProvider creates a sandbox provider
+func NewSandboxProvider() *SandboxProvider {
+	return &SandboxProvider{}
+}
+
+func (p *SandboxProvider) Name() string {
+	return "sandbox"
+}
+
+func (p *SandboxProvider) CreateLinkToken(ctx context.Context, userID, clientName string) (string, error) {
+	return "link-sandbox-" + common.GenerateUUID(), nil
+}
+
+func (p *SandboxProvider) ExchangePublicToken(ctx context.Context, publicToken string) (*LinkedItem, error) {
+	if !strings.HasPrefix(publicToken, "public-sandbox") {
+		return nil, fmt.Errorf("invalid public token")
+	}
+
+	sum := sha256.Sum256([]byte(publicToken))
+	itemID := hex.EncodeToString(sum[:8])
+
+	return &LinkedItem{
+		AccessToken:     "access-sandbox-" + itemID + "|" + strings.TrimPrefix(strings.TrimPrefix(publicToken, "public-sandbox"), "-"),
+		ItemID:          itemID,
+		InstitutionName: "Sandbox Bank",
+		Accounts: []ExternalAccount{
+			{ID: itemID + "-chk", Name: "Sandbox Checking", Mask: "0000", Type: "depository", Subtype: "checking"},
+			{ID: itemID + "-sav", Name: "Sandbox Savings", Mask: "1111", Type: "depository", Subtype: "savings"},
+		},
+	}, nil
+}
+
+func (p *SandboxProvider) VerifyOwnership(ctx context.Context, accessToken, accountID, ownerName string) (bool, error) {
+	parts := strings.SplitN(accessToken, "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		// Tokens without an embedded owner always verify
+		return true, nil
+	}
+	return NamesMatch(strings.ReplaceAll(parts[1], "-", " "), ownerName), nil
+}
+
+func (p *SandboxProvider) InitiateDebit(ctx context.Context, req DebitRequest) (*Transfer, error) {
+	if req.AmountUSD <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	return &Transfer{ID: "transfer-sandbox-" + common.GenerateUUID(), Status: "posted"}, nil
+}
+
+// ResolveAccount returns fixed test numbers for sandbox accounts; the holder
+// name comes from the public token as for ExchangePublicToken
+func (p *SandboxProvider) ResolveAccount(ctx context.Context, publicToken, accountID string) (*BankAccountDetails, error) {
+	item, err := p.ExchangePublicToken(ctx, publicToken)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range item.Accounts {
+		if account.ID != accountID {
+			continue
+		}
+		holder := strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(publicToken, "public-sandbox"), "-"), "-", " ")
+		return &BankAccountDetails{
+			HolderName:    holder,
+			RoutingNumber: "011000015",
+			AccountNumber: "00001111222" + account.Mask,
+			AccountType:   account.Subtype,
+		}, nil
+	}
+	return nil, fmt.Errorf("account %s not found", accountID)
+}
+
+// SendMicroDeposits logs the amounts instead of moving money
+func (p *SandboxProvider) SendMicroDeposits(ctx context.Context, account BankAccountDetails, amountsCents []int64) (string, error) {
+	common.Info("Sandbox micro-deposits of %v cents to account ending %s", amountsCents, Mask(account.AccountNumber))
+	return "microdeposit-sandbox-" + common.GenerateUUID(), nil
+}
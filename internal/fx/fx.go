@@ -0,0 +1,167 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rounding"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// BaseCurrency is the currency risk limits, consents and rail thresholds are expressed in
+const BaseCurrency = "USD"
+
+var (
+	ErrInvalidCurrency     = errors.New("currency must be a 3-letter ISO 4217 code")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// RateProvider quotes exchange rates: one unit of from is worth rate units of to
+type RateProvider interface {
+	Name() string
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Conversion is a quoted conversion between two currencies
+type Conversion struct {
+	FromCurrency string  `json:"fromCurrency"`
+	FromAmount   float64 `json:"fromAmount"`
+	ToCurrency   string  `json:"toCurrency"`
+	ToAmount     float64 `json:"toAmount"`
+	Rate         float64 `json:"rate"`
+	Provider     string  `json:"provider"`
+}
+
+// NormalizeCurrency upper-cases a currency code, defaulting to the base currency
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return BaseCurrency, nil
+	}
+	if len(code) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return code, nil
+}
+
+// RoundAmount rounds to cents, the precision stored by the ledger, under the
+// ledger's rounding policy
+func RoundAmount(amount float64) float64 {
+	return rounding.Round(amount)
+}
+
+// Converter converts amounts using a rate provider
+type Converter struct {
+	provider RateProvider
+}
+
+// NewConverter creates a converter backed by the provider
+func NewConverter(provider RateProvider) *Converter {
+	return &Converter{provider: provider}
+}
+
+// NewConverterFromEnv returns a converter for the provider selected by FX_PROVIDER.
+// Only "static" is built in; FX_STATIC_RATES overrides its rates.
+func NewConverterFromEnv() (*Converter, error) {
+	switch provider := common.GetEnv("FX_PROVIDER", "static"); provider {
+	case "static":
+		rates := DefaultRates()
+		if overrides := common.GetEnv("FX_STATIC_RATES", ""); overrides != "" {
+			parsed, err := ParseRates(overrides)
+			if err != nil {
+				return nil, err
+			}
+			for currency, rate := range parsed {
+				rates[currency] = rate
+			}
+		}
+		return NewConverter(NewStaticRateProvider(rates)), nil
+	default:
+		return nil, fmt.Errorf("unknown FX provider: %s", provider)
+	}
+}
+
+// Convert quotes amount in from as an amount in to, rounded to cents
+func (c *Converter) Convert(ctx context.Context, amount float64, from, to string) (*Conversion, error) {
+	from, err := NormalizeCurrency(from)
+	if err != nil {
+		return nil, err
+	}
+	to, err = NormalizeCurrency(to)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := 1.0
+	if from != to {
+		rate, err = c.provider.Rate(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Conversion{
+		FromCurrency: from,
+		FromAmount:   amount,
+		ToCurrency:   to,
+		ToAmount:     RoundAmount(amount * rate),
+		Rate:         rate,
+		Provider:     c.provider.Name(),
+	}, nil
+}
+
+// ToBase converts an amount to the base currency
+func (c *Converter) ToBase(ctx context.Context, amount float64, currency string) (float64, error) {
+	conversion, err := c.Convert(ctx, amount, currency, BaseCurrency)
+	if err != nil {
+		return 0, err
+	}
+	return conversion.ToAmount, nil
+}
+
+// ConversionPostings returns the postings that move a conversion between two
+// accounts. Each currency leg is balanced against an FX trading account in the
+// same currency, so the transaction nets to zero per currency.
+func ConversionPostings(conversion *Conversion, fromAccountID, fromTradingAccountID, toAccountID, toTradingAccountID string) []*database.Posting {
+	return []*database.Posting{
+		{AccountID: fromAccountID, Amount: -conversion.FromAmount, Currency: conversion.FromCurrency},
+		{AccountID: fromTradingAccountID, Amount: conversion.FromAmount, Currency: conversion.FromCurrency},
+		{AccountID: toTradingAccountID, Amount: -conversion.ToAmount, Currency: conversion.ToCurrency},
+		{AccountID: toAccountID, Amount: conversion.ToAmount, Currency: conversion.ToCurrency},
+	}
+}
+
+// TradingAccountName is the name of the per-currency FX trading account
+func TradingAccountName(currency string) string {
+	return "FX Trading " + currency
+}
+
+// ParseRates parses "EUR=1.08,GBP=1.27" into base currency rates
+func ParseRates(spec string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid FX rate entry: %q", entry)
+		}
+		currency, err := NormalizeCurrency(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid FX rate for %s: %q", currency, parts[1])
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
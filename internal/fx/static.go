@@ -0,0 +1,54 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultRates are indicative base currency values of one unit of each currency
+func DefaultRates() map[string]float64 {
+	return map[string]float64{
+		"USD": 1.0,
+		"EUR": 1.08,
+		"GBP": 1.27,
+		"JPY": 0.0067,
+		"CAD": 0.73,
+		"AUD": 0.66,
+		"CHF": 1.12,
+		"SGD": 0.74,
+		"INR": 0.012,
+		"MXN": 0.058,
+	}
+}
+
+// StaticRateProvider quotes fixed rates, for development and tests. Rates are
+// the base currency value of one unit; cross rates are derived through the base.
+type StaticRateProvider struct {
+	rates map[string]float64
+}
+
+// NewStaticRateProvider creates a provider from base currency rates
+func NewStaticRateProvider(rates map[string]float64) *StaticRateProvider {
+	copied := make(map[string]float64, len(rates)+1)
+	for currency, rate := range rates {
+		copied[currency] = rate
+	}
+	copied[BaseCurrency] = 1.0
+	return &StaticRateProvider{rates: copied}
+}
+
+func (p *StaticRateProvider) Name() string {
+	return "static"
+}
+
+func (p *StaticRateProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+	return fromRate / toRate, nil
+}
@@ -0,0 +1,145 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UpdateEnvVar is the environment variable that, when set to "1" or "true",
+// rewrites golden files with the current output instead of comparing
+const UpdateEnvVar = "GOLDEN_UPDATE"
+
+// DefaultDir is the directory (relative to the caller) holding golden files
+const DefaultDir = "testdata"
+
+// Placeholder replaces every normalized timestamp
+const Placeholder = "<TIMESTAMP>"
+
+// Normalizer rewrites volatile parts of a generated artifact (timestamps,
+// message IDs) so the remaining bytes can be compared deterministically
+type Normalizer func([]byte) []byte
+
+// TB is the subset of testing.TB used by Assert
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// 2006-01-02T15:04:05, optionally with fractional seconds and a zone
+var isoDateTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?`)
+
+// NormalizeISODateTimes replaces ISO 8601 / RFC 3339 date-times, as used by
+// ISO 20022 (CreDtTm, AccptncDtTm)
+func NormalizeISODateTimes(data []byte) []byte {
+	return isoDateTimePattern.ReplaceAll(data, []byte(Placeholder))
+}
+
+// NormalizeLineEndings converts CRLF to LF so checked-in files compare equal
+// regardless of platform
+func NormalizeLineEndings(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// ReplaceAll returns a normalizer that replaces every match of pattern with
+// replacement, for message IDs and other generated references
+func ReplaceAll(pattern, replacement string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return func(data []byte) []byte {
+		return re.ReplaceAll(data, []byte(replacement))
+	}
+}
+
+// ISO20022 normalizes ISO 20022 XML messages
+var ISO20022 = []Normalizer{NormalizeLineEndings, NormalizeISODateTimes}
+
+// Normalize applies the normalizers in order
+func Normalize(data []byte, normalizers ...Normalizer) []byte {
+	out := append([]byte(nil), data...)
+	for _, n := range normalizers {
+		out = n(out)
+	}
+	return out
+}
+
+// Path returns the golden file path for name under DefaultDir
+func Path(name string) string {
+	return filepath.Join(DefaultDir, name+".golden")
+}
+
+// ShouldUpdate reports whether golden files should be rewritten
+func ShouldUpdate() bool {
+	value := strings.ToLower(os.Getenv(UpdateEnvVar))
+	return value == "1" || value == "true"
+}
+
+// Compare normalizes got and compares it against the golden file at path.
+// In update mode the normalized output is written to path instead.
+func Compare(path string, got []byte, normalizers ...Normalizer) error {
+	normalized := Normalize(got, normalizers...)
+
+	if ShouldUpdate() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			return fmt.Errorf("failed to write golden file %s: %v", path, err)
+		}
+		return nil
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file %s (run with %s=1 to create it): %v", path, UpdateEnvVar, err)
+	}
+	want = NormalizeLineEndings(want)
+
+	if !bytes.Equal(want, normalized) {
+		return fmt.Errorf("golden mismatch for %s:\n%s", path, Diff(string(want), string(normalized)))
+	}
+	return nil
+}
+
+// Assert compares got against testdata/<name>.golden and fails the test on mismatch
+func Assert(t TB, name string, got []byte, normalizers ...Normalizer) {
+	t.Helper()
+	if err := Compare(Path(name), got, normalizers...); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+// Diff returns a line-oriented description of the differences between want and got
+func Diff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	var b strings.Builder
+	lines := len(wantLines)
+	if len(gotLines) > lines {
+		lines = len(gotLines)
+	}
+
+	shown := 0
+	for i := 0; i < lines && shown < 20; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n  - %q\n  + %q\n", i+1, w, g)
+		shown++
+	}
+
+	if shown == 20 {
+		b.WriteString("  ... (further differences omitted)\n")
+	}
+	return b.String()
+}
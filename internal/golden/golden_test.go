@@ -0,0 +1,55 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name        string
+		normalizers []Normalizer
+		in, want    string
+	}{
+		{"iso20022", ISO20022,
+			"<CreDtTm>2026-03-14T15:09:26Z</CreDtTm>\r\n<AccptncDtTm>2026-03-14T15:09:26.123+01:00</AccptncDtTm>",
+			"<CreDtTm><TIMESTAMP></CreDtTm>\n<AccptncDtTm><TIMESTAMP></AccptncDtTm>"},
+		{"iso20022 keeps dates", ISO20022,
+			"<IntrBkSttlmDt>2026-03-14</IntrBkSttlmDt>",
+			"<IntrBkSttlmDt>2026-03-14</IntrBkSttlmDt>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Normalize([]byte(tt.in), tt.normalizers...)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.golden")
+
+	t.Setenv(UpdateEnvVar, "1")
+	if err := Compare(path, []byte("at 2026-03-14T15:09:26Z\r\n"), ISO20022...); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	written, _ := os.ReadFile(path)
+	if string(written) != "at <TIMESTAMP>\n" {
+		t.Fatalf("wrote %q", written)
+	}
+
+	t.Setenv(UpdateEnvVar, "")
+	if err := Compare(path, []byte("at 2027-01-01T00:00:00Z\n"), ISO20022...); err != nil {
+		t.Fatalf("a different timestamp should match: %v", err)
+	}
+	err := Compare(path, []byte("on 2027-01-01T00:00:00Z\n"), ISO20022...)
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("want a mismatch on line 1, got %v", err)
+	}
+	if err := Compare(filepath.Join(t.TempDir(), "missing.golden"), nil); err == nil {
+		t.Fatal("want an error for a missing golden file")
+	}
+}
@@ -101,12 +101,15 @@ func (hc *HashChain) GetBlocks() []*Block {
 
 // calculateHash calculates the SHA-256 hash of a block
 func calculateHash(block *Block) string {
-	record := fmt.Sprintf("%d%s%s%d", block.Index, block.Timestamp.String(), block.PreviousHash, block.Data)
+	record := fmt.Sprintf("%d%s%s%s", block.Index, block.Timestamp.String(), block.PreviousHash, block.Data)
 	h := sha256.New()
 	h.Write([]byte(record))
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// GenesisHash is the previous hash of the first transaction in a chain
+const GenesisHash = "0"
+
 // TransactionHashData represents the data to be hashed for a transaction
 type TransactionHashData struct {
 	TransactionID string
@@ -116,6 +119,8 @@ type TransactionHashData struct {
 	Currency      string
 	Timestamp     time.Time
 	Postings      []PostingHashData
+	PreviousHash  string // Hash of the agent's previous transaction
+	BlockIndex    int    // Position in the agent's chain, from 1
 }
 
 // PostingHashData represents posting data for hashing
@@ -138,20 +143,74 @@ func GenerateTransactionHash(data TransactionHashData) string {
 			fmt.Sprintf("%s:%.2f:%s", posting.AccountID, posting.Amount, posting.Currency))
 	}
 
-	record := fmt.Sprintf("%s|%s|%s|%.2f|%s|%s|%s",
+	record := fmt.Sprintf("%s|%s|%s|%.2f|%s|%s|%s|%s|%d",
 		data.TransactionID,
 		data.AgentID,
 		data.Description,
 		data.Amount,
 		data.Currency,
-		data.Timestamp.Format(time.RFC3339),
-		strings.Join(postingStrings, "|"))
+		data.Timestamp.UTC().Format(time.RFC3339),
+		strings.Join(postingStrings, "|"),
+		data.PreviousHash,
+		data.BlockIndex)
 
 	h := sha256.New()
 	h.Write([]byte(record))
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// TransactionLink is a transaction as stored in its agent's chain
+type TransactionLink struct {
+	Data TransactionHashData
+	Hash string // Hash stored with the transaction
+}
+
+// ChainBreak describes the first link of a chain that fails verification
+type ChainBreak struct {
+	TransactionID string `json:"transactionId"`
+	BlockIndex    int    `json:"blockIndex"`
+	Reason        string `json:"reason"`
+	ExpectedHash  string `json:"expectedHash,omitempty"`
+	ActualHash    string `json:"actualHash,omitempty"`
+}
+
+// VerifyTransactionChain checks a chain of transactions in block order: each
+// must follow the previous one's index, carry its hash as previous hash, and
+// hash to its stored hash. It returns the first link that does not, or nil if
+// the chain is intact.
+func VerifyTransactionChain(links []TransactionLink) *ChainBreak {
+	previousHash := GenesisHash
+	for i, link := range links {
+		if link.Data.BlockIndex != i+1 {
+			return &ChainBreak{
+				TransactionID: link.Data.TransactionID,
+				BlockIndex:    link.Data.BlockIndex,
+				Reason:        fmt.Sprintf("expected block index %d", i+1),
+			}
+		}
+		if link.Data.PreviousHash != previousHash {
+			return &ChainBreak{
+				TransactionID: link.Data.TransactionID,
+				BlockIndex:    link.Data.BlockIndex,
+				Reason:        "previous hash does not match the previous transaction",
+				ExpectedHash:  previousHash,
+				ActualHash:    link.Data.PreviousHash,
+			}
+		}
+		if computed := GenerateTransactionHash(link.Data); computed != link.Hash {
+			return &ChainBreak{
+				TransactionID: link.Data.TransactionID,
+				BlockIndex:    link.Data.BlockIndex,
+				Reason:        "transaction data does not match its hash",
+				ExpectedHash:  computed,
+				ActualHash:    link.Hash,
+			}
+		}
+		previousHash = link.Hash
+	}
+	return nil
+}
+
 // PaymentHashData represents the data to be hashed for a payment
 type PaymentHashData struct {
 	PaymentID    string
@@ -252,3 +311,47 @@ func (mt *MerkleTree) VerifyMerkleProof(leafHash string, proof []string) bool {
 
 	return currentHash == mt.Root
 }
+
+// ProofStep is one sibling hash on the path from a leaf to the root
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"` // The sibling is hashed before the current hash
+}
+
+// Proof returns the sibling hashes linking the leaf at index to the root. A
+// node without a sibling is paired with itself, as when the tree is built.
+func (mt *MerkleTree) Proof(index int) ([]ProofStep, error) {
+	if index < 0 || index >= len(mt.Leaves) {
+		return nil, fmt.Errorf("leaf %d is not in the tree", index)
+	}
+
+	var proof []ProofStep
+	for _, level := range mt.Levels[:len(mt.Levels)-1] {
+		if index%2 == 0 {
+			sibling := index + 1
+			if sibling >= len(level) {
+				sibling = index
+			}
+			proof = append(proof, ProofStep{Hash: level[sibling]})
+		} else {
+			proof = append(proof, ProofStep{Hash: level[index-1], Left: true})
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyProof checks that a leaf hashes up to a root through a proof
+func VerifyProof(leafHash string, proof []ProofStep, root string) bool {
+	currentHash := leafHash
+	for _, step := range proof {
+		combined := currentHash + step.Hash
+		if step.Left {
+			combined = step.Hash + currentHash
+		}
+		h := sha256.New()
+		h.Write([]byte(combined))
+		currentHash = hex.EncodeToString(h.Sum(nil))
+	}
+	return currentHash == root
+}
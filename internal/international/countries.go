@@ -0,0 +1,53 @@
+package international
+
+import "regexp"
+
+// countryRule is what a destination country requires of a payment, and the
+// correspondent banks a wire to it usually passes through
+type countryRule struct {
+	IBANLength  int            // Length of the country's IBANs; zero if it does not use them
+	Account     *regexp.Regexp // Format of account numbers, for countries without IBANs
+	AccountName string         // Name of that format, for errors
+	PostalCode  bool           // Beneficiary addresses need a postal code
+	PurposeCode bool           // Payments need a purpose code
+
+	Intermediaries int     // Correspondent banks between the platform's bank and the beneficiary's
+	ReceivingFee   float64 // Fee the beneficiary's bank charges for incoming wires, USD
+}
+
+// countries are the supported destinations. Corridors with a direct
+// correspondent relationship pass through one intermediary; the rest
+// through two.
+var countries = map[string]countryRule{
+	// Europe: IBAN countries, mostly reached through one euro correspondent
+	"AT": {IBANLength: 20, PostalCode: true, Intermediaries: 1, ReceivingFee: 5},
+	"BE": {IBANLength: 16, PostalCode: true, Intermediaries: 1, ReceivingFee: 5},
+	"CH": {IBANLength: 21, PostalCode: true, Intermediaries: 1, ReceivingFee: 10},
+	"DE": {IBANLength: 22, PostalCode: true, Intermediaries: 1, ReceivingFee: 5},
+	"DK": {IBANLength: 18, PostalCode: true, Intermediaries: 1, ReceivingFee: 8},
+	"ES": {IBANLength: 24, PostalCode: true, Intermediaries: 1, ReceivingFee: 6},
+	"FR": {IBANLength: 27, PostalCode: true, Intermediaries: 1, ReceivingFee: 5},
+	"GB": {IBANLength: 22, PostalCode: true, Intermediaries: 1, ReceivingFee: 8},
+	"IE": {IBANLength: 22, Intermediaries: 1, ReceivingFee: 5},
+	"IT": {IBANLength: 27, PostalCode: true, Intermediaries: 1, ReceivingFee: 6},
+	"NL": {IBANLength: 18, PostalCode: true, Intermediaries: 1, ReceivingFee: 5},
+	"NO": {IBANLength: 15, PostalCode: true, Intermediaries: 2, ReceivingFee: 8},
+	"PL": {IBANLength: 28, PostalCode: true, Intermediaries: 2, ReceivingFee: 6},
+	"PT": {IBANLength: 25, PostalCode: true, Intermediaries: 1, ReceivingFee: 6},
+	"SE": {IBANLength: 24, PostalCode: true, Intermediaries: 1, ReceivingFee: 8},
+
+	// Middle East and Latin America IBAN countries
+	"AE": {IBANLength: 23, PurposeCode: true, Intermediaries: 2, ReceivingFee: 10},
+	"BR": {IBANLength: 29, PostalCode: true, PurposeCode: true, Intermediaries: 2, ReceivingFee: 15},
+	"SA": {IBANLength: 24, PurposeCode: true, Intermediaries: 2, ReceivingFee: 10},
+
+	// Countries paid by account number
+	"AU": {Account: regexp.MustCompile(`^[0-9]{6}[0-9]{5,9}$`), AccountName: "BSB and account number", PostalCode: true, Intermediaries: 1, ReceivingFee: 10},
+	"CA": {Account: regexp.MustCompile(`^[0-9]{7,12}$`), AccountName: "account number", PostalCode: true, Intermediaries: 1, ReceivingFee: 10},
+	"CN": {Account: regexp.MustCompile(`^[0-9]{8,25}$`), AccountName: "account number", PurposeCode: true, Intermediaries: 2, ReceivingFee: 15},
+	"HK": {Account: regexp.MustCompile(`^[0-9]{9,12}$`), AccountName: "account number", Intermediaries: 1, ReceivingFee: 10},
+	"IN": {Account: regexp.MustCompile(`^[0-9]{9,18}$`), AccountName: "account number", PostalCode: true, PurposeCode: true, Intermediaries: 2, ReceivingFee: 10},
+	"JP": {Account: regexp.MustCompile(`^[0-9]{7}$`), AccountName: "account number", PostalCode: true, Intermediaries: 1, ReceivingFee: 15},
+	"MX": {Account: regexp.MustCompile(`^[0-9]{18}$`), AccountName: "CLABE", PostalCode: true, Intermediaries: 2, ReceivingFee: 10},
+	"SG": {Account: regexp.MustCompile(`^[0-9]{7,14}$`), AccountName: "account number", PostalCode: true, Intermediaries: 1, ReceivingFee: 10},
+}
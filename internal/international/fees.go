@@ -0,0 +1,51 @@
+package international
+
+import "github.com/example/agent-payments/internal/rounding"
+
+// Correspondent fee schedule: each intermediary bank charges a flat fee
+// plus a share of the amount, kept within bounds
+const (
+	intermediaryFixedFeeUSD = 15.0
+	intermediaryPercentFee  = 0.0005
+	intermediaryMaxFeeUSD   = 50.0
+)
+
+// CorrespondentFees estimates the fees a wire abroad incurs beyond the
+// rail's own fee, and who pays them
+type CorrespondentFees struct {
+	ChargeBearer   string  `json:"chargeBearer"`
+	Intermediaries int     `json:"intermediaries"`
+	EstimatedUSD   float64 `json:"estimatedUSD"`   // Intermediary and receiving bank fees together
+	PayerUSD       float64 `json:"payerUSD"`       // Part charged to the payer with the payment's fee
+	DeductedUSD    float64 `json:"deductedUSD"`    // Part deducted from the amount in transit
+	BeneficiaryUSD float64 `json:"beneficiaryUSD"` // Amount the beneficiary is expected to receive
+}
+
+// EstimateFees estimates the correspondent fees of a payment of amountUSD
+// with the details, which should be valid. Under OUR the payer pays them;
+// under SHA and BEN they are deducted from the amount on the way.
+func EstimateFees(d *Details, amountUSD float64) *CorrespondentFees {
+	rule := countries[d.Country()]
+	perIntermediary := intermediaryFixedFeeUSD + amountUSD*intermediaryPercentFee
+	if perIntermediary > intermediaryMaxFeeUSD {
+		perIntermediary = intermediaryMaxFeeUSD
+	}
+	estimate := rounding.Round(float64(rule.Intermediaries)*perIntermediary + rule.ReceivingFee)
+
+	fees := &CorrespondentFees{
+		ChargeBearer:   d.ChargeBearer,
+		Intermediaries: rule.Intermediaries,
+		EstimatedUSD:   estimate,
+		BeneficiaryUSD: amountUSD,
+	}
+	if d.ChargeBearer == ChargeOurs {
+		fees.PayerUSD = estimate
+	} else {
+		fees.DeductedUSD = estimate
+		fees.BeneficiaryUSD = rounding.Round(amountUSD - estimate)
+		if fees.BeneficiaryUSD < 0 {
+			fees.BeneficiaryUSD = 0
+		}
+	}
+	return fees
+}
@@ -0,0 +1,196 @@
+// Package international describes payments to beneficiaries abroad, sent
+// as SWIFT wires: the beneficiary's bank details, checked against the rules
+// of the destination country, and the correspondent bank fees the payment
+// is expected to incur on its way.
+//
+// Who pays those fees is the payment's charge bearer:
+//
+//	OUR  the payer pays all of them, so the beneficiary receives the full amount
+//	SHA  the payer pays its own bank's fees; correspondents deduct theirs in transit
+//	BEN  the beneficiary bears all fees, deducted in transit
+package international
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// DomesticCountry is the platform's own country; payments to it are not
+// international
+const DomesticCountry = "US"
+
+// Charge bearers
+const (
+	ChargeOurs   = "OUR"
+	ChargeShared = "SHA"
+	ChargeBen    = "BEN"
+)
+
+// Address is a beneficiary's postal address
+type Address struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country"` // ISO 3166 alpha-2 code
+}
+
+// Details identify a beneficiary abroad and how its payment is sent
+type Details struct {
+	BeneficiaryName string  `json:"beneficiaryName"`
+	Address         Address `json:"address"`
+	IBAN            string  `json:"iban,omitempty"`          // For countries using IBANs
+	AccountNumber   string  `json:"accountNumber,omitempty"` // For countries that do not
+	BIC             string  `json:"bic"`                     // SWIFT code of the beneficiary's bank
+	PurposeCode     string  `json:"purposeCode,omitempty"`   // ISO 20022 purpose, such as SUPP or SALA
+	ChargeBearer    string  `json:"chargeBearer,omitempty"`  // OUR, SHA (default) or BEN
+}
+
+// Country is the destination country of the payment
+func (d *Details) Country() string {
+	if d == nil {
+		return ""
+	}
+	return strings.ToUpper(d.Address.Country)
+}
+
+// IsInternational reports whether a payment with these details leaves the
+// country. Payments without details are domestic.
+func IsInternational(d *Details) bool {
+	return d != nil && d.Country() != DomesticCountry
+}
+
+// Encode stores details as a JSON object, or empty for none
+func Encode(d *Details) string {
+	if d == nil {
+		return ""
+	}
+	data, _ := json.Marshal(d)
+	return string(data)
+}
+
+// Decode reads details stored by Encode; empty or malformed ones are none
+func Decode(encoded string) *Details {
+	if encoded == "" {
+		return nil
+	}
+	var d Details
+	if err := json.Unmarshal([]byte(encoded), &d); err != nil {
+		return nil
+	}
+	return &d
+}
+
+var (
+	bicPattern     = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	purposePattern = regexp.MustCompile(`^[A-Z]{4}$`)
+	ibanPattern    = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]+$`)
+)
+
+// Normalize upper-cases the codes and strips spaces from the IBAN and BIC,
+// and defaults the charge bearer to SHA
+func (d *Details) Normalize() {
+	d.IBAN = strings.ToUpper(strings.ReplaceAll(d.IBAN, " ", ""))
+	d.BIC = strings.ToUpper(strings.ReplaceAll(d.BIC, " ", ""))
+	d.Address.Country = strings.ToUpper(strings.TrimSpace(d.Address.Country))
+	d.PurposeCode = strings.ToUpper(strings.TrimSpace(d.PurposeCode))
+	d.ChargeBearer = strings.ToUpper(strings.TrimSpace(d.ChargeBearer))
+	if d.ChargeBearer == "" {
+		d.ChargeBearer = ChargeShared
+	}
+}
+
+// Validate checks the details against the rules of the destination
+// country. Details should be normalized first.
+func Validate(d *Details) error {
+	country := d.Country()
+	if country == DomesticCountry {
+		return fmt.Errorf("beneficiary in %s is paid domestically, without international details", DomesticCountry)
+	}
+	rule, ok := countries[country]
+	if !ok {
+		return fmt.Errorf("payments to country %q are not supported", d.Address.Country)
+	}
+
+	if strings.TrimSpace(d.BeneficiaryName) == "" {
+		return fmt.Errorf("beneficiaryName is required")
+	}
+	if strings.TrimSpace(d.Address.Street) == "" || strings.TrimSpace(d.Address.City) == "" {
+		return fmt.Errorf("beneficiary address requires street and city")
+	}
+	if rule.PostalCode && strings.TrimSpace(d.Address.PostalCode) == "" {
+		return fmt.Errorf("beneficiary address in %s requires a postal code", country)
+	}
+
+	if !bicPattern.MatchString(d.BIC) {
+		return fmt.Errorf("bic %q is not a valid SWIFT code", d.BIC)
+	}
+	if d.BIC[4:6] != country {
+		return fmt.Errorf("bic %s belongs to a bank in %s, not %s", d.BIC, d.BIC[4:6], country)
+	}
+
+	if rule.IBANLength > 0 {
+		if d.IBAN == "" {
+			return fmt.Errorf("beneficiaries in %s are paid by iban", country)
+		}
+		if err := validateIBAN(d.IBAN, country, rule.IBANLength); err != nil {
+			return err
+		}
+	} else {
+		if d.IBAN != "" {
+			return fmt.Errorf("%s does not use ibans; give accountNumber instead", country)
+		}
+		if d.AccountNumber == "" {
+			return fmt.Errorf("accountNumber is required for beneficiaries in %s", country)
+		}
+		if rule.Account != nil && !rule.Account.MatchString(d.AccountNumber) {
+			return fmt.Errorf("accountNumber is not a valid %s for %s", rule.AccountName, country)
+		}
+	}
+
+	if d.PurposeCode != "" && !purposePattern.MatchString(d.PurposeCode) {
+		return fmt.Errorf("purposeCode %q must be a four-letter ISO 20022 purpose code", d.PurposeCode)
+	}
+	if rule.PurposeCode && d.PurposeCode == "" {
+		return fmt.Errorf("payments to %s require a purposeCode", country)
+	}
+
+	switch d.ChargeBearer {
+	case ChargeOurs, ChargeShared, ChargeBen:
+	default:
+		return fmt.Errorf("chargeBearer must be OUR, SHA or BEN")
+	}
+	return nil
+}
+
+// validateIBAN checks an IBAN's country, length and ISO 7064 mod 97-10
+// check digits
+func validateIBAN(iban, country string, length int) error {
+	if !ibanPattern.MatchString(iban) {
+		return fmt.Errorf("iban is malformed")
+	}
+	if iban[:2] != country {
+		return fmt.Errorf("iban is from %s, not %s", iban[:2], country)
+	}
+	if len(iban) != length {
+		return fmt.Errorf("ibans in %s have %d characters, not %d", country, length, len(iban))
+	}
+
+	// Move the country and check digits to the end and read letters as
+	// numbers from 10 (A) to 35 (Z); the result mod 97 must be 1
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(fmt.Sprint(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	number, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok || new(big.Int).Mod(number, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("iban check digits are wrong")
+	}
+	return nil
+}
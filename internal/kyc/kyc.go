@@ -0,0 +1,168 @@
+// Package kyc verifies parties before their agents may pay freely: the
+// identity of individuals (KYC) and the business of organizations (KYB).
+//
+// A party submits attributes and documents, which a verification provider
+// checks. The provider verifies or rejects the submission at once, or leaves
+// it pending for a compliance reviewer to decide. A party's status moves
+// through a small state machine:
+//
+//	unverified -> pending -> verified
+//	                  |
+//	                  +----> rejected -> pending (resubmission)
+//
+// Submissions the provider decides at once skip pending. Verified is final.
+//
+// Agents of parties that are not verified may only make payments up to a
+// configured limit.
+package kyc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Verification statuses, of parties and of their submissions. Submissions
+// are never unverified.
+const (
+	StatusUnverified = "unverified"
+	StatusPending    = "pending"
+	StatusVerified   = "verified"
+	StatusRejected   = "rejected"
+)
+
+// Verification kinds
+const (
+	KindKYC = "kyc" // Individuals
+	KindKYB = "kyb" // Organizations
+)
+
+// transitions lists the statuses each party status may move to
+var transitions = map[string][]string{
+	StatusUnverified: {StatusPending, StatusVerified, StatusRejected},
+	StatusPending:    {StatusVerified, StatusRejected},
+	StatusRejected:   {StatusPending, StatusVerified, StatusRejected},
+}
+
+// Allowed reports whether a party may move from one status to another. A
+// submission decided by the provider at once moves an unverified or
+// rejected party straight to its decision.
+func Allowed(from, to string) bool {
+	for _, next := range transitions[Of(from)] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Of returns a stored status, where an empty status is unverified
+func Of(status string) string {
+	if status == "" {
+		return StatusUnverified
+	}
+	return status
+}
+
+// KindFor returns the verification an owner party of a type goes through
+func KindFor(partyType string) string {
+	if partyType == "organization" {
+		return KindKYB
+	}
+	return KindKYC
+}
+
+// requiredAttributes are the attributes each kind of submission must carry
+var requiredAttributes = map[string][]string{
+	KindKYC: {"legalName", "dateOfBirth", "country"},
+	KindKYB: {"legalName", "registrationNumber", "country"},
+}
+
+// documentTypes are the documents each kind of submission accepts
+var documentTypes = map[string][]string{
+	KindKYC: {"passport", "drivers_license", "national_id", "proof_of_address"},
+	KindKYB: {"certificate_of_incorporation", "articles_of_association", "proof_of_address", "beneficial_ownership"},
+}
+
+// Document is a document submitted for verification. Its content is held by
+// the caller's document store; Reference locates it there.
+type Document struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+	Country   string `json:"country,omitempty"` // ISO 3166-1 alpha-2 issuing country
+}
+
+// Submission is what a party submits for verification
+type Submission struct {
+	PartyID    string
+	PartyName  string
+	Kind       string
+	Attributes map[string]string
+	Documents  []Document
+}
+
+// Validate returns the problems with a submission
+func Validate(submission Submission) []common.ValidationError {
+	var problems []common.ValidationError
+	for _, name := range requiredAttributes[submission.Kind] {
+		if strings.TrimSpace(submission.Attributes[name]) == "" {
+			problems = append(problems, common.ValidationError{Field: "attributes." + name, Message: name + " is required"})
+		}
+	}
+	if len(submission.Documents) == 0 {
+		problems = append(problems, common.ValidationError{Field: "documents", Message: "at least one document is required"})
+	}
+	for i, document := range submission.Documents {
+		field := fmt.Sprintf("documents[%d]", i)
+		if !accepts(submission.Kind, document.Type) {
+			problems = append(problems, common.ValidationError{Field: field + ".type", Message: "type must be one of " + strings.Join(documentTypes[submission.Kind], ", ")})
+		}
+		if strings.TrimSpace(document.Reference) == "" {
+			problems = append(problems, common.ValidationError{Field: field + ".reference", Message: "reference is required"})
+		}
+	}
+	return problems
+}
+
+func accepts(kind, documentType string) bool {
+	for _, accepted := range documentTypes[kind] {
+		if accepted == documentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is a provider's decision on a submission
+type Result struct {
+	Status    string // StatusVerified, StatusRejected or StatusPending
+	Reason    string
+	Reference string // Provider's reference for the check
+}
+
+// Provider checks submissions, such as an identity verification vendor
+type Provider interface {
+	Name() string
+	// Verify checks a submission, returning StatusPending for submissions
+	// left to a compliance reviewer
+	Verify(ctx context.Context, submission Submission) (*Result, error)
+}
+
+// NewProviderFromEnv returns the provider selected by KYC_PROVIDER ("mock")
+func NewProviderFromEnv() (Provider, error) {
+	switch provider := common.GetEnv("KYC_PROVIDER", "mock"); provider {
+	case "mock":
+		return NewMockProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown KYC provider: %s", provider)
+	}
+}
+
+// Allows reports whether an agent of a party with a verification status may
+// make a payment of amountUSD, given the limit on payments of unverified
+// parties
+func Allows(status string, amountUSD, unverifiedLimitUSD float64) bool {
+	return Of(status) == StatusVerified || amountUSD <= unverifiedLimitUSD
+}
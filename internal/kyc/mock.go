@@ -0,0 +1,39 @@
+package kyc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// MockProvider is an in-process provider for development. Submissions are
+// verified unless a document reference contains "reject", which rejects
+// them, or "review", which leaves them pending for a reviewer.
+type MockProvider struct{}
+
+// NewMockProvider creates a mock provider
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Verify(ctx context.Context, submission Submission) (*Result, error) {
+	result := &Result{Status: StatusVerified, Reference: "kyc-mock-" + common.GenerateUUID()}
+	for _, document := range submission.Documents {
+		reference := strings.ToLower(document.Reference)
+		switch {
+		case strings.Contains(reference, "reject"):
+			result.Status = StatusRejected
+			result.Reason = "Document " + document.Reference + " could not be verified"
+			return result, nil
+		case strings.Contains(reference, "review"):
+			result.Status = StatusPending
+			result.Reason = "Document " + document.Reference + " needs manual review"
+		}
+	}
+	return result, nil
+}
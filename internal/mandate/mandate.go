@@ -0,0 +1,176 @@
+package mandate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// PayloadVersion prefixes every canonical mandate payload
+const PayloadVersion = "agentpay-mandate-v1"
+
+// MaxLifetime bounds how far in the future a mandate may expire
+const MaxLifetime = 24 * time.Hour
+
+var (
+	ErrKeyNotFound      = errors.New("mandate key not found")
+	ErrKeyRevoked       = errors.New("mandate key revoked")
+	ErrInvalidSignature = errors.New("invalid mandate signature")
+	ErrExpired          = errors.New("mandate expired")
+	ErrLifetimeTooLong  = errors.New("mandate expiry exceeds maximum lifetime")
+	ErrReplayed         = errors.New("mandate nonce already used")
+)
+
+// Intent is the payment an agent authorizes by signing a mandate
+type Intent struct {
+	AgentID      string
+	AmountUSD    float64
+	Counterparty string
+	ExpiresAt    time.Time
+	Nonce        string
+}
+
+// Proof is the detached signature carried on a payment request
+type Proof struct {
+	KeyID     string `json:"keyId" binding:"required"`
+	Signature string `json:"signature" binding:"required"` // Base64url Ed25519 signature over the canonical payload
+	ExpiresAt string `json:"expiresAt" binding:"required"` // RFC3339
+	Nonce     string `json:"nonce" binding:"required"`
+}
+
+// CanonicalPayload returns the exact bytes an agent signs for an intent.
+// Amounts are fixed to cents and times to UTC so signer and verifier agree.
+func CanonicalPayload(intent Intent) []byte {
+	return []byte(strings.Join([]string{
+		PayloadVersion,
+		intent.AgentID,
+		fmt.Sprintf("%.2f", intent.AmountUSD),
+		intent.Counterparty,
+		intent.ExpiresAt.UTC().Format(time.RFC3339),
+		intent.Nonce,
+	}, "\n"))
+}
+
+// Sign signs an intent with an agent's private key
+func Sign(privateKey ed25519.PrivateKey, intent Intent) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, CanonicalPayload(intent)))
+}
+
+// DecodePublicKey decodes and validates a base64url Ed25519 public key
+func DecodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("public key is not base64url: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Verifier checks mandates against registered agent keys
+type Verifier struct {
+	repo database.Repository
+}
+
+// NewVerifier creates a mandate verifier
+func NewVerifier(repo database.Repository) *Verifier {
+	return &Verifier{repo: repo}
+}
+
+// IntentFromProof builds the signed intent for a payment request and its proof
+func IntentFromProof(agentID string, amountUSD float64, counterparty string, proof *Proof) (Intent, error) {
+	expiresAt, err := time.Parse(time.RFC3339, proof.ExpiresAt)
+	if err != nil {
+		return Intent{}, fmt.Errorf("invalid mandate expiry: %v", err)
+	}
+	return Intent{
+		AgentID:      agentID,
+		AmountUSD:    amountUSD,
+		Counterparty: counterparty,
+		ExpiresAt:    expiresAt,
+		Nonce:        proof.Nonce,
+	}, nil
+}
+
+// Verify checks the signature, key and expiry of a mandate without recording it
+func (v *Verifier) Verify(intent Intent, proof *Proof) (*database.MandateKey, error) {
+	now := time.Now()
+	if !now.Before(intent.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	if intent.ExpiresAt.Sub(now) > MaxLifetime {
+		return nil, ErrLifetimeTooLong
+	}
+
+	key, err := v.repo.MandateKeyRepository().GetByID(proof.KeyID)
+	if err != nil || key.AgentID != intent.AgentID {
+		return nil, ErrKeyNotFound
+	}
+	if key.Status != "active" {
+		return nil, ErrKeyRevoked
+	}
+
+	publicKey, err := DecodePublicKey(key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("mandate key %s is invalid: %v", key.ID, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(proof.Signature)
+	if err != nil || !ed25519.Verify(publicKey, CanonicalPayload(intent), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return key, nil
+}
+
+// VerifyAndRecord verifies a mandate and records it, reserving its nonce so the
+// same signature cannot authorize a second payment
+func (v *Verifier) VerifyAndRecord(intent Intent, proof *Proof) (*database.Mandate, error) {
+	key, err := v.Verify(intent, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	mandate := &database.Mandate{
+		AgentID:      intent.AgentID,
+		Nonce:        intent.Nonce,
+		KeyID:        key.ID,
+		AmountUSD:    intent.AmountUSD,
+		Counterparty: intent.Counterparty,
+		ExpiresAt:    intent.ExpiresAt,
+		Signature:    proof.Signature,
+		VerifiedAt:   time.Now(),
+	}
+
+	// The unique (agent, nonce) index rejects replays
+	if err := v.repo.MandateRepository().Create(mandate); err != nil {
+		return nil, ErrReplayed
+	}
+
+	return mandate, nil
+}
+
+// CheckRecorded confirms a workflow's recorded mandate still covers it
+func (v *Verifier) CheckRecorded(workflow *database.PaymentWorkflow) error {
+	mandate, err := v.repo.MandateRepository().GetByID(workflow.MandateID)
+	if err != nil {
+		return fmt.Errorf("mandate %s not found", workflow.MandateID)
+	}
+
+	if mandate.AgentID != workflow.AgentID ||
+		fmt.Sprintf("%.2f", mandate.AmountUSD) != fmt.Sprintf("%.2f", workflow.AmountUSD) ||
+		mandate.Counterparty != workflow.Counterparty {
+		return fmt.Errorf("mandate %s does not match workflow %s", mandate.ID, workflow.ID)
+	}
+	if !time.Now().Before(mandate.ExpiresAt) {
+		return ErrExpired
+	}
+
+	return nil
+}
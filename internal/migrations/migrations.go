@@ -0,0 +1,203 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"gorm.io/gorm"
+)
+
+// Large tables such as postings and audit entries are changed online, in
+// steps that each leave the payment pipeline running:
+//
+//  1. Expand: AutoMigrate adds the new column or table, nullable.
+//  2. Dual write: a hook in internal/database writes the new column with
+//     every save (see dual_writes.go).
+//  3. Backfill: a Backfiller fills existing rows in small, rate-limited
+//     batches, checkpointing after each so it can pause and resume.
+//  4. Verify: Verify counts rows still not in the new shape.
+//  5. Contract: once verified, a later release reads the new column and
+//     drops the old one.
+
+// Checkpoint statuses
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ErrUnknownMigration is returned for a migration name that is not registered
+var ErrUnknownMigration = errors.New("unknown migration")
+
+// Migration is an online change to one table
+type Migration struct {
+	Name        string
+	Table       string
+	Description string
+	// Pending is a SQL condition matching rows not yet in the new shape
+	Pending string
+	// Backfill brings the pending rows among a batch, given by primary key,
+	// into the new shape
+	Backfill func(tx *gorm.DB, ids []string) error
+}
+
+// Registered lists the online migrations that can be backfilled and verified
+var Registered = []Migration{
+	{
+		Name:        "postings_amount_minor",
+		Table:       "postings",
+		Description: "Store posting amounts in cents alongside the decimal amount",
+		Pending:     "amount_minor IS NULL OR amount_minor <> ROUND(amount * 100)",
+		Backfill: func(tx *gorm.DB, ids []string) error {
+			return tx.Exec("UPDATE postings SET amount_minor = ROUND(amount * 100) "+
+				"WHERE id IN ? AND (amount_minor IS NULL OR amount_minor <> ROUND(amount * 100))", ids).Error
+		},
+	},
+}
+
+// Find returns the registered migration with a name
+func Find(name string) (Migration, error) {
+	for _, migration := range Registered {
+		if migration.Name == name {
+			return migration, nil
+		}
+	}
+	return Migration{}, fmt.Errorf("%w: %s", ErrUnknownMigration, name)
+}
+
+// Backfiller fills existing rows for online migrations
+type Backfiller struct {
+	db            *gorm.DB
+	checkpoints   database.MigrationCheckpointRepository
+	BatchSize     int
+	RowsPerSecond int // Zero for no limit
+}
+
+// NewBackfiller creates a backfiller sized by MIGRATION_BATCH_SIZE (1000)
+// and limited to MIGRATION_ROWS_PER_SECOND (5000)
+func NewBackfiller(db *gorm.DB, repo database.Repository) *Backfiller {
+	return &Backfiller{
+		db:            db,
+		checkpoints:   repo.MigrationCheckpointRepository(),
+		BatchSize:     common.GetEnvAsInt("MIGRATION_BATCH_SIZE", 1000),
+		RowsPerSecond: common.GetEnvAsInt("MIGRATION_ROWS_PER_SECOND", 5000),
+	}
+}
+
+// Run backfills a migration from its checkpoint until every row has been
+// visited or the context is cancelled, which pauses it. Each batch and its
+// checkpoint are committed together, so a resumed run neither skips nor
+// repeats a batch.
+func (b *Backfiller) Run(ctx context.Context, migration Migration) (*database.MigrationCheckpoint, error) {
+	checkpoint, err := b.start(migration)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var ids []string
+		err := b.db.Table(migration.Table).Where("id > ?", checkpoint.LastID).
+			Order("id ASC").Limit(b.BatchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return b.fail(checkpoint, err)
+		}
+		if len(ids) == 0 {
+			now := time.Now()
+			checkpoint.Status, checkpoint.CompletedAt = StatusCompleted, &now
+			return checkpoint, b.checkpoints.Save(checkpoint)
+		}
+
+		err = b.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Backfill(tx, ids); err != nil {
+				return err
+			}
+			checkpoint.LastID = ids[len(ids)-1]
+			checkpoint.Processed += int64(len(ids))
+			return tx.Save(checkpoint).Error
+		})
+		if err != nil {
+			return b.fail(checkpoint, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			checkpoint.Status = StatusPaused
+			return checkpoint, b.checkpoints.Save(checkpoint)
+		case <-time.After(b.pause(len(ids))):
+		}
+	}
+}
+
+// start loads the migration's checkpoint, or creates one, and marks it running
+func (b *Backfiller) start(migration Migration) (*database.MigrationCheckpoint, error) {
+	checkpoint, err := b.checkpoints.GetByName(migration.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		checkpoint = &database.MigrationCheckpoint{Name: migration.Name, StartedAt: time.Now()}
+	} else if err != nil {
+		return nil, err
+	}
+	if checkpoint.Status == StatusCompleted {
+		// Run again from the start to catch rows written outside GORM
+		checkpoint.LastID, checkpoint.Processed, checkpoint.CompletedAt = "", 0, nil
+		checkpoint.StartedAt = time.Now()
+	}
+
+	if err := b.db.Table(migration.Table).Count(&checkpoint.Total).Error; err != nil {
+		return nil, err
+	}
+	checkpoint.Status, checkpoint.Error = StatusRunning, ""
+	return checkpoint, b.checkpoints.Save(checkpoint)
+}
+
+// pause is how long to wait after a batch to keep to the rate limit
+func (b *Backfiller) pause(rows int) time.Duration {
+	if b.RowsPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(rows) * time.Second / time.Duration(b.RowsPerSecond)
+}
+
+func (b *Backfiller) fail(checkpoint *database.MigrationCheckpoint, err error) (*database.MigrationCheckpoint, error) {
+	checkpoint.Status, checkpoint.Error = StatusFailed, err.Error()
+	if len(checkpoint.Error) > 500 {
+		checkpoint.Error = checkpoint.Error[:500]
+	}
+	if saveErr := b.checkpoints.Save(checkpoint); saveErr != nil {
+		common.Error("Failed to save checkpoint of migration %s: %v", checkpoint.Name, saveErr)
+	}
+	return checkpoint, err
+}
+
+// Verification reports how many rows of a migration's table are not yet in
+// the new shape
+type Verification struct {
+	Migration string   `json:"migration"`
+	Rows      int64    `json:"rows"`
+	Pending   int64    `json:"pending"`
+	SampleIDs []string `json:"sampleIds,omitempty"` // Some of the pending rows
+	Verified  bool     `json:"verified"`            // No rows pending; safe to contract
+}
+
+// Verify counts the rows of a migration's table still pending
+func Verify(db *gorm.DB, migration Migration) (*Verification, error) {
+	verification := &Verification{Migration: migration.Name}
+	if err := db.Table(migration.Table).Count(&verification.Rows).Error; err != nil {
+		return nil, err
+	}
+	pending := db.Table(migration.Table).Where(migration.Pending)
+	if err := pending.Session(&gorm.Session{}).Count(&verification.Pending).Error; err != nil {
+		return nil, err
+	}
+	if verification.Pending > 0 {
+		if err := pending.Order("id ASC").Limit(10).Pluck("id", &verification.SampleIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	verification.Verified = verification.Pending == 0
+	return verification, nil
+}
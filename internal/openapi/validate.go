@@ -0,0 +1,260 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Register serves a service's OpenAPI document at /openapi.json and
+// validates request bodies against it, unless REQUEST_VALIDATION is false.
+// Call it before the service's routes are registered.
+func Register(router *gin.Engine, spec []byte) {
+	doc, err := Parse(spec)
+	if err != nil {
+		common.Error("Invalid OpenAPI document, request validation disabled: %v", err)
+	} else if common.GetEnvAsBool("REQUEST_VALIDATION", true) {
+		router.Use(Middleware(doc))
+	}
+	router.GET("/openapi.json", Handler(spec))
+}
+
+// Handler serves an OpenAPI document
+func Handler(spec []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
+
+// Middleware rejects JSON request bodies that do not match the schema of
+// their operation in doc, responding with an error for each field
+func Middleware(doc *Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schema := requestSchema(doc, c.Request.Method, c.FullPath())
+		if schema == nil || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_REQUEST", "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if len(bytes.TrimSpace(body)) == 0 {
+			c.Next()
+			return
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			// Malformed JSON is reported by the handler's binding
+			c.Next()
+			return
+		}
+		if errs := doc.Validate(schema, value); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(errs))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestSchema returns the JSON body schema of an operation; nil if it
+// takes none
+func requestSchema(doc *Document, method, path string) *Schema {
+	if path == "" {
+		return nil
+	}
+	operation := doc.Operation(method, path)
+	if operation == nil || operation.RequestBody == nil {
+		return nil
+	}
+	media := operation.RequestBody.Content["application/json"]
+	if media == nil {
+		return nil
+	}
+	return media.Schema
+}
+
+// Validate checks a decoded JSON value against a schema. Numbers must be
+// decoded as json.Number.
+func (d *Document) Validate(schema *Schema, value interface{}) []common.ValidationError {
+	var errs []common.ValidationError
+	d.validate(schema, value, "", &errs)
+	return errs
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, field string, errs *[]common.ValidationError) {
+	nullable := schema != nil && schema.Nullable
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		name := field
+		if name == "" {
+			name = "body"
+		}
+		*errs = append(*errs, common.ValidationError{Field: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		if !nullable && !schema.Nullable && schema.Type != "" {
+			fail("must be %s", article(schema.Type))
+		}
+		return
+	}
+	for _, part := range schema.AllOf {
+		d.validate(part, value, field, errs)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, common.ValidationError{Field: join(field, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				d.validate(property, object[name], join(field, name), errs)
+				continue
+			}
+			if schema.AdditionalProperties == nil {
+				continue
+			}
+			if schema.AdditionalProperties.Schema != nil {
+				d.validate(schema.AdditionalProperties.Schema, object[name], join(field, name), errs)
+			} else if !schema.AdditionalProperties.Allowed {
+				*errs = append(*errs, common.ValidationError{Field: join(field, name), Message: "is not a known field"})
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(s)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			if *schema.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *schema.MinLength)
+			}
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters", *schema.MaxLength)
+		}
+		if len(schema.Enum) > 0 && !inEnum(schema.Enum, s) {
+			fail("must be one of %s", enumList(schema.Enum))
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("must be %s", article(schema.Type))
+			return
+		}
+		n, err := number.Float64()
+		if err != nil {
+			fail("must be %s", article(schema.Type))
+			return
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			fail("must be an integer")
+			return
+		}
+		if schema.Minimum != nil {
+			if schema.ExclusiveMinimum && n <= *schema.Minimum {
+				fail("must be greater than %v", *schema.Minimum)
+			} else if !schema.ExclusiveMinimum && n < *schema.Minimum {
+				fail("must be at least %v", *schema.Minimum)
+			}
+		}
+		if schema.Maximum != nil {
+			if schema.ExclusiveMaximum && n >= *schema.Maximum {
+				fail("must be less than %v", *schema.Maximum)
+			} else if !schema.ExclusiveMaximum && n > *schema.Maximum {
+				fail("must be at most %v", *schema.Maximum)
+			}
+		}
+		if len(schema.Enum) > 0 && !inEnum(schema.Enum, number.String()) {
+			fail("must be one of %s", enumList(schema.Enum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func article(kind string) string {
+	switch kind {
+	case "object", "array", "integer":
+		return "an " + kind
+	}
+	return "a " + kind
+}
+
+func inEnum(enum []interface{}, value string) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,206 @@
+// Package rails is the catalog of payment rails. The orchestrator selects,
+// validates and prices rails and the router routes and reverses payments
+// from the same catalog, which is stored in the database. The built-in rails
+// seed it; operators add and change rails through the API without code
+// changes.
+package rails
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// namePattern is what rail identifiers look like, such as ach or sepa_instant
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// Catalog loads the rails from the database. Each load sees the catalog as
+// it is stored, so changes apply to the next payment of every service.
+type Catalog struct {
+	repo database.Repository
+
+	// overrides adjusts the loaded rails, such as with the deployment's
+	// configuration
+	overrides func(*types.RailSelector)
+
+	mu   sync.Mutex
+	last *types.RailSelector // Last catalog loaded, used while the database is unavailable
+}
+
+// NewCatalog creates a catalog whose loaded rails are adjusted by overrides,
+// which may be nil
+func NewCatalog(repo database.Repository, overrides func(*types.RailSelector)) *Catalog {
+	return &Catalog{repo: repo, overrides: overrides}
+}
+
+// Seed adds the built-in rails missing from the catalog
+func Seed(repo database.Repository) error {
+	builtin := types.NewRailSelector().GetAvailableRails()
+	rails := make([]*database.PaymentRail, 0, len(builtin))
+	for _, characteristics := range builtin {
+		rail := FromCharacteristics(characteristics)
+		rail.Enabled = true
+		rail.Builtin = true
+		rails = append(rails, rail)
+	}
+	sort.Slice(rails, func(i, j int) bool { return rails[i].Rail < rails[j].Rail })
+	return repo.PaymentRailRepository().Seed(rails)
+}
+
+// Selector returns a selector over the enabled rails. If the catalog cannot
+// be loaded it falls back to the last one loaded, or else the built-in rails.
+func (c *Catalog) Selector() *types.RailSelector {
+	stored, err := c.repo.PaymentRailRepository().List()
+	if err != nil {
+		common.Error("Failed to load the rail catalog: %v", err)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.last != nil {
+			return c.last
+		}
+		return c.adjust(types.NewRailSelector())
+	}
+
+	selector := &types.RailSelector{Rails: make(map[types.PaymentRail]*types.RailCharacteristics)}
+	for _, rail := range stored {
+		if rail.Enabled {
+			selector.Rails[types.PaymentRail(rail.Rail)] = ToCharacteristics(rail)
+		}
+	}
+	selector = c.adjust(selector)
+
+	c.mu.Lock()
+	c.last = selector
+	c.mu.Unlock()
+	return selector
+}
+
+func (c *Catalog) adjust(selector *types.RailSelector) *types.RailSelector {
+	if c.overrides != nil {
+		c.overrides(selector)
+	}
+	return selector
+}
+
+// Get returns a rail of the catalog whether or not it is enabled, for
+// payments already made on it
+func (c *Catalog) Get(rail string) (*types.RailCharacteristics, error) {
+	stored, err := c.repo.PaymentRailRepository().GetByRail(rail)
+	if err != nil {
+		return nil, fmt.Errorf("rail %s not found", rail)
+	}
+	// Overrides disabling the rail only stop new payments, so the rail is
+	// returned even if they remove it from the selector
+	characteristics := ToCharacteristics(stored)
+	c.adjust(&types.RailSelector{Rails: map[types.PaymentRail]*types.RailCharacteristics{characteristics.Rail: characteristics}})
+	return characteristics, nil
+}
+
+// ToCharacteristics converts a stored rail to the form rails are selected in
+func ToCharacteristics(rail *database.PaymentRail) *types.RailCharacteristics {
+	return &types.RailCharacteristics{
+		Rail:           types.PaymentRail(rail.Rail),
+		Name:           rail.Name,
+		Description:    rail.Description,
+		MinAmount:      rail.MinAmount,
+		MaxAmount:      rail.MaxAmount,
+		ProcessingTime: time.Duration(rail.ProcessingSeconds) * time.Second,
+		SettlementTime: time.Duration(rail.SettlementSeconds) * time.Second,
+		FeeStructure: types.FeeStructure{
+			FixedFee:   rail.FixedFee,
+			PercentFee: rail.PercentFee,
+			MinFee:     rail.MinFee,
+			MaxFee:     rail.MaxFee,
+		},
+		RiskLevel:            rail.RiskLevel,
+		Reversibility:        rail.Reversible,
+		InternationalSupport: rail.International,
+		RequiresVerification: rail.RequiresVerification,
+		MaxDescriptionLength: rail.MaxDescriptionLength,
+		Reliability:          rail.Reliability,
+		CounterpartyType:     rail.CounterpartyType,
+	}
+}
+
+// FromCharacteristics converts a rail to its stored form. Rails that do not
+// name the kind of counterparty they pay pay accounts.
+func FromCharacteristics(characteristics *types.RailCharacteristics) *database.PaymentRail {
+	rail := &database.PaymentRail{
+		Rail:                 string(characteristics.Rail),
+		Name:                 characteristics.Name,
+		Description:          characteristics.Description,
+		MinAmount:            characteristics.MinAmount,
+		MaxAmount:            characteristics.MaxAmount,
+		ProcessingSeconds:    int(characteristics.ProcessingTime / time.Second),
+		SettlementSeconds:    int(characteristics.SettlementTime / time.Second),
+		FixedFee:             characteristics.FeeStructure.FixedFee,
+		PercentFee:           characteristics.FeeStructure.PercentFee,
+		MinFee:               characteristics.FeeStructure.MinFee,
+		MaxFee:               characteristics.FeeStructure.MaxFee,
+		RiskLevel:            characteristics.RiskLevel,
+		Reversible:           characteristics.Reversibility,
+		International:        characteristics.InternationalSupport,
+		RequiresVerification: characteristics.RequiresVerification,
+		MaxDescriptionLength: characteristics.MaxDescriptionLength,
+		Reliability:          characteristics.Reliability,
+		CounterpartyType:     characteristics.CounterpartyType,
+	}
+	if rail.CounterpartyType == "" {
+		rail.CounterpartyType = types.CounterpartyAccount
+	}
+	return rail
+}
+
+// Validate returns every problem with a rail as one error
+func Validate(rail *database.PaymentRail) error {
+	var problems []string
+	if !namePattern.MatchString(rail.Rail) {
+		problems = append(problems, "rail must be 2 to 50 lowercase letters, digits or underscores, starting with a letter")
+	}
+	if strings.TrimSpace(rail.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if rail.MinAmount < 0 || rail.MaxAmount <= rail.MinAmount {
+		problems = append(problems, "needs 0 <= minAmount < maxAmount")
+	}
+	if rail.ProcessingSeconds < 0 || rail.SettlementSeconds < 0 {
+		problems = append(problems, "processing and settlement times cannot be negative")
+	}
+	if rail.FixedFee < 0 || rail.MinFee < 0 || rail.MaxFee < 0 {
+		problems = append(problems, "fees cannot be negative")
+	}
+	if rail.PercentFee < 0 || rail.PercentFee >= 1 {
+		problems = append(problems, "percentFee must be a fraction below 1")
+	}
+	if rail.MinFee > rail.MaxFee {
+		problems = append(problems, "minFee must not exceed maxFee")
+	}
+	switch rail.RiskLevel {
+	case "low", "medium", "high":
+	default:
+		problems = append(problems, "riskLevel must be low, medium or high")
+	}
+	if rail.MaxDescriptionLength < 0 {
+		problems = append(problems, "maxDescriptionLength cannot be negative")
+	}
+	if rail.Reliability < 0 || rail.Reliability > 1 {
+		problems = append(problems, "reliability must be from 0 to 1")
+	}
+	switch rail.CounterpartyType {
+	case types.CounterpartyAccount, types.CounterpartyWallet:
+	default:
+		problems = append(problems, "counterpartyType must be account or wallet")
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
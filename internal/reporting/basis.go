@@ -0,0 +1,187 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Basis is the accounting basis a report is prepared on
+type Basis string
+
+const (
+	// BasisAccrual recognizes every posted transaction when it is posted,
+	// whether or not its payment has settled
+	BasisAccrual Basis = "accrual"
+	// BasisCash recognizes a payment's transactions only once the payment has
+	// settled, when it reaches a final status. Transactions not booked for a
+	// payment are recognized when posted.
+	BasisCash Basis = "cash"
+)
+
+// ErrUnknownBasis is returned for a basis other than accrual or cash
+var ErrUnknownBasis = errors.New("basis must be accrual or cash")
+
+// ParseBasis reads a report's basis, defaulting to accrual
+func ParseBasis(value string) (Basis, error) {
+	switch Basis(value) {
+	case "", BasisAccrual:
+		return BasisAccrual, nil
+	case BasisCash:
+		return BasisCash, nil
+	}
+	return "", ErrUnknownBasis
+}
+
+// PaymentID returns the payment a transaction was booked for: its reference
+// up to any ":" suffix, such as ":fee", or "" if the reference is not a
+// payment ID
+func PaymentID(referenceID string) string {
+	id, _, _ := strings.Cut(referenceID, ":")
+	if _, err := uuid.Parse(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// settlement is the state of the payment a transaction was booked for
+type settlement struct {
+	payment   bool       // The reference names a payment workflow or execution
+	settledAt *time.Time // When the payment reached a final status
+}
+
+// Recognition is how a report treats one transaction
+type Recognition struct {
+	At       time.Time // When the transaction is recognized
+	InFlight bool      // Its payment had not settled by the end of the report
+}
+
+// Recognizer decides when transactions are recognized under a basis. Payment
+// states are looked up once per payment.
+type Recognizer struct {
+	Basis Basis
+	// Lookback is how far before a report's start transactions are loaded,
+	// so cash-basis reports include payments booked earlier that settled
+	// within the report
+	Lookback    time.Duration
+	repo        database.Repository
+	settlements map[string]*settlement
+	references  map[string]string // Source transaction to its reference
+}
+
+// NewRecognizer creates a recognizer looking back
+// REPORT_SETTLEMENT_LOOKBACK_DAYS (30) for payments settling within a report
+func NewRecognizer(repo database.Repository, basis Basis) *Recognizer {
+	return &Recognizer{
+		Basis:       basis,
+		Lookback:    time.Duration(common.GetEnvAsInt("REPORT_SETTLEMENT_LOOKBACK_DAYS", 30)) * 24 * time.Hour,
+		repo:        repo,
+		settlements: make(map[string]*settlement),
+		references:  make(map[string]string),
+	}
+}
+
+// Recognize returns how a report ending at end treats a transaction. A
+// payment still in flight at end is flagged under both bases: accrual
+// reports include it, cash reports leave it out. Under the cash basis a
+// settled payment's transactions are recognized when it settled, or when
+// posted if that was later. A transaction fanned out from another book
+// follows the payment of its source transaction.
+func (r *Recognizer) Recognize(transaction *database.Transaction, end time.Time) (Recognition, error) {
+	recognition := Recognition{At: transaction.CreatedAt}
+	reference, err := r.reference(transaction)
+	if err != nil {
+		return recognition, err
+	}
+	state, err := r.settlement(PaymentID(reference))
+	if err != nil {
+		return recognition, err
+	}
+	if !state.payment {
+		return recognition, nil
+	}
+	if state.settledAt == nil || state.settledAt.After(end) {
+		recognition.InFlight = true
+		return recognition, nil
+	}
+	if r.Basis == BasisCash && state.settledAt.After(recognition.At) {
+		recognition.At = *state.settledAt
+	}
+	return recognition, nil
+}
+
+// Counts reports whether a recognition falls in [from, to) under the basis
+func (r *Recognizer) Counts(recognition Recognition, from, to time.Time) bool {
+	if r.Basis == BasisCash && recognition.InFlight {
+		return false
+	}
+	return !recognition.At.Before(from) && recognition.At.Before(to)
+}
+
+// reference returns the reference of a transaction, or of the transaction it
+// was fanned out from
+func (r *Recognizer) reference(transaction *database.Transaction) (string, error) {
+	if transaction.ReferenceID != "" || transaction.SourceTransactionID == nil {
+		return transaction.ReferenceID, nil
+	}
+	sourceID := *transaction.SourceTransactionID
+	if reference, ok := r.references[sourceID]; ok {
+		return reference, nil
+	}
+	source, err := r.repo.TransactionRepository().GetByID(sourceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get source transaction %s: %w", sourceID, err)
+	}
+	r.references[sourceID] = source.ReferenceID
+	return source.ReferenceID, nil
+}
+
+// settlement looks up the payment workflow or execution with an ID. A
+// reversed execution settles when it is reversed.
+func (r *Recognizer) settlement(paymentID string) (*settlement, error) {
+	if paymentID == "" {
+		return &settlement{}, nil
+	}
+	if state, ok := r.settlements[paymentID]; ok {
+		return state, nil
+	}
+
+	state := &settlement{}
+	workflow, err := r.repo.PaymentWorkflowRepository().GetByID(paymentID)
+	switch {
+	case err == nil:
+		state.payment = true
+		switch workflow.Status {
+		case "completed", "failed", "cancelled":
+			state.settledAt = &workflow.UpdatedAt
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		execution, err := r.repo.PaymentExecutionRepository().GetByID(paymentID)
+		switch {
+		case err == nil:
+			state.payment = true
+			switch execution.Status {
+			case "completed", "failed":
+				state.settledAt = &execution.UpdatedAt
+			case "reversed":
+				state.settledAt = execution.ReversedAt
+				if state.settledAt == nil {
+					state.settledAt = &execution.UpdatedAt
+				}
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("failed to get payment execution %s: %w", paymentID, err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to get payment workflow %s: %w", paymentID, err)
+	}
+
+	r.settlements[paymentID] = state
+	return state, nil
+}
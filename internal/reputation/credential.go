@@ -0,0 +1,383 @@
+package reputation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// CredentialType is the "vct" of reputation credentials
+const CredentialType = "AgentPaymentReputation"
+
+// DefaultTTL and MaxTTL bound how long a reputation snapshot stays presentable
+const (
+	DefaultTTL = 30 * 24 * time.Hour
+	MaxTTL     = 90 * 24 * time.Hour
+)
+
+// Credential statuses
+const (
+	StatusActive  = "active"
+	StatusRevoked = "revoked"
+)
+
+var (
+	ErrUnknownClaim       = errors.New("unknown reputation claim")
+	ErrCredentialNotFound = errors.New("reputation credential not found")
+	ErrInvalidCredential  = errors.New("invalid reputation credential")
+	ErrInvalidDisclosure  = errors.New("disclosure does not belong to credential")
+	ErrExpired            = errors.New("reputation credential expired")
+	ErrRevoked            = errors.New("reputation credential revoked")
+)
+
+// Issuer signs reputation credentials as SD-JWTs: every claim is a salted
+// disclosure whose digest is signed, so holders present only the claims a
+// verifier needs and the signature still checks.
+type Issuer struct {
+	id                string
+	key               ed25519.PrivateKey
+	revocationListURL string
+}
+
+// Issued is returned at issuance. Presentations are the SD-JWT with the
+// disclosures to reveal appended, each followed by "~".
+type Issued struct {
+	Credential  *database.ReputationCredential
+	SDJWT       string            // Issuer-signed JWT with every disclosure
+	Disclosures map[string]string // Disclosure by claim name
+}
+
+// Verified is a checked presentation with only its disclosed claims
+type Verified struct {
+	CredentialID string
+	AgentID      string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+	Claims       map[string]interface{}
+}
+
+type payload struct {
+	Issuer         string   `json:"iss"`
+	Subject        string   `json:"sub"`
+	ID             string   `json:"jti"`
+	Type           string   `json:"vct"`
+	IssuedAt       int64    `json:"iat"`
+	ExpiresAt      int64    `json:"exp"`
+	WindowDays     int      `json:"windowDays"`
+	Digests        []string `json:"_sd"`
+	DigestAlg      string   `json:"_sd_alg"`
+	RevocationList string   `json:"revocationList,omitempty"` // Serves {"revoked": [credential IDs]}
+}
+
+// NewIssuer creates an issuer signing with the Ed25519 key derived from seed
+func NewIssuer(id string, seed []byte, revocationListURL string) (*Issuer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key seed must be %d bytes", ed25519.SeedSize)
+	}
+	return &Issuer{
+		id:                id,
+		key:               ed25519.NewKeyFromSeed(seed),
+		revocationListURL: revocationListURL,
+	}, nil
+}
+
+// NewIssuerFromEnv configures the issuer from REPUTATION_ISSUER,
+// REPUTATION_SIGNING_KEY (base64url Ed25519 seed) and
+// REPUTATION_REVOCATION_LIST_URL. Without a signing key an ephemeral key is
+// generated, so credentials stop verifying when the service restarts.
+func NewIssuerFromEnv() (*Issuer, error) {
+	id := common.GetEnv("REPUTATION_ISSUER", auth.DIDMethodPrefix+"platform")
+	revocationListURL := common.GetEnv("REPUTATION_REVOCATION_LIST_URL", "http://localhost:8080/v1/reputation/revocations")
+
+	encoded := common.GetEnv("REPUTATION_SIGNING_KEY", "")
+	if encoded == "" {
+		common.Warn("REPUTATION_SIGNING_KEY not set; reputation credentials will not verify after restart")
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		return NewIssuer(id, seed, revocationListURL)
+	}
+
+	seed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("REPUTATION_SIGNING_KEY is not base64url: %v", err)
+	}
+	return NewIssuer(id, seed, revocationListURL)
+}
+
+// KeyID identifies the issuer's signing key in its DID document
+func (i *Issuer) KeyID() string {
+	return i.id + "#reputation-key-1"
+}
+
+// DIDDocument publishes the issuer's public key for third-party verification
+func (i *Issuer) DIDDocument() *auth.DIDDocument {
+	keyID := i.KeyID()
+	return &auth.DIDDocument{
+		Context: []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/suites/jws-2020/v1"},
+		ID:      i.id,
+		VerificationMethod: []auth.VerificationMethod{{
+			ID:         keyID,
+			Type:       "JsonWebKey2020",
+			Controller: i.id,
+			PublicKeyJwk: auth.JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(i.key.Public().(ed25519.PublicKey)),
+				Kid: keyID,
+			},
+		}},
+		Authentication:  []string{},
+		AssertionMethod: []string{keyID},
+	}
+}
+
+// Issue summarizes an agent's recent payments and signs the requested claims.
+// No claim names selects every claim.
+func (i *Issuer) Issue(repo database.Repository, agentID string, claimNames []string, ttl time.Duration) (*Issued, error) {
+	if len(claimNames) == 0 {
+		claimNames = ClaimNames
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if ttl > MaxTTL {
+		ttl = MaxTTL
+	}
+
+	now := time.Now()
+	summary, err := Summarize(repo, agentID, now.Add(-DefaultWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize payments: %v", err)
+	}
+	all := summary.Claims()
+
+	claims := make(map[string]interface{}, len(claimNames))
+	for _, name := range claimNames {
+		value, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownClaim, name)
+		}
+		claims[name] = value
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	credential := &database.ReputationCredential{
+		AgentID:   agentID,
+		Claims:    string(claimsJSON),
+		Status:    StatusActive,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := repo.ReputationCredentialRepository().Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to store credential: %v", err)
+	}
+
+	issued := &Issued{Credential: credential, Disclosures: make(map[string]string, len(claims))}
+	body := payload{
+		Issuer:         i.id,
+		Subject:        auth.AgentDID(agentID),
+		ID:             credential.ID,
+		Type:           CredentialType,
+		IssuedAt:       now.Unix(),
+		ExpiresAt:      credential.ExpiresAt.Unix(),
+		WindowDays:     int(DefaultWindow / (24 * time.Hour)),
+		DigestAlg:      "sha-256",
+		RevocationList: i.revocationListURL,
+	}
+	for _, name := range ClaimNames {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		disclosure, err := newDisclosure(name, value)
+		if err != nil {
+			return nil, err
+		}
+		issued.Disclosures[name] = disclosure
+		body.Digests = append(body.Digests, digest(disclosure))
+	}
+	// Sorted digests do not reveal which claim each one belongs to
+	sort.Strings(body.Digests)
+
+	jwt, err := i.sign(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var sdJWT strings.Builder
+	sdJWT.WriteString(jwt + "~")
+	for _, name := range ClaimNames {
+		if disclosure, ok := issued.Disclosures[name]; ok {
+			sdJWT.WriteString(disclosure + "~")
+		}
+	}
+	issued.SDJWT = sdJWT.String()
+
+	return issued, nil
+}
+
+// Verify checks a presentation's signature, expiry and revocation status and
+// returns the claims it discloses
+func (i *Issuer) Verify(repo database.Repository, presentation string) (*Verified, error) {
+	parts := strings.Split(presentation, "~")
+	// Key binding JWTs (a final non-empty segment) are not supported
+	if len(parts) < 2 || parts[len(parts)-1] != "" {
+		return nil, ErrInvalidCredential
+	}
+
+	body, err := i.verifySignature(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if body.Issuer != i.id || body.Type != CredentialType || !strings.HasPrefix(body.Subject, auth.DIDMethodPrefix) {
+		return nil, ErrInvalidCredential
+	}
+	if time.Now().Unix() >= body.ExpiresAt {
+		return nil, ErrExpired
+	}
+
+	credential, err := repo.ReputationCredentialRepository().GetByID(body.ID)
+	if err != nil {
+		return nil, ErrCredentialNotFound
+	}
+	if credential.Status == StatusRevoked {
+		return nil, ErrRevoked
+	}
+
+	digests := make(map[string]bool, len(body.Digests))
+	for _, d := range body.Digests {
+		digests[d] = true
+	}
+
+	verified := &Verified{
+		CredentialID: body.ID,
+		AgentID:      strings.TrimPrefix(body.Subject, auth.DIDMethodPrefix),
+		IssuedAt:     time.Unix(body.IssuedAt, 0),
+		ExpiresAt:    time.Unix(body.ExpiresAt, 0),
+		Claims:       make(map[string]interface{}),
+	}
+	for _, disclosure := range parts[1 : len(parts)-1] {
+		if !digests[digest(disclosure)] {
+			return nil, ErrInvalidDisclosure
+		}
+		name, value, err := decodeDisclosure(disclosure)
+		if err != nil {
+			return nil, ErrInvalidDisclosure
+		}
+		if _, seen := verified.Claims[name]; seen {
+			return nil, ErrInvalidDisclosure
+		}
+		verified.Claims[name] = value
+	}
+
+	return verified, nil
+}
+
+// Revoke marks a credential revoked so it is listed by the revocation registry
+func Revoke(repo database.Repository, credential *database.ReputationCredential, reason string) error {
+	if credential.Status == StatusRevoked {
+		return nil
+	}
+	now := time.Now()
+	credential.Status = StatusRevoked
+	credential.RevokedAt = &now
+	credential.RevocationReason = reason
+	return repo.ReputationCredentialRepository().Update(credential)
+}
+
+func (i *Issuer) sign(body payload) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "vc+sd-jwt", "kid": i.KeyID()})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature := ed25519.Sign(i.key, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (i *Issuer) verifySignature(jwt string) (*payload, error) {
+	segments := strings.Split(jwt, ".")
+	if len(segments) != 3 {
+		return nil, ErrInvalidCredential
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(segments[0], &header); err != nil || header.Alg != "EdDSA" || header.Kid != i.KeyID() {
+		return nil, ErrInvalidCredential
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil || !ed25519.Verify(i.key.Public().(ed25519.PublicKey), []byte(segments[0]+"."+segments[1]), signature) {
+		return nil, ErrInvalidCredential
+	}
+
+	var body payload
+	if err := decodeSegment(segments[1], &body); err != nil {
+		return nil, ErrInvalidCredential
+	}
+	return &body, nil
+}
+
+// newDisclosure encodes a salted [salt, name, value] disclosure
+func newDisclosure(name string, value interface{}) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal([]interface{}{base64.RawURLEncoding.EncodeToString(salt), name, value})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeDisclosure(disclosure string) (string, interface{}, error) {
+	var parts []interface{}
+	if err := decodeSegment(disclosure, &parts); err != nil {
+		return "", nil, err
+	}
+	if len(parts) != 3 {
+		return "", nil, ErrInvalidDisclosure
+	}
+	name, ok := parts[1].(string)
+	if !ok {
+		return "", nil, ErrInvalidDisclosure
+	}
+	return name, parts[2], nil
+}
+
+func digest(disclosure string) string {
+	sum := sha256.Sum256([]byte(disclosure))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
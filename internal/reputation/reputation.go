@@ -0,0 +1,151 @@
+package reputation
+
+import (
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// DefaultWindow is the payment history summarized into a credential
+const DefaultWindow = 365 * 24 * time.Hour
+
+// minRatedPayments is the number of finished payments needed before rates are
+// bucketed; below it, rate claims report "insufficient_history"
+const minRatedPayments = 10
+
+// Claim names. Each claim is disclosed independently, so a holder can prove
+// one property (for example its dispute rate) without revealing the others.
+const (
+	ClaimPaymentCount   = "paymentCount"
+	ClaimVolume         = "volumeUSD"
+	ClaimCompletionRate = "completionRate"
+	ClaimDisputeRate    = "disputeRate"
+	ClaimActiveSince    = "activeSince"
+)
+
+// ClaimNames lists every reputation claim in issuance order
+var ClaimNames = []string{ClaimPaymentCount, ClaimVolume, ClaimCompletionRate, ClaimDisputeRate, ClaimActiveSince}
+
+// Summary is an agent's payment history over a window
+type Summary struct {
+	AgentID        string
+	Payments       int
+	Completed      int
+	Failed         int
+	Disputed       int // Completed payments later refunded at the processor
+	VolumeUSD      float64
+	FirstPaymentAt *time.Time
+}
+
+// Summarize aggregates an agent's executions created after since
+func Summarize(repo database.Repository, agentID string, since time.Time) (*Summary, error) {
+	executions, err := repo.PaymentExecutionRepository().ListByAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{AgentID: agentID}
+	for _, execution := range executions {
+		if execution.CreatedAt.Before(since) {
+			continue
+		}
+		summary.Payments++
+		if summary.FirstPaymentAt == nil || execution.CreatedAt.Before(*summary.FirstPaymentAt) {
+			createdAt := execution.CreatedAt
+			summary.FirstPaymentAt = &createdAt
+		}
+
+		switch execution.Status {
+		case "completed":
+			summary.Completed++
+			summary.VolumeUSD += execution.AmountUSD
+			if execution.ErrorMessage == "refunded" {
+				summary.Disputed++
+			}
+		case "failed":
+			summary.Failed++
+		}
+	}
+	return summary, nil
+}
+
+// Claims buckets the summary so credentials reveal ranges rather than exact figures
+func (s *Summary) Claims() map[string]interface{} {
+	claims := map[string]interface{}{
+		ClaimPaymentCount:   countBucket(s.Payments),
+		ClaimVolume:         volumeBucket(s.VolumeUSD),
+		ClaimCompletionRate: "insufficient_history",
+		ClaimDisputeRate:    "insufficient_history",
+		ClaimActiveSince:    "none",
+	}
+
+	if finished := s.Completed + s.Failed; finished >= minRatedPayments {
+		claims[ClaimCompletionRate] = completionBucket(float64(s.Completed) / float64(finished))
+	}
+	if s.Completed >= minRatedPayments {
+		claims[ClaimDisputeRate] = disputeBucket(float64(s.Disputed) / float64(s.Completed))
+	}
+	if s.FirstPaymentAt != nil {
+		// Month precision avoids disclosing the exact first payment
+		claims[ClaimActiveSince] = s.FirstPaymentAt.UTC().Format("2006-01")
+	}
+	return claims
+}
+
+func countBucket(count int) string {
+	switch {
+	case count == 0:
+		return "0"
+	case count < 10:
+		return "1-9"
+	case count < 100:
+		return "10-99"
+	case count < 1000:
+		return "100-999"
+	default:
+		return "1000+"
+	}
+}
+
+func volumeBucket(volumeUSD float64) string {
+	switch {
+	case volumeUSD == 0:
+		return "0"
+	case volumeUSD < 1000:
+		return "<1k"
+	case volumeUSD < 10000:
+		return "1k-10k"
+	case volumeUSD < 100000:
+		return "10k-100k"
+	case volumeUSD < 1000000:
+		return "100k-1m"
+	default:
+		return "1m+"
+	}
+}
+
+func completionBucket(rate float64) string {
+	switch {
+	case rate >= 0.99:
+		return "99%+"
+	case rate >= 0.95:
+		return "95-99%"
+	case rate >= 0.80:
+		return "80-95%"
+	default:
+		return "<80%"
+	}
+}
+
+func disputeBucket(rate float64) string {
+	switch {
+	case rate == 0:
+		return "0%"
+	case rate < 0.01:
+		return "<1%"
+	case rate < 0.05:
+		return "1-5%"
+	default:
+		return "5%+"
+	}
+}
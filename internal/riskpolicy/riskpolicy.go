@@ -0,0 +1,199 @@
+package riskpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/example/agent-payments/internal/riskprofile"
+)
+
+// Policy is the threshold and factor weights the risk service scores
+// payments with. Policies are stored as numbered versions, one of which is
+// active; version 0 is the built-in default used while none is.
+type Policy struct {
+	Version int `json:"version"`
+
+	Threshold   float64 `json:"threshold"`   // Score at which payments are denied
+	ReviewRatio float64 `json:"reviewRatio"` // Share of the threshold at which payments are reviewed
+
+	// AmountBands weigh the payment amount: the weight of the highest band
+	// the amount is above applies
+	AmountBands []AmountBand `json:"amountBands"`
+
+	SuspiciousCounterparty float64 `json:"suspiciousCounterparty"`
+	UnverifiedCounterparty float64 `json:"unverifiedCounterparty"`
+
+	// Rails weigh the payment rail; rails not listed add nothing
+	Rails map[string]float64 `json:"rails"`
+
+	// Signals override the weights of behavioral signals, by risk factor
+	// such as high_velocity_1h; signals not listed keep their built-in weight
+	Signals map[string]float64 `json:"signals,omitempty"`
+
+	// TierThresholds move the threshold for agents in a trust tier, e.g.
+	// -0.1 scores new agents against a threshold 0.1 lower; tiers not
+	// listed use the threshold as is
+	TierThresholds map[string]float64 `json:"tierThresholds,omitempty"`
+}
+
+// AmountBand weighs payments above an amount
+type AmountBand struct {
+	AboveUSD float64 `json:"aboveUSD"`
+	Weight   float64 `json:"weight"`
+	Factor   string  `json:"factor"` // Risk factor recorded on the decision
+}
+
+// Default returns the built-in policy with the given threshold
+func Default(threshold float64) Policy {
+	return Policy{
+		Threshold:   threshold,
+		ReviewRatio: 0.8,
+		AmountBands: []AmountBand{
+			{AboveUSD: 1000, Weight: 0.15, Factor: "medium_amount"},
+			{AboveUSD: 10000, Weight: 0.3, Factor: "high_amount"},
+			{AboveUSD: 25000, Weight: 0.4, Factor: "very_high_amount"},
+		},
+		SuspiciousCounterparty: 0.25,
+		UnverifiedCounterparty: 0.1,
+		Rails: map[string]float64{
+			"wire":          0.2,
+			"international": 0.3,
+			"card":          0.05,
+		},
+		TierThresholds: map[string]float64{
+			riskprofile.TierNew:        -0.1,
+			riskprofile.TierTrusted:    0.1,
+			riskprofile.TierRestricted: -0.3,
+		},
+	}
+}
+
+// Validate returns every problem with the policy as one error
+func (p *Policy) Validate() error {
+	var problems []string
+	if p.Threshold <= 0 || p.Threshold > 1 {
+		problems = append(problems, "threshold must be above 0 and at most 1")
+	}
+	if p.ReviewRatio <= 0 || p.ReviewRatio > 1 {
+		problems = append(problems, "reviewRatio must be above 0 and at most 1")
+	}
+	seen := map[float64]bool{}
+	for _, band := range p.AmountBands {
+		if band.AboveUSD < 0 || seen[band.AboveUSD] {
+			problems = append(problems, fmt.Sprintf("amount band above %.2f must be a distinct non-negative amount", band.AboveUSD))
+		}
+		seen[band.AboveUSD] = true
+		if band.Factor == "" {
+			problems = append(problems, fmt.Sprintf("amount band above %.2f needs a factor", band.AboveUSD))
+		}
+		problems = append(problems, checkWeight(fmt.Sprintf("amount band above %.2f", band.AboveUSD), band.Weight)...)
+	}
+	problems = append(problems, checkWeight("suspiciousCounterparty", p.SuspiciousCounterparty)...)
+	problems = append(problems, checkWeight("unverifiedCounterparty", p.UnverifiedCounterparty)...)
+	for _, name := range sortedKeys(p.Rails) {
+		problems = append(problems, checkWeight("rails."+name, p.Rails[name])...)
+	}
+	for _, name := range sortedKeys(p.Signals) {
+		problems = append(problems, checkWeight("signals."+name, p.Signals[name])...)
+	}
+	for _, tier := range sortedKeys(p.TierThresholds) {
+		if !riskprofile.ValidTier(tier) {
+			problems = append(problems, "tierThresholds."+tier+" is not a trust tier")
+		}
+		if offset := p.TierThresholds[tier]; offset < -0.5 || offset > 0.5 {
+			problems = append(problems, "tierThresholds."+tier+" must be from -0.5 to 0.5")
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkWeight(name string, weight float64) []string {
+	if weight < 0 || weight > 1 {
+		return []string{name + " weight must be from 0 to 1"}
+	}
+	return nil
+}
+
+func sortedKeys(weights map[string]float64) []string {
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AmountBand returns the band a payment amount falls in, if any
+func (p *Policy) AmountBand(amountUSD float64) (AmountBand, bool) {
+	var match AmountBand
+	found := false
+	for _, band := range p.AmountBands {
+		if amountUSD > band.AboveUSD && (!found || band.AboveUSD > match.AboveUSD) {
+			match, found = band, true
+		}
+	}
+	return match, found
+}
+
+// RailWeight returns the weight of a rail
+func (p *Policy) RailWeight(rail string) float64 {
+	return p.Rails[strings.ToLower(rail)]
+}
+
+// SignalWeight returns the weight of a behavioral signal, or its built-in
+// weight when the policy does not override it
+func (p *Policy) SignalWeight(factor string, builtin float64) float64 {
+	if weight, ok := p.Signals[factor]; ok {
+		return weight
+	}
+	return builtin
+}
+
+// ForTier returns the policy with the threshold for agents in a trust tier,
+// kept within 0.05 to 1
+func (p *Policy) ForTier(tier string) *Policy {
+	offset, ok := p.TierThresholds[tier]
+	if !ok || offset == 0 {
+		return p
+	}
+	adjusted := *p
+	adjusted.Threshold = math.Min(1, math.Max(0.05, math.Round((p.Threshold+offset)*100)/100))
+	return &adjusted
+}
+
+// Decide returns the decision for a score: deny at the threshold, review
+// from the review ratio of it, approve below
+func (p *Policy) Decide(score float64) string {
+	switch {
+	case score >= p.Threshold:
+		return "deny"
+	case score >= p.Threshold*p.ReviewRatio:
+		return "review"
+	}
+	return "approve"
+}
+
+// Encode serializes the policy for storage, without its version
+func (p *Policy) Encode() (string, error) {
+	stored := *p
+	stored.Version = 0
+	data, err := json.Marshal(stored)
+	return string(data), err
+}
+
+// Decode reads a stored policy
+func Decode(version int, value string) (*Policy, error) {
+	var policy Policy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode risk policy version %d: %v", version, err)
+	}
+	policy.Version = version
+	return &policy, nil
+}
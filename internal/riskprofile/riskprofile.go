@@ -0,0 +1,97 @@
+package riskprofile
+
+import (
+	"math"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Trust tiers. The active risk policy sets how each tier moves the threshold
+// an agent's payments are scored against.
+const (
+	TierNew        = "new"        // Too little history to judge
+	TierStandard   = "standard"   // Neither new, trusted nor restricted
+	TierTrusted    = "trusted"    // Long-running with a clean history
+	TierRestricted = "restricted" // Failing or refunded payments too often
+)
+
+// ValidTier reports whether a tier is one an agent can be in
+func ValidTier(tier string) bool {
+	switch tier {
+	case TierNew, TierStandard, TierTrusted, TierRestricted:
+		return true
+	}
+	return false
+}
+
+// Assessment criteria. Rates only count once an agent has MinFinished
+// finished payments, so one early failure does not restrict it.
+const (
+	NewAgentAge    = 30 * 24 * time.Hour // Younger agents are new
+	NewAgentMax    = 10                  // Agents with fewer completed payments are new
+	TrustedAge     = 90 * 24 * time.Hour
+	TrustedMin     = 50 // Completed payments needed to be trusted
+	TrustedMaxRate = 0.02
+	RestrictedRate = 0.1 // Failure or chargeback rate that restricts an agent
+	MinFinished    = 10
+)
+
+// History is an agent's payment history
+type History struct {
+	AgentCreatedAt time.Time
+	Completed      int64
+	Failed         int64
+	Refunded       int64 // Completed payments since refunded
+}
+
+// FailureRate is the failed share of finished payments
+func (h History) FailureRate() float64 {
+	if h.Completed+h.Failed == 0 {
+		return 0
+	}
+	return round4(float64(h.Failed) / float64(h.Completed+h.Failed))
+}
+
+// ChargebackRate is the refunded share of completed payments
+func (h History) ChargebackRate() float64 {
+	if h.Completed == 0 {
+		return 0
+	}
+	return round4(float64(h.Refunded) / float64(h.Completed))
+}
+
+// Assess returns the tier an agent's history puts it in as of now
+func Assess(history History, now time.Time) string {
+	age := now.Sub(history.AgentCreatedAt)
+	failureRate, chargebackRate := history.FailureRate(), history.ChargebackRate()
+
+	if history.Completed+history.Failed >= MinFinished && (failureRate >= RestrictedRate || chargebackRate >= RestrictedRate) {
+		return TierRestricted
+	}
+	if age < NewAgentAge || history.Completed < NewAgentMax {
+		return TierNew
+	}
+	if age >= TrustedAge && history.Completed >= TrustedMin && failureRate <= TrustedMaxRate && chargebackRate <= TrustedMaxRate {
+		return TierTrusted
+	}
+	return TierStandard
+}
+
+// Apply records an assessment of the history on a profile. A pinned tier is
+// kept; the history is updated either way.
+func Apply(profile *database.AgentRiskProfile, history History, now time.Time) {
+	profile.CompletedPayments = history.Completed
+	profile.FailedPayments = history.Failed
+	profile.RefundedPayments = history.Refunded
+	profile.FailureRate = history.FailureRate()
+	profile.ChargebackRate = history.ChargebackRate()
+	profile.AssessedAt = now
+	if !profile.TierPinned {
+		profile.TrustTier = Assess(history, now)
+	}
+}
+
+func round4(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
@@ -0,0 +1,176 @@
+// Package rounding rounds amounts to the units the ledger stores. Percentage
+// fees, partial refunds and FX conversion produce amounts finer than cents.
+// Every such amount is rounded under one policy, read from the environment,
+// so services agree on each cent. Amounts that must add up to a total, such
+// as the legs of a transaction, are rounded together and the cents lost to
+// rounding are given to one of them or booked to a rounding account.
+package rounding
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Mode says how amounts halfway between two cents are rounded
+type Mode string
+
+const (
+	HalfUp   Mode = "half_up"   // Away from zero: 0.125 -> 0.13, -0.125 -> -0.13
+	HalfEven Mode = "half_even" // To the even cent, banker's rounding: 0.125 -> 0.12, 0.135 -> 0.14
+)
+
+// Where the residual of a set of amounts rounded together is booked
+const (
+	ResidualLargest = "largest" // Added to the largest amount
+	ResidualAccount = "account" // Booked to the Rounding account
+)
+
+// AccountName is the equity account residuals are booked to under
+// ResidualAccount, one per agent and currency
+const AccountName = "Rounding"
+
+// Policy is how the ledger rounds amounts
+type Policy struct {
+	Mode     Mode
+	Decimals int    // Digits kept after the decimal point
+	Residual string // ResidualLargest or ResidualAccount
+}
+
+// DefaultPolicy rounds half up to cents and gives residuals to the largest amount
+var DefaultPolicy = Policy{Mode: HalfUp, Decimals: 2, Residual: ResidualLargest}
+
+var current = DefaultPolicy
+
+func init() {
+	policy, err := PolicyFromEnv()
+	if err != nil {
+		common.Warn("Using the default rounding policy: %v", err)
+		return
+	}
+	current = policy
+}
+
+// PolicyFromEnv reads LEDGER_ROUNDING_MODE (half_up or half_even) and
+// LEDGER_ROUNDING_RESIDUAL (largest or account)
+func PolicyFromEnv() (Policy, error) {
+	policy := DefaultPolicy
+	switch mode := Mode(common.GetEnv("LEDGER_ROUNDING_MODE", string(HalfUp))); mode {
+	case HalfUp, HalfEven:
+		policy.Mode = mode
+	default:
+		return DefaultPolicy, fmt.Errorf("unknown LEDGER_ROUNDING_MODE %q", mode)
+	}
+	switch residual := common.GetEnv("LEDGER_ROUNDING_RESIDUAL", ResidualLargest); residual {
+	case ResidualLargest, ResidualAccount:
+		policy.Residual = residual
+	default:
+		return DefaultPolicy, fmt.Errorf("unknown LEDGER_ROUNDING_RESIDUAL %q", residual)
+	}
+	return policy, nil
+}
+
+// Current returns the policy in force
+func Current() Policy {
+	return current
+}
+
+// Round rounds an amount under the current policy
+func Round(amount float64) float64 {
+	return current.Round(amount)
+}
+
+// Units returns an amount in minor units, such as cents
+func (p Policy) Units(amount float64) int64 {
+	scaled := amount * math.Pow10(p.Decimals)
+	// Snap away binary noise, such as 2.675 * 100 = 267.49999999999997, so
+	// amounts written with a half cent are treated as ties
+	scaled = math.Round(scaled*1e4) / 1e4
+	if p.Mode == HalfEven {
+		return int64(math.RoundToEven(scaled))
+	}
+	return int64(math.Round(scaled))
+}
+
+// FromUnits converts minor units back to an amount
+func (p Policy) FromUnits(units int64) float64 {
+	return float64(units) / math.Pow10(p.Decimals)
+}
+
+// Round rounds an amount to the policy's precision
+func (p Policy) Round(amount float64) float64 {
+	return p.FromUnits(p.Units(amount))
+}
+
+// Allocate divides a total among shares by weight. The rounded shares add up
+// to exactly the rounded total: the units left over after rounding every
+// share down go one each to the shares that lost the most.
+func (p Policy) Allocate(total float64, weights []float64) []float64 {
+	shares := make([]float64, len(weights))
+	var sum float64
+	for _, weight := range weights {
+		sum += weight
+	}
+	if len(weights) == 0 || sum <= 0 {
+		return shares
+	}
+
+	totalUnits := p.Units(total)
+	sign := int64(1)
+	if totalUnits < 0 {
+		sign, totalUnits = -1, -totalUnits
+	}
+
+	units := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	left := totalUnits
+	for i, weight := range weights {
+		exact := float64(totalUnits) * weight / sum
+		units[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(units[i])
+		left -= units[i]
+	}
+	for ; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		units[largest]++
+		remainders[largest] = -1
+	}
+
+	for i := range units {
+		shares[i] = p.FromUnits(sign * units[i])
+	}
+	return shares
+}
+
+// Scale multiplies amounts by a factor, rounding each. It returns the scaled
+// amounts and the residual: what must be added to them so they add up to
+// the rounded scaled total. Amounts that net to zero, such as the postings
+// of a transaction in one currency, then still net to zero once the residual
+// is booked.
+func (p Policy) Scale(amounts []float64, factor float64) ([]float64, float64) {
+	scaled := make([]float64, len(amounts))
+	var totalUnits, scaledUnits int64
+	for i, amount := range amounts {
+		totalUnits += p.Units(amount)
+		units := p.Units(amount * factor)
+		scaled[i] = p.FromUnits(units)
+		scaledUnits += units
+	}
+	target := p.Units(p.FromUnits(totalUnits) * factor)
+	return scaled, p.FromUnits(target - scaledUnits)
+}
+
+// Reconciles reports whether amounts add up to a total to the unit
+func (p Policy) Reconciles(amounts []float64, total float64) bool {
+	var units int64
+	for _, amount := range amounts {
+		units += p.Units(amount)
+	}
+	return units == p.Units(total)
+}
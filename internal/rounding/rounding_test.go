@@ -0,0 +1,184 @@
+package rounding
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// Property tests: random totals are split and random postings scaled under
+// policies keeping cents and whole units, and the rounded amounts must still
+// add up to the unit.
+
+var (
+	cents     = Policy{Mode: HalfUp, Decimals: 2, Residual: ResidualLargest}
+	wholeUnit = Policy{Mode: HalfEven, Decimals: 0, Residual: ResidualAccount} // Such as JPY
+)
+
+// split is a total, in minor units, divided by weights
+type split struct {
+	Units   int64 // From -1,000,000.00 to 1,000,000.00 in cents
+	Weights []float64
+}
+
+func (split) Generate(r *rand.Rand, size int) reflect.Value {
+	s := split{Units: r.Int63n(200000001) - 100000000, Weights: make([]float64, 1+r.Intn(size+1))}
+	for i := range s.Weights {
+		// Some weights are zero and some are fractions, as when splitting by percentage
+		switch r.Intn(4) {
+		case 0:
+			s.Weights[i] = 0
+		case 1:
+			s.Weights[i] = r.Float64()
+		default:
+			s.Weights[i] = float64(1 + r.Intn(100))
+		}
+	}
+	s.Weights[r.Intn(len(s.Weights))] = float64(1 + r.Intn(100))
+	return reflect.ValueOf(s)
+}
+
+// postings are amounts, in minor units, netting to zero like the legs of a
+// transaction in one currency, and an exchange rate
+type postings struct {
+	Units []int64
+	Rate  float64
+}
+
+func (postings) Generate(r *rand.Rand, size int) reflect.Value {
+	p := postings{Units: make([]int64, 1+r.Intn(size+1)), Rate: 0.0001 + r.Float64()*200}
+	var sum int64
+	for i := range p.Units {
+		p.Units[i] = r.Int63n(2000001) - 1000000
+		sum += p.Units[i]
+	}
+	p.Units = append(p.Units, -sum)
+	return reflect.ValueOf(p)
+}
+
+func TestAllocatedSharesAddUpToTheTotal(t *testing.T) {
+	for _, policy := range []Policy{cents, wholeUnit} {
+		property := func(s split) bool {
+			total := policy.FromUnits(s.Units)
+			shares := policy.Allocate(total, s.Weights)
+			if len(shares) != len(s.Weights) || !policy.Reconciles(shares, total) {
+				t.Logf("%+v: shares %v do not add up to %v", s, shares, total)
+				return false
+			}
+
+			var sum float64
+			for _, weight := range s.Weights {
+				sum += weight
+			}
+			for i, share := range shares {
+				// Each share is its exact portion rounded down or up, with the total's sign
+				exact := float64(s.Units) * s.Weights[i] / sum
+				units := policy.Units(share)
+				if math.Abs(float64(units)-exact) >= 1 || units*s.Units < 0 {
+					t.Logf("%+v: share %d is %d units, exact %v", s, i, units, exact)
+					return false
+				}
+			}
+			return true
+		}
+		if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+			t.Errorf("%d decimals: %v", policy.Decimals, err)
+		}
+	}
+}
+
+func TestScaledPostingsStillNetToZero(t *testing.T) {
+	for _, policy := range []Policy{cents, wholeUnit} {
+		property := func(p postings) bool {
+			amounts := make([]float64, len(p.Units))
+			for i, units := range p.Units {
+				amounts[i] = policy.FromUnits(units)
+			}
+			scaled, residual := policy.Scale(amounts, p.Rate)
+			if len(scaled) != len(amounts) || !policy.Reconciles(append(scaled, residual), 0) {
+				t.Logf("%+v: scaled %v with residual %v do not net to zero", p, scaled, residual)
+				return false
+			}
+			// Each rounded amount is off by at most half a unit
+			if units := policy.Units(residual); units > int64(len(amounts)) || units < -int64(len(amounts)) {
+				t.Logf("%+v: residual %v is too large", p, residual)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+			t.Errorf("%d decimals: %v", policy.Decimals, err)
+		}
+	}
+}
+
+func TestRoundingModes(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		amount float64
+		want   float64
+	}{
+		{cents, 0.125, 0.13},
+		{cents, -0.125, -0.13},
+		{cents, 2.675, 2.68}, // 267.49999999999997 cents in binary
+		{Policy{Mode: HalfEven, Decimals: 2}, 0.125, 0.12},
+		{Policy{Mode: HalfEven, Decimals: 2}, 0.135, 0.14},
+		{Policy{Mode: HalfEven, Decimals: 2}, -0.125, -0.12},
+		{wholeUnit, 1234.5, 1234},
+		{wholeUnit, 1235.5, 1236},
+		{Policy{Mode: HalfUp, Decimals: 0}, 1234.5, 1235},
+		{Policy{Mode: HalfUp, Decimals: 0}, -0.4, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Round(tt.amount); got != tt.want {
+			t.Errorf("%s to %d decimals: Round(%v) = %v, want %v", tt.policy.Mode, tt.policy.Decimals, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		total   float64
+		weights []float64
+		want    []float64
+	}{
+		{"thirds", cents, 100, []float64{1, 1, 1}, []float64{33.34, 33.33, 33.33}},
+		{"negative thirds", cents, -100, []float64{1, 1, 1}, []float64{-33.34, -33.33, -33.33}},
+		{"largest remainder wins", cents, 10, []float64{1, 2}, []float64{3.33, 6.67}},
+		{"zero weight", cents, 10, []float64{1, 0, 1}, []float64{5, 0, 5}},
+		{"yen", wholeUnit, 1000, []float64{1, 1, 1}, []float64{334, 333, 333}},
+		{"fewer units than shares", wholeUnit, 2, []float64{1, 1, 1}, []float64{1, 1, 0}},
+		{"no weights", cents, 10, nil, []float64{}},
+		{"weights summing to zero", cents, 10, []float64{0, 0}, []float64{0, 0}},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Allocate(tt.total, tt.weights); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Allocate(%v, %v) = %v, want %v", tt.name, tt.total, tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestReconciles(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		amounts []float64
+		total   float64
+		want    bool
+	}{
+		{cents, []float64{0.1, 0.2}, 0.3, true}, // 0.30000000000000004 in binary
+		{cents, []float64{33.33, 33.33, 33.33}, 100, false},
+		{cents, []float64{-50, 50}, 0, true},
+		{cents, nil, 0, true},
+		{wholeUnit, []float64{333, 333, 334}, 1000, true},
+		{wholeUnit, []float64{333.4, 333.4, 333.4}, 1000, false}, // Each rounds to 333
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Reconciles(tt.amounts, tt.total); got != tt.want {
+			t.Errorf("%d decimals: Reconciles(%v, %v) = %v, want %v", tt.policy.Decimals, tt.amounts, tt.total, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package rpc
+
+import (
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/types"
+)
+
+// Conversions between the gRPC messages and the types the REST API returns
+
+func RiskDecisionToProto(decision *types.RiskDecision) *agentpayv1.RiskDecision {
+	return &agentpayv1.RiskDecision{
+		Id:             decision.ID,
+		Decision:       decision.Decision,
+		Score:          decision.Score,
+		Reason:         decision.Reason,
+		Threshold:      decision.Threshold,
+		RiskFactors:    decision.RiskFactors,
+		TriggeredRules: decision.TriggeredRules,
+		PolicyVersion:  int32(decision.PolicyVersion),
+		TrustTier:      decision.TrustTier,
+		CaseId:         decision.CaseID,
+		CreatedAt:      decision.CreatedAt,
+	}
+}
+
+func RiskDecisionFromProto(decision *agentpayv1.RiskDecision) *types.RiskDecision {
+	return &types.RiskDecision{
+		ID:             decision.GetId(),
+		Decision:       decision.GetDecision(),
+		Score:          decision.GetScore(),
+		Reason:         decision.GetReason(),
+		Threshold:      decision.GetThreshold(),
+		RiskFactors:    decision.GetRiskFactors(),
+		TriggeredRules: decision.GetTriggeredRules(),
+		PolicyVersion:  int(decision.GetPolicyVersion()),
+		TrustTier:      decision.GetTrustTier(),
+		CaseID:         decision.GetCaseId(),
+		CreatedAt:      decision.GetCreatedAt(),
+	}
+}
+
+func ConsentCheckToProto(check *types.ConsentCheck) *agentpayv1.ConsentCheck {
+	return &agentpayv1.ConsentCheck{
+		Valid:            check.Valid,
+		ConsentId:        check.ConsentID,
+		Reason:           check.Reason,
+		RequiresApproval: check.RequiresApproval,
+		ApproverGroup:    check.ApproverGroup,
+		ApprovalId:       check.ApprovalID,
+	}
+}
+
+func ConsentCheckFromProto(check *agentpayv1.ConsentCheck) *types.ConsentCheck {
+	return &types.ConsentCheck{
+		Valid:            check.GetValid(),
+		ConsentID:        check.GetConsentId(),
+		Reason:           check.GetReason(),
+		RequiresApproval: check.GetRequiresApproval(),
+		ApproverGroup:    check.GetApproverGroup(),
+		ApprovalID:       check.GetApprovalId(),
+	}
+}
+
+func PaymentExecutionToProto(execution *types.PaymentExecution) *agentpayv1.PaymentExecution {
+	return &agentpayv1.PaymentExecution{
+		Id:             execution.ID,
+		AgentId:        execution.AgentID,
+		AmountUsd:      execution.AmountUSD,
+		Counterparty:   execution.Counterparty,
+		Rail:           execution.Rail,
+		Description:    execution.Description,
+		Status:         execution.Status,
+		Priority:       execution.Priority,
+		ReferenceId:    execution.ReferenceID,
+		ErrorMessage:   execution.ErrorMessage,
+		BatchId:        execution.BatchID,
+		BatchReference: execution.BatchReference,
+		BatchCutoffAt:  execution.BatchCutoffAt,
+		CreatedAt:      execution.CreatedAt,
+		UpdatedAt:      execution.UpdatedAt,
+	}
+}
+
+func PaymentExecutionFromProto(execution *agentpayv1.PaymentExecution) *types.PaymentExecution {
+	return &types.PaymentExecution{
+		ID:             execution.GetId(),
+		AgentID:        execution.GetAgentId(),
+		AmountUSD:      execution.GetAmountUsd(),
+		Counterparty:   execution.GetCounterparty(),
+		Rail:           execution.GetRail(),
+		Description:    execution.GetDescription(),
+		Status:         execution.GetStatus(),
+		Priority:       execution.GetPriority(),
+		ReferenceID:    execution.GetReferenceId(),
+		ErrorMessage:   execution.GetErrorMessage(),
+		BatchID:        execution.GetBatchId(),
+		BatchReference: execution.GetBatchReference(),
+		BatchCutoffAt:  execution.GetBatchCutoffAt(),
+		CreatedAt:      execution.GetCreatedAt(),
+		UpdatedAt:      execution.GetUpdatedAt(),
+	}
+}
+
+func InternationalToProto(details *international.Details) *agentpayv1.InternationalDetails {
+	if details == nil {
+		return nil
+	}
+	return &agentpayv1.InternationalDetails{
+		BeneficiaryName: details.BeneficiaryName,
+		Address: &agentpayv1.BeneficiaryAddress{
+			Street:     details.Address.Street,
+			City:       details.Address.City,
+			PostalCode: details.Address.PostalCode,
+			Country:    details.Address.Country,
+		},
+		Iban:          details.IBAN,
+		AccountNumber: details.AccountNumber,
+		Bic:           details.BIC,
+		PurposeCode:   details.PurposeCode,
+		ChargeBearer:  details.ChargeBearer,
+	}
+}
+
+func InternationalFromProto(details *agentpayv1.InternationalDetails) *international.Details {
+	if details == nil {
+		return nil
+	}
+	return &international.Details{
+		BeneficiaryName: details.GetBeneficiaryName(),
+		Address: international.Address{
+			Street:     details.GetAddress().GetStreet(),
+			City:       details.GetAddress().GetCity(),
+			PostalCode: details.GetAddress().GetPostalCode(),
+			Country:    details.GetAddress().GetCountry(),
+		},
+		IBAN:          details.GetIban(),
+		AccountNumber: details.GetAccountNumber(),
+		BIC:           details.GetBic(),
+		PurposeCode:   details.GetPurposeCode(),
+		ChargeBearer:  details.GetChargeBearer(),
+	}
+}
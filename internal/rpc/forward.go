@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/example/agent-payments/libs/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// REST serves gRPC calls through a service's REST handler, passing on the
+// caller's token, trace and correlation ID
+type REST struct {
+	handler http.Handler
+}
+
+// NewREST serves gRPC calls through handler, the service's router with its
+// middleware
+func NewREST(handler http.Handler) *REST {
+	return &REST{handler: handler}
+}
+
+// Call makes a request to the REST API in process and decodes the data of
+// its response into out. Error responses are returned as gRPC statuses
+// carrying the REST error's message.
+func (r *REST) Call(ctx context.Context, method, path string, payload, out interface{}) error {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, &body)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, name := range headers {
+			if values := md.Get(name); len(values) > 0 {
+				req.Header.Set(name, values[0])
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+	r.handler.ServeHTTP(recorder, req)
+
+	var response common.APIResponse
+	if err := json.Unmarshal(recorder.body.Bytes(), &response); err != nil {
+		return status.Errorf(statusCode(recorder.status), "invalid response from %s %s: %v", method, path, err)
+	}
+	if recorder.status >= http.StatusBadRequest || !response.Success {
+		message := http.StatusText(recorder.status)
+		if response.Error != nil {
+			message = response.Error.Message
+		}
+		return status.Error(statusCode(recorder.status), message)
+	}
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// responseRecorder holds the response of an in-process REST call
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
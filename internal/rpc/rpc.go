@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/example/agent-payments/libs/common"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC
+//
+// The risk, consent and router services serve the calls payment processing
+// makes to them over gRPC as well as REST, with the contracts in
+// api/proto/agentpay/v1. A service listens for gRPC on GRPC_PORT when it is
+// set. Each gRPC method is served by the REST handler of the same endpoint,
+// so both APIs share validation, authorization and auditing. Clients call
+// a service over gRPC when <NAME>_GRPC_ADDR is set, falling back to REST
+// while the gRPC server cannot be reached.
+
+// headers are passed between gRPC metadata and HTTP headers
+var headers = []string{"authorization", "traceparent", "tracestate", strings.ToLower(common.CorrelationIDHeader)}
+
+// Serve starts a gRPC server for a service on GRPC_PORT, if set, with its
+// services registered by register. The server stops with the HTTP server.
+func Serve(server *common.Server, register func(*grpc.Server)) error {
+	port := common.GetEnv("GRPC_PORT", "")
+	if port == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC on port %s: %v", port, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	register(grpcServer)
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			common.Error("gRPC server stopped: %v", err)
+		}
+	}()
+	server.OnShutdown("grpc", func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		return nil
+	})
+	common.Info("gRPC server listening on port %s", port)
+	return nil
+}
+
+// Dial connects to a service's gRPC server at addr. Calls authenticate with
+// the token and pass on the caller's trace and correlation ID. The
+// connection is made on the first call.
+func Dial(addr string, token func() (string, error)) (*grpc.ClientConn, error) {
+	return grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(clientInterceptor(token)),
+	)
+}
+
+// clientInterceptor calls in a client span, with the service token and trace
+// headers of ctx as metadata
+func clientInterceptor(token func() (string, error)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		ctx, span := common.StartSpan(ctx, "call "+method,
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+			attribute.String("server.address", cc.Target()),
+		)
+		defer func() { common.EndSpan(span, err) }()
+
+		header := http.Header{}
+		common.InjectTraceHeaders(ctx, header)
+		if token != nil {
+			value, err := token()
+			if err != nil {
+				return err
+			}
+			header.Set("Authorization", "Bearer "+value)
+		}
+		var pairs []string
+		for _, name := range headers {
+			if value := header.Get(name); value != "" {
+				pairs = append(pairs, name, value)
+			}
+		}
+		return invoker(metadata.AppendToOutgoingContext(ctx, pairs...), method, req, reply, cc, opts...)
+	}
+}
+
+// Unreachable reports whether a call failed because the gRPC server could
+// not be reached or does not serve the method, so it can be made over REST
+// instead
+func Unreachable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// ClientError converts an error returned by a gRPC call to the error the
+// REST client would have returned: failures of the service itself wrap
+// common.ErrServiceUnavailable
+func ClientError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.Internal, codes.Unknown, codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", common.ErrServiceUnavailable, s.Message())
+	case codes.Canceled:
+		return context.Canceled
+	}
+	return errors.New("service call failed: " + s.Message())
+}
+
+// statusCodes maps the HTTP statuses of REST handlers to gRPC codes
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+func statusCode(httpStatus int) codes.Code {
+	if code, ok := statusCodes[httpStatus]; ok {
+		return code
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,215 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule statuses
+const (
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+	StatusCompleted = "completed" // Past its end date or run limit
+)
+
+// Run statuses
+const (
+	RunCreated = "created" // A payment was created and started
+	RunFailed  = "failed"  // The payment could not be created
+)
+
+// MinInterval is the shortest interval a schedule can repeat at
+const MinInterval = time.Hour
+
+// Spec is when a schedule is due: a five-field cron expression (minute, hour,
+// day of month, month, day of week) in a time zone, or a fixed interval from
+// the schedule's start
+type Spec struct {
+	Cron     string
+	Interval time.Duration
+	Location *time.Location
+	Start    time.Time
+}
+
+// cronFields are the sets a cron expression matches, one bit per value
+type cronFields struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var fieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// ParseLocation returns the time zone a cron schedule is evaluated in;
+// empty is UTC
+func ParseLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return location, nil
+}
+
+// Validate checks that the spec has exactly one of a valid cron expression
+// or an interval of at least MinInterval
+func (s Spec) Validate() error {
+	switch {
+	case s.Cron == "" && s.Interval == 0:
+		return errors.New("cron or interval is required")
+	case s.Cron != "" && s.Interval != 0:
+		return errors.New("set either cron or interval, not both")
+	case s.Interval != 0 && s.Interval < MinInterval:
+		return fmt.Errorf("interval must be at least %s", MinInterval)
+	case s.Cron != "":
+		if _, err := parseCron(s.Cron); err != nil {
+			return err
+		}
+		// Expressions that fire more often than MinInterval are rejected
+		first := s.First()
+		if second := s.Next(first); !first.IsZero() && !second.IsZero() && second.Sub(first) < MinInterval {
+			return fmt.Errorf("cron must not fire more than once per %s", MinInterval)
+		}
+		if first.IsZero() {
+			return errors.New("cron never fires")
+		}
+	}
+	return nil
+}
+
+// First returns when the schedule is first due: its start for an interval,
+// or the first cron time from its start
+func (s Spec) First() time.Time {
+	return s.Next(s.Start.Add(-time.Nanosecond))
+}
+
+// Next returns the first time the schedule is due strictly after the given
+// time, or the zero time if it never is
+func (s Spec) Next(after time.Time) time.Time {
+	if s.Interval > 0 {
+		if after.Before(s.Start) {
+			return s.Start
+		}
+		elapsed := after.Sub(s.Start)
+		return s.Start.Add((elapsed/s.Interval + 1) * s.Interval)
+	}
+	fields, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	location := s.Location
+	if location == nil {
+		location = time.UTC
+	}
+	if after.Before(s.Start) {
+		after = s.Start.Add(-time.Minute)
+	}
+	return fields.next(after.In(location))
+}
+
+func parseCron(expression string) (*cronFields, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron %q must have five fields: minute hour day-of-month month day-of-week", expression)
+	}
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fieldBounds[i][0], fieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %v", expression, err)
+		}
+		sets[i] = set
+	}
+	// Sunday can be written as 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+	return &cronFields{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: parts[2] == "*", dowAny: parts[4] == "*",
+	}, nil
+}
+
+// parseField parses a comma-separated list of *, values, ranges and steps
+func parseField(field string, min, max int) (uint64, error) {
+	if min == 0 && max == 6 {
+		max = 7 // Sunday as 7
+	}
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+		}
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || low > high {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			low = value
+			if step > 1 {
+				high = max
+			} else {
+				high = value
+			}
+		}
+		if low < min || high > max {
+			return 0, fmt.Errorf("%q is outside %d-%d", item, min, max)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+func (f *cronFields) dayMatches(t time.Time) bool {
+	dom := f.dom&(1<<uint(t.Day())) != 0
+	dow := f.dow&(1<<uint(t.Weekday())) != 0
+	// As in cron, a restricted day of month and day of week match either
+	if !f.domAny && !f.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next finds the first matching minute after t, searching five years ahead
+func (f *cronFields) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case f.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !f.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case f.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case f.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
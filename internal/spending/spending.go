@@ -0,0 +1,131 @@
+package spending
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+// Periods a party's spending limit can cover. Each is a calendar window in
+// UTC; weeks start on Monday.
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// ValidPeriod reports whether a period is one a limit can cover
+func ValidPeriod(period string) bool {
+	switch period {
+	case Daily, Weekly, Monthly:
+		return true
+	}
+	return false
+}
+
+// Window returns the start and end of the period containing now
+func Window(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case Weekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case Monthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+// Lookback is how far back the workflows for a set of limits must be loaded
+// from: the start of the longest of their current periods
+func Lookback(limits []*database.PartySpendingLimit, now time.Time) time.Time {
+	earliest, _ := Window(Daily, now)
+	for _, limit := range limits {
+		if start, _ := Window(limit.Period, now); start.Before(earliest) {
+			earliest = start
+		}
+	}
+	return earliest
+}
+
+// AgentSpend is one agent's share of a party's spend in a period
+type AgentSpend struct {
+	AgentID  string
+	Payments int
+	SpentUSD float64
+	Share    float64 // Of the party's spend in the period
+}
+
+// Usage is a party's spend against one of its limits in the current period
+type Usage struct {
+	Limit            *database.PartySpendingLimit
+	PeriodStart      time.Time
+	PeriodEnd        time.Time
+	SpentUSD         float64
+	RemainingUSD     float64
+	UtilizationRatio float64      // Spent over the limit
+	Agents           []AgentSpend // Largest spend first
+}
+
+// Compute rolls up the workflows of a party's agents against a limit as of
+// now. Payments that were cancelled or failed do not count; those still in
+// flight do, so concurrent payments cannot together overrun the limit. The
+// workflow being checked is left out when excludeWorkflowID is set.
+func Compute(limit *database.PartySpendingLimit, workflows []*database.PaymentWorkflow, excludeWorkflowID string, now time.Time) *Usage {
+	start, end := Window(limit.Period, now)
+	usage := &Usage{Limit: limit, PeriodStart: start, PeriodEnd: end, Agents: []AgentSpend{}}
+
+	byAgent := map[string]*AgentSpend{}
+	for _, workflow := range workflows {
+		if workflow.ID == excludeWorkflowID || workflow.Status == "cancelled" || workflow.Status == "failed" {
+			continue
+		}
+		if workflow.CreatedAt.Before(start) || !workflow.CreatedAt.Before(end) {
+			continue
+		}
+		agent, ok := byAgent[workflow.AgentID]
+		if !ok {
+			agent = &AgentSpend{AgentID: workflow.AgentID}
+			byAgent[workflow.AgentID] = agent
+		}
+		agent.Payments++
+		agent.SpentUSD += workflow.AmountUSD
+		usage.SpentUSD += workflow.AmountUSD
+	}
+
+	for _, agent := range byAgent {
+		agent.SpentUSD = round2(agent.SpentUSD)
+		if usage.SpentUSD > 0 {
+			agent.Share = round2(agent.SpentUSD / usage.SpentUSD)
+		}
+		usage.Agents = append(usage.Agents, *agent)
+	}
+	sort.Slice(usage.Agents, func(i, j int) bool {
+		if usage.Agents[i].SpentUSD != usage.Agents[j].SpentUSD {
+			return usage.Agents[i].SpentUSD > usage.Agents[j].SpentUSD
+		}
+		return usage.Agents[i].AgentID < usage.Agents[j].AgentID
+	})
+
+	usage.SpentUSD = round2(usage.SpentUSD)
+	usage.RemainingUSD = math.Max(0, round2(limit.LimitUSD-usage.SpentUSD))
+	if limit.LimitUSD > 0 {
+		usage.UtilizationRatio = round2(usage.SpentUSD / limit.LimitUSD)
+	}
+	return usage
+}
+
+// Exceeds reports whether a payment of the amount would take the spend over
+// the limit
+func (u *Usage) Exceeds(amountUSD float64) bool {
+	return round2(u.SpentUSD+amountUSD) > u.Limit.LimitUSD
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,54 @@
+// Package tenancy isolates tenants' data. A tenant is an organization party
+// together with the parties belonging to it. Each request is confined to the
+// tenant of its principal's party, resolved when it is authenticated: it
+// sees that tenant's parties and agents, and the data they own, such as
+// consents, ledger accounts and funding sources, only. Services are not
+// confined.
+package tenancy
+
+import (
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Check reports whether the request may access a resource owned by a party.
+// Attempts to access another tenant's resource are recorded in the audit
+// trail; callers answer them as if the resource did not exist.
+func Check(c *gin.Context, repo database.Repository, ownerPartyID, resourceType, resourceID string) bool {
+	tenant := common.RequestTenant(c)
+	if tenant == "" {
+		return true
+	}
+	owner, err := repo.PartyRepository().TenantOf(ownerPartyID)
+	if err == nil && owner == tenant {
+		return true
+	}
+
+	common.Warn("Denied tenant %s access to %s %s of tenant %s", tenant, resourceType, resourceID, owner)
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditTenantAccessDenied,
+		Severity:     audit.SeverityHigh,
+		ResourceID:   resourceID,
+		ResourceType: resourceType,
+		Action:       "access",
+		Description:  "Cross-tenant access to " + resourceType + " " + resourceID + " denied",
+		Metadata:     map[string]interface{}{"tenantId": tenant, "resourceTenantId": owner},
+	})
+	return false
+}
+
+// CheckAgent reports whether the request may access a resource of an agent,
+// checking the agent's owner as Check does. Agents that do not exist belong
+// to no tenant.
+func CheckAgent(c *gin.Context, repo database.Repository, agentID, resourceType, resourceID string) bool {
+	if common.RequestTenant(c) == "" {
+		return true
+	}
+	agent, err := repo.AgentRepository().GetByID(agentID)
+	if err != nil {
+		return false
+	}
+	return Check(c, repo, agent.OwnerPartyID, resourceType, resourceID)
+}
@@ -0,0 +1,63 @@
+package tenancy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantsSeeTheirOwnAgentsData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ := dbtest.Open(t)
+
+	// Two tenants, each an organization with an agent and an account
+	accounts := map[string]*database.Account{}
+	agents := map[string]*database.Agent{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		account := &database.Account{AgentID: agent.ID, Name: "Wallet", Type: "asset", Currency: "USD"}
+		if err := repo.AccountRepository().Create(account); err != nil {
+			t.Fatal(err)
+		}
+		agents[name], accounts[name] = agent, account
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/accounts", nil)
+	c.Set("principal", &common.Principal{Type: common.PrincipalParty, PartyID: agents["Acme"].OwnerPartyID})
+
+	if !CheckAgent(c, repo, agents["Acme"].ID, "account", accounts["Acme"].ID) {
+		t.Fatal("denied access to the tenant's own account")
+	}
+	if CheckAgent(c, repo, agents["Globex"].ID, "account", accounts["Globex"].ID) {
+		t.Fatal("allowed access to another tenant's account")
+	}
+	if CheckAgent(c, repo, "no-such-agent", "account", "no-such-account") {
+		t.Fatal("allowed access to an account of an unknown agent")
+	}
+
+	listed, total, err := repo.AccountRepository().ListPage(database.AccountFilter{TenantID: common.RequestTenant(c)}, common.ListParams{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(listed) != 1 || listed[0].ID != accounts["Acme"].ID {
+		t.Fatalf("tenant's accounts: got %d of %d, want only %s", len(listed), total, accounts["Acme"].ID)
+	}
+
+	// Services are not confined
+	c.Set("principal", &common.Principal{Type: common.PrincipalService})
+	if !CheckAgent(c, repo, agents["Globex"].ID, "account", accounts["Globex"].ID) {
+		t.Fatal("denied a service access")
+	}
+}
@@ -6,6 +6,11 @@ type Party struct {
 	Name      string
 	Type      string // individual, organization
 	CreatedAt string
+
+	OrganizationID string `json:",omitempty"` // Organization party the party belongs to, which is its tenant
+
+	KYCStatus     string // unverified, pending, verified or rejected
+	KYCVerifiedAt string `json:",omitempty"`
 }
 
 // Agent represents an AI agent
@@ -15,6 +20,10 @@ type Agent struct {
 	OwnerPartyID string
 	IdentityMode string // did, oauth
 	CreatedAt    string
+
+	Status          string // active, suspended or decommissioned
+	StatusReason    string `json:",omitempty"`
+	StatusChangedAt string `json:",omitempty"`
 }
 
 // Consent artifact
@@ -29,6 +38,13 @@ type Consent struct {
 	CosignRule          CosignRule
 	CreatedAt           string
 	Revoked             bool
+	RevokedAt           string `json:",omitempty"`
+	RevocationReason    string `json:",omitempty"`
+	ExpiresAt           string `json:",omitempty"` // Never when empty
+	Status              string // active, expired, revoked or superseded
+	Version             int
+	PreviousConsentID   string `json:",omitempty"` // Consent this one renewed
+	SupersededByID      string `json:",omitempty"` // Consent that renewed this one
 }
 
 type ConsentLimits struct {
@@ -48,48 +64,98 @@ type CosignRule struct {
 
 // RiskDecision represents a risk evaluation result
 type RiskDecision struct {
-	ID           string
-	AgentID      string
-	AmountUSD    float64
-	Counterparty string
-	Rail         string
-	Decision     string  // "approve", "deny", "review"
-	Score        float64 // 0.0 to 1.0, higher is riskier
-	Reason       string
-	Threshold    float64
-	RiskFactors  []string
-	CreatedAt    string
+	ID             string
+	AgentID        string
+	AmountUSD      float64
+	Counterparty   string
+	Rail           string
+	Decision       string  // "approve", "deny", "review"
+	Score          float64 // 0.0 to 1.0, higher is riskier
+	Reason         string
+	Threshold      float64
+	PolicyVersion  int    // Risk policy scored under; 0 for the built-in default
+	TrustTier      string // Agent's trust tier, which set the threshold
+	RiskFactors    []string
+	TriggeredRules []string                 // IDs of the AML rules the payment triggered
+	Features       map[string]interface{}   // Agent behavior the score considered
+	DeclineTrace   []map[string]interface{} `json:",omitempty"` // Owner auto-decline rules evaluated before scoring
+	CaseID         string                   `json:",omitempty"` // Review case holding the payment, for "review" decisions
+	CreatedAt      string
 }
 
 // PaymentWorkflow represents a payment processing workflow
 type PaymentWorkflow struct {
-	ID           string
-	AgentID      string
-	AmountUSD    float64
-	Counterparty string
-	Rail         string
-	Description  string
-	Status       string // "pending", "processing", "completed", "failed"
-	Steps        []WorkflowStep
-	RiskDecision *RiskDecision
-	ConsentCheck *ConsentCheck
-	CreatedAt    string
-	UpdatedAt    string
+	ID             string
+	AgentID        string
+	Amount         float64
+	Currency       string
+	AmountUSD      float64
+	Counterparty   string
+	CounterpartyID string `json:",omitempty"` // Counterparty entity paid, when the payment referenced one
+	Rail           string
+	Description    string
+	Metadata       map[string]string `json:",omitempty"`
+	Status         string            // "pending", "processing", "awaiting_approval", "completed", "failed"
+	Steps          []WorkflowStep
+	RiskDecision   *RiskDecision
+	ConsentCheck   *ConsentCheck
+	MandateID      string // Signed mandate authorizing the payment, if any
+	CallbackURL    string `json:",omitempty"` // Receives a signed summary once the payment is final
+	Category       string `json:",omitempty"` // Spending category budgets apply to
+
+	// Version of the owner party's workflow template the steps were planned
+	// from; 0 for the built-in steps
+	WorkflowTemplateVersion int
+	ScheduleID              string `json:",omitempty"` // Payment schedule that created the payment
+
+	// Evidence the payment went ahead on, once each check has passed
+	ConsentID             string `json:",omitempty"`
+	ConsentVersion        int    `json:",omitempty"`
+	RiskDecisionID        string `json:",omitempty"`
+	ComplianceScreeningID string `json:",omitempty"`
+
+	// Fee experiment variant the payment is quoted and settled under
+	FeeExperimentID string `json:",omitempty"`
+	FeeVariantID    string `json:",omitempty"`
+
+	// Quote the payment was made under; empty when it was quoted afresh
+	QuoteID string `json:",omitempty"`
+
+	// Degradation modes applied while a dependency was unavailable
+	Degradations []Degradation `json:",omitempty"`
+
+	CreatedAt string
+	UpdatedAt string
+}
+
+// Degradation records how a payment went on while a dependency was
+// unavailable
+type Degradation struct {
+	Dependency string // "risk", "consent" or "compliance"
+	Mode       string // "fail_closed", "fail_open" or "queue"
+	Reason     string
+	Timestamp  string
 }
 
 // WorkflowStep represents a step in the payment workflow
 type WorkflowStep struct {
 	Name      string
-	Status    string // "pending", "running", "completed", "failed"
+	Status    string // "pending", "running", "completed", "failed", "skipped"
 	Message   string
 	Timestamp string
 }
 
 // ConsentCheck represents the result of a consent validation
 type ConsentCheck struct {
-	Valid     bool
-	Reason    string
-	ConsentID string
+	Valid          bool
+	Reason         string
+	ConsentID      string
+	ConsentVersion int `json:",omitempty"` // Version of the consent, set once the payment is linked to it
+
+	// Set for payments above the consent's cosign threshold
+	RequiresApproval bool   `json:",omitempty"`
+	ApproverGroup    string `json:",omitempty"`
+	ApprovalID       string `json:",omitempty"` // Approval the payment waited on
 }
 
 // PaymentExecution represents a payment execution through a specific rail
@@ -100,12 +166,47 @@ type PaymentExecution struct {
 	Counterparty string
 	Rail         string
 	Description  string
-	Status       string // "pending", "processing", "completed", "failed"
+	Status       string // "pending", "processing", "completed", "failed", "reversed"
 	Priority     string
 	ReferenceID  string
 	ErrorMessage string
 	CreatedAt    string
 	UpdatedAt    string
+
+	// Set for executions on batched rails, which stay pending until the
+	// batch is submitted after its cutoff
+	BatchID        string `json:",omitempty"`
+	BatchReference string `json:",omitempty"`
+	BatchCutoffAt  string `json:",omitempty"`
+
+	// Rail the execution was first sent on, set when its processor was
+	// unavailable and the execution failed over to Rail
+	FailedOverFrom string `json:",omitempty"`
+
+	// On-chain settlement of executions on crypto rails
+	ChainSettlement *ChainSettlement `json:",omitempty"`
+
+	// Set once a completed payment is reversed
+	ReversedAt            string `json:",omitempty"`
+	ReversalReason        string `json:",omitempty"`
+	ReversalTransactionID string `json:",omitempty"`
+}
+
+// ChainSettlement tracks a crypto rail execution's token transfer until it
+// has the confirmations the rail requires
+type ChainSettlement struct {
+	Network               string
+	Token                 string
+	FromAddress           string
+	ToAddress             string
+	TokenAmount           float64
+	RateUSD               float64 // USD per token
+	TxHash                string
+	BlockNumber           int64 `json:",omitempty"`
+	Confirmations         int
+	RequiredConfirmations int
+	Status                string // "submitted", "confirming", "confirmed", "failed"
+	ConfirmedAt           string `json:",omitempty"`
 }
 
 // Account represents a ledger account for double-entry bookkeeping
@@ -117,8 +218,12 @@ type Account struct {
 	Description string
 	Currency    string
 	Balance     float64
-	CreatedAt   string
-	UpdatedAt   string
+	// Overdraft policy in force: "disallow", "limit" or "unlimited"
+	OverdraftPolicy string
+	OverdraftLimit  float64 `json:",omitempty"`
+	Book            string
+	CreatedAt       string
+	UpdatedAt       string
 }
 
 // Transaction represents a financial transaction in the ledger
@@ -128,6 +233,7 @@ type Transaction struct {
 	Description string
 	ReferenceID string
 	Status      string // "pending", "posted", "failed"
+	Book        string
 	CreatedAt   string
 	UpdatedAt   string
 }
@@ -139,8 +245,17 @@ type TransactionDetail struct {
 	Description string
 	ReferenceID string
 	Status      string
-	Postings    []*Posting
-	CreatedAt   string
+	Book        string
+	// Transaction in another book this one was fanned out from
+	SourceTransactionID string `json:",omitempty"`
+	Postings            []*Posting
+
+	// Place in the agent's hash chain, once chained
+	Hash         string `json:",omitempty"`
+	PreviousHash string `json:",omitempty"`
+	BlockIndex   int    `json:",omitempty"`
+
+	CreatedAt string
 }
 
 // Posting represents an individual entry in a transaction (debit or credit)
@@ -150,6 +265,8 @@ type Posting struct {
 	AccountID     string
 	Amount        float64 // Positive = debit, negative = credit
 	Currency      string
+	TokenAmount   *float64 `json:",omitempty"` // Tokens moved by an on-chain payment; Amount is their USD value
+	Token         string   `json:",omitempty"`
 	CreatedAt     string
 }
 
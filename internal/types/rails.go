@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -9,12 +10,39 @@ import (
 type PaymentRail string
 
 const (
-	RailACH   PaymentRail = "ach"
-	RailCard  PaymentRail = "card"
-	RailWire  PaymentRail = "wire"
-	RailCheck PaymentRail = "check"
+	RailACH     PaymentRail = "ach"
+	RailCard    PaymentRail = "card"
+	RailWire    PaymentRail = "wire"
+	RailCheck   PaymentRail = "check"
+	RailInstant PaymentRail = "instant"
+	RailRTP     PaymentRail = "rtp"
+	RailUSDC    PaymentRail = "usdc"
 )
 
+// Kinds of counterparty a rail pays. Bank and card rails pay accounts; crypto
+// rails pay wallet addresses.
+const (
+	CounterpartyAccount = "account"
+	CounterpartyWallet  = "wallet"
+)
+
+// walletAddressPattern is an EVM wallet address, such as a USDC recipient
+var walletAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// IsWalletAddress reports whether a counterparty is an on-chain wallet address
+func IsWalletAddress(counterparty string) bool {
+	return walletAddressPattern.MatchString(counterparty)
+}
+
+// CounterpartyTypeOf returns the kind of a counterparty: a wallet for wallet
+// addresses, otherwise an account
+func CounterpartyTypeOf(counterparty string) string {
+	if IsWalletAddress(counterparty) {
+		return CounterpartyWallet
+	}
+	return CounterpartyAccount
+}
+
 // RailCharacteristics defines the characteristics of each payment rail
 type RailCharacteristics struct {
 	Rail                 PaymentRail
@@ -29,6 +57,18 @@ type RailCharacteristics struct {
 	Reversibility        bool
 	InternationalSupport bool
 	RequiresVerification bool
+	MaxDescriptionLength int     // Characters of payment description the rail carries
+	Reliability          float64 // Share of payments that complete, from 0.0 to 1.0
+	CounterpartyType     string  // Kind of counterparty the rail pays; empty for accounts
+}
+
+// Pays reports whether the rail can pay a counterparty
+func (r *RailCharacteristics) Pays(counterparty string) bool {
+	counterpartyType := r.CounterpartyType
+	if counterpartyType == "" {
+		counterpartyType = CounterpartyAccount
+	}
+	return counterpartyType == CounterpartyTypeOf(counterparty)
 }
 
 // FeeStructure defines the fee structure for a rail
@@ -44,7 +84,8 @@ type RailSelector struct {
 	Rails map[PaymentRail]*RailCharacteristics
 }
 
-// NewRailSelector creates a new rail selector with predefined rails
+// NewRailSelector creates a new rail selector with the built-in rails, which
+// seed the rail catalog
 func NewRailSelector() *RailSelector {
 	rs := &RailSelector{
 		Rails: make(map[PaymentRail]*RailCharacteristics),
@@ -69,6 +110,8 @@ func NewRailSelector() *RailSelector {
 		Reversibility:        true,
 		InternationalSupport: false,
 		RequiresVerification: false,
+		MaxDescriptionLength: 80,
+		Reliability:          0.95,
 	}
 
 	// Define Card rail
@@ -90,6 +133,8 @@ func NewRailSelector() *RailSelector {
 		Reversibility:        true,
 		InternationalSupport: true,
 		RequiresVerification: true,
+		MaxDescriptionLength: 22,
+		Reliability:          0.90,
 	}
 
 	// Define Wire rail
@@ -111,6 +156,8 @@ func NewRailSelector() *RailSelector {
 		Reversibility:        false,
 		InternationalSupport: true,
 		RequiresVerification: true,
+		MaxDescriptionLength: 140,
+		Reliability:          0.99,
 	}
 
 	// Define Check rail
@@ -132,6 +179,78 @@ func NewRailSelector() *RailSelector {
 		Reversibility:        true,
 		InternationalSupport: false,
 		RequiresVerification: false,
+		MaxDescriptionLength: 60,
+		Reliability:          0.90,
+	}
+
+	// Define Instant rail
+	rs.Rails[RailInstant] = &RailCharacteristics{
+		Rail:           RailInstant,
+		Name:           "Instant Payment",
+		Description:    "Real-time payment - Immediate, irrevocable, low value",
+		MinAmount:      0.01,
+		MaxAmount:      1000.00,
+		ProcessingTime: 0,
+		SettlementTime: 0, // Real-time
+		FeeStructure: FeeStructure{
+			FixedFee:   0,
+			PercentFee: 0.015, // 1.5%
+			MinFee:     0,
+			MaxFee:     15.00,
+		},
+		RiskLevel:            "medium",
+		Reversibility:        false,
+		InternationalSupport: false,
+		RequiresVerification: false,
+		MaxDescriptionLength: 140,
+		Reliability:          0.85,
+	}
+
+	// Define RTP rail
+	rs.Rails[RailRTP] = &RailCharacteristics{
+		Rail:           RailRTP,
+		Name:           "Real-Time Payments",
+		Description:    "RTP/FedNow credit transfer - ISO 20022, final on acceptance",
+		MinAmount:      0.01,
+		MaxAmount:      1000000.00, // RTP network limit
+		ProcessingTime: 10 * time.Second,
+		SettlementTime: 0, // Settled with finality on acceptance
+		FeeStructure: FeeStructure{
+			FixedFee:   0.25,
+			PercentFee: 0,
+			MinFee:     0.25,
+			MaxFee:     0.25,
+		},
+		RiskLevel:            "low",
+		Reversibility:        false,
+		InternationalSupport: false,
+		RequiresVerification: false,
+		MaxDescriptionLength: 140,
+		Reliability:          0.98,
+	}
+
+	// Define USDC rail
+	rs.Rails[RailUSDC] = &RailCharacteristics{
+		Rail:           RailUSDC,
+		Name:           "USDC Stablecoin",
+		Description:    "USDC on-chain transfer - Wallet addresses, final after confirmations",
+		MinAmount:      0.01,
+		MaxAmount:      1000000.00,
+		ProcessingTime: 1 * time.Minute,
+		SettlementTime: 3 * time.Minute, // Required block confirmations
+		FeeStructure: FeeStructure{
+			FixedFee:   0.10,
+			PercentFee: 0.001, // 0.1%
+			MinFee:     0.10,
+			MaxFee:     5.00,
+		},
+		RiskLevel:            "medium",
+		Reversibility:        false,
+		InternationalSupport: true,
+		RequiresVerification: false,
+		MaxDescriptionLength: 0, // Token transfers carry no memo; kept by the platform only
+		Reliability:          0.97,
+		CounterpartyType:     CounterpartyWallet,
 	}
 
 	return rs
@@ -141,15 +260,20 @@ func NewRailSelector() *RailSelector {
 func (rs *RailSelector) SelectRail(amount float64, counterparty string, preferences *RailPreferences) (PaymentRail, *RailCharacteristics, error) {
 	var candidates []*RailCharacteristics
 
-	// Filter rails based on amount limits
+	// Filter rails based on amount limits and the kind of counterparty, and
+	// for payments abroad, on the rails that can send them
+	international := preferences != nil && preferences.International
 	for _, rail := range rs.Rails {
-		if amount >= rail.MinAmount && amount <= rail.MaxAmount {
+		if amount >= rail.MinAmount && amount <= rail.MaxAmount && rail.Pays(counterparty) && (!international || rail.InternationalSupport) {
 			candidates = append(candidates, rail)
 		}
 	}
 
 	if len(candidates) == 0 {
-		return "", nil, fmt.Errorf("no suitable rail found for amount %.2f", amount)
+		if international {
+			return "", nil, fmt.Errorf("no international rail found for amount %.2f to %s", amount, CounterpartyTypeOf(counterparty))
+		}
+		return "", nil, fmt.Errorf("no suitable rail found for amount %.2f to %s", amount, CounterpartyTypeOf(counterparty))
 	}
 
 	// Apply preferences and scoring
@@ -168,7 +292,7 @@ type RailPreferences struct {
 	MaxSettlementTime time.Duration
 	PreferredRails    []PaymentRail
 	ExcludeRails      []PaymentRail
-	International     bool
+	International     bool // Only rails that send payments abroad
 }
 
 // selectWithPreferences selects rail based on user preferences
@@ -349,14 +473,19 @@ func (rs *RailSelector) selectMostSecureRail(candidates []*RailCharacteristics)
 
 // calculateFee calculates the total fee for a rail
 func (rs *RailSelector) calculateFee(rail *RailCharacteristics, amount float64) float64 {
-	fee := rail.FeeStructure.FixedFee + (amount * rail.FeeStructure.PercentFee)
+	return rail.FeeStructure.Calculate(amount)
+}
+
+// Calculate returns the rail's fee on an amount
+func (f FeeStructure) Calculate(amount float64) float64 {
+	fee := f.FixedFee + (amount * f.PercentFee)
 
 	// Apply min/max bounds
-	if fee < rail.FeeStructure.MinFee {
-		fee = rail.FeeStructure.MinFee
+	if fee < f.MinFee {
+		fee = f.MinFee
 	}
-	if fee > rail.FeeStructure.MaxFee {
-		fee = rail.FeeStructure.MaxFee
+	if fee > f.MaxFee {
+		fee = f.MaxFee
 	}
 
 	return fee
@@ -395,3 +524,28 @@ func (rs *RailSelector) ValidateRail(rail PaymentRail, amount float64) error {
 
 	return nil
 }
+
+// ValidateCounterparty checks a rail can pay a counterparty: wallet rails
+// pay wallet addresses only, and other rails never pay them
+func (rs *RailSelector) ValidateCounterparty(rail PaymentRail, counterparty string) error {
+	characteristics, exists := rs.Rails[rail]
+	if !exists {
+		return fmt.Errorf("rail %s not supported", rail)
+	}
+	if !characteristics.Pays(counterparty) {
+		return fmt.Errorf("rail %s cannot pay a counterparty of type %s", rail, CounterpartyTypeOf(counterparty))
+	}
+	return nil
+}
+
+// ValidateInternational checks a rail can send payments abroad
+func (rs *RailSelector) ValidateInternational(rail PaymentRail) error {
+	characteristics, exists := rs.Rails[rail]
+	if !exists {
+		return fmt.Errorf("rail %s not supported", rail)
+	}
+	if !characteristics.InternationalSupport {
+		return fmt.Errorf("rail %s does not send international payments", rail)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package velocity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Lookback is the history loaded for each evaluation. Velocity uses the last
+// week of it; counterparty novelty and the typical amount use all of it.
+const Lookback = 30 * 24 * time.Hour
+
+// Payment is the payment being evaluated
+type Payment struct {
+	AgentID      string
+	WorkflowID   string // Excluded from the history when set
+	Counterparty string
+	AmountUSD    float64
+	At           time.Time
+}
+
+// Features are an agent's recent behavior, as seen before the payment. They
+// are stored with the risk decision to explain its score.
+type Features struct {
+	Count1h              int     `json:"count1h"`
+	AmountUSD1h          float64 `json:"amountUSD1h"`
+	Count24h             int     `json:"count24h"`
+	AmountUSD24h         float64 `json:"amountUSD24h"`
+	Count7d              int     `json:"count7d"`
+	AmountUSD7d          float64 `json:"amountUSD7d"`
+	Payments30d          int     `json:"payments30d"`          // Earlier payments in the lookback
+	CounterpartyPayments int     `json:"counterpartyPayments"` // Earlier payments to the counterparty in the lookback
+	NewCounterparty      bool    `json:"newCounterparty"`
+	AverageAmountUSD     float64 `json:"averageAmountUSD"` // Mean amount over the lookback
+	AmountRatio          float64 `json:"amountRatio"`      // Payment amount over the mean, 0 without history
+	FinishedExecutions7d int     `json:"finishedExecutions7d"`
+	FailureRate7d        float64 `json:"failureRate7d"` // Failed share of finished executions
+}
+
+// Compute derives the features of a payment from the agent's workflows and
+// executions over the lookback. Cancelled workflows are ignored; failed ones
+// count toward velocity but do not make a counterparty known.
+func Compute(payment Payment, workflows []*database.PaymentWorkflow, executions []*database.PaymentExecution) Features {
+	var features Features
+	var lookbackAmount float64
+
+	for _, workflow := range workflows {
+		if workflow.ID == payment.WorkflowID || workflow.Status == "cancelled" {
+			continue
+		}
+		age := payment.At.Sub(workflow.CreatedAt)
+		if age < 0 || age > Lookback {
+			continue
+		}
+
+		features.Payments30d++
+		lookbackAmount += workflow.AmountUSD
+		if age <= time.Hour {
+			features.Count1h++
+			features.AmountUSD1h += workflow.AmountUSD
+		}
+		if age <= 24*time.Hour {
+			features.Count24h++
+			features.AmountUSD24h += workflow.AmountUSD
+		}
+		if age <= 7*24*time.Hour {
+			features.Count7d++
+			features.AmountUSD7d += workflow.AmountUSD
+		}
+		if workflow.Status != "failed" && strings.EqualFold(workflow.Counterparty, payment.Counterparty) {
+			features.CounterpartyPayments++
+		}
+	}
+
+	features.NewCounterparty = features.CounterpartyPayments == 0
+	if features.Payments30d > 0 {
+		features.AverageAmountUSD = round2(lookbackAmount / float64(features.Payments30d))
+		if features.AverageAmountUSD > 0 {
+			features.AmountRatio = round2(payment.AmountUSD / features.AverageAmountUSD)
+		}
+	}
+
+	var failed int
+	for _, execution := range executions {
+		age := payment.At.Sub(execution.CreatedAt)
+		if age < 0 || age > 7*24*time.Hour {
+			continue
+		}
+		switch execution.Status {
+		case "failed":
+			failed++
+			features.FinishedExecutions7d++
+		case "completed", "reversed":
+			features.FinishedExecutions7d++
+		}
+	}
+	if features.FinishedExecutions7d > 0 {
+		features.FailureRate7d = round2(float64(failed) / float64(features.FinishedExecutions7d))
+	}
+
+	features.AmountUSD1h = round2(features.AmountUSD1h)
+	features.AmountUSD24h = round2(features.AmountUSD24h)
+	features.AmountUSD7d = round2(features.AmountUSD7d)
+	return features
+}
+
+// Limits are the behavior that adds to a payment's risk score
+type Limits struct {
+	MaxCount1h             int     // Payments in the last hour, including this one
+	MaxCount24h            int     // Payments in the last day, including this one
+	MaxAmountUSD24h        float64 // Volume in the last day, including this one
+	NewCounterpartyMinUSD  float64 // Amount that is notable to a new counterparty
+	MaxAmountRatio         float64 // Multiple of the agent's typical amount
+	MinHistoryForRatio     int     // Payments in the lookback before the ratio applies
+	MaxFailureRate         float64 // Failed share of finished executions
+	MinExecutionsForFailed int     // Finished executions before the failure rate applies
+}
+
+// DefaultLimits returns the limits used unless configured
+func DefaultLimits() Limits {
+	return Limits{
+		MaxCount1h:             10,
+		MaxCount24h:            50,
+		MaxAmountUSD24h:        50000,
+		NewCounterpartyMinUSD:  1000,
+		MaxAmountRatio:         5,
+		MinHistoryForRatio:     5,
+		MaxFailureRate:         0.3,
+		MinExecutionsForFailed: 5,
+	}
+}
+
+// NewLimitsFromEnv reads the limits from RISK_VELOCITY_* variables, falling
+// back to DefaultLimits
+func NewLimitsFromEnv() Limits {
+	limits := DefaultLimits()
+	limits.MaxCount1h = common.GetEnvAsInt("RISK_VELOCITY_MAX_COUNT_1H", limits.MaxCount1h)
+	limits.MaxCount24h = common.GetEnvAsInt("RISK_VELOCITY_MAX_COUNT_24H", limits.MaxCount24h)
+	limits.MaxAmountUSD24h = common.GetEnvAsFloat("RISK_VELOCITY_MAX_AMOUNT_USD_24H", limits.MaxAmountUSD24h)
+	limits.MaxFailureRate = common.GetEnvAsFloat("RISK_VELOCITY_MAX_FAILURE_RATE", limits.MaxFailureRate)
+	return limits
+}
+
+// Signal is a feature that added to a payment's risk score
+type Signal struct {
+	Factor string  // Risk factor name recorded on the decision
+	Score  float64 // Added to the risk score
+	Detail string
+}
+
+// Score returns the features of a payment that exceed the limits
+func (l Limits) Score(payment Payment, features Features) []Signal {
+	var signals []Signal
+	if features.Count1h+1 > l.MaxCount1h {
+		signals = append(signals, Signal{"high_velocity_1h", 0.2,
+			fmt.Sprintf("%d payments within an hour", features.Count1h+1)})
+	} else if features.Count24h+1 > l.MaxCount24h {
+		signals = append(signals, Signal{"high_velocity_24h", 0.1,
+			fmt.Sprintf("%d payments within a day", features.Count24h+1)})
+	}
+	if volume := features.AmountUSD24h + payment.AmountUSD; volume > l.MaxAmountUSD24h {
+		signals = append(signals, Signal{"high_daily_volume", 0.15,
+			fmt.Sprintf("%.2f USD within a day", volume)})
+	}
+	if features.NewCounterparty && payment.AmountUSD >= l.NewCounterpartyMinUSD {
+		signals = append(signals, Signal{"new_counterparty", 0.1,
+			fmt.Sprintf("%.2f USD to a counterparty not paid in %d days", payment.AmountUSD, int(Lookback.Hours()/24))})
+	}
+	if features.Payments30d >= l.MinHistoryForRatio && features.AmountRatio > l.MaxAmountRatio {
+		signals = append(signals, Signal{"amount_spike", 0.15,
+			fmt.Sprintf("%.1fx the agent's typical %.2f USD", features.AmountRatio, features.AverageAmountUSD)})
+	}
+	if features.FinishedExecutions7d >= l.MinExecutionsForFailed && features.FailureRate7d > l.MaxFailureRate {
+		signals = append(signals, Signal{"high_failure_rate", 0.15,
+			fmt.Sprintf("%.0f%% of %d recent executions failed", features.FailureRate7d*100, features.FinishedExecutions7d)})
+	}
+	return signals
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
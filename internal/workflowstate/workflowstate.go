@@ -0,0 +1,188 @@
+package workflowstate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// State is a payment workflow status. The values match the check constraint
+// on payment_workflows.status.
+type State string
+
+const (
+	Pending          State = "pending"           // Created, not yet processing
+	Processing       State = "processing"        // Being taken through the workflow stages
+	AwaitingApproval State = "awaiting_approval" // Held for a cosign approval
+	Completed        State = "completed"
+	Failed           State = "failed"
+	Cancelled        State = "cancelled"
+)
+
+// transitions lists the states each state may move to. Failed workflows can
+// be processed again once funded through a payment link; completed and
+// cancelled workflows are final.
+var transitions = map[State][]State{
+	Pending:          {Processing, Failed, Cancelled},
+	Processing:       {AwaitingApproval, Completed, Failed, Cancelled},
+	AwaitingApproval: {Processing, Failed, Cancelled},
+	Failed:           {Processing},
+	Completed:        {},
+	Cancelled:        {},
+}
+
+// States returns every workflow state
+func States() []State {
+	return []State{Pending, Processing, AwaitingApproval, Completed, Failed, Cancelled}
+}
+
+// Valid reports whether the state exists
+func Valid(state State) bool {
+	_, ok := transitions[state]
+	return ok
+}
+
+// Final reports whether no transition leaves the state
+func Final(state State) bool {
+	return Valid(state) && len(transitions[state]) == 0
+}
+
+// Allowed reports whether a workflow may move from one state to another
+func Allowed(from, to State) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition is returned for a move the state machine does not allow
+var ErrInvalidTransition = errors.New("invalid workflow transition")
+
+// ErrConcurrentTransition is returned when the workflow's stored state changed
+// since it was read
+var ErrConcurrentTransition = errors.New("workflow status changed concurrently")
+
+// TransitionError describes a rejected transition
+type TransitionError struct {
+	WorkflowID string
+	From       State
+	To         State
+	Err        error  // ErrInvalidTransition, ErrConcurrentTransition or a guard's error
+	Guard      string // Name of the guard that rejected it, if any
+}
+
+func (e *TransitionError) Error() string {
+	switch {
+	case e.Guard != "":
+		return fmt.Sprintf("workflow %s cannot move from %s to %s: %s: %v", e.WorkflowID, e.From, e.To, e.Guard, e.Err)
+	case errors.Is(e.Err, ErrInvalidTransition):
+		return fmt.Sprintf("workflow %s cannot move from %s to %s; allowed: %v", e.WorkflowID, e.From, e.To, transitions[e.From])
+	default:
+		return fmt.Sprintf("workflow %s cannot move from %s to %s: %v", e.WorkflowID, e.From, e.To, e.Err)
+	}
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// Guard checks a transition against the workflow, returning an error to reject it
+type Guard func(workflow *database.PaymentWorkflow) error
+
+// Listener is told of each transition once it has been made
+type Listener func(workflow *database.PaymentWorkflow, from, to State)
+
+type guardEntry struct {
+	name  string
+	check Guard
+}
+
+// Machine applies transitions to stored workflows and records each one in
+// workflow_transitions
+type Machine struct {
+	repo      database.Repository
+	guards    map[[2]State][]guardEntry
+	listeners []Listener
+}
+
+// New creates a state machine with no guards
+func New(repo database.Repository) *Machine {
+	return &Machine{repo: repo, guards: make(map[[2]State][]guardEntry)}
+}
+
+// AddGuard adds a named check to a transition. Guards run in the order they
+// were added, and the first error rejects the transition.
+func (m *Machine) AddGuard(from, to State, name string, guard Guard) {
+	key := [2]State{from, to}
+	m.guards[key] = append(m.guards[key], guardEntry{name: name, check: guard})
+}
+
+// AddListener adds a function told of every transition the machine makes,
+// in the order listeners were added
+func (m *Machine) AddListener(listener Listener) {
+	m.listeners = append(m.listeners, listener)
+}
+
+// WithRepository returns the machine recording through repo, such as the
+// repository of a transaction, with the same guards and listeners
+func (m *Machine) WithRepository(repo database.Repository) *Machine {
+	return &Machine{repo: repo, guards: m.guards, listeners: m.listeners}
+}
+
+// Created records a new workflow's initial state
+func (m *Machine) Created(workflow *database.PaymentWorkflow, actor string) error {
+	return m.repo.WorkflowTransitionRepository().Create(&database.WorkflowTransition{
+		WorkflowID: workflow.ID,
+		ToStatus:   workflow.Status,
+		Reason:     "Created",
+		Actor:      actor,
+	})
+}
+
+// Transition moves a workflow to a new state. The move must be allowed from
+// the workflow's current state and pass the transition's guards, and the
+// stored state must not have changed since the workflow was read. The
+// workflow's Status is updated in place.
+func (m *Machine) Transition(workflow *database.PaymentWorkflow, to State, reason, actor string) error {
+	from := State(workflow.Status)
+	reject := func(err error, guard string) error {
+		return &TransitionError{WorkflowID: workflow.ID, From: from, To: to, Err: err, Guard: guard}
+	}
+
+	if !Allowed(from, to) {
+		return reject(ErrInvalidTransition, "")
+	}
+	for _, guard := range m.guards[[2]State{from, to}] {
+		if err := guard.check(workflow); err != nil {
+			return reject(err, guard.name)
+		}
+	}
+
+	moved, err := m.repo.PaymentWorkflowRepository().UpdateStatus(workflow.ID, string(from), string(to))
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return reject(ErrConcurrentTransition, "")
+	}
+	workflow.Status = string(to)
+	if Final(to) {
+		common.RecordPaymentOutcome(workflow.Rail, string(to))
+	}
+
+	err = m.repo.WorkflowTransitionRepository().Create(&database.WorkflowTransition{
+		WorkflowID: workflow.ID,
+		FromStatus: string(from),
+		ToStatus:   string(to),
+		Reason:     reason,
+		Actor:      actor,
+	})
+	for _, listener := range m.listeners {
+		listener(workflow, from, to)
+	}
+	return err
+}
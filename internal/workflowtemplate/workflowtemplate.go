@@ -0,0 +1,219 @@
+package workflowtemplate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Steps a template can order. Consent validation and execution always run,
+// execution last; the others can be left out or made conditional.
+const (
+	StepMandateCheck      = "mandate_check"
+	StepRiskEvaluation    = "risk_evaluation"
+	StepConsentValidation = "consent_validation"
+	StepComplianceCheck   = "compliance_check"
+	StepTaxCheck          = "tax_check" // Needs the payee's tax ID from a threshold
+	StepExecution         = "execution"
+)
+
+// DefaultTaxThresholdUSD is the amount from which a tax check needs the
+// payee's tax ID, unless the step sets its own threshold
+const DefaultTaxThresholdUSD = 600
+
+// Template is the ordered steps a tenant's payments go through. Templates are
+// stored per tenant as numbered versions, one of which is active; version 0
+// is the built-in template used while none is.
+type Template struct {
+	Version int    `json:"version"`
+	Steps   []Step `json:"steps"`
+}
+
+// Step is one step of a template
+type Step struct {
+	Name         string     `json:"name"`
+	When         *Condition `json:"when,omitempty"`         // Runs only for payments matching it
+	Unless       *Condition `json:"unless,omitempty"`       // Skipped for payments matching it
+	ThresholdUSD float64    `json:"thresholdUSD,omitempty"` // tax_check: amount from which the tax ID is needed
+}
+
+// Condition matches payments. Every field set must match.
+type Condition struct {
+	MinAmountUSD    float64  `json:"minAmountUSD,omitempty"` // At or above
+	MaxAmountUSD    float64  `json:"maxAmountUSD,omitempty"` // At or below
+	Rails           []string `json:"rails,omitempty"`
+	Currencies      []string `json:"currencies,omitempty"`
+	CounterpartyIDs []string `json:"counterpartyIds,omitempty"` // e.g. the tenant's own affiliates
+}
+
+// Payment is what conditions are matched against
+type Payment struct {
+	AmountUSD      float64
+	Rail           string
+	Currency       string
+	CounterpartyID string
+}
+
+// PlannedStep is a step of a template and, when the payment skips it, why
+type PlannedStep struct {
+	Step
+	Skip string
+}
+
+// Default returns the built-in template
+func Default() *Template {
+	return &Template{Steps: []Step{
+		{Name: StepMandateCheck},
+		{Name: StepRiskEvaluation},
+		{Name: StepConsentValidation},
+		{Name: StepComplianceCheck},
+		{Name: StepExecution},
+	}}
+}
+
+// KnownStep reports whether a template can use a step
+func KnownStep(name string) bool {
+	switch name {
+	case StepMandateCheck, StepRiskEvaluation, StepConsentValidation, StepComplianceCheck, StepTaxCheck, StepExecution:
+		return true
+	}
+	return false
+}
+
+// required reports whether a step must run for every payment
+func required(name string) bool {
+	return name == StepConsentValidation || name == StepExecution
+}
+
+// Validate returns every problem with the template as one error
+func (t *Template) Validate() error {
+	var problems []string
+	seen := map[string]bool{}
+	for i, step := range t.Steps {
+		if !KnownStep(step.Name) {
+			problems = append(problems, fmt.Sprintf("steps[%d]: unknown step %q", i, step.Name))
+			continue
+		}
+		if seen[step.Name] {
+			problems = append(problems, step.Name+" is listed more than once")
+		}
+		seen[step.Name] = true
+		if required(step.Name) && (step.When != nil || step.Unless != nil) {
+			problems = append(problems, step.Name+" always runs and cannot have conditions")
+		}
+		if step.Name == StepExecution && i != len(t.Steps)-1 {
+			problems = append(problems, "execution must be the last step")
+		}
+		if step.ThresholdUSD != 0 && step.Name != StepTaxCheck {
+			problems = append(problems, step.Name+" has no threshold")
+		}
+		if step.ThresholdUSD < 0 {
+			problems = append(problems, step.Name+" threshold cannot be negative")
+		}
+		problems = append(problems, step.When.problems(step.Name+".when")...)
+		problems = append(problems, step.Unless.problems(step.Name+".unless")...)
+	}
+	for _, name := range []string{StepConsentValidation, StepExecution} {
+		if !seen[name] {
+			problems = append(problems, "steps must include "+name)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *Condition) problems(name string) []string {
+	if c == nil {
+		return nil
+	}
+	var problems []string
+	if c.MinAmountUSD == 0 && c.MaxAmountUSD == 0 && len(c.Rails) == 0 && len(c.Currencies) == 0 && len(c.CounterpartyIDs) == 0 {
+		problems = append(problems, name+" matches every payment; leave it out instead")
+	}
+	if c.MinAmountUSD < 0 || c.MaxAmountUSD < 0 {
+		problems = append(problems, name+" amounts cannot be negative")
+	}
+	if c.MaxAmountUSD != 0 && c.MaxAmountUSD < c.MinAmountUSD {
+		problems = append(problems, name+".maxAmountUSD is below minAmountUSD")
+	}
+	for _, currency := range c.Currencies {
+		if len(currency) != 3 {
+			problems = append(problems, name+".currencies must be ISO 4217 codes")
+			break
+		}
+	}
+	return problems
+}
+
+// Matches reports whether a payment matches the condition
+func (c *Condition) Matches(payment Payment) bool {
+	if c.MinAmountUSD != 0 && payment.AmountUSD < c.MinAmountUSD {
+		return false
+	}
+	if c.MaxAmountUSD != 0 && payment.AmountUSD > c.MaxAmountUSD {
+		return false
+	}
+	return matchesAny(c.Rails, payment.Rail) &&
+		matchesAny(c.Currencies, payment.Currency) &&
+		matchesAny(c.CounterpartyIDs, payment.CounterpartyID)
+}
+
+// matchesAny reports whether value is one of values, or values is empty
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan returns the template's steps for a payment, in order, with the reason
+// for each step the payment skips
+func (t *Template) Plan(payment Payment) []PlannedStep {
+	plan := make([]PlannedStep, 0, len(t.Steps))
+	for _, step := range t.Steps {
+		planned := PlannedStep{Step: step}
+		switch {
+		case step.When != nil && !step.When.Matches(payment):
+			planned.Skip = fmt.Sprintf("Skipped: payment does not meet its condition in workflow template v%d", t.Version)
+		case step.Unless != nil && step.Unless.Matches(payment):
+			planned.Skip = fmt.Sprintf("Skipped: payment is exempt under workflow template v%d", t.Version)
+		}
+		plan = append(plan, planned)
+	}
+	return plan
+}
+
+// TaxThreshold returns the amount from which a tax check step needs the
+// payee's tax ID
+func (s Step) TaxThreshold() float64 {
+	if s.ThresholdUSD > 0 {
+		return s.ThresholdUSD
+	}
+	return DefaultTaxThresholdUSD
+}
+
+// Encode serializes the template for storage, without its version
+func (t *Template) Encode() (string, error) {
+	stored := *t
+	stored.Version = 0
+	data, err := json.Marshal(stored)
+	return string(data), err
+}
+
+// Decode reads a stored template
+func Decode(version int, value string) (*Template, error) {
+	var template Template
+	if err := json.Unmarshal([]byte(value), &template); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow template version %d: %v", version, err)
+	}
+	template.Version = version
+	return &template, nil
+}
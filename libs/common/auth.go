@@ -0,0 +1,541 @@
+package common
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes used for per-route authorization
+const (
+	ScopeAll              = "*"
+	ScopePartiesRead      = "parties:read"
+	ScopePartiesWrite     = "parties:write"
+	ScopeAgentsRead       = "agents:read"
+	ScopeAgentsWrite      = "agents:write"
+	ScopeCredentials      = "credentials:manage"
+	ScopeConsentsRead     = "consents:read"
+	ScopeConsentsWrite    = "consents:write"
+	ScopeRiskRead         = "risk:read"
+	ScopeRiskEvaluate     = "risk:evaluate"
+	ScopePaymentsRead     = "payments:read"
+	ScopePaymentsWrite    = "payments:write"
+	ScopeLedgerRead       = "ledger:read"
+	ScopeLedgerWrite      = "ledger:write"
+	ScopeRoutingExecute   = "routing:execute"
+	ScopeMandateKeys      = "mandates:manage"
+	ScopeFundingRead      = "funding:read"
+	ScopeFundingWrite     = "funding:write"
+	ScopeOperations       = "operations:manage"
+	ScopeComplianceRead   = "compliance:read"
+	ScopeComplianceScreen = "compliance:screen"
+	ScopeComplianceReview = "compliance:review" // Review queue, watchlists and AML rules
+	ScopePIIRead          = "pii:read"          // Unmasked bank numbers, tax IDs and account holders
+	ScopeTokensIntrospect = "tokens:introspect" // OAuth token introspection, for services
+)
+
+// Roles of users, checked by RequireRoles. Admins hold every role.
+const (
+	RoleAdmin    = "admin"    // Manages the party's users, roles and approver groups
+	RoleApprover = "approver" // Decides payment approvals of the approver groups they belong to
+	RoleReviewer = "reviewer" // Decides compliance, risk and KYC reviews
+)
+
+// Principal types
+const (
+	PrincipalParty   = "party"
+	PrincipalAgent   = "agent"
+	PrincipalService = "service"
+)
+
+// Authentication methods
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNoJWTSecret        = errors.New("AUTH_JWT_SECRET must be set when authentication is enabled")
+)
+
+// Principal is the authenticated caller of a request. A party's users are
+// party principals whose subject is the user.
+type Principal struct {
+	Subject  string   `json:"subject"`
+	Type     string   `json:"type"` // "party", "agent", "service"
+	PartyID  string   `json:"partyId,omitempty"`
+	TenantID string   `json:"tenantId,omitempty"` // Organization party whose data the principal accesses
+	AgentID  string   `json:"agentId,omitempty"`
+	UserID   string   `json:"userId,omitempty"`
+	Roles    []string `json:"roles,omitempty"` // Roles of the user
+	Scopes   []string `json:"scopes"`
+	Method   string   `json:"method"`             // "api_key", "jwt"
+	ClientID string   `json:"clientId,omitempty"` // OAuth client the token was issued to
+}
+
+// HasScope reports whether the principal holds the scope, directly or through
+// a wildcard ("*" or "payments:*")
+func (p *Principal) HasScope(scope string) bool {
+	resource := strings.SplitN(scope, ":", 2)[0]
+	for _, s := range p.Scopes {
+		if s == ScopeAll || s == scope || s == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the principal is a user holding the role, directly
+// or as an admin
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims are the JWT claims issued by the platform
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Type      string   `json:"typ"`
+	PartyID   string   `json:"party_id,omitempty"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	AgentID   string   `json:"agent_id,omitempty"`
+	UserID    string   `json:"user_id,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scopes    []string `json:"scopes"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"` // Set on tokens of the client_credentials grant
+}
+
+// CredentialStore resolves API keys to principals. Keys are looked up by
+// their SHA-256 hash so plaintext keys are never stored.
+type CredentialStore interface {
+	LookupAPIKey(keyHash string) (*Principal, error)
+}
+
+// TokenValidator checks OAuth client tokens beyond their signature and
+// expiry, such as whether the client they were issued to is still active
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string, claims *Claims) error
+}
+
+// TenantResolver finds the tenant a party belongs to
+type TenantResolver interface {
+	TenantOf(partyID string) (string, error)
+}
+
+// AuthConfig configures authentication for a service
+type AuthConfig struct {
+	Enabled      bool
+	JWTSecret    []byte
+	Issuer       string
+	BootstrapKey string
+	Store        CredentialStore
+	Validator    TokenValidator // Checks tokens carrying a client_id
+	Tenants      TenantResolver // Resolves the tenants of principals' parties
+	RateLimiter  *RateLimiter   // Limits authenticated requests by credential and agent
+}
+
+// NewAuthConfigFromEnv builds an AuthConfig from environment variables.
+// There is no default secret: callers check the result with Validate.
+func NewAuthConfigFromEnv(store CredentialStore) *AuthConfig {
+	cfg := &AuthConfig{
+		Enabled:      GetEnvAsBool("AUTH_ENABLED", true),
+		JWTSecret:    []byte(GetEnv("AUTH_JWT_SECRET", "")),
+		Issuer:       GetEnv("AUTH_JWT_ISSUER", "agent-payments"),
+		BootstrapKey: GetEnv("AUTH_BOOTSTRAP_API_KEY", ""),
+		Store:        store,
+		RateLimiter:  DefaultRateLimiter(),
+	}
+
+	// Stores that can check OAuth clients validate their tokens as well
+	if validator, ok := store.(TokenValidator); ok {
+		cfg.Validator = validator
+	}
+
+	// Stores that know parties' organizations resolve principals' tenants
+	if tenants, ok := store.(TenantResolver); ok {
+		cfg.Tenants = tenants
+	}
+	return cfg
+}
+
+// Validate reports a configuration that would accept requests it cannot
+// authenticate safely: enabled, without a secret to verify tokens with
+func (cfg *AuthConfig) Validate() error {
+	if cfg.Enabled && len(cfg.JWTSecret) == 0 {
+		return ErrNoJWTSecret
+	}
+	return nil
+}
+
+// HashAPIKey returns the hex SHA-256 hash used to store and look up API keys
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey generates a new random API key
+func GenerateAPIKey() (string, error) {
+	random, err := GenerateRandomString(40)
+	if err != nil {
+		return "", err
+	}
+	return "ak_" + random, nil
+}
+
+// SignJWT signs claims as an HS256 JWT
+func SignJWT(claims Claims, secret []byte) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrNoJWTSecret
+	}
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + signHS256(signingInput, secret), nil
+}
+
+// ParseJWT verifies an HS256 JWT and returns its claims. Tokens must carry
+// an expiry; an empty secret verifies nothing.
+func ParseJWT(token string, secret []byte) (*Claims, error) {
+	if len(secret) == 0 {
+		return nil, ErrInvalidToken
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header map[string]string
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header["alg"] != "HS256" {
+		return nil, ErrInvalidToken
+	}
+
+	expected := signHS256(parts[0]+"."+parts[1], secret)
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.ExpiresAt <= 0 {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func signHS256(input string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IssueToken issues a JWT for the principal valid for ttl
+func (cfg *AuthConfig) IssueToken(principal *Principal, ttl time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		Issuer:    cfg.Issuer,
+		Subject:   principal.Subject,
+		Type:      principal.Type,
+		PartyID:   principal.PartyID,
+		TenantID:  principal.TenantID,
+		AgentID:   principal.AgentID,
+		UserID:    principal.UserID,
+		Roles:     principal.Roles,
+		Scopes:    principal.Scopes,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		ID:        GenerateUUID(),
+		ClientID:  principal.ClientID,
+	}
+
+	token, err := SignJWT(claims, cfg.JWTSecret)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
+}
+
+// ServiceToken issues a short-lived token for service-to-service calls
+func (cfg *AuthConfig) ServiceToken(service string, scopes ...string) (string, error) {
+	token, _, err := cfg.IssueToken(&Principal{
+		Subject: service,
+		Type:    PrincipalService,
+		Scopes:  scopes,
+	}, 5*time.Minute)
+	return token, err
+}
+
+// Authenticate resolves the credentials on a request to a principal
+func (cfg *AuthConfig) Authenticate(r *http.Request) (*Principal, error) {
+	apiKey := r.Header.Get("X-API-Key")
+	bearer := ""
+
+	authHeader := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(authHeader, "Bearer "):
+		bearer = strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	case strings.HasPrefix(authHeader, "ApiKey "):
+		apiKey = strings.TrimSpace(strings.TrimPrefix(authHeader, "ApiKey "))
+	}
+
+	// Bearer values that are not JWTs are treated as API keys
+	if bearer != "" && strings.Count(bearer, ".") != 2 {
+		apiKey = bearer
+		bearer = ""
+	}
+
+	if bearer != "" {
+		claims, err := ParseJWT(bearer, cfg.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Issuer != "" && claims.Issuer != cfg.Issuer {
+			return nil, ErrInvalidToken
+		}
+		if claims.ClientID != "" && cfg.Validator != nil {
+			if err := cfg.Validator.ValidateToken(r.Context(), bearer, claims); err != nil {
+				Warn("Rejected token of OAuth client %s: %v", claims.ClientID, err)
+				return nil, ErrInvalidToken
+			}
+		}
+		principal := &Principal{
+			Subject:  claims.Subject,
+			Type:     claims.Type,
+			PartyID:  claims.PartyID,
+			TenantID: claims.TenantID,
+			AgentID:  claims.AgentID,
+			UserID:   claims.UserID,
+			Roles:    claims.Roles,
+			Scopes:   claims.Scopes,
+			Method:   AuthMethodJWT,
+			ClientID: claims.ClientID,
+		}
+		cfg.resolveTenant(principal)
+		return principal, nil
+	}
+
+	if apiKey == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if cfg.BootstrapKey != "" && hmac.Equal([]byte(apiKey), []byte(cfg.BootstrapKey)) {
+		return &Principal{
+			Subject: "bootstrap",
+			Type:    PrincipalService,
+			Scopes:  []string{ScopeAll},
+			Method:  AuthMethodAPIKey,
+		}, nil
+	}
+
+	if cfg.Store == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal, err := cfg.Store.LookupAPIKey(HashAPIKey(apiKey))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	principal.Method = AuthMethodAPIKey
+	cfg.resolveTenant(principal)
+	return principal, nil
+}
+
+// resolveTenant confines the principal of a party or agent to the tenant of
+// its party. When the tenant cannot be resolved the principal is confined
+// to its party alone.
+func (cfg *AuthConfig) resolveTenant(principal *Principal) {
+	if principal.TenantID != "" || principal.PartyID == "" || principal.Type == PrincipalService {
+		return
+	}
+	principal.TenantID = principal.PartyID
+	if cfg.Tenants == nil {
+		return
+	}
+	tenant, err := cfg.Tenants.TenantOf(principal.PartyID)
+	if err != nil {
+		Warn("Failed to resolve tenant of party %s: %v", principal.PartyID, err)
+		return
+	}
+	principal.TenantID = tenant
+}
+
+// AuthMiddleware authenticates requests using API keys or JWT bearer tokens
+func AuthMiddleware(cfg *AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		principal, err := cfg.Authenticate(c.Request)
+		if err != nil {
+			message := "Missing or invalid credentials"
+			if errors.Is(err, ErrTokenExpired) {
+				message = "Token expired"
+			}
+			c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", message))
+			c.Abort()
+			return
+		}
+
+		c.Set("principal", principal)
+		if cfg.RateLimiter != nil && !cfg.RateLimiter.AllowPrincipal(c, principal) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScopes rejects requests whose principal lacks any of the scopes.
+// It is a no-op when authentication is disabled.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("principal")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		principal := value.(*Principal)
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				c.JSON(http.StatusForbidden, NewErrorResponseWithDetails("FORBIDDEN", "Insufficient scope", fmt.Sprintf("requires %s", scope)))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePlatform rejects requests confined to a tenant, for routes that
+// read or change what every tenant shares, such as platform-wide rules.
+// It is a no-op when authentication is disabled.
+func RequirePlatform() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if RequestTenant(c) != "" {
+			c.JSON(http.StatusForbidden, NewErrorResponse("FORBIDDEN", "Only the platform may use this route"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRoles rejects requests whose principal holds none of the roles.
+// Only users hold roles, so agents, services and party keys not issued to a
+// user are rejected too, unless the route admits them with
+// RequireRolesOrPrincipals. It is a no-op when authentication is disabled.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return RequireRolesOrPrincipals(nil, roles...)
+}
+
+// RequireRolesOrPrincipals is RequireRoles for routes that principals of the
+// given types may also use when they are not users, such as services, or
+// party keys managing their party's first users. Users always need a role.
+func RequireRolesOrPrincipals(principalTypes []string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if principal == nil {
+			c.Next()
+			return
+		}
+		if principal.UserID == "" {
+			for _, principalType := range principalTypes {
+				if principal.Type == principalType {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, NewErrorResponseWithDetails("FORBIDDEN", "Insufficient role", fmt.Sprintf("requires one of %s", strings.Join(roles, ", "))))
+		c.Abort()
+	}
+}
+
+// GetPrincipal returns the authenticated principal, or nil when authentication is disabled
+func GetPrincipal(c *gin.Context) *Principal {
+	if value, exists := c.Get("principal"); exists {
+		return value.(*Principal)
+	}
+	return nil
+}
+
+// CanActForAgent reports whether the caller may act on behalf of agentID.
+// Agent principals are restricted to themselves; other principals are not
+// restricted here.
+func CanActForAgent(c *gin.Context, agentID string) bool {
+	principal := GetPrincipal(c)
+	if principal == nil || principal.Type != PrincipalAgent {
+		return true
+	}
+	return principal.AgentID == agentID
+}
+
+// RequestTenant returns the tenant the request is confined to, or "" for
+// requests that are not: those of services, and all requests when
+// authentication is disabled. Principals without a party are confined to a
+// tenant of their own.
+func RequestTenant(c *gin.Context) string {
+	principal := GetPrincipal(c)
+	if principal == nil || principal.Type == PrincipalService {
+		return ""
+	}
+	if principal.TenantID != "" {
+		return principal.TenantID
+	}
+	if principal.PartyID != "" {
+		return principal.PartyID
+	}
+	return principal.Subject
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseJWTRequiresExpiry(t *testing.T) {
+	secret := []byte("test-secret")
+	now := time.Now()
+
+	valid, _ := SignJWT(Claims{Subject: "svc", ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+	if _, err := ParseJWT(valid, secret); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	noExpiry, _ := SignJWT(Claims{Subject: "svc", Type: PrincipalService, Scopes: []string{"*"}}, secret)
+	if _, err := ParseJWT(noExpiry, secret); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token without exp: got %v, want ErrInvalidToken", err)
+	}
+
+	expired, _ := SignJWT(Claims{Subject: "svc", ExpiresAt: now.Add(-time.Minute).Unix()}, secret)
+	if _, err := ParseJWT(expired, secret); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expired token: got %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestEmptySecretVerifiesNothing(t *testing.T) {
+	if _, err := SignJWT(Claims{ExpiresAt: time.Now().Add(time.Minute).Unix()}, nil); !errors.Is(err, ErrNoJWTSecret) {
+		t.Fatalf("signing without a secret: got %v", err)
+	}
+
+	// A token signed with an empty HMAC key must not verify under one
+	token := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.thNsTQTQ0Nt4Ph3AfQL1yeVLaLNYxg36-AXY4z2fDvE"
+	if _, err := ParseJWT(token, nil); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("empty secret: got %v, want ErrInvalidToken", err)
+	}
+
+	cfg := &AuthConfig{Enabled: true}
+	if err := cfg.Validate(); !errors.Is(err, ErrNoJWTSecret) {
+		t.Fatalf("enabled without a secret: got %v", err)
+	}
+	cfg.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("disabled: %v", err)
+	}
+}
+
+func TestRequireRolesDeniesPrincipalsWithoutRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(principal *Principal, check gin.HandlerFunc) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", principal) })
+		router.POST("/reviews", check, func(c *gin.Context) { c.Status(http.StatusOK) })
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/reviews", nil))
+		return recorder.Code
+	}
+	service := &Principal{Type: PrincipalService, Subject: "svc"}
+	partyKey := &Principal{Type: PrincipalParty, PartyID: "party-1"}
+	agent := &Principal{Type: PrincipalAgent, AgentID: "agent-1"}
+	reviewer := &Principal{Type: PrincipalParty, PartyID: "party-1", UserID: "user-1", Roles: []string{RoleReviewer}}
+	approver := &Principal{Type: PrincipalParty, PartyID: "party-1", UserID: "user-2", Roles: []string{RoleApprover}}
+
+	strict := RequireRoles(RoleReviewer)
+	for name, principal := range map[string]*Principal{"service": service, "party key": partyKey, "agent": agent, "approver": approver} {
+		if code := serve(principal, strict); code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403", name, code)
+		}
+	}
+	if code := serve(reviewer, strict); code != http.StatusOK {
+		t.Errorf("reviewer: got %d, want 200", code)
+	}
+
+	// Routes may admit services, but users of any type still need the role
+	admitting := RequireRolesOrPrincipals([]string{PrincipalService}, RoleReviewer)
+	if code := serve(service, admitting); code != http.StatusOK {
+		t.Errorf("admitted service: got %d, want 200", code)
+	}
+	if code := serve(partyKey, admitting); code != http.StatusForbidden {
+		t.Errorf("party key: got %d, want 403", code)
+	}
+	if code := serve(approver, RequireRolesOrPrincipals([]string{PrincipalParty}, RoleReviewer)); code != http.StatusForbidden {
+		t.Errorf("user without the role: got %d, want 403", code)
+	}
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIntrospectionCache bounds the answers an IntrospectionValidator keeps
+const maxIntrospectionCache = 10000
+
+// IntrospectionValidator validates OAuth client tokens with the identity
+// service's token introspection endpoint (RFC 7662), for callers without a
+// credential store, such as the gateway. Answers are cached for CacheTTL, so
+// the tokens of a revoked client stop working within it.
+type IntrospectionValidator struct {
+	URL      string        // Such as http://identity:8081/v1/oauth/introspect
+	Service  string        // Subject of the service token introspection is called with
+	CacheTTL time.Duration // How long answers are kept, at most until the token expires
+	auth     *AuthConfig
+	client   *ResilientClient
+	mu       sync.Mutex
+	cache    map[string]introspection
+}
+
+type introspection struct {
+	active bool
+	until  time.Time
+}
+
+// NewIntrospectionValidator creates a validator calling an introspection
+// endpoint with service tokens of auth. OAUTH_INTROSPECTION_CACHE_SECONDS
+// (30) sets how long answers are kept.
+func NewIntrospectionValidator(endpoint, service string, auth *AuthConfig) *IntrospectionValidator {
+	return &IntrospectionValidator{
+		URL:      endpoint,
+		Service:  service,
+		CacheTTL: time.Duration(GetEnvAsInt("OAUTH_INTROSPECTION_CACHE_SECONDS", 30)) * time.Second,
+		auth:     auth,
+		client:   NewResilientClient(NewResilienceConfigFromEnv()),
+		cache:    map[string]introspection{},
+	}
+}
+
+// ValidateToken implements TokenValidator. Tokens are rejected when the
+// endpoint cannot be reached.
+func (v *IntrospectionValidator) ValidateToken(ctx context.Context, token string, claims *Claims) error {
+	key := claims.ID
+	if key == "" {
+		key = HashAPIKey(token)
+	}
+
+	now := time.Now()
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if !ok || now.After(cached.until) {
+		active, err := v.introspect(ctx, token)
+		if err != nil {
+			return err
+		}
+		cached = introspection{active: active, until: now.Add(v.CacheTTL)}
+		if expires := time.Unix(claims.ExpiresAt, 0); claims.ExpiresAt > 0 && expires.Before(cached.until) {
+			cached.until = expires
+		}
+		v.remember(key, cached, now)
+	}
+
+	if !cached.active {
+		return fmt.Errorf("token of client %s is not active", claims.ClientID)
+	}
+	return nil
+}
+
+func (v *IntrospectionValidator) remember(key string, answer introspection, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.cache) >= maxIntrospectionCache {
+		for cachedKey, cached := range v.cache {
+			if now.After(cached.until) {
+				delete(v.cache, cachedKey)
+			}
+		}
+		if len(v.cache) >= maxIntrospectionCache {
+			v.cache = map[string]introspection{}
+		}
+	}
+	v.cache[key] = answer
+}
+
+func (v *IntrospectionValidator) introspect(ctx context.Context, token string) (bool, error) {
+	serviceToken, err := v.auth.ServiceToken(v.Service, ScopeTokensIntrospect)
+	if err != nil {
+		return false, fmt.Errorf("failed to issue service token: %v", err)
+	}
+
+	form := url.Values{"token": {token}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, strings.NewReader(form))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	resp, err := v.client.Do("identity", req)
+	if err != nil {
+		return false, fmt.Errorf("token introspection failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("token introspection answered %d", resp.StatusCode)
+	}
+
+	var answer struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return false, fmt.Errorf("invalid introspection answer: %v", err)
+	}
+	return answer.Active, nil
+}
@@ -0,0 +1,241 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics
+//
+// Every service exposes Prometheus metrics at /metrics: request counts and
+// latencies per route, Go runtime and process metrics, and database pool
+// statistics, and the circuit breakers and retries of outgoing calls.
+// Payment outcomes are counted by the service that settles them
+// and the outbox backlog by the service that retries it; events are
+// counted by the handlers that consume them. Services are told
+// apart by the scrape job, so the metrics carry no service label.
+
+// MetricsNamespace prefixes the platform's metric names
+const MetricsNamespace = "agentpay"
+
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	paymentOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "payments_total",
+		Help:      "Payments reaching a final status, by rail and status.",
+	}, []string{"rail", "status"})
+
+	outboxEventsRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "outbox_events_removed_total",
+		Help:      "Published outbox events removed by the retention job, by the limit that removed them.",
+	}, []string{"reason"})
+
+	outboxEventsArchived = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "outbox_events_archived_total",
+		Help:      "Outbox events archived to cold storage before removal.",
+	})
+
+	eventsHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "events_handled_total",
+		Help:      "Events consumed, by handler, event type and outcome: handled, failed or panicked.",
+	}, []string{"handler", "event_type", "outcome"})
+
+	eventHandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "event_handler_duration_seconds",
+		Help:      "Time taken by event handlers, by handler and event type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "event_type"})
+
+	eventsDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "events_dead_lettered_total",
+		Help:      "Consumed messages dead-lettered after their handlers failed, by topic.",
+	}, []string{"topic"})
+
+	circuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker to each call target: 0 closed, 1 half open, 2 open.",
+	}, []string{"target"})
+
+	circuitBreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "Circuit breaker state changes, by call target and the state entered.",
+	}, []string{"target", "state"})
+
+	clientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_client_retries_total",
+		Help:      "Retries of failed outgoing calls, by call target.",
+	}, []string{"target"})
+
+	clientRetryBudgetExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_client_retry_budget_exhausted_total",
+		Help:      "Failed outgoing calls not retried because the target's retry budget was spent.",
+	}, []string{"target"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		paymentOutcomes,
+		outboxEventsRemoved,
+		outboxEventsArchived,
+		eventsHandled,
+		eventHandlerDuration,
+		eventsDeadLettered,
+		circuitBreakerState,
+		circuitBreakerTransitions,
+		clientRetries,
+		clientRetryBudgetExhausted,
+	)
+}
+
+// registerMetrics registers a collector, tolerating one already registered
+// by an earlier call
+func registerMetrics(collector prometheus.Collector) error {
+	err := metricsRegistry.Register(collector)
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+	return err
+}
+
+// MetricsMiddleware counts and times each request under its route pattern.
+// Requests matching no route are counted together, so unknown paths cannot
+// grow the number of series.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler serves the registered metrics in the Prometheus exposition format
+func MetricsHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{Registry: metricsRegistry}))
+}
+
+// RecordPaymentOutcome counts a payment reaching a final status on a rail
+func RecordPaymentOutcome(rail, status string) {
+	if rail == "" {
+		rail = "none" // Payments can fail before a rail is selected
+	}
+	paymentOutcomes.WithLabelValues(rail, status).Inc()
+}
+
+// RegisterDBStats exposes the connection pool statistics of a database
+func RegisterDBStats(db *sql.DB, name string) error {
+	return registerMetrics(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterOutboxBacklog exposes the number of outbox events in each status,
+// counted with count when metrics are scraped
+func RegisterOutboxBacklog(count func(status string) (int64, error), statuses ...string) error {
+	return registerMetrics(&outboxCollector{count: count, statuses: statuses})
+}
+
+// RegisterOutboxTableSize exposes the bytes the outbox table takes, measured
+// with size when metrics are scraped
+func RegisterOutboxTableSize(size func() (int64, error)) error {
+	return registerMetrics(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "outbox_table_bytes",
+		Help:      "Bytes taken by the outbox table and its indexes.",
+	}, func() float64 {
+		n, err := size()
+		if err != nil {
+			Warn("Failed to measure the outbox table for metrics: %v", err)
+			return 0
+		}
+		return float64(n)
+	}))
+}
+
+// RecordOutboxCleanup counts outbox events archived and removed by the
+// retention job, removed is keyed by the limit that removed them
+func RecordOutboxCleanup(archived int, removed map[string]int) {
+	outboxEventsArchived.Add(float64(archived))
+	for reason, n := range removed {
+		outboxEventsRemoved.WithLabelValues(reason).Add(float64(n))
+	}
+}
+
+// RecordEventHandled counts an event a handler consumed and times the handler
+func RecordEventHandled(handler, eventType, outcome string, duration time.Duration) {
+	eventsHandled.WithLabelValues(handler, eventType, outcome).Inc()
+	eventHandlerDuration.WithLabelValues(handler, eventType).Observe(duration.Seconds())
+}
+
+// RecordEventDeadLettered counts a consumed message dead-lettered
+func RecordEventDeadLettered(topic string) {
+	eventsDeadLettered.WithLabelValues(topic).Inc()
+}
+
+var outboxEventsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(MetricsNamespace, "outbox", "events"),
+	"Outbox events waiting to be published, by status.",
+	[]string{"status"}, nil,
+)
+
+// outboxCollector counts the outbox on each scrape, so the gauge is current
+// whichever service published the events
+type outboxCollector struct {
+	count    func(status string) (int64, error)
+	statuses []string
+}
+
+func (o *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- outboxEventsDesc
+}
+
+func (o *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range o.statuses {
+		n, err := o.count(status)
+		if err != nil {
+			Warn("Failed to count %s outbox events for metrics: %v", status, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(outboxEventsDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
@@ -19,7 +19,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID, X-Correlation-ID, traceparent, tracestate, X-API-Key, X-Agent-Credential, X-Agent-Signature")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -45,6 +45,46 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// CorrelationIDHeader carries the ID that ties together every request made on behalf of one client call
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationIDMiddleware propagates or assigns a correlation ID to each request
+func CorrelationIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		correlationID := c.GetHeader(CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = GenerateUUID()
+			c.Request.Header.Set(CorrelationIDHeader, correlationID)
+		}
+
+		c.Set("correlationID", correlationID)
+		c.Header(CorrelationIDHeader, correlationID)
+		c.Request = c.Request.WithContext(WithCorrelationID(c.Request.Context(), correlationID))
+
+		c.Next()
+	}
+}
+
+// GetCorrelationID returns the correlation ID assigned by CorrelationIDMiddleware
+func GetCorrelationID(c *gin.Context) string {
+	return c.GetString("correlationID")
+}
+
+// ParseAsOf reads the optional asOf query parameter of point-in-time reads,
+// responding with 400 if it is not an RFC3339 time
+func ParseAsOf(c *gin.Context) (*time.Time, bool) {
+	value := c.Query("asOf")
+	if value == "" {
+		return nil, true
+	}
+	asOf, err := ParseTime(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("VALIDATION_ERROR", "asOf must be an RFC3339 time"))
+		return nil, false
+	}
+	return &asOf, true
+}
+
 // RecoveryMiddleware handles panics and recovers from them
 func RecoveryMiddleware() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
@@ -96,61 +136,24 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware provides basic rate limiting (simplified version)
-func RateLimitMiddleware(requestsPerMinute int) gin.HandlerFunc {
-	// This is a simplified rate limiter - in production, use a proper rate limiting library
-	type clientInfo struct {
-		requests []time.Time
-	}
-
-	clients := make(map[string]*clientInfo)
-
-	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		now := time.Now()
-
-		if _, exists := clients[clientIP]; !exists {
-			clients[clientIP] = &clientInfo{
-				requests: []time.Time{now},
-			}
-			c.Next()
-			return
-		}
-
-		client := clients[clientIP]
-
-		// Remove requests older than 1 minute
-		var recentRequests []time.Time
-		for _, reqTime := range client.requests {
-			if now.Sub(reqTime) < time.Minute {
-				recentRequests = append(recentRequests, reqTime)
-			}
-		}
-
-		if len(recentRequests) >= requestsPerMinute {
-			c.JSON(http.StatusTooManyRequests, NewErrorResponse("RATE_LIMIT_EXCEEDED", "Too many requests"))
-			c.Abort()
-			return
-		}
-
-		client.requests = append(recentRequests, now)
-		clients[clientIP] = client
-
-		c.Next()
-	}
-}
-
 // SetupCommonMiddleware sets up all common middleware for a Gin router
 func SetupCommonMiddleware(router *gin.Engine, healthChecker func() error) {
 	router.Use(
 		LoggerMiddleware(),
 		CORSMiddleware(),
 		RequestIDMiddleware(),
+		CorrelationIDMiddleware(),
+		TracingMiddleware(),
+		MetricsMiddleware(),
 		RecoveryMiddleware(),
 		ErrorHandlerMiddleware(),
-		RateLimitMiddleware(100), // 100 requests per minute
+		RateLimitMiddleware(DefaultRateLimiter()),
+		RedactionMiddleware(NewRedactionPolicyFromEnv()),
 	)
 
 	// Add health check middleware
 	router.Use(HealthCheckMiddleware(healthChecker))
+
+	// Prometheus metrics
+	router.GET("/metrics", MetricsHandler())
 }
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes for list endpoints
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// ListParams pages, sorts and limits by creation time a list request
+type ListParams struct {
+	Limit      int
+	Offset     int
+	Sort       string // Field to sort by, empty for the list's default
+	Descending bool
+	From       *time.Time // Created at or after
+	To         *time.Time // Created before
+}
+
+// ParseListParams reads ?limit=&offset=&sort=&order=&from=&to=, writing a
+// 400 response and returning false if one is malformed. Lists default to
+// DefaultPageLimit items, newest first.
+func ParseListParams(c *gin.Context) (ListParams, bool) {
+	params := ListParams{Limit: DefaultPageLimit, Sort: c.Query("sort"), Descending: true}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("VALIDATION_ERROR", "limit must be from 1 to "+strconv.Itoa(MaxPageLimit)))
+			return params, false
+		}
+		params.Limit = limit
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("VALIDATION_ERROR", "offset must not be negative"))
+			return params, false
+		}
+		params.Offset = offset
+	}
+
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		params.Descending = false
+	default:
+		c.JSON(http.StatusBadRequest, NewErrorResponse("VALIDATION_ERROR", "order must be asc or desc"))
+		return params, false
+	}
+
+	for name, bound := range map[string]**time.Time{"from": &params.From, "to": &params.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := ParseTime(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, NewErrorResponse("VALIDATION_ERROR", name+" must be an RFC3339 time"))
+				return params, false
+			}
+			*bound = &parsed
+		}
+	}
+	return params, true
+}
+
+// Window returns the bounds of the page within a list of total items, for
+// lists paged in memory
+func (p ListParams) Window(total int) (int, int) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+	end := start + p.Limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// NewPageResponse creates the response for a page of a list of total items
+func NewPageResponse(items []interface{}, params ListParams, total int) *ListResponse {
+	response := NewListResponse(items, params.Offset/params.Limit+1, params.Limit, total)
+	response.Meta.Offset = params.Offset
+	response.Meta.HasMore = params.Offset+len(items) < total
+	return response
+}
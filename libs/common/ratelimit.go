@@ -0,0 +1,348 @@
+package common
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Rate limiting
+//
+// Requests are limited with token buckets: a client may burst up to its
+// per-minute limit, and tokens refill evenly over the minute. Each request
+// is counted against its client IP before authentication, and then against
+// its credential and, for agents, the agent, once authenticated. Service
+// principals are not limited by credential or agent. Route groups can be
+// given limits of their own with RATE_LIMIT_ROUTES; requests in a group draw
+// from buckets separate from the default ones. Buckets are kept in memory,
+// or in Redis (RATE_LIMIT_BACKEND=redis) so instances of a service share
+// them.
+
+// Rate limit scopes: what a limit counts requests by
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByKey   = "key"
+	RateLimitByAgent = "agent"
+)
+
+// RateLimitRule limits a scope to PerMinute requests, 0 for no limit
+type RateLimitRule struct {
+	Scope     string
+	PerMinute int
+}
+
+// RouteRateLimit overrides the limit of a scope for paths under Prefix
+type RouteRateLimit struct {
+	Prefix string
+	RateLimitRule
+}
+
+// RateLimitResult is the state of a bucket after taking a request from it
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // Until a token is available, when not allowed
+}
+
+// RateLimitStore keeps token buckets
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, perMinute int) (RateLimitResult, error)
+}
+
+// RateLimiter applies default and per-route limits to requests
+type RateLimiter struct {
+	store    RateLimitStore
+	fallback RateLimitStore // Used while the store fails
+	defaults map[string]int
+	routes   []RouteRateLimit // Longest prefix first
+	failing  atomic.Bool
+}
+
+// NewRateLimiter creates a limiter over a store. Routes may be given in any order.
+func NewRateLimiter(store RateLimitStore, defaults []RateLimitRule, routes []RouteRateLimit) *RateLimiter {
+	limiter := &RateLimiter{
+		store:    store,
+		fallback: NewMemoryRateLimitStore(),
+		defaults: make(map[string]int),
+		routes:   append([]RouteRateLimit(nil), routes...),
+	}
+	for _, rule := range defaults {
+		limiter.defaults[rule.Scope] = rule.PerMinute
+	}
+	sort.SliceStable(limiter.routes, func(i, j int) bool {
+		return len(limiter.routes[i].Prefix) > len(limiter.routes[j].Prefix)
+	})
+	return limiter
+}
+
+// NewRateLimiterFromEnv creates a limiter configured by the RATE_LIMIT_*
+// variables
+func NewRateLimiterFromEnv() *RateLimiter {
+	var store RateLimitStore = NewMemoryRateLimitStore()
+	if GetEnv("RATE_LIMIT_BACKEND", "memory") == "redis" {
+		options, err := redis.ParseURL(GetEnv("RATE_LIMIT_REDIS_URL", GetEnv("REDIS_URL", "redis://localhost:6379")))
+		if err != nil {
+			Error("Invalid rate limit Redis URL, limiting in memory: %v", err)
+		} else {
+			store = NewRedisRateLimitStore(redis.NewClient(options))
+			Info("Rate limits are shared through Redis at %s", options.Addr)
+		}
+	}
+
+	defaults := []RateLimitRule{
+		{Scope: RateLimitByIP, PerMinute: GetEnvAsInt("RATE_LIMIT_IP_PER_MINUTE", 100)},
+		{Scope: RateLimitByKey, PerMinute: GetEnvAsInt("RATE_LIMIT_KEY_PER_MINUTE", 600)},
+		{Scope: RateLimitByAgent, PerMinute: GetEnvAsInt("RATE_LIMIT_AGENT_PER_MINUTE", 300)},
+	}
+	routes, err := ParseRouteRateLimits(GetEnv("RATE_LIMIT_ROUTES", ""))
+	if err != nil {
+		Error("Ignoring RATE_LIMIT_ROUTES: %v", err)
+	}
+	return NewRateLimiter(store, defaults, routes)
+}
+
+var (
+	defaultRateLimiter     *RateLimiter
+	defaultRateLimiterOnce sync.Once
+)
+
+// DefaultRateLimiter returns the limiter shared by a service's middleware,
+// configured from the environment on first use
+func DefaultRateLimiter() *RateLimiter {
+	defaultRateLimiterOnce.Do(func() {
+		defaultRateLimiter = NewRateLimiterFromEnv()
+	})
+	return defaultRateLimiter
+}
+
+// ParseRouteRateLimits parses route limits written as comma-separated
+// "<prefix>[:<scope>]=<perMinute>" entries, such as
+// "/v1/payments:agent=60,/v1/reports=20". An entry without a scope applies
+// to every scope.
+func ParseRouteRateLimits(value string) ([]RouteRateLimit, error) {
+	var routes []RouteRateLimit
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, limit, ok := strings.Cut(entry, "=")
+		perMinute, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || err != nil || perMinute < 0 {
+			return nil, &rateLimitConfigError{entry: entry, reason: "expected <prefix>[:<scope>]=<perMinute>"}
+		}
+		prefix, scope, scoped := strings.Cut(strings.TrimSpace(target), ":")
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, &rateLimitConfigError{entry: entry, reason: "prefix must start with /"}
+		}
+		scopes := []string{RateLimitByIP, RateLimitByKey, RateLimitByAgent}
+		if scoped {
+			if scope != RateLimitByIP && scope != RateLimitByKey && scope != RateLimitByAgent {
+				return nil, &rateLimitConfigError{entry: entry, reason: "scope must be ip, key or agent"}
+			}
+			scopes = []string{scope}
+		}
+		for _, scope := range scopes {
+			routes = append(routes, RouteRateLimit{Prefix: prefix, RateLimitRule: RateLimitRule{Scope: scope, PerMinute: perMinute}})
+		}
+	}
+	return routes, nil
+}
+
+type rateLimitConfigError struct {
+	entry  string
+	reason string
+}
+
+func (e *rateLimitConfigError) Error() string {
+	return "invalid rate limit " + strconv.Quote(e.entry) + ": " + e.reason
+}
+
+// limitFor returns the bucket group and limit of a scope for a path
+func (l *RateLimiter) limitFor(scope, path string) (string, int) {
+	for _, route := range l.routes {
+		if route.Scope == scope && strings.HasPrefix(path, route.Prefix) {
+			return route.Prefix, route.PerMinute
+		}
+	}
+	return "default", l.defaults[scope]
+}
+
+// Allow takes a request from the bucket of a scope and client, writing the
+// rate limit headers, and the 429 response when the bucket is empty
+func (l *RateLimiter) Allow(c *gin.Context, scope, client string) bool {
+	group, perMinute := l.limitFor(scope, c.Request.URL.Path)
+	if perMinute <= 0 || client == "" {
+		return true
+	}
+
+	key := "ratelimit:" + group + ":" + scope + ":" + client
+	result, err := l.store.Take(c.Request.Context(), key, perMinute)
+	if err != nil {
+		// Keep limiting, per instance, while the shared store is unavailable
+		if l.failing.CompareAndSwap(false, true) {
+			Error("Rate limit store failed, limiting in memory: %v", err)
+		}
+		result, _ = l.fallback.Take(c.Request.Context(), key, perMinute)
+	} else if l.failing.CompareAndSwap(true, false) {
+		Info("Rate limit store recovered")
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(perMinute))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, NewErrorResponseWithDetails("RATE_LIMIT_EXCEEDED", "Too many requests", "limited by "+scope))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// AllowPrincipal takes an authenticated request from the buckets of its
+// credential and agent. Service principals are not limited.
+func (l *RateLimiter) AllowPrincipal(c *gin.Context, principal *Principal) bool {
+	if principal.Type == PrincipalService {
+		return true
+	}
+	if !l.Allow(c, RateLimitByKey, credentialKey(c.Request, principal)) {
+		return false
+	}
+	if principal.AgentID != "" {
+		return l.Allow(c, RateLimitByAgent, principal.AgentID)
+	}
+	return true
+}
+
+// credentialKey identifies the credential a request authenticated with: a
+// hash of its API key, or the subject of its token
+func credentialKey(r *http.Request, principal *Principal) string {
+	if principal.Method == AuthMethodJWT {
+		return "sub:" + principal.Subject
+	}
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), "ApiKey "))
+	}
+	return HashAPIKey(key)[:32]
+}
+
+// RateLimitMiddleware limits requests by client IP
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limiter.Allow(c, RateLimitByIP, c.ClientIP()) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// MemoryRateLimitStore keeps token buckets in memory. Buckets that have
+// refilled are dropped, so idle clients do not accumulate.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, perMinute int) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= time.Minute {
+		s.sweep(now)
+	}
+
+	capacity := float64(perMinute)
+	rate := capacity / time.Minute.Seconds() // Tokens per second
+	bucket := s.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+		return RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: wait}, nil
+	}
+	bucket.tokens--
+	return RateLimitResult{Allowed: true, Remaining: int(bucket.tokens)}, nil
+}
+
+// sweep drops buckets idle long enough to have refilled
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= time.Minute {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// RedisRateLimitStore keeps token buckets in Redis, so every instance of a
+// service draws from the same buckets. Buckets are updated by a script using
+// the Redis clock and expire once they would have refilled.
+type RedisRateLimitStore struct {
+	client redis.Scripter
+}
+
+// NewRedisRateLimitStore creates a store over a Redis client
+func NewRedisRateLimitStore(client redis.Scripter) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = capacity / 60000
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or capacity
+local updated = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], 60000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, perMinute int) (RateLimitResult, error) {
+	values, err := tokenBucketScript.Run(ctx, s.client, []string{key}, perMinute).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,311 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Resilient HTTP calls
+//
+// Calls to other services go through a ResilientClient, which keeps a
+// circuit breaker and a retry budget per target. A target whose calls keep
+// failing is cut off for a cooldown instead of tying up a goroutine per
+// caller until each call times out. Failed calls are retried with jittered
+// exponential backoff, but only when a repeat is safe: idempotent methods,
+// requests carrying an Idempotency-Key, and requests that never reached the
+// target. Retries draw on the target's budget, so a struggling target is not
+// sent a multiple of its normal load.
+
+// ErrServiceUnavailable marks a call that failed because the target could
+// not be reached or failed itself, as opposed to rejecting the request
+var ErrServiceUnavailable = errors.New("service unavailable")
+
+// ErrCircuitOpen is returned for calls not made because a breaker is open
+var ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrServiceUnavailable)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ResilienceConfig configures a ResilientClient
+type ResilienceConfig struct {
+	Timeout          time.Duration // Per attempt
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	RetryBudgetRatio float64 // Retries earned per call
+	RetryBudgetMax   float64 // Most retries saved up
+	BreakerThreshold int     // Consecutive failures that open a breaker
+	BreakerCooldown  time.Duration
+}
+
+// NewResilienceConfigFromEnv reads SERVICE_CALL_TIMEOUT_SECONDS (10),
+// SERVICE_CALL_MAX_RETRIES (2), SERVICE_CALL_RETRY_BASE_DELAY_MS (200),
+// SERVICE_CALL_RETRY_MAX_DELAY_MS (2000), SERVICE_CALL_RETRY_BUDGET_PERCENT
+// (10), SERVICE_CALL_RETRY_BUDGET_MAX (10), CIRCUIT_BREAKER_FAILURE_THRESHOLD
+// (5) and CIRCUIT_BREAKER_COOLDOWN_SECONDS (30)
+func NewResilienceConfigFromEnv() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          time.Duration(GetEnvAsInt("SERVICE_CALL_TIMEOUT_SECONDS", 10)) * time.Second,
+		MaxRetries:       GetEnvAsInt("SERVICE_CALL_MAX_RETRIES", 2),
+		RetryBaseDelay:   time.Duration(GetEnvAsInt("SERVICE_CALL_RETRY_BASE_DELAY_MS", 200)) * time.Millisecond,
+		RetryMaxDelay:    time.Duration(GetEnvAsInt("SERVICE_CALL_RETRY_MAX_DELAY_MS", 2000)) * time.Millisecond,
+		RetryBudgetRatio: float64(GetEnvAsInt("SERVICE_CALL_RETRY_BUDGET_PERCENT", 10)) / 100,
+		RetryBudgetMax:   float64(GetEnvAsInt("SERVICE_CALL_RETRY_BUDGET_MAX", 10)),
+		BreakerThreshold: GetEnvAsInt("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerCooldown:  time.Duration(GetEnvAsInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
+	}
+}
+
+// ResilientClient makes HTTP calls through a breaker and retry budget per
+// target. Targets are named by the caller, such as a service name or a host.
+type ResilientClient struct {
+	config  ResilienceConfig
+	client  *http.Client
+	mu      sync.Mutex
+	targets map[string]*callTarget
+}
+
+type callTarget struct {
+	breaker *CircuitBreaker
+	budget  *retryBudget
+}
+
+// NewResilientClient creates a client with the given settings
+func NewResilientClient(config ResilienceConfig) *ResilientClient {
+	return &ResilientClient{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		targets: map[string]*callTarget{},
+	}
+}
+
+func (c *ResilientClient) target(name string) *callTarget {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	target, ok := c.targets[name]
+	if !ok {
+		target = &callTarget{
+			breaker: NewCircuitBreaker(name, c.config.BreakerThreshold, c.config.BreakerCooldown),
+			budget:  &retryBudget{balance: c.config.RetryBudgetMax, ratio: c.config.RetryBudgetRatio, max: c.config.RetryBudgetMax},
+		}
+		c.targets[name] = target
+	}
+	return target
+}
+
+// Breaker returns the circuit breaker of a target
+func (c *ResilientClient) Breaker(target string) *CircuitBreaker {
+	return c.target(target).breaker
+}
+
+// Do sends a request to a target. Answers below 500 are returned as they
+// are; a 5xx answer is returned once retries are spent. Errors from calls
+// that did not get an answer wrap ErrServiceUnavailable.
+func (c *ResilientClient) Do(targetName string, req *http.Request) (*http.Response, error) {
+	target := c.target(targetName)
+	target.budget.deposit()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			req = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		if !target.breaker.Allow(time.Now()) {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, targetName)
+		}
+		resp, err := c.client.Do(req)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		target.breaker.Record(failed, time.Now())
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+		}
+		if !failed || attempt >= c.config.MaxRetries || !retryable(req, err) {
+			return resp, err
+		}
+		if !target.budget.withdraw() {
+			clientRetryBudgetExhausted.WithLabelValues(targetName).Inc()
+			Warn("Retry budget for %s is spent; not retrying", targetName)
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		delay := c.backoff(attempt)
+		clientRetries.WithLabelValues(targetName).Inc()
+		Warn("Call to %s failed with %s, retrying in %s", targetName, reason, delay)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("%w: %v", ErrServiceUnavailable, req.Context().Err())
+		}
+	}
+}
+
+// backoff is the jittered delay before the retry following an attempt, so
+// callers that failed together do not retry in step
+func (c *ResilientClient) backoff(attempt int) time.Duration {
+	delay := c.config.RetryBaseDelay << attempt
+	if delay > c.config.RetryMaxDelay || delay <= 0 {
+		delay = c.config.RetryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryable reports whether a failed request is safe to send again: its body
+// can be replayed, and either repeating it has no further effect or it
+// never reached the target
+func retryable(req *http.Request, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	var opErr *net.OpError
+	return err != nil && errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryBudget caps retries at a share of calls. Each call deposits ratio,
+// each retry withdraws one, and the balance holds at most max, so a burst of
+// failures can spend what was saved but a sustained outage retries only
+// ratio times as often as it is called.
+type retryBudget struct {
+	mu      sync.Mutex
+	balance float64
+	ratio   float64
+	max     float64
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance += b.ratio
+	if b.balance > b.max {
+		b.balance = b.max
+	}
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
+
+// CircuitBreaker tracks whether calls to a target are being let through.
+// After threshold consecutive failures it opens and calls fail fast; after
+// the cooldown one trial call is let through, and its outcome closes or
+// reopens the breaker.
+type CircuitBreaker struct {
+	name      string
+	mu        sync.Mutex
+	state     string
+	failures  int // Consecutive failures
+	openedAt  time.Time
+	trialOut  bool // A half-open trial call is in flight
+	threshold int
+	cooldown  time.Duration
+}
+
+// NewCircuitBreaker creates a closed breaker for the named target
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	circuitBreakerState.WithLabelValues(name).Set(0)
+	return &CircuitBreaker{name: name, state: CircuitClosed, threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made now. An open breaker lets one
+// trial call through once its cooldown has passed.
+func (b *CircuitBreaker) Allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		b.trialOut = true
+		return true
+	case CircuitHalfOpen:
+		if b.trialOut {
+			return false
+		}
+		b.trialOut = true
+		return true
+	}
+	return true
+}
+
+// Record counts the outcome of a call that was let through
+func (b *CircuitBreaker) Record(failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialOut = false
+	if !failed {
+		if b.state != CircuitClosed {
+			Info("Circuit to %s closed", b.name)
+			b.setState(CircuitClosed)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		if b.state != CircuitOpen {
+			Warn("Circuit to %s opened after %d consecutive failures", b.name, b.failures)
+			b.setState(CircuitOpen)
+		}
+		b.openedAt = now
+	}
+}
+
+// setState moves the breaker to a state, counting the transition
+func (b *CircuitBreaker) setState(state string) {
+	b.state = state
+	circuitBreakerTransitions.WithLabelValues(b.name, state).Inc()
+	value := 0.0
+	switch state {
+	case CircuitHalfOpen:
+		value = 1
+	case CircuitOpen:
+		value = 2
+	}
+	circuitBreakerState.WithLabelValues(b.name).Set(value)
+}
+
+// Status returns the breaker's state and its count of consecutive failures
+func (b *CircuitBreaker) Status() (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.failures
+}
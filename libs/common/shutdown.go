@@ -0,0 +1,204 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Graceful shutdown
+//
+// Services serve through a Server, which on SIGINT or SIGTERM stops
+// accepting connections, lets in-flight requests finish, then runs the
+// service's shutdown hooks, all within SHUTDOWN_TIMEOUT_SECONDS (30).
+// Background loops stop when the server's context is cancelled. Work started
+// in goroutines, such as payment processing, runs through Workers so that
+// a hook can wait for it: workers still running when the timeout passes have
+// their context cancelled and are given CheckpointTimeout to record where
+// they stopped before the process exits.
+
+// Server runs a service's HTTP server until it is told to stop
+type Server struct {
+	http            *http.Server
+	ShutdownTimeout time.Duration
+	ctx             context.Context
+	cancel          context.CancelFunc
+	hooks           []shutdownHook
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewServer creates a server for handler on addr, with the shutdown timeout
+// from SHUTDOWN_TIMEOUT_SECONDS
+func NewServer(addr string, handler http.Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		http:            &http.Server{Addr: addr, Handler: handler},
+		ShutdownTimeout: time.Duration(GetEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Context is cancelled when shutdown begins, for background loops to stop on
+func (s *Server) Context() context.Context {
+	return s.ctx
+}
+
+// OnShutdown adds a hook run once in-flight requests have finished. Hooks run
+// in the order they were added and share what is left of the timeout.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Run serves until SIGINT or SIGTERM, then shuts down. It returns an error
+// if the server could not serve; failures during shutdown are logged.
+func (s *Server) Run() error {
+	served := make(chan error, 1)
+	go func() {
+		served <- s.http.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-served:
+		s.cancel()
+		return err
+	case sig := <-signals:
+		Info("Received %s, shutting down", sig)
+	}
+
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.http.Shutdown(ctx); err != nil {
+		Error("HTTP server did not shut down cleanly: %v", err)
+	}
+	for _, hook := range s.hooks {
+		if err := hook.fn(ctx); err != nil {
+			Error("Shutdown of %s did not complete: %v", hook.name, err)
+		}
+	}
+	Info("Shutdown complete")
+	return nil
+}
+
+// Workers tracks goroutines doing work that should finish before the service
+// exits, each under an ID such as the payment it processes
+type Workers struct {
+	CheckpointTimeout time.Duration
+
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	running  map[string]int
+	draining bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewWorkers creates an empty worker registry
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{
+		CheckpointTimeout: 5 * time.Second,
+		running:           make(map[string]int),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// Go runs fn in a goroutine tracked under id. The context given to fn is
+// cancelled when a drain runs out of time, and fn should then record its
+// progress and return. Once draining has begun Go runs nothing and returns
+// false.
+func (w *Workers) Go(id string, fn func(ctx context.Context)) bool {
+	w.mu.Lock()
+	if w.draining {
+		w.mu.Unlock()
+		return false
+	}
+	w.running[id]++
+	w.wg.Add(1)
+	w.mu.Unlock()
+
+	go func() {
+		defer func() {
+			w.mu.Lock()
+			if w.running[id]--; w.running[id] <= 0 {
+				delete(w.running, id)
+			}
+			w.mu.Unlock()
+			w.wg.Done()
+		}()
+		fn(w.ctx)
+	}()
+	return true
+}
+
+// Context is the context given to workers, for code they call that needs to
+// know when to stop
+func (w *Workers) Context() context.Context {
+	return w.ctx
+}
+
+// Active lists the IDs of the running workers
+func (w *Workers) Active() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ids := make([]string, 0, len(w.running))
+	for id := range w.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Drain stops new workers starting and waits for the running ones to finish
+// until ctx is done. Workers still running are then cancelled and given
+// CheckpointTimeout to return; the error names any that did not.
+func (w *Workers) Drain(ctx context.Context) error {
+	w.mu.Lock()
+	w.draining = true
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	if active := w.Active(); len(active) > 0 {
+		Info("Waiting for %d workers to finish", len(active))
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	Warn("Workers still running at the shutdown deadline, checkpointing: %v", w.Active())
+	w.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(w.CheckpointTimeout):
+	}
+	if active := w.Active(); len(active) > 0 {
+		return fmt.Errorf("%d workers did not stop: %v", len(active), active)
+	}
+	return errors.New("workers did not stop")
+}
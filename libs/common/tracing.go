@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing
+//
+// Every service creates OpenTelemetry spans for the requests it serves, the
+// calls it makes to other services and its database operations. The W3C
+// traceparent header carries the trace from service to service alongside the
+// correlation ID, and events and audit entries record both, so one payment
+// can be followed from orchestration through risk, consent and the router.
+// Spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set. Without an endpoint trace IDs
+// are still assigned and propagated, so logs and records can be matched.
+
+// TracerName names the tracer the platform's spans are created with
+const TracerName = "github.com/example/agent-payments"
+
+// TraceIDHeader carries the trace ID of a request back to the caller
+const TraceIDHeader = "X-Trace-ID"
+
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// InitTracing installs the tracer provider of a service and returns the
+// function flushing its spans on shutdown. Sampling follows
+// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG.
+func InitTracing(serviceName string) (func(context.Context) error, error) {
+	ctx := context.Background()
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tracing resource: %w", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	} else {
+		Info("No OTLP endpoint configured - spans of %s are not exported", serviceName)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span under the span of ctx, if any
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan ends a span, marking it failed if err is not nil
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or "" if none
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// TraceParent returns the W3C traceparent of the span of ctx, or "" if none
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextWithTraceParent returns ctx continuing the trace of a W3C
+// traceparent, such as one recorded on an event
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns ctx carrying a correlation ID
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by ctx, or ""
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
+
+// InjectTraceHeaders sets the traceparent and correlation ID headers of an
+// outgoing request from ctx
+func InjectTraceHeaders(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+	if correlationID := CorrelationIDFromContext(ctx); correlationID != "" {
+		header.Set(CorrelationIDHeader, correlationID)
+	}
+}
+
+// TracingMiddleware starts a server span for each request, continuing the
+// trace of an incoming traceparent header. Handlers reach the span through
+// c.Request.Context(). It runs after CorrelationIDMiddleware, so the span
+// records the correlation ID.
+func TracingMiddleware() gin.HandlerFunc {
+	tracer := otel.Tracer(TracerName)
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("correlation.id", GetCorrelationID(c)),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		if traceID := TraceID(ctx); traceID != "" {
+			c.Set("traceID", traceID)
+			c.Header(TraceIDHeader, traceID)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
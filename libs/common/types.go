@@ -28,10 +28,12 @@ type APIError struct {
 
 // Meta represents pagination and metadata for list responses
 type Meta struct {
-	Page       int `json:"page,omitempty"`
-	Limit      int `json:"limit,omitempty"`
-	Total      int `json:"total,omitempty"`
-	TotalPages int `json:"totalPages,omitempty"`
+	Page       int  `json:"page,omitempty"`
+	Limit      int  `json:"limit,omitempty"`
+	Total      int  `json:"total,omitempty"`
+	TotalPages int  `json:"totalPages,omitempty"`
+	Offset     int  `json:"offset,omitempty"`
+	HasMore    bool `json:"hasMore,omitempty"` // More items follow this page
 }
 
 // ListResponse represents a paginated list response
@@ -96,7 +98,10 @@ func NewValidationErrorResponse(errors []ValidationError) *APIResponse {
 
 // NewListResponse creates a paginated list response
 func NewListResponse(items []interface{}, page, limit, total int) *ListResponse {
-	totalPages := (total + limit - 1) / limit // Ceiling division
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (total + limit - 1) / limit // Ceiling division
+	}
 	return &ListResponse{
 		Items: items,
 		Meta: Meta{
@@ -44,6 +44,16 @@ func GetEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// GetEnvAsFloat gets an environment variable as float with a fallback value
+func GetEnvAsFloat(key string, fallback float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return fallback
+}
+
 // GetEnvAsBool gets an environment variable as boolean with a fallback value
 func GetEnvAsBool(key string, fallback bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CreateParty registers a party
+func (c *Client) CreateParty(ctx context.Context, req *CreatePartyRequest, options ...CallOption) (*Party, error) {
+	var party Party
+	if err := c.do(ctx, http.MethodPost, "/v1/parties", nil, req, &party, options); err != nil {
+		return nil, err
+	}
+	return &party, nil
+}
+
+// GetParty returns a party
+func (c *Client) GetParty(ctx context.Context, partyID string, options ...CallOption) (*Party, error) {
+	var party Party
+	if err := c.do(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(partyID), nil, nil, &party, options); err != nil {
+		return nil, err
+	}
+	return &party, nil
+}
+
+// SubmitKYC submits a party's attributes and documents for verification.
+// The submission returned is verified, rejected or pending a reviewer.
+func (c *Client) SubmitKYC(ctx context.Context, partyID string, req *SubmitKYCRequest, options ...CallOption) (*KYCSubmission, error) {
+	var submission KYCSubmission
+	if err := c.do(ctx, http.MethodPost, "/v1/parties/"+url.PathEscape(partyID)+"/kyc", nil, req, &submission, options); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetKYCStatus returns a party's verification status and its submissions
+func (c *Client) GetKYCStatus(ctx context.Context, partyID string, options ...CallOption) (*KYCStatus, error) {
+	var status KYCStatus
+	if err := c.do(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(partyID)+"/kyc", nil, nil, &status, options); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CreateAgent registers an agent
+func (c *Client) CreateAgent(ctx context.Context, req *CreateAgentRequest, options ...CallOption) (*Agent, error) {
+	var agent Agent
+	if err := c.do(ctx, http.MethodPost, "/v1/agents", nil, req, &agent, options); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// GetAgent returns an agent
+func (c *Client) GetAgent(ctx context.Context, agentID string, options ...CallOption) (*Agent, error) {
+	var agent Agent
+	if err := c.do(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(agentID), nil, nil, &agent, options); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// ListAgents returns the agents of an owner party, or all agents the caller
+// may see when ownerPartyID is empty
+func (c *Client) ListAgents(ctx context.Context, ownerPartyID string, options ...CallOption) ([]Agent, error) {
+	query := url.Values{}
+	if ownerPartyID != "" {
+		query.Set("ownerPartyId", ownerPartyID)
+	}
+	var list struct {
+		Agents []Agent `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/agents", query, nil, &list, options); err != nil {
+		return nil, err
+	}
+	return list.Agents, nil
+}
+
+// SuspendAgent stops an agent's payments until ActivateAgent is called.
+// Payments it started and not yet executed fail.
+func (c *Client) SuspendAgent(ctx context.Context, agentID, reason string, options ...CallOption) (*Agent, error) {
+	return c.changeAgentStatus(ctx, agentID, "suspend", reason, options)
+}
+
+// ActivateAgent lets a suspended agent pay again
+func (c *Client) ActivateAgent(ctx context.Context, agentID, reason string, options ...CallOption) (*Agent, error) {
+	return c.changeAgentStatus(ctx, agentID, "activate", reason, options)
+}
+
+// DecommissionAgent retires an agent for good and revokes its credentials
+func (c *Client) DecommissionAgent(ctx context.Context, agentID, reason string, options ...CallOption) (*Agent, error) {
+	return c.changeAgentStatus(ctx, agentID, "decommission", reason, options)
+}
+
+func (c *Client) changeAgentStatus(ctx context.Context, agentID, action, reason string, options []CallOption) (*Agent, error) {
+	var agent Agent
+	body := map[string]string{"reason": reason}
+	if err := c.do(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(agentID)+"/"+action, nil, body, &agent, options); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// CreateConsent grants an agent consent to pay
+func (c *Client) CreateConsent(ctx context.Context, req *CreateConsentRequest, options ...CallOption) (*Consent, error) {
+	var consent Consent
+	if err := c.do(ctx, http.MethodPost, "/v1/consents", nil, req, &consent, options); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// GetConsent returns a consent
+func (c *Client) GetConsent(ctx context.Context, consentID string, options ...CallOption) (*Consent, error) {
+	var consent Consent
+	if err := c.do(ctx, http.MethodGet, "/v1/consents/"+url.PathEscape(consentID), nil, nil, &consent, options); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// ListConsents returns a page of consents
+func (c *Client) ListConsents(ctx context.Context, opts ConsentListOptions, options ...CallOption) (*Page[Consent], error) {
+	var page Page[Consent]
+	if err := c.do(ctx, http.MethodGet, "/v1/consents", opts.query(), nil, &page, options); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// RevokeConsent revokes a consent, stopping or moving the payments in flight
+// under it
+func (c *Client) RevokeConsent(ctx context.Context, consentID, reason string, options ...CallOption) (*ConsentRevocation, error) {
+	var revocation ConsentRevocation
+	body := map[string]string{"reason": reason}
+	if err := c.do(ctx, http.MethodPut, "/v1/consents/"+url.PathEscape(consentID)+"/revoke", nil, body, &revocation, options); err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// UpdateConsent changes a consent's terms. The consent returned is its next
+// version; the version updated is kept unchanged but stops authorizing
+// payments.
+func (c *Client) UpdateConsent(ctx context.Context, consentID string, req *UpdateConsentRequest, options ...CallOption) (*Consent, error) {
+	var consent Consent
+	if err := c.do(ctx, http.MethodPut, "/v1/consents/"+url.PathEscape(consentID), nil, req, &consent, options); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// ConsentVersions lists every version of the consent a version belongs to,
+// the first version first
+func (c *Client) ConsentVersions(ctx context.Context, consentID string, options ...CallOption) ([]Consent, error) {
+	var page Page[Consent]
+	if err := c.do(ctx, http.MethodGet, "/v1/consents/"+url.PathEscape(consentID)+"/versions", nil, nil, &page, options); err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// RenewConsent replaces a consent with its next version, expiring at
+// expiresAt or, when nil, after the consent's original term. The consent
+// returned is the successor; the renewed one stops authorizing payments.
+func (c *Client) RenewConsent(ctx context.Context, consentID string, expiresAt *time.Time, options ...CallOption) (*Consent, error) {
+	var consent Consent
+	body := map[string]*time.Time{}
+	if expiresAt != nil {
+		body["expiresAt"] = expiresAt
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/consents/"+url.PathEscape(consentID)+"/renew", nil, body, &consent, options); err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// InitiatePayment starts a payment. The payment returned may still be
+// processing; GetPaymentStatus follows it until it is final.
+func (c *Client) InitiatePayment(ctx context.Context, req *PaymentRequest, options ...CallOption) (*Payment, error) {
+	var payment Payment
+	if err := c.do(ctx, http.MethodPost, "/v1/payments", nil, req, &payment, options); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetPaymentStatus returns a payment with its workflow's current status and
+// steps
+func (c *Client) GetPaymentStatus(ctx context.Context, paymentID string, options ...CallOption) (*Payment, error) {
+	var payment Payment
+	if err := c.do(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(paymentID), nil, nil, &payment, options); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListPayments returns a page of payments
+func (c *Client) ListPayments(ctx context.Context, opts PaymentListOptions, options ...CallOption) (*Page[Payment], error) {
+	var page Page[Payment]
+	if err := c.do(ctx, http.MethodGet, "/v1/payments", opts.query(), nil, &page, options); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CancelPayment stops a payment that is not final yet
+func (c *Client) CancelPayment(ctx context.Context, paymentID, reason string, options ...CallOption) (*Payment, error) {
+	var payment Payment
+	body := map[string]string{"reason": reason}
+	if err := c.do(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(paymentID)+"/cancel", nil, body, &payment, options); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// ListTransactions returns a page of ledger transactions
+func (c *Client) ListTransactions(ctx context.Context, opts TransactionListOptions, options ...CallOption) (*Page[Transaction], error) {
+	var page Page[Transaction]
+	if err := c.do(ctx, http.MethodGet, "/v1/transactions", opts.query(), nil, &page, options); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Transactions iterates over the ledger transactions opts select, fetching
+// pages as it goes. Iteration stops after an error, which is yielded last.
+func (c *Client) Transactions(ctx context.Context, opts TransactionListOptions, options ...CallOption) iter.Seq2[Transaction, error] {
+	return all(&opts.ListOptions, func() (*Page[Transaction], error) {
+		return c.ListTransactions(ctx, opts, options...)
+	})
+}
+
+// Payments iterates over the payments opts select, like Transactions
+func (c *Client) Payments(ctx context.Context, opts PaymentListOptions, options ...CallOption) iter.Seq2[Payment, error] {
+	return all(&opts.ListOptions, func() (*Page[Payment], error) {
+		return c.ListPayments(ctx, opts, options...)
+	})
+}
+
+// Consents iterates over the consents opts select, like Transactions
+func (c *Client) Consents(ctx context.Context, opts ConsentListOptions, options ...CallOption) iter.Seq2[Consent, error] {
+	return all(&opts.ListOptions, func() (*Page[Consent], error) {
+		return c.ListConsents(ctx, opts, options...)
+	})
+}
+
+// all iterates over the items of a listing, fetching the page at the
+// offset in opts and moving the offset past it while more follow
+func all[T any](opts *ListOptions, fetch func() (*Page[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		offset := opts.Offset
+		defer func() { opts.Offset = offset }()
+		for {
+			page, err := fetch()
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !page.Meta.HasMore || len(page.Items) == 0 {
+				return
+			}
+			opts.Offset += len(page.Items)
+		}
+	}
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	set(query, "from", o.From)
+	set(query, "to", o.To)
+	set(query, "sort", o.Sort)
+	if o.Ascending {
+		query.Set("order", "asc")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return query
+}
+
+func (o TransactionListOptions) query() url.Values {
+	query := o.ListOptions.query()
+	set(query, "agentId", o.AgentID)
+	set(query, "status", o.Status)
+	set(query, "referenceId", o.ReferenceID)
+	set(query, "book", o.Book)
+	return query
+}
+
+func (o ConsentListOptions) query() url.Values {
+	query := o.ListOptions.query()
+	set(query, "agentId", o.AgentID)
+	set(query, "ownerPartyId", o.OwnerPartyID)
+	if o.Revoked != nil {
+		query.Set("revoked", strconv.FormatBool(*o.Revoked))
+	}
+	return query
+}
+
+func (o PaymentListOptions) query() url.Values {
+	query := o.ListOptions.query()
+	set(query, "agentId", o.AgentID)
+	set(query, "status", o.Status)
+	set(query, "rail", o.Rail)
+	set(query, "counterparty", o.Counterparty)
+	set(query, "consentId", o.ConsentID)
+	set(query, "category", o.Category)
+	return query
+}
+
+// set adds a query parameter unless its value is empty
+func set(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
@@ -0,0 +1,408 @@
+// Package client is the Go SDK of the Agent Payment Platform's API.
+//
+// A Client calls the API through the gateway with a party's API key or an
+// access token:
+//
+//	c := client.New("https://api.example.com", client.WithAPIKey(key))
+//	payment, err := c.InitiatePayment(ctx, &client.PaymentRequest{
+//		AgentID:      agentID,
+//		Amount:       1500,
+//		Currency:     "USD",
+//		Counterparty: "vendor@example.com",
+//	})
+//
+// Every call takes a context, which bounds the call including its retries.
+// Failed calls are retried with jittered exponential backoff when a repeat
+// is safe: reads, and writes that carry an Idempotency-Key. Writes get a
+// fresh key unless one is given with IdempotencyKey, and keep it across
+// their retries. Errors the API answers with are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is sent in the User-Agent of the SDK's requests
+const Version = "1.0.0"
+
+// IdempotencyKeyHeader carries the key that makes a write safe to repeat
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	token      string
+	userAgent  string
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates with a party's API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithToken authenticates with an access token from POST /v1/auth/token
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient sends requests with an HTTP client other than the default,
+// which times out each attempt after 30 seconds
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets how many times a failed call is retried (2) and the
+// backoff before the first retry (200ms), which doubles up to maxDelay (5s)
+func WithRetries(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+// WithUserAgent prefixes the User-Agent of requests, identifying the
+// integration to the platform's operators
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent + " " + c.userAgent }
+}
+
+// New creates a client of the API served at baseURL, the gateway's address
+// without the /v1 prefix
+func New(baseURL string, options ...Option) *Client {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		parsed = &url.URL{Path: baseURL}
+	}
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "agent-payments-go/" + Version,
+		maxRetries: 2,
+		baseDelay:  200 * time.Millisecond,
+		maxDelay:   5 * time.Second,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// CallOption configures a single call
+type CallOption func(*call)
+
+type call struct {
+	idempotencyKey string
+	header         http.Header
+	signingKey     ed25519.PrivateKey
+	signingKeyID   string
+}
+
+// IdempotencyKey sends a write with the given key instead of a generated
+// one, so that repeating the call, say after a restart, repeats the key
+func IdempotencyKey(key string) CallOption {
+	return func(c *call) { c.idempotencyKey = key }
+}
+
+// Header adds a header to the call's request
+func Header(name, value string) CallOption {
+	return func(c *call) { c.header.Add(name, value) }
+}
+
+// AgentSignatureHeader carries a DID agent's signature of a request
+const AgentSignatureHeader = "X-Agent-Signature"
+
+// SignedBy signs the call's request with the private key of a verification
+// method of the agent's DID document, such as
+// did:agentpay:<agent ID>#<credential ID>. Each attempt is signed anew.
+func SignedBy(privateKey ed25519.PrivateKey, keyID string) CallOption {
+	return func(c *call) { c.signingKey, c.signingKeyID = privateKey, keyID }
+}
+
+// signature returns the X-Agent-Signature of a request: an Ed25519
+// signature of its method, path, signing time and body digest
+func signature(privateKey ed25519.PrivateKey, keyID, method, path string, created time.Time, body []byte) string {
+	digest := sha256.Sum256(body)
+	payload := strings.Join([]string{"agentpay-request-v1", method, path, strconv.FormatInt(created.Unix(), 10), hex.EncodeToString(digest[:])}, "\n")
+	signed := ed25519.Sign(privateKey, []byte(payload))
+	return fmt.Sprintf(`keyId="%s",created=%d,signature="%s"`, keyID, created.Unix(), base64.RawURLEncoding.EncodeToString(signed))
+}
+
+// Error is an error the API answered with
+type Error struct {
+	StatusCode int
+	Code       string // Such as VALIDATION_ERROR or NOT_FOUND
+	Message    string
+	Details    string
+	Fields     []FieldError // Per field, for VALIDATION_ERROR
+	RequestID  string       // X-Request-ID of the response, for support
+}
+
+// FieldError is a validation failure of a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("agent-payments: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.Details != "" {
+		message += ": " + e.Details
+	}
+	for _, field := range e.Fields {
+		message += fmt.Sprintf("; %s %s", field.Field, field.Message)
+	}
+	return message
+}
+
+// IsNotFound reports whether err is an API answer that the resource does
+// not exist
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Call sends a request to any endpoint, for those without a method of their
+// own, such as Call(ctx, http.MethodPost, "/v1/payments/"+id+"/refunds",
+// nil, body, &refund). body is sent as JSON unless nil, and the data of the
+// response is decoded into out unless nil; a *[]byte receives the body as it
+// is, for endpoints answering with other than JSON. It is retried, and a POST
+// given an Idempotency-Key, like every other call.
+func (c *Client) Call(ctx context.Context, method, path string, query url.Values, body, out interface{}, options ...CallOption) error {
+	return c.do(ctx, method, path, query, body, out, options)
+}
+
+// do sends a call, retrying it while it fails and a repeat is safe, and
+// decodes the data of the response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}, options []CallOption) error {
+	settings := &call{header: http.Header{}}
+	for _, option := range options {
+		option(settings)
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("agent-payments: encoding request: %w", err)
+		}
+	}
+	if settings.idempotencyKey == "" && method == http.MethodPost {
+		settings.idempotencyKey = newIdempotencyKey()
+	}
+
+	// path has its parameters escaped already, so it extends the escaped path
+	target := *c.baseURL
+	target.RawPath = target.EscapedPath() + path
+	unescaped, err := url.PathUnescape(target.RawPath)
+	if err != nil {
+		return fmt.Errorf("agent-payments: %w", err)
+	}
+	target.Path = unescaped
+	target.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("agent-payments: %w", err)
+		}
+		if payload == nil {
+			req.Body = http.NoBody
+		}
+		c.authorize(req, settings)
+		if settings.signingKey != nil {
+			req.Header.Set(AgentSignatureHeader, signature(settings.signingKey, settings.signingKeyID, method, path, time.Now(), payload))
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		var delay time.Duration
+		if err == nil {
+			err = decode(resp, out)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				delay = retryAfter(resp)
+			}
+		}
+		if err == nil || attempt >= c.maxRetries || !c.retryable(req, resp, err) {
+			return err
+		}
+		if delay == 0 || delay > c.maxDelay {
+			delay = c.backoff(attempt)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("agent-payments: %w (after %v)", ctx.Err(), err)
+		}
+	}
+}
+
+func (c *Client) authorize(req *http.Request, settings *call) {
+	for name, values := range settings.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if settings.idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, settings.idempotencyKey)
+	}
+}
+
+// response is the envelope of the API's responses. A few endpoints answer
+// with the resource itself, or an error message alone.
+type response struct {
+	Success *bool           `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// decode reads a response, decoding its data into out or returning the
+// error it answers with
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return fmt.Errorf("agent-payments: reading response: %w", err)
+	}
+	if raw, ok := out.(*[]byte); ok && resp.StatusCode < 300 {
+		*raw = data
+		return nil
+	}
+
+	var envelope response
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &envelope); err != nil && resp.StatusCode < 300 {
+			return fmt.Errorf("agent-payments: decoding response: %w", err)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return apiError(resp, data, envelope)
+	}
+	if envelope.Success == nil {
+		envelope.Data = data
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("agent-payments: decoding response: %w", err)
+	}
+	return nil
+}
+
+func apiError(resp *http.Response, data []byte, envelope response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Code:       strings.ToUpper(strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "_")),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	var detail struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details string `json:"details"`
+	}
+	switch {
+	case json.Unmarshal(envelope.Error, &detail) == nil && detail.Code != "":
+		apiErr.Code = detail.Code
+		apiErr.Message = detail.Message
+		apiErr.Details = detail.Details
+		var fields struct {
+			Errors []FieldError `json:"errors"`
+		}
+		if json.Unmarshal(envelope.Data, &fields) == nil {
+			apiErr.Fields = fields.Errors
+		}
+	case json.Unmarshal(envelope.Error, &apiErr.Message) == nil:
+	default:
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
+
+// retryable reports whether a failed call is safe and worth sending again:
+// it did not get an answer or the answer was a transient failure, and
+// either repeating it has no further effect or it never reached the API
+func (c *Client) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return false
+		}
+	} else if resp != nil {
+		// A response that did not decode is not made better by repeating
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// backoff is the jittered delay before the retry following an attempt, so
+// clients that failed together do not retry in step
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.baseDelay << attempt
+	if delay > c.maxDelay || delay <= 0 {
+		delay = c.maxDelay
+	}
+	return delay/2 + time.Duration(mathrand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter reads the seconds a rate limited call is to wait
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds)) * time.Second
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
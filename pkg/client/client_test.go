@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer serves handler and returns a client of it that retries without
+// waiting, and the requests it received
+func testServer(t *testing.T, handler http.HandlerFunc) (*Client, func() []*http.Request) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Clone(context.Background()))
+		mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	c := New(server.URL, WithAPIKey("test-key"), WithRetries(2, time.Millisecond, time.Millisecond))
+	return c, func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), received...)
+	}
+}
+
+// failing answers status to the first failures requests and then a payment
+func failing(status, failures int) http.HandlerFunc {
+	var mu sync.Mutex
+	attempts := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if attempt <= failures {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"success":false,"error":{"code":"UNAVAILABLE","message":"try again"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"data":{"id":"pay-1","status":"completed"}}`)
+	}
+}
+
+func TestWritesKeepTheirIdempotencyKeyAcrossRetries(t *testing.T) {
+	c, received := testServer(t, failing(http.StatusServiceUnavailable, 2))
+
+	payment, err := c.InitiatePayment(context.Background(), &PaymentRequest{AgentID: "agent-1", Amount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.ID != "pay-1" {
+		t.Fatalf("payment: got %+v", payment)
+	}
+	requests := received()
+	if len(requests) != 3 {
+		t.Fatalf("attempts: got %d, want 3", len(requests))
+	}
+	key := requests[0].Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		t.Fatal("write sent without an idempotency key")
+	}
+	for i, req := range requests {
+		if got := req.Header.Get(IdempotencyKeyHeader); got != key {
+			t.Errorf("attempt %d: key %q, want %q", i+1, got, key)
+		}
+		if got := req.Header.Get("X-API-Key"); got != "test-key" {
+			t.Errorf("attempt %d: API key %q", i+1, got)
+		}
+	}
+
+	// Separate writes get separate keys, unless the caller gives one
+	c.InitiatePayment(context.Background(), &PaymentRequest{AgentID: "agent-1", Amount: 10})
+	c.InitiatePayment(context.Background(), &PaymentRequest{AgentID: "agent-1", Amount: 10}, IdempotencyKey("order-42"))
+	requests = received()
+	if got := requests[3].Header.Get(IdempotencyKeyHeader); got == key || got == "" {
+		t.Errorf("second write: key %q", got)
+	}
+	if got := requests[4].Header.Get(IdempotencyKeyHeader); got != "order-42" {
+		t.Errorf("given key: got %q, want order-42", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		attempts int
+	}{
+		{"reads retry transient failures", http.MethodGet, http.StatusBadGateway, 3},
+		{"rate limited calls retry", http.MethodGet, http.StatusTooManyRequests, 3},
+		{"writes without a key are not repeated", http.MethodPatch, http.StatusServiceUnavailable, 1},
+		{"server errors are not transient", http.MethodGet, http.StatusInternalServerError, 1},
+		{"client errors are final", http.MethodPost, http.StatusConflict, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, received := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"success":false,"error":{"code":"FAILED","message":"failed"}}`)
+			})
+			err := c.Call(context.Background(), tt.method, "/v1/payments/pay-1", nil, nil, nil)
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("got %v, want a %d *Error", err, tt.status)
+			}
+			if got := len(received()); got != tt.attempts {
+				t.Fatalf("attempts: got %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestContextBoundsRetries(t *testing.T) {
+	c, received := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c.baseDelay, c.maxDelay = time.Minute, time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := c.Call(ctx, http.MethodGet, "/v1/payments", nil, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if got := len(received()); got != 1 {
+		t.Fatalf("attempts: got %d, want 1", got)
+	}
+}
+
+func TestErrorsAreDecoded(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        Error
+	}{
+		{
+			name:   "envelope with field errors",
+			status: http.StatusBadRequest,
+			body:   `{"success":false,"error":{"code":"VALIDATION_ERROR","message":"Invalid request","details":"2 fields"},"data":{"errors":[{"field":"amount","message":"must be positive"},{"field":"agentId","message":"is required"}]}}`,
+			want: Error{StatusCode: http.StatusBadRequest, Code: "VALIDATION_ERROR", Message: "Invalid request", Details: "2 fields", RequestID: "req-1",
+				Fields: []FieldError{{Field: "amount", Message: "must be positive"}, {Field: "agentId", Message: "is required"}}},
+		},
+		{
+			name:   "message alone",
+			status: http.StatusUnauthorized,
+			body:   `{"error":"API key required"}`,
+			want:   Error{StatusCode: http.StatusUnauthorized, Code: "UNAUTHORIZED", Message: "API key required", RequestID: "req-1"},
+		},
+		{
+			name:        "not JSON",
+			status:      http.StatusNotFound,
+			contentType: "text/plain",
+			body:        "404 page not found\n",
+			want:        Error{StatusCode: http.StatusNotFound, Code: "NOT_FOUND", Message: "404 page not found", RequestID: "req-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-ID", "req-1")
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			_, err := c.GetPaymentStatus(context.Background(), "pay-1")
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want *Error", err)
+			}
+			if fmt.Sprintf("%+v", *apiErr) != fmt.Sprintf("%+v", tt.want) {
+				t.Fatalf("got %+v, want %+v", *apiErr, tt.want)
+			}
+			if IsNotFound(err) != (tt.status == http.StatusNotFound) {
+				t.Fatalf("IsNotFound: got %v", IsNotFound(err))
+			}
+		})
+	}
+}
+
+func TestResponsesWithoutEnvelopeAreDecoded(t *testing.T) {
+	c, received := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"pay-1","status":"completed"}`)
+	})
+	payment, err := c.GetPaymentStatus(context.Background(), "pay/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.ID != "pay-1" || payment.Status != "completed" {
+		t.Fatalf("payment: got %+v", payment)
+	}
+	if got := received()[0].URL.EscapedPath(); got != "/v1/payments/pay%2F1" {
+		t.Fatalf("path: got %s", got)
+	}
+}
+
+// listing serves total payments a page at a time, by offset and limit
+func listing(total int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		items := ""
+		for i := offset; i < offset+limit && i < total; i++ {
+			if items != "" {
+				items += ","
+			}
+			items += fmt.Sprintf(`{"id":"pay-%d"}`, i)
+		}
+		fmt.Fprintf(w, `{"success":true,"data":{"items":[%s],"meta":{"limit":%d,"offset":%d,"total":%d,"hasMore":%t}}}`,
+			items, limit, offset, total, offset+limit < total)
+	}
+}
+
+func TestPaymentsIteratesOverEveryPage(t *testing.T) {
+	c, received := testServer(t, listing(5))
+
+	opts := PaymentListOptions{ListOptions: ListOptions{Limit: 2}, Status: "completed"}
+	var ids []string
+	for payment, err := range c.Payments(context.Background(), opts) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, payment.ID)
+	}
+	if fmt.Sprint(ids) != "[pay-0 pay-1 pay-2 pay-3 pay-4]" {
+		t.Fatalf("payments: got %v", ids)
+	}
+
+	requests := received()
+	if len(requests) != 3 {
+		t.Fatalf("pages: got %d, want 3", len(requests))
+	}
+	for i, req := range requests {
+		query := req.URL.Query()
+		if want := strconv.Itoa(2 * i); i > 0 && query.Get("offset") != want {
+			t.Errorf("page %d: offset %q, want %s", i+1, query.Get("offset"), want)
+		}
+		if query.Get("limit") != "2" || query.Get("status") != "completed" {
+			t.Errorf("page %d: query %s", i+1, req.URL.RawQuery)
+		}
+	}
+}
+
+func TestPaymentsStopsAtTheFirstError(t *testing.T) {
+	serve := listing(5)
+	c, received := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "2" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"success":false,"error":{"code":"FORBIDDEN","message":"no"}}`)
+			return
+		}
+		serve(w, r)
+	})
+
+	var ids []string
+	var errs []error
+	for payment, err := range c.Payments(context.Background(), PaymentListOptions{ListOptions: ListOptions{Limit: 2}}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, payment.ID)
+	}
+	if fmt.Sprint(ids) != "[pay-0 pay-1]" || len(errs) != 1 {
+		t.Fatalf("got %v and errors %v", ids, errs)
+	}
+	if len(received()) != 2 {
+		t.Fatalf("pages: got %d, want 2", len(received()))
+	}
+
+	// Breaking out of the loop fetches no further pages
+	for range c.Payments(context.Background(), PaymentListOptions{ListOptions: ListOptions{Limit: 2}}) {
+		break
+	}
+	if len(received()) != 3 {
+		t.Fatalf("pages after break: got %d, want 3", len(received()))
+	}
+}
@@ -0,0 +1,3799 @@
+// Code generated by cmd/openapi-gen from api/openapi.json; DO NOT EDIT.
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/example/agent-payments/pkg/client"
+)
+
+// GET /v1/compliance/reviews
+func Example_listPendingReviews() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/compliance/reviews", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/compliance/reviews/{id}
+func Example_reviewScreening() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/compliance/reviews/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "decision": "decision"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/compliance/screen
+func Example_screenCounterparty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/compliance/screen", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "counterparty": "counterparty"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/compliance/screenings
+func Example_listScreenings() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/compliance/screenings", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/compliance/screenings/{id}
+func Example_getScreening() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/compliance/screenings/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/compliance/watchlist
+func Example_listWatchlistEntries() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/compliance/watchlist", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/compliance/watchlist
+func Example_createWatchlistEntry() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/compliance/watchlist", nil, json.RawMessage(`{
+	  "listType": "listType",
+	  "name": "name"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/compliance/watchlist/{id}
+func Example_updateWatchlistEntry() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/compliance/watchlist/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "active": false,
+	  "country": "country",
+	  "name": "name",
+	  "program": "program",
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/compliance/watchlist/{id}
+func Example_deactivateWatchlistEntry() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/compliance/watchlist/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/approvals
+func Example_listApprovals() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/approvals", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/approvals/{id}
+func Example_getApproval() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/approvals/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/approvals/{id}/approve
+func Example_approvePayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/approvals/"+url.PathEscape(id)+"/approve", nil, json.RawMessage(`{
+	  "notes": "notes"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/approvals/{id}/reject
+func Example_rejectPayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/approvals/"+url.PathEscape(id)+"/reject", nil, json.RawMessage(`{
+	  "notes": "notes"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/consents
+func Example_listConsents() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	for consent, err := range c.Consents(ctx, client.ConsentListOptions{AgentID: os.Getenv("AGENT_ID")}) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%+v\n", consent)
+	}
+}
+
+// POST /v1/consents
+func Example_createConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	consent, err := c.CreateConsent(ctx, &client.CreateConsentRequest{AgentID: os.Getenv("AGENT_ID"), OwnerPartyID: os.Getenv("PARTY_ID"), Rails: []string{"ach", "card"}, Limits: client.ConsentLimits{SingleTxnUSD: 500, DailyUSD: 2000, Velocity: client.VelocityCaps{MaxTxnPerHour: 10}}, CosignRule: client.CosignRule{ThresholdUSD: 1000, ApproverGroup: "finance"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", consent)
+}
+
+// POST /v1/consents/validate
+func Example_validateConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/consents/validate", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100,
+	  "counterparty": "counterparty",
+	  "ownerPartyId": "ownerPartyId",
+	  "rail": "rail"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/consents/{id}
+func Example_getConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	consent, err := c.GetConsent(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", consent)
+}
+
+// PUT /v1/consents/{id}
+func Example_updateConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	consent, err := c.UpdateConsent(ctx, id, &client.UpdateConsentRequest{Rails: &[]string{"ach", "card", "rtp"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", consent)
+}
+
+// POST /v1/consents/{id}/renew
+func Example_renewConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	consent, err := c.RenewConsent(ctx, id, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", consent)
+}
+
+// PUT /v1/consents/{id}/revoke
+func Example_revokeConsent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	revocation, err := c.RevokeConsent(ctx, id, "Agent retired")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", revocation)
+}
+
+// GET /v1/consents/{id}/versions
+func Example_listConsentVersions() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	versions, err := c.ConsentVersions(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", versions)
+}
+
+// GET /v1/parties/{id}/spending
+func Example_getSpendingReport() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(id)+"/spending", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/parties/{id}/spending-limits
+func Example_listSpendingLimits() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(id)+"/spending-limits", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/parties/{id}/spending-limits/{period}
+func Example_setSpendingLimit() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	period := os.Getenv("PERIOD")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/parties/"+url.PathEscape(id)+"/spending-limits/"+url.PathEscape(period), nil, json.RawMessage(`{
+	  "limitUSD": 1
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/parties/{id}/spending-limits/{period}
+func Example_deleteSpendingLimit() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	period := os.Getenv("PERIOD")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/parties/"+url.PathEscape(id)+"/spending-limits/"+url.PathEscape(period), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/counterparties
+func Example_listCounterparties() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/counterparties", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/counterparties
+func Example_createCounterparty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/counterparties", nil, json.RawMessage(`{
+	  "bankAccountId": "bankAccountId",
+	  "country": "country",
+	  "email": "email",
+	  "listReason": "listReason",
+	  "listStatus": "listStatus",
+	  "name": "name",
+	  "partyId": "partyId",
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/counterparties/{id}
+func Example_getCounterparty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/counterparties/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/counterparties/{id}
+func Example_updateCounterparty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/counterparties/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "bankAccountId": "bankAccountId",
+	  "country": "country",
+	  "email": "email",
+	  "listReason": "listReason",
+	  "listStatus": "listStatus",
+	  "name": "name",
+	  "partyId": "partyId",
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/counterparties/{id}
+func Example_deleteCounterparty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/counterparties/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/counterparties/{id}/kyc
+func Example_reviewCounterpartyKYC() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/counterparties/"+url.PathEscape(id)+"/kyc", nil, json.RawMessage(`{
+	  "kycStatus": "kycStatus"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/counterparty-accounts
+func Example_listCounterpartyAccounts() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/counterparty-accounts", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/counterparty-accounts
+func Example_createCounterpartyAccount() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/counterparty-accounts", nil, json.RawMessage(`{
+	  "counterparty": "counterparty",
+	  "holderName": "holderName",
+	  "partyId": "partyId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/counterparty-accounts/{id}
+func Example_getCounterpartyAccount() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/counterparty-accounts/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/counterparty-accounts/{id}
+func Example_disableCounterpartyAccount() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/counterparty-accounts/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/counterparty-accounts/{id}/verify
+func Example_confirmMicroDeposits() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/counterparty-accounts/"+url.PathEscape(id)+"/verify", nil, json.RawMessage(`{
+	  "amounts": [
+	    100
+	  ]
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/funding-sources
+func Example_listFundingSources() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/funding-sources", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/funding-sources
+func Example_linkFundingSource() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/funding-sources", nil, json.RawMessage(`{
+	  "partyId": "partyId",
+	  "publicToken": "publicToken"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/funding-sources/{id}
+func Example_getFundingSource() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/funding-sources/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/funding-sources/{id}
+func Example_disableFundingSource() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/funding-sources/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/funding-sources/{id}/debits
+func Example_achDebit() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/funding-sources/"+url.PathEscape(id)+"/debits", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/funding-sources/{id}/top-ups
+func Example_topUpWallet() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/funding-sources/"+url.PathEscape(id)+"/top-ups", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/funding-sources/{id}/transfers
+func Example_listFundingTransfers() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/funding-sources/"+url.PathEscape(id)+"/transfers", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/funding-sources/{id}/verify
+func Example_verifyFundingSource() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/funding-sources/"+url.PathEscape(id)+"/verify", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/funding/link-token
+func Example_createLinkToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/funding/link-token", nil, json.RawMessage(`{
+	  "partyId": "partyId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/graphql
+func Example_executeQueryGet() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/graphql", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/graphql
+func Example_executeQuery() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/graphql", nil, json.RawMessage(`{
+	  "operationName": "operationName",
+	  "query": "query"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/graphql/schema
+func Example_getSchema() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result []byte
+	err := c.Call(ctx, http.MethodGet, "/v1/graphql/schema", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(result)
+}
+
+// GET /v1/agents
+func Example_listAgents() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	agents, err := c.ListAgents(ctx, os.Getenv("PARTY_ID"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agents)
+}
+
+// POST /v1/agents
+func Example_createAgent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	agent, err := c.CreateAgent(ctx, &client.CreateAgentRequest{DisplayName: "Procurement agent", OwnerPartyID: os.Getenv("PARTY_ID"), IdentityMode: "did"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agent)
+}
+
+// GET /v1/agents/{id}
+func Example_getAgent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	agent, err := c.GetAgent(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agent)
+}
+
+// POST /v1/agents/{id}/activate
+func Example_activateAgent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	agent, err := c.ActivateAgent(ctx, id, "Investigation closed")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agent)
+}
+
+// GET /v1/agents/{id}/credentials
+func Example_listAgentCredentials() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(id)+"/credentials", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/credentials
+func Example_issueAgentCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/credentials", nil, json.RawMessage(`{
+	  "ttlSeconds": 1,
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/credentials/verify
+func Example_verifyAgentCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/credentials/verify", nil, json.RawMessage(`{
+	  "token": "token"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/agents/{id}/credentials/{credentialId}
+func Example_revokeAgentCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	credentialID := os.Getenv("CREDENTIAL_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/agents/"+url.PathEscape(id)+"/credentials/"+url.PathEscape(credentialID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/credentials/{credentialId}/rotate
+func Example_rotateAgentCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	credentialID := os.Getenv("CREDENTIAL_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/credentials/"+url.PathEscape(credentialID)+"/rotate", nil, json.RawMessage(`{
+	  "ttlSeconds": 1,
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/decommission
+func Example_decommissionAgent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	agent, err := c.DecommissionAgent(ctx, id, "Replaced by a new agent")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agent)
+}
+
+// GET /v1/agents/{id}/did.json
+func Example_getAgentDIDDocument() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(id)+"/did.json", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/did/challenges
+func Example_createDIDChallenge() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/did/challenges", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/did/challenges/{challengeId}
+func Example_answerDIDChallenge() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	challengeID := os.Getenv("CHALLENGE_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/did/challenges/"+url.PathEscape(challengeID), nil, json.RawMessage(`{
+	  "keyId": "keyId",
+	  "signature": "signature"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/did/keys
+func Example_registerDIDKey() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/did/keys", nil, json.RawMessage(`{
+	  "publicKey": "publicKey"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/agents/{id}/forecast
+func Example_getSpendingForecast() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(id)+"/forecast", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/agents/{id}/mandate-keys
+func Example_listMandateKeys() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(id)+"/mandate-keys", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/mandate-keys
+func Example_registerMandateKey() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/mandate-keys", nil, json.RawMessage(`{
+	  "publicKey": "publicKey"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/agents/{id}/mandate-keys/{keyId}
+func Example_revokeMandateKey() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	keyID := os.Getenv("KEY_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/agents/"+url.PathEscape(id)+"/mandate-keys/"+url.PathEscape(keyID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/agents/{id}/reputation-credentials
+func Example_listReputationCredentials() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/agents/"+url.PathEscape(id)+"/reputation-credentials", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/reputation-credentials
+func Example_issueReputationCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/reputation-credentials", nil, json.RawMessage(`{
+	  "ttlSeconds": 1
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/reputation-credentials/{credentialId}/revoke
+func Example_revokeReputationCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	credentialID := os.Getenv("CREDENTIAL_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/agents/"+url.PathEscape(id)+"/reputation-credentials/"+url.PathEscape(credentialID)+"/revoke", nil, json.RawMessage(`{
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/agents/{id}/suspend
+func Example_suspendAgent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	agent, err := c.SuspendAgent(ctx, id, "Investigating unusual spending")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", agent)
+}
+
+// DELETE /v1/api-keys/{id}
+func Example_revokeAPIKey() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/api-keys/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/auth/token
+func Example_issueToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/auth/token", nil, json.RawMessage(`{
+	  "ttlSeconds": 1
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/dids/{did}
+func Example_resolveDID() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	did := os.Getenv("DID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/dids/"+url.PathEscape(did), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/federation/issuers
+func Example_listTrustedIssuers() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/federation/issuers", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/federation/issuers
+func Example_createTrustedIssuer() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/federation/issuers", nil, json.RawMessage(`{
+	  "hostPartyId": "hostPartyId",
+	  "issuer": "issuer",
+	  "name": "name",
+	  "publicKeys": [
+	    {
+	      "crv": "crv",
+	      "kid": "kid",
+	      "kty": "kty",
+	      "x": "x",
+	      "y": "y"
+	    }
+	  ]
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/federation/issuers/{id}
+func Example_getTrustedIssuer() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/federation/issuers/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/federation/issuers/{id}
+func Example_updateTrustedIssuer() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/federation/issuers/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "revocationListUrl": "revocationListUrl",
+	  "status": "status"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/federation/issuers/{id}/revocations
+func Example_revokeFederatedCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/federation/issuers/"+url.PathEscape(id)+"/revocations", nil, json.RawMessage(`{
+	  "credentialId": "credentialId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/federation/verify
+func Example_verifyFederatedCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/federation/verify", nil, json.RawMessage(`{
+	  "token": "token"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/kyc/submissions
+func Example_listKYCSubmissions() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/kyc/submissions", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/kyc/submissions/{id}/review
+func Example_reviewKYCSubmission() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/kyc/submissions/"+url.PathEscape(id)+"/review", nil, json.RawMessage(`{
+	  "decision": "decision"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/oauth/introspect
+func Example_introspectToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/oauth/introspect", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/oauth/token
+func Example_issueAccessToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/oauth/token", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/parties
+func Example_createParty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	party, err := c.CreateParty(ctx, &client.CreatePartyRequest{Name: "Acme Corp", Type: "organization"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", party)
+}
+
+// GET /v1/parties/{id}
+func Example_getParty() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	party, err := c.GetParty(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", party)
+}
+
+// GET /v1/parties/{id}/api-keys
+func Example_listAPIKeys() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(id)+"/api-keys", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/parties/{id}/api-keys
+func Example_createAPIKey() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/parties/"+url.PathEscape(id)+"/api-keys", nil, json.RawMessage(`{
+	  "name": "name"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/parties/{id}/approver-groups/{group}/members
+func Example_listApproverGroupMembers() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	group := os.Getenv("GROUP")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(id)+"/approver-groups/"+url.PathEscape(group)+"/members", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/parties/{id}/approver-groups/{group}/members/{userId}
+func Example_addApproverGroupMember() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	group := os.Getenv("GROUP")
+	userID := os.Getenv("USER_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/parties/"+url.PathEscape(id)+"/approver-groups/"+url.PathEscape(group)+"/members/"+url.PathEscape(userID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/parties/{id}/approver-groups/{group}/members/{userId}
+func Example_removeApproverGroupMember() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	group := os.Getenv("GROUP")
+	userID := os.Getenv("USER_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/parties/"+url.PathEscape(id)+"/approver-groups/"+url.PathEscape(group)+"/members/"+url.PathEscape(userID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/parties/{id}/kyc
+func Example_getKYCStatus() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	status, err := c.GetKYCStatus(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", status)
+}
+
+// POST /v1/parties/{id}/kyc
+func Example_submitKYC() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	submission, err := c.SubmitKYC(ctx, id, &client.SubmitKYCRequest{Attributes: map[string]string{"legalName": "Acme Corp", "registrationNumber": "12345678", "country": "US"}, Documents: []client.KYCDocument{{Type: "certificate_of_incorporation", Reference: "kyc/acme/incorporation.pdf"}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", submission)
+}
+
+// GET /v1/parties/{id}/users
+func Example_listUsers() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/parties/"+url.PathEscape(id)+"/users", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/parties/{id}/users
+func Example_createUser() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/parties/"+url.PathEscape(id)+"/users", nil, json.RawMessage(`{
+	  "email": "someone@example.com",
+	  "name": "name"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/reputation/issuer
+func Example_getReputationIssuer() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/reputation/issuer", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/reputation/revocations
+func Example_listReputationRevocations() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/reputation/revocations", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/reputation/verify
+func Example_verifyReputationCredential() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/reputation/verify", nil, json.RawMessage(`{
+	  "credential": "credential"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/roles
+func Example_listRoles() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/roles", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/users/{id}
+func Example_getUser() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/users/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/users/{id}
+func Example_updateUser() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/users/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "name": "name",
+	  "status": "status"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/users/{id}/roles/{role}
+func Example_assignUserRole() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	role := os.Getenv("ROLE")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/users/"+url.PathEscape(id)+"/roles/"+url.PathEscape(role), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/users/{id}/roles/{role}
+func Example_revokeUserRole() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	role := os.Getenv("ROLE")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/users/"+url.PathEscape(id)+"/roles/"+url.PathEscape(role), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/accounts
+func Example_listAccounts() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/accounts", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/accounts
+func Example_createAccount() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/accounts", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "name": "name",
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/accounts/{id}
+func Example_getAccount() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/accounts/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/accounts/{id}/balance
+func Example_getAccountBalance() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/accounts/"+url.PathEscape(id)+"/balance", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/accounts/{id}/overdraft-policy
+func Example_updateOverdraftPolicy() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/accounts/"+url.PathEscape(id)+"/overdraft-policy", nil, json.RawMessage(`{
+	  "limit": 100,
+	  "policy": "policy"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/accounts/{id}/postings
+func Example_listAccountPostings() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/accounts/"+url.PathEscape(id)+"/postings", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/admin/migrations
+func Example_listMigrations() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/admin/migrations", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/admin/migrations/{name}/backfill
+func Example_startMigrationBackfill() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	name := os.Getenv("NAME")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/admin/migrations/"+url.PathEscape(name)+"/backfill", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/admin/migrations/{name}/pause
+func Example_pauseMigrationBackfill() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	name := os.Getenv("NAME")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/admin/migrations/"+url.PathEscape(name)+"/pause", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/admin/migrations/{name}/verify
+func Example_verifyMigration() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	name := os.Getenv("NAME")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/admin/migrations/"+url.PathEscape(name)+"/verify", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/anchors
+func Example_listAuditAnchors() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/anchors", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/audit/anchors
+func Example_createAuditAnchor() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/audit/anchors", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/anchors/{id}
+func Example_getAuditAnchor() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/anchors/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/changes/{resourceId}
+func Example_getAuditChangeHistory() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	resourceID := os.Getenv("RESOURCE_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/changes/"+url.PathEscape(resourceID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/compliance
+func Example_getAuditComplianceReport() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/compliance", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/entries/{id}/proof
+func Example_getAuditEntryProof() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/entries/"+url.PathEscape(id)+"/proof", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/events
+func Example_listAuditEvents() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/events", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/audit/events
+func Example_logAuditEvent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/audit/events", nil, json.RawMessage(`{
+	  "action": "action",
+	  "description": "description",
+	  "eventType": "eventType"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/events/export
+func Example_exportAuditEvents() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/events/export", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/audit/proofs/verify
+func Example_verifyAuditProof() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/audit/proofs/verify", nil, json.RawMessage(`{
+	  "anchorId": "anchorId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/audit/summary
+func Example_getAuditSummary() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/audit/summary", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/balances
+func Example_getBalances() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/balances", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/balances/agent/{agentId}
+func Example_getAgentBalances() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	agentID := os.Getenv("AGENT_ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/balances/agent/"+url.PathEscape(agentID), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/balances/drift
+func Example_getBalanceDrift() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/balances/drift", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/balances/snapshots
+func Example_snapshotBalances() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/balances/snapshots", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/books/{book}/trial-balance
+func Example_getTrialBalance() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	book := os.Getenv("BOOK")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/books/"+url.PathEscape(book)+"/trial-balance", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/fx/conversions
+func Example_createConversion() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/fx/conversions", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amount": 100,
+	  "fromAccountId": "fromAccountId",
+	  "toAccountId": "toAccountId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fx/rates
+func Example_getFXRate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fx/rates", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/holds
+func Example_listHolds() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/holds", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/holds
+func Example_createHold() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/holds", nil, json.RawMessage(`{
+	  "accountId": "accountId",
+	  "agentId": "agentId",
+	  "amount": 100
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/holds/expire
+func Example_expireHolds() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/holds/expire", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/holds/{id}
+func Example_getHold() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/holds/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/holds/{id}/capture
+func Example_captureHold() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/holds/"+url.PathEscape(id)+"/capture", nil, json.RawMessage(`{
+	  "destinationAccountId": "destinationAccountId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/holds/{id}/release
+func Example_releaseHold() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/holds/"+url.PathEscape(id)+"/release", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/posting-rules
+func Example_listPostingRules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/posting-rules", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/posting-rules
+func Example_createPostingRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/posting-rules", nil, json.RawMessage(`{
+	  "sourceAccountId": "sourceAccountId",
+	  "targetAccountId": "targetAccountId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/posting-rules/{id}
+func Example_deletePostingRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/posting-rules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/reports/activity
+func Example_getActivityReport() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/reports/activity", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/transactions
+func Example_listTransactions() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	for transaction, err := range c.Transactions(ctx, client.TransactionListOptions{AgentID: os.Getenv("AGENT_ID")}) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%+v\n", transaction)
+	}
+}
+
+// POST /v1/transactions
+func Example_createTransaction() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/transactions", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "postings": [
+	    {
+	      "accountId": "accountId",
+	      "amount": 100
+	    }
+	  ]
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/transactions/verify-chain
+func Example_verifyTransactionChain() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/transactions/verify-chain", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/transactions/{id}
+func Example_getTransaction() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/transactions/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/admin/dependencies
+func Example_listDependencies() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/admin/dependencies", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/budgets
+func Example_listBudgets() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/budgets", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/budgets
+func Example_createBudget() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/budgets", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 1,
+	  "period": "period"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/budgets/remaining
+func Example_getRemainingBudget() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/budgets/remaining", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/budgets/{id}
+func Example_getBudget() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/budgets/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/budgets/{id}
+func Example_updateBudget() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/budgets/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "active": false,
+	  "amountUSD": 1,
+	  "carryOver": "carryOver",
+	  "maxCarryOverUSD": 0
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/budgets/{id}
+func Example_deleteBudget() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/budgets/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/budgets/{id}/periods
+func Example_listBudgetPeriods() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/budgets/"+url.PathEscape(id)+"/periods", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/consents/{id}/payments
+func Example_listConsentPayments() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/consents/"+url.PathEscape(id)+"/payments", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/description-templates
+func Example_listDescriptionTemplates() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/description-templates", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/description-templates
+func Example_createDescriptionTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/description-templates", nil, json.RawMessage(`{
+	  "template": "template"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/description-templates/{id}
+func Example_getDescriptionTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/description-templates/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/description-templates/{id}
+func Example_updateDescriptionTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/description-templates/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "active": false,
+	  "template": "template"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/description-templates/{id}
+func Example_deleteDescriptionTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/description-templates/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fee-experiments
+func Example_listFeeExperiments() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fee-experiments", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/fee-experiments
+func Example_createFeeExperiment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/fee-experiments", nil, json.RawMessage(`{
+	  "name": "name",
+	  "variants": [
+	    {
+	      "name": "name"
+	    }
+	  ]
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fee-experiments/{id}
+func Example_getFeeExperiment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fee-experiments/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/fee-experiments/{id}/assignments
+func Example_assignFeeVariant() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/fee-experiments/"+url.PathEscape(id)+"/assignments", nil, json.RawMessage(`{
+	  "subjectId": "subjectId",
+	  "subjectType": "subjectType",
+	  "variantId": "variantId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/fee-experiments/{id}/end
+func Example_endFeeExperiment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/fee-experiments/"+url.PathEscape(id)+"/end", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fee-experiments/{id}/results
+func Example_getFeeExperimentResults() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fee-experiments/"+url.PathEscape(id)+"/results", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fee-schedules
+func Example_listFeeSchedules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fee-schedules", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/fee-schedules
+func Example_createFeeSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/fee-schedules", nil, json.RawMessage(`{
+	  "partyId": "partyId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fee-schedules/{id}
+func Example_getFeeSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fee-schedules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/fee-schedules/{id}
+func Example_updateFeeSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/fee-schedules/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "enabled": false,
+	  "markupFixed": 100,
+	  "markupPercent": 100,
+	  "maxMarkup": 100,
+	  "minMarkup": 100
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/fee-schedules/{id}
+func Example_deleteFeeSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/fee-schedules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/fees/quote
+func Example_quoteFee() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/fees/quote", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100,
+	  "rail": "rail"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/fees/revenue
+func Example_getFeeRevenue() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/fees/revenue", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-callbacks/secret
+func Example_getPaymentCallbackSecret() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-callbacks/secret", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-links/{token}
+func Example_getPaymentLink() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	token := os.Getenv("TOKEN")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-links/"+url.PathEscape(token), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-links/{token}/complete
+func Example_completePaymentLink() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	token := os.Getenv("TOKEN")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-links/"+url.PathEscape(token)+"/complete", nil, json.RawMessage(`{
+	  "fundingMethod": "fundingMethod",
+	  "fundingReference": "fundingReference"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-links/{token}/qr.png
+func Example_getPaymentLinkQR() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	token := os.Getenv("TOKEN")
+	var result []byte
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-links/"+url.PathEscape(token)+"/qr.png", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(result)
+}
+
+// GET /v1/payment-schedules
+func Example_listPaymentSchedules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-schedules", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-schedules
+func Example_createPaymentSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-schedules", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amount": 1
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-schedules/{id}
+func Example_getPaymentSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-schedules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-schedules/{id}/cancel
+func Example_cancelPaymentSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-schedules/"+url.PathEscape(id)+"/cancel", nil, json.RawMessage(`{
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-schedules/{id}/pause
+func Example_pausePaymentSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-schedules/"+url.PathEscape(id)+"/pause", nil, json.RawMessage(`{
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-schedules/{id}/resume
+func Example_resumePaymentSchedule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-schedules/"+url.PathEscape(id)+"/resume", nil, json.RawMessage(`{
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-schedules/{id}/runs
+func Example_listPaymentScheduleRuns() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-schedules/"+url.PathEscape(id)+"/runs", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payments
+func Example_listPayments() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	for payment, err := range c.Payments(ctx, client.PaymentListOptions{AgentID: os.Getenv("AGENT_ID")}) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%+v\n", payment)
+	}
+}
+
+// POST /v1/payments
+func Example_initiatePayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	payment, err := c.InitiatePayment(ctx, &client.PaymentRequest{AgentID: os.Getenv("AGENT_ID"), Amount: 1500, Currency: "USD", Counterparty: "vendor@example.com"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", payment)
+}
+
+// GET /v1/payments/{id}
+func Example_getPaymentStatus() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	payment, err := c.GetPaymentStatus(ctx, id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", payment)
+}
+
+// GET /v1/payments/{id}/callbacks
+func Example_listPaymentCallbacks() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/callbacks", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/cancel
+func Example_cancelPayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	payment, err := c.CancelPayment(ctx, id, "Ordered by mistake")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%+v\n", payment)
+}
+
+// GET /v1/payments/{id}/payment-links
+func Example_listPaymentLinks() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/payment-links", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/payment-links
+func Example_createPaymentLink() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(id)+"/payment-links", nil, json.RawMessage(`{
+	  "reason": "reason",
+	  "ttlSeconds": 1
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/process
+func Example_processPayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(id)+"/process", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payments/{id}/transitions
+func Example_listPaymentTransitions() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/transitions", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/quotes
+func Example_createQuote() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/quotes", nil, json.RawMessage(`{
+	  "agentId": "agentId"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/quotes/{id}
+func Example_getQuote() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/quotes/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/rails
+func Example_getAvailableRails() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/rails", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/rails
+func Example_createRail() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/rails", nil, json.RawMessage(`{
+	  "maxAmount": 100,
+	  "name": "name",
+	  "rail": "rail",
+	  "riskLevel": "riskLevel"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/rails/select
+func Example_selectRail() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/rails/select", nil, json.RawMessage(`{
+	  "amountUSD": 100
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/rails/{rail}
+func Example_getRail() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	rail := os.Getenv("RAIL")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/rails/"+url.PathEscape(rail), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/rails/{rail}
+func Example_updateRail() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	rail := os.Getenv("RAIL")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/rails/"+url.PathEscape(rail), nil, json.RawMessage(`{
+	  "counterpartyType": "counterpartyType",
+	  "description": "description",
+	  "enabled": false,
+	  "fixedFee": 100,
+	  "international": false,
+	  "maxAmount": 100,
+	  "maxDescriptionLength": 1,
+	  "maxFee": 100,
+	  "minAmount": 100,
+	  "minFee": 100,
+	  "name": "name",
+	  "percentFee": 100,
+	  "processingTime": "processingTime",
+	  "reliability": 100,
+	  "requiresVerification": false,
+	  "reversible": false,
+	  "riskLevel": "riskLevel",
+	  "settlementTime": "settlementTime"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/rails/{rail}
+func Example_deleteRail() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	rail := os.Getenv("RAIL")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/rails/"+url.PathEscape(rail), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/statement-tokens
+func Example_listStatementTokens() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/statement-tokens", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/statement-tokens
+func Example_createStatementToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/statement-tokens", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "counterparty": "counterparty"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/statement-tokens/{id}/revoke
+func Example_revokeStatementToken() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/statement-tokens/"+url.PathEscape(id)+"/revoke", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/statements/{token}
+func Example_getStatement() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	token := os.Getenv("TOKEN")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/statements/"+url.PathEscape(token), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/workflow-templates
+func Example_listWorkflowTemplates() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/workflow-templates", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/workflow-templates
+func Example_createWorkflowTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/workflow-templates", nil, json.RawMessage(`{
+	  "partyId": "partyId",
+	  "template": {
+	    "version": 1
+	  }
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/workflow-templates/active
+func Example_getActiveWorkflowTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/workflow-templates/active", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/workflow-templates/{id}
+func Example_getWorkflowTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/workflow-templates/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/workflow-templates/{id}/activate
+func Example_activateWorkflowTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/workflow-templates/"+url.PathEscape(id)+"/activate", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/workflow-templates/{id}/deactivate
+func Example_deactivateWorkflowTemplate() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/workflow-templates/"+url.PathEscape(id)+"/deactivate", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/agents/{id}/profile
+func Example_getRiskProfile() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/agents/"+url.PathEscape(id)+"/profile", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/risk/agents/{id}/profile
+func Example_updateRiskProfile() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/risk/agents/"+url.PathEscape(id)+"/profile", nil, json.RawMessage(`{
+	  "notes": "notes",
+	  "scoreAdjustment": -1,
+	  "trustTier": "trustTier"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/agents/{id}/profile/reassess
+func Example_reassessRiskProfile() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/agents/"+url.PathEscape(id)+"/profile/reassess", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/cases
+func Example_listReviewCases() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/cases", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/cases/{id}
+func Example_getReviewCase() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/cases/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/cases/{id}/decision
+func Example_decideReviewCase() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/cases/"+url.PathEscape(id)+"/decision", nil, json.RawMessage(`{
+	  "decision": "decision",
+	  "notes": "notes"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/decisions
+func Example_listRiskDecisions() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/decisions", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/decisions/{id}
+func Example_getRiskDecision() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/decisions/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/decline-rules
+func Example_listDeclineRules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/decline-rules", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/decline-rules
+func Example_createDeclineRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/decline-rules", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "enabled": false,
+	  "name": "name",
+	  "ownerPartyId": "ownerPartyId",
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/decline-rules/evaluate
+func Example_testDeclineRules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/decline-rules/evaluate", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "counterparty": "counterparty"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/decline-rules/{id}
+func Example_getDeclineRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/decline-rules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/risk/decline-rules/{id}
+func Example_updateDeclineRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/risk/decline-rules/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "enabled": false,
+	  "name": "name",
+	  "ownerPartyId": "ownerPartyId",
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/risk/decline-rules/{id}
+func Example_deleteDeclineRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/risk/decline-rules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/evaluate
+func Example_evaluateRisk() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/evaluate", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100,
+	  "counterparty": "counterparty",
+	  "rail": "rail"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/policies
+func Example_listRiskPolicies() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/policies", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/policies
+func Example_createRiskPolicy() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/policies", nil, json.RawMessage(`{
+	  "policy": {
+	    "reviewRatio": 100,
+	    "suspiciousCounterparty": 100,
+	    "threshold": 100,
+	    "unverifiedCounterparty": 100,
+	    "version": 1
+	  }
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/policies/active
+func Example_getActiveRiskPolicy() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/policies/active", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/policies/{version}
+func Example_getRiskPolicy() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	version := os.Getenv("VERSION")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/policies/"+url.PathEscape(version), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/policies/{version}/activate
+func Example_activateRiskPolicy() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	version := os.Getenv("VERSION")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/policies/"+url.PathEscape(version)+"/activate", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/profiles
+func Example_listRiskProfiles() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/profiles", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/rules
+func Example_listAMLRules() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/rules", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/risk/rules
+func Example_createAMLRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/risk/rules", nil, json.RawMessage(`{
+	  "action": "action",
+	  "description": "description",
+	  "enabled": false,
+	  "name": "name",
+	  "scoreImpact": 100,
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/risk/rules/{id}
+func Example_getAMLRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/risk/rules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PATCH /v1/risk/rules/{id}
+func Example_updateAMLRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPatch, "/v1/risk/rules/"+url.PathEscape(id), nil, json.RawMessage(`{
+	  "action": "action",
+	  "description": "description",
+	  "enabled": false,
+	  "name": "name",
+	  "scoreImpact": 100,
+	  "type": "type"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// DELETE /v1/risk/rules/{id}
+func Example_deleteAMLRule() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodDelete, "/v1/risk/rules/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/admin/eventing/status
+func Example_getEventingStatus() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/admin/eventing/status", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/admin/outbox/failed
+func Example_listFailedOutboxEvents() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/admin/outbox/failed", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/admin/outbox/{id}/requeue
+func Example_requeueOutboxEvent() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/admin/outbox/"+url.PathEscape(id)+"/requeue", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/kill-switches
+func Example_listKillSwitches() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/kill-switches", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// PUT /v1/kill-switches/{component}
+func Example_toggleKillSwitch() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	component := os.Getenv("COMPONENT")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPut, "/v1/kill-switches/"+url.PathEscape(component), nil, json.RawMessage(`{
+	  "engaged": false,
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-batches
+func Example_listPaymentBatches() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-batches", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payment-batches/{id}
+func Example_getPaymentBatch() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payment-batches/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-batches/{id}/close
+func Example_closePaymentBatch() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-batches/"+url.PathEscape(id)+"/close", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payment-batches/{id}/submit
+func Example_submitPaymentBatch() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payment-batches/"+url.PathEscape(id)+"/submit", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/execute
+func Example_executePayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/execute", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100,
+	  "counterparty": "counterparty"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payments/{id}/adapter-calls
+func Example_listAdapterCalls() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/adapter-calls", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payments/{id}/refunds
+func Example_listRefunds() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/refunds", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/refunds
+func Example_createRefund() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(id)+"/refunds", nil, json.RawMessage(`{
+	  "amountUSD": 100,
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/reverse
+func Example_reversePayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(id)+"/reverse", nil, json.RawMessage(`{
+	  "reason": "reason"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/payments/{id}/status
+func Example_routerGetPaymentStatus() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(id)+"/status", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/payments/{id}/void
+func Example_voidPayment() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/payments/"+url.PathEscape(id)+"/void", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/rails/health
+func Example_getRailHealth() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/rails/health", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/refunds/{id}
+func Example_getRefund() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	id := os.Getenv("ID")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/refunds/"+url.PathEscape(id), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/routing/quote
+func Example_getRoutingQuote() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/routing/quote", nil, json.RawMessage(`{
+	  "agentId": "agentId",
+	  "amountUSD": 100,
+	  "counterparty": "counterparty"
+	}`), &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// GET /v1/status
+func Example_getStatus() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodGet, "/v1/status", nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
+
+// POST /v1/webhooks/rails/{rail}
+func Example_handleRailWebhook() {
+	ctx := context.Background()
+	c := client.New(os.Getenv("BASE_URL"), client.WithAPIKey(os.Getenv("API_KEY")))
+
+	rail := os.Getenv("RAIL")
+	var result json.RawMessage
+	err := c.Call(ctx, http.MethodPost, "/v1/webhooks/rails/"+url.PathEscape(rail), nil, nil, &result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(result))
+}
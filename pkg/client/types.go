@@ -0,0 +1,395 @@
+package client
+
+import "time"
+
+// Requests
+
+// CreateAgentRequest registers an agent acting for an owner party
+type CreateAgentRequest struct {
+	DisplayName  string `json:"displayName"`
+	OwnerPartyID string `json:"ownerPartyId"`
+	IdentityMode string `json:"identityMode"` // "did" or "oauth"
+}
+
+// CreateConsentRequest grants an agent consent to pay within limits
+type CreateConsentRequest struct {
+	AgentID             string        `json:"agentId"`
+	OwnerPartyID        string        `json:"ownerPartyId"`
+	Rails               []string      `json:"rails,omitempty"`
+	CounterpartiesAllow []string      `json:"counterpartiesAllow,omitempty"`
+	Limits              ConsentLimits `json:"limits"`
+	PolicyBundleVersion string        `json:"policyBundleVersion,omitempty"`
+	CosignRule          CosignRule    `json:"cosignRule"`
+	ExpiresAt           *time.Time    `json:"expiresAt,omitempty"` // Never expires when nil
+}
+
+// UpdateConsentRequest changes a consent's terms. Nil fields keep the terms
+// of the current version.
+type UpdateConsentRequest struct {
+	Rails               *[]string      `json:"rails,omitempty"`
+	CounterpartiesAllow *[]string      `json:"counterpartiesAllow,omitempty"`
+	Limits              *ConsentLimits `json:"limits,omitempty"`
+	PolicyBundleVersion *string        `json:"policyBundleVersion,omitempty"`
+	CosignRule          *CosignRule    `json:"cosignRule,omitempty"`
+	ExpiresAt           *time.Time     `json:"expiresAt,omitempty"`
+}
+
+// PaymentRequest initiates a payment by an agent
+type PaymentRequest struct {
+	AgentID        string            `json:"agentId"`
+	Amount         float64           `json:"amount,omitempty"`   // Amount in Currency
+	Currency       string            `json:"currency,omitempty"` // ISO 4217 code, defaults to USD
+	Counterparty   string            `json:"counterparty,omitempty"`
+	CounterpartyID string            `json:"counterpartyId,omitempty"` // Counterparty entity of the agent's owner party
+	Rail           string            `json:"rail,omitempty"`           // Selected by the router when empty
+	Description    string            `json:"description,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"` // Values for the owner party's description template
+	Preferences    *RailPreferences  `json:"preferences,omitempty"`
+	Mandate        *Mandate          `json:"mandate,omitempty"`
+	CallbackURL    string            `json:"callbackUrl,omitempty"` // Receives a signed PaymentEvent once the payment is final
+	Category       string            `json:"category,omitempty"`    // Spending category budgets apply to
+
+	International *International `json:"international,omitempty"` // Beneficiary's bank details, for payments abroad
+}
+
+// International identifies a beneficiary abroad, paid by SWIFT wire
+type International struct {
+	BeneficiaryName string             `json:"beneficiaryName"`
+	Address         BeneficiaryAddress `json:"address"`
+	IBAN            string             `json:"iban,omitempty"`          // For countries using IBANs
+	AccountNumber   string             `json:"accountNumber,omitempty"` // For countries that do not
+	BIC             string             `json:"bic"`
+	PurposeCode     string             `json:"purposeCode,omitempty"`  // ISO 20022 purpose, such as SUPP
+	ChargeBearer    string             `json:"chargeBearer,omitempty"` // "OUR", "SHA" (default) or "BEN"
+}
+
+// BeneficiaryAddress is a beneficiary's postal address
+type BeneficiaryAddress struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country"` // ISO 3166 alpha-2 code
+}
+
+// RailPreferences guide the router's choice of rail
+type RailPreferences struct {
+	Priority          string   `json:"priority,omitempty"`          // "speed", "cost" or "security"
+	MaxProcessingTime string   `json:"maxProcessingTime,omitempty"` // Duration such as "30m"
+	MaxSettlementTime string   `json:"maxSettlementTime,omitempty"` // Duration such as "24h"
+	PreferredRails    []string `json:"preferredRails,omitempty"`
+	ExcludeRails      []string `json:"excludeRails,omitempty"`
+	International     bool     `json:"international,omitempty"`
+}
+
+// Mandate is an agent's signature over a payment's amount, counterparty
+// and expiry
+type Mandate struct {
+	KeyID     string `json:"keyId"`
+	Signature string `json:"signature"` // Base64url Ed25519 signature over the canonical payload
+	ExpiresAt string `json:"expiresAt"` // RFC3339
+	Nonce     string `json:"nonce"`
+}
+
+// CreatePartyRequest registers a party, the legal entity agents act for
+type CreatePartyRequest struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`                     // "individual" or "organization"
+	OrganizationID string `json:"organizationId,omitempty"` // Organization party the party belongs to, within the caller's tenant
+}
+
+// SubmitKYCRequest submits a party's attributes and documents for
+// verification: legalName, dateOfBirth and country for individuals (KYC),
+// legalName, registrationNumber and country for organizations (KYB)
+type SubmitKYCRequest struct {
+	Attributes map[string]string `json:"attributes"`
+	Documents  []KYCDocument     `json:"documents"`
+}
+
+// KYCDocument is a submitted document, held in the caller's document store
+type KYCDocument struct {
+	Type      string `json:"type"`      // Such as "passport" or "certificate_of_incorporation"
+	Reference string `json:"reference"` // Locates the document in the caller's store
+	Country   string `json:"country,omitempty"`
+}
+
+// ListOptions page a listing. Zero values are left out.
+type ListOptions struct {
+	From, To  string // RFC3339 bounds on the creation time
+	Sort      string
+	Ascending bool // Oldest first instead of newest first
+	Limit     int  // Items per page, up to 100
+	Offset    int
+}
+
+// TransactionListOptions filter a listing of ledger transactions
+type TransactionListOptions struct {
+	ListOptions
+	AgentID     string
+	Status      string // "pending", "posted" or "failed"
+	ReferenceID string
+	Book        string
+}
+
+// ConsentListOptions filter a listing of consents, which needs an agent or
+// an owner party
+type ConsentListOptions struct {
+	ListOptions
+	AgentID      string
+	OwnerPartyID string
+	Revoked      *bool
+}
+
+// PaymentListOptions filter a listing of payments
+type PaymentListOptions struct {
+	ListOptions
+	AgentID      string
+	Status       string
+	Rail         string
+	Counterparty string
+	ConsentID    string
+	Category     string
+}
+
+// Resources, as the API returns them
+
+// Party is a legal entity, an individual or an organization
+type Party struct {
+	ID        string
+	Name      string
+	Type      string
+	CreatedAt string
+
+	OrganizationID string // Organization party the party belongs to, if any
+
+	KYCStatus     string // KYC status constants
+	KYCVerifiedAt string
+}
+
+// KYC statuses
+const (
+	KYCUnverified = "unverified"
+	KYCPending    = "pending"
+	KYCVerified   = "verified"
+	KYCRejected   = "rejected"
+)
+
+// KYCStatus is a party's verification status and its submissions, newest
+// first
+type KYCStatus struct {
+	PartyID     string          `json:"partyId"`
+	Kind        string          `json:"kind"` // "kyc" for individuals, "kyb" for organizations
+	Status      string          `json:"status"`
+	VerifiedAt  string          `json:"verifiedAt"`
+	Submissions []KYCSubmission `json:"submissions"`
+}
+
+// KYCSubmission is a party's submission and the decision on it
+type KYCSubmission struct {
+	ID                string            `json:"id"`
+	PartyID           string            `json:"partyId"`
+	Kind              string            `json:"kind"`
+	Attributes        map[string]string `json:"attributes"`
+	Documents         []KYCDocument     `json:"documents"`
+	Status            string            `json:"status"`
+	Reason            string            `json:"reason"`
+	Provider          string            `json:"provider"`
+	ProviderReference string            `json:"providerReference"`
+	SubmittedBy       string            `json:"submittedBy"`
+	ReviewedBy        string            `json:"reviewedBy"`
+	DecidedAt         string            `json:"decidedAt"`
+	CreatedAt         string            `json:"createdAt"`
+}
+
+// Agent is an AI agent acting for an owner party
+type Agent struct {
+	ID           string
+	DisplayName  string
+	OwnerPartyID string
+	IdentityMode string
+	CreatedAt    string
+
+	Status          string // Agent status constants
+	StatusReason    string
+	StatusChangedAt string
+}
+
+// Agent statuses
+const (
+	AgentActive         = "active"
+	AgentSuspended      = "suspended"
+	AgentDecommissioned = "decommissioned"
+)
+
+// Consent is an owner party's consent to an agent's payments
+type Consent struct {
+	ID                  string
+	AgentID             string
+	OwnerPartyID        string
+	Rails               []string
+	CounterpartiesAllow []string
+	Limits              ConsentLimits
+	PolicyBundleVersion string
+	CosignRule          CosignRule
+	CreatedAt           string
+	Revoked             bool
+	RevokedAt           string
+	RevocationReason    string
+	ExpiresAt           string // Empty when the consent never expires
+	Status              string // Consent status constants
+	Version             int
+	PreviousConsentID   string // Consent this one renewed
+	SupersededByID      string // Consent that renewed this one
+}
+
+// Consent statuses
+const (
+	ConsentActive     = "active"
+	ConsentExpired    = "expired"
+	ConsentRevoked    = "revoked"
+	ConsentSuperseded = "superseded"
+)
+
+// ConsentRevocation is a revoked consent and what became of the payments in
+// flight under it
+type ConsentRevocation struct {
+	Consent          Consent             `json:"consent"`
+	AffectedPayments []RevocationOutcome `json:"affectedPayments"`
+}
+
+// RevocationOutcome is what a consent's revocation did to a payment
+type RevocationOutcome struct {
+	PaymentID string `json:"paymentId"`
+	Action    string `json:"action"`
+	ConsentID string `json:"consentId"` // Consent the payment continues under, if any
+	Reason    string `json:"reason"`
+}
+
+// ConsentLimits cap an agent's payments under a consent
+type ConsentLimits struct {
+	SingleTxnUSD float64      `json:"singleTxnUSD"`
+	DailyUSD     float64      `json:"dailyUSD"`
+	Velocity     VelocityCaps `json:"velocity"`
+}
+
+// VelocityCaps cap how often an agent pays
+type VelocityCaps struct {
+	MaxTxnPerHour int `json:"maxTxnPerHour"`
+}
+
+// CosignRule requires approval of payments from a threshold
+type CosignRule struct {
+	ThresholdUSD  float64 `json:"thresholdUSD"`
+	ApproverGroup string  `json:"approverGroup"`
+}
+
+// Payment is a payment and the workflow processing it
+type Payment struct {
+	ID             string
+	AgentID        string
+	Amount         float64
+	Currency       string
+	AmountUSD      float64
+	Counterparty   string
+	CounterpartyID string
+	Rail           string
+	Description    string
+	Metadata       map[string]string
+	Status         string // "pending", "processing", "awaiting_approval", "completed", "failed" or "cancelled"
+	Steps          []WorkflowStep
+	RiskDecision   *RiskDecision
+	ConsentCheck   *ConsentCheck
+	MandateID      string
+	CallbackURL    string
+	Category       string
+	ScheduleID     string
+
+	// Evidence the payment went ahead on, once each check has passed
+	ConsentID             string
+	ConsentVersion        int // Version of the consent, whose terms never change
+	RiskDecisionID        string
+	ComplianceScreeningID string
+
+	Degradations []Degradation
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// Final reports whether the payment has reached a status it will not leave
+func (p *Payment) Final() bool {
+	switch p.Status {
+	case "completed", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+// WorkflowStep is a step of a payment's workflow
+type WorkflowStep struct {
+	Name      string
+	Status    string // "pending", "running", "completed", "failed" or "skipped"
+	Message   string
+	Timestamp string
+}
+
+// RiskDecision is the risk evaluation of a payment
+type RiskDecision struct {
+	ID             string
+	Decision       string  // "approve", "deny" or "review"
+	Score          float64 // 0.0 to 1.0, higher is riskier
+	Reason         string
+	Threshold      float64
+	PolicyVersion  int
+	TrustTier      string
+	RiskFactors    []string
+	TriggeredRules []string
+	CaseID         string // Review case holding the payment, for "review" decisions
+	CreatedAt      string
+}
+
+// ConsentCheck is the validation of a payment against the agent's consent
+type ConsentCheck struct {
+	Valid            bool
+	Reason           string
+	ConsentID        string
+	ConsentVersion   int
+	RequiresApproval bool
+	ApproverGroup    string
+	ApprovalID       string
+}
+
+// Degradation records how a payment went on while a dependency was
+// unavailable
+type Degradation struct {
+	Dependency string
+	Mode       string
+	Reason     string
+	Timestamp  string
+}
+
+// Transaction is a ledger transaction
+type Transaction struct {
+	ID          string
+	AgentID     string
+	Description string
+	ReferenceID string
+	Status      string
+	Book        string
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// Meta describes a page of a listing
+type Meta struct {
+	Page       int  `json:"page"`
+	Limit      int  `json:"limit"`
+	Total      int  `json:"total"`
+	TotalPages int  `json:"totalPages"`
+	Offset     int  `json:"offset"`
+	HasMore    bool `json:"hasMore"` // More items follow this page
+}
+
+// Page is a page of a listing
+type Page[T any] struct {
+	Items []T  `json:"items"`
+	Meta  Meta `json:"meta"`
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Payment callbacks
+//
+// A payment initiated with a CallbackURL has a signed PaymentEvent POSTed
+// there once it is final. Callbacks are retried until the receiver answers
+// 2xx, so an event can arrive more than once; DeliveryID tells repeats
+// apart. Each agent's callbacks are signed with its own secret, which
+// GET /v1/payment-callbacks/secret?agentId= returns.
+
+// Headers of payment callbacks
+const (
+	SignatureHeader = "X-Payment-Callback-Signature" // "t=<unix>,v1=<hex HMAC-SHA256>" of the time, delivery ID and body
+	DeliveryHeader  = "X-Payment-Callback-ID"        // Same on every attempt of a callback
+	AttemptHeader   = "X-Payment-Callback-Attempt"   // From 1
+)
+
+// Payment event types
+const (
+	EventPaymentCompleted = "payment.completed"
+	EventPaymentFailed    = "payment.failed"
+	EventPaymentCancelled = "payment.cancelled"
+)
+
+// ErrInvalidSignature is returned for callbacks not signed with the
+// secret, and for signatures made more than SignatureTolerance from now
+var ErrInvalidSignature = errors.New("agent-payments: invalid callback signature")
+
+// SignatureTolerance is how far a callback's signature time may be from the
+// current time, so a captured callback cannot be replayed later
+const SignatureTolerance = 5 * time.Minute
+
+// PaymentEvent is the summary of a final payment POSTed to its callback URL
+type PaymentEvent struct {
+	Type         string  `json:"event"` // One of the Event constants
+	PaymentID    string  `json:"paymentId"`
+	AgentID      string  `json:"agentId"`
+	Status       string  `json:"status"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty"`
+	Rail         string  `json:"rail"`
+	Description  string  `json:"description,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	FinalizedAt  string  `json:"finalizedAt"`
+
+	// Set from the headers by ParseWebhook
+	DeliveryID string `json:"-"`
+	Attempt    int    `json:"-"`
+}
+
+// ParseWebhook reads a payment callback, verifying its signature under the
+// agent's callback secret. It returns ErrInvalidSignature for callbacks that
+// are not the platform's, or were signed too long ago.
+func ParseWebhook(r *http.Request, secret string) (*PaymentEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("agent-payments: reading callback: %w", err)
+	}
+	deliveryID := r.Header.Get(DeliveryHeader)
+	event, err := ParsePaymentEvent(body, r.Header.Get(SignatureHeader), deliveryID, secret)
+	if err != nil {
+		return nil, err
+	}
+	event.DeliveryID = deliveryID
+	event.Attempt, _ = strconv.Atoi(r.Header.Get(AttemptHeader))
+	return event, nil
+}
+
+// ParsePaymentEvent verifies the signature of a callback body delivered as
+// deliveryID and decodes it
+func ParsePaymentEvent(body []byte, signature, deliveryID, secret string) (*PaymentEvent, error) {
+	if err := verifyCallbackSignature(signature, deliveryID, body, secret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("agent-payments: decoding callback: %w", err)
+	}
+	return &event, nil
+}
+
+func verifyCallbackSignature(signature, deliveryID string, body []byte, secret string, now time.Time) error {
+	var timestamp, given string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			given = value
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || secret == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + deliveryID + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	decoded, err := hex.DecodeString(given)
+	if err != nil || !hmac.Equal(decoded, expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const callbackBody = `{"event":"payment.completed","paymentId":"pay-1","agentId":"agent-1","status":"completed","amount":25.5,"currency":"USD"}`
+
+// sign signs a callback the way the platform does
+func sign(secret, deliveryID, body string, at time.Time) string {
+	timestamp := fmt.Sprint(at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + deliveryID + "." + body))
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseWebhook(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/callbacks", strings.NewReader(callbackBody))
+	req.Header.Set(SignatureHeader, sign("agent-secret", "del-1", callbackBody, time.Now()))
+	req.Header.Set(DeliveryHeader, "del-1")
+	req.Header.Set(AttemptHeader, "2")
+
+	event, err := ParseWebhook(req, "agent-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventPaymentCompleted || event.PaymentID != "pay-1" || event.Amount != 25.5 {
+		t.Fatalf("event: got %+v", event)
+	}
+	if event.DeliveryID != "del-1" || event.Attempt != 2 {
+		t.Fatalf("delivery: got %q attempt %d", event.DeliveryID, event.Attempt)
+	}
+}
+
+func TestCallbacksNotSignedNowWithTheSecretAreRejected(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		signature  string
+		deliveryID string
+		body       string
+		secret     string
+	}{
+		{"other secret", sign("other-secret", "del-1", callbackBody, now), "del-1", callbackBody, "agent-secret"},
+		{"altered body", sign("agent-secret", "del-1", callbackBody, now), "del-1", strings.Replace(callbackBody, "25.5", "2550", 1), "agent-secret"},
+		{"other delivery", sign("agent-secret", "del-1", callbackBody, now), "del-2", callbackBody, "agent-secret"},
+		{"replayed later", sign("agent-secret", "del-1", callbackBody, now.Add(-SignatureTolerance-time.Minute)), "del-1", callbackBody, "agent-secret"},
+		{"signed ahead", sign("agent-secret", "del-1", callbackBody, now.Add(SignatureTolerance+time.Minute)), "del-1", callbackBody, "agent-secret"},
+		{"no timestamp", "v1=" + strings.SplitN(sign("agent-secret", "del-1", callbackBody, now), "v1=", 2)[1], "del-1", callbackBody, "agent-secret"},
+		{"no signature", "", "del-1", callbackBody, "agent-secret"},
+		{"no secret", sign("", "del-1", callbackBody, now), "del-1", callbackBody, ""},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePaymentEvent([]byte(tt.body), tt.signature, tt.deliveryID, tt.secret); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+
+	// Within the tolerance the signature holds, so receivers' clocks may drift
+	signature := sign("agent-secret", "del-1", callbackBody, now.Add(-SignatureTolerance+time.Minute))
+	if _, err := ParsePaymentEvent([]byte(callbackBody), signature, "del-1", "agent-secret"); err != nil {
+		t.Errorf("within tolerance: %v", err)
+	}
+}
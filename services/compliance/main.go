@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"log"
+	"net/http"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/compliance"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+var repo database.Repository
+var authConfig *common.AuthConfig
+var screener *compliance.Screener
+
+func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("compliance")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("compliance")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.Connection())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Run migrations
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Initialize repository
+	repo = database.NewRepository(db)
+
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Initialize watchlist screening
+	screener = compliance.NewScreenerFromEnv()
+	common.Info("Screening with match threshold %.2f and block threshold %.2f", screener.MatchThreshold, screener.BlockThreshold)
+
+	r := gin.Default()
+
+	// Setup common middleware
+	common.SetupCommonMiddleware(r, func() error {
+		return repo.HealthCheck()
+	})
+
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
+	// Health check endpoint
+	r.GET("/healthz", func(c *gin.Context) {
+		if err := repo.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unhealthy", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "compliance service ok"})
+	})
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
+	// API v1 routes
+	v1 := r.Group("/v1/compliance", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
+	{
+		// Screening
+		v1.POST("/screen", common.RequireScopes(common.ScopeComplianceScreen), screenCounterparty)
+		v1.GET("/screenings", common.RequireScopes(common.ScopeComplianceRead), listScreenings)
+		v1.GET("/screenings/:id", common.RequireScopes(common.ScopeComplianceRead), getScreening)
+
+		// Manual review queue
+		v1.GET("/reviews", common.RequireScopes(common.ScopeComplianceReview), listPendingReviews)
+		v1.POST("/reviews/:id", common.RequireScopes(common.ScopeComplianceReview), common.RequireRolesOrPrincipals([]string{common.PrincipalService}, common.RoleReviewer), reviewScreening)
+
+		// Sanctions and denylist entries
+		v1.GET("/watchlist", common.RequireScopes(common.ScopeComplianceRead), listWatchlistEntries)
+		v1.POST("/watchlist", common.RequireScopes(common.ScopeComplianceReview), createWatchlistEntry)
+		v1.PATCH("/watchlist/:id", common.RequireScopes(common.ScopeComplianceReview), updateWatchlistEntry)
+		v1.DELETE("/watchlist/:id", common.RequireScopes(common.ScopeComplianceReview), deactivateWatchlistEntry)
+	}
+
+	common.Info("Compliance service running on %s", cfg.Addr())
+	if err := common.NewServer(cfg.Addr(), r).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
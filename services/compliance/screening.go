@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/compliance"
+	"github.com/example/agent-payments/internal/counterparties"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/funding"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type ScreenRequest struct {
+	AgentID        string  `json:"agentId" binding:"required"`
+	Counterparty   string  `json:"counterparty" binding:"required"`
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, whose KYC status is checked too
+	AmountUSD      float64 `json:"amountUSD"`
+	WorkflowID     string  `json:"workflowId,omitempty"`
+}
+
+type ReviewRequest struct {
+	Decision string `json:"decision" binding:"required"` // "approve" or "reject"
+	Notes    string `json:"notes"`
+}
+
+type ScreeningResponse struct {
+	ID             string             `json:"id"`
+	WorkflowID     string             `json:"workflowId,omitempty"`
+	AgentID        string             `json:"agentId"`
+	Counterparty   string             `json:"counterparty"`
+	CounterpartyID string             `json:"counterpartyId,omitempty"`
+	AmountUSD      float64            `json:"amountUSD"`
+	Status         string             `json:"status"`
+	MatchScore     float64            `json:"matchScore"`
+	Matches        []compliance.Match `json:"matches"`
+	ReviewedBy     string             `json:"reviewedBy,omitempty"`
+	ReviewNotes    string             `json:"reviewNotes,omitempty"`
+	ReviewedAt     string             `json:"reviewedAt,omitempty"`
+	CreatedAt      string             `json:"createdAt"`
+	UpdatedAt      string             `json:"updatedAt"`
+}
+
+func toScreeningResponse(screening *database.ComplianceScreening) *ScreeningResponse {
+	response := &ScreeningResponse{
+		ID:           screening.ID,
+		AgentID:      screening.AgentID,
+		Counterparty: screening.Counterparty,
+		AmountUSD:    screening.AmountUSD,
+		Status:       screening.Status,
+		MatchScore:   screening.MatchScore,
+		Matches:      []compliance.Match{},
+		ReviewedBy:   screening.ReviewedBy,
+		ReviewNotes:  screening.ReviewNotes,
+		CreatedAt:    screening.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    screening.UpdatedAt.Format(time.RFC3339),
+	}
+	if screening.WorkflowID != nil {
+		response.WorkflowID = *screening.WorkflowID
+	}
+	if screening.CounterpartyID != nil {
+		response.CounterpartyID = *screening.CounterpartyID
+	}
+	if screening.Matches != "" {
+		json.Unmarshal([]byte(screening.Matches), &response.Matches)
+	}
+	if screening.ReviewedAt != nil {
+		response.ReviewedAt = screening.ReviewedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// screenCounterparty screens a payment counterparty against the active
+// sanctions and denylist entries and records the result. The counterparty's
+// verified bank account holder name, when the agent's owner has one on file,
+// is screened alongside the counterparty name. A counterparty entity that
+// failed KYC is blocked whatever the lists say.
+func screenCounterparty(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
+	var req ScreenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId and counterparty are required"))
+		return
+	}
+
+	if !common.CanActForAgent(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot screen payments for this agent"))
+		return
+	}
+
+	var counterparty *database.Counterparty
+	if req.CounterpartyID != "" {
+		var err error
+		if counterparty, err = repo.CounterpartyRepository().GetByID(req.CounterpartyID); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Counterparty not found"))
+			return
+		}
+	}
+
+	names := []string{req.Counterparty}
+	if holderName := verifiedHolderName(repo, req, counterparty); holderName != "" {
+		names = append(names, holderName)
+	}
+
+	entries, err := repo.WatchlistEntryRepository().ListActive()
+	if err != nil {
+		common.Error("Failed to load watchlist: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load watchlist"))
+		return
+	}
+
+	matches := screener.Screen(entries, names...)
+	matchesJSON, err := json.Marshal(matches)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to record matches"))
+		return
+	}
+
+	screening := &database.ComplianceScreening{
+		AgentID:      req.AgentID,
+		Counterparty: req.Counterparty,
+		AmountUSD:    req.AmountUSD,
+		Status:       screener.Status(matches),
+		Matches:      string(matchesJSON),
+	}
+	if req.WorkflowID != "" {
+		screening.WorkflowID = &req.WorkflowID
+	}
+	if counterparty != nil {
+		screening.CounterpartyID = &counterparty.ID
+		if counterparty.KYCStatus == counterparties.KYCRejected {
+			common.Warn("Counterparty %s of agent %s failed KYC: blocked", counterparty.ID, req.AgentID)
+			screening.Status = compliance.StatusBlocked
+		}
+	}
+	if len(matches) > 0 {
+		screening.MatchScore = matches[0].Score
+	}
+
+	if err := repo.ComplianceScreeningRepository().Create(screening); err != nil {
+		common.Error("Failed to save screening: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to save screening"))
+		return
+	}
+
+	if len(matches) > 0 {
+		common.Warn("Counterparty %q of agent %s matched %s (%s, score %.2f): %s",
+			req.Counterparty, req.AgentID, matches[0].Name, matches[0].ListName, matches[0].Score, screening.Status)
+	}
+
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toScreeningResponse(screening)))
+}
+
+// verifiedHolderName returns the holder name of the counterparty's verified
+// bank account: the one linked to the counterparty entity, or else the one
+// the agent's owner has on file under the counterparty name
+func verifiedHolderName(repo database.Repository, req ScreenRequest, counterparty *database.Counterparty) string {
+	if counterparty != nil && counterparty.BankAccountID != nil {
+		account, err := repo.CounterpartyBankAccountRepository().GetByID(*counterparty.BankAccountID)
+		if err == nil && account.Status == funding.StatusVerified {
+			return account.HolderName
+		}
+		return ""
+	}
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
+		return ""
+	}
+	if account, err := repo.CounterpartyBankAccountRepository().GetVerified(agent.OwnerPartyID, req.Counterparty); err == nil {
+		return account.HolderName
+	}
+	return ""
+}
+
+func getScreening(c *gin.Context) {
+	screening, err := repo.ComplianceScreeningRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, screening.AgentID) || !tenancy.CheckAgent(c, repo, screening.AgentID, "screening", screening.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Screening not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toScreeningResponse(screening)))
+}
+
+// listScreenings lists an agent's screenings (?agentId=) or the screenings in
+// a status (?status=), of the caller's tenant for callers confined to one
+func listScreenings(c *gin.Context) {
+	agentID := c.Query("agentId")
+	status := c.Query("status")
+
+	var screenings []*database.ComplianceScreening
+	var err error
+	switch {
+	case agentID != "":
+		if !common.CanActForAgent(c, agentID) || !tenancy.CheckAgent(c, repo, agentID, "agent", agentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view screenings of this agent"))
+			return
+		}
+		screenings, err = repo.ComplianceScreeningRepository().ListByAgentID(agentID)
+	case status != "" && common.RequestTenant(c) != "":
+		screenings, err = repo.ComplianceScreeningRepository().ListByTenantID(common.RequestTenant(c), status)
+	case status != "":
+		screenings, err = repo.ComplianceScreeningRepository().ListByStatus(status)
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId or status is required"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list screenings: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list screenings"))
+		return
+	}
+
+	items := []interface{}{}
+	for _, screening := range screenings {
+		if (status == "" || screening.Status == status) && common.CanActForAgent(c, screening.AgentID) {
+			items = append(items, toScreeningResponse(screening))
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// listPendingReviews returns the manual-review queue, oldest hit first
+func listPendingReviews(c *gin.Context) {
+	screenings, err := repo.ComplianceScreeningRepository().ListByStatus(compliance.StatusPendingReview)
+	if err != nil {
+		common.Error("Failed to list pending reviews: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list pending reviews"))
+		return
+	}
+
+	items := make([]interface{}, len(screenings))
+	for i, screening := range screenings {
+		items[i] = toScreeningResponse(screening)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// reviewScreening records a compliance officer's decision on a hit. The
+// orchestrator waiting on the screening proceeds with an approved payment and
+// fails a rejected one.
+func reviewScreening(c *gin.Context) {
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision is required"))
+		return
+	}
+
+	var status string
+	switch req.Decision {
+	case "approve":
+		status = compliance.StatusApproved
+	case "reject":
+		status = compliance.StatusRejected
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision must be approve or reject"))
+		return
+	}
+
+	screening, err := repo.ComplianceScreeningRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Screening not found"))
+		return
+	}
+	if screening.Status != compliance.StatusPendingReview {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Screening is not awaiting review"))
+		return
+	}
+
+	now := time.Now()
+	screening.Status = status
+	screening.ReviewNotes = req.Notes
+	screening.ReviewedAt = &now
+	if principal := common.GetPrincipal(c); principal != nil {
+		screening.ReviewedBy = principal.Subject
+	}
+
+	if err := repo.ComplianceScreeningRepository().Update(screening); err != nil {
+		common.Error("Failed to update screening: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record review"))
+		return
+	}
+
+	common.Info("Screening %s %s by %s", screening.ID, status, screening.ReviewedBy)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toScreeningResponse(screening)))
+}
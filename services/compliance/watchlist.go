@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/compliance"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWatchlistEntryRequest struct {
+	ListType string   `json:"listType" binding:"required"` // "sanctions" or "denylist"
+	ListName string   `json:"listName"`
+	Name     string   `json:"name" binding:"required"`
+	Aliases  []string `json:"aliases"`
+	Country  string   `json:"country"`
+	Program  string   `json:"program"`
+	Reason   string   `json:"reason"`
+}
+
+type UpdateWatchlistEntryRequest struct {
+	Name    *string   `json:"name"`
+	Aliases *[]string `json:"aliases"`
+	Country *string   `json:"country"`
+	Program *string   `json:"program"`
+	Reason  *string   `json:"reason"`
+	Active  *bool     `json:"active"`
+}
+
+type WatchlistEntryResponse struct {
+	ID        string   `json:"id"`
+	ListType  string   `json:"listType"`
+	ListName  string   `json:"listName"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases"`
+	Country   string   `json:"country,omitempty"`
+	Program   string   `json:"program,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Active    bool     `json:"active"`
+	CreatedBy string   `json:"createdBy,omitempty"`
+	CreatedAt string   `json:"createdAt"`
+	UpdatedAt string   `json:"updatedAt"`
+}
+
+func toWatchlistEntryResponse(entry *database.WatchlistEntry) *WatchlistEntryResponse {
+	aliases := compliance.EntryAliases(entry)
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return &WatchlistEntryResponse{
+		ID:        entry.ID,
+		ListType:  entry.ListType,
+		ListName:  entry.ListName,
+		Name:      entry.Name,
+		Aliases:   aliases,
+		Country:   entry.Country,
+		Program:   entry.Program,
+		Reason:    entry.Reason,
+		Active:    entry.Active,
+		CreatedBy: entry.CreatedBy,
+		CreatedAt: entry.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: entry.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func encodeAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(aliases)
+	return string(data)
+}
+
+func listWatchlistEntries(c *gin.Context) {
+	entries, err := repo.WatchlistEntryRepository().List(c.Query("listType"))
+	if err != nil {
+		common.Error("Failed to list watchlist: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list watchlist"))
+		return
+	}
+
+	items := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		items[i] = toWatchlistEntryResponse(entry)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func createWatchlistEntry(c *gin.Context) {
+	var req CreateWatchlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "listType and name are required"))
+		return
+	}
+
+	switch req.ListType {
+	case compliance.ListSanctions:
+		if req.ListName == "" {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "listName is required for sanctions entries"))
+			return
+		}
+	case compliance.ListDenylist:
+		if req.ListName == "" {
+			req.ListName = "internal"
+		}
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "listType must be sanctions or denylist"))
+		return
+	}
+
+	entry := &database.WatchlistEntry{
+		ListType: req.ListType,
+		ListName: req.ListName,
+		Name:     req.Name,
+		Aliases:  encodeAliases(req.Aliases),
+		Country:  req.Country,
+		Program:  req.Program,
+		Reason:   req.Reason,
+		Active:   true,
+	}
+	if principal := common.GetPrincipal(c); principal != nil {
+		entry.CreatedBy = principal.Subject
+	}
+
+	if err := repo.WatchlistEntryRepository().Create(entry); err != nil {
+		common.Error("Failed to create watchlist entry: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create watchlist entry"))
+		return
+	}
+
+	common.Info("Added %s entry %s to %s", entry.ListType, entry.Name, entry.ListName)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toWatchlistEntryResponse(entry)))
+}
+
+func updateWatchlistEntry(c *gin.Context) {
+	var req UpdateWatchlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	entry, err := repo.WatchlistEntryRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Watchlist entry not found"))
+		return
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "name cannot be empty"))
+			return
+		}
+		entry.Name = *req.Name
+	}
+	if req.Aliases != nil {
+		entry.Aliases = encodeAliases(*req.Aliases)
+	}
+	if req.Country != nil {
+		entry.Country = *req.Country
+	}
+	if req.Program != nil {
+		entry.Program = *req.Program
+	}
+	if req.Reason != nil {
+		entry.Reason = *req.Reason
+	}
+	if req.Active != nil {
+		entry.Active = *req.Active
+	}
+
+	if err := repo.WatchlistEntryRepository().Update(entry); err != nil {
+		common.Error("Failed to update watchlist entry: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update watchlist entry"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toWatchlistEntryResponse(entry)))
+}
+
+// deactivateWatchlistEntry stops screening against an entry. Entries are kept
+// so past screenings still resolve the entries they matched.
+func deactivateWatchlistEntry(c *gin.Context) {
+	entry, err := repo.WatchlistEntryRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Watchlist entry not found"))
+		return
+	}
+
+	entry.Active = false
+	if err := repo.WatchlistEntryRepository().Update(entry); err != nil {
+		common.Error("Failed to deactivate watchlist entry: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to deactivate watchlist entry"))
+		return
+	}
+
+	common.Info("Deactivated %s entry %s", entry.ListType, entry.Name)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toWatchlistEntryResponse(entry)))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Approval statuses. "expired" is never stored: it is reported for pending
+// approvals past their deadline.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+// approvalTimeout is how long an approval can be decided before the payment
+// it holds fails
+var approvalTimeout time.Duration
+
+type ApprovalDecisionRequest struct {
+	Notes string `json:"notes,omitempty"`
+}
+
+type ApprovalResponse struct {
+	ID            string  `json:"id"`
+	WorkflowID    string  `json:"workflowId"`
+	ConsentID     string  `json:"consentId"`
+	AgentID       string  `json:"agentId"`
+	OwnerPartyID  string  `json:"ownerPartyId"`
+	AmountUSD     float64 `json:"amountUSD"`
+	Counterparty  string  `json:"counterparty"`
+	Rail          string  `json:"rail"`
+	ApproverGroup string  `json:"approverGroup"`
+	Status        string  `json:"status"`
+	DecidedBy     string  `json:"decidedBy,omitempty"`
+	DecisionNotes string  `json:"decisionNotes,omitempty"`
+	DecidedAt     string  `json:"decidedAt,omitempty"`
+	ExpiresAt     string  `json:"expiresAt"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+func toApprovalResponse(approval *database.Approval) *ApprovalResponse {
+	response := &ApprovalResponse{
+		ID:            approval.ID,
+		WorkflowID:    approval.WorkflowID,
+		ConsentID:     approval.ConsentID,
+		AgentID:       approval.AgentID,
+		OwnerPartyID:  approval.OwnerPartyID,
+		AmountUSD:     approval.AmountUSD,
+		Counterparty:  approval.Counterparty,
+		Rail:          approval.Rail,
+		ApproverGroup: approval.ApproverGroup,
+		Status:        approvalStatus(approval, time.Now()),
+		DecidedBy:     approval.DecidedBy,
+		DecisionNotes: approval.DecisionNotes,
+		ExpiresAt:     approval.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:     approval.CreatedAt.Format(time.RFC3339),
+	}
+	if approval.DecidedAt != nil {
+		response.DecidedAt = approval.DecidedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// approvalStatus reports a pending approval past its deadline as expired
+func approvalStatus(approval *database.Approval, now time.Time) string {
+	if approval.Status == ApprovalPending && now.After(approval.ExpiresAt) {
+		return ApprovalExpired
+	}
+	return approval.Status
+}
+
+// canApprove reports whether the principal may decide the party's approvals.
+// Agents never approve payments, including their own; service principals may
+// decide any party's.
+func canApprove(c *gin.Context, ownerPartyID string) bool {
+	return actsForParty(c, ownerPartyID)
+}
+
+// actsForParty reports whether the principal is the party or a service.
+// Attempts to reach another tenant's party are audited.
+func actsForParty(c *gin.Context, partyID string) bool {
+	principal := common.GetPrincipal(c)
+	if principal == nil || principal.Type == common.PrincipalService {
+		return true
+	}
+	if !tenancy.Check(c, repo, partyID, "party", partyID) {
+		return false
+	}
+	return principal.Type == common.PrincipalParty && principal.PartyID == partyID
+}
+
+// requestApproval holds a workflow for approval by the consent's approver
+// group. Validating the same workflow again returns its existing approval.
+func requestApproval(consent *database.Consent, req ValidateConsentRequest, approverGroup string) (*database.Approval, error) {
+	existing, err := repo.ApprovalRepository().GetByWorkflowID(req.WorkflowID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	approval := &database.Approval{
+		WorkflowID:    req.WorkflowID,
+		ConsentID:     consent.ID,
+		AgentID:       req.AgentID,
+		OwnerPartyID:  req.OwnerPartyID,
+		AmountUSD:     req.AmountUSD,
+		Counterparty:  req.Counterparty,
+		Rail:          req.Rail,
+		ApproverGroup: approverGroup,
+		Status:        ApprovalPending,
+		ExpiresAt:     time.Now().Add(approvalTimeout),
+	}
+	if err := repo.ApprovalRepository().Create(approval); err != nil {
+		return nil, err
+	}
+	common.Info("Requested approval %s from %s for workflow %s", approval.ID, approverGroup, req.WorkflowID)
+	return approval, nil
+}
+
+// listApprovals lists a party's approvals (?ownerPartyId=, defaulting to the
+// caller's party) in a status (?status=, default pending), oldest first
+func listApprovals(c *gin.Context) {
+	ownerPartyID := c.Query("ownerPartyId")
+	if principal := common.GetPrincipal(c); ownerPartyID == "" && principal != nil {
+		ownerPartyID = principal.PartyID
+	}
+	if ownerPartyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "ownerPartyId is required"))
+		return
+	}
+	if !canApprove(c, ownerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view approvals of this party"))
+		return
+	}
+
+	status := c.DefaultQuery("status", ApprovalPending)
+	stored := status
+	if status == ApprovalExpired {
+		stored = ApprovalPending
+	}
+	approvals, err := repo.ApprovalRepository().ListByOwnerPartyID(ownerPartyID, stored)
+	if err != nil {
+		common.Error("Failed to list approvals: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list approvals"))
+		return
+	}
+
+	items := []interface{}{}
+	for _, approval := range approvals {
+		if response := toApprovalResponse(approval); response.Status == status {
+			items = append(items, response)
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getApproval is readable by the party's approvers and by the agent whose
+// payment is held
+func getApproval(c *gin.Context) {
+	approval, err := repo.ApprovalRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, approval.OwnerPartyID, "approval", approval.ID) || !(canApprove(c, approval.OwnerPartyID) || common.CanActForAgent(c, approval.AgentID)) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Approval not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toApprovalResponse(approval)))
+}
+
+func approvePayment(c *gin.Context) {
+	decideApproval(c, ApprovalApproved)
+}
+
+func rejectPayment(c *gin.Context) {
+	decideApproval(c, ApprovalRejected)
+}
+
+// decideApproval records an approver's decision and audits it. The
+// orchestrator holding the payment resumes it if approved and fails it if
+// rejected.
+func decideApproval(c *gin.Context, status string) {
+	var req ApprovalDecisionRequest
+	c.ShouldBindJSON(&req)
+
+	approval, err := repo.ApprovalRepository().GetByID(c.Param("id"))
+	if err != nil || !canApprove(c, approval.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Approval not found"))
+		return
+	}
+	if current := approvalStatus(approval, time.Now()); current != ApprovalPending {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Approval is already "+current))
+		return
+	}
+
+	principal := common.GetPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, common.NewErrorResponse("UNAUTHORIZED", "An approver identity is required"))
+		return
+	}
+
+	// Users decide only the approvals of the groups they are members of;
+	// admins decide any
+	if principal.UserID != "" && !principal.HasRole(common.RoleAdmin) {
+		member, err := repo.ApproverGroupRepository().IsMember(approval.OwnerPartyID, approval.ApproverGroup, principal.UserID)
+		if err != nil {
+			common.Error("Failed to check approver group membership: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record decision"))
+			return
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, common.NewErrorResponseWithDetails("FORBIDDEN", "Not a member of the approver group", approval.ApproverGroup))
+			return
+		}
+	}
+
+	now := time.Now()
+	approval.Status = status
+	approval.DecidedBy = principal.Subject
+	approval.DecisionNotes = req.Notes
+	approval.DecidedAt = &now
+
+	if err := repo.ApprovalRepository().Update(approval); err != nil {
+		common.Error("Failed to update approval: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record decision"))
+		return
+	}
+
+	eventType, action := audit.AuditPaymentApproved, "approve"
+	if status == ApprovalRejected {
+		eventType, action = audit.AuditPaymentRejected, "reject"
+	}
+	entry := &audit.AuditEntry{
+		EventType:    eventType,
+		Severity:     audit.SeverityMedium,
+		UserID:       principal.Subject,
+		AgentID:      approval.AgentID,
+		ResourceID:   approval.ID,
+		ResourceType: "approval",
+		Action:       action,
+		Description:  "Payment approval " + status + " by " + principal.Subject,
+		OldValues:    map[string]interface{}{"status": ApprovalPending},
+		NewValues:    map[string]interface{}{"status": status, "notes": req.Notes},
+		Metadata: map[string]interface{}{
+			"workflowId":    approval.WorkflowID,
+			"consentId":     approval.ConsentID,
+			"approverGroup": approval.ApproverGroup,
+			"amountUSD":     approval.AmountUSD,
+		},
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), entry)
+
+	common.Info("Approval %s %s by %s", approval.ID, status, principal.Subject)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toApprovalResponse(approval)))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/types"
+)
+
+// consentServer serves the consent service's gRPC API through its REST
+// handlers
+type consentServer struct {
+	agentpayv1.UnimplementedConsentServiceServer
+	rest *rpc.REST
+}
+
+func (s *consentServer) Validate(ctx context.Context, req *agentpayv1.ValidateConsentRequest) (*agentpayv1.ConsentCheck, error) {
+	var check types.ConsentCheck
+	err := s.rest.Call(ctx, http.MethodPost, "/v1/consents/validate", &ValidateConsentRequest{
+		AgentID:        req.GetAgentId(),
+		OwnerPartyID:   req.GetOwnerPartyId(),
+		AmountUSD:      req.GetAmountUsd(),
+		Counterparty:   req.GetCounterparty(),
+		CounterpartyID: req.GetCounterpartyId(),
+		Rail:           req.GetRail(),
+		WorkflowID:     req.GetWorkflowId(),
+	}, &check)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.ConsentCheckToProto(&check), nil
+}
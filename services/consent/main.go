@@ -1,17 +1,39 @@
 package main
 
 import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/counterparties"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var repo database.Repository
+var authConfig *common.AuthConfig
+var eventPublisher *events.EventPublisher
 
 type CreateConsentRequest struct {
 	AgentID             string           `json:"agentId" binding:"required"`
@@ -21,6 +43,7 @@ type CreateConsentRequest struct {
 	Limits              ConsentLimitsReq `json:"limits"`
 	PolicyBundleVersion string           `json:"policyBundleVersion"`
 	CosignRule          CosignRuleReq    `json:"cosignRule"`
+	ExpiresAt           *time.Time       `json:"expiresAt,omitempty"` // RFC3339; never expires when omitted
 }
 
 type ConsentLimitsReq struct {
@@ -39,9 +62,21 @@ type CosignRuleReq struct {
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("consent")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("consent")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -54,6 +89,20 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Revocations notify agents through the event outbox
+	eventBus, err := events.NewMessageBus(cfg.MessageBus())
+	if err != nil {
+		log.Fatalf("Failed to connect to the event bus: %v", err)
+	}
+	eventPublisher = events.NewEventPublisher(repo, eventBus, cfg.Kafka.Topic)
+
+	// Payments above a cosign threshold wait this long for an approver
+	approvalTimeout = time.Duration(common.GetEnvAsInt("APPROVAL_TIMEOUT_MINUTES", 60)) * time.Minute
+
 	r := gin.Default()
 
 	// Setup common middleware
@@ -61,21 +110,56 @@ func main() {
 		return repo.HealthCheck()
 	})
 
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Consent management
-		v1.POST("/consents", createConsent)
-		v1.GET("/consents/:id", getConsent)
-		v1.GET("/consents", listConsents)
-		v1.PUT("/consents/:id/revoke", revokeConsent)
+		v1.POST("/consents", common.RequireScopes(common.ScopeConsentsWrite), createConsent)
+		v1.GET("/consents/:id", common.RequireScopes(common.ScopeConsentsRead), getConsent)
+		v1.PUT("/consents/:id", common.RequireScopes(common.ScopeConsentsWrite), updateConsent)
+		v1.GET("/consents/:id/versions", common.RequireScopes(common.ScopeConsentsRead), listConsentVersions)
+		v1.GET("/consents", common.RequireScopes(common.ScopeConsentsRead), listConsents)
+		v1.PUT("/consents/:id/revoke", common.RequireScopes(common.ScopeConsentsWrite), revokeConsent)
+		v1.POST("/consents/:id/renew", common.RequireScopes(common.ScopeConsentsWrite), renewConsent)
 
 		// Consent validation
-		v1.POST("/consents/validate", validateConsent)
+		v1.POST("/consents/validate", common.RequireScopes(common.ScopeConsentsRead), validateConsent)
+
+		// Spending limits across all of a party's agents
+		v1.GET("/parties/:id/spending-limits", common.RequireScopes(common.ScopeConsentsRead), listSpendingLimits)
+		v1.PUT("/parties/:id/spending-limits/:period", common.RequireScopes(common.ScopeConsentsWrite), setSpendingLimit)
+		v1.DELETE("/parties/:id/spending-limits/:period", common.RequireScopes(common.ScopeConsentsWrite), deleteSpendingLimit)
+		v1.GET("/parties/:id/spending", common.RequireScopes(common.ScopeConsentsRead), getSpendingReport)
+
+		// Cosign approvals
+		v1.GET("/approvals", common.RequireScopes(common.ScopeConsentsRead), listApprovals)
+		v1.GET("/approvals/:id", common.RequireScopes(common.ScopeConsentsRead), getApproval)
+		v1.POST("/approvals/:id/approve", common.RequireScopes(common.ScopeConsentsWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalService}, common.RoleApprover), approvePayment)
+		v1.POST("/approvals/:id/reject", common.RequireScopes(common.ScopeConsentsWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalService}, common.RoleApprover), rejectPayment)
 	}
 
-	common.Info("Consent service running on :8082")
-	log.Fatal(r.Run(":8082"))
+	server := common.NewServer(cfg.Addr(), r)
+
+	// Warn agents of consents about to expire
+	initConsentExpiryWarnings(server.Context())
+
+	// The calls payment processing makes, over gRPC on GRPC_PORT
+	if err := rpc.Serve(server, func(s *grpc.Server) {
+		agentpayv1.RegisterConsentServiceServer(s, &consentServer{rest: rpc.NewREST(r)})
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	common.Info("Consent service running on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func createConsent(c *gin.Context) {
@@ -91,14 +175,20 @@ func createConsent(c *gin.Context) {
 		return
 	}
 
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "expiresAt must be in the future"))
+		return
+	}
+
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
 
 	// Verify owner party exists
-	if _, err := repo.PartyRepository().GetByID(req.OwnerPartyID); err != nil {
+	if _, err := repo.PartyRepository().GetByID(req.OwnerPartyID); err != nil || !tenancy.Check(c, repo, req.OwnerPartyID, "party", req.OwnerPartyID) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Owner party not found"))
 		return
 	}
@@ -111,15 +201,19 @@ func createConsent(c *gin.Context) {
 		CounterpartiesAllow: "[]", // Would serialize req.CounterpartiesAllow to JSON in production
 		PolicyBundleVersion: req.PolicyBundleVersion,
 		Revoked:             false,
+		ExpiresAt:           req.ExpiresAt,
+		Version:             1,
 	}
 
 	// Convert nested structures to JSON strings (simplified for now)
 	if req.Limits.SingleTxnUSD > 0 || req.Limits.DailyUSD > 0 || req.Limits.Velocity.MaxTxnPerHour > 0 {
-		consent.Limits = "{}" // Would serialize to JSON in production
+		limits, _ := json.Marshal(req.Limits)
+		consent.Limits = string(limits)
 	}
 
 	if req.CosignRule.ThresholdUSD > 0 || req.CosignRule.ApproverGroup != "" {
-		consent.CosignRule = "{}" // Would serialize to JSON in production
+		cosignRule, _ := json.Marshal(req.CosignRule)
+		consent.CosignRule = string(cosignRule)
 	}
 
 	if err := repo.ConsentRepository().Create(consent); err != nil {
@@ -128,69 +222,219 @@ func createConsent(c *gin.Context) {
 		return
 	}
 
-	// Convert to API response format
-	response := &types.Consent{
-		ID:                  consent.ID,
-		AgentID:             consent.AgentID,
-		OwnerPartyID:        consent.OwnerPartyID,
-		Rails:               []string{}, // Would deserialize from consent.Rails JSON in production
-		CounterpartiesAllow: []string{}, // Would deserialize from consent.CounterpartiesAllow JSON in production
-		PolicyBundleVersion: consent.PolicyBundleVersion,
-		CreatedAt:           consent.CreatedAt.Format(time.RFC3339),
-		Revoked:             consent.Revoked,
-	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditConsentCreated,
+		Severity:     audit.SeverityMedium,
+		AgentID:      consent.AgentID,
+		ResourceID:   consent.ID,
+		ResourceType: "consent",
+		Action:       "create",
+		Description:  "Consent created for agent " + consent.AgentID,
+		NewValues: map[string]interface{}{
+			"ownerPartyId":        consent.OwnerPartyID,
+			"limits":              req.Limits,
+			"cosignRule":          req.CosignRule,
+			"policyBundleVersion": consent.PolicyBundleVersion,
+			"expiresAt":           consent.ExpiresAt,
+		},
+	})
 
 	common.Info("Created consent: %s for agent %s", consent.ID, consent.AgentID)
-	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toConsentResponse(consent)))
 }
 
+// getConsent returns a consent, or with ?asOf=<RFC3339> the consent as it was
+// at that time, e.g. the limits in force when a past payment was approved
 func getConsent(c *gin.Context) {
-	_ = c.Param("id")
+	asOf, ok := common.ParseAsOf(c)
+	if !ok {
+		return
+	}
+
+	var consent *database.Consent
+	var err error
+	if asOf != nil {
+		consent, err = repo.ConsentRepository().GetAsOf(c.Param("id"), *asOf)
+	} else {
+		consent, err = repo.ConsentRepository().GetByID(c.Param("id"))
+	}
+	if err != nil || !common.CanActForAgent(c, consent.AgentID) || !consentInTenant(c, consent) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toConsentResponse(consent)))
+}
 
-	// For now, we'll need to add GetByID to the repository
-	// This is a placeholder - would need to implement in repository
-	c.JSON(http.StatusNotImplemented, common.NewErrorResponse("NOT_IMPLEMENTED", "Get consent by ID not yet implemented"))
+// consentInTenant reports whether a consent belongs to the caller's tenant.
+// Attempts to reach another tenant's consents are audited.
+func consentInTenant(c *gin.Context, consent *database.Consent) bool {
+	return tenancy.Check(c, repo, consent.OwnerPartyID, "consent", consent.ID)
 }
 
+// listConsents lists a page of the consents of an agent (?agentId=) or owner
+// party (?ownerPartyId=), optionally only revoked or unrevoked ones
+// (?revoked=). An agent's consents can be listed as of a past time; those
+// lists are paged but not sorted or filtered by time. Agents can only list
+// their own consents, and parties those of their tenant.
 func listConsents(c *gin.Context) {
-	_ = c.Query("agentId")
-	_ = c.Query("ownerPartyId")
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	asOf, ok := common.ParseAsOf(c)
+	if !ok {
+		return
+	}
+
+	filter := database.ConsentFilter{
+		AgentID:      c.Query("agentId"),
+		OwnerPartyID: c.Query("ownerPartyId"),
+		TenantID:     common.RequestTenant(c),
+	}
+	if value := c.Query("revoked"); value != "" {
+		revoked, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "revoked must be true or false"))
+			return
+		}
+		filter.Revoked = &revoked
+	}
+	switch {
+	case asOf != nil && filter.AgentID == "":
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "asOf requires agentId"))
+		return
+	case filter.AgentID == "" && filter.OwnerPartyID == "":
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId or ownerPartyId is required"))
+		return
+	}
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalAgent {
+		if filter.AgentID != "" && filter.AgentID != principal.AgentID {
+			c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewPageResponse([]interface{}{}, params, 0)))
+			return
+		}
+		filter.AgentID = principal.AgentID
+	}
+	if filter.TenantID != "" {
+		if filter.AgentID != "" && !tenancy.CheckAgent(c, repo, filter.AgentID, "agent", filter.AgentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list consents of this agent"))
+			return
+		}
+		if filter.OwnerPartyID != "" && !tenancy.Check(c, repo, filter.OwnerPartyID, "party", filter.OwnerPartyID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list consents of this party"))
+			return
+		}
+	}
+
+	var consents []*database.Consent
+	var total int
+	var err error
+	if asOf != nil {
+		consents, err = repo.ConsentRepository().ListByAgentIDAsOf(filter.AgentID, *asOf)
+		consents = matchConsents(consents, filter)
+		total = len(consents)
+		start, end := params.Window(total)
+		consents = consents[start:end]
+	} else {
+		consents, total, err = repo.ConsentRepository().ListPage(filter, params)
+	}
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list consents: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list consents"))
+		return
+	}
 
-	// For now, return empty list - would need to implement in repository
-	result := []*types.Consent{}
-	interfaces := make([]interface{}, len(result))
-	for i, v := range result {
-		interfaces[i] = v
+	items := make([]interface{}, len(consents))
+	for i, consent := range consents {
+		items[i] = toConsentResponse(consent)
 	}
-	response := common.NewListResponse(interfaces, 1, 10, len(result))
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewPageResponse(items, params, total)))
+}
 
-	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+// matchConsents keeps the consents a filter selects, for lists not filtered
+// by the database
+func matchConsents(consents []*database.Consent, filter database.ConsentFilter) []*database.Consent {
+	var matched []*database.Consent
+	for _, consent := range consents {
+		if filter.OwnerPartyID != "" && consent.OwnerPartyID != filter.OwnerPartyID {
+			continue
+		}
+		if filter.Revoked != nil && consent.Revoked != *filter.Revoked {
+			continue
+		}
+		matched = append(matched, consent)
+	}
+	return matched
 }
 
-func revokeConsent(c *gin.Context) {
-	_ = c.Param("id")
+func toConsentResponse(consent *database.Consent) *types.Consent {
+	response := &types.Consent{
+		ID:                  consent.ID,
+		AgentID:             consent.AgentID,
+		OwnerPartyID:        consent.OwnerPartyID,
+		Rails:               []string{},
+		CounterpartiesAllow: []string{},
+		PolicyBundleVersion: consent.PolicyBundleVersion,
+		CreatedAt:           consent.CreatedAt.Format(time.RFC3339),
+		Revoked:             consent.Revoked,
+		Status:              string(consentstate.Of(consent, time.Now())),
+		Version:             consent.Version,
+	}
+	decodeJSON(consent.Rails, &response.Rails)
+	decodeJSON(consent.CounterpartiesAllow, &response.CounterpartiesAllow)
+	decodeJSON(consent.Limits, &response.Limits)
+	decodeJSON(consent.CosignRule, &response.CosignRule)
+	if consent.RevokedAt != nil {
+		response.RevokedAt = consent.RevokedAt.Format(time.RFC3339)
+		response.RevocationReason = consent.RevocationReason
+	}
+	if consent.ExpiresAt != nil {
+		response.ExpiresAt = consent.ExpiresAt.Format(time.RFC3339)
+	}
+	if consent.PreviousConsentID != nil {
+		response.PreviousConsentID = *consent.PreviousConsentID
+	}
+	if consent.SupersededByID != nil {
+		response.SupersededByID = *consent.SupersededByID
+	}
+	return response
+}
 
-	// For now, return not implemented - would need to implement in repository
-	c.JSON(http.StatusNotImplemented, common.NewErrorResponse("NOT_IMPLEMENTED", "Revoke consent not yet implemented"))
+// decodeJSON decodes a stored JSON column, leaving the target unchanged if
+// the column is empty or malformed
+func decodeJSON(value string, target interface{}) {
+	if value != "" {
+		json.Unmarshal([]byte(value), target)
+	}
 }
 
 type ValidateConsentRequest struct {
-	AgentID      string  `json:"agentId" binding:"required"`
-	OwnerPartyID string  `json:"ownerPartyId" binding:"required"`
-	AmountUSD    float64 `json:"amountUSD" binding:"required"`
-	Counterparty string  `json:"counterparty" binding:"required"`
-	Rail         string  `json:"rail" binding:"required"`
+	AgentID        string  `json:"agentId" binding:"required"`
+	OwnerPartyID   string  `json:"ownerPartyId" binding:"required"`
+	AmountUSD      float64 `json:"amountUSD" binding:"required"`
+	Counterparty   string  `json:"counterparty" binding:"required"`
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, checked against the owner's deny list
+	Rail           string  `json:"rail" binding:"required"`
+	WorkflowID     string  `json:"workflowId,omitempty"` // Payment to hold for approval when a cosign rule applies
 }
 
 type ConsentValidationResponse struct {
 	Valid            bool   `json:"valid"`
 	ConsentID        string `json:"consentId,omitempty"`
+	ConsentVersion   int    `json:"consentVersion,omitempty"`
 	Reason           string `json:"reason,omitempty"`
 	RequiresApproval bool   `json:"requiresApproval,omitempty"`
 	ApproverGroup    string `json:"approverGroup,omitempty"`
+	ApprovalID       string `json:"approvalId,omitempty"`
 }
 
 func validateConsent(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
 	var req ValidateConsentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
@@ -203,6 +447,20 @@ func validateConsent(c *gin.Context) {
 		return
 	}
 
+	// No consent covers a counterparty the owner party has denied
+	if req.CounterpartyID != "" {
+		counterparty, err := repo.CounterpartyRepository().GetByID(req.CounterpartyID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Counterparty not found"))
+			return
+		}
+		if refusal := counterparties.Refusal(counterparty, req.OwnerPartyID); refusal != "" {
+			common.Info("Consent validation failed for agent %s: %s", req.AgentID, refusal)
+			c.JSON(http.StatusOK, common.NewSuccessResponse(&ConsentValidationResponse{Valid: false, Reason: refusal}))
+			return
+		}
+	}
+
 	// Find active consents for this agent and owner party
 	consents, err := repo.ConsentRepository().ListByAgentID(req.AgentID)
 	if err != nil {
@@ -211,11 +469,18 @@ func validateConsent(c *gin.Context) {
 		return
 	}
 
-	// Filter active (non-revoked) consents
+	// Filter active consents: not revoked, superseded or expired
+	now := time.Now()
 	var activeConsents []*database.Consent
+	var expired *database.Consent // Latest expiry, explained when nothing is active
 	for _, consent := range consents {
-		if !consent.Revoked {
+		switch consentstate.Of(consent, now) {
+		case consentstate.Active:
 			activeConsents = append(activeConsents, consent)
+		case consentstate.Expired:
+			if expired == nil || consent.ExpiresAt.After(*expired.ExpiresAt) {
+				expired = consent
+			}
 		}
 	}
 
@@ -224,6 +489,11 @@ func validateConsent(c *gin.Context) {
 			Valid:  false,
 			Reason: "No active consent found for this agent and owner party",
 		}
+		if expired != nil {
+			response.ConsentID = expired.ID
+			response.Reason = fmt.Sprintf("Consent %s expired at %s; renew it to continue", expired.ID, expired.ExpiresAt.Format(time.RFC3339))
+			common.Info("Consent validation failed for agent %s: %s", req.AgentID, response.Reason)
+		}
 		c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 		return
 	}
@@ -233,12 +503,35 @@ func validateConsent(c *gin.Context) {
 		validation := validateConsentRules(consent, req)
 
 		if validation.Valid {
+			// Limits of the owner party cap all its agents together
+			reason, err := checkPartySpending(req)
+			if err != nil {
+				common.Error("Failed to check party spending for agent %s: %v", req.AgentID, err)
+				c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to check party spending limits"))
+				return
+			}
+			if reason != "" {
+				common.Info("Consent validation failed for agent %s, amount %.2f: %s", req.AgentID, req.AmountUSD, reason)
+				c.JSON(http.StatusOK, common.NewSuccessResponse(&ConsentValidationResponse{Valid: false, ConsentID: consent.ID, Reason: reason}))
+				return
+			}
+
 			response := &ConsentValidationResponse{
 				Valid:            true,
 				ConsentID:        consent.ID,
+				ConsentVersion:   consent.Version,
 				RequiresApproval: validation.RequiresApproval,
 				ApproverGroup:    validation.ApproverGroup,
 			}
+			if validation.RequiresApproval && req.WorkflowID != "" {
+				approval, err := requestApproval(consent, req, validation.ApproverGroup)
+				if err != nil {
+					common.Error("Failed to request approval for workflow %s: %v", req.WorkflowID, err)
+					c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to request approval"))
+					return
+				}
+				response.ApprovalID = approval.ID
+			}
 			common.Info("Consent validation passed for agent %s, amount %.2f", req.AgentID, req.AmountUSD)
 			c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 			return
@@ -254,6 +547,12 @@ func validateConsent(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// Cosign rule applied to consents that do not define one
+const (
+	defaultCosignThresholdUSD = 10000
+	defaultApproverGroup      = "senior_approvers"
+)
+
 type ConsentValidationResult struct {
 	Valid            bool
 	RequiresApproval bool
@@ -275,11 +574,19 @@ func validateConsentRules(consent *database.Consent, req ValidateConsentRequest)
 	// Check amount limits (simplified - would parse JSON in production)
 	// For now, assume no limits if not specified
 
-	// Check cosign rules (simplified - would parse JSON in production)
-	// For now, check if amount exceeds threshold requiring approval
-	if req.AmountUSD > 10000 { // Example threshold
+	// Payments above the cosign threshold need an approver; consents without
+	// a cosign rule use the default threshold and group
+	var rule CosignRuleReq
+	decodeJSON(consent.CosignRule, &rule)
+	if rule.ThresholdUSD <= 0 {
+		rule.ThresholdUSD = defaultCosignThresholdUSD
+	}
+	if rule.ApproverGroup == "" {
+		rule.ApproverGroup = defaultApproverGroup
+	}
+	if req.AmountUSD > rule.ThresholdUSD {
 		result.RequiresApproval = true
-		result.ApproverGroup = "senior_approvers"
+		result.ApproverGroup = rule.ApproverGroup
 	}
 
 	return result
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Consents checked for expiry warnings per run
+const consentExpiryBatchSize = 500
+
+type RenewConsentRequest struct {
+	// RFC3339; defaults to the consent's original term from now, or never
+	// if it did not expire
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// renewConsent replaces a current or expired consent with a successor, the
+// next version with the same terms and a new expiry. The predecessor is
+// superseded and stops authorizing payments. The agent is notified with a
+// consent.renewed event.
+func renewConsent(c *gin.Context) {
+	var req RenewConsentRequest
+	c.ShouldBindJSON(&req)
+
+	consent, err := repo.ConsentRepository().GetByID(c.Param("id"))
+	if err != nil || !actsForParty(c, consent.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+
+	now := time.Now()
+	switch consentstate.Of(consent, now) {
+	case consentstate.Revoked:
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "A revoked consent cannot be renewed"))
+		return
+	case consentstate.Superseded:
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent was superseded by consent "+*consent.SupersededByID))
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil {
+		expiresAt = consentstate.RenewalExpiry(consent, now)
+	} else if !expiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "expiresAt must be in the future"))
+		return
+	}
+
+	successor := consentstate.Successor(consent, expiresAt)
+	renewed, err := repo.ConsentRepository().Supersede(consent, successor)
+	if err != nil {
+		common.Error("Failed to renew consent %s: %v", consent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to renew consent"))
+		return
+	}
+	if !renewed {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent was revoked or superseded meanwhile"))
+		return
+	}
+
+	actor := "consent"
+	if principal := common.GetPrincipal(c); principal != nil {
+		actor = principal.Subject
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditConsentRenewed,
+		Severity:     audit.SeverityMedium,
+		UserID:       actor,
+		AgentID:      consent.AgentID,
+		ResourceID:   successor.ID,
+		ResourceType: "consent",
+		Action:       "renew",
+		Description:  "Consent " + consent.ID + " renewed by " + actor,
+		OldValues:    map[string]interface{}{"consentId": consent.ID, "version": consent.Version, "expiresAt": consent.ExpiresAt},
+		NewValues:    map[string]interface{}{"consentId": successor.ID, "version": successor.Version, "expiresAt": successor.ExpiresAt},
+	})
+
+	notifyConsent(events.EventConsentRenewed, successor, map[string]interface{}{
+		"previousConsentId": consent.ID,
+		"version":           successor.Version,
+		"expiresAt":         successor.ExpiresAt,
+	})
+
+	common.Info("Consent %s renewed by %s as consent %s", consent.ID, actor, successor.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toConsentResponse(successor)))
+}
+
+// initConsentExpiryWarnings emits a consent.expiring event for each consent
+// expiring within CONSENT_EXPIRY_WARNING_HOURS, checking every
+// CONSENT_EXPIRY_CHECK_INTERVAL_SECONDS until ctx is done
+func initConsentExpiryWarnings(ctx context.Context) {
+	interval := time.Duration(common.GetEnvAsInt("CONSENT_EXPIRY_CHECK_INTERVAL_SECONDS", 300)) * time.Second
+	warning := time.Duration(common.GetEnvAsInt("CONSENT_EXPIRY_WARNING_HOURS", 72)) * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				warnExpiringConsents(now, warning)
+			}
+		}
+	}()
+}
+
+// warnExpiringConsents notifies the agents of consents expiring before
+// now+warning, once per consent. Consents that expired unwarned, e.g. while
+// the service was down, are still warned of.
+func warnExpiringConsents(now time.Time, warning time.Duration) {
+	expiring, err := repo.ConsentRepository().ListExpiring(now.Add(warning), consentExpiryBatchSize)
+	if err != nil {
+		common.Error("Failed to list expiring consents: %v", err)
+		return
+	}
+	for _, consent := range expiring {
+		marked, err := repo.ConsentRepository().MarkExpiringNotified(consent.ID, now)
+		if err != nil {
+			common.Error("Failed to record expiry warning of consent %s: %v", consent.ID, err)
+			continue
+		}
+		if !marked {
+			continue // Warned by another instance
+		}
+		notifyConsent(events.EventConsentExpiring, consent, map[string]interface{}{
+			"expiresAt": consent.ExpiresAt,
+			"expired":   !now.Before(*consent.ExpiresAt),
+		})
+	}
+}
+
+// notifyConsent publishes a consent event to the consent's agent
+func notifyConsent(eventType events.EventType, consent *database.Consent, data map[string]interface{}) {
+	data["consentId"] = consent.ID
+	data["agentId"] = consent.AgentID
+	data["ownerPartyId"] = consent.OwnerPartyID
+	event := events.NewEvent(eventType, consent.ID, "consent", data)
+	event.Metadata.Source = "consent"
+	if err := eventPublisher.PublishEvent(context.Background(), event); err != nil {
+		common.Error("Failed to publish %s for consent %s: %v", eventType, consent.ID, err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Revocation outcomes for in-flight payments
+const (
+	RevocationRevalidated = "revalidated" // Another consent allows the payment
+	RevocationCancelled   = "cancelled"   // No other consent allows it
+	RevocationUnchanged   = "unchanged"   // The payment finished before it could be cancelled
+)
+
+type RevokeConsentRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// RevocationOutcome is what a revocation did to one in-flight payment
+type RevocationOutcome struct {
+	PaymentID string `json:"paymentId"`
+	Action    string `json:"action"`
+	ConsentID string `json:"consentId,omitempty"` // Consent the payment continues under
+	Reason    string `json:"reason"`
+}
+
+type RevokeConsentResponse struct {
+	Consent          interface{}          `json:"consent"`
+	AffectedPayments []*RevocationOutcome `json:"affectedPayments"`
+}
+
+// revokeConsent revokes a consent and propagates the revocation to payments
+// in flight under it. The agent is notified with a consent.revoked event.
+func revokeConsent(c *gin.Context) {
+	var req RevokeConsentRequest
+	c.ShouldBindJSON(&req)
+
+	consent, err := repo.ConsentRepository().GetByID(c.Param("id"))
+	if err != nil || !actsForParty(c, consent.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+	if consent.Revoked {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent is already revoked"))
+		return
+	}
+	if consent.SupersededByID != nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent was superseded by consent "+*consent.SupersededByID+"; revoke that one"))
+		return
+	}
+
+	actor := "consent"
+	if principal := common.GetPrincipal(c); principal != nil {
+		actor = principal.Subject
+	}
+
+	now := time.Now()
+	consent.Revoked = true
+	consent.RevokedAt = &now
+	consent.RevocationReason = req.Reason
+	if err := repo.ConsentRepository().Update(consent); err != nil {
+		common.Error("Failed to revoke consent %s: %v", consent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke consent"))
+		return
+	}
+
+	entry := &audit.AuditEntry{
+		EventType:    audit.AuditConsentRevoked,
+		Severity:     audit.SeverityMedium,
+		UserID:       actor,
+		AgentID:      consent.AgentID,
+		ResourceID:   consent.ID,
+		ResourceType: "consent",
+		Action:       "revoke",
+		Description:  "Consent revoked by " + actor,
+		OldValues:    map[string]interface{}{"revoked": false},
+		NewValues:    map[string]interface{}{"revoked": true, "reason": req.Reason},
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), entry)
+
+	outcomes := propagateRevocation(consent, actor)
+	notifyRevocation(consent, outcomes)
+
+	common.Info("Consent %s revoked by %s; %d in-flight payments affected", consent.ID, actor, len(outcomes))
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&RevokeConsentResponse{
+		Consent:          toConsentResponse(consent),
+		AffectedPayments: outcomes,
+	}))
+}
+
+// propagateRevocation re-validates each payment in flight under a revoked
+// consent against the agent's remaining consents. Payments another consent
+// allows move to it; the rest are cancelled, along with their approvals.
+func propagateRevocation(consent *database.Consent, actor string) []*RevocationOutcome {
+	outcomes := []*RevocationOutcome{}
+	for _, workflow := range inFlightWorkflows(consent) {
+		approval, err := repo.ApprovalRepository().GetByWorkflowID(workflow.ID)
+		if err != nil {
+			approval = nil
+		}
+
+		if replacement := replacementConsent(consent, workflow, approval); replacement != nil {
+			outcomes = append(outcomes, revalidate(consent, replacement, workflow, approval))
+			continue
+		}
+		outcomes = append(outcomes, cancelForRevocation(consent, workflow, approval, actor))
+	}
+	return outcomes
+}
+
+// inFlightWorkflows finds the unfinished payments relying on a consent:
+// those validated against it and those awaiting approval under it
+func inFlightWorkflows(consent *database.Consent) []*database.PaymentWorkflow {
+	var workflows []*database.PaymentWorkflow
+	seen := make(map[string]bool)
+	add := func(workflow *database.PaymentWorkflow) {
+		state := workflowstate.State(workflow.Status)
+		if seen[workflow.ID] || !workflowstate.Allowed(state, workflowstate.Cancelled) {
+			return
+		}
+		seen[workflow.ID] = true
+		workflows = append(workflows, workflow)
+	}
+
+	if validated, err := repo.PaymentWorkflowRepository().ListByConsentID(consent.ID); err == nil {
+		for _, workflow := range validated {
+			add(workflow)
+		}
+	} else {
+		common.Error("Failed to list payments under consent %s: %v", consent.ID, err)
+	}
+
+	if approvals, err := repo.ApprovalRepository().ListByConsentID(consent.ID, ApprovalPending); err == nil {
+		for _, approval := range approvals {
+			if workflow, err := repo.PaymentWorkflowRepository().GetByID(approval.WorkflowID); err == nil {
+				add(workflow)
+			}
+		}
+	} else {
+		common.Error("Failed to list approvals under consent %s: %v", consent.ID, err)
+	}
+	return workflows
+}
+
+// replacementConsent returns another active consent of the agent that allows
+// the payment, or nil. A consent whose cosign rule applies only counts if the
+// payment already has an approval, since a payment past approval cannot be
+// held again.
+func replacementConsent(revoked *database.Consent, workflow *database.PaymentWorkflow, approval *database.Approval) *database.Consent {
+	consents, err := repo.ConsentRepository().ListByAgentID(workflow.AgentID)
+	if err != nil {
+		common.Error("Failed to list consents of agent %s: %v", workflow.AgentID, err)
+		return nil
+	}
+
+	req := ValidateConsentRequest{
+		AgentID:      workflow.AgentID,
+		OwnerPartyID: revoked.OwnerPartyID,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Rail:         workflow.Rail,
+		WorkflowID:   workflow.ID,
+	}
+	for _, candidate := range consents {
+		if !consentstate.IsActive(candidate, time.Now()) || candidate.ID == revoked.ID {
+			continue
+		}
+		validation := validateConsentRules(candidate, req)
+		if !validation.Valid {
+			continue
+		}
+		if validation.RequiresApproval && (approval == nil || approval.Status == ApprovalRejected) {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// revalidate moves a payment and its approval to the replacement consent
+func revalidate(revoked, replacement *database.Consent, workflow *database.PaymentWorkflow, approval *database.Approval) *RevocationOutcome {
+	if approval != nil && approval.ConsentID == revoked.ID {
+		approval.ConsentID = replacement.ID
+		if err := repo.ApprovalRepository().Update(approval); err != nil {
+			common.Error("Failed to move approval %s to consent %s: %v", approval.ID, replacement.ID, err)
+		}
+	}
+	if workflow.ConsentID != nil {
+		if err := repo.PaymentWorkflowRepository().LinkConsent(workflow.ID, replacement.ID, replacement.Version); err != nil {
+			common.Error("Failed to move payment %s to consent %s: %v", workflow.ID, replacement.ID, err)
+		}
+	}
+
+	common.Info("Payment %s moved from revoked consent %s to consent %s", workflow.ID, revoked.ID, replacement.ID)
+	return &RevocationOutcome{
+		PaymentID: workflow.ID,
+		Action:    RevocationRevalidated,
+		ConsentID: replacement.ID,
+		Reason:    fmt.Sprintf("Consent %s was revoked; the payment continues under consent %s", revoked.ID, replacement.ID),
+	}
+}
+
+// cancelForRevocation cancels a payment no remaining consent allows and
+// rejects its pending approval. The orchestrator stops the payment before its
+// next step.
+func cancelForRevocation(revoked *database.Consent, workflow *database.PaymentWorkflow, approval *database.Approval, actor string) *RevocationOutcome {
+	reason := fmt.Sprintf("Consent %s was revoked and no other consent allows this payment", revoked.ID)
+	if revoked.RevocationReason != "" {
+		reason += ": " + revoked.RevocationReason
+	}
+
+	err := workflowstate.New(repo).Transition(workflow, workflowstate.Cancelled, reason, actor)
+	if err != nil {
+		// A payment that finished first is left as it is
+		var transitionErr *workflowstate.TransitionError
+		if !errors.As(err, &transitionErr) {
+			common.Error("Failed to cancel payment %s after revocation of consent %s: %v", workflow.ID, revoked.ID, err)
+		}
+		return &RevocationOutcome{
+			PaymentID: workflow.ID,
+			Action:    RevocationUnchanged,
+			Reason:    fmt.Sprintf("Consent %s was revoked but the payment could not be cancelled: %v", revoked.ID, err),
+		}
+	}
+
+	if approval != nil && approval.Status == ApprovalPending {
+		now := time.Now()
+		approval.Status = ApprovalRejected
+		approval.DecidedBy = actor
+		approval.DecisionNotes = reason
+		approval.DecidedAt = &now
+		if err := repo.ApprovalRepository().Update(approval); err != nil {
+			common.Error("Failed to reject approval %s after revocation: %v", approval.ID, err)
+		}
+	}
+
+	common.Warn("Payment %s cancelled: %s", workflow.ID, reason)
+	return &RevocationOutcome{
+		PaymentID: workflow.ID,
+		Action:    RevocationCancelled,
+		Reason:    reason,
+	}
+}
+
+// notifyRevocation tells the agent its consent was revoked and what happened
+// to each of its payments in flight
+func notifyRevocation(consent *database.Consent, outcomes []*RevocationOutcome) {
+	event := events.NewEvent(events.EventConsentRevoked, consent.ID, "consent", map[string]interface{}{
+		"consentId":    consent.ID,
+		"agentId":      consent.AgentID,
+		"ownerPartyId": consent.OwnerPartyID,
+		"reason":       consent.RevocationReason,
+		"payments":     outcomes,
+	})
+	event.Metadata.Source = "consent"
+	if err := eventPublisher.PublishEvent(context.Background(), event); err != nil {
+		common.Error("Failed to notify agent %s of revocation of consent %s: %v", consent.AgentID, consent.ID, err)
+	}
+}
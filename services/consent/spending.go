@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/spending"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Party spending limits
+//
+// A party can cap what all of its agents spend combined in a day, week or
+// month, e.g. a household whose agents together may spend $5,000 a day.
+// These limits sit above each agent's consents: consent validation denies a
+// payment that would take the party over any of them, even if the agent's
+// own consent allows it.
+
+type SpendingLimitRequest struct {
+	LimitUSD float64 `json:"limitUSD" binding:"required,gt=0"`
+}
+
+type SpendingLimitResponse struct {
+	ID        string  `json:"id"`
+	PartyID   string  `json:"partyId"`
+	Period    string  `json:"period"`
+	LimitUSD  float64 `json:"limitUSD"`
+	CreatedBy string  `json:"createdBy,omitempty"`
+	CreatedAt string  `json:"createdAt"`
+	UpdatedAt string  `json:"updatedAt"`
+}
+
+type AgentSpendResponse struct {
+	AgentID     string  `json:"agentId"`
+	DisplayName string  `json:"displayName,omitempty"`
+	Payments    int     `json:"payments"`
+	SpentUSD    float64 `json:"spentUSD"`
+	Share       float64 `json:"share"`
+}
+
+type SpendingUsageResponse struct {
+	Period           string                `json:"period"`
+	LimitUSD         float64               `json:"limitUSD"`
+	PeriodStart      string                `json:"periodStart"`
+	PeriodEnd        string                `json:"periodEnd"`
+	SpentUSD         float64               `json:"spentUSD"`
+	RemainingUSD     float64               `json:"remainingUSD"`
+	UtilizationRatio float64               `json:"utilizationRatio"`
+	Agents           []*AgentSpendResponse `json:"agents"`
+}
+
+type SpendingReportResponse struct {
+	PartyID string                   `json:"partyId"`
+	AsOf    string                   `json:"asOf"`
+	Limits  []*SpendingUsageResponse `json:"limits"`
+}
+
+func toSpendingLimitResponse(limit *database.PartySpendingLimit) *SpendingLimitResponse {
+	return &SpendingLimitResponse{
+		ID:        limit.ID,
+		PartyID:   limit.PartyID,
+		Period:    limit.Period,
+		LimitUSD:  limit.LimitUSD,
+		CreatedBy: limit.CreatedBy,
+		CreatedAt: limit.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: limit.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// setSpendingLimit sets the party's limit for a period, replacing any it had
+func setSpendingLimit(c *gin.Context) {
+	partyID, period := c.Param("id"), c.Param("period")
+	if !actsForParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage spending limits of this party"))
+		return
+	}
+	if !spending.ValidPeriod(period) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "period must be daily, weekly or monthly"))
+		return
+	}
+	var req SpendingLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if _, err := repo.PartyRepository().GetByID(partyID); err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Party not found"))
+		return
+	}
+
+	action, oldLimit := "update", 0.0
+	limit, err := repo.PartySpendingLimitRepository().GetByPartyID(partyID, period)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		action = "create"
+		limit = &database.PartySpendingLimit{PartyID: partyID, Period: period, LimitUSD: req.LimitUSD}
+		if principal := common.GetPrincipal(c); principal != nil {
+			limit.CreatedBy = principal.Subject
+		}
+		err = repo.PartySpendingLimitRepository().Create(limit)
+	case err == nil:
+		oldLimit = limit.LimitUSD
+		limit.LimitUSD = req.LimitUSD
+		err = repo.PartySpendingLimitRepository().Update(limit)
+	}
+	if err != nil {
+		common.Error("Failed to set %s spending limit of party %s: %v", period, partyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to set spending limit"))
+		return
+	}
+	recordSpendingLimitChange(c, limit, action, oldLimit)
+
+	common.Info("Party %s %s spending limit set to %.2f USD", partyID, period, limit.LimitUSD)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toSpendingLimitResponse(limit)))
+}
+
+// listSpendingLimits lists the party's spending limits
+func listSpendingLimits(c *gin.Context) {
+	partyID := c.Param("id")
+	if !actsForParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view spending limits of this party"))
+		return
+	}
+
+	limits, err := repo.PartySpendingLimitRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list spending limits of party %s: %v", partyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list spending limits"))
+		return
+	}
+	items := make([]interface{}, len(limits))
+	for i, limit := range limits {
+		items[i] = toSpendingLimitResponse(limit)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// deleteSpendingLimit removes the party's limit for a period
+func deleteSpendingLimit(c *gin.Context) {
+	partyID, period := c.Param("id"), c.Param("period")
+	if !actsForParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage spending limits of this party"))
+		return
+	}
+
+	limit, err := repo.PartySpendingLimitRepository().GetByPartyID(partyID, period)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Spending limit not found"))
+		return
+	}
+	if err := repo.PartySpendingLimitRepository().Delete(limit.ID); err != nil {
+		common.Error("Failed to delete spending limit %s: %v", limit.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete spending limit"))
+		return
+	}
+	recordSpendingLimitChange(c, limit, "delete", limit.LimitUSD)
+
+	common.Info("Party %s %s spending limit removed", partyID, period)
+	c.Status(http.StatusNoContent)
+}
+
+func recordSpendingLimitChange(c *gin.Context, limit *database.PartySpendingLimit, action string, oldLimit float64) {
+	entry := &audit.AuditEntry{
+		EventType:    audit.AuditConsentUpdated,
+		Severity:     audit.SeverityMedium,
+		ResourceID:   limit.ID,
+		ResourceType: "party_spending_limit",
+		Action:       action,
+		Description:  fmt.Sprintf("%s spending limit of party %s: %s", limit.Period, limit.PartyID, action),
+	}
+	if action != "create" {
+		entry.OldValues = map[string]interface{}{"limitUSD": oldLimit}
+	}
+	if action != "delete" {
+		entry.NewValues = map[string]interface{}{"period": limit.Period, "limitUSD": limit.LimitUSD}
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), entry)
+}
+
+// getSpendingReport reports the party's spend against each of its limits in
+// the current period, attributed to the agents that spent it
+func getSpendingReport(c *gin.Context) {
+	partyID := c.Param("id")
+	if !actsForParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view spending of this party"))
+		return
+	}
+
+	now := time.Now()
+	usages, agents, err := partySpending(partyID, "", now)
+	if err != nil {
+		common.Error("Failed to roll up spending of party %s: %v", partyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to report spending"))
+		return
+	}
+
+	response := &SpendingReportResponse{PartyID: partyID, AsOf: now.UTC().Format(time.RFC3339), Limits: []*SpendingUsageResponse{}}
+	for _, usage := range usages {
+		item := &SpendingUsageResponse{
+			Period:           usage.Limit.Period,
+			LimitUSD:         usage.Limit.LimitUSD,
+			PeriodStart:      usage.PeriodStart.Format(time.RFC3339),
+			PeriodEnd:        usage.PeriodEnd.Format(time.RFC3339),
+			SpentUSD:         usage.SpentUSD,
+			RemainingUSD:     usage.RemainingUSD,
+			UtilizationRatio: usage.UtilizationRatio,
+			Agents:           []*AgentSpendResponse{},
+		}
+		for _, spend := range usage.Agents {
+			agentSpend := &AgentSpendResponse{
+				AgentID:  spend.AgentID,
+				Payments: spend.Payments,
+				SpentUSD: spend.SpentUSD,
+				Share:    spend.Share,
+			}
+			if agent, ok := agents[spend.AgentID]; ok {
+				agentSpend.DisplayName = agent.DisplayName
+			}
+			item.Agents = append(item.Agents, agentSpend)
+		}
+		response.Limits = append(response.Limits, item)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// partySpending rolls up the spend of the party's agents against each of its
+// limits, leaving out the given workflow, and returns the agents by ID
+func partySpending(partyID, excludeWorkflowID string, now time.Time) ([]*spending.Usage, map[string]*database.Agent, error) {
+	limits, err := repo.PartySpendingLimitRepository().ListByPartyID(partyID)
+	if err != nil || len(limits) == 0 {
+		return nil, nil, err
+	}
+	agents, err := repo.AgentRepository().ListByOwnerPartyID(partyID)
+	if err != nil || len(agents) == 0 {
+		return nil, nil, err
+	}
+
+	byID := make(map[string]*database.Agent, len(agents))
+	ids := make([]string, len(agents))
+	for i, agent := range agents {
+		byID[agent.ID] = agent
+		ids[i] = agent.ID
+	}
+	workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDsSince(ids, spending.Lookback(limits, now))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usages := make([]*spending.Usage, len(limits))
+	for i, limit := range limits {
+		usages[i] = spending.Compute(limit, workflows, excludeWorkflowID, now)
+	}
+	return usages, byID, nil
+}
+
+// checkPartySpending returns why a payment would take the agent's owner
+// party over one of its spending limits, or "" if it would not
+func checkPartySpending(req ValidateConsentRequest) (string, error) {
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
+		return "", err
+	}
+	usages, _, err := partySpending(agent.OwnerPartyID, req.WorkflowID, time.Now())
+	if err != nil {
+		return "", err
+	}
+	for _, usage := range usages {
+		if usage.Exceeds(req.AmountUSD) {
+			return fmt.Sprintf("Payment would exceed the party's %s spending limit of %.2f USD across all its agents (%.2f USD remaining)",
+				usage.Limit.Period, usage.Limit.LimitUSD, usage.RemainingUSD), nil
+		}
+	}
+	return "", nil
+}
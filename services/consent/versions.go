@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateConsentRequest holds the terms of a consent's next version. Terms
+// left out carry over from the current version.
+type UpdateConsentRequest struct {
+	Rails               *[]string         `json:"rails,omitempty"`
+	CounterpartiesAllow *[]string         `json:"counterpartiesAllow,omitempty"`
+	Limits              *ConsentLimitsReq `json:"limits,omitempty"`
+	PolicyBundleVersion *string           `json:"policyBundleVersion,omitempty"`
+	CosignRule          *CosignRuleReq    `json:"cosignRule,omitempty"`
+	ExpiresAt           *time.Time        `json:"expiresAt,omitempty"` // RFC3339
+}
+
+// updateConsent changes a consent's terms by creating its next version. The
+// current version is superseded but kept unchanged, as the record of what
+// authorized the payments made under it. The agent is notified with a
+// consent.updated event.
+func updateConsent(c *gin.Context) {
+	var req UpdateConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	consent, err := repo.ConsentRepository().GetByID(c.Param("id"))
+	if err != nil || !actsForParty(c, consent.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+
+	now := time.Now()
+	switch consentstate.Of(consent, now) {
+	case consentstate.Revoked:
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "A revoked consent cannot be updated"))
+		return
+	case consentstate.Superseded:
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent was superseded by consent "+*consent.SupersededByID+"; update that one"))
+		return
+	}
+
+	successor := consentstate.Successor(consent, consent.ExpiresAt)
+	if req.Rails != nil {
+		successor.Rails = encodeJSON(*req.Rails)
+	}
+	if req.CounterpartiesAllow != nil {
+		successor.CounterpartiesAllow = encodeJSON(*req.CounterpartiesAllow)
+	}
+	if req.Limits != nil {
+		successor.Limits = encodeJSON(*req.Limits)
+	}
+	if req.PolicyBundleVersion != nil {
+		successor.PolicyBundleVersion = *req.PolicyBundleVersion
+	}
+	if req.CosignRule != nil {
+		successor.CosignRule = encodeJSON(*req.CosignRule)
+	}
+	if req.ExpiresAt != nil {
+		successor.ExpiresAt = req.ExpiresAt
+	}
+	if successor.ExpiresAt != nil && !successor.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "expiresAt must be in the future"))
+		return
+	}
+
+	updated, err := repo.ConsentRepository().Supersede(consent, successor)
+	if err != nil {
+		common.Error("Failed to create version %d of consent %s: %v", successor.Version, consent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update consent"))
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Consent was revoked or updated meanwhile"))
+		return
+	}
+
+	actor := "consent"
+	if principal := common.GetPrincipal(c); principal != nil {
+		actor = principal.Subject
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditConsentUpdated,
+		Severity:     audit.SeverityMedium,
+		UserID:       actor,
+		AgentID:      consent.AgentID,
+		ResourceID:   successor.ID,
+		ResourceType: "consent",
+		Action:       "version", // Not "update", which rewinds the consent's own history
+		Description:  "Consent " + consent.ID + " superseded by " + actor,
+		OldValues:    consentTerms(toConsentResponse(consent)),
+		NewValues:    consentTerms(toConsentResponse(successor)),
+	})
+
+	notifyConsent(events.EventConsentUpdated, successor, map[string]interface{}{
+		"previousConsentId": consent.ID,
+		"version":           successor.Version,
+	})
+
+	common.Info("Consent %s superseded by version %d, %s, by %s", consent.ID, successor.Version, successor.ID, actor)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toConsentResponse(successor)))
+}
+
+// listConsentVersions lists every version of the consent a version belongs
+// to, the first version first, including revoked and deleted ones
+func listConsentVersions(c *gin.Context) {
+	versions, err := repo.ConsentRepository().ListVersions(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, versions[0].AgentID) || !consentInTenant(c, versions[0]) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+
+	items := make([]interface{}, len(versions))
+	for i, version := range versions {
+		items[i] = toConsentResponse(version)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// consentTerms returns the terms of a consent for its audit entries
+func consentTerms(consent interface{}) map[string]interface{} {
+	var terms map[string]interface{}
+	if data, err := json.Marshal(consent); err == nil {
+		json.Unmarshal(data, &terms)
+	}
+	return terms
+}
+
+// encodeJSON encodes a value for a JSON column
+func encodeJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
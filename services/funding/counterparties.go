@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/funding"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateCounterpartyAccountRequest registers a payee's bank account. Either
+// the account numbers are given and verified by micro-deposits, or the payee
+// links the account through the open-banking provider and the public token
+// verifies it instantly.
+type CreateCounterpartyAccountRequest struct {
+	PartyID       string `json:"partyId" binding:"required"`
+	Counterparty  string `json:"counterparty" binding:"required"`
+	HolderName    string `json:"holderName" binding:"required"`
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	AccountNumber string `json:"accountNumber,omitempty"`
+	AccountType   string `json:"accountType,omitempty"` // "checking" (default) or "savings"
+	PublicToken   string `json:"publicToken,omitempty"`
+	AccountID     string `json:"accountId,omitempty"` // Provider account ID within the public token's item
+}
+
+type ConfirmMicroDepositsRequest struct {
+	Amounts []float64 `json:"amounts" binding:"required"` // USD amounts, in any order
+}
+
+type CounterpartyAccountResponse struct {
+	ID                   string  `json:"id"`
+	PartyID              string  `json:"partyId"`
+	Counterparty         string  `json:"counterparty"`
+	HolderName           string  `json:"holderName"`
+	RoutingNumber        string  `json:"routingNumber"`
+	AccountMask          string  `json:"accountMask"`
+	AccountType          string  `json:"accountType"`
+	Status               string  `json:"status"`
+	VerificationMethod   string  `json:"verificationMethod"`
+	VerificationAttempts int     `json:"verificationAttempts"`
+	VerifiedAt           *string `json:"verifiedAt,omitempty"`
+	CreatedAt            string  `json:"createdAt"`
+	UpdatedAt            string  `json:"updatedAt"`
+}
+
+func toCounterpartyAccountResponse(account *database.CounterpartyBankAccount) *CounterpartyAccountResponse {
+	response := &CounterpartyAccountResponse{
+		ID:                   account.ID,
+		PartyID:              account.PartyID,
+		Counterparty:         account.Counterparty,
+		HolderName:           account.HolderName,
+		RoutingNumber:        account.RoutingNumber,
+		AccountMask:          account.AccountMask,
+		AccountType:          account.AccountType,
+		Status:               account.Status,
+		VerificationMethod:   account.VerificationMethod,
+		VerificationAttempts: account.VerificationAttempts,
+		CreatedAt:            account.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:            account.UpdatedAt.Format(time.RFC3339),
+	}
+	if account.VerifiedAt != nil {
+		verifiedAt := account.VerifiedAt.Format(time.RFC3339)
+		response.VerifiedAt = &verifiedAt
+	}
+	return response
+}
+
+// loadCounterpartyAccount fetches the account named in the path and checks
+// ownership, writing the error response itself when it returns nil
+func loadCounterpartyAccount(c *gin.Context) *database.CounterpartyBankAccount {
+	account, err := repo.CounterpartyBankAccountRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, account.PartyID, "counterparty_account", account.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Counterparty account not found"))
+		return nil
+	}
+	if !canManageParty(c, account.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to access this counterparty account"))
+		return nil
+	}
+	return account
+}
+
+func createCounterpartyAccount(c *gin.Context) {
+	var req CreateCounterpartyAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId, counterparty and holderName are required"))
+		return
+	}
+
+	if !canManageParty(c, req.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to add counterparty accounts for this party"))
+		return
+	}
+
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerCallTimeout)
+	defer cancel()
+
+	account := &database.CounterpartyBankAccount{
+		PartyID:      req.PartyID,
+		Counterparty: req.Counterparty,
+		HolderName:   req.HolderName,
+		Status:       funding.StatusPendingVerification,
+	}
+
+	var details *funding.BankAccountDetails
+	switch {
+	case req.PublicToken != "":
+		if req.AccountID == "" {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "accountId is required with publicToken"))
+			return
+		}
+		resolved, err := accountVerifier.ResolveAccount(ctx, req.PublicToken, req.AccountID)
+		if err != nil {
+			common.Error("Failed to resolve counterparty account: %v", err)
+			c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Failed to verify account"))
+			return
+		}
+		details = resolved
+		account.VerificationMethod = funding.VerificationInstant
+
+		// The linked account must belong to the declared holder
+		if resolved.HolderName == "" || funding.NamesMatch(resolved.HolderName, req.HolderName) {
+			now := time.Now()
+			account.Status = funding.StatusVerified
+			account.VerifiedAt = &now
+		} else {
+			account.Status = funding.StatusVerificationFailed
+		}
+	case req.RoutingNumber != "" && req.AccountNumber != "":
+		if req.AccountType == "" {
+			req.AccountType = "checking"
+		}
+		details = &funding.BankAccountDetails{
+			HolderName:    req.HolderName,
+			RoutingNumber: req.RoutingNumber,
+			AccountNumber: req.AccountNumber,
+			AccountType:   req.AccountType,
+		}
+		account.VerificationMethod = funding.VerificationMicroDeposits
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Either routingNumber and accountNumber, or publicToken and accountId, are required"))
+		return
+	}
+
+	if !funding.ValidRoutingNumber(details.RoutingNumber) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", funding.ErrInvalidRoutingNumber.Error()))
+		return
+	}
+	if details.AccountType != "checking" && details.AccountType != "savings" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "accountType must be checking or savings"))
+		return
+	}
+
+	sealedNumber, err := sealer.Seal(details.AccountNumber)
+	if err != nil {
+		common.Error("Failed to encrypt account number: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to add counterparty account"))
+		return
+	}
+	account.RoutingNumber = details.RoutingNumber
+	account.AccountNumber = sealedNumber
+	account.AccountMask = funding.Mask(details.AccountNumber)
+	account.AccountType = details.AccountType
+
+	if account.VerificationMethod == funding.VerificationMicroDeposits {
+		amounts, err := funding.NewMicroDepositAmounts()
+		if err != nil {
+			common.Error("Failed to pick micro-deposit amounts: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to add counterparty account"))
+			return
+		}
+		reference, err := accountVerifier.SendMicroDeposits(ctx, *details, amounts)
+		if errors.Is(err, funding.ErrMicroDepositsUnsupported) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED", "Provider does not send micro-deposits; link the account with a public token"))
+			return
+		}
+		if err != nil {
+			common.Error("Failed to send micro-deposits: %v", err)
+			c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Failed to send micro-deposits"))
+			return
+		}
+		sealedAmounts, err := sealer.Seal(funding.EncodeMicroDeposits(amounts))
+		if err != nil {
+			common.Error("Failed to encrypt micro-deposit amounts: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to add counterparty account"))
+			return
+		}
+		account.MicroDeposits = sealedAmounts
+		account.ProviderReference = reference
+	}
+
+	if err := repo.CounterpartyBankAccountRepository().Create(account); err != nil {
+		common.Error("Failed to create counterparty account: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to add counterparty account"))
+		return
+	}
+
+	common.Info("Counterparty account %s added for %s (%s, %s)", account.ID, account.Counterparty, account.VerificationMethod, account.Status)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toCounterpartyAccountResponse(account)))
+}
+
+func listCounterpartyAccounts(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to view counterparty accounts for this party"))
+		return
+	}
+
+	accounts, err := repo.CounterpartyBankAccountRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list counterparty accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list counterparty accounts"))
+		return
+	}
+
+	responses := make([]interface{}, len(accounts))
+	for i, account := range accounts {
+		responses[i] = toCounterpartyAccountResponse(account)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(responses, 1, len(responses), len(responses))))
+}
+
+func getCounterpartyAccount(c *gin.Context) {
+	account := loadCounterpartyAccount(c)
+	if account == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyAccountResponse(account)))
+}
+
+// confirmMicroDeposits checks the amounts the payee saw arrive. Accounts fail
+// verification after MaxMicroDepositAttempts wrong confirmations.
+func confirmMicroDeposits(c *gin.Context) {
+	var req ConfirmMicroDepositsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amounts are required"))
+		return
+	}
+
+	account := loadCounterpartyAccount(c)
+	if account == nil {
+		return
+	}
+
+	if account.VerificationMethod != funding.VerificationMicroDeposits || account.Status != funding.StatusPendingVerification {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATE", "Counterparty account is not awaiting micro-deposit confirmation"))
+		return
+	}
+
+	expected, err := sealer.Open(account.MicroDeposits)
+	if err != nil {
+		common.Error("Failed to decrypt micro-deposits for counterparty account %s: %v", account.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to verify counterparty account"))
+		return
+	}
+
+	account.VerificationAttempts++
+	matched := funding.MicroDepositsMatch(expected, req.Amounts)
+	if matched {
+		now := time.Now()
+		account.Status = funding.StatusVerified
+		account.VerifiedAt = &now
+		account.MicroDeposits = ""
+	} else if account.VerificationAttempts >= funding.MaxMicroDepositAttempts {
+		account.Status = funding.StatusVerificationFailed
+		account.MicroDeposits = ""
+	}
+
+	if err := repo.CounterpartyBankAccountRepository().Update(account); err != nil {
+		common.Error("Failed to update counterparty account: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update counterparty account"))
+		return
+	}
+
+	if !matched {
+		common.Warn("Micro-deposit mismatch for counterparty account %s (attempt %d)", account.ID, account.VerificationAttempts)
+		message := funding.ErrMicroDepositMismatch.Error()
+		if account.Status == funding.StatusVerificationFailed {
+			message = funding.ErrVerificationAttemptsSpent.Error()
+		}
+		c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("VERIFICATION_FAILED", message))
+		return
+	}
+
+	common.Info("Counterparty account %s verified by micro-deposits", account.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyAccountResponse(account)))
+}
+
+func disableCounterpartyAccount(c *gin.Context) {
+	account := loadCounterpartyAccount(c)
+	if account == nil {
+		return
+	}
+
+	account.Status = funding.StatusDisabled
+	account.MicroDeposits = ""
+	if err := repo.CounterpartyBankAccountRepository().Update(account); err != nil {
+		common.Error("Failed to disable counterparty account: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to disable counterparty account"))
+		return
+	}
+
+	common.Info("Counterparty account disabled: %s", account.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyAccountResponse(account)))
+}
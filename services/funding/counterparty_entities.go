@@ -0,0 +1,324 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/counterparties"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Counterparties
+//
+// A counterparty is a payee a party's agents pay, with its identifiers, the
+// bank account payments go to, its KYC status and risk rating. Payments that
+// reference one by ID let consent, risk and compliance check the payee
+// itself rather than a free-form name. The party keeps its counterparties on
+// an allow or deny list; only compliance officers set KYC status and rating.
+
+type CounterpartyRequest struct {
+	PartyID       string            `json:"partyId"`
+	Name          *string           `json:"name" binding:"omitempty,min=1,max=255"`
+	Type          *string           `json:"type"` // "individual" or "organization"
+	Email         *string           `json:"email" binding:"omitempty,max=255"`
+	Country       *string           `json:"country" binding:"omitempty,len=2"`
+	Identifiers   map[string]string `json:"identifiers"`   // e.g. taxId, lei, domain; replaces any set
+	BankAccountID *string           `json:"bankAccountId"` // "" clears it
+	ListStatus    *string           `json:"listStatus"`    // "none", "allowed" or "denied"
+	ListReason    *string           `json:"listReason" binding:"omitempty,max=500"`
+}
+
+type CounterpartyKYCRequest struct {
+	KYCStatus  string `json:"kycStatus" binding:"required"`
+	RiskRating string `json:"riskRating,omitempty"`
+	Notes      string `json:"notes,omitempty" binding:"max=1000"`
+}
+
+type CounterpartyResponse struct {
+	ID            string            `json:"id"`
+	PartyID       string            `json:"partyId"`
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	Email         string            `json:"email,omitempty"`
+	Country       string            `json:"country,omitempty"`
+	Identifiers   map[string]string `json:"identifiers"`
+	BankAccountID string            `json:"bankAccountId,omitempty"`
+	KYCStatus     string            `json:"kycStatus"`
+	RiskRating    string            `json:"riskRating"`
+	KYCNotes      string            `json:"kycNotes,omitempty"`
+	KYCReviewedBy string            `json:"kycReviewedBy,omitempty"`
+	KYCReviewedAt string            `json:"kycReviewedAt,omitempty"`
+	ListStatus    string            `json:"listStatus"`
+	ListReason    string            `json:"listReason,omitempty"`
+	CreatedAt     string            `json:"createdAt"`
+	UpdatedAt     string            `json:"updatedAt"`
+}
+
+func toCounterpartyResponse(counterparty *database.Counterparty) *CounterpartyResponse {
+	response := &CounterpartyResponse{
+		ID:            counterparty.ID,
+		PartyID:       counterparty.PartyID,
+		Name:          counterparty.Name,
+		Type:          counterparty.Type,
+		Email:         counterparty.Email,
+		Country:       counterparty.Country,
+		Identifiers:   counterparties.DecodeIdentifiers(counterparty.Identifiers),
+		KYCStatus:     counterparty.KYCStatus,
+		RiskRating:    counterparty.RiskRating,
+		KYCNotes:      counterparty.KYCNotes,
+		KYCReviewedBy: counterparty.KYCReviewedBy,
+		ListStatus:    counterparty.ListStatus,
+		ListReason:    counterparty.ListReason,
+		CreatedAt:     counterparty.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     counterparty.UpdatedAt.Format(time.RFC3339),
+	}
+	if counterparty.BankAccountID != nil {
+		response.BankAccountID = *counterparty.BankAccountID
+	}
+	if counterparty.KYCReviewedAt != nil {
+		response.KYCReviewedAt = counterparty.KYCReviewedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// loadCounterparty fetches the counterparty named in the path and checks
+// ownership, writing the error response itself when it returns nil
+func loadCounterparty(c *gin.Context) *database.Counterparty {
+	counterparty, err := repo.CounterpartyRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, counterparty.PartyID, "counterparty", counterparty.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Counterparty not found"))
+		return nil
+	}
+	if !canManageParty(c, counterparty.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to access this counterparty"))
+		return nil
+	}
+	return counterparty
+}
+
+func createCounterparty(c *gin.Context) {
+	var req CounterpartyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if req.PartyID == "" || req.Name == nil || req.Type == nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId, name and type are required"))
+		return
+	}
+	if !canManageParty(c, req.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to add counterparties for this party"))
+		return
+	}
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+	if _, err := repo.CounterpartyRepository().GetByName(req.PartyID, *req.Name); err == nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("CONFLICT", "The party already has a counterparty with this name"))
+		return
+	}
+
+	counterparty := &database.Counterparty{
+		PartyID:    req.PartyID,
+		KYCStatus:  counterparties.KYCUnverified,
+		RiskRating: counterparties.RatingUnrated,
+		ListStatus: counterparties.ListNone,
+	}
+	if principal := common.GetPrincipal(c); principal != nil {
+		counterparty.CreatedBy = principal.Subject
+	}
+	if !applyCounterpartyRequest(c, counterparty, req) {
+		return
+	}
+	if err := repo.CounterpartyRepository().Create(counterparty); err != nil {
+		common.Error("Failed to create counterparty: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create counterparty"))
+		return
+	}
+
+	common.Info("Created counterparty %s (%s) for party %s", counterparty.ID, counterparty.Name, counterparty.PartyID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toCounterpartyResponse(counterparty)))
+}
+
+// listCounterparties lists a party's counterparties, filtered by
+// ?kycStatus= and ?listStatus=
+func listCounterparties(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to view counterparties of this party"))
+		return
+	}
+
+	filter := database.CounterpartyFilter{KYCStatus: c.Query("kycStatus"), ListStatus: c.Query("listStatus")}
+	list, err := repo.CounterpartyRepository().ListByPartyID(partyID, filter)
+	if err != nil {
+		common.Error("Failed to list counterparties: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list counterparties"))
+		return
+	}
+	items := make([]interface{}, len(list))
+	for i, counterparty := range list {
+		items[i] = toCounterpartyResponse(counterparty)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getCounterparty(c *gin.Context) {
+	counterparty := loadCounterparty(c)
+	if counterparty == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyResponse(counterparty)))
+}
+
+// updateCounterparty changes the fields given; the party cannot be changed
+func updateCounterparty(c *gin.Context) {
+	counterparty := loadCounterparty(c)
+	if counterparty == nil {
+		return
+	}
+	var req CounterpartyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if req.PartyID != "" && req.PartyID != counterparty.PartyID {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId cannot be changed"))
+		return
+	}
+	if req.Name != nil && !strings.EqualFold(*req.Name, counterparty.Name) {
+		if _, err := repo.CounterpartyRepository().GetByName(counterparty.PartyID, *req.Name); err == nil {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("CONFLICT", "The party already has a counterparty with this name"))
+			return
+		}
+	}
+	if !applyCounterpartyRequest(c, counterparty, req) {
+		return
+	}
+	if err := repo.CounterpartyRepository().Update(counterparty); err != nil {
+		common.Error("Failed to update counterparty %s: %v", counterparty.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update counterparty"))
+		return
+	}
+
+	common.Info("Updated counterparty %s", counterparty.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyResponse(counterparty)))
+}
+
+// applyCounterpartyRequest validates the fields given and sets them on the
+// counterparty, writing the error response itself when it returns false
+func applyCounterpartyRequest(c *gin.Context, counterparty *database.Counterparty, req CounterpartyRequest) bool {
+	if req.Type != nil && !counterparties.ValidType(*req.Type) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "type must be individual or organization"))
+		return false
+	}
+	if req.ListStatus != nil && !counterparties.ValidListStatus(*req.ListStatus) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "listStatus must be none, allowed or denied"))
+		return false
+	}
+	if req.BankAccountID != nil && *req.BankAccountID != "" {
+		account, err := repo.CounterpartyBankAccountRepository().GetByID(*req.BankAccountID)
+		if err != nil || account.PartyID != counterparty.PartyID {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Counterparty account not found for this party"))
+			return false
+		}
+	}
+
+	if req.Name != nil {
+		counterparty.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		counterparty.Type = *req.Type
+	}
+	if req.Email != nil {
+		counterparty.Email = *req.Email
+	}
+	if req.Country != nil {
+		counterparty.Country = strings.ToUpper(*req.Country)
+	}
+	if req.Identifiers != nil {
+		counterparty.Identifiers = counterparties.EncodeIdentifiers(req.Identifiers)
+	}
+	if req.BankAccountID != nil {
+		counterparty.BankAccountID = nil
+		if *req.BankAccountID != "" {
+			counterparty.BankAccountID = req.BankAccountID
+		}
+	}
+	if req.ListStatus != nil {
+		counterparty.ListStatus = *req.ListStatus
+	}
+	if req.ListReason != nil {
+		counterparty.ListReason = *req.ListReason
+	}
+	return true
+}
+
+func deleteCounterparty(c *gin.Context) {
+	counterparty := loadCounterparty(c)
+	if counterparty == nil {
+		return
+	}
+	if err := repo.CounterpartyRepository().Delete(counterparty.ID); err != nil {
+		common.Error("Failed to delete counterparty %s: %v", counterparty.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete counterparty"))
+		return
+	}
+
+	common.Info("Deleted counterparty %s", counterparty.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// reviewCounterpartyKYC records a compliance officer's KYC decision and risk
+// rating for a counterparty
+func reviewCounterpartyKYC(c *gin.Context) {
+	counterparty, err := repo.CounterpartyRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, counterparty.PartyID, "counterparty", counterparty.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Counterparty not found"))
+		return
+	}
+	var req CounterpartyKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if !counterparties.ValidKYCStatus(req.KYCStatus) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "kycStatus must be unverified, pending, verified or rejected"))
+		return
+	}
+	if req.RiskRating != "" && !counterparties.ValidRiskRating(req.RiskRating) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "riskRating must be unrated, low, medium or high"))
+		return
+	}
+
+	now := time.Now()
+	counterparty.KYCStatus = req.KYCStatus
+	if req.RiskRating != "" {
+		counterparty.RiskRating = req.RiskRating
+	}
+	counterparty.KYCNotes = req.Notes
+	counterparty.KYCReviewedAt = &now
+	if principal := common.GetPrincipal(c); principal != nil {
+		counterparty.KYCReviewedBy = principal.Subject
+	}
+	if err := repo.CounterpartyRepository().Update(counterparty); err != nil {
+		common.Error("Failed to record KYC review of counterparty %s: %v", counterparty.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record KYC review"))
+		return
+	}
+
+	common.Info("Counterparty %s KYC status %s, risk rating %s", counterparty.ID, counterparty.KYCStatus, counterparty.RiskRating)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toCounterpartyResponse(counterparty)))
+}
@@ -0,0 +1,689 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/funding"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+var repo database.Repository
+var authConfig *common.AuthConfig
+var provider funding.Provider
+var sealer *funding.Sealer
+var accountVerifier funding.AccountVerifier
+
+// Ledger accounts funding transfers post to, created per agent on first use
+const (
+	walletAccountName        = "Wallet"
+	achClearingAccountName   = "ACH Clearing"
+	ownerFundingAccountName  = "Owner Funding"
+	providerCallTimeout      = 30 * time.Second
+	fundingLinkClientName    = "Agent Payments"
+	maxFundingTransferAmount = 10000.0
+)
+
+type LinkTokenRequest struct {
+	PartyID string `json:"partyId" binding:"required"`
+}
+
+type LinkFundingSourceRequest struct {
+	PartyID     string `json:"partyId" binding:"required"`
+	PublicToken string `json:"publicToken" binding:"required"`
+}
+
+type FundingTransferRequest struct {
+	AgentID     string  `json:"agentId" binding:"required"`
+	AmountUSD   float64 `json:"amountUSD" binding:"required"`
+	Description string  `json:"description"`
+}
+
+type FundingSourceResponse struct {
+	ID                 string  `json:"id"`
+	PartyID            string  `json:"partyId"`
+	Provider           string  `json:"provider"`
+	InstitutionName    string  `json:"institutionName"`
+	AccountName        string  `json:"accountName"`
+	Mask               string  `json:"mask"`
+	AccountType        string  `json:"accountType"`
+	AccountSubtype     string  `json:"accountSubtype"`
+	Status             string  `json:"status"`
+	VerificationMethod string  `json:"verificationMethod,omitempty"`
+	VerifiedAt         *string `json:"verifiedAt,omitempty"`
+	CreatedAt          string  `json:"createdAt"`
+	UpdatedAt          string  `json:"updatedAt"`
+}
+
+type FundingTransferResponse struct {
+	ID                  string  `json:"id"`
+	FundingSourceID     string  `json:"fundingSourceId"`
+	AgentID             string  `json:"agentId"`
+	Type                string  `json:"type"`
+	AmountUSD           float64 `json:"amountUSD"`
+	Description         string  `json:"description"`
+	Status              string  `json:"status"`
+	ProviderTransferID  string  `json:"providerTransferId,omitempty"`
+	LedgerTransactionID string  `json:"ledgerTransactionId,omitempty"`
+	ErrorMessage        string  `json:"errorMessage,omitempty"`
+	CreatedAt           string  `json:"createdAt"`
+}
+
+func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("funding")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("funding")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.Connection())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Run migrations
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Initialize repository
+	repo = database.NewRepository(db)
+
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Initialize the open-banking provider and access token encryption
+	provider, err = funding.NewProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize funding provider: %v", err)
+	}
+	sealer, err = funding.NewSealerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize funding encryption: %v", err)
+	}
+	accountVerifier, err = funding.NewAccountVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize account verifier: %v", err)
+	}
+	common.Info("Using funding provider: %s", provider.Name())
+
+	r := gin.Default()
+
+	// Setup common middleware
+	common.SetupCommonMiddleware(r, func() error {
+		return repo.HealthCheck()
+	})
+
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
+	// Health check endpoint
+	r.GET("/healthz", func(c *gin.Context) {
+		if err := repo.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unhealthy", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "funding service ok"})
+	})
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
+	// API v1 routes
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
+	{
+		// Account linking
+		v1.POST("/funding/link-token", common.RequireScopes(common.ScopeFundingWrite), createLinkToken)
+		v1.POST("/funding-sources", common.RequireScopes(common.ScopeFundingWrite), linkFundingSource)
+		v1.GET("/funding-sources", common.RequireScopes(common.ScopeFundingRead), listFundingSources)
+		v1.GET("/funding-sources/:id", common.RequireScopes(common.ScopeFundingRead), getFundingSource)
+		v1.POST("/funding-sources/:id/verify", common.RequireScopes(common.ScopeFundingWrite), verifyFundingSource)
+		v1.DELETE("/funding-sources/:id", common.RequireScopes(common.ScopeFundingWrite), disableFundingSource)
+
+		// Transfers from verified sources
+		v1.POST("/funding-sources/:id/top-ups", common.RequireScopes(common.ScopeFundingWrite), topUpWallet)
+		v1.POST("/funding-sources/:id/debits", common.RequireScopes(common.ScopeFundingWrite), achDebit)
+		v1.GET("/funding-sources/:id/transfers", common.RequireScopes(common.ScopeFundingRead), listFundingTransfers)
+
+		// Payee bank accounts, verified before ACH payments are sent to them
+		v1.POST("/counterparty-accounts", common.RequireScopes(common.ScopeFundingWrite), createCounterpartyAccount)
+		v1.GET("/counterparty-accounts", common.RequireScopes(common.ScopeFundingRead), listCounterpartyAccounts)
+		v1.GET("/counterparty-accounts/:id", common.RequireScopes(common.ScopeFundingRead), getCounterpartyAccount)
+		v1.POST("/counterparty-accounts/:id/verify", common.RequireScopes(common.ScopeFundingWrite), confirmMicroDeposits)
+		v1.DELETE("/counterparty-accounts/:id", common.RequireScopes(common.ScopeFundingWrite), disableCounterpartyAccount)
+
+		// Counterparties payments reference by ID
+		v1.POST("/counterparties", common.RequireScopes(common.ScopeFundingWrite), createCounterparty)
+		v1.GET("/counterparties", common.RequireScopes(common.ScopeFundingRead), listCounterparties)
+		v1.GET("/counterparties/:id", common.RequireScopes(common.ScopeFundingRead), getCounterparty)
+		v1.PATCH("/counterparties/:id", common.RequireScopes(common.ScopeFundingWrite), updateCounterparty)
+		v1.DELETE("/counterparties/:id", common.RequireScopes(common.ScopeFundingWrite), deleteCounterparty)
+		v1.PUT("/counterparties/:id/kyc", common.RequireScopes(common.ScopeComplianceReview), reviewCounterpartyKYC)
+	}
+
+	common.Info("Funding service running on %s", cfg.Addr())
+	if err := common.NewServer(cfg.Addr(), r).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// canManageParty reports whether the caller may manage funding sources of a
+// party. Attempts to reach another tenant's party are audited.
+func canManageParty(c *gin.Context, partyID string) bool {
+	principal := common.GetPrincipal(c)
+	if principal == nil || principal.Type == common.PrincipalService {
+		return true
+	}
+	if !tenancy.Check(c, repo, partyID, "party", partyID) {
+		return false
+	}
+	return principal.Type == common.PrincipalParty && principal.PartyID == partyID
+}
+
+// loadFundingSource fetches the source named in the path and checks ownership,
+// writing the error response itself when it returns nil
+func loadFundingSource(c *gin.Context) *database.FundingSource {
+	source, err := repo.FundingSourceRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, source.PartyID, "funding_source", source.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Funding source not found"))
+		return nil
+	}
+	if !canManageParty(c, source.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to access this funding source"))
+		return nil
+	}
+	return source
+}
+
+func toFundingSourceResponse(source *database.FundingSource) *FundingSourceResponse {
+	response := &FundingSourceResponse{
+		ID:                 source.ID,
+		PartyID:            source.PartyID,
+		Provider:           source.Provider,
+		InstitutionName:    source.InstitutionName,
+		AccountName:        source.AccountName,
+		Mask:               source.Mask,
+		AccountType:        source.AccountType,
+		AccountSubtype:     source.AccountSubtype,
+		Status:             source.Status,
+		VerificationMethod: source.VerificationMethod,
+		CreatedAt:          source.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:          source.UpdatedAt.Format(time.RFC3339),
+	}
+	if source.VerifiedAt != nil {
+		verifiedAt := source.VerifiedAt.Format(time.RFC3339)
+		response.VerifiedAt = &verifiedAt
+	}
+	return response
+}
+
+func toFundingTransferResponse(transfer *database.FundingTransfer) *FundingTransferResponse {
+	return &FundingTransferResponse{
+		ID:                  transfer.ID,
+		FundingSourceID:     transfer.FundingSourceID,
+		AgentID:             transfer.AgentID,
+		Type:                transfer.Type,
+		AmountUSD:           transfer.AmountUSD,
+		Description:         transfer.Description,
+		Status:              transfer.Status,
+		ProviderTransferID:  transfer.ProviderTransferID,
+		LedgerTransactionID: transfer.LedgerTransactionID,
+		ErrorMessage:        transfer.ErrorMessage,
+		CreatedAt:           transfer.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func createLinkToken(c *gin.Context) {
+	var req LinkTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+
+	if !canManageParty(c, req.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to link accounts for this party"))
+		return
+	}
+
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerCallTimeout)
+	defer cancel()
+
+	linkToken, err := provider.CreateLinkToken(ctx, req.PartyID, fundingLinkClientName)
+	if err != nil {
+		common.Error("Failed to create link token: %v", err)
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Failed to create link token"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(gin.H{
+		"linkToken": linkToken,
+		"provider":  provider.Name(),
+	}))
+}
+
+func linkFundingSource(c *gin.Context) {
+	var req LinkFundingSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId and publicToken are required"))
+		return
+	}
+
+	if !canManageParty(c, req.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to link accounts for this party"))
+		return
+	}
+
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerCallTimeout)
+	defer cancel()
+
+	item, err := provider.ExchangePublicToken(ctx, req.PublicToken)
+	if err != nil {
+		common.Error("Failed to exchange public token: %v", err)
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Failed to link account"))
+		return
+	}
+	if len(item.Accounts) == 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "No debitable accounts were linked"))
+		return
+	}
+
+	// The access token grants ongoing access to the bank login, so it is only stored sealed
+	sealedToken, err := sealer.Seal(item.AccessToken)
+	if err != nil {
+		common.Error("Failed to encrypt access token: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to link account"))
+		return
+	}
+
+	responses := make([]interface{}, 0, len(item.Accounts))
+	for _, account := range item.Accounts {
+		source := &database.FundingSource{
+			PartyID:           req.PartyID,
+			Provider:          provider.Name(),
+			ItemID:            item.ItemID,
+			ExternalAccountID: account.ID,
+			AccessToken:       sealedToken,
+			InstitutionName:   item.InstitutionName,
+			AccountName:       account.Name,
+			Mask:              account.Mask,
+			AccountType:       account.Type,
+			AccountSubtype:    account.Subtype,
+			Status:            funding.StatusPendingVerification,
+		}
+		if err := repo.FundingSourceRepository().Create(source); err != nil {
+			common.Error("Failed to create funding source: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create funding source"))
+			return
+		}
+		responses = append(responses, toFundingSourceResponse(source))
+	}
+
+	common.Info("Linked %d funding source(s) from %s for party %s", len(responses), item.InstitutionName, req.PartyID)
+	c.JSON(http.StatusCreated, common.NewListResponse(responses, 1, len(responses), len(responses)))
+}
+
+func listFundingSources(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Not permitted to view funding sources for this party"))
+		return
+	}
+
+	sources, err := repo.FundingSourceRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list funding sources: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list funding sources"))
+		return
+	}
+
+	responses := make([]interface{}, len(sources))
+	for i, source := range sources {
+		responses[i] = toFundingSourceResponse(source)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(responses, 1, len(responses), len(responses))))
+}
+
+func getFundingSource(c *gin.Context) {
+	source := loadFundingSource(c)
+	if source == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFundingSourceResponse(source)))
+}
+
+// verifyFundingSource confirms with the provider that the account is held by
+// the party that linked it, before any money can be pulled from it
+func verifyFundingSource(c *gin.Context) {
+	source := loadFundingSource(c)
+	if source == nil {
+		return
+	}
+
+	if source.Status == funding.StatusDisabled {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATE", "Funding source is disabled"))
+		return
+	}
+	if source.Status == funding.StatusVerified {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(toFundingSourceResponse(source)))
+		return
+	}
+
+	party, err := repo.PartyRepository().GetByID(source.PartyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	accessToken, err := sealer.Open(source.AccessToken)
+	if err != nil {
+		common.Error("Failed to decrypt access token for funding source %s: %v", source.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to verify funding source"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerCallTimeout)
+	defer cancel()
+
+	owned, err := provider.VerifyOwnership(ctx, accessToken, source.ExternalAccountID, party.Name)
+	if err != nil {
+		common.Error("Failed to verify ownership of funding source %s: %v", source.ID, err)
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Failed to verify funding source"))
+		return
+	}
+
+	source.VerificationMethod = "identity_match"
+	if owned {
+		now := time.Now()
+		source.Status = funding.StatusVerified
+		source.VerifiedAt = &now
+	} else {
+		source.Status = funding.StatusVerificationFailed
+	}
+
+	if err := repo.FundingSourceRepository().Update(source); err != nil {
+		common.Error("Failed to update funding source: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update funding source"))
+		return
+	}
+
+	common.Info("Funding source %s verification: %s", source.ID, source.Status)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFundingSourceResponse(source)))
+}
+
+func disableFundingSource(c *gin.Context) {
+	source := loadFundingSource(c)
+	if source == nil {
+		return
+	}
+
+	source.Status = funding.StatusDisabled
+	if err := repo.FundingSourceRepository().Update(source); err != nil {
+		common.Error("Failed to disable funding source: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to disable funding source"))
+		return
+	}
+
+	common.Info("Funding source disabled: %s", source.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFundingSourceResponse(source)))
+}
+
+// topUpWallet pulls funds into the agent's wallet
+func topUpWallet(c *gin.Context) {
+	executeFundingTransfer(c, funding.TransferTopUp, walletAccountName)
+}
+
+// achDebit pulls funds to settle an ACH payment made by the agent
+func achDebit(c *gin.Context) {
+	executeFundingTransfer(c, funding.TransferACHDebit, achClearingAccountName)
+}
+
+// executeFundingTransfer debits a verified source through the provider and
+// records the funds in the agent's ledger: the destination asset account is
+// debited against the owner funding equity account
+func executeFundingTransfer(c *gin.Context, transferType, destinationAccount string) {
+	source := loadFundingSource(c)
+	if source == nil {
+		return
+	}
+
+	var req FundingTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	if req.AmountUSD <= 0 || req.AmountUSD > maxFundingTransferAmount {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amountUSD must be between 0 and 10000"))
+		return
+	}
+
+	if source.Status != funding.StatusVerified {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("NOT_VERIFIED", funding.ErrNotVerified.Error()))
+		return
+	}
+
+	// Funds may only flow to agents owned by the party holding the account
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
+		return
+	}
+	if agent.OwnerPartyID != source.PartyID {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Agent is not owned by the funding source's party"))
+		return
+	}
+
+	party, err := repo.PartyRepository().GetByID(source.PartyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	if req.Description == "" {
+		req.Description = "Funding " + transferType
+	}
+
+	transfer := &database.FundingTransfer{
+		FundingSourceID: source.ID,
+		AgentID:         req.AgentID,
+		Type:            transferType,
+		AmountUSD:       req.AmountUSD,
+		Description:     req.Description,
+		Status:          "pending",
+	}
+	if err := repo.FundingTransferRepository().Create(transfer); err != nil {
+		common.Error("Failed to create funding transfer: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create funding transfer"))
+		return
+	}
+
+	accessToken, err := sealer.Open(source.AccessToken)
+	if err != nil {
+		common.Error("Failed to decrypt access token for funding source %s: %v", source.ID, err)
+		failFundingTransfer(c, transfer, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to initiate debit")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), providerCallTimeout)
+	defer cancel()
+
+	result, err := provider.InitiateDebit(ctx, funding.DebitRequest{
+		AccessToken:    accessToken,
+		AccountID:      source.ExternalAccountID,
+		AmountUSD:      req.AmountUSD,
+		Description:    req.Description,
+		LegalName:      party.Name,
+		IdempotencyKey: transfer.ID,
+	})
+	if err != nil {
+		common.Error("Debit from funding source %s failed: %v", source.ID, err)
+		transfer.ErrorMessage = err.Error()
+		failFundingTransfer(c, transfer, http.StatusBadGateway, "PROVIDER_ERROR", "Failed to initiate debit")
+		return
+	}
+	transfer.ProviderTransferID = result.ID
+
+	transactionID, err := postFundingTransaction(transfer, destinationAccount)
+	if err != nil {
+		common.Error("Failed to post funding transfer %s to ledger: %v", transfer.ID, err)
+		transfer.ErrorMessage = "ledger posting failed"
+		failFundingTransfer(c, transfer, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to record funding transfer")
+		return
+	}
+
+	transfer.LedgerTransactionID = transactionID
+	transfer.Status = "posted"
+	if result.Status == "pending" {
+		transfer.Status = "pending"
+	}
+	if err := repo.FundingTransferRepository().Update(transfer); err != nil {
+		common.Error("Failed to update funding transfer: %v", err)
+	}
+
+	common.Info("Funding transfer %s (%s) of $%.2f from %s for agent %s", transfer.ID, transferType, transfer.AmountUSD, source.ID, transfer.AgentID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toFundingTransferResponse(transfer)))
+}
+
+func failFundingTransfer(c *gin.Context, transfer *database.FundingTransfer, status int, code, message string) {
+	transfer.Status = "failed"
+	if err := repo.FundingTransferRepository().Update(transfer); err != nil {
+		common.Error("Failed to update funding transfer: %v", err)
+	}
+	c.JSON(status, common.NewErrorResponse(code, message))
+}
+
+// postFundingTransaction records a balanced ledger transaction for the transfer
+func postFundingTransaction(transfer *database.FundingTransfer, destinationAccount string) (string, error) {
+	destination, err := findOrCreateAccount(transfer.AgentID, destinationAccount, "asset", "Funds pulled from linked bank accounts")
+	if err != nil {
+		return "", err
+	}
+	equity, err := findOrCreateAccount(transfer.AgentID, ownerFundingAccountName, "equity", "Capital contributed by the owning party")
+	if err != nil {
+		return "", err
+	}
+
+	transaction := &database.Transaction{
+		AgentID:     transfer.AgentID,
+		Description: transfer.Description,
+		ReferenceID: transfer.ID,
+		Status:      "posted",
+	}
+	if err := repo.TransactionRepository().Create(transaction); err != nil {
+		return "", err
+	}
+
+	postings := []struct {
+		account *database.Account
+		amount  float64
+	}{
+		{destination, transfer.AmountUSD},
+		{equity, -transfer.AmountUSD},
+	}
+	for _, p := range postings {
+		posting := &database.Posting{
+			TransactionID: transaction.ID,
+			AccountID:     p.account.ID,
+			Amount:        p.amount,
+			Currency:      p.account.Currency,
+		}
+		if err := repo.PostingRepository().Create(posting); err != nil {
+			return "", err
+		}
+
+		p.account.Balance += p.amount
+		if err := repo.AccountRepository().Update(p.account); err != nil {
+			return "", err
+		}
+	}
+
+	if err := repo.TransactionRepository().Chain(transaction.ID); err != nil {
+		return "", err
+	}
+	return transaction.ID, nil
+}
+
+func findOrCreateAccount(agentID, name, accountType, description string) (*database.Account, error) {
+	accounts, err := repo.AccountRepository().ListByAgentIDAndType(agentID, accountType)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, nil
+		}
+	}
+
+	account := &database.Account{
+		AgentID:     agentID,
+		Name:        name,
+		Type:        accountType,
+		Description: description,
+		Currency:    "USD",
+	}
+	if err := repo.AccountRepository().Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func listFundingTransfers(c *gin.Context) {
+	source := loadFundingSource(c)
+	if source == nil {
+		return
+	}
+
+	transfers, err := repo.FundingTransferRepository().ListByFundingSourceID(source.ID)
+	if err != nil {
+		common.Error("Failed to list funding transfers: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list funding transfers"))
+		return
+	}
+
+	responses := make([]interface{}, len(transfers))
+	for i, transfer := range transfers {
+		responses[i] = toFundingTransferResponse(transfer)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(responses, 1, len(responses), len(responses))))
+}
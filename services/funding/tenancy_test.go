@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOtherTenantsCounterpartiesAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+
+	// Two tenants, each an organization paying a vendor
+	parties := map[string]*database.Party{}
+	counterparties := map[string]*database.Counterparty{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		counterparty := &database.Counterparty{PartyID: party.ID, Name: "Vendor", Type: "organization"}
+		if err := repo.CounterpartyRepository().Create(counterparty); err != nil {
+			t.Fatal(err)
+		}
+		parties[name], counterparties[name] = party, counterparty
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: parties["Acme"].ID, TenantID: parties["Acme"].ID}
+	get := func(id string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.GET("/v1/counterparties/:id", getCounterparty)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/counterparties/"+id, nil))
+		return recorder.Code
+	}
+
+	if code := get(counterparties["Acme"].ID); code != http.StatusOK {
+		t.Fatalf("own counterparty: got %d", code)
+	}
+	if code := get(counterparties["Globex"].ID); code != http.StatusNotFound {
+		t.Fatalf("other tenant's counterparty: got %d, want 404", code)
+	}
+}
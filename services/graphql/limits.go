@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/example/agent-payments/services/graphql/generated"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Limits bound the cost of a query before it runs
+type Limits struct {
+	MaxDepth      int // Nesting of selection sets
+	MaxComplexity int // Estimated number of resolved fields
+	MaxListSize   int // Upper bound on "first"
+}
+
+// DefaultLimits are used where a limit is not configured
+var DefaultLimits = Limits{MaxDepth: 8, MaxComplexity: 2000, MaxListSize: 100}
+
+// newExecutor returns the executor of the schema, which rejects queries over
+// the limits before resolving any field. Introspection is disabled.
+func newExecutor(limits Limits) *executor.Executor {
+	config := generated.Config{Resolvers: resolver{}}
+
+	// A list's selections count once for each item it may return
+	list := func(childComplexity int, first *int) int {
+		return 1 + childComplexity*listSize(first)
+	}
+	complexity := &config.Complexity
+	complexity.Query.Agents = func(childComplexity int, first, offset *int) int { return list(childComplexity, first) }
+	complexity.Query.Payments = func(childComplexity int, agentID, status *string, first, offset *int) int {
+		return list(childComplexity, first)
+	}
+	complexity.Query.Executions = func(childComplexity int, agentID, status *string, first, offset *int) int {
+		return list(childComplexity, first)
+	}
+	complexity.Agent.Payments = list
+	complexity.Agent.Executions = list
+	complexity.Agent.Consents = list
+	complexity.Agent.RiskDecisions = list
+	complexity.Payment.Consents = list
+	complexity.Payment.Executions = list
+	complexity.Payment.LedgerEntries = list
+	complexity.Execution.LedgerEntries = list
+	complexity.LedgerTransaction.Postings = list
+
+	exec := executor.New(generated.NewExecutableSchema(config))
+	exec.Use(queryLimits{limits})
+	exec.Use(extension.FixedComplexityLimit(limits.MaxComplexity))
+	return exec
+}
+
+// defaultListSize is the length of lists whose "first" is null. Omitted, it
+// defaults to the same in the schema.
+const defaultListSize = 20
+
+// listSize is the number of items a list may return
+func listSize(first *int) int {
+	if first == nil {
+		return defaultListSize
+	}
+	return *first
+}
+
+// queryLimits rejects queries nested deeper than the depth limit, and lists
+// asked for more items than the list size limit
+type queryLimits struct {
+	Limits
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = queryLimits{}
+
+func (queryLimits) ExtensionName() string {
+	return "QueryLimits"
+}
+
+func (queryLimits) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (l queryLimits) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if err := l.check(opCtx.Operation.SelectionSet, 1, opCtx.Variables); err != nil {
+		return gqlerror.Errorf("%s", err)
+	}
+	return nil
+}
+
+func (l queryLimits) check(selections ast.SelectionSet, depth int, variables map[string]interface{}) error {
+	if depth > l.MaxDepth {
+		return fmt.Errorf("query depth exceeds the limit of %d", l.MaxDepth)
+	}
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			arguments := s.ArgumentMap(variables)
+			if first, ok := intArgument(arguments["first"]); ok && (first < 0 || first > int64(l.MaxListSize)) {
+				return fmt.Errorf("first must be between 0 and %d on field %s", l.MaxListSize, s.Name)
+			}
+			if offset, ok := intArgument(arguments["offset"]); ok && offset < 0 {
+				return fmt.Errorf("offset must not be negative on field %s", s.Name)
+			}
+			if len(s.SelectionSet) > 0 {
+				if err := l.check(s.SelectionSet, depth+1, variables); err != nil {
+					return err
+				}
+			}
+		case *ast.InlineFragment:
+			if err := l.check(s.SelectionSet, depth, variables); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				if err := l.check(s.Definition.SelectionSet, depth, variables); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// intArgument reads an Int argument, given in the query or as a variable
+func intArgument(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
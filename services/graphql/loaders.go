@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/dataloader"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// loaders are a query's dataloaders and the caller they read for. Loaders
+// keyed by agent or reference ID return every row for the key, newest first.
+type loaders struct {
+	principal *common.Principal // nil when authentication is disabled
+	tenantID  string            // Empty for services
+
+	agents                   *dataloader.Loader[*database.Agent] // Only agents the caller can read
+	accounts                 *dataloader.Loader[*database.Account]
+	paymentsByAgent          *dataloader.Loader[[]*database.PaymentWorkflow]
+	executionsByAgent        *dataloader.Loader[[]*database.PaymentExecution]
+	consentsByAgent          *dataloader.Loader[[]*database.Consent]
+	riskDecisionsByAgent     *dataloader.Loader[[]*database.RiskDecision]
+	ledgerEntriesByReference *dataloader.Loader[[]*database.Transaction]
+}
+
+type loadersKey struct{}
+
+// withLoaders returns a context carrying fresh loaders for one query
+func withLoaders(ctx context.Context, principal *common.Principal, tenantID string) context.Context {
+	l := &loaders{principal: principal, tenantID: tenantID}
+	l.agents = dataloader.New(l.visibleAgents)
+	l.accounts = dataloader.New(func(ids []string) (map[string]*database.Account, error) {
+		accounts, err := repo.AccountRepository().ListByIDs(ids)
+		return byKey(accounts, func(account *database.Account) string { return account.ID }), err
+	})
+	l.paymentsByAgent = dataloader.New(func(agentIDs []string) (map[string][]*database.PaymentWorkflow, error) {
+		workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDs(agentIDs)
+		return groupBy(workflows, func(workflow *database.PaymentWorkflow) string { return workflow.AgentID }), err
+	})
+	l.executionsByAgent = dataloader.New(func(agentIDs []string) (map[string][]*database.PaymentExecution, error) {
+		executions, err := repo.PaymentExecutionRepository().ListByAgentIDs(agentIDs)
+		return groupBy(executions, func(execution *database.PaymentExecution) string { return execution.AgentID }), err
+	})
+	l.consentsByAgent = dataloader.New(func(agentIDs []string) (map[string][]*database.Consent, error) {
+		consents, err := repo.ConsentRepository().ListByAgentIDs(agentIDs)
+		return groupBy(consents, func(consent *database.Consent) string { return consent.AgentID }), err
+	})
+	l.riskDecisionsByAgent = dataloader.New(func(agentIDs []string) (map[string][]*database.RiskDecision, error) {
+		decisions, err := repo.RiskDecisionRepository().ListByAgentIDs(agentIDs)
+		return groupBy(decisions, func(decision *database.RiskDecision) string { return decision.AgentID }), err
+	})
+	l.ledgerEntriesByReference = dataloader.New(func(referenceIDs []string) (map[string][]*database.Transaction, error) {
+		transactions, err := repo.TransactionRepository().ListByReferenceIDs(referenceIDs)
+		return groupBy(transactions, func(transaction *database.Transaction) string { return transaction.ReferenceID }), err
+	})
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFor(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// visibleAgents scopes every query to the caller: agents see themselves,
+// parties see the agents of their tenant, and services see everything
+func (l *loaders) visibleAgents(ids []string) (map[string]*database.Agent, error) {
+	var agents []*database.Agent
+	var err error
+	switch {
+	case l.principal != nil && l.principal.Type == common.PrincipalAgent:
+		for _, id := range ids {
+			if id == l.principal.AgentID {
+				agents, err = repo.AgentRepository().ListByIDs([]string{id})
+			}
+		}
+	case l.tenantID != "":
+		agents, err = repo.AgentRepository().ListByIDsInTenant(ids, l.tenantID)
+	default:
+		agents, err = repo.AgentRepository().ListByIDs(ids)
+	}
+	return byKey(agents, func(agent *database.Agent) string { return agent.ID }), err
+}
+
+// primeAgentChildren adds agents to the batches of the loaders their fields
+// read from
+func (l *loaders) primeAgentChildren(agentIDs []string) {
+	l.agents.Prime(agentIDs...)
+	l.paymentsByAgent.Prime(agentIDs...)
+	l.executionsByAgent.Prime(agentIDs...)
+	l.consentsByAgent.Prime(agentIDs...)
+	l.riskDecisionsByAgent.Prime(agentIDs...)
+}
+
+// primePayments adds the keys the fields of payments read to the next batches
+func (l *loaders) primePayments(workflows []*database.PaymentWorkflow) {
+	var agentIDs, ids []string
+	for _, workflow := range workflows {
+		agentIDs = append(agentIDs, workflow.AgentID)
+		ids = append(ids, workflow.ID)
+	}
+	l.primeAgentChildren(agentIDs)
+	l.ledgerEntriesByReference.Prime(ids...)
+}
+
+// primeExecutions adds the keys the fields of executions read to the next
+// batches
+func (l *loaders) primeExecutions(executions []*database.PaymentExecution) {
+	for _, execution := range executions {
+		l.agents.Prime(execution.AgentID)
+		l.ledgerEntriesByReference.Prime(execution.ID, execution.ID+reversalReferenceSuffix)
+	}
+}
+
+// primeLedgerEntries adds the accounts of transactions' postings to the next
+// batch
+func (l *loaders) primeLedgerEntries(transactions []*database.Transaction) {
+	for _, transaction := range transactions {
+		for _, posting := range transaction.Postings {
+			l.accounts.Prime(posting.AccountID)
+		}
+	}
+}
+
+func byKey[T any](items []T, key func(T) string) map[string]T {
+	values := make(map[string]T, len(items))
+	for _, item := range items {
+		values[key(item)] = item
+	}
+	return values
+}
+
+func groupBy[T any](items []T, key func(T) string) map[string][]T {
+	values := map[string][]T{}
+	for _, item := range items {
+		values[key(item)] = append(values[key(item)], item)
+	}
+	return values
+}
+
+// firstItems truncates a list to the query's "first" argument
+func firstItems[T any](items []T, first *int) []T {
+	if size := listSize(first); len(items) > size {
+		return items[:size]
+	}
+	return items
+}
+
+func formatTime(t time.Time) *string {
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// jsonValue decodes a stored JSON document for the JSON scalar
+func jsonValue(document string) (interface{}, error) {
+	if document == "" {
+		return nil, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// jsonContains reports whether a stored JSON array is empty or holds the value
+func jsonContains(document, value string) bool {
+	var items []string
+	if err := json.Unmarshal([]byte(document), &items); err != nil || len(items) == 0 {
+		return true
+	}
+	for _, item := range items {
+		if item == value || item == "*" {
+			return true
+		}
+	}
+	return false
+}
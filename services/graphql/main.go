@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+//go:generate go run github.com/99designs/gqlgen generate
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+//go:embed schema.graphqls
+var schemaSDL string
+
+var repo database.Repository
+var authConfig *common.AuthConfig
+var exec *executor.Executor
+
+func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("graphql")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("graphql")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.Database.Connection())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Run migrations
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Initialize repository
+	repo = database.NewRepository(db)
+
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Initialize the executor with the query limits
+	exec = newExecutor(Limits{
+		MaxDepth:      common.GetEnvAsInt("GRAPHQL_MAX_DEPTH", DefaultLimits.MaxDepth),
+		MaxComplexity: common.GetEnvAsInt("GRAPHQL_MAX_COMPLEXITY", DefaultLimits.MaxComplexity),
+		MaxListSize:   common.GetEnvAsInt("GRAPHQL_MAX_LIST_SIZE", DefaultLimits.MaxListSize),
+	})
+
+	r := gin.Default()
+
+	// Setup common middleware
+	common.SetupCommonMiddleware(r, func() error {
+		return repo.HealthCheck()
+	})
+
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
+	// Health check endpoint
+	r.GET("/healthz", func(c *gin.Context) {
+		if err := repo.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unhealthy", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "graphql service ok"})
+	})
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
+	// API v1 routes
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig))
+	{
+		v1.POST("/graphql", common.RequireScopes(common.ScopePaymentsRead), executeQuery)
+		v1.GET("/graphql", common.RequireScopes(common.ScopePaymentsRead), executeQuery)
+		v1.GET("/graphql/schema", common.RequireScopes(common.ScopePaymentsRead), getSchema)
+	}
+
+	log.Printf("GraphQL service starting on %s", cfg.Addr())
+	if err := common.NewServer(cfg.Addr(), r).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// QueryRequest is the body of a POST query
+type QueryRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// executeQuery runs a read-only query. Requests rejected before execution
+// (syntax, unknown fields, depth or complexity) return 400; field errors are
+// reported alongside partial data with 200, as GraphQL clients expect.
+func executeQuery(c *gin.Context) {
+	var params QueryRequest
+	if c.Request.Method == http.MethodGet {
+		params.Query = c.Query("query")
+		params.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, requestError("variables must be a JSON object"))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, requestError("Invalid request format"))
+		return
+	}
+	if params.Query == "" {
+		c.JSON(http.StatusBadRequest, requestError("query is required"))
+		return
+	}
+
+	ctx := graphql.StartOperationTrace(withLoaders(c.Request.Context(), common.GetPrincipal(c), common.RequestTenant(c)))
+	operation, errs := exec.CreateOperationContext(ctx, &graphql.RawParams{
+		Query:         params.Query,
+		OperationName: params.OperationName,
+		Variables:     params.Variables,
+	})
+	if errs != nil {
+		c.JSON(http.StatusBadRequest, exec.DispatchError(graphql.WithOperationContext(ctx, operation), errs))
+		return
+	}
+	responses, ctx := exec.DispatchOperation(ctx, operation)
+	response := responses(ctx)
+	for _, fieldError := range response.Errors {
+		common.Warn("GraphQL field error at %v: %s", fieldError.Path, fieldError.Message)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func requestError(message string) *graphql.Response {
+	return &graphql.Response{Errors: gqlerror.List{{Message: message}}}
+}
+
+func getSchema(c *gin.Context) {
+	c.String(http.StatusOK, schemaSDL)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/example/agent-payments/services/graphql/generated"
+)
+
+// reversalReferenceSuffix marks the compensating transaction of a reversed
+// execution, whose reference is the execution ID plus this suffix
+const reversalReferenceSuffix = ":reversal"
+
+// resolver resolves the fields of schema.graphqls that are not read from the
+// rows themselves. Root fields return only what the caller can read; nested
+// fields inherit that check from their root.
+type resolver struct{}
+
+func (resolver) Query() generated.QueryResolver               { return queryResolver{} }
+func (resolver) Agent() generated.AgentResolver               { return agentResolver{} }
+func (resolver) Payment() generated.PaymentResolver           { return paymentResolver{} }
+func (resolver) Execution() generated.ExecutionResolver       { return executionResolver{} }
+func (resolver) Consent() generated.ConsentResolver           { return consentResolver{} }
+func (resolver) RiskDecision() generated.RiskDecisionResolver { return riskDecisionResolver{} }
+func (resolver) LedgerTransaction() generated.LedgerTransactionResolver {
+	return ledgerTransactionResolver{}
+}
+func (resolver) Posting() generated.PostingResolver { return postingResolver{} }
+
+type queryResolver struct{}
+
+func (queryResolver) Agent(ctx context.Context, id string) (*database.Agent, error) {
+	l := loadersFor(ctx)
+	agent, err := l.agents.Load(id)
+	if agent != nil {
+		l.primeAgentChildren([]string{id})
+	}
+	return agent, err
+}
+
+func (queryResolver) Agents(ctx context.Context, first, offset *int) ([]*database.Agent, error) {
+	l := loadersFor(ctx)
+	var agents []*database.Agent
+	var err error
+	if l.principal != nil && l.principal.Type == common.PrincipalAgent {
+		// An agent sees itself only
+		var agent *database.Agent
+		if agent, err = l.agents.Load(l.principal.AgentID); agent != nil && page(first, offset).Offset == 0 && listSize(first) > 0 {
+			agents = []*database.Agent{agent}
+		}
+	} else {
+		agents, _, err = repo.AgentRepository().ListPage(database.AgentFilter{TenantID: l.tenantID}, page(first, offset))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(agents))
+	for i, agent := range agents {
+		ids[i] = agent.ID
+	}
+	l.primeAgentChildren(ids)
+	return agents, nil
+}
+
+// rootScope returns the agent a root list is confined to, if any, and
+// whether the caller can read the requested agent's rows at all. Parties
+// are confined to their tenant by the list's query.
+func rootScope(l *loaders, agentID *string) (string, bool) {
+	requested := ""
+	if agentID != nil {
+		requested = *agentID
+	}
+	if l.principal != nil && l.principal.Type == common.PrincipalAgent {
+		return l.principal.AgentID, requested == "" || requested == l.principal.AgentID
+	}
+	return requested, true
+}
+
+func (queryResolver) Payment(ctx context.Context, id string) (*database.PaymentWorkflow, error) {
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(id)
+	if err != nil {
+		return nil, nil
+	}
+	l := loadersFor(ctx)
+	l.primePayments([]*database.PaymentWorkflow{workflow})
+	if agent, err := l.agents.Load(workflow.AgentID); agent == nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+func (queryResolver) Payments(ctx context.Context, agentID, status *string, first, offset *int) ([]*database.PaymentWorkflow, error) {
+	l := loadersFor(ctx)
+	scopedAgentID, ok := rootScope(l, agentID)
+	if !ok {
+		return []*database.PaymentWorkflow{}, nil
+	}
+	filter := database.PaymentWorkflowFilter{AgentID: scopedAgentID, TenantID: l.tenantID}
+	if status != nil {
+		filter.Status = *status
+	}
+	workflows, _, err := repo.PaymentWorkflowRepository().ListPage(filter, page(first, offset))
+	if err != nil {
+		return nil, err
+	}
+	l.primePayments(workflows)
+	return workflows, nil
+}
+
+func (queryResolver) Execution(ctx context.Context, id string) (*database.PaymentExecution, error) {
+	execution, err := repo.PaymentExecutionRepository().GetByID(id)
+	if err != nil {
+		return nil, nil
+	}
+	l := loadersFor(ctx)
+	l.primeExecutions([]*database.PaymentExecution{execution})
+	if agent, err := l.agents.Load(execution.AgentID); agent == nil {
+		return nil, err
+	}
+	return execution, nil
+}
+
+func (queryResolver) Executions(ctx context.Context, agentID, status *string, first, offset *int) ([]*database.PaymentExecution, error) {
+	l := loadersFor(ctx)
+	scopedAgentID, ok := rootScope(l, agentID)
+	if !ok {
+		return []*database.PaymentExecution{}, nil
+	}
+	filter := database.PaymentExecutionFilter{AgentID: scopedAgentID, TenantID: l.tenantID}
+	if status != nil {
+		filter.Status = *status
+	}
+	executions, _, err := repo.PaymentExecutionRepository().ListPage(filter, page(first, offset))
+	if err != nil {
+		return nil, err
+	}
+	l.primeExecutions(executions)
+	return executions, nil
+}
+
+// page is the page of a root list, newest first
+func page(first, offset *int) common.ListParams {
+	params := common.ListParams{Limit: listSize(first), Descending: true}
+	if offset != nil {
+		params.Offset = *offset
+	}
+	return params
+}
+
+type agentResolver struct{}
+
+func (agentResolver) CreatedAt(ctx context.Context, agent *database.Agent) (*string, error) {
+	return formatTime(agent.CreatedAt), nil
+}
+
+func (agentResolver) Payments(ctx context.Context, agent *database.Agent, first *int) ([]*database.PaymentWorkflow, error) {
+	l := loadersFor(ctx)
+	workflows, err := l.paymentsByAgent.Load(agent.ID)
+	workflows = firstItems(workflows, first)
+	l.primePayments(workflows)
+	return workflows, err
+}
+
+func (agentResolver) Executions(ctx context.Context, agent *database.Agent, first *int) ([]*database.PaymentExecution, error) {
+	l := loadersFor(ctx)
+	executions, err := l.executionsByAgent.Load(agent.ID)
+	executions = firstItems(executions, first)
+	l.primeExecutions(executions)
+	return executions, err
+}
+
+func (agentResolver) Consents(ctx context.Context, agent *database.Agent, first *int) ([]*database.Consent, error) {
+	consents, err := loadersFor(ctx).consentsByAgent.Load(agent.ID)
+	return firstItems(consents, first), err
+}
+
+func (agentResolver) RiskDecisions(ctx context.Context, agent *database.Agent, first *int) ([]*database.RiskDecision, error) {
+	decisions, err := loadersFor(ctx).riskDecisionsByAgent.Load(agent.ID)
+	return firstItems(decisions, first), err
+}
+
+type paymentResolver struct{}
+
+func (paymentResolver) Steps(ctx context.Context, workflow *database.PaymentWorkflow) (interface{}, error) {
+	return jsonValue(workflow.Steps)
+}
+
+func (paymentResolver) CreatedAt(ctx context.Context, workflow *database.PaymentWorkflow) (*string, error) {
+	return formatTime(workflow.CreatedAt), nil
+}
+
+func (paymentResolver) UpdatedAt(ctx context.Context, workflow *database.PaymentWorkflow) (*string, error) {
+	return formatTime(workflow.UpdatedAt), nil
+}
+
+func (paymentResolver) Agent(ctx context.Context, workflow *database.PaymentWorkflow) (*database.Agent, error) {
+	return loadersFor(ctx).agents.Load(workflow.AgentID)
+}
+
+// Workflows do not store the IDs of the risk decision and executions made for
+// them, so these are matched on agent, counterparty and amount among the rows
+// created after the workflow.
+
+func (paymentResolver) RiskDecision(ctx context.Context, workflow *database.PaymentWorkflow) (*database.RiskDecision, error) {
+	decisions, err := loadersFor(ctx).riskDecisionsByAgent.Load(workflow.AgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decisions are newest first; the payment's is the earliest after it was created
+	var earliest *database.RiskDecision
+	for _, decision := range decisions {
+		if decision.Counterparty == workflow.Counterparty &&
+			decision.AmountUSD == workflow.AmountUSD &&
+			!decision.CreatedAt.Before(workflow.CreatedAt) {
+			earliest = decision
+		}
+	}
+	return earliest, nil
+}
+
+func (paymentResolver) Consents(ctx context.Context, workflow *database.PaymentWorkflow, first *int) ([]*database.Consent, error) {
+	consents, err := loadersFor(ctx).consentsByAgent.Load(workflow.AgentID)
+	matching := []*database.Consent{}
+	for _, consent := range consents {
+		if consentstate.IsActive(consent, time.Now()) &&
+			jsonContains(consent.Rails, workflow.Rail) &&
+			jsonContains(consent.CounterpartiesAllow, workflow.Counterparty) {
+			matching = append(matching, consent)
+		}
+	}
+	return firstItems(matching, first), err
+}
+
+func (paymentResolver) Executions(ctx context.Context, workflow *database.PaymentWorkflow, first *int) ([]*database.PaymentExecution, error) {
+	l := loadersFor(ctx)
+	executions, err := l.executionsByAgent.Load(workflow.AgentID)
+	matching := []*database.PaymentExecution{}
+	for _, execution := range executions {
+		if execution.Counterparty == workflow.Counterparty &&
+			execution.AmountUSD == workflow.AmountUSD &&
+			!execution.CreatedAt.Before(workflow.CreatedAt) {
+			matching = append(matching, execution)
+		}
+	}
+	matching = firstItems(matching, first)
+	l.primeExecutions(matching)
+	return matching, err
+}
+
+// LedgerEntries lists the transactions referencing the payment
+func (paymentResolver) LedgerEntries(ctx context.Context, workflow *database.PaymentWorkflow, first *int) ([]*database.Transaction, error) {
+	l := loadersFor(ctx)
+	transactions, err := l.ledgerEntriesByReference.Load(workflow.ID)
+	transactions = firstItems(transactions, first)
+	l.primeLedgerEntries(transactions)
+	return transactions, err
+}
+
+type executionResolver struct{}
+
+func (executionResolver) ReversedAt(ctx context.Context, execution *database.PaymentExecution) (*string, error) {
+	return formatOptionalTime(execution.ReversedAt), nil
+}
+
+func (executionResolver) CreatedAt(ctx context.Context, execution *database.PaymentExecution) (*string, error) {
+	return formatTime(execution.CreatedAt), nil
+}
+
+func (executionResolver) UpdatedAt(ctx context.Context, execution *database.PaymentExecution) (*string, error) {
+	return formatTime(execution.UpdatedAt), nil
+}
+
+func (executionResolver) Agent(ctx context.Context, execution *database.PaymentExecution) (*database.Agent, error) {
+	return loadersFor(ctx).agents.Load(execution.AgentID)
+}
+
+// LedgerEntries lists the transactions referencing the execution. Its
+// reversal is booked under its own reference and included here.
+func (executionResolver) LedgerEntries(ctx context.Context, execution *database.PaymentExecution, first *int) ([]*database.Transaction, error) {
+	l := loadersFor(ctx)
+	groups, err := l.ledgerEntriesByReference.LoadMany([]string{execution.ID, execution.ID + reversalReferenceSuffix})
+	if err != nil {
+		return nil, err
+	}
+	transactions := firstItems(append(groups[0], groups[1]...), first)
+	l.primeLedgerEntries(transactions)
+	return transactions, nil
+}
+
+type consentResolver struct{}
+
+func (consentResolver) Rails(ctx context.Context, consent *database.Consent) (interface{}, error) {
+	return jsonValue(consent.Rails)
+}
+
+func (consentResolver) CounterpartiesAllow(ctx context.Context, consent *database.Consent) (interface{}, error) {
+	return jsonValue(consent.CounterpartiesAllow)
+}
+
+func (consentResolver) Limits(ctx context.Context, consent *database.Consent) (interface{}, error) {
+	return jsonValue(consent.Limits)
+}
+
+func (consentResolver) CosignRule(ctx context.Context, consent *database.Consent) (interface{}, error) {
+	return jsonValue(consent.CosignRule)
+}
+
+func (consentResolver) Status(ctx context.Context, consent *database.Consent) (*string, error) {
+	status := string(consentstate.Of(consent, time.Now()))
+	return &status, nil
+}
+
+func (consentResolver) ExpiresAt(ctx context.Context, consent *database.Consent) (*string, error) {
+	return formatOptionalTime(consent.ExpiresAt), nil
+}
+
+func (consentResolver) CreatedAt(ctx context.Context, consent *database.Consent) (*string, error) {
+	return formatTime(consent.CreatedAt), nil
+}
+
+type riskDecisionResolver struct{}
+
+func (riskDecisionResolver) RiskFactors(ctx context.Context, decision *database.RiskDecision) (interface{}, error) {
+	return jsonValue(decision.RiskFactors)
+}
+
+func (riskDecisionResolver) TriggeredRules(ctx context.Context, decision *database.RiskDecision) (interface{}, error) {
+	return jsonValue(decision.TriggeredRules)
+}
+
+func (riskDecisionResolver) CreatedAt(ctx context.Context, decision *database.RiskDecision) (*string, error) {
+	return formatTime(decision.CreatedAt), nil
+}
+
+type ledgerTransactionResolver struct{}
+
+func (ledgerTransactionResolver) CreatedAt(ctx context.Context, transaction *database.Transaction) (*string, error) {
+	return formatTime(transaction.CreatedAt), nil
+}
+
+func (ledgerTransactionResolver) Postings(ctx context.Context, transaction *database.Transaction, first *int) ([]*database.Posting, error) {
+	postings := make([]*database.Posting, len(transaction.Postings))
+	for i := range transaction.Postings {
+		postings[i] = &transaction.Postings[i]
+	}
+	return firstItems(postings, first), nil
+}
+
+type postingResolver struct{}
+
+func (postingResolver) Account(ctx context.Context, posting *database.Posting) (*database.Account, error) {
+	return loadersFor(ctx).accounts.Load(posting.AccountID)
+}
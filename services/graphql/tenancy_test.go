@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type queryResponse struct {
+	Data struct {
+		Agent    *struct{ ID string }
+		Agents   []struct{ ID string }
+		Payment  *struct{ ID string }
+		Payments []struct {
+			ID     string
+			Status string
+			Agent  *struct{ ID string }
+		}
+	}
+	Errors []struct{ Message string }
+}
+
+func TestQueriesSeeOnlyTheCallersTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+	exec = newExecutor(DefaultLimits)
+
+	// Two tenants, each an organization with an agent that made three payments
+	parties := map[string]*database.Party{}
+	agents := map[string]*database.Agent{}
+	payments := map[string][]*database.PaymentWorkflow{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " buyer", OwnerPartyID: party.ID, IdentityMode: "oauth"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []string{"completed", "failed", "completed"} {
+			payment := &database.PaymentWorkflow{AgentID: agent.ID, AmountUSD: 10, Counterparty: "vendor", Rail: "ach", Status: status}
+			if err := repo.PaymentWorkflowRepository().Create(payment); err != nil {
+				t.Fatal(err)
+			}
+			payments[name] = append(payments[name], payment)
+		}
+		parties[name], agents[name] = party, agent
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: parties["Acme"].ID, TenantID: parties["Acme"].ID}
+	query := func(query string) (int, queryResponse) {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.POST("/v1/graphql", executeQuery)
+		body, _ := json.Marshal(map[string]string{"query": query})
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body)))
+		var response queryResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: %v", recorder.Body, err)
+		}
+		return recorder.Code, response
+	}
+
+	if _, response := query(`{ agents { id } }`); len(response.Data.Agents) != 1 || response.Data.Agents[0].ID != agents["Acme"].ID {
+		t.Fatalf("agents: got %+v, want only Acme's", response.Data.Agents)
+	}
+	if _, response := query(`{ agent(id: "` + agents["Globex"].ID + `") { id } }`); response.Data.Agent != nil {
+		t.Fatal("other tenant's agent: got it, want null")
+	}
+	if _, response := query(`{ payment(id: "` + payments["Globex"][0].ID + `") { id } }`); response.Data.Payment != nil {
+		t.Fatal("other tenant's payment: got it, want null")
+	}
+
+	// Root lists filter and page in the query, so other tenants' rows never
+	// take up a page
+	_, response := query(`{ payments(status: "completed") { id status agent { id } } }`)
+	if len(response.Data.Payments) != 2 {
+		t.Fatalf("completed payments: got %+v, want Acme's two", response.Data.Payments)
+	}
+	for _, payment := range response.Data.Payments {
+		if payment.Status != "completed" || payment.Agent == nil || payment.Agent.ID != agents["Acme"].ID {
+			t.Fatalf("completed payments: got %+v", payment)
+		}
+	}
+	if _, response := query(`{ payments(first: 2, offset: 2) { id } }`); len(response.Data.Payments) != 1 {
+		t.Fatalf("last page: got %d payments, want 1", len(response.Data.Payments))
+	}
+	if _, response := query(`{ payments(agentId: "` + agents["Globex"].ID + `") { id } }`); len(response.Data.Payments) != 0 {
+		t.Fatalf("other tenant's agent's payments: got %d, want none", len(response.Data.Payments))
+	}
+}
+
+func TestQueriesOverTheLimitsAreRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+	exec = newExecutor(Limits{MaxDepth: 3, MaxComplexity: 100, MaxListSize: 10})
+
+	router := gin.New()
+	router.POST("/v1/graphql", executeQuery)
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"within the limits", `{ agents(first: 5) { id payments(first: 5) { id } } }`, http.StatusOK},
+		{"too deep", `{ agents(first: 1) { payments(first: 1) { agent { id } } } }`, http.StatusBadRequest},
+		{"list too long", `{ agents(first: 11) { id } }`, http.StatusBadRequest},
+		{"negative offset", `{ payments(offset: -1) { id } }`, http.StatusBadRequest},
+		{"too complex", `{ agents(first: 10) { id payments(first: 10) { id } } }`, http.StatusBadRequest},
+		{"unknown field", `{ agents { password } }`, http.StatusBadRequest},
+		{"no query", ``, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(map[string]string{"query": tt.query})
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body)))
+		if recorder.Code != tt.code {
+			t.Errorf("%s: got %d, want %d: %s", tt.name, recorder.Code, tt.code, recorder.Body)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build tools
+
+package main
+
+// The schema's resolvers are generated by gqlgen; importing it here keeps
+// its dependencies in go.mod for "go generate"
+import _ "github.com/99designs/gqlgen"
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type IssueAgentCredentialRequest struct {
+	TTLSeconds int      `json:"ttlSeconds,omitempty"`
+	Type       string   `json:"type,omitempty"`   // "oauth_client" issues client credentials to an OAuth agent
+	Scopes     []string `json:"scopes,omitempty"` // Scopes an oauth_client may request
+}
+
+type VerifyAgentCredentialRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type AgentCredentialResponse struct {
+	ID           string            `json:"id"`
+	AgentID      string            `json:"agentId"`
+	Type         string            `json:"type"`
+	Status       string            `json:"status"`
+	DID          string            `json:"did,omitempty"`
+	AccessToken  string            `json:"accessToken,omitempty"` // Only returned at issuance
+	ClientID     string            `json:"clientId,omitempty"`
+	ClientSecret string            `json:"clientSecret,omitempty"` // Only returned at issuance
+	Scopes       []string          `json:"scopes,omitempty"`
+	PrivateKey   *auth.JWK         `json:"privateKeyJwk,omitempty"`
+	DIDDocument  *auth.DIDDocument `json:"didDocument,omitempty"`
+	ReplacedByID string            `json:"replacedById,omitempty"`
+	ExpiresAt    string            `json:"expiresAt,omitempty"`
+	RevokedAt    string            `json:"revokedAt,omitempty"`
+	CreatedAt    string            `json:"createdAt"`
+}
+
+type VerifyAgentCredentialResponse struct {
+	Valid        bool   `json:"valid"`
+	AgentID      string `json:"agentId,omitempty"`
+	CredentialID string `json:"credentialId,omitempty"`
+	Type         string `json:"type,omitempty"`
+	ExpiresAt    string `json:"expiresAt,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+func toAgentCredentialResponse(credential *database.AgentCredential) *AgentCredentialResponse {
+	response := &AgentCredentialResponse{
+		ID:           credential.ID,
+		AgentID:      credential.AgentID,
+		Type:         credential.Type,
+		Status:       credential.Status,
+		DID:          credential.DID,
+		ReplacedByID: credential.ReplacedByID,
+		CreatedAt:    credential.CreatedAt.Format(time.RFC3339),
+	}
+	if credential.Type == auth.CredentialTypeOAuthClient {
+		response.ClientID = credential.ID
+		response.Scopes = auth.ClientScopes(credential)
+	}
+	if credential.ExpiresAt != nil {
+		response.ExpiresAt = credential.ExpiresAt.Format(time.RFC3339)
+	}
+	if credential.RevokedAt != nil {
+		response.RevokedAt = credential.RevokedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func toIssuedCredentialResponse(issued *auth.IssuedAgentCredential) *AgentCredentialResponse {
+	response := toAgentCredentialResponse(issued.Credential)
+	response.AccessToken = issued.AccessToken
+	response.ClientSecret = issued.ClientSecret
+	response.PrivateKey = issued.PrivateKey
+	response.DIDDocument = issued.DIDDocument
+	return response
+}
+
+// loadManagedAgent loads the agent in the path and checks the caller may manage it
+func loadManagedAgent(c *gin.Context) (*database.Agent, bool) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return nil, false
+	}
+
+	if !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage credentials for this agent"))
+		return nil, false
+	}
+
+	return agent, true
+}
+
+// credentialsAllowed refuses new credentials for decommissioned agents
+func credentialsAllowed(c *gin.Context, agent *database.Agent) bool {
+	if agentstate.Of(agent) == agentstate.Decommissioned {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Agent is decommissioned"))
+		return false
+	}
+	return true
+}
+
+func issueAgentCredential(c *gin.Context) {
+	var req IssueAgentCredentialRequest
+	c.ShouldBindJSON(&req)
+
+	agent, ok := loadManagedAgent(c)
+	if !ok || !credentialsAllowed(c, agent) {
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	var issued *auth.IssuedAgentCredential
+	var err error
+	switch req.Type {
+	case "":
+		issued, err = auth.IssueAgentCredential(repo, authConfig, agent, ttl)
+	case auth.CredentialTypeOAuthClient:
+		if agent.IdentityMode != "oauth" {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_IDENTITY_MODE", "Only OAuth agents are issued client credentials"))
+			return
+		}
+		// Callers cannot grant scopes they do not hold themselves
+		if principal := common.GetPrincipal(c); principal != nil {
+			for _, scope := range req.Scopes {
+				if !principal.HasScope(scope) {
+					c.JSON(http.StatusForbidden, common.NewErrorResponseWithDetails("FORBIDDEN", "Cannot grant scope", scope))
+					return
+				}
+			}
+		}
+		issued, err = auth.IssueOAuthClient(repo, agent, req.Scopes, ttl)
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "type must be oauth_client or omitted"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to issue credential for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("CREDENTIAL_ERROR", "Failed to issue credential"))
+		return
+	}
+
+	common.Info("Issued %s credential %s for agent %s", issued.Credential.Type, issued.Credential.ID, agent.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toIssuedCredentialResponse(issued)))
+}
+
+func listAgentCredentials(c *gin.Context) {
+	agent, ok := loadManagedAgent(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := repo.AgentCredentialRepository().ListByAgentID(agent.ID)
+	if err != nil {
+		common.Error("Failed to list credentials: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list credentials"))
+		return
+	}
+
+	response := common.NewListResponse(make([]interface{}, len(credentials)), 1, len(credentials), len(credentials))
+	for i, credential := range credentials {
+		response.Items[i] = toAgentCredentialResponse(credential)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func rotateAgentCredential(c *gin.Context) {
+	var req IssueAgentCredentialRequest
+	c.ShouldBindJSON(&req)
+
+	agent, ok := loadManagedAgent(c)
+	if !ok || !credentialsAllowed(c, agent) {
+		return
+	}
+
+	issued, err := auth.RotateAgentCredential(repo, authConfig, agent, c.Param("credentialId"), time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		respondCredentialError(c, err, "Failed to rotate credential")
+		return
+	}
+
+	common.Info("Rotated credential %s for agent %s, replaced by %s", c.Param("credentialId"), agent.ID, issued.Credential.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toIssuedCredentialResponse(issued)))
+}
+
+func revokeAgentCredential(c *gin.Context) {
+	agent, ok := loadManagedAgent(c)
+	if !ok {
+		return
+	}
+
+	credential, err := auth.RevokeAgentCredential(repo, agent.ID, c.Param("credentialId"))
+	if err != nil {
+		respondCredentialError(c, err, "Failed to revoke credential")
+		return
+	}
+
+	common.Info("Revoked credential %s for agent %s", credential.ID, agent.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAgentCredentialResponse(credential)))
+}
+
+// verifyAgentCredential lets other services check a credential presented by an agent
+// before acting on its behalf. Invalid credentials are reported with valid=false.
+func verifyAgentCredential(c *gin.Context) {
+	var req VerifyAgentCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "token is required"))
+		return
+	}
+
+	agentID := c.Param("id")
+	if !tenancy.CheckAgent(c, repo, agentID, "agent", agentID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+
+	// Credentials from trusted external platforms verify against the shadow agent
+	if isFederatedToken(req.Token) {
+		response, err := verifyFederatedAgentCredential(c, agentID, req.Token)
+		if err != nil {
+			common.Warn("Federated credential verification failed for agent %s: %v", agentID, err)
+			response = &VerifyAgentCredentialResponse{Valid: false, Reason: err.Error()}
+		}
+		c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+		return
+	}
+
+	credential, err := auth.VerifyAgentCredential(repo, authConfig, req.Token)
+	if err == nil && credential.AgentID != agentID {
+		err = auth.ErrCredentialMismatch
+	}
+	if err != nil {
+		common.Warn("Credential verification failed for agent %s: %v", agentID, err)
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&VerifyAgentCredentialResponse{
+			Valid:  false,
+			Reason: err.Error(),
+		}))
+		return
+	}
+
+	response := &VerifyAgentCredentialResponse{
+		Valid:        true,
+		AgentID:      credential.AgentID,
+		CredentialID: credential.ID,
+		Type:         credential.Type,
+	}
+	if credential.ExpiresAt != nil {
+		response.ExpiresAt = credential.ExpiresAt.Format(time.RFC3339)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func getAgentDIDDocument(c *gin.Context) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || agent.IdentityMode != "did" {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "DID document not found"))
+		return
+	}
+
+	document, err := didResolver.ResolveAgent(agent)
+	if err != nil {
+		common.Error("Failed to resolve DID of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load DID document"))
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+func respondCredentialError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, auth.ErrCredentialNotFound), errors.Is(err, auth.ErrCredentialMismatch):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Credential not found"))
+	case errors.Is(err, auth.ErrCredentialNotActive):
+		c.JSON(http.StatusConflict, common.NewErrorResponse("CREDENTIAL_NOT_ACTIVE", "Credential is not active"))
+	default:
+		common.Error("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("CREDENTIAL_ERROR", message))
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAPIKeyRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	AgentID    string   `json:"agentId,omitempty"` // Issue the key to one of the party's agents
+	UserID     string   `json:"userId,omitempty"`  // Issue the key to one of the party's users
+	Scopes     []string `json:"scopes,omitempty"`
+	TTLSeconds int      `json:"ttlSeconds,omitempty"`
+}
+
+type APIKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	KeyPrefix  string   `json:"keyPrefix"`
+	Key        string   `json:"key,omitempty"` // Only returned at creation
+	PartyID    string   `json:"partyId"`
+	AgentID    string   `json:"agentId,omitempty"`
+	UserID     string   `json:"userId,omitempty"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+type TokenRequest struct {
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken string   `json:"accessToken"`
+	TokenType   string   `json:"tokenType"`
+	ExpiresIn   int      `json:"expiresIn"`
+	Scopes      []string `json:"scopes"`
+}
+
+// canManageParty reports whether the caller may manage credentials of a
+// party. Attempts to reach another tenant's party are audited.
+func canManageParty(c *gin.Context, partyID string) bool {
+	principal := common.GetPrincipal(c)
+	if principal == nil || principal.Type == common.PrincipalService {
+		return true
+	}
+	if !tenancy.Check(c, repo, partyID, "party", partyID) {
+		return false
+	}
+	return principal.Type == common.PrincipalParty && principal.PartyID == partyID
+}
+
+// callerSubject names the caller in audit entries and records
+func callerSubject(c *gin.Context) string {
+	if principal := common.GetPrincipal(c); principal != nil {
+		return principal.Subject
+	}
+	return "identity"
+}
+
+func toAPIKeyResponse(credential *database.APICredential) *APIKeyResponse {
+	response := &APIKeyResponse{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		KeyPrefix: credential.KeyPrefix,
+		PartyID:   credential.PartyID,
+		AgentID:   credential.AgentID,
+		UserID:    credential.UserID,
+		Scopes:    []string{},
+		CreatedAt: credential.CreatedAt.Format(time.RFC3339),
+	}
+	if credential.Scopes != "" {
+		json.Unmarshal([]byte(credential.Scopes), &response.Scopes)
+	}
+	if credential.ExpiresAt != nil {
+		response.ExpiresAt = credential.ExpiresAt.Format(time.RFC3339)
+	}
+	if credential.RevokedAt != nil {
+		response.RevokedAt = credential.RevokedAt.Format(time.RFC3339)
+	}
+	if credential.LastUsedAt != nil {
+		response.LastUsedAt = credential.LastUsedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func createAPIKey(c *gin.Context) {
+	partyID := c.Param("id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage credentials for this party"))
+		return
+	}
+
+	if _, err := repo.PartyRepository().GetByID(partyID); err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Party not found"))
+		return
+	}
+
+	// Agent keys must belong to one of the party's agents
+	if req.AgentID != "" {
+		agent, err := repo.AgentRepository().GetByID(req.AgentID)
+		if err != nil || agent.OwnerPartyID != partyID {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found for this party"))
+			return
+		}
+	}
+
+	// Users without the admin role only issue keys to themselves, so they
+	// cannot shed their roles with a key of the party's
+	if principal := common.GetPrincipal(c); principal != nil && principal.UserID != "" && !principal.HasRole(common.RoleAdmin) {
+		if req.AgentID != "" || (req.UserID != "" && req.UserID != principal.UserID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Insufficient role"))
+			return
+		}
+		req.UserID = principal.UserID
+	}
+
+	// User keys must belong to one of the party's active users
+	if req.UserID != "" {
+		if req.AgentID != "" {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "A key belongs to an agent or a user, not both"))
+			return
+		}
+		user, err := repo.UserRepository().GetByID(req.UserID)
+		if err != nil || user.PartyID != partyID || user.Status != auth.UserStatusActive {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Active user not found for this party"))
+			return
+		}
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = auth.DefaultPartyScopes
+		if req.AgentID != "" {
+			scopes = auth.DefaultAgentScopes
+		}
+	}
+
+	// Callers cannot grant scopes they do not hold themselves
+	if principal := common.GetPrincipal(c); principal != nil {
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				c.JSON(http.StatusForbidden, common.NewErrorResponseWithDetails("FORBIDDEN", "Cannot grant scope", scope))
+				return
+			}
+		}
+	}
+
+	key, credential, err := auth.IssueAPIKey(repo, auth.IssueAPIKeyRequest{
+		Name:    req.Name,
+		PartyID: partyID,
+		AgentID: req.AgentID,
+		UserID:  req.UserID,
+		Scopes:  scopes,
+		TTL:     time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		common.Error("Failed to issue API key: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to issue API key"))
+		return
+	}
+
+	response := toAPIKeyResponse(credential)
+	response.Key = key
+
+	common.Info("Issued API key %s (%s) for party %s", credential.ID, credential.KeyPrefix, partyID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+func listAPIKeys(c *gin.Context) {
+	partyID := c.Param("id")
+
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage credentials for this party"))
+		return
+	}
+
+	credentials, err := repo.APICredentialRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list API keys: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list API keys"))
+		return
+	}
+
+	response := common.NewListResponse(make([]interface{}, len(credentials)), 1, len(credentials), len(credentials))
+	for i, credential := range credentials {
+		response.Items[i] = toAPIKeyResponse(credential)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func revokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+
+	credential, err := repo.APICredentialRepository().GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "API key not found"))
+		return
+	}
+
+	if !canManageParty(c, credential.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage credentials for this party"))
+		return
+	}
+
+	credential, err = auth.RevokeAPIKey(repo, id)
+	if err != nil {
+		common.Error("Failed to revoke API key: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke API key"))
+		return
+	}
+
+	common.Info("Revoked API key %s for party %s", credential.ID, credential.PartyID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAPIKeyResponse(credential)))
+}
+
+// issueToken exchanges an API key for a short-lived JWT carrying the same scopes
+func issueToken(c *gin.Context) {
+	principal := common.GetPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("AUTH_DISABLED", "Authentication is disabled"))
+		return
+	}
+	if principal.Method != common.AuthMethodAPIKey {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Tokens can only be issued for API key credentials"))
+		return
+	}
+
+	var req TokenRequest
+	c.ShouldBindJSON(&req)
+
+	ttl := time.Hour
+	if req.TTLSeconds > 0 && req.TTLSeconds < int(ttl.Seconds()) {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	token, _, err := authConfig.IssueToken(principal, ttl)
+	if err != nil {
+		common.Error("Failed to issue token: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("TOKEN_ERROR", "Failed to issue token"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scopes:      principal.Scopes,
+	}))
+}
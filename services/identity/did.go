@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/did"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+var didResolver *did.Resolver
+
+// RegisterDIDKeyRequest adds a public key the agent holds the private key of
+// to its DID document
+type RegisterDIDKeyRequest struct {
+	PublicKey  string `json:"publicKey" binding:"required"` // Base64url Ed25519 public key
+	TTLSeconds int    `json:"ttlSeconds,omitempty"`
+}
+
+// AnswerDIDChallengeRequest is an agent's signature of a challenge nonce
+type AnswerDIDChallengeRequest struct {
+	KeyID     string `json:"keyId" binding:"required"`     // DID URL of the key, or its fragment
+	Signature string `json:"signature" binding:"required"` // Base64url Ed25519 signature of the nonce
+}
+
+type DIDChallengeResponse struct {
+	ID         string `json:"id"`
+	AgentID    string `json:"agentId"`
+	DID        string `json:"did"`
+	Nonce      string `json:"nonce"`
+	Status     string `json:"status"`
+	KeyID      string `json:"keyId,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	ExpiresAt  string `json:"expiresAt"`
+	AnsweredAt string `json:"answeredAt,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// resolveDID resolves a DID the platform publishes to its DID document
+func resolveDID(c *gin.Context) {
+	document, _, err := didResolver.Resolve(c.Param("did"))
+	switch {
+	case errors.Is(err, did.ErrUnsupportedMethod):
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_DID_METHOD", "Only "+auth.DIDMethodPrefix+" DIDs resolve here"))
+	case errors.Is(err, did.ErrNotFound):
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "DID not found"))
+	case err != nil:
+		common.Error("Failed to resolve %s: %v", c.Param("did"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to resolve DID"))
+	default:
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// registerDIDKey adds a public key to a DID agent's document, for agents that
+// generate their keys themselves. It is managed like the other credentials:
+// listed, rotated and revoked with them.
+func registerDIDKey(c *gin.Context) {
+	var req RegisterDIDKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "publicKey is required"))
+		return
+	}
+	agent, ok := loadManagedAgent(c)
+	if !ok || !credentialsAllowed(c, agent) {
+		return
+	}
+	if agent.IdentityMode != "did" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_IDENTITY_MODE", "Agent does not use a DID"))
+		return
+	}
+
+	credential, err := auth.RegisterDIDKey(repo, agent, req.PublicKey, time.Duration(req.TTLSeconds)*time.Second)
+	if errors.Is(err, auth.ErrInvalidPublicKey) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to register DID key for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("CREDENTIAL_ERROR", "Failed to register key"))
+		return
+	}
+
+	response := toAgentCredentialResponse(credential)
+	if document, err := didResolver.ResolveAgent(agent); err == nil {
+		response.DIDDocument = document
+	}
+	common.Info("Registered DID key %s for agent %s", credential.ID, agent.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+// createDIDChallenge issues a nonce for a DID agent to sign, so a relying
+// party can check the agent controls its DID
+func createDIDChallenge(c *gin.Context) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || agent.IdentityMode != "did" || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "DID agent not found"))
+		return
+	}
+	nonce, err := did.NewNonce()
+	if err != nil {
+		common.Error("Failed to generate DID challenge nonce: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to create challenge"))
+		return
+	}
+
+	challenge := &database.DIDChallenge{
+		AgentID:     agent.ID,
+		DID:         auth.AgentDID(agent.ID),
+		Nonce:       nonce,
+		Status:      "pending",
+		RequestedBy: callerSubject(c),
+		ExpiresAt:   time.Now().Add(did.ChallengeTTL),
+	}
+	if err := repo.DIDChallengeRepository().Create(challenge); err != nil {
+		common.Error("Failed to store DID challenge for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create challenge"))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toDIDChallengeResponse(challenge)))
+}
+
+// answerDIDChallenge checks the agent's signature of a challenge nonce with a
+// key of its DID document. A challenge is answered once, whether or not the
+// signature holds; the outcome is returned as its status.
+func answerDIDChallenge(c *gin.Context) {
+	var req AnswerDIDChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "keyId and signature are required"))
+		return
+	}
+	challenge, err := repo.DIDChallengeRepository().GetByID(c.Param("challengeId"))
+	if err != nil || challenge.AgentID != c.Param("id") || !tenancy.CheckAgent(c, repo, challenge.AgentID, "did_challenge", challenge.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Challenge not found"))
+		return
+	}
+	if challenge.Status != "pending" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Challenge was already answered"))
+		return
+	}
+
+	now := time.Now()
+	challenge.AnsweredAt = &now
+	challenge.KeyID = req.KeyID
+	if now.After(challenge.ExpiresAt) {
+		challenge.Status, challenge.Reason = "failed", "challenge expired"
+	} else if document, _, err := didResolver.Resolve(challenge.DID); err != nil {
+		challenge.Status, challenge.Reason = "failed", err.Error()
+	} else if methodID, err := did.VerifyChallenge(document, req.KeyID, challenge.Nonce, req.Signature); err != nil {
+		challenge.Status, challenge.Reason = "failed", err.Error()
+	} else {
+		challenge.Status, challenge.KeyID = "verified", methodID
+	}
+
+	answered, err := repo.DIDChallengeRepository().Answer(challenge)
+	if err != nil {
+		common.Error("Failed to record answer to DID challenge %s: %v", challenge.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record answer"))
+		return
+	}
+	if !answered {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Challenge was already answered"))
+		return
+	}
+
+	verification := &did.Verification{
+		DID:        challenge.DID,
+		KeyID:      challenge.KeyID,
+		Method:     "challenge",
+		Verified:   challenge.Status == "verified",
+		Reason:     challenge.Reason,
+		VerifiedAt: now,
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditAgentDIDVerified,
+		Severity:     audit.SeverityMedium,
+		AgentID:      challenge.AgentID,
+		ResourceID:   challenge.ID,
+		ResourceType: "did_challenge",
+		Action:       "did_verify",
+		Description:  "DID challenge for " + challenge.DID + " " + challenge.Status,
+		NewValues:    verification.Map(),
+	})
+
+	if challenge.Status != "verified" {
+		common.Warn("DID challenge %s for agent %s failed: %s", challenge.ID, challenge.AgentID, challenge.Reason)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDIDChallengeResponse(challenge)))
+}
+
+func toDIDChallengeResponse(challenge *database.DIDChallenge) *DIDChallengeResponse {
+	response := &DIDChallengeResponse{
+		ID:        challenge.ID,
+		AgentID:   challenge.AgentID,
+		DID:       challenge.DID,
+		Nonce:     challenge.Nonce,
+		Status:    challenge.Status,
+		KeyID:     challenge.KeyID,
+		Reason:    challenge.Reason,
+		ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339),
+		CreatedAt: challenge.CreatedAt.Format(time.RFC3339),
+	}
+	if challenge.AnsweredAt != nil {
+		response.AnsweredAt = challenge.AnsweredAt.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/federation"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Default consent limits for shadow agents when an issuer does not set them
+const (
+	defaultFederatedSingleTxnUSD  = 100.0
+	defaultFederatedDailyUSD      = 500.0
+	defaultFederatedMaxTxnPerHour = 5
+)
+
+type CreateTrustedIssuerRequest struct {
+	Name              string           `json:"name" binding:"required"`
+	Issuer            string           `json:"issuer" binding:"required"`
+	HostPartyID       string           `json:"hostPartyId" binding:"required"`
+	PublicKeys        []federation.JWK `json:"publicKeys" binding:"required"`
+	RevocationListURL string           `json:"revocationListUrl,omitempty"`
+	DefaultLimits     *ConsentLimits   `json:"defaultLimits,omitempty"`
+}
+
+type UpdateTrustedIssuerRequest struct {
+	Status            string           `json:"status,omitempty"` // "active", "suspended"
+	PublicKeys        []federation.JWK `json:"publicKeys,omitempty"`
+	RevocationListURL *string          `json:"revocationListUrl,omitempty"`
+	DefaultLimits     *ConsentLimits   `json:"defaultLimits,omitempty"`
+}
+
+type ConsentLimits struct {
+	SingleTxnUSD  float64 `json:"singleTxnUSD"`
+	DailyUSD      float64 `json:"dailyUSD"`
+	MaxTxnPerHour int     `json:"maxTxnPerHour"`
+}
+
+type RevokeFederatedCredentialRequest struct {
+	CredentialID string `json:"credentialId" binding:"required"` // "jti" or subject of the external credential
+	Reason       string `json:"reason"`
+}
+
+type VerifyFederatedCredentialRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type TrustedIssuerResponse struct {
+	ID                  string           `json:"id"`
+	Name                string           `json:"name"`
+	Issuer              string           `json:"issuer"`
+	HostPartyID         string           `json:"hostPartyId"`
+	PublicKeys          []federation.JWK `json:"publicKeys"`
+	RevocationListURL   string           `json:"revocationListUrl,omitempty"`
+	RevocationsSyncedAt string           `json:"revocationsSyncedAt,omitempty"`
+	Status              string           `json:"status"`
+	DefaultLimits       ConsentLimits    `json:"defaultLimits"`
+	CreatedAt           string           `json:"createdAt"`
+	UpdatedAt           string           `json:"updatedAt"`
+}
+
+type FederatedVerificationResponse struct {
+	Valid        bool   `json:"valid"`
+	AgentID      string `json:"agentId,omitempty"`
+	ShadowAgent  bool   `json:"shadowAgentCreated,omitempty"`
+	IssuerID     string `json:"issuerId,omitempty"`
+	Issuer       string `json:"issuer,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	CredentialID string `json:"credentialId,omitempty"`
+	Format       string `json:"format,omitempty"`
+	ExpiresAt    string `json:"expiresAt,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+func toTrustedIssuerResponse(issuer *database.TrustedIssuer) *TrustedIssuerResponse {
+	keys, _ := federation.ParsePublicKeys(issuer.PublicKeys)
+	response := &TrustedIssuerResponse{
+		ID:                issuer.ID,
+		Name:              issuer.Name,
+		Issuer:            issuer.Issuer,
+		HostPartyID:       issuer.HostPartyID,
+		PublicKeys:        keys,
+		RevocationListURL: issuer.RevocationListURL,
+		Status:            issuer.Status,
+		DefaultLimits: ConsentLimits{
+			SingleTxnUSD:  issuer.DefaultSingleTxnUSD,
+			DailyUSD:      issuer.DefaultDailyUSD,
+			MaxTxnPerHour: issuer.DefaultMaxTxnPerHour,
+		},
+		CreatedAt: issuer.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: issuer.UpdatedAt.Format(time.RFC3339),
+	}
+	if issuer.RevocationsSyncedAt != nil {
+		response.RevocationsSyncedAt = issuer.RevocationsSyncedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// encodePublicKeys validates a JWK set and serializes it for storage
+func encodePublicKeys(keys []federation.JWK) (string, error) {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	if _, err := federation.ParsePublicKeys(string(raw)); err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// applyDefaultLimits sets the shadow consent limits given in the request and
+// fills any still unset with the platform defaults
+func applyDefaultLimits(issuer *database.TrustedIssuer, limits *ConsentLimits) {
+	if limits != nil {
+		if limits.SingleTxnUSD > 0 {
+			issuer.DefaultSingleTxnUSD = limits.SingleTxnUSD
+		}
+		if limits.DailyUSD > 0 {
+			issuer.DefaultDailyUSD = limits.DailyUSD
+		}
+		if limits.MaxTxnPerHour > 0 {
+			issuer.DefaultMaxTxnPerHour = limits.MaxTxnPerHour
+		}
+	}
+	if issuer.DefaultSingleTxnUSD == 0 {
+		issuer.DefaultSingleTxnUSD = defaultFederatedSingleTxnUSD
+	}
+	if issuer.DefaultDailyUSD == 0 {
+		issuer.DefaultDailyUSD = defaultFederatedDailyUSD
+	}
+	if issuer.DefaultMaxTxnPerHour == 0 {
+		issuer.DefaultMaxTxnPerHour = defaultFederatedMaxTxnPerHour
+	}
+}
+
+func createTrustedIssuer(c *gin.Context) {
+	var req CreateTrustedIssuerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "name, issuer, hostPartyId and publicKeys are required"))
+		return
+	}
+
+	// The platform's own DIDs are verified against local credentials, never federated
+	if strings.HasPrefix(req.Issuer, auth.DIDMethodPrefix) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Cannot federate the platform's own DID method"))
+		return
+	}
+
+	if _, err := repo.PartyRepository().GetByID(req.HostPartyID); err != nil || !tenancy.Check(c, repo, req.HostPartyID, "party", req.HostPartyID) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Host party not found"))
+		return
+	}
+
+	publicKeys, err := encodePublicKeys(req.PublicKeys)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	if _, err := repo.TrustedIssuerRepository().GetByIssuer(req.Issuer); err == nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("ALREADY_EXISTS", "Issuer is already registered"))
+		return
+	}
+
+	issuer := &database.TrustedIssuer{
+		Name:              req.Name,
+		Issuer:            req.Issuer,
+		HostPartyID:       req.HostPartyID,
+		PublicKeys:        publicKeys,
+		RevocationListURL: req.RevocationListURL,
+		Status:            federation.IssuerStatusActive,
+	}
+	applyDefaultLimits(issuer, req.DefaultLimits)
+
+	if err := repo.TrustedIssuerRepository().Create(issuer); err != nil {
+		common.Error("Failed to create trusted issuer: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create trusted issuer"))
+		return
+	}
+
+	common.Info("Trusted issuer registered: %s (%s)", issuer.Issuer, issuer.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toTrustedIssuerResponse(issuer)))
+}
+
+func listTrustedIssuers(c *gin.Context) {
+	var issuers []*database.TrustedIssuer
+	var err error
+	if tenantID := common.RequestTenant(c); tenantID != "" {
+		issuers, err = repo.TrustedIssuerRepository().ListByTenantID(tenantID)
+	} else {
+		issuers, err = repo.TrustedIssuerRepository().List()
+	}
+	if err != nil {
+		common.Error("Failed to list trusted issuers: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list trusted issuers"))
+		return
+	}
+
+	response := common.NewListResponse(make([]interface{}, len(issuers)), 1, len(issuers), len(issuers))
+	for i, issuer := range issuers {
+		response.Items[i] = toTrustedIssuerResponse(issuer)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func getTrustedIssuer(c *gin.Context) {
+	issuer, err := repo.TrustedIssuerRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, issuer.HostPartyID, "trusted_issuer", issuer.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Trusted issuer not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toTrustedIssuerResponse(issuer)))
+}
+
+func updateTrustedIssuer(c *gin.Context) {
+	var req UpdateTrustedIssuerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	issuer, err := repo.TrustedIssuerRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, issuer.HostPartyID, "trusted_issuer", issuer.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Trusted issuer not found"))
+		return
+	}
+
+	switch req.Status {
+	case "":
+	case federation.IssuerStatusActive, federation.IssuerStatusSuspended:
+		issuer.Status = req.Status
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "status must be active or suspended"))
+		return
+	}
+
+	if len(req.PublicKeys) > 0 {
+		publicKeys, err := encodePublicKeys(req.PublicKeys)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+			return
+		}
+		issuer.PublicKeys = publicKeys
+	}
+	if req.RevocationListURL != nil {
+		issuer.RevocationListURL = *req.RevocationListURL
+		issuer.RevocationsSyncedAt = nil
+	}
+	applyDefaultLimits(issuer, req.DefaultLimits)
+
+	if err := repo.TrustedIssuerRepository().Update(issuer); err != nil {
+		common.Error("Failed to update trusted issuer: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update trusted issuer"))
+		return
+	}
+
+	common.Info("Trusted issuer updated: %s (status %s)", issuer.Issuer, issuer.Status)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toTrustedIssuerResponse(issuer)))
+}
+
+func revokeFederatedCredential(c *gin.Context) {
+	var req RevokeFederatedCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "credentialId is required"))
+		return
+	}
+
+	issuer, err := repo.TrustedIssuerRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, issuer.HostPartyID, "trusted_issuer", issuer.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Trusted issuer not found"))
+		return
+	}
+
+	revocation := &database.FederatedRevocation{
+		IssuerID:     issuer.ID,
+		CredentialID: req.CredentialID,
+		Reason:       req.Reason,
+		Source:       "manual",
+	}
+	if err := repo.FederatedRevocationRepository().Create(revocation); err != nil {
+		common.Error("Failed to record revocation: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke credential"))
+		return
+	}
+
+	common.Info("Revoked federated credential %s from issuer %s", req.CredentialID, issuer.Issuer)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(gin.H{
+		"issuerId":     issuer.ID,
+		"credentialId": req.CredentialID,
+		"revoked":      true,
+	}))
+}
+
+// verifyFederatedCredential verifies a credential from a trusted external
+// platform and resolves (creating on first contact) its shadow agent
+func verifyFederatedCredential(c *gin.Context) {
+	var req VerifyFederatedCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "token is required"))
+		return
+	}
+
+	result, err := federationVerifier.Verify(c.Request.Context(), req.Token)
+	if err != nil {
+		common.Warn("Federated credential verification failed: %v", err)
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&FederatedVerificationResponse{
+			Valid:  false,
+			Reason: err.Error(),
+		}))
+		return
+	}
+	// Shadow agents are created under the issuer's host party, so only its
+	// tenant may resolve them
+	if !tenancy.Check(c, repo, result.Issuer.HostPartyID, "trusted_issuer", result.Issuer.ID) {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&FederatedVerificationResponse{
+			Valid:  false,
+			Reason: "issuer is not trusted by this tenant",
+		}))
+		return
+	}
+
+	agent, created, err := federationVerifier.ShadowAgent(result)
+	if err != nil {
+		common.Error("Failed to resolve shadow agent for %s from %s: %v", result.Subject, result.Issuer.Issuer, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to resolve shadow agent"))
+		return
+	}
+	if created {
+		common.Info("Created shadow agent %s for %s from issuer %s", agent.ID, result.Subject, result.Issuer.Issuer)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&FederatedVerificationResponse{
+		Valid:        true,
+		AgentID:      agent.ID,
+		ShadowAgent:  created,
+		IssuerID:     result.Issuer.ID,
+		Issuer:       result.Issuer.Issuer,
+		Subject:      result.Subject,
+		CredentialID: result.CredentialID,
+		Format:       result.Format,
+		ExpiresAt:    result.ExpiresAt.Format(time.RFC3339),
+	}))
+}
+
+// verifyFederatedAgentCredential verifies an external credential presented
+// for a specific shadow agent
+func verifyFederatedAgentCredential(c *gin.Context, agentID, token string) (*VerifyAgentCredentialResponse, error) {
+	result, err := federationVerifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := repo.FederatedIdentityRepository().GetByIssuerAndSubject(result.Issuer.ID, result.Subject)
+	if err != nil || identity.AgentID != agentID {
+		return nil, auth.ErrCredentialMismatch
+	}
+
+	identity.LastVerifiedAt = time.Now()
+	if err := repo.FederatedIdentityRepository().Update(identity); err != nil {
+		common.Warn("Failed to record federated verification for agent %s: %v", agentID, err)
+	}
+
+	return &VerifyAgentCredentialResponse{
+		Valid:        true,
+		AgentID:      agentID,
+		CredentialID: result.CredentialID,
+		Type:         "federated_" + result.Format,
+		ExpiresAt:    result.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// isFederatedToken reports whether a token names a registered external issuer
+func isFederatedToken(token string) bool {
+	issuer := federation.PeekIssuer(token)
+	if issuer == "" {
+		return false
+	}
+	_, err := repo.TrustedIssuerRepository().GetByIssuer(issuer)
+	return err == nil
+}
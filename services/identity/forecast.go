@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/consentstate"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/forecast"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/internal/velocity"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type SpendingForecastResponse struct {
+	AgentID            string                    `json:"agentId"`
+	PeriodStart        string                    `json:"periodStart"`
+	PeriodEnd          string                    `json:"periodEnd"`
+	SpentUSD           float64                   `json:"spentUSD"`
+	ExpectedDailyUSD   float64                   `json:"expectedDailyUSD"`
+	ProjectedRemainUSD float64                   `json:"projectedRemainingUSD"`
+	ProjectedTotalUSD  float64                   `json:"projectedTotalUSD"`
+	HistoryDays        int                       `json:"historyDays"`
+	HourlyProfile      []float64                 `json:"hourlyProfile"`
+	Budgets            []*BudgetForecastResponse `json:"budgets"`
+	AtRisk             bool                      `json:"atRisk"` // Some budget is projected to run out early
+}
+
+type BudgetForecastResponse struct {
+	Type              string  `json:"type"`
+	ConsentID         string  `json:"consentId,omitempty"`
+	LimitUSD          float64 `json:"limitUSD"`
+	RemainingUSD      float64 `json:"remainingUSD"`
+	ProjectedUSD      float64 `json:"projectedUSD"`
+	UtilizationRatio  float64 `json:"utilizationRatio"`
+	ExhaustedEarly    bool    `json:"exhaustedEarly"`
+	ProjectedExhausts string  `json:"projectedExhaustsAt,omitempty"`
+}
+
+// getSpendingForecast projects the agent's spend for the rest of the day and
+// flags the daily budgets it is on track to exhaust: the daily limit of each
+// active consent and the risk service's daily volume limit. Agents may read
+// their own forecast; parties may read it for agents they own.
+func getSpendingForecast(c *gin.Context) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalAgent {
+		if principal.AgentID != agent.ID {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot read the forecast of this agent"))
+			return
+		}
+	} else if !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot read the forecast of this agent"))
+		return
+	}
+
+	now := time.Now()
+	workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDSince(agent.ID, now.Add(-forecast.Lookback))
+	if err != nil {
+		common.Error("Failed to load payment history of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load payment history"))
+		return
+	}
+	budgets, err := spendingBudgets(agent.ID)
+	if err != nil {
+		common.Error("Failed to load consents of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load consents"))
+		return
+	}
+
+	projection := forecast.Project(workflows, now)
+	response := &SpendingForecastResponse{
+		AgentID:            agent.ID,
+		PeriodStart:        projection.PeriodStart.Format(time.RFC3339),
+		PeriodEnd:          projection.PeriodEnd.Format(time.RFC3339),
+		SpentUSD:           projection.SpentUSD,
+		ExpectedDailyUSD:   projection.ExpectedDailyUSD,
+		ProjectedRemainUSD: projection.ProjectedRemainUSD,
+		ProjectedTotalUSD:  projection.ProjectedTotalUSD,
+		HistoryDays:        projection.HistoryDays,
+		HourlyProfile:      projection.HourlyProfile,
+		Budgets:            []*BudgetForecastResponse{},
+	}
+	for _, budget := range forecast.Check(projection, budgets, now) {
+		item := &BudgetForecastResponse{
+			Type:             budget.Type,
+			ConsentID:        budget.ID,
+			LimitUSD:         budget.LimitUSD,
+			RemainingUSD:     budget.RemainingUSD,
+			ProjectedUSD:     budget.ProjectedUSD,
+			UtilizationRatio: budget.UtilizationRatio,
+			ExhaustedEarly:   budget.ExhaustedEarly,
+		}
+		if budget.ProjectedExhausts != nil {
+			item.ProjectedExhausts = budget.ProjectedExhausts.Format(time.RFC3339)
+		}
+		response.Budgets = append(response.Budgets, item)
+		response.AtRisk = response.AtRisk || budget.ExhaustedEarly
+	}
+
+	if response.AtRisk {
+		common.Warn("Agent %s is projected to exhaust a daily budget: %.2f USD projected", agent.ID, projection.ProjectedTotalUSD)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// spendingBudgets lists the daily limits the agent's spend counts against
+func spendingBudgets(agentID string) ([]forecast.Budget, error) {
+	consents, err := repo.ConsentRepository().ListByAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var budgets []forecast.Budget
+	for _, consent := range consents {
+		if limit := consentDailyLimit(consent); limit > 0 {
+			budgets = append(budgets, forecast.Budget{Type: "consent", ID: consent.ID, LimitUSD: limit})
+		}
+	}
+	if limit := velocity.NewLimitsFromEnv().MaxAmountUSD24h; limit > 0 {
+		budgets = append(budgets, forecast.Budget{Type: "risk_daily_volume", LimitUSD: limit})
+	}
+	return budgets, nil
+}
+
+func consentDailyLimit(consent *database.Consent) float64 {
+	if !consentstate.IsActive(consent, time.Now()) || consent.Limits == "" {
+		return 0
+	}
+	var limits types.ConsentLimits
+	if err := json.Unmarshal([]byte(consent.Limits), &limits); err != nil {
+		return 0
+	}
+	return limits.DailyUSD
+}
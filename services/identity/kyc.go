@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/kyc"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+var kycProvider kyc.Provider
+
+// SubmitKYCRequest carries the attributes and documents verifying a party,
+// such as legalName, dateOfBirth and country for individuals, or legalName,
+// registrationNumber and country for organizations
+type SubmitKYCRequest struct {
+	Attributes map[string]string `json:"attributes" binding:"required"`
+	Documents  []kyc.Document    `json:"documents" binding:"required"`
+}
+
+// ReviewKYCRequest decides a submission the provider left pending
+type ReviewKYCRequest struct {
+	Decision string `json:"decision" binding:"required"` // "approve" or "reject"
+	Reason   string `json:"reason"`
+}
+
+type KYCSubmissionResponse struct {
+	ID                string            `json:"id"`
+	PartyID           string            `json:"partyId"`
+	Kind              string            `json:"kind"`
+	Attributes        map[string]string `json:"attributes"`
+	Documents         []kyc.Document    `json:"documents"`
+	Status            string            `json:"status"`
+	Reason            string            `json:"reason,omitempty"`
+	Provider          string            `json:"provider"`
+	ProviderReference string            `json:"providerReference,omitempty"`
+	SubmittedBy       string            `json:"submittedBy,omitempty"`
+	ReviewedBy        string            `json:"reviewedBy,omitempty"`
+	DecidedAt         string            `json:"decidedAt,omitempty"`
+	CreatedAt         string            `json:"createdAt"`
+}
+
+// KYCStatusResponse is a party's verification status and its submissions
+type KYCStatusResponse struct {
+	PartyID     string                   `json:"partyId"`
+	Kind        string                   `json:"kind"`
+	Status      string                   `json:"status"`
+	VerifiedAt  string                   `json:"verifiedAt,omitempty"`
+	Submissions []*KYCSubmissionResponse `json:"submissions"`
+}
+
+// submitKYC submits a party's attributes and documents to the verification
+// provider. The provider verifies or rejects them at once, or leaves them
+// pending for a compliance reviewer. Verified parties and parties with a
+// pending submission cannot submit.
+func submitKYC(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	var req SubmitKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "attributes and documents are required"))
+		return
+	}
+
+	from := kyc.Of(party.KYCStatus)
+	if !kyc.Allowed(from, kyc.StatusPending) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Party verification is "+from))
+		return
+	}
+	submission := kyc.Submission{
+		PartyID:    party.ID,
+		PartyName:  party.Name,
+		Kind:       kyc.KindFor(party.Type),
+		Attributes: req.Attributes,
+		Documents:  req.Documents,
+	}
+	if problems := kyc.Validate(submission); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, common.NewValidationErrorResponse(problems))
+		return
+	}
+
+	result, err := kycProvider.Verify(c.Request.Context(), submission)
+	if err != nil {
+		common.Error("KYC provider %s failed for party %s: %v", kycProvider.Name(), party.ID, err)
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("PROVIDER_ERROR", "Verification provider is unavailable"))
+		return
+	}
+
+	stored := &database.KYCSubmission{
+		PartyID:           party.ID,
+		Kind:              submission.Kind,
+		Attributes:        encodeKYCJSON(req.Attributes),
+		Documents:         encodeKYCJSON(req.Documents),
+		Status:            result.Status,
+		Reason:            result.Reason,
+		Provider:          kycProvider.Name(),
+		ProviderReference: result.Reference,
+		SubmittedBy:       callerSubject(c),
+	}
+	if result.Status != kyc.StatusPending {
+		now := time.Now()
+		stored.DecidedAt = &now
+	}
+	submitted, err := repo.KYCSubmissionRepository().Submit(stored, from)
+	if err != nil {
+		common.Error("Failed to store KYC submission for party %s: %v", party.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to store submission"))
+		return
+	}
+	if !submitted {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Party verification changed meanwhile"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditPartyKYCSubmitted,
+		Severity:     audit.SeverityMedium,
+		ResourceID:   party.ID,
+		ResourceType: "party",
+		Action:       "kyc_submit",
+		UserID:       stored.SubmittedBy,
+		Description:  "Party " + party.Name + " submitted " + stored.Kind + " verification, " + stored.Status,
+		OldValues:    map[string]interface{}{"kycStatus": from},
+		NewValues:    map[string]interface{}{"kycStatus": stored.Status, "submissionId": stored.ID, "provider": stored.Provider},
+	})
+
+	common.Info("Party %s %s submission %s is %s", party.ID, stored.Kind, stored.ID, stored.Status)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toKYCSubmissionResponse(stored)))
+}
+
+// getKYCStatus returns a party's verification status and its submissions,
+// newest first
+func getKYCStatus(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	submissions, err := repo.KYCSubmissionRepository().ListByPartyID(party.ID)
+	if err != nil {
+		common.Error("Failed to list KYC submissions of party %s: %v", party.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list submissions"))
+		return
+	}
+
+	response := &KYCStatusResponse{
+		PartyID:     party.ID,
+		Kind:        kyc.KindFor(party.Type),
+		Status:      kyc.Of(party.KYCStatus),
+		Submissions: make([]*KYCSubmissionResponse, 0, len(submissions)),
+	}
+	if party.KYCVerifiedAt != nil {
+		response.VerifiedAt = party.KYCVerifiedAt.Format(time.RFC3339)
+	}
+	for _, submission := range submissions {
+		response.Submissions = append(response.Submissions, toKYCSubmissionResponse(submission))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// listKYCSubmissions lists submissions of a status, pending by default,
+// oldest first, as the queue of compliance reviewers. Reviewers confined to a
+// tenant see its submissions only.
+func listKYCSubmissions(c *gin.Context) {
+	status := c.DefaultQuery("status", kyc.StatusPending)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "limit must be between 1 and 500"))
+		return
+	}
+
+	var submissions []*database.KYCSubmission
+	if tenant := common.RequestTenant(c); tenant != "" {
+		submissions, err = repo.KYCSubmissionRepository().ListByTenantID(tenant, status, limit)
+	} else {
+		submissions, err = repo.KYCSubmissionRepository().ListByStatus(status, limit)
+	}
+	if err != nil {
+		common.Error("Failed to list KYC submissions: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list submissions"))
+		return
+	}
+	items := make([]interface{}, 0, len(submissions))
+	for _, submission := range submissions {
+		items = append(items, toKYCSubmissionResponse(submission))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// reviewKYCSubmission verifies or rejects a submission the provider left
+// pending, and its party with it
+func reviewKYCSubmission(c *gin.Context) {
+	var req ReviewKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision is required"))
+		return
+	}
+	var status string
+	switch req.Decision {
+	case "approve":
+		status = kyc.StatusVerified
+	case "reject":
+		status = kyc.StatusRejected
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision must be approve or reject"))
+		return
+	}
+
+	submission, err := repo.KYCSubmissionRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, submission.PartyID, "kyc_submission", submission.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Submission not found"))
+		return
+	}
+	if submission.Status != kyc.StatusPending {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Submission is not awaiting review"))
+		return
+	}
+
+	now := time.Now()
+	submission.Status = status
+	submission.Reason = req.Reason
+	submission.ReviewedBy = callerSubject(c)
+	submission.DecidedAt = &now
+	decided, err := repo.KYCSubmissionRepository().Decide(submission)
+	if err != nil {
+		common.Error("Failed to decide KYC submission %s: %v", submission.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record review"))
+		return
+	}
+	if !decided {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Submission was decided meanwhile"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditPartyKYCDecided,
+		Severity:     audit.SeverityHigh,
+		ResourceID:   submission.PartyID,
+		ResourceType: "party",
+		Action:       "kyc_review",
+		UserID:       submission.ReviewedBy,
+		Description:  "KYC submission " + submission.ID + " " + status + " by " + submission.ReviewedBy,
+		OldValues:    map[string]interface{}{"kycStatus": kyc.StatusPending},
+		NewValues:    map[string]interface{}{"kycStatus": status, "submissionId": submission.ID, "reason": req.Reason},
+	})
+
+	common.Info("KYC submission %s of party %s %s by %s", submission.ID, submission.PartyID, status, submission.ReviewedBy)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toKYCSubmissionResponse(submission)))
+}
+
+// loadManagedParty loads the party of the request path, answering 404 when
+// it does not exist or the caller cannot manage it
+func loadManagedParty(c *gin.Context) (*database.Party, bool) {
+	party, err := repo.PartyRepository().GetByID(c.Param("id"))
+	if err != nil || !canManageParty(c, party.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Party not found"))
+		return nil, false
+	}
+	return party, true
+}
+
+func encodeKYCJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func toKYCSubmissionResponse(submission *database.KYCSubmission) *KYCSubmissionResponse {
+	response := &KYCSubmissionResponse{
+		ID:                submission.ID,
+		PartyID:           submission.PartyID,
+		Kind:              submission.Kind,
+		Attributes:        map[string]string{},
+		Documents:         []kyc.Document{},
+		Status:            submission.Status,
+		Reason:            submission.Reason,
+		Provider:          submission.Provider,
+		ProviderReference: submission.ProviderReference,
+		SubmittedBy:       submission.SubmittedBy,
+		ReviewedBy:        submission.ReviewedBy,
+		CreatedAt:         submission.CreatedAt.Format(time.RFC3339),
+	}
+	if submission.Attributes != "" {
+		json.Unmarshal([]byte(submission.Attributes), &response.Attributes)
+	}
+	if submission.Documents != "" {
+		json.Unmarshal([]byte(submission.Documents), &response.Documents)
+	}
+	if submission.DecidedAt != nil {
+		response.DecidedAt = submission.DecidedAt.Format(time.RFC3339)
+	}
+	return response
+}
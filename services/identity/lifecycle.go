@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type ChangeAgentStatusRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// Audit event recorded for each status an agent moves to
+var agentStatusAuditEvents = map[agentstate.State]audit.AuditEventType{
+	agentstate.Active:         audit.AuditAgentActivated,
+	agentstate.Suspended:      audit.AuditAgentSuspended,
+	agentstate.Decommissioned: audit.AuditAgentDecommissioned,
+}
+
+// suspendAgent stops an active agent from initiating and executing payments
+// until it is activated again. Payments in flight fail before execution.
+func suspendAgent(c *gin.Context) {
+	changeAgentStatus(c, agentstate.Suspended)
+}
+
+// activateAgent lets a suspended agent pay again
+func activateAgent(c *gin.Context) {
+	changeAgentStatus(c, agentstate.Active)
+}
+
+// decommissionAgent retires an active or suspended agent for good and
+// revokes its credentials
+func decommissionAgent(c *gin.Context) {
+	changeAgentStatus(c, agentstate.Decommissioned)
+}
+
+// changeAgentStatus moves the agent in the path to a status, with the reason
+// in the body. Only the owner party or a service can change it.
+func changeAgentStatus(c *gin.Context, to agentstate.State) {
+	var req ChangeAgentStatusRequest
+	c.ShouldBindJSON(&req)
+
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+
+	from := agentstate.Of(agent)
+	if !agentstate.Allowed(from, to) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Agent is "+string(from)+" and cannot become "+string(to)))
+		return
+	}
+	changed, err := repo.AgentRepository().UpdateStatus(agent, string(to), req.Reason)
+	if err != nil {
+		common.Error("Failed to change status of agent %s to %s: %v", agent.ID, to, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to change agent status"))
+		return
+	}
+	if !changed {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Agent status changed meanwhile"))
+		return
+	}
+
+	if to == agentstate.Decommissioned {
+		revokeAgentCredentials(agent)
+	}
+
+	actor := callerSubject(c)
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    agentStatusAuditEvents[to],
+		Severity:     audit.SeverityHigh,
+		AgentID:      agent.ID,
+		ResourceID:   agent.ID,
+		ResourceType: "agent",
+		Action:       string(to),
+		UserID:       actor,
+		Description:  "Agent " + agent.DisplayName + " " + string(to) + " by " + actor,
+		OldValues:    map[string]interface{}{"status": from},
+		NewValues:    map[string]interface{}{"status": to, "reason": req.Reason},
+	})
+
+	common.Warn("Agent %s moved from %s to %s by %s: %s", agent.ID, from, to, actor, req.Reason)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAgentResponse(agent)))
+}
+
+// revokeAgentCredentials revokes the active credentials of a decommissioned
+// agent
+func revokeAgentCredentials(agent *database.Agent) {
+	credentials, err := repo.AgentCredentialRepository().ListByAgentID(agent.ID)
+	if err != nil {
+		common.Error("Failed to list credentials of decommissioned agent %s: %v", agent.ID, err)
+		return
+	}
+	for _, credential := range credentials {
+		if credential.Status != auth.CredentialStatusActive {
+			continue
+		}
+		if _, err := auth.RevokeAgentCredential(repo, agent.ID, credential.ID); err != nil {
+			common.Error("Failed to revoke credential %s of decommissioned agent %s: %v", credential.ID, agent.ID, err)
+		}
+	}
+}
+
+func toAgentResponse(agent *database.Agent) *types.Agent {
+	response := &types.Agent{
+		ID:           agent.ID,
+		DisplayName:  agent.DisplayName,
+		OwnerPartyID: agent.OwnerPartyID,
+		IdentityMode: agent.IdentityMode,
+		CreatedAt:    agent.CreatedAt.Format(time.RFC3339),
+		Status:       string(agentstate.Of(agent)),
+		StatusReason: agent.StatusReason,
+	}
+	if agent.StatusChangedAt != nil {
+		response.StatusChangedAt = agent.StatusChangedAt.Format(time.RFC3339)
+	}
+	return response
+}
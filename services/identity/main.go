@@ -1,17 +1,35 @@
 package main
 
 import (
+	"context"
+	_ "embed"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/did"
+	"github.com/example/agent-payments/internal/federation"
+	"github.com/example/agent-payments/internal/kyc"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/reputation"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
 )
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var repo database.Repository
+var authConfig *common.AuthConfig
+var federationVerifier *federation.Verifier
+var reputationIssuer *reputation.Issuer
 
 type CreateAgentRequest struct {
 	DisplayName  string `json:"displayName" binding:"required"`
@@ -20,14 +38,27 @@ type CreateAgentRequest struct {
 }
 
 type CreatePartyRequest struct {
-	Name string `json:"name" binding:"required"`
-	Type string `json:"type" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	Type           string `json:"type" binding:"required"`
+	OrganizationID string `json:"organizationId,omitempty"` // Organization party the party belongs to
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("identity")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("identity")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -40,7 +71,43 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
+	// Store the roles users may be assigned
+	if err := auth.SeedRoles(repo); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Initialize resolution of the DIDs of DID agents
+	didResolver = did.NewResolver(repo)
+
+	// Initialize verification of agents registered on trusted external platforms
+	federationVerifier = federation.NewVerifier(repo)
+
+	// Initialize signing of agent payment reputation credentials
+	reputationIssuer, err = reputation.NewIssuerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize reputation issuer: %v", err)
+	}
+
+	// Initialize the provider verifying parties (KYC/KYB)
+	kycProvider, err = kyc.NewProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize KYC provider: %v", err)
+	}
+
+	// Access tokens of agents' OAuth clients last this long
+	oauthTokenTTL = time.Duration(common.GetEnvAsInt("OAUTH_TOKEN_TTL_SECONDS", int(auth.DefaultClientTokenTTL.Seconds()))) * time.Second
+
 	r := gin.Default()
+	r.Use(common.CorrelationIDMiddleware(), common.TracingMiddleware(), common.MetricsMiddleware())
+
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
+	r.GET("/metrics", common.MetricsHandler())
 
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
@@ -51,21 +118,104 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "identity service ok"})
 	})
 
+	// Reputation issuer metadata, revocation registry and verification are
+	// public so third parties can check presented credentials
+	r.GET("/v1/reputation/issuer", getReputationIssuer)
+	r.GET("/v1/reputation/revocations", listReputationRevocations)
+	r.POST("/v1/reputation/verify", verifyReputationCredential)
+
+	// The OAuth token endpoint authenticates clients by their credentials
+	r.POST("/v1/oauth/token", issueAccessToken)
+
+	// DID resolution is public so relying parties can check what agents sign
+	r.GET("/v1/dids/:did", resolveDID)
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Party management
-		v1.POST("/parties", createParty)
-		v1.GET("/parties/:id", getParty)
+		v1.POST("/parties", common.RequireScopes(common.ScopePartiesWrite), createParty)
+		v1.GET("/parties/:id", common.RequireScopes(common.ScopePartiesRead), getParty)
+
+		// Party verification (KYC/KYB)
+		v1.POST("/parties/:id/kyc", common.RequireScopes(common.ScopePartiesWrite), submitKYC)
+		v1.GET("/parties/:id/kyc", common.RequireScopes(common.ScopePartiesRead), getKYCStatus)
+		v1.GET("/kyc/submissions", common.RequireScopes(common.ScopeComplianceReview), listKYCSubmissions)
+		v1.POST("/kyc/submissions/:id/review", common.RequireScopes(common.ScopeComplianceReview), common.RequireRolesOrPrincipals([]string{common.PrincipalService}, common.RoleReviewer), reviewKYCSubmission)
+
+		// Users of parties, their roles and approver groups
+		v1.POST("/parties/:id/users", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), createUser)
+		v1.GET("/parties/:id/users", common.RequireScopes(common.ScopePartiesRead), listUsers)
+		v1.GET("/users/:id", common.RequireScopes(common.ScopePartiesRead), getUser)
+		v1.PATCH("/users/:id", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), updateUser)
+		v1.PUT("/users/:id/roles/:role", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), assignUserRole)
+		v1.DELETE("/users/:id/roles/:role", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), revokeUserRole)
+		v1.GET("/roles", common.RequireScopes(common.ScopePartiesRead), listRoles)
+		v1.GET("/parties/:id/approver-groups/:group/members", common.RequireScopes(common.ScopePartiesRead), listApproverGroupMembers)
+		v1.PUT("/parties/:id/approver-groups/:group/members/:userId", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), addApproverGroupMember)
+		v1.DELETE("/parties/:id/approver-groups/:group/members/:userId", common.RequireScopes(common.ScopePartiesWrite), common.RequireRolesOrPrincipals([]string{common.PrincipalParty, common.PrincipalService}, common.RoleAdmin), removeApproverGroupMember)
 
 		// Agent management
-		v1.POST("/agents", createAgent)
-		v1.GET("/agents/:id", getAgent)
-		v1.GET("/agents", listAgents)
+		v1.POST("/agents", common.RequireScopes(common.ScopeAgentsWrite), createAgent)
+		v1.GET("/agents/:id", common.RequireScopes(common.ScopeAgentsRead), getAgent)
+		v1.GET("/agents", common.RequireScopes(common.ScopeAgentsRead), listAgents)
+
+		// Agent lifecycle
+		v1.POST("/agents/:id/suspend", common.RequireScopes(common.ScopeAgentsWrite), suspendAgent)
+		v1.POST("/agents/:id/activate", common.RequireScopes(common.ScopeAgentsWrite), activateAgent)
+		v1.POST("/agents/:id/decommission", common.RequireScopes(common.ScopeAgentsWrite), decommissionAgent)
+
+		// Agent credentials
+		v1.POST("/agents/:id/credentials", common.RequireScopes(common.ScopeAgentsWrite), issueAgentCredential)
+		v1.GET("/agents/:id/credentials", common.RequireScopes(common.ScopeAgentsRead), listAgentCredentials)
+		v1.POST("/agents/:id/credentials/verify", common.RequireScopes(common.ScopeAgentsRead), verifyAgentCredential)
+		v1.POST("/agents/:id/credentials/:credentialId/rotate", common.RequireScopes(common.ScopeAgentsWrite), rotateAgentCredential)
+		v1.DELETE("/agents/:id/credentials/:credentialId", common.RequireScopes(common.ScopeAgentsWrite), revokeAgentCredential)
+		v1.GET("/agents/:id/did.json", getAgentDIDDocument)
+
+		// DID keys and challenge-response verification of DID agents
+		v1.POST("/agents/:id/did/keys", common.RequireScopes(common.ScopeAgentsWrite), registerDIDKey)
+		v1.POST("/agents/:id/did/challenges", common.RequireScopes(common.ScopeAgentsRead), createDIDChallenge)
+		v1.POST("/agents/:id/did/challenges/:challengeId", common.RequireScopes(common.ScopeAgentsRead), answerDIDChallenge)
+
+		// Spending forecasts
+		v1.GET("/agents/:id/forecast", common.RequireScopes(common.ScopeAgentsRead), getSpendingForecast)
+
+		// Payment reputation credentials
+		v1.POST("/agents/:id/reputation-credentials", common.RequireScopes(common.ScopeAgentsRead), issueReputationCredential)
+		v1.GET("/agents/:id/reputation-credentials", common.RequireScopes(common.ScopeAgentsRead), listReputationCredentials)
+		v1.POST("/agents/:id/reputation-credentials/:credentialId/revoke", common.RequireScopes(common.ScopeAgentsWrite), revokeReputationCredential)
+
+		// Mandate signing keys
+		v1.POST("/agents/:id/mandate-keys", common.RequireScopes(common.ScopeMandateKeys), registerMandateKey)
+		v1.GET("/agents/:id/mandate-keys", common.RequireScopes(common.ScopeMandateKeys), listMandateKeys)
+		v1.DELETE("/agents/:id/mandate-keys/:keyId", common.RequireScopes(common.ScopeMandateKeys), revokeMandateKey)
+
+		// API key management
+		v1.POST("/parties/:id/api-keys", common.RequireScopes(common.ScopeCredentials), createAPIKey)
+		v1.GET("/parties/:id/api-keys", common.RequireScopes(common.ScopeCredentials), listAPIKeys)
+		v1.DELETE("/api-keys/:id", common.RequireScopes(common.ScopeCredentials), revokeAPIKey)
+
+		// Token exchange
+		v1.POST("/auth/token", issueToken)
+		v1.POST("/oauth/introspect", common.RequireScopes(common.ScopeTokensIntrospect), introspectToken)
+
+		// Agent identity federation
+		v1.POST("/federation/issuers", common.RequireScopes(common.ScopeOperations), createTrustedIssuer)
+		v1.GET("/federation/issuers", common.RequireScopes(common.ScopeOperations), listTrustedIssuers)
+		v1.GET("/federation/issuers/:id", common.RequireScopes(common.ScopeOperations), getTrustedIssuer)
+		v1.PATCH("/federation/issuers/:id", common.RequireScopes(common.ScopeOperations), updateTrustedIssuer)
+		v1.POST("/federation/issuers/:id/revocations", common.RequireScopes(common.ScopeOperations), revokeFederatedCredential)
+		v1.POST("/federation/verify", common.RequireScopes(common.ScopeAgentsWrite), verifyFederatedCredential)
 	}
 
-	log.Println("Identity service running on :8081")
-	log.Fatal(r.Run(":8081"))
+	log.Printf("Identity service running on %s", cfg.Addr())
+	if err := common.NewServer(cfg.Addr(), r).Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func createParty(c *gin.Context) {
@@ -86,6 +236,20 @@ func createParty(c *gin.Context) {
 		Type: req.Type,
 	}
 
+	// Parties belong to a top-level organization of the caller's tenant
+	if req.OrganizationID != "" {
+		organization, err := repo.PartyRepository().GetByID(req.OrganizationID)
+		if err != nil || !tenancy.Check(c, repo, organization.ID, "party", organization.ID) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Organization not found"))
+			return
+		}
+		if organization.Type != "organization" || organization.OrganizationID != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Parties can only belong to a top-level organization"))
+			return
+		}
+		party.OrganizationID = &organization.ID
+	}
+
 	if err := repo.PartyRepository().Create(party); err != nil {
 		common.Error("Failed to create party: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create party"))
@@ -93,12 +257,7 @@ func createParty(c *gin.Context) {
 	}
 
 	// Convert to API response format
-	response := &types.Party{
-		ID:        party.ID,
-		Name:      party.Name,
-		Type:      party.Type,
-		CreatedAt: party.CreatedAt.Format(time.RFC3339),
-	}
+	response := toPartyResponse(party)
 
 	common.Info("Created party: %s (%s)", party.Name, party.ID)
 	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
@@ -112,16 +271,32 @@ func getParty(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "party not found"})
 		return
 	}
+	if !tenancy.Check(c, repo, party.ID, "party", party.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "party not found"})
+		return
+	}
 
 	// Convert to API response format
+	response := toPartyResponse(party)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func toPartyResponse(party *database.Party) *types.Party {
 	response := &types.Party{
 		ID:        party.ID,
 		Name:      party.Name,
 		Type:      party.Type,
 		CreatedAt: party.CreatedAt.Format(time.RFC3339),
+		KYCStatus: kyc.Of(party.KYCStatus),
 	}
-
-	c.JSON(http.StatusOK, response)
+	if party.OrganizationID != nil {
+		response.OrganizationID = *party.OrganizationID
+	}
+	if party.KYCVerifiedAt != nil {
+		response.KYCVerifiedAt = party.KYCVerifiedAt.Format(time.RFC3339)
+	}
+	return response
 }
 
 func createAgent(c *gin.Context) {
@@ -137,8 +312,8 @@ func createAgent(c *gin.Context) {
 		return
 	}
 
-	// Verify owner party exists
-	if _, err := repo.PartyRepository().GetByID(req.OwnerPartyID); err != nil {
+	// Verify owner party exists within the caller's tenant
+	if _, err := repo.PartyRepository().GetByID(req.OwnerPartyID); err != nil || !tenancy.Check(c, repo, req.OwnerPartyID, "party", req.OwnerPartyID) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Owner party not found"))
 		return
 	}
@@ -147,6 +322,7 @@ func createAgent(c *gin.Context) {
 		DisplayName:  req.DisplayName,
 		OwnerPartyID: req.OwnerPartyID,
 		IdentityMode: req.IdentityMode,
+		Status:       string(agentstate.Active),
 	}
 
 	if err := repo.AgentRepository().Create(agent); err != nil {
@@ -155,17 +331,19 @@ func createAgent(c *gin.Context) {
 		return
 	}
 
-	// Convert to API response format
-	response := &types.Agent{
-		ID:           agent.ID,
-		DisplayName:  agent.DisplayName,
-		OwnerPartyID: agent.OwnerPartyID,
-		IdentityMode: agent.IdentityMode,
-		CreatedAt:    agent.CreatedAt.Format(time.RFC3339),
-	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditAgentCreated,
+		Severity:     audit.SeverityMedium,
+		AgentID:      agent.ID,
+		ResourceID:   agent.ID,
+		ResourceType: "agent",
+		Action:       "create",
+		Description:  "Agent " + agent.DisplayName + " created for party " + agent.OwnerPartyID,
+		NewValues:    map[string]interface{}{"displayName": agent.DisplayName, "ownerPartyId": agent.OwnerPartyID, "identityMode": agent.IdentityMode},
+	})
 
 	common.Info("Created agent: %s (%s) for party %s", agent.DisplayName, agent.ID, agent.OwnerPartyID)
-	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toAgentResponse(agent)))
 }
 
 func getAgent(c *gin.Context) {
@@ -176,17 +354,12 @@ func getAgent(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 		return
 	}
-
-	// Convert to API response format
-	response := &types.Agent{
-		ID:           agent.ID,
-		DisplayName:  agent.DisplayName,
-		OwnerPartyID: agent.OwnerPartyID,
-		IdentityMode: agent.IdentityMode,
-		CreatedAt:    agent.CreatedAt.Format(time.RFC3339),
+	if !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, toAgentResponse(agent))
 }
 
 func listAgents(c *gin.Context) {
@@ -195,9 +368,18 @@ func listAgents(c *gin.Context) {
 	var agents []*database.Agent
 	var err error
 
-	if ownerPartyID != "" {
+	// Callers confined to a tenant list its agents only
+	tenant := common.RequestTenant(c)
+	switch {
+	case ownerPartyID != "":
+		if !tenancy.Check(c, repo, ownerPartyID, "party", ownerPartyID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list agents of this party"))
+			return
+		}
 		agents, err = repo.AgentRepository().ListByOwnerPartyID(ownerPartyID)
-	} else {
+	case tenant != "":
+		agents, err = repo.AgentRepository().ListByTenantID(tenant)
+	default:
 		agents, err = repo.AgentRepository().List()
 	}
 
@@ -210,13 +392,7 @@ func listAgents(c *gin.Context) {
 	// Convert to API response format
 	var result []*types.Agent
 	for _, agent := range agents {
-		result = append(result, &types.Agent{
-			ID:           agent.ID,
-			DisplayName:  agent.DisplayName,
-			OwnerPartyID: agent.OwnerPartyID,
-			IdentityMode: agent.IdentityMode,
-			CreatedAt:    agent.CreatedAt.Format(time.RFC3339),
-		})
+		result = append(result, toAgentResponse(agent))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/mandate"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type RegisterMandateKeyRequest struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"publicKey" binding:"required"` // Base64url Ed25519 public key
+}
+
+type MandateKeyResponse struct {
+	ID        string `json:"id"`
+	AgentID   string `json:"agentId"`
+	Name      string `json:"name,omitempty"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+	Status    string `json:"status"`
+	RevokedAt string `json:"revokedAt,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func toMandateKeyResponse(key *database.MandateKey) *MandateKeyResponse {
+	response := &MandateKeyResponse{
+		ID:        key.ID,
+		AgentID:   key.AgentID,
+		Name:      key.Name,
+		Algorithm: key.Algorithm,
+		PublicKey: key.PublicKey,
+		Status:    key.Status,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.RevokedAt != nil {
+		response.RevokedAt = key.RevokedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// loadSigningAgent loads the agent in the path. Agents manage their own mandate
+// keys; owning parties and services may manage them on the agent's behalf.
+func loadSigningAgent(c *gin.Context) (*database.Agent, bool) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return nil, false
+	}
+
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalAgent {
+		if principal.AgentID != agent.ID {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage mandate keys for this agent"))
+			return nil, false
+		}
+		return agent, true
+	}
+
+	if !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage mandate keys for this agent"))
+		return nil, false
+	}
+	return agent, true
+}
+
+func registerMandateKey(c *gin.Context) {
+	var req RegisterMandateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	agent, ok := loadSigningAgent(c)
+	if !ok {
+		return
+	}
+
+	if _, err := mandate.DecodePublicKey(req.PublicKey); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	key := &database.MandateKey{
+		AgentID:   agent.ID,
+		Name:      req.Name,
+		Algorithm: "Ed25519",
+		PublicKey: req.PublicKey,
+		Status:    "active",
+	}
+
+	if err := repo.MandateKeyRepository().Create(key); err != nil {
+		common.Error("Failed to register mandate key: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to register mandate key"))
+		return
+	}
+
+	common.Info("Registered mandate key %s for agent %s", key.ID, agent.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toMandateKeyResponse(key)))
+}
+
+func listMandateKeys(c *gin.Context) {
+	agent, ok := loadSigningAgent(c)
+	if !ok {
+		return
+	}
+
+	keys, err := repo.MandateKeyRepository().ListByAgentID(agent.ID)
+	if err != nil {
+		common.Error("Failed to list mandate keys: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list mandate keys"))
+		return
+	}
+
+	response := common.NewListResponse(make([]interface{}, len(keys)), 1, len(keys), len(keys))
+	for i, key := range keys {
+		response.Items[i] = toMandateKeyResponse(key)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func revokeMandateKey(c *gin.Context) {
+	agent, ok := loadSigningAgent(c)
+	if !ok {
+		return
+	}
+
+	key, err := repo.MandateKeyRepository().GetByID(c.Param("keyId"))
+	if err != nil || key.AgentID != agent.ID {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Mandate key not found"))
+		return
+	}
+
+	if key.Status == "active" {
+		now := time.Now()
+		key.Status = "revoked"
+		key.RevokedAt = &now
+		if err := repo.MandateKeyRepository().Update(key); err != nil {
+			common.Error("Failed to revoke mandate key: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke mandate key"))
+			return
+		}
+	}
+
+	common.Info("Revoked mandate key %s for agent %s", key.ID, agent.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toMandateKeyResponse(key)))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthTokenTTL is how long access tokens of the client_credentials grant last
+var oauthTokenTTL time.Duration
+
+// OAuthTokenResponse is a successful token response (RFC 6749 section 5.1).
+// OAuth fixes its field names, so they are not camelCase.
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorResponse is an error of the token endpoint (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse describes a token (RFC 7662 section 2.2). Inactive
+// tokens are described by active alone.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	AgentID   string `json:"agent_id,omitempty"`
+	PartyID   string `json:"party_id,omitempty"`
+	Type      string `json:"typ,omitempty"` // Principal type: "party", "agent" or "service"
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+// issueAccessToken is the OAuth 2.0 token endpoint. An agent's OAuth client
+// exchanges its credentials, sent with HTTP Basic authentication or as
+// client_id and client_secret form parameters, for an access token
+// (client_credentials grant). The space-separated scope parameter narrows
+// the token to some of the client's scopes.
+func issueAccessToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	switch c.PostForm("grant_type") {
+	case "client_credentials":
+	case "":
+		c.JSON(http.StatusBadRequest, &OAuthErrorResponse{Error: "invalid_request", ErrorDescription: "grant_type is required"})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, &OAuthErrorResponse{Error: "unsupported_grant_type", ErrorDescription: "Only client_credentials is supported"})
+		return
+	}
+
+	clientID, secret, basic := c.Request.BasicAuth()
+	if !basic {
+		clientID, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	credential, agent, err := auth.AuthenticateClient(repo, clientID, secret)
+	if err != nil {
+		common.Warn("Rejected OAuth client %s: %v", clientID, err)
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="agent-payments"`)
+		}
+		c.JSON(http.StatusUnauthorized, &OAuthErrorResponse{Error: "invalid_client", ErrorDescription: "Client authentication failed"})
+		return
+	}
+
+	token, claims, err := auth.IssueClientToken(authConfig, credential, agent, strings.Fields(c.PostForm("scope")), oauthTokenTTL)
+	if errors.Is(err, auth.ErrInvalidScope) {
+		c.JSON(http.StatusBadRequest, &OAuthErrorResponse{Error: "invalid_scope", ErrorDescription: err.Error()})
+		return
+	}
+	if err != nil {
+		common.Error("Failed to issue token to OAuth client %s: %v", clientID, err)
+		c.JSON(http.StatusInternalServerError, &OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	common.Info("Issued token %s to OAuth client %s of agent %s", claims.ID, credential.ID, agent.ID)
+	c.JSON(http.StatusOK, &OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(claims.ExpiresAt - claims.IssuedAt),
+		Scope:       strings.Join(claims.Scopes, " "),
+	})
+}
+
+// introspectToken describes a token issued by the platform to the services
+// checking it (RFC 7662). Tokens of OAuth clients are only active while the
+// client and its agent are.
+func introspectToken(c *gin.Context) {
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, &OAuthErrorResponse{Error: "invalid_request", ErrorDescription: "token is required"})
+		return
+	}
+
+	claims, err := common.ParseJWT(token, authConfig.JWTSecret)
+	if err == nil && authConfig.Issuer != "" && claims.Issuer != authConfig.Issuer {
+		err = common.ErrInvalidToken
+	}
+	if err == nil && claims.ClientID != "" {
+		err = auth.CheckClientToken(repo, claims)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, &IntrospectionResponse{Active: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, &IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.ClientID,
+		Subject:   claims.Subject,
+		AgentID:   claims.AgentID,
+		PartyID:   claims.PartyID,
+		Type:      claims.Type,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		Issuer:    claims.Issuer,
+		ID:        claims.ID,
+	})
+}
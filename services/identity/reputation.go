@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/reputation"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type IssueReputationCredentialRequest struct {
+	Claims     []string `json:"claims,omitempty"` // Defaults to every claim
+	TTLSeconds int      `json:"ttlSeconds,omitempty"`
+}
+
+type RevokeReputationCredentialRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type VerifyReputationCredentialRequest struct {
+	Credential string `json:"credential" binding:"required"` // SD-JWT presentation
+}
+
+type ReputationCredentialResponse struct {
+	ID               string                 `json:"id"`
+	AgentID          string                 `json:"agentId"`
+	Status           string                 `json:"status"`
+	Claims           map[string]interface{} `json:"claims"`
+	SDJWT            string                 `json:"sdJwt,omitempty"`       // Only returned at issuance
+	Disclosures      map[string]string      `json:"disclosures,omitempty"` // Only returned at issuance
+	IssuedAt         string                 `json:"issuedAt"`
+	ExpiresAt        string                 `json:"expiresAt"`
+	RevokedAt        string                 `json:"revokedAt,omitempty"`
+	RevocationReason string                 `json:"revocationReason,omitempty"`
+}
+
+type VerifyReputationCredentialResponse struct {
+	Valid        bool                   `json:"valid"`
+	CredentialID string                 `json:"credentialId,omitempty"`
+	AgentID      string                 `json:"agentId,omitempty"`
+	Claims       map[string]interface{} `json:"claims,omitempty"` // Disclosed claims only
+	IssuedAt     string                 `json:"issuedAt,omitempty"`
+	ExpiresAt    string                 `json:"expiresAt,omitempty"`
+	Reason       string                 `json:"reason,omitempty"`
+}
+
+func toReputationCredentialResponse(credential *database.ReputationCredential) *ReputationCredentialResponse {
+	var claims map[string]interface{}
+	json.Unmarshal([]byte(credential.Claims), &claims)
+
+	response := &ReputationCredentialResponse{
+		ID:               credential.ID,
+		AgentID:          credential.AgentID,
+		Status:           credential.Status,
+		Claims:           claims,
+		IssuedAt:         credential.IssuedAt.Format(time.RFC3339),
+		ExpiresAt:        credential.ExpiresAt.Format(time.RFC3339),
+		RevocationReason: credential.RevocationReason,
+	}
+	if credential.RevokedAt != nil {
+		response.RevokedAt = credential.RevokedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// loadReputationHolder loads the agent in the path. Agents may request their
+// own credentials; parties may request them for agents they own.
+func loadReputationHolder(c *gin.Context) (*database.Agent, bool) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return nil, false
+	}
+
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalAgent {
+		if principal.AgentID != agent.ID {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage reputation credentials for this agent"))
+			return nil, false
+		}
+		return agent, true
+	}
+
+	if !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage reputation credentials for this agent"))
+		return nil, false
+	}
+	return agent, true
+}
+
+func issueReputationCredential(c *gin.Context) {
+	var req IssueReputationCredentialRequest
+	c.ShouldBindJSON(&req)
+
+	agent, ok := loadReputationHolder(c)
+	if !ok {
+		return
+	}
+
+	issued, err := reputationIssuer.Issue(repo, agent.ID, req.Claims, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		if errors.Is(err, reputation.ErrUnknownClaim) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+			return
+		}
+		common.Error("Failed to issue reputation credential for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("CREDENTIAL_ERROR", "Failed to issue reputation credential"))
+		return
+	}
+
+	response := toReputationCredentialResponse(issued.Credential)
+	response.SDJWT = issued.SDJWT
+	response.Disclosures = issued.Disclosures
+
+	common.Info("Issued reputation credential %s for agent %s", issued.Credential.ID, agent.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+func listReputationCredentials(c *gin.Context) {
+	agent, ok := loadReputationHolder(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := repo.ReputationCredentialRepository().ListByAgentID(agent.ID)
+	if err != nil {
+		common.Error("Failed to list reputation credentials: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list reputation credentials"))
+		return
+	}
+
+	items := make([]interface{}, len(credentials))
+	for i, credential := range credentials {
+		items[i] = toReputationCredentialResponse(credential)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func revokeReputationCredential(c *gin.Context) {
+	var req RevokeReputationCredentialRequest
+	c.ShouldBindJSON(&req)
+
+	agent, ok := loadReputationHolder(c)
+	if !ok {
+		return
+	}
+
+	credential, err := repo.ReputationCredentialRepository().GetByID(c.Param("credentialId"))
+	if err != nil || credential.AgentID != agent.ID {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Reputation credential not found"))
+		return
+	}
+
+	if err := reputation.Revoke(repo, credential, req.Reason); err != nil {
+		common.Error("Failed to revoke reputation credential %s: %v", credential.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke reputation credential"))
+		return
+	}
+
+	common.Info("Revoked reputation credential %s for agent %s", credential.ID, agent.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toReputationCredentialResponse(credential)))
+}
+
+// verifyReputationCredential lets third parties check a presentation without
+// platform credentials
+func verifyReputationCredential(c *gin.Context) {
+	var req VerifyReputationCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	verified, err := reputationIssuer.Verify(repo, req.Credential)
+	if err != nil {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&VerifyReputationCredentialResponse{Valid: false, Reason: err.Error()}))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&VerifyReputationCredentialResponse{
+		Valid:        true,
+		CredentialID: verified.CredentialID,
+		AgentID:      verified.AgentID,
+		Claims:       verified.Claims,
+		IssuedAt:     verified.IssuedAt.Format(time.RFC3339),
+		ExpiresAt:    verified.ExpiresAt.Format(time.RFC3339),
+	}))
+}
+
+func getReputationIssuer(c *gin.Context) {
+	c.JSON(http.StatusOK, reputationIssuer.DIDDocument())
+}
+
+// listReputationRevocations serves the revocation registry in the same
+// {"revoked": [...]} format the platform consumes from federated issuers
+func listReputationRevocations(c *gin.Context) {
+	credentials, err := repo.ReputationCredentialRepository().ListRevoked()
+	if err != nil {
+		common.Error("Failed to list revoked reputation credentials: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list revocations"))
+		return
+	}
+
+	revoked := make([]string, len(credentials))
+	for i, credential := range credentials {
+		revoked[i] = credential.ID
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOtherTenantsAgentKeysAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+
+	// Two tenants, each an organization with an agent
+	agents := map[string]*database.Agent{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		agents[name] = agent
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: agents["Acme"].OwnerPartyID, TenantID: agents["Acme"].OwnerPartyID}
+	get := func(path string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.GET("/v1/agents/:id/mandate-keys", listMandateKeys)
+		router.GET("/v1/agents/:id/credentials", listAgentCredentials)
+		router.GET("/v1/agents/:id/reputation-credentials", listReputationCredentials)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	for _, resource := range []string{"mandate-keys", "credentials", "reputation-credentials"} {
+		if code := get("/v1/agents/" + agents["Acme"].ID + "/" + resource); code != http.StatusOK {
+			t.Fatalf("own agent's %s: got %d", resource, code)
+		}
+		if code := get("/v1/agents/" + agents["Globex"].ID + "/" + resource); code != http.StatusNotFound {
+			t.Fatalf("other tenant's agent's %s: got %d, want 404", resource, code)
+		}
+	}
+}
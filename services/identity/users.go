@@ -0,0 +1,440 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateUserRequest struct {
+	Email string   `json:"email" binding:"required,email"`
+	Name  string   `json:"name" binding:"required,max=255"`
+	Roles []string `json:"roles,omitempty"` // Such as admin, approver or reviewer
+}
+
+type UpdateUserRequest struct {
+	Name   string `json:"name,omitempty" binding:"max=255"`
+	Status string `json:"status,omitempty"` // "active" or "disabled"
+}
+
+type UserResponse struct {
+	ID             string   `json:"id"`
+	PartyID        string   `json:"partyId"`
+	Email          string   `json:"email"`
+	Name           string   `json:"name"`
+	Status         string   `json:"status"`
+	Roles          []string `json:"roles"`
+	ApproverGroups []string `json:"approverGroups"`
+	CreatedBy      string   `json:"createdBy,omitempty"`
+	CreatedAt      string   `json:"createdAt"`
+	UpdatedAt      string   `json:"updatedAt"`
+}
+
+type RoleResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ApproverGroupMemberResponse struct {
+	PartyID   string `json:"partyId"`
+	Group     string `json:"group"`
+	UserID    string `json:"userId"`
+	Email     string `json:"email,omitempty"`
+	Name      string `json:"name,omitempty"`
+	AddedBy   string `json:"addedBy,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// createUser adds a person acting for a party, with its initial roles. The
+// user authenticates with API keys issued to it.
+func createUser(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "A valid email and a name are required"))
+		return
+	}
+	for _, role := range req.Roles {
+		if !auth.KnownRole(role) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Unknown role: "+role))
+			return
+		}
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if _, err := repo.UserRepository().GetByEmail(email); err == nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("ALREADY_EXISTS", "A user with this email exists"))
+		return
+	}
+
+	user := &database.User{
+		PartyID:   party.ID,
+		Email:     email,
+		Name:      req.Name,
+		Status:    auth.UserStatusActive,
+		CreatedBy: callerSubject(c),
+	}
+	if err := repo.UserRepository().Create(user); err != nil {
+		common.Error("Failed to create user for party %s: %v", party.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create user"))
+		return
+	}
+	for _, role := range req.Roles {
+		if _, ok := assignRole(c, user, role); !ok {
+			return
+		}
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditUserCreated,
+		Severity:     audit.SeverityMedium,
+		ResourceID:   user.ID,
+		ResourceType: "user",
+		Action:       "create",
+		Description:  "User " + user.Email + " created for party " + party.Name,
+		NewValues:    map[string]interface{}{"email": user.Email, "name": user.Name, "partyId": party.ID, "roles": req.Roles},
+	})
+
+	common.Info("Created user %s for party %s", user.ID, party.ID)
+	respondUser(c, http.StatusCreated, user)
+}
+
+// listUsers lists a party's users, oldest first
+func listUsers(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	users, err := repo.UserRepository().ListByPartyID(party.ID)
+	if err != nil {
+		common.Error("Failed to list users of party %s: %v", party.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list users"))
+		return
+	}
+
+	items := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		response, err := toUserResponse(user)
+		if err != nil {
+			common.Error("Failed to load roles of user %s: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list users"))
+			return
+		}
+		items = append(items, response)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getUser returns a user with its roles and approver groups
+func getUser(c *gin.Context) {
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return
+	}
+	respondUser(c, http.StatusOK, user)
+}
+
+// updateUser renames a user, or disables or reactivates it. Disabled users'
+// keys stop working.
+func updateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	if req.Status != "" && req.Status != auth.UserStatusActive && req.Status != auth.UserStatusDisabled {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "status must be active or disabled"))
+		return
+	}
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return
+	}
+	if principal := common.GetPrincipal(c); principal != nil && principal.UserID == user.ID && req.Status == auth.UserStatusDisabled {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Users cannot disable themselves"))
+		return
+	}
+
+	old := map[string]interface{}{"name": user.Name, "status": user.Status}
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	if req.Status != "" {
+		user.Status = req.Status
+	}
+	if err := repo.UserRepository().Update(user); err != nil {
+		common.Error("Failed to update user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update user"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditUserUpdated,
+		Severity:     audit.SeverityMedium,
+		ResourceID:   user.ID,
+		ResourceType: "user",
+		Action:       "update",
+		Description:  "User " + user.Email + " updated",
+		OldValues:    old,
+		NewValues:    map[string]interface{}{"name": user.Name, "status": user.Status},
+	})
+	respondUser(c, http.StatusOK, user)
+}
+
+// assignUserRole gives a user a role. Assigning a role the user holds
+// changes nothing.
+func assignUserRole(c *gin.Context) {
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return
+	}
+	if !auth.KnownRole(c.Param("role")) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Role not found"))
+		return
+	}
+	if assigned, ok := assignRole(c, user, c.Param("role")); !ok {
+		return
+	} else if assigned {
+		audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+			EventType:    audit.AuditUserRoleAssigned,
+			Severity:     audit.SeverityHigh,
+			ResourceID:   user.ID,
+			ResourceType: "user",
+			Action:       "assign_role",
+			Description:  "Role " + c.Param("role") + " assigned to " + user.Email,
+			NewValues:    map[string]interface{}{"role": c.Param("role")},
+		})
+		common.Info("Assigned role %s to user %s", c.Param("role"), user.ID)
+	}
+	respondUser(c, http.StatusOK, user)
+}
+
+// revokeUserRole takes a role from a user. Keys of the user lose it at once;
+// tokens already exchanged for them keep it until they expire.
+func revokeUserRole(c *gin.Context) {
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return
+	}
+	role, err := repo.RoleRepository().GetByName(c.Param("role"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Role not found"))
+		return
+	}
+	if principal := common.GetPrincipal(c); principal != nil && principal.UserID == user.ID && role.Name == common.RoleAdmin {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Admins cannot revoke their own admin role"))
+		return
+	}
+
+	revoked, err := repo.UserRepository().RevokeRole(user.ID, role.ID)
+	if err != nil {
+		common.Error("Failed to revoke role %s of user %s: %v", role.Name, user.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke role"))
+		return
+	}
+	if revoked {
+		audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+			EventType:    audit.AuditUserRoleRevoked,
+			Severity:     audit.SeverityHigh,
+			ResourceID:   user.ID,
+			ResourceType: "user",
+			Action:       "revoke_role",
+			Description:  "Role " + role.Name + " revoked from " + user.Email,
+			OldValues:    map[string]interface{}{"role": role.Name},
+		})
+		common.Info("Revoked role %s of user %s", role.Name, user.ID)
+	}
+	respondUser(c, http.StatusOK, user)
+}
+
+// listRoles lists the roles users may be assigned
+func listRoles(c *gin.Context) {
+	roles, err := repo.RoleRepository().List()
+	if err != nil {
+		common.Error("Failed to list roles: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list roles"))
+		return
+	}
+	items := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		items = append(items, &RoleResponse{Name: role.Name, Description: role.Description})
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// listApproverGroupMembers lists the users of a party's approver group
+func listApproverGroupMembers(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	members, err := repo.ApproverGroupRepository().ListMembers(party.ID, c.Param("group"))
+	if err != nil {
+		common.Error("Failed to list members of approver group %s: %v", c.Param("group"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list members"))
+		return
+	}
+	items := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		items = append(items, toApproverGroupMemberResponse(member))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// addApproverGroupMember makes one of the party's users a member of an
+// approver group. Members with the approver role decide the group's
+// approvals.
+func addApproverGroupMember(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	user, err := repo.UserRepository().GetByID(c.Param("userId"))
+	if err != nil || user.PartyID != party.ID {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "User not found"))
+		return
+	}
+
+	member := &database.ApproverGroupMember{
+		PartyID:   party.ID,
+		GroupName: c.Param("group"),
+		UserID:    user.ID,
+		AddedBy:   callerSubject(c),
+	}
+	added, err := repo.ApproverGroupRepository().AddMember(member)
+	if err != nil {
+		common.Error("Failed to add user %s to approver group %s: %v", user.ID, member.GroupName, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to add member"))
+		return
+	}
+	if added {
+		audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+			EventType:    audit.AuditApproverGroupMemberAdded,
+			Severity:     audit.SeverityHigh,
+			ResourceID:   user.ID,
+			ResourceType: "user",
+			Action:       "add_approver_group_member",
+			Description:  user.Email + " added to approver group " + member.GroupName,
+			NewValues:    map[string]interface{}{"partyId": party.ID, "group": member.GroupName},
+		})
+		common.Info("Added user %s to approver group %s of party %s", user.ID, member.GroupName, party.ID)
+	}
+	member.User = *user
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toApproverGroupMemberResponse(member)))
+}
+
+// removeApproverGroupMember removes a user from an approver group
+func removeApproverGroupMember(c *gin.Context) {
+	party, ok := loadManagedParty(c)
+	if !ok {
+		return
+	}
+	removed, err := repo.ApproverGroupRepository().RemoveMember(party.ID, c.Param("group"), c.Param("userId"))
+	if err != nil {
+		common.Error("Failed to remove user %s from approver group %s: %v", c.Param("userId"), c.Param("group"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to remove member"))
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "User is not a member of this group"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditApproverGroupMemberRemoved,
+		Severity:     audit.SeverityHigh,
+		ResourceID:   c.Param("userId"),
+		ResourceType: "user",
+		Action:       "remove_approver_group_member",
+		Description:  "User " + c.Param("userId") + " removed from approver group " + c.Param("group"),
+		OldValues:    map[string]interface{}{"partyId": party.ID, "group": c.Param("group")},
+	})
+	common.Info("Removed user %s from approver group %s of party %s", c.Param("userId"), c.Param("group"), party.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// assignRole gives a user a role, reporting whether it was new, and answers
+// the request on failure
+func assignRole(c *gin.Context, user *database.User, name string) (bool, bool) {
+	role, err := repo.RoleRepository().GetByName(name)
+	if err == nil {
+		var assigned bool
+		assigned, err = repo.UserRepository().AssignRole(&database.UserRole{UserID: user.ID, RoleID: role.ID, AssignedBy: callerSubject(c)})
+		if err == nil {
+			return assigned, true
+		}
+	}
+	common.Error("Failed to assign role %s to user %s: %v", name, user.ID, err)
+	c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to assign role"))
+	return false, false
+}
+
+// loadManagedUser loads the user of the request path, answering 404 when it
+// does not exist or the caller cannot manage its party
+func loadManagedUser(c *gin.Context) (*database.User, bool) {
+	user, err := repo.UserRepository().GetByID(c.Param("id"))
+	if err != nil || !canManageParty(c, user.PartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "User not found"))
+		return nil, false
+	}
+	return user, true
+}
+
+func respondUser(c *gin.Context, status int, user *database.User) {
+	response, err := toUserResponse(user)
+	if err != nil {
+		common.Error("Failed to load roles of user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load user"))
+		return
+	}
+	c.JSON(status, common.NewSuccessResponse(response))
+}
+
+func toUserResponse(user *database.User) (*UserResponse, error) {
+	roles, err := repo.UserRepository().RoleNames(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := repo.ApproverGroupRepository().ListByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &UserResponse{
+		ID:             user.ID,
+		PartyID:        user.PartyID,
+		Email:          user.Email,
+		Name:           user.Name,
+		Status:         user.Status,
+		Roles:          append([]string{}, roles...),
+		ApproverGroups: make([]string, 0, len(memberships)),
+		CreatedBy:      user.CreatedBy,
+		CreatedAt:      user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      user.UpdatedAt.Format(time.RFC3339),
+	}
+	for _, membership := range memberships {
+		response.ApproverGroups = append(response.ApproverGroups, membership.GroupName)
+	}
+	return response, nil
+}
+
+func toApproverGroupMemberResponse(member *database.ApproverGroupMember) *ApproverGroupMemberResponse {
+	return &ApproverGroupMemberResponse{
+		PartyID:   member.PartyID,
+		Group:     member.GroupName,
+		UserID:    member.UserID,
+		Email:     member.User.Email,
+		Name:      member.User.Name,
+		AddedBy:   member.AddedBy,
+		CreatedAt: member.CreatedAt.Format(time.RFC3339),
+	}
+}
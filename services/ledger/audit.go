@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Audit summaries, compliance reports and ledger reports cover the last
+// reportDefaultPeriod unless asked otherwise, and at most reportMaxPeriod, as
+// everything in the period is loaded
+const (
+	reportDefaultPeriod = 30 * 24 * time.Hour
+	reportMaxPeriod     = 366 * 24 * time.Hour
+)
+
+type LogAuditEventRequest struct {
+	EventType     string                 `json:"eventType" binding:"required,max=100"`
+	Severity      string                 `json:"severity,omitempty"` // Defaults to low
+	Action        string                 `json:"action" binding:"required,max=100"`
+	Description   string                 `json:"description" binding:"required,max=500"`
+	UserID        string                 `json:"userId,omitempty"` // Defaults to the caller
+	AgentID       string                 `json:"agentId,omitempty"`
+	ResourceID    string                 `json:"resourceId,omitempty"`
+	ResourceType  string                 `json:"resourceType,omitempty"`
+	OldValues     map[string]interface{} `json:"oldValues,omitempty"`
+	NewValues     map[string]interface{} `json:"newValues,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	SessionID     string                 `json:"sessionId,omitempty"`
+	CorrelationID string                 `json:"correlationId,omitempty"` // Defaults to the request's
+}
+
+func validAuditSeverity(severity string) bool {
+	switch audit.AuditSeverity(severity) {
+	case audit.SeverityLow, audit.SeverityMedium, audit.SeverityHigh, audit.SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// logAuditEvent records an audit event reported by a client, such as an
+// operator action taken outside the platform. The user defaults to the
+// caller, and the IP address and user agent are the caller's.
+func logAuditEvent(c *gin.Context) {
+	var req LogAuditEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if req.Severity == "" {
+		req.Severity = string(audit.SeverityLow)
+	}
+	if !validAuditSeverity(req.Severity) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "severity must be low, medium, high or critical"))
+		return
+	}
+	if req.AgentID != "" && !tenancy.CheckAgent(c, repo, req.AgentID, "agent", req.AgentID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	entry := &audit.AuditEntry{
+		EventType:     audit.AuditEventType(req.EventType),
+		Severity:      audit.AuditSeverity(req.Severity),
+		UserID:        req.UserID,
+		AgentID:       req.AgentID,
+		ResourceID:    req.ResourceID,
+		ResourceType:  req.ResourceType,
+		Action:        req.Action,
+		Description:   req.Description,
+		OldValues:     req.OldValues,
+		NewValues:     req.NewValues,
+		Metadata:      req.Metadata,
+		SessionID:     req.SessionID,
+		CorrelationID: req.CorrelationID,
+	}
+	if err := audit.Record(c, audit.NewAuditTrail(repo), entry); err != nil {
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to log audit event"))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(entry))
+}
+
+// listAuditEvents lists audit entries, newest first, filtered by ?agentId=
+// &userId=&resourceId=&resourceType=&eventType=&severity=&ipAddress= and
+// logged between ?from= and ?to=
+func listAuditEvents(c *gin.Context) {
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	if params.Sort != "" || !params.Descending {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Audit events are listed newest first only"))
+		return
+	}
+	filters, ok := parseAuditFilters(c, params)
+	if !ok {
+		return
+	}
+	filters.Limit, filters.Offset = params.Limit, params.Offset
+
+	trail := audit.NewAuditTrail(repo)
+	total, err := trail.CountAuditTrail(c.Request.Context(), filters)
+	if err != nil {
+		common.Error("Failed to count audit events: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list audit events"))
+		return
+	}
+	entries, err := trail.QueryAuditTrail(c.Request.Context(), filters)
+	if err != nil {
+		common.Error("Failed to list audit events: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list audit events"))
+		return
+	}
+
+	items := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		items[i] = entry
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewPageResponse(items, params, int(total))))
+}
+
+// parseAuditFilters reads the audit query filters, limited to the caller's
+// tenant, responding with 400 if the severity is not known
+func parseAuditFilters(c *gin.Context, params common.ListParams) (audit.AuditQueryFilters, bool) {
+	severity := c.Query("severity")
+	if severity != "" && !validAuditSeverity(severity) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "severity must be low, medium, high or critical"))
+		return audit.AuditQueryFilters{}, false
+	}
+	return audit.AuditQueryFilters{
+		UserID:       c.Query("userId"),
+		AgentID:      c.Query("agentId"),
+		ResourceID:   c.Query("resourceId"),
+		ResourceType: c.Query("resourceType"),
+		EventType:    audit.AuditEventType(c.Query("eventType")),
+		Severity:     audit.AuditSeverity(severity),
+		StartDate:    params.From,
+		EndDate:      params.To,
+		IPAddress:    c.Query("ipAddress"),
+		TenantID:     common.RequestTenant(c),
+	}, true
+}
+
+// getAuditSummary counts the audit entries logged between ?from= and ?to= by
+// event type, severity, user and resource
+func getAuditSummary(c *gin.Context) {
+	from, to, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+	summary, err := audit.NewAuditTrail(repo).GetAuditSummary(c.Request.Context(), common.RequestTenant(c), from, to)
+	if err != nil {
+		common.Error("Failed to summarize audit events: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to summarize audit events"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(summary))
+}
+
+// getAuditComplianceReport reports the failed logins, security alerts, failed
+// payments and critical events logged between ?from= and ?to=
+func getAuditComplianceReport(c *gin.Context) {
+	from, to, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+	report, err := audit.NewAuditTrail(repo).GetComplianceReport(c.Request.Context(), common.RequestTenant(c), from, to)
+	if err != nil {
+		common.Error("Failed to build audit compliance report: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build compliance report"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(report))
+}
+
+// getAuditChangeHistory lists the latest audit entries about a resource,
+// optionally of one ?resourceType=, up to ?limit= (default 50)
+func getAuditChangeHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(common.DefaultPageLimit)))
+	if err != nil || limit < 1 || limit > common.MaxPageLimit {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "limit must be from 1 to "+strconv.Itoa(common.MaxPageLimit)))
+		return
+	}
+
+	entries, err := audit.NewAuditTrail(repo).GetChangeHistory(c.Request.Context(), common.RequestTenant(c), c.Param("resourceId"), c.Query("resourceType"), limit)
+	if err != nil {
+		common.Error("Failed to get change history of %s: %v", c.Param("resourceId"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get change history"))
+		return
+	}
+	items := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		items[i] = entry
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, limit, len(items))))
+}
+
+// parseReportPeriod reads ?from= and ?to=, defaulting to the
+// reportDefaultPeriod up to now, and responds with 400 if they are malformed
+// or span more than reportMaxPeriod
+func parseReportPeriod(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		parsed, err := common.ParseTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "to must be an RFC3339 time"))
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	from := to.Add(-reportDefaultPeriod)
+	if value := c.Query("from"); value != "" {
+		parsed, err := common.ParseTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "from must be an RFC3339 time"))
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "from must be before to"))
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Sub(from) > reportMaxPeriod {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "The period must not exceed 366 days"))
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
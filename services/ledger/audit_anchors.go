@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/hashchain"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Audit entries are anchored in batches of at most auditAnchorBatch, leaving
+// out those logged in the last auditAnchorSettle so entries still being
+// written are not skipped
+const (
+	auditAnchorBatch  = 10000
+	auditAnchorSettle = time.Minute
+)
+
+type AuditAnchorResponse struct {
+	ID            string `json:"id"`
+	Root          string `json:"root"`
+	EntryCount    int    `json:"entryCount"`
+	FirstEntryID  string `json:"firstEntryId"`
+	LastEntryID   string `json:"lastEntryId"`
+	FromTimestamp string `json:"fromTimestamp"`
+	ToTimestamp   string `json:"toTimestamp"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type VerifyAuditProofRequest struct {
+	AnchorID string                `json:"anchorId" binding:"required"`
+	EntryID  string                `json:"entryId,omitempty"`  // Hash the stored entry as the leaf
+	LeafHash string                `json:"leafHash,omitempty"` // Or verify this leaf
+	Proof    []hashchain.ProofStep `json:"proof"`
+}
+
+func toAuditAnchorResponse(anchor *database.AuditAnchor) *AuditAnchorResponse {
+	return &AuditAnchorResponse{
+		ID:            anchor.ID,
+		Root:          anchor.Root,
+		EntryCount:    anchor.EntryCount,
+		FirstEntryID:  anchor.FirstEntryID,
+		LastEntryID:   anchor.LastEntryID,
+		FromTimestamp: anchor.FromTimestamp.Format(time.RFC3339Nano),
+		ToTimestamp:   anchor.ToTimestamp.Format(time.RFC3339Nano),
+		CreatedAt:     anchor.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// createAuditAnchor anchors the audit entries logged since the last anchor
+// straight away
+func createAuditAnchor(c *gin.Context) {
+	anchor, err := anchorAuditEntries(time.Now())
+	if err != nil {
+		common.Error("Failed to anchor audit entries: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to anchor audit entries"))
+		return
+	}
+	if anchor == nil {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"anchored": 0}))
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toAuditAnchorResponse(anchor)))
+}
+
+// listAuditAnchors lists the latest anchors, up to ?limit= (default 50)
+func listAuditAnchors(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(common.DefaultPageLimit)))
+	if err != nil || limit < 1 || limit > common.MaxPageLimit {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "limit must be from 1 to "+strconv.Itoa(common.MaxPageLimit)))
+		return
+	}
+
+	anchors, err := repo.AuditAnchorRepository().List(limit)
+	if err != nil {
+		common.Error("Failed to list audit anchors: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list audit anchors"))
+		return
+	}
+
+	items := make([]interface{}, len(anchors))
+	for i, anchor := range anchors {
+		items[i] = toAuditAnchorResponse(anchor)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, limit, len(items))))
+}
+
+func getAuditAnchor(c *gin.Context) {
+	anchor, err := repo.AuditAnchorRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Audit anchor not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAuditAnchorResponse(anchor)))
+}
+
+// getAuditEntryProof returns the Merkle proof linking an audit entry to the
+// root of the anchor covering it
+func getAuditEntryProof(c *gin.Context) {
+	if !auditEntryInTenant(c, c.Param("id")) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Audit entry not found"))
+		return
+	}
+	proof, err := audit.NewAuditTrail(repo).ProveEntry(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, audit.ErrNotAnchored) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("NOT_ANCHORED", err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Audit entry not found"))
+		return
+	}
+	if !proof.Verified {
+		common.Warn("Audit entry %s no longer matches the root of anchor %s", proof.EntryID, proof.AnchorID)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(proof))
+}
+
+// verifyAuditProof checks a proof against the root stored for an anchor. The
+// leaf is the given hash, or the hash of the stored entry.
+func verifyAuditProof(c *gin.Context) {
+	var req VerifyAuditProofRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "anchorId is required"))
+		return
+	}
+	leaf := req.LeafHash
+	if leaf == "" {
+		if req.EntryID == "" {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "entryId or leafHash is required"))
+			return
+		}
+		entry, err := repo.AuditEntryRepository().GetByID(req.EntryID)
+		if err != nil || !tenancy.CheckAgent(c, repo, entry.AgentID, "audit_entry", entry.ID) {
+			c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Audit entry not found"))
+			return
+		}
+		leaf = audit.EntryHash(entry)
+	}
+
+	verified, anchor, err := audit.NewAuditTrail(repo).VerifyProof(c.Request.Context(), req.AnchorID, leaf, req.Proof)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Audit anchor not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{
+		"verified": verified,
+		"leafHash": leaf,
+		"root":     anchor.Root,
+	}))
+}
+
+// auditEntryInTenant reports whether an audit entry is about an agent of the
+// caller's tenant. Entries that do not exist are left to the handler.
+func auditEntryInTenant(c *gin.Context, entryID string) bool {
+	entry, err := repo.AuditEntryRepository().GetByID(entryID)
+	return err != nil || tenancy.CheckAgent(c, repo, entry.AgentID, "audit_entry", entry.ID)
+}
+
+func anchorAuditEntriesPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		if _, err := anchorAuditEntries(now); err != nil {
+			common.Error("Failed to anchor audit entries: %v", err)
+		}
+	}
+}
+
+// anchorAuditEntries anchors the settled entries no anchor covers yet, one
+// batch at a time, and returns the last anchor stored
+func anchorAuditEntries(now time.Time) (*database.AuditAnchor, error) {
+	trail := audit.NewAuditTrail(repo)
+	var last *database.AuditAnchor
+	for {
+		anchor, err := trail.AnchorEntries(context.Background(), now.Add(-auditAnchorSettle), auditAnchorBatch)
+		if err != nil || anchor == nil {
+			return last, err
+		}
+		common.Info("Anchored %d audit entries under root %s", anchor.EntryCount, anchor.Root)
+		last = anchor
+		if anchor.EntryCount < auditAnchorBatch {
+			return last, nil
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// auditExportChunk is how many entries an export loads and writes at a time
+const auditExportChunk = 1000
+
+var auditExportColumns = []string{
+	"id", "timestamp", "eventType", "severity", "userId", "agentId", "resourceId", "resourceType",
+	"action", "description", "ipAddress", "userAgent", "sessionId", "correlationId", "traceId",
+	"oldValues", "newValues", "metadata",
+}
+
+// exportAuditEvents streams the audit entries matching the query filters,
+// newest first, as CSV or JSON lines (?format=csv|jsonl). Entries are loaded
+// in chunks, so any period can be exported. The export is itself recorded in
+// the audit trail before any entry is sent.
+func exportAuditEvents(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "format must be csv or jsonl"))
+		return
+	}
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	filters, ok := parseAuditFilters(c, params)
+	if !ok {
+		return
+	}
+	// Entries logged while the export runs, including its own, are left out
+	// so the chunks do not shift
+	now := time.Now().UTC()
+	if filters.EndDate == nil || filters.EndDate.After(now) {
+		filters.EndDate = &now
+	}
+
+	trail := audit.NewAuditTrail(repo)
+	total, err := trail.CountAuditTrail(c.Request.Context(), filters)
+	if err != nil {
+		common.Error("Failed to count audit events for export: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to export audit events"))
+		return
+	}
+
+	entry := &audit.AuditEntry{
+		EventType:    audit.AuditDataExport,
+		Severity:     audit.SeverityMedium,
+		ResourceType: "audit_entry",
+		Action:       "export",
+		Description:  "Exported audit events as " + format,
+		Metadata: map[string]interface{}{
+			"format":  format,
+			"entries": total,
+			"filters": c.Request.URL.RawQuery,
+		},
+	}
+	if err := audit.Record(c, trail, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record the export"))
+		return
+	}
+
+	filters.Limit = auditExportChunk
+	entries, err := trail.QueryAuditTrail(c.Request.Context(), filters)
+	if err != nil {
+		common.Error("Failed to export audit events: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to export audit events"))
+		return
+	}
+
+	filename := "audit-events-" + now.Format("20060102T150405Z") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Status(http.StatusOK)
+
+	write := writeAuditJSONLines
+	if format == "csv" {
+		write = writeAuditCSV
+		if err := writeCSVRow(c.Writer, auditExportColumns); err != nil {
+			return
+		}
+	}
+
+	// Once streaming has started the status cannot change, so a failure ends
+	// the export early; the recorded entry count shows it is incomplete
+	written := 0
+	for len(entries) > 0 {
+		if err := write(c.Writer, entries); err != nil {
+			common.Warn("Audit export stopped after %d of %d entries: %v", written, total, err)
+			return
+		}
+		written += len(entries)
+		c.Writer.Flush()
+		if len(entries) < auditExportChunk {
+			break
+		}
+
+		filters.Offset += auditExportChunk
+		entries, err = trail.QueryAuditTrail(c.Request.Context(), filters)
+		if err != nil {
+			common.Error("Audit export failed after %d of %d entries: %v", written, total, err)
+			return
+		}
+	}
+}
+
+func writeAuditJSONLines(w io.Writer, entries []*audit.AuditEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAuditCSV(w io.Writer, entries []*audit.AuditEntry) error {
+	for _, entry := range entries {
+		row := []string{
+			entry.ID, entry.Timestamp.UTC().Format(time.RFC3339Nano), string(entry.EventType), string(entry.Severity),
+			entry.UserID, entry.AgentID, entry.ResourceID, entry.ResourceType,
+			entry.Action, entry.Description, entry.IPAddress, entry.UserAgent, entry.SessionID, entry.CorrelationID, entry.TraceID,
+			csvJSON(entry.OldValues), csvJSON(entry.NewValues), csvJSON(entry.Metadata),
+		}
+		if err := writeCSVRow(w, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSVRow writes a row, quoting values a spreadsheet would otherwise run
+// as a formula, since descriptions and user agents come from callers
+func writeCSVRow(w io.Writer, row []string) error {
+	for i, value := range row {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			row[i] = "'" + value
+		}
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(row)
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvJSON encodes a map of values as JSON for a CSV column
+func csvJSON(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
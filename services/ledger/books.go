@@ -0,0 +1,300 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/reporting"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type PostingRuleRequest struct {
+	SourceAccountID string `json:"sourceAccountId" binding:"required"`
+	TargetAccountID string `json:"targetAccountId" binding:"required"` // In another book of the same agent
+	Description     string `json:"description,omitempty" binding:"max=500"`
+}
+
+type PostingRuleResponse struct {
+	ID              string `json:"id"`
+	AgentID         string `json:"agentId"`
+	SourceAccountID string `json:"sourceAccountId"`
+	SourceBook      string `json:"sourceBook,omitempty"`
+	TargetAccountID string `json:"targetAccountId"`
+	TargetBook      string `json:"targetBook"`
+	Description     string `json:"description,omitempty"`
+	CreatedAt       string `json:"createdAt"`
+}
+
+func toPostingRuleResponse(rule *database.PostingRule, sourceBook string) *PostingRuleResponse {
+	return &PostingRuleResponse{
+		ID:              rule.ID,
+		AgentID:         rule.AgentID,
+		SourceAccountID: rule.SourceAccountID,
+		SourceBook:      sourceBook,
+		TargetAccountID: rule.TargetAccountID,
+		TargetBook:      rule.TargetBook,
+		Description:     rule.Description,
+		CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// createPostingRule maps an account onto an account of the same agent and
+// currency in another book, so transactions posted to it are fanned out
+// into that book. An account maps onto at most one account per book.
+func createPostingRule(c *gin.Context) {
+	var req PostingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+
+	source, err := repo.AccountRepository().GetByID(req.SourceAccountID)
+	if err != nil || !tenancy.CheckAgent(c, repo, source.AgentID, "account", source.ID) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Source account not found"))
+		return
+	}
+	target, err := repo.AccountRepository().GetByID(req.TargetAccountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Target account not found"))
+		return
+	}
+	switch {
+	case source.AgentID != target.AgentID:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Accounts must belong to the same agent"))
+		return
+	case source.Book == target.Book:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Target account must be in another book"))
+		return
+	case source.Currency != target.Currency:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Accounts must have the same currency"))
+		return
+	}
+
+	rules, err := repo.PostingRuleRepository().ListByAgentID(source.AgentID)
+	if err != nil {
+		common.Error("Failed to list posting rules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create posting rule"))
+		return
+	}
+	for _, rule := range rules {
+		if rule.SourceAccountID == source.ID && rule.TargetBook == target.Book {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("DUPLICATE_RULE", "Account already maps into the "+target.Book+" book"))
+			return
+		}
+	}
+
+	rule := &database.PostingRule{
+		AgentID:         source.AgentID,
+		SourceAccountID: source.ID,
+		TargetBook:      target.Book,
+		TargetAccountID: target.ID,
+		Description:     req.Description,
+	}
+	if err := repo.PostingRuleRepository().Create(rule); err != nil {
+		common.Error("Failed to create posting rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create posting rule"))
+		return
+	}
+
+	common.Info("Posting rule %s maps account %s (%s) onto %s (%s)", rule.ID, source.ID, source.Book, target.ID, target.Book)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toPostingRuleResponse(rule, source.Book)))
+}
+
+// listPostingRules lists an agent's posting rules by target book
+func listPostingRules(c *gin.Context) {
+	agentID := c.Query("agentId")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId parameter is required"))
+		return
+	}
+	if !agentInTenant(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list posting rules of this agent"))
+		return
+	}
+
+	rules, err := repo.PostingRuleRepository().ListByAgentID(agentID)
+	if err != nil {
+		common.Error("Failed to list posting rules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list posting rules"))
+		return
+	}
+	accounts, err := repo.AccountRepository().ListByAgentID(agentID)
+	if err != nil {
+		common.Error("Failed to list accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list posting rules"))
+		return
+	}
+	books := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		books[account.ID] = account.Book
+	}
+
+	items := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		items[i] = toPostingRuleResponse(rule, books[rule.SourceAccountID])
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// deletePostingRule stops fanning out postings to an account; transactions
+// already fanned out stay in the target book
+func deletePostingRule(c *gin.Context) {
+	rule, err := repo.PostingRuleRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.CheckAgent(c, repo, rule.AgentID, "posting_rule", rule.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Posting rule not found"))
+		return
+	}
+	if err := repo.PostingRuleRepository().Delete(rule.ID); err != nil {
+		common.Error("Failed to delete posting rule %s: %v", rule.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete posting rule"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": rule.ID, "deleted": true}))
+}
+
+type TrialBalanceLine struct {
+	AccountID   string  `json:"accountId"`
+	AccountName string  `json:"accountName"`
+	Type        string  `json:"type"`
+	Currency    string  `json:"currency"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+}
+
+type TrialBalanceTotal struct {
+	Currency string  `json:"currency"`
+	Debits   float64 `json:"debits"`
+	Credits  float64 `json:"credits"`
+	Balanced bool    `json:"balanced"`
+}
+
+type TrialBalanceResponse struct {
+	Book    string               `json:"book"`
+	AgentID string               `json:"agentId"`
+	Basis   reporting.Basis      `json:"basis"`
+	AsOf    string               `json:"asOf"`
+	Lines   []*TrialBalanceLine  `json:"lines"`
+	Totals  []*TrialBalanceTotal `json:"totals"` // By currency
+}
+
+// getTrialBalance reports the balance of each of an agent's accounts in a
+// book as a debit or credit, with the totals per currency, now or as of
+// ?asOf=. On the cash basis (?basis=cash) the transactions of payments still
+// in flight are left out.
+func getTrialBalance(c *gin.Context) {
+	book := c.Param("book")
+	agentID := c.Query("agentId")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId parameter is required"))
+		return
+	}
+	if !agentInTenant(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view the trial balance of this agent"))
+		return
+	}
+	basis, err := reporting.ParseBasis(c.Query("basis"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	asOf, ok := common.ParseAsOf(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := repo.AccountRepository().ListByAgentID(agentID)
+	if err != nil {
+		common.Error("Failed to list accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build trial balance"))
+		return
+	}
+
+	response := &TrialBalanceResponse{Book: book, AgentID: agentID, Basis: basis, Lines: []*TrialBalanceLine{}, Totals: []*TrialBalanceTotal{}}
+	reportedAt := time.Now().UTC()
+	if asOf != nil {
+		reportedAt = *asOf
+	}
+	response.AsOf = reportedAt.Format(time.RFC3339)
+
+	var inFlight map[string]float64
+	if basis == reporting.BasisCash {
+		if inFlight, err = inFlightAmounts(agentID, book, reportedAt); err != nil {
+			common.Error("Failed to find in-flight payments: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build trial balance"))
+			return
+		}
+	}
+
+	totals := make(map[string]*TrialBalanceTotal)
+	for _, account := range accounts {
+		if account.Book != book {
+			continue
+		}
+		if asOf != nil && account.CreatedAt.After(*asOf) {
+			continue
+		}
+		id := account.ID
+		account, err := lookupAccount(id, asOf)
+		if err != nil {
+			common.Error("Failed to get balance of account %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build trial balance"))
+			return
+		}
+
+		balance := math.Round((account.Balance-inFlight[account.ID])*100) / 100
+		line := &TrialBalanceLine{AccountID: account.ID, AccountName: account.Name, Type: account.Type, Currency: account.Currency}
+		if balance >= 0 {
+			line.Debit = balance
+		} else {
+			line.Credit = -balance
+		}
+		response.Lines = append(response.Lines, line)
+
+		total := totals[account.Currency]
+		if total == nil {
+			total = &TrialBalanceTotal{Currency: account.Currency}
+			totals[account.Currency] = total
+			response.Totals = append(response.Totals, total)
+		}
+		total.Debits += line.Debit
+		total.Credits += line.Credit
+	}
+
+	for _, total := range response.Totals {
+		total.Debits = math.Round(total.Debits*100) / 100
+		total.Credits = math.Round(total.Credits*100) / 100
+		total.Balanced = total.Debits == total.Credits
+	}
+	sort.Slice(response.Totals, func(i, j int) bool { return response.Totals[i].Currency < response.Totals[j].Currency })
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// inFlightAmounts sums per account the postings of an agent's transactions
+// in a book for payments not settled at a time. Only transactions posted
+// within the settlement lookback are considered.
+func inFlightAmounts(agentID, book string, at time.Time) (map[string]float64, error) {
+	recognizer := reporting.NewRecognizer(repo, reporting.BasisCash)
+	transactions, err := repo.TransactionRepository().ListPostedBetween(agentID, book, at.Add(-recognizer.Lookback), at)
+	if err != nil {
+		return nil, err
+	}
+	amounts := make(map[string]float64)
+	for _, transaction := range transactions {
+		recognition, err := recognizer.Recognize(transaction, at)
+		if err != nil {
+			return nil, err
+		}
+		if !recognition.InFlight {
+			continue
+		}
+		for _, posting := range transaction.Postings {
+			amounts[posting.AccountID] += posting.Amount
+		}
+	}
+	return amounts, nil
+}
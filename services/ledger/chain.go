@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/hashchain"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ChainVerification is the result of verifying one agent's transaction chain
+type ChainVerification struct {
+	AgentID         string                `json:"agentId"`
+	Length          int                   `json:"length"` // Transactions in the chain
+	Verified        bool                  `json:"verified"`
+	FirstBrokenLink *hashchain.ChainBreak `json:"firstBrokenLink,omitempty"`
+}
+
+// verifyTransactionChain verifies the hash chain of one agent's posted
+// transactions, with ?agentId=, or of every agent's, those of the caller's
+// tenant for callers confined to one
+func verifyTransactionChain(c *gin.Context) {
+	var agentIDs []string
+	if agentID := c.Query("agentId"); agentID != "" {
+		if !agentInTenant(c, agentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot verify transactions of this agent"))
+			return
+		}
+		agentIDs = []string{agentID}
+	} else {
+		var agents []*database.Agent
+		var err error
+		if tenant := common.RequestTenant(c); tenant != "" {
+			agents, err = repo.AgentRepository().ListByTenantID(tenant)
+		} else {
+			agents, err = repo.AgentRepository().List()
+		}
+		if err != nil {
+			common.Error("Failed to list agents: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to verify transaction chains"))
+			return
+		}
+		for _, agent := range agents {
+			agentIDs = append(agentIDs, agent.ID)
+		}
+	}
+
+	verified := true
+	chains := make([]*ChainVerification, 0, len(agentIDs))
+	for _, agentID := range agentIDs {
+		chain, err := verifyAgentChain(agentID)
+		if err != nil {
+			common.Error("Failed to verify transaction chain of agent %s: %v", agentID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to verify transaction chains"))
+			return
+		}
+		if !chain.Verified {
+			verified = false
+			common.Warn("Transaction chain of agent %s broken at block %d (%s): %s", agentID, chain.FirstBrokenLink.BlockIndex, chain.FirstBrokenLink.TransactionID, chain.FirstBrokenLink.Reason)
+		}
+		chains = append(chains, chain)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{
+		"verified":  verified,
+		"checkedAt": time.Now().Format(time.RFC3339),
+		"chains":    chains,
+	}))
+}
+
+// verifyAgentChain recomputes each link of an agent's chain from the stored
+// transactions and postings
+func verifyAgentChain(agentID string) (*ChainVerification, error) {
+	transactions, err := repo.TransactionRepository().ListChain(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]hashchain.TransactionLink, len(transactions))
+	for i, transaction := range transactions {
+		links[i] = hashchain.TransactionLink{Data: database.TransactionHashData(transaction), Hash: transaction.Hash}
+	}
+	broken := hashchain.VerifyTransactionChain(links)
+	return &ChainVerification{
+		AgentID:         agentID,
+		Length:          len(transactions),
+		Verified:        broken == nil,
+		FirstBrokenLink: broken,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fx"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type ConversionRequest struct {
+	AgentID       string  `json:"agentId" binding:"required"`
+	FromAccountID string  `json:"fromAccountId" binding:"required"`
+	ToAccountID   string  `json:"toAccountId" binding:"required"`
+	Amount        float64 `json:"amount" binding:"required"` // In the source account's currency
+	Description   string  `json:"description"`
+	ReferenceID   string  `json:"referenceId,omitempty"`
+}
+
+type ConversionResponse struct {
+	TransactionID string         `json:"transactionId"`
+	Conversion    *fx.Conversion `json:"conversion"`
+	CreatedAt     string         `json:"createdAt"`
+}
+
+// getFXRate quotes a conversion, e.g. GET /v1/fx/rates?from=EUR&to=USD&amount=100
+func getFXRate(c *gin.Context) {
+	amount := 1.0
+	if value := c.Query("amount"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amount must be a positive number"))
+			return
+		}
+		amount = parsed
+	}
+
+	conversion, err := converter.Convert(c.Request.Context(), amount, c.Query("from"), c.DefaultQuery("to", fx.BaseCurrency))
+	if err != nil {
+		respondFXError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(conversion))
+}
+
+// createConversion moves funds between two of an agent's accounts held in
+// different currencies, recording the FX legs against trading accounts
+func createConversion(c *gin.Context) {
+	var req ConversionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, fromAccountId, toAccountId and amount are required"))
+		return
+	}
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amount must be positive"))
+		return
+	}
+
+	if !common.CanActForAgent(c, req.AgentID) || !agentInTenant(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot convert funds for this agent"))
+		return
+	}
+
+	from, err := repo.AccountRepository().GetByID(req.FromAccountID)
+	if err != nil || from.AgentID != req.AgentID {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Source account not found for agent"))
+		return
+	}
+	to, err := repo.AccountRepository().GetByID(req.ToAccountID)
+	if err != nil || to.AgentID != req.AgentID {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Destination account not found for agent"))
+		return
+	}
+	if from.Currency == to.Currency {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Accounts share a currency; post a transaction instead"))
+		return
+	}
+
+	conversion, err := converter.Convert(c.Request.Context(), fx.RoundAmount(req.Amount), from.Currency, to.Currency)
+	if err != nil {
+		respondFXError(c, err)
+		return
+	}
+
+	fromTrading, err := findOrCreateTradingAccount(req.AgentID, from.Currency)
+	if err != nil {
+		common.Error("Failed to resolve FX trading account: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to resolve FX trading account"))
+		return
+	}
+	toTrading, err := findOrCreateTradingAccount(req.AgentID, to.Currency)
+	if err != nil {
+		common.Error("Failed to resolve FX trading account: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to resolve FX trading account"))
+		return
+	}
+
+	if req.Description == "" {
+		req.Description = fmt.Sprintf("FX %.2f %s -> %.2f %s @ %.6f", conversion.FromAmount, conversion.FromCurrency, conversion.ToAmount, conversion.ToCurrency, conversion.Rate)
+	}
+
+	transaction := &database.Transaction{
+		AgentID:     req.AgentID,
+		Description: req.Description,
+		ReferenceID: req.ReferenceID,
+		Status:      "posted",
+	}
+	postings := fx.ConversionPostings(conversion, from.ID, fromTrading.ID, to.ID, toTrading.ID)
+	if err := repo.TransactionRepository().Post(transaction, postings); err != nil {
+		respondPostingError(c, err)
+		return
+	}
+
+	common.Info("FX conversion posted: %s %.2f %s -> %.2f %s for agent %s", transaction.ID, conversion.FromAmount, conversion.FromCurrency, conversion.ToAmount, conversion.ToCurrency, req.AgentID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(&ConversionResponse{
+		TransactionID: transaction.ID,
+		Conversion:    conversion,
+		CreatedAt:     transaction.CreatedAt.Format(time.RFC3339),
+	}))
+}
+
+func findOrCreateTradingAccount(agentID, currency string) (*database.Account, error) {
+	name := fx.TradingAccountName(currency)
+	accounts, err := repo.AccountRepository().ListByAgentIDAndType(agentID, "equity")
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == name && account.Currency == currency {
+			return account, nil
+		}
+	}
+
+	account := &database.Account{
+		AgentID:     agentID,
+		Name:        name,
+		Type:        "equity",
+		Description: "Currency position from FX conversions",
+		Currency:    currency,
+	}
+	if err := repo.AccountRepository().Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func respondFXError(c *gin.Context, err error) {
+	if errors.Is(err, fx.ErrInvalidCurrency) || errors.Is(err, fx.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_CURRENCY", err.Error()))
+		return
+	}
+	common.Error("FX rate lookup failed: %v", err)
+	c.JSON(http.StatusBadGateway, common.NewErrorResponse("FX_RATE_ERROR", "Failed to quote exchange rate"))
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rounding"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Hold statuses
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// holdTTL is how long a hold lasts when its request does not say
+var holdTTL time.Duration
+
+type CreateHoldRequest struct {
+	AgentID     string  `json:"agentId" binding:"required"`
+	AccountID   string  `json:"accountId" binding:"required"`
+	Amount      float64 `json:"amount" binding:"required"`
+	Currency    string  `json:"currency,omitempty"`    // Defaults to the account's currency
+	ReferenceID string  `json:"referenceId,omitempty"` // What the funds are reserved for
+	Description string  `json:"description,omitempty"`
+	TTLSeconds  int     `json:"ttlSeconds,omitempty"`
+}
+
+type CaptureHoldRequest struct {
+	DestinationAccountID string  `json:"destinationAccountId" binding:"required"`
+	Amount               float64 `json:"amount,omitempty"` // Defaults to the full hold
+	Description          string  `json:"description,omitempty"`
+	TokenAmount          float64 `json:"tokenAmount,omitempty"` // Tokens paid, for payments settled on chain
+	Token                string  `json:"token,omitempty"`
+}
+
+type HoldResponse struct {
+	ID             string  `json:"id"`
+	AgentID        string  `json:"agentId"`
+	AccountID      string  `json:"accountId"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	ReferenceID    string  `json:"referenceId,omitempty"`
+	Description    string  `json:"description,omitempty"`
+	Status         string  `json:"status"`
+	CapturedAmount float64 `json:"capturedAmount,omitempty"`
+	TransactionID  string  `json:"transactionId,omitempty"`
+	ExpiresAt      string  `json:"expiresAt"`
+	ResolvedAt     string  `json:"resolvedAt,omitempty"`
+	CreatedAt      string  `json:"createdAt"`
+}
+
+func toHoldResponse(hold *database.Hold) *HoldResponse {
+	response := &HoldResponse{
+		ID:             hold.ID,
+		AgentID:        hold.AgentID,
+		AccountID:      hold.AccountID,
+		Amount:         hold.Amount,
+		Currency:       hold.Currency,
+		ReferenceID:    hold.ReferenceID,
+		Description:    hold.Description,
+		Status:         hold.Status,
+		CapturedAmount: hold.CapturedAmount,
+		TransactionID:  hold.TransactionID,
+		ExpiresAt:      hold.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:      hold.CreatedAt.Format(time.RFC3339),
+	}
+	if hold.ResolvedAt != nil {
+		response.ResolvedAt = hold.ResolvedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// createHold reserves funds on an account. The account's available balance
+// must cover the hold. An active hold with the same account and reference is
+// returned instead of placing another.
+func createHold(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
+	var req CreateHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, accountId and a positive amount are required"))
+		return
+	}
+	if !common.CanActForAgent(c, req.AgentID) || !agentInTenant(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot place holds for this agent"))
+		return
+	}
+
+	account, err := repo.AccountRepository().GetByID(req.AccountID)
+	if err != nil || account.AgentID != req.AgentID {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Account not found"))
+		return
+	}
+	if req.Currency != "" && req.Currency != account.Currency {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Hold currency must match account currency %s", account.Currency)))
+		return
+	}
+
+	if req.ReferenceID != "" {
+		if existing, err := repo.HoldRepository().ListByReferenceID(req.ReferenceID); err == nil {
+			for _, hold := range existing {
+				if hold.AccountID == account.ID && hold.Status == HoldActive {
+					c.JSON(http.StatusOK, common.NewSuccessResponse(toHoldResponse(hold)))
+					return
+				}
+			}
+		}
+	}
+
+	available, err := balanceCalculator.AvailableBalance(account)
+	if err != nil {
+		common.Error("Failed to get available balance of account %s: %v", account.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get available balance"))
+		return
+	}
+	// The hold may not take the account past its overdraft policy
+	amount := rounding.Round(req.Amount)
+	if floor, limited := account.BalanceFloor(); limited && available-amount < floor {
+		c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("INSUFFICIENT_FUNDS", fmt.Sprintf("Available balance %.2f %s does not cover %.2f", available, account.Currency, amount)))
+		return
+	}
+
+	ttl := holdTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	hold := &database.Hold{
+		AgentID:     req.AgentID,
+		AccountID:   account.ID,
+		Amount:      amount,
+		Currency:    account.Currency,
+		ReferenceID: req.ReferenceID,
+		Description: req.Description,
+		Status:      HoldActive,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := repo.HoldRepository().Create(hold); err != nil {
+		common.Error("Failed to create hold: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create hold"))
+		return
+	}
+
+	common.Info("Hold %s of %.2f %s placed on account %s", hold.ID, hold.Amount, hold.Currency, hold.AccountID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toHoldResponse(hold)))
+}
+
+// listHolds lists an account's holds (?accountId=, optionally ?status=) or a
+// reference's holds (?referenceId=)
+func listHolds(c *gin.Context) {
+	var holds []*database.Hold
+	var err error
+	switch {
+	case c.Query("accountId") != "":
+		holds, err = repo.HoldRepository().ListByAccountID(c.Query("accountId"), c.Query("status"))
+	case c.Query("referenceId") != "":
+		holds, err = repo.HoldRepository().ListByReferenceID(c.Query("referenceId"))
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "accountId or referenceId is required"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list holds: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list holds"))
+		return
+	}
+
+	items := []interface{}{}
+	for _, hold := range holds {
+		if common.CanActForAgent(c, hold.AgentID) && tenancy.CheckAgent(c, repo, hold.AgentID, "hold", hold.ID) {
+			items = append(items, toHoldResponse(hold))
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getHold(c *gin.Context) {
+	hold := loadHold(c)
+	if hold == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toHoldResponse(hold)))
+}
+
+func loadHold(c *gin.Context) *database.Hold {
+	hold, err := repo.HoldRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, hold.AgentID) || !tenancy.CheckAgent(c, repo, hold.AgentID, "hold", hold.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Hold not found"))
+		return nil
+	}
+	return hold
+}
+
+// captureHold turns a hold into a posted transaction moving the captured
+// amount from the held account to the destination. Any uncaptured remainder
+// is released with the hold.
+func captureHold(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
+	var req CaptureHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "destinationAccountId is required"))
+		return
+	}
+	hold := loadHold(c)
+	if hold == nil {
+		return
+	}
+
+	amount := hold.Amount
+	if req.Amount > 0 {
+		amount = rounding.Round(req.Amount)
+	}
+	if amount > hold.Amount {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Cannot capture more than the hold"))
+		return
+	}
+
+	source, err := repo.AccountRepository().GetByID(hold.AccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Held account not found"))
+		return
+	}
+	destination, err := repo.AccountRepository().GetByID(req.DestinationAccountID)
+	if err != nil || destination.AgentID != hold.AgentID || destination.Currency != hold.Currency {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Destination must be an account of the same agent and currency"))
+		return
+	}
+
+	// Claim the hold first so a concurrent release or expiry cannot also win
+	hold.CapturedAmount = amount
+	captured, err := repo.HoldRepository().Resolve(hold, HoldCaptured)
+	if err != nil {
+		common.Error("Failed to capture hold %s: %v", hold.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to capture hold"))
+		return
+	}
+	if !captured {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only active holds can be captured"))
+		return
+	}
+
+	description := req.Description
+	if description == "" {
+		description = "Capture of hold " + hold.ID
+	}
+	referenceID := hold.ReferenceID
+	if referenceID == "" {
+		referenceID = hold.ID
+	}
+	transactionID, err := postHoldCapture(hold, source, destination, amount, referenceID, description, req.Token, req.TokenAmount)
+	if err != nil {
+		// Reactivate the hold so the capture can be retried
+		common.Error("Failed to post capture of hold %s: %v", hold.ID, err)
+		hold.Status = HoldActive
+		hold.CapturedAmount = 0
+		hold.ResolvedAt = nil
+		if err := repo.HoldRepository().Update(hold); err != nil {
+			common.Error("Failed to reactivate hold %s: %v", hold.ID, err)
+		}
+		respondPostingError(c, err)
+		return
+	}
+
+	hold.TransactionID = transactionID
+	if err := repo.HoldRepository().Update(hold); err != nil {
+		common.Error("Failed to record transaction of hold %s: %v", hold.ID, err)
+	}
+
+	common.Info("Hold %s captured: %.2f %s in transaction %s", hold.ID, amount, hold.Currency, transactionID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toHoldResponse(hold)))
+}
+
+// postHoldCapture books the captured amount from the held account to the
+// destination. A payment settled on chain also records the tokens it paid
+// on both postings.
+func postHoldCapture(hold *database.Hold, source, destination *database.Account, amount float64, referenceID, description, token string, tokenAmount float64) (string, error) {
+	transaction := &database.Transaction{
+		AgentID:     hold.AgentID,
+		Description: description,
+		ReferenceID: referenceID,
+		Status:      "posted",
+	}
+	postings := []*database.Posting{
+		{AccountID: destination.ID, Amount: amount, Currency: destination.Currency},
+		{AccountID: source.ID, Amount: -amount, Currency: source.Currency},
+	}
+	if token != "" && tokenAmount > 0 {
+		credit, debit := tokenAmount, -tokenAmount
+		postings[0].Token, postings[0].TokenAmount = token, &credit
+		postings[1].Token, postings[1].TokenAmount = token, &debit
+	}
+	if err := repo.TransactionRepository().Post(transaction, postings); err != nil {
+		return "", err
+	}
+	return transaction.ID, nil
+}
+
+// releaseHold frees a hold's funds without moving them
+func releaseHold(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
+	hold := loadHold(c)
+	if hold == nil {
+		return
+	}
+
+	released, err := repo.HoldRepository().Resolve(hold, HoldReleased)
+	if err != nil {
+		common.Error("Failed to release hold %s: %v", hold.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to release hold"))
+		return
+	}
+	if !released {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only active holds can be released"))
+		return
+	}
+
+	common.Info("Hold %s released", hold.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toHoldResponse(hold)))
+}
+
+// expireHolds expires the active holds past their expiry now, rather than at
+// the next periodic sweep
+func expireHolds(c *gin.Context) {
+	expired, err := expireDueHolds(time.Now())
+	if err != nil {
+		common.Error("Failed to expire holds: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to expire holds"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]int{"expired": expired}))
+}
+
+func expireHoldsPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		if _, err := expireDueHolds(now); err != nil {
+			common.Error("Failed to expire holds: %v", err)
+		}
+	}
+}
+
+// expireDueHolds expires active holds past their expiry, freeing their funds
+func expireDueHolds(now time.Time) (int, error) {
+	holds, err := repo.HoldRepository().ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, hold := range holds {
+		ok, err := repo.HoldRepository().Resolve(hold, HoldExpired)
+		if err != nil {
+			common.Error("Failed to expire hold %s: %v", hold.ID, err)
+			continue
+		}
+		if ok {
+			expired++
+			common.Info("Hold %s of %.2f %s on account %s expired", hold.ID, hold.Amount, hold.Currency, hold.AccountID)
+		}
+	}
+	return expired, nil
+}
@@ -1,18 +1,36 @@
 package main
 
 import (
+	"context"
+	_ "embed"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"time"
 
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/balances"
+	"github.com/example/agent-payments/internal/config"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fx"
+	"github.com/example/agent-payments/internal/migrations"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
 )
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var repo database.Repository
+var authConfig *common.AuthConfig
+var converter *fx.Converter
+var balanceCalculator *balances.BalanceCalculator
 
 type AccountRequest struct {
 	AgentID     string `json:"agentId" binding:"required"`
@@ -20,11 +38,15 @@ type AccountRequest struct {
 	Type        string `json:"type" binding:"required"` // "asset", "liability", "equity", "revenue", "expense"
 	Description string `json:"description"`
 	Currency    string `json:"currency,omitempty"` // Default to USD
+	// "disallow", "limit" or "unlimited"; defaults to disallow for assets and unlimited otherwise
+	OverdraftPolicy string  `json:"overdraftPolicy,omitempty"`
+	OverdraftLimit  float64 `json:"overdraftLimit,omitempty"` // Required by the limit policy
+	Book            string  `json:"book,omitempty"`           // Defaults to management
 }
 
 type TransactionRequest struct {
 	AgentID     string           `json:"agentId" binding:"required"`
-	Description string           `json:"description" binding:"required"`
+	Description string           `json:"description"` // Defaults to the payment's description when referenceId names a payment
 	ReferenceID string           `json:"referenceId,omitempty"`
 	Postings    []PostingRequest `json:"postings" binding:"required"`
 }
@@ -36,16 +58,49 @@ type PostingRequest struct {
 }
 
 type BalanceResponse struct {
-	AccountID   string  `json:"accountId"`
-	AccountName string  `json:"accountName"`
-	Balance     float64 `json:"balance"`
-	Currency    string  `json:"currency"`
+	AccountID        string   `json:"accountId"`
+	AccountName      string   `json:"accountName"`
+	Balance          float64  `json:"balance"`
+	AvailableBalance *float64 `json:"availableBalance,omitempty"` // Balance less active holds; current balances only
+	Currency         string   `json:"currency"`
+}
+
+// toBalanceResponse reports an account's current balance and what of it is
+// not held
+func toBalanceResponse(account *database.Account) (BalanceResponse, error) {
+	balance, err := balanceCalculator.CurrentBalance(account)
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+	available, err := balanceCalculator.AvailableBalance(account)
+	if err != nil {
+		return BalanceResponse{}, err
+	}
+	return BalanceResponse{
+		AccountID:        account.ID,
+		AccountName:      account.Name,
+		Balance:          balance,
+		AvailableBalance: &available,
+		Currency:         account.Currency,
+	}, nil
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("ledger")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("ledger")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -58,13 +113,45 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	balanceCalculator = balances.NewBalanceCalculator(repo)
+
 	r := gin.Default()
+	server := common.NewServer(cfg.Addr(), r)
+
+	// Active holds past this age expire
+	holdTTL = time.Duration(common.GetEnvAsInt("HOLD_TTL_MINUTES", 60*24)) * time.Minute
+	go expireHoldsPeriodically(server.Context(), time.Duration(common.GetEnvAsInt("HOLD_EXPIRY_INTERVAL_SECONDS", 60))*time.Second)
+
+	// Balances are derived from postings; snapshots bound how many are summed,
+	// and reconciliation reports stored balances that no longer match
+	go snapshotBalancesPeriodically(server.Context(), time.Duration(common.GetEnvAsInt("BALANCE_SNAPSHOT_INTERVAL_MINUTES", 60))*time.Minute)
+	go reconcileBalancesPeriodically(server.Context(), time.Duration(common.GetEnvAsInt("BALANCE_RECONCILE_INTERVAL_MINUTES", 15))*time.Minute)
+
+	// Audit entries are anchored under Merkle roots for tamper evidence
+	go anchorAuditEntriesPeriodically(server.Context(), time.Duration(common.GetEnvAsInt("AUDIT_ANCHOR_INTERVAL_MINUTES", 60))*time.Minute)
+
+	// Online schema migrations are backfilled on request by operators
+	migrationDB = db
+	backfiller = migrations.NewBackfiller(db, repo)
+
+	// Initialize FX conversion for multi-currency accounts
+	converter, err = fx.NewConverterFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize FX rates: %v", err)
+	}
 
 	// Setup common middleware
 	common.SetupCommonMiddleware(r, func() error {
 		return repo.HealthCheck()
 	})
 
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
 		if err := repo.HealthCheck(); err != nil {
@@ -74,27 +161,82 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ledger service ok"})
 	})
 
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Account management
-		v1.POST("/accounts", createAccount)
-		v1.GET("/accounts/:id", getAccount)
-		v1.GET("/accounts", listAccounts)
-		v1.GET("/accounts/:id/balance", getAccountBalance)
+		v1.POST("/accounts", common.RequireScopes(common.ScopeLedgerWrite), createAccount)
+		v1.GET("/accounts/:id", common.RequireScopes(common.ScopeLedgerRead), getAccount)
+		v1.GET("/accounts", common.RequireScopes(common.ScopeLedgerRead), listAccounts)
+		v1.GET("/accounts/:id/balance", common.RequireScopes(common.ScopeLedgerRead), getAccountBalance)
+		v1.GET("/accounts/:id/postings", common.RequireScopes(common.ScopeLedgerRead), listAccountPostings)
+		v1.PUT("/accounts/:id/overdraft-policy", common.RequireScopes(common.ScopeLedgerWrite), updateOverdraftPolicy)
 
 		// Transaction management
-		v1.POST("/transactions", createTransaction)
-		v1.GET("/transactions/:id", getTransaction)
-		v1.GET("/transactions", listTransactions)
+		v1.POST("/transactions", common.RequireScopes(common.ScopeLedgerWrite), createTransaction)
+		v1.GET("/transactions/verify-chain", common.RequireScopes(common.ScopeLedgerRead), verifyTransactionChain)
+		v1.GET("/transactions/:id", common.RequireScopes(common.ScopeLedgerRead), getTransaction)
+		v1.GET("/transactions", common.RequireScopes(common.ScopeLedgerRead), listTransactions)
+
+		// Holds reserve funds until they are captured, released or expire
+		v1.POST("/holds", common.RequireScopes(common.ScopeLedgerWrite), createHold)
+		v1.GET("/holds", common.RequireScopes(common.ScopeLedgerRead), listHolds)
+		v1.GET("/holds/:id", common.RequireScopes(common.ScopeLedgerRead), getHold)
+		v1.POST("/holds/:id/capture", common.RequireScopes(common.ScopeLedgerWrite), captureHold)
+		v1.POST("/holds/:id/release", common.RequireScopes(common.ScopeLedgerWrite), releaseHold)
+		v1.POST("/holds/expire", common.RequireScopes(common.ScopeLedgerWrite), expireHolds)
+
+		// Parallel books and the rules fanning postings out between them
+		v1.POST("/posting-rules", common.RequireScopes(common.ScopeLedgerWrite), createPostingRule)
+		v1.GET("/posting-rules", common.RequireScopes(common.ScopeLedgerRead), listPostingRules)
+		v1.DELETE("/posting-rules/:id", common.RequireScopes(common.ScopeLedgerWrite), deletePostingRule)
+		v1.GET("/books/:book/trial-balance", common.RequireScopes(common.ScopeLedgerRead), getTrialBalance)
+
+		// Activity reports on the accrual or cash basis
+		v1.GET("/reports/activity", common.RequireScopes(common.ScopeLedgerRead), getActivityReport)
 
 		// Balance queries
-		v1.GET("/balances", getBalances)
-		v1.GET("/balances/agent/:agentId", getAgentBalances)
+		v1.GET("/balances", common.RequireScopes(common.ScopeLedgerRead), getBalances)
+		v1.GET("/balances/agent/:agentId", common.RequireScopes(common.ScopeLedgerRead), getAgentBalances)
+		v1.GET("/balances/drift", common.RequireScopes(common.ScopeLedgerRead), getBalanceDrift)
+		v1.POST("/balances/snapshots", common.RequireScopes(common.ScopeLedgerWrite), snapshotBalances)
+
+		// Audit trail
+		v1.POST("/audit/events", common.RequireScopes(common.ScopeOperations), logAuditEvent)
+		v1.GET("/audit/events", common.RequireScopes(common.ScopeOperations), listAuditEvents)
+		v1.GET("/audit/events/export", common.RequireScopes(common.ScopeComplianceRead), exportAuditEvents)
+		v1.GET("/audit/summary", common.RequireScopes(common.ScopeOperations), getAuditSummary)
+		v1.GET("/audit/compliance", common.RequireScopes(common.ScopeComplianceRead), getAuditComplianceReport)
+		v1.GET("/audit/changes/:resourceId", common.RequireScopes(common.ScopeOperations), getAuditChangeHistory)
+
+		// Merkle anchors over the audit trail
+		v1.POST("/audit/anchors", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), createAuditAnchor)
+		v1.GET("/audit/anchors", common.RequireScopes(common.ScopeOperations), listAuditAnchors)
+		v1.GET("/audit/anchors/:id", common.RequireScopes(common.ScopeOperations), getAuditAnchor)
+		v1.GET("/audit/entries/:id/proof", common.RequireScopes(common.ScopeOperations), getAuditEntryProof)
+		v1.POST("/audit/proofs/verify", common.RequireScopes(common.ScopeOperations), verifyAuditProof)
+
+		// Online schema migrations
+		v1.GET("/admin/migrations", common.RequireScopes(common.ScopeOperations), listMigrations)
+		v1.POST("/admin/migrations/:name/backfill", common.RequireScopes(common.ScopeOperations), startMigrationBackfill)
+		v1.POST("/admin/migrations/:name/pause", common.RequireScopes(common.ScopeOperations), pauseMigrationBackfill)
+		v1.GET("/admin/migrations/:name/verify", common.RequireScopes(common.ScopeOperations), verifyMigration)
+
+		// Currency conversion
+		v1.GET("/fx/rates", common.RequireScopes(common.ScopeLedgerRead), getFXRate)
+		v1.POST("/fx/conversions", common.RequireScopes(common.ScopeLedgerWrite), createConversion)
+	}
+
+	// Backfills are paused at their next checkpoint before exiting
+	server.OnShutdown("migration backfills", stopBackfills)
+
+	common.Info("Ledger service running on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
 	}
-
-	common.Info("Ledger service running on :8086")
-	log.Fatal(r.Run(":8086"))
 }
 
 func createAccount(c *gin.Context) {
@@ -120,25 +262,46 @@ func createAccount(c *gin.Context) {
 		return
 	}
 
+	if msg := validateOverdraftPolicy(req.OverdraftPolicy, req.OverdraftLimit); msg != "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", msg))
+		return
+	}
+	if req.OverdraftPolicy != database.OverdraftLimited {
+		req.OverdraftLimit = 0
+	}
+	if req.Book == "" {
+		req.Book = database.BookManagement
+	}
+	if !database.ValidBook(req.Book) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "book must be lowercase letters, digits, dashes and underscores"))
+		return
+	}
+
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil || !agentInTenant(c, req.AgentID) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
 
-	// Set default currency
-	if req.Currency == "" {
-		req.Currency = "USD"
+	// Default to USD and normalize the currency code
+	currency, err := fx.NormalizeCurrency(req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
 	}
+	req.Currency = currency
 
 	// Create account
 	account := &database.Account{
-		AgentID:     req.AgentID,
-		Name:        req.Name,
-		Type:        req.Type,
-		Description: req.Description,
-		Currency:    req.Currency,
-		Balance:     0.0,
+		AgentID:         req.AgentID,
+		Name:            req.Name,
+		Type:            req.Type,
+		Description:     req.Description,
+		Currency:        req.Currency,
+		Balance:         0.0,
+		OverdraftPolicy: req.OverdraftPolicy,
+		OverdraftLimit:  req.OverdraftLimit,
+		Book:            req.Book,
 	}
 
 	if err := repo.AccountRepository().Create(account); err != nil {
@@ -149,63 +312,86 @@ func createAccount(c *gin.Context) {
 
 	// Convert to API response format
 	response := &types.Account{
-		ID:          account.ID,
-		AgentID:     account.AgentID,
-		Name:        account.Name,
-		Type:        account.Type,
-		Description: account.Description,
-		Currency:    account.Currency,
-		Balance:     account.Balance,
-		CreatedAt:   account.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   account.UpdatedAt.Format(time.RFC3339),
+		ID:              account.ID,
+		AgentID:         account.AgentID,
+		Name:            account.Name,
+		Type:            account.Type,
+		Description:     account.Description,
+		Currency:        account.Currency,
+		Balance:         account.Balance,
+		OverdraftPolicy: account.EffectiveOverdraftPolicy(),
+		OverdraftLimit:  account.OverdraftLimit,
+		Book:            account.Book,
+		CreatedAt:       account.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       account.UpdatedAt.Format(time.RFC3339),
 	}
 
 	common.Info("Account created: %s (%s) for agent %s", account.Name, account.ID, req.AgentID)
 	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
 }
 
+// getAccount returns an account, or with ?asOf=<RFC3339> the account and its
+// balance as they were at that time
 func getAccount(c *gin.Context) {
-	id := c.Param("id")
-	account, err := repo.AccountRepository().GetByID(id)
+	asOf, ok := common.ParseAsOf(c)
+	if !ok {
+		return
+	}
+
+	account, err := lookupAccount(c.Param("id"), asOf)
 	if err != nil {
 		log.Printf("Failed to get account: %v", err)
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
 		return
 	}
+	if !tenancy.CheckAgent(c, repo, account.AgentID, "account", account.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
+		return
+	}
 
 	// Convert to API response format
 	response := &types.Account{
-		ID:          account.ID,
-		AgentID:     account.AgentID,
-		Name:        account.Name,
-		Type:        account.Type,
-		Description: account.Description,
-		Currency:    account.Currency,
-		Balance:     account.Balance,
-		CreatedAt:   account.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   account.UpdatedAt.Format(time.RFC3339),
+		ID:              account.ID,
+		AgentID:         account.AgentID,
+		Name:            account.Name,
+		Type:            account.Type,
+		Description:     account.Description,
+		Currency:        account.Currency,
+		Balance:         account.Balance,
+		OverdraftPolicy: account.EffectiveOverdraftPolicy(),
+		OverdraftLimit:  account.OverdraftLimit,
+		Book:            account.Book,
+		CreatedAt:       account.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       account.UpdatedAt.Format(time.RFC3339),
 	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// listAccounts lists a page of accounts, filtered by agent, type, currency
+// and creation time. Callers confined to a tenant list its accounts only.
 func listAccounts(c *gin.Context) {
-	agentID := c.Query("agentId")
-	accountType := c.Query("type")
-
-	var accounts []*database.Account
-	var err error
-
-	if agentID != "" && accountType != "" {
-		accounts, err = repo.AccountRepository().ListByAgentIDAndType(agentID, accountType)
-	} else if agentID != "" {
-		accounts, err = repo.AccountRepository().ListByAgentID(agentID)
-	} else if accountType != "" {
-		accounts, err = repo.AccountRepository().ListByType(accountType)
-	} else {
-		accounts, err = repo.AccountRepository().List()
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	filter := database.AccountFilter{
+		AgentID:  c.Query("agentId"),
+		Type:     c.Query("type"),
+		Currency: c.Query("currency"),
+		Book:     c.Query("book"),
+		TenantID: common.RequestTenant(c),
+	}
+	if filter.AgentID != "" && !agentInTenant(c, filter.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list accounts of this agent"))
+		return
 	}
 
+	accounts, total, err := repo.AccountRepository().ListPage(filter, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to list accounts: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list accounts"))
@@ -215,20 +401,28 @@ func listAccounts(c *gin.Context) {
 	// Convert to API response format
 	var result []*types.Account
 	for _, acc := range accounts {
+		if acc.Balance, err = balanceCalculator.CurrentBalance(acc); err != nil {
+			common.Error("Failed to get balance: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list accounts"))
+			return
+		}
 		result = append(result, &types.Account{
-			ID:          acc.ID,
-			AgentID:     acc.AgentID,
-			Name:        acc.Name,
-			Type:        acc.Type,
-			Description: acc.Description,
-			Currency:    acc.Currency,
-			Balance:     acc.Balance,
-			CreatedAt:   acc.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   acc.UpdatedAt.Format(time.RFC3339),
+			ID:              acc.ID,
+			AgentID:         acc.AgentID,
+			Name:            acc.Name,
+			Type:            acc.Type,
+			Description:     acc.Description,
+			Currency:        acc.Currency,
+			Balance:         acc.Balance,
+			OverdraftPolicy: acc.EffectiveOverdraftPolicy(),
+			OverdraftLimit:  acc.OverdraftLimit,
+			Book:            acc.Book,
+			CreatedAt:       acc.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:       acc.UpdatedAt.Format(time.RFC3339),
 		})
 	}
 
-	response := common.NewListResponse(make([]interface{}, len(result)), 1, 10, len(result))
+	response := common.NewPageResponse(make([]interface{}, len(result)), params, total)
 	for i, acc := range result {
 		response.Items[i] = acc
 	}
@@ -237,13 +431,21 @@ func listAccounts(c *gin.Context) {
 }
 
 func getAccountBalance(c *gin.Context) {
-	id := c.Param("id")
-	account, err := repo.AccountRepository().GetByID(id)
+	asOf, ok := common.ParseAsOf(c)
+	if !ok {
+		return
+	}
+
+	account, err := lookupAccount(c.Param("id"), asOf)
 	if err != nil {
 		log.Printf("Failed to get account: %v", err)
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
 		return
 	}
+	if !tenancy.CheckAgent(c, repo, account.AgentID, "account", account.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
+		return
+	}
 
 	response := BalanceResponse{
 		AccountID:   account.ID,
@@ -251,17 +453,57 @@ func getAccountBalance(c *gin.Context) {
 		Balance:     account.Balance,
 		Currency:    account.Currency,
 	}
+	// Holds are not versioned, so past balances have no available balance
+	if asOf == nil {
+		if response, err = toBalanceResponse(account); err != nil {
+			common.Error("Failed to get available balance: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get available balance"))
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// agentInTenant reports whether an agent belongs to the caller's tenant.
+// Attempts to reach another tenant's ledger are audited.
+func agentInTenant(c *gin.Context, agentID string) bool {
+	return tenancy.CheckAgent(c, repo, agentID, "agent", agentID)
+}
+
+// lookupAccount loads an account with its balance derived from its postings,
+// as of a past time when asOf is set
+func lookupAccount(id string, asOf *time.Time) (*database.Account, error) {
+	if asOf != nil {
+		return repo.AccountRepository().GetAsOf(id, *asOf)
+	}
+	account, err := repo.AccountRepository().GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if account.Balance, err = balanceCalculator.CurrentBalance(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
 func createTransaction(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
 	var req TransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
 		return
 	}
 
+	// Transactions recording a payment carry the payment's description, so
+	// ledger entries match what the rail carried
+	if req.Description == "" && req.ReferenceID != "" {
+		if workflow, err := repo.PaymentWorkflowRepository().GetByID(req.ReferenceID); err == nil && workflow.AgentID == req.AgentID {
+			req.Description = workflow.Description
+		}
+	}
+
 	// Validate required fields
 	if req.AgentID == "" || req.Description == "" || len(req.Postings) == 0 {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, description, and postings are required"))
@@ -269,42 +511,15 @@ func createTransaction(c *gin.Context) {
 	}
 
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil || !agentInTenant(c, req.AgentID) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
 
-	// Validate double-entry bookkeeping (debits must equal credits)
-	var totalDebit, totalCredit float64
-	for _, posting := range req.Postings {
-		if posting.Amount > 0 {
-			totalDebit += posting.Amount
-		} else {
-			totalCredit += -posting.Amount
-		}
-	}
-
-	if fmt.Sprintf("%.2f", totalDebit) != fmt.Sprintf("%.2f", totalCredit) {
-		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Debits must equal credits"))
-		return
-	}
-
-	// Create transaction
-	transaction := &database.Transaction{
-		AgentID:     req.AgentID,
-		Description: req.Description,
-		ReferenceID: req.ReferenceID,
-		Status:      "posted",
-	}
-
-	if err := repo.TransactionRepository().Create(transaction); err != nil {
-		common.Error("Failed to create transaction: %v", err)
-		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create transaction"))
-		return
-	}
-
-	// Create postings
-	for _, postingReq := range req.Postings {
+	// Resolve posting accounts and currencies before writing anything
+	accounts := make([]*database.Account, len(req.Postings))
+	currencies := make([]string, len(req.Postings))
+	for i, postingReq := range req.Postings {
 		// Verify account exists and belongs to agent
 		account, err := repo.AccountRepository().GetByID(postingReq.AccountID)
 		if err != nil {
@@ -318,32 +533,57 @@ func createTransaction(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Account does not belong to agent"))
 			return
 		}
-
-		posting := &database.Posting{
-			TransactionID: transaction.ID,
-			AccountID:     postingReq.AccountID,
-			Amount:        postingReq.Amount,
-			Currency:      postingReq.Currency,
+		if i > 0 && account.Book != accounts[0].Book {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Postings must all be to accounts in one book"))
+			return
 		}
 
-		if posting.Currency == "" {
-			posting.Currency = account.Currency
+		// Accounts hold a single currency; conversions go through FX trading accounts
+		currency := account.Currency
+		if postingReq.Currency != "" {
+			normalized, err := fx.NormalizeCurrency(postingReq.Currency)
+			if err != nil || normalized != account.Currency {
+				c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Posting currency must match account currency %s", account.Currency)))
+				return
+			}
 		}
 
-		if err := repo.PostingRepository().Create(posting); err != nil {
-			common.Error("Failed to create posting: %v", err)
-			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create posting"))
+		accounts[i] = account
+		currencies[i] = currency
+	}
+
+	// Validate double-entry bookkeeping (debits must equal credits in each currency)
+	totals := make(map[string]int64)
+	for i, posting := range req.Postings {
+		totals[currencies[i]] += int64(math.Round(posting.Amount * 100))
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Debits must equal credits in %s", currency)))
 			return
 		}
+	}
 
-		// Update account balance
-		account.Balance += posting.Amount
-		if err := repo.AccountRepository().Update(account); err != nil {
-			common.Error("Failed to update account balance: %v", err)
-			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update account balance"))
-			return
+	// Post the transaction and its postings together, enforcing each
+	// account's overdraft policy
+	transaction := &database.Transaction{
+		AgentID:     req.AgentID,
+		Description: req.Description,
+		ReferenceID: req.ReferenceID,
+		Status:      "posted",
+	}
+	postings := make([]*database.Posting, len(req.Postings))
+	for i, postingReq := range req.Postings {
+		postings[i] = &database.Posting{
+			AccountID: accounts[i].ID,
+			Amount:    postingReq.Amount,
+			Currency:  currencies[i],
 		}
 	}
+	if err := repo.TransactionRepository().Post(transaction, postings); err != nil {
+		respondPostingError(c, err)
+		return
+	}
 
 	// Convert to API response format
 	response := &types.Transaction{
@@ -352,13 +592,50 @@ func createTransaction(c *gin.Context) {
 		Description: transaction.Description,
 		ReferenceID: transaction.ReferenceID,
 		Status:      transaction.Status,
+		Book:        transaction.Book,
 		CreatedAt:   transaction.CreatedAt.Format(time.RFC3339),
 	}
 
+	legs := make([]map[string]interface{}, len(postings))
+	for i, posting := range postings {
+		legs[i] = map[string]interface{}{"accountId": posting.AccountID, "amount": posting.Amount, "currency": posting.Currency}
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditTransactionPosted,
+		Severity:     audit.SeverityMedium,
+		AgentID:      transaction.AgentID,
+		ResourceID:   transaction.ID,
+		ResourceType: "transaction",
+		Action:       "post",
+		Description:  "Transaction posted: " + transaction.Description,
+		NewValues:    map[string]interface{}{"status": transaction.Status, "referenceId": transaction.ReferenceID, "postings": legs},
+	})
+
 	common.Info("Transaction posted: %s for agent %s", transaction.ID, req.AgentID)
 	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
 }
 
+// respondPostingError reports a failed posting, distinguishing a breached
+// overdraft policy and incomplete posting rules from other failures
+func respondPostingError(c *gin.Context, err error) {
+	var insufficient *database.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("INSUFFICIENT_FUNDS", insufficient.Error()))
+		return
+	}
+	var unmapped *database.UnmappedPostingError
+	if errors.As(err, &unmapped) {
+		c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("POSTING_RULES_INCOMPLETE", unmapped.Error()))
+		return
+	}
+	if errors.Is(err, database.ErrMixedBooks) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Postings must all be to accounts in one book"))
+		return
+	}
+	common.Error("Failed to post transaction: %v", err)
+	c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to post transaction"))
+}
+
 func getTransaction(c *gin.Context) {
 	id := c.Param("id")
 	transaction, err := repo.TransactionRepository().GetByID(id)
@@ -367,6 +644,10 @@ func getTransaction(c *gin.Context) {
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Transaction not found"))
 		return
 	}
+	if !tenancy.CheckAgent(c, repo, transaction.AgentID, "transaction", transaction.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Transaction not found"))
+		return
+	}
 
 	// Get postings for this transaction
 	postings, err := repo.PostingRepository().ListByTransactionID(id)
@@ -385,36 +666,58 @@ func getTransaction(c *gin.Context) {
 			AccountID:     p.AccountID,
 			Amount:        p.Amount,
 			Currency:      p.Currency,
+			TokenAmount:   p.TokenAmount,
+			Token:         p.Token,
 			CreatedAt:     p.CreatedAt.Format(time.RFC3339),
 		})
 	}
 
 	// Convert to API response format
 	response := &types.TransactionDetail{
-		ID:          transaction.ID,
-		AgentID:     transaction.AgentID,
-		Description: transaction.Description,
-		ReferenceID: transaction.ReferenceID,
-		Status:      transaction.Status,
-		Postings:    postingResponses,
-		CreatedAt:   transaction.CreatedAt.Format(time.RFC3339),
+		ID:           transaction.ID,
+		AgentID:      transaction.AgentID,
+		Description:  transaction.Description,
+		ReferenceID:  transaction.ReferenceID,
+		Status:       transaction.Status,
+		Book:         transaction.Book,
+		Postings:     postingResponses,
+		Hash:         transaction.Hash,
+		PreviousHash: transaction.PreviousHash,
+		BlockIndex:   transaction.BlockIndex,
+		CreatedAt:    transaction.CreatedAt.Format(time.RFC3339),
+	}
+	if transaction.SourceTransactionID != nil {
+		response.SourceTransactionID = *transaction.SourceTransactionID
 	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// listTransactions lists a page of transactions, filtered by agent, status,
+// reference and creation time. Callers confined to a tenant list its
+// transactions only.
 func listTransactions(c *gin.Context) {
-	agentID := c.Query("agentId")
-
-	var transactions []*database.Transaction
-	var err error
-
-	if agentID != "" {
-		transactions, err = repo.TransactionRepository().ListByAgentID(agentID)
-	} else {
-		transactions, err = repo.TransactionRepository().List()
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	filter := database.TransactionFilter{
+		AgentID:     c.Query("agentId"),
+		Status:      c.Query("status"),
+		ReferenceID: c.Query("referenceId"),
+		Book:        c.Query("book"),
+		TenantID:    common.RequestTenant(c),
+	}
+	if filter.AgentID != "" && !agentInTenant(c, filter.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list transactions of this agent"))
+		return
 	}
 
+	transactions, total, err := repo.TransactionRepository().ListPage(filter, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to list transactions: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list transactions"))
@@ -430,11 +733,12 @@ func listTransactions(c *gin.Context) {
 			Description: tx.Description,
 			ReferenceID: tx.ReferenceID,
 			Status:      tx.Status,
+			Book:        tx.Book,
 			CreatedAt:   tx.CreatedAt.Format(time.RFC3339),
 		})
 	}
 
-	response := common.NewListResponse(make([]interface{}, len(result)), 1, 10, len(result))
+	response := common.NewPageResponse(make([]interface{}, len(result)), params, total)
 	for i, tx := range result {
 		response.Items[i] = tx
 	}
@@ -449,6 +753,10 @@ func getBalances(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId parameter is required"))
 		return
 	}
+	if !agentInTenant(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view balances of this agent"))
+		return
+	}
 
 	accounts, err := repo.AccountRepository().ListByAgentID(agentID)
 	if err != nil {
@@ -459,12 +767,13 @@ func getBalances(c *gin.Context) {
 
 	var balances []BalanceResponse
 	for _, account := range accounts {
-		balances = append(balances, BalanceResponse{
-			AccountID:   account.ID,
-			AccountName: account.Name,
-			Balance:     account.Balance,
-			Currency:    account.Currency,
-		})
+		balance, err := toBalanceResponse(account)
+		if err != nil {
+			common.Error("Failed to get available balance: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get balances"))
+			return
+		}
+		balances = append(balances, balance)
 	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]interface{}{
@@ -475,6 +784,10 @@ func getBalances(c *gin.Context) {
 
 func getAgentBalances(c *gin.Context) {
 	agentID := c.Param("agentId")
+	if !agentInTenant(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view balances of this agent"))
+		return
+	}
 
 	accounts, err := repo.AccountRepository().ListByAgentID(agentID)
 	if err != nil {
@@ -485,12 +798,13 @@ func getAgentBalances(c *gin.Context) {
 
 	var balances []BalanceResponse
 	for _, account := range accounts {
-		balances = append(balances, BalanceResponse{
-			AccountID:   account.ID,
-			AccountName: account.Name,
-			Balance:     account.Balance,
-			Currency:    account.Currency,
-		})
+		balance, err := toBalanceResponse(account)
+		if err != nil {
+			common.Error("Failed to get available balance: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get balances"))
+			return
+		}
+		balances = append(balances, balance)
 	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]interface{}{
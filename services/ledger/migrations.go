@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/migrations"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// backfiller runs online migration backfills in the background; each
+// migration runs at most once at a time in this process, and a checkpoint
+// another instance updated within migrationStaleAfter is taken as running there
+var (
+	backfiller          *migrations.Backfiller
+	migrationDB         *gorm.DB
+	runningBackfills    = map[string]context.CancelFunc{}
+	runningBackfillsMu  sync.Mutex
+	migrationStaleAfter = 5 * time.Minute
+	backfillWorkers     = common.NewWorkers()
+)
+
+type MigrationResponse struct {
+	Name        string  `json:"name"`
+	Table       string  `json:"table"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"` // pending if never backfilled
+	Processed   int64   `json:"processed"`
+	Total       int64   `json:"total"`
+	Error       string  `json:"error,omitempty"`
+	StartedAt   *string `json:"startedAt,omitempty"`
+	CompletedAt *string `json:"completedAt,omitempty"`
+	UpdatedAt   *string `json:"updatedAt,omitempty"`
+}
+
+func toMigrationResponse(migration migrations.Migration, checkpoint *database.MigrationCheckpoint) *MigrationResponse {
+	response := &MigrationResponse{
+		Name:        migration.Name,
+		Table:       migration.Table,
+		Description: migration.Description,
+		Status:      "pending",
+	}
+	if checkpoint == nil {
+		return response
+	}
+	startedAt := checkpoint.StartedAt.Format(time.RFC3339)
+	updatedAt := checkpoint.UpdatedAt.Format(time.RFC3339)
+	response.Status = checkpoint.Status
+	response.Processed = checkpoint.Processed
+	response.Total = checkpoint.Total
+	response.Error = checkpoint.Error
+	response.StartedAt = &startedAt
+	response.UpdatedAt = &updatedAt
+	if checkpoint.CompletedAt != nil {
+		completedAt := checkpoint.CompletedAt.Format(time.RFC3339)
+		response.CompletedAt = &completedAt
+	}
+	return response
+}
+
+// listMigrations lists the registered online migrations with their progress
+func listMigrations(c *gin.Context) {
+	checkpoints, err := repo.MigrationCheckpointRepository().List()
+	if err != nil {
+		common.Error("Failed to list migration checkpoints: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list migrations"))
+		return
+	}
+	byName := make(map[string]*database.MigrationCheckpoint, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		byName[checkpoint.Name] = checkpoint
+	}
+
+	responses := make([]*MigrationResponse, len(migrations.Registered))
+	for i, migration := range migrations.Registered {
+		responses[i] = toMigrationResponse(migration, byName[migration.Name])
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(responses))
+}
+
+// startMigrationBackfill starts or resumes a migration's backfill from its
+// checkpoint in the background
+func startMigrationBackfill(c *gin.Context) {
+	migration, ok := findMigration(c)
+	if !ok {
+		return
+	}
+
+	checkpoint, err := repo.MigrationCheckpointRepository().GetByName(migration.Name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		common.Error("Failed to get checkpoint of migration %s: %v", migration.Name, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to get migration"))
+		return
+	}
+	if err == nil && checkpoint.Status == migrations.StatusRunning && time.Since(checkpoint.UpdatedAt) < migrationStaleAfter {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("MIGRATION_RUNNING", "Migration backfill is already running"))
+		return
+	}
+
+	runningBackfillsMu.Lock()
+	if _, running := runningBackfills[migration.Name]; running {
+		runningBackfillsMu.Unlock()
+		c.JSON(http.StatusConflict, common.NewErrorResponse("MIGRATION_RUNNING", "Migration backfill is already running"))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	runningBackfills[migration.Name] = cancel
+	runningBackfillsMu.Unlock()
+
+	started := backfillWorkers.Go(migration.Name, func(context.Context) {
+		defer func() {
+			runningBackfillsMu.Lock()
+			delete(runningBackfills, migration.Name)
+			runningBackfillsMu.Unlock()
+			cancel()
+		}()
+		checkpoint, err := backfiller.Run(ctx, migration)
+		if err != nil {
+			common.Error("Backfill of migration %s failed: %v", migration.Name, err)
+			return
+		}
+		common.Info("Backfill of migration %s %s after %d of %d rows", migration.Name, checkpoint.Status, checkpoint.Processed, checkpoint.Total)
+	})
+	if !started {
+		runningBackfillsMu.Lock()
+		delete(runningBackfills, migration.Name)
+		runningBackfillsMu.Unlock()
+		cancel()
+		c.JSON(http.StatusServiceUnavailable, common.NewErrorResponse("SHUTTING_DOWN", "Service is shutting down"))
+		return
+	}
+
+	common.Info("Started backfill of migration %s", migration.Name)
+	c.JSON(http.StatusAccepted, common.NewSuccessResponse(gin.H{"name": migration.Name, "status": migrations.StatusRunning}))
+}
+
+// pauseMigrationBackfill stops a backfill running in this process after its
+// current batch; starting it again resumes from the checkpoint
+func pauseMigrationBackfill(c *gin.Context) {
+	migration, ok := findMigration(c)
+	if !ok {
+		return
+	}
+
+	runningBackfillsMu.Lock()
+	cancel, running := runningBackfills[migration.Name]
+	runningBackfillsMu.Unlock()
+	if !running {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("MIGRATION_NOT_RUNNING", "Migration backfill is not running on this instance"))
+		return
+	}
+	cancel()
+	c.JSON(http.StatusAccepted, common.NewSuccessResponse(gin.H{"name": migration.Name, "status": migrations.StatusPaused}))
+}
+
+// verifyMigration counts the rows a migration has not reached yet
+func verifyMigration(c *gin.Context) {
+	migration, ok := findMigration(c)
+	if !ok {
+		return
+	}
+
+	verification, err := migrations.Verify(migrationDB, migration)
+	if err != nil {
+		common.Error("Failed to verify migration %s: %v", migration.Name, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to verify migration"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(verification))
+}
+
+func findMigration(c *gin.Context) (migrations.Migration, bool) {
+	migration, err := migrations.Find(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Migration not found"))
+		return migration, false
+	}
+	return migration, true
+}
+
+// stopBackfills pauses the backfills running in this process and waits for
+// them to record their checkpoints
+func stopBackfills(ctx context.Context) error {
+	runningBackfillsMu.Lock()
+	for _, cancel := range runningBackfills {
+		cancel()
+	}
+	runningBackfillsMu.Unlock()
+	return backfillWorkers.Drain(ctx)
+}
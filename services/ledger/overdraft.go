@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type OverdraftPolicyRequest struct {
+	Policy string  `json:"policy"` // Empty restores the account type's default
+	Limit  float64 `json:"limit,omitempty"`
+}
+
+type OverdraftPolicyResponse struct {
+	AccountID string  `json:"accountId"`
+	Policy    string  `json:"policy"`
+	Limit     float64 `json:"limit,omitempty"`
+	Default   bool    `json:"default"` // The policy is the account type's default
+}
+
+// validateOverdraftPolicy returns why a policy cannot be set, or ""
+func validateOverdraftPolicy(policy string, limit float64) string {
+	if !database.ValidOverdraftPolicy(policy) {
+		return "overdraftPolicy must be disallow, limit or unlimited"
+	}
+	if limit < 0 {
+		return "overdraftLimit cannot be negative"
+	}
+	if policy == database.OverdraftLimited && limit <= 0 {
+		return "The limit policy requires a positive overdraftLimit"
+	}
+	return ""
+}
+
+// updateOverdraftPolicy sets how far below zero an account may be posted.
+// The new policy applies to later postings; an account already below its
+// floor stays there but cannot be debited further.
+func updateOverdraftPolicy(c *gin.Context) {
+	var req OverdraftPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	if msg := validateOverdraftPolicy(req.Policy, req.Limit); msg != "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", msg))
+		return
+	}
+
+	account, err := repo.AccountRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, account.AgentID) || !tenancy.CheckAgent(c, repo, account.AgentID, "account", account.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
+		return
+	}
+
+	account.OverdraftPolicy = req.Policy
+	account.OverdraftLimit = 0
+	if req.Policy == database.OverdraftLimited {
+		account.OverdraftLimit = req.Limit
+	}
+	if err := repo.AccountRepository().Update(account); err != nil {
+		common.Error("Failed to update overdraft policy of account %s: %v", account.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update overdraft policy"))
+		return
+	}
+
+	common.Info("Overdraft policy of account %s set to %s", account.ID, account.EffectiveOverdraftPolicy())
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&OverdraftPolicyResponse{
+		AccountID: account.ID,
+		Policy:    account.EffectiveOverdraftPolicy(),
+		Limit:     account.OverdraftLimit,
+		Default:   account.OverdraftPolicy == "",
+	}))
+}
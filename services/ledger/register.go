@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Account register
+//
+// An account's register lists its postings with the balance after each, for
+// rendering the account like a bank statement. Running balances are derived
+// from the postings themselves, so a page needs one sum over the postings
+// before it rather than the whole history.
+
+type AccountPostingResponse struct {
+	ID             string  `json:"id"`
+	TransactionID  string  `json:"transactionId"`
+	Description    string  `json:"description"`
+	ReferenceID    string  `json:"referenceId,omitempty"`
+	Amount         float64 `json:"amount"` // Positive = debit, negative = credit
+	Debit          float64 `json:"debit"`
+	Credit         float64 `json:"credit"`
+	Currency       string  `json:"currency"`
+	RunningBalance float64 `json:"runningBalance"` // Balance after this posting
+	CreatedAt      string  `json:"createdAt"`
+}
+
+// AccountPostingTotals summarizes the postings in the selected range
+type AccountPostingTotals struct {
+	From           string  `json:"from,omitempty"`
+	To             string  `json:"to,omitempty"`
+	Count          int64   `json:"count"`
+	Debits         float64 `json:"debits"`
+	Credits        float64 `json:"credits"`
+	Net            float64 `json:"net"`
+	OpeningBalance float64 `json:"openingBalance"` // Before the first posting in range
+	ClosingBalance float64 `json:"closingBalance"` // After the last posting in range
+}
+
+type AccountPostingsResponse struct {
+	*common.ListResponse
+	AccountID string                `json:"accountId"`
+	Currency  string                `json:"currency"`
+	Totals    *AccountPostingTotals `json:"totals"`
+}
+
+// listAccountPostings lists a page of an account's postings created in
+// ?from= to ?to=, newest first unless ?order=asc, each with the account's
+// balance after it, and totals for the whole range
+func listAccountPostings(c *gin.Context) {
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+
+	account, err := repo.AccountRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.CheckAgent(c, repo, account.AgentID, "account", account.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Account not found"))
+		return
+	}
+	if !common.CanActForAgent(c, account.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view postings of this account"))
+		return
+	}
+
+	postings, total, err := repo.PostingRepository().ListPageByAccountID(account.ID, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list postings of account %s: %v", account.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list postings"))
+		return
+	}
+
+	totals, err := accountPostingTotals(account.ID, params.From, params.To)
+	if err != nil {
+		common.Error("Failed to total postings of account %s: %v", account.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list postings"))
+		return
+	}
+
+	items := make([]interface{}, len(postings))
+	if len(postings) > 0 {
+		// The balance after the page's first posting anchors the rest, which
+		// step forward or back through the amounts in page order
+		balance, err := repo.PostingRepository().BalanceThrough(postings[0])
+		if err != nil {
+			common.Error("Failed to get running balance of account %s: %v", account.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list postings"))
+			return
+		}
+		for i, posting := range postings {
+			if i > 0 {
+				if params.Descending {
+					balance -= postings[i-1].Amount
+				} else {
+					balance += posting.Amount
+				}
+			}
+			items[i] = toAccountPostingResponse(posting, balance)
+		}
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&AccountPostingsResponse{
+		ListResponse: common.NewPageResponse(items, params, total),
+		AccountID:    account.ID,
+		Currency:     account.Currency,
+		Totals:       totals,
+	}))
+}
+
+// accountPostingTotals sums an account's postings created in [from, to),
+// with its balance either side of them
+func accountPostingTotals(accountID string, from, to *time.Time) (*AccountPostingTotals, error) {
+	inRange, err := repo.PostingRepository().Totals(accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	totals := &AccountPostingTotals{
+		Count:   inRange.Count,
+		Debits:  roundCents(inRange.Debits),
+		Credits: roundCents(inRange.Credits),
+		Net:     roundCents(inRange.Debits - inRange.Credits),
+	}
+	if from != nil {
+		before, err := repo.PostingRepository().Totals(accountID, nil, from)
+		if err != nil {
+			return nil, err
+		}
+		totals.From = from.UTC().Format(time.RFC3339)
+		totals.OpeningBalance = roundCents(before.Debits - before.Credits)
+	}
+	if to != nil {
+		totals.To = to.UTC().Format(time.RFC3339)
+	}
+	totals.ClosingBalance = roundCents(totals.OpeningBalance + totals.Net)
+	return totals, nil
+}
+
+func toAccountPostingResponse(posting *database.Posting, balance float64) *AccountPostingResponse {
+	response := &AccountPostingResponse{
+		ID:             posting.ID,
+		TransactionID:  posting.TransactionID,
+		Description:    posting.Transaction.Description,
+		ReferenceID:    posting.Transaction.ReferenceID,
+		Amount:         posting.Amount,
+		Currency:       posting.Currency,
+		RunningBalance: roundCents(balance),
+		CreatedAt:      posting.CreatedAt.Format(time.RFC3339),
+	}
+	if posting.Amount >= 0 {
+		response.Debit = posting.Amount
+	} else {
+		response.Credit = -posting.Amount
+	}
+	return response
+}
+
+// roundCents removes the binary noise left by summing amounts
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
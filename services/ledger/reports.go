@@ -0,0 +1,210 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/reporting"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type ActivityLine struct {
+	AccountID   string  `json:"accountId"`
+	AccountName string  `json:"accountName"`
+	Type        string  `json:"type"`
+	Currency    string  `json:"currency"`
+	Debits      float64 `json:"debits"`
+	Credits     float64 `json:"credits"`
+	Net         float64 `json:"net"` // Debits less credits
+}
+
+type ActivityTotal struct {
+	Type     string  `json:"type"`
+	Currency string  `json:"currency"`
+	Debits   float64 `json:"debits"`
+	Credits  float64 `json:"credits"`
+	Net      float64 `json:"net"`
+}
+
+// InFlightActivity is the activity of payments posted in the period that had
+// not settled by its end
+type InFlightActivity struct {
+	Transactions int             `json:"transactions"`
+	Included     bool            `json:"included"` // Whether the lines and totals include it
+	Lines        []*ActivityLine `json:"lines"`
+}
+
+type ActivityReportResponse struct {
+	AgentID      string            `json:"agentId"`
+	Book         string            `json:"book"`
+	Basis        reporting.Basis   `json:"basis"`
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	Transactions int               `json:"transactions"`
+	Lines        []*ActivityLine   `json:"lines"`
+	Totals       []*ActivityTotal  `json:"totals"` // By account type and currency
+	InFlight     *InFlightActivity `json:"inFlight"`
+}
+
+// activity accumulates the postings of transactions per account
+type activity struct {
+	accounts map[string]*database.Account
+	lines    map[string]*ActivityLine
+}
+
+func newActivity(accounts map[string]*database.Account) *activity {
+	return &activity{accounts: accounts, lines: make(map[string]*ActivityLine)}
+}
+
+func (a *activity) add(transaction *database.Transaction) {
+	for _, posting := range transaction.Postings {
+		line := a.lines[posting.AccountID]
+		if line == nil {
+			line = &ActivityLine{AccountID: posting.AccountID, Currency: posting.Currency}
+			if account := a.accounts[posting.AccountID]; account != nil {
+				line.AccountName = account.Name
+				line.Type = account.Type
+				line.Currency = account.Currency
+			}
+			a.lines[posting.AccountID] = line
+		}
+		if posting.Amount >= 0 {
+			line.Debits += posting.Amount
+		} else {
+			line.Credits -= posting.Amount
+		}
+	}
+}
+
+// sorted returns the lines by account type and name, rounded to cents
+func (a *activity) sorted() []*ActivityLine {
+	lines := make([]*ActivityLine, 0, len(a.lines))
+	for _, line := range a.lines {
+		line.Debits = math.Round(line.Debits*100) / 100
+		line.Credits = math.Round(line.Credits*100) / 100
+		line.Net = math.Round((line.Debits-line.Credits)*100) / 100
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Type != lines[j].Type {
+			return lines[i].Type < lines[j].Type
+		}
+		if lines[i].AccountName != lines[j].AccountName {
+			return lines[i].AccountName < lines[j].AccountName
+		}
+		return lines[i].AccountID < lines[j].AccountID
+	})
+	return lines
+}
+
+// getActivityReport reports the debits and credits to each of an agent's
+// accounts in a book over a period (?from=&to=, the last 30 days by default)
+// on the accrual or cash basis (?basis=). Accrual reports every transaction
+// posted in the period. Cash reports a payment's transactions in the period
+// its payment settled in, and leaves out payments still in flight at the end
+// of the period. Either way, payments posted in the period and still in
+// flight are broken out under inFlight.
+func getActivityReport(c *gin.Context) {
+	agentID := c.Query("agentId")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId parameter is required"))
+		return
+	}
+	if !agentInTenant(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view activity of this agent"))
+		return
+	}
+	basis, err := reporting.ParseBasis(c.Query("basis"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	book := c.DefaultQuery("book", database.BookManagement)
+	from, to, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+
+	recognizer := reporting.NewRecognizer(repo, basis)
+	// Cash-basis reports also take in payments posted before the period that
+	// settled within it
+	loadFrom := from
+	if basis == reporting.BasisCash {
+		loadFrom = from.Add(-recognizer.Lookback)
+	}
+	transactions, err := repo.TransactionRepository().ListPostedBetween(agentID, book, loadFrom, to)
+	if err != nil {
+		common.Error("Failed to list transactions for activity report: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build activity report"))
+		return
+	}
+	accountList, err := repo.AccountRepository().ListByAgentID(agentID)
+	if err != nil {
+		common.Error("Failed to list accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build activity report"))
+		return
+	}
+	accounts := make(map[string]*database.Account, len(accountList))
+	for _, account := range accountList {
+		accounts[account.ID] = account
+	}
+
+	response := &ActivityReportResponse{
+		AgentID:  agentID,
+		Book:     book,
+		Basis:    basis,
+		From:     from.Format(time.RFC3339),
+		To:       to.Format(time.RFC3339),
+		InFlight: &InFlightActivity{Included: basis == reporting.BasisAccrual},
+	}
+	recognized := newActivity(accounts)
+	inFlight := newActivity(accounts)
+	for _, transaction := range transactions {
+		recognition, err := recognizer.Recognize(transaction, to)
+		if err != nil {
+			common.Error("Failed to recognize transaction %s: %v", transaction.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to build activity report"))
+			return
+		}
+		if recognition.InFlight && !transaction.CreatedAt.Before(from) {
+			inFlight.add(transaction)
+			response.InFlight.Transactions++
+		}
+		if recognizer.Counts(recognition, from, to) {
+			recognized.add(transaction)
+			response.Transactions++
+		}
+	}
+
+	response.Lines = recognized.sorted()
+	response.InFlight.Lines = inFlight.sorted()
+	response.Totals = []*ActivityTotal{}
+	totals := make(map[[2]string]*ActivityTotal)
+	for _, line := range response.Lines {
+		key := [2]string{line.Type, line.Currency}
+		total := totals[key]
+		if total == nil {
+			total = &ActivityTotal{Type: line.Type, Currency: line.Currency}
+			totals[key] = total
+			response.Totals = append(response.Totals, total)
+		}
+		total.Debits += line.Debits
+		total.Credits += line.Credits
+	}
+	for _, total := range response.Totals {
+		total.Debits = math.Round(total.Debits*100) / 100
+		total.Credits = math.Round(total.Credits*100) / 100
+		total.Net = math.Round((total.Debits-total.Credits)*100) / 100
+	}
+	sort.Slice(response.Totals, func(i, j int) bool {
+		if response.Totals[i].Type != response.Totals[j].Type {
+			return response.Totals[i].Type < response.Totals[j].Type
+		}
+		return response.Totals[i].Currency < response.Totals[j].Currency
+	})
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
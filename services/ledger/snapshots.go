@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/balances"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// snapshotSettle is how far in the past snapshots are taken, so postings in
+// transactions still being committed are not left out of them
+const snapshotSettle = 5 * time.Minute
+
+// snapshotBalances snapshots the balance of every account
+func snapshotBalances(c *gin.Context) {
+	count, err := snapshotAllBalances(time.Now())
+	if err != nil {
+		common.Error("Failed to snapshot balances: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to snapshot balances"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]interface{}{
+		"snapshots": count,
+		"asOf":      time.Now().Add(-snapshotSettle).Format(time.RFC3339),
+	}))
+}
+
+// getBalanceDrift reports accounts, optionally of one agent, whose stored
+// balance differs from the balance derived from their postings
+func getBalanceDrift(c *gin.Context) {
+	drifts, err := findBalanceDrift(c.Query("agentId"))
+	if err != nil {
+		common.Error("Failed to check balance drift: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to check balance drift"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]interface{}{
+		"checkedAt": time.Now().Format(time.RFC3339),
+		"drifts":    drifts,
+	}))
+}
+
+func snapshotBalancesPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		if _, err := snapshotAllBalances(now); err != nil {
+			common.Error("Failed to snapshot balances: %v", err)
+		}
+	}
+}
+
+func reconcileBalancesPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := findBalanceDrift(""); err != nil {
+			common.Error("Failed to check balance drift: %v", err)
+		}
+	}
+}
+
+// snapshotAllBalances snapshots every account's balance as of a settled time
+// before now
+func snapshotAllBalances(now time.Time) (int, error) {
+	accounts, err := repo.AccountRepository().List()
+	if err != nil {
+		return 0, err
+	}
+
+	asOf := now.Add(-snapshotSettle)
+	count := 0
+	for _, account := range accounts {
+		if _, err := balanceCalculator.SnapshotBalance(account, asOf); err != nil {
+			common.Error("%v", err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// findBalanceDrift compares stored and derived balances, logging each account
+// that has drifted
+func findBalanceDrift(agentID string) ([]balances.BalanceDrift, error) {
+	var accounts []*database.Account
+	var err error
+	if agentID != "" {
+		accounts, err = repo.AccountRepository().ListByAgentID(agentID)
+	} else {
+		accounts, err = repo.AccountRepository().List()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	drifts, err := balanceCalculator.FindDrift(accounts)
+	if err != nil {
+		return nil, err
+	}
+	for _, drift := range drifts {
+		common.Warn("Balance drift on account %s: stored %.2f %s, postings sum to %.2f", drift.AccountID, drift.StoredBalance, drift.Currency, drift.DerivedBalance)
+	}
+	return drifts, nil
+}
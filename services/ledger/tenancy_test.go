@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOtherTenantsAuditEntriesAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+
+	// Two tenants, each an organization with an agent that made a payment
+	agents := map[string]*database.Agent{}
+	entries := map[string]*database.AuditEntry{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		entry := &database.AuditEntry{EventType: "payment_initiated", Severity: "low", AgentID: agent.ID, Action: "create", Description: name + " paid a vendor", Timestamp: time.Now()}
+		if err := repo.AuditEntryRepository().Create(entry); err != nil {
+			t.Fatal(err)
+		}
+		agents[name], entries[name] = agent, entry
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: agents["Acme"].OwnerPartyID, TenantID: agents["Acme"].OwnerPartyID}
+	get := func(path string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.GET("/v1/audit/events", listAuditEvents)
+		router.GET("/v1/audit/entries/:id/proof", getAuditEntryProof)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	// The tenant's own entry is found, though not yet anchored
+	if recorder := get("/v1/audit/entries/" + entries["Acme"].ID + "/proof"); recorder.Code != http.StatusConflict {
+		t.Fatalf("own entry: got %d, want 409", recorder.Code)
+	}
+	if recorder := get("/v1/audit/entries/" + entries["Globex"].ID + "/proof"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("other tenant's entry: got %d, want 404", recorder.Code)
+	}
+
+	// The tenant lists only entries about its own agents
+	recorder := get("/v1/audit/events")
+	var response struct {
+		Data struct {
+			Items []struct {
+				ID string `json:"id"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data.Items) != 1 || response.Data.Items[0].ID != entries["Acme"].ID {
+		t.Fatalf("tenant's audit events: got %s", recorder.Body)
+	}
+}
@@ -0,0 +1,592 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/budgets"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/spending"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Budgets
+//
+// An agent's owner sets budgets on what the agent may spend per day, week or
+// month, in one category of payments or across all of them, e.g. $500 a
+// month on marketing. Payments name their category. Every payment goes
+// through a budget check right before execution, whatever its workflow
+// template, and is failed if it would take the spend over a budget covering
+// it. Budgets roll over at the end of each period, carrying over what was
+// not spent if set to.
+
+// budgetCheckStage is the stage checking a payment against its agent's
+// budgets. It is not a template step: every plan runs it before execution.
+const budgetCheckStage = "budget_check"
+
+// budgetRolloverBatchSize is how many due budgets one rollover pass rolls
+const budgetRolloverBatchSize = 500
+
+type BudgetRequest struct {
+	AgentID         string  `json:"agentId" binding:"required"`
+	Category        string  `json:"category,omitempty" binding:"max=100"` // Empty covers all the agent's payments
+	Period          string  `json:"period" binding:"required"`            // "daily", "weekly" or "monthly"
+	AmountUSD       float64 `json:"amountUSD" binding:"required,gt=0"`
+	CarryOver       string  `json:"carryOver,omitempty"` // "none" (default), "unspent" or "capped"
+	MaxCarryOverUSD float64 `json:"maxCarryOverUSD,omitempty" binding:"gte=0"`
+}
+
+type UpdateBudgetRequest struct {
+	AmountUSD       *float64 `json:"amountUSD,omitempty" binding:"omitempty,gt=0"`
+	CarryOver       *string  `json:"carryOver,omitempty"`
+	MaxCarryOverUSD *float64 `json:"maxCarryOverUSD,omitempty" binding:"omitempty,gte=0"`
+	Active          *bool    `json:"active,omitempty"`
+}
+
+type BudgetResponse struct {
+	ID              string  `json:"id"`
+	AgentID         string  `json:"agentId"`
+	Category        string  `json:"category,omitempty"`
+	Period          string  `json:"period"`
+	AmountUSD       float64 `json:"amountUSD"`
+	CarryOver       string  `json:"carryOver"`
+	MaxCarryOverUSD float64 `json:"maxCarryOverUSD,omitempty"`
+	Active          bool    `json:"active"`
+	PeriodStart     string  `json:"periodStart"`
+	PeriodEnd       string  `json:"periodEnd"`
+	CarriedOverUSD  float64 `json:"carriedOverUSD"` // Carried into the current period
+	AvailableUSD    float64 `json:"availableUSD"`   // Amount plus carry-over
+	SpentUSD        float64 `json:"spentUSD"`
+	RemainingUSD    float64 `json:"remainingUSD"`
+	CreatedBy       string  `json:"createdBy,omitempty"`
+	CreatedAt       string  `json:"createdAt"`
+	UpdatedAt       string  `json:"updatedAt"`
+}
+
+type BudgetPeriodResponse struct {
+	PeriodStart   string  `json:"periodStart"`
+	PeriodEnd     string  `json:"periodEnd"`
+	AmountUSD     float64 `json:"amountUSD"`
+	CarriedInUSD  float64 `json:"carriedInUSD"`
+	SpentUSD      float64 `json:"spentUSD"`
+	CarriedOutUSD float64 `json:"carriedOutUSD"`
+}
+
+type RemainingBudgetResponse struct {
+	AgentID  string `json:"agentId"`
+	Category string `json:"category,omitempty"`
+	AsOf     string `json:"asOf"`
+	// RemainingUSD is what the agent can still spend in the category under
+	// all budgets covering it; null when none does
+	RemainingUSD *float64          `json:"remainingUSD"`
+	Budgets      []*BudgetResponse `json:"budgets"`
+}
+
+func toBudgetResponse(budget *database.Budget, spent float64) *BudgetResponse {
+	return &BudgetResponse{
+		ID:              budget.ID,
+		AgentID:         budget.AgentID,
+		Category:        budget.Category,
+		Period:          budget.Period,
+		AmountUSD:       budget.AmountUSD,
+		CarryOver:       budget.CarryOver,
+		MaxCarryOverUSD: budget.MaxCarryOverUSD,
+		Active:          budget.Active,
+		PeriodStart:     budget.PeriodStart.Format(time.RFC3339),
+		PeriodEnd:       budget.PeriodEnd.Format(time.RFC3339),
+		CarriedOverUSD:  budget.CarriedOverUSD,
+		AvailableUSD:    budgets.Available(budget),
+		SpentUSD:        spent,
+		RemainingUSD:    budgets.Remaining(budget, spent),
+		CreatedBy:       budget.CreatedBy,
+		CreatedAt:       budget.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       budget.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// initBudgetRollover rolls over budgets whose period has ended every
+// BUDGET_ROLLOVER_INTERVAL_SECONDS until ctx is done
+func initBudgetRollover(ctx context.Context) {
+	interval := time.Duration(common.GetEnvAsInt("BUDGET_ROLLOVER_INTERVAL_SECONDS", 300)) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				rollOverDueBudgets(now)
+			}
+		}
+	}()
+}
+
+func rollOverDueBudgets(now time.Time) {
+	due, err := repo.BudgetRepository().ListDue(now, budgetRolloverBatchSize)
+	if err != nil {
+		common.Error("Failed to list budgets due for rollover: %v", err)
+		return
+	}
+	for _, budget := range due {
+		if err := rollOverBudget(budget, now); err != nil {
+			common.Error("Failed to roll over budget %s: %v", budget.ID, err)
+		}
+	}
+}
+
+// rollOverBudget moves the budget on to the period containing now if its
+// period has ended, recording the periods it closes. A budget rolled over by
+// another instance meanwhile is reloaded instead.
+func rollOverBudget(budget *database.Budget, now time.Time) error {
+	if budget.PeriodEnd.After(now) {
+		return nil
+	}
+	previousStart := budget.PeriodStart
+	workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDsSince([]string{budget.AgentID}, previousStart)
+	if err != nil {
+		return err
+	}
+	closed := budgets.Rollover(budget, now, func(start, end time.Time) float64 {
+		return budgets.Spent(budget, workflows, "", start, end)
+	})
+
+	rolled, err := repo.BudgetRepository().Rollover(budget, previousStart, closed)
+	if err != nil {
+		return err
+	}
+	if !rolled {
+		current, err := repo.BudgetRepository().GetByID(budget.ID)
+		if err != nil {
+			return err
+		}
+		*budget = *current
+		return nil
+	}
+	last := closed[len(closed)-1]
+	common.Info("Rolled over %s budget %s of agent %s: spent %.2f USD, carried over %.2f USD",
+		budget.Period, budget.ID, budget.AgentID, last.SpentUSD, last.CarriedOutUSD)
+	return nil
+}
+
+// performBudgetCheck fails a payment that would take its agent's spend over
+// an active budget covering the payment's category
+func performBudgetCheck(workflow *database.PaymentWorkflow) error {
+	list, err := repo.BudgetRepository().ListByAgentID(workflow.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to load budgets: %v", err)
+	}
+
+	now := time.Now()
+	var covering []*database.Budget
+	for _, budget := range list {
+		if !budget.Active || !budgets.Covers(budget, workflow.Category) {
+			continue
+		}
+		if err := rollOverBudget(budget, now); err != nil {
+			return fmt.Errorf("failed to roll over budget %s: %v", budget.ID, err)
+		}
+		covering = append(covering, budget)
+	}
+	if len(covering) == 0 {
+		return nil
+	}
+
+	spent, err := budgetSpend(covering, workflow.ID)
+	if err != nil {
+		return fmt.Errorf("failed to total spend against budgets: %v", err)
+	}
+	for _, budget := range covering {
+		if budgets.Exceeds(budget, spent[budget.ID], workflow.AmountUSD) {
+			return fmt.Errorf("payment would exceed the agent's %s budget of %.2f USD (%.2f USD remaining)",
+				describeBudget(budget), budgets.Available(budget), budgets.Remaining(budget, spent[budget.ID]))
+		}
+	}
+	common.Info("Budget check passed for workflow %s", workflow.ID)
+	return nil
+}
+
+// describeBudget names a budget's period and category, e.g. "monthly marketing"
+func describeBudget(budget *database.Budget) string {
+	if budget.Category == "" {
+		return budget.Period
+	}
+	return budget.Period + " " + budget.Category
+}
+
+// budgetSpend totals the spend against each budget in its current period,
+// leaving out the given workflow, by budget ID
+func budgetSpend(list []*database.Budget, excludeWorkflowID string) (map[string]float64, error) {
+	spent := make(map[string]float64, len(list))
+	if len(list) == 0 {
+		return spent, nil
+	}
+	since := list[0].PeriodStart
+	agentIDs := map[string]bool{}
+	var ids []string
+	for _, budget := range list {
+		if budget.PeriodStart.Before(since) {
+			since = budget.PeriodStart
+		}
+		if !agentIDs[budget.AgentID] {
+			agentIDs[budget.AgentID] = true
+			ids = append(ids, budget.AgentID)
+		}
+	}
+	workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDsSince(ids, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, budget := range list {
+		spent[budget.ID] = budgets.Spent(budget, workflows, excludeWorkflowID, budget.PeriodStart, budget.PeriodEnd)
+	}
+	return spent, nil
+}
+
+// respondBudgets rolls the budgets over to the current period and responds
+// with each one's spend
+func respondBudgets(c *gin.Context, list []*database.Budget) ([]*BudgetResponse, bool) {
+	now := time.Now()
+	for _, budget := range list {
+		if budget.Active {
+			if err := rollOverBudget(budget, now); err != nil {
+				common.Error("Failed to roll over budget %s: %v", budget.ID, err)
+			}
+		}
+	}
+	spent, err := budgetSpend(list, "")
+	if err != nil {
+		common.Error("Failed to total spend against budgets: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load budgets"))
+		return nil, false
+	}
+	responses := make([]*BudgetResponse, len(list))
+	for i, budget := range list {
+		responses[i] = toBudgetResponse(budget, spent[budget.ID])
+	}
+	return responses, true
+}
+
+// createBudget sets a budget for an agent, starting in the current period
+func createBudget(c *gin.Context) {
+	var req BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if !spending.ValidPeriod(req.Period) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "period must be daily, weekly or monthly"))
+		return
+	}
+	if req.CarryOver == "" {
+		req.CarryOver = budgets.CarryOverNone
+	}
+	if err := validateCarryOver(req.CarryOver, req.MaxCarryOverUSD); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if !canManageBudgets(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage budgets of this agent"))
+		return
+	}
+	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
+		return
+	}
+
+	budget := &database.Budget{
+		AgentID:         req.AgentID,
+		Category:        req.Category,
+		Period:          req.Period,
+		AmountUSD:       req.AmountUSD,
+		CarryOver:       req.CarryOver,
+		MaxCarryOverUSD: req.MaxCarryOverUSD,
+		Active:          true,
+		CreatedBy:       principalSubject(c),
+	}
+	existing, err := repo.BudgetRepository().ListByAgentID(req.AgentID)
+	if err != nil {
+		common.Error("Failed to list budgets of agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create budget"))
+		return
+	}
+	for _, other := range existing {
+		if other.Period == budget.Period && strings.EqualFold(other.Category, budget.Category) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("BUDGET_EXISTS", fmt.Sprintf("Agent already has %s budget %s", describeBudget(other), other.ID)))
+			return
+		}
+	}
+
+	budgets.Start(budget, time.Now())
+	if err := repo.BudgetRepository().Create(budget); err != nil {
+		common.Error("Failed to create budget for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create budget"))
+		return
+	}
+
+	common.Info("Agent %s %s budget set to %.2f USD", budget.AgentID, describeBudget(budget), budget.AmountUSD)
+	responses, ok := respondBudgets(c, []*database.Budget{budget})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(responses[0]))
+}
+
+// listBudgets lists the budgets of ?agentId=, or of the party principal's
+// agents
+func listBudgets(c *gin.Context) {
+	agentID := c.Query("agentId")
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalAgent {
+		agentID = principal.AgentID
+	}
+
+	var list []*database.Budget
+	var err error
+	switch {
+	case agentID != "":
+		if !canAccessAgent(c, agentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view budgets of this agent"))
+			return
+		}
+		list, err = repo.BudgetRepository().ListByAgentID(agentID)
+	case principal != nil && principal.Type == common.PrincipalParty:
+		var agents []*database.Agent
+		if agents, err = repo.AgentRepository().ListByOwnerPartyID(principal.PartyID); err == nil && len(agents) > 0 {
+			ids := make([]string, len(agents))
+			for i, agent := range agents {
+				ids[i] = agent.ID
+			}
+			list, err = repo.BudgetRepository().ListByAgentIDs(ids)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId is required"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list budgets: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list budgets"))
+		return
+	}
+
+	responses, ok := respondBudgets(c, list)
+	if !ok {
+		return
+	}
+	items := make([]interface{}, len(responses))
+	for i, response := range responses {
+		items[i] = response
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getRemainingBudget reports what an agent can still spend in ?category=
+// under each active budget covering it
+func getRemainingBudget(c *gin.Context) {
+	agentID, category := c.Query("agentId"), c.Query("category")
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalAgent {
+		agentID = principal.AgentID
+	}
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId is required"))
+		return
+	}
+	if !canAccessAgent(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view budgets of this agent"))
+		return
+	}
+
+	list, err := repo.BudgetRepository().ListByAgentID(agentID)
+	if err != nil {
+		common.Error("Failed to list budgets of agent %s: %v", agentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list budgets"))
+		return
+	}
+	var covering []*database.Budget
+	for _, budget := range list {
+		if budget.Active && budgets.Covers(budget, category) {
+			covering = append(covering, budget)
+		}
+	}
+	responses, ok := respondBudgets(c, covering)
+	if !ok {
+		return
+	}
+
+	response := &RemainingBudgetResponse{
+		AgentID:  agentID,
+		Category: category,
+		AsOf:     time.Now().UTC().Format(time.RFC3339),
+		Budgets:  responses,
+	}
+	for _, budget := range responses {
+		remaining := budget.RemainingUSD
+		if response.RemainingUSD != nil {
+			remaining = math.Min(remaining, *response.RemainingUSD)
+		}
+		response.RemainingUSD = &remaining
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func getBudget(c *gin.Context) {
+	budget, ok := loadBudget(c)
+	if !ok {
+		return
+	}
+	responses, ok := respondBudgets(c, []*database.Budget{budget})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(responses[0]))
+}
+
+// updateBudget changes a budget's amount or carry-over, which apply to the
+// current period, or pauses it. A budget made active again starts afresh
+// in the current period.
+func updateBudget(c *gin.Context) {
+	var req UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	budget, ok := loadBudget(c)
+	if !ok {
+		return
+	}
+	if !canManageBudgets(c, budget.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage budgets of this agent"))
+		return
+	}
+	// Changes apply to the current period, so close any that has ended first
+	if budget.Active {
+		if err := rollOverBudget(budget, time.Now()); err != nil {
+			common.Error("Failed to roll over budget %s: %v", budget.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update budget"))
+			return
+		}
+	}
+
+	if req.AmountUSD != nil {
+		budget.AmountUSD = *req.AmountUSD
+	}
+	if req.CarryOver != nil {
+		budget.CarryOver = *req.CarryOver
+	}
+	if req.MaxCarryOverUSD != nil {
+		budget.MaxCarryOverUSD = *req.MaxCarryOverUSD
+	}
+	if err := validateCarryOver(budget.CarryOver, budget.MaxCarryOverUSD); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if req.Active != nil {
+		if *req.Active && !budget.Active {
+			budgets.Start(budget, time.Now())
+		}
+		budget.Active = *req.Active
+	}
+	if err := repo.BudgetRepository().Update(budget); err != nil {
+		common.Error("Failed to update budget %s: %v", budget.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update budget"))
+		return
+	}
+
+	common.Info("Budget %s of agent %s updated", budget.ID, budget.AgentID)
+	responses, ok := respondBudgets(c, []*database.Budget{budget})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(responses[0]))
+}
+
+func deleteBudget(c *gin.Context) {
+	budget, ok := loadBudget(c)
+	if !ok {
+		return
+	}
+	if !canManageBudgets(c, budget.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage budgets of this agent"))
+		return
+	}
+	if err := repo.BudgetRepository().Delete(budget.ID); err != nil {
+		common.Error("Failed to delete budget %s: %v", budget.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete budget"))
+		return
+	}
+	common.Info("Budget %s of agent %s removed", budget.ID, budget.AgentID)
+	c.Status(http.StatusNoContent)
+}
+
+// listBudgetPeriods lists a budget's closed periods, newest first
+func listBudgetPeriods(c *gin.Context) {
+	budget, ok := loadBudget(c)
+	if !ok {
+		return
+	}
+	if budget.Active {
+		if err := rollOverBudget(budget, time.Now()); err != nil {
+			common.Error("Failed to roll over budget %s: %v", budget.ID, err)
+		}
+	}
+	periods, err := repo.BudgetRepository().ListPeriods(budget.ID, 100)
+	if err != nil {
+		common.Error("Failed to list periods of budget %s: %v", budget.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list budget periods"))
+		return
+	}
+
+	items := make([]interface{}, len(periods))
+	for i, period := range periods {
+		items[i] = &BudgetPeriodResponse{
+			PeriodStart:   period.PeriodStart.Format(time.RFC3339),
+			PeriodEnd:     period.PeriodEnd.Format(time.RFC3339),
+			AmountUSD:     period.AmountUSD,
+			CarriedInUSD:  period.CarriedInUSD,
+			SpentUSD:      period.SpentUSD,
+			CarriedOutUSD: period.CarriedOutUSD,
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func loadBudget(c *gin.Context) (*database.Budget, bool) {
+	budget, err := repo.BudgetRepository().GetByID(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !canAccessAgent(c, budget.AgentID)) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Budget not found"))
+		return nil, false
+	}
+	if err != nil {
+		common.Error("Failed to load budget %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load budget"))
+		return nil, false
+	}
+	return budget, true
+}
+
+// canManageBudgets reports whether the principal may set an agent's budgets:
+// its owner party or a service, but not the agent itself
+func canManageBudgets(c *gin.Context, agentID string) bool {
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalAgent {
+		return false
+	}
+	return canAccessAgent(c, agentID)
+}
+
+func validateCarryOver(option string, maxCarryOverUSD float64) error {
+	if !budgets.ValidCarryOver(option) {
+		return errors.New("carryOver must be none, unspent or capped")
+	}
+	if option == budgets.CarryOverCapped && maxCarryOverUSD <= 0 {
+		return errors.New("maxCarryOverUSD is required to cap the carry-over")
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// PaymentCallbackSignatureHeader carries "t=<unix>,v1=<hex hmac>": the
+	// HMAC-SHA256 under the agent's callback secret of the time, the callback
+	// ID and the body, joined by dots
+	PaymentCallbackSignatureHeader = "X-Payment-Callback-Signature"
+	// PaymentCallbackIDHeader identifies a callback across its attempts, so
+	// receivers can drop repeats
+	PaymentCallbackIDHeader = "X-Payment-Callback-ID"
+	// PaymentCallbackAttemptHeader numbers the attempt, from 1
+	PaymentCallbackAttemptHeader = "X-Payment-Callback-Attempt"
+)
+
+// Payment callbacks
+//
+// A payment initiated with a callbackUrl has a signed summary POSTed there
+// once it completes, fails or is cancelled. This serves agents that run too
+// briefly to host a webhook endpoint. Deliveries are stored and retried with
+// backoff until the receiver answers 2xx or the attempts run out, so a
+// callback arrives at least once.
+//
+// Each agent signs with its own secret, derived from PAYMENT_CALLBACK_SECRET,
+// so no agent can forge another's callbacks. Callback URLs must resolve to
+// public addresses, checked when they are given and again when each attempt
+// connects, so the orchestrator cannot be pointed at its own network.
+
+var (
+	callbackSecret       string
+	callbackAllowHTTP    bool
+	callbackAllowPrivate bool
+	callbackMaxAttempts  int
+	callbackBaseDelay    time.Duration
+	callbackMaxDelay     time.Duration
+	callbackClient       *http.Client
+	callbackWake         = make(chan struct{}, 1)
+)
+
+// PaymentCallbackPayload is the summary POSTed to a payment's callback URL
+type PaymentCallbackPayload struct {
+	Event        string  `json:"event"` // payment.completed, payment.failed or payment.cancelled
+	PaymentID    string  `json:"paymentId"`
+	AgentID      string  `json:"agentId"`
+	Status       string  `json:"status"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty"`
+	Rail         string  `json:"rail"`
+	Description  string  `json:"description,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	FinalizedAt  string  `json:"finalizedAt"`
+}
+
+type PaymentCallbackResponse struct {
+	ID             string `json:"id"`
+	URL            string `json:"url"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttemptAt  string `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int    `json:"lastStatusCode,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+	DeliveredAt    string `json:"deliveredAt,omitempty"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+// PaymentCallbackSecretResponse is the secret an agent verifies its
+// callbacks with
+type PaymentCallbackSecretResponse struct {
+	AgentID string `json:"agentId"`
+	Secret  string `json:"secret"`
+}
+
+// initPaymentCallbacks reads the PAYMENT_CALLBACK_* settings, queues a
+// callback whenever a payment with a callback URL reaches a final status and
+// starts the delivery loop, which stops with ctx
+func initPaymentCallbacks(ctx context.Context) {
+	callbackSecret = common.GetEnv("PAYMENT_CALLBACK_SECRET", "")
+	callbackAllowHTTP = common.GetEnvAsBool("PAYMENT_CALLBACK_ALLOW_HTTP", false)
+	callbackAllowPrivate = common.GetEnvAsBool("PAYMENT_CALLBACK_ALLOW_PRIVATE", false)
+	callbackMaxAttempts = common.GetEnvAsInt("PAYMENT_CALLBACK_MAX_ATTEMPTS", 8)
+	callbackBaseDelay = time.Duration(common.GetEnvAsInt("PAYMENT_CALLBACK_BASE_DELAY_SECONDS", 10)) * time.Second
+	callbackMaxDelay = time.Duration(common.GetEnvAsInt("PAYMENT_CALLBACK_MAX_DELAY_SECONDS", 3600)) * time.Second
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: checkCallbackDial}
+	callbackClient = &http.Client{
+		Timeout: time.Duration(common.GetEnvAsInt("PAYMENT_CALLBACK_TIMEOUT_SECONDS", 10)) * time.Second,
+		// No proxy: the dialer must see the receiver's address to check it
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		// A redirect would send the signed summary somewhere the payer did not name
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	if callbackSecret == "" {
+		common.Warn("PAYMENT_CALLBACK_SECRET is not set - payments with a callbackUrl are rejected")
+	}
+
+	workflowStates.AddListener(func(workflow *database.PaymentWorkflow, _, to workflowstate.State) {
+		if workflowstate.Final(to) && workflow.CallbackURL != "" {
+			queuePaymentCallback(workflow, to)
+		}
+	})
+
+	interval := time.Duration(common.GetEnvAsInt("PAYMENT_CALLBACK_INTERVAL_SECONDS", 5)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-callbackWake:
+			}
+			deliverDueCallbacks(time.Now())
+		}
+	}()
+}
+
+// validateCallbackURL checks a callback URL given with a payment
+func validateCallbackURL(ctx context.Context, raw string) error {
+	if callbackSecret == "" {
+		return errors.New("payment callbacks are not enabled")
+	}
+	if len(raw) > 2048 {
+		return errors.New("callbackUrl is too long")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return errors.New("callbackUrl must be an absolute URL")
+	}
+	if parsed.Scheme != "https" && !(callbackAllowHTTP && parsed.Scheme == "http") {
+		return errors.New("callbackUrl must use https")
+	}
+	if parsed.User != nil {
+		return errors.New("callbackUrl must not carry credentials")
+	}
+	if callbackAllowPrivate {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicAddress(ip) {
+			return errCallbackAddress
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addresses) == 0 {
+		return errors.New("callbackUrl host cannot be resolved")
+	}
+	for _, address := range addresses {
+		if !publicAddress(address.IP) {
+			return errCallbackAddress
+		}
+	}
+	return nil
+}
+
+var errCallbackAddress = errors.New("callbackUrl must resolve to a public address")
+
+// nonPublicNetworks are reserved ranges the net.IP predicates do not cover:
+// "this network", carrier-grade NAT and benchmarking
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// publicAddress reports whether ip may receive callbacks. Loopback,
+// private, link-local (which holds cloud metadata services), unspecified,
+// multicast and reserved addresses may not.
+func publicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCallbackDial refuses connections to addresses that are not public.
+// It runs on the address being dialled, after resolution, so a host that
+// resolved to a public address when the payment was made cannot be
+// re-pointed at the orchestrator's network.
+func checkCallbackDial(network, address string, _ syscall.RawConn) error {
+	if callbackAllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+		return errCallbackAddress
+	}
+	return nil
+}
+
+// agentCallbackSecret derives the secret an agent's callbacks are signed
+// with from PAYMENT_CALLBACK_SECRET
+func agentCallbackSecret(agentID string) string {
+	mac := hmac.New(sha256.New, []byte(callbackSecret))
+	mac.Write([]byte("payment-callback:" + agentID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signCallback signs a callback attempt made at now
+func signCallback(secret, callbackID string, body []byte, now time.Time) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + callbackID + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// queuePaymentCallback stores the callback of a payment that reached a final
+// status and wakes the delivery loop
+func queuePaymentCallback(workflow *database.PaymentWorkflow, status workflowstate.State) {
+	now := time.Now()
+	payload, err := json.Marshal(&PaymentCallbackPayload{
+		Event:        "payment." + string(status),
+		PaymentID:    workflow.ID,
+		AgentID:      workflow.AgentID,
+		Status:       string(status),
+		Amount:       workflow.Amount,
+		Currency:     workflow.Currency,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Rail:         workflow.Rail,
+		Description:  workflow.Description,
+		CreatedAt:    workflow.CreatedAt.Format(time.RFC3339),
+		FinalizedAt:  now.Format(time.RFC3339),
+	})
+	if err != nil {
+		common.Error("Failed to encode callback of workflow %s: %v", workflow.ID, err)
+		return
+	}
+
+	callback := &database.PaymentCallback{
+		WorkflowID:    workflow.ID,
+		URL:           workflow.CallbackURL,
+		Payload:       string(payload),
+		Status:        "pending",
+		NextAttemptAt: now,
+	}
+	if err := repo.PaymentCallbackRepository().Create(callback); err != nil {
+		common.Error("Failed to queue callback of workflow %s: %v", workflow.ID, err)
+		return
+	}
+
+	select {
+	case callbackWake <- struct{}{}:
+	default:
+	}
+}
+
+// callbackBackoff is how long a callback that has failed the given number of
+// times waits before its next attempt
+func callbackBackoff(failures int) time.Duration {
+	delay := callbackBaseDelay
+	for i := 1; i < failures && delay < callbackMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > callbackMaxDelay {
+		delay = callbackMaxDelay
+	}
+	return delay
+}
+
+// deliverDueCallbacks attempts each pending callback whose time has come
+func deliverDueCallbacks(now time.Time) {
+	callbacks, err := repo.PaymentCallbackRepository().ListDue(now, 50)
+	if err != nil {
+		common.Error("Failed to list due payment callbacks: %v", err)
+		return
+	}
+	for _, callback := range callbacks {
+		deliverCallback(callback)
+	}
+}
+
+// deliverCallback makes one attempt at a callback and records the outcome
+func deliverCallback(callback *database.PaymentCallback) {
+	callback.Attempts++
+	statusCode, err := postCallback(callback)
+	callback.LastStatusCode = statusCode
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		callback.Status = "delivered"
+		callback.DeliveredAt = &now
+		callback.LastError = ""
+		common.Info("Delivered callback %s of workflow %s", callback.ID, callback.WorkflowID)
+	case callback.Attempts >= callbackMaxAttempts:
+		callback.Status = "failed"
+		callback.LastError = callbackFailure(statusCode)
+		common.Warn("Giving up on callback %s of workflow %s after %d attempts: %v", callback.ID, callback.WorkflowID, callback.Attempts, err)
+	default:
+		callback.NextAttemptAt = now.Add(callbackBackoff(callback.Attempts))
+		callback.LastError = callbackFailure(statusCode)
+		common.Warn("Callback %s of workflow %s failed, attempt %d of %d: %v", callback.ID, callback.WorkflowID, callback.Attempts, callbackMaxAttempts, err)
+	}
+
+	if err := repo.PaymentCallbackRepository().Update(callback); err != nil {
+		common.Error("Failed to record attempt of callback %s: %v", callback.ID, err)
+	}
+}
+
+// callbackFailure describes a failed attempt to the payer. Transport
+// errors are only logged: they would describe the network the orchestrator
+// runs in.
+func callbackFailure(statusCode int) string {
+	if statusCode != 0 {
+		return fmt.Sprintf("receiver answered %d", statusCode)
+	}
+	return "receiver could not be reached"
+}
+
+// postCallback POSTs the signed summary, succeeding on a 2xx answer
+func postCallback(callback *database.PaymentCallback) (int, error) {
+	body := []byte(callback.Payload)
+	var payload PaymentCallbackPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, callback.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(PaymentCallbackSignatureHeader, signCallback(agentCallbackSecret(payload.AgentID), callback.ID, body, time.Now()))
+	req.Header.Set(PaymentCallbackIDHeader, callback.ID)
+	req.Header.Set(PaymentCallbackAttemptHeader, strconv.Itoa(callback.Attempts))
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// listPaymentCallbacks lists the callback deliveries of a payment
+func listPaymentCallbacks(c *gin.Context) {
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(c.Param("id"))
+	if err != nil || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
+	if !common.CanActForAgent(c, workflow.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view callbacks of this payment"))
+		return
+	}
+
+	callbacks, err := repo.PaymentCallbackRepository().ListByWorkflowID(workflow.ID)
+	if err != nil {
+		common.Error("Failed to list callbacks of workflow %s: %v", workflow.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment callbacks"))
+		return
+	}
+
+	items := make([]interface{}, len(callbacks))
+	for i, callback := range callbacks {
+		response := &PaymentCallbackResponse{
+			ID:             callback.ID,
+			URL:            callback.URL,
+			Status:         callback.Status,
+			Attempts:       callback.Attempts,
+			LastStatusCode: callback.LastStatusCode,
+			LastError:      callback.LastError,
+			CreatedAt:      callback.CreatedAt.Format(time.RFC3339),
+		}
+		if callback.Status == "pending" {
+			response.NextAttemptAt = callback.NextAttemptAt.Format(time.RFC3339)
+		}
+		if callback.DeliveredAt != nil {
+			response.DeliveredAt = callback.DeliveredAt.Format(time.RFC3339)
+		}
+		items[i] = response
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getPaymentCallbackSecret returns the secret an agent's payment callbacks
+// are signed with
+func getPaymentCallbackSecret(c *gin.Context) {
+	agentID := c.Query("agentId")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId is required"))
+		return
+	}
+	if callbackSecret == "" {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment callbacks are not enabled"))
+		return
+	}
+	if !common.CanActForAgent(c, agentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view the callback secret of this agent"))
+		return
+	}
+	agent, err := repo.AgentRepository().GetByID(agentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agentID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&PaymentCallbackSecretResponse{AgentID: agentID, Secret: agentCallbackSecret(agentID)}))
+}
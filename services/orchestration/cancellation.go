@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CancelPaymentRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// cancelPayment stops a workflow that has not reached a terminal state. A
+// workflow already being processed stops before its next step; completed
+// payments are undone with a reversal in the router instead.
+func cancelPayment(c *gin.Context) {
+	var req CancelPaymentRequest
+	c.ShouldBindJSON(&req)
+
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(c.Param("id"))
+	if err != nil || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
+
+	if !common.CanActForAgent(c, workflow.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot cancel this payment"))
+		return
+	}
+
+	if !workflowstate.Allowed(workflowstate.State(workflow.Status), workflowstate.Cancelled) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only pending, processing or awaiting approval payments can be cancelled"))
+		return
+	}
+
+	message := "Cancelled"
+	if req.Reason != "" {
+		message = "Cancelled: " + req.Reason
+	}
+	if err := workflowStates.Transition(workflow, workflowstate.Cancelled, message, principalSubject(c)); err != nil {
+		if errors.Is(err, workflowstate.ErrConcurrentTransition) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", err.Error()))
+			return
+		}
+		common.Error("Failed to cancel payment workflow %s: %v", workflow.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to cancel payment"))
+		return
+	}
+	appendWorkflowStep(workflow, "cancellation", "completed", message)
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to record cancellation of workflow %s: %v", workflow.ID, err)
+	}
+
+	// Open payment links can no longer fund the workflow
+	if links, err := repo.PaymentLinkRepository().ListByWorkflowID(workflow.ID); err == nil {
+		for _, link := range links {
+			if link.Status == "open" {
+				link.Status = "cancelled"
+				if err := repo.PaymentLinkRepository().Update(link); err != nil {
+					common.Error("Failed to cancel payment link %s: %v", link.ID, err)
+				}
+			}
+		}
+	}
+
+	common.Info("Cancelled payment workflow %s", workflow.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentResponse(workflow)))
+}
+
+// workflowCancelled reports whether the workflow was cancelled since it was
+// loaded, so background processing can stop between steps
+func workflowCancelled(workflow *database.PaymentWorkflow) bool {
+	current, err := repo.PaymentWorkflowRepository().GetByID(workflow.ID)
+	if err != nil || current.Status != "cancelled" {
+		return false
+	}
+	workflow.Status = current.Status
+	workflow.Steps = current.Steps
+	common.Info("Payment workflow %s was cancelled, stopping processing", workflow.ID)
+	return true
+}
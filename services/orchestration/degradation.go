@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Degradation
+//
+// Each service a payment is checked against sits behind its client's circuit
+// breaker (see internal/clients). After consecutive failures to reach the service the breaker opens and
+// calls fail fast; after a cooldown one trial call is let through, and its
+// outcome closes or reopens the breaker. While a service cannot be reached,
+// its degradation mode says what happens to the payments that need it:
+//
+//   - fail_closed: the payment fails, as before
+//   - fail_open: payments up to a USD limit skip the check; larger ones fail
+//   - queue: the payment waits, retrying as the breaker allows, and fails
+//     if the service is still down after the maximum wait
+//
+// Each mode applied is recorded on the workflow.
+
+// Degradation modes
+const (
+	DegradeFailClosed = "fail_closed"
+	DegradeFailOpen   = "fail_open"
+	DegradeQueue      = "queue"
+)
+
+// errServiceUnavailable marks a service call that failed because the service
+// could not be reached or failed itself, as opposed to rejecting the request
+var errServiceUnavailable = common.ErrServiceUnavailable
+
+// DegradationPolicy is what happens to payments while a dependency is down
+type DegradationPolicy struct {
+	Mode           string        `json:"mode"`
+	FailOpenMaxUSD float64       `json:"failOpenMaxUSD,omitempty"` // Largest payment that skips the check under fail_open
+	QueueMaxWait   time.Duration `json:"-"`                        // Longest a payment waits under queue
+}
+
+// dependency is a service payments are checked against
+type dependency struct {
+	name    string
+	policy  DegradationPolicy
+	breaker *common.CircuitBreaker // The breaker of the dependency's client
+}
+
+var (
+	riskDependency       = &dependency{name: "risk"}
+	consentDependency    = &dependency{name: "consent"}
+	complianceDependency = &dependency{name: "compliance"}
+	dependencies         = []*dependency{riskDependency, consentDependency, complianceDependency}
+)
+
+// degradationRetryInterval is how often a queued payment retries its dependency
+var degradationRetryInterval = 5 * time.Second
+
+// initDegradation reads each dependency's policy from <NAME>_DEGRADATION_MODE
+// (fail_closed), <NAME>_FAIL_OPEN_MAX_USD (100) and
+// <NAME>_QUEUE_MAX_WAIT_MINUTES (30). The service clients must be set up first.
+func initDegradation() {
+	riskDependency.breaker = riskClient.Breaker()
+	consentDependency.breaker = consentClient.Breaker()
+	complianceDependency.breaker = complianceClient.Breaker()
+	for _, dep := range dependencies {
+		prefix := strings.ToUpper(dep.name)
+		dep.policy = DegradationPolicy{
+			Mode:           common.GetEnv(prefix+"_DEGRADATION_MODE", DegradeFailClosed),
+			FailOpenMaxUSD: float64(common.GetEnvAsInt(prefix+"_FAIL_OPEN_MAX_USD", 100)),
+			QueueMaxWait:   time.Duration(common.GetEnvAsInt(prefix+"_QUEUE_MAX_WAIT_MINUTES", 30)) * time.Minute,
+		}
+		switch dep.policy.Mode {
+		case DegradeFailClosed, DegradeFailOpen, DegradeQueue:
+		default:
+			common.Warn("Unknown %s_DEGRADATION_MODE %q; failing closed", prefix, dep.policy.Mode)
+			dep.policy.Mode = DegradeFailClosed
+		}
+	}
+}
+
+// callDegradable calls a dependency for a workflow's check, applying the
+// dependency's degradation mode if it cannot be reached. It returns a nil
+// response with no error when the check is skipped under fail_open.
+func callDegradable(workflow *database.PaymentWorkflow, dep *dependency, request func() (*common.APIResponse, error)) (*common.APIResponse, error) {
+	response, err := request()
+	if !errors.Is(err, errServiceUnavailable) {
+		return response, err
+	}
+
+	switch dep.policy.Mode {
+	case DegradeFailOpen:
+		if workflow.AmountUSD <= dep.policy.FailOpenMaxUSD {
+			reason := fmt.Sprintf("%s service unavailable; check skipped for a payment up to %.2f USD: %v", dep.name, dep.policy.FailOpenMaxUSD, err)
+			common.Warn("Workflow %s: %s", workflow.ID, reason)
+			recordDegradation(workflow, dep, DegradeFailOpen, reason)
+			return nil, nil
+		}
+		reason := fmt.Sprintf("%s service unavailable and the payment is above the %.2f USD fail-open limit: %v", dep.name, dep.policy.FailOpenMaxUSD, err)
+		recordDegradation(workflow, dep, DegradeFailClosed, reason)
+		return nil, errors.New(reason)
+
+	case DegradeQueue:
+		reason := fmt.Sprintf("%s service unavailable; payment queued for up to %s: %v", dep.name, dep.policy.QueueMaxWait, err)
+		common.Warn("Workflow %s: %s", workflow.ID, reason)
+		recordDegradation(workflow, dep, DegradeQueue, reason)
+		appendWorkflowStep(workflow, dep.name+"_queue", "pending", reason)
+		if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+			return nil, fmt.Errorf("failed to record queued payment: %v", err)
+		}
+		return awaitDependency(workflow, dep, request)
+
+	default:
+		reason := fmt.Sprintf("%s service unavailable: %v", dep.name, err)
+		recordDegradation(workflow, dep, DegradeFailClosed, reason)
+		return nil, errors.New(reason)
+	}
+}
+
+// awaitDependency retries a queued workflow's call until the dependency
+// answers or the policy's maximum wait passes
+func awaitDependency(workflow *database.PaymentWorkflow, dep *dependency, request func() (*common.APIResponse, error)) (*common.APIResponse, error) {
+	deadline := time.Now().Add(dep.policy.QueueMaxWait)
+	for time.Now().Before(deadline) {
+		if err := pauseWorkflow(degradationRetryInterval); err != nil {
+			return nil, err
+		}
+		if workflowCancelled(workflow) {
+			return nil, fmt.Errorf("workflow cancelled while queued for the %s service", dep.name)
+		}
+
+		response, err := request()
+		if errors.Is(err, errServiceUnavailable) {
+			continue
+		}
+		appendWorkflowStep(workflow, dep.name+"_queue", "completed", "Service available again")
+		return response, err
+	}
+	appendWorkflowStep(workflow, dep.name+"_queue", "failed", "Service still unavailable")
+	return nil, fmt.Errorf("%s service still unavailable after %s", dep.name, dep.policy.QueueMaxWait)
+}
+
+// recordDegradation adds a degradation mode applied to the workflow's record.
+// Payments that fail closed are recorded but not marked degraded, since no
+// check was bypassed.
+func recordDegradation(workflow *database.PaymentWorkflow, dep *dependency, mode, reason string) {
+	degradations := workflowDegradations(workflow)
+	degradations = append(degradations, types.Degradation{
+		Dependency: dep.name,
+		Mode:       mode,
+		Reason:     reason,
+		Timestamp:  time.Now().Format(time.RFC3339),
+	})
+	if data, err := json.Marshal(degradations); err == nil {
+		workflow.Degradations = string(data)
+	}
+	if mode != DegradeFailClosed {
+		workflow.Degraded = true
+	}
+}
+
+func workflowDegradations(workflow *database.PaymentWorkflow) []types.Degradation {
+	var degradations []types.Degradation
+	if workflow.Degradations != "" {
+		json.Unmarshal([]byte(workflow.Degradations), &degradations)
+	}
+	return degradations
+}
+
+type DependencyStatus struct {
+	Name     string            `json:"name"`
+	Circuit  string            `json:"circuit"` // closed, open or half_open
+	Failures int               `json:"consecutiveFailures"`
+	Policy   DegradationPolicy `json:"policy"`
+	MaxWait  string            `json:"queueMaxWait,omitempty"`
+}
+
+// listDependencies reports the breaker state and degradation policy of each
+// dependency as seen by this instance
+func listDependencies(c *gin.Context) {
+	statuses := make([]DependencyStatus, len(dependencies))
+	for i, dep := range dependencies {
+		state, failures := dep.breaker.Status()
+		statuses[i] = DependencyStatus{Name: dep.name, Circuit: state, Failures: failures, Policy: dep.policy}
+		if dep.policy.Mode == DegradeQueue {
+			statuses[i].MaxWait = dep.policy.QueueMaxWait.String()
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(statuses))
+}
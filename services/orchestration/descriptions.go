@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/descriptions"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DescriptionTemplateRequest struct {
+	PartyID  string `json:"partyId"`
+	Rail     string `json:"rail,omitempty"` // Empty applies to all rails
+	Template string `json:"template" binding:"required"`
+}
+
+type UpdateDescriptionTemplateRequest struct {
+	Template *string `json:"template"`
+	Active   *bool   `json:"active"`
+}
+
+type DescriptionTemplateResponse struct {
+	ID        string `json:"id"`
+	PartyID   string `json:"partyId"`
+	Rail      string `json:"rail,omitempty"`
+	Template  string `json:"template"`
+	Active    bool   `json:"active"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+func toDescriptionTemplateResponse(template *database.DescriptionTemplate) *DescriptionTemplateResponse {
+	return &DescriptionTemplateResponse{
+		ID:        template.ID,
+		PartyID:   template.PartyID,
+		Rail:      template.Rail,
+		Template:  template.Template,
+		Active:    template.Active,
+		CreatedBy: template.CreatedBy,
+		CreatedAt: template.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: template.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// canManageParty reports whether the principal may manage the party's
+// templates. Service principals manage any party's.
+func canManageParty(c *gin.Context, partyID string) bool {
+	principal := common.GetPrincipal(c)
+	if principal == nil || principal.Type == common.PrincipalService {
+		return true
+	}
+	if !tenancy.Check(c, repo, partyID, "party", partyID) {
+		return false
+	}
+	return principal.Type == common.PrincipalParty && principal.PartyID == partyID
+}
+
+// describePayment builds the description of a new payment from the owner
+// party's template for the rail, limited to the length the rail carries.
+// Without a template the caller's description is used as given.
+func describePayment(agent *database.Agent, req PaymentRequest, rail string) (string, error) {
+	description := req.Description
+
+	template, err := repo.DescriptionTemplateRepository().GetActive(agent.OwnerPartyID, rail)
+	switch {
+	case err == nil:
+		description, err = descriptions.Render(template.Template, map[string]string{
+			descriptions.VarAgentName:    agent.DisplayName,
+			descriptions.VarAgentID:      agent.ID,
+			descriptions.VarCounterparty: req.Counterparty,
+			descriptions.VarAmount:       fmt.Sprintf("%.2f", req.Amount),
+			descriptions.VarCurrency:     req.Currency,
+			descriptions.VarRail:         rail,
+			descriptions.VarDescription:  req.Description,
+			descriptions.VarDate:         time.Now().UTC().Format("2006-01-02"),
+		}, req.Metadata)
+		if err != nil {
+			return "", fmt.Errorf("description template %s is invalid: %v", template.ID, err)
+		}
+		// A template that renders empty leaves the caller's description in place
+		if description == "" {
+			description = req.Description
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", err
+	}
+
+	characteristics, _ := railCatalog.Get(rail)
+	return descriptions.ForRail(description, characteristics), nil
+}
+
+func encodeMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(metadata)
+	return string(data)
+}
+
+func decodeMetadata(metadata string) map[string]string {
+	if metadata == "" {
+		return nil
+	}
+	var values map[string]string
+	json.Unmarshal([]byte(metadata), &values)
+	return values
+}
+
+// validTemplateRail reports whether a template may target the rail; empty
+// targets all rails
+func validTemplateRail(rail string) bool {
+	if rail == "" {
+		return true
+	}
+	_, err := railCatalog.Get(rail)
+	return err == nil
+}
+
+// createDescriptionTemplate defines a party's template for a rail, replacing
+// the active template for that rail
+func createDescriptionTemplate(c *gin.Context) {
+	var req DescriptionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "template is required"))
+		return
+	}
+
+	principal := common.GetPrincipal(c)
+	if req.PartyID == "" && principal != nil {
+		req.PartyID = principal.PartyID
+	}
+	if req.PartyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, req.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage description templates of this party"))
+		return
+	}
+	if !validTemplateRail(req.Rail) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Unknown rail "+req.Rail))
+		return
+	}
+	if err := descriptions.Validate(req.Template); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_TEMPLATE", err.Error()))
+		return
+	}
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	// A party has at most one active template per rail
+	if existing, err := repo.DescriptionTemplateRepository().ListByPartyID(req.PartyID); err == nil {
+		for _, template := range existing {
+			if template.Active && template.Rail == req.Rail {
+				template.Active = false
+				if err := repo.DescriptionTemplateRepository().Update(template); err != nil {
+					common.Error("Failed to deactivate description template %s: %v", template.ID, err)
+				}
+			}
+		}
+	}
+
+	template := &database.DescriptionTemplate{
+		PartyID:  req.PartyID,
+		Rail:     req.Rail,
+		Template: req.Template,
+		Active:   true,
+	}
+	if principal != nil {
+		template.CreatedBy = principal.Subject
+	}
+
+	if err := repo.DescriptionTemplateRepository().Create(template); err != nil {
+		common.Error("Failed to create description template: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create description template"))
+		return
+	}
+
+	common.Info("Created description template %s for party %s", template.ID, template.PartyID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toDescriptionTemplateResponse(template)))
+}
+
+// listDescriptionTemplates lists a party's templates (?partyId=, defaulting to
+// the caller's party), newest first
+func listDescriptionTemplates(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view description templates of this party"))
+		return
+	}
+
+	templates, err := repo.DescriptionTemplateRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list description templates: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list description templates"))
+		return
+	}
+
+	items := make([]interface{}, len(templates))
+	for i, template := range templates {
+		items[i] = toDescriptionTemplateResponse(template)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// loadDescriptionTemplate fetches the template named in the path and checks
+// ownership, writing the error response itself when it returns nil
+func loadDescriptionTemplate(c *gin.Context) *database.DescriptionTemplate {
+	template, err := repo.DescriptionTemplateRepository().GetByID(c.Param("id"))
+	if err != nil || !canManageParty(c, template.PartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Description template not found"))
+		return nil
+	}
+	return template
+}
+
+func getDescriptionTemplate(c *gin.Context) {
+	template := loadDescriptionTemplate(c)
+	if template == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDescriptionTemplateResponse(template)))
+}
+
+func updateDescriptionTemplate(c *gin.Context) {
+	var req UpdateDescriptionTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	template := loadDescriptionTemplate(c)
+	if template == nil {
+		return
+	}
+
+	if req.Template != nil {
+		if err := descriptions.Validate(*req.Template); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_TEMPLATE", err.Error()))
+			return
+		}
+		template.Template = *req.Template
+	}
+	if req.Active != nil && *req.Active && !template.Active {
+		// Reactivating replaces the active template for the rail
+		if active, err := repo.DescriptionTemplateRepository().GetActive(template.PartyID, template.Rail); err == nil && active.Rail == template.Rail {
+			active.Active = false
+			if err := repo.DescriptionTemplateRepository().Update(active); err != nil {
+				common.Error("Failed to deactivate description template %s: %v", active.ID, err)
+			}
+		}
+	}
+	if req.Active != nil {
+		template.Active = *req.Active
+	}
+
+	if err := repo.DescriptionTemplateRepository().Update(template); err != nil {
+		common.Error("Failed to update description template: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update description template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDescriptionTemplateResponse(template)))
+}
+
+func deleteDescriptionTemplate(c *gin.Context) {
+	template := loadDescriptionTemplate(c)
+	if template == nil {
+		return
+	}
+
+	if err := repo.DescriptionTemplateRepository().Delete(template.ID); err != nil {
+		common.Error("Failed to delete description template: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete description template"))
+		return
+	}
+
+	common.Info("Deleted description template %s of party %s", template.ID, template.PartyID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": template.ID, "deleted": true}))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// In-process eventing
+//
+// With EVENT_BUS=memory there is no broker for the worker to read, so the
+// orchestration service relays the outbox to its in-process bus every
+// OUTBOX_RELAY_INTERVAL_MS (1000) and consumes the events itself with the
+// worker's default handlers. A single binary then runs the payment flow end
+// to end in development. On kafka and nats the worker does both.
+
+// inProcessGroupID is the consumer group of the in-process consumer, the
+// worker's default group
+const inProcessGroupID = "agent-payments"
+
+func initInProcessEventing(ctx context.Context, topic string) {
+	if eventBus.Name() != events.BusMemory {
+		return
+	}
+
+	interval := time.Duration(common.GetEnvAsInt("OUTBOX_RELAY_INTERVAL_MS", 1000)) * time.Millisecond
+	go eventPublisher.Run(ctx, interval)
+
+	consumer := events.NewEventConsumer(repo, eventBus, topic, inProcessGroupID)
+	consumer.RegisterHandler(events.NewPaymentEventHandler(repo))
+	consumer.RegisterHandler(events.NewLedgerEventHandler(repo))
+	if err := consumer.Start(ctx); err != nil {
+		log.Fatalf("Failed to start the in-process event consumer: %v", err)
+	}
+	server.OnShutdown("event consumer", func(ctx context.Context) error {
+		return consumer.Stop()
+	})
+	common.Info("Relaying the outbox to the in-process bus and consuming %s", topic)
+}
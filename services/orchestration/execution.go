@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/clients"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Ledger accounts a payment moves through. The amount is held on the wallet
+// while the rail executes and the hold is captured to the payments account
+// once it completes.
+const (
+	walletAccountName   = "Wallet"
+	paymentsAccountName = "Payments Sent"
+)
+
+// railExecutionTimeout is how long a rail execution may take to settle
+// before it is cancelled
+var railExecutionTimeout time.Duration
+
+// railExecutionPollInterval is how often a rail execution's status is checked
+const railExecutionPollInterval = 2 * time.Second
+
+// executePayment holds the payment's funds and fee, executes it on its rail,
+// captures the hold and charges the fee, compensating the completed steps if
+// a later one fails
+func executePayment(workflow *database.PaymentWorkflow) error {
+	common.Info("Executing payment for workflow %s", workflow.ID)
+
+	// An agent suspended while the payment was in flight stops it here
+	agent, err := repo.AgentRepository().GetByID(workflow.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to get agent: %v", err)
+	}
+	if refusal := agentstate.Refusal(agent); refusal != "" {
+		return errors.New(refusal)
+	}
+
+	fee, err := newPaymentFee(workflow)
+	if err != nil {
+		return err
+	}
+	execution := &railExecution{}
+	hold := &fundsHold{fee: fee.quote.TotalFeeUSD, execution: execution}
+	return runSaga(workflow, []sagaStep{
+		{name: "place_hold", action: hold.place, compensation: "release_hold", compensate: hold.release},
+		{name: "rail_execution", action: execution.run, compensation: "cancel_rail_execution", compensate: execution.cancel},
+		{name: "capture_hold", action: hold.capture, compensation: "reverse_posting", compensate: reverseCapture},
+		{name: "charge_fee", action: fee.charge, compensation: "refund_fee", compensate: fee.refund},
+	})
+}
+
+// fundsHold is a payment's hold on the agent's wallet in the ledger service
+type fundsHold struct {
+	id        string
+	fee       float64        // Fee held with the payment's amount
+	execution *railExecution // Execution whose token amount the capture records
+}
+
+// place holds the payment's amount and fee on the agent's wallet. The ledger
+// refuses the hold if the wallet's available balance does not cover them.
+func (h *fundsHold) place(workflow *database.PaymentWorkflow) error {
+	wallet, err := ledgerAccount(workflow.AgentID, walletAccountName)
+	if err != nil {
+		return err
+	}
+	response, err := ledgerClient.PlaceHold(workflowContext(workflow), &clients.HoldRequest{
+		AgentID:     workflow.AgentID,
+		AccountID:   wallet.ID,
+		Amount:      workflow.AmountUSD + h.fee,
+		ReferenceID: workflow.ID,
+		Description: "Hold for payment " + workflow.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to place hold: %v", err)
+	}
+	var hold struct {
+		ID string `json:"id"`
+	}
+	if err := decodeData(response, &hold); err != nil || hold.ID == "" {
+		return fmt.Errorf("invalid ledger service response: %v", err)
+	}
+	h.id = hold.ID
+	return nil
+}
+
+func (h *fundsHold) release(workflow *database.PaymentWorkflow) error {
+	if h.id == "" {
+		return nil
+	}
+	_, err := ledgerClient.ReleaseHold(workflowContext(workflow), h.id)
+	return err
+}
+
+// capture books the payment's amount as paid once the rail has completed,
+// freeing the held fee for the fee to be charged
+func (h *fundsHold) capture(workflow *database.PaymentWorkflow) error {
+	payments, err := ledgerAccount(workflow.AgentID, paymentsAccountName)
+	if err != nil {
+		return err
+	}
+	_, err = ledgerClient.CaptureHold(workflowContext(workflow), h.id, &clients.CaptureRequest{
+		DestinationAccountID: payments.ID,
+		Amount:               workflow.AmountUSD,
+		Description:          "Settlement of payment " + workflow.ID,
+		TokenAmount:          h.execution.tokenAmount,
+		Token:                h.execution.token,
+	})
+	return err
+}
+
+// reverseCapture returns a captured payment's amount to the agent's wallet
+func reverseCapture(workflow *database.PaymentWorkflow) error {
+	return postTransfer(workflow, ":settlement-reversal", "Reversal of settlement of payment "+workflow.ID, paymentsAccountName, walletAccountName)
+}
+
+// postTransfer books a transaction moving the payment's amount between two
+// of the agent's accounts, referenced by the workflow ID plus a suffix. A
+// transaction already booked under the reference is not booked again.
+func postTransfer(workflow *database.PaymentWorkflow, referenceSuffix, description, fromName, toName string) error {
+	referenceID := workflow.ID + referenceSuffix
+	existing, err := repo.TransactionRepository().ListByReferenceID(referenceID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	from, err := ledgerAccount(workflow.AgentID, fromName)
+	if err != nil {
+		return err
+	}
+	to, err := ledgerAccount(workflow.AgentID, toName)
+	if err != nil {
+		return err
+	}
+
+	transaction := &database.Transaction{
+		AgentID:     workflow.AgentID,
+		Description: description,
+		ReferenceID: referenceID,
+		Status:      "posted",
+	}
+	if err := repo.TransactionRepository().Create(transaction); err != nil {
+		return err
+	}
+
+	postings := []struct {
+		account *database.Account
+		amount  float64
+	}{
+		{to, workflow.AmountUSD},
+		{from, -workflow.AmountUSD},
+	}
+	for _, p := range postings {
+		posting := &database.Posting{
+			TransactionID: transaction.ID,
+			AccountID:     p.account.ID,
+			Amount:        p.amount,
+			Currency:      p.account.Currency,
+		}
+		if err := repo.PostingRepository().Create(posting); err != nil {
+			return err
+		}
+
+		p.account.Balance += p.amount
+		if err := repo.AccountRepository().Update(p.account); err != nil {
+			return err
+		}
+	}
+	return repo.TransactionRepository().Chain(transaction.ID)
+}
+
+// ledgerAccount finds one of the agent's payment accounts by name, creating
+// it if needed. The payments account is an expense; the others are assets.
+func ledgerAccount(agentID, name string) (*database.Account, error) {
+	accountType := "asset"
+	switch name {
+	case paymentsAccountName, railFeesAccountName, platformFeesAccountName:
+		accountType = "expense"
+	}
+
+	accounts, err := repo.AccountRepository().ListByAgentIDAndType(agentID, accountType)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, nil
+		}
+	}
+
+	account := &database.Account{
+		AgentID:  agentID,
+		Name:     name,
+		Type:     accountType,
+		Currency: "USD",
+	}
+	if err := repo.AccountRepository().Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// railExecution is a payment's execution in the router service
+type railExecution struct {
+	id string
+
+	// Token and amount of it paid, for executions settled on chain
+	token       string
+	tokenAmount float64
+}
+
+// executionChainSettlement is the part of an execution's on-chain
+// settlement the ledger records
+type executionChainSettlement struct {
+	Token       string  `json:"token"`
+	TokenAmount float64 `json:"tokenAmount"`
+}
+
+// setChainSettlement keeps the token amount of an execution settled on chain
+func (e *railExecution) setChainSettlement(settlement *executionChainSettlement) {
+	if settlement != nil {
+		e.token, e.tokenAmount = settlement.Token, settlement.TokenAmount
+	}
+}
+
+// run asks the router to execute the payment and waits for it to complete or
+// fail. An execution still settling at the timeout is cancelled.
+func (e *railExecution) run(workflow *database.PaymentWorkflow) error {
+	response, err := routerClient.Execute(workflowContext(workflow), &clients.ExecutionRequest{
+		AgentID:       workflow.AgentID,
+		AmountUSD:     workflow.AmountUSD,
+		Counterparty:  workflow.Counterparty,
+		Rail:          workflow.Rail,
+		Description:   workflow.Description,
+		International: international.Decode(workflow.International),
+
+		IdempotencyKey: executionIdempotencyKey(workflow),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to call router service: %v", err)
+	}
+	var execution struct {
+		ID              string                    `json:"id"`
+		Status          string                    `json:"status"`
+		ChainSettlement *executionChainSettlement `json:"chainSettlement"`
+	}
+	if err := decodeData(response, &execution); err != nil || execution.ID == "" {
+		return fmt.Errorf("invalid router service response: %v", err)
+	}
+	e.id = execution.ID
+	e.setChainSettlement(execution.ChainSettlement)
+	recordSagaStep(workflow, "rail_execution", "running", "Execution "+e.id)
+
+	// Rails settling on acceptance answer with the final status, so the hold
+	// is captured without waiting for the next poll
+	status := execution.Status
+	if status != "completed" && status != "failed" {
+		status, err = e.await(workflowContext(workflow))
+	}
+	if err != nil {
+		if cancelErr := e.cancel(workflow); cancelErr != nil {
+			return fmt.Errorf("%v; cancelling execution %s failed: %v", err, e.id, cancelErr)
+		}
+		return err
+	}
+	if status != "completed" {
+		return fmt.Errorf("execution %s %s", e.id, status)
+	}
+	return nil
+}
+
+// executionIdempotencyKey identifies the current attempt to execute the
+// workflow's payment, as the workflow and the number of attempts that failed
+// before it. Resending the attempt, after a cut-off call or a restart, reuses
+// the key so the router returns the execution already started; an attempt
+// after a recorded failure gets a new one.
+func executionIdempotencyKey(workflow *database.PaymentWorkflow) string {
+	attempt := 1
+	for _, step := range workflowSteps(workflow) {
+		if step.Name == "rail_execution" && step.Status == "failed" {
+			attempt++
+		}
+	}
+	return fmt.Sprintf("%s:attempt-%d", workflow.ID, attempt)
+}
+
+// await polls the execution until it reaches a final status. An execution
+// waiting in a batch has until its batch's cutoff plus the timeout.
+func (e *railExecution) await(ctx context.Context) (string, error) {
+	deadline := time.Now().Add(railExecutionTimeout)
+	for time.Now().Before(deadline) {
+		status, batchCutoff, err := e.check(ctx)
+		if err != nil {
+			common.Warn("Failed to check rail execution %s: %v", e.id, err)
+		} else if status == "completed" || status == "failed" || status == "reversed" {
+			return status, nil
+		} else if batchDeadline := batchCutoff.Add(railExecutionTimeout); batchDeadline.After(deadline) {
+			deadline = batchDeadline
+		}
+		time.Sleep(railExecutionPollInterval)
+	}
+	return "", fmt.Errorf("execution %s did not settle within %s", e.id, railExecutionTimeout)
+}
+
+func (e *railExecution) status(ctx context.Context) (string, error) {
+	status, _, err := e.check(ctx)
+	return status, err
+}
+
+// check returns the execution's status and, for a batched execution, its
+// batch's cutoff
+func (e *railExecution) check(ctx context.Context) (string, time.Time, error) {
+	response, err := routerClient.Status(ctx, e.id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var execution struct {
+		Status          string                    `json:"status"`
+		BatchCutoffAt   string                    `json:"batchCutoffAt"`
+		ChainSettlement *executionChainSettlement `json:"chainSettlement"`
+	}
+	if err := decodeData(response, &execution); err != nil {
+		return "", time.Time{}, err
+	}
+	e.setChainSettlement(execution.ChainSettlement)
+	batchCutoff, _ := time.Parse(time.RFC3339, execution.BatchCutoffAt)
+	return execution.Status, batchCutoff, nil
+}
+
+// cancel undoes the execution: an authorization still open is voided and a
+// completed payment is reversed through its rail
+func (e *railExecution) cancel(workflow *database.PaymentWorkflow) error {
+	if e.id == "" {
+		return nil
+	}
+	ctx := workflowContext(workflow)
+	status, err := e.status(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case "failed", "reversed":
+		return nil
+	case "completed":
+		_, err = routerClient.Reverse(ctx, e.id, "Payment workflow "+workflow.ID+" failed after execution")
+	default:
+		_, err = routerClient.Void(ctx, e.id)
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+)
+
+func TestExecutionIdempotencyKeyChangesOnlyAfterAFailedAttempt(t *testing.T) {
+	workflow := &database.PaymentWorkflow{ID: "workflow-1"}
+	if key := executionIdempotencyKey(workflow); key != "workflow-1:attempt-1" {
+		t.Fatalf("first attempt: got %s", key)
+	}
+
+	// An attempt cut off before its outcome was recorded is resent as itself
+	appendWorkflowStep(workflow, "place_hold", "completed", "")
+	appendWorkflowStep(workflow, "rail_execution", "running", "Execution execution-1")
+	if key := executionIdempotencyKey(workflow); key != "workflow-1:attempt-1" {
+		t.Fatalf("resent attempt: got %s", key)
+	}
+
+	appendWorkflowStep(workflow, "rail_execution", "failed", "execution execution-1 failed")
+	if key := executionIdempotencyKey(workflow); key != "workflow-1:attempt-2" {
+		t.Fatalf("attempt after a failure: got %s", key)
+	}
+}
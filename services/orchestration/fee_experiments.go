@@ -0,0 +1,410 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fees"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Fee experiment statuses
+const (
+	ExperimentActive = "active"
+	ExperimentEnded  = "ended"
+)
+
+type FeeVariantRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	Weight        *int    `json:"weight,omitempty"` // Defaults to 1
+	MarkupPercent float64 `json:"markupPercent"`    // Fraction of the rail fee, 0.2 = 20%
+	MarkupFixed   float64 `json:"markupFixed"`
+	MinMarkup     float64 `json:"minMarkup,omitempty"`
+	MaxMarkup     float64 `json:"maxMarkup,omitempty"` // Zero for no maximum
+}
+
+type FeeExperimentRequest struct {
+	Name       string              `json:"name" binding:"required"`
+	Rail       string              `json:"rail,omitempty"` // Empty applies to all rails
+	AutoAssign bool                `json:"autoAssign"`     // Assign tenants by weight on their first quote
+	Variants   []FeeVariantRequest `json:"variants" binding:"required"`
+}
+
+type FeeAssignmentRequest struct {
+	SubjectID   string `json:"subjectId" binding:"required"`
+	SubjectType string `json:"subjectType" binding:"required"` // "party" or "agent"
+	VariantID   string `json:"variantId" binding:"required"`
+}
+
+type FeeVariantResponse struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Weight        int     `json:"weight"`
+	MarkupPercent float64 `json:"markupPercent"`
+	MarkupFixed   float64 `json:"markupFixed"`
+	MinMarkup     float64 `json:"minMarkup"`
+	MaxMarkup     float64 `json:"maxMarkup"`
+}
+
+type FeeExperimentResponse struct {
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Rail       string                `json:"rail,omitempty"`
+	AutoAssign bool                  `json:"autoAssign"`
+	Status     string                `json:"status"`
+	Variants   []*FeeVariantResponse `json:"variants"`
+	EndedAt    string                `json:"endedAt,omitempty"`
+	CreatedAt  string                `json:"createdAt"`
+	UpdatedAt  string                `json:"updatedAt"`
+}
+
+type FeeAssignmentResponse struct {
+	ExperimentID string `json:"experimentId"`
+	SubjectID    string `json:"subjectId"`
+	SubjectType  string `json:"subjectType"`
+	VariantID    string `json:"variantId"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// FeeVariantResults compares a variant's conversion and volume with the others
+type FeeVariantResults struct {
+	VariantID         string  `json:"variantId"`
+	Name              string  `json:"name"`
+	Subjects          int     `json:"subjects"` // Tenants and agents assigned
+	Quotes            int64   `json:"quotes"`
+	Payments          int     `json:"payments"` // Initiated under the variant
+	CompletedPayments int     `json:"completedPayments"`
+	ConversionRate    float64 `json:"conversionRate"` // Payments per quote
+	CompletionRate    float64 `json:"completionRate"` // Completed payments per payment
+	VolumeUSD         float64 `json:"volumeUSD"`
+	MarkupUSD         float64 `json:"markupUSD"`
+}
+
+func toFeeExperimentResponse(experiment *database.FeeExperiment) *FeeExperimentResponse {
+	response := &FeeExperimentResponse{
+		ID:         experiment.ID,
+		Name:       experiment.Name,
+		Rail:       experiment.Rail,
+		AutoAssign: experiment.AutoAssign,
+		Status:     experiment.Status,
+		Variants:   make([]*FeeVariantResponse, len(experiment.Variants)),
+		CreatedAt:  experiment.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  experiment.UpdatedAt.Format(time.RFC3339),
+	}
+	for i, variant := range experiment.Variants {
+		response.Variants[i] = &FeeVariantResponse{
+			ID:            variant.ID,
+			Name:          variant.Name,
+			Weight:        variant.Weight,
+			MarkupPercent: variant.MarkupPercent,
+			MarkupFixed:   variant.MarkupFixed,
+			MinMarkup:     variant.MinMarkup,
+			MaxMarkup:     variant.MaxMarkup,
+		}
+	}
+	if experiment.EndedAt != nil {
+		response.EndedAt = experiment.EndedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// createFeeExperiment starts a pricing experiment. Active experiments may not
+// cover the same rail, so each payment falls under at most one of them.
+func createFeeExperiment(c *gin.Context) {
+	var req FeeExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "name and variants are required"))
+		return
+	}
+	if !validTemplateRail(req.Rail) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Unknown rail "+req.Rail))
+		return
+	}
+
+	experiment := &database.FeeExperiment{
+		Name:       req.Name,
+		Rail:       req.Rail,
+		AutoAssign: req.AutoAssign,
+		Status:     ExperimentActive,
+		Variants:   make([]database.FeeExperimentVariant, len(req.Variants)),
+	}
+	for i, variant := range req.Variants {
+		weight := 1
+		if variant.Weight != nil {
+			weight = *variant.Weight
+		}
+		experiment.Variants[i] = database.FeeExperimentVariant{
+			Name:          variant.Name,
+			Weight:        weight,
+			MarkupPercent: variant.MarkupPercent,
+			MarkupFixed:   variant.MarkupFixed,
+			MinMarkup:     variant.MinMarkup,
+			MaxMarkup:     variant.MaxMarkup,
+		}
+	}
+	if err := fees.ValidateExperiment(experiment); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	active, err := repo.FeeExperimentRepository().ListActive()
+	if err != nil {
+		common.Error("Failed to list fee experiments: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create fee experiment"))
+		return
+	}
+	for _, other := range active {
+		if req.Rail == "" || fees.AppliesTo(other, req.Rail) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("EXPERIMENT_EXISTS", fmt.Sprintf("Fee experiment %s is already running on this rail", other.ID)))
+			return
+		}
+	}
+
+	if err := repo.FeeExperimentRepository().Create(experiment); err != nil {
+		common.Error("Failed to create fee experiment: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create fee experiment"))
+		return
+	}
+
+	common.Info("Started fee experiment %s with %d variants", experiment.ID, len(experiment.Variants))
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toFeeExperimentResponse(experiment)))
+}
+
+func listFeeExperiments(c *gin.Context) {
+	experiments, err := repo.FeeExperimentRepository().List()
+	if err != nil {
+		common.Error("Failed to list fee experiments: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list fee experiments"))
+		return
+	}
+
+	items := make([]interface{}, len(experiments))
+	for i, experiment := range experiments {
+		items[i] = toFeeExperimentResponse(experiment)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// loadFeeExperiment fetches the experiment named in the path, writing the
+// error response itself when it returns nil
+func loadFeeExperiment(c *gin.Context) *database.FeeExperiment {
+	experiment, err := repo.FeeExperimentRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Fee experiment not found"))
+		return nil
+	}
+	return experiment
+}
+
+func getFeeExperiment(c *gin.Context) {
+	experiment := loadFeeExperiment(c)
+	if experiment == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFeeExperimentResponse(experiment)))
+}
+
+// endFeeExperiment stops quoting under an experiment's variants. Payments
+// already initiated under a variant are still settled under it.
+func endFeeExperiment(c *gin.Context) {
+	experiment := loadFeeExperiment(c)
+	if experiment == nil {
+		return
+	}
+	if experiment.Status == ExperimentEnded {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("EXPERIMENT_ENDED", "Fee experiment has already ended"))
+		return
+	}
+
+	now := time.Now()
+	experiment.Status = ExperimentEnded
+	experiment.EndedAt = &now
+	if err := repo.FeeExperimentRepository().Update(experiment); err != nil {
+		common.Error("Failed to end fee experiment: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to end fee experiment"))
+		return
+	}
+
+	common.Info("Ended fee experiment %s", experiment.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFeeExperimentResponse(experiment)))
+}
+
+// assignFeeVariant places a tenant or agent in one of an active experiment's
+// variants, replacing its assignment. Payments already initiated keep the
+// variant they were quoted under.
+func assignFeeVariant(c *gin.Context) {
+	var req FeeAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "subjectId, subjectType and variantId are required"))
+		return
+	}
+
+	experiment := loadFeeExperiment(c)
+	if experiment == nil {
+		return
+	}
+	if experiment.Status != ExperimentActive {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("EXPERIMENT_ENDED", "Fee experiment has ended"))
+		return
+	}
+	if experimentVariant(experiment, req.VariantID) == nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Variant is not part of this experiment"))
+		return
+	}
+
+	var err error
+	switch req.SubjectType {
+	case "party":
+		_, err = repo.PartyRepository().GetByID(req.SubjectID)
+	case "agent":
+		_, err = repo.AgentRepository().GetByID(req.SubjectID)
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "subjectType must be party or agent"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Subject not found"))
+		return
+	}
+
+	assignment := &database.FeeExperimentAssignment{
+		ExperimentID: experiment.ID,
+		SubjectID:    req.SubjectID,
+		SubjectType:  req.SubjectType,
+		VariantID:    req.VariantID,
+	}
+	if err := repo.FeeExperimentRepository().Assign(assignment); err != nil {
+		common.Error("Failed to assign fee variant: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to assign fee variant"))
+		return
+	}
+
+	common.Info("Assigned %s %s to variant %s of fee experiment %s", req.SubjectType, req.SubjectID, req.VariantID, experiment.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&FeeAssignmentResponse{
+		ExperimentID: assignment.ExperimentID,
+		SubjectID:    assignment.SubjectID,
+		SubjectType:  assignment.SubjectType,
+		VariantID:    assignment.VariantID,
+		UpdatedAt:    time.Now().Format(time.RFC3339),
+	}))
+}
+
+// getFeeExperimentResults compares the variants of an experiment: how many
+// quotes turned into payments, how many of those completed, and the volume
+// and markup revenue of each
+func getFeeExperimentResults(c *gin.Context) {
+	experiment := loadFeeExperiment(c)
+	if experiment == nil {
+		return
+	}
+
+	subjects, err := repo.FeeExperimentRepository().CountAssignments(experiment.ID)
+	if err != nil {
+		common.Error("Failed to count fee experiment assignments: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to report fee experiment results"))
+		return
+	}
+	rows, err := repo.FeeExperimentRepository().Results(experiment.ID)
+	if err != nil {
+		common.Error("Failed to report fee experiment results: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to report fee experiment results"))
+		return
+	}
+	byVariant := make(map[string]*database.FeeVariantResult, len(rows))
+	for _, row := range rows {
+		byVariant[row.VariantID] = row
+	}
+
+	results := make([]*FeeVariantResults, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		result := &FeeVariantResults{
+			VariantID: variant.ID,
+			Name:      variant.Name,
+			Subjects:  subjects[variant.ID],
+			Quotes:    variant.Quotes,
+		}
+		if row, ok := byVariant[variant.ID]; ok {
+			result.Payments = row.Payments
+			result.CompletedPayments = row.CompletedPayments
+			result.VolumeUSD = row.VolumeUSD
+			result.MarkupUSD = row.MarkupUSD
+		}
+		if result.Quotes > 0 {
+			result.ConversionRate = float64(result.Payments) / float64(result.Quotes)
+		}
+		if result.Payments > 0 {
+			result.CompletionRate = float64(result.CompletedPayments) / float64(result.Payments)
+		}
+		results[i] = result
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{
+		"experiment": toFeeExperimentResponse(experiment),
+		"variants":   results,
+	}))
+}
+
+// assignedFeeVariant finds the variant an agent's payments on a rail are
+// quoted under: its own assignment in the active experiment for the rail,
+// else its tenant's. Tenants not yet assigned in an experiment that assigns
+// automatically are assigned by weight, and keep that variant. It returns nil
+// if the agent is in no experiment.
+func assignedFeeVariant(agent *database.Agent, rail string) (*database.FeeExperimentVariant, error) {
+	experiments, err := repo.FeeExperimentRepository().ListActive()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, experiment := range experiments {
+		if !fees.AppliesTo(experiment, rail) {
+			continue
+		}
+		for _, subjectID := range []string{agent.ID, agent.OwnerPartyID} {
+			assignment, err := repo.FeeExperimentRepository().GetAssignment(experiment.ID, subjectID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if variant := experimentVariant(experiment, assignment.VariantID); variant != nil {
+				return variant, nil
+			}
+		}
+
+		if !experiment.AutoAssign {
+			continue
+		}
+		variant := fees.AssignVariant(experiment, agent.OwnerPartyID)
+		if variant == nil {
+			continue
+		}
+		assignment := &database.FeeExperimentAssignment{
+			ExperimentID: experiment.ID,
+			SubjectID:    agent.OwnerPartyID,
+			SubjectType:  "party",
+			VariantID:    variant.ID,
+		}
+		if err := repo.FeeExperimentRepository().Assign(assignment); err != nil {
+			return nil, err
+		}
+		common.Info("Assigned party %s to variant %s of fee experiment %s", agent.OwnerPartyID, variant.Name, experiment.ID)
+		return variant, nil
+	}
+	return nil, nil
+}
+
+// experimentVariant finds one of an experiment's variants by ID
+func experimentVariant(experiment *database.FeeExperiment, variantID string) *database.FeeExperimentVariant {
+	for i := range experiment.Variants {
+		if experiment.Variants[i].ID == variantID {
+			return &experiment.Variants[i]
+		}
+	}
+	return nil
+}
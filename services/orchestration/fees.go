@@ -0,0 +1,493 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fees"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Ledger accounts a payment's fee is booked to. The rail's fee is the cost of
+// the payment; the markup is platform revenue, kept in its own account.
+const (
+	railFeesAccountName     = "Rail Fees"
+	platformFeesAccountName = "Platform Fees"
+)
+
+// Fee charge statuses
+const (
+	FeeCharged  = "charged"
+	FeeRefunded = "refunded"
+)
+
+type FeeScheduleRequest struct {
+	PartyID       string  `json:"partyId" binding:"required"`
+	Rail          string  `json:"rail,omitempty"` // Empty applies to all rails
+	MarkupPercent float64 `json:"markupPercent"`  // Fraction of the rail fee, 0.2 = 20%
+	MarkupFixed   float64 `json:"markupFixed"`
+	MinMarkup     float64 `json:"minMarkup,omitempty"`
+	MaxMarkup     float64 `json:"maxMarkup,omitempty"` // Zero for no maximum
+	Enabled       *bool   `json:"enabled,omitempty"`
+}
+
+type UpdateFeeScheduleRequest struct {
+	MarkupPercent *float64 `json:"markupPercent"`
+	MarkupFixed   *float64 `json:"markupFixed"`
+	MinMarkup     *float64 `json:"minMarkup"`
+	MaxMarkup     *float64 `json:"maxMarkup"`
+	Enabled       *bool    `json:"enabled"`
+}
+
+type FeeScheduleResponse struct {
+	ID            string  `json:"id"`
+	PartyID       string  `json:"partyId"`
+	Rail          string  `json:"rail,omitempty"`
+	MarkupPercent float64 `json:"markupPercent"`
+	MarkupFixed   float64 `json:"markupFixed"`
+	MinMarkup     float64 `json:"minMarkup"`
+	MaxMarkup     float64 `json:"maxMarkup"`
+	Enabled       bool    `json:"enabled"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+type FeeQuoteRequest struct {
+	AgentID   string  `json:"agentId" binding:"required"`
+	AmountUSD float64 `json:"amountUSD" binding:"required"`
+	Rail      string  `json:"rail" binding:"required"`
+}
+
+func toFeeScheduleResponse(schedule *database.FeeSchedule) *FeeScheduleResponse {
+	return &FeeScheduleResponse{
+		ID:            schedule.ID,
+		PartyID:       schedule.PartyID,
+		Rail:          schedule.Rail,
+		MarkupPercent: schedule.MarkupPercent,
+		MarkupFixed:   schedule.MarkupFixed,
+		MinMarkup:     schedule.MinMarkup,
+		MaxMarkup:     schedule.MaxMarkup,
+		Enabled:       schedule.Enabled,
+		CreatedAt:     schedule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     schedule.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// createFeeSchedule sets a tenant's markup for a rail, or for all rails. A
+// tenant has one schedule per rail; change it with an update.
+func createFeeSchedule(c *gin.Context) {
+	var req FeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !validTemplateRail(req.Rail) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Unknown rail "+req.Rail))
+		return
+	}
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+
+	schedule := &database.FeeSchedule{
+		PartyID:       req.PartyID,
+		Rail:          req.Rail,
+		MarkupPercent: req.MarkupPercent,
+		MarkupFixed:   req.MarkupFixed,
+		MinMarkup:     req.MinMarkup,
+		MaxMarkup:     req.MaxMarkup,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	if err := fees.Validate(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	existing, err := repo.FeeScheduleRepository().ListByPartyID(req.PartyID)
+	if err != nil {
+		common.Error("Failed to list fee schedules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create fee schedule"))
+		return
+	}
+	for _, other := range existing {
+		if strings.EqualFold(other.Rail, req.Rail) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("SCHEDULE_EXISTS", fmt.Sprintf("Party already has fee schedule %s for this rail", other.ID)))
+			return
+		}
+	}
+
+	if err := repo.FeeScheduleRepository().Create(schedule); err != nil {
+		common.Error("Failed to create fee schedule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create fee schedule"))
+		return
+	}
+
+	common.Info("Created fee schedule %s for party %s", schedule.ID, schedule.PartyID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toFeeScheduleResponse(schedule)))
+}
+
+// listFeeSchedules lists every tenant's schedules, or one tenant's with ?partyId=
+func listFeeSchedules(c *gin.Context) {
+	var schedules []*database.FeeSchedule
+	var err error
+	if partyID := c.Query("partyId"); partyID != "" {
+		schedules, err = repo.FeeScheduleRepository().ListByPartyID(partyID)
+	} else {
+		schedules, err = repo.FeeScheduleRepository().List()
+	}
+	if err != nil {
+		common.Error("Failed to list fee schedules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list fee schedules"))
+		return
+	}
+
+	items := make([]interface{}, len(schedules))
+	for i, schedule := range schedules {
+		items[i] = toFeeScheduleResponse(schedule)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// loadFeeSchedule fetches the schedule named in the path, writing the error
+// response itself when it returns nil
+func loadFeeSchedule(c *gin.Context) *database.FeeSchedule {
+	schedule, err := repo.FeeScheduleRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Fee schedule not found"))
+		return nil
+	}
+	return schedule
+}
+
+func getFeeSchedule(c *gin.Context) {
+	schedule := loadFeeSchedule(c)
+	if schedule == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFeeScheduleResponse(schedule)))
+}
+
+// updateFeeSchedule changes a schedule's markup. Payments already charged
+// keep the fee they were charged.
+func updateFeeSchedule(c *gin.Context) {
+	var req UpdateFeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	schedule := loadFeeSchedule(c)
+	if schedule == nil {
+		return
+	}
+	if req.MarkupPercent != nil {
+		schedule.MarkupPercent = *req.MarkupPercent
+	}
+	if req.MarkupFixed != nil {
+		schedule.MarkupFixed = *req.MarkupFixed
+	}
+	if req.MinMarkup != nil {
+		schedule.MinMarkup = *req.MinMarkup
+	}
+	if req.MaxMarkup != nil {
+		schedule.MaxMarkup = *req.MaxMarkup
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+	if err := fees.Validate(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	if err := repo.FeeScheduleRepository().Update(schedule); err != nil {
+		common.Error("Failed to update fee schedule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update fee schedule"))
+		return
+	}
+
+	common.Info("Updated fee schedule %s of party %s", schedule.ID, schedule.PartyID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toFeeScheduleResponse(schedule)))
+}
+
+func deleteFeeSchedule(c *gin.Context) {
+	schedule := loadFeeSchedule(c)
+	if schedule == nil {
+		return
+	}
+
+	if err := repo.FeeScheduleRepository().Delete(schedule.ID); err != nil {
+		common.Error("Failed to delete fee schedule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete fee schedule"))
+		return
+	}
+
+	common.Info("Deleted fee schedule %s of party %s", schedule.ID, schedule.PartyID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": schedule.ID, "deleted": true}))
+}
+
+// quoteFee quotes the fee an agent would be charged for a payment on a rail
+func quoteFee(c *gin.Context) {
+	var req FeeQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, amountUSD and rail are required"))
+		return
+	}
+	if req.AmountUSD <= 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amountUSD must be greater than 0"))
+		return
+	}
+	if !common.CanActForAgent(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot quote fees for this agent"))
+		return
+	}
+
+	quote, err := quoteAgentFee(req.AgentID, req.Rail, req.AmountUSD)
+	if err != nil {
+		common.Error("Failed to quote fee for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("QUOTE_ERROR", err.Error()))
+		return
+	}
+	countFeeQuote(quote)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(quote))
+}
+
+// getFeeRevenue reports the fees charged by tenant and rail between ?from=
+// and ?to= (RFC3339, defaulting to the last 30 days), for one tenant with
+// ?partyId=. Refunded fees are left out.
+func getFeeRevenue(c *gin.Context) {
+	until := time.Now()
+	since := until.AddDate(0, 0, -30)
+	for param, value := range map[string]*time.Time{"from": &since, "to": &until} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", param+" must be an RFC3339 time"))
+				return
+			}
+			*value = parsed
+		}
+	}
+
+	revenue, err := repo.FeeChargeRepository().Revenue(c.Query("partyId"), since, until)
+	if err != nil {
+		common.Error("Failed to report fee revenue: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to report fee revenue"))
+		return
+	}
+
+	var total float64
+	for _, row := range revenue {
+		total += row.MarkupUSD
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{
+		"from":           since.Format(time.RFC3339),
+		"to":             until.Format(time.RFC3339),
+		"revenue":        revenue,
+		"totalMarkupUSD": total,
+	}))
+}
+
+// quoteAgentFee quotes a payment's fee under the experiment variant the
+// agent is assigned for the rail, or else under the schedule of the agent's
+// owner
+func quoteAgentFee(agentID, rail string, amountUSD float64) (fees.Quote, error) {
+	agent, err := repo.AgentRepository().GetByID(agentID)
+	if err != nil {
+		return fees.Quote{}, fmt.Errorf("agent %s not found", agentID)
+	}
+
+	variant, err := assignedFeeVariant(agent, rail)
+	if err != nil {
+		return fees.Quote{}, err
+	}
+	if variant == nil {
+		return quoteScheduleFee(agent, rail, amountUSD)
+	}
+	return fees.NewVariantQuote(rail, amountUSD, railFee(rail, amountUSD), variant), nil
+}
+
+// countFeeQuote counts a quote shown to a caller towards the conversion of
+// the experiment variant it was made under
+func countFeeQuote(quote fees.Quote) {
+	if quote.VariantID == "" {
+		return
+	}
+	if err := repo.FeeExperimentRepository().CountQuote(quote.VariantID); err != nil {
+		common.Error("Failed to count quote for fee variant %s: %v", quote.VariantID, err)
+	}
+}
+
+// quoteScheduleFee quotes a payment's fee under the schedule of the agent's owner
+func quoteScheduleFee(agent *database.Agent, rail string, amountUSD float64) (fees.Quote, error) {
+	schedules, err := repo.FeeScheduleRepository().ListByPartyID(agent.OwnerPartyID)
+	if err != nil {
+		return fees.Quote{}, err
+	}
+	return fees.NewQuote(rail, amountUSD, railFee(rail, amountUSD), fees.SelectSchedule(schedules, rail)), nil
+}
+
+// railFee is the rail's own fee for a payment. Rails missing from the rail
+// catalog carry only the markup.
+func railFee(rail string, amountUSD float64) float64 {
+	characteristics, err := railCatalog.Get(rail)
+	if err != nil {
+		return 0
+	}
+	return characteristics.FeeStructure.Calculate(amountUSD)
+}
+
+// paymentFee is a payment's fee, quoted before its funds are held and booked
+// once it has been captured
+type paymentFee struct {
+	quote fees.Quote
+}
+
+// newPaymentFee quotes the workflow's fee. A payment made under a quote is
+// settled at the quoted fee. A payment initiated under a fee experiment
+// variant is settled under that variant, even if the experiment has ended
+// since; any other payment is settled under its tenant's schedule. Payments
+// abroad add the correspondent fees their payer bears.
+func newPaymentFee(workflow *database.PaymentWorkflow) (*paymentFee, error) {
+	if workflow.QuoteID != nil {
+		quote, err := repo.QuoteRepository().GetByID(*workflow.QuoteID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load quote %s: %v", *workflow.QuoteID, err)
+		}
+		return &paymentFee{quote: quotedFee(quote)}, nil
+	}
+	if workflow.FeeVariantID != nil {
+		variant, err := repo.FeeExperimentRepository().GetVariant(*workflow.FeeVariantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fee variant %s: %v", *workflow.FeeVariantID, err)
+		}
+		quote := fees.NewVariantQuote(workflow.Rail, workflow.AmountUSD, railFee(workflow.Rail, workflow.AmountUSD), variant)
+		return &paymentFee{quote: withCorrespondentFee(quote, international.Decode(workflow.International))}, nil
+	}
+
+	agent, err := repo.AgentRepository().GetByID(workflow.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote fee: agent %s not found", workflow.AgentID)
+	}
+	quote, err := quoteScheduleFee(agent, workflow.Rail, workflow.AmountUSD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote fee: %v", err)
+	}
+	return &paymentFee{quote: withCorrespondentFee(quote, international.Decode(workflow.International))}, nil
+}
+
+// charge books the fee from the agent's wallet: the rail fee to its Rail Fees
+// account and the markup to its Platform Fees account. A fee already charged
+// for the payment is not charged again.
+func (f *paymentFee) charge(workflow *database.PaymentWorkflow) error {
+	if f.quote.TotalFeeUSD <= 0 {
+		return nil
+	}
+	if _, err := repo.FeeChargeRepository().GetByWorkflowID(workflow.ID); err == nil {
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	agent, err := repo.AgentRepository().GetByID(workflow.AgentID)
+	if err != nil {
+		return err
+	}
+	transactionID, err := f.post(workflow, ":fee", "Fees for payment "+workflow.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	charge := &database.FeeCharge{
+		WorkflowID:    workflow.ID,
+		AgentID:       workflow.AgentID,
+		PartyID:       agent.OwnerPartyID,
+		Rail:          workflow.Rail,
+		AmountUSD:     workflow.AmountUSD,
+		RailFeeUSD:    f.quote.RailFeeUSD,
+		MarkupUSD:     f.quote.MarkupUSD,
+		TotalFeeUSD:   f.quote.TotalFeeUSD,
+		ScheduleID:    f.quote.ScheduleID,
+		VariantID:     f.quote.VariantID,
+		TransactionID: transactionID,
+		Status:        FeeCharged,
+	}
+	return repo.FeeChargeRepository().Create(charge)
+}
+
+// refund returns a charged fee to the agent's wallet
+func (f *paymentFee) refund(workflow *database.PaymentWorkflow) error {
+	charge, err := repo.FeeChargeRepository().GetByWorkflowID(workflow.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if charge.Status == FeeRefunded {
+		return nil
+	}
+
+	f.quote.RailFeeUSD, f.quote.MarkupUSD = charge.RailFeeUSD, charge.MarkupUSD
+	if _, err := f.post(workflow, ":fee-refund", "Refund of fees for payment "+workflow.ID, -1); err != nil {
+		return err
+	}
+	charge.Status = FeeRefunded
+	return repo.FeeChargeRepository().Update(charge)
+}
+
+// post books a fee's postings, reversed when sign is -1, under the workflow
+// ID plus a suffix. A transaction already booked under the reference is not
+// booked again.
+func (f *paymentFee) post(workflow *database.PaymentWorkflow, referenceSuffix, description string, sign float64) (string, error) {
+	referenceID := workflow.ID + referenceSuffix
+	existing, err := repo.TransactionRepository().ListByReferenceID(referenceID)
+	if err != nil {
+		return "", err
+	}
+	if len(existing) > 0 {
+		return existing[0].ID, nil
+	}
+
+	wallet, err := ledgerAccount(workflow.AgentID, walletAccountName)
+	if err != nil {
+		return "", err
+	}
+	var postings []*database.Posting
+	for _, part := range []struct {
+		account string
+		amount  float64
+	}{
+		{railFeesAccountName, f.quote.RailFeeUSD},
+		{platformFeesAccountName, f.quote.MarkupUSD},
+	} {
+		if part.amount == 0 {
+			continue
+		}
+		account, err := ledgerAccount(workflow.AgentID, part.account)
+		if err != nil {
+			return "", err
+		}
+		postings = append(postings, &database.Posting{AccountID: account.ID, Amount: sign * part.amount, Currency: account.Currency})
+	}
+	postings = append(postings, &database.Posting{AccountID: wallet.ID, Amount: -sign * (f.quote.RailFeeUSD + f.quote.MarkupUSD), Currency: wallet.Currency})
+
+	transaction := &database.Transaction{
+		AgentID:     workflow.AgentID,
+		Description: description,
+		ReferenceID: referenceID,
+		Status:      "posted",
+	}
+	if err := repo.TransactionRepository().Post(transaction, postings); err != nil {
+		return "", err
+	}
+	return transaction.ID, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/example/agent-payments/internal/fees"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/rounding"
+)
+
+// validateInternational normalizes a payment's beneficiary details and
+// checks them against the rules of the destination country
+func validateInternational(req *PaymentRequest) *paymentError {
+	if req.International == nil {
+		return nil
+	}
+	req.International.Normalize()
+	if err := international.Validate(req.International); err != nil {
+		return &paymentError{http.StatusBadRequest, "INVALID_BENEFICIARY", err.Error()}
+	}
+	return nil
+}
+
+// withCorrespondentFee adds the correspondent fees the payer pays for a
+// payment abroad to its fee. Only the OUR charge bearer puts them on the
+// payer; the others have them deducted from the amount in transit.
+func withCorrespondentFee(quote fees.Quote, details *international.Details) fees.Quote {
+	if !international.IsInternational(details) {
+		return quote
+	}
+	payer := international.EstimateFees(details, quote.AmountUSD).PayerUSD
+	if payer <= 0 {
+		return quote
+	}
+	quote.CorrespondentFeeUSD = payer
+	quote.RailFeeUSD = rounding.Round(quote.RailFeeUSD + payer)
+	quote.TotalFeeUSD = rounding.Round(quote.TotalFeeUSD + payer)
+	return quote
+}
@@ -2,38 +2,100 @@ package main
 
 import (
 	"bytes"
-	"context" // Used for HTTP request timeouts
+	"context"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
 
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/clients"
+	"github.com/example/agent-payments/internal/config"
+	"github.com/example/agent-payments/internal/counterparties"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/did"
+	"github.com/example/agent-payments/internal/discovery"
 	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/internal/fees"
+	"github.com/example/agent-payments/internal/fx"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/kyc"
+	"github.com/example/agent-payments/internal/mandate"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/rails"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/internal/workflowtemplate"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var repo database.Repository
-var railSelector *types.RailSelector
+//go:embed openapi.json
+var openAPISpec []byte
 
-// Placeholder for event publishing - will be implemented later
-var _ = func() interface{} {
-	// This prevents the events import from being removed by the formatter
-	_ = events.EventPublisher{}
-	return nil
-}()
+var repo database.Repository
+var authConfig *common.AuthConfig
+var requireAgentCredentials bool
+var mandateVerifier *mandate.Verifier
+var requireMandates bool
+var requireCounterpartyIDs bool
+
+// requireAgentSignatures makes agents of identity mode "did" sign their
+// payment requests with a key of their DID document
+var requireAgentSignatures bool
+var didResolver *did.Resolver
+
+// kycUnverifiedLimitUSD is the largest payment an agent of a party that is
+// not KYC/KYB verified may make
+var kycUnverifiedLimitUSD float64
+var railCatalog *rails.Catalog
+var converter *fx.Converter
+var complianceReviewTimeout time.Duration
+var riskReviewTimeout time.Duration
+var approvalTimeout time.Duration
+var workflowStates *workflowstate.Machine
+var server *common.Server
+
+// workflowWorkers run payment workflows, which shutdown waits for
+var workflowWorkers = common.NewWorkers()
+
+// complianceReviewPollInterval is how often a payment held for compliance
+// review checks whether it has been decided
+const complianceReviewPollInterval = 10 * time.Second
+
+// eventPublisher saves payment events to the outbox
+var eventBus events.MessageBus
+var eventPublisher *events.EventPublisher
 
 type PaymentRequest struct {
-	AgentID      string           `json:"agentId" binding:"required"`
-	AmountUSD    float64          `json:"amountUSD" binding:"required"`
-	Counterparty string           `json:"counterparty" binding:"required"`
-	Rail         string           `json:"rail,omitempty"` // Optional - will auto-select if not provided
-	Description  string           `json:"description"`
-	Preferences  *RailPreferences `json:"preferences,omitempty"`
+	AgentID        string            `json:"agentId" binding:"required"`
+	Amount         float64           `json:"amount,omitempty"`         // Amount in Currency; takes precedence over AmountUSD
+	Currency       string            `json:"currency,omitempty"`       // ISO 4217 code, defaults to USD
+	AmountUSD      float64           `json:"amountUSD,omitempty"`      // Legacy USD amount
+	Counterparty   string            `json:"counterparty,omitempty"`   // Free-form payee name, unless counterpartyId is given
+	CounterpartyID string            `json:"counterpartyId,omitempty"` // Counterparty entity of the agent's owner party
+	Rail           string            `json:"rail,omitempty"`           // Optional - will auto-select if not provided
+	Description    string            `json:"description"`
+	Metadata       map[string]string `json:"metadata,omitempty"` // Values for the owner party's description template
+	Preferences    *RailPreferences  `json:"preferences,omitempty"`
+	Mandate        *mandate.Proof    `json:"mandate,omitempty"`                    // Agent signature over amount/counterparty/expiry
+	CallbackURL    string            `json:"callbackUrl,omitempty"`                // Receives a signed summary once the payment is final
+	Category       string            `json:"category,omitempty" binding:"max=100"` // Spending category, such as marketing, for budgets
+	QuoteID        string            `json:"quoteId,omitempty"`                    // Quote whose rail, fee and FX rate apply while it is valid
+
+	International *international.Details `json:"international,omitempty"` // Beneficiary's bank details, for payments abroad
+
+	quote *database.Quote // Quote the payment is made under, once applied
 }
 
 type RailPreferences struct {
@@ -52,7 +114,7 @@ type PaymentWorkflow struct {
 	Counterparty string         `json:"counterparty"`
 	Rail         string         `json:"rail"`
 	Description  string         `json:"description"`
-	Status       string         `json:"status"` // "pending", "processing", "completed", "failed"
+	Status       string         `json:"status"` // "pending", "processing", "awaiting_approval", "completed", "failed"
 	Steps        []WorkflowStep `json:"steps"`
 	RiskDecision *RiskDecision  `json:"riskDecision,omitempty"`
 	ConsentCheck *ConsentCheck  `json:"consentCheck,omitempty"`
@@ -62,28 +124,51 @@ type PaymentWorkflow struct {
 
 type WorkflowStep struct {
 	Name      string `json:"name"`
-	Status    string `json:"status"` // "pending", "running", "completed", "failed"
+	Status    string `json:"status"` // "pending", "running", "completed", "failed", "skipped"
 	Message   string `json:"message"`
 	Timestamp string `json:"timestamp"`
 }
 
 type RiskDecision struct {
-	Decision    string   `json:"decision"`
-	Score       float64  `json:"score"`
-	Reason      string   `json:"reason"`
-	RiskFactors []string `json:"riskFactors"`
+	ID             string   `json:"id"`
+	Decision       string   `json:"decision"`
+	Score          float64  `json:"score"`
+	Reason         string   `json:"reason"`
+	Threshold      float64  `json:"threshold"`
+	PolicyVersion  int      `json:"policyVersion"`
+	TrustTier      string   `json:"trustTier,omitempty"`
+	RiskFactors    []string `json:"riskFactors"`
+	TriggeredRules []string `json:"triggeredRules,omitempty"`
+	CaseID         string   `json:"caseId,omitempty"` // Review case holding a "review" decision
+	CreatedAt      string   `json:"createdAt,omitempty"`
 }
 
 type ConsentCheck struct {
-	Valid     bool   `json:"valid"`
-	Reason    string `json:"reason"`
-	ConsentID string `json:"consentId,omitempty"`
+	Valid            bool   `json:"valid"`
+	Reason           string `json:"reason"`
+	ConsentID        string `json:"consentId,omitempty"`
+	ConsentVersion   int    `json:"consentVersion,omitempty"`
+	RequiresApproval bool   `json:"requiresApproval,omitempty"`
+	ApproverGroup    string `json:"approverGroup,omitempty"`
+	ApprovalID       string `json:"approvalId,omitempty"` // Approval holding the payment
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("orchestration")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("orchestration")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -96,17 +181,78 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
-	// Initialize rail selector for multi-rail routing
-	railSelector = types.NewRailSelector()
-	common.Info("Initialized rail selector with %d payment rails", len(railSelector.GetAvailableRails()))
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+	requireAgentCredentials = common.GetEnvAsBool("AGENT_CREDENTIALS_REQUIRED", false)
+
+	// New payments are announced through the event outbox
+	eventBus, err = events.NewMessageBus(cfg.MessageBus())
+	if err != nil {
+		log.Fatalf("Failed to connect to the event bus: %v", err)
+	}
+	eventPublisher = events.NewEventPublisher(repo, eventBus, cfg.Kafka.Topic)
+
+	// Initialize mandate verification against agent-registered keys
+	mandateVerifier = mandate.NewVerifier(repo)
+	didResolver = did.NewResolver(repo)
+
+	// Workflow statuses change only through the state machine; a payment
+	// completes only once it has been executed
+	workflowStates = workflowstate.New(repo)
+	workflowStates.AddGuard(workflowstate.Processing, workflowstate.Completed, "execution", requireStage("execution"))
+	requireMandates = common.GetEnvAsBool("MANDATES_REQUIRED", false)
+	requireCounterpartyIDs = common.GetEnvAsBool("COUNTERPARTY_IDS_REQUIRED", false)
+	requireAgentSignatures = common.GetEnvAsBool("AGENT_SIGNATURES_REQUIRED", false)
+	kycUnverifiedLimitUSD = common.GetEnvAsFloat("KYC_UNVERIFIED_LIMIT_USD", 1000)
+
+	// Initialize FX conversion for non-USD payments
+	converter, err = fx.NewConverterFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize FX rates: %v", err)
+	}
+
+	// Quotes fix a payment's rail, fee and exchange rate this long
+	quoteTTL = time.Duration(common.GetEnvAsInt("QUOTE_TTL_SECONDS", 300)) * time.Second
+
+	// Rail executions that have not settled in this time are cancelled
+	railExecutionTimeout = time.Duration(common.GetEnvAsInt("RAIL_EXECUTION_TIMEOUT_SECONDS", 300)) * time.Second
+
+	// Payments with watchlist hits wait this long for a compliance decision
+	complianceReviewTimeout = time.Duration(common.GetEnvAsInt("COMPLIANCE_REVIEW_TIMEOUT_MINUTES", 60)) * time.Minute
+
+	// Payments the risk service sends to manual review wait this long for a decision
+	riskReviewTimeout = time.Duration(common.GetEnvAsInt("RISK_REVIEW_TIMEOUT_MINUTES", 60)) * time.Minute
+
+	// Payments above a cosign threshold wait this long for an approver, the
+	// time the consent service gives approvals before they expire
+	approvalTimeout = time.Duration(common.GetEnvAsInt("APPROVAL_TIMEOUT_MINUTES", 60)) * time.Minute
+
+	// Clients of downstream services, then the degradation modes of the
+	// services payments are checked against
+	if err := initServiceClients(); err != nil {
+		log.Fatalf("Failed to initialize service clients: %v", err)
+	}
+	initDegradation()
+
+	// Seed the rail catalog, which the router routes payments from too
+	if err := rails.Seed(repo); err != nil {
+		log.Fatalf("Failed to seed the rail catalog: %v", err)
+	}
+	railCatalog = rails.NewCatalog(repo, cfg.ApplyRails)
+	common.Info("Initialized rail catalog with %d enabled payment rails", len(railCatalog.Selector().GetAvailableRails()))
 
 	r := gin.Default()
+	server = common.NewServer(cfg.Addr(), r)
 
 	// Setup common middleware
 	common.SetupCommonMiddleware(r, func() error {
 		return repo.HealthCheck()
 	})
 
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
 		if err := repo.HealthCheck(); err != nil {
@@ -116,25 +262,137 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "orchestration service ok"})
 	})
 
+	// Hosted funding flow for payment links, authorized by the link token
+	r.GET("/v1/payment-links/:token", getPaymentLink)
+	r.GET("/v1/payment-links/:token/qr.png", getPaymentLinkQR)
+	r.POST("/v1/payment-links/:token/complete", completePaymentLink)
+
+	// Read-only counterparty statements, authorized by the statement token
+	r.GET("/v1/statements/:token", getStatement)
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Payment orchestration
-		v1.POST("/payments", initiatePayment)
-		v1.GET("/payments/:id", getPaymentStatus)
-		v1.GET("/payments", listPayments)
-		v1.POST("/payments/:id/process", processPayment)
+		v1.POST("/payments", common.RequireScopes(common.ScopePaymentsWrite), initiatePayment)
+		v1.GET("/payments/:id", common.RequireScopes(common.ScopePaymentsRead), getPaymentStatus)
+		v1.GET("/payments", common.RequireScopes(common.ScopePaymentsRead), listPayments)
+		v1.POST("/payments/:id/process", common.RequireScopes(common.ScopePaymentsWrite), processPayment)
+		v1.POST("/payments/:id/cancel", common.RequireScopes(common.ScopePaymentsWrite), cancelPayment)
+		v1.GET("/payments/:id/transitions", common.RequireScopes(common.ScopePaymentsRead), listPaymentTransitions)
+		v1.GET("/payments/:id/callbacks", common.RequireScopes(common.ScopePaymentsRead), listPaymentCallbacks)
+		v1.GET("/payment-callbacks/secret", common.RequireScopes(common.ScopePaymentsWrite), getPaymentCallbackSecret)
+		v1.GET("/consents/:id/payments", common.RequireScopes(common.ScopePaymentsRead), listConsentPayments)
+
+		// Circuit breakers and degradation modes of the services payments are checked against
+		v1.GET("/admin/dependencies", common.RequireScopes(common.ScopeOperations), listDependencies)
+
+		// Human funding fallback
+		v1.POST("/payments/:id/payment-links", common.RequireScopes(common.ScopePaymentsWrite), createPaymentLink)
+		v1.GET("/payments/:id/payment-links", common.RequireScopes(common.ScopePaymentsRead), listPaymentLinks)
+
+		// Payment description templates, managed by the owning party
+		v1.POST("/description-templates", common.RequireScopes(common.ScopePartiesWrite), createDescriptionTemplate)
+		v1.GET("/description-templates", common.RequireScopes(common.ScopePartiesRead), listDescriptionTemplates)
+		v1.GET("/description-templates/:id", common.RequireScopes(common.ScopePartiesRead), getDescriptionTemplate)
+		v1.PATCH("/description-templates/:id", common.RequireScopes(common.ScopePartiesWrite), updateDescriptionTemplate)
+		v1.DELETE("/description-templates/:id", common.RequireScopes(common.ScopePartiesWrite), deleteDescriptionTemplate)
+
+		// Workflow templates per tenant, set by operators
+		v1.POST("/workflow-templates", common.RequireScopes(common.ScopeOperations), createWorkflowTemplate)
+		v1.GET("/workflow-templates", common.RequireScopes(common.ScopePartiesRead), listWorkflowTemplates)
+		v1.GET("/workflow-templates/active", common.RequireScopes(common.ScopePartiesRead), getActiveWorkflowTemplate)
+		v1.GET("/workflow-templates/:id", common.RequireScopes(common.ScopePartiesRead), getWorkflowTemplate)
+		v1.POST("/workflow-templates/:id/activate", common.RequireScopes(common.ScopeOperations), activateWorkflowTemplate)
+		v1.POST("/workflow-templates/:id/deactivate", common.RequireScopes(common.ScopeOperations), deactivateWorkflowTemplate)
+
+		// Recurring payments an agent makes on a schedule
+		v1.POST("/payment-schedules", common.RequireScopes(common.ScopePaymentsWrite), createPaymentSchedule)
+		v1.GET("/payment-schedules", common.RequireScopes(common.ScopePaymentsRead), listPaymentSchedules)
+		v1.GET("/payment-schedules/:id", common.RequireScopes(common.ScopePaymentsRead), getPaymentSchedule)
+		v1.GET("/payment-schedules/:id/runs", common.RequireScopes(common.ScopePaymentsRead), listPaymentScheduleRuns)
+		v1.POST("/payment-schedules/:id/pause", common.RequireScopes(common.ScopePaymentsWrite), pausePaymentSchedule)
+		v1.POST("/payment-schedules/:id/resume", common.RequireScopes(common.ScopePaymentsWrite), resumePaymentSchedule)
+		v1.POST("/payment-schedules/:id/cancel", common.RequireScopes(common.ScopePaymentsWrite), cancelPaymentSchedule)
+
+		// What an agent's owner lets it spend per period and category
+		v1.POST("/budgets", common.RequireScopes(common.ScopePartiesWrite), createBudget)
+		v1.GET("/budgets", common.RequireScopes(common.ScopePaymentsRead), listBudgets)
+		v1.GET("/budgets/remaining", common.RequireScopes(common.ScopePaymentsRead), getRemainingBudget)
+		v1.GET("/budgets/:id", common.RequireScopes(common.ScopePaymentsRead), getBudget)
+		v1.PUT("/budgets/:id", common.RequireScopes(common.ScopePartiesWrite), updateBudget)
+		v1.DELETE("/budgets/:id", common.RequireScopes(common.ScopePartiesWrite), deleteBudget)
+		v1.GET("/budgets/:id/periods", common.RequireScopes(common.ScopePaymentsRead), listBudgetPeriods)
+
+		// Statement tokens shared with counterparties, managed by the owning party
+		v1.POST("/statement-tokens", common.RequireScopes(common.ScopePartiesWrite), createStatementToken)
+		v1.GET("/statement-tokens", common.RequireScopes(common.ScopePartiesRead), listStatementTokens)
+		v1.POST("/statement-tokens/:id/revoke", common.RequireScopes(common.ScopePartiesWrite), revokeStatementToken)
 
 		// Rail information
-		v1.GET("/rails", getAvailableRails)
-		v1.POST("/rails/select", selectRail)
+		v1.GET("/rails", common.RequireScopes(common.ScopePaymentsRead), getAvailableRails)
+		v1.POST("/rails/select", common.RequireScopes(common.ScopePaymentsRead), selectRail)
+
+		// Rail catalog, shared with the router
+		v1.POST("/rails", common.RequireScopes(common.ScopeOperations), createRail)
+		v1.GET("/rails/:rail", common.RequireScopes(common.ScopePaymentsRead), getRail)
+		v1.PUT("/rails/:rail", common.RequireScopes(common.ScopeOperations), updateRail)
+		v1.DELETE("/rails/:rail", common.RequireScopes(common.ScopeOperations), deleteRail)
+
+		// Quotes binding a payment's rail, fee and exchange rate until they expire
+		v1.POST("/quotes", common.RequireScopes(common.ScopePaymentsRead), createQuote)
+		v1.GET("/quotes/:id", common.RequireScopes(common.ScopePaymentsRead), getQuote)
+
+		// Fee markups per tenant and the revenue they earn
+		v1.POST("/fee-schedules", common.RequireScopes(common.ScopeOperations), createFeeSchedule)
+		v1.GET("/fee-schedules", common.RequireScopes(common.ScopeOperations), listFeeSchedules)
+		v1.GET("/fee-schedules/:id", common.RequireScopes(common.ScopeOperations), getFeeSchedule)
+		v1.PUT("/fee-schedules/:id", common.RequireScopes(common.ScopeOperations), updateFeeSchedule)
+		v1.DELETE("/fee-schedules/:id", common.RequireScopes(common.ScopeOperations), deleteFeeSchedule)
+		v1.POST("/fees/quote", common.RequireScopes(common.ScopePaymentsRead), quoteFee)
+		v1.GET("/fees/revenue", common.RequireScopes(common.ScopeOperations), getFeeRevenue)
+		v1.POST("/fee-experiments", common.RequireScopes(common.ScopeOperations), createFeeExperiment)
+		v1.GET("/fee-experiments", common.RequireScopes(common.ScopeOperations), listFeeExperiments)
+		v1.GET("/fee-experiments/:id", common.RequireScopes(common.ScopeOperations), getFeeExperiment)
+		v1.POST("/fee-experiments/:id/end", common.RequireScopes(common.ScopeOperations), endFeeExperiment)
+		v1.PUT("/fee-experiments/:id/assignments", common.RequireScopes(common.ScopeOperations), assignFeeVariant)
+		v1.GET("/fee-experiments/:id/results", common.RequireScopes(common.ScopeOperations), getFeeExperimentResults)
 	}
 
-	common.Info("Orchestration service running on :8084")
-	log.Fatal(r.Run(":8084"))
+	// Deliver the callbacks of payments reaching a final status
+	initPaymentCallbacks(server.Context())
+
+	// Resume or fail workflows left unfinished by a previous run
+	initWorkflowRecovery(server.Context())
+
+	// Make the payments of schedules as they fall due
+	initPaymentScheduler(server.Context())
+	initBudgetRollover(server.Context())
+
+	// Relay and consume events in-process when there is no broker
+	initInProcessEventing(server.Context(), cfg.Kafka.Topic)
+
+	// Let workflows being processed finish, or checkpoint them, before exiting
+	server.OnShutdown("payment workflows", workflowWorkers.Drain)
+
+	common.Info("Orchestration service running on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func initiatePayment(c *gin.Context) {
+	// Keep the raw body, which DID agents sign
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
 	var req PaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
@@ -142,17 +400,255 @@ func initiatePayment(c *gin.Context) {
 	}
 
 	// Validate required fields
-	if req.AgentID == "" || req.AmountUSD <= 0 || req.Counterparty == "" {
-		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, amountUSD, and counterparty are required"))
+	if req.AgentID == "" || (req.Amount <= 0 && req.AmountUSD <= 0) || (req.Counterparty == "" && req.CounterpartyID == "") {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, amount (or amountUSD), and counterparty (or counterpartyId) are required"))
+		return
+	}
+	if req.CounterpartyID == "" && requireCounterpartyIDs {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("COUNTERPARTY_ID_REQUIRED", "Payments must reference a counterparty by counterpartyId"))
+		return
+	}
+
+	// Resolve the payment currency; limits, consents and routing work on the USD equivalent
+	if _, err := resolvePaymentAmount(c.Request.Context(), &req); err != nil {
+		common.Warn("Rejected payment currency for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_CURRENCY", err.Error()))
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(c.Request.Context(), req.CallbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_CALLBACK_URL", err.Error()))
+			return
+		}
+	}
+
+	// Agents may only initiate payments on their own behalf
+	if !common.CanActForAgent(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot initiate payments for this agent"))
 		return
 	}
 
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
 
+	// Party principals may only use their own agents
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalParty && principal.PartyID != agent.OwnerPartyID {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot initiate payments for this agent"))
+		return
+	}
+
+	if err := resolveCounterparty(agent, &req); err != nil {
+		c.JSON(err.status, common.NewErrorResponse(err.code, err.message))
+		return
+	}
+	if err := validateInternational(&req); err != nil {
+		c.JSON(err.status, common.NewErrorResponse(err.code, err.message))
+		return
+	}
+
+	// Make the payment under its quote, which fixes the USD amount signed
+	// in mandates and checked against limits
+	if err := applyQuote(&req); err != nil {
+		c.JSON(err.status, common.NewErrorResponse(err.code, err.message))
+		return
+	}
+
+	// Verify the agent's own credential when one is presented or required
+	if credential := c.GetHeader(AgentCredentialHeader); credential != "" || requireAgentCredentials {
+		if err := verifyAgentCredential(c.Request.Context(), req.AgentID, credential); err != nil {
+			common.Warn("Rejected payment for agent %s: %v", req.AgentID, err)
+			c.JSON(http.StatusUnauthorized, common.NewErrorResponse("INVALID_AGENT_CREDENTIAL", err.Error()))
+			return
+		}
+	}
+
+	// Verify a DID agent's signature of the request when one is presented or required
+	var signature *did.Verification
+	if header := c.GetHeader(did.SignatureHeader); header != "" || (requireAgentSignatures && agent.IdentityMode == "did") {
+		signature, err = verifyAgentSignature(c, agent, header, body)
+		if err != nil {
+			common.Warn("Rejected payment signature of agent %s: %v", req.AgentID, err)
+			c.JSON(http.StatusUnauthorized, common.NewErrorResponse("INVALID_AGENT_SIGNATURE", err.Error()))
+			return
+		}
+	}
+
+	// Verify the agent's signed mandate before any risk or consent checks
+	var signedMandate *database.Mandate
+	if req.Mandate != nil || requireMandates {
+		if req.Mandate == nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("MANDATE_REQUIRED", "A signed mandate is required"))
+			return
+		}
+
+		// Mandates are denominated in USD, so non-USD payments sign the USD equivalent
+		intent, err := mandate.IntentFromProof(req.AgentID, req.AmountUSD, req.Counterparty, req.Mandate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+			return
+		}
+
+		signedMandate, err = mandateVerifier.VerifyAndRecord(intent, req.Mandate)
+		if err != nil {
+			common.Warn("Rejected mandate for agent %s: %v", req.AgentID, err)
+			c.JSON(http.StatusUnauthorized, common.NewErrorResponse("INVALID_MANDATE", err.Error()))
+			return
+		}
+	}
+
+	workflow, perr := newPaymentWorkflow(agent, &req)
+	if perr != nil {
+		c.JSON(perr.status, common.NewErrorResponse(perr.code, perr.message))
+		return
+	}
+	if signedMandate != nil {
+		workflow.MandateID = signedMandate.ID
+	}
+
+	err = createPaymentWorkflow(c.Request.Context(), workflow, principalSubject(c), func(trail *audit.AuditTrail) error {
+		entry := paymentInitiatedAuditEntry(workflow)
+		if signature != nil {
+			entry.Metadata = map[string]interface{}{"didVerification": signature.Map()}
+		}
+		return audit.Record(c, trail, entry)
+	})
+	if errors.Is(err, errQuoteUnavailable) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("QUOTE_UNAVAILABLE", "Quote was used or expired meanwhile; request a new one"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to initiate payment for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create payment workflow"))
+		return
+	}
+
+	// Link the mandate back to the workflow it authorized
+	if signedMandate != nil {
+		signedMandate.WorkflowID = workflow.ID
+		if err := repo.MandateRepository().Update(signedMandate); err != nil {
+			common.Error("Failed to link mandate %s to workflow %s: %v", signedMandate.ID, workflow.ID, err)
+		}
+	}
+
+	// Convert to API response format
+	response := toPaymentResponse(workflow)
+
+	common.Info("Payment workflow initiated: %s for agent %s using rail %s", workflow.ID, req.AgentID, workflow.Rail)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+// paymentError is a payment request refused with a status and error code
+type paymentError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *paymentError) Error() string {
+	return e.message
+}
+
+// resolveCounterparty checks that a referenced counterparty is one of the
+// owner party's that its agents may pay, and names the payee after it
+func resolveCounterparty(agent *database.Agent, req *PaymentRequest) *paymentError {
+	if req.CounterpartyID == "" {
+		return nil
+	}
+	counterparty, err := repo.CounterpartyRepository().GetByID(req.CounterpartyID)
+	if err != nil {
+		return &paymentError{http.StatusBadRequest, "VALIDATION_ERROR", "Counterparty not found"}
+	}
+	if refusal := counterparties.Refusal(counterparty, agent.OwnerPartyID); refusal != "" {
+		return &paymentError{http.StatusForbidden, "COUNTERPARTY_REFUSED", refusal}
+	}
+	req.Counterparty = counterparty.Name
+	return nil
+}
+
+// newPaymentWorkflow builds the workflow of a validated payment request:
+// its rail, description, fee quote and workflow template. Payments made
+// through the API and by schedules are built the same way.
+func newPaymentWorkflow(agent *database.Agent, req *PaymentRequest) (*database.PaymentWorkflow, *paymentError) {
+	// Suspended and decommissioned agents cannot pay
+	if refusal := agentstate.Refusal(agent); refusal != "" {
+		return nil, &paymentError{status: http.StatusForbidden, code: "AGENT_NOT_ACTIVE", message: refusal}
+	}
+
+	// Agents of parties that are not verified pay only up to a limit
+	if !kyc.Allows(agent.OwnerParty.KYCStatus, req.AmountUSD, kycUnverifiedLimitUSD) {
+		return nil, &paymentError{status: http.StatusForbidden, code: "KYC_REQUIRED", message: fmt.Sprintf("Owner party is %s; payments above %.2f USD require KYC verification", kyc.Of(agent.OwnerParty.KYCStatus), kycUnverifiedLimitUSD)}
+	}
+
+	selectedRail, perr := selectPaymentRail(req)
+	if perr != nil {
+		return nil, perr
+	}
+
+	// Apply the owner party's description template for the rail
+	description, err := describePayment(agent, *req, selectedRail)
+	if err != nil {
+		common.Error("Failed to describe payment for agent %s: %v", req.AgentID, err)
+		return nil, &paymentError{http.StatusUnprocessableEntity, "DESCRIPTION_TEMPLATE_ERROR", err.Error()}
+	}
+
+	// Quote the fee now so a payment under a fee experiment is settled under
+	// the variant it was quoted under. Payments made under a quote keep its fee.
+	var feeQuote fees.Quote
+	if req.quote != nil {
+		feeQuote = quotedFee(req.quote)
+	} else {
+		feeQuote, err = quoteAgentFee(req.AgentID, selectedRail, req.AmountUSD)
+		if err != nil {
+			common.Error("Failed to quote fee for agent %s: %v", req.AgentID, err)
+			return nil, &paymentError{http.StatusInternalServerError, "DATABASE_ERROR", "Failed to quote payment fee"}
+		}
+		feeQuote = withCorrespondentFee(feeQuote, req.International)
+	}
+
+	// Create payment workflow
+	workflow := &database.PaymentWorkflow{
+		AgentID:       req.AgentID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		AmountUSD:     req.AmountUSD,
+		Counterparty:  req.Counterparty,
+		Rail:          selectedRail,
+		Description:   description,
+		Metadata:      encodeMetadata(req.Metadata),
+		Status:        "pending",
+		Steps:         "[]", // Will be populated with workflow steps
+		CallbackURL:   req.CallbackURL,
+		Category:      req.Category,
+		International: international.Encode(req.International),
+	}
+	if req.CounterpartyID != "" {
+		workflow.CounterpartyID = &req.CounterpartyID
+	}
+	if req.quote != nil {
+		workflow.QuoteID = &req.quote.ID
+	}
+	if err := applyWorkflowTemplate(workflow, agent.OwnerPartyID); err != nil {
+		common.Error("Failed to load workflow template for agent %s: %v", req.AgentID, err)
+		return nil, &paymentError{http.StatusInternalServerError, "DATABASE_ERROR", "Failed to load workflow template"}
+	}
+	if feeQuote.VariantID != "" {
+		workflow.FeeExperimentID = &feeQuote.ExperimentID
+		workflow.FeeVariantID = &feeQuote.VariantID
+	}
+	return workflow, nil
+}
+
+// selectPaymentRail returns the rail a payment request names, once validated
+// for its amount and payee, or else the best rail for it. Payments abroad
+// only go on rails that send international payments.
+func selectPaymentRail(req *PaymentRequest) (string, *paymentError) {
+	abroad := international.IsInternational(req.International)
+
 	// Handle rail selection - auto-select if not provided
 	selectedRail := req.Rail
 	if selectedRail == "" {
@@ -187,56 +683,135 @@ func initiatePayment(c *gin.Context) {
 			}
 		}
 
+		if abroad {
+			if prefs == nil {
+				prefs = &types.RailPreferences{}
+			}
+			prefs.International = true
+		}
+
 		// Auto-select the best rail
-		rail, _, err := railSelector.SelectRail(req.AmountUSD, req.Counterparty, prefs)
+		rail, _, err := railCatalog.Selector().SelectRail(req.AmountUSD, req.Counterparty, prefs)
 		if err != nil {
 			common.Error("Failed to select rail for amount %.2f: %v", req.AmountUSD, err)
-			c.JSON(http.StatusBadRequest, common.NewErrorResponse("RAIL_SELECTION_ERROR", fmt.Sprintf("No suitable rail found: %v", err)))
-			return
+			return "", &paymentError{http.StatusBadRequest, "RAIL_SELECTION_ERROR", fmt.Sprintf("No suitable rail found: %v", err)}
 		}
 		selectedRail = string(rail)
 		common.Info("Auto-selected rail %s for payment amount %.2f", selectedRail, req.AmountUSD)
 	} else {
 		// Validate manually specified rail
-		if err := railSelector.ValidateRail(types.PaymentRail(selectedRail), req.AmountUSD); err != nil {
-			c.JSON(http.StatusBadRequest, common.NewErrorResponse("RAIL_VALIDATION_ERROR", err.Error()))
-			return
+		selector := railCatalog.Selector()
+		if err := selector.ValidateRail(types.PaymentRail(selectedRail), req.AmountUSD); err != nil {
+			return "", &paymentError{http.StatusBadRequest, "RAIL_VALIDATION_ERROR", err.Error()}
+		}
+		if err := selector.ValidateCounterparty(types.PaymentRail(selectedRail), req.Counterparty); err != nil {
+			return "", &paymentError{http.StatusBadRequest, "RAIL_VALIDATION_ERROR", err.Error()}
+		}
+		if abroad {
+			if err := selector.ValidateInternational(types.PaymentRail(selectedRail)); err != nil {
+				return "", &paymentError{http.StatusBadRequest, "RAIL_VALIDATION_ERROR", err.Error()}
+			}
 		}
 	}
 
-	// Create payment workflow
-	workflow := &database.PaymentWorkflow{
-		AgentID:      req.AgentID,
-		AmountUSD:    req.AmountUSD,
-		Counterparty: req.Counterparty,
-		Rail:         selectedRail,
-		Description:  req.Description,
-		Status:       "pending",
-		Steps:        "[]", // Will be populated with workflow steps
+	return selectedRail, nil
+}
+
+// createPaymentWorkflow stores a new workflow. The workflow, its initial
+// transition, its audit entry and its payment.initiated event commit
+// together, so consumers never see a payment without its trail or a trail
+// without its payment.
+func createPaymentWorkflow(ctx context.Context, workflow *database.PaymentWorkflow, actor string, recordAudit func(trail *audit.AuditTrail) error) error {
+	return repo.Transaction(func(tx database.Repository) error {
+		if err := tx.PaymentWorkflowRepository().Create(workflow); err != nil {
+			return fmt.Errorf("failed to create payment workflow: %v", err)
+		}
+		if workflow.QuoteID != nil {
+			used, err := tx.QuoteRepository().Use(*workflow.QuoteID, workflow.ID, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to use quote %s: %v", *workflow.QuoteID, err)
+			}
+			if !used {
+				return errQuoteUnavailable
+			}
+		}
+		if err := workflowStates.WithRepository(tx).Created(workflow, actor); err != nil {
+			return fmt.Errorf("failed to record creation of workflow %s: %v", workflow.ID, err)
+		}
+		if err := recordAudit(audit.NewAuditTrail(tx)); err != nil {
+			return fmt.Errorf("failed to audit workflow %s: %v", workflow.ID, err)
+		}
+		if err := eventPublisher.PublishEventTx(ctx, tx, paymentInitiatedEvent(workflow, actor)); err != nil {
+			return fmt.Errorf("failed to publish initiation of workflow %s: %v", workflow.ID, err)
+		}
+		return nil
+	})
+}
+
+// paymentInitiatedAuditEntry is the audit entry recorded with a new workflow
+func paymentInitiatedAuditEntry(workflow *database.PaymentWorkflow) *audit.AuditEntry {
+	return &audit.AuditEntry{
+		EventType:    audit.AuditPaymentInitiated,
+		Severity:     audit.SeverityMedium,
+		AgentID:      workflow.AgentID,
+		ResourceID:   workflow.ID,
+		ResourceType: "payment",
+		Action:       "initiate",
+		Description:  fmt.Sprintf("Payment of %.2f %s to %s initiated on %s", workflow.Amount, workflow.Currency, workflow.Counterparty, workflow.Rail),
+		NewValues: map[string]interface{}{
+			"amount":       workflow.Amount,
+			"currency":     workflow.Currency,
+			"amountUSD":    workflow.AmountUSD,
+			"counterparty": workflow.Counterparty,
+			"rail":         workflow.Rail,
+			"status":       workflow.Status,
+			"mandateId":    workflow.MandateID,
+		},
 	}
+}
 
-	if err := repo.PaymentWorkflowRepository().Create(workflow); err != nil {
-		common.Error("Failed to create payment workflow: %v", err)
-		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create payment workflow"))
-		return
+// paymentInitiatedEvent is the payment.initiated event published with a new
+// workflow, carrying the fields of events.PaymentInitiatedEventData
+func paymentInitiatedEvent(workflow *database.PaymentWorkflow, actor string) *events.Event {
+	event := events.NewEvent(events.EventPaymentInitiated, workflow.ID, "payment", map[string]interface{}{
+		"paymentId":    workflow.ID,
+		"agentId":      workflow.AgentID,
+		"amount":       workflow.Amount,
+		"currency":     workflow.Currency,
+		"amountUSD":    workflow.AmountUSD,
+		"counterparty": workflow.Counterparty,
+		"rail":         workflow.Rail,
+		"description":  workflow.Description,
+	})
+	event.Metadata.Source = "orchestration"
+	event.Metadata.UserID = actor
+	return event
+}
+
+// resolvePaymentAmount fills in Amount, Currency and the USD equivalent of a
+// payment request, returning the conversion to USD. Requests that only carry
+// amountUSD are treated as USD.
+func resolvePaymentAmount(ctx context.Context, req *PaymentRequest) (*fx.Conversion, error) {
+	currency, err := fx.NormalizeCurrency(req.Currency)
+	if err != nil {
+		return nil, err
 	}
+	req.Currency = currency
 
-	// Convert to API response format
-	response := &types.PaymentWorkflow{
-		ID:           workflow.ID,
-		AgentID:      workflow.AgentID,
-		AmountUSD:    workflow.AmountUSD,
-		Counterparty: workflow.Counterparty,
-		Rail:         workflow.Rail,
-		Description:  workflow.Description,
-		Status:       workflow.Status,
-		Steps:        []types.WorkflowStep{}, // Would deserialize from workflow.Steps JSON in production
-		CreatedAt:    workflow.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    workflow.UpdatedAt.Format(time.RFC3339),
+	if req.Amount <= 0 {
+		if currency != fx.BaseCurrency {
+			return nil, fmt.Errorf("amount is required for %s payments", currency)
+		}
+		req.Amount = req.AmountUSD
 	}
+	req.Amount = fx.RoundAmount(req.Amount)
 
-	common.Info("Payment workflow initiated: %s for agent %s using rail %s", workflow.ID, req.AgentID, selectedRail)
-	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+	conversion, err := converter.Convert(ctx, req.Amount, currency, fx.BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	req.AmountUSD = conversion.ToAmount
+	return conversion, nil
 }
 
 func getPaymentStatus(c *gin.Context) {
@@ -247,39 +822,112 @@ func getPaymentStatus(c *gin.Context) {
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
 		return
 	}
+	// Agents only reach their own payments
+	if !common.CanActForAgent(c, workflow.AgentID) || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
 
 	// Convert to API response format
-	response := &types.PaymentWorkflow{
-		ID:           workflow.ID,
-		AgentID:      workflow.AgentID,
-		AmountUSD:    workflow.AmountUSD,
-		Counterparty: workflow.Counterparty,
-		Rail:         workflow.Rail,
-		Description:  workflow.Description,
-		Status:       workflow.Status,
-		Steps:        []types.WorkflowStep{}, // Would deserialize from workflow.Steps JSON in production
-		CreatedAt:    workflow.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    workflow.UpdatedAt.Format(time.RFC3339),
-	}
+	response := toPaymentResponse(workflow)
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
-func listPayments(c *gin.Context) {
-	agentID := c.Query("agentId")
-	status := c.Query("status")
+// workflowInTenant reports whether a payment belongs to the caller's tenant.
+// Attempts to reach another tenant's payments are audited.
+func workflowInTenant(c *gin.Context, workflow *database.PaymentWorkflow) bool {
+	return tenancy.Check(c, repo, workflow.Agent.OwnerPartyID, "payment", workflow.ID)
+}
 
-	var workflows []*database.PaymentWorkflow
-	var err error
+// WorkflowTransitionResponse is one entry of a payment's status history
+type WorkflowTransitionResponse struct {
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	Reason    string `json:"reason,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
 
-	if agentID != "" {
-		workflows, err = repo.PaymentWorkflowRepository().ListByAgentID(agentID)
-	} else if status != "" {
-		workflows, err = repo.PaymentWorkflowRepository().ListByStatus(status)
-	} else {
-		workflows, err = repo.PaymentWorkflowRepository().List()
+// listPaymentTransitions returns a payment's status history, oldest first
+func listPaymentTransitions(c *gin.Context) {
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, workflow.AgentID) || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
 	}
 
+	transitions, err := repo.WorkflowTransitionRepository().ListByWorkflowID(workflow.ID)
+	if err != nil {
+		common.Error("Failed to list transitions of workflow %s: %v", workflow.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list transitions"))
+		return
+	}
+
+	items := []interface{}{}
+	for _, transition := range transitions {
+		items = append(items, &WorkflowTransitionResponse{
+			From:      transition.FromStatus,
+			To:        transition.ToStatus,
+			Reason:    transition.Reason,
+			Actor:     transition.Actor,
+			CreatedAt: transition.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// listPayments lists a page of payments, filtered by agent, status, rail,
+// counterparty and creation time. Auditors look payments up by the evidence
+// they went ahead on, or by whether a check was degraded.
+func listPayments(c *gin.Context) {
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	filter := database.PaymentWorkflowFilter{
+		AgentID:               c.Query("agentId"),
+		Status:                c.Query("status"),
+		Rail:                  c.Query("rail"),
+		Counterparty:          c.Query("counterparty"),
+		ConsentID:             c.Query("consentId"),
+		RiskDecisionID:        c.Query("riskDecisionId"),
+		ComplianceScreeningID: c.Query("complianceScreeningId"),
+		ScheduleID:            c.Query("scheduleId"),
+		Category:              c.Query("category"),
+		TenantID:              common.RequestTenant(c),
+	}
+	// Agents list only their own payments
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalAgent {
+		if filter.AgentID == "" {
+			filter.AgentID = principal.AgentID
+		}
+		if !common.CanActForAgent(c, filter.AgentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list payments of this agent"))
+			return
+		}
+	}
+	if filter.AgentID != "" && filter.TenantID != "" {
+		agent, err := repo.AgentRepository().GetByID(filter.AgentID)
+		if err == nil && !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list payments of this agent"))
+			return
+		}
+	}
+	if value := c.Query("degraded"); value != "" {
+		degraded, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "degraded must be true or false"))
+			return
+		}
+		filter.Degraded = &degraded
+	}
+
+	workflows, total, err := repo.PaymentWorkflowRepository().ListPage(filter, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to list payment workflows: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment workflows"))
@@ -289,21 +937,10 @@ func listPayments(c *gin.Context) {
 	// Convert to API response format
 	var result []*types.PaymentWorkflow
 	for _, wf := range workflows {
-		result = append(result, &types.PaymentWorkflow{
-			ID:           wf.ID,
-			AgentID:      wf.AgentID,
-			AmountUSD:    wf.AmountUSD,
-			Counterparty: wf.Counterparty,
-			Rail:         wf.Rail,
-			Description:  wf.Description,
-			Status:       wf.Status,
-			Steps:        []types.WorkflowStep{}, // Would deserialize from wf.Steps JSON in production
-			CreatedAt:    wf.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:    wf.UpdatedAt.Format(time.RFC3339),
-		})
+		result = append(result, toPaymentResponse(wf))
 	}
 
-	response := common.NewListResponse(make([]interface{}, len(result)), 1, 10, len(result))
+	response := common.NewPageResponse(make([]interface{}, len(result)), params, total)
 	for i, wf := range result {
 		response.Items[i] = wf
 	}
@@ -311,6 +948,39 @@ func listPayments(c *gin.Context) {
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// listConsentPayments lists a page of the payments that went ahead under a
+// consent version, optionally with one status (?status=). Payments a
+// revocation moved to another consent are listed under that one.
+func listConsentPayments(c *gin.Context) {
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	consent, err := repo.ConsentRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, consent.AgentID) || !tenancy.Check(c, repo, consent.OwnerPartyID, "consent", consent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Consent not found"))
+		return
+	}
+
+	filter := database.PaymentWorkflowFilter{ConsentID: consent.ID, Status: c.Query("status")}
+	workflows, total, err := repo.PaymentWorkflowRepository().ListPage(filter, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list payments under consent %s: %v", consent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment workflows"))
+		return
+	}
+
+	items := make([]interface{}, len(workflows))
+	for i, workflow := range workflows {
+		items[i] = toPaymentResponse(workflow)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewPageResponse(items, params, total)))
+}
+
 func processPayment(c *gin.Context) {
 	id := c.Param("id")
 
@@ -321,23 +991,43 @@ func processPayment(c *gin.Context) {
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
 		return
 	}
+	// Agents only reach their own payments
+	if !common.CanActForAgent(c, workflow.AgentID) || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
 
 	// Only process if status is pending
-	if workflow.Status != "pending" {
+	if workflow.Status != string(workflowstate.Pending) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_STATUS", "Payment workflow is not in pending status"))
 		return
 	}
 
 	// Update status to processing
-	workflow.Status = "processing"
-	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+	if err := workflowStates.Transition(workflow, workflowstate.Processing, "Processing requested", principalSubject(c)); err != nil {
+		if errors.Is(err, workflowstate.ErrConcurrentTransition) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", err.Error()))
+			return
+		}
 		common.Error("Failed to update payment workflow status: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update workflow status"))
 		return
 	}
 
-	// Process the payment workflow asynchronously
-	go processPaymentWorkflow(workflow)
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditPaymentProcessing,
+		Severity:     audit.SeverityMedium,
+		AgentID:      workflow.AgentID,
+		ResourceID:   workflow.ID,
+		ResourceType: "payment",
+		Action:       "process",
+		Description:  "Payment processing requested",
+		OldValues:    map[string]interface{}{"status": workflowstate.Pending},
+		NewValues:    map[string]interface{}{"status": workflow.Status},
+	})
+
+	// Process the payment workflow asynchronously, in the request's trace
+	startWorkflow(context.WithoutCancel(c.Request.Context()), workflow)
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(map[string]string{
 		"message":    "Payment processing started",
@@ -345,82 +1035,360 @@ func processPayment(c *gin.Context) {
 	}))
 }
 
-func processPaymentWorkflow(workflow *database.PaymentWorkflow) {
-	common.Info("Starting payment processing for workflow %s", workflow.ID)
+// workflowStage is one step of payment processing. Completed stages are
+// recorded in the workflow's steps, so a recovered workflow resumes after them.
+type workflowStage struct {
+	name    string
+	failure string // Status message when the stage fails
+	run     func(workflow *database.PaymentWorkflow) error
+	unsafe  bool // Not repeated after an interruption, since it may have moved money
+}
 
-	// Step 1: Risk Evaluation
-	if err := performRiskEvaluation(workflow); err != nil {
-		common.Error("Risk evaluation failed for workflow %s: %v", workflow.ID, err)
-		updateWorkflowStatus(workflow, "failed", "Risk evaluation failed")
-		return
+// workflowStages are the stages workflow templates can order, and the
+// budget check planned before execution
+var workflowStages = []workflowStage{
+	{name: workflowtemplate.StepMandateCheck, failure: "Mandate check failed", run: checkMandate},
+	{name: workflowtemplate.StepRiskEvaluation, failure: "Risk evaluation failed", run: performRiskEvaluation},
+	{name: workflowtemplate.StepConsentValidation, failure: "Consent validation failed", run: performConsentValidation},
+	{name: workflowtemplate.StepComplianceCheck, failure: "Compliance check failed", run: performComplianceCheck},
+	{name: workflowtemplate.StepTaxCheck, failure: "Tax check failed"},
+	{name: budgetCheckStage, failure: "Budget check failed", run: performBudgetCheck},
+	{name: workflowtemplate.StepExecution, failure: "Payment execution failed", run: executePayment, unsafe: true},
+}
+
+// startWorkflow processes a workflow in a tracked worker, so shutdown waits
+// for it. A workflow not started because the service is shutting down is
+// left for recovery.
+func startWorkflow(ctx context.Context, workflow *database.PaymentWorkflow) {
+	started := workflowWorkers.Go(workflow.ID, func(stop context.Context) {
+		processPaymentWorkflow(ctx, stop, workflow)
+	})
+	if !started {
+		common.Warn("Shutting down, leaving workflow %s for recovery", workflow.ID)
 	}
+}
 
-	// Step 2: Consent Validation
-	if err := performConsentValidation(workflow); err != nil {
-		common.Error("Consent validation failed for workflow %s: %v", workflow.ID, err)
-		updateWorkflowStatus(workflow, "failed", "Consent validation failed")
+// processPaymentWorkflow runs the workflow's stages, each in a span under
+// ctx. The calls a stage makes to other services take its span from
+// workflowContext. Once stop is done the workflow is checkpointed before its
+// next stage, or while a stage is waiting on a review, and left for recovery.
+func processPaymentWorkflow(ctx context.Context, stop context.Context, workflow *database.PaymentWorkflow) {
+	if !trackWorkflow(workflow.ID) {
+		common.Warn("Workflow %s is already being processed", workflow.ID)
 		return
 	}
+	defer untrackWorkflow(workflow.ID)
+
+	ctx, span := common.StartSpan(ctx, "payment.workflow",
+		attribute.String("payment.id", workflow.ID),
+		attribute.String("agent.id", workflow.AgentID),
+		attribute.String("payment.rail", workflow.Rail),
+	)
+	defer span.End()
+
+	statuses := stageStatuses(workflow)
+	if len(statuses) > 0 {
+		common.Info("Resuming payment processing for workflow %s", workflow.ID)
+	} else {
+		common.Info("Starting payment processing for workflow %s", workflow.ID)
+	}
 
-	// Step 3: Compliance Check (placeholder)
-	if err := performComplianceCheck(workflow); err != nil {
-		common.Error("Compliance check failed for workflow %s: %v", workflow.ID, err)
-		updateWorkflowStatus(workflow, "failed", "Compliance check failed")
+	plan, err := workflowPlan(workflow)
+	if err != nil {
+		common.Error("Failed to plan workflow %s: %v", workflow.ID, err)
+		updateWorkflowStatus(workflow, workflowstate.Failed, "Workflow template unavailable")
 		return
 	}
 
-	// Step 4: Payment Execution (placeholder)
-	if err := executePayment(workflow); err != nil {
-		common.Error("Payment execution failed for workflow %s: %v", workflow.ID, err)
-		updateWorkflowStatus(workflow, "failed", "Payment execution failed")
-		return
+	for _, stage := range plan {
+		if statuses[stage.name] == "completed" || statuses[stage.name] == "skipped" {
+			continue
+		}
+		if stage.skip != "" {
+			appendWorkflowStep(workflow, stage.name, "skipped", stage.skip)
+			continue
+		}
+		if workflowCancelled(workflow) {
+			return
+		}
+		if stop.Err() != nil {
+			checkpointWorkflow(workflow, stage.name)
+			return
+		}
+		if stage.unsafe {
+			appendWorkflowStep(workflow, stage.name, "running", "Started")
+			if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+				common.Error("Failed to record start of %s for workflow %s: %v", stage.name, workflow.ID, err)
+				return
+			}
+		}
+		stageCtx, stageSpan := common.StartSpan(ctx, "payment.stage."+stage.name, attribute.String("payment.id", workflow.ID))
+		setWorkflowContext(workflow.ID, stageCtx)
+		err := stage.run(workflow)
+		common.EndSpan(stageSpan, err)
+		if errors.Is(err, errWorkflowInterrupted) {
+			checkpointWorkflow(workflow, stage.name)
+			return
+		}
+		if err != nil {
+			common.Error("%s for workflow %s: %v", stage.failure, workflow.ID, err)
+			appendWorkflowStep(workflow, stage.name, "failed", err.Error())
+			updateWorkflowStatus(workflow, workflowstate.Failed, stage.failure)
+			return
+		}
+		if workflowCancelled(workflow) {
+			return
+		}
+		appendWorkflowStep(workflow, stage.name, "completed", "")
+		workflow.UpdatedAt = time.Now()
+		if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+			common.Error("Failed to record %s for workflow %s: %v", stage.name, workflow.ID, err)
+		}
 	}
 
 	// Mark as completed
-	updateWorkflowStatus(workflow, "completed", "Payment processed successfully")
+	updateWorkflowStatus(workflow, workflowstate.Completed, "Payment processed successfully")
 	common.Info("Payment processing completed for workflow %s", workflow.ID)
 }
 
+// checkMandate verifies the signed mandate recorded for the workflow
+func checkMandate(workflow *database.PaymentWorkflow) error {
+	if workflow.MandateID != "" {
+		return mandateVerifier.CheckRecorded(workflow)
+	}
+	if requireMandates {
+		return fmt.Errorf("workflow has no signed mandate")
+	}
+	return nil
+}
+
 func performRiskEvaluation(workflow *database.PaymentWorkflow) error {
 	common.Info("Performing risk evaluation for workflow %s", workflow.ID)
 
 	// Call Risk Service
-	riskRequest := map[string]interface{}{
-		"agentId":      workflow.AgentID,
-		"amountUSD":    workflow.AmountUSD,
-		"counterparty": workflow.Counterparty,
-		"rail":         workflow.Rail,
+	riskRequest := &clients.RiskEvaluationRequest{
+		AgentID:      workflow.AgentID,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Rail:         workflow.Rail,
+		WorkflowID:   workflow.ID,
+	}
+	if workflow.CounterpartyID != nil {
+		riskRequest.CounterpartyID = *workflow.CounterpartyID
 	}
 
-	riskResponse, err := callService("http://localhost:8083/v1/risk/evaluate", riskRequest)
+	riskResponse, err := callDegradable(workflow, riskDependency, func() (*common.APIResponse, error) {
+		return riskClient.Evaluate(workflowContext(workflow), riskRequest)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to call risk service: %v", err)
+		return fmt.Errorf("failed to call risk service: %w", err)
+	}
+	if riskResponse == nil {
+		// Skipped under fail_open; the workflow records why
+		return repo.PaymentWorkflowRepository().Update(workflow)
 	}
 
 	// Parse risk evaluation result
-	riskData := riskResponse.Data.(map[string]interface{})
-	decision := riskData["decision"].(string)
-	score := riskData["score"].(float64)
-	reason := riskData["reason"].(string)
+	var decision RiskDecision
+	if err := decodeData(riskResponse, &decision); err != nil {
+		return fmt.Errorf("invalid risk service response: %v", err)
+	}
 
 	// Check if payment should be blocked based on risk decision
-	if decision == "deny" {
-		return fmt.Errorf("payment denied by risk evaluation: %s", reason)
+	if decision.Decision == "deny" {
+		return fmt.Errorf("payment denied by risk evaluation: %s", decision.Reason)
 	}
 
-	// For review decisions, we could implement approval workflow
-	if decision == "review" {
-		common.Warn("Payment %s requires manual review: %s", workflow.ID, reason)
-		// In production, this would trigger approval workflow
+	// Review decisions hold the payment until a reviewer decides its case
+	if decision.Decision == "review" {
+		common.Warn("Payment %s held for manual review in case %s: %s", workflow.ID, decision.CaseID, decision.Reason)
+		if decision.CaseID == "" {
+			return fmt.Errorf("risk review required but no review case was opened")
+		}
+		status, err := awaitRiskReview(workflow, decision.CaseID)
+		if err != nil {
+			return err
+		}
+		if status != "approved" {
+			return fmt.Errorf("payment %s in risk review (case %s)", status, decision.CaseID)
+		}
 	}
 
 	// Store risk decision in workflow
-	workflow.RiskDecision = "{}" // Would serialize riskResponse.Data to JSON
+	if data, err := json.Marshal(decision); err == nil {
+		workflow.RiskDecision = string(data)
+	}
+	workflow.RiskDecisionID = evidenceID(decision.ID)
 
-	common.Info("Risk evaluation completed for workflow %s: %s (score: %.2f)", workflow.ID, decision, score)
+	common.Info("Risk evaluation completed for workflow %s: %s (score: %.2f)", workflow.ID, decision.Decision, decision.Score)
 	return repo.PaymentWorkflowRepository().Update(workflow)
 }
 
+// awaitRiskReview polls a review case until a reviewer decides it
+func awaitRiskReview(workflow *database.PaymentWorkflow, caseID string) (string, error) {
+	deadline := time.Now().Add(riskReviewTimeout)
+	for time.Now().Before(deadline) {
+		if err := pauseWorkflow(complianceReviewPollInterval); err != nil {
+			return "", err
+		}
+		if workflowCancelled(workflow) {
+			return "", fmt.Errorf("workflow cancelled during risk review")
+		}
+
+		response, err := riskClient.GetCase(workflowContext(workflow), caseID)
+		if err != nil {
+			common.Warn("Failed to check risk review case %s: %v", caseID, err)
+			continue
+		}
+		var reviewCase struct {
+			Status string `json:"status"`
+		}
+		if err := decodeData(response, &reviewCase); err == nil && reviewCase.Status != "pending" {
+			return reviewCase.Status, nil
+		}
+	}
+	return "", fmt.Errorf("risk review of case %s timed out", caseID)
+}
+
+// evidenceID returns the ID to link a payment to, or nil when a service
+// returned none
+func evidenceID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
+
+// toPaymentResponse converts a workflow to its API response, with the steps,
+// risk decision and consent check recorded as it was processed
+func toPaymentResponse(workflow *database.PaymentWorkflow) *types.PaymentWorkflow {
+	payment := &types.PaymentWorkflow{
+		ID:           workflow.ID,
+		AgentID:      workflow.AgentID,
+		Amount:       workflow.Amount,
+		Currency:     workflow.Currency,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Rail:         workflow.Rail,
+		Description:  workflow.Description,
+		Metadata:     decodeMetadata(workflow.Metadata),
+		Status:       workflow.Status,
+		Steps:        workflowSteps(workflow),
+		RiskDecision: workflowRiskDecision(workflow),
+		ConsentCheck: workflowConsentCheck(workflow),
+		MandateID:    workflow.MandateID,
+		CallbackURL:  workflow.CallbackURL,
+		CreatedAt:    workflow.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    workflow.UpdatedAt.Format(time.RFC3339),
+	}
+	setEvidenceIDs(payment, workflow)
+	return payment
+}
+
+// workflowSteps decodes the steps recorded for a workflow, oldest first
+func workflowSteps(workflow *database.PaymentWorkflow) []types.WorkflowStep {
+	steps := []types.WorkflowStep{}
+	if workflow.Steps != "" {
+		if err := json.Unmarshal([]byte(workflow.Steps), &steps); err != nil {
+			common.Warn("Workflow %s has unreadable steps: %v", workflow.ID, err)
+		}
+	}
+	return steps
+}
+
+// workflowRiskDecision decodes the risk decision the workflow went ahead on;
+// nil before risk evaluation or when it was skipped
+func workflowRiskDecision(workflow *database.PaymentWorkflow) *types.RiskDecision {
+	if workflow.RiskDecision == "" {
+		return nil
+	}
+	var stored RiskDecision
+	if err := json.Unmarshal([]byte(workflow.RiskDecision), &stored); err != nil {
+		common.Warn("Workflow %s has an unreadable risk decision: %v", workflow.ID, err)
+		return nil
+	}
+	return &types.RiskDecision{
+		ID:             stored.ID,
+		AgentID:        workflow.AgentID,
+		AmountUSD:      workflow.AmountUSD,
+		Counterparty:   workflow.Counterparty,
+		Rail:           workflow.Rail,
+		Decision:       stored.Decision,
+		Score:          stored.Score,
+		Reason:         stored.Reason,
+		Threshold:      stored.Threshold,
+		PolicyVersion:  stored.PolicyVersion,
+		TrustTier:      stored.TrustTier,
+		RiskFactors:    stored.RiskFactors,
+		TriggeredRules: stored.TriggeredRules,
+		CaseID:         stored.CaseID,
+		CreatedAt:      stored.CreatedAt,
+	}
+}
+
+// workflowConsentCheck decodes the consent check the workflow went ahead on;
+// nil before consent validation or when it was skipped
+func workflowConsentCheck(workflow *database.PaymentWorkflow) *types.ConsentCheck {
+	if workflow.ConsentCheck == "" {
+		return nil
+	}
+	var stored ConsentCheck
+	if err := json.Unmarshal([]byte(workflow.ConsentCheck), &stored); err != nil {
+		common.Warn("Workflow %s has an unreadable consent check: %v", workflow.ID, err)
+		return nil
+	}
+	return &types.ConsentCheck{
+		Valid:            stored.Valid,
+		Reason:           stored.Reason,
+		ConsentID:        stored.ConsentID,
+		ConsentVersion:   stored.ConsentVersion,
+		RequiresApproval: stored.RequiresApproval,
+		ApproverGroup:    stored.ApproverGroup,
+		ApprovalID:       stored.ApprovalID,
+	}
+}
+
+// setEvidenceIDs copies the IDs of a payment's consent, risk decision and
+// compliance screening into its API response, with any degradation modes
+// applied while one of those services was down
+func setEvidenceIDs(payment *types.PaymentWorkflow, workflow *database.PaymentWorkflow) {
+	if workflow.CounterpartyID != nil {
+		payment.CounterpartyID = *workflow.CounterpartyID
+	}
+	payment.Category = workflow.Category
+	payment.WorkflowTemplateVersion = workflow.WorkflowTemplateVersion
+	if workflow.ScheduleID != nil {
+		payment.ScheduleID = *workflow.ScheduleID
+	}
+	if workflow.ConsentID != nil {
+		payment.ConsentID = *workflow.ConsentID
+		payment.ConsentVersion = workflow.ConsentVersion
+	}
+	if workflow.RiskDecisionID != nil {
+		payment.RiskDecisionID = *workflow.RiskDecisionID
+	}
+	if workflow.ComplianceScreeningID != nil {
+		payment.ComplianceScreeningID = *workflow.ComplianceScreeningID
+	}
+	if workflow.FeeVariantID != nil {
+		payment.FeeExperimentID = *workflow.FeeExperimentID
+		payment.FeeVariantID = *workflow.FeeVariantID
+	}
+	if workflow.QuoteID != nil {
+		payment.QuoteID = *workflow.QuoteID
+	}
+	payment.Degradations = workflowDegradations(workflow)
+}
+
+// decodeData decodes the data of a service response into v
+func decodeData(response *common.APIResponse, v interface{}) error {
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
 func performConsentValidation(workflow *database.PaymentWorkflow) error {
 	common.Info("Performing consent validation for workflow %s", workflow.ID)
 
@@ -431,74 +1399,277 @@ func performConsentValidation(workflow *database.PaymentWorkflow) error {
 	}
 
 	// Call Consent Service to validate
-	consentRequest := map[string]interface{}{
-		"agentId":      workflow.AgentID,
-		"ownerPartyId": agent.OwnerPartyID,
-		"amountUSD":    workflow.AmountUSD,
-		"counterparty": workflow.Counterparty,
-		"rail":         workflow.Rail,
+	consentRequest := &clients.ConsentValidationRequest{
+		AgentID:      workflow.AgentID,
+		OwnerPartyID: agent.OwnerPartyID,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Rail:         workflow.Rail,
+		WorkflowID:   workflow.ID,
+	}
+	if workflow.CounterpartyID != nil {
+		consentRequest.CounterpartyID = *workflow.CounterpartyID
 	}
 
-	consentResponse, err := callService("http://localhost:8082/v1/consents/validate", consentRequest)
+	consentResponse, err := callDegradable(workflow, consentDependency, func() (*common.APIResponse, error) {
+		return consentClient.Validate(workflowContext(workflow), consentRequest)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to call consent service: %v", err)
+		return fmt.Errorf("failed to call consent service: %w", err)
+	}
+	if consentResponse == nil {
+		return repo.PaymentWorkflowRepository().Update(workflow)
 	}
 
 	// Parse consent validation result
-	consentData := consentResponse.Data.(map[string]interface{})
-	valid := consentData["valid"].(bool)
+	var check ConsentCheck
+	if err := decodeData(consentResponse, &check); err != nil {
+		return fmt.Errorf("invalid consent service response: %v", err)
+	}
 
-	if !valid {
-		reason := "Consent validation failed"
-		if reasonVal, exists := consentData["reason"]; exists {
-			reason = reasonVal.(string)
+	if !check.Valid {
+		reason := check.Reason
+		if reason == "" {
+			reason = "Consent validation failed"
 		}
 		return fmt.Errorf("consent validation failed: %s", reason)
 	}
 
+	// Payments above the consent's cosign threshold wait for an approver
+	if check.RequiresApproval {
+		if check.ApprovalID == "" {
+			return fmt.Errorf("approval required from %s but none was requested", check.ApproverGroup)
+		}
+		// A revocation while the payment waits can move it to another consent
+		consentID, err := awaitApproval(workflow, check.ApprovalID, check.ApproverGroup)
+		if err != nil {
+			return err
+		}
+		check.ConsentID = consentID
+	}
+
+	// Link the payment to the consent version that authorized it, which
+	// stays as it was even if the consent is updated later
+	if check.ConsentID != "" {
+		consent, err := repo.ConsentRepository().GetByID(check.ConsentID)
+		if err != nil {
+			return fmt.Errorf("failed to get consent %s: %v", check.ConsentID, err)
+		}
+		check.ConsentVersion = consent.Version
+		if err := repo.PaymentWorkflowRepository().LinkConsent(workflow.ID, consent.ID, consent.Version); err != nil {
+			return fmt.Errorf("failed to link consent: %v", err)
+		}
+		workflow.ConsentID = evidenceID(consent.ID)
+		workflow.ConsentVersion = consent.Version
+
+		err = audit.NewAuditTrail(repo).LogPaymentEvent(workflowContext(workflow), audit.AuditPaymentAuthorized, workflow.ID, workflow.AgentID, orchestratorActor, map[string]interface{}{
+			"consentId":      consent.ID,
+			"consentVersion": consent.Version,
+			"approvalId":     check.ApprovalID,
+		})
+		if err != nil {
+			common.Error("Failed to audit consent of payment %s: %v", workflow.ID, err)
+		}
+	}
+
 	// Store consent validation result
-	workflow.ConsentCheck = "{}" // Would serialize consentResponse.Data to JSON
+	if data, err := json.Marshal(check); err == nil {
+		workflow.ConsentCheck = string(data)
+	}
 
 	common.Info("Consent validation passed for workflow %s", workflow.ID)
 	return repo.PaymentWorkflowRepository().Update(workflow)
 }
 
+// awaitApproval holds the workflow in awaiting_approval until an approver
+// decides the approval, returning it to processing once approved, or the
+// approval timeout passes. It returns the consent the approval was decided
+// under.
+func awaitApproval(workflow *database.PaymentWorkflow, approvalID, approverGroup string) (string, error) {
+	common.Warn("Payment %s awaiting approval %s from %s", workflow.ID, approvalID, approverGroup)
+	appendWorkflowStep(workflow, "approval", "pending", "Awaiting approval from "+approverGroup)
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		return "", fmt.Errorf("failed to record approval request: %v", err)
+	}
+	// A workflow recovered while awaiting approval is already held
+	if workflow.Status != string(workflowstate.AwaitingApproval) {
+		if err := workflowStates.Transition(workflow, workflowstate.AwaitingApproval, "Approval "+approvalID+" requested from "+approverGroup, orchestratorActor); err != nil {
+			return "", fmt.Errorf("failed to hold workflow for approval: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(approvalTimeout)
+	for time.Now().Before(deadline) {
+		if err := pauseWorkflow(complianceReviewPollInterval); err != nil {
+			return "", err
+		}
+		if workflowCancelled(workflow) {
+			return "", fmt.Errorf("workflow cancelled while awaiting approval")
+		}
+
+		response, err := consentClient.GetApproval(workflowContext(workflow), approvalID)
+		if err != nil {
+			common.Warn("Failed to check approval %s: %v", approvalID, err)
+			continue
+		}
+		var approval struct {
+			Status    string `json:"status"`
+			DecidedBy string `json:"decidedBy"`
+			ConsentID string `json:"consentId"`
+		}
+		if err := decodeData(response, &approval); err != nil || approval.Status == "pending" {
+			continue
+		}
+
+		if approval.Status != "approved" {
+			appendWorkflowStep(workflow, "approval", "failed", "Approval "+approval.Status)
+			return "", fmt.Errorf("approval %s %s", approvalID, approval.Status)
+		}
+		appendWorkflowStep(workflow, "approval", "completed", "Approved by "+approval.DecidedBy)
+		if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+			return "", err
+		}
+		return approval.ConsentID, workflowStates.Transition(workflow, workflowstate.Processing, "Approval "+approvalID+" approved by "+approval.DecidedBy, orchestratorActor)
+	}
+	appendWorkflowStep(workflow, "approval", "failed", "Approval timed out")
+	return "", fmt.Errorf("approval %s timed out", approvalID)
+}
+
+// performComplianceCheck screens the counterparty with the compliance
+// service. A blocked counterparty fails the payment; a possible match holds it
+// until a compliance officer decides the review or the review times out.
 func performComplianceCheck(workflow *database.PaymentWorkflow) error {
 	common.Info("Performing compliance check for workflow %s", workflow.ID)
 
-	// Placeholder for compliance check
-	// Would call Compliance Service in production
-	time.Sleep(100 * time.Millisecond) // Simulate processing time
+	// Call Compliance Service
+	screenRequest := &clients.ScreeningRequest{
+		AgentID:      workflow.AgentID,
+		Counterparty: workflow.Counterparty,
+		AmountUSD:    workflow.AmountUSD,
+		WorkflowID:   workflow.ID,
+	}
+	if workflow.CounterpartyID != nil {
+		screenRequest.CounterpartyID = *workflow.CounterpartyID
+	}
 
-	return nil
-}
+	screenResponse, err := callDegradable(workflow, complianceDependency, func() (*common.APIResponse, error) {
+		return complianceClient.Screen(workflowContext(workflow), screenRequest)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to call compliance service: %w", err)
+	}
+	if screenResponse == nil {
+		return repo.PaymentWorkflowRepository().Update(workflow)
+	}
 
-func executePayment(workflow *database.PaymentWorkflow) error {
-	common.Info("Executing payment for workflow %s", workflow.ID)
+	var screening struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := decodeData(screenResponse, &screening); err != nil {
+		return fmt.Errorf("invalid compliance service response: %v", err)
+	}
+	if screening.ID == "" || screening.Status == "" {
+		return fmt.Errorf("invalid compliance service response: screening without an ID or status")
+	}
+	screeningID, status := screening.ID, screening.Status
 
-	// Placeholder for payment execution
-	// Would call Ledger/Router services in production
-	time.Sleep(200 * time.Millisecond) // Simulate processing time
+	if status == "pending_review" {
+		common.Warn("Payment %s held for compliance review of screening %s", workflow.ID, screeningID)
+		status, err = awaitComplianceReview(workflow, screeningID)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
+	switch status {
+	case "clear", "approved":
+		common.Info("Compliance check passed for workflow %s: %s", workflow.ID, status)
+		workflow.ComplianceScreeningID = evidenceID(screeningID)
+		return repo.PaymentWorkflowRepository().Update(workflow)
+	case "blocked":
+		return fmt.Errorf("counterparty matched a sanctions or denylist entry (screening %s)", screeningID)
+	default:
+		return fmt.Errorf("payment %s in compliance review (screening %s)", status, screeningID)
+	}
+}
+
+// awaitComplianceReview polls a screening until it leaves the review queue
+func awaitComplianceReview(workflow *database.PaymentWorkflow, screeningID string) (string, error) {
+	deadline := time.Now().Add(complianceReviewTimeout)
+	for time.Now().Before(deadline) {
+		if err := pauseWorkflow(complianceReviewPollInterval); err != nil {
+			return "", err
+		}
+		if workflowCancelled(workflow) {
+			return "", fmt.Errorf("workflow cancelled during compliance review")
+		}
+
+		response, err := complianceClient.GetScreening(workflowContext(workflow), screeningID)
+		if err != nil {
+			common.Warn("Failed to check compliance review %s: %v", screeningID, err)
+			continue
+		}
+		var screening struct {
+			Status string `json:"status"`
+		}
+		if err := decodeData(response, &screening); err == nil && screening.Status != "" && screening.Status != "pending_review" {
+			return screening.Status, nil
+		}
+	}
+	return "", fmt.Errorf("compliance review of screening %s timed out", screeningID)
 }
 
-func updateWorkflowStatus(workflow *database.PaymentWorkflow, status, message string) {
-	workflow.Status = status
-	workflow.UpdatedAt = time.Now()
+// updateWorkflowStatus saves the workflow and moves it to a new status through
+// the state machine, recording the message as the reason
+func updateWorkflowStatus(workflow *database.PaymentWorkflow, status workflowstate.State, message string) {
+	// A cancellation made while a step was running takes precedence
+	if workflowCancelled(workflow) {
+		return
+	}
 	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to update workflow %s: %v", workflow.ID, err)
+	}
+	if err := workflowStates.Transition(workflow, status, message, orchestratorActor); err != nil {
 		common.Error("Failed to update workflow status: %v", err)
 	}
 }
 
+// orchestratorActor is the actor recorded for transitions made while processing
+const orchestratorActor = "orchestration"
+
+// principalSubject names the caller in transition history
+func principalSubject(c *gin.Context) string {
+	if principal := common.GetPrincipal(c); principal != nil {
+		return principal.Subject
+	}
+	return orchestratorActor
+}
+
+// requireStage is a guard that the workflow has completed a stage
+func requireStage(name string) workflowstate.Guard {
+	return func(workflow *database.PaymentWorkflow) error {
+		if stageStatuses(workflow)[name] != "completed" {
+			return fmt.Errorf("stage %s has not completed", name)
+		}
+		return nil
+	}
+}
+
 func getAvailableRails(c *gin.Context) {
-	rails := railSelector.GetAvailableRails()
+	available := railCatalog.Selector().GetAvailableRails()
+	names := make([]string, 0, len(available))
+	for rail := range available {
+		names = append(names, string(rail))
+	}
+	sort.Strings(names)
 
 	// Convert to API response format
 	var result []map[string]interface{}
-	for rail, characteristics := range rails {
+	for _, rail := range names {
+		characteristics := available[types.PaymentRail(rail)]
 		result = append(result, map[string]interface{}{
-			"rail":           string(rail),
+			"rail":           rail,
 			"name":           characteristics.Name,
 			"description":    characteristics.Description,
 			"minAmount":      characteristics.MinAmount,
@@ -515,6 +1686,8 @@ func getAvailableRails(c *gin.Context) {
 			"reversibility":        characteristics.Reversibility,
 			"internationalSupport": characteristics.InternationalSupport,
 			"requiresVerification": characteristics.RequiresVerification,
+			"maxDescriptionLength": characteristics.MaxDescriptionLength,
+			"reliability":          characteristics.Reliability,
 		})
 	}
 
@@ -529,6 +1702,7 @@ func getAvailableRails(c *gin.Context) {
 type RailSelectionRequest struct {
 	AmountUSD    float64          `json:"amountUSD" binding:"required"`
 	Counterparty string           `json:"counterparty"`
+	AgentID      string           `json:"agentId,omitempty"` // Adds the tenant's markup to the estimated fee
 	Preferences  *RailPreferences `json:"preferences,omitempty"`
 }
 
@@ -577,21 +1751,29 @@ func selectRail(c *gin.Context) {
 	}
 
 	// Select the best rail
-	selectedRail, characteristics, err := railSelector.SelectRail(req.AmountUSD, req.Counterparty, prefs)
+	selectedRail, characteristics, err := railCatalog.Selector().SelectRail(req.AmountUSD, req.Counterparty, prefs)
 	if err != nil {
 		common.Error("Failed to select rail for amount %.2f: %v", req.AmountUSD, err)
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("RAIL_SELECTION_ERROR", fmt.Sprintf("No suitable rail found: %v", err)))
 		return
 	}
 
-	// Calculate estimated fee (using the unexported method through a workaround)
-	// In production, this method should be exported
-	fee := characteristics.FeeStructure.FixedFee + (req.AmountUSD * characteristics.FeeStructure.PercentFee)
-	if fee < characteristics.FeeStructure.MinFee {
-		fee = characteristics.FeeStructure.MinFee
-	}
-	if fee > characteristics.FeeStructure.MaxFee {
-		fee = characteristics.FeeStructure.MaxFee
+	// Estimate the fee, including the markup of the agent's tenant when known
+	fee := characteristics.FeeStructure.Calculate(req.AmountUSD)
+	var quote *fees.Quote
+	if req.AgentID != "" {
+		if !common.CanActForAgent(c, req.AgentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot quote fees for this agent"))
+			return
+		}
+		agentQuote, err := quoteAgentFee(req.AgentID, string(selectedRail), req.AmountUSD)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("QUOTE_ERROR", err.Error()))
+			return
+		}
+		countFeeQuote(agentQuote)
+		quote = &agentQuote
+		fee = quote.TotalFeeUSD
 	}
 
 	response := map[string]interface{}{
@@ -609,46 +1791,102 @@ func selectRail(c *gin.Context) {
 		},
 		"amountUSD": req.AmountUSD,
 	}
+	if quote != nil {
+		response["feeQuote"] = quote
+	}
 
 	common.Info("Selected rail %s for payment amount %.2f", selectedRail, req.AmountUSD)
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
-func callService(url string, payload interface{}) (*common.APIResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// AgentCredentialHeader carries the credential an agent was issued by the identity service
+const AgentCredentialHeader = "X-Agent-Credential"
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
+// verifyAgentCredential checks an agent credential with the identity service
+func verifyAgentCredential(ctx context.Context, agentID, credential string) error {
+	if credential == "" {
+		return fmt.Errorf("agent credential is required")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	response, err := identityClient.VerifyCredential(ctx, agentID, credential)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to call identity service: %v", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	var result struct {
+		Valid  bool   `json:"valid"`
+		Reason string `json:"reason"`
 	}
-	defer resp.Body.Close()
+	if err := decodeData(response, &result); err != nil {
+		return fmt.Errorf("invalid identity service response: %v", err)
+	}
+	if !result.Valid {
+		return fmt.Errorf("agent credential rejected: %s", result.Reason)
+	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// verifyAgentSignature checks a DID agent's X-Agent-Signature of a request
+// against the keys of its DID document. Rejected signatures are audited
+// here; accepted ones are kept with the payment they authorized.
+func verifyAgentSignature(c *gin.Context, agent *database.Agent, header string, body []byte) (*did.Verification, error) {
+	if header == "" {
+		return nil, fmt.Errorf("signed request is required")
+	}
+	document, err := didResolver.ResolveAgent(agent)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("agent DID cannot be resolved: %v", err)
 	}
 
-	var apiResponse common.APIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
+	verification, err := did.VerifyRequest(document, header, c.Request.Method, c.Request.URL.Path, body, time.Now())
+	if err != nil {
+		audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+			EventType:    audit.AuditAgentDIDVerified,
+			Severity:     audit.SeverityHigh,
+			AgentID:      agent.ID,
+			ResourceID:   agent.ID,
+			ResourceType: "agent",
+			Action:       "did_verify",
+			Description:  "Payment request signature of " + document.ID + " rejected",
+			NewValues:    verification.Map(),
+		})
 		return nil, err
 	}
+	return verification, nil
+}
+
+// Clients of the services the orchestrator calls
+var (
+	identityClient   *clients.IdentityClient
+	consentClient    *clients.ConsentClient
+	riskClient       *clients.RiskClient
+	complianceClient *clients.ComplianceClient
+	routerClient     *clients.RouterClient
+	ledgerClient     *clients.LedgerClient
+)
 
-	if !apiResponse.Success {
-		return nil, fmt.Errorf("service call failed: %v", apiResponse.Error)
+// initServiceClients sets up the clients of downstream services, found
+// through the resolver SERVICE_DISCOVERY names
+func initServiceClients() error {
+	resolver, err := discovery.NewResolverFromEnv()
+	if err != nil {
+		return err
 	}
+	common.Info("Resolving downstream services through %s discovery", resolver.Name())
 
-	return &apiResponse, nil
+	// Authenticate as the orchestration service with only the scopes it needs
+	token := func() (string, error) {
+		return authConfig.ServiceToken("orchestration", common.ScopeRiskEvaluate, common.ScopeRiskRead, common.ScopeConsentsRead, common.ScopeAgentsRead,
+			common.ScopeComplianceScreen, common.ScopeComplianceRead, common.ScopeRoutingExecute, common.ScopePaymentsRead, common.ScopePaymentsWrite,
+			common.ScopeLedgerRead, common.ScopeLedgerWrite)
+	}
+	config := clients.NewConfigFromEnv(resolver, token)
+
+	identityClient = clients.NewIdentityClient(config)
+	consentClient = clients.NewConsentClient(config)
+	riskClient = clients.NewRiskClient(config)
+	complianceClient = clients.NewComplianceClient(config)
+	routerClient = clients.NewRouterClient(config)
+	ledgerClient = clients.NewLedgerClient(config)
+	return nil
 }
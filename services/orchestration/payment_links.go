@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultPaymentLinkTTL = 72 * time.Hour
+	maxPaymentLinkTTL     = 30 * 24 * time.Hour
+	paymentLinkQRSize     = 256
+
+	// PaymentLinkSignatureHeader carries the hosted flow's HMAC over a completion callback
+	PaymentLinkSignatureHeader = "X-Payment-Link-Signature"
+)
+
+type CreatePaymentLinkRequest struct {
+	Reason     string `json:"reason"`
+	TTLSeconds int    `json:"ttlSeconds,omitempty"`
+}
+
+type CompletePaymentLinkRequest struct {
+	FundingMethod    string `json:"fundingMethod" binding:"required"` // e.g. "card", "bank_transfer"
+	FundingReference string `json:"fundingReference" binding:"required"`
+}
+
+type PaymentLinkResponse struct {
+	ID           string  `json:"id"`
+	WorkflowID   string  `json:"workflowId,omitempty"`
+	URL          string  `json:"url"`
+	DeepLink     string  `json:"deepLink"`
+	QRCodeURL    string  `json:"qrCodeUrl"`
+	QRCodePNG    string  `json:"qrCodePng,omitempty"` // Base64 PNG, only returned at creation
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty"`
+	Description  string  `json:"description,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+	Status       string  `json:"status"`
+	ExpiresAt    string  `json:"expiresAt"`
+	CompletedAt  string  `json:"completedAt,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+}
+
+func paymentLinkURL(token string) string {
+	base := strings.TrimSuffix(common.GetEnv("PAYMENT_LINK_BASE_URL", "http://localhost:8080/pay"), "/")
+	return base + "/" + token
+}
+
+func toPaymentLinkResponse(link *database.PaymentLink) *PaymentLinkResponse {
+	response := &PaymentLinkResponse{
+		ID:           link.ID,
+		WorkflowID:   link.WorkflowID,
+		URL:          paymentLinkURL(link.Token),
+		DeepLink:     "agentpay://pay/" + link.Token,
+		QRCodeURL:    "/v1/payment-links/" + link.Token + "/qr.png",
+		AmountUSD:    link.AmountUSD,
+		Counterparty: link.Counterparty,
+		Description:  link.Description,
+		Reason:       link.Reason,
+		Status:       link.Status,
+		ExpiresAt:    link.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:    link.CreatedAt.Format(time.RFC3339),
+	}
+	if link.CompletedAt != nil {
+		response.CompletedAt = link.CompletedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// expireIfDue marks an open link expired once its expiry has passed
+func expireIfDue(link *database.PaymentLink) {
+	if link.Status == "open" && time.Now().After(link.ExpiresAt) {
+		link.Status = "expired"
+		if err := repo.PaymentLinkRepository().Update(link); err != nil {
+			common.Error("Failed to expire payment link %s: %v", link.ID, err)
+		}
+	}
+}
+
+// appendWorkflowStep records a step in the workflow's step history
+func appendWorkflowStep(workflow *database.PaymentWorkflow, name, status, message string) {
+	steps := append(workflowSteps(workflow), types.WorkflowStep{
+		Name:      name,
+		Status:    status,
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+
+	if data, err := json.Marshal(steps); err == nil {
+		workflow.Steps = string(data)
+	}
+}
+
+func createPaymentLink(c *gin.Context) {
+	var req CreatePaymentLinkRequest
+	c.ShouldBindJSON(&req)
+
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(c.Param("id"))
+	if err != nil || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
+
+	if !common.CanActForAgent(c, workflow.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot create payment links for this payment"))
+		return
+	}
+
+	// Only payments that have not gone through can fall back to human funding
+	if workflow.Status != string(workflowstate.Pending) && workflow.Status != string(workflowstate.Failed) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_STATUS", "Payment links can only be created for pending or failed payments"))
+		return
+	}
+
+	ttl := defaultPaymentLinkTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl > maxPaymentLinkTTL {
+			ttl = maxPaymentLinkTTL
+		}
+	}
+
+	token, err := common.GenerateRandomString(32)
+	if err != nil {
+		common.Error("Failed to generate payment link token: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to create payment link"))
+		return
+	}
+
+	// A workflow has at most one open link
+	if existing, err := repo.PaymentLinkRepository().ListByWorkflowID(workflow.ID); err == nil {
+		for _, link := range existing {
+			if link.Status == "open" {
+				link.Status = "cancelled"
+				if err := repo.PaymentLinkRepository().Update(link); err != nil {
+					common.Error("Failed to cancel payment link %s: %v", link.ID, err)
+				}
+			}
+		}
+	}
+
+	link := &database.PaymentLink{
+		WorkflowID:   workflow.ID,
+		Token:        token,
+		AmountUSD:    workflow.AmountUSD,
+		Counterparty: workflow.Counterparty,
+		Description:  workflow.Description,
+		Reason:       req.Reason,
+		Status:       "open",
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	if err := repo.PaymentLinkRepository().Create(link); err != nil {
+		common.Error("Failed to create payment link: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create payment link"))
+		return
+	}
+
+	appendWorkflowStep(workflow, "human_funding", "pending", "Payment link issued for human funding")
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to record payment link on workflow %s: %v", workflow.ID, err)
+	}
+
+	response := toPaymentLinkResponse(link)
+	if png, err := qrcode.Encode(response.URL, qrcode.Medium, paymentLinkQRSize); err == nil {
+		response.QRCodePNG = base64.StdEncoding.EncodeToString(png)
+	} else {
+		common.Error("Failed to render QR code for payment link %s: %v", link.ID, err)
+	}
+
+	common.Info("Created payment link %s for workflow %s", link.ID, workflow.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+func listPaymentLinks(c *gin.Context) {
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, workflow.AgentID) || !workflowInTenant(c, workflow) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment workflow not found"))
+		return
+	}
+
+	links, err := repo.PaymentLinkRepository().ListByWorkflowID(workflow.ID)
+	if err != nil {
+		common.Error("Failed to list payment links: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment links"))
+		return
+	}
+
+	response := common.NewListResponse(make([]interface{}, len(links)), 1, len(links), len(links))
+	for i, link := range links {
+		expireIfDue(link)
+		response.Items[i] = toPaymentLinkResponse(link)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// getPaymentLink serves the hosted funding flow. It is public: the token is the credential.
+func getPaymentLink(c *gin.Context) {
+	link, err := repo.PaymentLinkRepository().GetByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment link not found"))
+		return
+	}
+	expireIfDue(link)
+
+	// The hosted page does not need internal identifiers
+	response := toPaymentLinkResponse(link)
+	response.WorkflowID = ""
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+func getPaymentLinkQR(c *gin.Context) {
+	link, err := repo.PaymentLinkRepository().GetByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment link not found"))
+		return
+	}
+
+	png, err := qrcode.Encode(paymentLinkURL(link.Token), qrcode.Medium, paymentLinkQRSize)
+	if err != nil {
+		common.Error("Failed to render QR code for payment link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to render QR code"))
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// completePaymentLink is called by the hosted funding flow once a human has funded
+// the payment. It records the funding on the workflow and resumes processing.
+func completePaymentLink(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request body"))
+		return
+	}
+
+	// When a callback secret is configured, only the hosted flow can complete links
+	if secret := common.GetEnv("PAYMENT_LINK_CALLBACK_SECRET", ""); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(c.GetHeader(PaymentLinkSignatureHeader))) {
+			c.JSON(http.StatusUnauthorized, common.NewErrorResponse("UNAUTHORIZED", "Invalid callback signature"))
+			return
+		}
+	}
+
+	var req CompletePaymentLinkRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.FundingMethod == "" || req.FundingReference == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "fundingMethod and fundingReference are required"))
+		return
+	}
+
+	link, err := repo.PaymentLinkRepository().GetByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment link not found"))
+		return
+	}
+	expireIfDue(link)
+
+	if link.Status != "open" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Payment link is "+link.Status))
+		return
+	}
+
+	now := time.Now()
+	link.Status = "completed"
+	link.FundingMethod = req.FundingMethod
+	link.FundingReference = req.FundingReference
+	link.CompletedAt = &now
+	if err := repo.PaymentLinkRepository().Update(link); err != nil {
+		common.Error("Failed to complete payment link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to complete payment link"))
+		return
+	}
+
+	// Synchronize the funding back to the original workflow
+	workflow, err := repo.PaymentWorkflowRepository().GetByID(link.WorkflowID)
+	if err != nil {
+		common.Error("Payment link %s completed but workflow %s not found: %v", link.ID, link.WorkflowID, err)
+		c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentLinkResponse(link)))
+		return
+	}
+
+	appendWorkflowStep(workflow, "human_funding", "completed", "Funded via "+req.FundingMethod+" ("+req.FundingReference+")")
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to record funding on workflow %s: %v", workflow.ID, err)
+	} else if workflowstate.Allowed(workflowstate.State(workflow.Status), workflowstate.Processing) {
+		if err := workflowStates.Transition(workflow, workflowstate.Processing, "Funded via payment link "+link.ID, orchestratorActor); err != nil {
+			common.Error("Failed to update workflow %s after funding: %v", workflow.ID, err)
+		} else {
+			startWorkflow(context.WithoutCancel(c.Request.Context()), workflow)
+		}
+	}
+
+	common.Info("Payment link %s completed via %s, resuming workflow %s", link.ID, req.FundingMethod, workflow.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentLinkResponse(link)))
+}
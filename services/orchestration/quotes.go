@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fees"
+	"github.com/example/agent-payments/internal/fx"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// quoteTTL is how long a quote binds its rail, fee and exchange rate
+var quoteTTL time.Duration
+
+// errQuoteUnavailable is returned when a payment's quote was used by another
+// payment or expired between being checked and being used
+var errQuoteUnavailable = errors.New("quote is no longer available")
+
+type QuoteRequest struct {
+	AgentID        string           `json:"agentId" binding:"required"`
+	Amount         float64          `json:"amount,omitempty"`    // Amount in Currency; takes precedence over AmountUSD
+	Currency       string           `json:"currency,omitempty"`  // ISO 4217 code, defaults to USD
+	AmountUSD      float64          `json:"amountUSD,omitempty"` // Legacy USD amount
+	Counterparty   string           `json:"counterparty,omitempty"`
+	CounterpartyID string           `json:"counterpartyId,omitempty"`
+	Rail           string           `json:"rail,omitempty"` // Optional - the best rail is quoted if not provided
+	Preferences    *RailPreferences `json:"preferences,omitempty"`
+
+	International *international.Details `json:"international,omitempty"` // Beneficiary's bank details, for payments abroad
+}
+
+type QuoteFXResponse struct {
+	Rate     float64 `json:"rate"` // USD per unit of the payment currency
+	Provider string  `json:"provider"`
+}
+
+type QuoteResponse struct {
+	ID            string           `json:"id"`
+	AgentID       string           `json:"agentId"`
+	Amount        float64          `json:"amount"`
+	Currency      string           `json:"currency"`
+	AmountUSD     float64          `json:"amountUSD"`
+	FX            *QuoteFXResponse `json:"fx,omitempty"` // Set for payments not in USD
+	Counterparty  string           `json:"counterparty,omitempty"`
+	Rail          string           `json:"rail"`
+	Fee           fees.Quote       `json:"fee"`
+	TotalDebitUSD float64          `json:"totalDebitUSD"` // Amount plus fees, debited from the agent's wallet
+	ExpiresAt     string           `json:"expiresAt"`
+	PaymentID     string           `json:"paymentId,omitempty"` // Payment made under the quote
+	CreatedAt     string           `json:"createdAt"`
+
+	// Correspondent bank fees expected for a payment abroad, and who bears them
+	Correspondent *international.CorrespondentFees `json:"correspondent,omitempty"`
+}
+
+// createQuote quotes a payment: the rail it would take, its fee and, for
+// payments not in USD, the exchange rate. A payment referencing the quote
+// by quoteId before it expires is made on the quoted rail at the quoted fee
+// and rate.
+func createQuote(c *gin.Context) {
+	var body QuoteRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	req := PaymentRequest{
+		AgentID:        body.AgentID,
+		Amount:         body.Amount,
+		Currency:       body.Currency,
+		AmountUSD:      body.AmountUSD,
+		Counterparty:   body.Counterparty,
+		CounterpartyID: body.CounterpartyID,
+		Rail:           body.Rail,
+		Preferences:    body.Preferences,
+		International:  body.International,
+	}
+	if req.Amount <= 0 && req.AmountUSD <= 0 {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amount (or amountUSD) must be greater than 0"))
+		return
+	}
+
+	conversion, err := resolvePaymentAmount(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_CURRENCY", err.Error()))
+		return
+	}
+
+	if !common.CanActForAgent(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot quote payments for this agent"))
+		return
+	}
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", req.AgentID) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
+		return
+	}
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalParty && principal.PartyID != agent.OwnerPartyID {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot quote payments for this agent"))
+		return
+	}
+	if perr := resolveCounterparty(agent, &req); perr != nil {
+		c.JSON(perr.status, common.NewErrorResponse(perr.code, perr.message))
+		return
+	}
+	if perr := validateInternational(&req); perr != nil {
+		c.JSON(perr.status, common.NewErrorResponse(perr.code, perr.message))
+		return
+	}
+
+	rail, perr := selectPaymentRail(&req)
+	if perr != nil {
+		c.JSON(perr.status, common.NewErrorResponse(perr.code, perr.message))
+		return
+	}
+	fee, err := quoteAgentFee(agent.ID, rail, req.AmountUSD)
+	if err != nil {
+		common.Error("Failed to quote fee for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to quote payment fee"))
+		return
+	}
+	fee = withCorrespondentFee(fee, req.International)
+	countFeeQuote(fee)
+
+	quote := &database.Quote{
+		AgentID:       agent.ID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		AmountUSD:     req.AmountUSD,
+		FXRate:        conversion.Rate,
+		FXProvider:    conversion.Provider,
+		Counterparty:  req.Counterparty,
+		Rail:          rail,
+		RailFeeUSD:    fee.RailFeeUSD,
+		MarkupUSD:     fee.MarkupUSD,
+		TotalFeeUSD:   fee.TotalFeeUSD,
+		TotalDebitUSD: fx.RoundAmount(req.AmountUSD + fee.TotalFeeUSD),
+		ScheduleID:    fee.ScheduleID,
+		ExperimentID:  fee.ExperimentID,
+		VariantID:     fee.VariantID,
+		ExpiresAt:     time.Now().Add(quoteTTL),
+
+		International:       international.Encode(req.International),
+		CorrespondentFeeUSD: fee.CorrespondentFeeUSD,
+	}
+	if err := repo.QuoteRepository().Create(quote); err != nil {
+		common.Error("Failed to store quote for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to store quote"))
+		return
+	}
+
+	common.Info("Quoted %.2f %s for agent %s on %s, fee %.2f USD, until %s", quote.Amount, quote.Currency, agent.ID, rail, quote.TotalFeeUSD, quote.ExpiresAt.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toQuoteResponse(quote)))
+}
+
+// getQuote returns a quote, and the payment made under it if any
+func getQuote(c *gin.Context) {
+	quote, err := repo.QuoteRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, quote.AgentID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Quote not found"))
+		return
+	}
+	agent, err := repo.AgentRepository().GetByID(quote.AgentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "quote", quote.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Quote not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toQuoteResponse(quote)))
+}
+
+// applyQuote makes a payment under the quote it references. The payment
+// must match the quote's agent, amount, currency and beneficiary details
+// and, when given, payee and rail. While the quote is unused and unexpired, it fixes the payment's
+// rail, fee and USD amount; otherwise the payment is quoted afresh.
+func applyQuote(req *PaymentRequest) *paymentError {
+	if req.QuoteID == "" {
+		return nil
+	}
+	quote, err := repo.QuoteRepository().GetByID(req.QuoteID)
+	if err != nil || quote.AgentID != req.AgentID {
+		return &paymentError{http.StatusBadRequest, "VALIDATION_ERROR", "Quote not found"}
+	}
+	if math.Abs(quote.Amount-req.Amount) >= 0.005 || quote.Currency != req.Currency ||
+		(quote.Counterparty != "" && quote.Counterparty != req.Counterparty) ||
+		(req.Rail != "" && req.Rail != quote.Rail) ||
+		quote.International != international.Encode(req.International) {
+		return &paymentError{http.StatusBadRequest, "QUOTE_MISMATCH", "Payment does not match its quote"}
+	}
+
+	if quote.WorkflowID != nil || !time.Now().Before(quote.ExpiresAt) {
+		common.Info("Quote %s is used or expired; quoting payment of agent %s afresh", quote.ID, req.AgentID)
+		return nil
+	}
+	req.quote = quote
+	req.AmountUSD = quote.AmountUSD
+	req.Rail = quote.Rail
+	return nil
+}
+
+// quotedFee is the fee a payment made under a quote is charged
+func quotedFee(quote *database.Quote) fees.Quote {
+	return fees.Quote{
+		Rail:         quote.Rail,
+		AmountUSD:    quote.AmountUSD,
+		RailFeeUSD:   quote.RailFeeUSD,
+		MarkupUSD:    quote.MarkupUSD,
+		TotalFeeUSD:  quote.TotalFeeUSD,
+		ScheduleID:   quote.ScheduleID,
+		ExperimentID: quote.ExperimentID,
+		VariantID:    quote.VariantID,
+
+		CorrespondentFeeUSD: quote.CorrespondentFeeUSD,
+	}
+}
+
+func toQuoteResponse(quote *database.Quote) *QuoteResponse {
+	response := &QuoteResponse{
+		ID:            quote.ID,
+		AgentID:       quote.AgentID,
+		Amount:        quote.Amount,
+		Currency:      quote.Currency,
+		AmountUSD:     quote.AmountUSD,
+		Counterparty:  quote.Counterparty,
+		Rail:          quote.Rail,
+		Fee:           quotedFee(quote),
+		TotalDebitUSD: quote.TotalDebitUSD,
+		ExpiresAt:     quote.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:     quote.CreatedAt.Format(time.RFC3339),
+	}
+	if quote.Currency != fx.BaseCurrency {
+		response.FX = &QuoteFXResponse{Rate: quote.FXRate, Provider: quote.FXProvider}
+	}
+	if quote.WorkflowID != nil {
+		response.PaymentID = *quote.WorkflowID
+	}
+	if details := international.Decode(quote.International); international.IsInternational(details) {
+		response.Correspondent = international.EstimateFees(details, quote.AmountUSD)
+	}
+	return response
+}
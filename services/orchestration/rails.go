@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rails"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RailRequest struct {
+	Rail                 string  `json:"rail" binding:"required"` // Identifier payments name the rail by
+	Name                 string  `json:"name" binding:"required"`
+	Description          string  `json:"description,omitempty"`
+	MinAmount            float64 `json:"minAmount"`
+	MaxAmount            float64 `json:"maxAmount" binding:"required"`
+	ProcessingTime       string  `json:"processingTime,omitempty"` // Duration such as "30m"
+	SettlementTime       string  `json:"settlementTime,omitempty"`
+	FixedFee             float64 `json:"fixedFee"`
+	PercentFee           float64 `json:"percentFee"` // Fraction of the amount, 0.029 = 2.9%
+	MinFee               float64 `json:"minFee"`
+	MaxFee               float64 `json:"maxFee"` // Caps the fee, so zero charges none
+	RiskLevel            string  `json:"riskLevel" binding:"required"`
+	Reversible           bool    `json:"reversible"`
+	International        bool    `json:"international"`
+	RequiresVerification bool    `json:"requiresVerification"`
+	MaxDescriptionLength int     `json:"maxDescriptionLength,omitempty"` // Zero for no limit
+	Reliability          float64 `json:"reliability"`
+	CounterpartyType     string  `json:"counterpartyType,omitempty"` // "account" (default), or "wallet" for crypto rails
+	Enabled              *bool   `json:"enabled,omitempty"`
+}
+
+type UpdateRailRequest struct {
+	Name                 *string  `json:"name"`
+	Description          *string  `json:"description"`
+	MinAmount            *float64 `json:"minAmount"`
+	MaxAmount            *float64 `json:"maxAmount"`
+	ProcessingTime       *string  `json:"processingTime"`
+	SettlementTime       *string  `json:"settlementTime"`
+	FixedFee             *float64 `json:"fixedFee"`
+	PercentFee           *float64 `json:"percentFee"`
+	MinFee               *float64 `json:"minFee"`
+	MaxFee               *float64 `json:"maxFee"`
+	RiskLevel            *string  `json:"riskLevel"`
+	Reversible           *bool    `json:"reversible"`
+	International        *bool    `json:"international"`
+	RequiresVerification *bool    `json:"requiresVerification"`
+	MaxDescriptionLength *int     `json:"maxDescriptionLength"`
+	Reliability          *float64 `json:"reliability"`
+	CounterpartyType     *string  `json:"counterpartyType"`
+	Enabled              *bool    `json:"enabled"`
+}
+
+type RailResponse struct {
+	Rail                 string  `json:"rail"`
+	Name                 string  `json:"name"`
+	Description          string  `json:"description,omitempty"`
+	MinAmount            float64 `json:"minAmount"`
+	MaxAmount            float64 `json:"maxAmount"`
+	ProcessingTime       string  `json:"processingTime"`
+	SettlementTime       string  `json:"settlementTime"`
+	FixedFee             float64 `json:"fixedFee"`
+	PercentFee           float64 `json:"percentFee"`
+	MinFee               float64 `json:"minFee"`
+	MaxFee               float64 `json:"maxFee"`
+	RiskLevel            string  `json:"riskLevel"`
+	Reversible           bool    `json:"reversible"`
+	International        bool    `json:"international"`
+	RequiresVerification bool    `json:"requiresVerification"`
+	MaxDescriptionLength int     `json:"maxDescriptionLength"`
+	Reliability          float64 `json:"reliability"`
+	CounterpartyType     string  `json:"counterpartyType"`
+	Enabled              bool    `json:"enabled"`
+	Builtin              bool    `json:"builtin"`
+	CreatedAt            string  `json:"createdAt"`
+	UpdatedAt            string  `json:"updatedAt"`
+}
+
+func toRailResponse(rail *database.PaymentRail) *RailResponse {
+	return &RailResponse{
+		Rail:                 rail.Rail,
+		Name:                 rail.Name,
+		Description:          rail.Description,
+		MinAmount:            rail.MinAmount,
+		MaxAmount:            rail.MaxAmount,
+		ProcessingTime:       (time.Duration(rail.ProcessingSeconds) * time.Second).String(),
+		SettlementTime:       (time.Duration(rail.SettlementSeconds) * time.Second).String(),
+		FixedFee:             rail.FixedFee,
+		PercentFee:           rail.PercentFee,
+		MinFee:               rail.MinFee,
+		MaxFee:               rail.MaxFee,
+		RiskLevel:            rail.RiskLevel,
+		Reversible:           rail.Reversible,
+		International:        rail.International,
+		RequiresVerification: rail.RequiresVerification,
+		MaxDescriptionLength: rail.MaxDescriptionLength,
+		Reliability:          rail.Reliability,
+		CounterpartyType:     rail.CounterpartyType,
+		Enabled:              rail.Enabled,
+		Builtin:              rail.Builtin,
+		CreatedAt:            rail.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:            rail.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// parseSeconds parses a duration such as "30m" into whole seconds; empty is zero
+func parseSeconds(field, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 30m", field)
+	}
+	return int(duration / time.Second), nil
+}
+
+// createRail adds a rail to the rail catalog. The orchestrator selects and
+// prices it, and the router routes payments on it, from the next payment
+// on; payments on it are only executed once the router has an adapter for
+// it.
+func createRail(c *gin.Context) {
+	var req RailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "rail, name, maxAmount and riskLevel are required"))
+		return
+	}
+
+	rail := &database.PaymentRail{
+		Rail:                 req.Rail,
+		Name:                 req.Name,
+		Description:          req.Description,
+		MinAmount:            req.MinAmount,
+		MaxAmount:            req.MaxAmount,
+		FixedFee:             req.FixedFee,
+		PercentFee:           req.PercentFee,
+		MinFee:               req.MinFee,
+		MaxFee:               req.MaxFee,
+		RiskLevel:            req.RiskLevel,
+		Reversible:           req.Reversible,
+		International:        req.International,
+		RequiresVerification: req.RequiresVerification,
+		MaxDescriptionLength: req.MaxDescriptionLength,
+		Reliability:          req.Reliability,
+		CounterpartyType:     req.CounterpartyType,
+		Enabled:              req.Enabled == nil || *req.Enabled,
+	}
+	if rail.CounterpartyType == "" {
+		rail.CounterpartyType = types.CounterpartyAccount
+	}
+	var err error
+	if rail.ProcessingSeconds, err = parseSeconds("processingTime", req.ProcessingTime); err == nil {
+		rail.SettlementSeconds, err = parseSeconds("settlementTime", req.SettlementTime)
+	}
+	if err == nil {
+		err = rails.Validate(rail)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	if _, err := repo.PaymentRailRepository().GetByRail(rail.Rail); err == nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("RAIL_EXISTS", fmt.Sprintf("Rail %s is already in the catalog", rail.Rail)))
+		return
+	}
+	if err := repo.PaymentRailRepository().Create(rail); err != nil {
+		common.Error("Failed to create rail %s: %v", rail.Rail, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create rail"))
+		return
+	}
+
+	common.Info("Added rail %s to the rail catalog", rail.Rail)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toRailResponse(rail)))
+}
+
+// loadRail fetches the catalog rail named in the path, writing the error
+// response itself when it returns nil
+func loadRail(c *gin.Context) *database.PaymentRail {
+	rail, err := repo.PaymentRailRepository().GetByRail(c.Param("rail"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Rail not found"))
+		return nil
+	}
+	if err != nil {
+		common.Error("Failed to load rail %s: %v", c.Param("rail"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load rail"))
+		return nil
+	}
+	return rail
+}
+
+// getRail returns a catalog rail as stored, enabled or not. Overrides in
+// the deployment's configuration are not applied.
+func getRail(c *gin.Context) {
+	rail := loadRail(c)
+	if rail == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toRailResponse(rail)))
+}
+
+// updateRail changes a catalog rail. Disabling it stops new payments on it;
+// payments already made on it are still settled, priced and reversed by it.
+func updateRail(c *gin.Context) {
+	var req UpdateRailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	rail := loadRail(c)
+	if rail == nil {
+		return
+	}
+	if req.Name != nil {
+		rail.Name = *req.Name
+	}
+	if req.Description != nil {
+		rail.Description = *req.Description
+	}
+	if req.MinAmount != nil {
+		rail.MinAmount = *req.MinAmount
+	}
+	if req.MaxAmount != nil {
+		rail.MaxAmount = *req.MaxAmount
+	}
+	if req.FixedFee != nil {
+		rail.FixedFee = *req.FixedFee
+	}
+	if req.PercentFee != nil {
+		rail.PercentFee = *req.PercentFee
+	}
+	if req.MinFee != nil {
+		rail.MinFee = *req.MinFee
+	}
+	if req.MaxFee != nil {
+		rail.MaxFee = *req.MaxFee
+	}
+	if req.RiskLevel != nil {
+		rail.RiskLevel = *req.RiskLevel
+	}
+	if req.Reversible != nil {
+		rail.Reversible = *req.Reversible
+	}
+	if req.International != nil {
+		rail.International = *req.International
+	}
+	if req.RequiresVerification != nil {
+		rail.RequiresVerification = *req.RequiresVerification
+	}
+	if req.MaxDescriptionLength != nil {
+		rail.MaxDescriptionLength = *req.MaxDescriptionLength
+	}
+	if req.Reliability != nil {
+		rail.Reliability = *req.Reliability
+	}
+	if req.CounterpartyType != nil {
+		rail.CounterpartyType = *req.CounterpartyType
+	}
+	if req.Enabled != nil {
+		rail.Enabled = *req.Enabled
+	}
+	var err error
+	if req.ProcessingTime != nil {
+		rail.ProcessingSeconds, err = parseSeconds("processingTime", *req.ProcessingTime)
+	}
+	if err == nil && req.SettlementTime != nil {
+		rail.SettlementSeconds, err = parseSeconds("settlementTime", *req.SettlementTime)
+	}
+	if err == nil {
+		err = rails.Validate(rail)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	if err := repo.PaymentRailRepository().Update(rail); err != nil {
+		common.Error("Failed to update rail %s: %v", rail.Rail, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update rail"))
+		return
+	}
+
+	common.Info("Updated rail %s of the rail catalog", rail.Rail)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toRailResponse(rail)))
+}
+
+// deleteRail removes a rail added to the catalog. Built-in rails are seeded
+// again at startup, so they are disabled instead.
+func deleteRail(c *gin.Context) {
+	rail := loadRail(c)
+	if rail == nil {
+		return
+	}
+	if rail.Builtin {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("BUILTIN_RAIL", fmt.Sprintf("Rail %s is built in; disable it instead", rail.Rail)))
+		return
+	}
+
+	if err := repo.PaymentRailRepository().Delete(rail.Rail); err != nil {
+		common.Error("Failed to delete rail %s: %v", rail.Rail, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete rail"))
+		return
+	}
+
+	common.Info("Removed rail %s from the rail catalog", rail.Rail)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"rail": rail.Rail, "deleted": true}))
+}
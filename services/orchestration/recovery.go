@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Workflow recovery
+//
+// Workflows are processed in goroutines, which a crash or restart loses. A
+// workflow being processed has its updated_at touched every heartbeat, so one
+// left in processing or awaiting_approval without an update for the stale
+// timeout has no goroutine behind it. The recovery loop claims such
+// workflows and resumes them after their last completed stage, or fails
+// them when resuming is unsafe or they are too old.
+//
+// On shutdown, workflows are given until the shutdown timeout to finish.
+// Those still running are checkpointed before their next stage, or while
+// waiting on a review or approval, and stop heartbeating so that recovery
+// resumes them once they go stale.
+
+var (
+	workflowStaleTimeout    time.Duration
+	workflowRecoveryMaxAge  time.Duration
+	workflowHeartbeatPeriod time.Duration
+)
+
+// activeWorkflows are the workflows this instance is processing, each with
+// the channel that stops its heartbeat and the context of its current stage
+var activeWorkflows = struct {
+	sync.Mutex
+	stops    map[string]chan struct{}
+	contexts map[string]context.Context
+}{stops: make(map[string]chan struct{}), contexts: make(map[string]context.Context)}
+
+// errWorkflowInterrupted is returned by a stage that stopped waiting because
+// the workflow is being checkpointed for shutdown
+var errWorkflowInterrupted = errors.New("workflow interrupted by shutdown")
+
+// initWorkflowRecovery reads the WORKFLOW_* settings and starts the recovery
+// loop, which first runs immediately and stops with ctx
+func initWorkflowRecovery(ctx context.Context) {
+	workflowStaleTimeout = time.Duration(common.GetEnvAsInt("WORKFLOW_STALE_TIMEOUT_MINUTES", 10)) * time.Minute
+	workflowRecoveryMaxAge = time.Duration(common.GetEnvAsInt("WORKFLOW_RECOVERY_MAX_AGE_HOURS", 24)) * time.Hour
+	workflowHeartbeatPeriod = workflowStaleTimeout / 3
+	interval := time.Duration(common.GetEnvAsInt("WORKFLOW_RECOVERY_INTERVAL_SECONDS", 60)) * time.Second
+
+	go func() {
+		recoverStaleWorkflows(time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				recoverStaleWorkflows(now)
+			}
+		}
+	}()
+}
+
+// pauseWorkflow waits between polls of a stage, returning
+// errWorkflowInterrupted if workflows are checkpointed for shutdown first
+func pauseWorkflow(d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-workflowWorkers.Context().Done():
+		return errWorkflowInterrupted
+	}
+}
+
+// checkpointWorkflow records that processing stopped for shutdown before
+// the named stage finished; recovery resumes the workflow from there
+func checkpointWorkflow(workflow *database.PaymentWorkflow, stage string) {
+	common.Info("Checkpointing workflow %s at %s for shutdown", workflow.ID, stage)
+	appendWorkflowStep(workflow, "shutdown", "completed", "Processing stopped for shutdown before "+stage+" finished")
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to checkpoint workflow %s: %v", workflow.ID, err)
+	}
+}
+
+// trackWorkflow marks a workflow as processed by this instance and starts its
+// heartbeat. It returns false if the workflow is already being processed.
+func trackWorkflow(workflowID string) bool {
+	activeWorkflows.Lock()
+	defer activeWorkflows.Unlock()
+	if _, active := activeWorkflows.stops[workflowID]; active {
+		return false
+	}
+	stop := make(chan struct{})
+	activeWorkflows.stops[workflowID] = stop
+
+	if workflowHeartbeatPeriod > 0 {
+		go func() {
+			ticker := time.NewTicker(workflowHeartbeatPeriod)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if err := repo.PaymentWorkflowRepository().Touch(workflowID); err != nil {
+						common.Warn("Failed to record heartbeat of workflow %s: %v", workflowID, err)
+					}
+				}
+			}
+		}()
+	}
+	return true
+}
+
+func untrackWorkflow(workflowID string) {
+	activeWorkflows.Lock()
+	defer activeWorkflows.Unlock()
+	if stop, active := activeWorkflows.stops[workflowID]; active {
+		close(stop)
+		delete(activeWorkflows.stops, workflowID)
+		delete(activeWorkflows.contexts, workflowID)
+	}
+}
+
+// setWorkflowContext records the context of the stage a workflow is running
+func setWorkflowContext(workflowID string, ctx context.Context) {
+	activeWorkflows.Lock()
+	defer activeWorkflows.Unlock()
+	activeWorkflows.contexts[workflowID] = ctx
+}
+
+// workflowContext returns the context of the stage a workflow is running, so
+// calls made for it are traced under the stage's span
+func workflowContext(workflow *database.PaymentWorkflow) context.Context {
+	activeWorkflows.Lock()
+	defer activeWorkflows.Unlock()
+	if ctx, ok := activeWorkflows.contexts[workflow.ID]; ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+func workflowActive(workflowID string) bool {
+	activeWorkflows.Lock()
+	defer activeWorkflows.Unlock()
+	_, active := activeWorkflows.stops[workflowID]
+	return active
+}
+
+// stageStatuses returns the last recorded status of each stage
+func stageStatuses(workflow *database.PaymentWorkflow) map[string]string {
+	statuses := make(map[string]string)
+	for _, step := range workflowSteps(workflow) {
+		statuses[step.Name] = step.Status
+	}
+	for name := range statuses {
+		if !isWorkflowStage(name) {
+			delete(statuses, name)
+		}
+	}
+	return statuses
+}
+
+func isWorkflowStage(name string) bool {
+	for _, stage := range workflowStages {
+		if stage.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// recoverStaleWorkflows takes over the workflows no instance is processing
+func recoverStaleWorkflows(now time.Time) {
+	stale, err := repo.PaymentWorkflowRepository().ListStale([]string{string(workflowstate.Processing), string(workflowstate.AwaitingApproval)}, now.Add(-workflowStaleTimeout))
+	if err != nil {
+		common.Error("Failed to list stale workflows: %v", err)
+		return
+	}
+
+	for _, workflow := range stale {
+		if workflowActive(workflow.ID) {
+			continue
+		}
+		claimed, err := repo.PaymentWorkflowRepository().Claim(workflow.ID, workflow.UpdatedAt)
+		if err != nil || !claimed {
+			continue
+		}
+		// Reload so the saved copy carries the claimed updated_at
+		if workflow, err = repo.PaymentWorkflowRepository().GetByID(workflow.ID); err != nil {
+			continue
+		}
+		recoverWorkflow(workflow, now)
+	}
+}
+
+// recoverWorkflow resumes a stale workflow after its last completed stage,
+// or fails it with the reason it cannot be resumed
+func recoverWorkflow(workflow *database.PaymentWorkflow, now time.Time) {
+	if reason := unrecoverableReason(workflow, now); reason != "" {
+		common.Warn("Failing stale workflow %s: %s", workflow.ID, reason)
+		appendWorkflowStep(workflow, "recovery", "failed", reason)
+		updateWorkflowStatus(workflow, workflowstate.Failed, reason)
+		return
+	}
+
+	common.Info("Recovering stale workflow %s", workflow.ID)
+	appendWorkflowStep(workflow, "recovery", "completed", "Resumed after the orchestrator stopped processing it")
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to record recovery of workflow %s: %v", workflow.ID, err)
+		return
+	}
+	startWorkflow(context.Background(), workflow)
+}
+
+// unrecoverableReason explains why a stale workflow must fail rather than
+// resume, or returns "" if it can resume
+func unrecoverableReason(workflow *database.PaymentWorkflow, now time.Time) string {
+	if age := now.Sub(workflow.CreatedAt); age > workflowRecoveryMaxAge {
+		return fmt.Sprintf("Processing stopped and the workflow is older than %s", workflowRecoveryMaxAge)
+	}
+	plan, err := workflowPlan(workflow)
+	if err != nil {
+		return "Workflow template unavailable: " + err.Error()
+	}
+	statuses := stageStatuses(workflow)
+	for _, stage := range plan {
+		if statuses[stage.name] == "completed" || statuses[stage.name] == "skipped" || stage.skip != "" {
+			continue
+		}
+		if stage.unsafe && statuses[stage.name] == "running" {
+			return "Processing stopped during " + stage.name + "; reconcile with the payment rail before retrying"
+		}
+		return ""
+	}
+	return ""
+}
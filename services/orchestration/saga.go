@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Sagas
+//
+// Payment execution touches the ledger and a payment rail, which cannot share
+// a transaction. It runs as a saga instead: each step registers a
+// compensating action, and when a step fails the compensations of the steps
+// already completed run in reverse order. Every step and compensation is
+// recorded in the workflow's steps, so a payment left half-compensated can
+// be reconciled by hand.
+
+// sagaStep is one step of a saga and the action that undoes it
+type sagaStep struct {
+	name         string
+	action       func(workflow *database.PaymentWorkflow) error
+	compensation string // Step name recorded for the compensation
+	compensate   func(workflow *database.PaymentWorkflow) error
+}
+
+// runSaga runs the steps in order. If one fails, it compensates the completed
+// steps in reverse order and returns the failure, noting any compensation
+// that failed too.
+func runSaga(workflow *database.PaymentWorkflow, steps []sagaStep) error {
+	for i, step := range steps {
+		if err := step.action(workflow); err != nil {
+			recordSagaStep(workflow, step.name, "failed", err.Error())
+			if failed := compensateSaga(workflow, steps[:i]); len(failed) > 0 {
+				return fmt.Errorf("%s failed: %v; compensation incomplete: %s", step.name, err, strings.Join(failed, ", "))
+			}
+			return fmt.Errorf("%s failed: %v", step.name, err)
+		}
+		recordSagaStep(workflow, step.name, "completed", "")
+	}
+	return nil
+}
+
+// compensateSaga undoes completed steps, newest first, and returns the names
+// of the compensations that failed. A failed compensation does not stop the
+// others.
+func compensateSaga(workflow *database.PaymentWorkflow, completed []sagaStep) []string {
+	var failed []string
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := completed[i]
+		if step.compensate == nil {
+			continue
+		}
+		if err := step.compensate(workflow); err != nil {
+			common.Error("Compensation %s for workflow %s failed: %v", step.compensation, workflow.ID, err)
+			recordSagaStep(workflow, step.compensation, "failed", err.Error())
+			failed = append(failed, step.compensation)
+			continue
+		}
+		common.Info("Compensated %s for workflow %s", step.name, workflow.ID)
+		recordSagaStep(workflow, step.compensation, "completed", "Compensated "+step.name)
+	}
+	return failed
+}
+
+func recordSagaStep(workflow *database.PaymentWorkflow, name, status, message string) {
+	appendWorkflowStep(workflow, name, status, message)
+	if err := repo.PaymentWorkflowRepository().Update(workflow); err != nil {
+		common.Error("Failed to record %s for workflow %s: %v", name, workflow.ID, err)
+	}
+}
@@ -0,0 +1,656 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/fx"
+	"github.com/example/agent-payments/internal/schedules"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Payment schedules
+//
+// A schedule is a standing payment an agent makes on a cron expression or at
+// a fixed interval, such as a weekly SaaS bill, until its end date or run
+// limit. The scheduler polls every PAYMENT_SCHEDULER_INTERVAL_SECONDS (30)
+// for schedules that are due, creates each payment as the API would and
+// starts processing it. Times missed while the scheduler was down, or the
+// schedule paused, are not made up: the latest one is paid once. A schedule
+// whose payments cannot be created PAYMENT_SCHEDULE_MAX_FAILURES (3) times
+// in a row is paused.
+
+// schedulerActor is the actor recorded for payments a schedule creates
+const schedulerActor = "scheduler"
+
+// scheduleBatchSize is how many due schedules one poll runs
+const scheduleBatchSize = 100
+
+var scheduleMaxFailures int
+
+type PaymentScheduleRequest struct {
+	AgentID        string            `json:"agentId" binding:"required"`
+	Amount         float64           `json:"amount" binding:"required,gt=0"`
+	Currency       string            `json:"currency,omitempty"`
+	Counterparty   string            `json:"counterparty,omitempty"`
+	CounterpartyID string            `json:"counterpartyId,omitempty"`
+	Rail           string            `json:"rail,omitempty"` // Empty selects a rail for each payment
+	Preferences    *RailPreferences  `json:"preferences,omitempty"`
+	Description    string            `json:"description,omitempty" binding:"max=500"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	CallbackURL    string            `json:"callbackUrl,omitempty"`
+	Category       string            `json:"category,omitempty" binding:"max=100"`
+	Cron           string            `json:"cron,omitempty"`     // e.g. "0 9 * * 1" for Mondays at 09:00
+	Interval       string            `json:"interval,omitempty"` // e.g. "168h"; set cron or interval
+	Timezone       string            `json:"timezone,omitempty"` // IANA zone of the cron expression, default UTC
+	StartAt        *time.Time        `json:"startAt,omitempty"`  // Default now
+	EndAt          *time.Time        `json:"endAt,omitempty"`
+	MaxRuns        int               `json:"maxRuns,omitempty" binding:"gte=0"` // Payments to make; 0 for no limit
+}
+
+type ScheduleActionRequest struct {
+	Reason string `json:"reason,omitempty" binding:"max=500"`
+}
+
+type PaymentScheduleResponse struct {
+	ID             string            `json:"id"`
+	AgentID        string            `json:"agentId"`
+	Amount         float64           `json:"amount"`
+	Currency       string            `json:"currency"`
+	Counterparty   string            `json:"counterparty"`
+	CounterpartyID string            `json:"counterpartyId,omitempty"`
+	Rail           string            `json:"rail,omitempty"`
+	Preferences    *RailPreferences  `json:"preferences,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	CallbackURL    string            `json:"callbackUrl,omitempty"`
+	Category       string            `json:"category,omitempty"`
+	Cron           string            `json:"cron,omitempty"`
+	Interval       string            `json:"interval,omitempty"`
+	Timezone       string            `json:"timezone,omitempty"`
+	StartAt        string            `json:"startAt"`
+	EndAt          string            `json:"endAt,omitempty"`
+	MaxRuns        int               `json:"maxRuns,omitempty"`
+	Status         string            `json:"status"`
+	StatusReason   string            `json:"statusReason,omitempty"`
+	NextRunAt      string            `json:"nextRunAt,omitempty"`
+	LastRunAt      string            `json:"lastRunAt,omitempty"`
+	RunCount       int               `json:"runCount"`
+	FailureCount   int               `json:"failureCount"` // Consecutive failed runs
+	CreatedBy      string            `json:"createdBy,omitempty"`
+	CreatedAt      string            `json:"createdAt"`
+	UpdatedAt      string            `json:"updatedAt"`
+}
+
+type PaymentScheduleRunResponse struct {
+	ID            string `json:"id"`
+	ScheduledFor  string `json:"scheduledFor"`
+	Status        string `json:"status"` // "created" or "failed"
+	WorkflowID    string `json:"workflowId,omitempty"`
+	PaymentStatus string `json:"paymentStatus,omitempty"` // Current status of the payment created
+	Error         string `json:"error,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+func toPaymentScheduleResponse(schedule *database.PaymentSchedule) *PaymentScheduleResponse {
+	response := &PaymentScheduleResponse{
+		ID:           schedule.ID,
+		AgentID:      schedule.AgentID,
+		Amount:       schedule.Amount,
+		Currency:     schedule.Currency,
+		Counterparty: schedule.Counterparty,
+		Rail:         schedule.Rail,
+		Description:  schedule.Description,
+		Metadata:     decodeMetadata(schedule.Metadata),
+		CallbackURL:  schedule.CallbackURL,
+		Category:     schedule.Category,
+		Cron:         schedule.Cron,
+		Timezone:     schedule.Timezone,
+		StartAt:      schedule.StartAt.Format(time.RFC3339),
+		MaxRuns:      schedule.MaxRuns,
+		Status:       schedule.Status,
+		StatusReason: schedule.StatusReason,
+		RunCount:     schedule.RunCount,
+		FailureCount: schedule.FailureCount,
+		CreatedBy:    schedule.CreatedBy,
+		CreatedAt:    schedule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    schedule.UpdatedAt.Format(time.RFC3339),
+	}
+	if schedule.CounterpartyID != nil {
+		response.CounterpartyID = *schedule.CounterpartyID
+	}
+	if schedule.Preferences != "" {
+		json.Unmarshal([]byte(schedule.Preferences), &response.Preferences)
+	}
+	if schedule.IntervalSeconds > 0 {
+		response.Interval = (time.Duration(schedule.IntervalSeconds) * time.Second).String()
+	}
+	if schedule.EndAt != nil {
+		response.EndAt = schedule.EndAt.Format(time.RFC3339)
+	}
+	if schedule.NextRunAt != nil {
+		response.NextRunAt = schedule.NextRunAt.Format(time.RFC3339)
+	}
+	if schedule.LastRunAt != nil {
+		response.LastRunAt = schedule.LastRunAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// scheduleSpec returns when a stored schedule is due
+func scheduleSpec(schedule *database.PaymentSchedule) (schedules.Spec, error) {
+	location, err := schedules.ParseLocation(schedule.Timezone)
+	if err != nil {
+		return schedules.Spec{}, err
+	}
+	return schedules.Spec{
+		Cron:     schedule.Cron,
+		Interval: time.Duration(schedule.IntervalSeconds) * time.Second,
+		Location: location,
+		Start:    schedule.StartAt,
+	}, nil
+}
+
+// nextScheduleRun returns the schedule's first due time after the given
+// time, or nil once it has ended
+func nextScheduleRun(schedule *database.PaymentSchedule, spec schedules.Spec, after time.Time) *time.Time {
+	if schedule.MaxRuns > 0 && schedule.RunCount >= schedule.MaxRuns {
+		return nil
+	}
+	next := spec.Next(after)
+	if next.IsZero() || (schedule.EndAt != nil && next.After(*schedule.EndAt)) {
+		return nil
+	}
+	return &next
+}
+
+// initPaymentScheduler reads the PAYMENT_SCHEDULE* settings and runs due
+// schedules until ctx is done
+func initPaymentScheduler(ctx context.Context) {
+	scheduleMaxFailures = common.GetEnvAsInt("PAYMENT_SCHEDULE_MAX_FAILURES", 3)
+	interval := time.Duration(common.GetEnvAsInt("PAYMENT_SCHEDULER_INTERVAL_SECONDS", 30)) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				runDueSchedules(ctx, now)
+			}
+		}
+	}()
+}
+
+// runDueSchedules runs the schedules due by now
+func runDueSchedules(ctx context.Context, now time.Time) {
+	due, err := repo.PaymentScheduleRepository().ListDue(now, scheduleBatchSize)
+	if err != nil {
+		common.Error("Failed to list due payment schedules: %v", err)
+		return
+	}
+	for _, schedule := range due {
+		runSchedule(ctx, schedule, now)
+	}
+}
+
+// runSchedule claims a schedule's due run, creates its payment and starts
+// processing it. Another instance may claim the run first.
+func runSchedule(ctx context.Context, schedule *database.PaymentSchedule, now time.Time) {
+	spec, err := scheduleSpec(schedule)
+	if err != nil {
+		common.Error("Payment schedule %s is invalid: %v", schedule.ID, err)
+		return
+	}
+	scheduledFor := *schedule.NextRunAt
+	claimed, err := repo.PaymentScheduleRepository().Claim(schedule.ID, scheduledFor, nextScheduleRun(schedule, spec, now))
+	if err != nil || !claimed {
+		return
+	}
+
+	run := &database.PaymentScheduleRun{ScheduleID: schedule.ID, ScheduledFor: scheduledFor}
+	workflow, createErr := createScheduledPayment(ctx, schedule)
+	if createErr != nil {
+		run.Status, run.Error = schedules.RunFailed, truncate(createErr.Error(), 500)
+	} else {
+		run.Status, run.WorkflowID = schedules.RunCreated, &workflow.ID
+	}
+	if err := repo.PaymentScheduleRepository().CreateRun(run); err != nil {
+		common.Error("Failed to record run of payment schedule %s: %v", schedule.ID, err)
+	}
+
+	// Reload so a pause or cancellation made meanwhile is kept
+	if current, err := repo.PaymentScheduleRepository().GetByID(schedule.ID); err == nil {
+		schedule = current
+	}
+	recordScheduleRun(schedule, spec, scheduledFor, createErr, now)
+	if workflow != nil {
+		startScheduledPayment(ctx, schedule, workflow)
+	}
+}
+
+// createScheduledPayment creates a payment from a schedule, as the API
+// would for the same request
+func createScheduledPayment(ctx context.Context, schedule *database.PaymentSchedule) (*database.PaymentWorkflow, error) {
+	agent, err := repo.AgentRepository().GetByID(schedule.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("agent not found")
+	}
+	req := PaymentRequest{
+		AgentID:      schedule.AgentID,
+		Amount:       schedule.Amount,
+		Currency:     schedule.Currency,
+		Counterparty: schedule.Counterparty,
+		Rail:         schedule.Rail,
+		Description:  schedule.Description,
+		Metadata:     decodeMetadata(schedule.Metadata),
+		CallbackURL:  schedule.CallbackURL,
+		Category:     schedule.Category,
+	}
+	if schedule.CounterpartyID != nil {
+		req.CounterpartyID = *schedule.CounterpartyID
+	}
+	if schedule.Preferences != "" {
+		json.Unmarshal([]byte(schedule.Preferences), &req.Preferences)
+	}
+	if _, err := resolvePaymentAmount(ctx, &req); err != nil {
+		return nil, err
+	}
+	if perr := resolveCounterparty(agent, &req); perr != nil {
+		return nil, perr
+	}
+	workflow, perr := newPaymentWorkflow(agent, &req)
+	if perr != nil {
+		return nil, perr
+	}
+	workflow.ScheduleID = &schedule.ID
+
+	err = createPaymentWorkflow(ctx, workflow, schedulerActor, func(trail *audit.AuditTrail) error {
+		entry := paymentInitiatedAuditEntry(workflow)
+		entry.UserID = schedulerActor
+		entry.Description += " by payment schedule " + schedule.ID
+		return trail.LogEvent(ctx, entry)
+	})
+	if err != nil {
+		common.Error("Failed to create payment for schedule %s: %v", schedule.ID, err)
+		return nil, errors.New("failed to create payment workflow")
+	}
+	return workflow, nil
+}
+
+// recordScheduleRun counts a run on its schedule: a created payment resets
+// the failure count, and too many failures in a row pause the schedule
+func recordScheduleRun(schedule *database.PaymentSchedule, spec schedules.Spec, scheduledFor time.Time, err error, now time.Time) {
+	if err != nil {
+		schedule.FailureCount++
+		common.Warn("Payment schedule %s failed to create a payment: %v", schedule.ID, err)
+		if schedule.Status == schedules.StatusActive && schedule.FailureCount >= scheduleMaxFailures {
+			schedule.Status = schedules.StatusPaused
+			schedule.StatusReason = truncate(fmt.Sprintf("Paused after %d failed runs: %v", schedule.FailureCount, err), 500)
+			schedule.NextRunAt = nil
+			common.Warn("Paused payment schedule %s after %d failed runs", schedule.ID, schedule.FailureCount)
+		}
+	} else {
+		schedule.FailureCount = 0
+		schedule.RunCount++
+		schedule.LastRunAt = &scheduledFor
+	}
+	if schedule.Status == schedules.StatusActive {
+		schedule.NextRunAt = nextScheduleRun(schedule, spec, now)
+		if schedule.NextRunAt == nil {
+			schedule.Status = schedules.StatusCompleted
+			schedule.StatusReason = "Reached its end date or run limit"
+		}
+	}
+	if err := repo.PaymentScheduleRepository().Update(schedule); err != nil {
+		common.Error("Failed to update payment schedule %s: %v", schedule.ID, err)
+	}
+}
+
+// startScheduledPayment moves a scheduled payment to processing and starts
+// its workflow
+func startScheduledPayment(ctx context.Context, schedule *database.PaymentSchedule, workflow *database.PaymentWorkflow) {
+	if workflow == nil {
+		return
+	}
+	if err := workflowStates.Transition(workflow, workflowstate.Processing, "Scheduled by payment schedule "+schedule.ID, schedulerActor); err != nil {
+		common.Error("Failed to start scheduled payment %s: %v", workflow.ID, err)
+		return
+	}
+	common.Info("Payment schedule %s created payment %s", schedule.ID, workflow.ID)
+	startWorkflow(ctx, workflow)
+}
+
+// createPaymentSchedule sets up a standing payment for an agent. The request
+// is checked as a payment would be; each payment is still checked in full
+// when it is made.
+func createPaymentSchedule(c *gin.Context) {
+	var req PaymentScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if req.Counterparty == "" && req.CounterpartyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "counterparty or counterpartyId is required"))
+		return
+	}
+	if req.CounterpartyID == "" && requireCounterpartyIDs {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("COUNTERPARTY_ID_REQUIRED", "Payments must reference a counterparty by counterpartyId"))
+		return
+	}
+	// Each payment needs its own signed mandate, which a schedule cannot carry
+	if requireMandates {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("MANDATE_REQUIRED", "Scheduled payments are unavailable while signed mandates are required"))
+		return
+	}
+	currency, err := fx.NormalizeCurrency(req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_CURRENCY", err.Error()))
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(c.Request.Context(), req.CallbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_CALLBACK_URL", err.Error()))
+			return
+		}
+	}
+
+	// When the schedule is due
+	start := time.Now()
+	if req.StartAt != nil {
+		start = *req.StartAt
+	}
+	var interval time.Duration
+	if req.Interval != "" {
+		if interval, err = time.ParseDuration(req.Interval); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "interval must be a duration such as 168h"))
+			return
+		}
+	}
+	location, err := schedules.ParseLocation(req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	spec := schedules.Spec{Cron: req.Cron, Interval: interval, Location: location, Start: start}
+	if err := spec.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if req.EndAt != nil && !req.EndAt.After(start) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "endAt must be after startAt"))
+		return
+	}
+
+	// Agents may only schedule payments on their own behalf
+	if !common.CanActForAgent(c, req.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot schedule payments for this agent"))
+		return
+	}
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
+		return
+	}
+	if principal := common.GetPrincipal(c); principal != nil && principal.Type == common.PrincipalParty && principal.PartyID != agent.OwnerPartyID {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot schedule payments for this agent"))
+		return
+	}
+	if credential := c.GetHeader(AgentCredentialHeader); credential != "" || requireAgentCredentials {
+		if err := verifyAgentCredential(c.Request.Context(), req.AgentID, credential); err != nil {
+			common.Warn("Rejected payment schedule for agent %s: %v", req.AgentID, err)
+			c.JSON(http.StatusUnauthorized, common.NewErrorResponse("INVALID_AGENT_CREDENTIAL", err.Error()))
+			return
+		}
+	}
+
+	// Check the counterparty and rail as the first payment would be
+	payment := PaymentRequest{AgentID: req.AgentID, Amount: req.Amount, Currency: currency, Counterparty: req.Counterparty, CounterpartyID: req.CounterpartyID}
+	if _, err := resolvePaymentAmount(c.Request.Context(), &payment); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("UNSUPPORTED_CURRENCY", err.Error()))
+		return
+	}
+	if perr := resolveCounterparty(agent, &payment); perr != nil {
+		c.JSON(perr.status, common.NewErrorResponse(perr.code, perr.message))
+		return
+	}
+	if req.Rail != "" && !validTemplateRail(req.Rail) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("RAIL_VALIDATION_ERROR", "Unknown rail "+req.Rail))
+		return
+	}
+
+	schedule := &database.PaymentSchedule{
+		AgentID:         req.AgentID,
+		Amount:          payment.Amount,
+		Currency:        currency,
+		Counterparty:    payment.Counterparty,
+		Rail:            req.Rail,
+		Description:     req.Description,
+		Metadata:        encodeMetadata(req.Metadata),
+		CallbackURL:     req.CallbackURL,
+		Category:        req.Category,
+		Cron:            req.Cron,
+		IntervalSeconds: int64(interval / time.Second),
+		Timezone:        req.Timezone,
+		StartAt:         start,
+		EndAt:           req.EndAt,
+		MaxRuns:         req.MaxRuns,
+		Status:          schedules.StatusActive,
+		CreatedBy:       principalSubject(c),
+	}
+	if req.CounterpartyID != "" {
+		schedule.CounterpartyID = &req.CounterpartyID
+	}
+	if req.Preferences != nil {
+		data, _ := json.Marshal(req.Preferences)
+		schedule.Preferences = string(data)
+	}
+	first := spec.First()
+	if req.EndAt != nil && first.After(*req.EndAt) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "The schedule is not due before endAt"))
+		return
+	}
+	schedule.NextRunAt = &first
+
+	if err := repo.PaymentScheduleRepository().Create(schedule); err != nil {
+		common.Error("Failed to create payment schedule for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create payment schedule"))
+		return
+	}
+
+	common.Info("Created payment schedule %s for agent %s, first due %s", schedule.ID, schedule.AgentID, first.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toPaymentScheduleResponse(schedule)))
+}
+
+// listPaymentSchedules lists schedules, filtered by ?agentId= and ?status=.
+// Agents see their own and parties their agents'.
+func listPaymentSchedules(c *gin.Context) {
+	agentID, status := c.Query("agentId"), c.Query("status")
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalAgent {
+		agentID = principal.AgentID
+	}
+
+	var list []*database.PaymentSchedule
+	var err error
+	if principal != nil && principal.Type == common.PrincipalParty && agentID == "" {
+		var agents []*database.Agent
+		if agents, err = repo.AgentRepository().ListByOwnerPartyID(principal.PartyID); err == nil {
+			agentIDs := make([]string, 0, len(agents))
+			for _, agent := range agents {
+				agentIDs = append(agentIDs, agent.ID)
+			}
+			list, err = repo.PaymentScheduleRepository().ListByAgentIDs(agentIDs, status)
+		}
+	} else if tenantID := common.RequestTenant(c); agentID == "" && tenantID != "" {
+		list, err = repo.PaymentScheduleRepository().ListByTenantID(tenantID, status)
+	} else {
+		if agentID != "" && !canAccessAgent(c, agentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view this agent's payment schedules"))
+			return
+		}
+		list, err = repo.PaymentScheduleRepository().List(agentID, status)
+	}
+	if err != nil {
+		common.Error("Failed to list payment schedules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment schedules"))
+		return
+	}
+
+	items := make([]interface{}, 0, len(list))
+	for _, schedule := range list {
+		items = append(items, toPaymentScheduleResponse(schedule))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getPaymentSchedule(c *gin.Context) {
+	schedule, ok := loadPaymentSchedule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentScheduleResponse(schedule)))
+}
+
+// listPaymentScheduleRuns lists a schedule's runs, newest first, with the
+// current status of each payment created
+func listPaymentScheduleRuns(c *gin.Context) {
+	schedule, ok := loadPaymentSchedule(c)
+	if !ok {
+		return
+	}
+	runs, err := repo.PaymentScheduleRepository().ListRuns(schedule.ID, 100)
+	if err != nil {
+		common.Error("Failed to list runs of payment schedule %s: %v", schedule.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment schedule runs"))
+		return
+	}
+
+	items := make([]interface{}, 0, len(runs))
+	for _, run := range runs {
+		response := &PaymentScheduleRunResponse{
+			ID:           run.ID,
+			ScheduledFor: run.ScheduledFor.Format(time.RFC3339),
+			Status:       run.Status,
+			Error:        run.Error,
+			CreatedAt:    run.CreatedAt.Format(time.RFC3339),
+		}
+		if run.WorkflowID != nil {
+			response.WorkflowID = *run.WorkflowID
+			if workflow, err := repo.PaymentWorkflowRepository().GetByID(*run.WorkflowID); err == nil {
+				response.PaymentStatus = workflow.Status
+			}
+		}
+		items = append(items, response)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// pausePaymentSchedule stops an active schedule until it is resumed
+func pausePaymentSchedule(c *gin.Context) {
+	changeScheduleStatus(c, schedules.StatusPaused, "Paused", func(schedule *database.PaymentSchedule) bool {
+		return schedule.Status == schedules.StatusActive
+	})
+}
+
+// resumePaymentSchedule restarts a paused schedule from its next due time;
+// times missed while paused are not paid
+func resumePaymentSchedule(c *gin.Context) {
+	changeScheduleStatus(c, schedules.StatusActive, "", func(schedule *database.PaymentSchedule) bool {
+		return schedule.Status == schedules.StatusPaused
+	})
+}
+
+// cancelPaymentSchedule ends a schedule for good
+func cancelPaymentSchedule(c *gin.Context) {
+	changeScheduleStatus(c, schedules.StatusCancelled, "Cancelled", func(schedule *database.PaymentSchedule) bool {
+		return schedule.Status == schedules.StatusActive || schedule.Status == schedules.StatusPaused
+	})
+}
+
+func changeScheduleStatus(c *gin.Context, status, message string, allowed func(schedule *database.PaymentSchedule) bool) {
+	var req ScheduleActionRequest
+	c.ShouldBindJSON(&req)
+
+	schedule, ok := loadPaymentSchedule(c)
+	if !ok {
+		return
+	}
+	if !allowed(schedule) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", fmt.Sprintf("Payment schedule is %s", schedule.Status)))
+		return
+	}
+
+	schedule.Status = status
+	schedule.StatusReason = message
+	if message != "" && req.Reason != "" {
+		schedule.StatusReason = truncate(message+": "+req.Reason, 500)
+	}
+	schedule.NextRunAt = nil
+	if status == schedules.StatusActive {
+		spec, err := scheduleSpec(schedule)
+		if err != nil {
+			common.Error("Payment schedule %s is invalid: %v", schedule.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INVALID_SCHEDULE", "Payment schedule is invalid"))
+			return
+		}
+		schedule.FailureCount = 0
+		if schedule.NextRunAt = nextScheduleRun(schedule, spec, time.Now()); schedule.NextRunAt == nil {
+			schedule.Status = schedules.StatusCompleted
+			schedule.StatusReason = "Reached its end date or run limit"
+		}
+	}
+	if err := repo.PaymentScheduleRepository().Update(schedule); err != nil {
+		common.Error("Failed to update payment schedule %s: %v", schedule.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update payment schedule"))
+		return
+	}
+
+	common.Info("Payment schedule %s is now %s", schedule.ID, schedule.Status)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentScheduleResponse(schedule)))
+}
+
+// loadPaymentSchedule loads the schedule named in the path, responding with
+// an error if it is missing or the principal cannot see it
+func loadPaymentSchedule(c *gin.Context) (*database.PaymentSchedule, bool) {
+	schedule, err := repo.PaymentScheduleRepository().GetByID(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !canAccessAgent(c, schedule.AgentID)) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment schedule not found"))
+		return nil, false
+	}
+	if err != nil {
+		common.Error("Failed to load payment schedule %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load payment schedule"))
+		return nil, false
+	}
+	return schedule, true
+}
+
+// canAccessAgent reports whether the principal may see and manage what
+// belongs to an agent, such as its schedules: the agent itself, its owner
+// party, a user of its tenant, or a service
+func canAccessAgent(c *gin.Context, agentID string) bool {
+	if !common.CanActForAgent(c, agentID) {
+		return false
+	}
+	principal := common.GetPrincipal(c)
+	if principal != nil && principal.Type == common.PrincipalParty {
+		agent, err := repo.AgentRepository().GetByID(agentID)
+		if err != nil || agent.OwnerPartyID != principal.PartyID {
+			return false
+		}
+	}
+	return tenancy.CheckAgent(c, repo, agentID, "agent", agentID)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/internal/workflowstate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatementTokenTTL = 30 * 24 * time.Hour
+	maxStatementTokenTTL     = 365 * 24 * time.Hour
+	statementTokenPrefixLen  = 10
+)
+
+type CreateStatementTokenRequest struct {
+	AgentID      string `json:"agentId" binding:"required"`
+	Counterparty string `json:"counterparty" binding:"required"`
+	TTLSeconds   int    `json:"ttlSeconds,omitempty"`
+}
+
+type StatementTokenResponse struct {
+	ID           string `json:"id"`
+	PartyID      string `json:"partyId"`
+	AgentID      string `json:"agentId"`
+	Counterparty string `json:"counterparty"`
+	Token        string `json:"token,omitempty"` // Only returned at creation
+	TokenPrefix  string `json:"tokenPrefix"`
+	URL          string `json:"url,omitempty"` // Only returned at creation
+	Status       string `json:"status"`
+	ExpiresAt    string `json:"expiresAt"`
+	RevokedAt    string `json:"revokedAt,omitempty"`
+	LastUsedAt   string `json:"lastUsedAt,omitempty"`
+	CreatedBy    string `json:"createdBy,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// StatementLine is one payment on a counterparty statement
+type StatementLine struct {
+	Reference   string  `json:"reference"` // Payment ID, as quoted to the counterparty
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Description string  `json:"description,omitempty"`
+	Date        string  `json:"date"`
+}
+
+type StatementResponse struct {
+	Payer        string           `json:"payer"`
+	Counterparty string           `json:"counterparty"`
+	ExpiresAt    string           `json:"expiresAt"`
+	Payments     []*StatementLine `json:"payments"`
+	Meta         common.Meta      `json:"meta"`
+}
+
+func statementURL(token string) string {
+	base := strings.TrimSuffix(common.GetEnv("STATEMENT_BASE_URL", "http://localhost:8080/statements"), "/")
+	return base + "/" + token
+}
+
+func statementTokenStatus(token *database.StatementToken) string {
+	switch {
+	case token.RevokedAt != nil:
+		return "revoked"
+	case time.Now().After(token.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+func toStatementTokenResponse(token *database.StatementToken) *StatementTokenResponse {
+	response := &StatementTokenResponse{
+		ID:           token.ID,
+		PartyID:      token.PartyID,
+		AgentID:      token.AgentID,
+		Counterparty: token.Counterparty,
+		TokenPrefix:  token.TokenPrefix,
+		Status:       statementTokenStatus(token),
+		ExpiresAt:    token.ExpiresAt.Format(time.RFC3339),
+		CreatedBy:    token.CreatedBy,
+		CreatedAt:    token.CreatedAt.Format(time.RFC3339),
+	}
+	if token.RevokedAt != nil {
+		response.RevokedAt = token.RevokedAt.Format(time.RFC3339)
+	}
+	if token.LastUsedAt != nil {
+		response.LastUsedAt = token.LastUsedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// createStatementToken issues a token letting a counterparty view the
+// payments one of the owner's agents made to it. The token is returned once;
+// only its hash is kept.
+func createStatementToken(c *gin.Context) {
+	var req CreateStatementTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId and counterparty are required"))
+		return
+	}
+
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	if !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot share statements of this agent"))
+		return
+	}
+
+	ttl := defaultStatementTokenTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl > maxStatementTokenTTL {
+			ttl = maxStatementTokenTTL
+		}
+	}
+
+	random, err := common.GenerateRandomString(40)
+	if err != nil {
+		common.Error("Failed to generate statement token: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("INTERNAL_ERROR", "Failed to create statement token"))
+		return
+	}
+	plaintext := "st_" + random
+
+	token := &database.StatementToken{
+		PartyID:      agent.OwnerPartyID,
+		AgentID:      agent.ID,
+		Counterparty: req.Counterparty,
+		TokenPrefix:  plaintext[:statementTokenPrefixLen],
+		TokenHash:    common.HashAPIKey(plaintext),
+		ExpiresAt:    time.Now().Add(ttl),
+		CreatedBy:    principalSubject(c),
+	}
+	if err := repo.StatementTokenRepository().Create(token); err != nil {
+		common.Error("Failed to create statement token: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create statement token"))
+		return
+	}
+
+	response := toStatementTokenResponse(token)
+	response.Token = plaintext
+	response.URL = statementURL(plaintext)
+
+	common.Info("Created statement token %s for agent %s and counterparty %s", token.ID, agent.ID, token.Counterparty)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+// listStatementTokens lists a party's statement tokens (?partyId=, defaulting
+// to the caller's party, and optionally ?agentId=), newest first
+func listStatementTokens(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view statement tokens of this party"))
+		return
+	}
+
+	tokens, err := repo.StatementTokenRepository().ListByPartyID(partyID, c.Query("agentId"))
+	if err != nil {
+		common.Error("Failed to list statement tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list statement tokens"))
+		return
+	}
+
+	items := make([]interface{}, len(tokens))
+	for i, token := range tokens {
+		items[i] = toStatementTokenResponse(token)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// revokeStatementToken stops a token from opening its statement
+func revokeStatementToken(c *gin.Context) {
+	token, err := repo.StatementTokenRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, token.PartyID, "statement_token", token.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Statement token not found"))
+		return
+	}
+	if !canManageParty(c, token.PartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot revoke statement tokens of this party"))
+		return
+	}
+
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		if err := repo.StatementTokenRepository().Update(token); err != nil {
+			common.Error("Failed to revoke statement token %s: %v", token.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to revoke statement token"))
+			return
+		}
+		common.Info("Revoked statement token %s of party %s", token.ID, token.PartyID)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toStatementTokenResponse(token)))
+}
+
+// getStatement serves the statement a token opens: the completed payments
+// its agent made to its counterparty, newest first (?limit=&offset=&from=&to=).
+// Lines carry only what the counterparty needs to reconcile them, and a token
+// never reaches the agent's other payments or the rest of the tenant.
+func getStatement(c *gin.Context) {
+	token, err := repo.StatementTokenRepository().GetByTokenHash(common.HashAPIKey(c.Param("token")))
+	if err != nil || statementTokenStatus(token) != "active" {
+		// Unknown, expired and revoked tokens look the same to the caller
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Statement not found"))
+		return
+	}
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	params.Sort = "" // Statements are always by date
+
+	workflows, total, err := repo.PaymentWorkflowRepository().ListPage(database.PaymentWorkflowFilter{
+		AgentID:      token.AgentID,
+		Counterparty: token.Counterparty,
+		Status:       string(workflowstate.Completed),
+	}, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to list payments for statement token %s: %v", token.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load statement"))
+		return
+	}
+
+	now := time.Now()
+	token.LastUsedAt = &now
+	if err := repo.StatementTokenRepository().Update(token); err != nil {
+		common.Warn("Failed to record use of statement token %s: %v", token.ID, err)
+	}
+
+	response := &StatementResponse{
+		Counterparty: token.Counterparty,
+		ExpiresAt:    token.ExpiresAt.Format(time.RFC3339),
+		Payments:     make([]*StatementLine, 0, len(workflows)),
+	}
+	if agent, err := repo.AgentRepository().GetByID(token.AgentID); err == nil {
+		response.Payer = agent.DisplayName
+	}
+	for _, workflow := range workflows {
+		response.Payments = append(response.Payments, &StatementLine{
+			Reference:   workflow.ID,
+			Amount:      workflow.Amount,
+			Currency:    workflow.Currency,
+			Description: workflow.Description,
+			Date:        workflow.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	response.Meta = common.NewPageResponse(make([]interface{}, len(workflows)), params, total).Meta
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOtherTenantsSchedulesAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+
+	// Two tenants, each an organization with an agent paying a weekly bill
+	agents := map[string]*database.Agent{}
+	schedules := map[string]*database.PaymentSchedule{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		schedule := &database.PaymentSchedule{AgentID: agent.ID, Amount: 49, Currency: "USD", Counterparty: "saas@example.com", IntervalSeconds: 7 * 24 * 3600, StartAt: time.Now(), Status: "active"}
+		if err := repo.PaymentScheduleRepository().Create(schedule); err != nil {
+			t.Fatal(err)
+		}
+		agents[name], schedules[name] = agent, schedule
+	}
+
+	get := func(principal *common.Principal, path string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", principal) })
+		router.GET("/v1/payment-schedules", listPaymentSchedules)
+		router.GET("/v1/payment-schedules/:id", getPaymentSchedule)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: agents["Acme"].OwnerPartyID, TenantID: agents["Acme"].OwnerPartyID}
+
+	if recorder := get(acme, "/v1/payment-schedules/"+schedules["Acme"].ID); recorder.Code != http.StatusOK {
+		t.Fatalf("own schedule: got %d", recorder.Code)
+	}
+	if recorder := get(acme, "/v1/payment-schedules/"+schedules["Globex"].ID); recorder.Code != http.StatusNotFound {
+		t.Fatalf("other tenant's schedule: got %d, want 404", recorder.Code)
+	}
+
+	// The tenant lists only its own schedules
+	recorder := get(acme, "/v1/payment-schedules")
+	var response struct {
+		Data struct {
+			Items []struct {
+				ID string `json:"id"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data.Items) != 1 || response.Data.Items[0].ID != schedules["Acme"].ID {
+		t.Fatalf("tenant's schedules: got %s", recorder.Body)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/counterparties"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/internal/workflowtemplate"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Workflow templates
+//
+// Each tenant's payments go through the steps of its active workflow
+// template, in the template's order: a tenant can skip compliance for
+// transfers to its own affiliates, or add a tax check from an amount.
+// Operators create template versions for a tenant and activate one; a
+// tenant without an active template gets the built-in steps. A payment is
+// planned from the version active when it was created, recorded on the
+// workflow, so activating another version does not change payments already
+// under way, and a recovered workflow resumes with the same steps.
+
+type WorkflowTemplateRequest struct {
+	PartyID     string                     `json:"partyId" binding:"required"`
+	Template    *workflowtemplate.Template `json:"template" binding:"required"`
+	Description string                     `json:"description,omitempty" binding:"max=500"`
+}
+
+type WorkflowTemplateResponse struct {
+	ID          string                     `json:"id,omitempty"`
+	PartyID     string                     `json:"partyId"`
+	Version     int                        `json:"version"`
+	Template    *workflowtemplate.Template `json:"template"`
+	Description string                     `json:"description,omitempty"`
+	Active      bool                       `json:"active"`
+	CreatedBy   string                     `json:"createdBy,omitempty"`
+	ActivatedAt string                     `json:"activatedAt,omitempty"`
+	CreatedAt   string                     `json:"createdAt,omitempty"`
+}
+
+func toWorkflowTemplateResponse(stored *database.WorkflowTemplate) (*WorkflowTemplateResponse, error) {
+	template, err := workflowtemplate.Decode(stored.Version, stored.Template)
+	if err != nil {
+		return nil, err
+	}
+	response := &WorkflowTemplateResponse{
+		ID:          stored.ID,
+		PartyID:     stored.PartyID,
+		Version:     stored.Version,
+		Template:    template,
+		Description: stored.Description,
+		Active:      stored.Active,
+		CreatedBy:   stored.CreatedBy,
+		CreatedAt:   stored.CreatedAt.Format(time.RFC3339),
+	}
+	if stored.ActivatedAt != nil {
+		response.ActivatedAt = stored.ActivatedAt.Format(time.RFC3339)
+	}
+	return response, nil
+}
+
+// templateCache holds decoded template versions by ID. Versions never
+// change once stored, so they are cached for good.
+var templateCache sync.Map
+
+// plannedStage is a stage of a workflow's template and, when the payment
+// skips it, why
+type plannedStage struct {
+	workflowStage
+	skip string
+}
+
+// applyWorkflowTemplate records the owner party's active template on a new
+// workflow
+func applyWorkflowTemplate(workflow *database.PaymentWorkflow, partyID string) error {
+	template, err := repo.WorkflowTemplateRepository().GetActive(partyID)
+	if err != nil || template == nil {
+		return err
+	}
+	workflow.WorkflowTemplateID = &template.ID
+	workflow.WorkflowTemplateVersion = template.Version
+	return nil
+}
+
+// workflowTemplate returns the template a workflow was planned from
+func workflowTemplate(workflow *database.PaymentWorkflow) (*workflowtemplate.Template, error) {
+	if workflow.WorkflowTemplateID == nil {
+		return workflowtemplate.Default(), nil
+	}
+	id := *workflow.WorkflowTemplateID
+	if cached, ok := templateCache.Load(id); ok {
+		return cached.(*workflowtemplate.Template), nil
+	}
+	stored, err := repo.WorkflowTemplateRepository().GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow template %s: %v", id, err)
+	}
+	template, err := workflowtemplate.Decode(stored.Version, stored.Template)
+	if err != nil {
+		return nil, err
+	}
+	templateCache.Store(id, template)
+	return template, nil
+}
+
+// workflowPlan returns the stages of the workflow's template in order, each
+// with why the payment skips it, if it does
+func workflowPlan(workflow *database.PaymentWorkflow) ([]plannedStage, error) {
+	template, err := workflowTemplate(workflow)
+	if err != nil {
+		return nil, err
+	}
+	payment := workflowtemplate.Payment{
+		AmountUSD: workflow.AmountUSD,
+		Rail:      workflow.Rail,
+		Currency:  workflow.Currency,
+	}
+	if workflow.CounterpartyID != nil {
+		payment.CounterpartyID = *workflow.CounterpartyID
+	}
+
+	// The budget check runs right before execution whatever the template,
+	// except for workflows recovered once execution had started
+	_, executing := stageStatuses(workflow)[workflowtemplate.StepExecution]
+
+	var plan []plannedStage
+	for _, step := range template.Plan(payment) {
+		stage, ok := stageNamed(step.Name)
+		if !ok {
+			return nil, fmt.Errorf("workflow template v%d has unknown step %s", template.Version, step.Name)
+		}
+		if step.Name == workflowtemplate.StepTaxCheck {
+			threshold := step.TaxThreshold()
+			stage.run = func(workflow *database.PaymentWorkflow) error {
+				return performTaxCheck(workflow, threshold)
+			}
+		}
+		if step.Name == workflowtemplate.StepExecution && !executing {
+			budget, _ := stageNamed(budgetCheckStage)
+			plan = append(plan, plannedStage{workflowStage: budget})
+		}
+		plan = append(plan, plannedStage{workflowStage: stage, skip: step.Skip})
+	}
+	return plan, nil
+}
+
+func stageNamed(name string) (workflowStage, bool) {
+	for _, stage := range workflowStages {
+		if stage.name == name {
+			return stage, true
+		}
+	}
+	return workflowStage{}, false
+}
+
+// performTaxCheck requires payments from the threshold to be made to a
+// counterparty entity with a tax ID on file
+func performTaxCheck(workflow *database.PaymentWorkflow, thresholdUSD float64) error {
+	if workflow.AmountUSD < thresholdUSD {
+		return nil
+	}
+	if workflow.CounterpartyID == nil {
+		return fmt.Errorf("payments from %.2f USD must reference a counterparty with a tax ID", thresholdUSD)
+	}
+	counterparty, err := repo.CounterpartyRepository().GetByID(*workflow.CounterpartyID)
+	if err != nil {
+		return fmt.Errorf("failed to load counterparty: %v", err)
+	}
+	if counterparties.DecodeIdentifiers(counterparty.Identifiers)[counterparties.IdentifierTaxID] == "" {
+		return fmt.Errorf("counterparty %s has no tax ID on file", counterparty.Name)
+	}
+	common.Info("Tax check passed for workflow %s", workflow.ID)
+	return nil
+}
+
+// createWorkflowTemplate stores a template as the party's next version,
+// inactive until it is activated
+func createWorkflowTemplate(c *gin.Context) {
+	var req WorkflowTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if err := req.Template.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	for _, step := range req.Template.Steps {
+		for _, condition := range []*workflowtemplate.Condition{step.When, step.Unless} {
+			if condition == nil {
+				continue
+			}
+			for _, rail := range condition.Rails {
+				if !validTemplateRail(rail) {
+					c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Unknown rail "+rail))
+					return
+				}
+			}
+		}
+	}
+	if _, err := repo.PartyRepository().GetByID(req.PartyID); err != nil || !tenancy.Check(c, repo, req.PartyID, "party", req.PartyID) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Party not found"))
+		return
+	}
+	encoded, err := req.Template.Encode()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid template"))
+		return
+	}
+
+	stored := &database.WorkflowTemplate{PartyID: req.PartyID, Template: encoded, Description: req.Description}
+	if principal := common.GetPrincipal(c); principal != nil {
+		stored.CreatedBy = principal.Subject
+	}
+	if err := repo.WorkflowTemplateRepository().Create(stored); err != nil {
+		common.Error("Failed to create workflow template for party %s: %v", req.PartyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create workflow template"))
+		return
+	}
+
+	common.Info("Created workflow template version %d for party %s", stored.Version, stored.PartyID)
+	response, _ := toWorkflowTemplateResponse(stored)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+// listWorkflowTemplates lists a party's template versions, newest first
+func listWorkflowTemplates(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view this party's workflow templates"))
+		return
+	}
+
+	templates, err := repo.WorkflowTemplateRepository().ListByPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list workflow templates of party %s: %v", partyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list workflow templates"))
+		return
+	}
+	items := make([]interface{}, 0, len(templates))
+	for _, template := range templates {
+		response, err := toWorkflowTemplateResponse(template)
+		if err != nil {
+			common.Error("Failed to read workflow template %s: %v", template.ID, err)
+			continue
+		}
+		items = append(items, response)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getActiveWorkflowTemplate returns the template a party's new payments are
+// planned from: its active version, or the built-in version 0
+func getActiveWorkflowTemplate(c *gin.Context) {
+	partyID := c.Query("partyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "partyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view this party's workflow templates"))
+		return
+	}
+
+	stored, err := repo.WorkflowTemplateRepository().GetActive(partyID)
+	if err != nil {
+		common.Error("Failed to load active workflow template of party %s: %v", partyID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load workflow template"))
+		return
+	}
+	if stored == nil {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&WorkflowTemplateResponse{
+			PartyID:     partyID,
+			Template:    workflowtemplate.Default(),
+			Description: "Built-in template",
+			Active:      true,
+		}))
+		return
+	}
+	respondWorkflowTemplate(c, stored)
+}
+
+func getWorkflowTemplate(c *gin.Context) {
+	stored, ok := loadWorkflowTemplate(c)
+	if !ok {
+		return
+	}
+	respondWorkflowTemplate(c, stored)
+}
+
+// activateWorkflowTemplate makes a version the one the party's new payments
+// are planned from
+func activateWorkflowTemplate(c *gin.Context) {
+	stored, ok := loadWorkflowTemplate(c)
+	if !ok {
+		return
+	}
+	stored, err := repo.WorkflowTemplateRepository().Activate(stored.ID)
+	if err != nil {
+		common.Error("Failed to activate workflow template %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to activate workflow template"))
+		return
+	}
+	common.Info("Activated workflow template version %d for party %s", stored.Version, stored.PartyID)
+	respondWorkflowTemplate(c, stored)
+}
+
+// deactivateWorkflowTemplate returns the party to the built-in template if
+// the version was its active one
+func deactivateWorkflowTemplate(c *gin.Context) {
+	stored, ok := loadWorkflowTemplate(c)
+	if !ok {
+		return
+	}
+	stored, err := repo.WorkflowTemplateRepository().Deactivate(stored.ID)
+	if err != nil {
+		common.Error("Failed to deactivate workflow template %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to deactivate workflow template"))
+		return
+	}
+	common.Info("Deactivated workflow template version %d for party %s", stored.Version, stored.PartyID)
+	respondWorkflowTemplate(c, stored)
+}
+
+// loadWorkflowTemplate loads the template named in the path, responding with
+// an error if it is missing or the principal cannot see it
+func loadWorkflowTemplate(c *gin.Context) (*database.WorkflowTemplate, bool) {
+	stored, err := repo.WorkflowTemplateRepository().GetByID(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !canManageParty(c, stored.PartyID)) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Workflow template not found"))
+		return nil, false
+	}
+	if err != nil {
+		common.Error("Failed to load workflow template %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load workflow template"))
+		return nil, false
+	}
+	return stored, true
+}
+
+func respondWorkflowTemplate(c *gin.Context, stored *database.WorkflowTemplate) {
+	response, err := toWorkflowTemplateResponse(stored)
+	if err != nil {
+		common.Error("Failed to read workflow template %s: %v", stored.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to read workflow template"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
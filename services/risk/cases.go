@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Review case statuses
+const (
+	CasePending  = "pending"
+	CaseApproved = "approved"
+	CaseDenied   = "denied"
+)
+
+type CaseDecisionRequest struct {
+	Decision string `json:"decision" binding:"required"` // "approve" or "deny"
+	Notes    string `json:"notes" binding:"required"`
+}
+
+type ReviewCaseResponse struct {
+	ID             string   `json:"id"`
+	RiskDecisionID string   `json:"riskDecisionId"`
+	WorkflowID     string   `json:"workflowId,omitempty"`
+	AgentID        string   `json:"agentId"`
+	AmountUSD      float64  `json:"amountUSD"`
+	Counterparty   string   `json:"counterparty"`
+	Rail           string   `json:"rail"`
+	Score          float64  `json:"score"`
+	Reason         string   `json:"reason"`
+	RiskFactors    []string `json:"riskFactors"`
+	Status         string   `json:"status"`
+	ReviewedBy     string   `json:"reviewedBy,omitempty"`
+	ReviewNotes    string   `json:"reviewNotes,omitempty"`
+	ReviewedAt     string   `json:"reviewedAt,omitempty"`
+	CreatedAt      string   `json:"createdAt"`
+	UpdatedAt      string   `json:"updatedAt"`
+}
+
+func toReviewCaseResponse(reviewCase *database.ReviewCase) *ReviewCaseResponse {
+	response := &ReviewCaseResponse{
+		ID:             reviewCase.ID,
+		RiskDecisionID: reviewCase.RiskDecisionID,
+		AgentID:        reviewCase.AgentID,
+		AmountUSD:      reviewCase.AmountUSD,
+		Counterparty:   reviewCase.Counterparty,
+		Rail:           reviewCase.Rail,
+		Score:          reviewCase.Score,
+		Reason:         reviewCase.Reason,
+		RiskFactors:    decodeStrings(reviewCase.RiskFactors),
+		Status:         reviewCase.Status,
+		ReviewedBy:     reviewCase.ReviewedBy,
+		ReviewNotes:    reviewCase.ReviewNotes,
+		CreatedAt:      reviewCase.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      reviewCase.UpdatedAt.Format(time.RFC3339),
+	}
+	if reviewCase.WorkflowID != nil {
+		response.WorkflowID = *reviewCase.WorkflowID
+	}
+	if reviewCase.ReviewedAt != nil {
+		response.ReviewedAt = reviewCase.ReviewedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// openReviewCase queues a decision that needs manual review. The payment it
+// was made for, if any, waits until the case is decided.
+func openReviewCase(req RiskEvaluationRequest, riskDecision *database.RiskDecision) (*database.ReviewCase, error) {
+	reviewCase := &database.ReviewCase{
+		RiskDecisionID: riskDecision.ID,
+		AgentID:        riskDecision.AgentID,
+		AmountUSD:      riskDecision.AmountUSD,
+		Counterparty:   riskDecision.Counterparty,
+		Rail:           riskDecision.Rail,
+		Score:          riskDecision.Score,
+		Reason:         riskDecision.Reason,
+		RiskFactors:    riskDecision.RiskFactors,
+		Status:         CasePending,
+	}
+	if req.WorkflowID != "" {
+		reviewCase.WorkflowID = &req.WorkflowID
+	}
+	if err := repo.ReviewCaseRepository().Create(reviewCase); err != nil {
+		return nil, err
+	}
+	common.Info("Opened review case %s for risk decision %s", reviewCase.ID, riskDecision.ID)
+	return reviewCase, nil
+}
+
+// listReviewCases lists the cases in a status (?status=, default pending),
+// oldest first, or an agent's cases (?agentId=), newest first
+func listReviewCases(c *gin.Context) {
+	var cases []*database.ReviewCase
+	var err error
+	if agentID := c.Query("agentId"); agentID != "" {
+		if !common.CanActForAgent(c, agentID) || !tenancy.CheckAgent(c, repo, agentID, "agent", agentID) {
+			c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view review cases of this agent"))
+			return
+		}
+		cases, err = repo.ReviewCaseRepository().ListByAgentID(agentID)
+	} else if tenant := common.RequestTenant(c); tenant != "" {
+		cases, err = repo.ReviewCaseRepository().ListByTenantID(tenant, c.DefaultQuery("status", CasePending))
+	} else {
+		cases, err = repo.ReviewCaseRepository().ListByStatus(c.DefaultQuery("status", CasePending))
+	}
+	if err != nil {
+		common.Error("Failed to list review cases: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list review cases"))
+		return
+	}
+
+	items := []interface{}{}
+	for _, reviewCase := range cases {
+		if common.CanActForAgent(c, reviewCase.AgentID) {
+			items = append(items, toReviewCaseResponse(reviewCase))
+		}
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getReviewCase(c *gin.Context) {
+	reviewCase, err := repo.ReviewCaseRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, reviewCase.AgentID) || !tenancy.CheckAgent(c, repo, reviewCase.AgentID, "review_case", reviewCase.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Review case not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toReviewCaseResponse(reviewCase)))
+}
+
+// decideReviewCase records a reviewer's decision on a case and audits it. The
+// orchestrator holding the payment proceeds if it is approved and fails it if
+// it is denied.
+func decideReviewCase(c *gin.Context) {
+	var req CaseDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision and notes are required"))
+		return
+	}
+
+	var status string
+	switch req.Decision {
+	case "approve":
+		status = CaseApproved
+	case "deny":
+		status = CaseDenied
+	default:
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "decision must be approve or deny"))
+		return
+	}
+
+	reviewCase, err := repo.ReviewCaseRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.CheckAgent(c, repo, reviewCase.AgentID, "review_case", reviewCase.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Review case not found"))
+		return
+	}
+	if reviewCase.Status != CasePending {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Review case is already decided"))
+		return
+	}
+
+	principal := common.GetPrincipal(c)
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, common.NewErrorResponse("UNAUTHORIZED", "A reviewer identity is required"))
+		return
+	}
+
+	now := time.Now()
+	reviewCase.Status = status
+	reviewCase.ReviewedBy = principal.Subject
+	reviewCase.ReviewNotes = req.Notes
+	reviewCase.ReviewedAt = &now
+
+	if err := repo.ReviewCaseRepository().Update(reviewCase); err != nil {
+		common.Error("Failed to update review case: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to record decision"))
+		return
+	}
+
+	entry := &audit.AuditEntry{
+		EventType:    audit.AuditPaymentReviewed,
+		Severity:     audit.SeverityMedium,
+		UserID:       principal.Subject,
+		AgentID:      reviewCase.AgentID,
+		ResourceID:   reviewCase.ID,
+		ResourceType: "review_case",
+		Action:       req.Decision,
+		Description:  "Risk review case " + status + " by " + principal.Subject,
+		OldValues:    map[string]interface{}{"status": CasePending},
+		NewValues:    map[string]interface{}{"status": status, "notes": req.Notes},
+		Metadata: map[string]interface{}{
+			"riskDecisionId": reviewCase.RiskDecisionID,
+			"workflowId":     reviewCase.WorkflowID,
+			"score":          reviewCase.Score,
+		},
+	}
+	audit.Record(c, audit.NewAuditTrail(repo), entry)
+
+	common.Info("Review case %s %s by %s", reviewCase.ID, status, principal.Subject)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toReviewCaseResponse(reviewCase)))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/example/agent-payments/internal/counterparties"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/riskpolicy"
+	"github.com/example/agent-payments/internal/velocity"
+)
+
+// counterpartySignals scores a counterparty entity on its KYC status and
+// risk rating, with the policy's counterparty weights. Counterparties the
+// owner party has allowed are not scored as unverified.
+func counterpartySignals(counterparty *database.Counterparty, policy *riskpolicy.Policy) []velocity.Signal {
+	var signals []velocity.Signal
+	switch counterparty.KYCStatus {
+	case counterparties.KYCRejected:
+		signals = append(signals, velocity.Signal{Factor: "kyc_rejected_counterparty", Score: policy.SuspiciousCounterparty, Detail: "counterparty failed KYC"})
+	case counterparties.KYCUnverified, counterparties.KYCPending:
+		if counterparty.ListStatus != counterparties.ListAllowed {
+			signals = append(signals, velocity.Signal{Factor: "unverified_counterparty", Score: policy.UnverifiedCounterparty, Detail: "counterparty KYC is " + counterparty.KYCStatus})
+		}
+	}
+	switch counterparty.RiskRating {
+	case counterparties.RatingHigh:
+		signals = append(signals, velocity.Signal{Factor: "high_risk_counterparty", Score: policy.SuspiciousCounterparty, Detail: "counterparty is rated high risk"})
+	case counterparties.RatingMedium:
+		signals = append(signals, velocity.Signal{Factor: "medium_risk_counterparty", Score: policy.UnverifiedCounterparty, Detail: "counterparty is rated medium risk"})
+	}
+	return signals
+}
+
+// knownCounterparty reports whether the owner party has allowed the
+// counterparty, so paying it for the first time is not a risk signal
+func knownCounterparty(counterparty *database.Counterparty) bool {
+	return counterparty != nil && counterparty.ListStatus == counterparties.ListAllowed
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/declines"
+	"github.com/example/agent-payments/internal/riskpolicy"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type DeclineRuleRequest struct {
+	OwnerPartyID string               `json:"ownerPartyId,omitempty"` // Defaults to the caller's party
+	AgentID      *string              `json:"agentId,omitempty"`      // Empty applies the rule to every agent of the party
+	Name         string               `json:"name"`
+	Type         string               `json:"type"`
+	Parameters   *declines.Parameters `json:"parameters"`
+	Enabled      *bool                `json:"enabled"`
+}
+
+type DeclineRuleResponse struct {
+	ID           string              `json:"id"`
+	OwnerPartyID string              `json:"ownerPartyId"`
+	AgentID      string              `json:"agentId,omitempty"`
+	Name         string              `json:"name"`
+	Type         string              `json:"type"`
+	Parameters   declines.Parameters `json:"parameters"`
+	Enabled      bool                `json:"enabled"`
+	CreatedBy    string              `json:"createdBy,omitempty"`
+	CreatedAt    string              `json:"createdAt"`
+	UpdatedAt    string              `json:"updatedAt"`
+}
+
+type DeclineRuleTestRequest struct {
+	AgentID      string  `json:"agentId" binding:"required"`
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty" binding:"required"`
+	At           string  `json:"at,omitempty"` // RFC3339, defaults to now
+}
+
+type DeclineRuleTestResponse struct {
+	Declined bool             `json:"declined"`
+	Trace    []declines.Trace `json:"trace"`
+}
+
+func toDeclineRuleResponse(rule *database.AutoDeclineRule) *DeclineRuleResponse {
+	params, _ := declines.RuleParameters(rule)
+	response := &DeclineRuleResponse{
+		ID:           rule.ID,
+		OwnerPartyID: rule.OwnerPartyID,
+		Name:         rule.Name,
+		Type:         rule.Type,
+		Parameters:   params,
+		Enabled:      rule.Enabled,
+		CreatedBy:    rule.CreatedBy,
+		CreatedAt:    rule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    rule.UpdatedAt.Format(time.RFC3339),
+	}
+	if rule.AgentID != nil {
+		response.AgentID = *rule.AgentID
+	}
+	return response
+}
+
+// canManageParty reports whether the principal may manage the party's
+// decline rules. Service principals manage any party's; agents none.
+func canManageParty(c *gin.Context, partyID string) bool {
+	principal := common.GetPrincipal(c)
+	if principal == nil || principal.Type == common.PrincipalService {
+		return true
+	}
+	if !tenancy.Check(c, repo, partyID, "party", partyID) {
+		return false
+	}
+	return principal.Type == common.PrincipalParty && principal.PartyID == partyID
+}
+
+// evaluateDeclineRules runs the owner's auto-decline rules for the agent over
+// the payment. Without the rules or the agent's history the payment cannot
+// be checked, which is reported as an error so it is not approved unchecked.
+func evaluateDeclineRules(req RiskEvaluationRequest, agent *database.Agent, at time.Time) ([]declines.Trace, error) {
+	rules, err := repo.AutoDeclineRuleRepository().ListEnabledForAgent(agent.OwnerPartyID, agent.ID)
+	if err != nil || len(rules) == 0 {
+		return []declines.Trace{}, err
+	}
+
+	lookback := 24 * time.Hour
+	for _, rule := range rules {
+		if params, err := declines.RuleParameters(rule); err == nil && rule.Type == declines.RuleNewCounterpartyDailyLimit {
+			if window := time.Duration(params.LookbackDays+1) * 24 * time.Hour; window > lookback {
+				lookback = window
+			}
+		}
+	}
+	history, err := repo.RiskDecisionRepository().ListByAgentIDSince(agent.ID, at.Add(-lookback))
+	if err != nil {
+		return nil, err
+	}
+
+	return declines.Evaluate(rules, declines.Payment{
+		AgentID:      agent.ID,
+		Counterparty: req.Counterparty,
+		AmountUSD:    req.AmountUSD,
+		At:           at,
+	}, history), nil
+}
+
+// declinedDecision denies a payment that matched auto-decline rules without
+// scoring it
+func declinedDecision(matched []declines.Trace, policy *riskpolicy.Policy) RiskDecision {
+	factors := make([]string, len(matched))
+	names := make([]string, len(matched))
+	for i, trace := range matched {
+		factors[i] = "auto_decline_" + trace.Type
+		names[i] = trace.Name
+	}
+	return RiskDecision{
+		Decision:       "deny",
+		Score:          1.0,
+		Reason:         "Transaction denied - owner auto-decline rule: " + strings.Join(names, ", "),
+		Threshold:      policy.Threshold,
+		RiskFactors:    factors,
+		TriggeredRules: []string{},
+		PolicyVersion:  policy.Version,
+	}
+}
+
+// auditDeclineRuleHits records each matched rule against the decision that
+// denied the payment
+func auditDeclineRuleHits(c *gin.Context, decision *database.RiskDecision, matched []declines.Trace) {
+	trail := audit.NewAuditTrail(repo)
+	for _, trace := range matched {
+		entry := &audit.AuditEntry{
+			EventType:    audit.AuditPaymentAutoDeclined,
+			Severity:     audit.SeverityMedium,
+			AgentID:      decision.AgentID,
+			ResourceID:   decision.ID,
+			ResourceType: "risk_decision",
+			Action:       "auto_decline",
+			Description:  "Payment to " + decision.Counterparty + " declined by owner rule " + trace.Name + ": " + trace.Detail,
+			Metadata: map[string]interface{}{
+				"ruleId":    trace.RuleID,
+				"ruleType":  trace.Type,
+				"amountUSD": decision.AmountUSD,
+			},
+		}
+		audit.Record(c, trail, entry)
+	}
+}
+
+// encodeDeclineTrace serializes a decline trace for a JSON column
+func encodeDeclineTrace(trace []declines.Trace) string {
+	if len(trace) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(trace)
+	return string(data)
+}
+
+// decodeDeclineTrace reads a decline trace from a JSON column
+func decodeDeclineTrace(value string) []map[string]interface{} {
+	var trace []map[string]interface{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &trace)
+	}
+	return trace
+}
+
+func listDeclineRules(c *gin.Context) {
+	partyID := c.Query("ownerPartyId")
+	if principal := common.GetPrincipal(c); partyID == "" && principal != nil {
+		partyID = principal.PartyID
+	}
+	if partyID == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "ownerPartyId is required"))
+		return
+	}
+	if !canManageParty(c, partyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot read decline rules of this party"))
+		return
+	}
+
+	rules, err := repo.AutoDeclineRuleRepository().ListByOwnerPartyID(partyID)
+	if err != nil {
+		common.Error("Failed to list decline rules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list decline rules"))
+		return
+	}
+
+	items := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		items[i] = toDeclineRuleResponse(rule)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getDeclineRule(c *gin.Context) {
+	rule := loadDeclineRule(c)
+	if rule == nil {
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDeclineRuleResponse(rule)))
+}
+
+func loadDeclineRule(c *gin.Context) *database.AutoDeclineRule {
+	rule, err := repo.AutoDeclineRuleRepository().GetByID(c.Param("id"))
+	if err != nil || !canManageParty(c, rule.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Decline rule not found"))
+		return nil
+	}
+	return rule
+}
+
+func createDeclineRule(c *gin.Context) {
+	var req DeclineRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	principal := common.GetPrincipal(c)
+	if req.OwnerPartyID == "" && principal != nil {
+		req.OwnerPartyID = principal.PartyID
+	}
+	if req.OwnerPartyID == "" || req.Name == "" || !declines.ValidRuleType(req.Type) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "ownerPartyId, name and a valid type are required"))
+		return
+	}
+	if !canManageParty(c, req.OwnerPartyID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot manage decline rules of this party"))
+		return
+	}
+
+	rule := &database.AutoDeclineRule{
+		OwnerPartyID: req.OwnerPartyID,
+		Name:         req.Name,
+		Type:         req.Type,
+		Enabled:      true,
+	}
+	if principal != nil {
+		rule.CreatedBy = principal.Subject
+	}
+	if !applyDeclineRuleRequest(c, rule, req) {
+		return
+	}
+
+	if err := repo.AutoDeclineRuleRepository().Create(rule); err != nil {
+		common.Error("Failed to create decline rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create decline rule"))
+		return
+	}
+
+	common.Info("Created decline rule %s (%s) for party %s", rule.ID, rule.Name, rule.OwnerPartyID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toDeclineRuleResponse(rule)))
+}
+
+func updateDeclineRule(c *gin.Context) {
+	var req DeclineRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	rule := loadDeclineRule(c)
+	if rule == nil {
+		return
+	}
+	if req.Type != "" && req.Type != rule.Type {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "A rule's type cannot be changed"))
+		return
+	}
+	if req.OwnerPartyID != "" && req.OwnerPartyID != rule.OwnerPartyID {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "A rule's owner cannot be changed"))
+		return
+	}
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if !applyDeclineRuleRequest(c, rule, req) {
+		return
+	}
+
+	if err := repo.AutoDeclineRuleRepository().Update(rule); err != nil {
+		common.Error("Failed to update decline rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update decline rule"))
+		return
+	}
+
+	common.Info("Updated decline rule %s (%s)", rule.ID, rule.Name)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toDeclineRuleResponse(rule)))
+}
+
+// applyDeclineRuleRequest sets the agent, parameters and enabled flag of a
+// rule, responding with 400 if they are invalid
+func applyDeclineRuleRequest(c *gin.Context, rule *database.AutoDeclineRule, req DeclineRuleRequest) bool {
+	if req.AgentID != nil {
+		rule.AgentID = nil
+		if *req.AgentID != "" {
+			agent, err := repo.AgentRepository().GetByID(*req.AgentID)
+			if err != nil || agent.OwnerPartyID != rule.OwnerPartyID {
+				c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found for this party"))
+				return false
+			}
+			rule.AgentID = &agent.ID
+		}
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Parameters != nil || rule.Parameters == "" {
+		params, _ := declines.RuleParameters(rule)
+		if req.Parameters != nil {
+			params = mergeDeclineParameters(params, *req.Parameters)
+		}
+		if err := declines.Validate(rule.Type, params); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+			return false
+		}
+		encoded, _ := json.Marshal(params)
+		rule.Parameters = string(encoded)
+	}
+	return true
+}
+
+// mergeDeclineParameters overlays the parameters set in a request on the
+// rule's current ones
+func mergeDeclineParameters(params, set declines.Parameters) declines.Parameters {
+	if set.Counterparties != nil {
+		params.Counterparties = set.Counterparties
+	}
+	if set.StartHour != nil {
+		params.StartHour = set.StartHour
+	}
+	if set.EndHour != nil {
+		params.EndHour = set.EndHour
+	}
+	if set.Timezone != "" {
+		params.Timezone = set.Timezone
+	}
+	if set.MaxPayments != 0 {
+		params.MaxPayments = set.MaxPayments
+	}
+	if set.LookbackDays != 0 {
+		params.LookbackDays = set.LookbackDays
+	}
+	return params
+}
+
+func deleteDeclineRule(c *gin.Context) {
+	rule := loadDeclineRule(c)
+	if rule == nil {
+		return
+	}
+	if err := repo.AutoDeclineRuleRepository().Delete(rule.ID); err != nil {
+		common.Error("Failed to delete decline rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete decline rule"))
+		return
+	}
+
+	common.Info("Deleted decline rule %s (%s)", rule.ID, rule.Name)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": rule.ID, "deleted": true}))
+}
+
+// testDeclineRules evaluates the rules that apply to an agent against a
+// hypothetical payment and returns the trace, without recording a decision
+func testDeclineRules(c *gin.Context) {
+	var req DeclineRuleTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId and counterparty are required"))
+		return
+	}
+	at := time.Now()
+	if req.At != "" {
+		parsed, err := time.Parse(time.RFC3339, req.At)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "at must be an RFC3339 time"))
+			return
+		}
+		at = parsed
+	}
+
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil || !canManageParty(c, agent.OwnerPartyID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+
+	trace, err := evaluateDeclineRules(RiskEvaluationRequest{
+		AgentID:      agent.ID,
+		AmountUSD:    req.AmountUSD,
+		Counterparty: req.Counterparty,
+	}, agent, at)
+	if err != nil {
+		common.Error("Failed to evaluate decline rules for agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to evaluate decline rules"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&DeclineRuleTestResponse{
+		Declined: len(declines.Matched(trace)) > 0,
+		Trace:    trace,
+	}))
+}
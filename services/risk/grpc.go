@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/types"
+)
+
+// riskServer serves the risk service's gRPC API through its REST handlers
+type riskServer struct {
+	agentpayv1.UnimplementedRiskServiceServer
+	rest *rpc.REST
+}
+
+func (s *riskServer) Evaluate(ctx context.Context, req *agentpayv1.EvaluateRiskRequest) (*agentpayv1.RiskDecision, error) {
+	var decision types.RiskDecision
+	err := s.rest.Call(ctx, http.MethodPost, "/v1/risk/evaluate", &RiskEvaluationRequest{
+		AgentID:        req.GetAgentId(),
+		AmountUSD:      req.GetAmountUsd(),
+		Counterparty:   req.GetCounterparty(),
+		Rail:           req.GetRail(),
+		WorkflowID:     req.GetWorkflowId(),
+		CounterpartyID: req.GetCounterpartyId(),
+	}, &decision)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.RiskDecisionToProto(&decision), nil
+}
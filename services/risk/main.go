@@ -1,38 +1,78 @@
 package main
 
 import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/declines"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/riskpolicy"
+	"github.com/example/agent-payments/internal/riskprofile"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/internal/velocity"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var repo database.Repository
+var authConfig *common.AuthConfig
 
 type RiskEvaluationRequest struct {
-	AgentID      string  `json:"agentId" binding:"required"`
-	AmountUSD    float64 `json:"amountUSD" binding:"required"`
-	Counterparty string  `json:"counterparty" binding:"required"`
-	Rail         string  `json:"rail" binding:"required"`
+	AgentID        string  `json:"agentId" binding:"required"`
+	AmountUSD      float64 `json:"amountUSD" binding:"required"`
+	Counterparty   string  `json:"counterparty" binding:"required"`
+	Rail           string  `json:"rail" binding:"required"`
+	WorkflowID     string  `json:"workflowId,omitempty"`     // Payment being evaluated, left out of the agent's history
+	CounterpartyID string  `json:"counterpartyId,omitempty"` // Counterparty entity, scored on its KYC status and rating
 }
 
 type RiskDecision struct {
-	Decision    string   `json:"decision"` // "approve", "deny", "review"
-	Score       float64  `json:"score"`    // 0.0 to 1.0, higher is riskier
-	Reason      string   `json:"reason"`
-	Threshold   float64  `json:"threshold"`
-	RiskFactors []string `json:"riskFactors"`
+	Decision       string             `json:"decision"` // "approve", "deny", "review"
+	Score          float64            `json:"score"`    // 0.0 to 1.0, higher is riskier
+	Reason         string             `json:"reason"`
+	Threshold      float64            `json:"threshold"`
+	RiskFactors    []string           `json:"riskFactors"`
+	TriggeredRules []string           `json:"triggeredRules"`     // IDs of triggered AML rules
+	Features       *velocity.Features `json:"features,omitempty"` // Agent behavior the score considered
+	DeclineTrace   []declines.Trace   `json:"declineTrace"`       // Owner auto-decline rules evaluated before scoring
+	PolicyVersion  int                `json:"policyVersion"`      // Risk policy scored under
+	TrustTier      string             `json:"trustTier"`          // Agent's trust tier, which set the threshold
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("risk")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("risk")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -45,13 +85,29 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Create the standard AML rules on first start
+	if err := seedDefaultRules(); err != nil {
+		log.Fatalf("Failed to create default AML rules: %v", err)
+	}
+
 	r := gin.Default()
+	server := common.NewServer(cfg.Addr(), r)
+
+	// Score with the active risk policy, reloading it as it changes
+	initRiskPolicy(server.Context(), cfg.Risk.Threshold)
 
 	// Setup common middleware
 	common.SetupCommonMiddleware(r, func() error {
 		return repo.HealthCheck()
 	})
 
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
 		if err := repo.HealthCheck(); err != nil {
@@ -61,20 +117,67 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "risk service ok"})
 	})
 
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Risk evaluation
-		v1.POST("/risk/evaluate", evaluateRisk)
-		v1.GET("/risk/decisions/:id", getRiskDecision)
-		v1.GET("/risk/decisions", listRiskDecisions)
+		v1.POST("/risk/evaluate", common.RequireScopes(common.ScopeRiskEvaluate), evaluateRisk)
+		v1.GET("/risk/decisions/:id", common.RequireScopes(common.ScopeRiskRead), getRiskDecision)
+		v1.GET("/risk/decisions", common.RequireScopes(common.ScopeRiskRead), listRiskDecisions)
+
+		// AML transaction-monitoring rules
+		v1.GET("/risk/rules", common.RequireScopes(common.ScopeRiskRead), common.RequirePlatform(), listAMLRules)
+		v1.GET("/risk/rules/:id", common.RequireScopes(common.ScopeRiskRead), common.RequirePlatform(), getAMLRule)
+		v1.POST("/risk/rules", common.RequireScopes(common.ScopeComplianceReview), common.RequirePlatform(), createAMLRule)
+		v1.PATCH("/risk/rules/:id", common.RequireScopes(common.ScopeComplianceReview), common.RequirePlatform(), updateAMLRule)
+		v1.DELETE("/risk/rules/:id", common.RequireScopes(common.ScopeComplianceReview), common.RequirePlatform(), deleteAMLRule)
+
+		// Owner auto-decline rules, checked before scoring
+		v1.GET("/risk/decline-rules", common.RequireScopes(common.ScopeConsentsRead), listDeclineRules)
+		v1.GET("/risk/decline-rules/:id", common.RequireScopes(common.ScopeConsentsRead), getDeclineRule)
+		v1.POST("/risk/decline-rules", common.RequireScopes(common.ScopeConsentsWrite), createDeclineRule)
+		v1.PATCH("/risk/decline-rules/:id", common.RequireScopes(common.ScopeConsentsWrite), updateDeclineRule)
+		v1.DELETE("/risk/decline-rules/:id", common.RequireScopes(common.ScopeConsentsWrite), deleteDeclineRule)
+		v1.POST("/risk/decline-rules/evaluate", common.RequireScopes(common.ScopeConsentsRead), testDeclineRules)
+
+		// Manual review of "review" decisions
+		v1.GET("/risk/cases", common.RequireScopes(common.ScopeRiskRead), listReviewCases)
+		v1.GET("/risk/cases/:id", common.RequireScopes(common.ScopeRiskRead), getReviewCase)
+		v1.POST("/risk/cases/:id/decision", common.RequireScopes(common.ScopeComplianceReview), common.RequireRolesOrPrincipals([]string{common.PrincipalService}, common.RoleReviewer), decideReviewCase)
+
+		// Per-agent trust tiers and score adjustments
+		v1.GET("/risk/profiles", common.RequireScopes(common.ScopeRiskRead), listRiskProfiles)
+		v1.GET("/risk/agents/:id/profile", common.RequireScopes(common.ScopeRiskRead), getRiskProfile)
+		v1.PUT("/risk/agents/:id/profile", common.RequireScopes(common.ScopeComplianceReview), updateRiskProfile)
+		v1.POST("/risk/agents/:id/profile/reassess", common.RequireScopes(common.ScopeComplianceReview), reassessRiskProfile)
+
+		// Versioned scoring policies
+		v1.GET("/risk/policies", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), listRiskPolicies)
+		v1.GET("/risk/policies/active", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), getActiveRiskPolicy)
+		v1.GET("/risk/policies/:version", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), getRiskPolicy)
+		v1.POST("/risk/policies", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), createRiskPolicy)
+		v1.POST("/risk/policies/:version/activate", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), activateRiskPolicy)
 	}
 
-	common.Info("Risk service running on :8083")
-	log.Fatal(r.Run(":8083"))
+	// The calls payment processing makes, over gRPC on GRPC_PORT
+	if err := rpc.Serve(server, func(s *grpc.Server) {
+		agentpayv1.RegisterRiskServiceServer(s, &riskServer{rest: rpc.NewREST(r)})
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	common.Info("Risk service running on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func evaluateRisk(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
 	var req RiskEvaluationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
@@ -88,25 +191,66 @@ func evaluateRisk(c *gin.Context) {
 	}
 
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
 
+	// A counterparty entity is scored on its KYC status and rating
+	var counterparty *database.Counterparty
+	if req.CounterpartyID != "" {
+		counterparty, err = repo.CounterpartyRepository().GetByID(req.CounterpartyID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Counterparty not found"))
+			return
+		}
+	}
+
+	// The owner's auto-decline rules deny a payment before it is scored
+	trace, err := evaluateDeclineRules(req, agent, time.Now())
+	if err != nil {
+		common.Error("Failed to evaluate decline rules for agent %s: %v", req.AgentID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to evaluate decline rules"))
+		return
+	}
+	matched := declines.Matched(trace)
+
+	// The agent's trust tier sets its threshold under the active policy
+	profile, err := agentRiskProfile(agent, false)
+	if err != nil {
+		common.Error("Failed to load risk profile of agent %s, scoring as standard: %v", req.AgentID, err)
+		profile = &database.AgentRiskProfile{AgentID: agent.ID, TrustTier: riskprofile.TierStandard}
+	}
+	policy := currentPolicy().ForTier(profile.TrustTier)
+
 	// Perform risk evaluation
-	decision := evaluateRiskLogic(req)
+	var decision RiskDecision
+	if len(matched) > 0 {
+		decision = declinedDecision(matched, policy)
+	} else {
+		decision = evaluateRiskLogic(req, policy, profile, counterparty)
+		applyAMLRules(req, &decision, policy)
+	}
+	decision.DeclineTrace = trace
+	decision.TrustTier = profile.TrustTier
 
 	// Store risk decision in database
 	riskDecision := &database.RiskDecision{
-		AgentID:      req.AgentID,
-		AmountUSD:    req.AmountUSD,
-		Counterparty: req.Counterparty,
-		Rail:         req.Rail,
-		Decision:     decision.Decision,
-		Score:        decision.Score,
-		Reason:       decision.Reason,
-		Threshold:    decision.Threshold,
-		RiskFactors:  "[]", // Would serialize decision.RiskFactors to JSON in production
+		AgentID:        req.AgentID,
+		AmountUSD:      req.AmountUSD,
+		Counterparty:   req.Counterparty,
+		Rail:           req.Rail,
+		Decision:       decision.Decision,
+		Score:          decision.Score,
+		Reason:         decision.Reason,
+		Threshold:      decision.Threshold,
+		RiskFactors:    encodeStrings(decision.RiskFactors),
+		TriggeredRules: encodeStrings(decision.TriggeredRules),
+		Features:       encodeFeatures(decision.Features),
+		DeclineTrace:   encodeDeclineTrace(decision.DeclineTrace),
+		PolicyVersion:  decision.PolicyVersion,
+		TrustTier:      decision.TrustTier,
 	}
 
 	if err := repo.RiskDecisionRepository().Create(riskDecision); err != nil {
@@ -115,92 +259,182 @@ func evaluateRisk(c *gin.Context) {
 		return
 	}
 
+	if len(matched) > 0 {
+		auditDeclineRuleHits(c, riskDecision, matched)
+	}
+
+	// Decisions needing manual review hold the payment in a review case
+	caseID := ""
+	if riskDecision.Decision == "review" {
+		reviewCase, err := openReviewCase(req, riskDecision)
+		if err != nil {
+			common.Error("Failed to open review case for risk decision %s: %v", riskDecision.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to open review case"))
+			return
+		}
+		caseID = reviewCase.ID
+	}
+
 	// Convert to API response format
 	response := &types.RiskDecision{
-		ID:           riskDecision.ID,
-		AgentID:      riskDecision.AgentID,
-		AmountUSD:    riskDecision.AmountUSD,
-		Counterparty: riskDecision.Counterparty,
-		Rail:         riskDecision.Rail,
-		Decision:     riskDecision.Decision,
-		Score:        riskDecision.Score,
-		Reason:       riskDecision.Reason,
-		Threshold:    riskDecision.Threshold,
-		RiskFactors:  []string{}, // Would deserialize from riskDecision.RiskFactors JSON in production
-		CreatedAt:    riskDecision.CreatedAt.Format(time.RFC3339),
+		ID:             riskDecision.ID,
+		AgentID:        riskDecision.AgentID,
+		AmountUSD:      riskDecision.AmountUSD,
+		Counterparty:   riskDecision.Counterparty,
+		Rail:           riskDecision.Rail,
+		Decision:       riskDecision.Decision,
+		Score:          riskDecision.Score,
+		Reason:         riskDecision.Reason,
+		Threshold:      riskDecision.Threshold,
+		RiskFactors:    decodeStrings(riskDecision.RiskFactors),
+		TriggeredRules: decodeStrings(riskDecision.TriggeredRules),
+		Features:       decodeFeatures(riskDecision.Features),
+		DeclineTrace:   decodeDeclineTrace(riskDecision.DeclineTrace),
+		PolicyVersion:  riskDecision.PolicyVersion,
+		TrustTier:      riskDecision.TrustTier,
+		CaseID:         caseID,
+		CreatedAt:      riskDecision.CreatedAt.Format(time.RFC3339),
 	}
 
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditPaymentRiskChecked,
+		Severity:     riskDecisionSeverity(riskDecision.Decision),
+		AgentID:      riskDecision.AgentID,
+		ResourceID:   riskDecision.ID,
+		ResourceType: "risk_decision",
+		Action:       riskDecision.Decision,
+		Description:  fmt.Sprintf("Risk decision %s for %.2f USD to %s (score %.2f)", riskDecision.Decision, riskDecision.AmountUSD, riskDecision.Counterparty, riskDecision.Score),
+		NewValues:    map[string]interface{}{"decision": riskDecision.Decision, "score": riskDecision.Score, "reason": riskDecision.Reason, "caseId": caseID},
+		Metadata:     map[string]interface{}{"workflowId": req.WorkflowID, "rail": riskDecision.Rail},
+	})
+
 	common.Info("Risk evaluation completed: %s for agent %s, decision: %s", riskDecision.ID, req.AgentID, decision.Decision)
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
-func evaluateRiskLogic(req RiskEvaluationRequest) RiskDecision {
+// riskDecisionSeverity is the audit severity of a risk decision
+func riskDecisionSeverity(decision string) audit.AuditSeverity {
+	switch decision {
+	case "deny":
+		return audit.SeverityHigh
+	case "review":
+		return audit.SeverityMedium
+	}
+	return audit.SeverityLow
+}
+
+// evaluateRiskLogic scores a payment with the weights of a risk policy and
+// the agent's manual score adjustment. The counterparty entity is nil when
+// the payment named its counterparty only.
+func evaluateRiskLogic(req RiskEvaluationRequest, policy *riskpolicy.Policy, profile *database.AgentRiskProfile, counterparty *database.Counterparty) RiskDecision {
 	score := 0.0
 	riskFactors := []string{}
-	threshold := 0.7 // Configurable threshold
 
 	// Amount-based risk
-	if req.AmountUSD > 25000 {
-		score += 0.4
-		riskFactors = append(riskFactors, "very_high_amount")
-	} else if req.AmountUSD > 10000 {
-		score += 0.3
-		riskFactors = append(riskFactors, "high_amount")
-	} else if req.AmountUSD > 1000 {
-		score += 0.15
-		riskFactors = append(riskFactors, "medium_amount")
-	}
-
-	// Counterparty risk (simplified - in production would check against sanctions lists, etc.)
+	if band, ok := policy.AmountBand(req.AmountUSD); ok {
+		score += band.Weight
+		riskFactors = append(riskFactors, band.Factor)
+	}
+
+	// Counterparty risk: entities on their KYC status and rating, free-form
+	// names on what the name suggests
 	counterpartyLower := strings.ToLower(req.Counterparty)
-	if strings.Contains(counterpartyLower, "suspicious") ||
+	if counterparty != nil {
+		for _, signal := range counterpartySignals(counterparty, policy) {
+			score += signal.Score
+			riskFactors = append(riskFactors, signal.Factor)
+		}
+	} else if strings.Contains(counterpartyLower, "suspicious") ||
 		strings.Contains(counterpartyLower, "unknown") ||
 		len(req.Counterparty) < 3 {
-		score += 0.25
+		score += policy.SuspiciousCounterparty
 		riskFactors = append(riskFactors, "suspicious_counterparty")
 	} else if strings.Contains(counterpartyLower, "new") ||
 		strings.Contains(counterpartyLower, "unverified") {
-		score += 0.1
+		score += policy.UnverifiedCounterparty
 		riskFactors = append(riskFactors, "unverified_counterparty")
 	}
 
 	// Rail risk
-	switch strings.ToLower(req.Rail) {
-	case "wire":
-		score += 0.2
-		riskFactors = append(riskFactors, "wire_transfer")
-	case "international":
-		score += 0.3
-		riskFactors = append(riskFactors, "international_transfer")
-	case "card":
-		score += 0.05
-		riskFactors = append(riskFactors, "card_payment")
+	if weight := policy.RailWeight(req.Rail); weight > 0 {
+		score += weight
+		riskFactors = append(riskFactors, railRiskFactor(req.Rail))
+	}
+
+	// Behavioral risk from the agent's recent payments
+	features, signals := behavioralSignals(req)
+	for _, signal := range signals {
+		if signal.Factor == "new_counterparty" && knownCounterparty(counterparty) {
+			continue
+		}
+		score += policy.SignalWeight(signal.Factor, signal.Score)
+		riskFactors = append(riskFactors, signal.Factor)
 	}
 
-	// Cap score at 1.0
+	// Operator adjustment from the agent's risk profile
+	if profile.ScoreAdjustment != 0 {
+		score += profile.ScoreAdjustment
+		riskFactors = append(riskFactors, "manual_adjustment")
+	}
+
+	// Keep score within 0.0 to 1.0
 	if score > 1.0 {
 		score = 1.0
+	} else if score < 0 {
+		score = 0
 	}
 
 	// Determine decision
-	decision := "approve"
+	decision := policy.Decide(score)
 	reason := "Transaction approved - low risk"
-
-	if score >= threshold {
-		decision = "deny"
+	switch decision {
+	case "deny":
 		reason = "Transaction denied - risk score exceeds threshold"
-	} else if score >= threshold*0.8 {
-		decision = "review"
+	case "review":
 		reason = "Transaction requires manual review"
 	}
 
 	return RiskDecision{
-		Decision:    decision,
-		Score:       score,
-		Reason:      reason,
-		Threshold:   threshold,
-		RiskFactors: riskFactors,
+		Decision:       decision,
+		Score:          score,
+		Reason:         reason,
+		Threshold:      policy.Threshold,
+		RiskFactors:    riskFactors,
+		TriggeredRules: []string{},
+		Features:       features,
+		PolicyVersion:  policy.Version,
+	}
+}
+
+// railRiskFactor is the risk factor recorded for a weighted rail
+func railRiskFactor(rail string) string {
+	switch rail = strings.ToLower(rail); rail {
+	case "wire":
+		return "wire_transfer"
+	case "international":
+		return "international_transfer"
+	case "card":
+		return "card_payment"
+	}
+	return rail + "_rail"
+}
+
+// encodeStrings serializes a string list for a JSON column
+func encodeStrings(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// decodeStrings reads a string list from a JSON column
+func decodeStrings(value string) []string {
+	values := []string{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &values)
 	}
+	return values
 }
 
 func getRiskDecision(c *gin.Context) {
@@ -211,37 +445,58 @@ func getRiskDecision(c *gin.Context) {
 		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Risk decision not found"))
 		return
 	}
+	if !tenancy.CheckAgent(c, repo, riskDecision.AgentID, "risk_decision", riskDecision.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Risk decision not found"))
+		return
+	}
 
 	// Convert to API response format
 	response := &types.RiskDecision{
-		ID:           riskDecision.ID,
-		AgentID:      riskDecision.AgentID,
-		AmountUSD:    riskDecision.AmountUSD,
-		Counterparty: riskDecision.Counterparty,
-		Rail:         riskDecision.Rail,
-		Decision:     riskDecision.Decision,
-		Score:        riskDecision.Score,
-		Reason:       riskDecision.Reason,
-		Threshold:    riskDecision.Threshold,
-		RiskFactors:  []string{}, // Would deserialize from riskDecision.RiskFactors JSON in production
-		CreatedAt:    riskDecision.CreatedAt.Format(time.RFC3339),
+		ID:             riskDecision.ID,
+		AgentID:        riskDecision.AgentID,
+		AmountUSD:      riskDecision.AmountUSD,
+		Counterparty:   riskDecision.Counterparty,
+		Rail:           riskDecision.Rail,
+		Decision:       riskDecision.Decision,
+		Score:          riskDecision.Score,
+		Reason:         riskDecision.Reason,
+		Threshold:      riskDecision.Threshold,
+		RiskFactors:    decodeStrings(riskDecision.RiskFactors),
+		TriggeredRules: decodeStrings(riskDecision.TriggeredRules),
+		Features:       decodeFeatures(riskDecision.Features),
+		DeclineTrace:   decodeDeclineTrace(riskDecision.DeclineTrace),
+		PolicyVersion:  riskDecision.PolicyVersion,
+		TrustTier:      riskDecision.TrustTier,
+		CreatedAt:      riskDecision.CreatedAt.Format(time.RFC3339),
 	}
 
 	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
 }
 
+// listRiskDecisions lists a page of risk decisions, filtered by agent,
+// decision, rail, counterparty and creation time
 func listRiskDecisions(c *gin.Context) {
-	agentID := c.Query("agentId")
-
-	var riskDecisions []*database.RiskDecision
-	var err error
-
-	if agentID != "" {
-		riskDecisions, err = repo.RiskDecisionRepository().ListByAgentID(agentID)
-	} else {
-		riskDecisions, err = repo.RiskDecisionRepository().List()
+	params, ok := common.ParseListParams(c)
+	if !ok {
+		return
+	}
+	filter := database.RiskDecisionFilter{
+		AgentID:      c.Query("agentId"),
+		Decision:     c.Query("decision"),
+		Rail:         c.Query("rail"),
+		Counterparty: c.Query("counterparty"),
+		TenantID:     common.RequestTenant(c),
+	}
+	if filter.AgentID != "" && !tenancy.CheckAgent(c, repo, filter.AgentID, "agent", filter.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot list risk decisions of this agent"))
+		return
 	}
 
+	riskDecisions, total, err := repo.RiskDecisionRepository().ListPage(filter, params)
+	if errors.Is(err, database.ErrInvalidListParams) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to list risk decisions: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list risk decisions"))
@@ -252,21 +507,26 @@ func listRiskDecisions(c *gin.Context) {
 	var result []*types.RiskDecision
 	for _, rd := range riskDecisions {
 		result = append(result, &types.RiskDecision{
-			ID:           rd.ID,
-			AgentID:      rd.AgentID,
-			AmountUSD:    rd.AmountUSD,
-			Counterparty: rd.Counterparty,
-			Rail:         rd.Rail,
-			Decision:     rd.Decision,
-			Score:        rd.Score,
-			Reason:       rd.Reason,
-			Threshold:    rd.Threshold,
-			RiskFactors:  []string{}, // Would deserialize from rd.RiskFactors JSON in production
-			CreatedAt:    rd.CreatedAt.Format(time.RFC3339),
+			ID:             rd.ID,
+			AgentID:        rd.AgentID,
+			AmountUSD:      rd.AmountUSD,
+			Counterparty:   rd.Counterparty,
+			Rail:           rd.Rail,
+			Decision:       rd.Decision,
+			Score:          rd.Score,
+			Reason:         rd.Reason,
+			Threshold:      rd.Threshold,
+			RiskFactors:    decodeStrings(rd.RiskFactors),
+			TriggeredRules: decodeStrings(rd.TriggeredRules),
+			Features:       decodeFeatures(rd.Features),
+			DeclineTrace:   decodeDeclineTrace(rd.DeclineTrace),
+			PolicyVersion:  rd.PolicyVersion,
+			TrustTier:      rd.TrustTier,
+			CreatedAt:      rd.CreatedAt.Format(time.RFC3339),
 		})
 	}
 
-	response := common.NewListResponse(make([]interface{}, len(result)), 1, 10, len(result))
+	response := common.NewPageResponse(make([]interface{}, len(result)), params, total)
 	for i, rd := range result {
 		response.Items[i] = rd
 	}
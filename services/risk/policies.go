@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/riskpolicy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Risk policies
+//
+// The threshold and factor weights payments are scored with come from the
+// active risk policy version. Operators create versions and activate one;
+// activation takes effect at once on the instance that served it, and the
+// other instances pick it up on their next reload, every
+// RISK_POLICY_RELOAD_SECONDS (30). Until a version is activated, payments
+// are scored with the built-in policy, version 0, whose threshold is
+// RISK_THRESHOLD. Every decision records the version it was scored under.
+
+var activePolicy = struct {
+	sync.RWMutex
+	policy *riskpolicy.Policy
+}{}
+
+type RiskPolicyRequest struct {
+	Policy      *riskpolicy.Policy `json:"policy" binding:"required"`
+	Description string             `json:"description,omitempty" binding:"max=500"`
+}
+
+type RiskPolicyResponse struct {
+	Version     int                `json:"version"`
+	Policy      *riskpolicy.Policy `json:"policy"`
+	Description string             `json:"description,omitempty"`
+	Active      bool               `json:"active"`
+	CreatedBy   string             `json:"createdBy,omitempty"`
+	ActivatedAt string             `json:"activatedAt,omitempty"`
+	CreatedAt   string             `json:"createdAt"`
+}
+
+func toRiskPolicyResponse(stored *database.RiskPolicy) (*RiskPolicyResponse, error) {
+	policy, err := riskpolicy.Decode(stored.Version, stored.Policy)
+	if err != nil {
+		return nil, err
+	}
+	response := &RiskPolicyResponse{
+		Version:     stored.Version,
+		Policy:      policy,
+		Description: stored.Description,
+		Active:      stored.Active,
+		CreatedBy:   stored.CreatedBy,
+		CreatedAt:   stored.CreatedAt.Format(time.RFC3339),
+	}
+	if stored.ActivatedAt != nil {
+		response.ActivatedAt = stored.ActivatedAt.Format(time.RFC3339)
+	}
+	return response, nil
+}
+
+// initRiskPolicy loads the active policy, falling back to the built-in one
+// with the configured threshold, and reloads it until ctx is done
+func initRiskPolicy(ctx context.Context, threshold float64) {
+	builtin := riskpolicy.Default(threshold)
+	setActivePolicy(&builtin)
+	reloadRiskPolicy()
+
+	interval := time.Duration(common.GetEnvAsInt("RISK_POLICY_RELOAD_SECONDS", 30)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloadRiskPolicy()
+			}
+		}
+	}()
+}
+
+// reloadRiskPolicy switches to the active stored policy if it has changed.
+// A policy that cannot be loaded leaves the current one in force.
+func reloadRiskPolicy() {
+	stored, err := repo.RiskPolicyRepository().GetActive()
+	if err != nil {
+		common.Error("Failed to load the active risk policy: %v", err)
+		return
+	}
+	if stored == nil || stored.Version == currentPolicy().Version {
+		return
+	}
+	policy, err := riskpolicy.Decode(stored.Version, stored.Policy)
+	if err != nil {
+		common.Error("Failed to load the active risk policy: %v", err)
+		return
+	}
+	setActivePolicy(policy)
+}
+
+func setActivePolicy(policy *riskpolicy.Policy) {
+	activePolicy.Lock()
+	defer activePolicy.Unlock()
+	if activePolicy.policy != nil {
+		common.Info("Risk policy version %d replaces version %d (threshold %.2f)", policy.Version, activePolicy.policy.Version, policy.Threshold)
+	}
+	activePolicy.policy = policy
+}
+
+// currentPolicy is the policy payments are scored with. It must not be
+// modified; a reload replaces it.
+func currentPolicy() *riskpolicy.Policy {
+	activePolicy.RLock()
+	defer activePolicy.RUnlock()
+	return activePolicy.policy
+}
+
+// listRiskPolicies lists the policy versions, newest first
+func listRiskPolicies(c *gin.Context) {
+	policies, err := repo.RiskPolicyRepository().List()
+	if err != nil {
+		common.Error("Failed to list risk policies: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list risk policies"))
+		return
+	}
+	items := make([]interface{}, 0, len(policies))
+	for _, stored := range policies {
+		response, err := toRiskPolicyResponse(stored)
+		if err != nil {
+			common.Error("Failed to list risk policies: %v", err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list risk policies"))
+			return
+		}
+		items = append(items, response)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getActiveRiskPolicy returns the policy this instance is scoring with,
+// including the built-in version 0
+func getActiveRiskPolicy(c *gin.Context) {
+	policy := currentPolicy()
+	if policy.Version == 0 {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(&RiskPolicyResponse{Policy: policy, Description: "Built-in policy", Active: true}))
+		return
+	}
+	getRiskPolicyVersion(c, policy.Version)
+}
+
+func getRiskPolicy(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "version must be a number"))
+		return
+	}
+	getRiskPolicyVersion(c, version)
+}
+
+func getRiskPolicyVersion(c *gin.Context, version int) {
+	stored, err := repo.RiskPolicyRepository().GetByVersion(version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Risk policy not found"))
+		return
+	}
+	response, err := toRiskPolicyResponse(stored)
+	if err != nil {
+		common.Error("Failed to read risk policy %d: %v", version, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to read risk policy"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
+
+// createRiskPolicy stores a policy as the next version, inactive until it is
+// activated
+func createRiskPolicy(c *gin.Context) {
+	var req RiskPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if err := req.Policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	encoded, err := req.Policy.Encode()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid policy"))
+		return
+	}
+
+	stored := &database.RiskPolicy{Policy: encoded, Description: req.Description}
+	if principal := common.GetPrincipal(c); principal != nil {
+		stored.CreatedBy = principal.Subject
+	}
+	if err := repo.RiskPolicyRepository().Create(stored); err != nil {
+		common.Error("Failed to create risk policy: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create risk policy"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditSystemConfigChanged,
+		Severity:     audit.SeverityMedium,
+		ResourceID:   stored.ID,
+		ResourceType: "risk_policy",
+		Action:       "create",
+		Description:  fmt.Sprintf("Risk policy version %d created", stored.Version),
+		NewValues:    map[string]interface{}{"version": stored.Version, "policy": req.Policy},
+	})
+
+	common.Info("Created risk policy version %d", stored.Version)
+	response, _ := toRiskPolicyResponse(stored)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
+}
+
+// activateRiskPolicy makes a version the one payments are scored with
+func activateRiskPolicy(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "version must be a number"))
+		return
+	}
+	previous := currentPolicy().Version
+
+	stored, err := repo.RiskPolicyRepository().Activate(version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Risk policy not found"))
+		return
+	}
+	if err != nil {
+		common.Error("Failed to activate risk policy %d: %v", version, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to activate risk policy"))
+		return
+	}
+	reloadRiskPolicy()
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditSystemConfigChanged,
+		Severity:     audit.SeverityHigh,
+		ResourceID:   stored.ID,
+		ResourceType: "risk_policy",
+		Action:       "activate",
+		Description:  fmt.Sprintf("Risk policy version %d activated", version),
+		OldValues:    map[string]interface{}{"version": previous},
+		NewValues:    map[string]interface{}{"version": version},
+	})
+
+	response, err := toRiskPolicyResponse(stored)
+	if err != nil {
+		common.Error("Failed to read risk policy %d: %v", version, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to read risk policy"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/riskprofile"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Agent risk profiles
+//
+// Each agent has a trust tier that sets the threshold its payments are
+// scored against, so a long-running agent with a clean history is not held
+// to the same line as one registered yesterday. The tier is assessed from
+// the agent's payment history, refreshed at most hourly as it pays, unless
+// an operator pins it. Operators can also add a standing adjustment to the
+// agent's scores.
+
+// profileReassessAfter is how old an assessment can be before the next
+// evaluation refreshes it
+const profileReassessAfter = time.Hour
+
+type RiskProfileRequest struct {
+	TrustTier       *string  `json:"trustTier"`                                        // Pins the tier; "" returns to assessing it
+	ScoreAdjustment *float64 `json:"scoreAdjustment" binding:"omitempty,gte=-1,lte=1"` // Added to every score
+	Notes           *string  `json:"notes" binding:"omitempty,max=1000"`
+}
+
+type RiskProfileResponse struct {
+	AgentID           string  `json:"agentId"`
+	TrustTier         string  `json:"trustTier"`
+	TierPinned        bool    `json:"tierPinned"`
+	AssessedTier      string  `json:"assessedTier"` // Tier the history alone puts the agent in
+	Threshold         float64 `json:"threshold"`    // Threshold under the active risk policy
+	ScoreAdjustment   float64 `json:"scoreAdjustment"`
+	Notes             string  `json:"notes,omitempty"`
+	UpdatedBy         string  `json:"updatedBy,omitempty"`
+	CompletedPayments int64   `json:"completedPayments"`
+	FailedPayments    int64   `json:"failedPayments"`
+	RefundedPayments  int64   `json:"refundedPayments"`
+	FailureRate       float64 `json:"failureRate"`
+	ChargebackRate    float64 `json:"chargebackRate"`
+	AssessedAt        string  `json:"assessedAt"`
+	UpdatedAt         string  `json:"updatedAt"`
+}
+
+func toRiskProfileResponse(profile *database.AgentRiskProfile, agentCreatedAt time.Time) *RiskProfileResponse {
+	history := riskprofile.History{
+		AgentCreatedAt: agentCreatedAt,
+		Completed:      profile.CompletedPayments,
+		Failed:         profile.FailedPayments,
+		Refunded:       profile.RefundedPayments,
+	}
+	return &RiskProfileResponse{
+		AgentID:           profile.AgentID,
+		TrustTier:         profile.TrustTier,
+		TierPinned:        profile.TierPinned,
+		AssessedTier:      riskprofile.Assess(history, profile.AssessedAt),
+		Threshold:         currentPolicy().ForTier(profile.TrustTier).Threshold,
+		ScoreAdjustment:   profile.ScoreAdjustment,
+		Notes:             profile.Notes,
+		UpdatedBy:         profile.UpdatedBy,
+		CompletedPayments: profile.CompletedPayments,
+		FailedPayments:    profile.FailedPayments,
+		RefundedPayments:  profile.RefundedPayments,
+		FailureRate:       profile.FailureRate,
+		ChargebackRate:    profile.ChargebackRate,
+		AssessedAt:        profile.AssessedAt.Format(time.RFC3339),
+		UpdatedAt:         profile.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// agentRiskProfile returns the agent's profile, creating it on the agent's
+// first evaluation and reassessing it once it is stale or when forced
+func agentRiskProfile(agent *database.Agent, reassess bool) (*database.AgentRiskProfile, error) {
+	now := time.Now()
+	profile, err := repo.AgentRiskProfileRepository().GetByAgentID(agent.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		history, err := agentHistory(agent)
+		if err != nil {
+			return nil, err
+		}
+		profile = &database.AgentRiskProfile{AgentID: agent.ID}
+		riskprofile.Apply(profile, history, now)
+		return profile, repo.AgentRiskProfileRepository().Create(profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !reassess && now.Sub(profile.AssessedAt) < profileReassessAfter {
+		return profile, nil
+	}
+
+	history, err := agentHistory(agent)
+	if err != nil {
+		return nil, err
+	}
+	previous := profile.TrustTier
+	riskprofile.Apply(profile, history, now)
+	if profile.TrustTier != previous {
+		common.Info("Agent %s moved from trust tier %s to %s", agent.ID, previous, profile.TrustTier)
+	}
+	return profile, repo.AgentRiskProfileRepository().Update(profile)
+}
+
+// agentHistory loads the agent's payment history
+func agentHistory(agent *database.Agent) (riskprofile.History, error) {
+	counts, err := repo.PaymentWorkflowRepository().CountByAgentIDByStatus(agent.ID)
+	if err != nil {
+		return riskprofile.History{}, err
+	}
+	refunded, err := repo.RefundRepository().CountRefundedExecutions(agent.ID)
+	if err != nil {
+		return riskprofile.History{}, err
+	}
+	return riskprofile.History{
+		AgentCreatedAt: agent.CreatedAt,
+		Completed:      counts["completed"],
+		Failed:         counts["failed"],
+		Refunded:       refunded,
+	}, nil
+}
+
+// listRiskProfiles lists the agents' risk profiles, of one tier with ?tier=
+func listRiskProfiles(c *gin.Context) {
+	tier := c.Query("tier")
+	if tier != "" && !riskprofile.ValidTier(tier) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "tier must be new, standard, trusted or restricted"))
+		return
+	}
+
+	var profiles []*database.AgentRiskProfile
+	var err error
+	if tenant := common.RequestTenant(c); tenant != "" {
+		profiles, err = repo.AgentRiskProfileRepository().ListByTenantID(tenant, tier)
+	} else {
+		profiles, err = repo.AgentRiskProfileRepository().List(tier)
+	}
+	if err != nil {
+		common.Error("Failed to list risk profiles: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list risk profiles"))
+		return
+	}
+	items := make([]interface{}, 0, len(profiles))
+	for _, profile := range profiles {
+		agent, err := repo.AgentRepository().GetByID(profile.AgentID)
+		if err != nil {
+			continue // Agent deleted
+		}
+		items = append(items, toRiskProfileResponse(profile, agent.CreatedAt))
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getRiskProfile(c *gin.Context) {
+	respondRiskProfile(c, false)
+}
+
+// reassessRiskProfile refreshes the agent's history and tier now
+func reassessRiskProfile(c *gin.Context) {
+	respondRiskProfile(c, true)
+}
+
+func respondRiskProfile(c *gin.Context, reassess bool) {
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	profile, err := agentRiskProfile(agent, reassess)
+	if err != nil {
+		common.Error("Failed to load risk profile of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load risk profile"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toRiskProfileResponse(profile, agent.CreatedAt)))
+}
+
+// updateRiskProfile pins or unpins the agent's tier and sets its manual
+// score adjustment and notes
+func updateRiskProfile(c *gin.Context) {
+	var req RiskProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponseWithDetails("VALIDATION_ERROR", "Invalid request body", err.Error()))
+		return
+	}
+	if req.TrustTier != nil && *req.TrustTier != "" && !riskprofile.ValidTier(*req.TrustTier) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "trustTier must be new, standard, trusted or restricted"))
+		return
+	}
+
+	agent, err := repo.AgentRepository().GetByID(c.Param("id"))
+	if err != nil || !tenancy.Check(c, repo, agent.OwnerPartyID, "agent", agent.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Agent not found"))
+		return
+	}
+	profile, err := agentRiskProfile(agent, false)
+	if err != nil {
+		common.Error("Failed to load risk profile of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load risk profile"))
+		return
+	}
+
+	oldValues := map[string]interface{}{"trustTier": profile.TrustTier, "tierPinned": profile.TierPinned, "scoreAdjustment": profile.ScoreAdjustment}
+	if req.TrustTier != nil {
+		profile.TierPinned = *req.TrustTier != ""
+		if profile.TierPinned {
+			profile.TrustTier = *req.TrustTier
+		} else {
+			profile.TrustTier = riskprofile.Assess(riskprofile.History{
+				AgentCreatedAt: agent.CreatedAt,
+				Completed:      profile.CompletedPayments,
+				Failed:         profile.FailedPayments,
+				Refunded:       profile.RefundedPayments,
+			}, time.Now())
+		}
+	}
+	if req.ScoreAdjustment != nil {
+		profile.ScoreAdjustment = *req.ScoreAdjustment
+	}
+	if req.Notes != nil {
+		profile.Notes = *req.Notes
+	}
+	if principal := common.GetPrincipal(c); principal != nil {
+		profile.UpdatedBy = principal.Subject
+	}
+	if err := repo.AgentRiskProfileRepository().Update(profile); err != nil {
+		common.Error("Failed to update risk profile of agent %s: %v", agent.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update risk profile"))
+		return
+	}
+
+	audit.Record(c, audit.NewAuditTrail(repo), &audit.AuditEntry{
+		EventType:    audit.AuditSystemConfigChanged,
+		Severity:     audit.SeverityMedium,
+		AgentID:      agent.ID,
+		ResourceID:   profile.ID,
+		ResourceType: "agent_risk_profile",
+		Action:       "update",
+		Description:  fmt.Sprintf("Risk profile of agent %s updated: tier %s, score adjustment %.2f", agent.ID, profile.TrustTier, profile.ScoreAdjustment),
+		OldValues:    oldValues,
+		NewValues:    map[string]interface{}{"trustTier": profile.TrustTier, "tierPinned": profile.TierPinned, "scoreAdjustment": profile.ScoreAdjustment},
+	})
+
+	common.Info("Risk profile of agent %s set to tier %s (pinned %t), adjustment %.2f", agent.ID, profile.TrustTier, profile.TierPinned, profile.ScoreAdjustment)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toRiskProfileResponse(profile, agent.CreatedAt)))
+}
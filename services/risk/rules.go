@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/aml"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/riskpolicy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type AMLRuleRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Type        string          `json:"type"`
+	Parameters  *aml.Parameters `json:"parameters"`
+	Action      string          `json:"action"`
+	ScoreImpact *float64        `json:"scoreImpact"`
+	Enabled     *bool           `json:"enabled"`
+}
+
+type AMLRuleResponse struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Type        string         `json:"type"`
+	Parameters  aml.Parameters `json:"parameters"`
+	Action      string         `json:"action"`
+	ScoreImpact float64        `json:"scoreImpact"`
+	Enabled     bool           `json:"enabled"`
+	CreatedBy   string         `json:"createdBy,omitempty"`
+	CreatedAt   string         `json:"createdAt"`
+	UpdatedAt   string         `json:"updatedAt"`
+}
+
+func toAMLRuleResponse(rule *database.AMLRule) *AMLRuleResponse {
+	params, _ := aml.RuleParameters(rule)
+	return &AMLRuleResponse{
+		ID:          rule.ID,
+		Name:        rule.Name,
+		Description: rule.Description,
+		Type:        rule.Type,
+		Parameters:  params,
+		Action:      rule.Action,
+		ScoreImpact: rule.ScoreImpact,
+		Enabled:     rule.Enabled,
+		CreatedBy:   rule.CreatedBy,
+		CreatedAt:   rule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   rule.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// seedDefaultRules creates the standard rules when none are defined, so a new
+// deployment monitors transactions from the start
+func seedDefaultRules() error {
+	rules, err := repo.AMLRuleRepository().List()
+	if err != nil || len(rules) > 0 {
+		return err
+	}
+	for _, rule := range aml.DefaultRules() {
+		params, _ := json.Marshal(aml.DefaultParameters(rule.Type))
+		rule.Parameters = string(params)
+		rule.Enabled = true
+		rule.CreatedBy = "system"
+		if err := repo.AMLRuleRepository().Create(rule); err != nil {
+			return err
+		}
+	}
+	common.Info("Created %d default AML rules", len(aml.DefaultRules()))
+	return nil
+}
+
+// applyAMLRules evaluates the enabled rules against the payment and the
+// agent's recent decisions, adding the triggered rules to the decision. A
+// deny or review rule sets the decision to at least that outcome.
+func applyAMLRules(req RiskEvaluationRequest, decision *RiskDecision, policy *riskpolicy.Policy) {
+	rules, err := repo.AMLRuleRepository().ListEnabled()
+	if err != nil {
+		common.Error("Failed to load AML rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	now := time.Now()
+	history, err := repo.RiskDecisionRepository().ListByAgentIDSince(req.AgentID, now.Add(-aml.LookbackWindow(rules)))
+	if err != nil {
+		common.Error("Failed to load risk history of agent %s: %v", req.AgentID, err)
+		return
+	}
+
+	triggers, err := aml.Evaluate(rules, aml.Payment{
+		AgentID:      req.AgentID,
+		Counterparty: req.Counterparty,
+		AmountUSD:    req.AmountUSD,
+		At:           now,
+	}, history)
+	if err != nil {
+		common.Warn("Skipped AML rules: %v", err)
+	}
+
+	for _, trigger := range triggers {
+		decision.TriggeredRules = append(decision.TriggeredRules, trigger.RuleID)
+		decision.RiskFactors = append(decision.RiskFactors, "aml_"+trigger.Type)
+		decision.Score += trigger.ScoreImpact
+		common.Info("AML rule %s (%s) triggered for agent %s: %s", trigger.RuleID, trigger.Name, req.AgentID, trigger.Detail)
+
+		switch {
+		case trigger.Action == aml.ActionDeny:
+			decision.Decision = "deny"
+			decision.Reason = "Transaction denied - AML rule triggered: " + trigger.Name
+		case trigger.Action == aml.ActionReview && decision.Decision == "approve":
+			decision.Decision = "review"
+			decision.Reason = "Transaction requires manual review - AML rule triggered: " + trigger.Name
+		}
+	}
+	if decision.Score > 1.0 {
+		decision.Score = 1.0
+	}
+
+	// Score impact alone can also cross the thresholds
+	switch outcome := policy.Decide(decision.Score); {
+	case decision.Decision != "deny" && outcome == "deny":
+		decision.Decision = "deny"
+		decision.Reason = "Transaction denied - risk score exceeds threshold"
+	case decision.Decision == "approve" && outcome == "review":
+		decision.Decision = "review"
+		decision.Reason = "Transaction requires manual review"
+	}
+}
+
+func listAMLRules(c *gin.Context) {
+	rules, err := repo.AMLRuleRepository().List()
+	if err != nil {
+		common.Error("Failed to list AML rules: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list AML rules"))
+		return
+	}
+
+	items := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		items[i] = toAMLRuleResponse(rule)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getAMLRule(c *gin.Context) {
+	rule, err := repo.AMLRuleRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "AML rule not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAMLRuleResponse(rule)))
+}
+
+func createAMLRule(c *gin.Context) {
+	var req AMLRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	if req.Name == "" || !aml.ValidRuleType(req.Type) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "name and a valid type are required"))
+		return
+	}
+
+	rule := &database.AMLRule{
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Action:      aml.ActionReview,
+		Enabled:     true,
+	}
+	if principal := common.GetPrincipal(c); principal != nil {
+		rule.CreatedBy = principal.Subject
+	}
+	if !applyAMLRuleRequest(c, rule, req) {
+		return
+	}
+
+	if err := repo.AMLRuleRepository().Create(rule); err != nil {
+		common.Error("Failed to create AML rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create AML rule"))
+		return
+	}
+
+	common.Info("Created AML rule %s (%s)", rule.ID, rule.Name)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toAMLRuleResponse(rule)))
+}
+
+func updateAMLRule(c *gin.Context) {
+	var req AMLRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+
+	rule, err := repo.AMLRuleRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "AML rule not found"))
+		return
+	}
+	if req.Type != "" && req.Type != rule.Type {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "A rule's type cannot be changed"))
+		return
+	}
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if req.Description != "" {
+		rule.Description = req.Description
+	}
+	if !applyAMLRuleRequest(c, rule, req) {
+		return
+	}
+
+	if err := repo.AMLRuleRepository().Update(rule); err != nil {
+		common.Error("Failed to update AML rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to update AML rule"))
+		return
+	}
+
+	common.Info("Updated AML rule %s (%s)", rule.ID, rule.Name)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toAMLRuleResponse(rule)))
+}
+
+// applyAMLRuleRequest sets the tunable fields of a rule, responding with 400
+// if they are invalid
+func applyAMLRuleRequest(c *gin.Context, rule *database.AMLRule, req AMLRuleRequest) bool {
+	if req.Action != "" {
+		if !aml.ValidAction(req.Action) {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "action must be flag, review or deny"))
+			return false
+		}
+		rule.Action = req.Action
+	}
+	if req.ScoreImpact != nil {
+		if *req.ScoreImpact < 0 || *req.ScoreImpact > 1 {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "scoreImpact must be between 0 and 1"))
+			return false
+		}
+		rule.ScoreImpact = *req.ScoreImpact
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Parameters != nil || rule.Parameters == "" {
+		params := aml.DefaultParameters(rule.Type)
+		if req.Parameters != nil {
+			params = *req.Parameters
+		}
+		encoded, _ := json.Marshal(params)
+		rule.Parameters = string(encoded)
+	}
+	return true
+}
+
+func deleteAMLRule(c *gin.Context) {
+	rule, err := repo.AMLRuleRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "AML rule not found"))
+		return
+	}
+	if err := repo.AMLRuleRepository().Delete(rule.ID); err != nil {
+		common.Error("Failed to delete AML rule: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to delete AML rule"))
+		return
+	}
+
+	common.Info("Deleted AML rule %s (%s)", rule.ID, rule.Name)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": rule.ID, "deleted": true}))
+}
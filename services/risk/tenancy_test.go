@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOtherTenantsDeclineRulesAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, _ = dbtest.Open(t)
+
+	// Two tenants, each an organization with a quiet-hours rule
+	parties := map[string]*database.Party{}
+	rules := map[string]*database.AutoDeclineRule{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		rule := &database.AutoDeclineRule{OwnerPartyID: party.ID, Name: "Quiet hours", Type: "quiet_hours", Parameters: `{"startHour":22,"endHour":6}`, Enabled: true}
+		if err := repo.AutoDeclineRuleRepository().Create(rule); err != nil {
+			t.Fatal(err)
+		}
+		parties[name], rules[name] = party, rule
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: parties["Acme"].ID, TenantID: parties["Acme"].ID}
+	get := func(path string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.GET("/v1/risk/decline-rules/:id", getDeclineRule)
+		router.GET("/v1/risk/rules", common.RequirePlatform(), listAMLRules)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if code := get("/v1/risk/decline-rules/" + rules["Acme"].ID); code != http.StatusOK {
+		t.Fatalf("own decline rule: got %d", code)
+	}
+	if code := get("/v1/risk/decline-rules/" + rules["Globex"].ID); code != http.StatusNotFound {
+		t.Fatalf("other tenant's decline rule: got %d, want 404", code)
+	}
+
+	// The AML rules apply to every tenant and are the platform's alone
+	if code := get("/v1/risk/rules"); code != http.StatusForbidden {
+		t.Fatalf("AML rules as a tenant: got %d, want 403", code)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/example/agent-payments/internal/velocity"
+	"github.com/example/agent-payments/libs/common"
+)
+
+var velocityLimits = velocity.NewLimitsFromEnv()
+
+// behavioralSignals computes the agent's recent payment behavior and the
+// signals it raises for the payment. When history cannot be loaded the
+// payment is scored on its own, without features.
+func behavioralSignals(req RiskEvaluationRequest) (*velocity.Features, []velocity.Signal) {
+	now := time.Now()
+	since := now.Add(-velocity.Lookback)
+
+	workflows, err := repo.PaymentWorkflowRepository().ListByAgentIDSince(req.AgentID, since)
+	if err != nil {
+		common.Error("Failed to load payment history of agent %s: %v", req.AgentID, err)
+		return nil, nil
+	}
+	executions, err := repo.PaymentExecutionRepository().ListByAgentIDSince(req.AgentID, now.Add(-7*24*time.Hour))
+	if err != nil {
+		common.Error("Failed to load execution history of agent %s: %v", req.AgentID, err)
+		return nil, nil
+	}
+
+	payment := velocity.Payment{
+		AgentID:      req.AgentID,
+		WorkflowID:   req.WorkflowID,
+		Counterparty: req.Counterparty,
+		AmountUSD:    req.AmountUSD,
+		At:           now,
+	}
+	features := velocity.Compute(payment, workflows, executions)
+	signals := velocityLimits.Score(payment, features)
+	for _, signal := range signals {
+		common.Info("Behavioral signal %s for agent %s: %s", signal.Factor, req.AgentID, signal.Detail)
+	}
+	return &features, signals
+}
+
+// encodeFeatures serializes behavioral features for a JSON column
+func encodeFeatures(features *velocity.Features) string {
+	if features == nil {
+		return "{}"
+	}
+	data, _ := json.Marshal(features)
+	return string(data)
+}
+
+// decodeFeatures reads behavioral features from a JSON column
+func decodeFeatures(value string) map[string]interface{} {
+	features := map[string]interface{}{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &features)
+	}
+	return features
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/descriptions"
+	"github.com/example/agent-payments/internal/funding"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// adapterTimeout bounds each call to a processor
+const adapterTimeout = 30 * time.Second
+
+// railFailover sends executions over the next-best rail when their rail's
+// processor is unavailable; RAIL_FAILOVER_ENABLED=false turns it off
+var railFailover = common.GetEnvAsBool("RAIL_FAILOVER_ENABLED", true)
+
+// executePaymentAsync sends a payment through its rail adapter. Card-style
+// rails authorize first and are captured here; push rails move funds at
+// authorization. Payments still settling stay "processing" until the
+// processor reports a final status by webhook or status refresh. Rails
+// settling on acceptance are executed within the execution request instead
+// of in the background.
+func executePaymentAsync(execution *database.PaymentExecution) {
+	common.Info("Executing payment %s via %s", execution.ID, execution.Rail)
+
+	// Update status to processing
+	execution.Status = "processing"
+	if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
+		common.Error("Failed to update payment execution status: %v", err)
+		return
+	}
+
+	adapter, err := railAdapters.Get(execution.Rail)
+	if err != nil {
+		failExecution(execution, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adapters.WithExecution(context.Background(), execution.ID), adapterTimeout)
+	defer cancel()
+
+	adapter, result, err := authorize(ctx, execution, adapter)
+	if err != nil {
+		failExecution(execution, "authorization failed: "+err.Error())
+		return
+	}
+	execution.ReferenceID = result.ReferenceID
+
+	if result.Status == adapters.StatusAuthorized {
+		result, err = adapter.Capture(ctx, result.ReferenceID)
+		if err != nil {
+			// Release the hold so the payer is not left with reserved funds
+			if _, cancelErr := adapter.Cancel(ctx, execution.ReferenceID); cancelErr != nil {
+				common.Error("Failed to cancel authorization %s: %v", execution.ReferenceID, cancelErr)
+			}
+			failExecution(execution, "capture failed: "+err.Error())
+			return
+		}
+	}
+
+	recordChainSettlement(execution, result)
+	applyAdapterStatus(execution, result.Status, result.Message)
+}
+
+// authorize sends an execution to its rail's processor. When the processor
+// is unavailable, so that it certainly did not take the payment, the
+// execution fails over to the next-best healthy rail, trying each rail once.
+// It returns the adapter of the rail the payment was authorized on.
+func authorize(ctx context.Context, execution *database.PaymentExecution, adapter adapters.RailAdapter) (adapters.RailAdapter, *adapters.Result, error) {
+	tried := map[string]bool{}
+	for {
+		tried[execution.Rail] = true
+		result, err := adapter.Authorize(ctx, adapters.Instruction{
+			PaymentID:     execution.ID,
+			AgentID:       execution.AgentID,
+			AmountUSD:     execution.AmountUSD,
+			Counterparty:  execution.Counterparty,
+			Description:   execution.Description,
+			International: international.Decode(execution.International),
+		})
+		if err == nil || !railFailover || !adapters.IsRetryable(err) {
+			return adapter, result, err
+		}
+
+		next := failoverRail(execution, tried)
+		if next == "" {
+			return adapter, nil, err
+		}
+		nextAdapter, getErr := railAdapters.Get(next)
+		if getErr != nil {
+			return adapter, nil, err
+		}
+
+		common.Warn("Failing over payment %s from %s to %s: %v", execution.ID, execution.Rail, next, err)
+		if execution.FailedOverFrom == "" {
+			execution.FailedOverFrom = execution.Rail
+		}
+		execution.Rail = next
+		execution.Description = descriptions.ForRail(execution.Description, railCharacteristics(next))
+		if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
+			return adapter, nil, fmt.Errorf("failed to record failover to %s: %v", next, err)
+		}
+		adapter = nextAdapter
+	}
+}
+
+// failoverRail picks the next-best healthy rail for an execution among those
+// not yet tried that can pay its counterparty, or returns "" if there is
+// none. Batched rails are left out, since the execution is already being
+// sent, as are ACH payments to counterparties without a verified bank
+// account.
+func failoverRail(execution *database.PaymentExecution, tried map[string]bool) string {
+	var candidates []RailOption
+	for _, rail := range payableRails(getAvailableRails(execution.AmountUSD), execution.Counterparty, international.Decode(execution.International)) {
+		if tried[rail.Rail] || batchingFor(rail.Rail) != nil {
+			continue
+		}
+		if _, err := railAdapters.Get(rail.Rail); err != nil {
+			continue
+		}
+		if rail.Rail == "ach" && requireVerifiedCounterparties {
+			agent, err := repo.AgentRepository().GetByID(execution.AgentID)
+			if err != nil {
+				continue
+			}
+			if _, err := funding.CheckCounterpartyAccount(repo, agent.OwnerPartyID, execution.Counterparty); err != nil {
+				continue
+			}
+		}
+		candidates = append(candidates, rail)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	rail, _ := pickRail(candidates, execution.Priority)
+	return rail.Rail
+}
+
+// applyAdapterStatus maps a processor status onto the execution and saves it
+func applyAdapterStatus(execution *database.PaymentExecution, status, message string) {
+	switch status {
+	case adapters.StatusSettled:
+		execution.Status = "completed"
+		common.Info("Payment %s completed successfully", execution.ID)
+	case adapters.StatusFailed, adapters.StatusCancelled:
+		execution.Status = "failed"
+		execution.ErrorMessage = message
+		common.Error("Payment %s failed: %s", execution.ID, message)
+	case adapters.StatusRefunded:
+		// Refunds happen after completion; the execution itself succeeded
+		execution.Status = "completed"
+		execution.ErrorMessage = "refunded"
+	default:
+		execution.Status = "processing"
+	}
+
+	execution.UpdatedAt = time.Now()
+	if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
+		common.Error("Failed to update payment execution final status: %v", err)
+	}
+	if execution.BatchID != nil {
+		recordBatchItemResult(execution)
+	}
+}
+
+func failExecution(execution *database.PaymentExecution, message string) {
+	applyAdapterStatus(execution, adapters.StatusFailed, message)
+}
+
+// refreshExecutionStatus polls the processor for a payment still settling
+func refreshExecutionStatus(ctx context.Context, execution *database.PaymentExecution) {
+	adapter, err := railAdapters.Get(execution.Rail)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adapters.WithExecution(ctx, execution.ID), adapterTimeout)
+	defer cancel()
+
+	result, err := adapter.GetStatus(ctx, execution.ReferenceID)
+	if err != nil {
+		if !errors.Is(err, adapters.ErrNotFound) {
+			common.Warn("Failed to refresh status of payment %s: %v", execution.ID, err)
+		}
+		return
+	}
+	recordChainSettlement(execution, result)
+	if adapters.IsTerminal(result.Status) {
+		applyAdapterStatus(execution, result.Status, result.Message)
+	}
+}
+
+// handleRailWebhook applies a status change pushed by a processor
+func handleRailWebhook(c *gin.Context) {
+	adapter, err := railAdapters.Get(c.Param("rail"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Unknown rail"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid webhook body"))
+		return
+	}
+
+	event, err := adapter.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		common.Warn("Rejected %s webhook: %v", adapter.Rail(), err)
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_WEBHOOK", err.Error()))
+		return
+	}
+	// Events unrelated to payment status are acknowledged and ignored
+	if event == nil || event.ReferenceID == "" {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"received": true}))
+		return
+	}
+
+	execution, err := repo.PaymentExecutionRepository().GetByReferenceID(event.ReferenceID)
+	if err != nil {
+		// Acknowledge unknown references so the processor stops retrying
+		common.Warn("Webhook %s for unknown %s reference %s", event.ID, adapter.Rail(), event.ReferenceID)
+		c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"received": true}))
+		return
+	}
+
+	// Final statuses are not reopened by late or replayed events
+	// Only a refund may follow completion
+	if execution.Status == "failed" || execution.Status == "reversed" || (execution.Status == "completed" && event.Status != adapters.StatusRefunded) {
+		c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"received": true}))
+		return
+	}
+
+	applyAdapterStatus(execution, event.Status, event.Message)
+	common.Info("Webhook %s updated payment %s to %s", event.ID, execution.ID, execution.Status)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"received": true, "paymentId": execution.ID}))
+}
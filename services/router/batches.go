@@ -0,0 +1,632 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ACH batching
+//
+// With ACH_BATCHING_ENABLED, ACH executions are not sent one by one. Each is
+// added to the open batch of the next cutoff window (ACH_BATCH_CUTOFFS, times
+// of day on weekdays in ACH_BATCH_TIMEZONE) and stays pending until the batch
+// is submitted. Batches are closed at their cutoff and, with
+// ACH_BATCH_AUTO_SUBMIT, submitted right away; otherwise operators submit them.
+// Submitting books each agent's share of the batch to its ACH In Transit
+// account, referenced by the batch reference, then sends the items. Once every
+// item has completed or failed the batch is settled and the entries reversed.
+
+// Batch statuses
+const (
+	BatchOpen      = "open"
+	BatchClosed    = "closed"
+	BatchSubmitted = "submitted"
+	BatchCompleted = "completed"
+	BatchFailed    = "failed" // Every item failed
+)
+
+// Batch item statuses
+const (
+	BatchItemPending   = "pending"
+	BatchItemSubmitted = "submitted"
+	BatchItemCompleted = "completed"
+	BatchItemFailed    = "failed"
+	BatchItemRemoved   = "removed" // Voided before the batch was submitted
+)
+
+// Ledger accounts batched payments clear through
+const (
+	achInTransitAccountName = "ACH In Transit"
+	achClearingAccountName  = "ACH Clearing"
+)
+
+// batchActor is recorded for batches closed and submitted at their cutoff
+const batchActor = "batch-scheduler"
+
+// achBatching is the ACH cutoff windows, or nil when ACH is not batched
+var achBatching *batchWindows
+
+// batchWindows are the cutoffs a rail's batches close at
+type batchWindows struct {
+	cutoffs    [][2]int // Hour and minute of each cutoff, in order
+	location   *time.Location
+	autoSubmit bool
+}
+
+type PaymentBatchResponse struct {
+	ID                   string                      `json:"id"`
+	Rail                 string                      `json:"rail"`
+	Reference            string                      `json:"reference"`
+	CutoffAt             string                      `json:"cutoffAt"`
+	Status               string                      `json:"status"`
+	ItemCount            int                         `json:"itemCount"`
+	TotalUSD             float64                     `json:"totalUSD"`
+	SettledCount         int                         `json:"settledCount"`
+	SettledUSD           float64                     `json:"settledUSD"`
+	FailedCount          int                         `json:"failedCount"`
+	ClosedBy             string                      `json:"closedBy,omitempty"`
+	SubmittedBy          string                      `json:"submittedBy,omitempty"`
+	ClosedAt             string                      `json:"closedAt,omitempty"`
+	SubmittedAt          string                      `json:"submittedAt,omitempty"`
+	CompletedAt          string                      `json:"completedAt,omitempty"`
+	LedgerTransactionIDs []string                    `json:"ledgerTransactionIds,omitempty"`
+	Items                []*PaymentBatchItemResponse `json:"items,omitempty"`
+	CreatedAt            string                      `json:"createdAt"`
+	UpdatedAt            string                      `json:"updatedAt"`
+}
+
+type PaymentBatchItemResponse struct {
+	ID           string  `json:"id"`
+	ExecutionID  string  `json:"executionId"`
+	AgentID      string  `json:"agentId"`
+	AmountUSD    float64 `json:"amountUSD"`
+	Counterparty string  `json:"counterparty"`
+	Status       string  `json:"status"`
+	ReferenceID  string  `json:"referenceId,omitempty"`
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+	SubmittedAt  string  `json:"submittedAt,omitempty"`
+	CompletedAt  string  `json:"completedAt,omitempty"`
+}
+
+func toPaymentBatchResponse(batch *database.PaymentBatch, items []*database.PaymentBatchItem) *PaymentBatchResponse {
+	response := &PaymentBatchResponse{
+		ID:           batch.ID,
+		Rail:         batch.Rail,
+		Reference:    batch.Reference,
+		CutoffAt:     batch.CutoffAt.Format(time.RFC3339),
+		Status:       batch.Status,
+		ItemCount:    batch.ItemCount,
+		TotalUSD:     batch.TotalUSD,
+		SettledCount: batch.SettledCount,
+		SettledUSD:   batch.SettledUSD,
+		FailedCount:  batch.FailedCount,
+		ClosedBy:     batch.ClosedBy,
+		SubmittedBy:  batch.SubmittedBy,
+		CreatedAt:    batch.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    batch.UpdatedAt.Format(time.RFC3339),
+	}
+	if batch.ClosedAt != nil {
+		response.ClosedAt = batch.ClosedAt.Format(time.RFC3339)
+	}
+	if batch.SubmittedAt != nil {
+		response.SubmittedAt = batch.SubmittedAt.Format(time.RFC3339)
+	}
+	if batch.CompletedAt != nil {
+		response.CompletedAt = batch.CompletedAt.Format(time.RFC3339)
+	}
+	if batch.LedgerTransactionIDs != "" {
+		json.Unmarshal([]byte(batch.LedgerTransactionIDs), &response.LedgerTransactionIDs)
+	}
+	for _, item := range items {
+		itemResponse := &PaymentBatchItemResponse{
+			ID:           item.ID,
+			ExecutionID:  item.ExecutionID,
+			AgentID:      item.AgentID,
+			AmountUSD:    item.AmountUSD,
+			Counterparty: item.Counterparty,
+			Status:       item.Status,
+			ReferenceID:  item.ReferenceID,
+			ErrorMessage: item.ErrorMessage,
+		}
+		if item.SubmittedAt != nil {
+			itemResponse.SubmittedAt = item.SubmittedAt.Format(time.RFC3339)
+		}
+		if item.CompletedAt != nil {
+			itemResponse.CompletedAt = item.CompletedAt.Format(time.RFC3339)
+		}
+		response.Items = append(response.Items, itemResponse)
+	}
+	return response
+}
+
+// initACHBatching reads the ACH_BATCH* settings and, when batching is
+// enabled, closes and submits batches at their cutoffs until ctx is done
+func initACHBatching(ctx context.Context) error {
+	if !common.GetEnvAsBool("ACH_BATCHING_ENABLED", false) {
+		return nil
+	}
+	windows, err := parseBatchWindows(common.GetEnv("ACH_BATCH_CUTOFFS", "10:30,14:30,16:45"), common.GetEnv("ACH_BATCH_TIMEZONE", "America/New_York"))
+	if err != nil {
+		return err
+	}
+	windows.autoSubmit = common.GetEnvAsBool("ACH_BATCH_AUTO_SUBMIT", true)
+	achBatching = windows
+
+	interval := time.Duration(common.GetEnvAsInt("ACH_BATCH_INTERVAL_SECONDS", 60)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				closeDueBatches(now)
+			}
+		}
+	}()
+	common.Info("ACH batching enabled with cutoffs %s", common.GetEnv("ACH_BATCH_CUTOFFS", "10:30,14:30,16:45"))
+	return nil
+}
+
+// parseBatchWindows parses comma-separated HH:MM cutoffs in a time zone
+func parseBatchWindows(cutoffs, timezone string) (*batchWindows, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown ACH_BATCH_TIMEZONE %q", timezone)
+	}
+	windows := &batchWindows{location: location}
+	for _, cutoff := range strings.Split(cutoffs, ",") {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(cutoff))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACH_BATCH_CUTOFFS time %q, expected HH:MM", cutoff)
+		}
+		windows.cutoffs = append(windows.cutoffs, [2]int{parsed.Hour(), parsed.Minute()})
+	}
+	for i := 1; i < len(windows.cutoffs); i++ {
+		previous, current := windows.cutoffs[i-1], windows.cutoffs[i]
+		if current[0]*60+current[1] <= previous[0]*60+previous[1] {
+			return nil, errors.New("ACH_BATCH_CUTOFFS must be in ascending order")
+		}
+	}
+	return windows, nil
+}
+
+// next returns the first cutoff after now, on a weekday
+func (w *batchWindows) next(now time.Time) time.Time {
+	local := now.In(w.location)
+	for day := 0; day < 8; day++ {
+		date := local.AddDate(0, 0, day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		for _, cutoff := range w.cutoffs {
+			at := time.Date(date.Year(), date.Month(), date.Day(), cutoff[0], cutoff[1], 0, 0, w.location)
+			if at.After(now) {
+				return at
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// batchingFor returns the windows a rail's executions are batched in, or nil
+// if they execute individually
+func batchingFor(rail string) *batchWindows {
+	if rail == "ach" {
+		return achBatching
+	}
+	return nil
+}
+
+// addToBatch adds a new execution to the open batch of the next cutoff. A
+// batch closed while it was being added to is followed by another.
+func addToBatch(execution *database.PaymentExecution, windows *batchWindows) (*database.PaymentBatch, error) {
+	var batch *database.PaymentBatch
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		cutoff := windows.next(time.Now())
+		prefix := strings.ToUpper(execution.Rail) + "-" + cutoff.Format("20060102-1504")
+		item := &database.PaymentBatchItem{
+			ExecutionID:  execution.ID,
+			AgentID:      execution.AgentID,
+			AmountUSD:    execution.AmountUSD,
+			Counterparty: execution.Counterparty,
+		}
+		err = repo.Transaction(func(tx database.Repository) error {
+			if batch, err = tx.PaymentBatchRepository().AddItem(execution.Rail, cutoff, prefix, item); err != nil {
+				return err
+			}
+			execution.BatchID = &batch.ID
+			return tx.PaymentExecutionRepository().Update(execution)
+		})
+		if err == nil {
+			return batch, nil
+		}
+	}
+	execution.BatchID = nil
+	return nil, err
+}
+
+// closeDueBatches closes the open batches past their cutoff and submits them
+// when auto submission is on
+func closeDueBatches(now time.Time) {
+	due, err := repo.PaymentBatchRepository().ListDue(now)
+	if err != nil {
+		common.Error("Failed to list due payment batches: %v", err)
+		return
+	}
+	for _, batch := range due {
+		batch.ClosedAt = &now
+		if closed, err := repo.PaymentBatchRepository().Transition(batch, BatchOpen, BatchClosed); err != nil || !closed {
+			continue
+		}
+		common.Info("Closed batch %s with %d payments of %.2f USD", batch.Reference, batch.ItemCount, batch.TotalUSD)
+
+		if achBatching != nil && achBatching.autoSubmit {
+			startBatchSubmission(batch, batchActor)
+		}
+	}
+}
+
+// startBatchSubmission marks a closed batch submitted and sends its items in
+// the background
+func startBatchSubmission(batch *database.PaymentBatch, actor string) bool {
+	now := time.Now()
+	batch.SubmittedAt = &now
+	batch.SubmittedBy = actor
+	submitted, err := repo.PaymentBatchRepository().Transition(batch, BatchClosed, BatchSubmitted)
+	if err != nil {
+		common.Error("Failed to submit batch %s: %v", batch.Reference, err)
+	}
+	if !submitted {
+		return false
+	}
+	sendBatchInBackground(batch)
+	return true
+}
+
+func sendBatchInBackground(batch *database.PaymentBatch) {
+	if !executionWorkers.Go(batch.ID, func(context.Context) { sendBatch(batch) }) {
+		common.Warn("Shutting down, batch %s left with pending items", batch.Reference)
+	}
+}
+
+// sendBatch books the submitted batch's clearing entries, unless already
+// booked, and sends each pending item through the rail
+func sendBatch(batch *database.PaymentBatch) {
+	items, err := repo.PaymentBatchRepository().ListItems(batch.ID)
+	if err != nil {
+		common.Error("Failed to list items of batch %s: %v", batch.Reference, err)
+		return
+	}
+
+	booked, err := repo.TransactionRepository().ListByReferenceID(batch.Reference)
+	if err != nil {
+		common.Error("Failed to check ledger entries of batch %s: %v", batch.Reference, err)
+		return
+	}
+	if len(booked) == 0 {
+		if err := postBatchClearing(batch, items, false); err != nil {
+			common.Error("Batch %s submitted but clearing entries failed: %v", batch.Reference, err)
+		}
+	}
+
+	sent := 0
+	for _, item := range items {
+		if item.Status != BatchItemPending {
+			continue
+		}
+		execution, err := repo.PaymentExecutionRepository().GetByID(item.ExecutionID)
+		if err != nil {
+			common.Error("Failed to load execution %s of batch %s: %v", item.ExecutionID, batch.Reference, err)
+			continue
+		}
+		if execution.Status != "pending" {
+			continue
+		}
+		executePaymentAsync(execution)
+		sent++
+	}
+	common.Info("Submitted batch %s: sent %d payments", batch.Reference, sent)
+
+	// A batch whose items all finished at submission is settled now
+	settleBatch(batch.ID)
+}
+
+// recordBatchItemResult records the status of a batched execution in its
+// batch item, and settles the batch once its last item has finished
+func recordBatchItemResult(execution *database.PaymentExecution) {
+	item, err := repo.PaymentBatchRepository().GetItemByExecutionID(execution.ID)
+	if err != nil {
+		common.Error("Failed to load batch item of execution %s: %v", execution.ID, err)
+		return
+	}
+
+	now := time.Now()
+	item.ReferenceID = execution.ReferenceID
+	if item.SubmittedAt == nil {
+		item.SubmittedAt = &now
+	}
+	switch execution.Status {
+	case "processing":
+		if item.Status == BatchItemPending {
+			item.Status = BatchItemSubmitted
+			if err := repo.PaymentBatchRepository().UpdateItem(item); err != nil {
+				common.Error("Failed to update batch item %s: %v", item.ID, err)
+			}
+		}
+		return
+	case "completed":
+		item.Status = BatchItemCompleted
+	case "failed":
+		item.Status = BatchItemFailed
+		item.ErrorMessage = execution.ErrorMessage
+	default:
+		return
+	}
+	item.CompletedAt = &now
+
+	finished, err := repo.PaymentBatchRepository().FinishItem(item)
+	if err != nil {
+		common.Error("Failed to record result of batch item %s: %v", item.ID, err)
+		return
+	}
+	if finished {
+		settleBatch(item.BatchID)
+	}
+}
+
+// settleBatch completes a submitted batch once every item has completed or
+// failed, and reverses its clearing entries
+func settleBatch(batchID string) {
+	batch, err := repo.PaymentBatchRepository().GetByID(batchID)
+	if err != nil {
+		common.Error("Failed to load batch %s: %v", batchID, err)
+		return
+	}
+	if batch.Status != BatchSubmitted || batch.SettledCount+batch.FailedCount < batch.ItemCount {
+		return
+	}
+
+	status := BatchCompleted
+	if batch.ItemCount > 0 && batch.SettledCount == 0 {
+		status = BatchFailed
+	}
+	now := time.Now()
+	batch.CompletedAt = &now
+	settled, err := repo.PaymentBatchRepository().Transition(batch, BatchSubmitted, status)
+	if err != nil || !settled {
+		return
+	}
+
+	items, err := repo.PaymentBatchRepository().ListItems(batch.ID)
+	if err == nil {
+		err = postBatchClearing(batch, items, true)
+	}
+	if err != nil {
+		common.Error("Batch %s settled but clearing entries failed: %v", batch.Reference, err)
+	}
+	common.Info("Batch %s %s: %d of %d payments settled, %.2f USD", batch.Reference, status, batch.SettledCount, batch.ItemCount, batch.SettledUSD)
+}
+
+// postBatchClearing books, per agent, the agent's share of the batch from its
+// ACH Clearing account to its ACH In Transit account at submission, and back
+// at settlement. The transactions are referenced by the batch reference.
+func postBatchClearing(batch *database.PaymentBatch, items []*database.PaymentBatchItem, settlement bool) error {
+	totals := make(map[string]float64)
+	counts := make(map[string]int)
+	var agentIDs []string
+	for _, item := range items {
+		if item.Status == BatchItemRemoved {
+			continue
+		}
+		if _, ok := totals[item.AgentID]; !ok {
+			agentIDs = append(agentIDs, item.AgentID)
+		}
+		totals[item.AgentID] += item.AmountUSD
+		counts[item.AgentID]++
+	}
+
+	var transactionIDs []string
+	for _, agentID := range agentIDs {
+		inTransit, err := batchAccount(agentID, achInTransitAccountName, "asset")
+		if err != nil {
+			return err
+		}
+		clearing, err := batchAccount(agentID, achClearingAccountName, "liability")
+		if err != nil {
+			return err
+		}
+
+		amount := totals[agentID]
+		description := fmt.Sprintf("Batch %s submitted: %d payments", batch.Reference, counts[agentID])
+		if settlement {
+			amount = -amount
+			description = fmt.Sprintf("Batch %s settled: %d payments", batch.Reference, counts[agentID])
+		}
+		transaction := &database.Transaction{
+			AgentID:     agentID,
+			Description: description,
+			ReferenceID: batch.Reference,
+			Status:      "posted",
+		}
+		postings := []*database.Posting{
+			{AccountID: inTransit.ID, Amount: amount, Currency: inTransit.Currency},
+			{AccountID: clearing.ID, Amount: -amount, Currency: clearing.Currency},
+		}
+		if err := repo.TransactionRepository().Post(transaction, postings); err != nil {
+			return err
+		}
+		transactionIDs = append(transactionIDs, transaction.ID)
+	}
+	if len(transactionIDs) == 0 {
+		return nil
+	}
+	return repo.PaymentBatchRepository().AddLedgerTransactions(batch.ID, transactionIDs)
+}
+
+// batchAccount finds one of the agent's batch clearing accounts, creating it
+// if needed
+func batchAccount(agentID, name, accountType string) (*database.Account, error) {
+	accounts, err := repo.AccountRepository().ListByAgentIDAndType(agentID, accountType)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == name && account.Currency == "USD" {
+			return account, nil
+		}
+	}
+
+	account := &database.Account{
+		AgentID:     agentID,
+		Name:        name,
+		Type:        accountType,
+		Description: "Batched ACH payments submitted and not yet settled",
+		Currency:    "USD",
+	}
+	if err := repo.AccountRepository().Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// removeFromBatch takes a pending execution out of its batch so it can be
+// voided, returning false once the batch has been submitted
+func removeFromBatch(execution *database.PaymentExecution) (bool, error) {
+	item, err := repo.PaymentBatchRepository().GetItemByExecutionID(execution.ID)
+	if err != nil {
+		return false, err
+	}
+	return repo.PaymentBatchRepository().RemoveItem(item)
+}
+
+// listPaymentBatches lists batches newest first, filtered by ?rail= and
+// ?status=
+func listPaymentBatches(c *gin.Context) {
+	limit := 100
+	if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 && value < limit {
+		limit = value
+	}
+	batches, err := repo.PaymentBatchRepository().List(c.Query("rail"), c.Query("status"), limit)
+	if err != nil {
+		common.Error("Failed to list payment batches: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list payment batches"))
+		return
+	}
+
+	items := make([]interface{}, len(batches))
+	for i, batch := range batches {
+		items[i] = toPaymentBatchResponse(batch, nil)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// getPaymentBatch returns a batch with the result of each item
+func getPaymentBatch(c *gin.Context) {
+	batch, ok := loadPaymentBatch(c)
+	if !ok {
+		return
+	}
+	items, err := repo.PaymentBatchRepository().ListItems(batch.ID)
+	if err != nil {
+		common.Error("Failed to list items of batch %s: %v", batch.Reference, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load payment batch"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentBatchResponse(batch, items)))
+}
+
+// closePaymentBatch closes an open batch before its cutoff. New executions
+// go to another batch for the same cutoff.
+func closePaymentBatch(c *gin.Context) {
+	batch, ok := loadPaymentBatch(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	batch.ClosedAt = &now
+	batch.ClosedBy = actorOf(c)
+	closed, err := repo.PaymentBatchRepository().Transition(batch, BatchOpen, BatchClosed)
+	if err != nil {
+		common.Error("Failed to close batch %s: %v", batch.Reference, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to close payment batch"))
+		return
+	}
+	if !closed {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only open batches can be closed"))
+		return
+	}
+
+	common.Info("Batch %s closed early by %s", batch.Reference, batch.ClosedBy)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toPaymentBatchResponse(batch, nil)))
+}
+
+// submitPaymentBatch submits a batch, closing it first if it is open. For a
+// batch already submitted it sends the items left pending, such as after a
+// restart during submission.
+func submitPaymentBatch(c *gin.Context) {
+	batch, ok := loadPaymentBatch(c)
+	if !ok {
+		return
+	}
+	actor := actorOf(c)
+
+	switch batch.Status {
+	case BatchOpen:
+		now := time.Now()
+		batch.ClosedAt = &now
+		batch.ClosedBy = actor
+		if closed, err := repo.PaymentBatchRepository().Transition(batch, BatchOpen, BatchClosed); err != nil || !closed {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Batch changed while being submitted"))
+			return
+		}
+		fallthrough
+	case BatchClosed:
+		if !startBatchSubmission(batch, actor) {
+			c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Batch changed while being submitted"))
+			return
+		}
+	case BatchSubmitted:
+		sendBatchInBackground(batch)
+	default:
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Batch is already "+batch.Status))
+		return
+	}
+
+	common.Info("Batch %s submitted by %s", batch.Reference, actor)
+	c.JSON(http.StatusAccepted, common.NewSuccessResponse(toPaymentBatchResponse(batch, nil)))
+}
+
+func loadPaymentBatch(c *gin.Context) (*database.PaymentBatch, bool) {
+	batch, err := repo.PaymentBatchRepository().GetByID(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment batch not found"))
+		return nil, false
+	}
+	if err != nil {
+		common.Error("Failed to load payment batch %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load payment batch"))
+		return nil, false
+	}
+	return batch, true
+}
+
+func actorOf(c *gin.Context) string {
+	if principal := common.GetPrincipal(c); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
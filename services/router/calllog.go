@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// recordAdapterCall stores a call made to a processor in the adapter call log.
+// Failing to store it is logged but does not fail the payment.
+func recordAdapterCall(ctx context.Context, call *adapters.Call) {
+	entry := &database.AdapterCallLog{
+		ExecutionID:       call.ExecutionID,
+		Rail:              call.Rail,
+		Operation:         call.Operation,
+		Method:            call.Method,
+		Endpoint:          call.Endpoint,
+		StatusCode:        call.StatusCode,
+		Outcome:           call.Outcome,
+		ProviderReference: call.Reference,
+		LatencyMs:         call.Latency.Milliseconds(),
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = "unknown"
+	}
+	if call.Err != nil {
+		entry.Error = call.Err.Error()
+		if len(entry.Error) > 500 {
+			entry.Error = entry.Error[:500]
+		}
+	}
+	if len(entry.Endpoint) > 500 {
+		entry.Endpoint = entry.Endpoint[:500]
+	}
+	if data, err := json.Marshal(call.Request); err == nil {
+		entry.RequestSummary = string(data)
+	}
+
+	if err := repo.AdapterCallLogRepository().Create(entry); err != nil {
+		common.Error("Failed to log %s %s call for payment %s: %v", call.Rail, call.Operation, call.ExecutionID, err)
+	}
+}
+
+type AdapterCallResponse struct {
+	ID                string            `json:"id"`
+	Rail              string            `json:"rail"`
+	Operation         string            `json:"operation"`
+	Method            string            `json:"method,omitempty"`
+	Endpoint          string            `json:"endpoint,omitempty"`
+	Request           map[string]string `json:"request"`
+	StatusCode        int               `json:"statusCode,omitempty"`
+	Outcome           string            `json:"outcome"`
+	Error             string            `json:"error,omitempty"`
+	ProviderReference string            `json:"providerReference,omitempty"`
+	LatencyMs         int64             `json:"latencyMs"`
+	CreatedAt         string            `json:"createdAt"`
+}
+
+// listAdapterCalls lists the calls made to the processor for a payment, in
+// the order they were made
+func listAdapterCalls(c *gin.Context) {
+	execution, err := repo.PaymentExecutionRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
+	}
+
+	calls, err := repo.AdapterCallLogRepository().ListByExecutionID(execution.ID)
+	if err != nil {
+		common.Error("Failed to list adapter calls: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list adapter calls"))
+		return
+	}
+
+	items := make([]interface{}, len(calls))
+	for i, call := range calls {
+		response := &AdapterCallResponse{
+			ID:                call.ID,
+			Rail:              call.Rail,
+			Operation:         call.Operation,
+			Method:            call.Method,
+			Endpoint:          call.Endpoint,
+			StatusCode:        call.StatusCode,
+			Outcome:           call.Outcome,
+			Error:             call.Error,
+			ProviderReference: call.ProviderReference,
+			LatencyMs:         call.LatencyMs,
+			CreatedAt:         call.CreatedAt.Format(time.RFC3339),
+		}
+		if call.RequestSummary != "" {
+			json.Unmarshal([]byte(call.RequestSummary), &response.Request)
+		}
+		items[i] = response
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+)
+
+// Chain transfer statuses
+const (
+	ChainSubmitted  = "submitted"  // Broadcast, not yet in a block
+	ChainConfirming = "confirming" // In a block, short of the required confirmations
+	ChainConfirmed  = "confirmed"
+	ChainFailed     = "failed" // Reverted
+)
+
+// recordChainSettlement stores the on-chain progress a crypto rail adapter
+// reported for an execution. The transfer is recorded when it is submitted
+// and its confirmations are updated on each status refresh.
+func recordChainSettlement(execution *database.PaymentExecution, result *adapters.Result) {
+	if result == nil || result.Chain == nil {
+		return
+	}
+	chain := result.Chain
+
+	transfer, err := repo.ChainTransferRepository().GetByExecutionID(execution.ID)
+	if err != nil {
+		if chain.TokenAmount <= 0 {
+			// Only the submission carries the amounts to record
+			return
+		}
+		transfer = &database.ChainTransfer{
+			ExecutionID:           execution.ID,
+			Network:               chain.Network,
+			Token:                 chain.Token,
+			FromAddress:           chain.FromAddress,
+			ToAddress:             chain.ToAddress,
+			TokenAmount:           chain.TokenAmount,
+			AmountUSD:             execution.AmountUSD,
+			RateUSD:               chain.RateUSD,
+			TxHash:                chain.TxHash,
+			RequiredConfirmations: chain.RequiredConfirmations,
+		}
+	}
+
+	transfer.BlockNumber = chain.BlockNumber
+	transfer.Confirmations = chain.Confirmations
+	switch {
+	case result.Status == adapters.StatusFailed:
+		transfer.Status = ChainFailed
+	case result.Status == adapters.StatusSettled:
+		if transfer.Status != ChainConfirmed {
+			now := time.Now()
+			transfer.ConfirmedAt = &now
+		}
+		transfer.Status = ChainConfirmed
+	case chain.BlockNumber != nil:
+		transfer.Status = ChainConfirming
+	default:
+		transfer.Status = ChainSubmitted
+	}
+
+	if transfer.ID == "" {
+		err = repo.ChainTransferRepository().Create(transfer)
+	} else {
+		err = repo.ChainTransferRepository().Update(transfer)
+	}
+	if err != nil {
+		common.Error("Failed to record chain transfer %s of payment %s: %v", chain.TxHash, execution.ID, err)
+	}
+}
+
+// chainSettlement returns the on-chain settlement of an execution on a
+// crypto rail, or nil for other rails
+func chainSettlement(execution *database.PaymentExecution) *types.ChainSettlement {
+	characteristics := railCharacteristics(execution.Rail)
+	if characteristics == nil || characteristics.CounterpartyType != types.CounterpartyWallet {
+		return nil
+	}
+	transfer, err := repo.ChainTransferRepository().GetByExecutionID(execution.ID)
+	if err != nil {
+		return nil
+	}
+
+	settlement := &types.ChainSettlement{
+		Network:               transfer.Network,
+		Token:                 transfer.Token,
+		FromAddress:           transfer.FromAddress,
+		ToAddress:             transfer.ToAddress,
+		TokenAmount:           transfer.TokenAmount,
+		RateUSD:               transfer.RateUSD,
+		TxHash:                transfer.TxHash,
+		Confirmations:         transfer.Confirmations,
+		RequiredConfirmations: transfer.RequiredConfirmations,
+		Status:                transfer.Status,
+	}
+	if transfer.BlockNumber != nil {
+		settlement.BlockNumber = *transfer.BlockNumber
+	}
+	if transfer.ConfirmedAt != nil {
+		settlement.ConfirmedAt = transfer.ConfirmedAt.Format(time.RFC3339)
+	}
+	return settlement
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// deadLetterWindow is the period recent dead-letter counts cover
+const deadLetterWindow = 24 * time.Hour
+
+type EventingStatus struct {
+	Status           string            `json:"status"`
+	CheckedAt        string            `json:"checkedAt"`
+	Bus              string            `json:"bus"` // EVENT_BUS backend; consumer lag is only read from kafka
+	Outbox           EventBusStatus    `json:"outbox"`
+	OutboxRetry      OutboxRetryStatus `json:"outboxRetry"`
+	ConsumerLag      []events.TopicLag `json:"consumerLag"`
+	ConsumerLagError string            `json:"consumerLagError,omitempty"`
+	DeadLetters      DeadLetterCounts  `json:"deadLetters"`
+}
+
+type OutboxRetryStatus struct {
+	MaxAttempts     int   `json:"maxAttempts"`
+	ExhaustedEvents int64 `json:"exhaustedEvents"` // Failed events with no attempts left
+}
+
+type FailedOutboxEvent struct {
+	ID            string `json:"id"`
+	EventType     string `json:"eventType"`
+	AggregateType string `json:"aggregateType"`
+	AggregateID   string `json:"aggregateId"`
+	RetryCount    int    `json:"retryCount"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+	NextAttemptAt string `json:"nextAttemptAt,omitempty"` // Empty once attempts are exhausted
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+type DeadLetterCounts struct {
+	Window  string           `json:"window"`
+	Total   int64            `json:"total"`
+	ByTopic map[string]int64 `json:"byTopic"`
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEventingStatus reports the health of the event pipeline: the outbox
+// backlog, each consumer group's lag per topic when the bus is Kafka, and
+// messages dead-lettered by consumers in the last day
+func getEventingStatus(c *gin.Context) {
+	now := time.Now()
+	status := &EventingStatus{
+		CheckedAt:   now.Format(time.RFC3339),
+		Bus:         common.GetEnv("EVENT_BUS", events.BusKafka),
+		Outbox:      eventBusStatus(now),
+		ConsumerLag: []events.TopicLag{},
+		DeadLetters: DeadLetterCounts{Window: "24h", ByTopic: map[string]int64{}},
+	}
+	status.Status = status.Outbox.Status
+
+	status.OutboxRetry.MaxAttempts = outboxRetrier.MaxAttempts
+	exhausted, err := repo.OutboxEventRepository().CountExhausted(outboxRetrier.MaxAttempts)
+	if err != nil {
+		common.Error("Failed to count exhausted outbox events: %v", err)
+	}
+	status.OutboxRetry.ExhaustedEvents = exhausted
+
+	if status.Bus == events.BusKafka {
+		readConsumerLag(c.Request.Context(), status)
+	}
+
+	counts, err := repo.DeadLetterEventRepository().CountByTopicSince(now.Add(-deadLetterWindow))
+	if err != nil {
+		common.Error("Failed to count dead-lettered events: %v", err)
+	} else {
+		status.DeadLetters.ByTopic = counts
+		for _, count := range counts {
+			status.DeadLetters.Total += count
+		}
+	}
+	if status.DeadLetters.Total > 0 {
+		status.Status = worstStatus(status.Status, StatusDegraded)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(status))
+}
+
+// readConsumerLag adds each Kafka consumer group's lag to the status
+func readConsumerLag(ctx context.Context, status *EventingStatus) {
+	brokers := splitList(common.GetEnv("KAFKA_BROKERS", "localhost:9092"))
+	topics := splitList(common.GetEnv("KAFKA_TOPIC", "agent-payments"))
+	groups := splitList(common.GetEnv("KAFKA_CONSUMER_GROUPS", "agent-payments"))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	lags, err := events.ConsumerLag(ctx, brokers, groups, topics)
+	if err != nil {
+		common.Error("Failed to read consumer lag: %v", err)
+		status.ConsumerLagError = err.Error()
+		status.Status = worstStatus(status.Status, StatusDegraded)
+		return
+	}
+	status.ConsumerLag = lags
+	for _, lag := range lags {
+		if lag.Error != "" {
+			status.Status = worstStatus(status.Status, StatusDegraded)
+		}
+	}
+}
+
+// listFailedOutboxEvents lists failed outbox events with when each is next
+// retried, least recently attempted first
+func listFailedOutboxEvents(c *gin.Context) {
+	failed, err := repo.OutboxEventRepository().ListFailed(100)
+	if err != nil {
+		common.Error("Failed to list failed outbox events: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list failed outbox events"))
+		return
+	}
+
+	items := make([]interface{}, len(failed))
+	for i, event := range failed {
+		item := &FailedOutboxEvent{
+			ID:            event.ID,
+			EventType:     event.EventType,
+			AggregateType: event.AggregateType,
+			AggregateID:   event.AggregateID,
+			RetryCount:    event.RetryCount,
+			ErrorMessage:  event.ErrorMessage,
+			CreatedAt:     event.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:     event.UpdatedAt.Format(time.RFC3339),
+		}
+		if next := outboxRetrier.NextAttempt(event); next != nil {
+			item.NextAttemptAt = next.Format(time.RFC3339)
+		}
+		items[i] = item
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+// requeueOutboxEvent returns a failed event to pending with its attempts
+// reset, including events that have exhausted automatic retries
+func requeueOutboxEvent(c *gin.Context) {
+	event, err := repo.OutboxEventRepository().GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Outbox event not found"))
+		return
+	}
+	if event.Status != "failed" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only failed events can be requeued"))
+		return
+	}
+
+	if err := outboxRetrier.Requeue(event); err != nil {
+		common.Error("Failed to requeue outbox event %s: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to requeue outbox event"))
+		return
+	}
+
+	actor := ""
+	if principal := common.GetPrincipal(c); principal != nil {
+		actor = principal.Subject
+	}
+	common.Info("Outbox event %s (%s) requeued by %s", event.ID, event.EventType, actor)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{"id": event.ID, "status": event.Status}))
+}
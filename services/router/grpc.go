@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/types"
+)
+
+// routerServer serves the router service's gRPC API through its REST
+// handlers
+type routerServer struct {
+	agentpayv1.UnimplementedRouterServiceServer
+	rest *rpc.REST
+}
+
+func (s *routerServer) Execute(ctx context.Context, req *agentpayv1.ExecutePaymentRequest) (*agentpayv1.PaymentExecution, error) {
+	var execution types.PaymentExecution
+	err := s.rest.Call(ctx, http.MethodPost, "/v1/payments/execute", &PaymentExecutionRequest{
+		AgentID:      req.GetAgentId(),
+		AmountUSD:    req.GetAmountUsd(),
+		Counterparty: req.GetCounterparty(),
+		Rail:         req.GetRail(),
+		Description:  req.GetDescription(),
+		Priority:     req.GetPriority(),
+
+		International:  rpc.InternationalFromProto(req.GetInternational()),
+		IdempotencyKey: req.GetIdempotencyKey(),
+	}, &execution)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.PaymentExecutionToProto(&execution), nil
+}
+
+func (s *routerServer) GetStatus(ctx context.Context, req *agentpayv1.GetPaymentStatusRequest) (*agentpayv1.PaymentExecution, error) {
+	var execution types.PaymentExecution
+	if err := s.rest.Call(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(req.GetId())+"/status", nil, &execution); err != nil {
+		return nil, err
+	}
+	return rpc.PaymentExecutionToProto(&execution), nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// railHealthWindow is how far back processor calls count towards rail health
+var railHealthWindow = time.Duration(common.GetEnvAsInt("RAIL_HEALTH_WINDOW_MINUTES", 15)) * time.Minute
+
+// railDegradedLatency is the mean processor latency above which a rail is degraded
+var railDegradedLatency = time.Duration(common.GetEnvAsInt("RAIL_DEGRADED_LATENCY_MS", 5000)) * time.Millisecond
+
+// heartbeatTimeout bounds each heartbeat check of a processor
+const heartbeatTimeout = 10 * time.Second
+
+type RailHealth struct {
+	Rail    string   `json:"rail"`
+	Status  string   `json:"status"`            // "operational", "degraded" or "outage"
+	Reasons []string `json:"reasons,omitempty"` // Why the rail is not operational
+
+	// Calls to the rail's processor in the health window
+	Calls        int      `json:"calls"`
+	Errors       int      `json:"errors"`
+	SuccessRate  *float64 `json:"successRate,omitempty"` // Share of calls that did not fail with an error
+	AvgLatencyMs *float64 `json:"avgLatencyMs,omitempty"`
+	MaxLatencyMs int64    `json:"maxLatencyMs,omitempty"`
+
+	Adapter    bool             `json:"adapter"` // Whether the router has an adapter for the rail
+	KillSwitch bool             `json:"killSwitch"`
+	Heartbeat  *HeartbeatStatus `json:"heartbeat,omitempty"`
+}
+
+type HeartbeatStatus struct {
+	Healthy             bool   `json:"healthy"`
+	CheckedAt           string `json:"checkedAt"`
+	Error               string `json:"error,omitempty"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+}
+
+// heartbeat is the outcome of the latest checks of a rail's processor
+type heartbeat struct {
+	checkedAt time.Time
+	err       error
+	failures  int
+}
+
+var (
+	heartbeatsMu sync.Mutex
+	heartbeats   = make(map[string]*heartbeat)
+)
+
+// runRailHeartbeats checks every adapter's processor at each interval until
+// the context ends
+func runRailHeartbeats(ctx context.Context, interval time.Duration) {
+	checkRailHeartbeats(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkRailHeartbeats(ctx)
+		}
+	}
+}
+
+func checkRailHeartbeats(ctx context.Context) {
+	for _, rail := range railAdapters.Rails() {
+		adapter, err := railAdapters.Get(rail)
+		if err != nil {
+			continue
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+		err = adapters.Ping(pingCtx, adapter)
+		cancel()
+
+		heartbeatsMu.Lock()
+		previous := heartbeats[rail]
+		current := &heartbeat{checkedAt: time.Now(), err: err}
+		if err != nil {
+			current.failures = 1
+			if previous != nil {
+				current.failures = previous.failures + 1
+			}
+			if previous == nil || previous.err == nil {
+				common.Warn("Heartbeat of the %s processor failed: %v", rail, err)
+			}
+		} else if previous != nil && previous.err != nil {
+			common.Info("Heartbeat of the %s processor recovered", rail)
+		}
+		heartbeats[rail] = current
+		heartbeatsMu.Unlock()
+	}
+}
+
+// railHealth assesses the rails from their processors' recent calls and
+// heartbeats and their kill switches. A rail is in outage when its kill
+// switch is engaged, it has no adapter or its heartbeat is failing, and
+// degraded when too many calls fail or they are too slow.
+func railHealth(rails []string, engaged map[string]*database.KillSwitchEvent) map[string]*RailHealth {
+	stats := make(map[string]*database.AdapterCallStats)
+	calls, err := repo.AdapterCallLogRepository().StatsSince(time.Now().Add(-railHealthWindow))
+	if err != nil {
+		common.Error("Failed to load adapter call stats for rail health: %v", err)
+	}
+	for _, call := range calls {
+		stats[call.Rail] = call
+	}
+
+	heartbeatsMu.Lock()
+	defer heartbeatsMu.Unlock()
+
+	health := make(map[string]*RailHealth, len(rails))
+	for _, rail := range rails {
+		h := &RailHealth{Rail: rail, Status: StatusOperational}
+		degrade := func(status, reason string) {
+			h.Status = worstStatus(h.Status, status)
+			h.Reasons = append(h.Reasons, reason)
+		}
+
+		if stat := stats[rail]; stat != nil {
+			h.Calls, h.Errors, h.MaxLatencyMs = stat.Calls, stat.Errors, stat.MaxLatencyMs
+			rate := roundTo(1-float64(stat.Errors)/float64(stat.Calls), 4)
+			latency := roundTo(stat.AvgLatencyMs, 2)
+			h.SuccessRate, h.AvgLatencyMs = &rate, &latency
+			if stat.Calls >= minSampleSize && rate < degradedSuccessRate {
+				degrade(StatusDegraded, "processor calls are failing")
+			}
+			if stat.Calls >= minSampleSize && time.Duration(stat.AvgLatencyMs)*time.Millisecond > railDegradedLatency {
+				degrade(StatusDegraded, "processor calls are slow")
+			}
+		}
+
+		if beat := heartbeats[rail]; beat != nil {
+			h.Heartbeat = &HeartbeatStatus{
+				Healthy:             beat.err == nil,
+				CheckedAt:           beat.checkedAt.Format(time.RFC3339),
+				ConsecutiveFailures: beat.failures,
+			}
+			if beat.err != nil {
+				h.Heartbeat.Error = beat.err.Error()
+				degrade(StatusOutage, "processor heartbeat is failing")
+			}
+		}
+
+		if _, err := railAdapters.Get(rail); err == nil {
+			h.Adapter = true
+		} else {
+			degrade(StatusOutage, "no adapter for the rail")
+		}
+		if _, ok := engaged[rail]; ok {
+			h.KillSwitch = true
+			degrade(StatusOutage, "kill switch engaged")
+		}
+
+		health[rail] = h
+	}
+	return health
+}
+
+// getRailHealth reports the health of every enabled rail, which routing
+// uses to avoid rails that are degraded or in outage
+func getRailHealth(c *gin.Context) {
+	options := allRails(0)
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = option.Rail
+	}
+	health := railHealth(names, engagedKillSwitches())
+
+	rails := make([]*RailHealth, len(names))
+	for i, name := range names {
+		rails[i] = health[name]
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(gin.H{
+		"window": railHealthWindow.String(),
+		"rails":  rails,
+	}))
+}
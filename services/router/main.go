@@ -1,18 +1,52 @@
 package main
 
 import (
+	"context"
+	_ "embed"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"sort"
 	"time"
 
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/agentstate"
+	"github.com/example/agent-payments/internal/audit"
+	"github.com/example/agent-payments/internal/auth"
+	"github.com/example/agent-payments/internal/config"
 	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/descriptions"
+	"github.com/example/agent-payments/internal/events"
+	"github.com/example/agent-payments/internal/funding"
+	"github.com/example/agent-payments/internal/international"
+	"github.com/example/agent-payments/internal/openapi"
+	"github.com/example/agent-payments/internal/rails"
+	"github.com/example/agent-payments/internal/rpc"
+	"github.com/example/agent-payments/internal/rpc/agentpayv1"
+	"github.com/example/agent-payments/internal/tenancy"
 	"github.com/example/agent-payments/internal/types"
 	"github.com/example/agent-payments/libs/common"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
+//go:embed openapi.json
+var openAPISpec []byte
+
 var repo database.Repository
+var authConfig *common.AuthConfig
+var railAdapters *adapters.Registry
+var railCatalog *rails.Catalog
+var outboxRetrier *events.OutboxRetrier
+
+// executionWorkers run payment and refund executions, which shutdown waits for
+var executionWorkers = common.NewWorkers()
+
+// requireVerifiedCounterparties rejects ACH executions to counterparties
+// without a verified bank account; ACH_COUNTERPARTY_VERIFICATION=off disables
+// it for local development
+var requireVerifiedCounterparties = common.GetEnv("ACH_COUNTERPARTY_VERIFICATION", "required") != "off"
 
 type PaymentExecutionRequest struct {
 	AgentID      string  `json:"agentId" binding:"required"`
@@ -21,6 +55,11 @@ type PaymentExecutionRequest struct {
 	Rail         string  `json:"rail,omitempty"` // Optional - if not provided, will be auto-selected
 	Description  string  `json:"description"`
 	Priority     string  `json:"priority,omitempty"` // "fast", "cheap", "reliable"
+
+	International *international.Details `json:"international,omitempty"` // Beneficiary's bank details, for payments abroad
+
+	// Repeating a key returns the key's execution instead of paying again
+	IdempotencyKey string `json:"idempotencyKey,omitempty" binding:"max=100"`
 }
 
 type RailOption struct {
@@ -30,6 +69,10 @@ type RailOption struct {
 	SpeedHours  int     `json:"speedHours"`
 	Reliability float64 `json:"reliability"` // 0.0 to 1.0
 	Available   bool    `json:"available"`
+	Health      string  `json:"health,omitempty"` // Rail health status, for rails offered for routing
+
+	CounterpartyType string `json:"counterpartyType"` // "account", or "wallet" for crypto rails
+	International    bool   `json:"international"`    // Sends payments abroad
 }
 
 type RoutingDecision struct {
@@ -41,9 +84,21 @@ type RoutingDecision struct {
 }
 
 func main() {
+	// Initialize tracing
+	shutdownTracing, err := common.InitTracing("router")
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Load and validate configuration
+	cfg, err := config.Load("router")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
 	// Initialize database
-	config := database.NewConfig()
-	db, err := database.Connect(config)
+	db, err := database.Connect(cfg.Database.Connection())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -56,13 +111,48 @@ func main() {
 	// Initialize repository
 	repo = database.NewRepository(db)
 
+	// Initialize authentication backed by stored API credentials
+	authConfig = common.NewAuthConfigFromEnv(auth.NewCredentialStore(repo))
+	cfg.Auth.Apply(authConfig)
+
+	// Seed the rail catalog, which the orchestrator selects and prices rails from too
+	if err := rails.Seed(repo); err != nil {
+		log.Fatalf("Failed to seed the rail catalog: %v", err)
+	}
+	railCatalog = rails.NewCatalog(repo, cfg.ApplyRails)
+
+	// Initialize rail adapters that execute payments with processors
+	railAdapters, err = adapters.NewRegistryFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize rail adapters: %v", err)
+	}
+	railAdapters.LogCalls(recordAdapterCall)
+	common.Info("Registered rail adapters: %v", railAdapters.Rails())
+
 	r := gin.Default()
+	server := common.NewServer(cfg.Addr(), r)
+
+	// Retry failed outbox events with backoff and alert on sustained failures
+	outboxRetrier = events.NewOutboxRetrierFromEnv(repo)
+	go outboxRetrier.Run(server.Context(), time.Duration(common.GetEnvAsInt("OUTBOX_RETRY_INTERVAL_SECONDS", 30))*time.Second)
+	if err := common.RegisterOutboxBacklog(repo.OutboxEventRepository().CountByStatus, "pending", "failed"); err != nil {
+		log.Fatalf("Failed to register outbox metrics: %v", err)
+	}
+
+	// Archive and remove published outbox events past the retention limits
+	go events.NewOutboxCleanerFromEnv(repo).Run(server.Context(), time.Duration(common.GetEnvAsInt("OUTBOX_CLEANUP_INTERVAL_SECONDS", 3600))*time.Second)
+	if err := common.RegisterOutboxTableSize(repo.OutboxEventRepository().TableSize); err != nil {
+		log.Fatalf("Failed to register outbox metrics: %v", err)
+	}
 
 	// Setup common middleware
 	common.SetupCommonMiddleware(r, func() error {
 		return repo.HealthCheck()
 	})
 
+	// Serve the OpenAPI document and validate request bodies against it
+	openapi.Register(r, openAPISpec)
+
 	// Health check endpoint
 	r.GET("/healthz", func(c *gin.Context) {
 		if err := repo.HealthCheck(); err != nil {
@@ -72,21 +162,74 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "router service ok"})
 	})
 
+	// Public status page data
+	r.GET("/v1/status", getStatus)
+
+	// Processor callbacks, authenticated by each adapter's webhook signature
+	r.POST("/v1/webhooks/rails/:rail", handleRailWebhook)
+
+	// Configuration in effect, secrets redacted
+	r.GET("/v1/config", common.AuthMiddleware(authConfig), common.RequireScopes(common.ScopeOperations), config.Handler(cfg))
+
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", common.AuthMiddleware(authConfig), audit.Middleware(audit.NewAuditTrail(repo)))
 	{
 		// Payment routing and execution
-		v1.POST("/payments/execute", executePayment)
-		v1.GET("/payments/:id/status", getPaymentStatus)
-		v1.POST("/routing/quote", getRoutingQuote)
-		v1.GET("/rails", listAvailableRails)
+		v1.POST("/payments/execute", common.RequireScopes(common.ScopeRoutingExecute), executePayment)
+		v1.GET("/payments/:id/status", common.RequireScopes(common.ScopePaymentsRead), getPaymentStatus)
+		v1.POST("/payments/:id/reverse", common.RequireScopes(common.ScopePaymentsWrite), reversePayment)
+		v1.POST("/payments/:id/void", common.RequireScopes(common.ScopeRoutingExecute), voidPayment)
+		v1.POST("/payments/:id/refunds", common.RequireScopes(common.ScopePaymentsWrite), createRefund)
+		v1.GET("/payments/:id/refunds", common.RequireScopes(common.ScopePaymentsRead), listRefunds)
+		v1.GET("/payments/:id/adapter-calls", common.RequireScopes(common.ScopeOperations), listAdapterCalls)
+		v1.GET("/refunds/:id", common.RequireScopes(common.ScopePaymentsRead), getRefund)
+		v1.POST("/routing/quote", common.RequireScopes(common.ScopeRoutingExecute), getRoutingQuote)
+		v1.GET("/rails", common.RequireScopes(common.ScopePaymentsRead), listAvailableRails)
+		v1.GET("/rails/health", common.RequireScopes(common.ScopePaymentsRead), getRailHealth)
+
+		// Batches of executions on batched rails
+		v1.GET("/payment-batches", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), listPaymentBatches)
+		v1.GET("/payment-batches/:id", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), getPaymentBatch)
+		v1.POST("/payment-batches/:id/close", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), closePaymentBatch)
+		v1.POST("/payment-batches/:id/submit", common.RequireScopes(common.ScopeOperations), common.RequirePlatform(), submitPaymentBatch)
+
+		// Kill switches
+		v1.GET("/kill-switches", common.RequireScopes(common.ScopeOperations), listKillSwitches)
+		v1.PUT("/kill-switches/:component", common.RequireScopes(common.ScopeOperations), toggleKillSwitch)
+
+		// Event pipeline health
+		v1.GET("/admin/eventing/status", common.RequireScopes(common.ScopeOperations), getEventingStatus)
+		v1.GET("/admin/outbox/failed", common.RequireScopes(common.ScopeOperations), listFailedOutboxEvents)
+		v1.POST("/admin/outbox/:id/requeue", common.RequireScopes(common.ScopeOperations), requeueOutboxEvent)
+	}
+
+	// Check each rail processor's heartbeat for rail health
+	go runRailHeartbeats(server.Context(), time.Duration(common.GetEnvAsInt("RAIL_HEARTBEAT_INTERVAL_SECONDS", 30))*time.Second)
+
+	// Close and submit ACH batches at their cutoffs, when batching is enabled
+	if err := initACHBatching(server.Context()); err != nil {
+		log.Fatalf("Failed to initialize ACH batching: %v", err)
+	}
+
+	// Let payment and refund executions finish before exiting
+	server.OnShutdown("executions", executionWorkers.Drain)
+
+	// The calls payment processing makes, over gRPC on GRPC_PORT
+	if err := rpc.Serve(server, func(s *grpc.Server) {
+		agentpayv1.RegisterRouterServiceServer(s, &routerServer{rest: rpc.NewREST(r)})
+	}); err != nil {
+		log.Fatal(err)
 	}
 
-	common.Info("Router service running on :8085")
-	log.Fatal(r.Run(":8085"))
+	common.Info("Router service running on %s", cfg.Addr())
+	if err := server.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func executePayment(c *gin.Context) {
+	repo := repo.WithContext(c.Request.Context()) // Traced under the request's span
+
 	var req PaymentExecutionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
@@ -98,12 +241,31 @@ func executePayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "agentId, amountUSD, and counterparty are required"))
 		return
 	}
+	if req.International != nil {
+		req.International.Normalize()
+		if err := international.Validate(req.International); err != nil {
+			c.JSON(http.StatusBadRequest, common.NewErrorResponse("INVALID_BENEFICIARY", err.Error()))
+			return
+		}
+	}
+
+	// A repeated request, such as one sent over REST after its gRPC call was
+	// cut off, is answered with the execution its key started
+	if repeatedExecution(c, repo, req) {
+		return
+	}
 
 	// Verify agent exists
-	if _, err := repo.AgentRepository().GetByID(req.AgentID); err != nil {
+	agent, err := repo.AgentRepository().GetByID(req.AgentID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Agent not found"))
 		return
 	}
+	if refusal := agentstate.Refusal(agent); refusal != "" {
+		common.Warn("Rejected execution for agent %s: %s", req.AgentID, refusal)
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("AGENT_NOT_ACTIVE", refusal))
+		return
+	}
 
 	// Determine payment rail if not specified
 	selectedRail := req.Rail
@@ -112,25 +274,70 @@ func executePayment(c *gin.Context) {
 		selectedRail = routingDecision.SelectedRail
 	}
 
-	// Create payment execution record
+	// Wallet addresses are only paid on crypto rails, and crypto rails only
+	// pay wallet addresses
+	if characteristics := railCharacteristics(selectedRail); characteristics != nil && !characteristics.Pays(req.Counterparty) {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Rail %s cannot pay a counterparty of type %s", selectedRail, types.CounterpartyTypeOf(req.Counterparty))))
+		return
+	}
+
+	// Payments abroad only go on rails that send international payments
+	if characteristics := railCharacteristics(selectedRail); characteristics != nil && international.IsInternational(req.International) && !characteristics.InternationalSupport {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Rail %s does not send international payments", selectedRail)))
+		return
+	}
+
+	// ACH payments are only sent to bank accounts the payer has verified
+	if selectedRail == "ach" && requireVerifiedCounterparties {
+		if _, err := funding.CheckCounterpartyAccount(repo, agent.OwnerPartyID, req.Counterparty); err != nil {
+			common.Warn("Rejected ACH execution for agent %s: %s has no verified bank account", req.AgentID, req.Counterparty)
+			c.JSON(http.StatusUnprocessableEntity, common.NewErrorResponse("COUNTERPARTY_NOT_VERIFIED", err.Error()))
+			return
+		}
+	}
+
+	// Create payment execution record. The description is limited to what
+	// the rail carries, so the execution records what the processor receives.
 	paymentExecution := &database.PaymentExecution{
-		AgentID:      req.AgentID,
-		AmountUSD:    req.AmountUSD,
-		Counterparty: req.Counterparty,
-		Rail:         selectedRail,
-		Description:  req.Description,
-		Status:       "pending",
-		Priority:     req.Priority,
+		AgentID:       req.AgentID,
+		AmountUSD:     req.AmountUSD,
+		Counterparty:  req.Counterparty,
+		Rail:          selectedRail,
+		Description:   descriptions.ForRail(req.Description, railCharacteristics(selectedRail)),
+		Status:        "pending",
+		Priority:      req.Priority,
+		International: international.Encode(req.International),
+	}
+	if req.IdempotencyKey != "" {
+		paymentExecution.IdempotencyKey = &req.IdempotencyKey
 	}
 
 	if err := repo.PaymentExecutionRepository().Create(paymentExecution); err != nil {
+		// A concurrent request with the same key created it first
+		if repeatedExecution(c, repo, req) {
+			return
+		}
 		common.Error("Failed to create payment execution: %v", err)
 		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create payment execution"))
 		return
 	}
 
-	// Execute payment asynchronously
-	go executePaymentAsync(paymentExecution)
+	// Batched rails wait for their batch to be submitted; others execute now.
+	// Rails settling on acceptance execute before responding, so the caller
+	// sees the payment's final status and can book it at once.
+	var batch *database.PaymentBatch
+	if windows := batchingFor(selectedRail); windows != nil {
+		if batch, err = addToBatch(paymentExecution, windows); err != nil {
+			common.Error("Failed to batch payment execution %s: %v", paymentExecution.ID, err)
+			failExecution(paymentExecution, "could not be added to a batch")
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to batch payment execution"))
+			return
+		}
+	} else if adapter, err := railAdapters.Get(selectedRail); err == nil && adapters.SettlesOnAcceptance(adapter) {
+		executePaymentAsync(paymentExecution)
+	} else if !executionWorkers.Go(paymentExecution.ID, func(context.Context) { executePaymentAsync(paymentExecution) }) {
+		common.Warn("Shutting down, payment execution %s left pending", paymentExecution.ID)
+	}
 
 	// Convert to API response format
 	response := &types.PaymentExecution{
@@ -145,13 +352,15 @@ func executePayment(c *gin.Context) {
 		CreatedAt:    paymentExecution.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    paymentExecution.UpdatedAt.Format(time.RFC3339),
 	}
+	setBatch(response, batch)
+	response.ChainSettlement = chainSettlement(paymentExecution)
 
 	common.Info("Payment execution initiated: %s for agent %s via %s", paymentExecution.ID, req.AgentID, selectedRail)
 	c.JSON(http.StatusCreated, common.NewSuccessResponse(response))
 }
 
 func selectOptimalRail(req PaymentExecutionRequest) RoutingDecision {
-	availableRails := getAvailableRails(req.AmountUSD)
+	availableRails := payableRails(getAvailableRails(req.AmountUSD), req.Counterparty, req.International)
 
 	if len(availableRails) == 0 {
 		return RoutingDecision{
@@ -162,19 +371,7 @@ func selectOptimalRail(req PaymentExecutionRequest) RoutingDecision {
 	}
 
 	// Select optimal rail based on priority
-	var selectedRail RailOption
-	var reason string
-
-	switch req.Priority {
-	case "fast":
-		selectedRail, reason = selectFastestRail(availableRails)
-	case "cheap":
-		selectedRail, reason = selectCheapestRail(availableRails)
-	case "reliable":
-		selectedRail, reason = selectMostReliableRail(availableRails)
-	default:
-		selectedRail, reason = selectBalancedRail(availableRails)
-	}
+	selectedRail, reason := pickRail(availableRails, req.Priority)
 
 	return RoutingDecision{
 		SelectedRail:  selectedRail.Rail,
@@ -185,51 +382,99 @@ func selectOptimalRail(req PaymentExecutionRequest) RoutingDecision {
 	}
 }
 
-func getAvailableRails(amount float64) []RailOption {
-	rails := []RailOption{
-		{
-			Rail:        "ach",
-			Name:        "ACH Transfer",
-			CostUSD:     0.50,
-			SpeedHours:  24,
-			Reliability: 0.95,
-			Available:   true,
-		},
-		{
-			Rail:        "wire",
-			Name:        "Wire Transfer",
-			CostUSD:     25.00,
-			SpeedHours:  2,
-			Reliability: 0.99,
-			Available:   true,
-		},
-		{
-			Rail:        "card",
-			Name:        "Card Payment",
-			CostUSD:     amount * 0.029, // 2.9% fee
-			SpeedHours:  0,
-			Reliability: 0.90,
-			Available:   amount <= 5000, // Cards limited to $5k
-		},
-		{
-			Rail:        "instant",
-			Name:        "Instant Payment",
-			CostUSD:     amount * 0.015, // 1.5% fee
-			SpeedHours:  0,
-			Reliability: 0.85,
-			Available:   amount <= 1000, // Instant limited to $1k
-		},
-	}
-
-	// Filter available rails
-	var available []RailOption
+// allRails returns every enabled rail of the rail catalog with its cost and
+// availability for the amount
+func allRails(amount float64) []RailOption {
+	catalog := railCatalog.Selector().GetAvailableRails()
+	options := make([]RailOption, 0, len(catalog))
+	for rail, characteristics := range catalog {
+		option := RailOption{
+			Rail:             string(rail),
+			Name:             characteristics.Name,
+			CostUSD:          characteristics.FeeStructure.Calculate(amount),
+			SpeedHours:       int(characteristics.ProcessingTime.Hours()),
+			Reliability:      characteristics.Reliability,
+			Available:        amount >= characteristics.MinAmount && amount <= characteristics.MaxAmount,
+			CounterpartyType: characteristics.CounterpartyType,
+			International:    characteristics.InternationalSupport,
+		}
+		if option.CounterpartyType == "" {
+			option.CounterpartyType = types.CounterpartyAccount
+		}
+		options = append(options, option)
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Rail < options[j].Rail })
+	return options
+}
+
+// payableRails keeps the rails that can pay a counterparty: wallet rails
+// pay wallet addresses and other rails everything else. Beneficiaries abroad
+// are only paid on rails that send international payments.
+func payableRails(rails []RailOption, counterparty string, details *international.Details) []RailOption {
+	payable := make([]RailOption, 0, len(rails))
+	counterpartyType := types.CounterpartyTypeOf(counterparty)
+	abroad := international.IsInternational(details)
 	for _, rail := range rails {
-		if rail.Available {
-			available = append(available, rail)
+		if rail.CounterpartyType == counterpartyType && (!abroad || rail.International) {
+			payable = append(payable, rail)
 		}
 	}
+	return payable
+}
 
-	return available
+// railCharacteristics returns a rail of the catalog, or nil if it is unknown
+func railCharacteristics(rail string) *types.RailCharacteristics {
+	characteristics, err := railCatalog.Get(rail)
+	if err != nil {
+		return nil
+	}
+	return characteristics
+}
+
+// pickRail selects one of the rails for a priority: "fast", "cheap",
+// "reliable" or, by default, balanced
+func pickRail(rails []RailOption, priority string) (RailOption, string) {
+	switch priority {
+	case "fast":
+		return selectFastestRail(rails)
+	case "cheap":
+		return selectCheapestRail(rails)
+	case "reliable":
+		return selectMostReliableRail(rails)
+	default:
+		return selectBalancedRail(rails)
+	}
+}
+
+// getAvailableRails returns the rails that can carry the amount and are
+// healthy. Rails in outage, including those whose kill switch is engaged,
+// are never offered; degraded rails only when no rail is operational.
+func getAvailableRails(amount float64) []RailOption {
+	options := allRails(amount)
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = option.Rail
+	}
+	health := railHealth(names, engagedKillSwitches())
+
+	var operational, degraded []RailOption
+	for _, rail := range options {
+		if !rail.Available {
+			continue
+		}
+		rail.Health = health[rail.Rail].Status
+		switch rail.Health {
+		case StatusOperational:
+			operational = append(operational, rail)
+		case StatusDegraded:
+			degraded = append(degraded, rail)
+		}
+	}
+
+	if len(operational) == 0 {
+		return degraded
+	}
+	return operational
 }
 
 func selectFastestRail(rails []RailOption) (RailOption, string) {
@@ -291,58 +536,55 @@ func selectBalancedRail(rails []RailOption) (RailOption, string) {
 	return best.rail, fmt.Sprintf("Selected %s for balanced cost/speed/reliability", best.rail.Name)
 }
 
-func executePaymentAsync(execution *database.PaymentExecution) {
-	common.Info("Executing payment %s via %s", execution.ID, execution.Rail)
-
-	// Update status to processing
-	execution.Status = "processing"
-	if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
-		common.Error("Failed to update payment execution status: %v", err)
+func getPaymentStatus(c *gin.Context) {
+	id := c.Param("id")
+	execution, err := repo.PaymentExecutionRepository().GetByID(id)
+	if err != nil {
+		log.Printf("Failed to get payment execution: %v", err)
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
 		return
 	}
-
-	// Simulate payment processing based on rail
-	var processingTime time.Duration
-	switch execution.Rail {
-	case "instant":
-		processingTime = 100 * time.Millisecond
-	case "card":
-		processingTime = 200 * time.Millisecond
-	case "wire":
-		processingTime = 500 * time.Millisecond
-	case "ach":
-		processingTime = 1 * time.Second
-	default:
-		processingTime = 500 * time.Millisecond
+	if !common.CanActForAgent(c, execution.AgentID) || !executionInTenant(c, execution) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
 	}
 
-	time.Sleep(processingTime)
-
-	// Simulate success/failure (90% success rate)
-	if time.Now().Unix()%10 != 0 { // 90% success rate
-		execution.Status = "completed"
-		common.Info("Payment %s completed successfully", execution.ID)
-	} else {
-		execution.Status = "failed"
-		common.Error("Payment %s failed", execution.ID)
+	// Payments awaiting settlement are refreshed from the processor
+	if execution.Status == "processing" && execution.ReferenceID != "" {
+		refreshExecutionStatus(c.Request.Context(), execution)
 	}
 
-	execution.UpdatedAt = time.Now()
-	if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
-		common.Error("Failed to update payment execution final status: %v", err)
-	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toExecutionResponse(execution)))
 }
 
-func getPaymentStatus(c *gin.Context) {
-	id := c.Param("id")
-	execution, err := repo.PaymentExecutionRepository().GetByID(id)
+// executionInTenant reports whether an execution belongs to the caller's
+// tenant. Attempts to reach another tenant's payments are audited.
+func executionInTenant(c *gin.Context, execution *database.PaymentExecution) bool {
+	return tenancy.CheckAgent(c, repo, execution.AgentID, "payment_execution", execution.ID)
+}
+
+// repeatedExecution answers a request whose idempotency key already started
+// an execution with that execution, reporting whether it did. A key reused
+// for a different payment is refused.
+func repeatedExecution(c *gin.Context, repo database.Repository, req PaymentExecutionRequest) bool {
+	if req.IdempotencyKey == "" {
+		return false
+	}
+	execution, err := repo.PaymentExecutionRepository().GetByIdempotencyKey(req.IdempotencyKey)
 	if err != nil {
-		log.Printf("Failed to get payment execution: %v", err)
-		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
-		return
+		return false
+	}
+	if execution.AgentID != req.AgentID || execution.Counterparty != req.Counterparty || math.Round(execution.AmountUSD*100) != math.Round(req.AmountUSD*100) {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("IDEMPOTENCY_KEY_REUSED", "The idempotency key was used for a different payment"))
+		return true
 	}
+	common.Info("Payment execution %s repeated under its idempotency key", execution.ID)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toExecutionResponse(execution)))
+	return true
+}
 
-	// Convert to API response format
+// toExecutionResponse converts a stored execution to its API response
+func toExecutionResponse(execution *database.PaymentExecution) *types.PaymentExecution {
 	response := &types.PaymentExecution{
 		ID:           execution.ID,
 		AgentID:      execution.AgentID,
@@ -355,8 +597,29 @@ func getPaymentStatus(c *gin.Context) {
 		CreatedAt:    execution.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    execution.UpdatedAt.Format(time.RFC3339),
 	}
+	if execution.BatchID != nil {
+		if batch, err := repo.PaymentBatchRepository().GetByID(*execution.BatchID); err == nil {
+			setBatch(response, batch)
+		}
+	}
+	response.FailedOverFrom = execution.FailedOverFrom
+	response.ChainSettlement = chainSettlement(execution)
+	if execution.ReversedAt != nil {
+		response.ReversedAt = execution.ReversedAt.Format(time.RFC3339)
+		response.ReversalReason = execution.ReversalReason
+		response.ReversalTransactionID = execution.ReversalTransactionID
+	}
+	return response
+}
 
-	c.JSON(http.StatusOK, common.NewSuccessResponse(response))
+// setBatch adds the batch a batched execution waits in to its response
+func setBatch(response *types.PaymentExecution, batch *database.PaymentBatch) {
+	if batch == nil {
+		return
+	}
+	response.BatchID = batch.ID
+	response.BatchReference = batch.Reference
+	response.BatchCutoffAt = batch.CutoffAt.Format(time.RFC3339)
 }
 
 func getRoutingQuote(c *gin.Context) {
@@ -370,6 +633,9 @@ func getRoutingQuote(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "amountUSD must be greater than 0"))
 		return
 	}
+	if req.International != nil {
+		req.International.Normalize()
+	}
 
 	decision := selectOptimalRail(req)
 	c.JSON(http.StatusOK, common.NewSuccessResponse(decision))
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/database/dbtest"
+	"github.com/example/agent-payments/internal/rails"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRepeatedExecutionIsNotPaidAgain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testRepo, _ := dbtest.Open(t)
+	repo, railCatalog = testRepo, rails.NewCatalog(testRepo, nil)
+
+	party := &database.Party{Name: "Payer", Type: "organization"}
+	if err := repo.PartyRepository().Create(party); err != nil {
+		t.Fatal(err)
+	}
+	agent := &database.Agent{DisplayName: "Agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+	if err := repo.AgentRepository().Create(agent); err != nil {
+		t.Fatal(err)
+	}
+	key := "workflow-1:attempt-1"
+	executed := &database.PaymentExecution{AgentID: agent.ID, AmountUSD: 125.5, Counterparty: "vendor@example.com", Rail: "ach", Status: "processing", IdempotencyKey: &key}
+	if err := repo.PaymentExecutionRepository().Create(executed); err != nil {
+		t.Fatal(err)
+	}
+
+	answer := func(req PaymentExecutionRequest) (bool, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		return repeatedExecution(c, repo, req), recorder
+	}
+
+	// The same payment under the same key gets the execution already started
+	repeated, recorder := answer(PaymentExecutionRequest{AgentID: agent.ID, AmountUSD: 125.50, Counterparty: "vendor@example.com", IdempotencyKey: key})
+	if !repeated || recorder.Code != http.StatusOK {
+		t.Fatalf("repeat: answered %v with %d", repeated, recorder.Code)
+	}
+	var response struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Data.ID != executed.ID {
+		t.Fatalf("repeat: got %s, want execution %s (%v)", recorder.Body, executed.ID, err)
+	}
+
+	// A different payment may not reuse the key
+	repeated, recorder = answer(PaymentExecutionRequest{AgentID: agent.ID, AmountUSD: 999, Counterparty: "vendor@example.com", IdempotencyKey: key})
+	if !repeated || recorder.Code != http.StatusConflict {
+		t.Fatalf("reused key: answered %v with %d", repeated, recorder.Code)
+	}
+
+	// New keys, and requests without one, are executed
+	for _, other := range []string{"workflow-1:attempt-2", ""} {
+		if repeated, _ := answer(PaymentExecutionRequest{AgentID: agent.ID, AmountUSD: 125.5, Counterparty: "vendor@example.com", IdempotencyKey: other}); repeated {
+			t.Fatalf("key %q treated as a repeat", other)
+		}
+	}
+
+	// The key is unique, so concurrent requests cannot both create an execution
+	duplicate := &database.PaymentExecution{AgentID: agent.ID, AmountUSD: 125.5, Counterparty: "vendor@example.com", Rail: "ach", Status: "pending", IdempotencyKey: &key}
+	if err := repo.PaymentExecutionRepository().Create(duplicate); err == nil {
+		t.Fatal("a second execution was created under the same key")
+	}
+}
+
+func TestOtherTenantsExecutionsAreNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testRepo, _ := dbtest.Open(t)
+	repo, railCatalog = testRepo, rails.NewCatalog(testRepo, nil)
+
+	// Two tenants, each an organization with an agent that paid a vendor
+	agents := map[string]*database.Agent{}
+	executions := map[string]*database.PaymentExecution{}
+	for _, name := range []string{"Acme", "Globex"} {
+		party := &database.Party{Name: name, Type: "organization"}
+		if err := repo.PartyRepository().Create(party); err != nil {
+			t.Fatal(err)
+		}
+		agent := &database.Agent{DisplayName: name + " agent", OwnerPartyID: party.ID, IdentityMode: "did"}
+		if err := repo.AgentRepository().Create(agent); err != nil {
+			t.Fatal(err)
+		}
+		execution := &database.PaymentExecution{AgentID: agent.ID, AmountUSD: 20, Counterparty: "vendor@example.com", Rail: "ach", Status: "completed"}
+		if err := repo.PaymentExecutionRepository().Create(execution); err != nil {
+			t.Fatal(err)
+		}
+		agents[name], executions[name] = agent, execution
+	}
+
+	acme := &common.Principal{Type: common.PrincipalParty, PartyID: agents["Acme"].OwnerPartyID, TenantID: agents["Acme"].OwnerPartyID}
+	get := func(path string) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("principal", acme) })
+		router.GET("/v1/payments/:id", getPaymentStatus)
+		router.GET("/v1/payment-batches/:id", common.RequirePlatform(), getPaymentBatch)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if code := get("/v1/payments/" + executions["Acme"].ID); code != http.StatusOK {
+		t.Fatalf("own execution: got %d", code)
+	}
+	if code := get("/v1/payments/" + executions["Globex"].ID); code != http.StatusNotFound {
+		t.Fatalf("other tenant's execution: got %d, want 404", code)
+	}
+
+	// Batches carry every tenant's payments and are the platform's alone
+	if code := get("/v1/payment-batches/any"); code != http.StatusForbidden {
+		t.Fatalf("payment batch as a tenant: got %d, want 403", code)
+	}
+}
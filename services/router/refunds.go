@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rounding"
+	"github.com/example/agent-payments/internal/tenancy"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateRefundRequest struct {
+	AmountUSD float64 `json:"amountUSD,omitempty"` // Defaults to the amount not yet refunded
+	Reason    string  `json:"reason,omitempty"`
+}
+
+type RefundResponse struct {
+	ID                  string  `json:"id"`
+	ExecutionID         string  `json:"executionId"`
+	AgentID             string  `json:"agentId"`
+	AmountUSD           float64 `json:"amountUSD"`
+	Reason              string  `json:"reason,omitempty"`
+	Status              string  `json:"status"`
+	ReferenceID         string  `json:"referenceId,omitempty"`
+	ErrorMessage        string  `json:"errorMessage,omitempty"`
+	LedgerTransactionID string  `json:"ledgerTransactionId,omitempty"`
+	CompletedAt         string  `json:"completedAt,omitempty"`
+	CreatedAt           string  `json:"createdAt"`
+	UpdatedAt           string  `json:"updatedAt"`
+}
+
+func toRefundResponse(refund *database.Refund) *RefundResponse {
+	response := &RefundResponse{
+		ID:                  refund.ID,
+		ExecutionID:         refund.ExecutionID,
+		AgentID:             refund.AgentID,
+		AmountUSD:           refund.AmountUSD,
+		Reason:              refund.Reason,
+		Status:              refund.Status,
+		ReferenceID:         refund.ReferenceID,
+		ErrorMessage:        refund.ErrorMessage,
+		LedgerTransactionID: refund.LedgerTransactionID,
+		CreatedAt:           refund.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           refund.UpdatedAt.Format(time.RFC3339),
+	}
+	if refund.CompletedAt != nil {
+		response.CompletedAt = refund.CompletedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// refundedAmount totals the refunds of an execution that have not failed
+func refundedAmount(executionID string) (float64, error) {
+	refunds, err := repo.RefundRepository().ListByExecutionID(executionID)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, refund := range refunds {
+		if refund.Status != "failed" {
+			total += refund.AmountUSD
+		}
+	}
+	return rounding.Round(total), nil
+}
+
+// createRefund returns all or part of a completed payment. Refunds together
+// may not exceed the captured amount. The refund is sent to the processor
+// asynchronously, like the payment itself.
+func createRefund(c *gin.Context) {
+	var req CreateRefundRequest
+	c.ShouldBindJSON(&req)
+
+	execution, err := repo.PaymentExecutionRepository().GetByID(c.Param("id"))
+	if err != nil || !executionInTenant(c, execution) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
+	}
+
+	if !common.CanActForAgent(c, execution.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot refund this payment"))
+		return
+	}
+
+	if execution.Status != "completed" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only completed payments can be refunded"))
+		return
+	}
+
+	refunded, err := refundedAmount(execution.ID)
+	if err != nil {
+		common.Error("Failed to load refunds of payment %s: %v", execution.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load refunds"))
+		return
+	}
+	refundable := rounding.Round(execution.AmountUSD - refunded)
+
+	amount := rounding.Round(req.AmountUSD)
+	if req.AmountUSD == 0 {
+		amount = refundable
+	}
+	if amount <= 0 || refundable <= 0 {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("ALREADY_REFUNDED", "Payment has been fully refunded"))
+		return
+	}
+	if amount > refundable {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", fmt.Sprintf("Refund exceeds the refundable amount of %.2f USD", refundable)))
+		return
+	}
+
+	refund := &database.Refund{
+		ExecutionID: execution.ID,
+		AgentID:     execution.AgentID,
+		AmountUSD:   amount,
+		Reason:      req.Reason,
+		Status:      "pending",
+	}
+	if err := repo.RefundRepository().Create(refund); err != nil {
+		common.Error("Failed to create refund for payment %s: %v", execution.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to create refund"))
+		return
+	}
+
+	if !executionWorkers.Go(refund.ID, func(context.Context) { executeRefundAsync(refund, execution) }) {
+		common.Warn("Shutting down, refund %s left pending", refund.ID)
+	}
+
+	common.Info("Refund %s of %.2f USD initiated for payment %s", refund.ID, amount, execution.ID)
+	c.JSON(http.StatusCreated, common.NewSuccessResponse(toRefundResponse(refund)))
+}
+
+// executeRefundAsync sends the refund through the rail's adapter and books
+// the proportional offset of the payment's ledger entries
+func executeRefundAsync(refund *database.Refund, execution *database.PaymentExecution) {
+	refund.Status = "processing"
+	if err := repo.RefundRepository().Update(refund); err != nil {
+		common.Error("Failed to update refund status: %v", err)
+		return
+	}
+
+	adapter, err := railAdapters.Get(execution.Rail)
+	if err != nil {
+		failRefund(refund, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adapters.WithExecution(context.Background(), execution.ID), adapterTimeout)
+	defer cancel()
+
+	result, err := adapter.Refund(ctx, execution.ReferenceID, refund.AmountUSD)
+	if err != nil {
+		failRefund(refund, "refund failed: "+err.Error())
+		return
+	}
+	if result.Status == adapters.StatusFailed {
+		failRefund(refund, result.Message)
+		return
+	}
+	refund.ReferenceID = result.RefundID
+
+	transactionID, err := postCompensatingTransaction(execution, refund.ID, "Refund of payment "+execution.ID, refund.AmountUSD/execution.AmountUSD)
+	if err != nil {
+		common.Error("Refund %s sent but ledger postings failed: %v", refund.ID, err)
+	}
+	refund.LedgerTransactionID = transactionID
+
+	now := time.Now()
+	refund.Status = "completed"
+	refund.CompletedAt = &now
+	if err := repo.RefundRepository().Update(refund); err != nil {
+		common.Error("Failed to update refund final status: %v", err)
+	}
+	common.Info("Refund %s completed for payment %s", refund.ID, execution.ID)
+
+	// A payment refunded in full is marked as such, as processor refund webhooks do
+	if refunded, err := refundedAmount(execution.ID); err == nil && refunded >= execution.AmountUSD-0.005 {
+		applyAdapterStatus(execution, adapters.StatusRefunded, "")
+	}
+}
+
+func failRefund(refund *database.Refund, message string) {
+	refund.Status = "failed"
+	refund.ErrorMessage = message
+	if err := repo.RefundRepository().Update(refund); err != nil {
+		common.Error("Failed to update refund final status: %v", err)
+	}
+	common.Error("Refund %s failed: %s", refund.ID, message)
+}
+
+func listRefunds(c *gin.Context) {
+	execution, err := repo.PaymentExecutionRepository().GetByID(c.Param("id"))
+	if err != nil || !executionInTenant(c, execution) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
+	}
+	if !common.CanActForAgent(c, execution.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot view refunds of this payment"))
+		return
+	}
+
+	refunds, err := repo.RefundRepository().ListByExecutionID(execution.ID)
+	if err != nil {
+		common.Error("Failed to list refunds: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list refunds"))
+		return
+	}
+
+	items := make([]interface{}, len(refunds))
+	for i, refund := range refunds {
+		items[i] = toRefundResponse(refund)
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(common.NewListResponse(items, 1, len(items), len(items))))
+}
+
+func getRefund(c *gin.Context) {
+	refund, err := repo.RefundRepository().GetByID(c.Param("id"))
+	if err != nil || !common.CanActForAgent(c, refund.AgentID) || !tenancy.CheckAgent(c, repo, refund.AgentID, "refund", refund.ID) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Refund not found"))
+		return
+	}
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toRefundResponse(refund)))
+}
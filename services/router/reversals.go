@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/adapters"
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/internal/rounding"
+	"github.com/example/agent-payments/internal/types"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// reversalReferenceSuffix marks the compensating ledger transaction of a
+// reversed execution, referenced by the execution ID plus this suffix
+const reversalReferenceSuffix = ":reversal"
+
+type ReversePaymentRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// reversePayment undoes a completed payment. Funds are returned through the
+// rail's adapter, so only rails marked reversible in the rail catalog are
+// accepted, and any ledger entries booked against the payment are offset by a
+// compensating transaction.
+func reversePayment(c *gin.Context) {
+	var req ReversePaymentRequest
+	c.ShouldBindJSON(&req)
+
+	execution, err := repo.PaymentExecutionRepository().GetByID(c.Param("id"))
+	if err != nil || !executionInTenant(c, execution) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
+	}
+
+	if !common.CanActForAgent(c, execution.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot reverse this payment"))
+		return
+	}
+
+	if execution.Status != "completed" || execution.ErrorMessage == "refunded" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only completed payments that have not been refunded can be reversed"))
+		return
+	}
+	if refunded, err := refundedAmount(execution.ID); err != nil || refunded > 0 {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Payments with refunds cannot be reversed"))
+		return
+	}
+
+	characteristics, err := railCatalog.Get(execution.Rail)
+	if err != nil || !characteristics.Reversibility {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("RAIL_NOT_REVERSIBLE", fmt.Sprintf("Payments on the %s rail cannot be reversed", execution.Rail)))
+		return
+	}
+
+	adapter, err := railAdapters.Get(execution.Rail)
+	if err != nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("RAIL_NOT_REVERSIBLE", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adapters.WithExecution(c.Request.Context(), execution.ID), adapterTimeout)
+	defer cancel()
+
+	result, err := adapter.Refund(ctx, execution.ReferenceID, execution.AmountUSD)
+	if err != nil {
+		common.Error("Failed to reverse payment %s via %s: %v", execution.ID, execution.Rail, err)
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("REVERSAL_FAILED", "Processor rejected the reversal"))
+		return
+	}
+	if result.Status == adapters.StatusFailed {
+		c.JSON(http.StatusBadGateway, common.NewErrorResponse("REVERSAL_FAILED", result.Message))
+		return
+	}
+
+	// The funds have moved back, so from here on failures are logged and the
+	// execution is still marked reversed
+	transactionID, err := postCompensatingTransaction(execution, execution.ID+reversalReferenceSuffix, "Reversal of payment "+execution.ID, 1)
+	if err != nil {
+		common.Error("Payment %s reversed but compensating ledger entries failed: %v", execution.ID, err)
+	}
+
+	now := time.Now()
+	execution.Status = "reversed"
+	execution.ReversedAt = &now
+	execution.ReversalReason = req.Reason
+	execution.ReversalTransactionID = transactionID
+	if err := repo.PaymentExecutionRepository().Update(execution); err != nil {
+		common.Error("Failed to record reversal of payment %s: %v", execution.ID, err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Payment was reversed but could not be updated"))
+		return
+	}
+
+	common.Info("Reversed payment %s via %s", execution.ID, execution.Rail)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&types.PaymentExecution{
+		ID:                    execution.ID,
+		AgentID:               execution.AgentID,
+		AmountUSD:             execution.AmountUSD,
+		Counterparty:          execution.Counterparty,
+		Rail:                  execution.Rail,
+		Description:           execution.Description,
+		Status:                execution.Status,
+		Priority:              execution.Priority,
+		ReferenceID:           execution.ReferenceID,
+		CreatedAt:             execution.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:             execution.UpdatedAt.Format(time.RFC3339),
+		ReversedAt:            now.Format(time.RFC3339),
+		ReversalReason:        execution.ReversalReason,
+		ReversalTransactionID: transactionID,
+	}))
+}
+
+// voidPayment cancels an execution whose funds have not been captured, for a
+// caller compensating a payment it no longer wants. Executions the processor
+// has already captured must be reversed instead.
+func voidPayment(c *gin.Context) {
+	execution, err := repo.PaymentExecutionRepository().GetByID(c.Param("id"))
+	if err != nil || !executionInTenant(c, execution) {
+		c.JSON(http.StatusNotFound, common.NewErrorResponse("NOT_FOUND", "Payment execution not found"))
+		return
+	}
+
+	if !common.CanActForAgent(c, execution.AgentID) {
+		c.JSON(http.StatusForbidden, common.NewErrorResponse("FORBIDDEN", "Cannot void this payment"))
+		return
+	}
+
+	if execution.Status != "pending" && execution.Status != "processing" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Only pending or processing payments can be voided"))
+		return
+	}
+	// A batched execution not yet submitted is taken out of its batch
+	if execution.BatchID != nil && execution.ReferenceID == "" {
+		removed, err := removeFromBatch(execution)
+		if err != nil {
+			common.Error("Failed to remove payment %s from its batch: %v", execution.ID, err)
+			c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to void payment"))
+			return
+		}
+		if removed {
+			failExecution(execution, "voided")
+			common.Info("Voided payment %s, removed from its batch", execution.ID)
+			c.JSON(http.StatusOK, common.NewSuccessResponse(&types.PaymentExecution{
+				ID:           execution.ID,
+				AgentID:      execution.AgentID,
+				AmountUSD:    execution.AmountUSD,
+				Counterparty: execution.Counterparty,
+				Rail:         execution.Rail,
+				Description:  execution.Description,
+				Status:       execution.Status,
+				Priority:     execution.Priority,
+				ErrorMessage: execution.ErrorMessage,
+				CreatedAt:    execution.CreatedAt.Format(time.RFC3339),
+				UpdatedAt:    execution.UpdatedAt.Format(time.RFC3339),
+			}))
+			return
+		}
+	}
+	if execution.ReferenceID == "" {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("INVALID_STATUS", "Payment has not been authorized yet"))
+		return
+	}
+
+	adapter, err := railAdapters.Get(execution.Rail)
+	if err != nil {
+		c.JSON(http.StatusConflict, common.NewErrorResponse("VOID_FAILED", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adapters.WithExecution(c.Request.Context(), execution.ID), adapterTimeout)
+	defer cancel()
+
+	result, err := adapter.Cancel(ctx, execution.ReferenceID)
+	if err != nil {
+		common.Error("Failed to void payment %s via %s: %v", execution.ID, execution.Rail, err)
+		c.JSON(http.StatusConflict, common.NewErrorResponse("VOID_FAILED", "Processor could not cancel the payment: "+err.Error()))
+		return
+	}
+
+	applyAdapterStatus(execution, result.Status, "voided")
+	common.Info("Voided payment %s via %s", execution.ID, execution.Rail)
+	c.JSON(http.StatusOK, common.NewSuccessResponse(&types.PaymentExecution{
+		ID:           execution.ID,
+		AgentID:      execution.AgentID,
+		AmountUSD:    execution.AmountUSD,
+		Counterparty: execution.Counterparty,
+		Rail:         execution.Rail,
+		Description:  execution.Description,
+		Status:       execution.Status,
+		Priority:     execution.Priority,
+		ReferenceID:  execution.ReferenceID,
+		ErrorMessage: execution.ErrorMessage,
+		CreatedAt:    execution.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    execution.UpdatedAt.Format(time.RFC3339),
+	}))
+}
+
+// postCompensatingTransaction offsets the given fraction of every posting of
+// the ledger transactions referencing the execution, rounded under the
+// ledger's rounding policy. The rounding residual of each currency is taken
+// up by its largest posting, or booked to the agent's Rounding account, so
+// the transaction balances. It returns an empty ID when nothing was booked.
+func postCompensatingTransaction(execution *database.PaymentExecution, referenceID, description string, fraction float64) (string, error) {
+	originals, err := repo.TransactionRepository().ListByReferenceID(execution.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var postings []database.Posting
+	for _, original := range originals {
+		if original.Status == "posted" {
+			postings = append(postings, original.Postings...)
+		}
+	}
+	if len(postings) == 0 {
+		return "", nil
+	}
+
+	policy := rounding.Current()
+	byCurrency := make(map[string][]int)
+	var currencies []string
+	for i, original := range postings {
+		if _, ok := byCurrency[original.Currency]; !ok {
+			currencies = append(currencies, original.Currency)
+		}
+		byCurrency[original.Currency] = append(byCurrency[original.Currency], i)
+	}
+
+	amounts := make([]float64, len(postings))
+	for _, currency := range currencies {
+		indexes := byCurrency[currency]
+		originalAmounts := make([]float64, len(indexes))
+		for j, i := range indexes {
+			originalAmounts[j] = -postings[i].Amount
+		}
+		scaled, residual := policy.Scale(originalAmounts, fraction)
+		largest := indexes[0]
+		for j, i := range indexes {
+			amounts[i] = scaled[j]
+			if math.Abs(amounts[i]) > math.Abs(amounts[largest]) {
+				largest = i
+			}
+		}
+		if residual == 0 {
+			continue
+		}
+		if policy.Residual == rounding.ResidualAccount {
+			account, err := roundingAccount(execution.AgentID, currency)
+			if err != nil {
+				return "", err
+			}
+			postings = append(postings, database.Posting{AccountID: account.ID, Currency: currency})
+			amounts = append(amounts, residual)
+			continue
+		}
+		amounts[largest] = policy.Round(amounts[largest] + residual)
+	}
+
+	transaction := &database.Transaction{
+		AgentID:     execution.AgentID,
+		Description: description,
+		ReferenceID: referenceID,
+		Status:      "posted",
+	}
+	if err := repo.TransactionRepository().Create(transaction); err != nil {
+		return "", err
+	}
+
+	for i, original := range postings {
+		posting := &database.Posting{
+			TransactionID: transaction.ID,
+			AccountID:     original.AccountID,
+			Amount:        amounts[i],
+			Currency:      original.Currency,
+		}
+		if err := repo.PostingRepository().Create(posting); err != nil {
+			return transaction.ID, err
+		}
+
+		account, err := repo.AccountRepository().GetByID(original.AccountID)
+		if err != nil {
+			return transaction.ID, err
+		}
+		account.Balance += amounts[i]
+		if err := repo.AccountRepository().Update(account); err != nil {
+			return transaction.ID, err
+		}
+	}
+
+	return transaction.ID, repo.TransactionRepository().Chain(transaction.ID)
+}
+
+// roundingAccount finds the agent's Rounding account in a currency, creating
+// it if needed
+func roundingAccount(agentID, currency string) (*database.Account, error) {
+	accounts, err := repo.AccountRepository().ListByAgentIDAndType(agentID, "equity")
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Name == rounding.AccountName && account.Currency == currency {
+			return account, nil
+		}
+	}
+
+	account := &database.Account{
+		AgentID:     agentID,
+		Name:        rounding.AccountName,
+		Type:        "equity",
+		Description: "Residuals of amounts rounded to the ledger's precision",
+		Currency:    currency,
+	}
+	if err := repo.AccountRepository().Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
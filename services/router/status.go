@@ -0,0 +1,379 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/example/agent-payments/internal/database"
+	"github.com/example/agent-payments/libs/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Component statuses, from best to worst
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusOutage      = "outage"
+)
+
+const (
+	// EventBusComponent is the kill switch and status component for event publishing
+	EventBusComponent = "event-bus"
+
+	statusWindow        = 24 * time.Hour
+	incidentWindow      = 7 * 24 * time.Hour
+	degradedSuccessRate = 0.90
+	minSampleSize       = 5
+	maxOutboxLag        = 5 * time.Minute
+)
+
+// uptimeWindows are the periods reported in per-component uptime counters
+var uptimeWindows = []struct {
+	Label  string
+	Window time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+type KillSwitchRequest struct {
+	Engaged bool   `json:"engaged"`
+	Reason  string `json:"reason"`
+}
+
+type KillSwitchState struct {
+	Component string `json:"component"`
+	Engaged   bool   `json:"engaged"`
+	Reason    string `json:"reason,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type StatusPage struct {
+	Status            string            `json:"status"`
+	UpdatedAt         string            `json:"updatedAt"`
+	Components        []ComponentStatus `json:"components"`
+	Incidents         []IncidentMarker  `json:"incidents"`
+	PaymentCompletion CompletionStats   `json:"paymentCompletion"`
+	EventBus          EventBusStatus    `json:"eventBus"`
+}
+
+type ComponentStatus struct {
+	Name       string             `json:"name"`
+	Type       string             `json:"type"` // "rail", "event_bus"
+	Status     string             `json:"status"`
+	Uptime     map[string]float64 `json:"uptime"` // Percentage per window
+	Executions int                `json:"executions,omitempty"`
+	// SuccessRate is the share of finished executions that completed in the status window
+	SuccessRate          *float64 `json:"successRate,omitempty"`
+	AvgCompletionSeconds *float64 `json:"avgCompletionSeconds,omitempty"`
+}
+
+type IncidentMarker struct {
+	Component string `json:"component"`
+	Type      string `json:"type"` // "engaged", "released"
+	Reason    string `json:"reason,omitempty"`
+	At        string `json:"at"`
+}
+
+type CompletionStats struct {
+	Window               string   `json:"window"`
+	Completed            int      `json:"completed"`
+	Failed               int      `json:"failed"`
+	AvgCompletionSeconds *float64 `json:"avgCompletionSeconds"`
+}
+
+type EventBusStatus struct {
+	Status           string   `json:"status"`
+	PendingEvents    int64    `json:"pendingEvents"`
+	FailedEvents     int64    `json:"failedEvents"`
+	OldestPendingAge *float64 `json:"oldestPendingAgeSeconds"`
+}
+
+// engagedKillSwitches returns the components whose kill switch is currently engaged
+func engagedKillSwitches() map[string]*database.KillSwitchEvent {
+	engaged := make(map[string]*database.KillSwitchEvent)
+
+	events, err := repo.KillSwitchEventRepository().ListCurrent()
+	if err != nil {
+		common.Error("Failed to load kill switches: %v", err)
+		return engaged
+	}
+
+	for _, event := range events {
+		if event.Engaged {
+			engaged[event.Component] = event
+		}
+	}
+	return engaged
+}
+
+func toggleKillSwitch(c *gin.Context) {
+	component := c.Param("component")
+
+	var req KillSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "Invalid request format"))
+		return
+	}
+	if req.Engaged && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.NewErrorResponse("VALIDATION_ERROR", "reason is required when engaging a kill switch"))
+		return
+	}
+
+	event := &database.KillSwitchEvent{
+		Component: component,
+		Engaged:   req.Engaged,
+		Reason:    req.Reason,
+	}
+	if principal := common.GetPrincipal(c); principal != nil {
+		event.Actor = principal.Subject
+	}
+
+	if err := repo.KillSwitchEventRepository().Create(event); err != nil {
+		common.Error("Failed to record kill switch toggle: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to toggle kill switch"))
+		return
+	}
+
+	if event.Engaged {
+		common.Warn("Kill switch engaged for %s: %s", component, event.Reason)
+	} else {
+		common.Info("Kill switch released for %s", component)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(toKillSwitchState(event)))
+}
+
+func listKillSwitches(c *gin.Context) {
+	events, err := repo.KillSwitchEventRepository().ListCurrent()
+	if err != nil {
+		common.Error("Failed to list kill switches: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to list kill switches"))
+		return
+	}
+
+	states := make([]KillSwitchState, len(events))
+	for i, event := range events {
+		states[i] = *toKillSwitchState(event)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(states))
+}
+
+func toKillSwitchState(event *database.KillSwitchEvent) *KillSwitchState {
+	return &KillSwitchState{
+		Component: event.Component,
+		Engaged:   event.Engaged,
+		Reason:    event.Reason,
+		Actor:     event.Actor,
+		UpdatedAt: event.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// getStatus returns public status page data. It is served without authentication
+// and only exposes aggregate figures.
+func getStatus(c *gin.Context) {
+	now := time.Now()
+
+	executions, err := repo.PaymentExecutionRepository().ListSince(now.Add(-statusWindow))
+	if err != nil {
+		common.Error("Failed to load executions for status: %v", err)
+		c.JSON(http.StatusInternalServerError, common.NewErrorResponse("DATABASE_ERROR", "Failed to load status"))
+		return
+	}
+
+	engaged := engagedKillSwitches()
+	page := &StatusPage{
+		Status:     StatusOperational,
+		UpdatedAt:  now.Format(time.RFC3339),
+		Components: []ComponentStatus{},
+		Incidents:  []IncidentMarker{},
+	}
+
+	// Rail health from recent executions
+	byRail := make(map[string][]*database.PaymentExecution)
+	for _, execution := range executions {
+		byRail[execution.Rail] = append(byRail[execution.Rail], execution)
+	}
+
+	for _, rail := range allRails(0) {
+		component := ComponentStatus{
+			Name:   rail.Rail,
+			Type:   "rail",
+			Status: StatusOperational,
+			Uptime: componentUptime(rail.Rail, now),
+		}
+
+		completed, failed, avg := completionStats(byRail[rail.Rail])
+		component.Executions = len(byRail[rail.Rail])
+		component.AvgCompletionSeconds = avg
+		if finished := completed + failed; finished > 0 {
+			rate := roundTo(float64(completed)/float64(finished), 4)
+			component.SuccessRate = &rate
+			if finished >= minSampleSize && rate < degradedSuccessRate {
+				component.Status = StatusDegraded
+			}
+		}
+		if _, ok := engaged[rail.Rail]; ok {
+			component.Status = StatusOutage
+		}
+
+		page.Components = append(page.Components, component)
+	}
+
+	// Event bus lag from the outbox
+	page.EventBus = eventBusStatus(now)
+	if _, ok := engaged[EventBusComponent]; ok {
+		page.EventBus.Status = StatusOutage
+	}
+	page.Components = append(page.Components, ComponentStatus{
+		Name:   EventBusComponent,
+		Type:   "event_bus",
+		Status: page.EventBus.Status,
+		Uptime: componentUptime(EventBusComponent, now),
+	})
+
+	completed, failed, avg := completionStats(executions)
+	page.PaymentCompletion = CompletionStats{
+		Window:               "24h",
+		Completed:            completed,
+		Failed:               failed,
+		AvgCompletionSeconds: avg,
+	}
+
+	// Incident markers from kill switch toggles
+	events, err := repo.KillSwitchEventRepository().ListSince(now.Add(-incidentWindow))
+	if err != nil {
+		common.Error("Failed to load incidents for status: %v", err)
+	}
+	for _, event := range events {
+		marker := IncidentMarker{
+			Component: event.Component,
+			Type:      "released",
+			Reason:    event.Reason,
+			At:        event.CreatedAt.Format(time.RFC3339),
+		}
+		if event.Engaged {
+			marker.Type = "engaged"
+		}
+		page.Incidents = append(page.Incidents, marker)
+	}
+
+	for _, component := range page.Components {
+		page.Status = worstStatus(page.Status, component.Status)
+	}
+
+	c.JSON(http.StatusOK, common.NewSuccessResponse(page))
+}
+
+// completionStats counts finished executions and averages completion time
+func completionStats(executions []*database.PaymentExecution) (completed, failed int, avgSeconds *float64) {
+	var total time.Duration
+	for _, execution := range executions {
+		switch execution.Status {
+		case "completed":
+			completed++
+			total += execution.UpdatedAt.Sub(execution.CreatedAt)
+		case "failed":
+			failed++
+		}
+	}
+
+	if completed > 0 {
+		avg := roundTo(total.Seconds()/float64(completed), 2)
+		avgSeconds = &avg
+	}
+	return completed, failed, avgSeconds
+}
+
+func eventBusStatus(now time.Time) EventBusStatus {
+	status := EventBusStatus{Status: StatusOperational}
+
+	pending, err := repo.OutboxEventRepository().CountByStatus("pending")
+	if err != nil {
+		common.Error("Failed to count pending outbox events: %v", err)
+	}
+	failed, err := repo.OutboxEventRepository().CountByStatus("failed")
+	if err != nil {
+		common.Error("Failed to count failed outbox events: %v", err)
+	}
+	status.PendingEvents = pending
+	status.FailedEvents = failed
+
+	oldest, err := repo.OutboxEventRepository().ListPending(1)
+	if err == nil && len(oldest) > 0 {
+		age := now.Sub(oldest[0].CreatedAt)
+		seconds := roundTo(age.Seconds(), 2)
+		status.OldestPendingAge = &seconds
+		if age > maxOutboxLag {
+			status.Status = StatusDegraded
+		}
+	}
+
+	if failed > 0 {
+		status.Status = StatusDegraded
+	}
+	return status
+}
+
+// componentUptime computes the uptime percentage of a component per window,
+// counting time with its kill switch engaged as downtime
+func componentUptime(component string, now time.Time) map[string]float64 {
+	uptime := make(map[string]float64)
+
+	events, err := repo.KillSwitchEventRepository().ListByComponent(component)
+	if err != nil {
+		common.Error("Failed to load kill switch history for %s: %v", component, err)
+	}
+
+	for _, w := range uptimeWindows {
+		uptime[w.Label] = uptimePercentage(events, now.Add(-w.Window), now)
+	}
+	return uptime
+}
+
+// uptimePercentage replays toggle events (oldest first) over [start, end]
+func uptimePercentage(events []*database.KillSwitchEvent, start, end time.Time) float64 {
+	var downtime time.Duration
+	engaged := false
+	downSince := start
+
+	for _, event := range events {
+		at := event.CreatedAt
+		if at.Before(start) {
+			at = start
+		}
+		if at.After(end) {
+			break
+		}
+
+		if event.Engaged && !engaged {
+			engaged = true
+			downSince = at
+		} else if !event.Engaged && engaged {
+			engaged = false
+			downtime += at.Sub(downSince)
+		}
+	}
+	if engaged {
+		downtime += end.Sub(downSince)
+	}
+
+	return roundTo(100*(1-downtime.Seconds()/end.Sub(start).Seconds()), 3)
+}
+
+func worstStatus(a, b string) string {
+	rank := map[string]int{StatusOperational: 0, StatusDegraded: 1, StatusOutage: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}